@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rizqme/gode/internal/admin"
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+// adminTarget exposes the current runtime to the admin server. Under
+// --supervise the runtime is replaced after a crash while the server, and
+// its token, stay the same.
+type adminTarget struct {
+	rt     atomic.Pointer[runtime.Runtime]
+	server *admin.Server
+}
+
+// Attach makes rt the inspected runtime, starting the server on
+// localhost:port the first time. The token comes from GODE_ADMIN_TOKEN or
+// is generated and printed to stderr.
+func (t *adminTarget) Attach(rt *runtime.Runtime, port int) error {
+	t.rt.Store(rt)
+	if t.server != nil {
+		return nil
+	}
+
+	token := os.Getenv(admin.EnvToken)
+	server, err := admin.Listen(net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), token, t)
+	if err != nil {
+		return fmt.Errorf("admin server: %w", err)
+	}
+	t.server = server
+	if token == "" {
+		fmt.Fprintf(os.Stderr, "gode: admin server on http://%s (token %s)\n", server.Addr(), server.Token())
+	} else {
+		fmt.Fprintf(os.Stderr, "gode: admin server on http://%s\n", server.Addr())
+	}
+	return nil
+}
+
+// Close stops the server
+func (t *adminTarget) Close() {
+	if t.server != nil {
+		t.server.Close()
+	}
+}
+
+func (t *adminTarget) Ping(timeout time.Duration) error {
+	return t.rt.Load().Ping(timeout)
+}
+
+func (t *adminTarget) Inspect(source string, timeout time.Duration) (string, error) {
+	return t.rt.Load().Inspect(source, timeout)
+}
+
+func (t *adminTarget) Metrics() []admin.Metric {
+	s := t.rt.Load().Stats()
+	metrics := []admin.Metric{
+		{Name: "uptime_seconds", Help: "Time since the runtime started", Type: "gauge", Value: s.Uptime.Seconds()},
+		{Name: "js_busy_seconds_total", Help: "Time the JS thread spent running operations", Type: "counter", Value: s.Busy.Seconds()},
+		{Name: "queue_length", Help: "JS operations waiting to run", Type: "gauge", Value: float64(s.Queue.Length)},
+		{Name: "queue_capacity", Help: "JS operations the queue holds before dropping", Type: "gauge", Value: float64(s.Queue.Capacity)},
+		{Name: "queue_processed_total", Help: "JS operations run", Type: "counter", Value: float64(s.Queue.Processed)},
+		{Name: "queue_dropped_total", Help: "JS operations dropped because the queue was full", Type: "counter", Value: float64(s.Queue.Dropped)},
+		{Name: "handles", Help: "Open handles keeping the program alive", Type: "gauge", Value: float64(s.Handles)},
+		{Name: "timers", Help: "Pending timeouts and intervals", Type: "gauge", Value: float64(s.Timers)},
+		{Name: "modules", Help: "Modules loaded", Type: "gauge", Value: float64(s.Modules)},
+		{Name: "plugins", Help: "Go plugins loaded", Type: "gauge", Value: float64(s.Plugins)},
+		{Name: "goroutines", Help: "Goroutines in the process", Type: "gauge", Value: float64(s.Goroutines)},
+		{Name: "heap_alloc_bytes", Help: "Bytes of allocated heap objects", Type: "gauge", Value: float64(s.HeapAlloc)},
+		{Name: "sys_bytes", Help: "Bytes obtained from the OS", Type: "gauge", Value: float64(s.Sys)},
+		{Name: "gc_total", Help: "Completed GC cycles", Type: "counter", Value: float64(s.NumGC)},
+	}
+	for _, lane := range s.Queue.Lanes {
+		labels := map[string]string{"lane": lane.Priority.String()}
+		metrics = append(metrics,
+			admin.Metric{Name: "queue_lane_length", Help: "JS operations waiting, per priority lane", Type: "gauge", Labels: labels, Value: float64(lane.Length)},
+			admin.Metric{Name: "queue_lane_processed_total", Help: "JS operations run, per priority lane", Type: "counter", Labels: labels, Value: float64(lane.Processed)},
+			admin.Metric{Name: "queue_lane_dropped_total", Help: "JS operations dropped, per priority lane", Type: "counter", Labels: labels, Value: float64(lane.Dropped)},
+		)
+	}
+	return metrics
+}
+
+func (t *adminTarget) Modules() interface{} {
+	rt := t.rt.Load()
+	type module struct {
+		Specifier string    `json:"specifier"`
+		Path      string    `json:"path"`
+		Size      int       `json:"size"`
+		LoadTime  float64   `json:"loadTime"` // milliseconds
+		LoadedAt  time.Time `json:"loadedAt"`
+	}
+	type plugin struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Path    string `json:"path"`
+	}
+
+	result := struct {
+		Modules []module `json:"modules"`
+		Plugins []plugin `json:"plugins"`
+	}{Modules: []module{}, Plugins: []plugin{}}
+	for _, m := range rt.Modules() {
+		result.Modules = append(result.Modules, module{m.Specifier, m.Path, m.Size, float64(m.LoadTime) / float64(time.Millisecond), m.LoadedAt})
+	}
+	for _, p := range rt.Plugins() {
+		result.Plugins = append(result.Plugins, plugin{p.Name, p.Version, p.Path})
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rizqme/gode/internal/diskcache"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// cacheCommand shows and cleans the on-disk caches of compiled scripts,
+// remote modules and npm packages
+func cacheCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 1
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode cache: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case args[0] == "dir" && len(args) == 1:
+		fmt.Println(cache.Dir())
+		return 0
+	case args[0] == "ls" && len(args) == 1:
+		return cacheList(cache)
+	case args[0] == "prune":
+		return cachePrune(cache, args[1:])
+	case args[0] == "verify" && len(args) == 1:
+		return cacheVerify(cache)
+	}
+	fmt.Fprint(os.Stderr, usage)
+	return 1
+}
+
+// openCache returns the cache of the project in the working directory
+func openCache() (*diskcache.Cache, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadPackageJSON(config.FindProjectRoot(filepath.Join(cwd, "package.json")))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := diskcache.Dir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return diskcache.New(dir), nil
+}
+
+// cacheList prints each entry with its size and where it came from
+func cacheList(cache *diskcache.Cache) int {
+	entries, err := cache.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode cache: %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var total int64
+	for _, entry := range entries {
+		origins := strings.Join(entry.Meta.Origins, ", ")
+		if origins == "" {
+			origins = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Kind, entry.Name, formatSize(entry.Size), origins)
+		total += entry.Size
+	}
+	w.Flush()
+	fmt.Printf("%d entries, %s\n", len(entries), formatSize(total))
+	return 0
+}
+
+// cachePrune removes the entries not used within --older-than
+func cachePrune(cache *diskcache.Cache, args []string) int {
+	flags := flag.NewFlagSet("cache prune", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	olderThan := flags.String("older-than", "30d", "remove entries not used for this long")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	age, err := diskcache.ParseAge(*olderThan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode cache: %v\n", err)
+		return 2
+	}
+
+	removed, err := cache.Prune(time.Now().Add(-age))
+	var freed int64
+	for _, entry := range removed {
+		freed += entry.Size
+	}
+	fmt.Printf("Removed %d entries, %s\n", len(removed), formatSize(freed))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode cache: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cacheVerify re-checks the integrity hash of every entry that has one,
+// failing if any no longer matches
+func cacheVerify(cache *diskcache.Cache) int {
+	checked, problems, err := cache.Verify()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode cache: %v\n", err)
+		return 1
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "%s/%s: %v\n", problem.Entry.Kind, problem.Entry.Name, problem.Err)
+	}
+	fmt.Printf("Verified %d entries, %d failed\n", checked, len(problems))
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// formatSize formats a byte count as B, KB, MB or GB
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB"} {
+		if size < unit {
+			break
+		}
+		size, suffix = size/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", size, suffix)
+}
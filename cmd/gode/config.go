@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// configCommand reads and writes the user-level config, ~/.gode/config.json
+func configCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 1
+	}
+
+	var err error
+	switch {
+	case args[0] == "get" && len(args) <= 2:
+		key := ""
+		if len(args) == 2 {
+			key = args[1]
+		}
+		return configGet(key)
+	case args[0] == "set" && len(args) == 3:
+		err = config.SetGlobalSetting(args[1], args[2])
+	case args[0] == "unset" && len(args) == 2:
+		err = config.UnsetGlobalSetting(args[1])
+	case args[0] == "path" && len(args) == 1:
+		var path string
+		if path, err = config.GlobalConfigPath(); err == nil {
+			fmt.Println(path)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 1
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode config: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// configGet prints the value of key, or the whole config without a key.
+// Strings are printed as they are and other values as JSON.
+func configGet(key string) int {
+	value, ok, err := config.GetGlobalSetting(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode config: %v\n", err)
+		return 1
+	}
+	if !ok {
+		return 1
+	}
+
+	if s, ok := value.(string); ok {
+		fmt.Println(s)
+		return 0
+	}
+	data, _ := json.MarshalIndent(value, "", "  ")
+	fmt.Println(string(data))
+	return 0
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rizqme/gode/internal/docgen"
+)
+
+// docCommand writes API documentation for the gode: built-ins and the given
+// plugins. With -extract it instead generates the Go file a plugin embeds
+// so its doc comments are available at runtime.
+func docCommand(args []string) int {
+	flags := flag.NewFlagSet("doc", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	format := flags.String("format", "md", "output format: md or html")
+	out := flags.String("o", "", `output file ("-" for stdout)`)
+	extract := flags.Bool("extract", false, "generate embedded docs from plugin Go sources")
+	pkg := flags.String("pkg", "main", "package name for -extract")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *extract {
+		return extractDocs(flags.Args(), *pkg, *out)
+	}
+
+	var render func(io.Writer, []docgen.Module) error
+	switch *format {
+	case "md":
+		render = docgen.Markdown
+	case "html":
+		render = docgen.HTML
+	default:
+		fmt.Fprintf(os.Stderr, "gode doc: unknown format %q\n", *format)
+		return 2
+	}
+	if *out == "" {
+		*out = "API." + *format
+	}
+
+	rt, ok := pluginRuntime("[doc]", flags.Args())
+	if !ok {
+		return 1
+	}
+	defer rt.Dispose()
+
+	var b bytes.Buffer
+	if err := render(&b, rt.DocModules()); err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+	return writeOutput(*out, b.Bytes())
+}
+
+// extractDocs generates Docs and ParamNames functions from the exported
+// functions in files
+func extractDocs(files []string, pkg, out string) int {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "gode doc -extract: missing Go source files")
+		return 1
+	}
+	docs, params, err := docgen.Extract(files...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+
+	var b bytes.Buffer
+	if err := docgen.WriteGo(&b, pkg, docs, params); err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+	if out == "" {
+		out = "docs_gen.go"
+	}
+	return writeOutput(out, b.Bytes())
+}
+
+// writeOutput writes data to path, or to stdout when path is "-"
+func writeOutput(path string, data []byte) int {
+	if path == "-" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return 0
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/rizqme/gode/internal/diskcache"
+	"github.com/rizqme/gode/internal/download"
+	"github.com/rizqme/gode/internal/install"
+	"github.com/rizqme/gode/internal/proxy"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// installCommand installs the npm dependencies of the project in the
+// working directory and writes gode.lock
+func installCommand(args []string) int {
+	flags := flag.NewFlagSet("install", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	frozen := flags.Bool("frozen", false, "fail instead of changing gode.lock")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode install: %v\n", err)
+		return 1
+	}
+	cfg, err := config.LoadPackageJSON(config.FindProjectRoot(filepath.Join(cwd, "package.json")))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode install: %v\n", err)
+		return 1
+	}
+	dir, err := diskcache.Dir(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode install: %v\n", err)
+		return 1
+	}
+	proxy.Configure(cfg.Gode.Network)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	client := proxy.Client(0)
+	result, err := install.Install(ctx, cfg, diskcache.New(dir), install.Options{
+		Frozen:     *frozen,
+		Client:     client,
+		Downloader: download.New(download.Options{Client: client, Progress: download.NewProgress(os.Stderr)}),
+		Out:        os.Stdout,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode install: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%d packages installed, %d added, %d removed\n", len(result.Lockfile.Packages), len(result.Added), len(result.Removed))
+	return 0
+}
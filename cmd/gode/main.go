@@ -0,0 +1,314 @@
+// Command gode runs JavaScript programs and tests on the gode runtime
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rizqme/gode/internal/modules/globals"
+	"github.com/rizqme/gode/internal/runtime"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// stdinName identifies a script read from stdin in stack traces and argv
+const stdinName = "[stdin]"
+
+const usage = `Usage: gode <command> [arguments]
+
+Commands:
+  run [-r module]... <file> [args...]   Run a JavaScript file ("-" reads stdin)
+  <file> [args...]                      Same as run, for #!/usr/bin/env gode
+  -e code, -p code                      Same as run -e / run -p
+  test [flags] [files, directories or globs...]
+                                        Run test files (default: tests/)
+  types [-o file] [plugin.so...]        Write TypeScript declarations for the
+                                        gode: modules and plugins (gode.d.ts)
+  doc [-format md|html] [-o file] [plugin.so...]
+                                        Write API docs for the gode: modules
+                                        and plugins (API.md / API.html)
+  doc -extract [-o file] [-pkg name] <file.go...>
+                                        Generate docs_gen.go so a plugin
+                                        embeds its doc comments
+  config get [key] | set <key> <value> | unset <key> | path
+                                        Read or change the user-level config
+                                        (~/.gode/config.json)
+  install [--frozen]                    Install the npm dependencies into
+                                        node_modules and pin them in gode.lock
+                                        (--frozen: fail if gode.lock would
+                                        change)
+  cache dir | ls | prune [--older-than 30d] | verify
+                                        Show, clean or re-check the caches of
+                                        compiled scripts, remote modules and
+                                        npm packages
+  version                               Print the gode version
+  help                                  Show this help
+
+Run flags:
+  -r, --require module   Load a module before the entrypoint (repeatable)
+  -e, --eval code        Run code instead of a file
+  -p, --print code       Run code and print the result
+  --no-warnings          Don't print process warnings to stderr
+  --trace-warnings       Print the stack trace of each warning
+  --supervise            Recreate the runtime after a fatal Go panic on the
+                         JS thread, with exponential backoff
+  --max-restarts n       Stop restarting after n restarts (default: no limit)
+  --frozen-intrinsics    Freeze Object.prototype, Array.prototype and the
+                         other built-ins before any module loads, against
+                         prototype pollution
+  --max-procs n          Run Go code on at most n threads at once
+                         (GOMAXPROCS; default: GODE_MAX_PROCS or the number
+                         of CPUs)
+  --gc-percent n         Collect garbage when the heap has grown by n% since
+                         the last collection, or never with "off" (default:
+                         GODE_GC_PERCENT, GOGC or 100)
+  --admin-port port      Serve health, metrics, pprof, modules and an eval
+                         console on localhost:port. Requests need the token
+                         from GODE_ADMIN_TOKEN, or the one printed at start.
+
+Test flags:
+  --reporter r           Report with r (repeatable, default: default): one
+                         of default, spec, dot, json or junit, optionally as
+                         r=file to write to file; the name of a reporter
+                         registered by a plugin or with test.registerReporter;
+                         or a ./reporter.js module
+  --filter re, --grep re Run only the tests whose full name (suite names and
+                         test name joined with spaces) matches re
+  --bail                 Skip the remaining tests after the first failure
+  --timeout t            Default test timeout, in milliseconds or as a
+                         duration like 10s (overrides gode.test.timeout)
+  --update               Write the files compared by expect().toMatchFile
+                         instead of failing on a difference
+`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run dispatches a command and returns the process exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 1
+	}
+
+	// Every command runs JavaScript on the Go runtime tuned by GODE_*
+	tuning, err := runtime.TuningFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+	tuning.Apply()
+
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:])
+	case "-e", "--eval", "-p", "--print", "-r", "--require", "--no-warnings", "--trace-warnings", "--admin-port", "--supervise", "--frozen-intrinsics", "--max-procs", "--gc-percent":
+		// node-style "gode -p expr" without the run subcommand
+		return runCommand(args)
+	case "test":
+		return testCommand(args[1:])
+	case "types":
+		return typesCommand(args[1:])
+	case "doc":
+		return docCommand(args[1:])
+	case "config":
+		return configCommand(args[1:])
+	case "cache":
+		return cacheCommand(args[1:])
+	case "install":
+		return installCommand(args[1:])
+	case "version", "--version", "-v":
+		fmt.Printf("gode %s\n", runtime.Version)
+		return 0
+	case "help", "--help", "-h":
+		fmt.Print(usage)
+		return 0
+	}
+
+	// Invoked as an interpreter through a "#!/usr/bin/env gode" line: the
+	// script path comes first and everything after it belongs to the script
+	if info, err := os.Stat(args[0]); err == nil && !info.IsDir() {
+		return runCommand(args)
+	}
+
+	fmt.Fprintf(os.Stderr, "gode: unknown command %q\n\n%s", args[0], usage)
+	return 1
+}
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	var preload stringList
+	var eval, print string
+	flags.Var(&preload, "r", "module to load before the entrypoint")
+	flags.Var(&preload, "require", "module to load before the entrypoint")
+	flags.StringVar(&eval, "e", "", "code to run instead of a file")
+	flags.StringVar(&eval, "eval", "", "code to run instead of a file")
+	flags.StringVar(&print, "p", "", "code to run, printing the result")
+	flags.StringVar(&print, "print", "", "code to run, printing the result")
+	noWarnings := flags.Bool("no-warnings", false, "don't print process warnings")
+	traceWarnings := flags.Bool("trace-warnings", false, "print warning stack traces")
+	adminPort := flags.Int("admin-port", 0, "serve the admin endpoint on this port")
+	supervise := flags.Bool("supervise", false, "restart the runtime after a fatal panic")
+	maxRestarts := flags.Int("max-restarts", 0, "give up after this many restarts (0: no limit)")
+	frozenIntrinsics := flags.Bool("frozen-intrinsics", false, "freeze the built-in prototypes and constructors")
+	var tuning runtime.Tuning
+	flags.Func("max-procs", "GOMAXPROCS", tuning.ParseMaxProcs)
+	flags.Func("gc-percent", "heap growth that triggers a collection, or off", tuning.ParseGCPercent)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	// Before the runtime starts, over the GODE_* settings
+	tuning.Apply()
+
+	// Inline code takes the place of the entrypoint, so every positional
+	// argument is passed through to the script
+	inline := eval != "" || print != ""
+	if !inline && flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "gode run: missing file")
+		return 1
+	}
+
+	var source []byte
+	var entrypoint string
+	var scriptArgs []string
+	switch {
+	case inline:
+		entrypoint = "[eval]"
+		scriptArgs = flags.Args()
+
+	case flags.Arg(0) == "-":
+		// A script piped on stdin, resolved against the working directory
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gode: failed to read stdin: %v\n", err)
+			return 1
+		}
+		source = data
+		entrypoint = stdinName
+		scriptArgs = append([]string{stdinName}, flags.Args()[1:]...)
+
+	default:
+		path, err := filepath.Abs(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+			return 1
+		}
+		entrypoint = path
+		scriptArgs = append([]string{path}, flags.Args()[1:]...)
+	}
+
+	// The globals module reads these when it installs process.emitWarning
+	if *noWarnings {
+		os.Setenv(globals.EnvNoWarnings, "1")
+	}
+	if *traceWarnings {
+		os.Setenv(globals.EnvTraceWarnings, "1")
+	}
+
+	path, err := filepath.Abs(entrypoint)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+
+	var admin *adminTarget
+	if *adminPort != 0 {
+		admin = &adminTarget{}
+		defer admin.Close()
+	}
+
+	signals := handleSignals()
+	defer signals.Stop()
+
+	opts := runtime.SupervisorOptions{Restart: *supervise, MaxRestarts: *maxRestarts}
+	err = runtime.Supervise(opts, func() (*runtime.Runtime, error) {
+		return newRuntime(path, scriptArgs)
+	}, func(rt *runtime.Runtime) error {
+		signals.Attach(rt)
+		defer signals.Attach(nil)
+		if admin != nil {
+			if err := admin.Attach(rt, *adminPort); err != nil {
+				return err
+			}
+		}
+		if *frozenIntrinsics {
+			if err := rt.FreezeIntrinsics(); err != nil {
+				return err
+			}
+		}
+		if err := rt.Preload(preload); err != nil {
+			return err
+		}
+
+		switch {
+		case print != "":
+			return rt.Eval(print, true)
+		case eval != "":
+			return rt.Eval(eval, false)
+		case source != nil:
+			return rt.RunSource(stdinName, string(source))
+		default:
+			return rt.Run(entrypoint)
+		}
+	})
+	return exitCode(err)
+}
+
+// exitCode maps the outcome of running a script to the process status.
+// The script's own process.exit/exitCode wins; uncaught errors, which the
+// runtime has already printed, give 1.
+func exitCode(err error) int {
+	var exitErr *runtime.ExitError
+	var panicErr *runtime.PanicError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.As(err, &panicErr):
+		// Already reported with its stack by the runtime
+	case err.Error() != "execution failed":
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+	}
+	return 1
+}
+
+// newRuntime creates a runtime configured from the package.json nearest to
+// path. scriptArgs become process.argv after the executable.
+func newRuntime(path string, scriptArgs []string) (*runtime.Runtime, error) {
+	cfg, err := config.LoadPackageJSON(config.FindProjectRoot(path))
+	if err != nil {
+		return nil, err
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		execPath = os.Args[0]
+	}
+
+	rt := runtime.New()
+	if err := rt.Configure(cfg, append([]string{execPath}, scriptArgs...)); err != nil {
+		rt.Dispose()
+		return nil, err
+	}
+	return rt, nil
+}
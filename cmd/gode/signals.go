@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+// signalTarget delivers the signals the process receives to the current
+// runtime, which hands them to the script's listeners or stops the script
+// gracefully. A second signal the script doesn't handle, or one arriving
+// while no runtime is running, such as between supervisor restarts, exits
+// straight away.
+type signalTarget struct {
+	rt      atomic.Pointer[runtime.Runtime]
+	signals chan os.Signal
+}
+
+// handleSignals starts delivering runtime.Signals to the runtime set with
+// Attach
+func handleSignals() *signalTarget {
+	t := &signalTarget{signals: make(chan os.Signal, 1)}
+	signal.Notify(t.signals, runtime.Signals...)
+	go t.forward()
+	return t
+}
+
+// Attach makes rt the runtime signals go to, nil for none
+func (t *signalTarget) Attach(rt *runtime.Runtime) {
+	t.rt.Store(rt)
+}
+
+// Stop restores the default handling of signals
+func (t *signalTarget) Stop() {
+	signal.Stop(t.signals)
+}
+
+func (t *signalTarget) forward() {
+	stopping := false
+	for sig := range t.signals {
+		if rt := t.rt.Load(); rt != nil && !stopping {
+			stopping = !rt.Signal(sig)
+			continue
+		}
+		os.Exit(runtime.SignalExitCode(sig))
+	}
+}
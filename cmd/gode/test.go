@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/internal/modules/test"
+)
+
+// testCommand runs the given test files, the files matching the given glob
+// patterns, or every *.test.js and *.spec.js below the given directories,
+// and reports the results with the selected reporters
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	var reporters stringList
+	flags.Var(&reporters, "reporter", "reporter name or module path (repeatable)")
+	update := flags.Bool("update", false, "write golden files instead of comparing with them")
+	var filter string
+	flags.StringVar(&filter, "filter", "", "run only the tests whose full name matches this regular expression")
+	flags.StringVar(&filter, "grep", "", "same as --filter")
+	bail := flags.Bool("bail", false, "skip the remaining tests after the first failure")
+	timeoutFlag := flags.String("timeout", "", "default test timeout, in milliseconds or as a duration like 10s")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if len(reporters) == 0 {
+		reporters = stringList{"default"}
+	}
+
+	var filterRE *regexp.Regexp
+	if filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gode test: invalid --filter: %v\n", err)
+			return 2
+		}
+		filterRE = re
+	}
+	timeout, err := parseTestTimeout(*timeoutFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode test: invalid --timeout: %v\n", err)
+		return 2
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		args = []string{"tests"}
+	}
+
+	files, err := findTestFiles(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode test: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "gode test: no test files found")
+		return 1
+	}
+
+	rt, err := newRuntime(files[0], files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+	defer rt.Dispose()
+
+	results, err := rt.RunTestsWithOptions(files, test.RunOptions{
+		Reporters: reporters,
+		Update:    *update,
+		Timeout:   timeout,
+		Filter:    filterRE,
+		Bail:      *bail,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode test: %v\n", err)
+		return 1
+	}
+
+	for _, suite := range results {
+		if suite.Failed > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseTestTimeout parses --timeout: milliseconds, as gode.test.timeout,
+// or a duration. Empty means the configured or default timeout.
+func parseTestTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("negative timeout %s", value)
+	}
+	return d, err
+}
+
+func findTestFiles(args []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		abs, _ := filepath.Abs(path)
+		if !seen[abs] {
+			seen[abs] = true
+			files = append(files, abs)
+		}
+	}
+	for _, arg := range args {
+		if hasGlobMeta(arg) {
+			matches, err := globFiles(arg)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				add(match)
+			}
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(arg)
+			continue
+		}
+
+		err = filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && info.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() && isTestFile(path) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func isTestFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".test.js") || strings.HasSuffix(name, ".spec.js")
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globFiles returns the files matching pattern, in which ** matches any
+// number of directories, as in "src/**/*.test.js". node_modules is only
+// searched when the pattern names it.
+func globFiles(pattern string) ([]string, error) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	static := 0
+	for static < len(segments) && !hasGlobMeta(segments[static]) {
+		static++
+	}
+	for _, segment := range segments[static:] {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	root := path.Join(segments[:static]...)
+	if strings.HasPrefix(pattern, "/") {
+		root = "/" + root
+	}
+	if root == "" {
+		root = "."
+	}
+	rest := segments[static:]
+
+	var files []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "node_modules" && !strings.Contains(pattern, "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if matchSegments(rest, strings.Split(filepath.ToSlash(rel), "/")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// matchSegments matches the segments of a path against those of a pattern
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchSegments(pattern[1:], name[1:])
+}
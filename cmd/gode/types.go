@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+// typesCommand writes TypeScript declarations for the gode: built-ins and
+// the given plugins
+func typesCommand(args []string) int {
+	flags := flag.NewFlagSet("types", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	out := flags.String("o", "gode.d.ts", `output file ("-" for stdout)`)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	rt, ok := pluginRuntime("[types]", flags.Args())
+	if !ok {
+		return 1
+	}
+	defer rt.Dispose()
+
+	defs := rt.TypeDefinitions()
+	if *out == "-" {
+		fmt.Print(defs)
+		return 0
+	}
+	if err := os.WriteFile(*out, []byte(defs), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", *out)
+	return 0
+}
+
+// pluginRuntime creates a runtime for the working directory with the given
+// plugins loaded, printing any error. name labels the scripts it runs.
+func pluginRuntime(name string, pluginPaths []string) (*runtime.Runtime, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return nil, false
+	}
+	rt, err := newRuntime(filepath.Join(cwd, name), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gode: %v\n", err)
+		return nil, false
+	}
+
+	// Loading a plugin through require registers it with the runtime
+	for _, plugin := range pluginPaths {
+		path, err := filepath.Abs(plugin)
+		if err == nil {
+			_, err = rt.RunScript(name, "require("+strconv.Quote(path)+")")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gode: failed to load %s: %v\n", plugin, err)
+			rt.Dispose()
+			return nil, false
+		}
+	}
+	return rt, true
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -11,20 +12,60 @@ func Name() string { return "async" }
 func Version() string { return "2.0.0" }
 func Description() string { return "Async operations plugin" }
 
+// taskRunner is implemented by the gode runtime passed to Initialize. Work
+// started through it is cancelled when the runtime shuts down instead of
+// outliving it.
+type taskRunner interface {
+	Go(name string, fn func(ctx context.Context))
+}
+
+var runner taskRunner
+
+// promiseFactory is implemented by the gode runtime passed to Initialize.
+// The promises it creates settle on the JS thread, so they chain and work
+// with await.
+type promiseFactory interface {
+	NewPromise(executor func(resolve, reject func(interface{}))) interface{}
+}
+
+var promises promiseFactory
+
+// newPromise returns a JS Promise settled by executor
+func newPromise(executor func(resolve, reject func(interface{}))) interface{} {
+	if promises == nil {
+		return fmt.Errorf("async plugin is not initialized")
+	}
+	return promises.NewPromise(executor)
+}
+
+// after calls fn once delay has passed, unless the runtime shuts down first
+func after(name string, delay time.Duration, fn func()) {
+	task := func(ctx context.Context) {
+		select {
+		case <-time.After(delay):
+			fn()
+		case <-ctx.Done():
+		}
+	}
+	if runner == nil {
+		go task(context.Background())
+		return
+	}
+	runner.Go(name, task)
+}
+
 // DelayedAdd performs addition after a delay with callback
 func DelayedAdd(a, b, delayMs int, callback func(error, interface{})) {
-	go func() {
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
+	after("delayedAdd", time.Duration(delayMs)*time.Millisecond, func() {
 		if callback != nil {
 			callback(nil, a+b)
 		}
-	}()
+	})
 }
 
 // DelayedMultiply performs multiplication after a delay with callback
 func DelayedMultiply(a, b, delayMs int, callback func(error, interface{})) {
-	go func() {
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
+	after("delayedMultiply", time.Duration(delayMs)*time.Millisecond, func() {
 		if callback != nil {
 			if a < 0 || b < 0 {
 				callback(fmt.Errorf("negative numbers not allowed"), nil)
@@ -32,13 +73,12 @@ func DelayedMultiply(a, b, delayMs int, callback func(error, interface{})) {
 				callback(nil, a*b)
 			}
 		}
-	}()
+	})
 }
 
 // FetchData simulates fetching data asynchronously
 func FetchData(id string, callback func(error, interface{})) {
-	go func() {
-		time.Sleep(50 * time.Millisecond) // Simulate network delay
+	after("fetchData", 50*time.Millisecond, func() {
 		if callback != nil {
 			data := map[string]interface{}{
 				"id":    id,
@@ -47,13 +87,12 @@ func FetchData(id string, callback func(error, interface{})) {
 			}
 			callback(nil, data)
 		}
-	}()
+	})
 }
 
 // ProcessArray processes an array and returns statistics
 func ProcessArray(numbers []int, callback func(error, interface{})) {
-	go func() {
-		time.Sleep(30 * time.Millisecond) // Simulate processing delay
+	after("processArray", 30*time.Millisecond, func() {
 		if callback != nil {
 			if len(numbers) == 0 {
 				callback(fmt.Errorf("empty array"), nil)
@@ -72,101 +111,36 @@ func ProcessArray(numbers []int, callback func(error, interface{})) {
 			}
 			callback(nil, result)
 		}
-	}()
+	})
 }
 
-// PromiseAdd simulates a promise-like operation for compatibility
+// PromiseAdd resolves with a+b after a delay
 func PromiseAdd(a, b, delayMs int) interface{} {
-	// Create a simple object that mimics a promise
-	result := make(map[string]interface{})
-	
-	// Add then method
-	result["then"] = func(onResolve func(interface{})) interface{} {
-		go func() {
-			time.Sleep(time.Duration(delayMs) * time.Millisecond)
-			if onResolve != nil {
-				onResolve(a + b)
-			}
-		}()
-		
-		// Return an object with catch method for chaining
-		catchObj := make(map[string]interface{})
-		catchObj["catch"] = func(onReject func(interface{})) interface{} {
-			// For this simple case, we don't expect errors
-			return catchObj
-		}
-		return catchObj
-	}
-	
-	// Add catch method
-	result["catch"] = func(onReject func(interface{})) interface{} {
-		// For this simple case, we don't expect errors in add operation
-		catchObj := make(map[string]interface{})
-		catchObj["then"] = result["then"]
-		catchObj["catch"] = result["catch"]
-		return catchObj
-	}
-	
-	return result
+	return newPromise(func(resolve, reject func(interface{})) {
+		after("promiseAdd", time.Duration(delayMs)*time.Millisecond, func() {
+			resolve(a + b)
+		})
+	})
 }
 
-// PromiseMultiply simulates a promise-like operation for compatibility  
+// PromiseMultiply resolves with a*b after a delay, or rejects for negative
+// numbers
 func PromiseMultiply(a, b, delayMs int) interface{} {
-	// Create a simple object that mimics a promise
-	result := make(map[string]interface{})
-	
-	// Add then method
-	result["then"] = func(onResolve func(interface{})) interface{} {
-		go func() {
-			time.Sleep(time.Duration(delayMs) * time.Millisecond)
-			if onResolve != nil {
-				// Simulate error for negative numbers
-				if a < 0 || b < 0 {
-					// This won't trigger onResolve, should trigger catch instead
-					return
-				}
-				onResolve(a * b)
-			}
-		}()
-		
-		// Return an object with catch method for chaining
-		catchObj := make(map[string]interface{})
-		catchObj["catch"] = func(onReject func(interface{})) interface{} {
-			go func() {
-				time.Sleep(time.Duration(delayMs) * time.Millisecond)
-				if a < 0 || b < 0 {
-					if onReject != nil {
-						onReject("Negative numbers not allowed")
-					}
-				}
-			}()
-			return catchObj
-		}
-		return catchObj
-	}
-	
-	// Add catch method
-	result["catch"] = func(onReject func(interface{})) interface{} {
-		go func() {
-			time.Sleep(time.Duration(delayMs) * time.Millisecond)
+	return newPromise(func(resolve, reject func(interface{})) {
+		after("promiseMultiply", time.Duration(delayMs)*time.Millisecond, func() {
 			if a < 0 || b < 0 {
-				if onReject != nil {
-					onReject("Negative numbers not allowed")
-				}
+				reject(fmt.Errorf("negative numbers not allowed"))
+				return
 			}
-		}()
-		
-		catchObj := make(map[string]interface{})
-		catchObj["then"] = result["then"]
-		catchObj["catch"] = result["catch"]
-		return catchObj
-	}
-	
-	return result
+			resolve(a * b)
+		})
+	})
 }
 
 // Plugin interface implementation
 func Initialize(rt interface{}) error {
+	runner, _ = rt.(taskRunner)
+	promises, _ = rt.(promiseFactory)
 	fmt.Println("Async plugin v2.0 initialized")
 	return nil
 }
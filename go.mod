@@ -2,13 +2,26 @@ module github.com/rizqme/gode
 
 go 1.21
 
-require github.com/rizqme/gode/goja v0.0.0
+require (
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/rizqme/gode/goja v0.0.0
+	github.com/vektah/gqlparser/v2 v2.5.16
+	golang.org/x/crypto v0.31.0
+	golang.org/x/net v0.33.0
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.2
+)
 
 replace github.com/rizqme/gode/goja => ./goja
 
 require (
+	github.com/agnivade/levenshtein v1.1.1 // indirect
 	github.com/dlclark/regexp2 v1.11.4 // indirect
 	github.com/go-sourcemap/sourcemap v2.1.3+incompatible // indirect
 	github.com/google/pprof v0.0.0-20230207041349-798e818bf904 // indirect
-	golang.org/x/text v0.15.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
 )
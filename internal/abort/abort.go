@@ -0,0 +1,120 @@
+// Package abort lets Go operations started from JS be cancelled with an
+// AbortSignal. It works with any object that behaves like one: aborted,
+// reason, and the abort event through addEventListener.
+package abort
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Signal is an AbortSignal passed to a Go operation. Its methods must be
+// called on the JS thread. A nil *Signal, for an operation given no
+// signal, never aborts.
+type Signal struct {
+	vm  *goja.Runtime
+	obj *goja.Object
+}
+
+// From returns the signal given as an option, or nil when value is
+// undefined or null. Anything else that is not an AbortSignal is an error.
+func From(vm *goja.Runtime, value goja.Value) (*Signal, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, nil
+	}
+	if obj, ok := value.(*goja.Object); ok {
+		if _, ok := goja.AssertFunction(obj.Get("addEventListener")); ok && obj.Get("aborted") != nil {
+			return &Signal{vm: vm, obj: obj}, nil
+		}
+	}
+	return nil, errors.New("signal must be an AbortSignal")
+}
+
+// Aborted reports whether the signal has aborted
+func (s *Signal) Aborted() bool {
+	return s != nil && s.obj.Get("aborted").ToBoolean()
+}
+
+// Err returns an *Error carrying the reason once the signal has aborted,
+// and nil before
+func (s *Signal) Err() error {
+	if !s.Aborted() {
+		return nil
+	}
+	reason := s.obj.Get("reason")
+	return &Error{reason: reason, message: reasonMessage(reason)}
+}
+
+// OnAbort calls fn with the reason when the signal aborts, straight away
+// if it already has. remove stops waiting for it.
+func (s *Signal) OnAbort(fn func(reason goja.Value)) (remove func()) {
+	if s == nil {
+		return func() {}
+	}
+	if s.Aborted() {
+		fn(s.obj.Get("reason"))
+		return func() {}
+	}
+
+	listener := s.vm.ToValue(func() {
+		fn(s.obj.Get("reason"))
+	})
+	add, _ := goja.AssertFunction(s.obj.Get("addEventListener"))
+	options := s.vm.NewObject()
+	options.Set("once", true)
+	if _, err := add(s.obj, s.vm.ToValue("abort"), listener, options); err != nil {
+		panic(err)
+	}
+	return func() {
+		if remove, ok := goja.AssertFunction(s.obj.Get("removeEventListener")); ok {
+			remove(s.obj, s.vm.ToValue("abort"), listener)
+		}
+	}
+}
+
+// Context returns a child of parent that is cancelled when the signal
+// aborts, for Go code that takes a context. stop releases it once the
+// operation is over, and must also be called on the JS thread.
+func (s *Signal) Context(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	remove := s.OnAbort(func(goja.Value) {
+		cancel()
+	})
+	return ctx, func() {
+		remove()
+		cancel()
+	}
+}
+
+// Error is the error of an operation stopped by its signal. A promise
+// rejected with it rejects with the signal's reason itself, as in the
+// Fetch API.
+type Error struct {
+	reason  goja.Value
+	message string
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// JSValue returns the reason the signal aborted with
+func (e *Error) JSValue() goja.Value {
+	return e.reason
+}
+
+// reasonMessage describes reason for Go, from its message when it is an
+// error
+func reasonMessage(reason goja.Value) string {
+	if obj, ok := reason.(*goja.Object); ok {
+		if message := obj.Get("message"); message != nil && !goja.IsUndefined(message) {
+			return message.String()
+		}
+	}
+	if reason == nil || goja.IsUndefined(reason) {
+		return "the operation was aborted"
+	}
+	return reason.String()
+}
@@ -0,0 +1,220 @@
+// Package admin serves an HTTP endpoint for inspecting a running gode
+// program without changing its code: health, metrics, pprof profiles,
+// loaded modules and an eval console.
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EnvToken names the environment variable holding the admin auth token
+const EnvToken = "GODE_ADMIN_TOKEN"
+
+const (
+	// pingTimeout bounds how long /health waits for the JS thread
+	pingTimeout = time.Second
+	// evalTimeout bounds how long an /eval expression may run
+	evalTimeout = 5 * time.Second
+	// maxEvalSize limits the source accepted by /eval
+	maxEvalSize = 64 << 10
+)
+
+// Target is the runtime an admin server inspects
+type Target interface {
+	// Ping reports whether the JS thread responds within timeout
+	Ping(timeout time.Duration) error
+	// Metrics returns the current metrics
+	Metrics() []Metric
+	// Modules returns the loaded modules and plugins, encodable as JSON
+	Modules() interface{}
+	// Inspect evaluates source on the JS thread and formats the result
+	Inspect(source string, timeout time.Duration) (string, error)
+}
+
+// Metric is a single value exported on /metrics. Metrics that share a name
+// are told apart by their labels.
+type Metric struct {
+	Name   string // without the gode_ prefix
+	Help   string
+	Type   string // "gauge" or "counter"
+	Labels map[string]string
+	Value  float64
+}
+
+// series returns the metric name with its labels, e.g. queue_length{lane="background"}
+func (m Metric) series() string {
+	if len(m.Labels) == 0 {
+		return m.Name
+	}
+	keys := make([]string, 0, len(m.Labels))
+	for key := range m.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", key, m.Labels[key])
+	}
+	return m.Name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Server is a running admin endpoint
+type Server struct {
+	target   Target
+	token    string
+	listener net.Listener
+	server   *http.Server
+}
+
+// Listen starts an admin server on addr. Every endpoint except /health
+// requires token, sent as "Authorization: Bearer <token>" or, for browsers,
+// a ?token= query parameter. An empty token generates a random one; see
+// Token.
+func Listen(addr, token string, target Target) (*Server, error) {
+	if token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{target: target, token: token, listener: listener}
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Token returns the token clients must present
+func (s *Server) Token() string {
+	return s.token
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// Handler returns the admin routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.health)
+	mux.Handle("/metrics", s.auth(http.HandlerFunc(s.metrics)))
+	mux.Handle("/modules", s.auth(http.HandlerFunc(s.modules)))
+	mux.Handle("/eval", s.auth(http.HandlerFunc(s.eval)))
+
+	mux.Handle("/debug/pprof/", s.auth(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", s.auth(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", s.auth(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", s.auth(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", s.auth(http.HandlerFunc(pprof.Trace)))
+	return mux
+}
+
+// auth rejects requests that don't carry the token
+func (s *Server) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := req.URL.Query().Get("token")
+		if header := req.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gode"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// health reports 200 while the JS thread responds and 503 while it is blocked
+func (s *Server) health(w http.ResponseWriter, req *http.Request) {
+	status, code := "ok", http.StatusOK
+	if err := s.target.Ping(pingTimeout); err != nil {
+		status, code = err.Error(), http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]string{"status": status})
+}
+
+// metrics writes the metrics in the Prometheus text format, or as a JSON
+// object with ?format=json
+func (s *Server) metrics(w http.ResponseWriter, req *http.Request) {
+	metrics := s.target.Metrics()
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+
+	if req.URL.Query().Get("format") == "json" {
+		values := make(map[string]float64, len(metrics))
+		for _, m := range metrics {
+			values[m.series()] = m.Value
+		}
+		writeJSON(w, http.StatusOK, values)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for i, m := range metrics {
+		if i == 0 || metrics[i-1].Name != m.Name {
+			fmt.Fprintf(w, "# HELP gode_%s %s\n", m.Name, m.Help)
+			fmt.Fprintf(w, "# TYPE gode_%s %s\n", m.Name, m.Type)
+		}
+		fmt.Fprintf(w, "gode_%s %v\n", m.series(), m.Value)
+	}
+}
+
+func (s *Server) modules(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, s.target.Modules())
+}
+
+// eval runs the POSTed source on the JS thread and returns the inspected
+// result. Errors, including timeouts, are reported with status 422.
+func (s *Server) eval(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	source, err := io.ReadAll(io.LimitReader(req.Body, maxEvalSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(source) > maxEvalSize {
+		http.Error(w, "source too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	result, err := s.target.Inspect(string(source), evalTimeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, result)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeTarget struct {
+	busy bool
+}
+
+func (f *fakeTarget) Ping(timeout time.Duration) error {
+	if f.busy {
+		return errors.New("JS thread is busy")
+	}
+	return nil
+}
+
+func (f *fakeTarget) Metrics() []Metric {
+	return []Metric{
+		{Name: "queue_length", Help: "Operations waiting", Type: "gauge", Value: 3},
+		{Name: "queue_lane_length", Help: "Operations waiting per lane", Type: "gauge", Labels: map[string]string{"lane": "default"}, Value: 2},
+		{Name: "queue_lane_length", Help: "Operations waiting per lane", Type: "gauge", Labels: map[string]string{"lane": "background"}, Value: 1},
+	}
+}
+
+func (f *fakeTarget) Modules() interface{} {
+	return []string{"./lib.js"}
+}
+
+func (f *fakeTarget) Inspect(source string, timeout time.Duration) (string, error) {
+	if source == "throw" {
+		return "", errors.New("boom")
+	}
+	return "result of " + source, nil
+}
+
+func request(t *testing.T, s *Server, method, path, token, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	data, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(data)
+}
+
+func TestServer(t *testing.T) {
+	target := &fakeTarget{}
+	s, err := Listen("127.0.0.1:0", "secret", target)
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer s.Close()
+
+	if code, body := request(t, s, "GET", "/health", "", ""); code != http.StatusOK || !strings.Contains(body, `"ok"`) {
+		t.Errorf("Unexpected health response: %d %s", code, body)
+	}
+	target.busy = true
+	if code, _ := request(t, s, "GET", "/health", "", ""); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while busy, got %d", code)
+	}
+
+	if code, _ := request(t, s, "GET", "/metrics", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", code)
+	}
+	if code, _ := request(t, s, "GET", "/metrics", "wrong", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", code)
+	}
+
+	code, body := request(t, s, "GET", "/metrics", "secret", "")
+	if code != http.StatusOK || !strings.Contains(body, "gode_queue_length 3") || !strings.Contains(body, "# TYPE gode_queue_length gauge") {
+		t.Errorf("Unexpected metrics: %d %s", code, body)
+	}
+	if strings.Count(body, "# TYPE gode_queue_lane_length") != 1 || !strings.Contains(body, `gode_queue_lane_length{lane="background"} 1`) {
+		t.Errorf("Unexpected labelled metrics: %s", body)
+	}
+	if _, body := request(t, s, "GET", "/metrics?format=json", "secret", ""); !strings.Contains(body, `"queue_length":3`) {
+		t.Errorf("Unexpected JSON metrics: %s", body)
+	}
+	if _, body := request(t, s, "GET", "/modules?token=secret", "", ""); !strings.Contains(body, "./lib.js") {
+		t.Errorf("Unexpected modules: %s", body)
+	}
+
+	if code, _ := request(t, s, "GET", "/eval", "secret", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET /eval, got %d", code)
+	}
+	if code, body := request(t, s, "POST", "/eval", "secret", "1+1"); code != http.StatusOK || body != "result of 1+1\n" {
+		t.Errorf("Unexpected eval response: %d %q", code, body)
+	}
+	if code, body := request(t, s, "POST", "/eval", "secret", "throw"); code != http.StatusUnprocessableEntity || !strings.Contains(body, "boom") {
+		t.Errorf("Unexpected eval error response: %d %q", code, body)
+	}
+}
+
+func TestListenGeneratesToken(t *testing.T) {
+	s, err := Listen("127.0.0.1:0", "", &fakeTarget{})
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer s.Close()
+
+	if len(s.Token()) != 32 {
+		t.Errorf("Expected a generated token, got %q", s.Token())
+	}
+	resp, err := http.Get("http://" + s.Addr() + "/health")
+	if err != nil {
+		t.Fatalf("GET /health failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,116 @@
+// Package asynccontext follows a context.Context through the asynchronous
+// continuations of JS code, so that values set while handling something,
+// such as the ID of an HTTP request, are still there in the promise
+// reactions, awaits and timers it leads to.
+//
+// Promise reactions are followed by the engine through goja's
+// AsyncContextTracker. Callbacks that Go schedules, such as timers, capture
+// the context with Capture when scheduled and call back with Run.
+package asynccontext
+
+import (
+	"context"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Tracker holds the context of the JS code running on a runtime. Its
+// methods must be called on the JS thread. A nil *Tracker always has the
+// background context.
+type Tracker struct {
+	current   context.Context
+	saved     []context.Context // contexts to restore, see Resumed
+	onCapture func(ctx context.Context) context.Context
+}
+
+// Install creates a Tracker for vm and registers it with the engine
+func Install(vm *goja.Runtime) *Tracker {
+	t := &Tracker{current: context.Background()}
+	vm.SetAsyncContextTracker(t)
+	return t
+}
+
+// Current returns the context of the running code
+func (t *Tracker) Current() context.Context {
+	if t == nil {
+		return context.Background()
+	}
+	return t.current
+}
+
+// Capture returns the context a callback scheduled now runs in: the
+// current one, as changed by the function set with OnCapture
+func (t *Tracker) Capture() context.Context {
+	if t == nil {
+		return context.Background()
+	}
+	if t.onCapture != nil {
+		return t.onCapture(t.current)
+	}
+	return t.current
+}
+
+// OnCapture sets fn to derive the context of callbacks and promise
+// reactions from the current one when they are scheduled. The runtime uses
+// it to note which code scheduled them.
+func (t *Tracker) OnCapture(fn func(ctx context.Context) context.Context) {
+	t.onCapture = fn
+}
+
+// Run calls fn with ctx as the current context, restoring the previous one
+// afterwards, even if fn panics. Promise reactions queued by fn keep ctx.
+func (t *Tracker) Run(ctx context.Context, fn func()) {
+	if t == nil {
+		fn()
+		return
+	}
+	t.Resumed(ctx)
+	defer t.Exited()
+	fn()
+}
+
+// Enter makes ctx the current context for the rest of the running code and
+// the continuations it queues, until the callback or reaction it runs in
+// returns
+func (t *Tracker) Enter(ctx context.Context) {
+	if t != nil {
+		t.current = ctx
+	}
+}
+
+// Bind returns fn wrapped to run in the captured context, wherever it is
+// called from
+func (t *Tracker) Bind(fn func()) func() {
+	ctx := t.Capture()
+	return func() {
+		t.Run(ctx, fn)
+	}
+}
+
+// Grab implements goja.AsyncContextTracker: the engine keeps the returned
+// context with each promise reaction
+func (t *Tracker) Grab() interface{} {
+	return t.Capture()
+}
+
+// Resumed implements goja.AsyncContextTracker: a promise reaction is about
+// to run in the context grabbed for it
+func (t *Tracker) Resumed(trackingObject interface{}) {
+	t.saved = append(t.saved, t.current)
+	if ctx, ok := trackingObject.(context.Context); ok && ctx != nil {
+		t.current = ctx
+	} else {
+		t.current = context.Background()
+	}
+}
+
+// Exited implements goja.AsyncContextTracker: the reaction has finished,
+// so the context it interrupted is restored
+func (t *Tracker) Exited() {
+	if n := len(t.saved); n > 0 {
+		t.current = t.saved[n-1]
+		t.saved = t.saved[:n-1]
+		return
+	}
+	t.current = context.Background()
+}
@@ -0,0 +1,475 @@
+// Package codec encodes plain Go values (nil, booleans, numbers, strings,
+// byte slices, slices, maps and times) as MessagePack and decodes them
+// back, for compact messages between services.
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// MaxDepth bounds how deeply arrays and maps nest, so that circular
+// values and hostile input fail instead of exhausting the stack
+const MaxDepth = 1000
+
+// ErrTruncated is returned when data ends in the middle of a value
+var ErrTruncated = errors.New("msgpack: unexpected end of data")
+
+// Map is a map that keeps the order of its entries, whose keys may be of
+// any type. Decoded maps are Maps.
+type Map []Entry
+
+// Entry is a key and value of a Map
+type Entry struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Ext is a value of an application-defined extension type, 0 to 127.
+// Timestamps, extension type -1, are time.Time instead.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+const extTimestamp = -1
+
+// EncodeMsgpack encodes v, which may be nil, a bool, an integer or float
+// of any size, a string, []byte, []interface{}, map[string]interface{}
+// (whose keys are written sorted), Map, time.Time or Ext. Integers take the
+// smallest form that holds them.
+func EncodeMsgpack(v interface{}) ([]byte, error) {
+	e := &encoder{}
+	if err := e.encode(v, 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) encode(v interface{}, depth int) error {
+	if depth > MaxDepth {
+		return fmt.Errorf("msgpack: values are nested more than %d deep", MaxDepth)
+	}
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xc0)
+	case bool:
+		if v {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case int:
+		e.int(int64(v))
+	case int8:
+		e.int(int64(v))
+	case int16:
+		e.int(int64(v))
+	case int32:
+		e.int(int64(v))
+	case int64:
+		e.int(v)
+	case uint:
+		e.uint(uint64(v))
+	case uint8:
+		e.uint(uint64(v))
+	case uint16:
+		e.uint(uint64(v))
+	case uint32:
+		e.uint(uint64(v))
+	case uint64:
+		e.uint(v)
+	case float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(v))
+	case float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v))
+	case string:
+		if err := e.header(len(v), 0xa0, 32, 0xd9, 0xda, 0xdb); err != nil {
+			return err
+		}
+		e.buf = append(e.buf, v...)
+	case []byte:
+		if err := e.header(len(v), 0, 0, 0xc4, 0xc5, 0xc6); err != nil {
+			return err
+		}
+		e.buf = append(e.buf, v...)
+	case []interface{}:
+		if err := e.header(len(v), 0x90, 16, 0, 0xdc, 0xdd); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := e.encode(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if err := e.header(len(v), 0x80, 16, 0, 0xde, 0xdf); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			e.encode(key, depth+1)
+			if err := e.encode(v[key], depth+1); err != nil {
+				return err
+			}
+		}
+	case Map:
+		if err := e.header(len(v), 0x80, 16, 0, 0xde, 0xdf); err != nil {
+			return err
+		}
+		for _, entry := range v {
+			if err := e.encode(entry.Key, depth+1); err != nil {
+				return err
+			}
+			if err := e.encode(entry.Value, depth+1); err != nil {
+				return err
+			}
+		}
+	case time.Time:
+		e.timestamp(v)
+	case Ext:
+		if v.Type < 0 {
+			return fmt.Errorf("msgpack: extension type %d is reserved", v.Type)
+		}
+		return e.ext(v.Type, v.Data)
+	case *Ext:
+		return e.encode(*v, depth)
+	default:
+		return fmt.Errorf("msgpack: cannot encode %T", v)
+	}
+	return nil
+}
+
+func (e *encoder) int(n int64) {
+	switch {
+	case n >= 0:
+		e.uint(uint64(n))
+	case n >= -32:
+		e.buf = append(e.buf, byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(n))
+	}
+}
+
+func (e *encoder) uint(n uint64) {
+	switch {
+	case n < 0x80:
+		e.buf = append(e.buf, byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, n)
+	}
+}
+
+// header writes the length n of a string, binary, array or map: in the
+// fixed form fix|n when n < fixMax, or else after the 8, 16 or 32-bit
+// code. Forms a type doesn't have are 0.
+func (e *encoder) header(n int, fix byte, fixMax int, code8, code16, code32 byte) error {
+	switch {
+	case n < fixMax:
+		e.buf = append(e.buf, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		e.buf = append(e.buf, code8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, code16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case uint64(n) <= math.MaxUint32:
+		e.buf = append(e.buf, code32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		return fmt.Errorf("msgpack: length %d is too long", n)
+	}
+	return nil
+}
+
+func (e *encoder) ext(typ int8, data []byte) error {
+	switch len(data) {
+	case 1:
+		e.buf = append(e.buf, 0xd4, byte(typ))
+	case 2:
+		e.buf = append(e.buf, 0xd5, byte(typ))
+	case 4:
+		e.buf = append(e.buf, 0xd6, byte(typ))
+	case 8:
+		e.buf = append(e.buf, 0xd7, byte(typ))
+	case 16:
+		e.buf = append(e.buf, 0xd8, byte(typ))
+	default:
+		if err := e.header(len(data), 0, 0, 0xc7, 0xc8, 0xc9); err != nil {
+			return err
+		}
+		e.buf = append(e.buf, byte(typ))
+	}
+	e.buf = append(e.buf, data...)
+	return nil
+}
+
+// timestamp writes t in the smallest of the 32, 64 and 96-bit forms
+func (e *encoder) timestamp(t time.Time) {
+	sec, nsec := t.Unix(), uint32(t.Nanosecond())
+	var data []byte
+	switch {
+	case sec>>34 != 0:
+		data = binary.BigEndian.AppendUint32(data, nsec)
+		data = binary.BigEndian.AppendUint64(data, uint64(sec))
+	case nsec == 0 && sec <= math.MaxUint32:
+		data = binary.BigEndian.AppendUint32(data, uint32(sec))
+	default:
+		data = binary.BigEndian.AppendUint64(data, uint64(nsec)<<34|uint64(sec))
+	}
+	e.ext(extTimestamp, data)
+}
+
+// DecodeMsgpack decodes a single value that makes up all of data.
+// Integers are int64, or uint64 when they are larger than the largest
+// int64, floats float64, strings string, binaries []byte, arrays
+// []interface{}, maps Map, timestamps time.Time and other extensions Ext.
+func DecodeMsgpack(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, fmt.Errorf("msgpack: %d bytes follow the value", len(data)-d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, ErrTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// length reads a length of size bytes
+func (d *decoder) length(size int) (int, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(b[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(b)), nil
+	}
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("msgpack: values are nested more than %d deep", MaxDepth)
+	}
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code <= 0x8f:
+		return d.mapOf(int(code&0x0f), depth)
+	case code <= 0x9f:
+		return d.array(int(code&0x0f), depth)
+	case code <= 0xbf:
+		return d.str(int(code & 0x1f))
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), data...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(1 << (code - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.extension(n)
+	case 0xca:
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := d.read(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		n := uintOf(b)
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		b, err := d.read(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the size of the integer
+		shift := 64 - 8*size
+		return int64(uintOf(b)<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.extension(1 << (code - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xdc, 0xdd:
+		n, err := d.length(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.length(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapOf(n, depth)
+	}
+	return nil, fmt.Errorf("msgpack: invalid code 0x%x at offset %d", code, d.pos-1)
+}
+
+func uintOf(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+func (d *decoder) str(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *decoder) array(n int, depth int) (interface{}, error) {
+	// Every item takes at least a byte, which bounds what hostile lengths
+	// can make us allocate
+	if n > len(d.data)-d.pos {
+		return nil, ErrTruncated
+	}
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (d *decoder) mapOf(n int, depth int) (interface{}, error) {
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, ErrTruncated
+	}
+	m := make(Map, n)
+	for i := range m {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[i] = Entry{Key: key, Value: value}
+	}
+	return m, nil
+}
+
+// extension reads the type and n bytes of data of an extension
+func (d *decoder) extension(n int) (interface{}, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	typ := int8(b[0])
+	data, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if typ != extTimestamp {
+		return Ext{Type: typ, Data: append([]byte(nil), data...)}, nil
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))), nil
+	}
+	return nil, fmt.Errorf("msgpack: a timestamp can't be %d bytes", n)
+}
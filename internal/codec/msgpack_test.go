@@ -0,0 +1,147 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeMsgpack(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, "c0"},
+		{true, "c3"},
+		{false, "c2"},
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{256, "cd0100"},
+		{70000, "ce00011170"},
+		{int64(1) << 40, "cf0000010000000000"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-200, "d1ff38"},
+		{-70000, "d2fffeee90"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{float32(1.5), "ca3fc00000"},
+		{1.5, "cb3ff8000000000000"},
+		{"", "a0"},
+		{"abc", "a3616263"},
+		{strings.Repeat("x", 32), "d920" + strings.Repeat("78", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]interface{}{1, "a"}, "9201a161"},
+		{map[string]interface{}{"b": 2, "a": 1}, "82a16101a16202"},
+		{Map{{Key: "b", Value: 2}, {Key: 1, Value: nil}}, "82a1620201c0"},
+		{time.Unix(1, 0), "d6ff00000001"},
+		{time.Unix(1, 5), "d7ff0000001400000001"},
+		{time.Unix(-1, 0), "c70cff00000000ffffffffffffffff"},
+		{Ext{Type: 5, Data: []byte{9}}, "d40509"},
+		{Ext{Type: 5, Data: []byte{1, 2, 3}}, "c703050102 03"},
+	}
+	for _, tt := range tests {
+		got, err := EncodeMsgpack(tt.value)
+		if err != nil {
+			t.Errorf("EncodeMsgpack(%#v) failed: %v", tt.value, err)
+			continue
+		}
+		want := strings.ReplaceAll(tt.want, " ", "")
+		if hex.EncodeToString(got) != want {
+			t.Errorf("EncodeMsgpack(%#v) = %x, want %s", tt.value, got, want)
+		}
+	}
+
+	long := make([]interface{}, 16)
+	if got, _ := EncodeMsgpack(long); !bytes.HasPrefix(got, []byte{0xdc, 0, 16}) {
+		t.Errorf("array of 16 starts with %x", got[:3])
+	}
+	if _, err := EncodeMsgpack(struct{}{}); err == nil {
+		t.Error("expected an error for a struct")
+	}
+	if _, err := EncodeMsgpack(Ext{Type: -2}); err == nil {
+		t.Error("expected an error for a reserved extension type")
+	}
+	var nested interface{}
+	for i := 0; i <= MaxDepth+1; i++ {
+		nested = []interface{}{nested}
+	}
+	if _, err := EncodeMsgpack(nested); err == nil {
+		t.Error("expected an error for values nested too deeply")
+	}
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	at := time.Date(2030, 1, 2, 3, 4, 5, 678, time.UTC)
+	far := time.Date(2700, 1, 1, 0, 0, 0, 0, time.UTC)
+	value := Map{
+		{Key: "nil", Value: nil},
+		{Key: "flags", Value: []interface{}{true, false}},
+		{Key: "ints", Value: []interface{}{int64(0), int64(-1), int64(-33), int64(300), int64(-70000), int64(math.MaxInt64), uint64(math.MaxUint64)}},
+		{Key: "floats", Value: []interface{}{1.5, math.Inf(-1)}},
+		{Key: "text", Value: strings.Repeat("é", 200)},
+		{Key: "data", Value: bytes.Repeat([]byte{7}, 300)},
+		{Key: int64(42), Value: Map{}},
+		{Key: "times", Value: []interface{}{time.Unix(7, 0), at, far}},
+		{Key: "ext", Value: Ext{Type: 3, Data: []byte("hello")}},
+	}
+	data, err := EncodeMsgpack(value)
+	if err != nil {
+		t.Fatalf("EncodeMsgpack() failed: %v", err)
+	}
+	got, err := DecodeMsgpack(data)
+	if err != nil {
+		t.Fatalf("DecodeMsgpack() failed: %v", err)
+	}
+	// Times decode in the local zone
+	times := got.(Map)[7].Value.([]interface{})
+	for i, v := range times {
+		times[i] = v.(time.Time).UTC()
+	}
+	value[7].Value = []interface{}{time.Unix(7, 0).UTC(), at, far}
+	if !reflect.DeepEqual(got, value) {
+		t.Errorf("DecodeMsgpack() = %#v\nwant %#v", got, value)
+	}
+
+	if v, err := DecodeMsgpack([]byte{0xca, 0x3f, 0xc0, 0, 0}); err != nil || v != 1.5 {
+		t.Errorf("DecodeMsgpack(float32) = %v, %v", v, err)
+	}
+	if v, err := DecodeMsgpack([]byte{0xd1, 0xff, 0x38}); err != nil || v != int64(-200) {
+		t.Errorf("DecodeMsgpack(int16) = %v, %v", v, err)
+	}
+}
+
+func TestDecodeMsgpackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated string", []byte{0xa3, 'a'}},
+		{"truncated array", []byte{0x92, 0x01}},
+		{"hostile length", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}},
+		{"hostile map", []byte{0xdf, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"trailing bytes", []byte{0x01, 0x02}},
+		{"reserved code", []byte{0xc1}},
+		{"bad timestamp", []byte{0xd5, 0xff, 0, 0}},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeMsgpack(tt.data); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+	if _, err := DecodeMsgpack([]byte{0x91}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("DecodeMsgpack() of a truncated array = %v, want ErrTruncated", err)
+	}
+	deep := append(bytes.Repeat([]byte{0x91}, MaxDepth+2), 0xc0)
+	if _, err := DecodeMsgpack(deep); err == nil || errors.Is(err, ErrTruncated) {
+		t.Errorf("DecodeMsgpack() of values nested too deeply = %v", err)
+	}
+}
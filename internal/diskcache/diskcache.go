@@ -0,0 +1,234 @@
+// Package diskcache manages the caches gode keeps on disk: compiled
+// scripts, remote modules and npm packages. Each kind has a directory of its
+// own under the cache directory, holding one file or directory per entry
+// and, next to it, <entry>.meta.json with where the entry came from and its
+// integrity hash.
+package diskcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/internal/download"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// The kinds of cache entry
+const (
+	Compiled = "compiled" // Scripts compiled ahead of running them
+	Remote   = "remote"   // Modules loaded from http(s) URLs
+	NPM      = "npm"      // npm package tarballs and their contents
+)
+
+// Kinds lists every kind of cache entry
+var Kinds = []string{Compiled, Remote, NPM}
+
+const metaSuffix = ".meta.json"
+
+// Dir returns the cache directory of a project: gode.cache-dir from
+// package.json or the user-level config, taken from the project root when
+// relative, or cache/ in the user-level gode directory
+func Dir(cfg *config.PackageJSON) (string, error) {
+	if cfg != nil && cfg.Gode.CacheDir != "" {
+		if filepath.IsAbs(cfg.Gode.CacheDir) {
+			return cfg.Gode.CacheDir, nil
+		}
+		return filepath.Join(cfg.ProjectRoot, cfg.Gode.CacheDir), nil
+	}
+	dir, err := config.GlobalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache"), nil
+}
+
+// Meta is what the cache records about an entry
+type Meta struct {
+	Origins   []string  `json:"origins,omitempty"`   // URLs or paths the entry was fetched or compiled from
+	Integrity string    `json:"integrity,omitempty"` // SRI hash of the entry, for files
+	Created   time.Time `json:"created"`
+}
+
+// Entry is a cached file or directory
+type Entry struct {
+	Kind string
+	Name string
+	Path string
+	Size int64     // Total size of its files
+	Used time.Time // When it was last stored or touched
+	Meta Meta
+}
+
+// Cache is a cache directory
+type Cache struct {
+	dir string
+}
+
+// New returns the cache in dir, which need not exist yet
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path returns where the entry name of kind is kept
+func (c *Cache) Path(kind, name string) string {
+	return filepath.Join(c.dir, kind, name)
+}
+
+// Store records meta for the entry written to Path(kind, name). Created
+// defaults to now.
+func (c *Cache) Store(kind, name string, meta Meta) error {
+	if meta.Created.IsZero() {
+		meta.Created = time.Now()
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path(kind, name)+metaSuffix, data, 0644)
+}
+
+// Touch marks an entry as used now, so that prune keeps it
+func (c *Cache) Touch(kind, name string) error {
+	now := time.Now()
+	return os.Chtimes(c.Path(kind, name), now, now)
+}
+
+// List returns the entries of every kind, by kind and name
+func (c *Cache) List() ([]Entry, error) {
+	var entries []Entry
+	for _, kind := range Kinds {
+		files, err := os.ReadDir(filepath.Join(c.dir, kind))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file.Name(), metaSuffix) {
+				continue
+			}
+			entry, err := c.entry(kind, file.Name())
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+func (c *Cache) entry(kind, name string) (Entry, error) {
+	entry := Entry{Kind: kind, Name: name, Path: c.Path(kind, name)}
+	info, err := os.Stat(entry.Path)
+	if err != nil {
+		return entry, err
+	}
+	entry.Used = info.ModTime()
+	entry.Size = info.Size()
+	if info.IsDir() {
+		entry.Size = 0
+		err := filepath.WalkDir(entry.Path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if info, err := d.Info(); err == nil {
+				entry.Size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return entry, err
+		}
+	}
+
+	// Entries written without metadata are still listed
+	if data, err := os.ReadFile(entry.Path + metaSuffix); err == nil {
+		if err := json.Unmarshal(data, &entry.Meta); err != nil {
+			return entry, fmt.Errorf("invalid %s: %w", entry.Path+metaSuffix, err)
+		}
+	}
+	return entry, nil
+}
+
+// Prune removes the entries last used before cutoff and returns them
+func (c *Cache) Prune(cutoff time.Time) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Entry
+	for _, entry := range entries {
+		if !entry.Used.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			return removed, err
+		}
+		os.Remove(entry.Path + metaSuffix)
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// Problem is an entry that failed verification
+type Problem struct {
+	Entry Entry
+	Err   error
+}
+
+// Verify re-hashes the entries that have an integrity hash. It returns how
+// many were checked and those that no longer match.
+func (c *Cache) Verify() (checked int, problems []Problem, err error) {
+	entries, err := c.List()
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, entry := range entries {
+		if entry.Meta.Integrity == "" {
+			continue
+		}
+		checked++
+		if err := download.VerifyFile(entry.Path, entry.Meta.Integrity); err != nil {
+			problems = append(problems, Problem{Entry: entry, Err: err})
+		}
+	}
+	return checked, problems, nil
+}
+
+// ParseAge parses an age such as 30d, 2w or 12h. Days and weeks are added
+// to the units of time.ParseDuration.
+func ParseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,140 @@
+package diskcache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/internal/download"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func sri(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func store(t *testing.T, c *Cache, kind, name, data string, meta Meta) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(c.Dir(), kind), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.Path(kind, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Store(kind, name, meta); err != nil {
+		t.Fatalf("Store() failed: %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	c := New(t.TempDir())
+	store(t, c, Remote, "b.js", "export default 1;", Meta{Origins: []string{"https://example.com/b.js"}})
+	store(t, c, Compiled, "a.bin", "compiled", Meta{})
+
+	// A package directory without metadata
+	pkg := c.Path(NPM, "left-pad@1.3.0")
+	os.MkdirAll(filepath.Join(pkg, "lib"), 0755)
+	os.WriteFile(filepath.Join(pkg, "package.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(pkg, "lib", "index.js"), []byte("module.exports = 1;"), 0644)
+
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Kind != Compiled || entries[1].Kind != NPM || entries[2].Kind != Remote {
+		t.Errorf("Unexpected order: %v, %v, %v", entries[0].Kind, entries[1].Kind, entries[2].Kind)
+	}
+	if entries[1].Size != 21 {
+		t.Errorf("Expected the package to total 21 bytes, got %d", entries[1].Size)
+	}
+	if got := entries[2].Meta.Origins; len(got) != 1 || got[0] != "https://example.com/b.js" {
+		t.Errorf("Unexpected origins %q", got)
+	}
+	if entries[2].Meta.Created.IsZero() {
+		t.Error("Expected Store to set Created")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := New(t.TempDir())
+	store(t, c, Remote, "old.js", "old", Meta{})
+	store(t, c, Remote, "new.js", "new", Meta{})
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	os.Chtimes(c.Path(Remote, "old.js"), old, old)
+
+	removed, err := c.Prune(time.Now().Add(-30 * 24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Name != "old.js" {
+		t.Fatalf("Expected old.js to be removed, got %v", removed)
+	}
+	if _, err := os.Stat(c.Path(Remote, "old.js") + metaSuffix); !os.IsNotExist(err) {
+		t.Error("Expected the metadata of old.js to be removed")
+	}
+	entries, _ := c.List()
+	if len(entries) != 1 || entries[0].Name != "new.js" {
+		t.Errorf("Expected new.js to remain, got %v", entries)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	c := New(t.TempDir())
+	store(t, c, Remote, "good.js", "good", Meta{Integrity: sri("good")})
+	store(t, c, Remote, "bad.js", "tampered", Meta{Integrity: sri("bad")})
+	store(t, c, Compiled, "unhashed.bin", "data", Meta{})
+
+	checked, problems, err := c.Verify()
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if checked != 2 {
+		t.Errorf("Expected 2 entries checked, got %d", checked)
+	}
+	if len(problems) != 1 || problems[0].Entry.Name != "bad.js" {
+		t.Fatalf("Expected bad.js to fail, got %v", problems)
+	}
+	var integrityErr *download.IntegrityError
+	if !errors.As(problems[0].Err, &integrityErr) || integrityErr.Got != sri("tampered") {
+		t.Errorf("Unexpected error %v", problems[0].Err)
+	}
+}
+
+func TestDir(t *testing.T) {
+	cfg := &config.PackageJSON{ProjectRoot: "/project"}
+	cfg.Gode.CacheDir = ".cache/gode"
+	if dir, _ := Dir(cfg); dir != filepath.Join("/project", ".cache/gode") {
+		t.Errorf("Dir() = %q", dir)
+	}
+	cfg.Gode.CacheDir = "/var/cache/gode"
+	if dir, _ := Dir(cfg); dir != "/var/cache/gode" {
+		t.Errorf("Dir() = %q", dir)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	}
+	for s, want := range tests {
+		if got, err := ParseAge(s); err != nil || got != want {
+			t.Errorf("ParseAge(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "d", "-1d", "soon"} {
+		if _, err := ParseAge(s); err == nil {
+			t.Errorf("ParseAge(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package docgen builds API documentation for the modules a gode runtime
+// exposes: Go plugins, described from their exports and the doc comments
+// recorded when they were built, and gode: built-ins.
+package docgen
+
+import (
+	"fmt"
+	"reflect"
+	goruntime "runtime"
+	"strings"
+)
+
+// Module is the documentation of one module
+type Module struct {
+	Name    string
+	Version string // empty for built-ins
+	Kind    string // "builtin" or "plugin"
+	Members []Member
+}
+
+// Member is an exported function, class or value of a module
+type Member struct {
+	Name      string
+	Kind      string // "function", "class" or "value"
+	Signature string // e.g. "add(a int, b int) int" or "add(arg0, arg1)"
+	Doc       string
+}
+
+// Plugin documents a plugin from its exports. docs and params hold the doc
+// comments and parameter names of its Go functions, keyed by Go name, as
+// produced by Extract; either may be nil.
+func Plugin(name, version string, exports map[string]interface{}, docs map[string]string, params map[string][]string) Module {
+	mod := Module{Name: name, Version: version, Kind: "plugin"}
+	for _, key := range sortedKeys(exports) {
+		value := exports[key]
+		member := Member{Name: key, Kind: "value"}
+
+		v := reflect.ValueOf(value)
+		if v.Kind() == reflect.Func {
+			goName := funcName(v)
+			member.Kind = "function"
+			member.Signature = key + goSignature(v.Type(), params[goName])
+			member.Doc = docs[goName]
+		} else if value != nil {
+			member.Signature = key + " " + reflect.TypeOf(value).String()
+		}
+		mod.Members = append(mod.Members, member)
+	}
+	return mod
+}
+
+// funcName returns the unqualified name of a top-level Go function, which
+// is how Extract keys docs; closures and methods yield names that match
+// nothing
+func funcName(v reflect.Value) string {
+	fn := goruntime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := fn.Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// goSignature formats a function type like a Go declaration, using names
+// for the parameters when they are known
+func goSignature(t reflect.Type, names []string) string {
+	params := make([]string, t.NumIn())
+	for i := range params {
+		typ := t.In(i).String()
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			typ = "..." + t.In(i).Elem().String()
+		}
+		if len(names) == t.NumIn() && names[i] != "" && names[i] != "_" {
+			typ = names[i] + " " + typ
+		}
+		params[i] = typ
+	}
+
+	results := make([]string, t.NumOut())
+	for i := range results {
+		results[i] = t.Out(i).String()
+	}
+
+	sig := "(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		sig += " " + results[0]
+	default:
+		sig += " (" + strings.Join(results, ", ") + ")"
+	}
+	return sig
+}
+
+// Title returns the heading for a module
+func (m Module) Title() string {
+	if m.Version != "" {
+		return fmt.Sprintf("%s v%s", m.Name, m.Version)
+	}
+	return m.Name
+}
@@ -0,0 +1,98 @@
+package docgen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `package main
+
+// Add returns the sum of a and b
+func Add(a, b int) int { return a + b }
+
+func Concat(parts ...string) string { return "" }
+
+// helper is unexported
+func helper() {}
+`
+
+func Add(a, b int) int { return a + b }
+
+func TestExtract(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, params, err := Extract(file)
+	if err != nil {
+		t.Fatalf("Extract() failed: %v", err)
+	}
+	if docs["Add"] != "Add returns the sum of a and b" {
+		t.Errorf("Unexpected doc for Add: %q", docs["Add"])
+	}
+	if _, ok := docs["Concat"]; ok {
+		t.Error("Expected no doc for Concat")
+	}
+	if got := strings.Join(params["Add"], ","); got != "a,b" {
+		t.Errorf("Unexpected params for Add: %s", got)
+	}
+	if _, ok := params["helper"]; ok {
+		t.Error("Expected unexported functions to be skipped")
+	}
+
+	var b bytes.Buffer
+	if err := WriteGo(&b, "main", docs, params); err != nil {
+		t.Fatalf("WriteGo() failed: %v", err)
+	}
+	for _, want := range []string{
+		"package main",
+		`"Add": "Add returns the sum of a and b",`,
+		`"Concat": {"parts"},`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected generated file to contain %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestPluginMarkdown(t *testing.T) {
+	mod := Plugin("math", "1.0.0", map[string]interface{}{
+		"add": Add,
+		"pi":  3.14,
+	}, map[string]string{"Add": "Add returns the sum of a and b"}, map[string][]string{"Add": {"a", "b"}})
+
+	if len(mod.Members) != 2 {
+		t.Fatalf("Expected 2 members, got %d", len(mod.Members))
+	}
+	if got := mod.Members[0].Signature; got != "add(a int, b int) int" {
+		t.Errorf("Unexpected signature: %s", got)
+	}
+
+	var b bytes.Buffer
+	if err := Markdown(&b, []Module{mod}); err != nil {
+		t.Fatalf("Markdown() failed: %v", err)
+	}
+	for _, want := range []string{
+		"## math v1.0.0",
+		"### `add`",
+		"Add returns the sum of a and b",
+		"pi float64",
+		"_value_",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected markdown to contain %q:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := HTML(&b, []Module{mod}); err != nil {
+		t.Fatalf("HTML() failed: %v", err)
+	}
+	if !strings.Contains(b.String(), `<h2 id="math">math v1.0.0</h2>`) {
+		t.Errorf("Unexpected HTML:\n%s", b.String())
+	}
+}
@@ -0,0 +1,86 @@
+package docgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Extract reads the doc comments and parameter names of the exported
+// top-level functions in Go source files, keyed by function name. Plugins
+// cannot recover either by reflection, so gode doc -extract records them at
+// build time in a generated file (see WriteGo).
+func Extract(files ...string) (docs map[string]string, params map[string][]string, err error) {
+	docs = make(map[string]string)
+	params = make(map[string][]string)
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+				continue
+			}
+			if text := strings.TrimSpace(fn.Doc.Text()); text != "" {
+				docs[fn.Name.Name] = text
+			}
+			var names []string
+			for _, field := range fn.Type.Params.List {
+				if len(field.Names) == 0 {
+					names = append(names, "")
+				}
+				for _, name := range field.Names {
+					names = append(names, name.Name)
+				}
+			}
+			params[fn.Name.Name] = names
+		}
+	}
+	return docs, params, nil
+}
+
+// WriteGo writes a Go file for package pkg that exposes docs and params as
+// the optional Docs and ParamNames plugin functions
+func WriteGo(w io.Writer, pkg string, docs map[string]string, params map[string][]string) error {
+	var b strings.Builder
+	b.WriteString("// Code generated by gode doc -extract. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+
+	b.WriteString("// Docs returns the doc comments of the exported functions\n")
+	b.WriteString("func Docs() map[string]string {\n\treturn map[string]string{\n")
+	for _, name := range sortedKeys(docs) {
+		fmt.Fprintf(&b, "\t\t%q: %q,\n", name, docs[name])
+	}
+	b.WriteString("\t}\n}\n\n")
+
+	b.WriteString("// ParamNames returns the parameter names of the exported functions\n")
+	b.WriteString("func ParamNames() map[string][]string {\n\treturn map[string][]string{\n")
+	for _, name := range sortedKeys(params) {
+		quoted := make([]string, len(params[name]))
+		for i, p := range params[name] {
+			quoted[i] = fmt.Sprintf("%q", p)
+		}
+		fmt.Fprintf(&b, "\t\t%q: {%s},\n", name, strings.Join(quoted, ", "))
+	}
+	b.WriteString("\t}\n}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package docgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Builtin documents a gode: module from its JavaScript exports. Built-ins
+// have no recorded docs, so members list their kind and arity.
+func Builtin(name string, exports goja.Value) Module {
+	mod := Module{Name: name, Kind: "builtin"}
+	obj, ok := exports.(*goja.Object)
+	if !ok {
+		return mod
+	}
+
+	keys := obj.Keys()
+	for _, key := range sortedKeys(keySet(keys)) {
+		value := obj.Get(key)
+		member := Member{Name: key, Kind: "value"}
+		if fn, ok := value.(*goja.Object); ok {
+			if _, isFunc := goja.AssertFunction(fn); isFunc {
+				member.Kind = "function"
+				if key != "" && key[0] >= 'A' && key[0] <= 'Z' {
+					member.Kind = "class"
+				}
+				member.Signature = key + "(" + params(int(fn.Get("length").ToInteger())) + ")"
+			}
+		}
+		mod.Members = append(mod.Members, member)
+	}
+	return mod
+}
+
+func keySet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+func params(n int) string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("arg%d", i)
+	}
+	return strings.Join(names, ", ")
+}
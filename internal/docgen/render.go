@@ -0,0 +1,69 @@
+package docgen
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Markdown writes modules as a Markdown document
+func Markdown(w io.Writer, modules []Module) error {
+	var b strings.Builder
+	b.WriteString("# API Reference\n")
+	for _, mod := range modules {
+		fmt.Fprintf(&b, "\n## %s\n\n", mod.Title())
+		if len(mod.Members) == 0 {
+			b.WriteString("_No exports._\n")
+			continue
+		}
+		for _, m := range mod.Members {
+			fmt.Fprintf(&b, "### `%s`\n\n", m.Name)
+			if m.Signature != "" {
+				fmt.Fprintf(&b, "```go\n%s\n```\n\n", m.Signature)
+			}
+			if m.Kind != "function" {
+				fmt.Fprintf(&b, "_%s_\n\n", m.Kind)
+			}
+			if m.Doc != "" {
+				b.WriteString(m.Doc + "\n\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var htmlTemplate = template.Must(template.New("doc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API Reference</title>
+<style>
+body { font-family: sans-serif; max-width: 56em; margin: 2em auto; padding: 0 1em; }
+pre { background: #f4f4f4; padding: .5em; }
+.kind { color: #777; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>API Reference</h1>
+<ul>{{range .}}<li><a href="#{{.Name}}">{{.Title}}</a></li>{{end}}</ul>
+{{range .}}
+<h2 id="{{.Name}}">{{.Title}}</h2>
+{{range .Members}}
+<h3>{{.Name}}</h3>
+{{if .Signature}}<pre>{{.Signature}}</pre>{{end}}
+{{if ne .Kind "function"}}<p class="kind">{{.Kind}}</p>{{end}}
+{{if .Doc}}<p>{{.Doc}}</p>{{end}}
+{{else}}
+<p><em>No exports.</em></p>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// HTML writes modules as a standalone HTML page
+func HTML(w io.Writer, modules []Module) error {
+	return htmlTemplate.Execute(w, modules)
+}
@@ -0,0 +1,259 @@
+// Package download fetches files in parallel for the module loader. Each
+// file is written to <dest>.part, hashed as its chunks arrive, and renamed
+// to dest only once it matches its integrity. A partial file left by an
+// interrupted download is resumed with a Range request, both when the
+// connection drops and on the next run.
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rizqme/gode/internal/proxy"
+)
+
+const (
+	// DefaultConcurrency is the number of files fetched at once
+	DefaultConcurrency = 8
+	// attempts is how many times a download is tried, resuming each time
+	attempts = 3
+)
+
+// Item is a file to download
+type Item struct {
+	URL       string
+	Dest      string
+	Integrity string // Optional SRI string, e.g. "sha512-<base64>"
+}
+
+// Options configures a Downloader
+type Options struct {
+	Concurrency int          // Defaults to DefaultConcurrency
+	Client      *http.Client // Defaults to a client that honours the proxy
+	Progress    Progress     // Optional
+}
+
+// Downloader fetches files with a limit on how many run at once
+type Downloader struct {
+	client      *http.Client
+	concurrency int
+	progress    Progress
+}
+
+// New creates a Downloader
+func New(opts Options) *Downloader {
+	d := &Downloader{
+		client:      opts.Client,
+		concurrency: opts.Concurrency,
+		progress:    opts.Progress,
+	}
+	if d.client == nil {
+		d.client = proxy.Client(0)
+	}
+	if d.concurrency <= 0 {
+		d.concurrency = DefaultConcurrency
+	}
+	if d.progress == nil {
+		d.progress = nopProgress{}
+	}
+	return d
+}
+
+// Download fetches every item, at most Concurrency at a time, and returns
+// the errors of those that failed, joined. Items already at their dest are
+// skipped.
+func (d *Downloader) Download(ctx context.Context, items []Item) error {
+	errs := make([]error, len(items))
+	slots := make(chan struct{}, d.concurrency)
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		if _, err := os.Stat(item.Dest); err == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, item Item) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+
+			err := d.fetch(ctx, item)
+			d.progress.Finish(item.URL, err)
+			errs[i] = err
+		}(i, item)
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// fetch downloads one item, resuming after dropped connections
+func (d *Downloader) fetch(ctx context.Context, item Item) error {
+	if err := os.MkdirAll(filepath.Dir(item.Dest), 0755); err != nil {
+		return err
+	}
+	part := item.Dest + ".part"
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		var retry bool
+		retry, err = d.fetchPart(ctx, item, part)
+		if err == nil || !retry || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("download %s: %w", item.URL, err)
+	}
+	return nil
+}
+
+// fetchPart continues the download into part and, once it is complete and
+// verified, moves it to dest. retry reports whether another attempt may
+// get further.
+func (d *Downloader) fetchPart(ctx context.Context, item Item, part string) (retry bool, err error) {
+	var check *integrity
+	if item.Integrity != "" {
+		if check, err = parseIntegrity(item.Integrity); err != nil {
+			return false, err
+		}
+	}
+
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// Hash what an earlier attempt already wrote
+	offset, err := io.Copy(hashWriter(check), f)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && rangeStart(resp) == offset:
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The part file already holds the whole file
+		return false, d.complete(item, part, f, check)
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the Range header; start over
+		if offset > 0 {
+			if check != nil {
+				check.hash.Reset()
+			}
+			if err := f.Truncate(0); err != nil {
+				return false, err
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return false, err
+			}
+			offset = 0
+		}
+	default:
+		return resp.StatusCode >= 500, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	size := int64(-1)
+	if resp.ContentLength >= 0 {
+		size = offset + resp.ContentLength
+	}
+	d.progress.Start(item.URL, size)
+	if offset > 0 {
+		d.progress.Advance(item.URL, offset)
+	}
+
+	body := io.TeeReader(resp.Body, hashWriter(check))
+	if _, err := io.Copy(f, &progressReader{r: body, url: item.URL, progress: d.progress}); err != nil {
+		return true, err
+	}
+	return false, d.complete(item, part, f, check)
+}
+
+// complete verifies the part file and moves it into place. A part file
+// that fails verification is removed, so the next attempt starts over.
+func (d *Downloader) complete(item Item, part string, f *os.File, check *integrity) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if check != nil {
+		if err := check.verify(); err != nil {
+			os.Remove(part)
+			var integrityErr *IntegrityError
+			if errors.As(err, &integrityErr) {
+				integrityErr.URL = item.URL
+			}
+			return err
+		}
+	}
+	return os.Rename(part, item.Dest)
+}
+
+// hashWriter returns the writer that feeds check, or io.Discard
+func hashWriter(check *integrity) io.Writer {
+	if check == nil {
+		return io.Discard
+	}
+	return check.hash
+}
+
+// rangeStart returns the first byte of a 206 response's Content-Range
+func rangeStart(resp *http.Response) int64 {
+	// Content-Range: bytes 100-199/200
+	value := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	start, _, _ := strings.Cut(value, "-")
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// progressReader reports each chunk read to progress
+type progressReader struct {
+	r        io.Reader
+	url      string
+	progress Progress
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	if n > 0 {
+		p.progress.Advance(p.url, int64(n))
+	}
+	return n, err
+}
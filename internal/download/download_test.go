@@ -0,0 +1,163 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sri(data []byte) string {
+	sum := sha512.Sum512(data)
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// files serves content for /<name> with Range support
+func files(content map[string][]byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, ok := content[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(data))
+	}
+}
+
+func TestDownloadParallel(t *testing.T) {
+	content := make(map[string][]byte)
+	for i := 0; i < 6; i++ {
+		content[fmt.Sprintf("pkg%d.js", i)] = bytes.Repeat([]byte{byte('a' + i)}, 10000)
+	}
+
+	var running, peak int32
+	serve := files(content)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		serve(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var items []Item
+	for name, data := range content {
+		items = append(items, Item{URL: server.URL + "/" + name, Dest: filepath.Join(dir, name), Integrity: sri(data)})
+	}
+
+	var log bytes.Buffer
+	d := New(Options{Concurrency: 2, Progress: NewProgress(&log)})
+	if err := d.Download(context.Background(), items); err != nil {
+		t.Fatalf("Download() failed: %v", err)
+	}
+	if peak > 2 {
+		t.Errorf("%d downloads ran at once, want at most 2", peak)
+	}
+	for name, data := range content {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s not downloaded correctly: %v", name, err)
+		}
+	}
+	if strings.Count(log.String(), "fetched ") != 6 || !strings.Contains(log.String(), "(10.0 kB)") {
+		t.Errorf("Unexpected progress log:\n%s", log.String())
+	}
+}
+
+func TestDownloadIntegrityMismatch(t *testing.T) {
+	server := httptest.NewServer(files(map[string][]byte{"lib.js": []byte("tampered")}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "lib.js")
+	err := New(Options{}).Download(context.Background(), []Item{
+		{URL: server.URL + "/lib.js", Dest: dest, Integrity: sri([]byte("original"))},
+	})
+
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) || integrityErr.URL != server.URL+"/lib.js" {
+		t.Fatalf("Expected an IntegrityError, got %v", err)
+	}
+	if integrityErr.Got != sri([]byte("tampered")) {
+		t.Errorf("IntegrityError.Got = %s", integrityErr.Got)
+	}
+	for _, path := range []string{dest, dest + ".part"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+}
+
+func TestDownloadResumes(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 5000)
+
+	var mu sync.Mutex
+	var ranges []string
+	var dropped bool
+	serve := files(map[string][]byte{"big.js": data})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		drop := !dropped && r.Header.Get("Range") == "bytes=1000-"
+		dropped = dropped || drop
+		mu.Unlock()
+
+		if drop {
+			// Send part of the file, then lose the connection
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)-1000))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 1000-%d/%d", len(data)-1, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[1000:20000])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		serve(w, r)
+	}))
+	defer server.Close()
+
+	// An earlier run stopped after 1000 bytes
+	dest := filepath.Join(t.TempDir(), "big.js")
+	os.WriteFile(dest+".part", data[:1000], 0644)
+
+	err := New(Options{}).Download(context.Background(), []Item{
+		{URL: server.URL + "/big.js", Dest: dest, Integrity: sri(data)},
+	})
+	if err != nil {
+		t.Fatalf("Download() failed: %v", err)
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, data) {
+		t.Error("Resumed download does not match")
+	}
+	want := []string{"bytes=1000-", "bytes=20000-"}
+	if strings.Join(ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("Range headers = %q, want %q", ranges, want)
+	}
+}
+
+func TestParseIntegrity(t *testing.T) {
+	check, err := parseIntegrity("sha256-" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + " " + sri([]byte("x")))
+	if err != nil || check.algorithm != "sha512" || len(check.digests) != 1 {
+		t.Errorf("Expected the strongest hash to be used, got %+v, %v", check, err)
+	}
+	if _, err := parseIntegrity("md5-abc"); err == nil {
+		t.Error("Expected an unsupported hash to fail")
+	}
+}
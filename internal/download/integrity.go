@@ -0,0 +1,116 @@
+package download
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// algorithms are the Subresource Integrity hashes, weakest first
+var algorithms = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha256", sha256.New},
+	{"sha384", sha512.New384},
+	{"sha512", sha512.New},
+}
+
+// integrity checks data against an SRI string such as "sha512-<base64>"
+type integrity struct {
+	algorithm string
+	hash      hash.Hash
+	digests   [][]byte
+}
+
+// parseIntegrity parses an SRI string. With several hashes, only those of
+// the strongest algorithm count, and any of them may match, as in browsers.
+func parseIntegrity(s string) (*integrity, error) {
+	best := -1
+	var digests [][]byte
+	for _, field := range strings.Fields(s) {
+		name, encoded, ok := strings.Cut(field, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid integrity %q", field)
+		}
+		// Options such as ?foo after the digest are ignored
+		encoded, _, _ = strings.Cut(encoded, "?")
+
+		index := -1
+		for i, alg := range algorithms {
+			if alg.name == name {
+				index = i
+			}
+		}
+		if index < 0 || index < best {
+			continue
+		}
+		digest, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s digest: %w", name, err)
+		}
+		if index > best {
+			best = index
+			digests = nil
+		}
+		digests = append(digests, digest)
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("no supported hash in integrity %q", s)
+	}
+	return &integrity{
+		algorithm: algorithms[best].name,
+		hash:      algorithms[best].new(),
+		digests:   digests,
+	}, nil
+}
+
+// verify reports whether the data written to the hash matches
+func (i *integrity) verify() error {
+	sum := i.hash.Sum(nil)
+	for _, digest := range i.digests {
+		if subtle.ConstantTimeCompare(sum, digest) == 1 {
+			return nil
+		}
+	}
+	return &IntegrityError{Algorithm: i.algorithm, Got: i.algorithm + "-" + base64.StdEncoding.EncodeToString(sum)}
+}
+
+// VerifyFile checks the file at path against the SRI string sri. A
+// mismatch is reported as an *IntegrityError with the path as its URL.
+func VerifyFile(path, sri string) error {
+	check, err := parseIntegrity(sri)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(check.hash, f); err != nil {
+		return err
+	}
+	if err := check.verify(); err != nil {
+		err.(*IntegrityError).URL = path
+		return err
+	}
+	return nil
+}
+
+// IntegrityError reports downloaded data that does not match its integrity
+type IntegrityError struct {
+	URL       string
+	Algorithm string
+	Got       string // the SRI string of what was downloaded
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("integrity check failed for %s: got %s", e.URL, e.Got)
+}
@@ -0,0 +1,192 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress is told about each download as it runs. Its methods may be
+// called from several goroutines at once.
+type Progress interface {
+	// Start is called when the response arrives; size is -1 if unknown
+	Start(url string, size int64)
+	// Advance is called with the number of bytes received
+	Advance(url string, n int64)
+	// Finish is called once the download succeeded or failed
+	Finish(url string, err error)
+}
+
+// NewProgress returns a Progress writing to w: bars and spinners that are
+// redrawn in place when w is a terminal, and one line per finished file
+// otherwise
+func NewProgress(w io.Writer) Progress {
+	if f, ok := w.(*os.File); ok && isTerminal(f) {
+		return &barProgress{w: w, active: make(map[string]*transfer)}
+	}
+	return &logProgress{w: w, sizes: make(map[string]int64)}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+type nopProgress struct{}
+
+func (nopProgress) Start(string, int64)   {}
+func (nopProgress) Advance(string, int64) {}
+func (nopProgress) Finish(string, error)  {}
+
+// logProgress writes a line when each download ends, for logs and CI
+type logProgress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	sizes map[string]int64
+}
+
+func (p *logProgress) Start(url string, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sizes[url] = 0
+}
+
+func (p *logProgress) Advance(url string, n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sizes[url] += n
+}
+
+func (p *logProgress) Finish(url string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(p.w, "failed %s: %v\n", url, err)
+	} else {
+		fmt.Fprintf(p.w, "fetched %s (%s)\n", url, formatBytes(p.sizes[url]))
+	}
+	delete(p.sizes, url)
+}
+
+// transfer is one download shown by barProgress
+type transfer struct {
+	name     string
+	received int64
+	size     int64
+}
+
+// barProgress keeps a line per running download at the bottom of a
+// terminal, redrawn at most every redrawInterval
+type barProgress struct {
+	mu       sync.Mutex
+	w        io.Writer
+	active   map[string]*transfer
+	order    []string
+	lines    int // lines drawn last time
+	frame    int
+	lastDraw time.Time
+}
+
+const (
+	redrawInterval = 100 * time.Millisecond
+	barWidth       = 24
+)
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+func (p *barProgress) Start(url string, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.active[url]; !ok {
+		p.order = append(p.order, url)
+	}
+	p.active[url] = &transfer{name: path.Base(url), size: size}
+	p.draw(true)
+}
+
+func (p *barProgress) Advance(url string, n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.active[url]; ok {
+		t.received += n
+	}
+	p.draw(false)
+}
+
+func (p *barProgress) Finish(url string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := path.Base(url)
+	var received int64
+	if t, ok := p.active[url]; ok {
+		received = t.received
+		delete(p.active, url)
+		for i, u := range p.order {
+			if u == url {
+				p.order = append(p.order[:i], p.order[i+1:]...)
+				break
+			}
+		}
+	}
+
+	// Finished downloads scroll up above the running ones
+	p.clear()
+	if err != nil {
+		fmt.Fprintf(p.w, "✗ %s: %v\n", name, err)
+	} else {
+		fmt.Fprintf(p.w, "✓ %s %s\n", name, formatBytes(received))
+	}
+	p.draw(true)
+}
+
+// clear moves the cursor back over the lines drawn last time
+func (p *barProgress) clear() {
+	if p.lines > 0 {
+		fmt.Fprintf(p.w, "\x1b[%dA\x1b[J", p.lines)
+		p.lines = 0
+	}
+}
+
+// draw redraws the running downloads, unless it did so very recently
+func (p *barProgress) draw(force bool) {
+	if !force && time.Since(p.lastDraw) < redrawInterval {
+		return
+	}
+	p.lastDraw = time.Now()
+	p.frame++
+
+	p.clear()
+	var b strings.Builder
+	for _, url := range p.order {
+		t := p.active[url]
+		if t.size > 0 {
+			done := int(float64(barWidth) * float64(t.received) / float64(t.size))
+			if done > barWidth {
+				done = barWidth
+			}
+			fmt.Fprintf(&b, "[%s%s] %3d%% %s\n", strings.Repeat("=", done), strings.Repeat(" ", barWidth-done),
+				100*t.received/t.size, t.name)
+		} else {
+			fmt.Fprintf(&b, "%s %s %s\n", spinner[p.frame%len(spinner)], t.name, formatBytes(t.received))
+		}
+	}
+	io.WriteString(p.w, b.String())
+	p.lines = len(p.order)
+}
+
+// formatBytes formats n as 512 B, 1.2 kB or 3.4 MB
+func formatBytes(n int64) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprintf("%d B", n)
+	case n < 1000*1000:
+		return fmt.Sprintf("%.1f kB", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1000*1000))
+	}
+}
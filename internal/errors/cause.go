@@ -0,0 +1,64 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+)
+
+// maxCauses bounds how much of a cause chain is followed, since a JS error
+// can be its own cause
+const maxCauses = 16
+
+// Error implements the error interface, so a JS error can sit in a Go error
+// chain
+func (e *JSError) Error() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Type
+	}
+	return e.Type + ": " + e.Message
+}
+
+// Unwrap returns the error's cause
+func (e *JSError) Unwrap() error {
+	return e.Cause
+}
+
+// WithCause records the cause of the error, typically the Error.cause of
+// the JS exception in Err
+func (e *ModuleError) WithCause(cause error) *ModuleError {
+	e.Cause = cause
+	return e
+}
+
+// causes returns the chain of errors behind e: Cause and the errors it
+// wraps, or failing that the errors Err wraps
+func (e *ModuleError) causes() []error {
+	next := e.Cause
+	if next == nil {
+		next = stderrors.Unwrap(e.Err)
+	}
+
+	var chain []error
+	for ; next != nil && len(chain) < maxCauses; next = stderrors.Unwrap(next) {
+		chain = append(chain, next)
+	}
+	return chain
+}
+
+// writeCauses writes one "Caused by" line per error in chain. The errors of
+// an AggregateError are listed under it.
+func writeCauses(b *strings.Builder, chain []error, indent string) {
+	for _, cause := range chain {
+		b.WriteString(fmt.Sprintf("%sCaused by: %s\n", indent, cause.Error()))
+
+		if jsErr, ok := cause.(*JSError); ok {
+			for i, err := range jsErr.Errors {
+				b.WriteString(fmt.Sprintf("%s  [%d] %s\n", indent, i, err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJSErrorError(t *testing.T) {
+	tests := []struct {
+		err  *JSError
+		want string
+	}{
+		{&JSError{Type: "TypeError", Message: "bad input"}, "TypeError: bad input"},
+		{&JSError{Message: "thrown string"}, "thrown string"},
+		{&JSError{Type: "AbortError"}, "AbortError"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestJSErrorUnwrap(t *testing.T) {
+	root := fmt.Errorf("connection refused")
+	err := &JSError{Type: "Error", Message: "query failed", Cause: root}
+
+	if !stderrors.Is(err, root) {
+		t.Error("Expected errors.Is to find the cause")
+	}
+}
+
+func TestModuleErrorFormatErrorCauses(t *testing.T) {
+	cause := &JSError{
+		Type:    "Error",
+		Message: "query failed",
+		Cause: &JSError{
+			Type:    "AggregateError",
+			Message: "all replicas failed",
+			Errors:  []error{fmt.Errorf("replica 1: timeout"), fmt.Errorf("replica 2: refused")},
+		},
+	}
+	moduleErr := NewModuleError("db", "/app/db.js", "execute", fmt.Errorf("request failed")).WithCause(cause)
+
+	formatted := moduleErr.FormatError()
+	for _, want := range []string{
+		"   Caused by: Error: query failed\n",
+		"   Caused by: AggregateError: all replicas failed\n",
+		"     [0] replica 1: timeout\n",
+		"     [1] replica 2: refused\n",
+	} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("Expected formatted error to contain %q, got:\n%s", want, formatted)
+		}
+	}
+}
+
+func TestModuleErrorFormatErrorWrappedGoError(t *testing.T) {
+	root := fmt.Errorf("no such file")
+	moduleErr := NewModuleError("config", "", "load", fmt.Errorf("reading config: %w", root))
+
+	formatted := moduleErr.FormatError()
+	if !strings.Contains(formatted, "   Caused by: no such file\n") {
+		t.Errorf("Expected the wrapped error in the cause chain, got:\n%s", formatted)
+	}
+}
+
+func TestModuleErrorCausesCycle(t *testing.T) {
+	cyclic := &JSError{Type: "Error", Message: "loop"}
+	cyclic.Cause = cyclic
+	moduleErr := NewModuleError("m", "", "execute", fmt.Errorf("failed")).WithCause(cyclic)
+
+	if n := len(moduleErr.causes()); n != maxCauses {
+		t.Errorf("Expected the chain to stop at %d causes, got %d", maxCauses, n)
+	}
+}
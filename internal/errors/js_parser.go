@@ -16,6 +16,8 @@ type JSError struct {
 	ColumnNumber int               `json:"column_number"`
 	Stack        []JSStackFrame    `json:"stack"`
 	Properties   map[string]string `json:"properties"`
+	Cause        error             `json:"-"` // Error.cause
+	Errors       []error           `json:"-"` // AggregateError.errors
 }
 
 // JSStackFrame represents a frame in the JavaScript stack trace
@@ -29,11 +31,12 @@ type JSStackFrame struct {
 
 // Various regex patterns for parsing JavaScript errors
 var (
-	// V8 (Node.js, Chrome) - "at Function (file:line:column)"
-	v8StackFrameRegex = regexp.MustCompile(`^\s*at\s+(.+?)\s+\((.+?):(\d+):(\d+)\)$`)
+	// V8 (Node.js, Chrome) - "at Function (file:line:column)". Goja's
+	// frames follow the column with the bytecode offset, e.g. ":2:24(6)".
+	v8StackFrameRegex = regexp.MustCompile(`^\s*at\s+(.+?)\s+\((.+?):(\d+):(\d+)(?:\(\d+\))?\)$`)
 	
 	// V8 without function name - "at file:line:column"
-	v8SimpleStackFrameRegex = regexp.MustCompile(`^\s*at\s+(.+?):(\d+):(\d+)$`)
+	v8SimpleStackFrameRegex = regexp.MustCompile(`^\s*at\s+(.+?):(\d+):(\d+)(?:\(\d+\))?$`)
 	
 	// SpiderMonkey (Firefox) - "function@file:line:column"
 	spiderMonkeyStackFrameRegex = regexp.MustCompile(`^(.+?)@(.+?):(\d+):(\d+)$`)
@@ -0,0 +1,302 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Position is a place in a source file. Lines and columns count from 1, as
+// they do in stack traces.
+type Position struct {
+	Source string
+	Line   int
+	Column int
+	Name   string
+}
+
+// PositionMapper maps a position in the code that ran back to the source it
+// was generated from
+type PositionMapper interface {
+	Original(line, column int) (Position, bool)
+}
+
+// SourceMap is a parsed version 3 source map
+type SourceMap struct {
+	File           string
+	Sources        []string
+	SourcesContent []string
+	Names          []string
+	// lines holds the segments of each generated line, by generated column
+	lines [][]segment
+}
+
+// segment maps a generated column to a source position, all counted from 0.
+// A segment with a source of -1 marks code that has no source.
+type segment struct {
+	column, source, line, sourceColumn, name int
+}
+
+// ParseSourceMap parses a version 3 source map. Index maps, which have
+// sections instead of mappings, are not supported.
+func ParseSourceMap(data []byte) (*SourceMap, error) {
+	var raw struct {
+		Version        int       `json:"version"`
+		File           string    `json:"file"`
+		SourceRoot     string    `json:"sourceRoot"`
+		Sources        []*string `json:"sources"`
+		SourcesContent []*string `json:"sourcesContent"`
+		Names          []string  `json:"names"`
+		Mappings       *string   `json:"mappings"`
+		Sections       []any     `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+	if raw.Sections != nil {
+		return nil, fmt.Errorf("index source maps are not supported")
+	}
+	if raw.Mappings == nil {
+		return nil, fmt.Errorf("invalid source map: no mappings")
+	}
+
+	m := &SourceMap{File: raw.File, Names: raw.Names}
+	root := raw.SourceRoot
+	if root != "" && !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	for _, source := range raw.Sources {
+		if source == nil {
+			m.Sources = append(m.Sources, "")
+			continue
+		}
+		m.Sources = append(m.Sources, root+*source)
+	}
+	for _, content := range raw.SourcesContent {
+		if content == nil {
+			m.SourcesContent = append(m.SourcesContent, "")
+			continue
+		}
+		m.SourcesContent = append(m.SourcesContent, *content)
+	}
+
+	lines, err := decodeMappings(*raw.Mappings, len(m.Sources), len(m.Names))
+	if err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	m.lines = lines
+	return m, nil
+}
+
+// decodeMappings decodes the Base64 VLQ mappings of a source map. Source,
+// line, source column and name are relative to the previous segment of the
+// map, the generated column to the previous segment of its line.
+func decodeMappings(mappings string, sources, names int) ([][]segment, error) {
+	var lines [][]segment
+	var source, line, sourceColumn, name int
+	for _, text := range strings.Split(mappings, ";") {
+		var segments []segment
+		column := 0
+		for _, field := range strings.Split(text, ",") {
+			if field == "" {
+				continue
+			}
+			values, err := decodeVLQ(field)
+			if err != nil {
+				return nil, err
+			}
+			column += values[0]
+			seg := segment{column: column, source: -1, name: -1}
+			switch len(values) {
+			case 1:
+			case 4, 5:
+				source += values[1]
+				line += values[2]
+				sourceColumn += values[3]
+				if source < 0 || source >= sources {
+					return nil, fmt.Errorf("mapping names source %d of %d", source, sources)
+				}
+				seg.source, seg.line, seg.sourceColumn = source, line, sourceColumn
+				if len(values) == 5 {
+					name += values[4]
+					if name >= 0 && name < names {
+						seg.name = name
+					}
+				}
+			default:
+				return nil, fmt.Errorf("mapping segment %q has %d fields", field, len(values))
+			}
+			segments = append(segments, seg)
+		}
+		sort.SliceStable(segments, func(i, j int) bool { return segments[i].column < segments[j].column })
+		lines = append(lines, segments)
+	}
+	return lines, nil
+}
+
+const vlqChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// decodeVLQ decodes the Base64 VLQ values of one mapping segment
+func decodeVLQ(field string) ([]int, error) {
+	var values []int
+	value, shift := 0, 0
+	for i := 0; i < len(field); i++ {
+		digit := strings.IndexByte(vlqChars, field[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid character %q in mappings", field[i])
+		}
+		if shift > 30 {
+			return nil, fmt.Errorf("mapping value too large in %q", field)
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		// The lowest bit is the sign
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated mapping segment %q", field)
+	}
+	return values, nil
+}
+
+// Original returns the source position of a generated one: that of the
+// last segment of the line that starts at or before column
+func (m *SourceMap) Original(line, column int) (Position, bool) {
+	if line < 1 || line > len(m.lines) {
+		return Position{}, false
+	}
+	segments := m.lines[line-1]
+	i := sort.Search(len(segments), func(i int) bool { return segments[i].column > column-1 }) - 1
+	if i < 0 {
+		// Before the first segment, the line's first mapping is the best guess
+		if len(segments) == 0 {
+			return Position{}, false
+		}
+		i = 0
+	}
+	seg := segments[i]
+	if seg.source < 0 {
+		return Position{}, false
+	}
+	pos := Position{Source: m.Sources[seg.source], Line: seg.line + 1, Column: seg.sourceColumn + 1}
+	if seg.name >= 0 {
+		pos.Name = m.Names[seg.name]
+	}
+	return pos, true
+}
+
+// Offset maps positions in code that was wrapped before it ran back to the
+// code itself: Lines lines were added above it, and Columns characters in
+// front of its first line. Map, if set, maps the unwrapped position further;
+// otherwise positions are reported in Source.
+type Offset struct {
+	Source  string
+	Lines   int
+	Columns int
+	Map     PositionMapper
+}
+
+// Original implements PositionMapper
+func (o Offset) Original(line, column int) (Position, bool) {
+	line -= o.Lines
+	if line < 1 {
+		return Position{}, false
+	}
+	if line == 1 {
+		if column -= o.Columns; column < 1 {
+			column = 1
+		}
+	}
+	if o.Map != nil {
+		return o.Map.Original(line, column)
+	}
+	return Position{Source: o.Source, Line: line, Column: column}, true
+}
+
+// sourceMaps holds the mappers of the files that have run, by the name they
+// have in stack traces
+var sourceMaps = struct {
+	sync.RWMutex
+	files map[string]PositionMapper
+}{files: make(map[string]PositionMapper)}
+
+// RegisterSourceMap makes stack traces that name file report positions
+// through m. A nil m removes the file's mapper.
+func RegisterSourceMap(file string, m PositionMapper) {
+	sourceMaps.Lock()
+	defer sourceMaps.Unlock()
+	if m == nil {
+		delete(sourceMaps.files, file)
+		return
+	}
+	sourceMaps.files[file] = m
+}
+
+// LookupSourceMap returns the mapper registered for file, if any
+func LookupSourceMap(file string) PositionMapper {
+	sourceMaps.RLock()
+	defer sourceMaps.RUnlock()
+	return sourceMaps.files[file]
+}
+
+// MapPosition maps a position in file to its source, returning it unchanged
+// when file has no source map or the position is not mapped
+func MapPosition(file string, line, column int) Position {
+	if m := LookupSourceMap(file); m != nil && line > 0 {
+		if pos, ok := m.Original(line, column); ok {
+			if pos.Source == "" {
+				pos.Source = file
+			}
+			return pos
+		}
+	}
+	return Position{Source: file, Line: line, Column: column}
+}
+
+// MapStackFrames maps the positions of frames to their sources
+func MapStackFrames(frames []JSStackFrame) []JSStackFrame {
+	mapped := make([]JSStackFrame, len(frames))
+	for i, frame := range frames {
+		mapped[i] = frame
+		if frame.Line == 0 || frame.File == "native" || frame.File == "<unknown>" {
+			continue
+		}
+		pos := MapPosition(frame.File, frame.Line, frame.Column)
+		mapped[i].File, mapped[i].Line, mapped[i].Column = pos.Source, pos.Line, pos.Column
+	}
+	return mapped
+}
+
+// locationRegex matches the file:line:column locations of stack traces.
+// File names may themselves hold colons, as in "app:src/index.js".
+var locationRegex = regexp.MustCompile(`([^\s()]+):(\d+):(\d+)`)
+
+// MapStackTrace rewrites the file:line:column locations of a formatted
+// stack trace that fall in files with source maps
+func MapStackTrace(stack string) string {
+	return locationRegex.ReplaceAllStringFunc(stack, func(location string) string {
+		parts := locationRegex.FindStringSubmatch(location)
+		if LookupSourceMap(parts[1]) == nil {
+			return location
+		}
+		line, _ := strconv.Atoi(parts[2])
+		column, _ := strconv.Atoi(parts[3])
+		pos := MapPosition(parts[1], line, column)
+		return fmt.Sprintf("%s:%d:%d", pos.Source, pos.Line, pos.Column)
+	})
+}
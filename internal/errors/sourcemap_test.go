@@ -0,0 +1,133 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// encodeVLQ encodes mapping values the way source map generators do
+func encodeVLQ(values ...int) string {
+	var b strings.Builder
+	for _, value := range values {
+		v := value << 1
+		if value < 0 {
+			v = (-value << 1) | 1
+		}
+		for {
+			digit := v & 31
+			v >>= 5
+			if v > 0 {
+				digit |= 32
+			}
+			b.WriteByte(vlqChars[digit])
+			if v == 0 {
+				break
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestDecodeVLQ(t *testing.T) {
+	for _, values := range [][]int{{0}, {1, -1, 15, -16}, {1000, -123456, 0, 31, 32}} {
+		decoded, err := decodeVLQ(encodeVLQ(values...))
+		if err != nil || len(decoded) != len(values) {
+			t.Fatalf("decodeVLQ(%v) = %v, %v", values, decoded, err)
+		}
+		for i := range values {
+			if decoded[i] != values[i] {
+				t.Errorf("decodeVLQ(%v) = %v", values, decoded)
+			}
+		}
+	}
+	for _, field := range []string{"g", "A!", "gggggggggg"} {
+		if _, err := decodeVLQ(field); err == nil {
+			t.Errorf("decodeVLQ(%q) should fail", field)
+		}
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	// Generated line 1: column 0 from src/a.ts 3:0, column 10 from
+	// src/a.ts 4:2 named "fail". Line 2 is unmapped, and line 3 column 4
+	// comes from src/b.ts 1:0.
+	mappings := encodeVLQ(0, 0, 2, 0) + "," + encodeVLQ(10, 0, 1, 2, 0) + ";;" + encodeVLQ(4, 1, -3, -2)
+	m, err := ParseSourceMap([]byte(`{"version":3,"file":"out.js","sourceRoot":"src","sources":["a.ts","b.ts"],"names":["fail"],"mappings":"` + mappings + `"}`))
+	if err != nil {
+		t.Fatalf("ParseSourceMap() failed: %v", err)
+	}
+
+	tests := []struct {
+		line, column int
+		want         Position
+		ok           bool
+	}{
+		{1, 1, Position{Source: "src/a.ts", Line: 3, Column: 1}, true},
+		{1, 10, Position{Source: "src/a.ts", Line: 3, Column: 1}, true},
+		{1, 11, Position{Source: "src/a.ts", Line: 4, Column: 3, Name: "fail"}, true},
+		{1, 40, Position{Source: "src/a.ts", Line: 4, Column: 3, Name: "fail"}, true},
+		{2, 1, Position{}, false},
+		{3, 1, Position{Source: "src/b.ts", Line: 1, Column: 1}, true},
+		{9, 1, Position{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := m.Original(tt.line, tt.column)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Original(%d, %d) = %+v, %v, want %+v, %v", tt.line, tt.column, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	for _, data := range []string{
+		`{"version":2,"sources":[],"mappings":""}`,
+		`{"version":3,"sections":[]}`,
+		`{"version":3,"sources":["a.ts"],"mappings":"` + encodeVLQ(0, 1, 0, 0) + `"}`,
+		`not json`,
+	} {
+		if _, err := ParseSourceMap([]byte(data)); err == nil {
+			t.Errorf("ParseSourceMap(%s) should fail", data)
+		}
+	}
+}
+
+func TestMapStackTrace(t *testing.T) {
+	mappings := ";" + encodeVLQ(2, 0, 6, 0)
+	m, err := ParseSourceMap([]byte(`{"version":3,"sources":["app:src/main.ts"],"names":[],"mappings":"` + mappings + `"}`))
+	if err != nil {
+		t.Fatalf("ParseSourceMap() failed: %v", err)
+	}
+	RegisterSourceMap("app:dist/main.js", m)
+	defer RegisterSourceMap("app:dist/main.js", nil)
+	RegisterSourceMap("app:test/a.test.js", Offset{Source: "app:test/a.test.js", Lines: 1})
+	defer RegisterSourceMap("app:test/a.test.js", nil)
+
+	stack := "Error: boom\n    at run (app:dist/main.js:2:9)\n    at app:test/a.test.js:5:3\n    at other (app:lib.js:2:9)"
+	want := "Error: boom\n    at run (app:src/main.ts:7:1)\n    at app:test/a.test.js:4:3\n    at other (app:lib.js:2:9)"
+	if got := MapStackTrace(stack); got != want {
+		t.Errorf("MapStackTrace() = %q, want %q", got, want)
+	}
+
+	frames := MapStackFrames([]JSStackFrame{{Function: "run", File: "app:dist/main.js", Line: 2, Column: 9}, {Function: "JSON.parse (native)", File: "native"}})
+	if frames[0].File != "app:src/main.ts" || frames[0].Line != 7 || frames[1].File != "native" {
+		t.Errorf("MapStackFrames() = %+v", frames)
+	}
+
+	moduleErr := NewModuleError("main", "app:dist/main.js", "execute", fmt.Errorf("boom")).WithLineInfo(2, 9).WithJSStackTrace(stack)
+	formatted := moduleErr.FormatError()
+	if !strings.Contains(formatted, "Source: app:src/main.ts\n   Line: 7, Column: 1") || !strings.Contains(formatted, "at run (app:src/main.ts:7:1)") {
+		t.Errorf("FormatError() did not map positions:\n%s", formatted)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	offset := Offset{Source: "wrapped.js", Lines: 1, Columns: 13}
+	if _, ok := offset.Original(1, 5); ok {
+		t.Error("Expected the wrapper's line to be unmapped")
+	}
+	if pos, ok := offset.Original(2, 20); !ok || pos != (Position{Source: "wrapped.js", Line: 1, Column: 7}) {
+		t.Errorf("Original(2, 20) = %+v, %v", pos, ok)
+	}
+	if pos, ok := offset.Original(3, 4); !ok || pos != (Position{Source: "wrapped.js", Line: 2, Column: 4}) {
+		t.Errorf("Original(3, 4) = %+v, %v", pos, ok)
+	}
+}
@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseStackTrace parses the "at ..." lines of a JavaScript stack trace,
+// naming Go native functions the way error reports do
+func ParseStackTrace(lines []string) []JSStackFrame {
+	return parseStackTrace(lines)
+}
+
+// String formats the frame as a V8 stack line without the indentation,
+// e.g. "at handler (app:/src/index.js:12:5)"
+func (f JSStackFrame) String() string {
+	switch {
+	case f.File == "native":
+		return "at " + f.Function
+	case f.File == "<unknown>":
+		return strings.TrimSpace(f.Source)
+	case f.Function == "<anonymous>":
+		return fmt.Sprintf("at %s:%d:%d", f.File, f.Line, f.Column)
+	}
+	return fmt.Sprintf("at %s (%s:%d:%d)", f.Function, f.File, f.Line, f.Column)
+}
+
+// FormatStack formats frames the way Error.prototype.stack lists them, one
+// indented "at ..." line per frame
+func FormatStack(frames []JSStackFrame) string {
+	var b strings.Builder
+	for i, frame := range frames {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("    ")
+		b.WriteString(frame.String())
+	}
+	return b.String()
+}
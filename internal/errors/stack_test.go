@@ -0,0 +1,39 @@
+package errors
+
+import "testing"
+
+func TestJSStackFrameString(t *testing.T) {
+	tests := []struct {
+		frame JSStackFrame
+		want  string
+	}{
+		{JSStackFrame{Function: "handler", File: "app:/src/index.js", Line: 12, Column: 5}, "at handler (app:/src/index.js:12:5)"},
+		{JSStackFrame{Function: "<anonymous>", File: "/src/index.js", Line: 1, Column: 1}, "at /src/index.js:1:1"},
+		{JSStackFrame{Function: "JSON.parse (native)", File: "native"}, "at JSON.parse (native)"},
+		{JSStackFrame{Function: "<unknown>", File: "<unknown>", Source: "  at native"}, "at native"},
+	}
+	for _, tt := range tests {
+		if got := tt.frame.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFormatStackRoundTrip(t *testing.T) {
+	lines := []string{
+		"at handler (app:/src/index.js:12:5)",
+		"at /src/index.js:1:1",
+	}
+	frames := ParseStackTrace(lines)
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(frames))
+	}
+	if frames[0].File != "app:/src/index.js" || frames[0].Line != 12 {
+		t.Errorf("Expected the enhanced file name to survive parsing, got %+v", frames[0])
+	}
+
+	want := "    at handler (app:/src/index.js:12:5)\n    at /src/index.js:1:1"
+	if got := FormatStack(frames); got != want {
+		t.Errorf("FormatStack() = %q, want %q", got, want)
+	}
+}
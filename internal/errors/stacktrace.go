@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // StackFrame represents a single frame in the stack trace
@@ -28,13 +30,27 @@ type ModuleError struct {
 	ModulePath    string     `json:"module_path"`
 	Operation     string     `json:"operation"`
 	Err           error      `json:"error"`
-	StackTrace    StackTrace `json:"stack_trace"`
 	JSStackTrace  string     `json:"js_stack_trace,omitempty"`
 	Line          int        `json:"line,omitempty"`
 	Column        int        `json:"column,omitempty"`
 	SourceContext string     `json:"source_context,omitempty"`
+	Cause         error      `json:"-"` // the JS Error.cause, when Err is a JS exception
+	
+	callers *callers // where the error was made, see Stack
+}
+
+// callers is a Go stack recorded as program counters, which is cheap. They
+// are looked up only when the stack is shown, as most errors, such as
+// modules that aren't found, are handled without ever showing theirs.
+type callers struct {
+	pcs   []uintptr
+	once  sync.Once
+	trace StackTrace
 }
 
+// maxFrames is the number of Go frames a stack trace keeps
+const maxFrames = 32
+
 // Error implements the error interface
 func (e *ModuleError) Error() string {
 	return fmt.Sprintf("ModuleError in %s (%s): %s", e.ModuleName, e.Operation, e.Err.Error())
@@ -47,44 +63,57 @@ func (e *ModuleError) Unwrap() error {
 
 // NewModuleError creates a new module error with stack trace
 func NewModuleError(moduleName, modulePath, operation string, err error) *ModuleError {
+	pc := make([]uintptr, maxFrames)
+	n := runtime.Callers(1, pc) // Skip runtime.Callers itself
 	return &ModuleError{
 		ModuleName:   moduleName,
 		ModulePath:   modulePath,
 		Operation:    operation,
 		Err:          err,
-		StackTrace:   captureStackTrace(),
+		callers:      &callers{pcs: pc[:n]},
 	}
 }
 
+// Stack returns the Go stack trace of where the error was created, looking
+// up its frames the first time
+func (e *ModuleError) Stack() StackTrace {
+	if e.callers == nil {
+		return StackTrace{}
+	}
+	e.callers.once.Do(func() {
+		e.callers.trace = stackTraceOf(e.callers.pcs)
+	})
+	return e.callers.trace
+}
+
 // captureStackTrace captures the current Go stack trace
 func captureStackTrace() StackTrace {
-	const maxFrames = 32
 	pc := make([]uintptr, maxFrames)
 	n := runtime.Callers(2, pc) // Skip this function and the calling function
+	return stackTraceOf(pc[:n])
+}
+
+// stackTraceOf looks up the frames of a stack recorded by runtime.Callers,
+// including those of inlined functions
+func stackTraceOf(pc []uintptr) StackTrace {
+	frames := make([]StackFrame, 0, len(pc))
 	
-	frames := make([]StackFrame, 0, n)
-	
-	for i := 0; i < n; i++ {
-		fn := runtime.FuncForPC(pc[i])
-		if fn == nil {
-			continue
+	callers := runtime.CallersFrames(pc)
+	for {
+		f, more := callers.Next()
+		if f.Function != "" {
+			names := namesOf(f.Function, f.File)
+			frames = append(frames, StackFrame{
+				File:     f.File,
+				Line:     f.Line,
+				Function: f.Function,
+				Module:   names.module,
+				Package:  names.pkg,
+			})
 		}
-		
-		file, line := fn.FileLine(pc[i])
-		
-		// Extract package and module information
-		packageName := extractPackageName(fn.Name())
-		moduleName := extractModuleName(file)
-		
-		frame := StackFrame{
-			File:     file,
-			Line:     line,
-			Function: fn.Name(),
-			Module:   moduleName,
-			Package:  packageName,
+		if !more {
+			break
 		}
-		
-		frames = append(frames, frame)
 	}
 	
 	return StackTrace{
@@ -93,6 +122,33 @@ func captureStackTrace() StackTrace {
 	}
 }
 
+// frameNames are the module and package names of a function's frames
+type frameNames struct {
+	module, pkg string
+}
+
+// interned holds the frameNames of every function seen, so that errors
+// don't work them out again for each of their frames
+var interned struct {
+	sync.Mutex
+	names map[string]frameNames // by function
+}
+
+// namesOf returns the frameNames of function, defined in file
+func namesOf(function, file string) frameNames {
+	interned.Lock()
+	defer interned.Unlock()
+	names, ok := interned.names[function]
+	if !ok {
+		if interned.names == nil {
+			interned.names = make(map[string]frameNames)
+		}
+		names = frameNames{module: extractModuleName(file), pkg: extractPackageName(function)}
+		interned.names[function] = names
+	}
+	return names
+}
+
 // extractPackageName extracts the package name from a function name
 func extractPackageName(funcName string) string {
 	// Function names look like: github.com/user/repo/package.function
@@ -142,9 +198,20 @@ func (st StackTrace) FormatStackTrace() string {
 	
 	b.WriteString("Stack Trace:\n")
 	for i, frame := range st.Frames {
-		b.WriteString(fmt.Sprintf("  %d. %s:%d\n", i+1, frame.File, frame.Line))
-		b.WriteString(fmt.Sprintf("     Function: %s\n", frame.Function))
-		b.WriteString(fmt.Sprintf("     Module: %s, Package: %s\n", frame.Module, frame.Package))
+		// Written piece by piece, as errors format every frame
+		b.WriteString("  ")
+		b.WriteString(strconv.Itoa(i + 1))
+		b.WriteString(". ")
+		b.WriteString(frame.File)
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteString("\n     Function: ")
+		b.WriteString(frame.Function)
+		b.WriteString("\n     Module: ")
+		b.WriteString(frame.Module)
+		b.WriteString(", Package: ")
+		b.WriteString(frame.Package)
+		b.WriteString("\n")
 		if i < len(st.Frames)-1 {
 			b.WriteString("\n")
 		}
@@ -163,9 +230,14 @@ func (e *ModuleError) FormatError() string {
 	b.WriteString(fmt.Sprintf("   Error: %s\n", e.Err.Error()))
 	
 	if e.Line > 0 {
-		b.WriteString(fmt.Sprintf("   Line: %d", e.Line))
-		if e.Column > 0 {
-			b.WriteString(fmt.Sprintf(", Column: %d", e.Column))
+		// Positions in generated or wrapped code are reported in the source
+		pos := MapPosition(e.ModulePath, e.Line, e.Column)
+		if pos.Source != e.ModulePath {
+			b.WriteString(fmt.Sprintf("   Source: %s\n", pos.Source))
+		}
+		b.WriteString(fmt.Sprintf("   Line: %d", pos.Line))
+		if pos.Column > 0 {
+			b.WriteString(fmt.Sprintf(", Column: %d", pos.Column))
 		}
 		b.WriteString("\n")
 	}
@@ -175,12 +247,14 @@ func (e *ModuleError) FormatError() string {
 	}
 	
 	if e.JSStackTrace != "" {
-		b.WriteString(fmt.Sprintf("   JavaScript Stack Trace:\n%s\n", e.JSStackTrace))
+		b.WriteString(fmt.Sprintf("   JavaScript Stack Trace:\n%s\n", MapStackTrace(e.JSStackTrace)))
 	}
 	
+	writeCauses(&b, e.causes(), "   ")
+	
 	// Add Go stack trace
 	b.WriteString("\n")
-	b.WriteString(e.StackTrace.FormatStackTrace())
+	b.WriteString(e.Stack().FormatStackTrace())
 	
 	return b.String()
 }
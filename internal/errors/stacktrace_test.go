@@ -26,11 +26,32 @@ func TestNewModuleError(t *testing.T) {
 		t.Errorf("Expected Err to be the original error, got %v", moduleErr.Err)
 	}
 
-	if len(moduleErr.StackTrace.Frames) == 0 {
+	if len(moduleErr.Stack().Frames) == 0 {
 		t.Error("Expected StackTrace to have frames, got empty")
 	}
 }
 
+func TestModuleErrorStack(t *testing.T) {
+	moduleErr := NewModuleError("test-module", "/path/to/module", "load", fmt.Errorf("boom"))
+
+	// Frames are looked up once, when first asked for
+	stack := moduleErr.Stack()
+	if len(stack.Frames) == 0 || !strings.HasSuffix(stack.Frames[0].Function, ".NewModuleError") {
+		t.Fatalf("Expected the stack to start at NewModuleError, got %+v", stack.Frames)
+	}
+	if !strings.Contains(stack.Frames[1].Function, "TestModuleErrorStack") {
+		t.Errorf("Expected the caller next, got %s", stack.Frames[1].Function)
+	}
+	if again := moduleErr.Stack(); &again.Frames[0] != &stack.Frames[0] {
+		t.Error("Expected the frames to be looked up once")
+	}
+
+	// Errors made without NewModuleError have no stack
+	if frames := (&ModuleError{Err: fmt.Errorf("boom")}).Stack().Frames; len(frames) != 0 {
+		t.Errorf("Expected no frames, got %d", len(frames))
+	}
+}
+
 func TestModuleErrorError(t *testing.T) {
 	originalErr := fmt.Errorf("test error")
 	moduleErr := NewModuleError("my-module", "/path", "execute", originalErr)
@@ -235,15 +256,15 @@ func TestNestedStackTrace(t *testing.T) {
 	moduleErr := nestedFunction1()
 
 	// Check that at least one nested function appears in the stack trace
-	formatted := moduleErr.StackTrace.FormatStackTrace()
+	formatted := moduleErr.Stack().FormatStackTrace()
 	
 	if !strings.Contains(formatted, "nestedFunction") {
 		t.Error("Expected stack trace to contain at least one nestedFunction")
 	}
 	
 	// Check that we have multiple frames (indicating proper stack capture)
-	if len(moduleErr.StackTrace.Frames) < 3 {
-		t.Errorf("Expected at least 3 stack frames, got %d", len(moduleErr.StackTrace.Frames))
+	if len(moduleErr.Stack().Frames) < 3 {
+		t.Errorf("Expected at least 3 stack frames, got %d", len(moduleErr.Stack().Frames))
 	}
 }
 
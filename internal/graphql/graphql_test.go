@@ -0,0 +1,206 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+interface Node { id: ID! }
+
+type User implements Node {
+  id: ID!
+  name: String!
+  friends(first: Int = 10): [User!]!
+}
+
+type Post implements Node {
+  id: ID!
+  title: String
+  status: Status
+  legacy: String @deprecated(reason: "use title")
+}
+
+enum Status { DRAFT PUBLISHED }
+
+union Item = User | Post
+
+type Query {
+  node(id: ID!): Node
+  items: [Item]
+  posts(status: Status = PUBLISHED): [Post!]
+}
+
+type Mutation {
+  rename(id: ID!, name: String!): User
+}
+`
+
+func parseTestSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := Parse("schema.graphql", testSchema)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	return schema
+}
+
+func names(fields []*Field) string {
+	var parts []string
+	for _, f := range fields {
+		parts = append(parts, f.Alias)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("bad.graphql", "type Query { x: Missing }"); err == nil {
+		t.Error("expected an error for an undefined type")
+	}
+	if _, err := Parse("empty.graphql", "type User { id: ID }"); err == nil {
+		t.Error("expected an error for a schema without a query type")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := parseTestSchema(t)
+	if errs := schema.Validate("{ posts { title } }"); errs != nil {
+		t.Errorf("Validate() = %v", errs)
+	}
+	errs := schema.Validate("{ posts { nope } }")
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "nope") {
+		t.Fatalf("Validate() = %v", errs)
+	}
+	if loc := errs[0].Locations; len(loc) != 1 || loc[0].Line != 1 || loc[0].Column != 11 {
+		t.Errorf("locations = %v", loc)
+	}
+	if errs := schema.Validate("{ posts { "); len(errs) != 1 {
+		t.Errorf("Validate() of a syntax error = %v", errs)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	schema := parseTestSchema(t)
+	op, errs := schema.Prepare(`
+		query Q($id: ID!, $withName: Boolean!) {
+			node(id: $id) {
+				id
+				... on User { name @include(if: $withName) friends { id } }
+				...PostFields
+			}
+			items { __typename ... on Node { id } }
+			posts { status }
+		}
+		fragment PostFields on Post { title id }
+	`, "", map[string]interface{}{"id": "u1", "withName": false})
+	if errs != nil {
+		t.Fatalf("Prepare() failed: %v", errs)
+	}
+	if op.Type != "query" || op.Name != "Q" || op.RootType != "Query" {
+		t.Errorf("operation = %s %s on %s", op.Type, op.Name, op.RootType)
+	}
+	if got := names(op.Fields); got != "node,items,posts" {
+		t.Fatalf("root fields = %s", got)
+	}
+
+	node := op.Fields[0]
+	if node.Args["id"] != "u1" || node.Type.Kind != "INTERFACE" || node.Type.Name != "Node" {
+		t.Errorf("node = %v %+v", node.Args, node.Type)
+	}
+	if got := names(node.Selections["User"]); got != "id,friends" {
+		t.Errorf("node on User = %s", got)
+	}
+	if got := names(node.Selections["Post"]); got != "id,title" {
+		t.Errorf("node on Post = %s", got)
+	}
+	friends := node.Selections["User"][1]
+	if friends.Args["first"] != int64(10) {
+		t.Errorf("friends args = %v", friends.Args)
+	}
+	if friends.Type.Kind != "NON_NULL" || friends.Type.OfType.Kind != "LIST" || friends.Type.OfType.OfType.OfType.Name != "User" {
+		t.Errorf("friends type = %+v", friends.Type)
+	}
+
+	items := op.Fields[1]
+	if got := names(items.Selections["Post"]); got != "__typename,id" {
+		t.Errorf("items on Post = %s", got)
+	}
+	posts := op.Fields[2]
+	if posts.Args["status"] != "PUBLISHED" || strings.Join(posts.Selections["Post"][0].Type.Values, ",") != "DRAFT,PUBLISHED" {
+		t.Errorf("posts = %v %+v", posts.Args, posts.Selections["Post"][0].Type)
+	}
+}
+
+func TestPrepareErrors(t *testing.T) {
+	schema := parseTestSchema(t)
+	tests := []struct {
+		query, operation string
+		variables        map[string]interface{}
+		want             string
+	}{
+		{"query A { posts { id } } query B { posts { id } }", "", nil, "Must provide operation name"},
+		{"query A { posts { id } }", "C", nil, `Unknown operation named "C"`},
+		{"query($id: ID!) { node(id: $id) { id } }", "", nil, "must be defined"},
+		{"query($id: ID!) { node(id: $id) { id } }", "", map[string]interface{}{"id": true}, "cannot use bool as ID"},
+	}
+	for _, tt := range tests {
+		_, errs := schema.Prepare(tt.query, tt.operation, tt.variables)
+		if len(errs) != 1 || !strings.Contains(errs[0].Message, tt.want) {
+			t.Errorf("Prepare(%q) = %v, want %q", tt.query, errs, tt.want)
+		}
+	}
+
+	op, errs := schema.Prepare(`mutation { rename(id: "1", name: "x") { name } }`, "", nil)
+	if errs != nil || op.Type != "mutation" || op.RootType != "Mutation" {
+		t.Errorf("mutation = %+v, %v", op, errs)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	schema := parseTestSchema(t)
+	op, errs := schema.Prepare(`{
+		__schema { queryType { name } mutationType { name } subscriptionType { name } }
+		post: __type(name: "Post") {
+			kind
+			fields { name type { kind ofType { name } } }
+			all: fields(includeDeprecated: true) { name isDeprecated deprecationReason }
+			interfaces { name }
+		}
+		item: __type(name: "Item") { possibleTypes { name } }
+		status: __type(name: "Status") { enumValues { name } }
+		missing: __type(name: "Missing") { name }
+	}`, "", nil)
+	if errs != nil {
+		t.Fatalf("Prepare() failed: %v", errs)
+	}
+
+	results := make(map[string]string)
+	for _, f := range op.Fields {
+		if !f.Introspection {
+			t.Fatalf("%s is not an introspection field", f.Alias)
+		}
+		data, err := json.Marshal(f.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results[f.Alias] = string(data)
+	}
+
+	want := map[string]string{
+		"__schema": `{"queryType":{"name":"Query"},"mutationType":{"name":"Mutation"},"subscriptionType":null}`,
+		"post": `{"kind":"OBJECT","fields":[{"name":"id","type":{"kind":"NON_NULL","ofType":{"name":"ID"}}},` +
+			`{"name":"title","type":{"kind":"SCALAR","ofType":null}},{"name":"status","type":{"kind":"ENUM","ofType":null}}],` +
+			`"all":[{"name":"id","isDeprecated":false,"deprecationReason":null},{"name":"title","isDeprecated":false,"deprecationReason":null},` +
+			`{"name":"status","isDeprecated":false,"deprecationReason":null},{"name":"legacy","isDeprecated":true,"deprecationReason":"use title"}],` +
+			`"interfaces":[{"name":"Node"}]}`,
+		"item":    `{"possibleTypes":[{"name":"User"},{"name":"Post"}]}`,
+		"status":  `{"enumValues":[{"name":"DRAFT"},{"name":"PUBLISHED"}]}`,
+		"missing": `null`,
+	}
+	for alias, w := range want {
+		if results[alias] != w {
+			t.Errorf("%s =\n%s\nwant\n%s", alias, results[alias], w)
+		}
+	}
+}
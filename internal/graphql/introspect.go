@@ -0,0 +1,365 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Result is an object in a response, its fields in the order they were
+// selected
+type Result struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func (r *Result) set(key string, value interface{}) {
+	if _, ok := r.Values[key]; !ok {
+		r.Keys = append(r.Keys, key)
+	}
+	r.Values[key] = value
+}
+
+// MarshalJSON writes the fields in order
+func (r *Result) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range r.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(r.Values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// introspected is an object of the introspection types
+type introspected interface {
+	typeName() string
+	field(name string, args map[string]interface{}) interface{}
+}
+
+// introspect resolves a __schema or __type field
+func (s *Schema) introspect(field *Field) interface{} {
+	if field.Name == "__schema" {
+		return complete(schemaObject{s}, field)
+	}
+	name, _ := field.Args["name"].(string)
+	if s.schema.Types[name] == nil {
+		return nil
+	}
+	return complete(typeObject{s, ast.NamedType(name, nil)}, field)
+}
+
+// complete selects the fields of field from value
+func complete(value interface{}, field *Field) interface{} {
+	switch v := value.(type) {
+	case introspected:
+		result := &Result{Values: make(map[string]interface{})}
+		for _, f := range field.Selections[v.typeName()] {
+			if f.Name == "__typename" {
+				result.set(f.Alias, v.typeName())
+				continue
+			}
+			result.set(f.Alias, complete(v.field(f.Name, f.Args), f))
+		}
+		return result
+	case []introspected:
+		if v == nil {
+			return nil
+		}
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = complete(item, field)
+		}
+		return items
+	}
+	return value
+}
+
+// deprecation returns whether directives mark a definition deprecated and
+// why
+func deprecation(directives ast.DirectiveList) (bool, interface{}) {
+	d := directives.ForName("deprecated")
+	if d == nil {
+		return false, nil
+	}
+	return true, d.ArgumentMap(nil)["reason"]
+}
+
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+type schemaObject struct {
+	s *Schema
+}
+
+func (o schemaObject) typeName() string { return "__Schema" }
+
+func (o schemaObject) field(name string, args map[string]interface{}) interface{} {
+	schema := o.s.schema
+	named := func(def *ast.Definition) interface{} {
+		if def == nil {
+			return nil
+		}
+		return typeObject{o.s, ast.NamedType(def.Name, nil)}
+	}
+	switch name {
+	case "description":
+		return optional(schema.Description)
+	case "types":
+		names := make([]string, 0, len(schema.Types))
+		for name := range schema.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		types := make([]introspected, len(names))
+		for i, name := range names {
+			types[i] = typeObject{o.s, ast.NamedType(name, nil)}
+		}
+		return types
+	case "queryType":
+		return named(schema.Query)
+	case "mutationType":
+		return named(schema.Mutation)
+	case "subscriptionType":
+		return named(schema.Subscription)
+	case "directives":
+		names := make([]string, 0, len(schema.Directives))
+		for name := range schema.Directives {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		directives := make([]introspected, len(names))
+		for i, name := range names {
+			directives[i] = directiveObject{o.s, schema.Directives[name]}
+		}
+		return directives
+	}
+	return nil
+}
+
+type typeObject struct {
+	s   *Schema
+	typ *ast.Type
+}
+
+func (o typeObject) typeName() string { return "__Type" }
+
+func (o typeObject) field(name string, args map[string]interface{}) interface{} {
+	if o.typ.NonNull || o.typ.Elem != nil {
+		return o.wrapperField(name)
+	}
+	def := o.s.schema.Types[o.typ.NamedType]
+	includeDeprecated := args["includeDeprecated"] == true
+	switch name {
+	case "kind":
+		return string(def.Kind)
+	case "name":
+		return def.Name
+	case "description":
+		return optional(def.Description)
+	case "fields":
+		if def.Kind != ast.Object && def.Kind != ast.Interface {
+			return nil
+		}
+		fields := []introspected{}
+		for _, f := range def.Fields {
+			deprecated, _ := deprecation(f.Directives)
+			if strings.HasPrefix(f.Name, "__") || (deprecated && !includeDeprecated) {
+				continue
+			}
+			fields = append(fields, fieldObject{o.s, f})
+		}
+		return fields
+	case "interfaces":
+		if def.Kind != ast.Object && def.Kind != ast.Interface {
+			return nil
+		}
+		interfaces := []introspected{}
+		for _, name := range def.Interfaces {
+			interfaces = append(interfaces, typeObject{o.s, ast.NamedType(name, nil)})
+		}
+		return interfaces
+	case "possibleTypes":
+		if !def.IsAbstractType() {
+			return nil
+		}
+		possible := []introspected{}
+		for _, t := range o.s.schema.GetPossibleTypes(def) {
+			possible = append(possible, typeObject{o.s, ast.NamedType(t.Name, nil)})
+		}
+		return possible
+	case "enumValues":
+		if def.Kind != ast.Enum {
+			return nil
+		}
+		values := []introspected{}
+		for _, v := range def.EnumValues {
+			if deprecated, _ := deprecation(v.Directives); deprecated && !includeDeprecated {
+				continue
+			}
+			values = append(values, enumValueObject{v})
+		}
+		return values
+	case "inputFields":
+		if def.Kind != ast.InputObject {
+			return nil
+		}
+		fields := []introspected{}
+		for _, f := range def.Fields {
+			fields = append(fields, inputValueObject{o.s, f.Name, f.Description, f.Type, f.DefaultValue})
+		}
+		return fields
+	case "specifiedByURL":
+		if d := def.Directives.ForName("specifiedBy"); d != nil {
+			return d.ArgumentMap(nil)["url"]
+		}
+	}
+	return nil
+}
+
+// wrapperField resolves the fields of NON_NULL and LIST types
+func (o typeObject) wrapperField(name string) interface{} {
+	switch name {
+	case "kind":
+		if o.typ.NonNull {
+			return "NON_NULL"
+		}
+		return "LIST"
+	case "ofType":
+		if o.typ.NonNull {
+			inner := *o.typ
+			inner.NonNull = false
+			return typeObject{o.s, &inner}
+		}
+		return typeObject{o.s, o.typ.Elem}
+	}
+	return nil
+}
+
+type fieldObject struct {
+	s   *Schema
+	def *ast.FieldDefinition
+}
+
+func (o fieldObject) typeName() string { return "__Field" }
+
+func (o fieldObject) field(name string, args map[string]interface{}) interface{} {
+	switch name {
+	case "name":
+		return o.def.Name
+	case "description":
+		return optional(o.def.Description)
+	case "args":
+		return inputValues(o.s, o.def.Arguments)
+	case "type":
+		return typeObject{o.s, o.def.Type}
+	case "isDeprecated":
+		deprecated, _ := deprecation(o.def.Directives)
+		return deprecated
+	case "deprecationReason":
+		_, reason := deprecation(o.def.Directives)
+		return reason
+	}
+	return nil
+}
+
+func inputValues(s *Schema, args ast.ArgumentDefinitionList) []introspected {
+	values := []introspected{}
+	for _, arg := range args {
+		values = append(values, inputValueObject{s, arg.Name, arg.Description, arg.Type, arg.DefaultValue})
+	}
+	return values
+}
+
+type inputValueObject struct {
+	s            *Schema
+	name         string
+	description  string
+	typ          *ast.Type
+	defaultValue *ast.Value
+}
+
+func (o inputValueObject) typeName() string { return "__InputValue" }
+
+func (o inputValueObject) field(name string, args map[string]interface{}) interface{} {
+	switch name {
+	case "name":
+		return o.name
+	case "description":
+		return optional(o.description)
+	case "type":
+		return typeObject{o.s, o.typ}
+	case "defaultValue":
+		if o.defaultValue == nil {
+			return nil
+		}
+		return o.defaultValue.String()
+	}
+	return nil
+}
+
+type enumValueObject struct {
+	def *ast.EnumValueDefinition
+}
+
+func (o enumValueObject) typeName() string { return "__EnumValue" }
+
+func (o enumValueObject) field(name string, args map[string]interface{}) interface{} {
+	switch name {
+	case "name":
+		return o.def.Name
+	case "description":
+		return optional(o.def.Description)
+	case "isDeprecated":
+		deprecated, _ := deprecation(o.def.Directives)
+		return deprecated
+	case "deprecationReason":
+		_, reason := deprecation(o.def.Directives)
+		return reason
+	}
+	return nil
+}
+
+type directiveObject struct {
+	s   *Schema
+	def *ast.DirectiveDefinition
+}
+
+func (o directiveObject) typeName() string { return "__Directive" }
+
+func (o directiveObject) field(name string, args map[string]interface{}) interface{} {
+	switch name {
+	case "name":
+		return o.def.Name
+	case "description":
+		return optional(o.def.Description)
+	case "locations":
+		locations := make([]interface{}, len(o.def.Locations))
+		for i, location := range o.def.Locations {
+			locations[i] = string(location)
+		}
+		return locations
+	case "args":
+		return inputValues(o.s, o.def.Arguments)
+	case "isRepeatable":
+		return o.def.IsRepeatable
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package graphql
+
+import (
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Operation is a planned query or mutation
+type Operation struct {
+	Type      string // "query" or "mutation"
+	Name      string
+	RootType  string
+	Fields    []*Field
+	Variables map[string]interface{}
+}
+
+// Field is a field to resolve and what to select from its value
+type Field struct {
+	// Alias is the key of the field in the response
+	Alias string
+	Name  string
+	// Args holds the field's arguments, variables and defaults applied
+	Args map[string]interface{}
+	Type *Type
+	// Selections holds the fields selected from an object, interface or
+	// union value, by the name of each object type the value may be
+	Selections map[string][]*Field
+	// Introspection is set on __schema and __type, whose Value is resolved
+	// already
+	Introspection bool
+	Value         interface{}
+
+	Line, Column int
+}
+
+// Type is the type of a field's value
+type Type struct {
+	// Kind is NON_NULL, LIST or the kind of a named type: SCALAR, ENUM,
+	// OBJECT, INTERFACE or UNION
+	Kind string
+	// Name is set on named types
+	Name string
+	// OfType is the type wrapped by NON_NULL and LIST
+	OfType *Type
+	// Values holds the values of an enum
+	Values []string
+}
+
+// typeOf converts t, sharing the named types of the schema
+func (s *Schema) typeOf(t *ast.Type) *Type {
+	if t.NonNull {
+		inner := *t
+		inner.NonNull = false
+		return &Type{Kind: "NON_NULL", OfType: s.typeOf(&inner)}
+	}
+	if t.Elem != nil {
+		return &Type{Kind: "LIST", OfType: s.typeOf(t.Elem)}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if named, ok := s.types[t.NamedType]; ok {
+		return named
+	}
+	def := s.schema.Types[t.NamedType]
+	named := &Type{Kind: string(def.Kind), Name: def.Name}
+	for _, value := range def.EnumValues {
+		named.Values = append(named.Values, value.Name)
+	}
+	s.types[t.NamedType] = named
+	return named
+}
+
+// planner plans the selections of one operation
+type planner struct {
+	schema *Schema
+	vars   map[string]interface{}
+}
+
+// collect merges the fields that sets select from a value of object, in
+// the order they are first selected
+func (p *planner) collect(object *ast.Definition, sets []ast.SelectionSet) []*Field {
+	var fields []*Field
+	byAlias := make(map[string]*Field)
+	subsets := make(map[*Field][]ast.SelectionSet)
+	definitions := make(map[*Field]*ast.FieldDefinition)
+
+	var walk func(set ast.SelectionSet)
+	walk = func(set ast.SelectionSet) {
+		for _, selection := range set {
+			switch sel := selection.(type) {
+			case *ast.Field:
+				if p.skipped(sel.Directives) {
+					continue
+				}
+				field := byAlias[sel.Alias]
+				if field == nil {
+					field = &Field{
+						Alias:  sel.Alias,
+						Name:   sel.Name,
+						Args:   sel.ArgumentMap(p.vars),
+						Type:   p.schema.typeOf(sel.Definition.Type),
+						Line:   sel.Position.Line,
+						Column: sel.Position.Column,
+					}
+					byAlias[sel.Alias] = field
+					definitions[field] = sel.Definition
+					fields = append(fields, field)
+				}
+				if len(sel.SelectionSet) > 0 {
+					subsets[field] = append(subsets[field], sel.SelectionSet)
+				}
+			case *ast.InlineFragment:
+				if !p.skipped(sel.Directives) && p.applies(sel.TypeCondition, object) {
+					walk(sel.SelectionSet)
+				}
+			case *ast.FragmentSpread:
+				if !p.skipped(sel.Directives) && p.applies(sel.Definition.TypeCondition, object) {
+					walk(sel.Definition.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, set := range sets {
+		walk(set)
+	}
+
+	for _, field := range fields {
+		sets := subsets[field]
+		if len(sets) == 0 {
+			continue
+		}
+		named := p.schema.schema.Types[definitions[field].Type.Name()]
+		field.Selections = make(map[string][]*Field)
+		for _, possible := range p.possibleTypes(named) {
+			field.Selections[possible.Name] = p.collect(possible, sets)
+		}
+		if object == p.schema.schema.Query && (field.Name == "__schema" || field.Name == "__type") {
+			field.Introspection = true
+			field.Value = p.schema.introspect(field)
+		}
+	}
+	return fields
+}
+
+// skipped applies @skip and @include
+func (p *planner) skipped(directives ast.DirectiveList) bool {
+	if d := directives.ForName("skip"); d != nil && d.ArgumentMap(p.vars)["if"] == true {
+		return true
+	}
+	if d := directives.ForName("include"); d != nil && d.ArgumentMap(p.vars)["if"] == false {
+		return true
+	}
+	return false
+}
+
+// applies reports whether a fragment on condition applies to object
+func (p *planner) applies(condition string, object *ast.Definition) bool {
+	if condition == "" || condition == object.Name {
+		return true
+	}
+	def := p.schema.schema.Types[condition]
+	if def == nil || !def.IsAbstractType() {
+		return false
+	}
+	for _, possible := range p.schema.schema.GetPossibleTypes(def) {
+		if possible.Name == object.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *planner) possibleTypes(def *ast.Definition) []*ast.Definition {
+	if def.IsAbstractType() {
+		return p.schema.schema.GetPossibleTypes(def)
+	}
+	return []*ast.Definition{def}
+}
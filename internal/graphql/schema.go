@@ -0,0 +1,136 @@
+// Package graphql parses schemas written in the GraphQL schema definition
+// language, validates queries against them and plans their execution:
+// fragments are expanded, @skip and @include applied, variables coerced
+// and arguments resolved, leaving the runtime only to call resolvers and
+// complete their values. Introspection is answered here, from the schema.
+package graphql
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// maxDocuments bounds the validated queries a schema keeps
+const maxDocuments = 256
+
+// Schema is a parsed and validated schema
+type Schema struct {
+	schema *ast.Schema
+
+	mu        sync.Mutex
+	documents map[string]*ast.QueryDocument
+	types     map[string]*Type
+}
+
+// Parse reads a schema from sdl, named name in errors
+func Parse(name, sdl string) (*Schema, error) {
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: name, Input: sdl})
+	if err != nil {
+		return nil, err
+	}
+	if schema.Query == nil {
+		return nil, fmt.Errorf("%s: schema has no query type", name)
+	}
+	return &Schema{
+		schema:    schema,
+		documents: make(map[string]*ast.QueryDocument),
+		types:     make(map[string]*Type),
+	}, nil
+}
+
+// Definition returns the type named name, or nil
+func (s *Schema) Definition(name string) *ast.Definition {
+	return s.schema.Types[name]
+}
+
+// Validate parses query and checks it against the schema
+func (s *Schema) Validate(query string) gqlerror.List {
+	_, errs := s.document(query)
+	return errs
+}
+
+// document parses and validates query, or returns it as validated before
+func (s *Schema) document(query string) (*ast.QueryDocument, gqlerror.List) {
+	s.mu.Lock()
+	doc := s.documents[query]
+	s.mu.Unlock()
+	if doc != nil {
+		return doc, nil
+	}
+
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return nil, gqlerror.List{toError(err)}
+	}
+	if errs := validator.Validate(s.schema, doc); len(errs) > 0 {
+		return nil, errs
+	}
+
+	s.mu.Lock()
+	if len(s.documents) >= maxDocuments {
+		s.documents = make(map[string]*ast.QueryDocument)
+	}
+	s.documents[query] = doc
+	s.mu.Unlock()
+	return doc, nil
+}
+
+// Prepare validates query and plans its operation named operationName, or
+// its only operation when operationName is empty, with variables
+func (s *Schema) Prepare(query, operationName string, variables map[string]interface{}) (*Operation, gqlerror.List) {
+	doc, errs := s.document(query)
+	if errs != nil {
+		return nil, errs
+	}
+
+	var op *ast.OperationDefinition
+	switch {
+	case operationName != "":
+		op = doc.Operations.ForName(operationName)
+		if op == nil {
+			return nil, gqlerror.List{gqlerror.Errorf("Unknown operation named %q.", operationName)}
+		}
+	case len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	default:
+		return nil, gqlerror.List{gqlerror.Errorf("Must provide operation name if query contains multiple operations.")}
+	}
+
+	root := s.schema.Query
+	switch op.Operation {
+	case ast.Mutation:
+		root = s.schema.Mutation
+	case ast.Subscription:
+		return nil, gqlerror.List{gqlerror.Errorf("Subscriptions are not supported.")}
+	}
+	if root == nil {
+		return nil, gqlerror.List{gqlerror.Errorf("Schema is not configured for %ss.", op.Operation)}
+	}
+
+	vars, err := validator.VariableValues(s.schema, op, variables)
+	if err != nil {
+		return nil, gqlerror.List{toError(err)}
+	}
+
+	p := &planner{schema: s, vars: vars}
+	return &Operation{
+		Type:      string(op.Operation),
+		Name:      op.Name,
+		RootType:  root.Name,
+		Fields:    p.collect(root, []ast.SelectionSet{op.SelectionSet}),
+		Variables: vars,
+	}, nil
+}
+
+func toError(err error) *gqlerror.Error {
+	if gqlErr, ok := err.(*gqlerror.Error); ok {
+		return gqlErr
+	}
+	return gqlerror.Wrap(err)
+}
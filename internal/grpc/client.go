@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ClientOptions configures a Client. The zero value connects over
+// plaintext HTTP/2.
+type ClientOptions struct {
+	TLS            *tls.Config // connects over TLS with it when set
+	MaxMessageSize int         // of a response in bytes; 4MB by default
+}
+
+// Client calls the services of one server. It connects when first used
+// and reconnects as needed.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient creates a client of target, such as "localhost:50051" or
+// "dns:///api.example.com:443"; opts may be nil
+func NewClient(target string, opts *ClientOptions) (*Client, error) {
+	creds := insecure.NewCredentials()
+	var callOpts []grpc.CallOption
+	if opts != nil {
+		if opts.TLS != nil {
+			creds = credentials.NewTLS(opts.TLS)
+		}
+		if opts.MaxMessageSize > 0 {
+			callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(opts.MaxMessageSize))
+		}
+	}
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(callOpts...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn}, nil
+}
+
+// Close closes the connection, cancelling the calls in progress
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Call makes a unary call of method with request, see ToMessage, and
+// returns the response. md is sent as request metadata.
+func (c *Client) Call(ctx context.Context, method protoreflect.MethodDescriptor, request interface{}, md map[string]string) (map[string]interface{}, error) {
+	req, err := ToMessage(method.Input(), request)
+	if err != nil {
+		return nil, err
+	}
+	res := dynamicpb.NewMessage(method.Output())
+	if err := c.conn.Invoke(outgoing(ctx, md), fullMethod(method), req, res); err != nil {
+		return nil, err
+	}
+	return FromMessage(res), nil
+}
+
+// ClientStream is a streaming call. Requests are sent with Send and,
+// when the client streams, ended with CloseSend; responses are read with
+// Recv until it returns io.EOF.
+type ClientStream struct {
+	Method protoreflect.MethodDescriptor
+
+	opened chan struct{}
+	stream grpc.ClientStream
+	err    error
+	out    *outbox
+}
+
+// Stream starts a streaming call of method. md is sent as request
+// metadata. The call is cancelled with ctx. It returns at once: the call
+// is started in the background, and a failure to start it is returned by
+// the methods of the stream.
+func (c *Client) Stream(ctx context.Context, method protoreflect.MethodDescriptor, md map[string]string) *ClientStream {
+	desc := &grpc.StreamDesc{
+		StreamName:    string(method.Name()),
+		ServerStreams: method.IsStreamingServer(),
+		ClientStreams: method.IsStreamingClient(),
+	}
+	s := &ClientStream{Method: method, opened: make(chan struct{})}
+	s.out = newOutbox(method.Input(), func(m interface{}) error {
+		if err := s.open(); err != nil {
+			return err
+		}
+		return s.stream.SendMsg(m)
+	})
+	go func() {
+		defer close(s.opened)
+		s.stream, s.err = c.conn.NewStream(outgoing(ctx, md), desc, fullMethod(method))
+	}()
+	return s
+}
+
+// open waits for the call to start
+func (s *ClientStream) open() error {
+	<-s.opened
+	return s.err
+}
+
+// Send converts value to a request and queues it to be sent without
+// waiting for it; a failure sending an earlier request is returned by the
+// next call
+func (s *ClientStream) Send(value interface{}) error {
+	return s.out.push(value)
+}
+
+// CloseSend ends the requests once those queued are sent
+func (s *ClientStream) CloseSend() error {
+	if err := s.out.flush(); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	return s.stream.CloseSend()
+}
+
+// Recv returns the next response, or io.EOF after the last one
+func (s *ClientStream) Recv() (map[string]interface{}, error) {
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(s.Method.Output())
+	if err := s.stream.RecvMsg(msg); err != nil {
+		return nil, err
+	}
+	return FromMessage(msg), nil
+}
+
+// Header returns the response metadata, waiting for it to arrive
+func (s *ClientStream) Header() (map[string][]string, error) {
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s.stream.Header()
+}
+
+func fullMethod(method protoreflect.MethodDescriptor) string {
+	return "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+}
+
+func outgoing(ctx context.Context, md map[string]string) context.Context {
+	if len(md) == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.New(md))
+}
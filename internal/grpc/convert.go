@@ -0,0 +1,313 @@
+package grpc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ToMessage builds a message of type desc from value, a map from field
+// names, either lowerCamelCase as in JSON or as declared, to:
+//
+//   - booleans, strings and numbers for scalar fields; 64-bit integers may
+//     also be decimal strings, as protobuf's JSON mapping writes them
+//   - []byte or a string for bytes fields
+//   - the name or number of an enum value
+//   - maps for message fields, []interface{} for repeated fields and maps
+//     with string keys for map fields
+//
+// nil values leave their field unset. Unknown fields and values of the
+// wrong type are errors. A message of type desc, converted already, is
+// returned as it is.
+func ToMessage(desc protoreflect.MessageDescriptor, value interface{}) (*dynamicpb.Message, error) {
+	if msg, ok := value.(*dynamicpb.Message); ok && msg.Descriptor() == desc {
+		return msg, nil
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if value == nil {
+		return msg, nil
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object, not %T", desc.FullName(), value)
+	}
+	if err := setFields(msg, fields); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func setFields(msg protoreflect.Message, fields map[string]interface{}) error {
+	desc := msg.Descriptor()
+	for name, value := range fields {
+		fd := desc.Fields().ByJSONName(name)
+		if fd == nil {
+			fd = desc.Fields().ByName(protoreflect.Name(name))
+		}
+		if fd == nil {
+			return fmt.Errorf("%s has no field %s", desc.FullName(), name)
+		}
+		if value == nil {
+			continue
+		}
+		if err := setField(msg, fd, value); err != nil {
+			return fmt.Errorf("%s: %w", fd.FullName(), err)
+		}
+	}
+	return nil
+}
+
+func setField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value interface{}) error {
+	switch {
+	case fd.IsList():
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected an array, got %T", value)
+		}
+		list := msg.Mutable(fd).List()
+		for _, item := range items {
+			v, err := singular(fd, item, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+	case fd.IsMap():
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected an object, got %T", value)
+		}
+		m := msg.Mutable(fd).Map()
+		for key, item := range entries {
+			k, err := mapKey(fd.MapKey(), key)
+			if err != nil {
+				return err
+			}
+			v, err := singular(fd.MapValue(), item, m.NewValue)
+			if err != nil {
+				return fmt.Errorf("[%s]: %w", key, err)
+			}
+			m.Set(k, v)
+		}
+	default:
+		v, err := singular(fd, value, func() protoreflect.Value { return msg.NewField(fd) })
+		if err != nil {
+			return err
+		}
+		msg.Set(fd, v)
+	}
+	return nil
+}
+
+// singular converts one value of a field, or of an element of a repeated
+// or map field; newMessage creates the message a message value is set on
+func singular(fd protoreflect.FieldDescriptor, value interface{}, newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if b, ok := value.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.StringKind:
+		if s, ok := value.(string); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+	case protoreflect.BytesKind:
+		switch v := value.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(v), nil
+		case string:
+			return protoreflect.ValueOfBytes([]byte(v)), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := toInt(value, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := toInt(value, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := toUint(value, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := toUint(value, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := toFloat(value)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := toFloat(value)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if name, ok := value.(string); ok {
+			enum := fd.Enum().Values().ByName(protoreflect.Name(name))
+			if enum == nil {
+				return protoreflect.Value{}, fmt.Errorf("%s has no value %s", fd.Enum().FullName(), name)
+			}
+			return protoreflect.ValueOfEnum(enum.Number()), nil
+		}
+		n, err := toInt(value, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		v := newMessage()
+		if err := setFields(v.Message(), fields); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return v, nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("expected %s, got %T", fd.Kind(), value)
+}
+
+func mapKey(fd protoreflect.FieldDescriptor, key string) (protoreflect.MapKey, error) {
+	var v interface{} = key
+	if fd.Kind() == protoreflect.BoolKind {
+		b, err := strconv.ParseBool(key)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("map key %q is not a bool", key)
+		}
+		v = b
+	}
+	value, err := singular(fd, v, nil)
+	if err != nil {
+		return protoreflect.MapKey{}, fmt.Errorf("map key %q: %w", key, err)
+	}
+	return value.MapKey(), nil
+}
+
+// toInt converts a whole number, or its decimal string, that fits in bits
+func toInt(value interface{}, bits int) (int64, error) {
+	var n int64
+	switch v := value.(type) {
+	case int:
+		n = int64(v)
+	case int32:
+		n = int64(v)
+	case int64:
+		n = v
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		n = int64(v)
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, bits)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer", v)
+		}
+		return parsed, nil
+	default:
+		return 0, fmt.Errorf("expected an integer, got %T", value)
+	}
+	if bits == 32 && (n < math.MinInt32 || n > math.MaxInt32) {
+		return 0, fmt.Errorf("%d overflows int32", n)
+	}
+	return n, nil
+}
+
+// toUint converts a non-negative whole number, or its decimal string, that
+// fits in bits
+func toUint(value interface{}, bits int) (uint64, error) {
+	if s, ok := value.(string); ok {
+		n, err := strconv.ParseUint(s, 10, bits)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an unsigned integer", s)
+		}
+		return n, nil
+	}
+	if f, ok := value.(float64); ok && f >= math.MaxInt64 && f == math.Trunc(f) && f < math.MaxUint64 && bits == 64 {
+		return uint64(f), nil
+	}
+	n, err := toInt(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || (bits == 32 && n > math.MaxUint32) {
+		return 0, fmt.Errorf("%d overflows uint%d", n, bits)
+	}
+	return uint64(n), nil
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("expected a number, got %T", value)
+}
+
+// FromMessage converts msg to a map from the lowerCamelCase names of its
+// fields to their values, the reverse of ToMessage. Every field is present,
+// with its default value when it is not set, except message fields and
+// members of a oneof that are not set. 64-bit integers are int64 and
+// uint64, enums the names of their values and bytes []byte.
+func FromMessage(msg protoreflect.Message) map[string]interface{} {
+	result := make(map[string]interface{})
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !msg.Has(fd) && (fd.ContainingOneof() != nil || (fd.Message() != nil && !fd.IsList() && !fd.IsMap())) {
+			continue
+		}
+		result[fd.JSONName()] = fromField(fd, msg.Get(fd))
+	}
+	return result
+}
+
+func fromField(fd protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := value.List()
+		items := make([]interface{}, list.Len())
+		for i := range items {
+			items[i] = fromSingular(fd, list.Get(i))
+		}
+		return items
+	case fd.IsMap():
+		entries := make(map[string]interface{}, value.Map().Len())
+		value.Map().Range(func(key protoreflect.MapKey, v protoreflect.Value) bool {
+			entries[key.String()] = fromSingular(fd.MapValue(), v)
+			return true
+		})
+		return entries
+	}
+	return fromSingular(fd, value)
+}
+
+func fromSingular(fd protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return value.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return value.Uint()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return value.Float()
+	case protoreflect.BytesKind:
+		return append([]byte(nil), value.Bytes()...)
+	case protoreflect.EnumKind:
+		if enum := fd.Enum().Values().ByNumber(value.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+		return int64(value.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return FromMessage(value.Message())
+	}
+	return value.Interface()
+}
@@ -0,0 +1,255 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const greeterProto = `
+syntax = "proto3";
+package hello;
+
+import "google/protobuf/timestamp.proto";
+
+enum Mood {
+  MOOD_UNSPECIFIED = 0;
+  HAPPY = 1;
+}
+
+message HelloRequest {
+  string name = 1;
+  repeated string tags = 2;
+  map<string, int64> counts = 3;
+  Mood mood = 4;
+  bytes payload = 5;
+  google.protobuf.Timestamp sent = 6;
+}
+
+message HelloReply {
+  string message = 1;
+  int64 total = 2;
+}
+
+service Greeter {
+  rpc SayHello (HelloRequest) returns (HelloReply);
+  rpc Countdown (HelloRequest) returns (stream HelloReply);
+  rpc Collect (stream HelloRequest) returns (HelloReply);
+  rpc Chat (stream HelloRequest) returns (stream HelloReply);
+}
+`
+
+func loadGreeter(t *testing.T) *Schema {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "greeter.proto")
+	if err := os.WriteFile(path, []byte(greeterProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := Load([]string{path}, nil)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	return schema
+}
+
+func TestMessageConversion(t *testing.T) {
+	schema := loadGreeter(t)
+	desc, err := schema.Message("hello.HelloRequest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := ToMessage(desc, map[string]interface{}{
+		"name":    "gode",
+		"tags":    []interface{}{"a", "b"},
+		"counts":  map[string]interface{}{"x": int64(1), "y": "9007199254740993"},
+		"mood":    "HAPPY",
+		"payload": []byte{1, 2},
+		"sent":    map[string]interface{}{"seconds": float64(60)},
+	})
+	if err != nil {
+		t.Fatalf("ToMessage() failed: %v", err)
+	}
+	got := FromMessage(msg)
+	want := map[string]interface{}{
+		"name":    "gode",
+		"tags":    []interface{}{"a", "b"},
+		"counts":  map[string]interface{}{"x": int64(1), "y": int64(9007199254740993)},
+		"mood":    "HAPPY",
+		"payload": []byte{1, 2},
+		"sent":    map[string]interface{}{"seconds": int64(60), "nanos": int64(0)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FromMessage() =\n%v\nwant\n%v", got, want)
+	}
+
+	// Defaults are filled in, unset messages are left out
+	empty, _ := ToMessage(desc, nil)
+	if got := FromMessage(empty); len(got) != 5 || got["name"] != "" || got["mood"] != "MOOD_UNSPECIFIED" {
+		t.Errorf("FromMessage(empty) = %v", got)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"nope": 1},
+		{"name": 1},
+		{"mood": "SAD"},
+		{"counts": map[string]interface{}{"x": 1.5}},
+	} {
+		if _, err := ToMessage(desc, bad); err == nil {
+			t.Errorf("ToMessage(%v) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestServerAndClient(t *testing.T) {
+	schema := loadGreeter(t)
+	service, err := schema.Service("Greeter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := service.Methods()
+
+	server := NewServer(nil)
+	err = server.Handle(service, map[string]Handler{
+		"SayHello": func(call *ServerCall) error {
+			req, err := call.Recv()
+			if err != nil {
+				return err
+			}
+			if req["name"] == "" {
+				return Error(Codes["INVALID_ARGUMENT"], "name is required")
+			}
+			return call.Send(map[string]interface{}{"message": "hello " + req["name"].(string) + " " + strings.Join(call.Metadata["x-mood"], "")})
+		},
+		"Countdown": func(call *ServerCall) error {
+			if _, err := call.Recv(); err != nil {
+				return err
+			}
+			for i := 3; i > 0; i-- {
+				call.Send(map[string]interface{}{"total": i})
+			}
+			return nil
+		},
+		"Collect": func(call *ServerCall) error {
+			var names []string
+			for {
+				req, err := call.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				names = append(names, req["name"].(string))
+			}
+			return call.Send(map[string]interface{}{"message": strings.Join(names, ","), "total": len(names)})
+		},
+	})
+	if err != nil {
+		t.Fatalf("Handle() failed: %v", err)
+	}
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	defer server.Shutdown(time.Second)
+
+	client, err := NewClient(server.Address(), nil)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.Call(ctx, methods.ByName("SayHello"), map[string]interface{}{"name": "gode"}, map[string]string{"x-mood": "happy"})
+	if err != nil || res["message"] != "hello gode happy" {
+		t.Errorf("SayHello = %v, %v", res, err)
+	}
+	_, err = client.Call(ctx, methods.ByName("SayHello"), nil, nil)
+	if code, message := StatusOf(err); code != Codes["INVALID_ARGUMENT"] || message != "name is required" {
+		t.Errorf("SayHello({}) status = %d %q", code, message)
+	}
+
+	stream := client.Stream(ctx, methods.ByName("Countdown"), nil)
+	stream.Send(map[string]interface{}{"name": "x"})
+	stream.CloseSend()
+	var totals []int64
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Countdown Recv() failed: %v", err)
+		}
+		totals = append(totals, res["total"].(int64))
+	}
+	if !reflect.DeepEqual(totals, []int64{3, 2, 1}) {
+		t.Errorf("Countdown = %v", totals)
+	}
+
+	stream = client.Stream(ctx, methods.ByName("Collect"), nil)
+	for _, name := range []string{"a", "b", "c"} {
+		stream.Send(map[string]interface{}{"name": name})
+	}
+	stream.CloseSend()
+	if res, err := stream.Recv(); err != nil || res["message"] != "a,b,c" || res["total"] != int64(3) {
+		t.Errorf("Collect = %v, %v", res, err)
+	}
+
+	// Methods without a handler are unimplemented
+	stream = client.Stream(ctx, methods.ByName("Chat"), nil)
+	stream.CloseSend()
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected Chat to fail")
+	} else if code, _ := StatusOf(err); code != Codes["UNIMPLEMENTED"] {
+		t.Errorf("Chat status = %d, want UNIMPLEMENTED", code)
+	}
+
+	if err := server.Handle(service, nil); err == nil {
+		t.Error("Expected Handle() after Listen() to fail")
+	}
+}
+
+func TestLoadDescriptorSet(t *testing.T) {
+	schema := loadGreeter(t)
+	service, _ := schema.Service("hello.Greeter")
+
+	// What protoc --descriptor_set_out --include_imports writes
+	set := &descriptorpb.FileDescriptorSet{}
+	file := service.ParentFile()
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(imports.Get(i).FileDescriptor))
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "greeter.pb")
+	os.WriteFile(path, data, 0644)
+
+	loaded, err := Load([]string{path}, nil)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.Services(); !reflect.DeepEqual(got, []string{"hello.Greeter"}) {
+		t.Errorf("Services() = %v", got)
+	}
+	if _, err := loaded.Message("hello.HelloReply"); err != nil {
+		t.Errorf("Message() failed: %v", err)
+	}
+	if _, err := loaded.Service("Missing"); err == nil {
+		t.Error("Expected an unknown service to fail")
+	}
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// outbox sends the messages of one side of a stream, in the order they
+// are queued, from a goroutine of its own, so that whoever queues them
+// never waits on flow control
+type outbox struct {
+	desc protoreflect.MessageDescriptor
+	send func(m interface{}) error
+
+	mu      sync.Mutex
+	queue   []proto.Message
+	sending bool
+	err     error
+	idle    *sync.Cond
+}
+
+func newOutbox(desc protoreflect.MessageDescriptor, send func(m interface{}) error) *outbox {
+	o := &outbox{desc: desc, send: send}
+	o.idle = sync.NewCond(&o.mu)
+	return o
+}
+
+// push converts value to a message and queues it. Conversion errors are
+// returned at once; an error from sending an earlier message is returned
+// instead of queuing more.
+func (o *outbox) push(value interface{}) error {
+	msg, err := ToMessage(o.desc, value)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err != nil {
+		return o.err
+	}
+	o.queue = append(o.queue, msg)
+	if !o.sending {
+		o.sending = true
+		go o.drain()
+	}
+	return nil
+}
+
+func (o *outbox) drain() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for len(o.queue) > 0 && o.err == nil {
+		msg := o.queue[0]
+		o.queue = o.queue[1:]
+		o.mu.Unlock()
+		err := o.send(msg)
+		o.mu.Lock()
+		if err != nil {
+			o.err = err
+		}
+	}
+	o.queue = nil
+	o.sending = false
+	o.idle.Broadcast()
+}
+
+// flush waits until everything queued is sent, and returns the first
+// error sending it
+func (o *outbox) flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for o.sending {
+		o.idle.Wait()
+	}
+	return o.err
+}
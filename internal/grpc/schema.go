@@ -0,0 +1,174 @@
+// Package grpc serves and calls gRPC services described by .proto files or
+// compiled descriptor sets, without generated code. Messages are dynamic
+// and converted to and from plain Go values (maps, slices, strings,
+// numbers, booleans and byte slices), which the runtime maps to JS.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// resolver finds the descriptors of one load
+type resolver interface {
+	FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+// Schema holds the services and messages of loaded .proto files and
+// descriptor sets
+type Schema struct {
+	resolvers []resolver
+	services  []protoreflect.ServiceDescriptor
+}
+
+// Load reads .proto files, which are compiled along with what they import,
+// and descriptor sets written by protoc --descriptor_set_out (any other
+// extension, such as .pb or .protoset). Imports are looked up in
+// includeDirs, then in the directory of the importing file; the standard
+// google/protobuf files are always available.
+func Load(paths []string, includeDirs []string) (*Schema, error) {
+	schema := &Schema{}
+	var sources []string
+	for _, path := range paths {
+		if strings.EqualFold(filepath.Ext(path), ".proto") {
+			sources = append(sources, path)
+			continue
+		}
+		if err := schema.loadDescriptorSet(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(sources) > 0 {
+		if err := schema.compile(sources, includeDirs); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// compile parses .proto files. Each is named relative to the include
+// directory it is in, as its imports would name it, or else by its base
+// name with its own directory searched.
+func (s *Schema) compile(paths []string, includeDirs []string) error {
+	importPaths := append([]string(nil), includeDirs...)
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		for _, dir := range includeDirs {
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(dir, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				names[i] = filepath.ToSlash(rel)
+				break
+			}
+		}
+		if names[i] == "" {
+			names[i] = filepath.Base(abs)
+			importPaths = append(importPaths, filepath.Dir(abs))
+		}
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	files, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return err
+	}
+	s.resolvers = append(s.resolvers, files.AsResolver())
+	for _, file := range files {
+		s.addServices(file)
+	}
+	return nil
+}
+
+// loadDescriptorSet reads a serialized google.protobuf.FileDescriptorSet,
+// which must include the files its files import
+func (s *Schema) loadDescriptorSet(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("%s is not a descriptor set: %w", path, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.resolvers = append(s.resolvers, files)
+	files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		s.addServices(file)
+		return true
+	})
+	return nil
+}
+
+func (s *Schema) addServices(file protoreflect.FileDescriptor) {
+	services := file.Services()
+	for i := 0; i < services.Len(); i++ {
+		s.services = append(s.services, services.Get(i))
+	}
+}
+
+// Services returns the full names of the services, sorted
+func (s *Schema) Services() []string {
+	names := make([]string, len(s.services))
+	for i, service := range s.services {
+		names[i] = string(service.FullName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Service returns the service with the full name, such as
+// "helloworld.Greeter". A name without a package matches the only service
+// of that name.
+func (s *Schema) Service(name string) (protoreflect.ServiceDescriptor, error) {
+	var found protoreflect.ServiceDescriptor
+	for _, service := range s.services {
+		switch {
+		case string(service.FullName()) == name:
+			return service, nil
+		case string(service.Name()) == name:
+			if found != nil {
+				return nil, fmt.Errorf("service %s is ambiguous: %s or %s", name, found.FullName(), service.FullName())
+			}
+			found = service
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("service %s is not defined", name)
+	}
+	return found, nil
+}
+
+// Message returns the message type with the full name
+func (s *Schema) Message(name string) (protoreflect.MessageDescriptor, error) {
+	for _, r := range s.resolvers {
+		desc, err := r.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			continue
+		}
+		if message, ok := desc.(protoreflect.MessageDescriptor); ok {
+			return message, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s is not defined", name)
+}
@@ -0,0 +1,196 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Handler serves the calls of one method, returning once the call is
+// done. Errors made with Error carry their status code; others are
+// reported to the client as UNKNOWN with their message.
+type Handler func(call *ServerCall) error
+
+// ServerCall is a call being served. Unary and server streaming calls
+// receive one request; the client streaming ones receive requests until
+// Recv returns io.EOF. Responses are sent with Send, once for unary and
+// client streaming calls.
+type ServerCall struct {
+	Method   protoreflect.MethodDescriptor
+	Metadata map[string][]string
+
+	stream grpc.ServerStream
+	out    *outbox
+}
+
+// Context is cancelled when the call ends, or the client cancels it or
+// its deadline passes
+func (c *ServerCall) Context() context.Context {
+	return c.stream.Context()
+}
+
+// Recv returns the next request, or io.EOF after the last one
+func (c *ServerCall) Recv() (map[string]interface{}, error) {
+	msg := dynamicpb.NewMessage(c.Method.Input())
+	if err := c.stream.RecvMsg(msg); err != nil {
+		return nil, err
+	}
+	return FromMessage(msg), nil
+}
+
+// Send converts value to a response, see ToMessage, and queues it to be
+// sent without waiting for it; a failure sending an earlier response is
+// returned by the next call. Everything sent is flushed before the call
+// ends.
+func (c *ServerCall) Send(value interface{}) error {
+	return c.out.push(value)
+}
+
+// ServerOptions configures a Server. The zero value serves plaintext HTTP/2.
+type ServerOptions struct {
+	TLS            *tls.Config // serves over TLS with it when set
+	MaxMessageSize int         // of a request in bytes; 4MB by default
+}
+
+// Server serves the services given to Handle once it listens
+type Server struct {
+	server *grpc.Server
+
+	mu       sync.Mutex
+	listener net.Listener
+	done     chan struct{}
+}
+
+// NewServer creates a server, see ServerOptions; opts may be nil
+func NewServer(opts *ServerOptions) *Server {
+	var serverOpts []grpc.ServerOption
+	if opts != nil {
+		if opts.TLS != nil {
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(opts.TLS)))
+		}
+		if opts.MaxMessageSize > 0 {
+			serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(opts.MaxMessageSize))
+		}
+	}
+	return &Server{server: grpc.NewServer(serverOpts...)}
+}
+
+// Handle serves the methods of service with handlers, keyed by method
+// name. Methods without a handler are answered with UNIMPLEMENTED. It must
+// be called before Listen.
+func (s *Server) Handle(service protoreflect.ServiceDescriptor, handlers map[string]Handler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return errors.New("services must be added before the server listens")
+	}
+
+	desc := &grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*interface{})(nil),
+		Metadata:    service.ParentFile().Path(),
+	}
+	methods := service.Methods()
+	for name, handler := range handlers {
+		method := methods.ByName(protoreflect.Name(name))
+		if method == nil {
+			return fmt.Errorf("%s has no method %s", service.FullName(), name)
+		}
+		handler := handler
+		// Every method is served as a stream, which unary calls are on the
+		// wire too
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    name,
+			ServerStreams: method.IsStreamingServer(),
+			ClientStreams: method.IsStreamingClient(),
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				md, _ := metadata.FromIncomingContext(stream.Context())
+				call := &ServerCall{
+					Method:   method,
+					Metadata: md,
+					stream:   stream,
+					out:      newOutbox(method.Output(), stream.SendMsg),
+				}
+				err := handler(call)
+				if flushErr := call.out.flush(); err == nil {
+					err = flushErr
+				}
+				return err
+			},
+		})
+	}
+	s.server.RegisterService(desc, struct{}{})
+	return nil
+}
+
+// Listen starts serving on addr, such as ":50051" or "127.0.0.1:0" for a
+// free port
+func (s *Server) Listen(addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return errors.New("server is already listening")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.server.Serve(listener)
+	}()
+	return nil
+}
+
+// Address returns the address the server listens on
+func (s *Server) Address() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Shutdown stops accepting calls and waits up to timeout for the ones in
+// progress, then cancels those left
+func (s *Server) Shutdown(timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		s.server.Stop()
+	}
+	s.wait()
+}
+
+// Close stops the server at once, cancelling the calls in progress
+func (s *Server) Close() {
+	s.server.Stop()
+	s.wait()
+}
+
+func (s *Server) wait() {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
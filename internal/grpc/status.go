@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Codes are the gRPC status codes by name
+var Codes = map[string]int{
+	"OK":                  0,
+	"CANCELLED":           1,
+	"UNKNOWN":             2,
+	"INVALID_ARGUMENT":    3,
+	"DEADLINE_EXCEEDED":   4,
+	"NOT_FOUND":           5,
+	"ALREADY_EXISTS":      6,
+	"PERMISSION_DENIED":   7,
+	"RESOURCE_EXHAUSTED":  8,
+	"FAILED_PRECONDITION": 9,
+	"ABORTED":             10,
+	"OUT_OF_RANGE":        11,
+	"UNIMPLEMENTED":       12,
+	"INTERNAL":            13,
+	"UNAVAILABLE":         14,
+	"DATA_LOSS":           15,
+	"UNAUTHENTICATED":     16,
+}
+
+// Error returns an error that a Handler reports with the status code,
+// one of Codes
+func Error(code int, message string) error {
+	return status.Error(codes.Code(code), message)
+}
+
+// StatusOf returns the status code and message of an error of a call.
+// Errors without a status, which the call did not get to make, are
+// UNKNOWN.
+func StatusOf(err error) (code int, message string) {
+	s := status.Convert(err)
+	return int(s.Code()), s.Message()
+}
+
+// CodeName returns the name of a status code, such as "NOT_FOUND"
+func CodeName(code int) string {
+	for name, c := range Codes {
+		if c == code {
+			return name
+		}
+	}
+	return "UNKNOWN"
+}
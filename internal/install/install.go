@@ -0,0 +1,497 @@
+// Package install installs the npm dependencies of a project into its
+// node_modules and pins them in gode.lock. Versions are resolved against
+// the registries of the project config, placed at the top of node_modules
+// unless another version is already there, as npm does, and fetched through
+// the npm package cache. With a lockfile, locked versions are preferred, so
+// that an install with an unchanged package.json installs the same tree.
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rizqme/gode/internal/diskcache"
+	"github.com/rizqme/gode/internal/download"
+	"github.com/rizqme/gode/internal/proxy"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// Options configures an install
+type Options struct {
+	// Frozen refuses to install when the lockfile is missing or the
+	// install would change it
+	Frozen bool
+	// Client fetches package metadata; it defaults to one that honours
+	// the proxy
+	Client     *http.Client
+	Downloader *download.Downloader // Defaults to download.New with Client
+	Out        io.Writer            // Where added and removed packages are listed; optional
+}
+
+// Result is what an install did
+type Result struct {
+	Lockfile *Lockfile
+	Added    []string // Keys of the packages extracted
+	Removed  []string // Keys of the packages no longer needed
+}
+
+// installer holds the state of one install
+type installer struct {
+	cfg        *config.PackageJSON
+	root       string
+	cache      *diskcache.Cache
+	opts       Options
+	registries map[string]*Registry // by URL
+	old        *Lockfile
+	lock       *Lockfile
+}
+
+// dependency is a package a dependent asks for
+type dependency struct {
+	parent string // key of the dependent; "" for the project
+	name   string // name it is installed under
+	pkg    string // name of the package in the registry
+	spec   string // range or dist-tag
+	from   *Registry
+}
+
+// Install installs the dependencies and devDependencies of the project
+// cfg describes and writes its lockfile
+func Install(ctx context.Context, cfg *config.PackageJSON, cache *diskcache.Cache, opts Options) (*Result, error) {
+	if opts.Client == nil {
+		opts.Client = proxy.Client(0)
+	}
+	if opts.Downloader == nil {
+		opts.Downloader = download.New(download.Options{Client: opts.Client})
+	}
+	in := &installer{
+		cfg:        cfg,
+		root:       cfg.ProjectRoot,
+		cache:      cache,
+		opts:       opts,
+		registries: make(map[string]*Registry),
+		lock:       &Lockfile{LockfileVersion: lockfileVersion, Packages: make(map[string]LockedPackage)},
+	}
+
+	old, err := ReadLockfile(in.root)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if opts.Frozen {
+			return nil, fmt.Errorf("--frozen needs a %s; run gode install first", LockfileName)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		in.old = old
+	}
+
+	if err := in.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if opts.Frozen {
+		if diff := in.old.Diff(in.lock); len(diff) > 0 {
+			return nil, fmt.Errorf("%s is out of date with package.json; run gode install without --frozen:\n  %s", LockfileName, strings.Join(diff, "\n  "))
+		}
+	}
+
+	result := &Result{Lockfile: in.lock}
+	if err := in.fetch(ctx); err != nil {
+		return nil, err
+	}
+	if result.Added, err = in.extract(); err != nil {
+		return nil, err
+	}
+	if result.Removed, err = in.prune(); err != nil {
+		return nil, err
+	}
+	if !opts.Frozen {
+		if err := in.lock.Write(in.root); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// registry returns the client of the registry at url
+func (in *installer) registry(url string) *Registry {
+	if r, ok := in.registries[url]; ok {
+		return r
+	}
+	r := NewRegistry(url, in.opts.Client)
+	in.registries[url] = r
+	return r
+}
+
+// parseSpec splits what package.json says of a dependency into the
+// registry, package name and range. Aliases ("npm:other@^1") install
+// another package under the dependency's name, and a spec prefixed with
+// the name of a registry from gode.registries comes from that registry.
+// ok is false for dependencies gode install leaves alone, such as file:
+// paths.
+func (in *installer) parseSpec(name, spec string, from *Registry) (registry *Registry, pkg, rng string, ok bool) {
+	registry, pkg, rng = from, name, spec
+	if prefix, rest, found := strings.Cut(spec, ":"); found {
+		url, known := in.cfg.Gode.Registries[prefix]
+		if !known {
+			return nil, "", "", false
+		}
+		registry, rng = in.registry(url), rest
+		// The version may name the package too, making it an alias
+		if at := strings.LastIndex(rest, "@"); at > 0 {
+			pkg, rng = rest[:at], rest[at+1:]
+		} else if rest != "" && !strings.ContainsAny(rest[:1], "0123456789^~<>=*xX ") {
+			pkg, rng = rest, ""
+		}
+	}
+	return registry, pkg, strings.TrimSpace(rng), true
+}
+
+// resolve works out the tree of packages breadth first, the project's own
+// dependencies first, each level in name order
+func (in *installer) resolve(ctx context.Context) error {
+	npm := in.cfg.Gode.Registries["npm"]
+	if npm == "" {
+		npm = "https://registry.npmjs.org/"
+	}
+	defaultRegistry := in.registry(npm)
+
+	var queue []dependency
+	seen := make(map[string]bool)
+	for _, deps := range []map[string]string{in.cfg.Dependencies, in.cfg.DevDependencies} {
+		for _, name := range sortedKeys(deps) {
+			if seen[name] {
+				continue // dependencies win over devDependencies
+			}
+			seen[name] = true
+			registry, pkg, rng, ok := in.parseSpec(name, deps[name], defaultRegistry)
+			if ok {
+				queue = append(queue, dependency{name: name, pkg: pkg, spec: rng, from: registry})
+			}
+		}
+	}
+
+	for len(queue) > 0 {
+		dep := queue[0]
+		queue = queue[1:]
+
+		key, found := in.visible(dep.parent, dep.name)
+		if found {
+			locked := in.lock.Packages[key]
+			if sameName(locked, dep.name, dep.pkg) && satisfies(locked.Version, dep.spec) {
+				continue
+			}
+			// Another version is in the way; this one goes under its dependent
+			key = path.Join(dep.parent, "node_modules", dep.name)
+		}
+
+		locked, err := in.pick(ctx, key, dep)
+		if err != nil {
+			if dep.parent != "" {
+				return fmt.Errorf("%s (needed by %s): %w", dep.name, dep.parent, err)
+			}
+			return err
+		}
+		in.lock.Packages[key] = locked
+
+		for _, name := range sortedKeys(locked.Dependencies) {
+			registry, pkg, rng, ok := in.parseSpec(name, locked.Dependencies[name], dep.from)
+			if !ok {
+				return fmt.Errorf("%s depends on %s@%s, which gode install does not support", key, name, locked.Dependencies[name])
+			}
+			queue = append(queue, dependency{parent: key, name: name, pkg: pkg, spec: rng, from: registry})
+		}
+	}
+	return nil
+}
+
+// visible returns the key of the package name that code in parent
+// requires: the nearest one in the node_modules of parent or above it.
+// When there is none, the key returned is the top-level one, where a new
+// package goes.
+func (in *installer) visible(parent, name string) (string, bool) {
+	dir := parent
+	for {
+		key := path.Join(dir, "node_modules", name)
+		if _, ok := in.lock.Packages[key]; ok {
+			return key, true
+		}
+		if dir == "" {
+			return key, false
+		}
+		// node_modules/a/node_modules/b -> node_modules/a
+		i := strings.LastIndex(dir, "/node_modules/")
+		if i < 0 {
+			dir = ""
+		} else {
+			dir = dir[:i]
+		}
+	}
+}
+
+// pick chooses the version of dep installed at key: the one the old
+// lockfile has there, or another it has of the same package, when they
+// are in range, or else the highest one the registry has
+func (in *installer) pick(ctx context.Context, key string, dep dependency) (LockedPackage, error) {
+	if in.old != nil {
+		var best *LockedPackage
+		var bestVersion Version
+		for _, oldKey := range sortedKeys(in.old.Packages) {
+			locked := in.old.Packages[oldKey]
+			if !sameName(locked, dep.name, dep.pkg) || !satisfies(locked.Version, dep.spec) {
+				continue
+			}
+			v, _ := ParseVersion(locked.Version)
+			if oldKey == key {
+				best = &locked
+				break
+			}
+			if best == nil || v.Compare(bestVersion) > 0 {
+				best, bestVersion = &locked, v
+			}
+		}
+		if best != nil {
+			locked := *best
+			if old, ok := in.old.Packages[key]; !ok || old.Integrity != locked.Integrity || old.Resolved != locked.Resolved {
+				locked.Contents = ""
+			}
+			return locked, nil
+		}
+	}
+
+	m, err := dep.from.Resolve(ctx, dep.pkg, dep.spec)
+	if err != nil {
+		return LockedPackage{}, err
+	}
+	if m.Dist.Tarball == "" {
+		return LockedPackage{}, fmt.Errorf("%s@%s has no tarball", dep.pkg, m.Version)
+	}
+	locked := LockedPackage{
+		Version:      m.Version,
+		Resolved:     m.Dist.Tarball,
+		Integrity:    m.Dist.Integrity,
+		Dependencies: m.Dependencies,
+	}
+	if dep.pkg != dep.name {
+		locked.Name = dep.pkg
+	}
+	return locked, nil
+}
+
+// tarball returns the name of the cache entry of a locked package
+func tarball(key string, locked LockedPackage) string {
+	name := locked.Name
+	if name == "" {
+		name = key[strings.LastIndex(key, "node_modules/")+len("node_modules/"):]
+	}
+	return strings.ReplaceAll(name, "/", "+") + "@" + locked.Version + ".tgz"
+}
+
+// fetch downloads the tarballs the cache doesn't have
+func (in *installer) fetch(ctx context.Context) error {
+	var items []download.Item
+	queued := make(map[string]bool)
+	for _, key := range sortedKeys(in.lock.Packages) {
+		locked := in.lock.Packages[key]
+		name := tarball(key, locked)
+		dest := in.cache.Path(diskcache.NPM, name)
+		if queued[dest] {
+			continue
+		}
+		queued[dest] = true
+		if _, err := os.Stat(dest); err == nil {
+			if locked.Integrity == "" || download.VerifyFile(dest, locked.Integrity) == nil {
+				in.cache.Touch(diskcache.NPM, name)
+				continue
+			}
+			os.Remove(dest) // Corrupted in the cache; fetch it again
+		}
+		items = append(items, download.Item{URL: locked.Resolved, Dest: dest, Integrity: locked.Integrity})
+	}
+	if err := in.opts.Downloader.Download(ctx, items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		name := filepath.Base(item.Dest)
+		if err := in.cache.Store(diskcache.NPM, name, diskcache.Meta{Origins: []string{item.URL}, Integrity: item.Integrity}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extract unpacks each package whose directory doesn't hold what the
+// lockfile says, and records the contents hash of those that are new
+func (in *installer) extract() ([]string, error) {
+	var added []string
+	// Keys sort before the keys nested in them, so a package is unpacked
+	// before the packages in its node_modules
+	for _, key := range sortedKeys(in.lock.Packages) {
+		locked := in.lock.Packages[key]
+		dir := filepath.Join(in.root, filepath.FromSlash(key))
+		if locked.Contents != "" {
+			if got, err := ContentsIntegrity(dir); err == nil && got == locked.Contents {
+				continue
+			}
+		}
+
+		if err := clearPackage(dir); err != nil {
+			return added, err
+		}
+		if err := extractTarball(in.cache.Path(diskcache.NPM, tarball(key, locked)), dir); err != nil {
+			return added, fmt.Errorf("extracting %s: %w", key, err)
+		}
+		contents, err := ContentsIntegrity(dir)
+		if err != nil {
+			return added, err
+		}
+		if in.opts.Frozen && locked.Contents != "" && contents != locked.Contents {
+			return added, fmt.Errorf("%s@%s does not match the contents hash in %s", key, locked.Version, LockfileName)
+		}
+		locked.Contents = contents
+		in.lock.Packages[key] = locked
+		added = append(added, key)
+		if in.opts.Out != nil {
+			fmt.Fprintf(in.opts.Out, "+ %s@%s\n", strings.TrimPrefix(key, "node_modules/"), locked.Version)
+		}
+	}
+	return added, nil
+}
+
+// prune removes the packages of the old lockfile that are no longer needed
+func (in *installer) prune() ([]string, error) {
+	if in.old == nil {
+		return nil, nil
+	}
+	var removed []string
+	for _, key := range sortedKeys(in.old.Packages) {
+		if _, ok := in.lock.Packages[key]; ok {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(in.root, filepath.FromSlash(key))); err != nil {
+			return removed, err
+		}
+		removed = append(removed, key)
+		if in.opts.Out != nil {
+			fmt.Fprintf(in.opts.Out, "- %s@%s\n", strings.TrimPrefix(key, "node_modules/"), in.old.Packages[key].Version)
+		}
+	}
+	return removed, nil
+}
+
+// clearPackage empties a package directory, keeping its node_modules
+func clearPackage(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == "node_modules" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTarball unpacks an npm tarball into dir, dropping the directory
+// its files are packed under (package/ by convention)
+func extractTarball(file, dir string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, name, _ := strings.Cut(path.Clean(strings.TrimPrefix(header.Name, "/")), "/")
+		if name == "" || name == ".." || strings.HasPrefix(name, "../") {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			mode := os.FileMode(0644)
+			if header.Mode&0111 != 0 {
+				mode = 0755
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sameName reports whether locked is the package pkg installed as name
+func sameName(locked LockedPackage, name, pkg string) bool {
+	if locked.Name == "" {
+		return name == pkg
+	}
+	return locked.Name == pkg
+}
+
+// satisfies reports whether version is in spec. A dist-tag is only known
+// to the registry, so it is never satisfied by what is already installed.
+func satisfies(version, spec string) bool {
+	if spec == "" {
+		spec = "*"
+	}
+	rng, err := ParseRange(spec)
+	if err != nil {
+		return false
+	}
+	v, err := ParseVersion(version)
+	return err == nil && rng.Satisfies(v)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,212 @@
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rizqme/gode/internal/diskcache"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// tgz packs files under package/, as npm pack does
+func tgz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: "package/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// fakeRegistry serves packuments at /<name> and tarballs at /-/<name>-<version>.tgz
+type fakeRegistry struct {
+	server     *httptest.Server
+	packuments map[string]*Packument
+	tarballs   map[string][]byte
+	requests   atomic.Int32
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{packuments: make(map[string]*Packument), tarballs: make(map[string][]byte)}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.requests.Add(1)
+		path := strings.TrimPrefix(req.URL.Path, "/")
+		if data, ok := r.tarballs[path]; ok {
+			w.Write(data)
+			return
+		}
+		if packument, ok := r.packuments[path]; ok {
+			json.NewEncoder(w).Encode(packument)
+			return
+		}
+		http.NotFound(w, req)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *fakeRegistry) publish(t *testing.T, name, version string, deps map[string]string) {
+	data := tgz(t, map[string]string{
+		"package.json": `{"name":"` + name + `","version":"` + version + `"}`,
+		"index.js":     "module.exports = '" + name + "@" + version + "';",
+	})
+	file := "-/" + name + "-" + version + ".tgz"
+	r.tarballs[file] = data
+	sum := sha512.Sum512(data)
+
+	packument := r.packuments[name]
+	if packument == nil {
+		packument = &Packument{Name: name, DistTags: map[string]string{}, Versions: map[string]PackageManifest{}}
+		r.packuments[name] = packument
+	}
+	m := PackageManifest{Name: name, Version: version, Dependencies: deps}
+	m.Dist.Tarball = r.server.URL + "/" + file
+	m.Dist.Integrity = "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+	packument.Versions[version] = m
+	packument.DistTags["latest"] = version
+}
+
+func TestInstall(t *testing.T) {
+	registry := newFakeRegistry(t)
+	registry.publish(t, "a", "1.0.0", nil)
+	registry.publish(t, "a", "1.1.0", map[string]string{"b": "^1.0.0"})
+	registry.publish(t, "b", "1.0.0", nil)
+	registry.publish(t, "b", "1.2.0", nil)
+	registry.publish(t, "b", "2.0.0", nil)
+	registry.publish(t, "c", "1.0.0", map[string]string{"b": "^2.0.0"})
+
+	root := t.TempDir()
+	cfg := &config.PackageJSON{
+		Dependencies:    map[string]string{"a": "^1.0.0", "c": "^1.0.0", "local": "file:../local"},
+		DevDependencies: map[string]string{"renamed": "npm:b@~1.0.0"},
+		ProjectRoot:     root,
+	}
+	cfg.Gode.Registries = map[string]string{"npm": registry.server.URL}
+	cache := diskcache.New(t.TempDir())
+	ctx := context.Background()
+
+	if _, err := Install(ctx, cfg, cache, Options{Frozen: true}); err == nil || !strings.Contains(err.Error(), "needs a gode.lock") {
+		t.Fatalf("frozen install without a lockfile: %v", err)
+	}
+
+	result, err := Install(ctx, cfg, cache, Options{})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	versions := make(map[string]string)
+	for key, locked := range result.Lockfile.Packages {
+		versions[key] = locked.Version
+		if locked.Contents == "" {
+			t.Errorf("%s has no contents hash", key)
+		}
+	}
+	want := map[string]string{
+		"node_modules/a":                "1.1.0",
+		"node_modules/b":                "1.2.0",
+		"node_modules/c":                "1.0.0",
+		"node_modules/c/node_modules/b": "2.0.0",
+		"node_modules/renamed":          "1.0.0",
+	}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("installed %v, want %v", versions, want)
+	}
+	if result.Lockfile.Packages["node_modules/renamed"].Name != "b" {
+		t.Errorf("alias not recorded: %+v", result.Lockfile.Packages["node_modules/renamed"])
+	}
+	data, err := os.ReadFile(filepath.Join(root, "node_modules/c/node_modules/b/index.js"))
+	if err != nil || string(data) != "module.exports = 'b@2.0.0';" {
+		t.Errorf("nested b = %q, %v", data, err)
+	}
+	lockData, err := os.ReadFile(filepath.Join(root, LockfileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged: nothing is fetched, extracted or rewritten
+	registry.requests.Store(0)
+	result, err = Install(ctx, cfg, cache, Options{Frozen: true})
+	if err != nil {
+		t.Fatalf("frozen install: %v", err)
+	}
+	if len(result.Added) != 0 || registry.requests.Load() != 0 {
+		t.Errorf("frozen install added %v with %d requests", result.Added, registry.requests.Load())
+	}
+
+	// A newer version in the registry doesn't move what is locked
+	registry.publish(t, "a", "1.2.0", nil)
+	if _, err := Install(ctx, cfg, cache, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(filepath.Join(root, LockfileName)); !bytes.Equal(again, lockData) {
+		t.Errorf("lockfile changed:\n%s\nwas\n%s", again, lockData)
+	}
+
+	// Changed files are restored
+	os.WriteFile(filepath.Join(root, "node_modules/a/index.js"), []byte("tampered"), 0644)
+	result, err = Install(ctx, cfg, cache, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"node_modules/a"}) {
+		t.Errorf("restored %v", result.Added)
+	}
+
+	// Frozen installs refuse to change the lockfile
+	delete(cfg.Dependencies, "c")
+	_, err = Install(ctx, cfg, cache, Options{Frozen: true})
+	if err == nil || !strings.Contains(err.Error(), "- node_modules/c@1.0.0") {
+		t.Fatalf("frozen install with a changed package.json: %v", err)
+	}
+
+	result, err = Install(ctx, cfg, cache, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(result.Removed)
+	if !reflect.DeepEqual(result.Removed, []string{"node_modules/c", "node_modules/c/node_modules/b"}) {
+		t.Errorf("removed %v", result.Removed)
+	}
+	if _, err := os.Stat(filepath.Join(root, "node_modules/c")); !os.IsNotExist(err) {
+		t.Errorf("node_modules/c still exists: %v", err)
+	}
+}
+
+func TestLockfilePackageOf(t *testing.T) {
+	lock := &Lockfile{Packages: map[string]LockedPackage{
+		"node_modules/a":                       {},
+		"node_modules/@scope/b":                {},
+		"node_modules/a/node_modules/@scope/b": {},
+	}}
+	tests := map[string]string{
+		"node_modules/a/index.js":                           "node_modules/a",
+		"node_modules/a/lib/x.js":                           "node_modules/a",
+		"node_modules/@scope/b/index.js":                    "node_modules/@scope/b",
+		"node_modules/a/node_modules/@scope/b/lib/index.js": "node_modules/a/node_modules/@scope/b",
+		"node_modules/missing/index.js":                     "",
+	}
+	for rel, want := range tests {
+		if got, _ := lock.PackageOf(rel); got != want {
+			t.Errorf("PackageOf(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package install
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LockfileName is the lockfile gode install writes next to package.json
+const LockfileName = "gode.lock"
+
+// lockfileVersion is the format version gode install writes
+const lockfileVersion = 1
+
+// Lockfile pins every installed package to the version, tarball and
+// contents it was installed with
+type Lockfile struct {
+	LockfileVersion int `json:"lockfileVersion"`
+	// Packages are keyed by install path, relative to the project root:
+	// node_modules/a, or node_modules/a/node_modules/b for a copy of b
+	// only a sees
+	Packages map[string]LockedPackage `json:"packages"`
+}
+
+// LockedPackage is one installed package
+type LockedPackage struct {
+	Name         string            `json:"name,omitempty"` // Package name, when installed under an alias
+	Version      string            `json:"version"`
+	Resolved     string            `json:"resolved"`            // Tarball URL
+	Integrity    string            `json:"integrity,omitempty"` // SRI hash of the tarball
+	Contents     string            `json:"contents"`            // SRI hash of the extracted files, see ContentsIntegrity
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// ReadLockfile reads the lockfile of the project in dir. A missing
+// lockfile is an error that matches fs.ErrNotExist.
+func ReadLockfile(dir string) (*Lockfile, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LockfileName))
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lockfile{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", LockfileName, err)
+	}
+	if lock.LockfileVersion > lockfileVersion {
+		return nil, fmt.Errorf("%s has version %d; this gode reads up to version %d", LockfileName, lock.LockfileVersion, lockfileVersion)
+	}
+	if lock.Packages == nil {
+		lock.Packages = make(map[string]LockedPackage)
+	}
+	return lock, nil
+}
+
+// Write writes the lockfile into the project in dir. Packages are written
+// in key order, so an unchanged install writes the same bytes.
+func (l *Lockfile) Write(dir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, LockfileName), append(data, '\n'), 0644)
+}
+
+// PackageOf returns the key of the package that the file at rel, relative
+// to the project root, belongs to: the deepest package directory above it
+func (l *Lockfile) PackageOf(rel string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] != "node_modules" {
+			continue
+		}
+		end := i + 2
+		if strings.HasPrefix(parts[i+1], "@") && end < len(parts) {
+			end++ // @scope/name
+		}
+		key := path.Join(parts[:end]...)
+		if _, ok := l.Packages[key]; ok {
+			return key, true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// Diff lists the packages that differ between l and other, ignoring their
+// contents hashes, as "+ key", "- key" or "~ key" lines in key order
+func (l *Lockfile) Diff(other *Lockfile) []string {
+	keys := make(map[string]bool)
+	for key := range l.Packages {
+		keys[key] = true
+	}
+	for key := range other.Packages {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var diff []string
+	for _, key := range sorted {
+		a, inL := l.Packages[key]
+		b, inOther := other.Packages[key]
+		switch {
+		case !inL:
+			diff = append(diff, "+ "+key+"@"+b.Version)
+		case !inOther:
+			diff = append(diff, "- "+key+"@"+a.Version)
+		default:
+			a.Contents, b.Contents = "", ""
+			x, _ := json.Marshal(a)
+			y, _ := json.Marshal(b)
+			if string(x) != string(y) {
+				diff = append(diff, "~ "+key+"@"+a.Version+" -> "+b.Version)
+			}
+		}
+	}
+	return diff
+}
+
+// ContentsIntegrity returns the SRI hash of the files of the package in
+// dir, leaving out the packages in its node_modules. Paths and contents
+// both count, so a file that was added, removed, renamed or changed after
+// installing gives a different hash.
+func ContentsIntegrity(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "node_modules" && p != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha512.New()
+	var size [8]byte
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		io.WriteString(h, filepath.ToSlash(rel))
+		h.Write([]byte{0})
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			binary.BigEndian.PutUint64(size[:], uint64(info.Size()))
+			h.Write(size[:])
+			_, err = io.Copy(h, f)
+		}
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return "sha512-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
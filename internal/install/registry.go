@@ -0,0 +1,138 @@
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Packument is what the registry knows of a package: its versions and
+// dist-tags, in the abbreviated form npm installs with
+type Packument struct {
+	Name     string                     `json:"name"`
+	DistTags map[string]string          `json:"dist-tags"`
+	Versions map[string]PackageManifest `json:"versions"`
+}
+
+// PackageManifest describes one version of a package
+type PackageManifest struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Dependencies         map[string]string `json:"dependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	Dist                 struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+		Shasum    string `json:"shasum"`
+	} `json:"dist"`
+}
+
+// Registry fetches package metadata from an npm registry, once per package
+type Registry struct {
+	URL    string
+	Client *http.Client
+
+	mu         sync.Mutex
+	packuments map[string]*Packument
+}
+
+// NewRegistry returns a client of the registry at registryURL
+func NewRegistry(registryURL string, client *http.Client) *Registry {
+	return &Registry{
+		URL:        strings.TrimSuffix(registryURL, "/") + "/",
+		Client:     client,
+		packuments: make(map[string]*Packument),
+	}
+}
+
+// Packument returns the metadata of the package name
+func (r *Registry) Packument(ctx context.Context, name string) (*Packument, error) {
+	r.mu.Lock()
+	cached, ok := r.packuments[name]
+	r.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	// Scoped names keep their @ but escape the slash: @scope%2fname
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL+strings.Replace(url.PathEscape(name), "%40", "@", 1), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8")
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("package %s not found in %s", name, r.URL)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetching %s: %s", name, resp.Status)
+	}
+
+	packument := &Packument{}
+	if err := json.NewDecoder(resp.Body).Decode(packument); err != nil {
+		return nil, fmt.Errorf("reading metadata of %s: %w", name, err)
+	}
+	r.mu.Lock()
+	r.packuments[name] = packument
+	r.mu.Unlock()
+	return packument, nil
+}
+
+// Resolve returns the manifest of the highest version of name that spec
+// allows. spec is a range or a dist-tag such as latest; as in npm, the
+// version the latest tag points to is preferred when it is in the range.
+func (r *Registry) Resolve(ctx context.Context, name, spec string) (*PackageManifest, error) {
+	packument, err := r.Packument(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if spec == "" {
+		spec = "latest"
+	}
+	if tagged, ok := packument.DistTags[spec]; ok {
+		return manifest(packument, name, tagged)
+	}
+
+	rng, err := ParseRange(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s@%s: %w", name, spec, err)
+	}
+	if latest, ok := packument.DistTags["latest"]; ok {
+		if v, err := ParseVersion(latest); err == nil && rng.Satisfies(v) {
+			return manifest(packument, name, latest)
+		}
+	}
+	versions := make([]string, 0, len(packument.Versions))
+	for version := range packument.Versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	best, ok := MaxSatisfying(versions, rng)
+	if !ok {
+		return nil, fmt.Errorf("no version of %s matches %s", name, spec)
+	}
+	return manifest(packument, name, best.String())
+}
+
+func manifest(packument *Packument, name, version string) (*PackageManifest, error) {
+	m, ok := packument.Versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%s@%s is not in the registry", name, version)
+	}
+	if m.Name == "" {
+		m.Name = name
+	}
+	if m.Version == "" {
+		m.Version = version
+	}
+	return &m, nil
+}
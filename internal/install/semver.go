@@ -0,0 +1,368 @@
+package install
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, as npm packages are versioned
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          []string
+	raw                 string
+}
+
+// ParseVersion parses a version such as 1.2.3, v1.2.3 or 1.2.3-beta.1+build
+func ParseVersion(s string) (Version, error) {
+	raw := strings.TrimSpace(s)
+	v := strings.TrimPrefix(strings.TrimPrefix(raw, "="), "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, hasPre := strings.Cut(v, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	version := Version{Major: nums[0], Minor: nums[1], Patch: nums[2], raw: raw}
+	if hasPre {
+		if pre == "" {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		version.Prerelease = strings.Split(pre, ".")
+	}
+	return version, nil
+}
+
+// String returns the version as it was parsed
+func (v Version) String() string {
+	if v.raw != "" {
+		return v.raw
+	}
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than w.
+// A prerelease is lower than its release.
+func (v Version) Compare(w Version) int {
+	for _, d := range [][2]int{{v.Major, w.Major}, {v.Minor, w.Minor}, {v.Patch, w.Patch}} {
+		if d[0] != d[1] {
+			return sign(d[0] - d[1])
+		}
+	}
+	switch {
+	case len(v.Prerelease) == 0 && len(w.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(w.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(w.Prerelease); i++ {
+		a, b := v.Prerelease[i], w.Prerelease[i]
+		an, aErr := strconv.Atoi(a)
+		bn, bErr := strconv.Atoi(b)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1 // numeric identifiers sort first
+		case bErr == nil:
+			return 1
+		case a != b:
+			return strings.Compare(a, b)
+		}
+	}
+	return sign(len(v.Prerelease) - len(w.Prerelease))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// comparator is one condition of a range, such as >=1.2.0
+type comparator struct {
+	op      string // one of < <= > >= =
+	version Version
+}
+
+func (c comparator) matches(v Version) bool {
+	n := v.Compare(c.version)
+	switch c.op {
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	}
+	return n == 0
+}
+
+// Range is an npm version range: sets of comparators joined by ||, any of
+// which a version must satisfy all of
+type Range struct {
+	sets [][]comparator
+	raw  string
+}
+
+// ParseRange parses an npm range: 1.2.3, ^1.2, ~1.2.3, >=1 <2, 1.x, *,
+// 1.0.0 - 2.0.0 and alternatives joined by ||
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		set, err := parseSet(strings.TrimSpace(alternative))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q: %w", s, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// String returns the range as it was parsed
+func (r Range) String() string {
+	return r.raw
+}
+
+// Satisfies reports whether v is in the range. As in npm, prereleases only
+// satisfy a range that names a prerelease of the same major.minor.patch.
+func (r Range) Satisfies(v Version) bool {
+	for _, set := range r.sets {
+		if setMatches(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func setMatches(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	if len(v.Prerelease) == 0 {
+		return true
+	}
+	for _, c := range set {
+		cv := c.version
+		if len(cv.Prerelease) > 0 && cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSet parses the comparators of one alternative of a range
+func parseSet(s string) ([]comparator, error) {
+	if s == "" || s == "*" || s == "x" || s == "X" {
+		return []comparator{{op: ">=", version: Version{}}}, nil
+	}
+	if lo, hi, ok := strings.Cut(s, " - "); ok {
+		return hyphenRange(strings.TrimSpace(lo), strings.TrimSpace(hi))
+	}
+
+	// Operators may be separated from their versions by spaces
+	var fields []string
+	for _, field := range strings.Fields(s) {
+		if n := len(fields); n > 0 && strings.Trim(fields[n-1], "<>=~^") == "" {
+			fields[n-1] += field
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	var set []comparator
+	for _, field := range fields {
+		comparators, err := parseComparator(field)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+// partial is a version with missing or wildcard parts, such as 1.2 or 1.x
+type partial struct {
+	nums [3]int
+	n    int // number of parts given
+	pre  []string
+}
+
+func parsePartial(s string) (partial, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "="), "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, hasPre := strings.Cut(s, "-")
+	var p partial
+	if hasPre {
+		p.pre = strings.Split(pre, ".")
+	}
+	for i, part := range strings.Split(core, ".") {
+		if i > 2 {
+			return partial{}, fmt.Errorf("invalid version %q", s)
+		}
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return partial{}, fmt.Errorf("invalid version %q", s)
+		}
+		p.nums[i] = n
+		p.n = i + 1
+	}
+	return p, nil
+}
+
+func (p partial) version() Version {
+	v := Version{Major: p.nums[0], Minor: p.nums[1], Patch: p.nums[2]}
+	if p.n == 3 {
+		v.Prerelease = p.pre
+	}
+	return v
+}
+
+// next returns the lowest version above every version p matches
+func (p partial) next() Version {
+	switch p.n {
+	case 1:
+		return Version{Major: p.nums[0] + 1, Prerelease: []string{"0"}}
+	case 2:
+		return Version{Major: p.nums[0], Minor: p.nums[1] + 1, Prerelease: []string{"0"}}
+	}
+	return Version{}
+}
+
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~>", "~"} {
+		if strings.HasPrefix(s, candidate) {
+			op, s = candidate, s[len(candidate):]
+			break
+		}
+	}
+	p, err := parsePartial(s)
+	if err != nil {
+		return nil, err
+	}
+	low := p.version()
+	all := []comparator{{op: ">=", version: Version{}}}
+
+	switch op {
+	case "^":
+		if p.n == 0 {
+			return all, nil
+		}
+		// The first non-zero part given may not change
+		var high Version
+		switch {
+		case p.nums[0] > 0 || p.n == 1:
+			high = Version{Major: p.nums[0] + 1, Prerelease: []string{"0"}}
+		case p.nums[1] > 0 || p.n == 2:
+			high = Version{Minor: p.nums[1] + 1, Prerelease: []string{"0"}}
+		default:
+			high = Version{Patch: p.nums[2] + 1, Prerelease: []string{"0"}}
+		}
+		return []comparator{{">=", low}, {"<", high}}, nil
+	case "~", "~>":
+		if p.n == 0 {
+			return all, nil
+		}
+		high := Version{Major: p.nums[0] + 1, Prerelease: []string{"0"}}
+		if p.n > 1 {
+			high = Version{Major: p.nums[0], Minor: p.nums[1] + 1, Prerelease: []string{"0"}}
+		}
+		return []comparator{{">=", low}, {"<", high}}, nil
+	case ">":
+		if p.n == 0 {
+			return []comparator{{"<", Version{}}}, nil
+		}
+		if p.n < 3 {
+			return []comparator{{">=", p.next()}}, nil
+		}
+		return []comparator{{">", low}}, nil
+	case ">=":
+		return []comparator{{">=", low}}, nil
+	case "<":
+		return []comparator{{"<", low}}, nil
+	case "<=":
+		if p.n > 0 && p.n < 3 {
+			return []comparator{{"<", p.next()}}, nil
+		}
+		if p.n == 0 {
+			return all, nil
+		}
+		return []comparator{{"<=", low}}, nil
+	}
+
+	// A bare or = version, matching every version it leaves open
+	switch p.n {
+	case 0:
+		return all, nil
+	case 3:
+		return []comparator{{"=", low}}, nil
+	}
+	return []comparator{{">=", low}, {"<", p.next()}}, nil
+}
+
+// hyphenRange parses lo - hi, where a partial hi includes every version it
+// matches
+func hyphenRange(lo, hi string) ([]comparator, error) {
+	low, err := parsePartial(lo)
+	if err != nil {
+		return nil, err
+	}
+	high, err := parsePartial(hi)
+	if err != nil {
+		return nil, err
+	}
+	set := []comparator{{">=", low.version()}}
+	switch high.n {
+	case 0:
+	case 3:
+		set = append(set, comparator{"<=", high.version()})
+	default:
+		set = append(set, comparator{"<", high.next()})
+	}
+	return set, nil
+}
+
+// MaxSatisfying returns the highest of versions in r, skipping versions
+// that don't parse
+func MaxSatisfying(versions []string, r Range) (Version, bool) {
+	var best Version
+	found := false
+	for _, s := range versions {
+		v, err := ParseVersion(s)
+		if err != nil || !r.Satisfies(v) {
+			continue
+		}
+		if !found || v.Compare(best) > 0 {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,79 @@
+package install
+
+import "testing"
+
+func TestRangeSatisfies(t *testing.T) {
+	tests := []struct {
+		rng     string
+		version string
+		want    bool
+	}{
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "2.0.0", false},
+		{"^1.2.3", "1.2.2", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"~1", "1.9.9", true},
+		{"1.x", "1.4.0", true},
+		{"1.x", "2.0.0", false},
+		{"1.2", "1.2.7", true},
+		{"*", "3.1.4", true},
+		{"", "3.1.4", true},
+		{">=1.0.0 <2", "1.5.0", true},
+		{">= 1.0.0 < 2", "2.0.0", false},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"1.0.0 - 1.2", "1.2.5", true},
+		{"1.0.0 - 1.2.0", "1.2.5", false},
+		{"1.0.0 || ^3.0.0", "3.4.0", true},
+		{"1.0.0 || ^3.0.0", "2.0.0", false},
+		{"=1.0.0", "1.0.0", true},
+		{"^1.0.0", "1.5.0-beta.1", false},
+		{"^1.5.0-beta.0", "1.5.0-beta.1", true},
+		{"^1.5.0-beta.0", "1.6.0-beta.1", false},
+	}
+	for _, tt := range tests {
+		rng, err := ParseRange(tt.rng)
+		if err != nil {
+			t.Errorf("ParseRange(%q): %v", tt.rng, err)
+			continue
+		}
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Errorf("ParseVersion(%q): %v", tt.version, err)
+			continue
+		}
+		if got := rng.Satisfies(v); got != tt.want {
+			t.Errorf("%q satisfies %q = %v, want %v", tt.version, tt.rng, got, tt.want)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, _ := ParseVersion(ordered[i])
+		b, _ := ParseVersion(ordered[i+1])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+	if _, err := ParseVersion("1.2"); err == nil {
+		t.Error("ParseVersion accepted 1.2")
+	}
+}
+
+func TestMaxSatisfying(t *testing.T) {
+	rng, _ := ParseRange("^1.0.0")
+	best, ok := MaxSatisfying([]string{"0.9.0", "1.0.0", "1.10.0", "1.9.0", "2.0.0", "1.11.0-rc.1", "junk"}, rng)
+	if !ok || best.String() != "1.10.0" {
+		t.Errorf("MaxSatisfying = %v, %v", best, ok)
+	}
+	if _, ok := MaxSatisfying([]string{"2.0.0"}, rng); ok {
+		t.Error("MaxSatisfying found a version out of range")
+	}
+}
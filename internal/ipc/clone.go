@@ -0,0 +1,214 @@
+package ipc
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Serialize converts a JavaScript value into a JSON-compatible tree that
+// Deserialize turns back into an equivalent value. Beyond plain JSON it
+// keeps undefined, NaN/Infinity, Date, RegExp, Map, Set and Error, in the
+// spirit of the structured clone algorithm. Functions, symbols and
+// circular references cannot be sent.
+func Serialize(vm *goja.Runtime, value goja.Value) (interface{}, error) {
+	s := &serializer{vm: vm, seen: make(map[*goja.Object]bool)}
+	return s.value(value)
+}
+
+type serializer struct {
+	vm   *goja.Runtime
+	seen map[*goja.Object]bool
+}
+
+// tagged wraps values that JSON cannot represent directly
+func tagged(kind string, v interface{}) map[string]interface{} {
+	return map[string]interface{}{"$t": kind, "v": v}
+}
+
+func (s *serializer) value(value goja.Value) (interface{}, error) {
+	if value == nil || goja.IsUndefined(value) {
+		return tagged("undefined", nil), nil
+	}
+	if goja.IsNull(value) {
+		return nil, nil
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		switch v := value.Export().(type) {
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return tagged("number", value.String()), nil
+			}
+			return v, nil
+		case string, bool, int64:
+			return v, nil
+		}
+		return nil, fmt.Errorf("DataCloneError: %s could not be cloned", value.String())
+	}
+
+	if _, isFunc := goja.AssertFunction(obj); isFunc {
+		return nil, fmt.Errorf("DataCloneError: functions could not be cloned")
+	}
+	if s.seen[obj] {
+		return nil, fmt.Errorf("DataCloneError: circular references could not be cloned")
+	}
+	s.seen[obj] = true
+	defer delete(s.seen, obj)
+
+	switch obj.ClassName() {
+	case "Date":
+		getTime, _ := goja.AssertFunction(obj.Get("getTime"))
+		ms, err := getTime(obj)
+		if err != nil {
+			return nil, err
+		}
+		return tagged("Date", ms.ToFloat()), nil
+
+	case "RegExp":
+		return tagged("RegExp", []interface{}{obj.Get("source").String(), obj.Get("flags").String()}), nil
+
+	case "Error":
+		return tagged("Error", map[string]interface{}{
+			"name":    obj.Get("name").String(),
+			"message": obj.Get("message").String(),
+			"stack":   s.optionalString(obj.Get("stack")),
+		}), nil
+
+	case "Map", "Set":
+		// Array.from(map) yields [key, value] pairs; Array.from(set) yields values
+		from, _ := goja.AssertFunction(s.vm.Get("Array").ToObject(s.vm).Get("from"))
+		entries, err := from(goja.Undefined(), obj)
+		if err != nil {
+			return nil, err
+		}
+		items, err := s.value(entries)
+		if err != nil {
+			return nil, err
+		}
+		return tagged(obj.ClassName(), items), nil
+
+	case "Array":
+		length := int(obj.Get("length").ToInteger())
+		items := make([]interface{}, length)
+		for i := 0; i < length; i++ {
+			item, err := s.value(obj.Get(fmt.Sprint(i)))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+
+	fields := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		field, err := s.value(obj.Get(key))
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = field
+	}
+	if _, clash := fields["$t"]; clash {
+		return tagged("Object", fields), nil
+	}
+	return fields, nil
+}
+
+func (s *serializer) optionalString(value goja.Value) interface{} {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value.String()
+}
+
+// Deserialize rebuilds a JavaScript value from the output of Serialize
+func Deserialize(vm *goja.Runtime, data interface{}) (goja.Value, error) {
+	switch v := data.(type) {
+	case nil:
+		return goja.Null(), nil
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := Deserialize(vm, item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return vm.NewArray(items...), nil
+	case map[string]interface{}:
+		if kind, ok := v["$t"].(string); ok {
+			return deserializeTagged(vm, kind, v["v"])
+		}
+		return deserializeObject(vm, v)
+	}
+	return vm.ToValue(data), nil
+}
+
+func deserializeObject(vm *goja.Runtime, fields map[string]interface{}) (goja.Value, error) {
+	obj := vm.NewObject()
+	for key, field := range fields {
+		value, err := Deserialize(vm, field)
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(key, value)
+	}
+	return obj, nil
+}
+
+func deserializeTagged(vm *goja.Runtime, kind string, data interface{}) (goja.Value, error) {
+	switch kind {
+	case "undefined":
+		return goja.Undefined(), nil
+
+	case "number":
+		s, _ := data.(string)
+		switch s {
+		case "NaN":
+			return vm.ToValue(math.NaN()), nil
+		case "Infinity":
+			return vm.ToValue(math.Inf(1)), nil
+		case "-Infinity":
+			return vm.ToValue(math.Inf(-1)), nil
+		}
+
+	case "Date":
+		return vm.New(vm.Get("Date"), vm.ToValue(data))
+
+	case "RegExp":
+		if parts, ok := data.([]interface{}); ok && len(parts) == 2 {
+			return vm.New(vm.Get("RegExp"), vm.ToValue(parts[0]), vm.ToValue(parts[1]))
+		}
+
+	case "Error":
+		if fields, ok := data.(map[string]interface{}); ok {
+			obj, err := vm.New(vm.Get("Error"), vm.ToValue(fields["message"]))
+			if err != nil {
+				return nil, err
+			}
+			obj.Set("name", fields["name"])
+			if stack, ok := fields["stack"].(string); ok {
+				obj.Set("stack", stack)
+			}
+			return obj, nil
+		}
+
+	case "Map", "Set":
+		entries, err := Deserialize(vm, data)
+		if err != nil {
+			return nil, err
+		}
+		return vm.New(vm.Get(kind), entries)
+
+	case "Object":
+		if fields, ok := data.(map[string]interface{}); ok {
+			return deserializeObject(vm, fields)
+		}
+	}
+
+	return nil, fmt.Errorf("invalid ipc message: bad %q value", kind)
+}
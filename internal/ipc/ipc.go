@@ -0,0 +1,164 @@
+// Package ipc implements the message channel between a gode process and a
+// child it spawned. Messages travel as newline-delimited JSON over a pair of
+// pipes handed to the child as extra file descriptors.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EnvFD names the variable that tells a child which descriptors carry the
+// channel, as "readFD,writeFD"
+const EnvFD = "GODE_IPC_FD"
+
+// ErrClosed is returned when sending on a disconnected channel
+var ErrClosed = errors.New("ipc channel is closed")
+
+// Channel is one end of a parent/child message channel. Send is safe for
+// concurrent use; Receive must be called from a single goroutine.
+type Channel struct {
+	r      io.ReadCloser
+	w      io.WriteCloser
+	reader *bufio.Reader
+
+	mu     sync.Mutex // Serializes writes
+	once   sync.Once
+	closed chan struct{}
+}
+
+// New creates a channel reading from r and writing to w
+func New(r io.ReadCloser, w io.WriteCloser) *Channel {
+	return &Channel{
+		r:      r,
+		w:      w,
+		reader: bufio.NewReaderSize(r, 64*1024),
+		closed: make(chan struct{}),
+	}
+}
+
+// FromEnv opens the channel a parent set up for this process. It returns
+// nil without error when the process was not spawned with IPC.
+func FromEnv() (*Channel, error) {
+	spec := os.Getenv(EnvFD)
+	if spec == "" {
+		return nil, nil
+	}
+
+	// Children of this process should not inherit the channel
+	os.Unsetenv(EnvFD)
+
+	fds := strings.Split(spec, ",")
+	if len(fds) != 2 {
+		return nil, fmt.Errorf("invalid %s %q", EnvFD, spec)
+	}
+	readFD, err1 := strconv.Atoi(fds[0])
+	writeFD, err2 := strconv.Atoi(fds[1])
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("invalid %s %q", EnvFD, spec)
+	}
+
+	return New(os.NewFile(uintptr(readFD), "ipc-read"), os.NewFile(uintptr(writeFD), "ipc-write")), nil
+}
+
+// Attach prepares cmd to be started with an IPC channel and returns the
+// parent's end. The child finds its end through EnvFD. Call it before
+// cmd.Start; the child's pipe ends are closed in the parent once the
+// command has started.
+func Attach(cmd *exec.Cmd) (*Channel, func(), error) {
+	// parent -> child
+	childR, parentW, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	// child -> parent
+	parentR, childW, err := os.Pipe()
+	if err != nil {
+		childR.Close()
+		parentW.Close()
+		return nil, nil, err
+	}
+
+	first := 3 + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, childR, childW)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d,%d", EnvFD, first, first+1))
+
+	started := func() {
+		childR.Close()
+		childW.Close()
+	}
+	return New(parentR, parentW), started, nil
+}
+
+// Send writes one message. msg must be JSON-serializable; use Serialize to
+// turn a JavaScript value into one.
+func (c *Channel) Send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode ipc message: %w", err)
+	}
+	data = append(data, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+	if _, err := c.w.Write(data); err != nil {
+		return fmt.Errorf("failed to send ipc message: %w", err)
+	}
+	return nil
+}
+
+// Receive blocks for the next message. It returns io.EOF once the other
+// end disconnects.
+func (c *Channel) Receive() (interface{}, error) {
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		if len(line) == 0 || err != io.EOF {
+			c.Close()
+			if errors.Is(err, os.ErrClosed) {
+				err = io.EOF
+			}
+			return nil, err
+		}
+	}
+
+	var msg interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode ipc message: %w", err)
+	}
+	return msg, nil
+}
+
+// Close disconnects the channel. It is safe to call more than once.
+func (c *Channel) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.closed)
+		c.mu.Lock()
+		err = c.w.Close()
+		c.mu.Unlock()
+		c.r.Close()
+	})
+	return err
+}
+
+// Done is closed once the channel is disconnected
+func (c *Channel) Done() <-chan struct{} {
+	return c.closed
+}
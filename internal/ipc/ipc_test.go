@@ -0,0 +1,87 @@
+package ipc
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestChannelRoundTrip(t *testing.T) {
+	aR, bW, _ := os.Pipe()
+	bR, aW, _ := os.Pipe()
+	a := New(aR, aW)
+	b := New(bR, bW)
+	defer b.Close()
+
+	msg := map[string]interface{}{"type": "job", "ids": []interface{}{1.0, 2.0}}
+	if err := a.Send(msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	got, err := b.Receive()
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("Expected %v, got %v", msg, got)
+	}
+
+	a.Close()
+	if _, err := b.Receive(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the other end closed, got %v", err)
+	}
+	if err := a.Send("late"); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// TestHelperProcess is the child side of TestAttach
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GODE_IPC_HELPER") != "1" {
+		return
+	}
+
+	channel, err := FromEnv()
+	if err != nil || channel == nil {
+		os.Exit(2)
+	}
+	for {
+		msg, err := channel.Receive()
+		if err != nil {
+			os.Exit(0)
+		}
+		channel.Send(map[string]interface{}{"echo": msg})
+	}
+}
+
+func TestAttach(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "GODE_IPC_HELPER=1")
+
+	channel, started, err := Attach(cmd)
+	if err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	started()
+
+	if err := channel.Send("ping"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	reply, err := channel.Receive()
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if want := map[string]interface{}{"echo": "ping"}; !reflect.DeepEqual(reply, want) {
+		t.Errorf("Expected %v, got %v", want, reply)
+	}
+
+	channel.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Child did not exit cleanly after disconnect: %v", err)
+	}
+}
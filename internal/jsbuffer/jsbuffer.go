@@ -0,0 +1,22 @@
+// Package jsbuffer hands binary data from Go to scripts as Buffers, for the
+// built-in modules that return bytes
+package jsbuffer
+
+import "github.com/rizqme/gode/goja"
+
+// New returns data as a Buffer, or as a Uint8Array when Buffer is not
+// available
+func New(vm *goja.Runtime, data []byte) goja.Value {
+	if ctor, ok := vm.Get("Buffer").(*goja.Object); ok {
+		if from, ok := goja.AssertFunction(ctor.Get("from")); ok {
+			if buffer, err := from(ctor, vm.ToValue(data)); err == nil {
+				return buffer
+			}
+		}
+	}
+	array, err := vm.New(vm.Get("Uint8Array"), vm.ToValue(vm.NewArrayBuffer(data)))
+	if err != nil {
+		panic(err)
+	}
+	return array
+}
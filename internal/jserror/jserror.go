@@ -0,0 +1,154 @@
+// Package jserror converts errors between Go and JS without losing their
+// causes. A Go error wrapped with %w becomes the cause of the JS Error made
+// from it, and the cause of a JS Error becomes the next link of a Go error
+// chain.
+package jserror
+
+import (
+	"github.com/rizqme/gode/goja"
+	goderrors "github.com/rizqme/gode/internal/errors"
+)
+
+// maxDepth bounds how much of a cause chain is converted, since a JS error
+// can be its own cause
+const maxDepth = 16
+
+// New converts err to a JS Error with err.Error() as its message, taking
+// its name and extra properties from errors that provide them. The error
+// err wraps becomes its cause, converted the same way; errors joined with
+// errors.Join or several %w verbs give an AggregateError cause, and a
+// wrapped JS exception gives the value that was thrown.
+func New(vm *goja.Runtime, err error) *goja.Object {
+	return newError(vm, err, 0)
+}
+
+// typed is implemented by Go errors that become a named JS error, such as
+// PermissionDenied, with extra properties like code
+type typed interface {
+	JSName() string
+	JSProperties() map[string]interface{}
+}
+
+func newError(vm *goja.Runtime, err error, depth int) *goja.Object {
+	obj := vm.NewGoError(err)
+	if t, ok := err.(typed); ok {
+		obj.Set("name", t.JSName())
+		for name, value := range t.JSProperties() {
+			obj.Set(name, value)
+		}
+	}
+	if depth < maxDepth {
+		if cause := causeValue(vm, err, depth+1); cause != nil {
+			obj.DefineDataProperty("cause", cause, goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+		}
+	}
+	return obj
+}
+
+// causeValue converts what err wraps, or returns nil when it wraps nothing
+func causeValue(vm *goja.Runtime, err error, depth int) goja.Value {
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() error }:
+		if next := wrapper.Unwrap(); next != nil {
+			return value(vm, next, depth)
+		}
+	case interface{ Unwrap() []error }:
+		if errs := wrapper.Unwrap(); len(errs) > 0 {
+			return aggregate(vm, errs, "", depth)
+		}
+	}
+	return nil
+}
+
+// value converts err, passing a JS exception's value through as it is
+func value(vm *goja.Runtime, err error, depth int) goja.Value {
+	if exception, ok := err.(*goja.Exception); ok {
+		return exception.Value()
+	}
+	return newError(vm, err, depth)
+}
+
+// Aggregate creates an AggregateError with message whose errors are errs,
+// for operations that fail in several places at once
+func Aggregate(vm *goja.Runtime, errs []error, message string) *goja.Object {
+	return aggregate(vm, errs, message, 0)
+}
+
+func aggregate(vm *goja.Runtime, errs []error, message string, depth int) *goja.Object {
+	items := make([]interface{}, len(errs))
+	for i, err := range errs {
+		items[i] = value(vm, err, depth)
+	}
+	obj, err := vm.New(vm.Get("AggregateError"), vm.NewArray(items...), vm.ToValue(message))
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+// Cause returns the cause of the JS error value as a Go error, or nil when
+// it has none. See Error.
+func Cause(value goja.Value) error {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	return toError(obj.Get("cause"), 0)
+}
+
+// Error converts a thrown JS value to a Go error. Errors created from Go
+// give back the Go error; anything else becomes a *errors.JSError whose
+// Cause, and Errors for an AggregateError, are converted the same way.
+// It returns nil for undefined.
+func Error(value goja.Value) error {
+	return toError(value, 0)
+}
+
+func toError(value goja.Value, depth int) error {
+	if value == nil || goja.IsUndefined(value) {
+		return nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return &goderrors.JSError{Message: value.String()}
+	}
+	if wrapped := obj.Get("value"); wrapped != nil {
+		if err, ok := wrapped.Export().(error); ok {
+			return err
+		}
+	}
+
+	jsErr := &goderrors.JSError{
+		Type:    stringProperty(obj, "name"),
+		Message: stringProperty(obj, "message"),
+	}
+	if jsErr.Type == "" && jsErr.Message == "" {
+		jsErr.Message = obj.String()
+	}
+	if stack := stringProperty(obj, "stack"); stack != "" {
+		if parsed, err := goderrors.ParseJSError(stack); err == nil {
+			jsErr.Stack = parsed.Stack
+		}
+	}
+	if depth >= maxDepth {
+		return jsErr
+	}
+
+	if errs, ok := obj.Get("errors").(*goja.Object); ok && jsErr.Type == "AggregateError" {
+		for _, key := range errs.Keys() {
+			if err := toError(errs.Get(key), depth+1); err != nil {
+				jsErr.Errors = append(jsErr.Errors, err)
+			}
+		}
+	}
+	jsErr.Cause = toError(obj.Get("cause"), depth+1)
+	return jsErr
+}
+
+func stringProperty(obj *goja.Object, name string) string {
+	v := obj.Get(name)
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return ""
+	}
+	return v.String()
+}
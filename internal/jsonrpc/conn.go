@@ -0,0 +1,223 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// ErrClosed fails the calls of a closed connection
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+// Call is one call of a batch
+type Call struct {
+	Method string
+	Params interface{}
+	// Notify sends the call as a notification, which gets no response
+	Notify bool
+}
+
+// Conn is one end of a connection over which both ends may call the
+// other: requests that arrive are answered by its server, and responses
+// settle its own calls
+type Conn struct {
+	stream Stream
+	server *Server
+
+	mu      sync.Mutex
+	pending map[string]chan *Response
+	nextID  int64
+	err     error
+}
+
+// NewConn creates a connection over stream whose requests server answers;
+// a nil server answers them with MethodNotFound
+func NewConn(stream Stream, server *Server) *Conn {
+	if server == nil {
+		server = NewServer()
+	}
+	return &Conn{stream: stream, server: server, pending: make(map[string]chan *Response)}
+}
+
+// Run reads messages until the stream ends, fails or is closed, or ctx is
+// done, then fails the calls still waiting
+func (c *Conn) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		c.stream.Close()
+	}()
+
+	var err error
+	for {
+		var msg []byte
+		if msg, err = c.stream.Read(); err != nil {
+			break
+		}
+		if c.settle(msg) {
+			continue
+		}
+		go func(msg []byte) {
+			if res := c.server.Serve(ctx, msg); res != nil {
+				c.stream.Write(res)
+			}
+		}(msg)
+	}
+	c.fail(ErrClosed)
+	return err
+}
+
+// settle routes msg to the calls it answers, reporting whether it was one
+// or more responses
+func (c *Conn) settle(msg []byte) bool {
+	var items []json.RawMessage
+	if isBatch(msg) {
+		if json.Unmarshal(msg, &items) != nil || len(items) == 0 {
+			return false
+		}
+	} else {
+		items = []json.RawMessage{msg}
+	}
+	responses := make([]*Response, len(items))
+	for i, item := range items {
+		res, ok := decodeResponse(item)
+		if !ok {
+			return false
+		}
+		responses[i] = res
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, res := range responses {
+		key := string(res.ID)
+		if ch, ok := c.pending[key]; ok {
+			delete(c.pending, key)
+			ch <- res
+		}
+	}
+	return true
+}
+
+// fail ends the calls waiting for responses with err
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	for key, ch := range c.pending {
+		delete(c.pending, key)
+		ch <- &Response{Error: NewError(InternalError, err.Error(), nil)}
+	}
+}
+
+// request builds the request of call, registering a channel for its
+// response unless it is a notification
+func (c *Conn) request(call Call) (*Request, chan *Response, error) {
+	req := &Request{Method: call.Method}
+	if call.Params != nil {
+		params, err := marshal(call.Params)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Params = params
+	}
+	if call.Notify {
+		return req, nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	c.nextID++
+	req.ID = json.RawMessage(strconv.FormatInt(c.nextID, 10))
+	ch := make(chan *Response, 1)
+	c.pending[string(req.ID)] = ch
+	return req, ch, nil
+}
+
+func (c *Conn) forget(req *Request) {
+	if req.ID == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, string(req.ID))
+}
+
+// Call calls method with params, returning its result or its *Error
+func (c *Conn) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	responses, err := c.Batch(ctx, []Call{{Method: method, Params: params}}, false)
+	if err != nil {
+		return nil, err
+	}
+	if responses[0].Error != nil {
+		return nil, responses[0].Error
+	}
+	return responses[0].Result, nil
+}
+
+// Notify sends a notification of method with params
+func (c *Conn) Notify(method string, params interface{}) error {
+	_, err := c.Batch(context.Background(), []Call{{Method: method, Params: params, Notify: true}}, false)
+	return err
+}
+
+// Batch sends calls, as a batch when batch is set and one by one
+// otherwise, returning their responses in order, nil for notifications
+func (c *Conn) Batch(ctx context.Context, calls []Call, batch bool) ([]*Response, error) {
+	requests := make([]*Request, len(calls))
+	channels := make([]chan *Response, len(calls))
+	defer func() {
+		for _, req := range requests {
+			if req != nil {
+				c.forget(req)
+			}
+		}
+	}()
+	for i, call := range calls {
+		req, ch, err := c.request(call)
+		if err != nil {
+			return nil, err
+		}
+		requests[i], channels[i] = req, ch
+	}
+
+	if batch {
+		if err := c.stream.Write(encode(requests)); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, req := range requests {
+			if err := c.stream.Write(encode(req)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	responses := make([]*Response, len(calls))
+	for i, ch := range channels {
+		if ch == nil {
+			continue
+		}
+		select {
+		case res := <-ch:
+			responses[i] = res
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return responses, nil
+}
+
+// Close closes the stream, which ends Run
+func (c *Conn) Close() error {
+	c.fail(ErrClosed)
+	return c.stream.Close()
+}
@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// HTTPClient calls a server by POSTing requests to URL
+type HTTPClient struct {
+	URL    string
+	Header http.Header
+	// Client sends the requests; nil means http.DefaultClient
+	Client *http.Client
+
+	nextID int64
+}
+
+// Call calls method with params, returning its result or its *Error
+func (c *HTTPClient) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	responses, err := c.Batch(ctx, []Call{{Method: method, Params: params}})
+	if err != nil {
+		return nil, err
+	}
+	if responses[0].Error != nil {
+		return nil, responses[0].Error
+	}
+	return responses[0].Result, nil
+}
+
+// Notify sends a notification of method with params
+func (c *HTTPClient) Notify(ctx context.Context, method string, params interface{}) error {
+	_, err := c.Batch(ctx, []Call{{Method: method, Params: params, Notify: true}})
+	return err
+}
+
+// Batch sends calls in one request, as a batch unless there is only one,
+// returning their responses in order, nil for notifications
+func (c *HTTPClient) Batch(ctx context.Context, calls []Call) ([]*Response, error) {
+	requests := make([]*Request, len(calls))
+	index := make(map[string]int)
+	for i, call := range calls {
+		req := &Request{Method: call.Method}
+		if call.Params != nil {
+			params, err := marshal(call.Params)
+			if err != nil {
+				return nil, err
+			}
+			req.Params = params
+		}
+		if !call.Notify {
+			req.ID = json.RawMessage(strconv.FormatInt(atomic.AddInt64(&c.nextID, 1), 10))
+			index[string(req.ID)] = i
+		}
+		requests[i] = req
+	}
+
+	var body []byte
+	if len(requests) == 1 {
+		body = encode(requests[0])
+	} else {
+		body = encode(requests)
+	}
+	data, err := c.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*Response, len(calls))
+	if len(index) == 0 {
+		return responses, nil
+	}
+	var items []json.RawMessage
+	if isBatch(data) {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("jsonrpc: invalid response: %w", err)
+		}
+	} else {
+		items = []json.RawMessage{data}
+	}
+	for _, item := range items {
+		res, ok := decodeResponse(item)
+		if !ok {
+			return nil, fmt.Errorf("jsonrpc: invalid response: %s", item)
+		}
+		i, ok := index[string(res.ID)]
+		if !ok {
+			// An error for the request as a whole, such as a parse error
+			if res.Error != nil {
+				return nil, res.Error
+			}
+			continue
+		}
+		responses[i] = res
+		delete(index, string(res.ID))
+	}
+	if len(index) > 0 {
+		return nil, fmt.Errorf("jsonrpc: %d calls got no response", len(index))
+	}
+	return responses, nil
+}
+
+func (c *HTTPClient) post(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, MaxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 && firstByte(data) != '{' && firstByte(data) != '[' {
+		return nil, fmt.Errorf("jsonrpc: %s", res.Status)
+	}
+	return data, nil
+}
@@ -0,0 +1,225 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testServer(notified *int32) *Server {
+	s := NewServer()
+	s.Handle("add", func(ctx context.Context, req *Request) (interface{}, error) {
+		var args []int
+		if err := json.Unmarshal(req.Params, &args); err != nil {
+			return nil, NewError(InvalidParams, "Invalid params", err.Error())
+		}
+		sum := 0
+		for _, n := range args {
+			sum += n
+		}
+		return sum, nil
+	})
+	s.Handle("echo", func(ctx context.Context, req *Request) (interface{}, error) {
+		return req.Params, nil
+	})
+	s.Handle("fail", func(ctx context.Context, req *Request) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	s.Handle("panic", func(ctx context.Context, req *Request) (interface{}, error) {
+		panic("oops")
+	})
+	s.Handle("notify", func(ctx context.Context, req *Request) (interface{}, error) {
+		if notified != nil {
+			atomic.AddInt32(notified, 1)
+		}
+		return nil, nil
+	})
+	return s
+}
+
+func TestServe(t *testing.T) {
+	var notified int32
+	s := testServer(&notified)
+	tests := []struct {
+		name, request, want string
+	}{
+		{"call", `{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2,3]}`,
+			`{"jsonrpc":"2.0","id":1,"result":6}`},
+		{"named params", `{"jsonrpc":"2.0","id":"a","method":"echo","params":{"x":1}}`,
+			`{"jsonrpc":"2.0","id":"a","result":{"x":1}}`},
+		{"notification", `{"jsonrpc":"2.0","method":"notify"}`, ``},
+		{"unknown notification", `{"jsonrpc":"2.0","method":"nope"}`, ``},
+		{"null id", `{"jsonrpc":"2.0","id":null,"method":"echo"}`,
+			`{"jsonrpc":"2.0","id":null,"result":null}`},
+		{"not found", `{"jsonrpc":"2.0","id":2,"method":"nope"}`,
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found","data":"nope"}}`},
+		{"invalid params", `{"jsonrpc":"2.0","id":3,"method":"add","params":{"a":1}}`,
+			`{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"Invalid params","data":"json: cannot unmarshal object into Go value of type []int"}}`},
+		{"handler error", `{"jsonrpc":"2.0","id":4,"method":"fail"}`,
+			`{"jsonrpc":"2.0","id":4,"error":{"code":-32603,"message":"boom"}}`},
+		{"panic", `{"jsonrpc":"2.0","id":5,"method":"panic"}`,
+			`{"jsonrpc":"2.0","id":5,"error":{"code":-32603,"message":"oops"}}`},
+		{"parse error", `{"jsonrpc":"2.0",`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`},
+		{"wrong version", `{"jsonrpc":"1.0","id":6,"method":"echo"}`,
+			`{"jsonrpc":"2.0","id":6,"error":{"code":-32600,"message":"Invalid Request","data":"jsonrpc must be \"2.0\""}}`},
+		{"bad params", `{"jsonrpc":"2.0","id":7,"method":"echo","params":3}`,
+			`{"jsonrpc":"2.0","id":7,"error":{"code":-32600,"message":"Invalid Request","data":"params must be an array or object"}}`},
+		{"empty batch", `[]`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"a batch must not be empty"}}`},
+		{"batch", `[{"jsonrpc":"2.0","id":1,"method":"add","params":[1]},{"jsonrpc":"2.0","method":"notify"},1,{"jsonrpc":"2.0","id":2,"method":"nope"}]`,
+			`[{"jsonrpc":"2.0","id":1,"result":1},` +
+				`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid Request","data":"a request must be an object"}},` +
+				`{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"Method not found","data":"nope"}}]`},
+		{"batch of notifications", `[{"jsonrpc":"2.0","method":"notify"},{"jsonrpc":"2.0","method":"notify"}]`, ``},
+	}
+	for _, tt := range tests {
+		got := string(s.Serve(context.Background(), []byte(tt.request)))
+		if got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+	if notified != 4 {
+		t.Errorf("notify ran %d times, want 4", notified)
+	}
+	if got := strings.Join(s.Methods(), ","); got != "add,echo,fail,notify,panic" {
+		t.Errorf("Methods() = %s", got)
+	}
+}
+
+func TestStreamFraming(t *testing.T) {
+	for _, framing := range []Framing{Lines, Headers} {
+		var buf bytes.Buffer
+		w := NewStream(nil, &buf, nil, framing)
+		w.Write([]byte(`{"a":1}`))
+		w.Write([]byte(`{"b":"x\ny"}`))
+
+		r := NewStream(&buf, nil, nil, framing)
+		for _, want := range []string{`{"a":1}`, `{"b":"x\ny"}`} {
+			msg, err := r.Read()
+			if err != nil || string(msg) != want {
+				t.Errorf("framing %d: Read() = %s, %v, want %s", framing, msg, err, want)
+			}
+		}
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("framing %d: Read() at the end = %v, want io.EOF", framing, err)
+		}
+	}
+
+	r := NewStream(strings.NewReader("Content-Type: x\r\nContent-Length: 2\r\n\r\n{}Content-Length: 9\r\n\r\n{"), nil, nil, Headers)
+	if msg, err := r.Read(); err != nil || string(msg) != "{}" {
+		t.Errorf("Read() = %s, %v", msg, err)
+	}
+	if _, err := r.Read(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Read() of a truncated message = %v", err)
+	}
+	if _, err := ParseFraming("xml"); err == nil {
+		t.Error("expected an error for an unknown framing")
+	}
+}
+
+// pipe connects two Conns, each of which serves server
+func pipe(a, b *Server) (*Conn, *Conn) {
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+	left := NewConn(NewStream(ar, aw, aw, Lines), a)
+	right := NewConn(NewStream(br, bw, bw, Lines), b)
+	go left.Run(context.Background())
+	go right.Run(context.Background())
+	return left, right
+}
+
+func TestConn(t *testing.T) {
+	var notified int32
+	client, server := pipe(nil, testServer(&notified))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := client.Call(ctx, "add", []int{2, 3})
+	if err != nil || string(result) != "5" {
+		t.Errorf("Call() = %s, %v", result, err)
+	}
+	_, err = client.Call(ctx, "fail", nil)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != InternalError || rpcErr.Message != "boom" {
+		t.Errorf("Call() of a failing method = %v", err)
+	}
+
+	responses, err := client.Batch(ctx, []Call{
+		{Method: "echo", Params: map[string]int{"n": 1}},
+		{Method: "notify", Notify: true},
+		{Method: "nope"},
+	}, true)
+	if err != nil {
+		t.Fatalf("Batch() failed: %v", err)
+	}
+	if string(responses[0].Result) != `{"n":1}` || responses[1] != nil || responses[2].Error.Code != MethodNotFound {
+		t.Errorf("Batch() = %+v %+v %+v", responses[0], responses[1], responses[2])
+	}
+	if err := client.Notify("notify", nil); err != nil {
+		t.Errorf("Notify() failed: %v", err)
+	}
+
+	// The serving end calls back: the client has no methods
+	_, err = server.Call(ctx, "add", []int{1})
+	if !errors.As(err, &rpcErr) || rpcErr.Code != MethodNotFound {
+		t.Errorf("Call() to an end without methods = %v", err)
+	}
+
+	for atomic.LoadInt32(&notified) != 2 && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&notified); n != 2 {
+		t.Errorf("notify ran %d times, want 2", n)
+	}
+
+	server.Close()
+	if _, err := client.Call(ctx, "add", []int{1}); err == nil {
+		t.Error("expected calls to fail once the connection is closed")
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	s := testServer(nil)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		res := s.Serve(r.Context(), data)
+		if res == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	}))
+	defer ts.Close()
+
+	client := &HTTPClient{URL: ts.URL, Header: http.Header{"Authorization": {"Bearer t"}}}
+	ctx := context.Background()
+	if result, err := client.Call(ctx, "add", []int{4, 5}); err != nil || string(result) != "9" {
+		t.Errorf("Call() = %s, %v", result, err)
+	}
+	if err := client.Notify(ctx, "notify", nil); err != nil {
+		t.Errorf("Notify() failed: %v", err)
+	}
+	responses, err := client.Batch(ctx, []Call{{Method: "add", Params: []int{1}}, {Method: "notify", Notify: true}, {Method: "add", Params: []int{2}}})
+	if err != nil || string(responses[0].Result) != "1" || responses[1] != nil || string(responses[2].Result) != "2" {
+		t.Errorf("Batch() = %v, %v", responses, err)
+	}
+
+	unauthorized := &HTTPClient{URL: ts.URL}
+	if _, err := unauthorized.Call(ctx, "add", []int{1}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Call() without credentials = %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+// Package jsonrpc implements JSON-RPC 2.0: a Server dispatching requests,
+// batches and notifications to handlers, a Conn that both serves and
+// calls over a stream of messages, such as stdio with line or
+// Content-Length framing, and an HTTPClient.
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Error codes defined by JSON-RPC 2.0; -32000 to -32099 are left to
+// servers
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Error is the error of a response
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// NewError creates an error with data, which is left out when it is nil
+// or can't be marshaled
+func NewError(code int, message string, data interface{}) *Error {
+	e := &Error{Code: code, Message: message}
+	if data != nil {
+		e.Data, _ = marshal(data)
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// Request is a call or, without an ID, a notification
+type Request struct {
+	ID     json.RawMessage
+	Method string
+	Params json.RawMessage
+}
+
+// IsNotification reports whether r expects no response
+func (r *Request) IsNotification() bool {
+	return r.ID == nil
+}
+
+// MarshalJSON writes r with its jsonrpc version
+func (r *Request) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id,omitempty"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+	}{"2.0", r.ID, r.Method, r.Params})
+}
+
+// Response is the result or error of a call
+type Response struct {
+	ID     json.RawMessage
+	Result json.RawMessage
+	Error  *Error
+}
+
+// MarshalJSON writes r with its jsonrpc version and either its result or
+// its error
+func (r *Response) MarshalJSON() ([]byte, error) {
+	id := r.ID
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if r.Error != nil {
+		return json.Marshal(struct {
+			JSONRPC string          `json:"jsonrpc"`
+			ID      json.RawMessage `json:"id"`
+			Error   *Error          `json:"error"`
+		}{"2.0", id, r.Error})
+	}
+	result := r.Result
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	return json.Marshal(struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  json.RawMessage `json:"result"`
+	}{"2.0", id, result})
+}
+
+// decodeRequest checks a request, returning the error to respond with when
+// it is invalid and the ID, if any could be read, to respond to
+func decodeRequest(data json.RawMessage) (*Request, *Error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return &Request{}, NewError(InvalidRequest, "Invalid Request", "a request must be an object")
+	}
+	req := &Request{}
+	if id, ok := fields["id"]; ok {
+		if !validID(id) {
+			return req, NewError(InvalidRequest, "Invalid Request", "id must be a string, number or null")
+		}
+		req.ID = id
+	}
+	var version string
+	if json.Unmarshal(fields["jsonrpc"], &version) != nil || version != "2.0" {
+		return req, NewError(InvalidRequest, "Invalid Request", `jsonrpc must be "2.0"`)
+	}
+	if json.Unmarshal(fields["method"], &req.Method) != nil || req.Method == "" {
+		return req, NewError(InvalidRequest, "Invalid Request", "method must be a non-empty string")
+	}
+	if params, ok := fields["params"]; ok {
+		if first := firstByte(params); first != '[' && first != '{' {
+			return req, NewError(InvalidRequest, "Invalid Request", "params must be an array or object")
+		}
+		req.Params = params
+	}
+	return req, nil
+}
+
+// decodeResponse reads a response, or reports that data is not one
+func decodeResponse(data json.RawMessage) (*Response, bool) {
+	var fields struct {
+		ID     json.RawMessage `json:"id"`
+		Method *string         `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil || fields.Method != nil || fields.ID == nil {
+		return nil, false
+	}
+	if fields.Result == nil && fields.Error == nil {
+		return nil, false
+	}
+	return &Response{ID: fields.ID, Result: fields.Result, Error: fields.Error}, true
+}
+
+func validID(id json.RawMessage) bool {
+	switch first := firstByte(id); {
+	case first == '"' || first == 'n':
+		return true
+	case first == '-' || (first >= '0' && first <= '9'):
+		return true
+	}
+	return false
+}
+
+func firstByte(data []byte) byte {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
+}
+
+// marshal encodes v, passing json.RawMessage through
+func marshal(v interface{}) (json.RawMessage, error) {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(v)
+}
+
+// isBatch reports whether data is an array of messages
+func isBatch(data []byte) bool {
+	return firstByte(data) == '['
+}
@@ -0,0 +1,153 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Handler answers a request with its result, which is marshaled unless it
+// is a json.RawMessage, or an error. An *Error is sent as it is and other
+// errors as InternalError.
+type Handler func(ctx context.Context, req *Request) (interface{}, error)
+
+// Server dispatches requests to the handlers of their methods
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]Handler
+}
+
+// NewServer creates a server without methods
+func NewServer() *Server {
+	return &Server{methods: make(map[string]Handler)}
+}
+
+// Handle registers h for method, replacing any handler it had
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = h
+}
+
+// Remove unregisters method
+func (s *Server) Remove(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.methods, method)
+}
+
+// Methods returns the names of the registered methods in order
+func (s *Server) Methods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Serve answers a request or batch of requests, running the requests of a
+// batch concurrently. It returns nil when nothing is to be sent back,
+// because there were only notifications.
+func (s *Server) Serve(ctx context.Context, data []byte) []byte {
+	if !json.Valid(data) {
+		return encode(&Response{Error: NewError(ParseError, "Parse error", nil)})
+	}
+	if !isBatch(data) {
+		res := s.call(ctx, data)
+		if res == nil {
+			return nil
+		}
+		return encode(res)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil || len(items) == 0 {
+		return encode(&Response{Error: NewError(InvalidRequest, "Invalid Request", "a batch must not be empty")})
+	}
+	responses := make([]*Response, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item json.RawMessage) {
+			defer wg.Done()
+			responses[i] = s.call(ctx, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	var out []*Response
+	for _, res := range responses {
+		if res != nil {
+			out = append(out, res)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return encode(out)
+}
+
+// call answers one request, or returns nil for a notification
+func (s *Server) call(ctx context.Context, data json.RawMessage) *Response {
+	req, invalid := decodeRequest(data)
+	if invalid != nil {
+		return &Response{ID: req.ID, Error: invalid}
+	}
+
+	s.mu.RLock()
+	h := s.methods[req.Method]
+	s.mu.RUnlock()
+
+	var res *Response
+	if h == nil {
+		res = &Response{ID: req.ID, Error: NewError(MethodNotFound, "Method not found", req.Method)}
+	} else {
+		res = s.run(ctx, h, req)
+	}
+	if req.IsNotification() {
+		return nil
+	}
+	return res
+}
+
+func (s *Server) run(ctx context.Context, h Handler, req *Request) (res *Response) {
+	res = &Response{ID: req.ID}
+	defer func() {
+		if r := recover(); r != nil {
+			res.Result = nil
+			res.Error = NewError(InternalError, fmt.Sprint(r), nil)
+		}
+	}()
+	result, err := h(ctx, req)
+	if err != nil {
+		res.Error = toError(err)
+		return res
+	}
+	if result == nil {
+		return res
+	}
+	if res.Result, err = marshal(result); err != nil {
+		res.Error = NewError(InternalError, err.Error(), nil)
+	}
+	return res
+}
+
+func toError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return NewError(InternalError, err.Error(), nil)
+}
+
+func encode(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(&Response{Error: NewError(InternalError, err.Error(), nil)})
+	}
+	return data
+}
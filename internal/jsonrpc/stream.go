@@ -0,0 +1,146 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxMessageSize bounds the messages a stream reads
+const MaxMessageSize = 64 << 20
+
+// Framing is how a byte stream delimits messages
+type Framing int
+
+const (
+	// Lines puts each message on a line of its own
+	Lines Framing = iota
+	// Headers precedes each message with a Content-Length header and a
+	// blank line, as the Language Server Protocol does
+	Headers
+)
+
+// ParseFraming reads the name of a framing: "lines", the default, or
+// "headers"
+func ParseFraming(name string) (Framing, error) {
+	switch name {
+	case "", "lines":
+		return Lines, nil
+	case "headers":
+		return Headers, nil
+	}
+	return 0, fmt.Errorf("framing must be 'lines' or 'headers', got %q", name)
+}
+
+// Stream carries whole messages
+type Stream interface {
+	// Read returns the next message, or io.EOF after the last
+	Read() ([]byte, error)
+	Write(msg []byte) error
+	Close() error
+}
+
+// framedStream reads and writes messages on a byte stream
+type framedStream struct {
+	r       *bufio.Reader
+	w       io.Writer
+	closer  io.Closer
+	framing Framing
+	mu      sync.Mutex
+}
+
+// NewStream frames messages on r and w. Closing the stream closes closer,
+// when it isn't nil.
+func NewStream(r io.Reader, w io.Writer, closer io.Closer, framing Framing) Stream {
+	return &framedStream{r: bufio.NewReader(r), w: w, closer: closer, framing: framing}
+}
+
+func (s *framedStream) Read() ([]byte, error) {
+	if s.framing == Headers {
+		return s.readHeaders()
+	}
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			if err == io.EOF && len(bytes.TrimSpace(line)) > 0 {
+				return line, nil
+			}
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// readLine reads up to a newline, failing for lines beyond MaxMessageSize
+func (s *framedStream) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := s.r.ReadLine()
+		line = append(line, chunk...)
+		if len(line) > MaxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", MaxMessageSize)
+		}
+		if err != nil || !isPrefix {
+			return line, err
+		}
+	}
+}
+
+func (s *framedStream) readHeaders() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			if length < 0 {
+				continue
+			}
+			break
+		}
+		name, value, ok := strings.Cut(string(line), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 || n > MaxMessageSize {
+				return nil, fmt.Errorf("invalid Content-Length %q", strings.TrimSpace(value))
+			}
+			length = n
+		}
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(s.r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *framedStream) Write(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.framing == Headers {
+		if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(msg)); err != nil {
+			return err
+		}
+		_, err := s.w.Write(msg)
+		return err
+	}
+	_, err := s.w.Write(append(bytes.TrimRight(msg, "\r\n"), '\n'))
+	return err
+}
+
+func (s *framedStream) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
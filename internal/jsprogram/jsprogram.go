@@ -0,0 +1,44 @@
+// Package jsprogram compiles the JavaScript that sets up built-in globals
+// once per process. Compiled programs are immutable and shared by every
+// runtime, so runtimes after the first skip parsing it.
+package jsprogram
+
+import (
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+)
+
+var programs sync.Map // name -> *goja.Program
+
+// Run runs src in vm, compiling it the first time name is seen. name must
+// identify src: a second call with the same name reuses the first program.
+func Run(vm *goja.Runtime, name, src string) (goja.Value, error) {
+	program, err := compile(name, src)
+	if err != nil {
+		return nil, err
+	}
+	return vm.RunProgram(program)
+}
+
+func compile(name, src string) (*goja.Program, error) {
+	if program, ok := programs.Load(name); ok {
+		return program.(*goja.Program), nil
+	}
+	program, err := goja.Compile(name, src, false)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := programs.LoadOrStore(name, program)
+	return actual.(*goja.Program), nil
+}
+
+// Count returns the number of programs compiled so far
+func Count() int {
+	n := 0
+	programs.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
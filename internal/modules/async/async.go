@@ -0,0 +1,330 @@
+package async
+
+import (
+	"container/heap"
+	"math"
+	"time"
+)
+
+// Clock abstracts time so the utilities can run on the runtime's timers
+// and be driven by a fake clock in tests
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, fn func()) (stop func())
+}
+
+// DebounceOptions mirror lodash's debounce options. MaxWait 0 means no
+// maximum.
+type DebounceOptions struct {
+	Wait     time.Duration
+	MaxWait  time.Duration
+	Leading  bool
+	Trailing bool
+}
+
+// Debouncer delays calls to invoke until Wait has passed without another
+// Call. It follows lodash's algorithm, so a Debouncer with Leading and
+// MaxWait equal to Wait is a throttle. It is not safe for concurrent use;
+// the bridge only touches it on the JS thread.
+type Debouncer struct {
+	opts   DebounceOptions
+	clock  Clock
+	invoke func(args interface{}) interface{}
+
+	result     interface{}
+	lastArgs   interface{}
+	hasArgs    bool
+	lastCall   time.Time
+	hasCall    bool
+	lastInvoke time.Time
+	stop       func()
+}
+
+// NewDebouncer creates a debouncer that calls invoke with the arguments of
+// the most recent Call
+func NewDebouncer(clock Clock, opts DebounceOptions, invoke func(args interface{}) interface{}) *Debouncer {
+	if opts.MaxWait > 0 && opts.MaxWait < opts.Wait {
+		opts.MaxWait = opts.Wait
+	}
+	return &Debouncer{opts: opts, clock: clock, invoke: invoke}
+}
+
+// Call records a call and returns the result of the latest invocation
+func (d *Debouncer) Call(args interface{}) interface{} {
+	now := d.clock.Now()
+	invoking := d.shouldInvoke(now)
+
+	d.lastArgs, d.hasArgs = args, true
+	d.lastCall, d.hasCall = now, true
+
+	if invoking {
+		if d.stop == nil {
+			return d.leadingEdge(now)
+		}
+		if d.opts.MaxWait > 0 {
+			d.stop()
+			d.stop = d.clock.AfterFunc(d.opts.Wait, d.timerExpired)
+			return d.invokeAt(now)
+		}
+	}
+	if d.stop == nil {
+		d.stop = d.clock.AfterFunc(d.opts.Wait, d.timerExpired)
+	}
+	return d.result
+}
+
+// Cancel drops any pending trailing invocation
+func (d *Debouncer) Cancel() {
+	if d.stop != nil {
+		d.stop()
+	}
+	d.lastInvoke = time.Time{}
+	d.lastArgs, d.hasArgs = nil, false
+	d.hasCall = false
+	d.stop = nil
+}
+
+// Flush runs a pending trailing invocation immediately
+func (d *Debouncer) Flush() interface{} {
+	if d.stop == nil {
+		return d.result
+	}
+	d.stop()
+	return d.trailingEdge(d.clock.Now())
+}
+
+// Pending reports whether a trailing invocation is scheduled
+func (d *Debouncer) Pending() bool {
+	return d.stop != nil
+}
+
+func (d *Debouncer) invokeAt(now time.Time) interface{} {
+	args := d.lastArgs
+	d.lastArgs, d.hasArgs = nil, false
+	d.lastInvoke = now
+	d.result = d.invoke(args)
+	return d.result
+}
+
+func (d *Debouncer) leadingEdge(now time.Time) interface{} {
+	d.lastInvoke = now
+	d.stop = d.clock.AfterFunc(d.opts.Wait, d.timerExpired)
+	if d.opts.Leading {
+		return d.invokeAt(now)
+	}
+	return d.result
+}
+
+func (d *Debouncer) trailingEdge(now time.Time) interface{} {
+	d.stop = nil
+	if d.opts.Trailing && d.hasArgs {
+		return d.invokeAt(now)
+	}
+	d.lastArgs, d.hasArgs = nil, false
+	return d.result
+}
+
+func (d *Debouncer) timerExpired() {
+	now := d.clock.Now()
+	if d.shouldInvoke(now) {
+		d.trailingEdge(now)
+		return
+	}
+	d.stop = d.clock.AfterFunc(d.remainingWait(now), d.timerExpired)
+}
+
+func (d *Debouncer) shouldInvoke(now time.Time) bool {
+	if !d.hasCall {
+		return true
+	}
+	sinceCall := now.Sub(d.lastCall)
+	return sinceCall >= d.opts.Wait || sinceCall < 0 ||
+		d.opts.MaxWait > 0 && now.Sub(d.lastInvoke) >= d.opts.MaxWait
+}
+
+func (d *Debouncer) remainingWait(now time.Time) time.Duration {
+	wait := d.opts.Wait - now.Sub(d.lastCall)
+	if d.opts.MaxWait > 0 {
+		if untilMax := d.opts.MaxWait - now.Sub(d.lastInvoke); untilMax < wait {
+			wait = untilMax
+		}
+	}
+	return wait
+}
+
+// Backoff computes retry delays the way p-retry does: MinTimeout grown by
+// Factor per attempt, capped at MaxTimeout, optionally randomized by up to 2x
+type Backoff struct {
+	Retries    int
+	Factor     float64
+	MinTimeout time.Duration
+	MaxTimeout time.Duration // 0 means no cap
+	Randomize  bool
+}
+
+// DefaultBackoff matches p-retry's defaults
+func DefaultBackoff() Backoff {
+	return Backoff{Retries: 10, Factor: 2, MinTimeout: time.Second}
+}
+
+// Delay returns the wait before retry number attempt (starting at 1). random
+// is a value in [0, 1) used when Randomize is set.
+func (b Backoff) Delay(attempt int, random float64) time.Duration {
+	scale := 1.0
+	if b.Randomize {
+		scale += random
+	}
+
+	delay := time.Duration(math.Round(scale * float64(b.MinTimeout) * math.Pow(b.Factor, float64(attempt-1))))
+	if b.MaxTimeout > 0 && delay > b.MaxTimeout {
+		delay = b.MaxTimeout
+	}
+	return delay
+}
+
+// Queue runs tasks with bounded concurrency, higher priority first and
+// FIFO within a priority. Each task's start function must call Done when
+// the task finishes. Like Debouncer it is meant for the JS thread and is
+// not safe for concurrent use.
+type Queue struct {
+	concurrency int
+	running     int
+	tasks       taskHeap
+	seq         uint64
+	paused      bool
+	onEmpty     []func()
+	onIdle      []func()
+}
+
+type task struct {
+	priority int
+	seq      uint64
+	start    func()
+}
+
+// NewQueue creates a queue; concurrency <= 0 means unlimited
+func NewQueue(concurrency int) *Queue {
+	if concurrency <= 0 {
+		concurrency = math.MaxInt32
+	}
+	return &Queue{concurrency: concurrency}
+}
+
+// Add schedules start to run when a slot is free
+func (q *Queue) Add(priority int, start func()) {
+	q.seq++
+	heap.Push(&q.tasks, &task{priority: priority, seq: q.seq, start: start})
+	q.next()
+}
+
+// Done marks a running task as finished
+func (q *Queue) Done() {
+	q.running--
+	q.next()
+	if q.running == 0 && len(q.tasks) == 0 {
+		q.fire(&q.onIdle)
+	}
+}
+
+// Pause stops starting new tasks; running ones continue
+func (q *Queue) Pause() {
+	q.paused = true
+}
+
+// Start resumes a paused queue
+func (q *Queue) Start() {
+	q.paused = false
+	q.next()
+}
+
+// Clear drops every task that has not started and returns how many there were
+func (q *Queue) Clear() int {
+	n := len(q.tasks)
+	q.tasks = nil
+	q.fire(&q.onEmpty)
+	if q.running == 0 {
+		q.fire(&q.onIdle)
+	}
+	return n
+}
+
+// Size returns the number of tasks waiting to start
+func (q *Queue) Size() int {
+	return len(q.tasks)
+}
+
+// Pending returns the number of running tasks
+func (q *Queue) Pending() int {
+	return q.running
+}
+
+// Paused reports whether the queue is paused
+func (q *Queue) Paused() bool {
+	return q.paused
+}
+
+// SetConcurrency changes the limit, starting queued tasks if it grew
+func (q *Queue) SetConcurrency(n int) {
+	if n <= 0 {
+		n = math.MaxInt32
+	}
+	q.concurrency = n
+	q.next()
+}
+
+// OnEmpty calls fn once no tasks are waiting, immediately if that is already so
+func (q *Queue) OnEmpty(fn func()) {
+	if len(q.tasks) == 0 {
+		fn()
+		return
+	}
+	q.onEmpty = append(q.onEmpty, fn)
+}
+
+// OnIdle calls fn once nothing is waiting or running, immediately if that
+// is already so
+func (q *Queue) OnIdle(fn func()) {
+	if len(q.tasks) == 0 && q.running == 0 {
+		fn()
+		return
+	}
+	q.onIdle = append(q.onIdle, fn)
+}
+
+func (q *Queue) next() {
+	for !q.paused && q.running < q.concurrency && len(q.tasks) > 0 {
+		t := heap.Pop(&q.tasks).(*task)
+		q.running++
+		if len(q.tasks) == 0 {
+			q.fire(&q.onEmpty)
+		}
+		t.start()
+	}
+}
+
+func (q *Queue) fire(callbacks *[]func()) {
+	fns := *callbacks
+	*callbacks = nil
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// taskHeap orders tasks by descending priority, then insertion order
+type taskHeap []*task
+
+func (h taskHeap) Len() int { return len(h) }
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h taskHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*task)) }
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,167 @@
+package async
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock runs timers when advanced
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	fn      func()
+	stopped bool
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) AfterFunc(d time.Duration, fn func()) func() {
+	t := &fakeTimer{at: c.now.Add(d), fn: fn}
+	c.timers = append(c.timers, t)
+	return func() { t.stopped = true }
+}
+
+// advance moves time forward in 1ms steps, firing due timers in order
+func (c *fakeClock) advance(d time.Duration) {
+	end := c.now.Add(d)
+	for !c.now.After(end) {
+		for i := 0; i < len(c.timers); i++ {
+			t := c.timers[i]
+			if !t.stopped && !t.at.After(c.now) {
+				t.stopped = true
+				t.fn()
+			}
+		}
+		c.now = c.now.Add(time.Millisecond)
+	}
+	c.now = end
+}
+
+func TestDebounceTrailing(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var calls []interface{}
+	d := NewDebouncer(clock, DebounceOptions{Wait: 100 * time.Millisecond, Trailing: true}, func(args interface{}) interface{} {
+		calls = append(calls, args)
+		return args
+	})
+
+	d.Call(1)
+	clock.advance(50 * time.Millisecond)
+	d.Call(2)
+	clock.advance(50 * time.Millisecond)
+	d.Call(3)
+	if len(calls) != 0 {
+		t.Fatalf("Expected no calls yet, got %v", calls)
+	}
+
+	clock.advance(150 * time.Millisecond)
+	if len(calls) != 1 || calls[0] != 3 {
+		t.Errorf("Expected a single trailing call with 3, got %v", calls)
+	}
+
+	d.Call(4)
+	d.Cancel()
+	clock.advance(200 * time.Millisecond)
+	if len(calls) != 1 {
+		t.Errorf("Expected cancel to drop the pending call, got %v", calls)
+	}
+
+	d.Call(5)
+	if got := d.Flush(); got != 5 || len(calls) != 2 {
+		t.Errorf("Expected flush to invoke immediately, got %v %v", got, calls)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var calls []interface{}
+	wait := 100 * time.Millisecond
+	d := NewDebouncer(clock, DebounceOptions{Wait: wait, MaxWait: wait, Leading: true, Trailing: true}, func(args interface{}) interface{} {
+		calls = append(calls, args)
+		return nil
+	})
+
+	// Calls every 10ms for 250ms should invoke on the leading edge and
+	// then about once per wait
+	for i := 0; i < 25; i++ {
+		d.Call(i)
+		clock.advance(10 * time.Millisecond)
+	}
+	clock.advance(200 * time.Millisecond)
+
+	if len(calls) != 4 || calls[0] != 0 || calls[len(calls)-1] != 24 {
+		t.Errorf("Expected leading call, two throttled calls and a trailing call, got %v", calls)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Factor: 2, MinTimeout: 100 * time.Millisecond, MaxTimeout: time.Second}
+	want := []time.Duration{100, 200, 400, 800, 1000}
+	for i, w := range want {
+		if got := b.Delay(i+1, 0); got != w*time.Millisecond {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, w*time.Millisecond, got)
+		}
+	}
+
+	b.Randomize = true
+	if got := b.Delay(1, 0.5); got != 150*time.Millisecond {
+		t.Errorf("Expected randomized delay 150ms, got %v", got)
+	}
+}
+
+func TestQueueConcurrencyAndPriority(t *testing.T) {
+	q := NewQueue(2)
+	var started []string
+	add := func(name string, priority int) {
+		q.Add(priority, func() { started = append(started, name) })
+	}
+
+	add("a", 0)
+	add("b", 0)
+	add("low", 0)
+	add("high", 5)
+
+	if q.Pending() != 2 || q.Size() != 2 {
+		t.Fatalf("Expected 2 running and 2 waiting, got %d and %d", q.Pending(), q.Size())
+	}
+
+	idle := false
+	q.OnIdle(func() { idle = true })
+
+	q.Done()
+	q.Done()
+	if len(started) != 4 || started[2] != "high" || started[3] != "low" {
+		t.Errorf("Expected high priority task to start first, got %v", started)
+	}
+
+	q.Done()
+	q.Done()
+	if !idle {
+		t.Error("Expected OnIdle to fire once all tasks finished")
+	}
+}
+
+func TestQueuePauseAndClear(t *testing.T) {
+	q := NewQueue(1)
+	q.Pause()
+
+	ran := 0
+	for i := 0; i < 3; i++ {
+		q.Add(0, func() { ran++ })
+	}
+	if ran != 0 {
+		t.Fatal("Expected paused queue not to start tasks")
+	}
+
+	q.Start()
+	if ran != 1 {
+		t.Fatalf("Expected one task to start, got %d", ran)
+	}
+	if cleared := q.Clear(); cleared != 2 {
+		t.Errorf("Expected 2 cleared tasks, got %d", cleared)
+	}
+}
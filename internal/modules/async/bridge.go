@@ -0,0 +1,452 @@
+package async
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Bridge provides JavaScript bindings for the gode:async module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+	clock   Clock
+}
+
+// timerClock schedules on the runtime's timers so pending debounces,
+// retries and waits keep the event loop alive like setTimeout does
+type timerClock struct {
+	timers Timers
+	vm     *goja.Runtime
+}
+
+func (c timerClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c timerClock) AfterFunc(d time.Duration, fn func()) func() {
+	// Round up so a timer never fires before the deadline it was computed for
+	ms := int64((d + time.Millisecond - 1) / time.Millisecond)
+	id := c.timers.SetTimeout(c.vm.ToValue(fn), ms)
+	return func() { c.timers.ClearTimeout(id) }
+}
+
+// NewBridge creates a new async bridge
+func NewBridge(runtime RuntimeInterface, timers Timers) *Bridge {
+	vm := runtime.GetGojaRuntime()
+	return &Bridge{
+		runtime: runtime,
+		vm:      vm,
+		clock:   timerClock{timers: timers, vm: vm},
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("debounce", b.debounce)
+	exports.Set("throttle", b.throttle)
+	exports.Set("pRetry", b.pRetry)
+	exports.Set("pLimit", b.pLimit)
+	exports.Set("pQueue", b.pQueue)
+	exports.Set("waitFor", b.waitFor)
+	return exports
+}
+
+// debounce implements async.debounce(fn, wait, {leading, trailing, maxWait})
+func (b *Bridge) debounce(call goja.FunctionCall) goja.Value {
+	opts, _ := call.Argument(2).(*goja.Object)
+	debounce := DebounceOptions{
+		Wait:     b.millis(call.Argument(1), 0),
+		MaxWait:  b.millis(b.option(opts, "maxWait"), 0),
+		Leading:  b.boolOption(opts, "leading", false),
+		Trailing: b.boolOption(opts, "trailing", true),
+	}
+	return b.wrap(call.Argument(0), debounce)
+}
+
+// throttle implements async.throttle(fn, wait, {leading, trailing})
+func (b *Bridge) throttle(call goja.FunctionCall) goja.Value {
+	opts, _ := call.Argument(2).(*goja.Object)
+	wait := b.millis(call.Argument(1), 0)
+	throttle := DebounceOptions{
+		Wait:     wait,
+		MaxWait:  wait,
+		Leading:  b.boolOption(opts, "leading", true),
+		Trailing: b.boolOption(opts, "trailing", true),
+	}
+	return b.wrap(call.Argument(0), throttle)
+}
+
+// wrap returns a function driving a Debouncer, with cancel(), flush() and
+// pending() attached
+func (b *Bridge) wrap(fnValue goja.Value, opts DebounceOptions) goja.Value {
+	fn, ok := goja.AssertFunction(fnValue)
+	if !ok {
+		panic(b.vm.NewTypeError("expected a function"))
+	}
+
+	type invocation struct {
+		this goja.Value
+		args []goja.Value
+	}
+
+	d := NewDebouncer(b.clock, opts, func(args interface{}) interface{} {
+		inv := args.(invocation)
+		result, err := fn(inv.this, inv.args...)
+		if err != nil {
+			panic(err)
+		}
+		return result
+	})
+
+	result := func(value interface{}) goja.Value {
+		if value == nil {
+			return goja.Undefined()
+		}
+		return value.(goja.Value)
+	}
+
+	wrapped := b.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		return result(d.Call(invocation{this: call.This, args: call.Arguments}))
+	}).(*goja.Object)
+	wrapped.Set("cancel", d.Cancel)
+	wrapped.Set("flush", func() goja.Value { return result(d.Flush()) })
+	wrapped.Set("pending", d.Pending)
+	return wrapped
+}
+
+// pRetry implements async.pRetry(fn, {retries, factor, minTimeout,
+// maxTimeout, randomize, onFailedAttempt, shouldRetry}). fn receives the
+// attempt number; an error named AbortError stops retrying.
+func (b *Bridge) pRetry(call goja.FunctionCall) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(b.vm.NewTypeError("pRetry expects a function"))
+	}
+
+	opts, _ := call.Argument(1).(*goja.Object)
+	backoff := DefaultBackoff()
+	if v := b.option(opts, "retries"); v != nil {
+		backoff.Retries = int(v.ToInteger())
+	}
+	if v := b.option(opts, "factor"); v != nil {
+		backoff.Factor = v.ToFloat()
+	}
+	backoff.MinTimeout = b.millis(b.option(opts, "minTimeout"), backoff.MinTimeout)
+	backoff.MaxTimeout = b.millis(b.option(opts, "maxTimeout"), 0)
+	backoff.Randomize = b.boolOption(opts, "randomize", false)
+
+	onFailedAttempt, _ := goja.AssertFunction(b.option(opts, "onFailedAttempt"))
+	shouldRetry, _ := goja.AssertFunction(b.option(opts, "shouldRetry"))
+
+	promise, resolve, reject := b.vm.NewPromise()
+
+	var attempt func(n int)
+	attempt = func(n int) {
+		failed := func(reason goja.Value) {
+			obj, isObject := reason.(*goja.Object)
+			if isObject && obj.Get("name") != nil && obj.Get("name").String() == "AbortError" {
+				reject(reason)
+				return
+			}
+
+			retriesLeft := backoff.Retries - (n - 1)
+			if isObject {
+				obj.Set("attemptNumber", n)
+				obj.Set("retriesLeft", retriesLeft)
+			}
+
+			if onFailedAttempt != nil {
+				if _, err := onFailedAttempt(goja.Undefined(), reason); err != nil {
+					reject(b.errorValue(err))
+					return
+				}
+			}
+			if retriesLeft <= 0 {
+				reject(reason)
+				return
+			}
+			if shouldRetry != nil {
+				retry, err := shouldRetry(goja.Undefined(), reason)
+				if err != nil {
+					reject(b.errorValue(err))
+					return
+				}
+				if !retry.ToBoolean() {
+					reject(reason)
+					return
+				}
+			}
+
+			b.clock.AfterFunc(backoff.Delay(n, rand.Float64()), func() { attempt(n + 1) })
+		}
+
+		result, err := fn(goja.Undefined(), b.vm.ToValue(n))
+		if err != nil {
+			failed(b.errorValue(err))
+			return
+		}
+		b.settle(result, func(v goja.Value) { resolve(v) }, failed)
+	}
+	attempt(1)
+
+	return b.vm.ToValue(promise)
+}
+
+// pLimit implements async.pLimit(concurrency), returning limit(fn, ...args)
+// with activeCount, pendingCount and clearQueue()
+func (b *Bridge) pLimit(concurrency int) goja.Value {
+	if concurrency < 1 {
+		panic(b.vm.NewTypeError("concurrency must be a positive integer"))
+	}
+	q := NewQueue(concurrency)
+
+	limit := b.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		// Copied, as call.Arguments is the VM's stack and queued tasks run
+		// after this call returns
+		var args []goja.Value
+		if len(call.Arguments) > 1 {
+			args = append([]goja.Value(nil), call.Arguments[1:]...)
+		}
+		return b.enqueue(q, call.Argument(0), args, 0)
+	}).(*goja.Object)
+
+	limit.DefineAccessorProperty("activeCount", b.vm.ToValue(q.Pending), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	limit.DefineAccessorProperty("pendingCount", b.vm.ToValue(q.Size), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	limit.Set("clearQueue", func() { q.Clear() })
+	return limit
+}
+
+// pQueue implements async.pQueue({concurrency, autoStart}) returning a queue
+// with add(fn, {priority}), addAll, onEmpty, onIdle, pause, start, clear,
+// size, pending, isPaused and concurrency
+func (b *Bridge) pQueue(call goja.FunctionCall) goja.Value {
+	opts, _ := call.Argument(0).(*goja.Object)
+
+	concurrency := 0
+	if v := b.option(opts, "concurrency"); v != nil && !math.IsInf(v.ToFloat(), 1) {
+		concurrency = int(v.ToInteger())
+	}
+	q := NewQueue(concurrency)
+	if !b.boolOption(opts, "autoStart", true) {
+		q.Pause()
+	}
+
+	priority := func(value goja.Value) int {
+		o, _ := value.(*goja.Object)
+		if v := b.option(o, "priority"); v != nil {
+			return int(v.ToInteger())
+		}
+		return 0
+	}
+
+	queue := b.vm.NewObject()
+	queue.Set("add", func(call goja.FunctionCall) goja.Value {
+		return b.enqueue(q, call.Argument(0), nil, priority(call.Argument(1)))
+	})
+	queue.Set("addAll", func(call goja.FunctionCall) goja.Value {
+		var fns []goja.Value
+		if err := b.vm.ExportTo(call.Argument(0), &fns); err != nil {
+			panic(b.vm.NewTypeError("addAll expects an array of functions"))
+		}
+		p := priority(call.Argument(1))
+		promises := make([]interface{}, len(fns))
+		for i, fn := range fns {
+			promises[i] = b.enqueue(q, fn, nil, p)
+		}
+
+		promiseAll, _ := goja.AssertFunction(b.vm.Get("Promise").ToObject(b.vm).Get("all"))
+		all, err := promiseAll(b.vm.Get("Promise"), b.vm.NewArray(promises...))
+		if err != nil {
+			panic(err)
+		}
+		return all
+	})
+	queue.Set("onEmpty", func() goja.Value {
+		promise, resolve, _ := b.vm.NewPromise()
+		q.OnEmpty(func() { resolve(goja.Undefined()) })
+		return b.vm.ToValue(promise)
+	})
+	queue.Set("onIdle", func() goja.Value {
+		promise, resolve, _ := b.vm.NewPromise()
+		q.OnIdle(func() { resolve(goja.Undefined()) })
+		return b.vm.ToValue(promise)
+	})
+	queue.Set("pause", q.Pause)
+	queue.Set("start", func() goja.Value {
+		q.Start()
+		return queue
+	})
+	queue.Set("clear", func() { q.Clear() })
+	queue.DefineAccessorProperty("size", b.vm.ToValue(q.Size), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	queue.DefineAccessorProperty("pending", b.vm.ToValue(q.Pending), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	queue.DefineAccessorProperty("isPaused", b.vm.ToValue(q.Paused), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	queue.DefineAccessorProperty("concurrency", b.vm.ToValue(func() int { return q.concurrency }),
+		b.vm.ToValue(func(n int) { q.SetConcurrency(n) }), goja.FLAG_TRUE, goja.FLAG_TRUE)
+
+	return queue
+}
+
+// enqueue adds fn(...args) to q and returns a Promise of its result
+func (b *Bridge) enqueue(q *Queue, fnValue goja.Value, args []goja.Value, priority int) goja.Value {
+	fn, ok := goja.AssertFunction(fnValue)
+	if !ok {
+		panic(b.vm.NewTypeError("expected a function"))
+	}
+
+	promise, resolve, reject := b.vm.NewPromise()
+	q.Add(priority, func() {
+		result, err := fn(goja.Undefined(), args...)
+		if err != nil {
+			reject(b.errorValue(err))
+			q.Done()
+			return
+		}
+		b.settle(result, func(v goja.Value) {
+			resolve(v)
+			q.Done()
+		}, func(reason goja.Value) {
+			reject(reason)
+			q.Done()
+		})
+	})
+	return b.vm.ToValue(promise)
+}
+
+// waitFor implements async.waitFor(predicate, {interval, timeout}). The
+// predicate may be async; the Promise resolves with its first truthy
+// result or rejects with a TimeoutError.
+func (b *Bridge) waitFor(call goja.FunctionCall) goja.Value {
+	predicate, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(b.vm.NewTypeError("waitFor expects a predicate function"))
+	}
+
+	opts, _ := call.Argument(1).(*goja.Object)
+	interval := b.millis(b.option(opts, "interval"), 20*time.Millisecond)
+	timeout := b.millis(b.option(opts, "timeout"), 0)
+
+	promise, resolve, reject := b.vm.NewPromise()
+	settled := false
+	var stopCheck, stopTimeout func()
+
+	finish := func() {
+		settled = true
+		if stopCheck != nil {
+			stopCheck()
+		}
+		if stopTimeout != nil {
+			stopTimeout()
+		}
+	}
+
+	if timeout > 0 {
+		stopTimeout = b.clock.AfterFunc(timeout, func() {
+			if settled {
+				return
+			}
+			finish()
+			err := b.vm.NewGoError(&timeoutError{timeout})
+			err.Set("name", "TimeoutError")
+			reject(err)
+		})
+	}
+
+	var check func()
+	check = func() {
+		stopCheck = nil
+		result, err := predicate(goja.Undefined())
+		if err != nil {
+			if !settled {
+				finish()
+				reject(b.errorValue(err))
+			}
+			return
+		}
+		b.settle(result, func(v goja.Value) {
+			if settled {
+				return
+			}
+			if v.ToBoolean() {
+				finish()
+				resolve(v)
+				return
+			}
+			stopCheck = b.clock.AfterFunc(interval, check)
+		}, func(reason goja.Value) {
+			if !settled {
+				finish()
+				reject(reason)
+			}
+		})
+	}
+	check()
+
+	return b.vm.ToValue(promise)
+}
+
+// timeoutError is the Go error behind waitFor's TimeoutError
+type timeoutError struct {
+	after time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return "promise timed out after " + e.after.String()
+}
+
+// settle calls onFulfilled or onRejected once value settles. Plain values
+// fulfil immediately.
+func (b *Bridge) settle(value goja.Value, onFulfilled, onRejected func(goja.Value)) {
+	if obj, ok := value.(*goja.Object); ok {
+		if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+			then(obj, b.vm.ToValue(onFulfilled), b.vm.ToValue(onRejected))
+			return
+		}
+	}
+	onFulfilled(value)
+}
+
+// errorValue unwraps a thrown JS value from a call error
+func (b *Bridge) errorValue(err error) goja.Value {
+	if ex, ok := err.(*goja.Exception); ok {
+		return ex.Value()
+	}
+	return b.vm.NewGoError(err)
+}
+
+// millis reads a millisecond option, returning def when it is unset.
+// Infinity also maps to def.
+func (b *Bridge) millis(value goja.Value, def time.Duration) time.Duration {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return def
+	}
+	ms := value.ToFloat()
+	if math.IsInf(ms, 1) || math.IsNaN(ms) {
+		return def
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	if obj == nil {
+		return nil
+	}
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
+
+func (b *Bridge) boolOption(obj *goja.Object, name string, def bool) bool {
+	if value := b.option(obj, name); value != nil {
+		return value.ToBoolean()
+	}
+	return def
+}
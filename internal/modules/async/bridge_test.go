@@ -0,0 +1,31 @@
+package async_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestPLimitArguments(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	// More tasks than the limit wait in the queue, and each must still get
+	// the arguments it was queued with
+	value, err := rt.RunScriptAsync("plimit", `
+		const { pLimit } = require('gode:async');
+		const limit = pLimit(2);
+		const task = (name, n) => new Promise(resolve => setTimeout(() => resolve(name + n), 5));
+		Promise.all(['a', 'b', 'c', 'd', 'e'].map((name, i) => limit(task, name, i)))
+			.then(results => results.join(','));
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if want := "a0,b1,c2,d3,e4"; value != want {
+		t.Errorf("pLimit results = %v, want %s", value, want)
+	}
+}
@@ -0,0 +1,29 @@
+package async
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// Timers is the runtime's timer scheduler, implemented by timers.TimersModule
+type Timers interface {
+	SetTimeout(callback goja.Value, delay int64, args ...goja.Value) int64
+	ClearTimeout(id int64)
+}
+
+// RegisterAsyncModule registers gode:async in the JavaScript runtime
+func RegisterAsyncModule(runtime RuntimeInterface, timers Timers) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime, timers)
+		runtime.RegisterModule("gode:async", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
@@ -0,0 +1,245 @@
+package asynchooks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/asynccontext"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// asyncHooksSetup defines AsyncLocalStorage and AsyncResource, and the
+// Variable and Snapshot of the AsyncContext proposal, over the context the
+// runtime follows through promise reactions, timers, immediates, server
+// handlers and plugin callbacks. Stores live in that context, so a store
+// set by run is seen by everything the callback leads to, and by nothing
+// else.
+//
+// native provides key(), a new storage key, get(key), the value stored
+// under key in the current context, run(key, value, fn), which calls fn
+// with value stored under key, enter(key, value), which stores value for
+// the rest of the running code, and snapshot() and restore(snapshot, fn),
+// which capture the current context and call fn in a captured one.
+const asyncHooksSetup = `
+(function (native) {
+	'use strict';
+	const kKey = Symbol('key');
+	const kEnabled = Symbol('enabled');
+	const kSnapshot = Symbol('snapshot');
+	const kType = Symbol('type');
+	const kName = Symbol('name');
+	const kDefault = Symbol('defaultValue');
+
+	function callable(fn) {
+		if (typeof fn !== 'function') {
+			throw new TypeError('The callback must be a function');
+		}
+		return fn;
+	}
+
+	class AsyncResource {
+		constructor(type) {
+			if (typeof type !== 'string' || type === '') {
+				throw new TypeError('AsyncResource requires a type');
+			}
+			this[kType] = type;
+			this[kSnapshot] = native.snapshot();
+		}
+
+		get type() {
+			return this[kType];
+		}
+
+		runInAsyncScope(fn, thisArg, ...args) {
+			callable(fn);
+			return native.restore(this[kSnapshot], () => fn.apply(thisArg, args));
+		}
+
+		bind(fn, thisArg) {
+			callable(fn);
+			const resource = this;
+			return function (...args) {
+				return resource.runInAsyncScope(fn, thisArg === undefined ? this : thisArg, ...args);
+			};
+		}
+
+		emitDestroy() {
+			return this;
+		}
+
+		static bind(fn, type, thisArg) {
+			callable(fn);
+			return new AsyncResource(type || fn.name || 'bound-anonymous-fn').bind(fn, thisArg);
+		}
+	}
+
+	class AsyncLocalStorage {
+		constructor() {
+			this[kKey] = native.key();
+			this[kEnabled] = true;
+		}
+
+		getStore() {
+			return this[kEnabled] ? native.get(this[kKey]) : undefined;
+		}
+
+		run(store, fn, ...args) {
+			callable(fn);
+			this[kEnabled] = true;
+			return native.run(this[kKey], store, () => fn(...args));
+		}
+
+		exit(fn, ...args) {
+			callable(fn);
+			return native.run(this[kKey], undefined, () => fn(...args));
+		}
+
+		enterWith(store) {
+			this[kEnabled] = true;
+			native.enter(this[kKey], store);
+		}
+
+		// disable makes getStore return undefined until the next run or
+		// enterWith
+		disable() {
+			this[kEnabled] = false;
+		}
+
+		static bind(fn) {
+			return AsyncResource.bind(fn, 'AsyncLocalStorage.bind');
+		}
+
+		static snapshot() {
+			const snapshot = native.snapshot();
+			return (fn, ...args) => native.restore(snapshot, () => callable(fn)(...args));
+		}
+	}
+
+	class Variable {
+		constructor(options) {
+			options = options || {};
+			this[kKey] = native.key();
+			this[kName] = options.name === undefined ? '' : String(options.name);
+			this[kDefault] = options.defaultValue;
+		}
+
+		get name() {
+			return this[kName];
+		}
+
+		get() {
+			// Values are boxed to tell undefined from no value
+			const box = native.get(this[kKey]);
+			return box === undefined ? this[kDefault] : box.value;
+		}
+
+		run(value, fn, ...args) {
+			callable(fn);
+			return native.run(this[kKey], { value }, () => fn(...args));
+		}
+	}
+
+	class Snapshot {
+		constructor() {
+			this[kSnapshot] = native.snapshot();
+		}
+
+		run(fn, ...args) {
+			callable(fn);
+			return native.restore(this[kSnapshot], () => fn(...args));
+		}
+
+		static wrap(fn) {
+			callable(fn);
+			const snapshot = new Snapshot();
+			return function (...args) {
+				return snapshot.run(() => fn.apply(this, args));
+			};
+		}
+	}
+
+	return {
+		AsyncLocalStorage,
+		AsyncResource,
+		AsyncContext: Object.freeze({ Variable, Snapshot }),
+	};
+})
+`
+
+// Bridge provides JavaScript bindings for gode:async_hooks
+type Bridge struct {
+	vm      *goja.Runtime
+	tracker *asynccontext.Tracker
+	keys    int64 // storage keys handed out, see native.key
+}
+
+// NewBridge creates a new async_hooks bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		vm:      runtime.GetGojaRuntime(),
+		tracker: runtime.AsyncContext(),
+	}
+}
+
+// storageKey keys the value of a storage in a context
+type storageKey int64
+
+// snapshot is a captured context, opaque to JS
+type snapshot struct {
+	ctx context.Context
+}
+
+// Exports builds the module object. It must be called on the JS thread.
+func (b *Bridge) Exports() (*goja.Object, error) {
+	native := b.vm.NewObject()
+	native.Set("key", func() int64 {
+		b.keys++
+		return b.keys
+	})
+	native.Set("get", func(key int64) goja.Value {
+		if value, ok := b.tracker.Current().Value(storageKey(key)).(goja.Value); ok {
+			return value
+		}
+		return goja.Undefined()
+	})
+	native.Set("run", func(key int64, value goja.Value, fn goja.Callable) goja.Value {
+		return b.call(context.WithValue(b.tracker.Current(), storageKey(key), value), fn)
+	})
+	native.Set("enter", func(key int64, value goja.Value) {
+		b.tracker.Enter(context.WithValue(b.tracker.Current(), storageKey(key), value))
+	})
+	native.Set("snapshot", func() *snapshot {
+		return &snapshot{ctx: b.tracker.Capture()}
+	})
+	native.Set("restore", func(s *snapshot, fn goja.Callable) goja.Value {
+		return b.call(s.ctx, fn)
+	})
+
+	setup, err := jsprogram.Run(b.vm, "async-hooks-setup", asyncHooksSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("async_hooks setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
+
+// call calls fn with ctx as the current context, throwing what it throws
+func (b *Bridge) call(ctx context.Context, fn goja.Callable) goja.Value {
+	var result goja.Value
+	var err error
+	b.tracker.Run(ctx, func() {
+		result, err = fn(goja.Undefined())
+	})
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
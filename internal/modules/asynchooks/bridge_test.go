@@ -0,0 +1,77 @@
+package asynchooks_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestAsyncLocalStorage(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("async-local-storage", `
+		(async () => {
+			const { AsyncLocalStorage, AsyncResource } = require('async_hooks');
+			const als = new AsyncLocalStorage();
+			const seen = [];
+			const work = async (name) => {
+				await Promise.resolve();
+				seen.push(name + ':' + als.getStore().id);
+				await new Promise(resolve => setTimeout(resolve, 5));
+				seen.push(name + ':' + als.getStore().id);
+				await new Promise(resolve => setImmediate(resolve));
+				return als.getStore().id;
+			};
+			const results = await Promise.all([
+				als.run({ id: 'a' }, work, 'first'),
+				als.run({ id: 'b' }, work, 'second'),
+			]);
+			const isolated = [results.join(), seen.sort().join(), String(als.getStore())].join(';');
+
+			const nested = als.run(1, () => [als.getStore(), als.run(2, () => als.getStore()),
+				als.exit(() => String(als.getStore())), als.getStore()].join());
+			let thrown;
+			try {
+				als.run(3, () => { throw new Error('boom'); });
+			} catch (e) {
+				thrown = e.message + ':' + als.getStore();
+			}
+
+			const resource = als.run('bound', () => new AsyncResource('job'));
+			const snapshot = als.run('snap', () => AsyncLocalStorage.snapshot());
+			const scopes = [resource.runInAsyncScope(() => als.getStore()), snapshot(() => als.getStore())].join();
+
+			const entered = await new Promise(resolve => setTimeout(() => {
+				als.enterWith('entered');
+				Promise.resolve().then(() => resolve(als.getStore()));
+			}, 1));
+			const afterEnter = await new Promise(resolve => setTimeout(() => resolve(String(als.getStore())), 1));
+
+			const v = new AsyncContext.Variable({ name: 'v', defaultValue: 'none' });
+			const wrapped = v.run('wrapped', () => AsyncContext.Snapshot.wrap(() => v.get()));
+			const variable = [v.name, v.get(), await v.run('x', async () => { await null; return v.get(); }), wrapped()].join();
+
+			return [isolated, nested, thrown, scopes, entered, afterEnter, variable].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"a,b;first:a,first:a,second:b,second:b;undefined",
+		"1,2,undefined,1",
+		"boom:undefined",
+		"bound,snap",
+		"entered",
+		"undefined",
+		"v,none,x,wrapped",
+	}, "|")
+	if value != want {
+		t.Errorf("AsyncLocalStorage results = %v, want %s", value, want)
+	}
+}
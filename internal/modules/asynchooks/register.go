@@ -0,0 +1,37 @@
+package asynchooks
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/asynccontext"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	AsyncContext() *asynccontext.Tracker
+}
+
+// RegisterAsyncHooksModule registers gode:async_hooks, also available as
+// async_hooks, and the AsyncContext global in the JavaScript runtime
+func RegisterAsyncHooksModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		exports, err := NewBridge(runtime).Exports()
+		if err != nil {
+			done <- err
+			return
+		}
+		runtime.RegisterModule("gode:async_hooks", exports)
+		runtime.RegisterModule("async_hooks", exports)
+		if err := runtime.GetGojaRuntime().Set("AsyncContext", exports.Get("AsyncContext")); err != nil {
+			done <- fmt.Errorf("failed to register AsyncContext: %w", err)
+			return
+		}
+		done <- nil
+	})
+	return <-done
+}
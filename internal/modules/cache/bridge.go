@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Bridge provides JavaScript bindings for the gode:cache module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// jsCache pairs a Cache with the loads currently in flight for getOrSet.
+// Both are only touched on the JS thread.
+type jsCache struct {
+	cache    *Cache
+	pending  map[string]goja.Value
+	measured bool // Whether values must be sized for maxBytes
+}
+
+// NewBridge creates a new cache bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("createCache", b.createCache)
+	return exports
+}
+
+// createCache implements cache.createCache({max, maxBytes, ttl}) where ttl
+// is in milliseconds
+func (b *Bridge) createCache(call goja.FunctionCall) goja.Value {
+	opts := Options{}
+	if obj, ok := call.Argument(0).(*goja.Object); ok {
+		if v := b.option(obj, "max"); v != nil {
+			opts.MaxEntries = int(v.ToInteger())
+		}
+		if v := b.option(obj, "maxBytes"); v != nil {
+			opts.MaxBytes = v.ToInteger()
+		}
+		if v := b.option(obj, "ttl"); v != nil {
+			opts.TTL = time.Duration(v.ToInteger()) * time.Millisecond
+		}
+	}
+
+	c := &jsCache{
+		cache:    New(opts),
+		pending:  make(map[string]goja.Value),
+		measured: opts.MaxBytes > 0,
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("get", func(key string) goja.Value {
+		if value, ok := c.cache.Get(key); ok {
+			return value.(goja.Value)
+		}
+		return goja.Undefined()
+	})
+	obj.Set("set", func(call goja.FunctionCall) goja.Value {
+		b.set(c, call.Argument(0).String(), call.Argument(1), call.Argument(2))
+		return obj
+	})
+	obj.Set("has", c.cache.Has)
+	obj.Set("delete", c.cache.Delete)
+	obj.Set("clear", c.cache.Clear)
+	obj.Set("keys", c.cache.Keys)
+	obj.Set("prune", c.cache.Prune)
+	obj.Set("getOrSet", func(call goja.FunctionCall) goja.Value {
+		return b.getOrSet(c, call)
+	})
+	obj.Set("stats", func() map[string]interface{} {
+		stats := c.cache.Stats()
+		hitRate := 0.0
+		if total := stats.Hits + stats.Misses; total > 0 {
+			hitRate = float64(stats.Hits) / float64(total)
+		}
+		return map[string]interface{}{
+			"hits":        stats.Hits,
+			"misses":      stats.Misses,
+			"hitRate":     hitRate,
+			"evictions":   stats.Evictions,
+			"expirations": stats.Expirations,
+			"entries":     stats.Entries,
+			"bytes":       stats.Bytes,
+		}
+	})
+	obj.DefineAccessorProperty("size", b.vm.ToValue(c.cache.Len), nil, goja.FLAG_TRUE, goja.FLAG_FALSE)
+
+	return obj
+}
+
+// set stores value with options {ttl, size}
+func (b *Bridge) set(c *jsCache, key string, value goja.Value, options goja.Value) bool {
+	var ttl time.Duration
+	var size int64
+
+	opts, _ := options.(*goja.Object)
+	if v := b.option(opts, "ttl"); v != nil {
+		ttl = time.Duration(v.ToInteger()) * time.Millisecond
+	}
+	if v := b.option(opts, "size"); v != nil {
+		size = v.ToInteger()
+	} else if c.measured {
+		size = SizeOf(value.Export())
+	}
+
+	return c.cache.Set(key, value, size, ttl)
+}
+
+// getOrSet implements cache.getOrSet(key, loader, {ttl, size}). It always
+// returns a Promise. On a miss loader(key) is called once and concurrent
+// callers for the same key share its result; a rejected load is not cached.
+func (b *Bridge) getOrSet(c *jsCache, call goja.FunctionCall) goja.Value {
+	key := call.Argument(0).String()
+	options := call.Argument(2)
+
+	promise, resolve, reject := b.vm.NewPromise()
+
+	if value, ok := c.cache.Get(key); ok {
+		resolve(value)
+		return b.vm.ToValue(promise)
+	}
+	if inflight, ok := c.pending[key]; ok {
+		return inflight
+	}
+
+	loader, ok := goja.AssertFunction(call.Argument(1))
+	if !ok {
+		panic(b.vm.NewTypeError("loader must be a function"))
+	}
+
+	result, err := loader(goja.Undefined(), b.vm.ToValue(key))
+	if err != nil {
+		if ex, ok := err.(*goja.Exception); ok {
+			reject(ex.Value())
+		} else {
+			reject(b.vm.NewGoError(err))
+		}
+		return b.vm.ToValue(promise)
+	}
+
+	// resolve adopts the state of a returned promise. The bookkeeping
+	// handlers are attached before the caller sees the promise, so the value
+	// is cached by the time the caller's own handlers run.
+	resolve(result)
+	shared := b.vm.ToValue(promise)
+	c.pending[key] = shared
+
+	then, _ := goja.AssertFunction(shared.(*goja.Object).Get("then"))
+	then(shared,
+		b.vm.ToValue(func(value goja.Value) {
+			delete(c.pending, key)
+			b.set(c, key, value, options)
+		}),
+		b.vm.ToValue(func(goja.Value) {
+			delete(c.pending, key)
+		}),
+	)
+
+	return shared
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	if obj == nil {
+		return nil
+	}
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Options configures a Cache. Zero limits mean unbounded.
+type Options struct {
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration // Default time to live; 0 never expires
+	Now        func() time.Time
+}
+
+// Stats are cumulative counters plus the current occupancy
+type Stats struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64 // Entries dropped to stay within the limits
+	Expirations int64 // Entries dropped because their TTL passed
+	Entries     int
+	Bytes       int64
+}
+
+// Cache is an LRU cache with optional per-entry TTL and entry or byte
+// limits. It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	opts  Options
+	ll    *list.List // front is most recently used
+	items map[string]*list.Element
+	bytes int64
+	stats Stats
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	size    int64
+	expires time.Time // zero never expires
+}
+
+// New creates a cache with the given limits
+func New(opts Options) *Cache {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Cache{
+		opts:  opts,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value for key and marks it as recently used
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if c.expired(e) {
+		c.remove(el)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	c.stats.Hits++
+	return e.value, true
+}
+
+// Has reports whether key is present and unexpired without touching its
+// recency or the hit counters
+func (c *Cache) Has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	return ok && !c.expired(el.Value.(*entry))
+}
+
+// Set stores value under key. size is the value's weight against MaxBytes
+// and ttl overrides the default when non-zero (negative means never
+// expire). It returns false if the value alone exceeds MaxBytes.
+func (c *Cache) Set(key string, value interface{}, size int64, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.opts.MaxBytes > 0 && size > c.opts.MaxBytes {
+		if el, ok := c.items[key]; ok {
+			c.remove(el)
+		}
+		return false
+	}
+
+	if ttl == 0 {
+		ttl = c.opts.TTL
+	}
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.opts.Now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		c.bytes += size - e.size
+		e.value, e.size, e.expires = value, size, expires
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[key] = c.ll.PushFront(&entry{key: key, value: value, size: size, expires: expires})
+		c.bytes += size
+	}
+
+	c.evict()
+	return true
+}
+
+// Delete removes key and reports whether it was present
+func (c *Cache) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok {
+		c.remove(el)
+	}
+	return ok
+}
+
+// Clear removes every entry but keeps the counters
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+// Keys returns the unexpired keys from most to least recently used
+func (c *Cache) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.items))
+	for el := c.ll.Front(); el != nil; el = el.Next() {
+		if e := el.Value.(*entry); !c.expired(e) {
+			keys = append(keys, e.key)
+		}
+	}
+	return keys
+}
+
+// Prune removes expired entries and returns how many were dropped.
+// Expired entries are otherwise only removed when they are looked up.
+func (c *Cache) Prune() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for el := c.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if c.expired(el.Value.(*entry)) {
+			c.remove(el)
+			c.stats.Expirations++
+			removed++
+		}
+		el = prev
+	}
+	return removed
+}
+
+// Len returns the number of stored entries, including expired ones not yet pruned
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats returns a snapshot of the counters
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.ll.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// evict drops least recently used entries until both limits hold
+func (c *Cache) evict() {
+	for c.ll.Len() > 0 &&
+		(c.opts.MaxEntries > 0 && c.ll.Len() > c.opts.MaxEntries ||
+			c.opts.MaxBytes > 0 && c.bytes > c.opts.MaxBytes) {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size
+}
+
+func (c *Cache) expired(e *entry) bool {
+	return !e.expires.IsZero() && !c.opts.Now().Before(e.expires)
+}
+
+// SizeOf estimates the memory weight of an exported JavaScript value for
+// MaxBytes accounting
+func SizeOf(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case bool:
+		return 1
+	case int64, float64, int:
+		return 8
+	case []interface{}:
+		var size int64
+		for _, item := range v {
+			size += SizeOf(item)
+		}
+		return size
+	case map[string]interface{}:
+		var size int64
+		for key, item := range v {
+			size += int64(len(key)) + SizeOf(item)
+		}
+		return size
+	}
+	return 16
+}
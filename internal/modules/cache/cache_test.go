@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	c := New(Options{MaxEntries: 2})
+	c.Set("a", 1, 0, 0)
+	c.Set("b", 2, 0, 0)
+	c.Get("a") // b is now least recently used
+	c.Set("c", 3, 0, 0)
+
+	if c.Has("b") {
+		t.Error("Expected b to be evicted")
+	}
+	if !c.Has("a") || !c.Has("c") {
+		t.Error("Expected a and c to remain")
+	}
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != "c" || keys[1] != "a" {
+		t.Errorf("Expected keys [c a], got %v", keys)
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Hits != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestByteLimit(t *testing.T) {
+	c := New(Options{MaxBytes: 10})
+	c.Set("a", "aaaa", 4, 0)
+	c.Set("b", "bbbb", 4, 0)
+	c.Set("c", "cccc", 4, 0)
+
+	if c.Has("a") || c.Stats().Bytes != 8 {
+		t.Errorf("Expected a to be evicted leaving 8 bytes, got %+v", c.Stats())
+	}
+
+	// Replacing a value adjusts the byte count
+	c.Set("b", "b", 1, 0)
+	if c.Stats().Bytes != 5 {
+		t.Errorf("Expected 5 bytes after replace, got %d", c.Stats().Bytes)
+	}
+
+	if c.Set("huge", "x", 11, 0) {
+		t.Error("Expected oversized value to be rejected")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := New(Options{TTL: time.Minute, Now: func() time.Time { return now }})
+	c.Set("default", 1, 0, 0)
+	c.Set("short", 2, 0, time.Second)
+	c.Set("forever", 3, 0, -1)
+
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("short"); ok {
+		t.Error("Expected short to expire")
+	}
+	if _, ok := c.Get("default"); !ok {
+		t.Error("Expected default to still be cached")
+	}
+
+	now = now.Add(time.Hour)
+	if removed := c.Prune(); removed != 1 {
+		t.Errorf("Expected prune to remove 1 entry, removed %d", removed)
+	}
+	if !c.Has("forever") || c.Len() != 1 {
+		t.Error("Expected only forever to remain")
+	}
+
+	stats := c.Stats()
+	if stats.Expirations != 2 || stats.Misses != 1 || stats.Hits != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	value := map[string]interface{}{
+		"name": "gode",                       // 4 + 4
+		"tags": []interface{}{"a", int64(1)}, // 4 + 1 + 8
+	}
+	if size := SizeOf(value); size != 21 {
+		t.Errorf("Expected size 21, got %d", size)
+	}
+}
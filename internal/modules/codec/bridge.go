@@ -0,0 +1,377 @@
+package codec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/codec"
+	rpc "github.com/rizqme/gode/internal/grpc"
+	"github.com/rizqme/gode/internal/jsbuffer"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxSafeInteger is the largest integer a JS number holds exactly; larger
+// 64-bit integers are decoded to decimal strings instead
+const maxSafeInteger = 1<<53 - 1
+
+// Bridge provides JavaScript bindings for the gode:codec module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new codec bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	msgpack := b.vm.NewObject()
+	msgpack.Set("encode", b.encodeMsgpack)
+	msgpack.Set("decode", b.decodeMsgpack)
+
+	protobuf := b.vm.NewObject()
+	protobuf.Set("load", b.loadProtobuf)
+
+	exports := b.vm.NewObject()
+	exports.Set("msgpack", msgpack)
+	exports.Set("protobuf", protobuf)
+	return exports
+}
+
+// encodeMsgpack implements msgpack.encode(value), returning a Buffer
+func (b *Bridge) encodeMsgpack(value goja.Value) goja.Value {
+	data, err := codec.EncodeMsgpack(b.exportMsgpack(value, 0))
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return jsbuffer.New(b.vm, data)
+}
+
+// decodeMsgpack implements msgpack.decode(data) for a Buffer, typed array
+// or ArrayBuffer
+func (b *Bridge) decodeMsgpack(data goja.Value) goja.Value {
+	value, err := codec.DecodeMsgpack(b.input(data))
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return b.value(value)
+}
+
+// exportMsgpack converts value for EncodeMsgpack: whole numbers to
+// integers, Dates to times, Buffers, typed arrays and ArrayBuffers to
+// bytes, arrays and Sets to slices, Maps and other objects to Maps in the
+// order of their keys. Properties that are undefined are left out, as in
+// JSON; functions and symbols can't be encoded.
+func (b *Bridge) exportMsgpack(value goja.Value, depth int) interface{} {
+	if depth > codec.MaxDepth {
+		panic(b.vm.NewTypeError(fmt.Sprintf("msgpack: values are nested more than %d deep, or circular", codec.MaxDepth)))
+	}
+	if isNullish(value) {
+		return nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		switch v := value.Export().(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= maxSafeInteger && !(v == 0 && math.Signbit(v)) {
+				return int64(v)
+			}
+			return v
+		case bool, string, int64:
+			return v
+		}
+		panic(b.vm.NewTypeError(fmt.Sprintf("msgpack: cannot encode %s", value.String())))
+	}
+	if data, ok := bytesOf(obj); ok {
+		return data
+	}
+	switch obj.ClassName() {
+	case "Function":
+		panic(b.vm.NewTypeError("msgpack: cannot encode a function"))
+	case "Date":
+		t, ok := obj.Export().(time.Time)
+		if !ok {
+			panic(b.vm.NewTypeError("msgpack: cannot encode an invalid Date"))
+		}
+		return t
+	case "Array":
+		length := int(obj.Get("length").ToInteger())
+		items := make([]interface{}, length)
+		for i := range items {
+			items[i] = b.exportMsgpack(obj.Get(strconv.Itoa(i)), depth+1)
+		}
+		return items
+	case "Set":
+		items := []interface{}{}
+		b.forEach(obj, func(value, key goja.Value) {
+			items = append(items, b.exportMsgpack(value, depth+1))
+		})
+		return items
+	case "Map":
+		entries := codec.Map{}
+		b.forEach(obj, func(value, key goja.Value) {
+			entries = append(entries, codec.Entry{
+				Key:   b.exportMsgpack(key, depth+1),
+				Value: b.exportMsgpack(value, depth+1),
+			})
+		})
+		return entries
+	}
+	entries := codec.Map{}
+	for _, key := range obj.Keys() {
+		v := obj.Get(key)
+		if goja.IsUndefined(v) {
+			continue
+		}
+		entries = append(entries, codec.Entry{Key: key, Value: b.exportMsgpack(v, depth+1)})
+	}
+	return entries
+}
+
+// forEach calls fn with the values and keys of a Map or Set
+func (b *Bridge) forEach(obj *goja.Object, fn func(value, key goja.Value)) {
+	forEach, ok := goja.AssertFunction(obj.Get("forEach"))
+	if !ok {
+		panic(b.vm.NewTypeError("msgpack: cannot encode a " + obj.ClassName()))
+	}
+	if _, err := forEach(obj, b.vm.ToValue(fn)); err != nil {
+		panic(err)
+	}
+}
+
+// value converts a decoded value to JS: integers to numbers, or to decimal
+// strings when a number can't hold them exactly, bytes to Buffers, times to
+// Dates and Maps to objects, or to JS Maps when they have keys that aren't
+// strings. Other extensions become {type, data}.
+func (b *Bridge) value(v interface{}) goja.Value {
+	switch v := v.(type) {
+	case nil:
+		return goja.Null()
+	case int64:
+		if v > maxSafeInteger || v < -maxSafeInteger {
+			return b.vm.ToValue(strconv.FormatInt(v, 10))
+		}
+		return b.vm.ToValue(v)
+	case uint64:
+		if v > maxSafeInteger {
+			return b.vm.ToValue(strconv.FormatUint(v, 10))
+		}
+		return b.vm.ToValue(int64(v))
+	case []byte:
+		return jsbuffer.New(b.vm, v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = b.value(item)
+		}
+		return b.vm.NewArray(items...)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		obj := b.vm.NewObject()
+		for _, key := range keys {
+			obj.Set(key, b.value(v[key]))
+		}
+		return obj
+	case codec.Map:
+		return b.mapValue(v)
+	case time.Time:
+		date, err := b.vm.New(b.vm.Get("Date"), b.vm.ToValue(v.UnixMilli()))
+		if err != nil {
+			panic(err)
+		}
+		return date
+	case codec.Ext:
+		obj := b.vm.NewObject()
+		obj.Set("type", int(v.Type))
+		obj.Set("data", jsbuffer.New(b.vm, v.Data))
+		return obj
+	}
+	return b.vm.ToValue(v)
+}
+
+func (b *Bridge) mapValue(m codec.Map) goja.Value {
+	for _, entry := range m {
+		if _, ok := entry.Key.(string); !ok {
+			return b.jsMap(m)
+		}
+	}
+	obj := b.vm.NewObject()
+	for _, entry := range m {
+		obj.Set(entry.Key.(string), b.value(entry.Value))
+	}
+	return obj
+}
+
+func (b *Bridge) jsMap(m codec.Map) goja.Value {
+	obj, err := b.vm.New(b.vm.Get("Map"))
+	if err != nil {
+		panic(err)
+	}
+	set, _ := goja.AssertFunction(obj.Get("set"))
+	for _, entry := range m {
+		if _, err := set(obj, b.value(entry.Key), b.value(entry.Value)); err != nil {
+			panic(err)
+		}
+	}
+	return obj
+}
+
+// loadProtobuf implements protobuf.load(paths, {includeDirs}), reading
+// .proto files or descriptor sets as gode:grpc does, and returning
+// {type(name)} for the message types they define
+func (b *Bridge) loadProtobuf(paths goja.Value, options *goja.Object) *goja.Object {
+	var files, includeDirs []string
+	if s, ok := paths.Export().(string); ok {
+		files = []string{s}
+	} else if err := b.vm.ExportTo(paths, &files); err != nil {
+		panic(b.vm.NewTypeError("load expects a path or an array of paths"))
+	}
+	if options != nil {
+		if v := options.Get("includeDirs"); !isNullish(v) {
+			if err := b.vm.ExportTo(v, &includeDirs); err != nil {
+				panic(b.vm.NewTypeError("includeDirs must be an array of paths"))
+			}
+		}
+	}
+	if checker, ok := b.runtime.(permissions.Enforcer); ok {
+		for _, path := range append(append([]string(nil), files...), includeDirs...) {
+			if err := checker.CheckPermission("read", path); err != nil {
+				panic(jserror.New(b.vm, err))
+			}
+		}
+	}
+	schema, err := rpc.Load(files, includeDirs)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("type", func(name string) *goja.Object {
+		desc, err := schema.Message(name)
+		if err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		return b.messageType(desc)
+	})
+	return obj
+}
+
+// messageType describes a message type as {name, fields, encode(value),
+// decode(data)}
+func (b *Bridge) messageType(desc protoreflect.MessageDescriptor) *goja.Object {
+	fields := make([]interface{}, desc.Fields().Len())
+	for i := range fields {
+		fields[i] = desc.Fields().Get(i).JSONName()
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("name", string(desc.FullName()))
+	obj.Set("fields", b.vm.NewArray(fields...))
+	obj.Set("encode", func(value goja.Value) goja.Value {
+		msg, err := rpc.ToMessage(desc, b.exportMessage(value))
+		if err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+		return jsbuffer.New(b.vm, data)
+	})
+	obj.Set("decode", func(data goja.Value) goja.Value {
+		msg := dynamicpb.NewMessage(desc)
+		if err := proto.Unmarshal(b.input(data), msg); err != nil {
+			panic(b.vm.NewGoError(fmt.Errorf("%s: %w", desc.FullName(), err)))
+		}
+		return b.value(rpc.FromMessage(msg))
+	})
+	return obj
+}
+
+// exportMessage converts a message given by a script for ToMessage: arrays
+// to slices, Buffers, typed arrays and ArrayBuffers to bytes and other
+// objects to maps
+func (b *Bridge) exportMessage(value goja.Value) interface{} {
+	if isNullish(value) {
+		return nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return value.Export()
+	}
+	if data, ok := bytesOf(obj); ok {
+		return data
+	}
+	if obj.ClassName() == "Array" {
+		length := int(obj.Get("length").ToInteger())
+		items := make([]interface{}, length)
+		for i := range items {
+			items[i] = b.exportMessage(obj.Get(strconv.Itoa(i)))
+		}
+		return items
+	}
+	fields := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		fields[key] = b.exportMessage(obj.Get(key))
+	}
+	return fields
+}
+
+// input returns the bytes of data to decode, which must be binary
+func (b *Bridge) input(data goja.Value) []byte {
+	if obj, ok := data.(*goja.Object); ok {
+		if bytes, ok := bytesOf(obj); ok {
+			return bytes
+		}
+	}
+	panic(b.vm.NewTypeError("decode expects a Buffer, typed array or ArrayBuffer"))
+}
+
+// bytesOf returns the bytes of a Buffer, typed array or ArrayBuffer
+func bytesOf(obj *goja.Object) ([]byte, bool) {
+	// Buffers of the wrapper implementation hold a Go buffer
+	if goBuf := obj.Get("_goBuf"); goBuf != nil {
+		if inner, ok := goBuf.Export().(interface{ Bytes() []byte }); ok {
+			return inner.Bytes(), true
+		}
+	}
+	if buffer, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return buffer.Bytes(), true
+	}
+	v := obj.Get("buffer")
+	if v == nil {
+		return nil, false
+	}
+	buffer, ok := v.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	data := buffer.Bytes()
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+length], true
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
@@ -0,0 +1,110 @@
+package codec_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+const userProto = `
+syntax = "proto3";
+package app;
+
+enum Role {
+  GUEST = 0;
+  ADMIN = 1;
+}
+
+message User {
+  int64 id = 1;
+  string display_name = 2;
+  Role role = 3;
+  repeated string tags = 4;
+  bytes avatar = 5;
+  map<string, int32> scores = 6;
+}
+`
+
+func TestCodecModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	protoFile := filepath.Join(t.TempDir(), "user.proto")
+	if err := os.WriteFile(protoFile, []byte(userProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunScriptAsync("codec", `
+		(async () => {
+			const { msgpack, protobuf } = require('gode:codec');
+			const results = [];
+
+			results.push(msgpack.encode({ a: 1, b: [true, null], c: 'x', skip: undefined }).toString('hex'));
+			results.push(msgpack.encode(1.5).toString('hex') + ' ' + msgpack.encode(-0).length() + ' ' + msgpack.encode(2 ** 40).toString('hex'));
+
+			const decoded = msgpack.decode(msgpack.encode({
+				when: new Date(1700000000123),
+				data: Buffer.from('hi'),
+				ids: new Map([[1, 'one'], ['two', 2]]),
+				set: new Set(['s']),
+				nested: { deep: [1, [2, { x: 'y' }]] },
+			}));
+			results.push([
+				decoded.when instanceof Date, decoded.when.getTime(),
+				Buffer.isBuffer(decoded.data), decoded.data.toString(),
+				decoded.ids instanceof Map, decoded.ids.get(1), decoded.ids.get('two'),
+				JSON.stringify(decoded.set), JSON.stringify(decoded.nested),
+			].join(','));
+			results.push(JSON.stringify(msgpack.decode(new Uint8Array([0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff]))));
+			const ext = msgpack.decode(new Uint8Array([0xd4, 0x05, 0x09]));
+			results.push(ext.type + ' ' + ext.data.toString('hex'));
+
+			const circular = {};
+			circular.self = circular;
+			results.push(String((() => { try { msgpack.encode(circular); } catch (e) { return e instanceof TypeError; } })()));
+			results.push(String((() => { try { msgpack.encode({ f() {} }); } catch (e) { return e instanceof TypeError; } })()));
+			results.push(String((() => { try { msgpack.decode(Buffer.from([0x92, 0x01])); } catch (e) { return e.message; } })()));
+
+			const User = protobuf.load(`+"`"+protoFile+"`"+`).type('app.User');
+			results.push(User.name + ' ' + User.fields.join(','));
+			const bytes = User.encode({ id: '9007199254740993', displayName: 'Ann', role: 'ADMIN', tags: ['a', 'b'], avatar: Buffer.from([1, 2]), scores: { x: 3 } });
+			results.push(Buffer.isBuffer(bytes) + ' ' + bytes.length());
+			const user = User.decode(bytes);
+			results.push([user.id, user.displayName, user.role, user.tags.join('|'), user.avatar.toString('hex'), user.scores.x].join(','));
+			const defaults = User.decode(User.encode({ id: 5 }));
+			results.push([defaults.id, JSON.stringify(defaults.displayName), defaults.role, defaults.tags.length, defaults.avatar.length(), Object.keys(defaults.scores).length].join(','));
+			results.push(String((() => { try { User.encode({ nope: 1 }); } catch (e) { return e instanceof TypeError; } })()));
+			results.push(String((() => { try { protobuf.load(`+"`"+protoFile+"`"+`).type('app.Nope'); } catch (e) { return e.message; } })()));
+			return results.join('\n');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"83a16101a16292c3c0a163a178",
+		"cb3ff8000000000000 9 cf0000010000000000",
+		"true,1700000000123,true,hi,true,one,2,[\"s\"],{\"deep\":[1,[2,{\"x\":\"y\"}]]}",
+		`"18446744073709551615"`,
+		"5 09",
+		"true",
+		"true",
+		"msgpack: unexpected end of data",
+		"app.User id,displayName,role,tags,avatar,scores",
+		"true 33",
+		"9007199254740993,Ann,ADMIN,a|b,0102,3",
+		`5,"",GUEST,0,0,0`,
+		"true",
+		"message app.Nope is not defined",
+	}, "\n")
+	if value != want {
+		t.Errorf("got\n%v\nwant\n%v", value, want)
+	}
+}
@@ -0,0 +1,325 @@
+package crypto
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsbuffer"
+	"github.com/rizqme/gode/internal/jserror"
+)
+
+// maxRandomValues is the most bytes getRandomValues fills at once, as in
+// WebCrypto
+const maxRandomValues = 65536
+
+// Bridge provides JavaScript bindings for the gode:crypto module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+	keySym  *goja.Symbol // holds the *cryptoKey of a CryptoKey object
+}
+
+// NewBridge creates a new crypto bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+		keySym:  goja.NewSymbol("gode.cryptoKey"),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("createHash", b.createHash)
+	exports.Set("createHmac", b.createHmac)
+	exports.Set("getHashes", b.getHashes)
+	exports.Set("randomBytes", b.randomBytes)
+	exports.Set("randomUUID", b.randomUUID)
+	exports.Set("getRandomValues", b.getRandomValues)
+	exports.Set("pbkdf2", b.pbkdf2)
+	exports.Set("pbkdf2Sync", b.pbkdf2Sync)
+	exports.Set("timingSafeEqual", b.timingSafeEqual)
+
+	subtle := b.Subtle()
+	exports.Set("subtle", subtle)
+	webcrypto := b.vm.NewObject()
+	webcrypto.Set("subtle", subtle)
+	webcrypto.Set("getRandomValues", b.getRandomValues)
+	webcrypto.Set("randomUUID", b.randomUUID)
+	exports.Set("webcrypto", webcrypto)
+	return exports
+}
+
+// createHash implements createHash(algorithm)
+func (b *Bridge) createHash(call goja.FunctionCall) goja.Value {
+	algorithm := call.Argument(0).String()
+	h, err := NewHash(algorithm)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return b.hashObject(algorithm, h)
+}
+
+// createHmac implements createHmac(algorithm, key)
+func (b *Bridge) createHmac(call goja.FunctionCall) goja.Value {
+	algorithm := call.Argument(0).String()
+	h, err := NewHMAC(algorithm, b.input(call.Argument(1), goja.Undefined()))
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return b.hashObject(algorithm, h)
+}
+
+// hashObject wraps h as a Hash or Hmac object: update(data, encoding)
+// returns the object for chaining, and digest(encoding) returns a Buffer,
+// or a string when an encoding is given, and may only be called once.
+// copy() is only available on hashes whose state can be saved, which
+// excludes HMACs, as in Node.
+func (b *Bridge) hashObject(algorithm string, h hash.Hash) *goja.Object {
+	obj := b.vm.NewObject()
+	digested := false
+	checkDigested := func() {
+		if digested {
+			panic(b.vm.NewGoError(errors.New("digest already called")))
+		}
+	}
+
+	obj.Set("update", func(call goja.FunctionCall) goja.Value {
+		checkDigested()
+		h.Write(b.input(call.Argument(0), call.Argument(1)))
+		return obj
+	})
+	obj.Set("digest", func(call goja.FunctionCall) goja.Value {
+		checkDigested()
+		digested = true
+		return b.output(h.Sum(nil), call.Argument(0))
+	})
+	if saver, ok := h.(encoding.BinaryMarshaler); ok {
+		obj.Set("copy", func(call goja.FunctionCall) goja.Value {
+			checkDigested()
+			state, err := saver.MarshalBinary()
+			if err != nil {
+				panic(b.vm.NewGoError(err))
+			}
+			clone, _ := NewHash(algorithm)
+			if err := clone.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+				panic(b.vm.NewGoError(err))
+			}
+			return b.hashObject(algorithm, clone)
+		})
+	}
+	return obj
+}
+
+// getHashes implements getHashes()
+func (b *Bridge) getHashes(call goja.FunctionCall) goja.Value {
+	return b.vm.ToValue([]interface{}{"md5", "sha1", "sha256", "sha384", "sha512"})
+}
+
+// randomBytes implements randomBytes(size, callback). Without a callback
+// it returns the Buffer; with one, it calls callback(err, buf) later.
+func (b *Bridge) randomBytes(call goja.FunctionCall) goja.Value {
+	size := int(call.Argument(0).ToInteger())
+	if callback, ok := goja.AssertFunction(call.Argument(1)); ok {
+		b.callback(callback, func() ([]byte, error) { return RandomBytes(size) })
+		return goja.Undefined()
+	}
+	data, err := RandomBytes(size)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return jsbuffer.New(b.vm, data)
+}
+
+// randomUUID implements randomUUID()
+func (b *Bridge) randomUUID(call goja.FunctionCall) goja.Value {
+	id, err := RandomUUID()
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return b.vm.ToValue(id)
+}
+
+// getRandomValues implements getRandomValues(typedArray), filling the
+// array in place and returning it
+func (b *Bridge) getRandomValues(call goja.FunctionCall) goja.Value {
+	view := call.Argument(0)
+	data, ok := viewBytes(view)
+	if !ok {
+		panic(b.vm.NewTypeError("getRandomValues requires a typed array"))
+	}
+	if len(data) > maxRandomValues {
+		panic(jserror.New(b.vm, &domError{"QuotaExceededError",
+			fmt.Sprintf("getRandomValues fills at most %d bytes, got %d", maxRandomValues, len(data))}))
+	}
+	random, err := RandomBytes(len(data))
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	copy(data, random)
+	return view
+}
+
+// pbkdf2 implements pbkdf2(password, salt, iterations, keylen, digest,
+// callback), deriving the key on its own goroutine
+func (b *Bridge) pbkdf2(call goja.FunctionCall) goja.Value {
+	callback, ok := goja.AssertFunction(call.Argument(5))
+	if !ok {
+		panic(b.vm.NewTypeError("pbkdf2 requires a callback"))
+	}
+	password, salt, iterations, keyLen, digest := b.pbkdf2Args(call)
+	b.callback(callback, func() ([]byte, error) {
+		return PBKDF2(password, salt, iterations, keyLen, digest)
+	})
+	return goja.Undefined()
+}
+
+// pbkdf2Sync implements pbkdf2Sync(password, salt, iterations, keylen,
+// digest)
+func (b *Bridge) pbkdf2Sync(call goja.FunctionCall) goja.Value {
+	key, err := PBKDF2(b.pbkdf2Args(call))
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return jsbuffer.New(b.vm, key)
+}
+
+func (b *Bridge) pbkdf2Args(call goja.FunctionCall) (password, salt []byte, iterations, keyLen int, digest string) {
+	password = b.input(call.Argument(0), goja.Undefined())
+	salt = b.input(call.Argument(1), goja.Undefined())
+	digest = "sha1"
+	if v := call.Argument(4); !isNullish(v) {
+		digest = v.String()
+	}
+	return password, salt, int(call.Argument(2).ToInteger()), int(call.Argument(3).ToInteger()), digest
+}
+
+// timingSafeEqual implements timingSafeEqual(a, b)
+func (b *Bridge) timingSafeEqual(call goja.FunctionCall) goja.Value {
+	equal, err := TimingSafeEqual(b.input(call.Argument(0), goja.Undefined()), b.input(call.Argument(1), goja.Undefined()))
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return b.vm.ToValue(equal)
+}
+
+// callback runs fn on its own goroutine and calls callback(err, buf) with
+// its result on the JS thread, keeping the runtime alive until then
+func (b *Bridge) callback(callback goja.Callable, fn func() ([]byte, error)) {
+	release := b.runtime.KeepAlive()
+	go func() {
+		data, err := fn()
+		b.runtime.QueueJSOperation(func() {
+			defer release()
+			if err != nil {
+				callback(goja.Undefined(), jserror.New(b.vm, err))
+				return
+			}
+			callback(goja.Undefined(), goja.Null(), jsbuffer.New(b.vm, data))
+		})
+	}()
+}
+
+// input converts data given by a script to bytes, decoding strings with
+// encoding, UTF-8 by default. It throws a TypeError for anything else.
+func (b *Bridge) input(value, encoding goja.Value) []byte {
+	if s, ok := value.Export().(string); ok {
+		enc := ""
+		if !isNullish(encoding) {
+			enc = encoding.String()
+		}
+		data, err := Decode(s, enc)
+		if err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		return data
+	}
+	data, ok := bytesOf(value)
+	if !ok {
+		panic(b.vm.NewTypeError("data must be a string, Buffer, TypedArray, DataView or ArrayBuffer"))
+	}
+	return data
+}
+
+// output returns data as a Buffer, or as a string in encoding when one is
+// given
+func (b *Bridge) output(data []byte, encoding goja.Value) goja.Value {
+	if isNullish(encoding) || encoding.String() == "buffer" {
+		return jsbuffer.New(b.vm, data)
+	}
+	s, err := Encode(data, encoding.String())
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return b.vm.ToValue(s)
+}
+
+// bytesOf returns the bytes of a Buffer, typed array, DataView or
+// ArrayBuffer, sharing their memory
+func bytesOf(value goja.Value) ([]byte, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	// Buffers of the wrapper implementation hold a Go buffer
+	if goBuf := obj.Get("_goBuf"); goBuf != nil {
+		if inner, ok := goBuf.Export().(interface{ Bytes() []byte }); ok {
+			return inner.Bytes(), true
+		}
+	}
+	if buffer, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return buffer.Bytes(), true
+	}
+	// TextEncoder's encode() returns a plain array of bytes
+	if obj.ClassName() == "Array" {
+		return arrayBytes(obj)
+	}
+	return viewBytes(obj)
+}
+
+// arrayBytes returns the elements of an array of integers from 0 to 255
+func arrayBytes(obj *goja.Object) ([]byte, bool) {
+	length := obj.Get("length").ToInteger()
+	data := make([]byte, length)
+	for i := range data {
+		n, ok := obj.Get(strconv.Itoa(i)).Export().(int64)
+		if !ok || n < 0 || n > 255 {
+			return nil, false
+		}
+		data[i] = byte(n)
+	}
+	return data, true
+}
+
+// viewBytes returns the bytes a typed array or DataView covers in its
+// ArrayBuffer
+func viewBytes(value goja.Value) ([]byte, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	v := obj.Get("buffer")
+	if v == nil {
+		return nil, false
+	}
+	buffer, ok := v.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	data := buffer.Bytes()
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+length], true
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
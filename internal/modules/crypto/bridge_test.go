@@ -0,0 +1,63 @@
+package crypto_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestCryptoModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("crypto", `
+		(async () => {
+			const nodeCrypto = require('gode:crypto');
+			const results = [];
+			results.push(nodeCrypto.createHash('sha256').update('a').update('bc').digest('hex'));
+			results.push(nodeCrypto.createHmac('sha256', 'Jefe').update('what do ya want for nothing?').digest('hex'));
+			results.push(await new Promise((resolve, reject) => nodeCrypto.pbkdf2('password', 'salt', 2, 20, 'sha1',
+				(err, key) => err ? reject(err) : resolve(key.toString('hex')))));
+
+			const hex = buf => Array.from(new Uint8Array(buf), b => b.toString(16).padStart(2, '0')).join('');
+			const encode = s => new TextEncoder().encode(s);
+			results.push(hex(await crypto.subtle.digest('SHA-256', encode('abc'))));
+
+			const hmacKey = await crypto.subtle.importKey('raw', encode('Jefe'), { name: 'HMAC', hash: 'SHA-256' }, false, ['sign', 'verify']);
+			const signature = await crypto.subtle.sign('HMAC', hmacKey, encode('what do ya want for nothing?'));
+			results.push(await crypto.subtle.verify('HMAC', hmacKey, signature, encode('what do ya want for nothing?')));
+
+			const aesKey = await crypto.subtle.generateKey({ name: 'AES-GCM', length: 256 }, false, ['encrypt', 'decrypt']);
+			const iv = crypto.getRandomValues(new Uint8Array(12));
+			const sealed = await crypto.subtle.encrypt({ name: 'AES-GCM', iv }, aesKey, encode('secret'));
+			results.push(new TextDecoder().decode(await crypto.subtle.decrypt({ name: 'AES-GCM', iv }, aesKey, sealed)));
+			const tampered = new Uint8Array(sealed);
+			tampered[0] ^= 1;
+			results.push(await crypto.subtle.decrypt({ name: 'AES-GCM', iv }, aesKey, tampered).catch(e => e.name));
+			results.push(await crypto.subtle.exportKey('raw', aesKey).catch(e => e.name));
+			results.push(/^[0-9a-f-]{36}$/.test(crypto.randomUUID()));
+			return results.join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		"ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957",
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"true",
+		"secret",
+		"OperationError",
+		"InvalidAccessError",
+		"true",
+	}, "|")
+	if value != want {
+		t.Errorf("crypto results = %v, want %s", value, want)
+	}
+}
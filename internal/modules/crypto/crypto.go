@@ -0,0 +1,230 @@
+// Package crypto implements gode:crypto: hashes, HMACs, random values,
+// PBKDF2 and AES-GCM on top of Go's crypto packages.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// hashes are the supported hash algorithms by their Node names
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// ErrDecrypt is returned when a ciphertext fails authentication
+var ErrDecrypt = errors.New("decryption failed: the data or the key is wrong")
+
+// hashFunc looks up a hash algorithm by its Node name, such as "sha256",
+// or its WebCrypto name, such as "SHA-256"
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	name := strings.ReplaceAll(strings.ToLower(algorithm), "-", "")
+	if fn, ok := hashes[name]; ok {
+		return fn, nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm %q", algorithm)
+}
+
+// NewHash creates a hash of the given algorithm
+func NewHash(algorithm string) (hash.Hash, error) {
+	fn, err := hashFunc(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return fn(), nil
+}
+
+// NewHMAC creates an HMAC of the given hash algorithm keyed with key
+func NewHMAC(algorithm string, key []byte) (hash.Hash, error) {
+	fn, err := hashFunc(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return hmac.New(fn, key), nil
+}
+
+// Digest hashes data in one go
+func Digest(algorithm string, data []byte) ([]byte, error) {
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Sign computes the HMAC of data
+func Sign(algorithm string, key, data []byte) ([]byte, error) {
+	h, err := NewHMAC(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Verify checks the HMAC of data in constant time
+func Verify(algorithm string, key, signature, data []byte) (bool, error) {
+	expected, err := Sign(algorithm, key, data)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(expected, signature), nil
+}
+
+// RandomBytes returns n cryptographically random bytes
+func RandomBytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("size must not be negative, got %d", n)
+	}
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RandomUUID returns a random version 4 UUID
+func RandomUUID() (string, error) {
+	id, err := RandomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	s := hex.EncodeToString(id)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:], nil
+}
+
+// PBKDF2 derives a key of keyLen bytes from password and salt
+func PBKDF2(password, salt []byte, iterations, keyLen int, digest string) ([]byte, error) {
+	fn, err := hashFunc(digest)
+	if err != nil {
+		return nil, err
+	}
+	if iterations < 1 {
+		return nil, fmt.Errorf("iterations must be positive, got %d", iterations)
+	}
+	if keyLen < 0 {
+		return nil, fmt.Errorf("key length must not be negative, got %d", keyLen)
+	}
+	return pbkdf2.Key(password, salt, iterations, keyLen, fn), nil
+}
+
+// TimingSafeEqual compares a and b in constant time. They must have the
+// same length, as in Node.
+func TimingSafeEqual(a, b []byte) (bool, error) {
+	if len(a) != len(b) {
+		return false, errors.New("input buffers must have the same byte length")
+	}
+	return subtle.ConstantTimeCompare(a, b) == 1, nil
+}
+
+// gcm creates the AES-GCM cipher for key, with a nonce the size of iv and
+// tags of tagLength bits. Non-standard nonce sizes need 128-bit tags.
+func gcm(key, iv []byte, tagLength int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) == 0 {
+		return nil, errors.New("iv must not be empty")
+	}
+	if tagLength == 0 || tagLength == 128 {
+		return cipher.NewGCMWithNonceSize(block, len(iv))
+	}
+	if tagLength%8 != 0 {
+		return nil, fmt.Errorf("unsupported tag length %d", tagLength)
+	}
+	if len(iv) != 12 {
+		return nil, fmt.Errorf("a %d-bit tag needs a 12-byte iv", tagLength)
+	}
+	return cipher.NewGCMWithTagSize(block, tagLength/8)
+}
+
+// EncryptAESGCM encrypts plaintext, returning the ciphertext followed by
+// the authentication tag, as WebCrypto does. tagLength is in bits, 128 when
+// zero.
+func EncryptAESGCM(key, iv, plaintext, additionalData []byte, tagLength int) ([]byte, error) {
+	aead, err := gcm(key, iv, tagLength)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, iv, plaintext, additionalData), nil
+}
+
+// DecryptAESGCM decrypts what EncryptAESGCM returned, failing with
+// ErrDecrypt when the data does not authenticate
+func DecryptAESGCM(key, iv, ciphertext, additionalData []byte, tagLength int) ([]byte, error) {
+	aead, err := gcm(key, iv, tagLength)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Encode encodes data as a string: hex, base64, base64url, latin1 (or
+// binary) or utf8
+func Encode(data []byte, encoding string) (string, error) {
+	switch strings.ToLower(encoding) {
+	case "hex":
+		return hex.EncodeToString(data), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	case "latin1", "binary":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	case "utf8", "utf-8":
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// Decode decodes a string given in one of the encodings of Encode
+func Decode(s, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf8", "utf-8":
+		return []byte(s), nil
+	case "hex":
+		return hex.DecodeString(s)
+	case "base64":
+		return base64.StdEncoding.DecodeString(s)
+	case "base64url":
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	case "latin1", "binary":
+		data := make([]byte, 0, len(s))
+		for _, r := range s {
+			data = append(data, byte(r))
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"regexp"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	// Reference values from sha*sum and md5sum of "abc"
+	cases := map[string]string{
+		"md5":     "900150983cd24fb0d6963f7d28e17f72",
+		"sha1":    "a9993e364706816aba3e25717850c26c9cd0d89d",
+		"SHA-256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"sha512":  "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+	}
+	for algorithm, want := range cases {
+		sum, err := Digest(algorithm, []byte("abc"))
+		if err != nil || hex.EncodeToString(sum) != want {
+			t.Errorf("Digest(%s) = %x, %v, want %s", algorithm, sum, err, want)
+		}
+	}
+	if _, err := NewHash("whirlpool"); err == nil {
+		t.Error("expected an unknown algorithm to fail")
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	// RFC 4231 test case 2
+	sig, err := Sign("sha256", []byte("Jefe"), []byte("what do ya want for nothing?"))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if err != nil || hex.EncodeToString(sig) != want {
+		t.Fatalf("Sign = %x, %v, want %s", sig, err, want)
+	}
+	if ok, _ := Verify("sha256", []byte("Jefe"), sig, []byte("what do ya want for nothing?")); !ok {
+		t.Error("expected the signature to verify")
+	}
+	if ok, _ := Verify("sha256", []byte("Jefe"), sig, []byte("tampered")); ok {
+		t.Error("expected a tampered message not to verify")
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 6070 test case 2
+	key, err := PBKDF2([]byte("password"), []byte("salt"), 2, 20, "sha1")
+	want := "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"
+	if err != nil || hex.EncodeToString(key) != want {
+		t.Errorf("PBKDF2 = %x, %v, want %s", key, err, want)
+	}
+	if _, err := PBKDF2([]byte("password"), []byte("salt"), 0, 20, "sha1"); err == nil {
+		t.Error("expected zero iterations to fail")
+	}
+}
+
+func TestRandom(t *testing.T) {
+	a, _ := RandomBytes(16)
+	b, _ := RandomBytes(16)
+	if len(a) != 16 || bytes.Equal(a, b) {
+		t.Errorf("RandomBytes gave %x and %x", a, b)
+	}
+	if _, err := RandomBytes(-1); err == nil {
+		t.Error("expected a negative size to fail")
+	}
+
+	id, err := RandomUUID()
+	if err != nil || !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("RandomUUID = %q, %v", id, err)
+	}
+}
+
+func TestAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	iv := bytes.Repeat([]byte{2}, 12)
+	aad := []byte("header")
+
+	sealed, err := EncryptAESGCM(key, iv, []byte("secret"), aad, 0)
+	if err != nil || len(sealed) != len("secret")+16 {
+		t.Fatalf("EncryptAESGCM = %x, %v", sealed, err)
+	}
+	plain, err := DecryptAESGCM(key, iv, sealed, aad, 128)
+	if err != nil || string(plain) != "secret" {
+		t.Errorf("DecryptAESGCM = %q, %v", plain, err)
+	}
+	if _, err := DecryptAESGCM(key, iv, sealed, []byte("other"), 0); err != ErrDecrypt {
+		t.Errorf("expected other additional data to fail with ErrDecrypt, got %v", err)
+	}
+
+	short, err := EncryptAESGCM(key, iv, []byte("secret"), nil, 96)
+	if err != nil || len(short) != len("secret")+12 {
+		t.Errorf("EncryptAESGCM with a 96-bit tag = %x, %v", short, err)
+	}
+	if _, err := EncryptAESGCM(key[:7], iv, nil, nil, 0); err == nil {
+		t.Error("expected a bad key size to fail")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	data := []byte{0xff, 0x00, 'a'}
+	for _, encoding := range []string{"hex", "base64", "base64url", "latin1"} {
+		s, err := Encode(data, encoding)
+		if err != nil {
+			t.Fatalf("Encode(%s) failed: %v", encoding, err)
+		}
+		back, err := Decode(s, encoding)
+		if err != nil || !bytes.Equal(back, data) {
+			t.Errorf("%s round trip = %x, %v", encoding, back, err)
+		}
+	}
+	if ok, err := TimingSafeEqual([]byte("a"), []byte("ab")); ok || err == nil {
+		t.Error("expected buffers of different lengths to fail")
+	}
+}
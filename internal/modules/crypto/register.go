@@ -0,0 +1,27 @@
+package crypto
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	KeepAlive() (release func())
+}
+
+// RegisterCryptoModule registers gode:crypto, also as crypto, and the
+// WebCrypto global crypto with getRandomValues, randomUUID and subtle
+func RegisterCryptoModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime)
+		exports := bridge.Exports()
+		runtime.RegisterModule("gode:crypto", exports)
+		runtime.RegisterModule("crypto", exports)
+		done <- runtime.GetGojaRuntime().Set("crypto", exports.Get("webcrypto"))
+	})
+	return <-done
+}
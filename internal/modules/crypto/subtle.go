@@ -0,0 +1,376 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// domError is an error named like the DOMExceptions WebCrypto rejects
+// with, such as OperationError or NotSupportedError
+type domError struct {
+	name    string
+	message string
+}
+
+func (e *domError) Error() string { return e.message }
+
+func (e *domError) JSName() string { return e.name }
+
+func (e *domError) JSProperties() map[string]interface{} { return nil }
+
+// typeError is the TypeError a SubtleCrypto method rejects with for an
+// argument of the wrong type
+func typeError(message string) error {
+	return &domError{"TypeError", message}
+}
+
+// cryptoKey is the secret behind a CryptoKey object
+type cryptoKey struct {
+	algorithm   string // HMAC or AES-GCM
+	hash        string // the hash of an HMAC key, such as SHA-256
+	data        []byte
+	extractable bool
+	usages      []string
+}
+
+func (k *cryptoKey) allows(usage string) bool {
+	for _, u := range k.usages {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
+// subtleWork is the part of a SubtleCrypto method that runs off the JS
+// thread. A []byte result resolves as an ArrayBuffer and a *cryptoKey as
+// a CryptoKey.
+type subtleWork func() (interface{}, error)
+
+// Subtle builds the crypto.subtle object: digest, importKey, exportKey,
+// generateKey, sign and verify with HMAC, and encrypt and decrypt with
+// AES-GCM. Like WebCrypto, every method returns a promise and reports bad
+// arguments by rejecting it.
+func (b *Bridge) Subtle() *goja.Object {
+	subtle := b.vm.NewObject()
+	subtle.Set("digest", b.subtleMethod(b.digest))
+	subtle.Set("importKey", b.subtleMethod(b.importKey))
+	subtle.Set("exportKey", b.subtleMethod(b.exportKey))
+	subtle.Set("generateKey", b.subtleMethod(b.generateKey))
+	subtle.Set("sign", b.subtleMethod(b.sign))
+	subtle.Set("verify", b.subtleMethod(b.verify))
+	subtle.Set("encrypt", b.subtleMethod(b.cipher(EncryptAESGCM, "encrypt")))
+	subtle.Set("decrypt", b.subtleMethod(b.cipher(DecryptAESGCM, "decrypt")))
+	return subtle
+}
+
+// subtleMethod returns a SubtleCrypto method that parses its arguments
+// with parse on the JS thread and runs the work parse returns on its own
+// goroutine
+func (b *Bridge) subtleMethod(parse func(call goja.FunctionCall) (subtleWork, error)) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		p, resolver := promise.New(b.vm, b.runtime)
+		work, err := parse(call)
+		if err != nil {
+			resolver.Reject(err)
+			return p
+		}
+		go func() {
+			result, err := work()
+			resolver.SettleWith(func() (interface{}, error) {
+				if err != nil {
+					return nil, err
+				}
+				switch v := result.(type) {
+				case []byte:
+					return b.vm.NewArrayBuffer(v), nil
+				case *cryptoKey:
+					return b.keyObject(v), nil
+				}
+				return result, nil
+			})
+		}()
+		return p
+	}
+}
+
+// digest implements subtle.digest(algorithm, data)
+func (b *Bridge) digest(call goja.FunctionCall) (subtleWork, error) {
+	name, _ := algorithmOf(call.Argument(0))
+	data, err := b.data(call.Argument(1))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := hashFunc(name); err != nil || !strings.HasPrefix(name, "SHA-") {
+		return nil, &domError{"NotSupportedError", fmt.Sprintf("unsupported digest algorithm %q", name)}
+	}
+	return func() (interface{}, error) { return Digest(name, data) }, nil
+}
+
+// importKey implements subtle.importKey('raw', keyData, algorithm,
+// extractable, usages)
+func (b *Bridge) importKey(call goja.FunctionCall) (subtleWork, error) {
+	if format := call.Argument(0).String(); format != "raw" {
+		return nil, &domError{"NotSupportedError", fmt.Sprintf("unsupported key format %q", format)}
+	}
+	data, err := b.data(call.Argument(1))
+	if err != nil {
+		return nil, err
+	}
+	key, err := b.keyAlgorithm(call.Argument(2))
+	if err != nil {
+		return nil, err
+	}
+	if key.algorithm == "AES-GCM" && !validAESKey(len(data)) {
+		return nil, &domError{"DataError", fmt.Sprintf("AES keys are 16, 24 or 32 bytes, got %d", len(data))}
+	}
+	key.data = data
+	key.extractable = call.Argument(3).ToBoolean()
+	key.usages = usagesOf(call.Argument(4))
+	return func() (interface{}, error) { return key, nil }, nil
+}
+
+// exportKey implements subtle.exportKey('raw', key)
+func (b *Bridge) exportKey(call goja.FunctionCall) (subtleWork, error) {
+	if format := call.Argument(0).String(); format != "raw" {
+		return nil, &domError{"NotSupportedError", fmt.Sprintf("unsupported key format %q", format)}
+	}
+	key, err := b.key(call.Argument(1))
+	if err != nil {
+		return nil, err
+	}
+	if !key.extractable {
+		return nil, &domError{"InvalidAccessError", "key is not extractable"}
+	}
+	return func() (interface{}, error) { return append([]byte(nil), key.data...), nil }, nil
+}
+
+// generateKey implements subtle.generateKey(algorithm, extractable,
+// usages). HMAC keys are as long as the block of their hash unless a
+// length is given; AES-GCM keys need a length of 128, 192 or 256.
+func (b *Bridge) generateKey(call goja.FunctionCall) (subtleWork, error) {
+	key, err := b.keyAlgorithm(call.Argument(0))
+	if err != nil {
+		return nil, err
+	}
+	key.extractable = call.Argument(1).ToBoolean()
+	key.usages = usagesOf(call.Argument(2))
+
+	_, params := algorithmOf(call.Argument(0))
+	bits := int64(0)
+	if params != nil {
+		if v := params.Get("length"); !isNullish(v) {
+			bits = v.ToInteger()
+		}
+	}
+	switch {
+	case bits%8 != 0 || bits < 0:
+		return nil, &domError{"OperationError", fmt.Sprintf("key length must be a positive multiple of 8 bits, got %d", bits)}
+	case key.algorithm == "AES-GCM" && !validAESKey(int(bits/8)):
+		return nil, &domError{"OperationError", fmt.Sprintf("AES key length must be 128, 192 or 256 bits, got %d", bits)}
+	case key.algorithm == "HMAC" && bits == 0:
+		h, _ := NewHash(key.hash)
+		bits = int64(h.BlockSize() * 8)
+	}
+
+	return func() (interface{}, error) {
+		data, err := RandomBytes(int(bits / 8))
+		if err != nil {
+			return nil, err
+		}
+		key.data = data
+		return key, nil
+	}, nil
+}
+
+// sign implements subtle.sign('HMAC', key, data)
+func (b *Bridge) sign(call goja.FunctionCall) (subtleWork, error) {
+	key, err := b.usableKey(call.Argument(0), call.Argument(1), "HMAC", "sign")
+	if err != nil {
+		return nil, err
+	}
+	data, err := b.data(call.Argument(2))
+	if err != nil {
+		return nil, err
+	}
+	return func() (interface{}, error) { return Sign(key.hash, key.data, data) }, nil
+}
+
+// verify implements subtle.verify('HMAC', key, signature, data), resolving
+// with whether the signature matches
+func (b *Bridge) verify(call goja.FunctionCall) (subtleWork, error) {
+	key, err := b.usableKey(call.Argument(0), call.Argument(1), "HMAC", "verify")
+	if err != nil {
+		return nil, err
+	}
+	signature, err := b.data(call.Argument(2))
+	if err != nil {
+		return nil, err
+	}
+	data, err := b.data(call.Argument(3))
+	if err != nil {
+		return nil, err
+	}
+	return func() (interface{}, error) { return Verify(key.hash, key.data, signature, data) }, nil
+}
+
+// cipher returns subtle.encrypt or subtle.decrypt: ({name: 'AES-GCM', iv,
+// additionalData, tagLength}, key, data)
+func (b *Bridge) cipher(fn func(key, iv, data, additionalData []byte, tagLength int) ([]byte, error), usage string) func(goja.FunctionCall) (subtleWork, error) {
+	return func(call goja.FunctionCall) (subtleWork, error) {
+		key, err := b.usableKey(call.Argument(0), call.Argument(1), "AES-GCM", usage)
+		if err != nil {
+			return nil, err
+		}
+		data, err := b.data(call.Argument(2))
+		if err != nil {
+			return nil, err
+		}
+
+		_, params := algorithmOf(call.Argument(0))
+		if params == nil {
+			return nil, typeError("AES-GCM requires an iv")
+		}
+		iv, err := b.data(params.Get("iv"))
+		if err != nil {
+			return nil, err
+		}
+		var additionalData []byte
+		if v := params.Get("additionalData"); !isNullish(v) {
+			if additionalData, err = b.data(v); err != nil {
+				return nil, err
+			}
+		}
+		tagLength := 128
+		if v := params.Get("tagLength"); !isNullish(v) {
+			tagLength = int(v.ToInteger())
+		}
+
+		return func() (interface{}, error) {
+			result, err := fn(key.data, iv, data, additionalData, tagLength)
+			if err != nil {
+				return nil, &domError{"OperationError", err.Error()}
+			}
+			return result, nil
+		}, nil
+	}
+}
+
+// keyAlgorithm reads the algorithm of a key to import or generate:
+// {name: 'HMAC', hash} or 'AES-GCM'
+func (b *Bridge) keyAlgorithm(value goja.Value) (*cryptoKey, error) {
+	name, params := algorithmOf(value)
+	switch name {
+	case "HMAC":
+		if params == nil || isNullish(params.Get("hash")) {
+			return nil, typeError("HMAC requires a hash")
+		}
+		hashName, _ := algorithmOf(params.Get("hash"))
+		if _, err := hashFunc(hashName); err != nil || !strings.HasPrefix(hashName, "SHA-") {
+			return nil, &domError{"NotSupportedError", fmt.Sprintf("unsupported hash %q", hashName)}
+		}
+		return &cryptoKey{algorithm: name, hash: hashName}, nil
+	case "AES-GCM":
+		return &cryptoKey{algorithm: name}, nil
+	}
+	return nil, &domError{"NotSupportedError", fmt.Sprintf("unsupported algorithm %q", name)}
+}
+
+// usableKey returns the key of a CryptoKey object, checking that it is a
+// key of the algorithm being used and allows usage
+func (b *Bridge) usableKey(algorithm, value goja.Value, name, usage string) (*cryptoKey, error) {
+	if got, _ := algorithmOf(algorithm); got != name {
+		return nil, &domError{"NotSupportedError", fmt.Sprintf("%s is not supported by %s", got, usage)}
+	}
+	key, err := b.key(value)
+	if err != nil {
+		return nil, err
+	}
+	if key.algorithm != name {
+		return nil, &domError{"InvalidAccessError", fmt.Sprintf("key is for %s, not %s", key.algorithm, name)}
+	}
+	if !key.allows(usage) {
+		return nil, &domError{"InvalidAccessError", fmt.Sprintf("key does not allow %s", usage)}
+	}
+	return key, nil
+}
+
+// key returns the key behind a CryptoKey object
+func (b *Bridge) key(value goja.Value) (*cryptoKey, error) {
+	if obj, ok := value.(*goja.Object); ok {
+		if v := obj.GetSymbol(b.keySym); v != nil {
+			if key, ok := v.Export().(*cryptoKey); ok {
+				return key, nil
+			}
+		}
+	}
+	return nil, typeError("key is not a CryptoKey")
+}
+
+// keyObject creates the CryptoKey object of key. It must be called on the
+// JS thread.
+func (b *Bridge) keyObject(key *cryptoKey) *goja.Object {
+	algorithm := b.vm.NewObject()
+	algorithm.Set("name", key.algorithm)
+	algorithm.Set("length", len(key.data)*8)
+	if key.hash != "" {
+		hash := b.vm.NewObject()
+		hash.Set("name", key.hash)
+		algorithm.Set("hash", hash)
+	}
+
+	usages := make([]interface{}, len(key.usages))
+	for i, usage := range key.usages {
+		usages[i] = usage
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("type", "secret")
+	obj.Set("extractable", key.extractable)
+	obj.Set("algorithm", algorithm)
+	obj.Set("usages", usages)
+	obj.SetSymbol(b.keySym, key)
+	return obj
+}
+
+// data copies a BufferSource argument, since the script may change it
+// before the work runs
+func (b *Bridge) data(value goja.Value) ([]byte, error) {
+	data, ok := bytesOf(value)
+	if !ok {
+		return nil, typeError("data must be an ArrayBuffer, TypedArray or DataView")
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// algorithmOf reads an algorithm given as a name or as an object with a
+// name and parameters. Names are matched case-insensitively, as in
+// WebCrypto, and returned in their standard case.
+func algorithmOf(value goja.Value) (string, *goja.Object) {
+	var params *goja.Object
+	if obj, ok := value.(*goja.Object); ok {
+		params = obj
+		value = obj.Get("name")
+	}
+	if isNullish(value) {
+		return "", params
+	}
+	return strings.ToUpper(value.String()), params
+}
+
+func usagesOf(value goja.Value) []string {
+	var usages []string
+	if list, ok := value.Export().([]interface{}); ok {
+		for _, usage := range list {
+			usages = append(usages, fmt.Sprint(usage))
+		}
+	}
+	return usages
+}
+
+func validAESKey(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
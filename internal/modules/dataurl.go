@@ -0,0 +1,93 @@
+package modules
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rizqme/gode/internal/errors"
+)
+
+// MaxInlineSize is the largest module a data: URL may hold, and the largest
+// inline worker script, once decoded
+const MaxInlineSize = 8 << 20
+
+// IsDataURL reports whether specifier is a data: URL
+func IsDataURL(specifier string) bool {
+	return len(specifier) >= 5 && strings.EqualFold(specifier[:5], "data:")
+}
+
+// ParseDataURL decodes a data: URL, returning its media type without
+// parameters, such as text/javascript, and its contents
+func ParseDataURL(specifier string) (mediaType string, data []byte, err error) {
+	if !IsDataURL(specifier) {
+		return "", nil, fmt.Errorf("not a data: URL")
+	}
+	header, payload, ok := strings.Cut(specifier[5:], ",")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid data: URL: missing comma")
+	}
+
+	params := strings.Split(header, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(params[0]))
+	if mediaType == "" {
+		mediaType = "text/plain"
+	}
+	isBase64 := strings.EqualFold(params[len(params)-1], "base64") && len(params) > 1
+
+	// Checked before decoding too, so that a huge URL is not copied again.
+	// Percent-encoding takes at most three characters a byte.
+	if len(payload) > MaxInlineSize*3 {
+		return "", nil, fmt.Errorf("data: URL is larger than %d bytes", MaxInlineSize)
+	}
+	if isBase64 {
+		payload, err = url.PathUnescape(payload)
+		if err == nil {
+			encoding := base64.StdEncoding
+			if len(payload)%4 != 0 {
+				encoding = base64.RawStdEncoding
+			}
+			data, err = encoding.DecodeString(payload)
+		}
+	} else {
+		var text string
+		text, err = url.PathUnescape(payload)
+		data = []byte(text)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data: URL: %w", err)
+	}
+	if len(data) > MaxInlineSize {
+		return "", nil, fmt.Errorf("data: URL is larger than %d bytes", MaxInlineSize)
+	}
+	return mediaType, data, nil
+}
+
+// loadDataURL returns the source of a data: URL module. JavaScript and JSON
+// are supported; other media types are refused, as node does.
+func (m *ModuleManager) loadDataURL(specifier string) (string, error) {
+	if err := m.checkPermission("code", "data:"); err != nil {
+		return "", errors.NewModuleError("data:", DataURLName(specifier), "load", err)
+	}
+	mediaType, data, err := ParseDataURL(specifier)
+	if err != nil {
+		return "", errors.NewModuleError("data:", DataURLName(specifier), "load", err)
+	}
+	switch mediaType {
+	case "text/javascript", "application/javascript", "application/x-javascript", "text/ecmascript", "application/ecmascript":
+		return string(data), nil
+	case "application/json":
+		return fmt.Sprintf("module.exports = %s;", data), nil
+	}
+	return "", errors.NewModuleError("data:", DataURLName(specifier), "load", fmt.Errorf("unsupported data: URL media type %q", mediaType))
+}
+
+// DataURLName shortens a data: URL for error messages and stack traces
+func DataURLName(specifier string) string {
+	const max = 64
+	if len(specifier) <= max {
+		return specifier
+	}
+	return specifier[:max] + "..."
+}
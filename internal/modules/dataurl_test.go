@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDataURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		mediaType string
+		data      string
+	}{
+		{"data:text/javascript,export%20default%201;", "text/javascript", "export default 1;"},
+		{"data:text/javascript;base64,ZXhwb3J0IGRlZmF1bHQgMjs=", "text/javascript", "export default 2;"},
+		{"data:text/javascript;charset=utf-8;base64,ZXhwb3J0IGRlZmF1bHQgMjs", "text/javascript", "export default 2;"},
+		{"data:Application/JSON,%7B%22a%22%3A1%7D", "application/json", `{"a":1}`},
+		{"data:,hello", "text/plain", "hello"},
+	}
+	for _, tt := range tests {
+		mediaType, data, err := ParseDataURL(tt.url)
+		if err != nil || mediaType != tt.mediaType || string(data) != tt.data {
+			t.Errorf("ParseDataURL(%q) = %q, %q, %v", tt.url, mediaType, data, err)
+		}
+	}
+
+	for _, url := range []string{"data:text/javascript", "data:text/javascript;base64,!!!", "file:///a.js"} {
+		if _, _, err := ParseDataURL(url); err == nil {
+			t.Errorf("ParseDataURL(%q) should fail", url)
+		}
+	}
+
+	huge := "data:text/javascript," + strings.Repeat("a", MaxInlineSize+1)
+	if _, _, err := ParseDataURL(huge); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected a size error, got %v", err)
+	}
+}
+
+func TestLoadDataURL(t *testing.T) {
+	manager := NewModuleManager()
+
+	source, err := manager.Load("data:text/javascript,module.exports%20=%2042;")
+	if err != nil || source != "module.exports = 42;" {
+		t.Errorf("Load() = %q, %v", source, err)
+	}
+	source, err = manager.Load(`data:application/json,{"ok":true}`)
+	if err != nil || source != `module.exports = {"ok":true};` {
+		t.Errorf("Load() of JSON = %q, %v", source, err)
+	}
+	if _, err := manager.Load("data:text/plain,hello"); err == nil || !strings.Contains(err.Error(), "media type") {
+		t.Errorf("Expected text/plain to be refused, got %v", err)
+	}
+	if _, err := manager.Resolve("./helper.js", "data:text/javascript,"); err == nil {
+		t.Error("Expected a relative import from a data: URL module to fail")
+	}
+	if resolved, err := manager.Resolve("gode:fs", "data:text/javascript,"); err != nil || resolved != "gode:fs" {
+		t.Errorf("Resolve() of a built-in from a data: URL = %q, %v", resolved, err)
+	}
+}
@@ -0,0 +1,70 @@
+package diagnostics
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// Bridge provides JavaScript bindings for gode:diagnostics_channel
+type Bridge struct {
+	vm       *goja.Runtime
+	channels *Channels
+}
+
+// NewBridge creates a new diagnostics bridge over channels
+func NewBridge(vm *goja.Runtime, channels *Channels) *Bridge {
+	return &Bridge{
+		vm:       vm,
+		channels: channels,
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+
+	exports.Set("channel", func(name string) *goja.Object {
+		return b.object(b.channels.Channel(name))
+	})
+	exports.Set("hasSubscribers", b.channels.HasSubscribers)
+	exports.Set("subscribe", func(name string, fn goja.Value) {
+		b.channels.Channel(name).subscribe(fn, b.function(fn))
+	})
+	exports.Set("unsubscribe", func(name string, fn goja.Value) bool {
+		return b.channels.Channel(name).unsubscribe(fn)
+	})
+
+	return exports
+}
+
+// object returns the JS object of ch, the same one every time
+func (b *Bridge) object(ch *Channel) *goja.Object {
+	if ch.obj != nil {
+		return ch.obj
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("name", ch.name)
+	obj.DefineAccessorProperty("hasSubscribers", b.vm.ToValue(func() bool {
+		return len(ch.subscribers) > 0
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.Set("subscribe", func(fn goja.Value) {
+		ch.subscribe(fn, b.function(fn))
+	})
+	obj.Set("unsubscribe", ch.unsubscribe)
+	obj.Set("publish", func(message goja.Value) {
+		if len(ch.subscribers) > 0 {
+			b.channels.publish(ch, message)
+		}
+	})
+
+	ch.obj = obj
+	return obj
+}
+
+func (b *Bridge) function(fn goja.Value) goja.Callable {
+	call, ok := goja.AssertFunction(fn)
+	if !ok {
+		panic(b.vm.NewTypeError("subscriber must be a function"))
+	}
+	return call
+}
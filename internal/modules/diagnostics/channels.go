@@ -0,0 +1,114 @@
+// Package diagnostics implements gode:diagnostics_channel, named channels
+// that core modules publish to and scripts subscribe to, in the manner of
+// Node's diagnostics_channel. Core modules publish on:
+//
+//	http.client.request  fetch is called: {url, method}
+//	module.load          require loaded a module: {specifier, filename, duration, error}
+//	plugin.call          a plugin function returned: {plugin, method, duration, error}
+//
+// Publishing to a channel without subscribers costs a map lookup, so
+// publishers build messages only after checking HasSubscribers.
+package diagnostics
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// Channels is the registry of channels of one runtime. It is used from the
+// JS thread only.
+type Channels struct {
+	vm       *goja.Runtime
+	channels map[string]*Channel
+}
+
+// Channel delivers published messages to its subscribers in the order they
+// subscribed
+type Channel struct {
+	name        string
+	subscribers []*subscriber
+	obj         *goja.Object // JS object, created on first use
+}
+
+type subscriber struct {
+	fn   goja.Value
+	call goja.Callable
+}
+
+// NewChannels creates an empty registry
+func NewChannels(vm *goja.Runtime) *Channels {
+	return &Channels{
+		vm:       vm,
+		channels: make(map[string]*Channel),
+	}
+}
+
+// Channel returns the channel called name, creating it the first time
+func (c *Channels) Channel(name string) *Channel {
+	ch, exists := c.channels[name]
+	if !exists {
+		ch = &Channel{name: name}
+		c.channels[name] = ch
+	}
+	return ch
+}
+
+// HasSubscribers reports whether anything is subscribed to name
+func (c *Channels) HasSubscribers(name string) bool {
+	ch, exists := c.channels[name]
+	return exists && len(ch.subscribers) > 0
+}
+
+// Publish sends the message built by message to the subscribers of name.
+// message is not called when there are none.
+func (c *Channels) Publish(name string, message func() map[string]interface{}) {
+	if !c.HasSubscribers(name) {
+		return
+	}
+	c.publish(c.channels[name], c.vm.ToValue(message()))
+}
+
+// publish calls every subscriber with (message, name). A throwing
+// subscriber doesn't stop the others or the publisher: its error is
+// rethrown from a process.nextTick callback, making it an uncaught
+// exception, as in Node.
+func (c *Channels) publish(ch *Channel, message goja.Value) {
+	name := c.vm.ToValue(ch.name)
+	// Copy so subscribers added or removed by a subscriber wait for the
+	// next message
+	for _, s := range append([]*subscriber(nil), ch.subscribers...) {
+		if _, err := s.call(goja.Undefined(), message, name); err != nil {
+			c.rethrow(err)
+		}
+	}
+}
+
+func (c *Channels) rethrow(err error) {
+	ex, ok := err.(*goja.Exception)
+	if !ok {
+		return
+	}
+	process, ok := c.vm.Get("process").(*goja.Object)
+	if !ok {
+		return
+	}
+	if nextTick, ok := goja.AssertFunction(process.Get("nextTick")); ok {
+		thrown := ex.Value()
+		nextTick(process, c.vm.ToValue(func() { panic(thrown) }))
+	}
+}
+
+func (ch *Channel) subscribe(fn goja.Value, call goja.Callable) {
+	ch.subscribers = append(ch.subscribers, &subscriber{fn: fn, call: call})
+}
+
+// unsubscribe removes the first subscription of fn, reporting whether
+// there was one
+func (ch *Channel) unsubscribe(fn goja.Value) bool {
+	for i, s := range ch.subscribers {
+		if s.fn.StrictEquals(fn) {
+			ch.subscribers = append(ch.subscribers[:i], ch.subscribers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
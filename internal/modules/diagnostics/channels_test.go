@@ -0,0 +1,70 @@
+package diagnostics_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestDiagnosticsChannel(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.js")
+	if err := os.WriteFile(lib, []byte("module.exports = 1;"), 0644); err != nil {
+		t.Fatalf("Failed to write module: %v", err)
+	}
+
+	value, err := rt.RunScript("diagnostics", `
+		const dc = require('gode:diagnostics_channel');
+		const seen = [];
+		const onRequest = (msg, name) => seen.push(name + ' ' + msg.method + ' ' + msg.url);
+		const before = dc.hasSubscribers('http.client.request');
+		dc.subscribe('http.client.request', onRequest);
+		dc.subscribe('module.load', (msg) => seen.push('load ' + (msg.filename.indexOf('lib.js') >= 0) + ' ' + (typeof msg.duration)));
+
+		fetch('https://api.example.com/items', { method: 'post' });
+		require(`+strconv.Quote(lib)+`);
+		dc.unsubscribe('http.client.request', onRequest);
+		fetch('https://api.example.com/ignored');
+
+		// Channels are shared by name and scripts can publish their own
+		const custom = dc.channel('app.job');
+		const same = dc.channel('app.job') === custom;
+		custom.subscribe((msg) => seen.push('job ' + msg.id));
+		custom.publish({ id: 7 });
+		[before, custom.hasSubscribers, same, seen.join('|')].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	want := "false,true,true,http.client.request POST https://api.example.com/items|load true number|job 7"
+	if value != want {
+		t.Errorf("Diagnostics = %v, want %v", value, want)
+	}
+
+	// A throwing subscriber doesn't break the publisher; its error is uncaught
+	value, err = rt.RunScriptAsync("throwing", `
+		new Promise((resolve) => {
+			const dc = require('gode:diagnostics_channel');
+			process.once('uncaughtException', (err) => resolve(err.message + ',' + delivered));
+			let delivered = 0;
+			dc.subscribe('app.broken', () => { throw new Error('subscriber failed'); });
+			dc.subscribe('app.broken', () => { delivered++; });
+			dc.channel('app.broken').publish({});
+		})
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if value != "subscriber failed,1" {
+		t.Errorf("Throwing subscriber = %v", value)
+	}
+}
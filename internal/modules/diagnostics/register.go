@@ -0,0 +1,25 @@
+package diagnostics
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// RegisterDiagnosticsModule registers gode:diagnostics_channel in the
+// JavaScript runtime and returns the channels core modules publish to
+func RegisterDiagnosticsModule(runtime RuntimeInterface) (*Channels, error) {
+	done := make(chan *Channels, 1)
+	runtime.QueueJSOperation(func() {
+		channels := NewChannels(runtime.GetGojaRuntime())
+		bridge := NewBridge(runtime.GetGojaRuntime(), channels)
+		runtime.RegisterModule("gode:diagnostics_channel", bridge.Exports())
+		done <- channels
+	})
+	return <-done, nil
+}
@@ -0,0 +1,745 @@
+// Package esm turns ES modules into functions that goja can run as plain
+// scripts, for the module loader in internal/runtime.
+//
+// Import and export statements are removed, and the module body is wrapped
+// in a function that takes the loader's handle for the module:
+//
+//	(function* (__gode_module) {"use strict"; let a;
+//	  __gode_module.link({"f": () => f}, function () { a = __gode_module.get(0, "a"); }, []);
+//	  yield; ...body...
+//	})
+//
+// Calling the function hoists the module's declarations and hands the loader
+// getters for its exports; the body runs once the loader resumes it, after
+// the modules it imports. Imported bindings are refreshed by calling the
+// update function whenever a module in the graph finishes evaluating, which
+// is what lets circular imports see each other's hoisted functions. Modules
+// that use top-level await are wrapped in an async function that waits for
+// __gode_module.linked instead of a generator.
+//
+// The transform works on tokens rather than a syntax tree, so it keeps the
+// body as written: line numbers in stack traces stay those of the source.
+package esm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// handle is the parameter through which module code reaches the loader
+	handle = "__gode_module"
+	// defaultLocal holds the value of export default <expression>
+	defaultLocal = "__gode_default"
+)
+
+// Module is an ES module transformed into a script
+type Module struct {
+	// Code is a function expression to call with the loader's handle
+	Code string
+	// Requests are the specifiers the module imports or re-exports from,
+	// each once, in order of appearance. The handle's get and namespace
+	// take indexes into it.
+	Requests []string
+	// Exports are the names the module exports, not counting export *
+	Exports []string
+	// Async reports whether the module uses top-level await
+	Async bool
+}
+
+// IsModule reports whether source is an ES module, that is whether it has
+// import or export statements or uses import.meta
+func IsModule(source string) bool {
+	if !strings.Contains(source, "import") && !strings.Contains(source, "export") {
+		return false
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return false
+	}
+	p := newParser(source, tokens)
+	p.parse()
+	return p.module
+}
+
+// Transform converts the ES module source into a Module
+func Transform(source string) (*Module, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := newParser(source, tokens)
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	if p.async {
+		b.WriteString("(async function (" + handle + ") {\"use strict\"; ")
+	} else {
+		b.WriteString("(function* (" + handle + ") {\"use strict\"; ")
+	}
+	if len(p.bindings) > 0 {
+		locals := make([]string, len(p.bindings))
+		for i, binding := range p.bindings {
+			locals[i] = binding.local
+		}
+		b.WriteString("let " + strings.Join(locals, ", ") + "; ")
+	}
+
+	b.WriteString(handle + ".link({")
+	for i, export := range p.exports {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s: () => %s", quote(export.name), export.value)
+	}
+	b.WriteString("}, function () {")
+	for _, binding := range p.bindings {
+		if binding.name == "*" {
+			fmt.Fprintf(&b, " %s = %s.namespace(%d);", binding.local, handle, binding.request)
+		} else {
+			fmt.Fprintf(&b, " %s = %s.get(%d, %s);", binding.local, handle, binding.request, quote(binding.name))
+		}
+	}
+	b.WriteString(" }, [")
+	for i, star := range p.stars {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.Itoa(star))
+	}
+	b.WriteString("]); ")
+	if p.async {
+		b.WriteString("await " + handle + ".linked; ")
+	} else {
+		b.WriteString("yield; ")
+	}
+
+	sort.SliceStable(p.edits, func(i, j int) bool { return p.edits[i].start < p.edits[j].start })
+	pos := 0
+	for _, e := range p.edits {
+		b.WriteString(source[pos:e.start])
+		b.WriteString(e.text)
+		pos = e.end
+	}
+	b.WriteString(source[pos:])
+	b.WriteString("\n})")
+
+	module := &Module{Code: b.String(), Requests: p.requests, Async: p.async}
+	for _, export := range p.exports {
+		module.Exports = append(module.Exports, export.name)
+	}
+	return module, nil
+}
+
+// binding is a local name bound by an import statement. name is the
+// export it is bound to, or "*" for the namespace.
+type binding struct {
+	local   string
+	request int
+	name    string
+}
+
+// export is an exported name and the expression its getter returns
+type export struct {
+	name  string
+	value string
+}
+
+// edit replaces source[start:end] with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+// scope is an open bracket, or the body of an arrow function without braces
+type scope struct {
+	open  string
+	fn    bool   // the bracket holds a function body
+	owner string // for (, the token before it, such as if or a function name
+	arrow bool
+}
+
+// controlKeywords are followed by a parenthesized head and a block that is
+// not a function body
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "with": true,
+}
+
+type parser struct {
+	source string
+	tokens []token
+
+	module   bool
+	async    bool
+	edits    []edit
+	requests []string
+	bindings []binding
+	exports  []export
+	stars    []int
+}
+
+func newParser(source string, tokens []token) *parser {
+	return &parser{source: source, tokens: tokens}
+}
+
+// at returns the token at i, or an EOF token past the end
+func (p *parser) at(i int) token {
+	if i < 0 || i >= len(p.tokens) {
+		return token{kind: tokEOF, start: len(p.source), end: len(p.source)}
+	}
+	return p.tokens[i]
+}
+
+func (p *parser) errorAt(i int, format string, args ...interface{}) error {
+	return syntaxError(p.source, p.at(i).start, format, args...)
+}
+
+// parse finds the import and export statements at the top level, uses of
+// import.meta and import(), and top-level await
+func (p *parser) parse() error {
+	var (
+		stack       []scope
+		closedOwner string // owner of the last ( that was closed
+	)
+	popArrows := func() {
+		for len(stack) > 0 && stack[len(stack)-1].arrow {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	for i := 0; i < len(p.tokens); i++ {
+		t := p.tokens[i]
+		prev := p.at(i - 1)
+		if t.newline && len(stack) > 0 && stack[len(stack)-1].arrow && endsExpression(prev) && startsStatement(t) {
+			popArrows()
+		}
+
+		if t.kind == tokIdent && !prev.is(tokPunct, ".") && !prev.is(tokPunct, "?.") {
+			switch t.text {
+			case "import":
+				next := p.at(i + 1)
+				switch {
+				case next.is(tokPunct, "("):
+					p.replace(t.start, t.end, handle+".import")
+					continue
+				case next.is(tokPunct, "."):
+					if p.at(i+2).is(tokIdent, "meta") {
+						p.module = true
+						p.replace(t.start, p.at(i+2).end, handle+".meta")
+						i += 2
+						continue
+					}
+				case len(stack) == 0:
+					p.module = true
+					j, err := p.importStatement(i)
+					if err != nil {
+						return err
+					}
+					i = j - 1
+					continue
+				}
+			case "export":
+				if len(stack) == 0 {
+					p.module = true
+					j, err := p.exportStatement(i)
+					if err != nil {
+						return err
+					}
+					i = j - 1
+					continue
+				}
+			case "await":
+				if !inFunction(stack) {
+					p.async = true
+				}
+			}
+		}
+
+		if t.closes() {
+			popArrows()
+			if len(stack) > 0 {
+				if top := stack[len(stack)-1]; top.open == "(" {
+					closedOwner = top.owner
+				}
+				stack = stack[:len(stack)-1]
+			}
+		}
+		switch {
+		case t.opens() && t.text == "(":
+			owner := ""
+			if prev.kind == tokIdent {
+				owner = prev.text
+			}
+			stack = append(stack, scope{open: "(", owner: owner})
+		case t.opens() && t.text == "{":
+			fn := prev.is(tokPunct, "=>") || prev.is(tokPunct, ")") && closedOwner != "" && !controlKeywords[closedOwner]
+			stack = append(stack, scope{open: "{", fn: fn})
+		case t.opens():
+			stack = append(stack, scope{open: t.text})
+		case t.is(tokPunct, "=>") && !p.at(i+1).is(tokPunct, "{"):
+			stack = append(stack, scope{fn: true, arrow: true})
+		case t.is(tokPunct, ",") || t.is(tokPunct, ";"):
+			popArrows()
+		}
+	}
+	return nil
+}
+
+func inFunction(stack []scope) bool {
+	for _, s := range stack {
+		if s.fn {
+			return true
+		}
+	}
+	return false
+}
+
+// importStatement parses the import statement starting at i, records its
+// bindings and removes it. It returns the index of the token after it.
+func (p *parser) importStatement(i int) (int, error) {
+	j := i + 1
+	var specifier string
+	var bindings []binding
+
+	if t := p.at(j); t.kind == tokString {
+		specifier = unquote(t.text)
+		j++
+	} else {
+		if t.kind == tokIdent && !t.is(tokIdent, "from") || t.is(tokIdent, "from") && p.at(j+1).is(tokIdent, "from") {
+			bindings = append(bindings, binding{local: t.text, name: "default"})
+			j++
+			if p.at(j).is(tokPunct, ",") {
+				j++
+			}
+		}
+		switch {
+		case p.at(j).is(tokPunct, "*"):
+			if !p.at(j+1).is(tokIdent, "as") || p.at(j+2).kind != tokIdent {
+				return 0, p.errorAt(j, "expected * as name")
+			}
+			bindings = append(bindings, binding{local: p.at(j + 2).text, name: "*"})
+			j += 3
+		case p.at(j).is(tokPunct, "{"):
+			names, next, err := p.namedList(j, false)
+			if err != nil {
+				return 0, err
+			}
+			for _, name := range names {
+				bindings = append(bindings, binding{local: name[1], name: name[0]})
+			}
+			j = next
+		}
+		if !p.at(j).is(tokIdent, "from") || p.at(j+1).kind != tokString {
+			return 0, p.errorAt(j, "expected from \"module\" in import statement")
+		}
+		specifier = unquote(p.at(j + 1).text)
+		j += 2
+	}
+
+	j = p.skipAttributes(j)
+	if p.at(j).is(tokPunct, ";") {
+		j++
+	}
+
+	request := p.request(specifier)
+	for _, b := range bindings {
+		b.request = request
+		p.bindings = append(p.bindings, b)
+	}
+	p.blank(p.at(i).start, p.at(j-1).end)
+	return j, nil
+}
+
+// exportStatement parses the export statement starting at i and records
+// its exports. Declarations keep their source with the export keyword
+// removed; it returns the index of the next token to scan.
+func (p *parser) exportStatement(i int) (int, error) {
+	next := p.at(i + 1)
+	switch {
+	case next.is(tokPunct, "*"):
+		j := i + 2
+		name := ""
+		if p.at(j).is(tokIdent, "as") {
+			name = exportName(p.at(j + 1))
+			j += 2
+		}
+		if !p.at(j).is(tokIdent, "from") || p.at(j+1).kind != tokString {
+			return 0, p.errorAt(j, "expected from \"module\" after export *")
+		}
+		request := p.request(unquote(p.at(j + 1).text))
+		j = p.skipAttributes(j + 2)
+		if p.at(j).is(tokPunct, ";") {
+			j++
+		}
+		if name == "" {
+			p.stars = append(p.stars, request)
+		} else {
+			p.export(name, fmt.Sprintf("%s.namespace(%d)", handle, request))
+		}
+		p.blank(p.at(i).start, p.at(j-1).end)
+		return j, nil
+
+	case next.is(tokPunct, "{"):
+		names, j, err := p.namedList(i+1, true)
+		if err != nil {
+			return 0, err
+		}
+		if p.at(j).is(tokIdent, "from") && p.at(j+1).kind == tokString {
+			request := p.request(unquote(p.at(j + 1).text))
+			for _, name := range names {
+				p.export(name[1], fmt.Sprintf("%s.get(%d, %s)", handle, request, quote(name[0])))
+			}
+			j = p.skipAttributes(j + 2)
+		} else {
+			for _, name := range names {
+				p.export(name[1], name[0])
+			}
+		}
+		if p.at(j).is(tokPunct, ";") {
+			j++
+		}
+		p.blank(p.at(i).start, p.at(j-1).end)
+		return j, nil
+
+	case next.is(tokIdent, "default"):
+		k := i + 2
+		decl := k
+		if p.at(k).is(tokIdent, "async") && p.at(k+1).is(tokIdent, "function") && !p.at(k+1).newline {
+			decl++
+		}
+		isFunction := p.at(decl).is(tokIdent, "function")
+		if !isFunction && !p.at(decl).is(tokIdent, "class") {
+			// export default <expression>
+			p.replace(p.at(i).start, next.end, "const "+defaultLocal+" =")
+			p.export("default", defaultLocal)
+			return i + 2, nil
+		}
+		n := decl + 1
+		if isFunction && p.at(n).is(tokPunct, "*") {
+			n++
+		}
+		p.blank(p.at(i).start, p.at(k).start)
+		if name := p.at(n); name.kind == tokIdent && !name.is(tokIdent, "extends") {
+			p.export("default", name.text)
+		} else {
+			p.replace(p.at(n-1).end, p.at(n-1).end, " "+defaultLocal)
+			p.export("default", defaultLocal)
+		}
+		return k, nil
+
+	case next.is(tokIdent, "var") || next.is(tokIdent, "let") || next.is(tokIdent, "const"):
+		names, err := p.declarationNames(i + 2)
+		if err != nil {
+			return 0, err
+		}
+		for _, name := range names {
+			p.export(name, name)
+		}
+		p.blank(p.at(i).start, next.start)
+		return i + 1, nil
+
+	case next.is(tokIdent, "function") || next.is(tokIdent, "class") ||
+		next.is(tokIdent, "async") && p.at(i+2).is(tokIdent, "function"):
+		n := i + 2
+		if next.is(tokIdent, "async") {
+			n++
+		}
+		if p.at(n).is(tokPunct, "*") {
+			n++
+		}
+		if p.at(n).kind != tokIdent {
+			return 0, p.errorAt(n, "exported %s must have a name", next.text)
+		}
+		p.export(p.at(n).text, p.at(n).text)
+		p.blank(p.at(i).start, next.start)
+		return i + 1, nil
+	}
+	return 0, p.errorAt(i+1, "unexpected token %q after export", next.text)
+}
+
+// namedList parses { a, b as c, "d" as e } starting at i and returns the
+// [name, alias] pairs and the index after the closing brace. For exports,
+// names may be string literals only when re-exported from another module,
+// which the caller checks.
+func (p *parser) namedList(i int, exporting bool) ([][2]string, int, error) {
+	var names [][2]string
+	j := i + 1
+	for !p.at(j).is(tokPunct, "}") {
+		t := p.at(j)
+		if t.kind != tokIdent && t.kind != tokString {
+			return nil, 0, p.errorAt(j, "unexpected token %q in braces", t.text)
+		}
+		name := exportName(t)
+		alias := name
+		j++
+		if p.at(j).is(tokIdent, "as") {
+			as := p.at(j + 1)
+			if as.kind != tokIdent && !(exporting && as.kind == tokString) {
+				return nil, 0, p.errorAt(j+1, "expected a name after as")
+			}
+			alias = exportName(as)
+			j += 2
+		} else if !exporting && t.kind == tokString {
+			return nil, 0, p.errorAt(j-1, "a string import name needs as")
+		}
+		names = append(names, [2]string{name, alias})
+		if p.at(j).is(tokPunct, ",") {
+			j++
+		} else if !p.at(j).is(tokPunct, "}") {
+			return nil, 0, p.errorAt(j, "expected , or }")
+		}
+	}
+	return names, j + 1, nil
+}
+
+// declarationNames returns the names declared by the declarators of a
+// var, let or const statement starting at i
+func (p *parser) declarationNames(i int) ([]string, error) {
+	var names []string
+	for {
+		declared, j, err := p.pattern(i)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, declared...)
+		if p.at(j).is(tokPunct, "=") {
+			j = p.skipExpression(j + 1)
+		}
+		if !p.at(j).is(tokPunct, ",") {
+			return names, nil
+		}
+		i = j + 1
+	}
+}
+
+// pattern returns the names bound by the binding pattern at i, such as a,
+// { a, b: [c, ...d] } or [e = 1], and the index after it
+func (p *parser) pattern(i int) ([]string, int, error) {
+	t := p.at(i)
+	switch {
+	case t.kind == tokIdent:
+		return []string{t.text}, i + 1, nil
+
+	case t.is(tokPunct, "{"):
+		var names []string
+		j := i + 1
+		for !p.at(j).is(tokPunct, "}") {
+			switch key := p.at(j); {
+			case key.is(tokPunct, "..."):
+				rest, next, err := p.pattern(j + 1)
+				if err != nil {
+					return nil, 0, err
+				}
+				names, j = append(names, rest...), next
+			case key.kind == tokEOF:
+				return nil, 0, p.errorAt(j, "unterminated pattern")
+			default:
+				if key.is(tokPunct, "[") {
+					j = p.skipBalanced(j)
+				} else {
+					j++
+				}
+				if p.at(j).is(tokPunct, ":") {
+					value, next, err := p.pattern(j + 1)
+					if err != nil {
+						return nil, 0, err
+					}
+					names, j = append(names, value...), next
+				} else if key.kind == tokIdent {
+					names = append(names, key.text)
+				}
+				if p.at(j).is(tokPunct, "=") {
+					j = p.skipExpression(j + 1)
+				}
+			}
+			if p.at(j).is(tokPunct, ",") {
+				j++
+			} else if !p.at(j).is(tokPunct, "}") {
+				return nil, 0, p.errorAt(j, "expected , or } in pattern")
+			}
+		}
+		return names, j + 1, nil
+
+	case t.is(tokPunct, "["):
+		var names []string
+		j := i + 1
+		for !p.at(j).is(tokPunct, "]") {
+			if p.at(j).is(tokPunct, ",") {
+				j++
+				continue
+			}
+			if p.at(j).kind == tokEOF {
+				return nil, 0, p.errorAt(j, "unterminated pattern")
+			}
+			start := j
+			if p.at(j).is(tokPunct, "...") {
+				start++
+			}
+			element, next, err := p.pattern(start)
+			if err != nil {
+				return nil, 0, err
+			}
+			names, j = append(names, element...), next
+			if p.at(j).is(tokPunct, "=") {
+				j = p.skipExpression(j + 1)
+			}
+			if p.at(j).is(tokPunct, ",") {
+				j++
+			} else if !p.at(j).is(tokPunct, "]") {
+				return nil, 0, p.errorAt(j, "expected , or ] in pattern")
+			}
+		}
+		return names, j + 1, nil
+	}
+	return nil, 0, p.errorAt(i, "unexpected token %q in declaration", t.text)
+}
+
+// skipBalanced returns the index after the bracket that closes the one
+// opened at i
+func (p *parser) skipBalanced(i int) int {
+	depth := 0
+	for j := i; j < len(p.tokens); j++ {
+		t := p.tokens[j]
+		if t.closes() {
+			depth--
+		}
+		if t.opens() {
+			depth++
+		}
+		if depth == 0 {
+			return j + 1
+		}
+	}
+	return len(p.tokens)
+}
+
+// skipExpression returns the index of the token that ends the expression
+// starting at i: a , or ; outside brackets, a bracket closing one opened
+// before i, or the start of a new statement after a line break
+func (p *parser) skipExpression(i int) int {
+	depth := 0
+	for j := i; j < len(p.tokens); j++ {
+		t := p.tokens[j]
+		if depth == 0 {
+			if t.is(tokPunct, ",") || t.is(tokPunct, ";") || t.closes() {
+				return j
+			}
+			if j > i && t.newline && endsExpression(p.tokens[j-1]) && startsStatement(t) {
+				return j
+			}
+		}
+		if t.closes() {
+			depth--
+		}
+		if t.opens() {
+			depth++
+		}
+	}
+	return len(p.tokens)
+}
+
+// skipAttributes skips import attributes, with { type: "json" }, at i
+func (p *parser) skipAttributes(i int) int {
+	t := p.at(i)
+	if (t.is(tokIdent, "with") || t.is(tokIdent, "assert") && !t.newline) && p.at(i+1).is(tokPunct, "{") {
+		return p.skipBalanced(i + 1)
+	}
+	return i
+}
+
+// request returns the index of specifier in the module's requests, adding
+// it the first time
+func (p *parser) request(specifier string) int {
+	for i, r := range p.requests {
+		if r == specifier {
+			return i
+		}
+	}
+	p.requests = append(p.requests, specifier)
+	return len(p.requests) - 1
+}
+
+func (p *parser) export(name, value string) {
+	for _, e := range p.exports {
+		if e.name == name {
+			return
+		}
+	}
+	p.exports = append(p.exports, export{name: name, value: value})
+}
+
+func (p *parser) replace(start, end int, text string) {
+	p.edits = append(p.edits, edit{start: start, end: end, text: text})
+}
+
+// blank removes source[start:end], keeping its line breaks so that the
+// lines after it keep their numbers
+func (p *parser) blank(start, end int) {
+	p.replace(start, end, strings.Repeat("\n", strings.Count(p.source[start:end], "\n")))
+}
+
+// endsExpression reports whether an expression can end with t
+func endsExpression(t token) bool {
+	switch t.kind {
+	case tokIdent:
+		return !regexpAfter[t.text]
+	case tokNumber, tokString, tokRegexp:
+		return true
+	case tokTemplate:
+		return !t.opens()
+	case tokPunct:
+		return t.closes() || t.text == "++" || t.text == "--"
+	}
+	return false
+}
+
+// startsStatement reports whether t, after a line break, starts a new
+// statement rather than continuing the expression before it
+func startsStatement(t token) bool {
+	switch t.kind {
+	case tokIdent:
+		return t.text != "in" && t.text != "instanceof" && t.text != "of"
+	case tokNumber, tokString, tokRegexp:
+		return true
+	case tokPunct:
+		switch t.text {
+		case "{", "!", "~", "++", "--":
+			return true
+		}
+	}
+	return false
+}
+
+// exportName returns the name an identifier or string literal token spells
+func exportName(t token) string {
+	if t.kind == tokString {
+		return unquote(t.text)
+	}
+	return t.text
+}
+
+// unquote returns the value of a string literal token
+func unquote(text string) string {
+	inner := text[1 : len(text)-1]
+	if !strings.Contains(inner, `\`) {
+		return inner
+	}
+	if text[0] == '\'' {
+		inner = strings.ReplaceAll(strings.ReplaceAll(inner, `\'`, `'`), `"`, `\"`)
+	}
+	if s, err := strconv.Unquote(`"` + inner + `"`); err == nil {
+		return s
+	}
+	return inner
+}
+
+// quote returns s as a JavaScript string literal
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
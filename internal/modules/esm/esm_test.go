@@ -0,0 +1,211 @@
+package esm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsModule(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{`import fs from "gode:fs";`, true},
+		{`export const x = 1;`, true},
+		{`console.log(import.meta.url);`, true},
+		{`const m = await import("./mod.js");`, false},
+		{`module.exports = { import: 1, export: 2 };`, false},
+		{`// import x from "y"` + "\nconst s = 'export default 1';", false},
+		{"const t = `import ${x} from y`;", false},
+		{`obj.import("x"); obj.export = 1;`, false},
+	}
+	for _, tt := range tests {
+		if got := IsModule(tt.source); got != tt.want {
+			t.Errorf("IsModule(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestTransformImports(t *testing.T) {
+	source := `import def from "./a.js";
+import * as ns from "./b.js";
+import { x, y as z, "with-dash" as dash } from "./a.js";
+import other, { default as again } from "gode:fs";
+import "./side.js";
+import data from "./data.json" with { type: "json" };
+console.log(def, ns, x, z);
+`
+	m, err := Transform(source)
+	if err != nil {
+		t.Fatalf("Transform() failed: %v", err)
+	}
+
+	wantRequests := []string{"./a.js", "./b.js", "gode:fs", "./side.js", "./data.json"}
+	if !reflect.DeepEqual(m.Requests, wantRequests) {
+		t.Errorf("Requests = %q, want %q", m.Requests, wantRequests)
+	}
+	for _, want := range []string{
+		"let def, ns, x, z, dash, other, again, data;",
+		`def = __gode_module.get(0, "default");`,
+		"ns = __gode_module.namespace(1);",
+		`z = __gode_module.get(0, "y");`,
+		`dash = __gode_module.get(0, "with-dash");`,
+		`again = __gode_module.get(2, "default");`,
+		"yield;",
+	} {
+		if !strings.Contains(m.Code, want) {
+			t.Errorf("Expected code to contain %q:\n%s", want, m.Code)
+		}
+	}
+	if strings.Contains(m.Code, "import ") || strings.Contains(m.Code, "from ") {
+		t.Errorf("Expected import statements to be removed:\n%s", m.Code)
+	}
+	// The body keeps its line numbers
+	if lines := strings.Split(m.Code, "\n"); !strings.HasPrefix(lines[6], "console.log(def") {
+		t.Errorf("Line 7 = %q", lines[6])
+	}
+}
+
+func TestTransformExports(t *testing.T) {
+	source := `export const a = 1, { b, c: [d, ...e] } = obj, f = (x, y) => x + y;
+export let g
+export function h() {}
+export async function i() {}
+export class J {}
+export { a as k, h as default };
+export { l, m as n } from "./other.js";
+export * from "./star.js";
+export * as o from "./other.js";
+`
+	m, err := Transform(source)
+	if err != nil {
+		t.Fatalf("Transform() failed: %v", err)
+	}
+
+	want := []string{"a", "b", "d", "e", "f", "g", "h", "i", "J", "k", "default", "l", "n", "o"}
+	if !reflect.DeepEqual(m.Exports, want) {
+		t.Errorf("Exports = %q, want %q", m.Exports, want)
+	}
+	for _, part := range []string{
+		`"k": () => a`,
+		`"default": () => h`,
+		`"n": () => __gode_module.get(0, "m")`,
+		`"o": () => __gode_module.namespace(0)`,
+		"}, [1]);",
+		"const a = 1,",
+		"\nfunction h() {}",
+		"\nclass J {}",
+	} {
+		if !strings.Contains(m.Code, part) {
+			t.Errorf("Expected code to contain %q:\n%s", part, m.Code)
+		}
+	}
+	if strings.Contains(m.Code, "export") {
+		t.Errorf("Expected export keywords to be removed:\n%s", m.Code)
+	}
+}
+
+func TestTransformDefaultExports(t *testing.T) {
+	tests := []struct {
+		source string
+		code   string
+		getter string
+	}{
+		{"export default 42;", "const __gode_default = 42;", "() => __gode_default"},
+		{"export default function () {}", "function __gode_default () {}", "() => __gode_default"},
+		{"export default function* gen() {}", "function* gen() {}", "() => gen"},
+		{"export default async function load() {}", "async function load() {}", "() => load"},
+		{"export default class extends Base {}", "class __gode_default extends Base {}", "() => __gode_default"},
+		{"export default class Widget {}", "class Widget {}", "() => Widget"},
+	}
+	for _, tt := range tests {
+		m, err := Transform(tt.source)
+		if err != nil {
+			t.Errorf("Transform(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if !strings.Contains(m.Code, tt.code) || !strings.Contains(m.Code, `"default": `+tt.getter) {
+			t.Errorf("Transform(%q) =\n%s", tt.source, m.Code)
+		}
+	}
+}
+
+func TestTransformMetaAndDynamicImport(t *testing.T) {
+	m, err := Transform("export const url = import.meta.url;\nconst lazy = () => import(`./${name}.js`);")
+	if err != nil {
+		t.Fatalf("Transform() failed: %v", err)
+	}
+	if !strings.Contains(m.Code, "const url = __gode_module.meta.url;") ||
+		!strings.Contains(m.Code, "__gode_module.import(`./${name}.js`)") {
+		t.Errorf("Unexpected code:\n%s", m.Code)
+	}
+	if len(m.Requests) != 0 {
+		t.Errorf("Dynamic imports should not be requests, got %q", m.Requests)
+	}
+}
+
+func TestTopLevelAwait(t *testing.T) {
+	tests := []struct {
+		source string
+		async  bool
+	}{
+		{"export const data = await load();", true},
+		{"if (ready) { await start(); }", true},
+		{"for await (const chunk of stream) {}", true},
+		{"async function f() { await g(); }\nexport {f};", false},
+		{"export const f = async () => { await g(); };", false},
+		{"export const f = async x => await g(x);", false},
+		{"const f = async x => await g(x)\nawait f(1)\nexport {f}", true},
+		{"class A { async m() { await this.n(); } }\nexport default A;", false},
+		{"export const o = { async m() { return await 1; } };", false},
+		{"const re = /await/; export {re};", false},
+		{"export const s = `${await value}`;", true},
+	}
+	for _, tt := range tests {
+		m, err := Transform(tt.source)
+		if err != nil {
+			t.Errorf("Transform(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if m.Async != tt.async {
+			t.Errorf("Transform(%q).Async = %v, want %v", tt.source, m.Async, tt.async)
+		}
+		if tt.async && !strings.HasPrefix(m.Code, "(async function") {
+			t.Errorf("Expected an async wrapper for %q", tt.source)
+		}
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	for _, source := range []string{
+		"import { a from './a.js';",
+		"import x './a.js';",
+		"export function () {}",
+		"export * from;",
+		"const s = 'unterminated\nexport {s};",
+	} {
+		_, err := Transform(source)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || syntaxErr.Line == 0 {
+			t.Errorf("Transform(%q) = %v, want a SyntaxError", source, err)
+		}
+	}
+}
+
+func TestTokenizeRegexpAndDivision(t *testing.T) {
+	tokens, err := tokenize("a = b / c / d; r = /[/]}`/g.test(s); x = (1) / 2")
+	if err != nil {
+		t.Fatalf("tokenize() failed: %v", err)
+	}
+	var regexps []string
+	for _, tok := range tokens {
+		if tok.kind == tokRegexp {
+			regexps = append(regexps, tok.text)
+		}
+	}
+	if !reflect.DeepEqual(regexps, []string{"/[/]}`/g"}) {
+		t.Errorf("Regular expressions = %q", regexps)
+	}
+}
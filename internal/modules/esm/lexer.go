@@ -0,0 +1,299 @@
+package esm
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota // identifiers and keywords
+	tokPunct
+	tokString
+	tokNumber
+	tokRegexp
+	// tokTemplate is a template literal, or the part of one up to a ${
+	// or from the } that ends a substitution
+	tokTemplate
+	// tokEOF is returned when reading past the last token
+	tokEOF
+)
+
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+	newline    bool // a line break comes before the token
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+// opens reports whether t opens a bracket, or a substitution in a template
+func (t token) opens() bool {
+	if t.kind == tokTemplate {
+		return strings.HasSuffix(t.text, "${")
+	}
+	return t.kind == tokPunct && (t.text == "(" || t.text == "[" || t.text == "{")
+}
+
+// closes reports whether t closes a bracket or a template substitution
+func (t token) closes() bool {
+	if t.kind == tokTemplate {
+		return strings.HasPrefix(t.text, "}")
+	}
+	return t.kind == tokPunct && (t.text == ")" || t.text == "]" || t.text == "}")
+}
+
+// SyntaxError reports source that could not be tokenized, or an import or
+// export statement that could not be parsed
+type SyntaxError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("SyntaxError: %s (%d:%d)", e.Msg, e.Line, e.Column)
+}
+
+func syntaxError(source string, offset int, format string, args ...interface{}) *SyntaxError {
+	before := source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return &SyntaxError{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// punctuators, longest first so the longest match wins
+var punctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "**", "<<", ">>",
+}
+
+// regexpAfter are the keywords after which a / starts a regular expression
+var regexpAfter = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true, "extends": true,
+}
+
+// tokenize splits JavaScript source into tokens, dropping whitespace and
+// comments. It knows just enough of the grammar to tell regular expressions
+// from division and to follow template literals.
+func tokenize(source string) ([]token, error) {
+	var (
+		tokens    []token
+		templates []bool // for each open {, whether it began a template substitution
+		newline   bool
+	)
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == '\n' || c == '\r':
+			newline = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			i++
+			continue
+		case c == '/' && i+1 < len(source) && source[i+1] == '/':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(source) && source[i+1] == '*':
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				return nil, syntaxError(source, i, "unterminated comment")
+			}
+			if strings.ContainsAny(source[i:i+2+end], "\n\r") {
+				newline = true
+			}
+			i += end + 4
+			continue
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(source[i:])
+			if unicode.IsSpace(r) || r == '\uFEFF' {
+				if r == '\u2028' || r == '\u2029' {
+					newline = true
+				}
+				i += size
+				continue
+			}
+		}
+
+		start := i
+		var kind tokenKind
+		switch {
+		case c == '"' || c == '\'':
+			end, ok := scanString(source, i)
+			if !ok {
+				return nil, syntaxError(source, i, "unterminated string")
+			}
+			kind, i = tokString, end
+		case c == '`':
+			end, ok := scanTemplate(source, i+1)
+			if !ok {
+				return nil, syntaxError(source, i, "unterminated template literal")
+			}
+			kind, i = tokTemplate, end
+			if strings.HasSuffix(source[start:i], "${") {
+				templates = append(templates, true)
+			}
+		case c == '}' && len(templates) > 0 && templates[len(templates)-1]:
+			templates = templates[:len(templates)-1]
+			end, ok := scanTemplate(source, i+1)
+			if !ok {
+				return nil, syntaxError(source, i, "unterminated template literal")
+			}
+			kind, i = tokTemplate, end
+			if strings.HasSuffix(source[start:i], "${") {
+				templates = append(templates, true)
+			}
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(source) && source[i+1] >= '0' && source[i+1] <= '9':
+			kind, i = tokNumber, scanNumber(source, i)
+		case c == '/' && regexpAllowed(tokens):
+			end, ok := scanRegexp(source, i)
+			if !ok {
+				return nil, syntaxError(source, i, "unterminated regular expression")
+			}
+			kind, i = tokRegexp, end
+		case isIdentStart(source, i) || c == '#':
+			i++
+			for i < len(source) && isIdentPart(source, i) {
+				_, size := utf8.DecodeRuneInString(source[i:])
+				i += size
+			}
+			kind = tokIdent
+		default:
+			kind = tokPunct
+			i++
+			for _, p := range punctuators {
+				if strings.HasPrefix(source[start:], p) {
+					i = start + len(p)
+					break
+				}
+			}
+			switch source[start:i] {
+			case "{":
+				templates = append(templates, false)
+			case "}":
+				if len(templates) > 0 {
+					templates = templates[:len(templates)-1]
+				}
+			}
+		}
+
+		tokens = append(tokens, token{kind: kind, text: source[start:i], start: start, end: i, newline: newline})
+		newline = false
+	}
+	return tokens, nil
+}
+
+// regexpAllowed reports whether a / after tokens starts a regular
+// expression rather than a division
+func regexpAllowed(tokens []token) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	prev := tokens[len(tokens)-1]
+	switch prev.kind {
+	case tokIdent:
+		return regexpAfter[prev.text]
+	case tokPunct:
+		return !prev.closes() && prev.text != "++" && prev.text != "--"
+	case tokTemplate:
+		return prev.opens()
+	}
+	return false
+}
+
+func scanString(source string, i int) (int, bool) {
+	quote := source[i]
+	for i++; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, true
+		case '\n':
+			return i, false
+		}
+	}
+	return i, false
+}
+
+// scanTemplate scans template characters from i up to and including the
+// closing backtick or the next ${
+func scanTemplate(source string, i int) (int, bool) {
+	for ; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, true
+		case '$':
+			if i+1 < len(source) && source[i+1] == '{' {
+				return i + 2, true
+			}
+		}
+	}
+	return i, false
+}
+
+func scanNumber(source string, i int) int {
+	hex := strings.HasPrefix(strings.ToLower(source[i:]), "0x")
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '.':
+			i++
+		case (c == '+' || c == '-') && (source[i-1] == 'e' || source[i-1] == 'E') && !hex:
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func scanRegexp(source string, i int) (int, bool) {
+	inClass := false
+	for i++; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return i, false
+		case '/':
+			if !inClass {
+				i++
+				for i < len(source) && isIdentPart(source, i) {
+					i++
+				}
+				return i, true
+			}
+		}
+	}
+	return i, false
+}
+
+func isIdentStart(source string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(source[i:])
+	return r == '$' || r == '_' || r == '\\' || unicode.IsLetter(r)
+}
+
+func isIdentPart(source string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(source[i:])
+	return r == '$' || r == '_' || r == '\\' || r == '\u200C' || r == '\u200D' ||
+		unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r)
+}
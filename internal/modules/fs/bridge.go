@@ -0,0 +1,443 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/abort"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Bridge provides JavaScript bindings for the gode:fs module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new fs bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("writeFileAtomic", b.writeFileAtomic)
+	exports.Set("mkdtemp", b.mkdtemp)
+	exports.Set("open", b.open)
+	exports.Set("cp", b.cp)
+	exports.Set("rm", b.rm)
+	return exports
+}
+
+// writeFileAtomic implements fs.writeFileAtomic(path, data, {mode}) returning
+// a Promise that resolves once the new contents are durably in place
+func (b *Bridge) writeFileAtomic(call goja.FunctionCall) goja.Value {
+	path := b.path(call.Argument(0), "write")
+	data := b.bytes(call.Argument(1))
+	mode := b.mode(call.Argument(2), 0644)
+
+	return b.async(func() (interface{}, error) {
+		return nil, WriteFileAtomic(path, data, mode)
+	})
+}
+
+// mkdtemp implements fs.mkdtemp(prefix) returning a Promise of the new path
+func (b *Bridge) mkdtemp(call goja.FunctionCall) goja.Value {
+	prefix := call.Argument(0).String()
+	b.path(b.vm.ToValue(filepath.Dir(prefix)), "write")
+
+	return b.async(func() (interface{}, error) {
+		return Mkdtemp(prefix)
+	})
+}
+
+// open implements fs.open(path, flags = "r", mode = 0o666) returning a Promise
+// of a FileHandle. Flags containing "x" fail with EEXIST if the file exists,
+// which makes open usable for lock files.
+func (b *Bridge) open(call goja.FunctionCall) goja.Value {
+	flagArg := "r"
+	if v := call.Argument(1); !goja.IsUndefined(v) && !goja.IsNull(v) {
+		flagArg = v.String()
+	}
+	flags, err := ParseFlags(flagArg)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+
+	kind := "read"
+	if Writes(flags) {
+		kind = "write"
+	}
+	path := b.path(call.Argument(0), kind)
+	mode := os.FileMode(0666)
+	if v := call.Argument(2); !goja.IsUndefined(v) && !goja.IsNull(v) {
+		mode = os.FileMode(v.ToInteger())
+	}
+
+	p, resolver := promise.New(b.vm, b.runtime)
+	go func() {
+		f, err := os.OpenFile(path, flags, mode)
+		resolver.SettleWith(func() (interface{}, error) {
+			if err != nil {
+				return nil, err
+			}
+			return b.fileHandle(f), nil
+		})
+	}()
+	return p
+}
+
+// cp implements fs.cp(src, dest, options) returning a Promise. Options are
+// {recursive, filter, preserveTimestamps, force = true, errorOnExist,
+// concurrency, onProgress, signal}. filter(src, dest) may return a Promise.
+// onProgress receives {totalFiles, totalBytes, files, bytes, path}; calls
+// are coalesced when the copy outpaces the event loop.
+func (b *Bridge) cp(call goja.FunctionCall) goja.Value {
+	src := b.path(call.Argument(0), "read")
+	dst := b.path(call.Argument(1), "write")
+
+	opts := CopyOptions{}
+	var onProgress goja.Callable
+	if obj, ok := call.Argument(2).(*goja.Object); ok {
+		opts.Recursive = b.option(obj, "recursive").ToBoolean()
+		opts.PreserveTimestamps = b.option(obj, "preserveTimestamps").ToBoolean()
+		opts.Workers = int(b.option(obj, "concurrency").ToInteger())
+
+		// Like Node, force=false silently skips existing files unless
+		// errorOnExist asks for an error
+		force := b.option(obj, "force")
+		errorOnExist := b.option(obj, "errorOnExist").ToBoolean()
+		if !goja.IsUndefined(force) && !force.ToBoolean() {
+			if errorOnExist {
+				opts.ErrorOnExist = true
+			} else {
+				opts.Filter = skipExisting
+			}
+		}
+
+		if filter, ok := goja.AssertFunction(b.option(obj, "filter")); ok {
+			opts.Filter = chainFilters(opts.Filter, b.jsFilter(filter))
+		}
+		onProgress, _ = goja.AssertFunction(b.option(obj, "onProgress"))
+	}
+	opts.Progress = b.progress(onProgress)
+
+	return b.cancelable(call.Argument(2), func(cancel <-chan struct{}) error {
+		opts.Cancel = cancel
+		return Copy(src, dst, opts)
+	})
+}
+
+// rm implements fs.rm(path, {recursive, force, concurrency, onProgress,
+// signal}) returning a Promise
+func (b *Bridge) rm(call goja.FunctionCall) goja.Value {
+	path := b.path(call.Argument(0), "write")
+
+	opts := RemoveOptions{}
+	var onProgress goja.Callable
+	if obj, ok := call.Argument(1).(*goja.Object); ok {
+		opts.Recursive = b.option(obj, "recursive").ToBoolean()
+		opts.Force = b.option(obj, "force").ToBoolean()
+		opts.Workers = int(b.option(obj, "concurrency").ToInteger())
+		onProgress, _ = goja.AssertFunction(b.option(obj, "onProgress"))
+	}
+	opts.Progress = b.progress(onProgress)
+
+	return b.cancelable(call.Argument(1), func(cancel <-chan struct{}) error {
+		opts.Cancel = cancel
+		return Remove(path, opts)
+	})
+}
+
+// cancelable runs fn off the JS thread like async, with a channel that is
+// closed when the signal option in options aborts. The Promise then
+// rejects with the signal's reason.
+func (b *Bridge) cancelable(options goja.Value, fn func(cancel <-chan struct{}) error) goja.Value {
+	var signal *abort.Signal
+	if obj, ok := options.(*goja.Object); ok {
+		var err error
+		if signal, err = abort.From(b.vm, b.option(obj, "signal")); err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+	}
+
+	p, resolver := promise.New(b.vm, b.runtime)
+	if err := signal.Err(); err != nil {
+		resolver.Reject(err)
+		return p
+	}
+	ctx, stop := signal.Context(context.Background())
+	go func() {
+		err := fn(ctx.Done())
+		resolver.SettleWith(func() (interface{}, error) {
+			stop()
+			if errors.Is(err, ErrCanceled) {
+				if aborted := signal.Err(); aborted != nil {
+					return nil, aborted
+				}
+			}
+			return nil, err
+		})
+	}()
+	return p
+}
+
+// skipExisting is the cp filter for force=false
+func skipExisting(src, dst string) (bool, error) {
+	info, err := os.Lstat(dst)
+	return err != nil || info.IsDir(), nil
+}
+
+func chainFilters(first, second func(src, dst string) (bool, error)) func(src, dst string) (bool, error) {
+	if first == nil {
+		return second
+	}
+	return func(src, dst string) (bool, error) {
+		ok, err := first(src, dst)
+		if err != nil || !ok {
+			return ok, err
+		}
+		return second(src, dst)
+	}
+}
+
+// jsFilter calls a JavaScript filter on the JS thread from a copy goroutine,
+// waiting for a returned Promise to settle
+func (b *Bridge) jsFilter(fn goja.Callable) func(src, dst string) (bool, error) {
+	type outcome struct {
+		keep bool
+		err  error
+	}
+
+	return func(src, dst string) (bool, error) {
+		result := make(chan outcome, 1)
+		b.runtime.QueueJSOperation(func() {
+			value, err := fn(goja.Undefined(), b.vm.ToValue(src), b.vm.ToValue(dst))
+			if err != nil {
+				result <- outcome{err: err}
+				return
+			}
+			b.settle(value, func(v goja.Value) {
+				result <- outcome{keep: v.ToBoolean()}
+			}, func(reason goja.Value) {
+				result <- outcome{err: fmt.Errorf("filter rejected: %s", reason.String())}
+			})
+		})
+		r := <-result
+		return r.keep, r.err
+	}
+}
+
+// progress returns a Progress callback that forwards to fn on the JS thread.
+// At most one delivery is queued at a time and it reports the latest state,
+// so a fast copy cannot flood the event loop.
+func (b *Bridge) progress(fn goja.Callable) func(Progress) {
+	if fn == nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var latest Progress
+	queued := false
+
+	return func(p Progress) {
+		mu.Lock()
+		latest = p
+		if queued {
+			mu.Unlock()
+			return
+		}
+		queued = true
+		mu.Unlock()
+
+		b.runtime.QueueJSOperation(func() {
+			mu.Lock()
+			p := latest
+			queued = false
+			mu.Unlock()
+
+			event := b.vm.NewObject()
+			event.Set("totalFiles", p.TotalFiles)
+			event.Set("totalBytes", p.TotalBytes)
+			event.Set("files", p.Files)
+			event.Set("bytes", p.Bytes)
+			event.Set("path", p.Path)
+			fn(goja.Undefined(), event)
+		})
+	}
+}
+
+// settle calls onFulfilled or onRejected once value settles. Plain values
+// fulfil immediately.
+func (b *Bridge) settle(value goja.Value, onFulfilled, onRejected func(goja.Value)) {
+	if obj, ok := value.(*goja.Object); ok {
+		if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+			then(obj, b.vm.ToValue(onFulfilled), b.vm.ToValue(onRejected))
+			return
+		}
+	}
+	onFulfilled(value)
+}
+
+// option returns obj[name], or undefined when obj lacks it
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	value := obj.Get(name)
+	if value == nil {
+		return goja.Undefined()
+	}
+	return value
+}
+
+// fileHandle wraps an open file in the FileHandle object returned by open
+func (b *Bridge) fileHandle(f *os.File) *goja.Object {
+	handle := b.vm.NewObject()
+	handle.Set("fd", int64(f.Fd()))
+	handle.Set("path", f.Name())
+
+	// read(length = 64 KiB, position) resolves to {bytesRead, buffer}; without a
+	// position it reads from the current offset
+	handle.Set("read", func(call goja.FunctionCall) goja.Value {
+		length := 64 * 1024
+		if v := call.Argument(0); !goja.IsUndefined(v) && !goja.IsNull(v) {
+			length = int(v.ToInteger())
+		}
+		position := b.position(call.Argument(1))
+
+		p, resolver := promise.New(b.vm, b.runtime)
+		go func() {
+			buf := make([]byte, length)
+			var n int
+			var err error
+			if position >= 0 {
+				n, err = f.ReadAt(buf, position)
+			} else {
+				n, err = f.Read(buf)
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			resolver.SettleWith(func() (interface{}, error) {
+				if err != nil {
+					return nil, err
+				}
+				result := b.vm.NewObject()
+				result.Set("bytesRead", n)
+				result.Set("buffer", b.uint8Array(buf[:n]))
+				return result, nil
+			})
+		}()
+		return p
+	})
+
+	// write(data, position) resolves to {bytesWritten}
+	handle.Set("write", func(call goja.FunctionCall) goja.Value {
+		data := b.bytes(call.Argument(0))
+		position := b.position(call.Argument(1))
+
+		return b.async(func() (interface{}, error) {
+			var n int
+			var err error
+			if position >= 0 {
+				n, err = f.WriteAt(data, position)
+			} else {
+				n, err = f.Write(data)
+			}
+			return map[string]interface{}{"bytesWritten": n}, err
+		})
+	})
+
+	handle.Set("sync", func() goja.Value {
+		return b.async(func() (interface{}, error) {
+			return nil, f.Sync()
+		})
+	})
+
+	handle.Set("close", func() goja.Value {
+		return b.async(func() (interface{}, error) {
+			return nil, f.Close()
+		})
+	})
+
+	return handle
+}
+
+// async runs fn off the JS thread and settles the returned Promise back on it
+func (b *Bridge) async(fn func() (interface{}, error)) goja.Value {
+	return promise.Run(b.vm, b.runtime, fn)
+}
+
+// path checks the runtime's read or write permission for a path argument
+func (b *Bridge) path(value goja.Value, kind string) string {
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		panic(b.vm.NewTypeError("path must be a string"))
+	}
+	path := value.String()
+
+	if checker, ok := b.runtime.(permissions.Enforcer); ok {
+		if err := checker.CheckPermission(kind, path); err != nil {
+			panic(jserror.New(b.vm, err))
+		}
+	}
+	return path
+}
+
+// bytes accepts a string, ArrayBuffer, typed array or Buffer
+func (b *Bridge) bytes(value goja.Value) []byte {
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		panic(b.vm.NewTypeError("data must be a string, Buffer or Uint8Array"))
+	}
+
+	switch v := value.Export().(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return append([]byte(nil), v...)
+	case goja.ArrayBuffer:
+		return append([]byte(nil), v.Bytes()...)
+	}
+	return []byte(value.String())
+}
+
+func (b *Bridge) uint8Array(data []byte) goja.Value {
+	array, err := b.vm.New(b.vm.Get("Uint8Array"), b.vm.ToValue(b.vm.NewArrayBuffer(data)))
+	if err != nil {
+		panic(err)
+	}
+	return array
+}
+
+// mode reads {mode} from an options object
+func (b *Bridge) mode(value goja.Value, fallback os.FileMode) os.FileMode {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return fallback
+	}
+	v := obj.Get("mode")
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return fallback
+	}
+	return os.FileMode(v.ToInteger())
+}
+
+// position returns -1 when no explicit file position was given
+func (b *Bridge) position(value goja.Value) int64 {
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		return -1
+	}
+	if p := value.ToInteger(); p >= 0 {
+		return p
+	}
+	panic(b.vm.NewTypeError(fmt.Sprintf("invalid position %s", value.String())))
+}
@@ -0,0 +1,370 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Progress reports how far a Copy or Remove has got. Totals are known once
+// the tree has been scanned, before any file is copied or removed.
+type Progress struct {
+	TotalFiles int
+	TotalBytes int64
+	Files      int
+	Bytes      int64
+	Path       string // last file handled
+}
+
+// CopyOptions configures Copy
+type CopyOptions struct {
+	Recursive          bool
+	PreserveTimestamps bool
+	ErrorOnExist       bool // fail instead of overwriting existing files
+
+	// Filter decides whether src is copied; a skipped directory skips its
+	// whole subtree
+	Filter func(src, dst string) (bool, error)
+
+	// Progress is called after each file, never concurrently
+	Progress func(Progress)
+
+	// Workers is how many files are copied in parallel. Zero picks a
+	// default based on the number of CPUs.
+	Workers int
+
+	// Cancel, once closed, stops the copy before its next file, failing
+	// with ErrCanceled
+	Cancel <-chan struct{}
+}
+
+// RemoveOptions configures Remove
+type RemoveOptions struct {
+	Recursive bool
+	Force     bool // ignore a missing path
+	Progress  func(Progress)
+	Workers   int
+	Cancel    <-chan struct{} // as for Copy
+}
+
+// ErrCanceled is returned by a Copy or Remove stopped through Cancel. The
+// files already handled are left as they are.
+var ErrCanceled = errors.New("operation canceled")
+
+// canceled reports whether cancel has been closed
+func canceled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+type copyEntry struct {
+	src, dst string
+	info     os.FileInfo
+}
+
+// Copy copies src to dst like Node's fs.cp. Directories require Recursive;
+// their structure is created first and files are then copied by a pool of
+// workers. Symlinks are recreated rather than followed.
+func Copy(src, dst string, opts CopyOptions) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && !opts.Recursive {
+		return fmt.Errorf("%s is a directory (not copied, recursive is not set)", src)
+	}
+	if info.IsDir() && isWithin(dst, src) {
+		return fmt.Errorf("cannot copy %s to a subdirectory of itself, %s", src, dst)
+	}
+
+	var dirs, files []copyEntry
+	var total int64
+	err = walkCopy(src, dst, info, opts.Filter, func(e copyEntry) {
+		if e.info.IsDir() {
+			dirs = append(dirs, e)
+			return
+		}
+		files = append(files, e)
+		if e.info.Mode().IsRegular() {
+			total += e.info.Size()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range dirs {
+		if err := os.MkdirAll(d.dst, d.info.Mode().Perm()|0700); err != nil {
+			return err
+		}
+	}
+
+	tracker := newProgress(len(files), total, opts.Progress)
+	err = parallel(files, opts.Workers, func(e copyEntry) error {
+		if canceled(opts.Cancel) {
+			return ErrCanceled
+		}
+		if err := copyEntryTo(e, opts); err != nil {
+			return err
+		}
+		tracker.done(e.dst, e.info.Size())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Directory permissions and times last, since writing into them above
+	// would have changed their mtime
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if err := os.Chmod(d.dst, d.info.Mode().Perm()); err != nil {
+			return err
+		}
+		if opts.PreserveTimestamps {
+			if err := os.Chtimes(d.dst, d.info.ModTime(), d.info.ModTime()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkCopy visits src and, for directories, everything below it in
+// lexical order
+func walkCopy(src, dst string, info os.FileInfo, filter func(src, dst string) (bool, error), visit func(copyEntry)) error {
+	if filter != nil {
+		ok, err := filter(src, dst)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	visit(copyEntry{src: src, dst: dst, info: info})
+	if !info.IsDir() {
+		return nil
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		childInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if err := walkCopy(filepath.Join(src, name), filepath.Join(dst, name), childInfo, filter, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyEntryTo(e copyEntry, opts CopyOptions) error {
+	if _, err := os.Lstat(e.dst); err == nil {
+		if opts.ErrorOnExist {
+			return fmt.Errorf("%s already exists: %w", e.dst, os.ErrExist)
+		}
+		if e.info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(e.dst); err != nil {
+				return err
+			}
+		}
+	}
+
+	if e.info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(e.src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, e.dst)
+	}
+	if !e.info.Mode().IsRegular() {
+		return fmt.Errorf("cannot copy %s: not a regular file, directory or symlink", e.src)
+	}
+
+	in, err := os.Open(e.src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(e.dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, e.info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if opts.PreserveTimestamps {
+		return os.Chtimes(e.dst, e.info.ModTime(), e.info.ModTime())
+	}
+	return nil
+}
+
+// Remove deletes path like Node's fs.rm. Directories require Recursive;
+// their files are removed in parallel and the directories deepest first.
+func Remove(path string, opts RemoveOptions) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if opts.Force && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if !info.IsDir() {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		newProgress(1, info.Size(), opts.Progress).done(path, info.Size())
+		return nil
+	}
+	if !opts.Recursive {
+		return fmt.Errorf("%s is a directory (not removed, recursive is not set)", path)
+	}
+
+	var dirs []string
+	var files []copyEntry
+	var total int64
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		files = append(files, copyEntry{src: p, info: info})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	tracker := newProgress(len(files), total, opts.Progress)
+	err = parallel(files, opts.Workers, func(e copyEntry) error {
+		if canceled(opts.Cancel) {
+			return ErrCanceled
+		}
+		if err := os.Remove(e.src); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		tracker.done(e.src, e.info.Size())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deepest directories first so each is empty when removed
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], string(filepath.Separator)) > strings.Count(dirs[j], string(filepath.Separator))
+	})
+	for _, dir := range dirs {
+		if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// parallel runs fn for each entry on up to workers goroutines and returns
+// the first error. Entries not yet started when an error occurs are skipped.
+func parallel(entries []copyEntry, workers int, fn func(copyEntry) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+		if workers < 4 {
+			workers = 4
+		}
+	}
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+
+	jobs := make(chan copyEntry)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	failed := make(chan struct{})
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				if err := fn(e); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, e := range entries {
+		select {
+		case jobs <- e:
+		case <-failed:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
+}
+
+// progressTracker serializes progress callbacks from parallel workers
+type progressTracker struct {
+	mu       sync.Mutex
+	state    Progress
+	callback func(Progress)
+}
+
+func newProgress(totalFiles int, totalBytes int64, callback func(Progress)) *progressTracker {
+	return &progressTracker{
+		state:    Progress{TotalFiles: totalFiles, TotalBytes: totalBytes},
+		callback: callback,
+	}
+}
+
+func (t *progressTracker) done(path string, size int64) {
+	if t.callback == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state.Files++
+	t.state.Bytes += size
+	t.state.Path = path
+	t.callback(t.state)
+}
+
+// isWithin reports whether path is dir or lies beneath it
+func isWithin(path, dir string) bool {
+	absPath, err1 := filepath.Abs(path)
+	absDir, err2 := filepath.Abs(dir)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return absPath == absDir || strings.HasPrefix(absPath, absDir+string(filepath.Separator))
+}
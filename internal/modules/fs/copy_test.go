@@ -0,0 +1,148 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyRecursive(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	dst := filepath.Join(root, "dst")
+	writeTree(t, src, map[string]string{
+		"a.txt":          "a",
+		"sub/b.txt":      "bb",
+		"sub/deep/c.txt": "ccc",
+		"node_modules/x": "skip",
+		"sub/skip.log":   "skip",
+	})
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(filepath.Join(src, "a.txt"), old, old)
+
+	var last Progress
+	calls := 0
+	err := Copy(src, dst, CopyOptions{
+		Recursive:          true,
+		PreserveTimestamps: true,
+		Workers:            3,
+		Filter: func(src, dst string) (bool, error) {
+			return filepath.Base(src) != "node_modules" && !strings.HasSuffix(src, ".log"), nil
+		},
+		Progress: func(p Progress) {
+			calls++
+			last = p
+		},
+	})
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"a.txt": "a", "sub/b.txt": "bb", "sub/deep/c.txt": "ccc"} {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || string(got) != want {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, want, got, err)
+		}
+	}
+	for _, name := range []string{"node_modules", "sub/skip.log"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected filtered %s not to be copied", name)
+		}
+	}
+
+	if calls != 3 || last.Files != 3 || last.TotalFiles != 3 || last.Bytes != 6 || last.TotalBytes != 6 {
+		t.Errorf("Unexpected progress after %d calls: %+v", calls, last)
+	}
+
+	info, _ := os.Stat(filepath.Join(dst, "a.txt"))
+	if !info.ModTime().Equal(old) {
+		t.Errorf("Expected preserved mtime %v, got %v", old, info.ModTime())
+	}
+}
+
+func TestCopyErrors(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	writeTree(t, src, map[string]string{"a.txt": "a"})
+
+	if err := Copy(src, filepath.Join(root, "dst"), CopyOptions{}); err == nil {
+		t.Error("Expected an error copying a directory without recursive")
+	}
+	if err := Copy(src, filepath.Join(src, "inner"), CopyOptions{Recursive: true}); err == nil {
+		t.Error("Expected an error copying a directory into itself")
+	}
+
+	dst := filepath.Join(root, "b.txt")
+	writeTree(t, root, map[string]string{"b.txt": "b"})
+	err := Copy(filepath.Join(src, "a.txt"), dst, CopyOptions{ErrorOnExist: true})
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected ErrExist, got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "tree")
+	writeTree(t, dir, map[string]string{"a": "1", "b/c": "22", "b/d/e": "333"})
+
+	if err := Remove(dir, RemoveOptions{}); err == nil {
+		t.Error("Expected an error removing a directory without recursive")
+	}
+
+	var last Progress
+	if err := Remove(dir, RemoveOptions{Recursive: true, Workers: 2, Progress: func(p Progress) { last = p }}); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Expected the tree to be removed")
+	}
+	if last.Files != 3 || last.Bytes != 6 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+
+	if err := Remove(dir, RemoveOptions{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+	if err := Remove(dir, RemoveOptions{Force: true}); err != nil {
+		t.Errorf("Expected force to ignore a missing path, got %v", err)
+	}
+}
+
+func TestCopyCanceled(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	writeTree(t, src, map[string]string{"a": "1", "b/c": "22"})
+
+	cancel := make(chan struct{})
+	close(cancel)
+	err := Copy(src, filepath.Join(root, "dst"), CopyOptions{Recursive: true, Cancel: cancel})
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "dst", "a")); !os.IsNotExist(err) {
+		t.Error("Expected no file to be copied after canceling")
+	}
+
+	if err := Remove(src, RemoveOptions{Recursive: true, Cancel: cancel}); !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled from Remove, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "a")); err != nil {
+		t.Errorf("Expected files to be kept after canceling: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+// Package fs implements the gode:fs module
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WriteFileAtomic replaces path with data so that readers see either the old
+// contents or the new ones, never a partial write. The data goes to a
+// temporary file in the same directory, is fsynced and then renamed over
+// path; the directory is synced afterwards so the rename survives a crash.
+// perm is used when path does not exist yet, otherwise its mode is kept.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory entry change to disk. Windows cannot open
+// directories for syncing, and renames there are already durable.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Mkdtemp creates a unique directory by appending six random characters to
+// prefix, like Node's fs.mkdtemp. The prefix may include a directory; an
+// empty directory part means the current one.
+func Mkdtemp(prefix string) (string, error) {
+	dir, base := filepath.Split(prefix)
+	if dir == "" {
+		dir = "."
+	}
+	path, err := os.MkdirTemp(dir, base+"*")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(prefix), filepath.Base(path)), nil
+}
+
+// ParseFlags converts a Node-style flag string such as "r+", "wx" or "a" to
+// os.OpenFile flags. "x" adds O_EXCL so the open fails if the file exists.
+func ParseFlags(flags string) (int, error) {
+	switch flags {
+	case "", "r", "rs", "sr":
+		return os.O_RDONLY, nil
+	case "r+", "rs+", "sr+":
+		return os.O_RDWR, nil
+	case "w":
+		return os.O_WRONLY | os.O_CREATE | os.O_TRUNC, nil
+	case "wx", "xw":
+		return os.O_WRONLY | os.O_CREATE | os.O_EXCL, nil
+	case "w+":
+		return os.O_RDWR | os.O_CREATE | os.O_TRUNC, nil
+	case "wx+", "xw+":
+		return os.O_RDWR | os.O_CREATE | os.O_EXCL, nil
+	case "a", "as", "sa":
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND, nil
+	case "ax", "xa":
+		return os.O_WRONLY | os.O_CREATE | os.O_APPEND | os.O_EXCL, nil
+	case "a+", "as+", "sa+":
+		return os.O_RDWR | os.O_CREATE | os.O_APPEND, nil
+	case "ax+", "xa+":
+		return os.O_RDWR | os.O_CREATE | os.O_APPEND | os.O_EXCL, nil
+	}
+	return 0, fmt.Errorf("invalid flags %q", flags)
+}
+
+// Writes reports whether flags open the file for writing
+func Writes(flags int) bool {
+	return flags&(os.O_WRONLY|os.O_RDWR) != 0
+}
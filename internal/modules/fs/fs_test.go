@@ -0,0 +1,89 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	if err := WriteFileAtomic(path, []byte("one"), 0600); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("two"), 0644); err != nil {
+		t.Fatalf("Failed to overwrite: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "two" {
+		t.Errorf("Expected contents %q, got %q (%v)", "two", data, err)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected existing mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "file")
+	if err := WriteFileAtomic(path, []byte("x"), 0644); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestMkdtemp(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "build-")
+
+	a, err := Mkdtemp(prefix)
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	b, _ := Mkdtemp(prefix)
+
+	if a == b {
+		t.Error("Expected unique directories")
+	}
+	if !strings.HasPrefix(a, prefix) || len(a) <= len(prefix) {
+		t.Errorf("Expected %q to extend the prefix %q", a, prefix)
+	}
+	if info, err := os.Stat(a); err != nil || !info.IsDir() {
+		t.Errorf("Expected %q to be a directory", a)
+	}
+}
+
+func TestParseFlagsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+
+	flags, err := ParseFlags("wx")
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if !Writes(flags) {
+		t.Error("Expected wx to open for writing")
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		t.Fatalf("First exclusive open failed: %v", err)
+	}
+	f.Close()
+
+	if _, err := os.OpenFile(path, flags, 0644); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected second exclusive open to fail with ErrExist, got %v", err)
+	}
+
+	if _, err := ParseFlags("q"); err == nil {
+		t.Error("Expected an error for unknown flags")
+	}
+}
@@ -0,0 +1,23 @@
+package fs
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// RegisterFSModule registers gode:fs in the JavaScript runtime
+func RegisterFSModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime)
+		runtime.RegisterModule("gode:fs", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
@@ -0,0 +1,321 @@
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+)
+
+// Bridge provides JavaScript bindings for the gode:fuzz module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+	genKey  *goja.Symbol
+}
+
+// record is an object generated by fuzz.object, kept in Go so that every
+// property call gets a fresh JS object
+type record struct {
+	keys   []string
+	values []interface{}
+}
+
+// NewBridge creates a new fuzz bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+		genKey:  goja.NewSymbol("gode.fuzz.generator"),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+
+	// Generators
+	exports.Set("integer", func(call goja.FunctionCall) goja.Value {
+		opts, _ := call.Argument(0).(*goja.Object)
+		min, max := int64(math.MinInt32), int64(math.MaxInt32)
+		if v := b.option(opts, "min"); v != nil {
+			min = v.ToInteger()
+		}
+		if v := b.option(opts, "max"); v != nil {
+			max = v.ToInteger()
+		}
+		return b.generator(Integer(min, max))
+	})
+	exports.Set("float", func(call goja.FunctionCall) goja.Value {
+		opts, _ := call.Argument(0).(*goja.Object)
+		min, max := -1e6, 1e6
+		if v := b.option(opts, "min"); v != nil {
+			min = v.ToFloat()
+		}
+		if v := b.option(opts, "max"); v != nil {
+			max = v.ToFloat()
+		}
+		return b.generator(Float(min, max))
+	})
+	exports.Set("boolean", func() *goja.Object { return b.generator(Boolean()) })
+	exports.Set("constant", func(value goja.Value) *goja.Object { return b.generator(Constant(value)) })
+	exports.Set("constantFrom", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(b.vm.NewTypeError("constantFrom() expects at least one value"))
+		}
+		values := make([]interface{}, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			values[i] = arg
+		}
+		return b.generator(ElementOf(values...))
+	})
+	exports.Set("string", func(call goja.FunctionCall) goja.Value {
+		opts, _ := call.Argument(0).(*goja.Object)
+		minLength, maxLength := b.lengths(opts, 20)
+		alphabet := ""
+		if v := b.option(opts, "alphabet"); v != nil {
+			alphabet = v.String()
+		}
+		return b.generator(String(alphabet, minLength, maxLength))
+	})
+	exports.Set("array", func(call goja.FunctionCall) goja.Value {
+		opts, _ := call.Argument(1).(*goja.Object)
+		minLength, maxLength := b.lengths(opts, 10)
+		return b.generator(Array(b.unwrap(call.Argument(0), "array item"), minLength, maxLength))
+	})
+	exports.Set("tuple", func(call goja.FunctionCall) goja.Value {
+		return b.generator(Tuple(b.unwrapAll(call.Arguments)...))
+	})
+	exports.Set("object", b.objectGenerator)
+	exports.Set("oneOf", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) == 0 {
+			panic(b.vm.NewTypeError("oneOf() expects at least one generator"))
+		}
+		return b.generator(OneOf(b.unwrapAll(call.Arguments)...))
+	})
+
+	// Running properties
+	exports.Set("check", func(call goja.FunctionCall) goja.Value {
+		result := b.check(call.Argument(0), call.Argument(1), call.Argument(2))
+
+		obj := b.vm.NewObject()
+		obj.Set("passed", result.Passed)
+		obj.Set("runs", result.Runs)
+		obj.Set("seed", result.Seed)
+		obj.Set("shrinks", result.Shrinks)
+		if !result.Passed {
+			obj.Set("counterexample", b.toJS(result.Counterexample))
+			obj.Set("error", jserror.New(b.vm, result.Err))
+			obj.Set("message", result.Report(b.format))
+		}
+		return obj
+	})
+	exports.Set("assert", func(call goja.FunctionCall) goja.Value {
+		result := b.check(call.Argument(0), call.Argument(1), call.Argument(2))
+		if !result.Passed {
+			err := b.vm.NewGoError(errors.New(result.Report(b.format)))
+			err.Set("seed", result.Seed)
+			err.Set("counterexample", b.toJS(result.Counterexample))
+			panic(err)
+		}
+		return goja.Undefined()
+	})
+
+	return exports
+}
+
+// generator wraps a Go generator in a JS object with map, filter and sample
+func (b *Bridge) generator(gen Generator) *goja.Object {
+	obj := b.vm.NewObject()
+	obj.SetSymbol(b.genKey, gen)
+
+	obj.Set("map", func(fn goja.Value) *goja.Object {
+		mapper := b.function(fn, "map()")
+		return b.generator(Map(gen, func(v interface{}) interface{} {
+			result, err := mapper(goja.Undefined(), b.toJS(v))
+			if err != nil {
+				panic(err)
+			}
+			return result
+		}))
+	})
+	obj.Set("filter", func(fn goja.Value) *goja.Object {
+		predicate := b.function(fn, "filter()")
+		return b.generator(Filter(gen, func(v interface{}) bool {
+			result, err := predicate(goja.Undefined(), b.toJS(v))
+			if err != nil {
+				panic(err)
+			}
+			return result.ToBoolean()
+		}))
+	})
+	obj.Set("sample", func(count int, seed int64) *goja.Object {
+		if count <= 0 {
+			count = 10
+		}
+		r := rand.New(rand.NewSource(seed))
+		values := make([]interface{}, count)
+		for i := range values {
+			values[i] = b.toJS(gen(r, i*MaxSize/count).Value)
+		}
+		return b.vm.NewArray(values...)
+	})
+	return obj
+}
+
+// objectGenerator implements fuzz.object({key: generator, ...})
+func (b *Bridge) objectGenerator(call goja.FunctionCall) goja.Value {
+	shape, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(b.vm.NewTypeError("object() expects a map of field generators"))
+	}
+
+	keys := shape.Keys()
+	gens := make([]Generator, len(keys))
+	for i, key := range keys {
+		gens[i] = b.unwrap(shape.Get(key), fmt.Sprintf("field %q", key))
+	}
+	return b.generator(Map(Tuple(gens...), func(v interface{}) interface{} {
+		return record{keys: keys, values: v.([]interface{})}
+	}))
+}
+
+// check runs fn against gen as configured by {runs, seed, maxShrinks}. An
+// array of generators passes one value from each as separate arguments.
+func (b *Bridge) check(genValue, fnValue, optsValue goja.Value) Result {
+	spread := false
+	var gen Generator
+	if arr, ok := genValue.(*goja.Object); ok && arr.ClassName() == "Array" {
+		var items []goja.Value
+		for _, key := range arr.Keys() {
+			items = append(items, arr.Get(key))
+		}
+		gen, spread = Tuple(b.unwrapAll(items)...), true
+	} else {
+		gen = b.unwrap(genValue, "generator")
+	}
+	fn := b.function(fnValue, "property")
+
+	opts, _ := optsValue.(*goja.Object)
+	var cfg Config
+	if v := b.option(opts, "runs"); v != nil {
+		cfg.Runs = int(v.ToInteger())
+	}
+	if v := b.option(opts, "seed"); v != nil {
+		cfg.Seed = v.ToInteger()
+	}
+	if v := b.option(opts, "maxShrinks"); v != nil {
+		cfg.MaxShrinks = int(v.ToInteger())
+	}
+
+	return Check(gen, func(value interface{}) error {
+		args := []goja.Value{b.toJS(value)}
+		if spread {
+			values := value.([]interface{})
+			args = make([]goja.Value, len(values))
+			for i, v := range values {
+				args[i] = b.toJS(v)
+			}
+		}
+
+		result, err := fn(goja.Undefined(), args...)
+		if err != nil {
+			var exception *goja.Exception
+			if errors.As(err, &exception) {
+				return jserror.Error(exception.Value())
+			}
+			return err
+		}
+		if result != nil && result.StrictEquals(b.vm.ToValue(false)) {
+			return errors.New("property returned false")
+		}
+		return nil
+	}, cfg)
+}
+
+// toJS converts a generated value into a fresh JS value
+func (b *Bridge) toJS(value interface{}) goja.Value {
+	switch v := value.(type) {
+	case goja.Value:
+		return v
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = b.toJS(item)
+		}
+		return b.vm.NewArray(items...)
+	case record:
+		obj := b.vm.NewObject()
+		for i, key := range v.keys {
+			obj.Set(key, b.toJS(v.values[i]))
+		}
+		return obj
+	}
+	return b.vm.ToValue(value)
+}
+
+// format renders a counterexample as JSON, falling back to its string form
+func (b *Bridge) format(value interface{}) string {
+	js := b.toJS(value)
+	stringify, _ := goja.AssertFunction(b.vm.Get("JSON").ToObject(b.vm).Get("stringify"))
+	if s, err := stringify(goja.Undefined(), js); err == nil && !goja.IsUndefined(s) {
+		return s.String()
+	}
+	return js.String()
+}
+
+// unwrap returns the Go generator behind a value created by this module
+func (b *Bridge) unwrap(value goja.Value, what string) Generator {
+	if obj, ok := value.(*goja.Object); ok {
+		if sym := obj.GetSymbol(b.genKey); sym != nil {
+			if gen, ok := sym.Export().(Generator); ok {
+				return gen
+			}
+		}
+	}
+	panic(b.vm.NewTypeError(fmt.Sprintf("%s must be a gode:fuzz generator", what)))
+}
+
+func (b *Bridge) unwrapAll(values []goja.Value) []Generator {
+	gens := make([]Generator, len(values))
+	for i, value := range values {
+		gens[i] = b.unwrap(value, fmt.Sprintf("argument %d", i+1))
+	}
+	return gens
+}
+
+func (b *Bridge) function(value goja.Value, what string) goja.Callable {
+	fn, ok := goja.AssertFunction(value)
+	if !ok {
+		panic(b.vm.NewTypeError(fmt.Sprintf("%s must be a function", what)))
+	}
+	return fn
+}
+
+// lengths reads {minLength, maxLength}
+func (b *Bridge) lengths(opts *goja.Object, defaultMax int) (int, int) {
+	minLength, maxLength := 0, defaultMax
+	if v := b.option(opts, "minLength"); v != nil {
+		minLength = int(v.ToInteger())
+	}
+	if v := b.option(opts, "maxLength"); v != nil {
+		maxLength = int(v.ToInteger())
+	} else if minLength > maxLength {
+		maxLength = minLength + defaultMax
+	}
+	return minLength, maxLength
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	if obj == nil {
+		return nil
+	}
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package fuzz_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestFuzzModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("fuzz", `
+		const fuzz = require('gode:fuzz');
+		const point = fuzz.object({ x: fuzz.integer({ min: 0, max: 1000 }), tag: fuzz.string({ alphabet: 'ab' }) });
+		const result = fuzz.check(point, p => p.x < 300, { seed: 17 });
+		const again = fuzz.check(point, p => p.x < 300, { seed: 17 });
+		[result.passed, JSON.stringify(result.counterexample), result.seed, again.runs === result.runs].join('|');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	// The JSON global orders keys its own way, so the counterexample is
+	// compared parsed
+	text, _ := value.(string)
+	parts := strings.SplitN(text, "|", 4)
+	var counterexample map[string]interface{}
+	if len(parts) != 4 || json.Unmarshal([]byte(parts[1]), &counterexample) != nil {
+		t.Fatalf("fuzz.check() = %v", value)
+	}
+	if want := map[string]interface{}{"x": 300.0, "tag": ""}; !reflect.DeepEqual(counterexample, want) {
+		t.Errorf("counterexample = %v, want %v", counterexample, want)
+	}
+	if got := [3]string{parts[0], parts[2], parts[3]}; got != [3]string{"false", "17", "true"} {
+		t.Errorf("fuzz.check() = %v, want passed false, seed 17 and the same runs again", value)
+	}
+
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "prop.test.js")
+	os.WriteFile(testFile, []byte(`
+		const fuzz = require('gode:fuzz');
+		describe('properties', () => {
+			test.prop('reverse twice', fuzz.array(fuzz.integer()), arr => {
+				expect(JSON.stringify(arr.slice().reverse().reverse())).toBe(JSON.stringify(arr));
+			});
+			test.prop('sum is small', [fuzz.integer({ min: 0, max: 100 }), fuzz.integer({ min: 0, max: 100 })], (a, b) => {
+				expect(a + b < 150).toBe(true);
+			}, { seed: 5 });
+		});
+	`), 0644)
+
+	results, err := rt.RunTests([]string{testFile})
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Passed != 1 || results[0].Failed != 1 {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	message := results[0].Tests[1].Error
+	for _, want := range []string{"seed 5", "counterexample: [", "expected false to be true"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Failure %q does not contain %q", message, want)
+		}
+	}
+}
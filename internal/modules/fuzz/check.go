@@ -0,0 +1,155 @@
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrFilterExhausted is raised by a Filter generator that rejected too
+// many values in a row
+var ErrFilterExhausted = errors.New("filter rejected too many generated values")
+
+// Config controls a property check
+type Config struct {
+	Runs       int   // random values tried; defaults to 100
+	Seed       int64 // seed of the value sequence; 0 picks one below 2^53, exact as a JS number
+	MaxShrinks int   // property evaluations spent shrinking; defaults to 1000
+}
+
+// Result is the outcome of a property check. A failing check reports the
+// seed that reproduces it and the smallest counterexample found.
+type Result struct {
+	Passed         bool
+	Runs           int
+	Seed           int64
+	Original       interface{} // first failing value
+	Counterexample interface{} // failing value after shrinking
+	Shrinks        int         // successful shrink steps
+	Err            error       // the property failure for Counterexample
+}
+
+// Property checks one value, returning an error when it does not hold
+type Property func(value interface{}) error
+
+// Check runs prop against values from gen until one fails or cfg.Runs
+// values pass, then shrinks the failing value
+func Check(gen Generator, prop Property, cfg Config) Result {
+	if cfg.Runs <= 0 {
+		cfg.Runs = 100
+	}
+	if cfg.MaxShrinks <= 0 {
+		cfg.MaxShrinks = 1000
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()&(1<<53-1) | 1
+	}
+
+	r := rand.New(rand.NewSource(cfg.Seed))
+	result := Result{Passed: true, Seed: cfg.Seed}
+	for run := 0; run < cfg.Runs; run++ {
+		result.Runs = run + 1
+		size := MaxSize
+		if cfg.Runs > 1 {
+			size = run * MaxSize / (cfg.Runs - 1)
+		}
+
+		sample, err := generate(gen, r, size)
+		if err != nil {
+			result.Passed = false
+			result.Err = err
+			return result
+		}
+		if err := prop(sample.Value); err != nil {
+			result.Passed = false
+			result.Original = sample.Value
+			result.Counterexample, result.Shrinks, result.Err = shrink(sample, err, prop, cfg.MaxShrinks)
+			return result
+		}
+	}
+	return result
+}
+
+// generate draws a sample, turning a Filter giving up into an error
+func generate(gen Generator, r *rand.Rand, size int) (sample Sample, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p != ErrFilterExhausted {
+				panic(p)
+			}
+			err = ErrFilterExhausted
+		}
+	}()
+	return gen(r, size), nil
+}
+
+// shrink greedily moves to the first shrink candidate that still fails,
+// until none does or the evaluation budget is spent
+func shrink(sample Sample, failure error, prop Property, budget int) (interface{}, int, error) {
+	steps := 0
+	for budget > 0 {
+		shrunk := false
+		for _, candidate := range sample.Shrinks() {
+			if budget == 0 {
+				break
+			}
+			budget--
+			if err := prop(candidate.Value); err != nil {
+				sample, failure = candidate, err
+				steps++
+				shrunk = true
+				break
+			}
+		}
+		if !shrunk {
+			break
+		}
+	}
+	return sample.Value, steps, failure
+}
+
+// Report describes a failed check for a test report, including the seed
+// that reproduces it. format renders the counterexample; nil uses Format.
+func (r Result) Report(format func(interface{}) string) string {
+	if r.Passed {
+		return ""
+	}
+	if format == nil {
+		format = Format
+	}
+	if errors.Is(r.Err, ErrFilterExhausted) {
+		return fmt.Sprintf("property could not be checked (seed %d): %v", r.Seed, r.Err)
+	}
+	return fmt.Sprintf("property failed after %d run%s (seed %d, %d shrink%s)\ncounterexample: %s\n%v",
+		r.Runs, plural(r.Runs), r.Seed, r.Shrinks, plural(r.Shrinks), format(r.Counterexample), r.Err)
+}
+
+// Format renders a generated value as a JS literal
+func Format(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "undefined"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		s := "["
+		for i, item := range v {
+			if i > 0 {
+				s += ", "
+			}
+			s += Format(item)
+		}
+		return s + "]"
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
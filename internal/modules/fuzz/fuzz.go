@@ -0,0 +1,299 @@
+// Package fuzz implements property-based testing for gode:fuzz: random
+// value generators that shrink failing values to a minimal counterexample
+package fuzz
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// MaxSize is the largest size passed to generators, reached by the last
+// runs of a check. Collections scale their length with it.
+const MaxSize = 100
+
+// Sample is a generated value together with the simpler values it can
+// shrink to, simplest first
+type Sample struct {
+	Value  interface{}
+	shrink func() []Sample
+}
+
+// Shrinks returns the candidates to try when the sample fails a property
+func (s Sample) Shrinks() []Sample {
+	if s.shrink == nil {
+		return nil
+	}
+	return s.shrink()
+}
+
+// Generator produces a random sample. size grows from 0 to MaxSize over a
+// check, so early runs try small values.
+type Generator func(r *rand.Rand, size int) Sample
+
+// Constant always generates value
+func Constant(value interface{}) Generator {
+	return func(r *rand.Rand, size int) Sample {
+		return Sample{Value: value}
+	}
+}
+
+// Integer generates integers in [min, max], shrinking towards the value
+// closest to zero
+func Integer(min, max int64) Generator {
+	if min > max {
+		min, max = max, min
+	}
+	target := int64(0)
+	if min > 0 {
+		target = min
+	} else if max < 0 {
+		target = max
+	}
+	return func(r *rand.Rand, size int) Sample {
+		span := uint64(max - min)
+		var n int64
+		if span == ^uint64(0) {
+			n = int64(r.Uint64())
+		} else {
+			n = min + int64(randUint64n(r, span+1))
+		}
+		return integerSample(n, target)
+	}
+}
+
+// randUint64n returns a uniform value in [0, n)
+func randUint64n(r *rand.Rand, n uint64) uint64 {
+	if n < 1<<63 {
+		return uint64(r.Int63n(int64(n)))
+	}
+	for {
+		if v := r.Uint64(); v < n {
+			return v
+		}
+	}
+}
+
+// integerSample shrinks n towards target by halving the distance
+func integerSample(n, target int64) Sample {
+	return Sample{Value: n, shrink: func() []Sample {
+		var shrinks []Sample
+		for d := n - target; d != 0; d /= 2 {
+			shrinks = append(shrinks, integerSample(n-d, target))
+		}
+		return shrinks
+	}}
+}
+
+// Float generates numbers in [min, max), shrinking towards the integer
+// part and then towards the value closest to zero
+func Float(min, max float64) Generator {
+	if min > max {
+		min, max = max, min
+	}
+	target := 0.0
+	if min > 0 {
+		target = min
+	} else if max < 0 {
+		target = max
+	}
+	return func(r *rand.Rand, size int) Sample {
+		return floatSample(min+r.Float64()*(max-min), target, 0)
+	}
+}
+
+func floatSample(f, target float64, depth int) Sample {
+	return Sample{Value: f, shrink: func() []Sample {
+		if f == target || depth > 32 {
+			return nil
+		}
+		shrinks := []Sample{floatSample(target, target, depth+1)}
+		if whole := float64(int64(f)); whole != f && whole != target {
+			shrinks = append(shrinks, floatSample(whole, target, depth+1))
+		}
+		return append(shrinks, floatSample(target+(f-target)/2, target, depth+1))
+	}}
+}
+
+// Boolean generates true or false, shrinking to false
+func Boolean() Generator {
+	return func(r *rand.Rand, size int) Sample {
+		if r.Intn(2) == 0 {
+			return Sample{Value: false}
+		}
+		return Sample{Value: true, shrink: func() []Sample {
+			return []Sample{{Value: false}}
+		}}
+	}
+}
+
+// OneOf picks one of gens, shrinking towards the earlier ones
+func OneOf(gens ...Generator) Generator {
+	return func(r *rand.Rand, size int) Sample {
+		i := r.Intn(len(gens))
+		return oneOfSample(gens, i, gens[i](r, size), r.Int63())
+	}
+}
+
+func oneOfSample(gens []Generator, i int, s Sample, seed int64) Sample {
+	return Sample{Value: s.Value, shrink: func() []Sample {
+		var shrinks []Sample
+		for j := 0; j < i; j++ {
+			// Earlier alternatives are regenerated small and reproducibly
+			r := rand.New(rand.NewSource(seed))
+			shrinks = append(shrinks, oneOfSample(gens, j, gens[j](r, 0), seed))
+		}
+		for _, shrunk := range s.Shrinks() {
+			shrinks = append(shrinks, oneOfSample(gens, i, shrunk, seed))
+		}
+		return shrinks
+	}}
+}
+
+// ElementOf picks one of values, shrinking towards the first
+func ElementOf(values ...interface{}) Generator {
+	return Map(Integer(0, int64(len(values)-1)), func(i interface{}) interface{} {
+		return values[i.(int64)]
+	})
+}
+
+// Array generates slices of minLength to maxLength elements of gen. Arrays
+// shrink by dropping elements and then by shrinking the ones left.
+func Array(gen Generator, minLength, maxLength int) Generator {
+	if maxLength < minLength {
+		maxLength = minLength
+	}
+	return func(r *rand.Rand, size int) Sample {
+		n := minLength + r.Intn((maxLength-minLength)*size/MaxSize+1)
+		items := make([]Sample, n)
+		for i := range items {
+			items[i] = gen(r, size)
+		}
+		return arraySample(items, minLength)
+	}
+}
+
+func arraySample(items []Sample, minLength int) Sample {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item.Value
+	}
+	return Sample{Value: values, shrink: func() []Sample {
+		var shrinks []Sample
+		// Drop runs of elements, halving the run length
+		for k := len(items) - minLength; k > 0; k /= 2 {
+			for start := 0; start+k <= len(items); start += k {
+				rest := append(append([]Sample(nil), items[:start]...), items[start+k:]...)
+				shrinks = append(shrinks, arraySample(rest, minLength))
+			}
+		}
+		// Then shrink each element in place
+		for i, item := range items {
+			for _, shrunk := range item.Shrinks() {
+				replaced := append([]Sample(nil), items...)
+				replaced[i] = shrunk
+				shrinks = append(shrinks, arraySample(replaced, minLength))
+			}
+		}
+		return shrinks
+	}}
+}
+
+// Tuple generates a slice with one value from each of gens, shrinking one
+// position at a time
+func Tuple(gens ...Generator) Generator {
+	return func(r *rand.Rand, size int) Sample {
+		items := make([]Sample, len(gens))
+		for i, gen := range gens {
+			items[i] = gen(r, size)
+		}
+		return tupleSample(items)
+	}
+}
+
+func tupleSample(items []Sample) Sample {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item.Value
+	}
+	return Sample{Value: values, shrink: func() []Sample {
+		var shrinks []Sample
+		for i, item := range items {
+			for _, shrunk := range item.Shrinks() {
+				replaced := append([]Sample(nil), items...)
+				replaced[i] = shrunk
+				shrinks = append(shrinks, tupleSample(replaced))
+			}
+		}
+		return shrinks
+	}}
+}
+
+// DefaultAlphabet is the characters String draws from by default. Strings
+// shrink towards its first character.
+const DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// String generates strings of minLength to maxLength characters drawn
+// from alphabet, shrinking like an array of characters
+func String(alphabet string, minLength, maxLength int) Generator {
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+	chars := make([]interface{}, 0, len(alphabet))
+	for _, c := range alphabet {
+		chars = append(chars, string(c))
+	}
+	return Map(Array(ElementOf(chars...), minLength, maxLength), func(v interface{}) interface{} {
+		var b strings.Builder
+		for _, c := range v.([]interface{}) {
+			b.WriteString(c.(string))
+		}
+		return b.String()
+	})
+}
+
+// Map transforms the values of gen with fn. Shrinking happens on the
+// original values, so mapped values shrink too.
+func Map(gen Generator, fn func(interface{}) interface{}) Generator {
+	return func(r *rand.Rand, size int) Sample {
+		return mapSample(gen(r, size), fn)
+	}
+}
+
+func mapSample(s Sample, fn func(interface{}) interface{}) Sample {
+	return Sample{Value: fn(s.Value), shrink: func() []Sample {
+		shrinks := s.Shrinks()
+		mapped := make([]Sample, len(shrinks))
+		for i, shrunk := range shrinks {
+			mapped[i] = mapSample(shrunk, fn)
+		}
+		return mapped
+	}}
+}
+
+// maxFilterTries bounds how often Filter draws before giving up
+const maxFilterTries = 100
+
+// Filter keeps the values of gen that satisfy keep. It panics with
+// ErrFilterExhausted when keep rejects too many values in a row.
+func Filter(gen Generator, keep func(interface{}) bool) Generator {
+	return func(r *rand.Rand, size int) Sample {
+		for i := 0; i < maxFilterTries; i++ {
+			if s := gen(r, size); keep(s.Value) {
+				return filterSample(s, keep)
+			}
+		}
+		panic(ErrFilterExhausted)
+	}
+}
+
+func filterSample(s Sample, keep func(interface{}) bool) Sample {
+	return Sample{Value: s.Value, shrink: func() []Sample {
+		var kept []Sample
+		for _, shrunk := range s.Shrinks() {
+			if keep(shrunk.Value) {
+				kept = append(kept, filterSample(shrunk, keep))
+			}
+		}
+		return kept
+	}}
+}
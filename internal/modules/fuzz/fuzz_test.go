@@ -0,0 +1,130 @@
+package fuzz
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntegerRange(t *testing.T) {
+	gen := Integer(-5, 5)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		n := gen(r, MaxSize).Value.(int64)
+		if n < -5 || n > 5 {
+			t.Fatalf("Integer(-5, 5) generated %d", n)
+		}
+	}
+}
+
+func TestCheckPasses(t *testing.T) {
+	result := Check(Integer(0, 100), func(v interface{}) error {
+		if v.(int64) < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	}, Config{Runs: 50, Seed: 7})
+
+	if !result.Passed || result.Runs != 50 || result.Seed != 7 {
+		t.Errorf("Check() = %+v, want 50 passing runs with seed 7", result)
+	}
+	if result.Report(nil) != "" {
+		t.Errorf("Report() of a passing check = %q", result.Report(nil))
+	}
+}
+
+func TestCheckShrinksInteger(t *testing.T) {
+	result := Check(Integer(0, 1_000_000), func(v interface{}) error {
+		if v.(int64) >= 1234 {
+			return errors.New("too big")
+		}
+		return nil
+	}, Config{Seed: 42})
+
+	if result.Passed {
+		t.Fatal("Expected the property to fail")
+	}
+	if result.Counterexample != int64(1234) {
+		t.Errorf("Counterexample = %v, want 1234", result.Counterexample)
+	}
+}
+
+func TestCheckShrinksArray(t *testing.T) {
+	// Fails for any array holding a value of 10 or more
+	prop := func(v interface{}) error {
+		for _, item := range v.([]interface{}) {
+			if item.(int64) >= 10 {
+				return errors.New("contains a large value")
+			}
+		}
+		return nil
+	}
+
+	result := Check(Array(Integer(0, 100), 0, 20), prop, Config{Seed: 3})
+	if result.Passed {
+		t.Fatal("Expected the property to fail")
+	}
+	if want := []interface{}{int64(10)}; !reflect.DeepEqual(result.Counterexample, want) {
+		t.Errorf("Counterexample = %v, want %v", result.Counterexample, want)
+	}
+	if !strings.Contains(result.Report(nil), "counterexample: [10]") || !strings.Contains(result.Report(nil), "seed 3") {
+		t.Errorf("Unexpected report: %s", result.Report(nil))
+	}
+}
+
+func TestCheckSeedReproduces(t *testing.T) {
+	gen := Array(String("", 0, 10), 0, 5)
+	var first, second []interface{}
+	Check(gen, func(v interface{}) error { first = append(first, v); return nil }, Config{Runs: 20, Seed: 99})
+	Check(gen, func(v interface{}) error { second = append(second, v); return nil }, Config{Runs: 20, Seed: 99})
+
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected the same seed to generate the same values")
+	}
+}
+
+func TestStringShrinksTowardsAlphabetStart(t *testing.T) {
+	result := Check(String("abc", 1, 10), func(v interface{}) error {
+		if strings.Contains(v.(string), "c") {
+			return errors.New("contains c")
+		}
+		return nil
+	}, Config{Seed: 5})
+
+	if result.Counterexample != "c" {
+		t.Errorf("Counterexample = %q, want %q", result.Counterexample, "c")
+	}
+}
+
+func TestMapAndFilter(t *testing.T) {
+	even := Filter(Integer(0, 1000), func(v interface{}) bool { return v.(int64)%2 == 0 })
+	doubled := Map(even, func(v interface{}) interface{} { return v.(int64) * 2 })
+
+	result := Check(doubled, func(v interface{}) error {
+		if v.(int64)%4 != 0 {
+			return errors.New("not a multiple of 4")
+		}
+		if v.(int64) > 100 {
+			return errors.New("too big")
+		}
+		return nil
+	}, Config{Seed: 11})
+
+	// Shrinks stay within the filter, so the counterexample is a doubled
+	// even number that still fails
+	n := result.Counterexample.(int64)
+	if n%4 != 0 || n <= 100 || n > result.Original.(int64) {
+		t.Errorf("Counterexample = %d from %v, want a failing shrink", n, result.Original)
+	}
+}
+
+func TestFilterExhausted(t *testing.T) {
+	never := Filter(Integer(0, 10), func(interface{}) bool { return false })
+	result := Check(never, func(interface{}) error { return nil }, Config{Seed: 1})
+
+	if result.Passed || !errors.Is(result.Err, ErrFilterExhausted) {
+		t.Errorf("Check() = %+v, want ErrFilterExhausted", result)
+	}
+}
@@ -0,0 +1,265 @@
+package globals
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// abortSetup defines Event, EventTarget, DOMException, AbortSignal and
+// AbortController. native.timeout(ms, fn) backs AbortSignal.timeout with a
+// Go timer, so that a pending timeout does not keep the process alive.
+// Errors thrown by listeners are rethrown from a timer, as browsers report
+// them, rather than stopping the dispatch.
+const abortSetup = `
+(function (native) {
+	const codes = {
+		IndexSizeError: 1, NotFoundError: 8, NotSupportedError: 9, InvalidStateError: 11,
+		SyntaxError: 12, InvalidAccessError: 15, TypeMismatchError: 17, SecurityError: 18,
+		NetworkError: 19, AbortError: 20, QuotaExceededError: 22, TimeoutError: 23, DataCloneError: 25,
+	};
+
+	class DOMException extends Error {
+		constructor(message, name) {
+			super(message === undefined ? '' : String(message));
+			Object.defineProperty(this, 'name', {
+				value: name === undefined ? 'Error' : String(name), writable: true, configurable: true
+			});
+		}
+
+		get code() {
+			return codes[this.name] || 0;
+		}
+	}
+
+	const report = (err) => setTimeout(() => { throw err; }, 0);
+	const kStop = Symbol('stop');
+	const kListeners = Symbol('listeners');
+
+	class Event {
+		constructor(type, options) {
+			if (arguments.length === 0) {
+				throw new TypeError('Event requires a type');
+			}
+			this.type = String(type);
+			this.bubbles = !!(options && options.bubbles);
+			this.cancelable = !!(options && options.cancelable);
+			this.defaultPrevented = false;
+			this.timeStamp = Date.now();
+			this.target = null;
+			this.currentTarget = null;
+			this[kStop] = false;
+		}
+
+		preventDefault() {
+			if (this.cancelable) {
+				this.defaultPrevented = true;
+			}
+		}
+
+		stopPropagation() {}
+
+		stopImmediatePropagation() {
+			this[kStop] = true;
+		}
+	}
+
+	class EventTarget {
+		constructor() {
+			this[kListeners] = new Map();
+		}
+
+		addEventListener(type, listener, options) {
+			if (listener === null || listener === undefined) {
+				return;
+			}
+			const once = options !== null && typeof options === 'object' && !!options.once;
+			const signal = options !== null && typeof options === 'object' ? options.signal : undefined;
+			if (signal && signal.aborted) {
+				return;
+			}
+			let list = this[kListeners].get(type);
+			if (!list) {
+				list = [];
+				this[kListeners].set(type, list);
+			}
+			if (list.some(entry => entry.listener === listener)) {
+				return;
+			}
+			list.push({ listener, once });
+			if (signal) {
+				signal.addEventListener('abort', () => this.removeEventListener(type, listener), { once: true });
+			}
+		}
+
+		removeEventListener(type, listener) {
+			const list = this[kListeners].get(type);
+			if (!list) {
+				return;
+			}
+			const index = list.findIndex(entry => entry.listener === listener);
+			if (index >= 0) {
+				list.splice(index, 1);
+			}
+		}
+
+		dispatchEvent(event) {
+			if (!(event instanceof Event)) {
+				throw new TypeError('dispatchEvent requires an Event');
+			}
+			event.target = this;
+			event.currentTarget = this;
+			for (const entry of (this[kListeners].get(event.type) || []).slice()) {
+				if (entry.once) {
+					this.removeEventListener(event.type, entry.listener);
+				}
+				try {
+					if (typeof entry.listener === 'function') {
+						entry.listener.call(this, event);
+					} else {
+						entry.listener.handleEvent(event);
+					}
+				} catch (err) {
+					report(err);
+				}
+				if (event[kStop]) {
+					break;
+				}
+			}
+			event.currentTarget = null;
+			return !event.defaultPrevented;
+		}
+	}
+
+	const kAborted = Symbol('aborted');
+	const kReason = Symbol('reason');
+	const kCreate = Symbol('create');
+
+	class AbortSignal extends EventTarget {
+		constructor(key) {
+			if (key !== kCreate) {
+				throw new TypeError('Illegal constructor');
+			}
+			super();
+			this[kAborted] = false;
+			this[kReason] = undefined;
+			this.onabort = null;
+		}
+
+		get aborted() {
+			return this[kAborted];
+		}
+
+		get reason() {
+			return this[kReason];
+		}
+
+		throwIfAborted() {
+			if (this[kAborted]) {
+				throw this[kReason];
+			}
+		}
+
+		static abort(reason) {
+			const signal = new AbortSignal(kCreate);
+			abort(signal, reason);
+			return signal;
+		}
+
+		static timeout(ms) {
+			const signal = new AbortSignal(kCreate);
+			native.timeout(ms, () => {
+				abort(signal, new DOMException('The operation was aborted due to timeout', 'TimeoutError'));
+			});
+			return signal;
+		}
+
+		static any(signals) {
+			const signal = new AbortSignal(kCreate);
+			for (const source of signals) {
+				if (source.aborted) {
+					abort(signal, source.reason);
+					return signal;
+				}
+			}
+			for (const source of signals) {
+				source.addEventListener('abort', () => abort(signal, source.reason), { once: true });
+			}
+			return signal;
+		}
+	}
+
+	// abort aborts signal once: the reason is set before onabort and the
+	// abort listeners run
+	function abort(signal, reason) {
+		if (signal[kAborted]) {
+			return;
+		}
+		signal[kAborted] = true;
+		signal[kReason] = reason === undefined ? new DOMException('This operation was aborted', 'AbortError') : reason;
+		const event = new Event('abort');
+		if (typeof signal.onabort === 'function') {
+			try {
+				signal.onabort.call(signal, event);
+			} catch (err) {
+				report(err);
+			}
+		}
+		signal.dispatchEvent(event);
+	}
+
+	const kSignal = Symbol('signal');
+
+	class AbortController {
+		constructor() {
+			this[kSignal] = new AbortSignal(kCreate);
+		}
+
+		get signal() {
+			return this[kSignal];
+		}
+
+		abort(reason) {
+			abort(this[kSignal], reason);
+		}
+	}
+
+	return { DOMException, Event, EventTarget, AbortSignal, AbortController };
+})
+`
+
+// installAbort registers the abort and event globals
+func installAbort(runtime RuntimeInterface) error {
+	vm := runtime.GetRuntime()
+	setup, err := jsprogram.Run(vm, "abort-setup", abortSetup)
+	if err != nil {
+		return err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("abort setup did not return a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("timeout", func(ms int64, fn goja.Callable) {
+		time.AfterFunc(time.Duration(ms)*time.Millisecond, func() {
+			runtime.QueueJSOperation(func() {
+				fn(goja.Undefined())
+			})
+		})
+	})
+	classes, err := build(goja.Undefined(), native)
+	if err != nil {
+		return err
+	}
+
+	obj := classes.ToObject(vm)
+	for _, name := range []string{"DOMException", "Event", "EventTarget", "AbortSignal", "AbortController"} {
+		if err := runtime.SetGlobal(name, obj.Get(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
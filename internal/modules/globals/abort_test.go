@@ -0,0 +1,85 @@
+package globals_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestAbortSignal(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	value, err := rt.RunScriptAsync("abort", `
+		(async () => {
+			const results = [];
+			const controller = new AbortController();
+			const order = [];
+			controller.signal.onabort = () => order.push('onabort');
+			controller.signal.addEventListener('abort', (event) => order.push(event.type + ':' + controller.signal.aborted));
+			controller.abort();
+			controller.abort('again');
+			results.push(order.join(',') + ' ' + controller.signal.reason.name + ' ' + controller.signal.reason.code);
+			try {
+				controller.signal.throwIfAborted();
+			} catch (err) {
+				results.push(err instanceof DOMException);
+			}
+			results.push(AbortSignal.any([new AbortController().signal, AbortSignal.abort('why')]).reason);
+
+			const { setTimeout: sleep } = require('timers/promises');
+			results.push(await sleep(1, 'slept'));
+			const timeout = AbortSignal.timeout(5);
+			results.push(await sleep(5000, 'late', { signal: timeout }).catch(err => err.name));
+
+			const slow = new AbortController();
+			const pending = fetch(`+strconv.Quote(upstream.URL)+`, { signal: slow.signal });
+			setTimeout(() => slow.abort(new Error('gave up')), 5);
+			results.push(await pending.catch(err => err.message));
+
+			const { Readable, addAbortSignal } = require('stream');
+			const streamController = new AbortController();
+			const stream = addAbortSignal(streamController.signal, new Readable());
+			const streamError = new Promise(resolve => stream.on('error', err => resolve(err.name)));
+			streamController.abort();
+			results.push(await streamError);
+
+			const { $ } = require('gode:shell');
+			results.push(await $({ signal: AbortSignal.timeout(20) })`+"`sleep 5`"+`.catch(err => err.name));
+			return results.join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"onabort,abort:true AbortError 20",
+		"true",
+		"why",
+		"slept",
+		"TimeoutError",
+		"gave up",
+		"AbortError",
+		"TimeoutError",
+	}, "|")
+	if value != want {
+		t.Errorf("abort results = %v, want %s", value, want)
+	}
+}
@@ -201,6 +201,11 @@ func (b *Buffer) ToString(encoding ...string) string {
 	}
 }
 
+// Bytes returns the contents of the buffer, without copying them
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
 // Length returns the buffer length
 func (b *Buffer) Length() int {
 	return len(b.data)
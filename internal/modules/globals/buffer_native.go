@@ -0,0 +1,229 @@
+package globals
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// nativeBufferSetup builds the Buffer selected with "compat": {"buffer":
+// "native"}. Instances are Uint8Arrays whose prototype is Buffer.prototype,
+// as in Node, so buf.length is a property and buffers can be passed anywhere
+// a typed array is accepted. Encoding is done in Go by the codec argument.
+const nativeBufferSetup = `
+	(function(codec) {
+		function Buffer(arg, encodingOrOffset, length) {
+			if (typeof arg === 'number') {
+				return Buffer.alloc(arg);
+			}
+			return Buffer.from(arg, encodingOrOffset, length);
+		}
+		
+		Buffer.prototype = Object.create(Uint8Array.prototype, {
+			constructor: { value: Buffer, writable: true, configurable: true }
+		});
+		
+		function wrap(bytes) {
+			Object.setPrototypeOf(bytes, Buffer.prototype);
+			return bytes;
+		}
+		
+		function toBytes(value, encoding) {
+			if (typeof value === 'string') {
+				return new Uint8Array(codec.encode(value, encoding || 'utf8'));
+			}
+			if (typeof value === 'number') {
+				return new Uint8Array([value & 255]);
+			}
+			return value;
+		}
+		
+		// Static methods
+		Buffer.alloc = function(size, fill, encoding) {
+			var buf = wrap(new Uint8Array(size));
+			if (fill !== undefined && fill !== 0) {
+				buf.fill(fill, 0, size, encoding);
+			}
+			return buf;
+		};
+		
+		Buffer.allocUnsafe = function(size) {
+			return wrap(new Uint8Array(size));
+		};
+		
+		Buffer.allocUnsafeSlow = Buffer.allocUnsafe;
+		
+		Buffer.from = function(value, encodingOrOffset, length) {
+			if (typeof value === 'string') {
+				return wrap(toBytes(value, encodingOrOffset));
+			}
+			if (value instanceof ArrayBuffer) {
+				// Shares memory with the ArrayBuffer, like Node
+				var offset = encodingOrOffset || 0;
+				return wrap(new Uint8Array(value, offset, length === undefined ? value.byteLength - offset : length));
+			}
+			if (value && value.type === 'Buffer' && Array.isArray(value.data)) {
+				value = value.data;
+			}
+			return wrap(new Uint8Array(value));
+		};
+		
+		Buffer.concat = function(list, totalLength) {
+			if (totalLength === undefined) {
+				totalLength = 0;
+				for (var i = 0; i < list.length; i++) {
+					totalLength += list[i].length;
+				}
+			}
+			var result = Buffer.alloc(totalLength);
+			var pos = 0;
+			for (var j = 0; j < list.length && pos < totalLength; j++) {
+				var item = list[j].subarray(0, totalLength - pos);
+				result.set(item, pos);
+				pos += item.length;
+			}
+			return result;
+		};
+		
+		Buffer.isBuffer = function(obj) {
+			return obj instanceof Buffer;
+		};
+		
+		Buffer.byteLength = function(value, encoding) {
+			if (typeof value === 'string') {
+				return codec.byteLength(value, encoding || 'utf8');
+			}
+			return value.byteLength;
+		};
+		
+		Buffer.poolSize = 8192;
+		
+		// Instance methods
+		var proto = Buffer.prototype;
+		
+		proto.toString = function(encoding, start, end) {
+			return codec.decode(this.subarray(start || 0, end === undefined ? this.length : end), encoding || 'utf8');
+		};
+		
+		proto.toJSON = function() {
+			return { type: 'Buffer', data: Array.prototype.slice.call(this) };
+		};
+		
+		proto.subarray = function(start, end) {
+			return wrap(Uint8Array.prototype.subarray.call(this, start, end));
+		};
+		
+		// Like Node, slice shares memory instead of copying
+		proto.slice = proto.subarray;
+		
+		proto.fill = function(value, start, end, encoding) {
+			if (typeof start === 'string') {
+				encoding = start;
+				start = undefined;
+			}
+			start = start === undefined ? 0 : start;
+			end = end === undefined ? this.length : end;
+			var bytes = toBytes(value, encoding);
+			if (typeof bytes === 'number' || bytes.length === 1) {
+				Uint8Array.prototype.fill.call(this, typeof bytes === 'number' ? bytes : bytes[0], start, end);
+				return this;
+			}
+			for (var i = start; i < end && bytes.length > 0; i++) {
+				this[i] = bytes[(i - start) % bytes.length];
+			}
+			return this;
+		};
+		
+		proto.copy = function(target, targetStart, sourceStart, sourceEnd) {
+			targetStart = targetStart || 0;
+			sourceStart = sourceStart || 0;
+			sourceEnd = sourceEnd === undefined ? this.length : sourceEnd;
+			var chunk = this.subarray(sourceStart, Math.min(sourceEnd, sourceStart + target.length - targetStart));
+			target.set(chunk, targetStart);
+			return chunk.length;
+		};
+		
+		proto.indexOf = function(value, byteOffset, encoding) {
+			var needle = toBytes(value, encoding);
+			var from = byteOffset || 0;
+			if (from < 0) {
+				from = Math.max(this.length + from, 0);
+			}
+			outer:
+			for (var i = from; i <= this.length - needle.length; i++) {
+				for (var j = 0; j < needle.length; j++) {
+					if (this[i + j] !== needle[j]) {
+						continue outer;
+					}
+				}
+				return i;
+			}
+			return -1;
+		};
+		
+		proto.includes = function(value, byteOffset, encoding) {
+			return this.indexOf(value, byteOffset, encoding) !== -1;
+		};
+		
+		proto.equals = function(other) {
+			if (this.length !== other.length) {
+				return false;
+			}
+			for (var i = 0; i < this.length; i++) {
+				if (this[i] !== other[i]) {
+					return false;
+				}
+			}
+			return true;
+		};
+		
+		proto.write = function(string, offset, encoding) {
+			if (typeof offset === 'string') {
+				encoding = offset;
+				offset = 0;
+			}
+			offset = offset || 0;
+			var bytes = toBytes(string, encoding).subarray(0, this.length - offset);
+			this.set(bytes, offset);
+			return bytes.length;
+		};
+		
+		return Buffer;
+	})
+`
+
+// nativeBufferCodec converts between strings and bytes for the native Buffer
+func nativeBufferCodec(vm *goja.Runtime) *goja.Object {
+	bc := &BufferConstructor{}
+	codec := vm.NewObject()
+	codec.Set("encode", func(str, encoding string) goja.ArrayBuffer {
+		buf, err := bc.fromString(str, encoding)
+		if err != nil {
+			panic(vm.NewTypeError("Invalid buffer input: " + err.Error()))
+		}
+		return vm.NewArrayBuffer(buf.data)
+	})
+	codec.Set("decode", func(value goja.Value, encoding string) string {
+		var data []byte
+		if err := vm.ExportTo(value, &data); err != nil {
+			panic(vm.NewTypeError("expected a Buffer"))
+		}
+		return (&Buffer{data: data}).ToString(encoding)
+	})
+	codec.Set("byteLength", bc.ByteLength)
+	return codec
+}
+
+// newNativeBuffer creates the Uint8Array based Buffer constructor
+func newNativeBuffer(vm *goja.Runtime) (goja.Value, error) {
+	factory, err := jsprogram.Run(vm, "native-buffer-setup", nativeBufferSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(factory)
+	if !ok {
+		return nil, fmt.Errorf("native Buffer setup is not a function")
+	}
+	return build(goja.Undefined(), nativeBufferCodec(vm))
+}
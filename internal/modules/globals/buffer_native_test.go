@@ -0,0 +1,44 @@
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/modules/globals"
+	"github.com/rizqme/gode/internal/runtime"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestCompatNativeBuffer(t *testing.T) {
+	t.Setenv(globals.EnvNoWarnings, "1")
+
+	rt := runtime.New()
+	defer rt.Dispose()
+	cfg := &config.PackageJSON{
+		Name: "compat",
+		Gode: config.GodeConfig{Compat: map[string]string{"buffer": "native", "future": "x"}},
+	}
+	if err := rt.Configure(cfg); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	source := `
+		var buf = Buffer.from('hello');
+		[
+			process.compat.buffer,
+			buf instanceof Uint8Array,
+			buf.length,
+			buf.slice(1, 3).toString(),
+			buf.toString('hex'),
+			Buffer.concat([buf, Buffer.from([33])]).toString(),
+			buf.indexOf('llo'),
+			Buffer.isBuffer(new Uint8Array(1)),
+		].join(',');
+	`
+	result, err := rt.RunScript("compat", source)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if want := "native,true,5,el,68656c6c6f,hello!,2,false"; result != want {
+		t.Errorf("Expected %q, got %v", want, result)
+	}
+}
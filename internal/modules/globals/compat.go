@@ -0,0 +1,78 @@
+package globals
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// compatProvider is implemented by runtimes configured from a package.json,
+// returning the "gode": {"compat": {...}} selections
+type compatProvider interface {
+	Compat() map[string]string
+}
+
+// compatFeature describes a built-in whose behaviour can change between
+// gode releases. Projects pin a level in package.json so that a breaking
+// improvement becomes opt-in first and the default only moves once the
+// old level has been deprecated for a while.
+type compatFeature struct {
+	// levels lists the accepted values; the first one is the default
+	levels []string
+	// deprecated maps levels that are going away to a migration hint
+	deprecated map[string]string
+}
+
+var compatFeatures = map[string]compatFeature{
+	// wrapper: Buffer is a plain object around a Go buffer, buf.length() is
+	// a method and the capitalized Go methods are still reachable.
+	// native: Buffer is a Uint8Array subclass like in Node.
+	"buffer": {levels: []string{"wrapper", "native"}},
+}
+
+// resolveCompat picks a level for every known feature. Unknown features and
+// levels fall back to the default with a warning rather than failing, so a
+// package.json written for a newer gode still runs.
+func resolveCompat(selected map[string]string, warn *warnings) map[string]string {
+	levels := make(map[string]string, len(compatFeatures))
+	for name, feature := range compatFeatures {
+		levels[name] = feature.levels[0]
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		level := selected[name]
+		feature, ok := compatFeatures[name]
+		if !ok {
+			warn.warn("CompatWarning", "GODE_COMPAT_UNKNOWN",
+				fmt.Sprintf("Unknown gode.compat feature %q is ignored", name))
+			continue
+		}
+		if !feature.supports(level) {
+			warn.warn("CompatWarning", "GODE_COMPAT_LEVEL",
+				fmt.Sprintf("gode.compat.%s does not support %q (expected one of %s), using %q",
+					name, level, strings.Join(feature.levels, ", "), feature.levels[0]))
+			continue
+		}
+		if hint, ok := feature.deprecated[level]; ok {
+			warn.warn("DeprecationWarning", "GODE_COMPAT_"+strings.ToUpper(name),
+				fmt.Sprintf("gode.compat.%s %q is deprecated. %s", name, level, hint))
+		}
+		levels[name] = level
+	}
+	return levels
+}
+
+func (f compatFeature) supports(level string) bool {
+	for _, l := range f.levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
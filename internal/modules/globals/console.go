@@ -2,80 +2,108 @@ package globals
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/rizqme/gode/goja"
 )
 
 // Console provides enhanced console logging functionality
 type Console struct {
 	mu         sync.Mutex
+	vm         *goja.Runtime
+	stdout     consoleOutput
+	stderr     consoleOutput
 	timers     map[string]time.Time
 	counters   map[string]int
 	groupLevel int
+	stack      func() string // the JS call stack, for console.trace
+}
+
+// consoleOutput is a stream the console writes to
+type consoleOutput struct {
+	w      io.Writer
+	colors bool // whether inspected values are styled
 }
 
-// NewConsole creates a new console instance
-func NewConsole() *Console {
+// NewConsole creates a new console instance formatting values of vm.
+// Values are colored on terminals unless NO_COLOR is set.
+func NewConsole(vm *goja.Runtime) *Console {
 	return &Console{
+		vm:       vm,
+		stdout:   newConsoleOutput(os.Stdout),
+		stderr:   newConsoleOutput(os.Stderr),
 		timers:   make(map[string]time.Time),
 		counters: make(map[string]int),
 	}
 }
 
+func newConsoleOutput(f *os.File) consoleOutput {
+	return consoleOutput{w: f, colors: isTerminal(f) && os.Getenv("NO_COLOR") == ""}
+}
+
 // Helper method for indentation
 func (c *Console) indent() string {
 	return strings.Repeat("  ", c.groupLevel)
 }
 
+// format formats args as console.log does for out
+func (c *Console) format(out consoleOutput, args []goja.Value) string {
+	return Format(c.vm, args, InspectOptions{Colors: out.colors})
+}
+
+// write writes text as a line to out, indenting each of its lines to the
+// current group
+func (c *Console) write(out consoleOutput, text string) {
+	if indent := c.indent(); indent != "" {
+		text = indent + strings.ReplaceAll(text, "\n", "\n"+indent)
+	}
+	fmt.Fprintln(out.w, text)
+}
+
 // Log outputs to stdout
-func (c *Console) Log(args ...interface{}) {
+func (c *Console) Log(args ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	fmt.Print(c.indent())
-	fmt.Println(args...)
+	c.write(c.stdout, c.format(c.stdout, args))
 }
 
 // Error outputs to stderr
-func (c *Console) Error(args ...interface{}) {
+func (c *Console) Error(args ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	fmt.Fprint(os.Stderr, c.indent())
-	fmt.Fprintln(os.Stderr, args...)
+	c.write(c.stderr, c.format(c.stderr, args))
 }
 
 // Info is an alias for log
-func (c *Console) Info(args ...interface{}) {
+func (c *Console) Info(args ...goja.Value) {
 	c.Log(args...)
 }
 
 // Warn outputs to stderr with a warning prefix
-func (c *Console) Warn(args ...interface{}) {
+func (c *Console) Warn(args ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	fmt.Fprint(os.Stderr, c.indent())
-	fmt.Fprint(os.Stderr, "Warning: ")
-	fmt.Fprintln(os.Stderr, args...)
+	c.write(c.stderr, "Warning: "+c.format(c.stderr, args))
 }
 
 // Debug outputs debug information
-func (c *Console) Debug(args ...interface{}) {
+func (c *Console) Debug(args ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	fmt.Print(c.indent())
-	fmt.Print("Debug: ")
-	fmt.Println(args...)
+	c.write(c.stdout, "Debug: "+c.format(c.stdout, args))
 }
 
 // Table outputs data in a table format
-func (c *Console) Table(data interface{}) {
+func (c *Console) Table(data goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
 	// Simple implementation - just pretty print the data
-	fmt.Print(c.indent())
-	fmt.Printf("%+v\n", data)
+	c.write(c.stdout, Inspect(c.vm, data, InspectOptions{Colors: c.stdout.colors}))
 }
 
 // Time starts a timer with the given label
@@ -100,15 +128,15 @@ func (c *Console) TimeEnd(label string) {
 	
 	if start, exists := c.timers[label]; exists {
 		elapsed := time.Since(start)
-		fmt.Printf("%s%s: %v\n", c.indent(), label, elapsed)
+		c.write(c.stdout, fmt.Sprintf("%s: %v", label, elapsed))
 		delete(c.timers, label)
 	} else {
-		fmt.Printf("%sTimer '%s' does not exist\n", c.indent(), label)
+		c.write(c.stdout, fmt.Sprintf("Timer '%s' does not exist", label))
 	}
 }
 
 // TimeLog logs the current elapsed time for a timer
-func (c *Console) TimeLog(label string, args ...interface{}) {
+func (c *Console) TimeLog(label string, args ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
@@ -118,32 +146,29 @@ func (c *Console) TimeLog(label string, args ...interface{}) {
 	
 	if start, exists := c.timers[label]; exists {
 		elapsed := time.Since(start)
-		fmt.Printf("%s%s: %v", c.indent(), label, elapsed)
+		text := fmt.Sprintf("%s: %v", label, elapsed)
 		if len(args) > 0 {
-			fmt.Print(" ")
-			fmt.Println(args...)
-		} else {
-			fmt.Println()
+			text += " " + c.format(c.stdout, args)
 		}
+		c.write(c.stdout, text)
 	} else {
-		fmt.Printf("%sTimer '%s' does not exist\n", c.indent(), label)
+		c.write(c.stdout, fmt.Sprintf("Timer '%s' does not exist", label))
 	}
 }
 
 // Group increases the indentation level
-func (c *Console) Group(label ...interface{}) {
+func (c *Console) Group(label ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
 	if len(label) > 0 {
-		fmt.Print(c.indent())
-		fmt.Println(label...)
+		c.write(c.stdout, c.format(c.stdout, label))
 	}
 	c.groupLevel++
 }
 
 // GroupCollapsed is the same as group (collapsed state not applicable in terminal)
-func (c *Console) GroupCollapsed(label ...interface{}) {
+func (c *Console) GroupCollapsed(label ...goja.Value) {
 	c.Group(label...)
 }
 
@@ -158,18 +183,12 @@ func (c *Console) GroupEnd() {
 }
 
 // Assert logs an error if the assertion is false
-func (c *Console) Assert(condition bool, args ...interface{}) {
+func (c *Console) Assert(condition bool, args ...goja.Value) {
 	if !condition {
 		c.mu.Lock()
 		defer c.mu.Unlock()
 		
-		fmt.Fprint(os.Stderr, c.indent())
-		fmt.Fprint(os.Stderr, "Assertion failed: ")
-		if len(args) > 0 {
-			fmt.Fprintln(os.Stderr, args...)
-		} else {
-			fmt.Fprintln(os.Stderr)
-		}
+		c.write(c.stderr, "Assertion failed: "+c.format(c.stderr, args))
 	}
 }
 
@@ -183,7 +202,7 @@ func (c *Console) Count(label string) {
 	}
 	
 	c.counters[label]++
-	fmt.Printf("%s%s: %d\n", c.indent(), label, c.counters[label])
+	c.write(c.stdout, fmt.Sprintf("%s: %d", label, c.counters[label]))
 }
 
 // CountReset resets the counter for the given label
@@ -198,41 +217,47 @@ func (c *Console) CountReset(label string) {
 	delete(c.counters, label)
 }
 
-// Dir displays an object's properties (simplified version)
-func (c *Console) Dir(obj interface{}, options ...interface{}) {
+// Dir inspects obj, taking the depth and colors options of
+// util.inspect; a depth of null or Infinity shows every level
+func (c *Console) Dir(obj goja.Value, options goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	fmt.Print(c.indent())
-	fmt.Printf("%+v\n", obj)
+	opts := InspectOptions{Colors: c.stdout.colors}.withDefaults()
+	if o, ok := options.(*goja.Object); ok {
+		if depth := o.Get("depth"); depth != nil && !goja.IsUndefined(depth) {
+			if n := depth.ToFloat(); goja.IsNull(depth) || n > float64(1<<30) {
+				opts.Depth = -1
+			} else {
+				opts.Depth = int(n)
+			}
+		}
+		if colors := o.Get("colors"); colors != nil && !goja.IsUndefined(colors) {
+			opts.Colors = colors.ToBoolean()
+		}
+	}
+	c.write(c.stdout, newInspector(c.vm, opts).format(obj, 0, 0))
 }
 
 // DirXML is an alias for dir
-func (c *Console) DirXML(obj interface{}) {
-	c.Dir(obj)
+func (c *Console) DirXML(obj goja.Value) {
+	c.Dir(obj, nil)
 }
 
-// Trace outputs a stack trace
-func (c *Console) Trace(args ...interface{}) {
+// Trace outputs its arguments followed by the JavaScript stack trace
+func (c *Console) Trace(args ...goja.Value) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	fmt.Fprint(os.Stderr, c.indent())
-	fmt.Fprint(os.Stderr, "Trace: ")
-	if len(args) > 0 {
-		fmt.Fprintln(os.Stderr, args...)
-	} else {
-		fmt.Fprintln(os.Stderr)
+	c.write(c.stderr, "Trace: "+c.format(c.stderr, args))
+	if c.stack != nil {
+		c.write(c.stderr, c.stack())
 	}
-	
-	// In a real implementation, we would print the JavaScript stack trace
-	// For now, just indicate where trace was called
-	fmt.Fprintln(os.Stderr, c.indent(), "    at <JavaScript stack trace>")
 }
 
 // Clear would clear the console (not applicable in most terminals)
 func (c *Console) Clear() {
 	// In a terminal environment, we could use ANSI escape codes
 	// For now, just print some newlines
-	fmt.Print("\n\n\n")
+	fmt.Fprint(c.stdout.w, "\n\n\n")
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package globals
+
+import (
+	"sort"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
+)
+
+// envObject backs process.env so every variable read goes through the
+// runtime's permission check (and audit log, when enabled)
+type envObject struct {
+	vm      *goja.Runtime
+	vars    map[string]string
+	checker permissions.Enforcer
+}
+
+// newEnvObject wraps vars as a JS object; without a checker the plain map is used
+func newEnvObject(runtime RuntimeInterface, vars map[string]string) interface{} {
+	checker, ok := runtime.(permissions.Enforcer)
+	if !ok {
+		return vars
+	}
+
+	vm := runtime.GetRuntime()
+	return vm.NewDynamicObject(&envObject{vm: vm, vars: vars, checker: checker})
+}
+
+// Get returns the variable after checking env permission. Variables that
+// are not set are checked too, so that probing for them is audited and
+// cannot tell a denied variable from a missing one.
+func (e *envObject) Get(key string) goja.Value {
+	e.check(key)
+	value, exists := e.vars[key]
+	if !exists {
+		return goja.Undefined()
+	}
+	return e.vm.ToValue(value)
+}
+
+// Set stores the value as a string, like Node.js does
+func (e *envObject) Set(key string, val goja.Value) bool {
+	e.vars[key] = val.String()
+	return true
+}
+
+// Has reports whether the variable is defined, after checking env
+// permission for it
+func (e *envObject) Has(key string) bool {
+	e.check(key)
+	_, exists := e.vars[key]
+	return exists
+}
+
+// Delete removes the variable
+func (e *envObject) Delete(key string) bool {
+	delete(e.vars, key)
+	return true
+}
+
+// Keys returns the variable names in sorted order. Listing them, as
+// Object.keys(process.env) or {...process.env} do, needs env permission for
+// "*", which only allow-env: ["*"] or no allow-env at all grant.
+func (e *envObject) Keys() []string {
+	e.check("*")
+	keys := make([]string, 0, len(e.vars))
+	for key := range e.vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// check throws a PermissionDenied error unless the runtime allows access
+// to the variable name
+func (e *envObject) check(name string) {
+	if err := e.checker.CheckPermission("env", name); err != nil {
+		panic(jserror.New(e.vm, err))
+	}
+}
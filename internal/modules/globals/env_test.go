@@ -0,0 +1,56 @@
+package globals
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// recordingChecker allows the names in allowed and records every check
+type recordingChecker struct {
+	allowed map[string]bool
+	checked []string
+}
+
+func (c *recordingChecker) CheckPermission(kind, resource string) error {
+	c.checked = append(c.checked, kind+":"+resource)
+	if !c.allowed[resource] {
+		return errors.New("permission denied: env access to " + resource)
+	}
+	return nil
+}
+
+func TestEnvChecksEveryAccess(t *testing.T) {
+	vm := goja.New()
+	checker := &recordingChecker{allowed: map[string]bool{"HOME": true, "MISSING": true}}
+	env := &envObject{vm: vm, vars: map[string]string{"HOME": "/home/me", "SECRET": "x"}, checker: checker}
+	vm.Set("env", vm.NewDynamicObject(env))
+
+	run := func(script string) (string, error) {
+		value, err := vm.RunString(script)
+		if err != nil {
+			return "", err
+		}
+		return value.String(), nil
+	}
+
+	if value, err := run("env.HOME"); err != nil || value != "/home/me" {
+		t.Errorf("env.HOME = %q, %v", value, err)
+	}
+	if value, err := run("String(env.MISSING)"); err != nil || value != "undefined" {
+		t.Errorf("env.MISSING = %q, %v", value, err)
+	}
+	// A denied variable throws whether it is set or not
+	for _, script := range []string{"env.SECRET", "env.UNSET", "'SECRET' in env", "Object.keys(env)"} {
+		if _, err := run(script); err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Errorf("%s: err = %v, want a permission error", script, err)
+		}
+	}
+
+	want := []string{"env:HOME", "env:MISSING", "env:SECRET", "env:UNSET", "env:SECRET", "env:*"}
+	if strings.Join(checker.checked, " ") != strings.Join(want, " ") {
+		t.Errorf("checked %v, want %v", checker.checked, want)
+	}
+}
@@ -0,0 +1,69 @@
+package globals
+
+import (
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// errorsSetup provides AggregateError and Promise.any where the engine lacks
+// them, and makes sure AggregateError honours the {cause} options bag
+const errorsSetup = `
+(function () {
+	if (typeof AggregateError !== 'function') {
+		class AggregateError extends Error {
+			constructor(errors, message, options) {
+				super(message === undefined ? '' : String(message));
+				Object.defineProperty(this, 'errors', {
+					value: Array.from(errors), writable: true, configurable: true
+				});
+				if (options !== null && typeof options === 'object' && 'cause' in options && !('cause' in this)) {
+					Object.defineProperty(this, 'cause', {
+						value: options.cause, writable: true, configurable: true
+					});
+				}
+			}
+		}
+		Object.defineProperty(AggregateError.prototype, 'name', {
+			value: 'AggregateError', writable: true, configurable: true
+		});
+		Object.defineProperty(globalThis, 'AggregateError', {
+			value: AggregateError, writable: true, configurable: true
+		});
+	}
+
+	if (typeof Promise.any !== 'function') {
+		Object.defineProperty(Promise, 'any', {
+			value: function any(iterable) {
+				var C = this;
+				return new C(function (resolve, reject) {
+					var errors = [];
+					var pending = 1;
+					var done = function () {
+						if (--pending === 0) {
+							reject(new AggregateError(errors, 'All promises were rejected'));
+						}
+					};
+					var index = 0;
+					for (var item of iterable) {
+						(function (i) {
+							pending++;
+							C.resolve(item).then(resolve, function (reason) {
+								errors[i] = reason;
+								done();
+							});
+						})(index++);
+					}
+					done();
+				});
+			},
+			writable: true, configurable: true
+		});
+	}
+})()
+`
+
+// installErrors fills in the error built-ins the engine is missing
+func installErrors(vm *goja.Runtime) error {
+	_, err := jsprogram.Run(vm, "errors-setup", errorsSetup)
+	return err
+}
@@ -0,0 +1,148 @@
+package globals
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Format formats the arguments of console.log the way Node's util.format
+// does. A leading string may contain %s, %d, %i, %f, %j, %o, %O and %c
+// specifiers, each taking the next argument; the arguments left are
+// appended separated by spaces, strings as they are and other values
+// inspected. It must be called on the JS thread.
+func Format(vm *goja.Runtime, args []goja.Value, opts InspectOptions) string {
+	in := newInspector(vm, opts.withDefaults())
+	var b strings.Builder
+	if len(args) > 0 {
+		if _, isObject := args[0].(*goja.Object); !isObject {
+			if format, ok := args[0].Export().(string); ok && len(args) > 1 {
+				args = in.printf(&b, format, args[1:])
+			}
+		}
+	}
+	for _, arg := range args {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(in.formatArg(arg))
+	}
+	return b.String()
+}
+
+// formatArg formats an argument left after the specifiers
+func (in *inspector) formatArg(arg goja.Value) string {
+	if _, isObject := arg.(*goja.Object); !isObject {
+		if s, ok := arg.Export().(string); ok {
+			return s
+		}
+	}
+	return in.format(arg, 0, 0)
+}
+
+// printf writes format to b with its specifiers replaced by args, and
+// returns the arguments left over. Specifiers without an argument are
+// written as they are.
+func (in *inspector) printf(b *strings.Builder, format string, args []goja.Value) []goja.Value {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		verb := format[i+1]
+		if verb == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+		if !strings.ContainsRune("sdifjoOc", rune(verb)) || len(args) == 0 {
+			b.WriteByte('%')
+			continue
+		}
+		b.WriteString(in.specifier(verb, args[0]))
+		args = args[1:]
+		i++
+	}
+	return args
+}
+
+// specifier formats arg for the specifier verb
+func (in *inspector) specifier(verb byte, arg goja.Value) string {
+	plain := newInspector(in.vm, in.opts)
+	plain.opts.Colors = false
+	_, isSymbol := arg.(*goja.Symbol)
+	bigint, isBigInt := arg.Export().(*big.Int)
+
+	switch verb {
+	case 's':
+		switch {
+		case isBigInt:
+			return bigint.String() + "n"
+		case isSymbol:
+			return plain.primitive(arg)
+		}
+		if obj, ok := arg.(*goja.Object); ok {
+			if _, isFunction := goja.AssertFunction(obj); !isFunction && !hasOwnToString(obj) {
+				plain.opts.Depth = 0
+				return plain.format(arg, 0, 0)
+			}
+			return arg.String()
+		}
+		if f, ok := arg.Export().(float64); ok && f == 0 {
+			return plain.primitive(arg) // -0
+		}
+		return arg.String()
+	case 'd', 'i', 'f':
+		switch {
+		case isBigInt && verb != 'f':
+			return bigint.String() + "n"
+		case isSymbol:
+			return "NaN"
+		case verb == 'd':
+			return plain.primitive(in.vm.ToValue(arg.ToNumber()))
+		}
+		parse := map[byte]string{'i': "parseInt", 'f': "parseFloat"}[verb]
+		fn, _ := goja.AssertFunction(in.vm.Get(parse))
+		n, err := fn(goja.Undefined(), in.vm.ToValue(arg.String()))
+		if err != nil {
+			panic(err)
+		}
+		return plain.primitive(n)
+	case 'j':
+		return in.json(arg)
+	case 'o':
+		full := newInspector(in.vm, in.opts)
+		full.opts.Depth = 4
+		return full.format(arg, 0, 0)
+	case 'O':
+		return in.format(arg, 0, 0)
+	}
+	return "" // %c takes CSS, which a terminal ignores
+}
+
+// json formats arg with JSON.stringify, as [Circular] if it is cyclic
+func (in *inspector) json(arg goja.Value) string {
+	stringify, _ := goja.AssertFunction(in.vm.Get("JSON").ToObject(in.vm).Get("stringify"))
+	result, err := stringify(goja.Undefined(), arg)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "circular") {
+			return "[Circular]"
+		}
+		panic(err)
+	}
+	return result.String()
+}
+
+// hasOwnToString reports whether obj has a toString other than a built-in
+// one, which %s calls instead of inspecting it
+func hasOwnToString(obj *goja.Object) bool {
+	fn, ok := obj.Get("toString").(*goja.Object)
+	if !ok {
+		return false
+	}
+	if _, ok := goja.AssertFunction(fn); !ok {
+		return false
+	}
+	return !strings.Contains(fn.String(), "[native code]")
+}
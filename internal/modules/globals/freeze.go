@@ -0,0 +1,121 @@
+package globals
+
+import (
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// freezeSetup freezes every object reachable from the intrinsics: the
+// constructors and namespaces of the language and of gode's web globals,
+// their prototypes, properties and accessors, and the hidden intrinsics
+// such as the iterator and generator prototypes. globalThis itself,
+// process, console and the module globals stay writable, as does
+// Error.stackTraceLimit.
+const freezeSetup = `
+(function () {
+	var names = [
+		'Object', 'Function', 'Array', 'Number', 'Boolean', 'String', 'Symbol', 'BigInt',
+		'Date', 'RegExp', 'Error', 'EvalError', 'RangeError', 'ReferenceError', 'SyntaxError',
+		'TypeError', 'URIError', 'AggregateError', 'Promise', 'Proxy', 'Reflect', 'Math', 'JSON',
+		'Map', 'Set', 'WeakMap', 'WeakSet', 'WeakRef', 'FinalizationRegistry',
+		'ArrayBuffer', 'SharedArrayBuffer', 'DataView', 'Atomics',
+		'Int8Array', 'Uint8Array', 'Uint8ClampedArray', 'Int16Array', 'Uint16Array',
+		'Int32Array', 'Uint32Array', 'Float32Array', 'Float64Array', 'BigInt64Array', 'BigUint64Array',
+		'escape', 'unescape', 'eval', 'isFinite', 'isNaN', 'parseFloat', 'parseInt',
+		'decodeURI', 'decodeURIComponent', 'encodeURI', 'encodeURIComponent',
+		'Buffer', 'URL', 'URLSearchParams', 'TextEncoder', 'TextDecoder',
+		'atob', 'btoa', 'structuredClone', 'queueMicrotask', 'Blob', 'Worker', 'ShadowRealm',
+		'DOMException', 'Event', 'EventTarget', 'AbortSignal', 'AbortController',
+		'ReadableStream', 'ReadableStreamDefaultReader', 'ReadableStreamDefaultController',
+		'Headers', 'Request', 'Response', 'FormData', 'File', 'AsyncContext'
+	];
+	var roots = [];
+	names.forEach(function (name) {
+		if (globalThis[name] !== undefined) {
+			roots.push(globalThis[name]);
+		}
+	});
+
+	// Intrinsics only reachable through instances
+	var hidden = [
+		'return [][Symbol.iterator]()',
+		'return new Map()[Symbol.iterator]()',
+		'return new Set()[Symbol.iterator]()',
+		'return ""[Symbol.iterator]()',
+		'return /x/[Symbol.matchAll]("x")',
+		'return function* () {}',
+		'return (function* () {})()',
+		'return async function () {}',
+		'return async function* () {}',
+		'return (async function* () {})()'
+	];
+	hidden.forEach(function (source) {
+		try {
+			roots.push(Object.getPrototypeOf(new Function(source)()));
+		} catch (e) {
+			// Not supported by the engine
+		}
+	});
+
+	var writable = new Map([[Error, ['stackTraceLimit']]]);
+	var seen = new Set();
+	var pending = roots.slice();
+	var visit = function (value) {
+		if ((typeof value === 'object' && value !== null) || typeof value === 'function') {
+			pending.push(value);
+		}
+	};
+
+	while (pending.length > 0) {
+		var obj = pending.pop();
+		if (seen.has(obj)) {
+			continue;
+		}
+		seen.add(obj);
+
+		visit(Object.getPrototypeOf(obj));
+		Reflect.ownKeys(obj).forEach(function (key) {
+			var desc = Object.getOwnPropertyDescriptor(obj, key);
+			if (!desc) {
+				return;
+			}
+			if ('value' in desc) {
+				visit(desc.value);
+			} else {
+				visit(desc.get);
+				visit(desc.set);
+			}
+		});
+
+		try {
+			var keep = writable.get(obj);
+			if (!keep) {
+				Object.freeze(obj);
+				continue;
+			}
+			Object.preventExtensions(obj);
+			Reflect.ownKeys(obj).forEach(function (key) {
+				if (keep.indexOf(key) >= 0) {
+					return;
+				}
+				var desc = Object.getOwnPropertyDescriptor(obj, key);
+				if ('value' in desc) {
+					desc.writable = false;
+				}
+				desc.configurable = false;
+				Object.defineProperty(obj, key, desc);
+			});
+		} catch (e) {
+			// Host objects backed by Go values may refuse to be frozen
+		}
+	}
+})();
+`
+
+// FreezeIntrinsics freezes Object.prototype, Array.prototype and the other
+// intrinsics, so that code loaded later cannot pollute them. It must run on
+// the JS thread, after the built-ins are installed.
+func FreezeIntrinsics(vm *goja.Runtime) error {
+	_, err := jsprogram.Run(vm, "freeze-intrinsics", freezeSetup)
+	return err
+}
@@ -0,0 +1,415 @@
+package globals
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// InspectOptions controls how Inspect formats values
+type InspectOptions struct {
+	Depth       int  // nesting levels shown before [Object]; defaults to 2, negative for no limit
+	MaxArray    int  // array, Map and Set entries shown; defaults to 100
+	BreakLength int  // longer single-line results are split over lines; defaults to 72
+	Colors      bool // style values with ANSI escape codes, as in a terminal
+}
+
+// Inspect formats a value for humans the way Node's util.inspect does,
+// e.g. { a: 1, b: [ 'x', 'y' ] }. It must be called on the JS thread.
+func Inspect(vm *goja.Runtime, value goja.Value, opts InspectOptions) string {
+	return newInspector(vm, opts.withDefaults()).format(value, 0, 0)
+}
+
+// withDefaults fills in the options left zero
+func (opts InspectOptions) withDefaults() InspectOptions {
+	if opts.Depth == 0 {
+		opts.Depth = 2
+	}
+	if opts.MaxArray == 0 {
+		opts.MaxArray = 100
+	}
+	if opts.BreakLength == 0 {
+		opts.BreakLength = 72
+	}
+	return opts
+}
+
+func newInspector(vm *goja.Runtime, opts InspectOptions) *inspector {
+	return &inspector{vm: vm, opts: opts, seen: make(map[*goja.Object]bool)}
+}
+
+type inspector struct {
+	vm   *goja.Runtime
+	opts InspectOptions
+	seen map[*goja.Object]bool
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// maxBufferBytes is how many bytes of a Buffer are shown, as Node's
+// buffer.INSPECT_MAX_BYTES
+const maxBufferBytes = 50
+
+// inspectStyles are the ANSI codes turning each style on and off, the
+// colors Node uses
+var inspectStyles = map[string][2]int{
+	"special":   {36, 39}, // cyan
+	"number":    {33, 39}, // yellow
+	"bigint":    {33, 39},
+	"boolean":   {33, 39},
+	"undefined": {90, 39}, // grey
+	"null":      {1, 22},  // bold
+	"string":    {32, 39}, // green
+	"symbol":    {32, 39},
+	"date":      {35, 39}, // magenta
+	"regexp":    {31, 39}, // red
+}
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// stylize wraps text in the codes of style when colors are on
+func (in *inspector) stylize(text, style string) string {
+	codes, ok := inspectStyles[style]
+	if !in.opts.Colors || !ok {
+		return text
+	}
+	return fmt.Sprintf("\x1b[%dm%s\x1b[%dm", codes[0], text, codes[1])
+}
+
+// tooDeep reports whether values nested depth levels deep are summarized
+func (in *inspector) tooDeep(depth int) bool {
+	return in.opts.Depth >= 0 && depth > in.opts.Depth
+}
+
+func (in *inspector) format(value goja.Value, depth, indent int) string {
+	if value == nil || goja.IsUndefined(value) {
+		return in.stylize("undefined", "undefined")
+	}
+	if goja.IsNull(value) {
+		return in.stylize("null", "null")
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return in.primitive(value)
+	}
+
+	if fn, ok := goja.AssertFunction(obj); ok && fn != nil {
+		if name := obj.Get("name"); name != nil && name.String() != "" {
+			return in.stylize(fmt.Sprintf("[Function: %s]", name.String()), "special")
+		}
+		return in.stylize("[Function (anonymous)]", "special")
+	}
+
+	switch obj.ClassName() {
+	case "Date":
+		if s, err := in.call(obj, "toISOString"); err == nil {
+			return in.stylize(s, "date")
+		}
+		return in.stylize("Invalid Date", "date")
+	case "RegExp":
+		return in.stylize("/"+obj.Get("source").String()+"/"+obj.Get("flags").String(), "regexp")
+	case "Error":
+		return in.error(obj, depth, indent)
+	case "String", "Number", "Boolean":
+		plain := &inspector{vm: in.vm, opts: in.opts}
+		plain.opts.Colors = false
+		text := fmt.Sprintf("[%s: %s]", obj.ClassName(), plain.primitive(in.vm.ToValue(obj.Export())))
+		return in.stylize(text, strings.ToLower(obj.ClassName()))
+	}
+
+	if in.seen[obj] {
+		return in.stylize("[Circular]", "special")
+	}
+	in.seen[obj] = true
+	defer delete(in.seen, obj)
+
+	switch obj.ClassName() {
+	case "Array":
+		if in.tooDeep(depth) {
+			return in.stylize("[Array]", "special")
+		}
+		return in.array(obj, depth, indent)
+	case "Map", "Set":
+		if in.tooDeep(depth) {
+			return in.stylize("["+obj.ClassName()+"]", "special")
+		}
+		return in.collection(obj, depth, indent)
+	}
+
+	// The default Buffer wraps a Go one; the native one is a typed array
+	if v := obj.Get("_goBuf"); v != nil {
+		if buf, ok := v.Export().(*Buffer); ok {
+			return in.buffer(buf.data)
+		}
+	}
+	if buffer, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return in.arrayBuffer(buffer.Bytes())
+	}
+	if data, ok := typedArrayBytes(obj); ok {
+		name := in.constructorName(obj)
+		if name == "Buffer" {
+			return in.buffer(data)
+		}
+		if in.tooDeep(depth) {
+			return in.stylize("["+name+"]", "special")
+		}
+		return in.typedArray(obj, name, depth, indent)
+	}
+
+	if in.tooDeep(depth) {
+		return in.stylize("[Object]", "special")
+	}
+	return in.object(obj, depth, indent)
+}
+
+// error formats an Error as its stack, followed by its AggregateError
+// errors and its cause as Node does
+func (in *inspector) error(obj *goja.Object, depth, indent int) string {
+	text := obj.Get("name").String() + ": " + obj.Get("message").String()
+	if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+		text = stack.String()
+	}
+	if in.seen[obj] || in.tooDeep(depth) {
+		return text
+	}
+	in.seen[obj] = true
+	defer delete(in.seen, obj)
+
+	var extras []string
+	if errs, ok := obj.Get("errors").(*goja.Object); ok && errs.ClassName() == "Array" {
+		extras = append(extras, "[errors]: "+in.format(errs, depth+1, 0))
+	}
+	if cause := obj.Get("cause"); cause != nil && !goja.IsUndefined(cause) {
+		extras = append(extras, "[cause]: "+in.format(cause, depth+1, 0))
+	}
+	if len(extras) == 0 {
+		return text
+	}
+
+	// Nested values are formatted unindented and shifted here, so that the
+	// lines of a nested stack line up as well
+	pad := strings.Repeat(" ", indent+2)
+	for i, extra := range extras {
+		extras[i] = pad + strings.ReplaceAll(extra, "\n", "\n"+pad)
+	}
+	return text + " {\n" + strings.Join(extras, ",\n") + "\n" + strings.Repeat(" ", indent) + "}"
+}
+
+func (in *inspector) primitive(value goja.Value) string {
+	if sym, ok := value.(*goja.Symbol); ok {
+		return in.stylize("Symbol("+sym.String()+")", "symbol")
+	}
+	switch v := value.Export().(type) {
+	case string:
+		return in.stylize(quote(v), "string")
+	case bool:
+		return in.stylize(value.String(), "boolean")
+	case *big.Int:
+		return in.stylize(v.String()+"n", "bigint")
+	case float64:
+		if v == 0 && math.Signbit(v) {
+			return in.stylize("-0", "number")
+		}
+		return in.stylize(value.String(), "number")
+	case int64:
+		return in.stylize(value.String(), "number")
+	}
+	return value.String()
+}
+
+func (in *inspector) array(obj *goja.Object, depth, indent int) string {
+	length := int(obj.Get("length").ToInteger())
+	shown := length
+	if shown > in.opts.MaxArray {
+		shown = in.opts.MaxArray
+	}
+
+	items := make([]string, 0, shown+1)
+	for i := 0; i < shown; i++ {
+		items = append(items, in.format(obj.Get(strconv.Itoa(i)), depth+1, indent+2))
+	}
+	if length > shown {
+		items = append(items, fmt.Sprintf("... %d more item%s", length-shown, plural(length-shown)))
+	}
+	return in.wrap("[", "]", items, indent)
+}
+
+// typedArray formats a typed array as its type, length and elements, e.g.
+// Uint8Array(2) [ 1, 2 ]
+func (in *inspector) typedArray(obj *goja.Object, name string, depth, indent int) string {
+	length := int(obj.Get("length").ToInteger())
+	shown := length
+	if shown > in.opts.MaxArray {
+		shown = in.opts.MaxArray
+	}
+
+	items := make([]string, 0, shown+1)
+	for i := 0; i < shown; i++ {
+		items = append(items, in.primitive(obj.Get(strconv.Itoa(i))))
+	}
+	if length > shown {
+		items = append(items, fmt.Sprintf("... %d more item%s", length-shown, plural(length-shown)))
+	}
+	return fmt.Sprintf("%s(%d) %s", name, length, in.wrap("[", "]", items, indent))
+}
+
+// buffer formats the bytes of a Buffer in hex, as <Buffer 68 69>
+func (in *inspector) buffer(data []byte) string {
+	return "<Buffer" + hexBytes(data) + ">"
+}
+
+func (in *inspector) arrayBuffer(data []byte) string {
+	return fmt.Sprintf("ArrayBuffer { [Uint8Contents]: <%s>, byteLength: %s }",
+		strings.TrimPrefix(hexBytes(data), " "), in.primitive(in.vm.ToValue(len(data))))
+}
+
+// hexBytes lists up to maxBufferBytes of data in hex, each after a space
+func hexBytes(data []byte) string {
+	var b strings.Builder
+	for i, c := range data {
+		if i == maxBufferBytes {
+			fmt.Fprintf(&b, " ... %d more byte%s", len(data)-i, plural(len(data)-i))
+			break
+		}
+		fmt.Fprintf(&b, " %02x", c)
+	}
+	return b.String()
+}
+
+// typedArrayBytes returns the bytes a typed array views. DataViews, which
+// have a buffer too, are formatted as objects.
+func typedArrayBytes(obj *goja.Object) ([]byte, bool) {
+	if v := obj.Get("BYTES_PER_ELEMENT"); v == nil || goja.IsUndefined(v) {
+		return nil, false
+	}
+	v := obj.Get("buffer")
+	if v == nil {
+		return nil, false
+	}
+	buffer, ok := v.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	data := buffer.Bytes()
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+length], true
+}
+
+func (in *inspector) collection(obj *goja.Object, depth, indent int) string {
+	from, _ := goja.AssertFunction(in.vm.Get("Array").ToObject(in.vm).Get("from"))
+	entries, err := from(goja.Undefined(), obj)
+	if err != nil {
+		return "[" + obj.ClassName() + "]"
+	}
+	list := entries.ToObject(in.vm)
+	length := int(list.Get("length").ToInteger())
+	shown := length
+	if shown > in.opts.MaxArray {
+		shown = in.opts.MaxArray
+	}
+
+	items := make([]string, 0, shown+1)
+	for i := 0; i < shown; i++ {
+		entry := list.Get(strconv.Itoa(i))
+		if obj.ClassName() == "Map" {
+			pair := entry.ToObject(in.vm)
+			items = append(items, in.format(pair.Get("0"), depth+1, indent+2)+" => "+in.format(pair.Get("1"), depth+1, indent+2))
+		} else {
+			items = append(items, in.format(entry, depth+1, indent+2))
+		}
+	}
+	if length > shown {
+		items = append(items, fmt.Sprintf("... %d more item%s", length-shown, plural(length-shown)))
+	}
+	return fmt.Sprintf("%s(%d) %s", obj.ClassName(), length, in.wrap("{", "}", items, indent))
+}
+
+func (in *inspector) object(obj *goja.Object, depth, indent int) string {
+	keys := obj.Keys()
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		name := key
+		if !identifierPattern.MatchString(key) {
+			name = in.stylize(quote(key), "string")
+		}
+		items = append(items, name+": "+in.format(obj.Get(key), depth+1, indent+2))
+	}
+
+	result := in.wrap("{", "}", items, indent)
+	if obj.Prototype() == nil {
+		result = "[Object: null prototype] " + result
+	} else if name := in.constructorName(obj); name != "" && name != "Object" {
+		result = name + " " + result
+	}
+	return result
+}
+
+// wrap joins items on one line when they fit, or one per line otherwise
+func (in *inspector) wrap(open, close string, items []string, indent int) string {
+	if len(items) == 0 {
+		return open + close
+	}
+
+	line := open + " " + strings.Join(items, ", ") + " " + close
+	if visibleLength(line)+indent <= in.opts.BreakLength && !strings.Contains(line, "\n") {
+		return line
+	}
+
+	pad := strings.Repeat(" ", indent+2)
+	return open + "\n" + pad + strings.Join(items, ",\n"+pad) + "\n" + strings.Repeat(" ", indent) + close
+}
+
+// visibleLength is the length of s less the escape codes styling it
+func visibleLength(s string) int {
+	if !strings.Contains(s, "\x1b") {
+		return len(s)
+	}
+	return len(ansiPattern.ReplaceAllString(s, ""))
+}
+
+func (in *inspector) constructorName(obj *goja.Object) string {
+	ctor := obj.Get("constructor")
+	if ctor == nil || goja.IsUndefined(ctor) || goja.IsNull(ctor) {
+		return ""
+	}
+	if name := ctor.ToObject(in.vm).Get("name"); name != nil {
+		return name.String()
+	}
+	return ""
+}
+
+func (in *inspector) call(obj *goja.Object, method string) (string, error) {
+	fn, ok := goja.AssertFunction(obj.Get(method))
+	if !ok {
+		return "", fmt.Errorf("%s is not a function", method)
+	}
+	result, err := fn(obj)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// quote renders a string in single quotes as Node does
+func quote(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q[1:len(q)-1], `\"`, `"`)
+	return "'" + strings.ReplaceAll(q, "'", `\'`) + "'"
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
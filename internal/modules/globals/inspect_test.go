@@ -0,0 +1,83 @@
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/modules/globals"
+)
+
+func TestInspect(t *testing.T) {
+	vm := goja.New()
+	tests := []struct {
+		source string
+		opts   globals.InspectOptions
+		want   string
+	}{
+		{source: `({ a: 1, b: 'x', c: [1, 2], d: { e: { f: { g: 1 } } } })`,
+			want: `{ a: 1, b: 'x', c: [ 1, 2 ], d: { e: { f: [Object] } } }`},
+		{source: `({ d: { e: { f: { g: 1 } } } })`, opts: globals.InspectOptions{Depth: -1},
+			want: `{ d: { e: { f: { g: 1 } } } }`},
+		{source: `(() => { const o = { name: 'o', list: [] }; o.self = o; o.list.push(o); return o; })()`,
+			want: `{ name: 'o', list: [ [Circular] ], self: [Circular] }`},
+		{source: `new Map([['a', 1], [{ k: true }, null]])`, want: `Map(2) { 'a' => 1, { k: true } => null }`},
+		{source: `new Set([Symbol('s'), -0])`, want: `Set(2) { Symbol(s), 0 }`},
+		{source: `[-0, undefined, 'it\'s']`, want: `[ -0, undefined, 'it\'s' ]`},
+		{source: `Object.assign(Object.create(null), { a: 1 })`, want: `[Object: null prototype] { a: 1 }`},
+		{source: `new Uint8Array([1, 255])`, want: `Uint8Array(2) [ 1, 255 ]`},
+		{source: `new Uint8Array([1, 255]).buffer`, want: `ArrayBuffer { [Uint8Contents]: <01 ff>, byteLength: 2 }`},
+		{source: `(() => { class Buffer extends Uint8Array {}; return new Buffer([104, 105]); })()`,
+			want: `<Buffer 68 69>`},
+		{source: `(() => { class Buffer extends Uint8Array {}; return new Buffer(52); })()`,
+			want: `<Buffer 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 ... 2 more bytes>`},
+		{source: `({ n: 1, s: 'x', u: undefined, f() {} })`, opts: globals.InspectOptions{Colors: true},
+			want: "{ n: \x1b[33m1\x1b[39m, s: \x1b[32m'x'\x1b[39m, u: \x1b[90mundefined\x1b[39m, f: \x1b[36m[Function: f]\x1b[39m }"},
+	}
+
+	for _, tt := range tests {
+		value, err := vm.RunString(tt.source)
+		if err != nil {
+			t.Fatalf("RunString(%s) failed: %v", tt.source, err)
+		}
+		if got := globals.Inspect(vm, value, tt.opts); got != tt.want {
+			t.Errorf("Inspect(%s) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	vm := goja.New()
+	tests := []struct {
+		args string // a JS array of the arguments
+		want string
+	}{
+		{`['%s is %d years', 'Bob', 42]`, `Bob is 42 years`},
+		{`['%i|%f|%d|%d', '42.5px', '1.5e3', '7', {}]`, `42|1500|7|NaN`},
+		{`['%s|%s|%s', -0, { a: { b: 1 } }, { toString() { return 'custom'; } }]`, `-0|{ a: [Object] }|custom`},
+		{`['%j', { a: [1] }]`, `{"a":[1]}`},
+		{`(() => { const o = {}; o.o = o; return ['%j', o]; })()`, `[Circular]`},
+		{`['%o', { a: { b: { c: { d: { e: 1 } } } } }]`, `{ a: { b: { c: { d: { e: 1 } } } } }`},
+		{`['%O', { a: { b: { c: { d: { e: 1 } } } } }]`, `{ a: { b: { c: [Object] } } }`},
+		{`['%c styled', 'color: red']`, ` styled`},
+		{`['%s and %s', 'one']`, `one and %s`},
+		{`['100% %x %%', 'done']`, `100% %x % done`},
+		{`['%%']`, `%%`},
+		{`['a', 1, 'b', { x: 'y' }]`, `a 1 b { x: 'y' }`},
+		{`[1, 'a', [new Map()]]`, `1 a [ Map(0) {} ]`},
+	}
+
+	for _, tt := range tests {
+		value, err := vm.RunString(tt.args)
+		if err != nil {
+			t.Fatalf("RunString(%s) failed: %v", tt.args, err)
+		}
+		var args []goja.Value
+		obj := value.ToObject(vm)
+		for _, key := range obj.Keys() {
+			args = append(args, obj.Get(key))
+		}
+		if got := globals.Format(vm, args, globals.InspectOptions{}); got != tt.want {
+			t.Errorf("Format(%s) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
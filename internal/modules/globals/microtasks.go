@@ -0,0 +1,165 @@
+package globals
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// microtaskSetup installs queueMicrotask and process.nextTick on top of the
+// engine's job queue, which also runs promise reactions, so the two stay in
+// spec order with each other.
+//
+// Ticks are kept in their own queue and drained in a single job, scheduled
+// when the queue becomes non-empty. Ticks queued before that job runs, or
+// while it runs, join the same batch, ahead of any promise reaction queued
+// after the job.
+//
+// then and the resolved promise are captured up front so scripts replacing
+// Promise.prototype.then don't change when callbacks run.
+const microtaskSetup = `
+(function (process, report) {
+	var then = Promise.prototype.then;
+	var resolved = Promise.resolve();
+	var enqueue = function (job) {
+		then.call(resolved, job);
+	};
+
+	var ticks = [];
+	var scheduled = false;
+	var runTicks = function () {
+		for (var i = 0; i < ticks.length; i++) {
+			var tick = ticks[i];
+			try {
+				tick.fn.apply(undefined, tick.args);
+			} catch (e) {
+				report(e);
+			}
+		}
+		ticks = [];
+		scheduled = false;
+	};
+
+	var checkFunction = function (fn) {
+		if (typeof fn !== 'function') {
+			throw new TypeError('The "callback" argument must be of type function. Received ' +
+				(fn === null ? 'null' : typeof fn));
+		}
+	};
+
+	process.nextTick = function nextTick(fn) {
+		checkFunction(fn);
+		ticks.push({ fn: fn, args: Array.prototype.slice.call(arguments, 1) });
+		if (!scheduled) {
+			scheduled = true;
+			enqueue(runTicks);
+		}
+	};
+
+	globalThis.queueMicrotask = function queueMicrotask(fn) {
+		checkFunction(fn);
+		enqueue(function () {
+			try {
+				fn();
+			} catch (e) {
+				report(e);
+			}
+		});
+	};
+})
+`
+
+// installMicrotasks adds queueMicrotask and process.nextTick. It must run on
+// the JS thread.
+func installMicrotasks(vm *goja.Runtime, process *goja.Object) error {
+	setup, err := jsprogram.Run(vm, "microtask-setup", microtaskSetup)
+	if err != nil {
+		return err
+	}
+	install, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("microtask setup did not return a function")
+	}
+	report := func(err goja.Value) {
+		reportUncaught(vm, process, err, "uncaughtException")
+	}
+	_, err = install(goja.Undefined(), process, vm.ToValue(report))
+	return err
+}
+
+// ReportUncaught reports err, thrown by a callback with no caller to catch
+// it, as reportUncaught does. It must be called on the JS thread.
+func ReportUncaught(vm *goja.Runtime, err goja.Value) {
+	reportUncaughtFrom(vm, err, "uncaughtException")
+}
+
+// ReportUnhandledRejection reports a promise rejected with reason that
+// still had no handler once the microtasks after its rejection had run. It
+// goes to the process 'unhandledRejection' listeners or, when there are
+// none, is raised as an uncaught exception, as in Node. It must be called
+// on the JS thread.
+func ReportUnhandledRejection(vm *goja.Runtime, reason, promise goja.Value) {
+	process, ok := vm.Get("process").(*goja.Object)
+	if !ok {
+		return
+	}
+	if emit, ok := goja.AssertFunction(process.Get("emit")); ok {
+		handled, err := emit(process, vm.ToValue("unhandledRejection"), reason, promise)
+		if err == nil && handled.ToBoolean() {
+			return
+		}
+		if err != nil {
+			if ex, ok := err.(*goja.Exception); ok {
+				reason = ex.Value()
+			} else {
+				return // interrupted by process.exit
+			}
+		}
+	}
+	reportUncaughtFrom(vm, reason, "unhandledRejection")
+}
+
+// reportUncaughtFrom reports err through the global process, if any
+func reportUncaughtFrom(vm *goja.Runtime, err goja.Value, origin string) {
+	if process, ok := vm.Get("process").(*goja.Object); ok {
+		reportUncaught(vm, process, err, origin)
+	}
+}
+
+// reportUncaught hands an error thrown by a callback with no caller to
+// catch it, such as a microtask or an immediate, to the process
+// 'uncaughtException' listeners. When there are none, or one throws, the
+// error is printed to stderr and the process exits with status 1, or 7 for
+// a throwing listener, as in Node.
+func reportUncaught(vm *goja.Runtime, process *goja.Object, err goja.Value, origin string) {
+	code := 1
+	if emit, ok := goja.AssertFunction(process.Get("emit")); ok {
+		handled, emitErr := emit(process, vm.ToValue("uncaughtException"), err, vm.ToValue(origin))
+		if emitErr == nil && handled.ToBoolean() {
+			return
+		}
+		if emitErr != nil {
+			// A throwing listener is itself uncaught
+			ex, ok := emitErr.(*goja.Exception)
+			if !ok {
+				return // interrupted by process.exit
+			}
+			err = ex.Value()
+			code = 7
+		}
+	}
+
+	msg := err.String()
+	if obj, ok := err.(*goja.Object); ok {
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			msg = stack.String()
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Uncaught %s\n", msg)
+
+	if exit, ok := goja.AssertFunction(process.Get("reallyExit")); ok {
+		exit(process, vm.ToValue(code))
+	}
+}
@@ -0,0 +1,88 @@
+package globals_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestMicrotaskOrdering(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("ordering", `
+		new Promise((resolve) => {
+			const log = [];
+			setTimeout(() => { log.push('timeout'); }, 0);
+			setImmediate((tag) => {
+				log.push(tag);
+				Promise.resolve().then(() => log.push('immediate-promise'));
+			}, 'immediate');
+			setTimeout(() => resolve(log.join(',')), 20);
+			Promise.resolve().then(() => {
+				log.push('promise1');
+				process.nextTick(() => log.push('tick-in-promise'));
+				queueMicrotask(() => log.push('microtask-in-promise'));
+			});
+			queueMicrotask(() => log.push('microtask1'));
+			process.nextTick((a, b) => {
+				log.push('tick1:' + a + b);
+				process.nextTick(() => log.push('tick-in-tick'));
+			}, 'x', 'y');
+			Promise.resolve().then(() => log.push('promise2'));
+			log.push('sync');
+		})
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	// Promise reactions and queueMicrotask share one FIFO queue; nextTick
+	// callbacks run as one batch, which takes every tick queued before it
+	// runs or while it runs
+	got := strings.Split(value.(string), ",")
+	want := []string{"sync", "promise1", "microtask1", "tick1:xy", "tick-in-promise", "tick-in-tick", "promise2", "microtask-in-promise"}
+	if strings.Join(got[:len(want)], ",") != strings.Join(want, ",") {
+		t.Errorf("Microtask order = %v, want it to start with %v", got, want)
+	}
+	// Macrotasks come after every microtask, and each is followed by its own
+	rest := strings.Join(got[len(want):], ",")
+	if !strings.Contains(rest, "immediate,immediate-promise") || !strings.Contains(rest, "timeout") {
+		t.Errorf("Macrotask order = %v", got[len(want):])
+	}
+}
+
+func TestMicrotaskUncaughtException(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("uncaught", `
+		new Promise((resolve) => {
+			const caught = [];
+			process.on('uncaughtException', (err) => {
+				caught.push(err.message);
+				if (caught.length === 3) resolve(caught.sort().join(','));
+			});
+			queueMicrotask(() => { throw new Error('microtask'); });
+			process.nextTick(() => { throw new Error('tick'); });
+			setImmediate(() => { throw new Error('immediate'); });
+		})
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if value != "immediate,microtask,tick" {
+		t.Errorf("Caught errors = %v", value)
+	}
+
+	if _, err := rt.RunScript("bad-callback", "queueMicrotask(42)"); err == nil {
+		t.Error("Expected queueMicrotask to reject a non-function")
+	}
+}
@@ -31,6 +31,9 @@ type ProcessInfo struct {
 	
 	// Exit code
 	exitCode    int
+	
+	// Set by runtimes that end the script themselves instead of os.Exit
+	onExit      func(code int)
 }
 
 // NewProcess creates a new process object
@@ -87,6 +90,10 @@ func (p *ProcessInfo) Chdir(dir string) error {
 
 func (p *ProcessInfo) Exit(code int) {
 	p.exitCode = code
+	if p.onExit != nil {
+		p.onExit(code)
+		return
+	}
 	os.Exit(code)
 }
 
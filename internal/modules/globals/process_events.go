@@ -0,0 +1,135 @@
+package globals
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// processEvents backs process.on/once/off/emit. Runtime features such as
+// IPC deliver their events by calling process.emit on the JS thread.
+type processEvents struct {
+	vm        *goja.Runtime
+	process   *goja.Object
+	listeners map[string][]*processListener
+	observer  listenerObserver // may be nil
+}
+
+// listenerObserver is implemented by runtimes that act on whether scripts
+// listen for a process event, such as a signal, whose default handling a
+// listener replaces
+type listenerObserver interface {
+	ProcessListenersChanged(event string, count int)
+}
+
+type processListener struct {
+	fn   goja.Value
+	call goja.Callable
+	once bool
+}
+
+// installProcessEvents adds the EventEmitter methods to the process
+// object. observer, if not nil, is told when the listeners of an event
+// change.
+func installProcessEvents(vm *goja.Runtime, process *goja.Object, observer listenerObserver) {
+	e := &processEvents{
+		vm:        vm,
+		process:   process,
+		listeners: make(map[string][]*processListener),
+		observer:  observer,
+	}
+
+	process.Set("on", e.on)
+	process.Set("addListener", e.on)
+	process.Set("once", e.once)
+	process.Set("off", e.off)
+	process.Set("removeListener", e.off)
+	process.Set("removeAllListeners", e.removeAll)
+	process.Set("emit", e.emit)
+	process.Set("listenerCount", func(event string) int {
+		return len(e.listeners[event])
+	})
+	process.Set("listeners", func(event string) []interface{} {
+		fns := make([]interface{}, len(e.listeners[event]))
+		for i, l := range e.listeners[event] {
+			fns[i] = l.fn
+		}
+		return fns
+	})
+}
+
+func (e *processEvents) add(event string, fn goja.Value, once bool) *goja.Object {
+	call, ok := goja.AssertFunction(fn)
+	if !ok {
+		panic(e.vm.NewTypeError("listener must be a function"))
+	}
+	e.listeners[event] = append(e.listeners[event], &processListener{fn: fn, call: call, once: once})
+	e.changed(event)
+	return e.process
+}
+
+// changed tells the observer how many listeners event has now
+func (e *processEvents) changed(event string) {
+	if e.observer != nil {
+		e.observer.ProcessListenersChanged(event, len(e.listeners[event]))
+	}
+}
+
+func (e *processEvents) on(event string, fn goja.Value) *goja.Object {
+	return e.add(event, fn, false)
+}
+
+func (e *processEvents) once(event string, fn goja.Value) *goja.Object {
+	return e.add(event, fn, true)
+}
+
+func (e *processEvents) off(event string, fn goja.Value) *goja.Object {
+	list := e.listeners[event]
+	for i, l := range list {
+		if l.fn.StrictEquals(fn) {
+			e.listeners[event] = append(list[:i:i], list[i+1:]...)
+			e.changed(event)
+			break
+		}
+	}
+	return e.process
+}
+
+func (e *processEvents) removeAll(call goja.FunctionCall) goja.Value {
+	if event := call.Argument(0); !goja.IsUndefined(event) {
+		delete(e.listeners, event.String())
+		e.changed(event.String())
+	} else {
+		removed := e.listeners
+		e.listeners = make(map[string][]*processListener)
+		for event := range removed {
+			e.changed(event)
+		}
+	}
+	return e.process
+}
+
+// emit calls the listeners for event in order and reports whether there
+// were any. A throwing listener propagates to the caller like in Node.
+func (e *processEvents) emit(call goja.FunctionCall) goja.Value {
+	event := call.Argument(0).String()
+	list := e.listeners[event]
+	if len(list) == 0 {
+		return e.vm.ToValue(false)
+	}
+
+	// call.Arguments is the VM's stack, which the listeners' calls reuse
+	var args []goja.Value
+	if len(call.Arguments) > 1 {
+		args = append([]goja.Value(nil), call.Arguments[1:]...)
+	}
+
+	// Copy so listeners added or removed during emit don't affect this round
+	for _, l := range append([]*processListener(nil), list...) {
+		if l.once {
+			e.off(event, l.fn)
+		}
+		if _, err := l.call(e.process, args...); err != nil {
+			panic(err)
+		}
+	}
+	return e.vm.ToValue(true)
+}
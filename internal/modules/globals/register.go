@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/jsprogram"
 )
 
 // RuntimeInterface represents the methods we need from the runtime
@@ -15,6 +17,13 @@ type RuntimeInterface interface {
 	GetRuntime() *goja.Runtime
 }
 
+// exitHandler is implemented by runtimes that stop the script cleanly on
+// process.exit, running their shutdown hooks and reporting the code to the
+// caller of Run, rather than terminating the whole Go process
+type exitHandler interface {
+	Exit(code int)
+}
+
 // RegisterGlobals registers all global objects and functions
 func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 	// Get the current file being executed (for __filename and __dirname)
@@ -27,7 +36,11 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 	
 	// Register process object with proper JavaScript property names
 	processInfo := NewProcess(argv)
+	if h, ok := runtime.(exitHandler); ok {
+		processInfo.onExit = h.Exit
+	}
 	processObj := runtime.NewObject()
+	envObj := newEnvObject(runtime, processInfo.Env)
 	
 	// Set properties with lowercase names (Node.js compatibility)
 	processObj.Set("version", processInfo.Version)
@@ -37,7 +50,7 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 	processObj.Set("pid", processInfo.PID)
 	processObj.Set("ppid", processInfo.PPID)
 	processObj.Set("title", processInfo.Title)
-	processObj.Set("env", processInfo.Env)
+	processObj.Set("env", envObj)
 	processObj.Set("argv", processInfo.Argv)
 	processObj.Set("execPath", processInfo.ExecPath)
 	processObj.Set("execArgv", processInfo.ExecArgv)
@@ -45,25 +58,47 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 	// Set method with lowercase names
 	processObj.Set("cwd", processInfo.Cwd)
 	processObj.Set("chdir", processInfo.Chdir)
-	processObj.Set("exit", processInfo.Exit)
+	// process.exit() without a code uses process.exitCode, as in Node
+	exit := func(call goja.FunctionCall) goja.Value {
+		code := call.Argument(0)
+		if goja.IsUndefined(code) {
+			if code = processObj.Get("exitCode"); code == nil {
+				code = goja.Undefined()
+			}
+		}
+		processInfo.Exit(int(code.ToInteger()))
+		return goja.Undefined()
+	}
+	processObj.Set("exit", exit)
+	// reallyExit exits with code as is, for uncaught errors
+	processObj.Set("reallyExit", processInfo.Exit)
+	processObj.Set("exitCode", goja.Undefined())
 	processObj.Set("memoryUsage", processInfo.MemoryUsage)
 	
-	// Keep capitalized versions for compatibility with existing code
-	processObj.Set("Version", processInfo.Version)
-	processObj.Set("Versions", processInfo.Versions)
-	processObj.Set("Arch", processInfo.Arch)
-	processObj.Set("Platform", processInfo.Platform)
-	processObj.Set("PID", processInfo.PID)
-	processObj.Set("PPID", processInfo.PPID)
-	processObj.Set("Title", processInfo.Title)
-	processObj.Set("Env", processInfo.Env)
-	processObj.Set("Argv", processInfo.Argv)
-	processObj.Set("ExecPath", processInfo.ExecPath)
-	processObj.Set("ExecArgv", processInfo.ExecArgv)
-	processObj.Set("Cwd", processInfo.Cwd)
-	processObj.Set("Chdir", processInfo.Chdir)
-	processObj.Set("Exit", processInfo.Exit)
-	processObj.Set("MemoryUsage", processInfo.MemoryUsage)
+	// EventEmitter methods (process.on('message'), etc.)
+	observer, _ := runtime.(listenerObserver)
+	installProcessEvents(runtime.GetRuntime(), processObj, observer)
+	
+	// Keep capitalized versions for compatibility with existing code. They
+	// forward to the Node names and warn once so scripts can migrate.
+	warn := installWarnings(runtime.GetRuntime(), processObj)
+	for alias, name := range map[string]string{
+		"Version": "version", "Versions": "versions", "Arch": "arch",
+		"Platform": "platform", "PID": "pid", "PPID": "ppid", "Title": "title",
+		"Env": "env", "Argv": "argv", "ExecPath": "execPath", "ExecArgv": "execArgv",
+		"Cwd": "cwd", "Chdir": "chdir", "Exit": "exit", "MemoryUsage": "memoryUsage",
+	} {
+		warn.deprecate(processObj, alias, name, "GODE_DEP0001",
+			fmt.Sprintf("process.%s and the other capitalized process properties are deprecated. Use process.%s instead.", alias, name))
+	}
+	
+	// Behaviour levels of versioned built-ins, from "gode": {"compat": {...}}
+	var selected map[string]string
+	if p, ok := runtime.(compatProvider); ok {
+		selected = p.Compat()
+	}
+	compat := resolveCompat(selected, warn)
+	processObj.Set("compat", compat)
 	
 	if err := runtime.SetGlobal("process", processObj); err != nil {
 		return fmt.Errorf("failed to register process: %w", err)
@@ -126,16 +161,24 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 						return goBuf.Equals(other._goBuf || other);
 					},
 					
-					// Keep original capitalized methods for compatibility
-					ToString: goBuf.ToString,
-					Length: goBuf.Length,
-					Fill: goBuf.Fill,
-					Slice: goBuf.Slice,
-					Copy: goBuf.Copy,
-					IndexOf: goBuf.IndexOf,
-					Equals: goBuf.Equals
 				};
 				
+				// Keep original capitalized methods for compatibility, hidden
+				// from enumeration and warning once when used
+				['ToString', 'Length', 'Fill', 'Slice', 'Copy', 'IndexOf', 'Equals'].forEach(function(name) {
+					Object.defineProperty(jsBuffer, name, {
+						get: function() {
+							process.emitWarning(
+								'Buffer.' + name + '() and the other capitalized Buffer methods are deprecated. ' +
+								'Use buffer.' + name.charAt(0).toLowerCase() + name.slice(1) + '() instead, or set ' +
+								'"gode": {"compat": {"buffer": "native"}} in package.json for a Node-compatible Buffer.',
+								'DeprecationWarning', 'GODE_DEP0002');
+							return goBuf[name];
+						},
+						configurable: true
+					});
+				});
+				
 				return jsBuffer;
 			}
 			
@@ -211,7 +254,13 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 	
 	// Then create the Buffer constructor
 	gojaRuntime := runtime.GetRuntime()
-	bufferFunc, err := gojaRuntime.RunString(bufferSetup)
+	var bufferFunc goja.Value
+	var err error
+	if compat["buffer"] == "native" {
+		bufferFunc, err = newNativeBuffer(gojaRuntime)
+	} else {
+		bufferFunc, err = jsprogram.Run(gojaRuntime, "buffer-setup", bufferSetup)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create Buffer constructor: %w", err)
 	}
@@ -230,7 +279,11 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 	}
 	
 	// Register console with all methods
-	console := NewConsole()
+	console := NewConsole(gojaRuntime)
+	console.stack = func() string {
+		// Skip the frame of console.trace itself
+		return errors.FormatStack(callStack(gojaRuntime, 1))
+	}
 	consoleObj := runtime.NewObject()
 	consoleObj.Set("log", console.Log)
 	consoleObj.Set("error", console.Error)
@@ -256,18 +309,25 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 		return fmt.Errorf("failed to register console: %w", err)
 	}
 	
-	// Register extended timer functions  
-	extTimers := NewExtendedTimers(runtime)
+	// Register extended timer functions
+	reportError := func(err goja.Value) {
+		reportUncaught(runtime.GetRuntime(), processObj, err, "uncaughtException")
+	}
+	extTimers := NewExtendedTimers(runtime, reportError)
 	
-	if err := runtime.SetGlobal("setImmediate", func(callback interface{}, args ...interface{}) uint32 {
-		// Convert callback to Go function
-		if fn, ok := callback.(func()); ok {
-			return extTimers.SetImmediate(fn, args...)
+	if err := runtime.SetGlobal("setImmediate", func(call goja.FunctionCall) goja.Value {
+		vm := runtime.GetRuntime()
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(vm.NewTypeError("The \"callback\" argument must be of type function"))
+		}
+		// Copied, as call.Arguments is the VM's stack and the callback
+		// runs after this call returns
+		var args []goja.Value
+		if len(call.Arguments) > 1 {
+			args = append([]goja.Value(nil), call.Arguments[1:]...)
 		}
-		// Handle JavaScript function
-		return extTimers.SetImmediate(func() {
-			// In real implementation, we'd call the JS function here
-		}, args...)
+		return vm.ToValue(extTimers.SetImmediate(fn, args...))
 	}); err != nil {
 		return fmt.Errorf("failed to register setImmediate: %w", err)
 	}
@@ -276,17 +336,9 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 		return fmt.Errorf("failed to register clearImmediate: %w", err)
 	}
 	
-	if err := runtime.SetGlobal("queueMicrotask", func(callback interface{}) {
-		// Convert callback to Go function
-		if fn, ok := callback.(func()); ok {
-			extTimers.QueueMicrotask(fn)
-		} else {
-			// Handle JavaScript function
-			extTimers.QueueMicrotask(func() {
-				// In real implementation, we'd call the JS function here
-			})
-		}
-	}); err != nil {
+	// queueMicrotask and process.nextTick share the engine's job queue with
+	// promise reactions
+	if err := installMicrotasks(runtime.GetRuntime(), processObj); err != nil {
 		return fmt.Errorf("failed to register queueMicrotask: %w", err)
 	}
 	
@@ -360,7 +412,7 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 		return fmt.Errorf("failed to register URL implementation: %w", err)
 	}
 	
-	urlFunc, err := gojaRuntime.RunString(urlSetup)
+	urlFunc, err := jsprogram.Run(gojaRuntime, "url-setup", urlSetup)
 	if err != nil {
 		return fmt.Errorf("failed to create URL constructor: %w", err)
 	}
@@ -427,7 +479,7 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 		return fmt.Errorf("failed to register URLSearchParams factory: %w", err)
 	}
 	
-	uspFunc, err := gojaRuntime.RunString(urlSearchParamsSetup)
+	uspFunc, err := jsprogram.Run(gojaRuntime, "urlsearchparams-setup", urlSearchParamsSetup)
 	if err != nil {
 		return fmt.Errorf("failed to create URLSearchParams constructor: %w", err)
 	}
@@ -515,12 +567,12 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 		return fmt.Errorf("failed to register TextDecoder implementation: %w", err)
 	}
 	
-	encoderFunc, err := gojaRuntime.RunString(encoderSetup)
+	encoderFunc, err := jsprogram.Run(gojaRuntime, "textencoder-setup", encoderSetup)
 	if err != nil {
 		return fmt.Errorf("failed to create TextEncoder constructor: %w", err)
 	}
 	
-	decoderFunc, err := gojaRuntime.RunString(decoderSetup)
+	decoderFunc, err := jsprogram.Run(gojaRuntime, "textdecoder-setup", decoderSetup)
 	if err != nil {
 		return fmt.Errorf("failed to create TextDecoder constructor: %w", err)
 	}
@@ -547,6 +599,23 @@ func RegisterGlobals(runtime RuntimeInterface, argv []string) error {
 		return fmt.Errorf("failed to register structuredClone: %w", err)
 	}
 	
+	// AggregateError and Promise.any, where the engine lacks them
+	if err := installErrors(gojaRuntime); err != nil {
+		return fmt.Errorf("failed to register AggregateError: %w", err)
+	}
+	installStackTraces(gojaRuntime)
+	
+	// Symbol.asyncIterator, which the engine has but does not expose, so
+	// that async iterables made in JS and in Go use the same key
+	if symbol, ok := gojaRuntime.Get("Symbol").(*goja.Object); ok && symbol.Get("asyncIterator") == nil {
+		symbol.DefineDataProperty("asyncIterator", goja.SymAsyncIterator, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	}
+	
+	// Register AbortController/AbortSignal and the EventTarget they build on
+	if err := installAbort(runtime); err != nil {
+		return fmt.Errorf("failed to register AbortController: %w", err)
+	}
+	
 	// Set global reference
 	if err := runtime.SetGlobal("global", gojaRuntime.GlobalObject()); err != nil {
 		return fmt.Errorf("failed to register global: %w", err)
@@ -0,0 +1,144 @@
+package globals
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/errors"
+)
+
+// defaultStackTraceLimit is the number of frames captured when
+// Error.stackTraceLimit is not set, as in V8
+const defaultStackTraceLimit = 10
+
+// installStackTraces adds Error.captureStackTrace and Error.stackTraceLimit
+// where the engine lacks them
+func installStackTraces(vm *goja.Runtime) {
+	ctor, ok := vm.Get("Error").(*goja.Object)
+	if !ok {
+		return
+	}
+	if v := ctor.Get("stackTraceLimit"); v == nil || goja.IsUndefined(v) {
+		ctor.Set("stackTraceLimit", defaultStackTraceLimit)
+	}
+	if _, ok := goja.AssertFunction(ctor.Get("captureStackTrace")); !ok {
+		ctor.Set("captureStackTrace", captureStackTrace(vm))
+	}
+}
+
+// captureStackTrace implements Error.captureStackTrace(target,
+// constructorOpt): it sets target.stack to "Name: message" followed by the
+// current call stack. Frames from the innermost call to a function named
+// like constructorOpt inwards are left out, so that a library's own
+// helpers do not show in the errors it creates.
+func captureStackTrace(vm *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		target, ok := call.Argument(0).(*goja.Object)
+		if !ok {
+			panic(vm.NewTypeError("Error.captureStackTrace expects an object"))
+		}
+
+		// The innermost frame is this function
+		stack := vm.CaptureCallStack(0, nil)
+		if len(stack) > 0 {
+			stack = stack[1:]
+		}
+		if fn, ok := call.Argument(1).(*goja.Object); ok {
+			if name := fn.Get("name"); name != nil && name.String() != "" {
+				stack = framesAfter(stack, name.String())
+			}
+		}
+
+		text := errorHeader(target)
+		if frames := parseFrames(stack, stackTraceLimit(vm)); len(frames) > 0 {
+			text += "\n" + errors.FormatStack(frames)
+		}
+		target.DefineDataProperty("stack", vm.ToValue(text), goja.FLAG_TRUE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+		return goja.Undefined()
+	}
+}
+
+// framesAfter drops the frames up to and including the innermost call to a
+// function called name. Like V8, it returns no frames when there is none.
+func framesAfter(stack []goja.StackFrame, name string) []goja.StackFrame {
+	for i := range stack {
+		if stack[i].FuncName() == name {
+			return stack[i+1:]
+		}
+	}
+	return nil
+}
+
+// errorHeader returns the first line of an error's stack, as
+// Error.prototype.toString formats it
+func errorHeader(obj *goja.Object) string {
+	name, message := "Error", ""
+	if v := obj.Get("name"); v != nil && !goja.IsUndefined(v) {
+		name = v.String()
+	}
+	if v := obj.Get("message"); v != nil && !goja.IsUndefined(v) {
+		message = v.String()
+	}
+	switch {
+	case name == "":
+		return message
+	case message == "":
+		return name
+	}
+	return name + ": " + message
+}
+
+// callStack returns the current JS call stack without its innermost skip
+// frames, at most Error.stackTraceLimit of them
+func callStack(vm *goja.Runtime, skip int) []errors.JSStackFrame {
+	stack := vm.CaptureCallStack(0, nil)
+	if skip > len(stack) {
+		skip = len(stack)
+	}
+	return parseFrames(stack[skip:], stackTraceLimit(vm))
+}
+
+// parseFrames converts at most limit frames with the stack parser of error
+// reports, which gives Go native functions readable names, and maps their
+// positions through the source maps of the files they are in
+func parseFrames(stack []goja.StackFrame, limit int) []errors.JSStackFrame {
+	if len(stack) > limit {
+		stack = stack[:limit]
+	}
+	lines := make([]string, len(stack))
+	var buf bytes.Buffer
+	for i := range stack {
+		buf.Reset()
+		buf.WriteString("at ")
+		stack[i].Write(&buf)
+		lines[i] = buf.String()
+	}
+	return errors.MapStackFrames(errors.ParseStackTrace(lines))
+}
+
+// stackTraceLimit reads Error.stackTraceLimit. Values that are not numbers
+// capture nothing, as in V8.
+func stackTraceLimit(vm *goja.Runtime) int {
+	ctor, ok := vm.Get("Error").(*goja.Object)
+	if !ok {
+		return defaultStackTraceLimit
+	}
+	v := ctor.Get("stackTraceLimit")
+	if v == nil || goja.IsUndefined(v) {
+		return defaultStackTraceLimit
+	}
+	switch v.Export().(type) {
+	case int64, float64:
+	default:
+		return 0
+	}
+	n := v.ToFloat()
+	switch {
+	case math.IsNaN(n) || n <= 0:
+		return 0
+	case n > math.MaxInt32:
+		return math.MaxInt32
+	}
+	return int(n)
+}
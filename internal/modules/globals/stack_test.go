@@ -0,0 +1,46 @@
+package globals_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestCaptureStackTrace(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("stack.js", `
+		function MyError(message) {
+			this.name = 'MyError';
+			this.message = message;
+			Error.captureStackTrace(this, MyError);
+		}
+		function helper() { return new MyError('boom'); }
+		helper().stack`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	stack, _ := value.(string)
+	if !strings.HasPrefix(stack, "MyError: boom\n    at helper (stack.js:7:") {
+		t.Errorf("Expected the stack to start at helper, got:\n%s", stack)
+	}
+	if strings.Contains(stack, "at MyError") {
+		t.Errorf("Expected the constructor frame to be left out, got:\n%s", stack)
+	}
+
+	// Error.stackTraceLimit bounds the frames captured
+	value, err = rt.RunScript("limit.js", `
+		Error.stackTraceLimit = 0;
+		const target = { message: 'no frames' };
+		Error.captureStackTrace(target);
+		Error.stackTraceLimit = 10;
+		target.stack`)
+	if err != nil || value != "Error: no frames" {
+		t.Errorf("Expected only the header with stackTraceLimit 0, got %v, %v", value, err)
+	}
+}
@@ -3,54 +3,70 @@ package globals
 import (
 	"sync"
 	"sync/atomic"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/asynccontext"
 )
 
-// ExtendedTimers provides setImmediate, clearImmediate, and queueMicrotask functionality
+// ExtendedTimers provides setImmediate and clearImmediate. Immediates are
+// macrotasks: each one is queued on the event loop, so it runs after the
+// current task and all of its microtasks.
 type ExtendedTimers struct {
-	runtime         interface{ QueueJSOperation(fn func()) }
-	immediateID     uint32
-	immediates      map[uint32]func()
-	immediatesMu    sync.Mutex
-	microtaskQueue  []func()
-	microtaskMu     sync.Mutex
-	processingTasks bool
+	runtime      interface{ QueueJSOperation(fn func()) }
+	immediateID  uint32
+	immediates   map[uint32]struct{}
+	immediatesMu sync.Mutex
+	// report receives errors thrown by immediate callbacks
+	report func(err goja.Value)
 }
 
 // NewExtendedTimers creates a new extended timers instance
-func NewExtendedTimers(runtime interface{ QueueJSOperation(fn func()) }) *ExtendedTimers {
+func NewExtendedTimers(runtime interface{ QueueJSOperation(fn func()) }, report func(err goja.Value)) *ExtendedTimers {
 	return &ExtendedTimers{
 		runtime:    runtime,
-		immediates: make(map[uint32]func()),
+		immediates: make(map[uint32]struct{}),
+		report:     report,
 	}
 }
 
-// SetImmediate schedules a callback to be invoked in the next iteration of the event loop
-func (et *ExtendedTimers) SetImmediate(callback func(), args ...interface{}) uint32 {
+// contextTracker is implemented by runtimes that follow the async context
+// of JS code across callbacks, see asynccontext
+type contextTracker interface {
+	AsyncContext() *asynccontext.Tracker
+}
+
+// SetImmediate schedules callback to be called with args in the next
+// iteration of the event loop, in the async context it was scheduled from
+func (et *ExtendedTimers) SetImmediate(callback goja.Callable, args ...goja.Value) uint32 {
 	id := atomic.AddUint32(&et.immediateID, 1)
-	
-	// Wrap the callback to handle arguments
-	wrappedCallback := func() {
-		// In real implementation, we'd apply args to callback
-		callback()
+	var tracker *asynccontext.Tracker
+	if t, ok := et.runtime.(contextTracker); ok {
+		tracker = t.AsyncContext()
 	}
-	
+	ctx := tracker.Capture()
+
 	et.immediatesMu.Lock()
-	et.immediates[id] = wrappedCallback
+	et.immediates[id] = struct{}{}
 	et.immediatesMu.Unlock()
-	
-	// Schedule execution in the next tick
+
 	et.runtime.QueueJSOperation(func() {
 		et.immediatesMu.Lock()
-		fn, exists := et.immediates[id]
-		if exists {
-			delete(et.immediates, id)
-			et.immediatesMu.Unlock()
-			fn()
-		} else {
-			et.immediatesMu.Unlock()
+		_, exists := et.immediates[id]
+		delete(et.immediates, id)
+		et.immediatesMu.Unlock()
+		if !exists {
+			return
 		}
+
+		tracker.Run(ctx, func() {
+			if _, err := callback(goja.Undefined(), args...); err != nil && et.report != nil {
+				if ex, ok := err.(*goja.Exception); ok {
+					et.report(ex.Value())
+				}
+			}
+		})
 	})
-	
+
 	return id
 }
 
@@ -60,41 +76,3 @@ func (et *ExtendedTimers) ClearImmediate(id uint32) {
 	delete(et.immediates, id)
 	et.immediatesMu.Unlock()
 }
-
-// QueueMicrotask adds a microtask to be executed before the next task
-func (et *ExtendedTimers) QueueMicrotask(callback func()) {
-	et.microtaskMu.Lock()
-	et.microtaskQueue = append(et.microtaskQueue, callback)
-	shouldProcess := !et.processingTasks
-	et.microtaskMu.Unlock()
-	
-	// If we're not already processing microtasks, start processing
-	if shouldProcess {
-		et.runtime.QueueJSOperation(func() {
-			et.processMicrotasks()
-		})
-	}
-}
-
-// processMicrotasks executes all queued microtasks
-func (et *ExtendedTimers) processMicrotasks() {
-	et.microtaskMu.Lock()
-	et.processingTasks = true
-	
-	// Process all microtasks
-	for len(et.microtaskQueue) > 0 {
-		// Get the next task
-		task := et.microtaskQueue[0]
-		et.microtaskQueue = et.microtaskQueue[1:]
-		et.microtaskMu.Unlock()
-		
-		// Execute the task
-		task()
-		
-		// Re-lock for next iteration
-		et.microtaskMu.Lock()
-	}
-	
-	et.processingTasks = false
-	et.microtaskMu.Unlock()
-}
\ No newline at end of file
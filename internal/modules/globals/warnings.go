@@ -0,0 +1,188 @@
+package globals
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Environment switches for process warnings, set by gode run --no-warnings
+// and --trace-warnings
+const (
+	EnvNoWarnings    = "GODE_NO_WARNINGS"
+	EnvTraceWarnings = "GODE_TRACE_WARNINGS"
+)
+
+// warnings implements process.emitWarning. Every warning is emitted as a
+// process 'warning' event and, unless silenced, printed to stderr. Warnings
+// with a code are only reported the first time that code is seen.
+type warnings struct {
+	vm      *goja.Runtime
+	process *goja.Object
+	out     io.Writer
+	seen    map[string]bool
+	silent  bool
+	trace   bool
+	color   bool
+	hinted  bool
+}
+
+func installWarnings(vm *goja.Runtime, process *goja.Object) *warnings {
+	w := &warnings{
+		vm:      vm,
+		process: process,
+		out:     os.Stderr,
+		seen:    make(map[string]bool),
+		silent:  envFlag(EnvNoWarnings),
+		trace:   envFlag(EnvTraceWarnings),
+		color:   isTerminal(os.Stderr) && os.Getenv("NO_COLOR") == "",
+	}
+	process.Set("emitWarning", w.emitWarning)
+	return w
+}
+
+// emitWarning implements process.emitWarning(warning, options) where warning
+// is a string or Error and options is {type, code, detail} or, as in Node,
+// a type string followed by a code
+func (w *warnings) emitWarning(call goja.FunctionCall) goja.Value {
+	name, code, detail := "Warning", "", ""
+
+	if opts, ok := call.Argument(1).(*goja.Object); ok {
+		name = stringOption(opts, "type", name)
+		code = stringOption(opts, "code", code)
+		detail = stringOption(opts, "detail", detail)
+	} else {
+		if v := call.Argument(1); !goja.IsUndefined(v) {
+			name = v.String()
+		}
+		if v := call.Argument(2); !goja.IsUndefined(v) {
+			code = v.String()
+		}
+	}
+
+	var warning *goja.Object
+	if obj, ok := call.Argument(0).(*goja.Object); ok && obj.ClassName() == "Error" {
+		warning = obj
+		if v := obj.Get("name"); v != nil && v.String() != "Error" {
+			name = v.String()
+		}
+	} else {
+		var err error
+		warning, err = w.vm.New(w.vm.Get("Error"), call.Argument(0))
+		if err != nil {
+			panic(err)
+		}
+	}
+	warning.Set("name", name)
+	if code != "" {
+		warning.Set("code", code)
+	}
+	if detail != "" {
+		warning.Set("detail", detail)
+	}
+
+	w.emit(warning, name, code)
+	return goja.Undefined()
+}
+
+// deprecate defines alias on obj as an accessor for target that warns
+// with code the first time any alias sharing that code is used
+func (w *warnings) deprecate(obj *goja.Object, alias, target, code, message string) {
+	getter := w.vm.ToValue(func() goja.Value {
+		w.warn("DeprecationWarning", code, message)
+		return obj.Get(target)
+	})
+	setter := w.vm.ToValue(func(value goja.Value) {
+		w.warn("DeprecationWarning", code, message)
+		obj.Set(target, value)
+	})
+	obj.DefineAccessorProperty(alias, getter, setter, goja.FLAG_TRUE, goja.FLAG_FALSE)
+}
+
+// warn emits a warning raised by the runtime itself
+func (w *warnings) warn(name, code, message string) {
+	if w.seen[code] {
+		return
+	}
+	warning, err := w.vm.New(w.vm.Get("Error"), w.vm.ToValue(message))
+	if err != nil {
+		return
+	}
+	warning.Set("name", name)
+	warning.Set("code", code)
+	w.emit(warning, name, code)
+}
+
+func (w *warnings) emit(warning *goja.Object, name, code string) {
+	if name == "DeprecationWarning" {
+		if v := w.process.Get("noDeprecation"); v != nil && v.ToBoolean() {
+			return
+		}
+	}
+	if code != "" {
+		if w.seen[code] {
+			return
+		}
+		w.seen[code] = true
+	}
+
+	if emit, ok := goja.AssertFunction(w.process.Get("emit")); ok {
+		if _, err := emit(w.process, w.vm.ToValue("warning"), warning); err != nil {
+			panic(err)
+		}
+	}
+
+	if !w.silent {
+		w.print(warning, name, code)
+	}
+}
+
+// print writes a warning as "(gode:PID) [CODE] Type: message"
+func (w *warnings) print(warning *goja.Object, name, code string) {
+	label := name
+	if w.color {
+		label = "\x1b[33m" + name + "\x1b[39m"
+	}
+
+	text := label + ": " + warning.Get("message").String()
+	if w.trace {
+		// The stack was captured before name was set, so keep only its frames
+		if stack := warning.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			if i := strings.IndexByte(stack.String(), '\n'); i >= 0 {
+				text += stack.String()[i:]
+			}
+		}
+	}
+	if code != "" {
+		text = "[" + code + "] " + text
+	}
+	fmt.Fprintf(w.out, "(gode:%d) %s\n", os.Getpid(), text)
+
+	if detail := warning.Get("detail"); detail != nil && !goja.IsUndefined(detail) {
+		fmt.Fprintln(w.out, detail.String())
+	}
+	if !w.trace && !w.hinted {
+		w.hinted = true
+		fmt.Fprintln(w.out, "(Use `gode run --trace-warnings ...` to show where the warning was created)")
+	}
+}
+
+func stringOption(obj *goja.Object, name, fallback string) string {
+	if v := obj.Get(name); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		return v.String()
+	}
+	return fallback
+}
+
+func envFlag(name string) bool {
+	v := os.Getenv(name)
+	return v != "" && v != "0" && v != "false"
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
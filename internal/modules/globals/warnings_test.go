@@ -0,0 +1,38 @@
+package globals_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/modules/globals"
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestEmitWarning(t *testing.T) {
+	t.Setenv(globals.EnvNoWarnings, "1")
+
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	source := `
+		var seen = [];
+		process.on('warning', function(w) { seen.push(w.name + ':' + (w.code || '') + ':' + w.message); });
+		process.emitWarning('plain');
+		process.emitWarning('once', { type: 'CustomWarning', code: 'X1' });
+		process.emitWarning('once again', 'CustomWarning', 'X1');
+		process.PID; process.Argv;
+		seen.join('|');
+	`
+	result, err := rt.RunScript("warnings", source)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+
+	want := "Warning::plain|CustomWarning:X1:once|DeprecationWarning:GODE_DEP0001:" +
+		"process.PID and the other capitalized process properties are deprecated. Use process.pid instead."
+	if result != want {
+		t.Errorf("Expected %q, got %v", want, result)
+	}
+}
@@ -0,0 +1,218 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/graphql"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Bridge provides JavaScript bindings for the gode:graphql module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new GraphQL bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() (*goja.Object, error) {
+	native := b.vm.NewObject()
+	native.Set("parse", b.parse)
+
+	setup, err := jsprogram.Run(b.vm, "graphql-setup", graphqlSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("graphql setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
+
+// parse implements native.parse(sdl, name), returning the schema's
+// validate(query), prepare(query, operationName, variables), kind(type) and
+// hasField(type, field)
+func (b *Bridge) parse(sdl, name string) *goja.Object {
+	schema, err := graphql.Parse(name, sdl)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("validate", func(query string) goja.Value {
+		return b.errors(schema.Validate(query))
+	})
+	obj.Set("prepare", func(query, operationName string, variables map[string]interface{}) *goja.Object {
+		result := b.vm.NewObject()
+		op, errs := schema.Prepare(query, operationName, variables)
+		if errs != nil {
+			result.Set("errors", b.errors(errs))
+			return result
+		}
+		result.Set("operation", b.operation(op))
+		return result
+	})
+	obj.Set("kind", func(name string) goja.Value {
+		def := schema.Definition(name)
+		if def == nil {
+			return goja.Undefined()
+		}
+		return b.vm.ToValue(string(def.Kind))
+	})
+	obj.Set("hasField", func(typeName, field string) bool {
+		def := schema.Definition(typeName)
+		return def != nil && def.Fields.ForName(field) != nil
+	})
+	return obj
+}
+
+// errors converts errs to GraphQL's {message, locations, path} objects
+func (b *Bridge) errors(errs gqlerror.List) goja.Value {
+	items := make([]interface{}, len(errs))
+	for i, err := range errs {
+		item := b.vm.NewObject()
+		item.Set("message", err.Message)
+		if len(err.Locations) > 0 {
+			locations := make([]interface{}, len(err.Locations))
+			for j, loc := range err.Locations {
+				location := b.vm.NewObject()
+				location.Set("line", loc.Line)
+				location.Set("column", loc.Column)
+				locations[j] = location
+			}
+			item.Set("locations", b.vm.NewArray(locations...))
+		}
+		if len(err.Path) > 0 {
+			path := make([]interface{}, len(err.Path))
+			for j, element := range err.Path {
+				switch element := element.(type) {
+				case ast.PathIndex:
+					path[j] = int(element)
+				case ast.PathName:
+					path[j] = string(element)
+				}
+			}
+			item.Set("path", b.vm.NewArray(path...))
+		}
+		items[i] = item
+	}
+	return b.vm.NewArray(items...)
+}
+
+// planConverter converts a plan, each of its types once
+type planConverter struct {
+	b     *Bridge
+	types map[*graphql.Type]*goja.Object
+}
+
+func (b *Bridge) operation(op *graphql.Operation) *goja.Object {
+	c := &planConverter{b: b, types: make(map[*graphql.Type]*goja.Object)}
+	obj := b.vm.NewObject()
+	obj.Set("type", op.Type)
+	obj.Set("name", op.Name)
+	obj.Set("rootType", op.RootType)
+	obj.Set("fields", c.fields(op.Fields))
+	obj.Set("variables", b.value(op.Variables))
+	return obj
+}
+
+func (c *planConverter) fields(fields []*graphql.Field) goja.Value {
+	items := make([]interface{}, len(fields))
+	for i, field := range fields {
+		items[i] = c.field(field)
+	}
+	return c.b.vm.NewArray(items...)
+}
+
+func (c *planConverter) field(field *graphql.Field) *goja.Object {
+	vm := c.b.vm
+	obj := vm.NewObject()
+	obj.Set("alias", field.Alias)
+	obj.Set("name", field.Name)
+	obj.Set("args", c.b.value(field.Args))
+	obj.Set("type", c.typ(field.Type))
+	if field.Selections != nil {
+		selections := vm.NewObject()
+		for name, fields := range field.Selections {
+			selections.Set(name, c.fields(fields))
+		}
+		obj.Set("selections", selections)
+	}
+	if field.Introspection {
+		obj.Set("introspection", true)
+		obj.Set("value", c.b.value(field.Value))
+	}
+	obj.Set("line", field.Line)
+	obj.Set("column", field.Column)
+	return obj
+}
+
+func (c *planConverter) typ(t *graphql.Type) *goja.Object {
+	if obj, ok := c.types[t]; ok {
+		return obj
+	}
+	obj := c.b.vm.NewObject()
+	obj.Set("kind", t.Kind)
+	if t.Name != "" {
+		obj.Set("name", t.Name)
+	}
+	if t.OfType != nil {
+		obj.Set("ofType", c.typ(t.OfType))
+	}
+	if t.Values != nil {
+		values := make([]interface{}, len(t.Values))
+		for i, value := range t.Values {
+			values[i] = value
+		}
+		obj.Set("values", c.b.vm.NewArray(values...))
+	}
+	c.types[t] = obj
+	return obj
+}
+
+// value converts arguments, variables and introspection results to JS,
+// maps with their keys in order
+func (b *Bridge) value(v interface{}) goja.Value {
+	switch v := v.(type) {
+	case *graphql.Result:
+		obj := b.vm.NewObject()
+		for _, key := range v.Keys {
+			obj.Set(key, b.value(v.Values[key]))
+		}
+		return obj
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		obj := b.vm.NewObject()
+		for _, key := range keys {
+			obj.Set(key, b.value(v[key]))
+		}
+		return obj
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = b.value(item)
+		}
+		return b.vm.NewArray(items...)
+	}
+	return b.vm.ToValue(v)
+}
@@ -0,0 +1,120 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestGraphQLModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("graphql", `
+		(async () => {
+			const graphql = require('gode:graphql');
+			const users = { 1: { id: 1, name: 'Ann', friends: [2] }, 2: { id: 2, name: null, friends: [] } };
+			let count = 0;
+			const schema = graphql.buildSchema(`+"`"+`
+				type User { id: ID! name: String! friends: [User!]! }
+				type Query {
+					user(id: ID!): User
+					users: [User]
+					fail: String
+				}
+				type Mutation { add(by: Int = 1): Int! }
+			`+"`"+`, {
+				Query: {
+					user: (_, { id }) => users[id],
+					users: async () => Object.values(users),
+					fail: () => { throw new graphql.GraphQLError('nope', { code: 'NOPE' }); },
+				},
+				User: {
+					friends: (user) => user.friends.map(id => Promise.resolve(users[id])),
+				},
+				Mutation: {
+					add: (_, { by }) => count += by,
+				},
+			});
+			const results = [];
+			const run = async (query, variables) => JSON.stringify(await schema.execute({ query, variables }));
+
+			results.push(await run('query($id: ID!) { user(id: $id) { id name } }', { id: '1' }));
+			results.push(await run('{ users { name } fail }'));
+			results.push(await run('{ user(id: "1") { friends { name } } }'));
+			results.push(await run('{ nope }'));
+			results.push(await run('mutation { a: add b: add(by: 5) }'));
+			results.push(schema.validate('{ user { id } }').length);
+			try {
+				graphql.buildSchema('type Query { x: Int }', { Query: { y: () => 1 } });
+			} catch (e) {
+				results.push(e.name);
+			}
+
+			const server = testServer(graphql.createHandler({ schema, graphiql: true }));
+			const post = await server.request({ method: 'POST', path: '/graphql', body: { query: '{ user(id: 1) { name } }' } });
+			results.push(post.status + ' ' + post.text());
+			const get = await server.request({ path: '/graphql?query=' + encodeURIComponent('mutation { add }') });
+			results.push(get.status);
+			const page = await server.request({ path: '/graphql', headers: { Accept: 'text/html' } });
+			results.push(page.headers['content-type'] + ' ' + page.text().includes('GraphiQL'));
+			return results.join('\n');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		`{"data":{"user":{"id":"1","name":"Ann"}}}`,
+		`{"errors":[{"message":"nope","locations":[{"line":1,"column":18}],"path":["fail"],"extensions":{"code":"NOPE"}},` +
+			`{"message":"Cannot return null for non-nullable field User.name.","locations":[{"line":1,"column":11}],"path":["users",1,"name"]}],` +
+			`"data":{"users":[{"name":"Ann"},null],"fail":null}}`,
+		`{"errors":[{"message":"Cannot return null for non-nullable field User.name.","locations":[{"line":1,"column":29}],"path":["user","friends",0,"name"]}],"data":{"user":null}}`,
+		`{"errors":[{"message":"Cannot query field \"nope\" on type \"Query\".","locations":[{"line":1,"column":3}]}]}`,
+		`{"data":{"a":1,"b":6}}`,
+		"1",
+		"TypeError",
+		`200 {"data":{"user":{"name":"Ann"}}}`,
+		"405",
+		"text/html; charset=utf-8 true",
+	}, "\n")
+	if text, _ := value.(string); !sameLines(text, want) {
+		t.Errorf("got\n%v\nwant\n%v", value, want)
+	}
+}
+
+// sameLines compares results line by line, the JSON in them parsed, as the
+// JSON global does not keep the order of object keys. A line may start
+// with a word before its JSON, like "200 {...}".
+func sameLines(got, want string) bool {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	if len(gotLines) != len(wantLines) {
+		return false
+	}
+	for i := range gotLines {
+		if sameJSON(gotLines[i], wantLines[i]) {
+			continue
+		}
+		gotWord, gotJSON, _ := strings.Cut(gotLines[i], " ")
+		wantWord, wantJSON, _ := strings.Cut(wantLines[i], " ")
+		if gotWord != wantWord || !sameJSON(gotJSON, wantJSON) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameJSON(got, want string) bool {
+	var a, b interface{}
+	if json.Unmarshal([]byte(got), &a) != nil || json.Unmarshal([]byte(want), &b) != nil {
+		return got == want
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,27 @@
+package graphql
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// RegisterGraphQLModule registers gode:graphql in the JavaScript runtime
+func RegisterGraphQLModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		exports, err := NewBridge(runtime).Exports()
+		if err != nil {
+			done <- err
+			return
+		}
+		runtime.RegisterModule("gode:graphql", exports)
+		done <- nil
+	})
+	return <-done
+}
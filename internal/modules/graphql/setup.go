@@ -0,0 +1,408 @@
+package graphql
+
+// graphqlSetup builds gode:graphql on top of native.parse. Queries are
+// validated and planned natively; the plan is executed here, where
+// resolvers may return values or promises. Fields of queries resolve
+// concurrently and those of mutations one after another. An error in a
+// field nulls it and is reported with its path, or nulls the nearest
+// nullable field above it when the field is non-null.
+const graphqlSetup = `
+(function (native) {
+	class GraphQLError extends Error {
+		constructor(message, extensions) {
+			super(message);
+			this.name = 'GraphQLError';
+			if (extensions !== undefined) {
+				this.extensions = extensions;
+			}
+		}
+	}
+
+	function isThenable(value) {
+		return value != null && typeof value.then === 'function';
+	}
+
+	// then applies fn to value, or to what it resolves to
+	function then(value, fn) {
+		return isThenable(value) ? value.then(fn) : fn(value);
+	}
+
+	function describe(value) {
+		try {
+			return typeof value === 'string' ? JSON.stringify(value) : String(value);
+		} catch (err) {
+			return Object.prototype.toString.call(value);
+		}
+	}
+
+	// checkResolvers reports resolvers that don't match the schema
+	function checkResolvers(schema, resolvers) {
+		for (const typeName of Object.keys(resolvers)) {
+			const kind = schema.kind(typeName);
+			const typeResolvers = resolvers[typeName];
+			const where = 'resolvers.' + typeName;
+			if (kind === undefined) {
+				throw new TypeError(where + ': the schema has no type ' + typeName);
+			}
+			if (typeResolvers === null || typeof typeResolvers !== 'object') {
+				throw new TypeError(where + ' must be an object');
+			}
+			for (const name of Object.keys(typeResolvers)) {
+				const fn = typeResolvers[name];
+				const valid = kind === 'OBJECT' ? schema.hasField(typeName, name)
+					: kind === 'INTERFACE' || kind === 'UNION' ? name === '__resolveType'
+					: kind === 'SCALAR' ? name === 'serialize'
+					: false;
+				if (!valid) {
+					throw new TypeError(where + '.' + name + ' does not match the ' + kind.toLowerCase() + ' ' + typeName);
+				}
+				if (typeof fn !== 'function') {
+					throw new TypeError(where + '.' + name + ' must be a function');
+				}
+			}
+		}
+		return resolvers;
+	}
+
+	class Schema {
+		constructor(sdl, resolvers, options) {
+			const name = (options && options.name) || 'schema.graphql';
+			this._native = native.parse(String(sdl), name);
+			this.resolvers = checkResolvers(this._native, resolvers || {});
+		}
+
+		// validate returns the errors of query, empty when it is valid
+		validate(query) {
+			return this._native.validate(String(query));
+		}
+
+		// execute runs {query, variables, operationName, context,
+		// rootValue}, resolving to {data} or {errors, data}
+		execute(request) {
+			return execute(this, request || {}, false);
+		}
+	}
+
+	async function execute(schema, request, queriesOnly) {
+		if (typeof request.query !== 'string' || request.query === '') {
+			return { errors: [{ message: 'Must provide query string.' }] };
+		}
+		const prepared = schema._native.prepare(request.query, request.operationName || '', request.variables || {});
+		if (prepared.errors) {
+			return { errors: prepared.errors };
+		}
+		const operation = prepared.operation;
+		if (queriesOnly && operation.type !== 'query') {
+			return { errors: [{ message: 'Can only perform a ' + operation.type + ' operation from a POST request.' }], method: true };
+		}
+
+		const exe = {
+			schema: schema,
+			resolvers: schema.resolvers,
+			context: request.context,
+			operation: operation,
+			errors: [],
+			recorded: new WeakSet(),
+		};
+		let data = null;
+		try {
+			data = await executeFields(exe, operation.rootType, operation.fields, request.rootValue, [], operation.type === 'mutation');
+		} catch (err) {
+			// a non-null root field failed, reported already
+		}
+		return exe.errors.length > 0 ? { errors: exe.errors.slice(), data: data } : { data: data };
+	}
+
+	function executeFields(exe, typeName, fields, source, path, serial) {
+		const result = {};
+		if (serial) {
+			let chain = Promise.resolve();
+			for (const field of fields) {
+				chain = chain.then(() => executeField(exe, typeName, field, source, path.concat(field.alias)))
+					.then((value) => { result[field.alias] = value; });
+			}
+			return chain.then(() => result);
+		}
+		const pending = [];
+		for (const field of fields) {
+			const value = executeField(exe, typeName, field, source, path.concat(field.alias));
+			result[field.alias] = isThenable(value) ? null : value;
+			if (isThenable(value)) {
+				pending.push(value.then((v) => { result[field.alias] = v; }));
+			}
+		}
+		return pending.length > 0 ? Promise.all(pending).then(() => result) : result;
+	}
+
+	function executeField(exe, typeName, field, source, path) {
+		if (field.introspection) {
+			return field.value;
+		}
+		if (field.name === '__typename') {
+			return typeName;
+		}
+		return guard(exe, field, path, field.type.kind !== 'NON_NULL', () => {
+			const info = {
+				fieldName: field.name,
+				parentType: typeName,
+				path: path,
+				operation: exe.operation,
+				variableValues: exe.operation.variables,
+				schema: exe.schema,
+			};
+			const typeResolvers = exe.resolvers[typeName];
+			let value;
+			if (typeResolvers && typeResolvers[field.name]) {
+				value = typeResolvers[field.name](source, field.args, exe.context, info);
+			} else if (source != null) {
+				value = source[field.name];
+				if (typeof value === 'function') {
+					value = value.call(source, field.args, exe.context, info);
+				}
+			}
+			return then(value, (v) => complete(exe, typeName, field, field.type, v, path));
+		});
+	}
+
+	// guard reports what fn throws or rejects with at path, and stands null
+	// in for a nullable value; a non-null one passes the error on to the
+	// field above
+	function guard(exe, field, path, nullable, fn) {
+		const fail = (err) => {
+			const error = err instanceof Error ? err : new GraphQLError(String(err));
+			if (!exe.recorded.has(error)) {
+				exe.recorded.add(error);
+				const entry = {
+					message: error.message,
+					locations: [{ line: field.line, column: field.column }],
+					path: path.slice(),
+				};
+				if (error.extensions !== undefined) {
+					entry.extensions = error.extensions;
+				}
+				exe.errors.push(entry);
+			}
+			if (!nullable) {
+				throw error;
+			}
+			return null;
+		};
+		let result;
+		try {
+			result = fn();
+		} catch (err) {
+			return fail(err);
+		}
+		return isThenable(result) ? result.then(undefined, fail) : result;
+	}
+
+	// complete checks value against type and selects fields from objects
+	function complete(exe, parentType, field, type, value, path) {
+		if (type.kind === 'NON_NULL') {
+			return then(complete(exe, parentType, field, type.ofType, value, path), (v) => {
+				if (v === null) {
+					throw new GraphQLError('Cannot return null for non-nullable field ' + parentType + '.' + field.name + '.');
+				}
+				return v;
+			});
+		}
+		if (isThenable(value)) {
+			return value.then((v) => complete(exe, parentType, field, type, v, path));
+		}
+		if (value instanceof Error) {
+			throw value;
+		}
+		if (value == null) {
+			return null;
+		}
+		switch (type.kind) {
+		case 'LIST':
+			return completeList(exe, parentType, field, type, value, path);
+		case 'SCALAR':
+			return serialize(exe, type.name, value);
+		case 'ENUM':
+			if (!type.values.includes(String(value))) {
+				throw new GraphQLError('Enum "' + type.name + '" cannot represent value: ' + describe(value));
+			}
+			return String(value);
+		}
+
+		const objectType = type.kind === 'OBJECT' ? type.name : resolveType(exe, type, value, path);
+		return then(objectType, (name) => {
+			const fields = field.selections[name];
+			if (!fields) {
+				throw new GraphQLError('Abstract type "' + type.name + '" must resolve to an object type at runtime for field ' +
+					parentType + '.' + field.name + '; got ' + describe(name) + '.');
+			}
+			return executeFields(exe, name, fields, value, path, false);
+		});
+	}
+
+	function completeList(exe, parentType, field, type, value, path) {
+		if (typeof value === 'string' || typeof value[Symbol.iterator] !== 'function') {
+			throw new GraphQLError('Expected an iterable for field ' + parentType + '.' + field.name + '.');
+		}
+		const itemType = type.ofType;
+		const items = [];
+		let pending = false;
+		let i = 0;
+		for (const item of value) {
+			const itemPath = path.concat(i++);
+			const completed = guard(exe, field, itemPath, itemType.kind !== 'NON_NULL',
+				() => complete(exe, parentType, field, itemType, item, itemPath));
+			pending = pending || isThenable(completed);
+			items.push(completed);
+		}
+		return pending ? Promise.all(items) : items;
+	}
+
+	// resolveType names the object type of a value of an interface or union,
+	// with the __resolveType resolver or else the value's __typename
+	function resolveType(exe, type, value, path) {
+		const typeResolvers = exe.resolvers[type.name];
+		if (typeResolvers && typeResolvers.__resolveType) {
+			return typeResolvers.__resolveType(value, exe.context, { parentType: type.name, path: path, schema: exe.schema });
+		}
+		return value.__typename;
+	}
+
+	function serialize(exe, typeName, value) {
+		const custom = exe.resolvers[typeName];
+		if (custom && custom.serialize) {
+			return custom.serialize(value);
+		}
+		switch (typeName) {
+		case 'Int': {
+			const n = typeof value === 'boolean' ? Number(value) : value;
+			if (typeof n !== 'number' || !Number.isInteger(n) || n > 2147483647 || n < -2147483648) {
+				throw new GraphQLError('Int cannot represent non 32-bit signed integer value: ' + describe(value));
+			}
+			return n;
+		}
+		case 'Float': {
+			const n = typeof value === 'boolean' ? Number(value) : value;
+			if (typeof n !== 'number' || !Number.isFinite(n)) {
+				throw new GraphQLError('Float cannot represent non numeric value: ' + describe(value));
+			}
+			return n;
+		}
+		case 'String':
+			if (typeof value === 'string' || typeof value === 'number' || typeof value === 'boolean' || typeof value === 'bigint') {
+				return String(value);
+			}
+			throw new GraphQLError('String cannot represent value: ' + describe(value));
+		case 'Boolean':
+			if (typeof value === 'boolean' || typeof value === 'number') {
+				return typeof value === 'boolean' ? value : value !== 0;
+			}
+			throw new GraphQLError('Boolean cannot represent a non boolean value: ' + describe(value));
+		case 'ID':
+			if (typeof value === 'string' || Number.isInteger(value) || typeof value === 'bigint') {
+				return String(value);
+			}
+			throw new GraphQLError('ID cannot represent value: ' + describe(value));
+		}
+		return value;
+	}
+
+	function send(res, status, type, body, headers) {
+		res.writeHead(status, Object.assign({ 'Content-Type': type }, headers));
+		res.end(body);
+	}
+
+	function sendJSON(res, status, result, headers) {
+		send(res, status, 'application/json; charset=utf-8', JSON.stringify(result), headers);
+	}
+
+	// graphiqlPage loads GraphiQL from a CDN, querying endpoint
+	function graphiqlPage(endpoint) {
+		return '<!DOCTYPE html>\n<html>\n<head>\n<title>GraphiQL</title>\n' +
+			'<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">\n' +
+			'<style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>\n' +
+			'</head>\n<body>\n<div id="graphiql"></div>\n' +
+			'<script src="https://unpkg.com/react@18/umd/react.production.min.js"></script>\n' +
+			'<script src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>\n' +
+			'<script src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>\n' +
+			'<script>\n' +
+			'const fetcher = GraphiQL.createFetcher({ url: ' + JSON.stringify(endpoint).replace(/</g, '\\u003c') + ' });\n' +
+			'ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, { fetcher }));\n' +
+			'</script>\n</body>\n</html>\n';
+	}
+
+	// createHandler serves schema over HTTP as a (req, res) listener for
+	// gode:http: queries come as GET parameters or a POST body of JSON or
+	// application/graphql, and mutations only by POST. context is the
+	// context of resolvers, or a function of (req, res) that returns or
+	// resolves to it. With graphiql, a browser's GET without a query gets
+	// the GraphiQL IDE.
+	function createHandler(options) {
+		if (!options || !(options.schema instanceof Schema)) {
+			throw new TypeError('createHandler expects { schema } from buildSchema()');
+		}
+		const schema = options.schema;
+		return async function graphqlHandler(req, res) {
+			let params;
+			if (req.method === 'GET') {
+				const query = req.query || {};
+				const accept = (req.headers && req.headers.accept) || '';
+				if (options.graphiql && query.query === undefined && accept.includes('text/html')) {
+					send(res, 200, 'text/html; charset=utf-8', graphiqlPage(options.endpoint || req.path));
+					return;
+				}
+				params = { query: query.query, operationName: query.operationName, variables: query.variables };
+			} else if (req.method === 'POST') {
+				const type = (req.headers && req.headers['content-type']) || '';
+				try {
+					params = type.startsWith('application/graphql') ? { query: await req.text() } : await req.json();
+				} catch (err) {
+					sendJSON(res, 400, { errors: [{ message: 'Body is not valid JSON.' }] });
+					return;
+				}
+				if (params === null || typeof params !== 'object') {
+					params = {};
+				}
+			} else {
+				sendJSON(res, 405, { errors: [{ message: 'GraphQL only supports GET and POST requests.' }] }, { 'Allow': 'GET, POST' });
+				return;
+			}
+
+			let variables = params.variables;
+			if (typeof variables === 'string') {
+				try {
+					variables = variables === '' ? undefined : JSON.parse(variables);
+				} catch (err) {
+					sendJSON(res, 400, { errors: [{ message: 'Variables are invalid JSON.' }] });
+					return;
+				}
+			}
+			const context = typeof options.context === 'function' ? await options.context(req, res) : options.context;
+			const result = await execute(schema, {
+				query: params.query,
+				operationName: params.operationName,
+				variables: variables,
+				context: context,
+				rootValue: options.rootValue,
+			}, req.method === 'GET');
+
+			if (result.method) {
+				sendJSON(res, 405, { errors: result.errors }, { 'Allow': 'POST' });
+				return;
+			}
+			sendJSON(res, 'data' in result ? 200 : 400, result);
+		};
+	}
+
+	return {
+		buildSchema: (sdl, resolvers, options) => new Schema(sdl, resolvers, options),
+		execute: (schema, request) => {
+			if (!(schema instanceof Schema)) {
+				throw new TypeError('execute expects a schema from buildSchema()');
+			}
+			return execute(schema, request || {}, false);
+		},
+		createHandler: createHandler,
+		Schema: Schema,
+		GraphQLError: GraphQLError,
+	};
+})
+`
@@ -0,0 +1,674 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	rpc "github.com/rizqme/gode/internal/grpc"
+	"github.com/rizqme/gode/internal/jsbuffer"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsprogram"
+	godetls "github.com/rizqme/gode/internal/modules/tls"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/internal/promise"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// defaultShutdownTimeout bounds how long close() waits for calls in
+// progress when it is not given a timeout
+const defaultShutdownTimeout = 10 * time.Second
+
+// Bridge provides JavaScript bindings for the gode:grpc module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new gRPC bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// service is the handle of a loaded service, which scripts pass back to
+// serve or call it
+type service struct {
+	desc protoreflect.ServiceDescriptor
+}
+
+// callError is a failed call, a GrpcError in JS with the status code and
+// details
+type callError struct {
+	code    int
+	details string
+}
+
+func (e *callError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.code, rpc.CodeName(e.code), e.details)
+}
+
+func (e *callError) JSName() string { return "GrpcError" }
+
+func (e *callError) JSProperties() map[string]interface{} {
+	return map[string]interface{}{"code": e.code, "details": e.details}
+}
+
+func newCallError(err error) error {
+	code, details := rpc.StatusOf(err)
+	return &callError{code: code, details: details}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() (*goja.Object, error) {
+	status := b.vm.NewObject()
+	for code := 0; code < len(rpc.Codes); code++ {
+		status.Set(rpc.CodeName(code), code)
+	}
+	native := b.vm.NewObject()
+	native.Set("status", status)
+	native.Set("load", b.load)
+	native.Set("createServer", b.createServer)
+	native.Set("createClient", b.createClient)
+
+	setup, err := jsprogram.Run(b.vm, "grpc-setup", grpcSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("grpc setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
+
+// load implements native.load(paths, includeDirs), returning {services,
+// service(name)}; a service is described by its name and methods, with the
+// handle to serve or call it
+func (b *Bridge) load(paths []string, includeDirs []string) *goja.Object {
+	if checker, ok := b.runtime.(permissions.Enforcer); ok {
+		for _, path := range append(append([]string(nil), paths...), includeDirs...) {
+			if err := checker.CheckPermission("read", path); err != nil {
+				panic(jserror.New(b.vm, err))
+			}
+		}
+	}
+	schema, err := rpc.Load(paths, includeDirs)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("services", schema.Services())
+	obj.Set("service", func(name string) *goja.Object {
+		desc, err := schema.Service(name)
+		if err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		return b.describe(desc)
+	})
+	return obj
+}
+
+func (b *Bridge) describe(desc protoreflect.ServiceDescriptor) *goja.Object {
+	methods := desc.Methods()
+	items := make([]interface{}, methods.Len())
+	for i := range items {
+		method := methods.Get(i)
+		item := b.vm.NewObject()
+		item.Set("name", string(method.Name()))
+		item.Set("path", "/"+string(desc.FullName())+"/"+string(method.Name()))
+		item.Set("requestStream", method.IsStreamingClient())
+		item.Set("responseStream", method.IsStreamingServer())
+		item.Set("requestType", string(method.Input().FullName()))
+		item.Set("responseType", string(method.Output().FullName()))
+		items[i] = item
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("name", string(desc.FullName()))
+	obj.Set("methods", b.vm.NewArray(items...))
+	obj.Set("handle", &service{desc: desc})
+	return obj
+}
+
+// method returns the method of a service handle with the name
+func (b *Bridge) method(handle goja.Value, name string) protoreflect.MethodDescriptor {
+	svc, ok := handle.Export().(*service)
+	if !ok {
+		panic(b.vm.NewTypeError("expected a service from load()"))
+	}
+	method := svc.desc.Methods().ByName(protoreflect.Name(name))
+	if method == nil {
+		panic(b.vm.NewTypeError(fmt.Sprintf("%s has no method %s", svc.desc.FullName(), name)))
+	}
+	return method
+}
+
+// createServer implements native.createServer(options), returning
+// {addService(handle, handlers), listen(port, host), address(),
+// close(timeoutMs)}. The process stays alive while the server is
+// listening; shutting the runtime down closes it.
+func (b *Bridge) createServer(options *goja.Object) *goja.Object {
+	opts := &rpc.ServerOptions{}
+	if options != nil {
+		var err error
+		if opts.TLS, err = serverTLS(options); err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		if v := options.Get("maxMessageSize"); !isNullish(v) {
+			opts.MaxMessageSize = int(v.ToInteger())
+		}
+	}
+	server := rpc.NewServer(opts)
+	var release, unhook func()
+
+	obj := b.vm.NewObject()
+	obj.Set("addService", func(handle goja.Value, handlers *goja.Object) {
+		svc, ok := handle.Export().(*service)
+		if !ok {
+			panic(b.vm.NewTypeError("addService expects a service from load()"))
+		}
+		fns := make(map[string]rpc.Handler)
+		for _, name := range handlers.Keys() {
+			fn, ok := goja.AssertFunction(handlers.Get(name))
+			if !ok {
+				panic(b.vm.NewTypeError("handler of " + name + " must be a function"))
+			}
+			fns[name] = b.handler(b.method(handle, name), fn)
+		}
+		if err := server.Handle(svc.desc, fns); err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+	})
+	obj.Set("listen", func(port int64, host string) int {
+		if err := server.Listen(net.JoinHostPort(host, strconv.FormatInt(port, 10))); err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+		release = b.runtime.KeepAlive()
+		unhook = b.runtime.AddShutdownHook(func() {
+			server.Close()
+			release()
+		})
+		_, bound, _ := net.SplitHostPort(server.Address())
+		n, _ := strconv.Atoi(bound)
+		return n
+	})
+	obj.Set("address", server.Address)
+	obj.Set("close", func(timeout int64) goja.Value {
+		if unhook == nil {
+			return promise.Run(b.vm, b.runtime, func() (interface{}, error) { return nil, nil })
+		}
+		unhook()
+		unhook = nil
+		wait := defaultShutdownTimeout
+		if timeout >= 0 {
+			wait = time.Duration(timeout) * time.Millisecond
+		}
+		done := release
+		return promise.Run(b.vm, b.runtime, func() (interface{}, error) {
+			defer done()
+			server.Shutdown(wait)
+			return nil, nil
+		})
+	})
+	return obj
+}
+
+// handler serves the calls of method with fn, which is called on the JS
+// thread with the call and returns a promise of it being done. A rejection
+// with a numeric code from status fails the call with it, and with
+// anything else as UNKNOWN.
+func (b *Bridge) handler(method protoreflect.MethodDescriptor, fn goja.Callable) rpc.Handler {
+	return func(call *rpc.ServerCall) error {
+		var request map[string]interface{}
+		if !method.IsStreamingClient() {
+			req, err := call.Recv()
+			if err != nil {
+				return err
+			}
+			request = req
+		}
+
+		done := make(chan error, 1)
+		b.runtime.QueueJSOperation(func() {
+			result, err := fn(goja.Undefined(), b.serverCall(call, request))
+			if exception, ok := err.(*goja.Exception); ok {
+				done <- b.callStatus(exception.Value())
+				return
+			} else if err != nil {
+				done <- err
+				return
+			}
+			b.await(result, func(err error) { done <- err })
+		})
+
+		select {
+		case err := <-done:
+			return err
+		case <-call.Context().Done():
+			return call.Context().Err()
+		}
+	}
+}
+
+// serverCall is what a handler gets for a call: its method, metadata and
+// request, unless the client streams, recv() for the next request,
+// write(message) and cancelled
+func (b *Bridge) serverCall(call *rpc.ServerCall, request map[string]interface{}) *goja.Object {
+	obj := b.vm.NewObject()
+	obj.Set("method", string(call.Method.Name()))
+	obj.Set("metadata", b.metadata(call.Metadata))
+	if request != nil {
+		obj.Set("request", b.value(request))
+	}
+	obj.Set("recv", func() goja.Value {
+		if !call.Method.IsStreamingClient() {
+			p, resolver := promise.New(b.vm, b.runtime)
+			resolver.Resolve(goja.Undefined())
+			return p
+		}
+		p, resolver := promise.New(b.vm, b.runtime)
+		go func() {
+			req, err := call.Recv()
+			resolver.SettleWith(func() (interface{}, error) {
+				switch {
+				case err == io.EOF:
+					return goja.Undefined(), nil
+				case err != nil:
+					return nil, newCallError(err)
+				}
+				return b.value(req), nil
+			})
+		}()
+		return p
+	})
+	obj.Set("write", func(message goja.Value) {
+		if err := call.Send(b.message(call.Method.Output(), message)); err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+	})
+	obj.DefineAccessorProperty("cancelled", b.vm.ToValue(func() bool {
+		return call.Context().Err() != nil
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return obj
+}
+
+// await calls done once value, or the promise it is, settles
+func (b *Bridge) await(value goja.Value, done func(error)) {
+	if obj, ok := value.(*goja.Object); ok {
+		if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+			then(obj, b.vm.ToValue(func(goja.Value) {
+				done(nil)
+			}), b.vm.ToValue(func(reason goja.Value) {
+				done(b.callStatus(reason))
+			}))
+			return
+		}
+	}
+	done(nil)
+}
+
+// callStatus converts what a handler threw to the status of its call
+func (b *Bridge) callStatus(reason goja.Value) error {
+	code, details := rpc.Codes["UNKNOWN"], reason.String()
+	if obj, ok := reason.(*goja.Object); ok {
+		switch n := obj.Get("code").Export().(type) {
+		case int64:
+			if n > 0 && n <= 16 {
+				code = int(n)
+			}
+		case float64:
+			if n > 0 && n <= 16 && n == float64(int(n)) {
+				code = int(n)
+			}
+		}
+		if v := obj.Get("details"); !isNullish(v) {
+			details = v.String()
+		} else if v := obj.Get("message"); !isNullish(v) {
+			details = v.String()
+		}
+	}
+	return rpc.Error(code, details)
+}
+
+// createClient implements native.createClient(target, options), returning
+// {unary(handle, method, request, options), stream(handle, method,
+// options), close()}. Call options are metadata, an object of strings, and
+// timeout in milliseconds.
+func (b *Bridge) createClient(target string, options *goja.Object) *goja.Object {
+	opts := &rpc.ClientOptions{}
+	if options != nil {
+		var err error
+		if opts.TLS, err = clientTLS(options); err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		if v := options.Get("maxMessageSize"); !isNullish(v) {
+			opts.MaxMessageSize = int(v.ToInteger())
+		}
+	}
+	b.checkNet(target)
+	client, err := rpc.NewClient(target, opts)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	unhook := b.runtime.AddShutdownHook(func() { client.Close() })
+
+	obj := b.vm.NewObject()
+	obj.Set("unary", func(handle goja.Value, name string, request goja.Value, options *goja.Object) goja.Value {
+		method := b.method(handle, name)
+		req := b.message(method.Input(), request)
+		ctx, cancel, md := b.callOptions(options)
+		p, resolver := promise.New(b.vm, b.runtime)
+		go func() {
+			defer cancel()
+			res, err := client.Call(ctx, method, req, md)
+			resolver.SettleWith(func() (interface{}, error) {
+				if err != nil {
+					return nil, newCallError(err)
+				}
+				return b.value(res), nil
+			})
+		}()
+		return p
+	})
+	obj.Set("stream", func(handle goja.Value, name string, options *goja.Object) *goja.Object {
+		method := b.method(handle, name)
+		ctx, cancel, md := b.callOptions(options)
+		return b.clientStream(client.Stream(ctx, method, md), cancel)
+	})
+	obj.Set("close", func() {
+		unhook()
+		client.Close()
+	})
+	return obj
+}
+
+// clientStream is a streaming call: write(message), end(), which resolves
+// once the requests are sent, recv(), which resolves to the next response
+// or undefined after the last, and cancel()
+func (b *Bridge) clientStream(stream *rpc.ClientStream, cancel context.CancelFunc) *goja.Object {
+	obj := b.vm.NewObject()
+	obj.Set("write", func(message goja.Value) {
+		if err := stream.Send(b.message(stream.Method.Input(), message)); err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+	})
+	obj.Set("end", func() goja.Value {
+		return promise.Run(b.vm, b.runtime, func() (interface{}, error) {
+			if err := stream.CloseSend(); err != nil {
+				return nil, newCallError(err)
+			}
+			return nil, nil
+		})
+	})
+	obj.Set("recv", func() goja.Value {
+		p, resolver := promise.New(b.vm, b.runtime)
+		go func() {
+			res, err := stream.Recv()
+			// The call is over after its last response, or its only one
+			if err != nil || !stream.Method.IsStreamingServer() {
+				cancel()
+			}
+			resolver.SettleWith(func() (interface{}, error) {
+				switch {
+				case err == io.EOF:
+					return goja.Undefined(), nil
+				case err != nil:
+					return nil, newCallError(err)
+				}
+				return b.value(res), nil
+			})
+		}()
+		return p
+	})
+	obj.Set("cancel", func() { cancel() })
+	return obj
+}
+
+func (b *Bridge) callOptions(options *goja.Object) (context.Context, context.CancelFunc, map[string]string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if options == nil {
+		return ctx, cancel, nil
+	}
+	if v := options.Get("timeout"); !isNullish(v) {
+		cancel()
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(v.ToInteger())*time.Millisecond)
+	}
+	var md map[string]string
+	if v, ok := options.Get("metadata").(*goja.Object); ok {
+		md = make(map[string]string)
+		for _, key := range v.Keys() {
+			md[strings.ToLower(key)] = v.Get(key).String()
+		}
+	}
+	return ctx, cancel, md
+}
+
+// checkNet checks network access to the host and port of target, which
+// are 443 when it has none, as for gRPC
+func (b *Bridge) checkNet(target string) {
+	checker, ok := b.runtime.(permissions.NetEnforcer)
+	if !ok {
+		return
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(target, "dns:///"), "passthrough:///")
+	if err := checker.CheckNetURL("https://" + host); err != nil {
+		panic(jserror.New(b.vm, err))
+	}
+}
+
+// metadata converts call metadata to an object of strings, joining the
+// values of keys sent more than once with ", "
+func (b *Bridge) metadata(md map[string][]string) *goja.Object {
+	obj := b.vm.NewObject()
+	for key, values := range md {
+		obj.Set(key, strings.Join(values, ", "))
+	}
+	return obj
+}
+
+// value converts a message from FromMessage to JS, with bytes as Buffers
+// and fields in the order of their names
+func (b *Bridge) value(v interface{}) goja.Value {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		obj := b.vm.NewObject()
+		for _, key := range keys {
+			obj.Set(key, b.value(v[key]))
+		}
+		return obj
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = b.value(item)
+		}
+		return b.vm.NewArray(items...)
+	case []byte:
+		return jsbuffer.New(b.vm, v)
+	}
+	return b.vm.ToValue(v)
+}
+
+// message converts a message given by a script to desc, throwing a
+// TypeError when it doesn't fit
+func (b *Bridge) message(desc protoreflect.MessageDescriptor, value goja.Value) *dynamicpb.Message {
+	msg, err := rpc.ToMessage(desc, b.export(value))
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return msg
+}
+
+// export converts a message given by a script for ToMessage: arrays to
+// slices, Buffers, typed arrays and ArrayBuffers to bytes and other objects
+// to maps
+func (b *Bridge) export(value goja.Value) interface{} {
+	if isNullish(value) {
+		return nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return value.Export()
+	}
+	if data, ok := bytesOf(obj); ok {
+		return data
+	}
+	if obj.ClassName() == "Array" {
+		length := int(obj.Get("length").ToInteger())
+		items := make([]interface{}, length)
+		for i := range items {
+			items[i] = b.export(obj.Get(strconv.Itoa(i)))
+		}
+		return items
+	}
+	fields := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		fields[key] = b.export(obj.Get(key))
+	}
+	return fields
+}
+
+// serverTLS reads the tls option of a server: 'dev' serves with the
+// development certificate of gode:tls, {cert, key} with a PEM certificate
+// and key
+func serverTLS(obj *goja.Object) (*tls.Config, error) {
+	value := obj.Get("tls")
+	if isNullish(value) {
+		return nil, nil
+	}
+	if name, ok := value.Export().(string); ok {
+		if name != "dev" {
+			return nil, fmt.Errorf("tls must be 'dev' or {cert, key}, got %q", name)
+		}
+		dev, err := godetls.DevCertificate(godetls.DefaultDevCertDir(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return dev.TLSConfig()
+	}
+	options, ok := value.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("tls must be 'dev' or {cert, key}")
+	}
+	cert, certOK := bytesOf(options.Get("cert"))
+	key, keyOK := bytesOf(options.Get("key"))
+	if !certOK || !keyOK || len(cert) == 0 || len(key) == 0 {
+		return nil, fmt.Errorf("tls needs a PEM cert and key")
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// clientTLS reads the tls option of a client: true verifies the server
+// against the system roots, 'dev' against the local CA of gode:tls and
+// {ca, serverName} against a PEM CA certificate
+func clientTLS(obj *goja.Object) (*tls.Config, error) {
+	value := obj.Get("tls")
+	if isNullish(value) {
+		return nil, nil
+	}
+	switch v := value.Export().(type) {
+	case bool:
+		if !v {
+			return nil, nil
+		}
+		return &tls.Config{MinVersion: tls.VersionTLS12}, nil
+	case string:
+		if v != "dev" {
+			return nil, fmt.Errorf("tls must be true, 'dev' or {ca, serverName}, got %q", v)
+		}
+		dev, err := godetls.DevCertificate(godetls.DefaultDevCertDir(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return trusting(dev.CACert, "")
+	}
+	options, ok := value.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("tls must be true, 'dev' or {ca, serverName}")
+	}
+	serverName := ""
+	if v := options.Get("serverName"); !isNullish(v) {
+		serverName = v.String()
+	}
+	ca, ok := bytesOf(options.Get("ca"))
+	if !ok {
+		return &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}, nil
+	}
+	return trusting(ca, serverName)
+}
+
+func trusting(caPEM []byte, serverName string) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("tls: ca has no PEM certificates")
+	}
+	return &tls.Config{RootCAs: pool, ServerName: serverName, MinVersion: tls.VersionTLS12}, nil
+}
+
+// bytesOf returns the bytes of a string, as UTF-8, or of a Buffer, typed
+// array or ArrayBuffer
+func bytesOf(value goja.Value) ([]byte, bool) {
+	if isNullish(value) {
+		return nil, false
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		if s, ok := value.Export().(string); ok {
+			return []byte(s), true
+		}
+		return nil, false
+	}
+	// Buffers of the wrapper implementation hold a Go buffer
+	if goBuf := obj.Get("_goBuf"); goBuf != nil {
+		if inner, ok := goBuf.Export().(interface{ Bytes() []byte }); ok {
+			return inner.Bytes(), true
+		}
+	}
+	if buffer, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return buffer.Bytes(), true
+	}
+	v := obj.Get("buffer")
+	if v == nil {
+		return nil, false
+	}
+	buffer, ok := v.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	data := buffer.Bytes()
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return data[offset : offset+length], true
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
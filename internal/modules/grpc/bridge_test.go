@@ -0,0 +1,143 @@
+package grpc_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+const routeProto = `
+syntax = "proto3";
+package route;
+
+message Point {
+  int32 x = 1;
+  int32 y = 2;
+  bytes tag = 3;
+}
+
+message Summary {
+  int32 count = 1;
+  string names = 2;
+}
+
+service Router {
+  rpc GetPoint (Point) returns (Point);
+  rpc ListPoints (Point) returns (stream Point);
+  rpc Record (stream Point) returns (Summary);
+  rpc Echo (stream Point) returns (stream Point);
+}
+`
+
+func TestGRPCRegistered(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	value, err := rt.RunScript("grpc", "typeof require('gode:grpc').createServer")
+	if err != nil || value != "function" {
+		t.Errorf("typeof createServer = %v, %v, want function", value, err)
+	}
+}
+
+func TestGRPCModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	protoFile := filepath.Join(t.TempDir(), "route.proto")
+	if err := os.WriteFile(protoFile, []byte(routeProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := rt.RunScriptAsync("grpc", `
+		(async () => {
+			const grpc = require('gode:grpc');
+			// each iterates an async iterable as for await would
+			async function each(iterable, fn) {
+				const it = iterable[Symbol.asyncIterator]();
+				for (;;) {
+					const { value, done } = await it.next();
+					if (done) {
+						return;
+					}
+					fn(value);
+				}
+			}
+			const Router = grpc.load(`+"`"+protoFile+"`"+`).service('route.Router');
+			const results = [];
+
+			const server = grpc.createServer();
+			server.addService(Router, {
+				async getPoint(point, call) {
+					if (point.x < 0) {
+						throw new grpc.GrpcError(grpc.status.OUT_OF_RANGE, 'x must not be negative');
+					}
+					return { x: point.x * 2, y: Number(call.metadata['x-y']), tag: point.tag };
+				},
+				*listPoints(point) {
+					for (let i = 1; i <= point.x; i++) {
+						yield { x: i };
+					}
+				},
+				async record(call) {
+					let count = 0;
+					await each(call, (point) => { count += point.x; });
+					return { count, names: call.method };
+				},
+				async echo(call) {
+					await each(call, (point) => call.write({ x: point.x + 100 }));
+				},
+			});
+			const port = await server.listen(0, '127.0.0.1');
+			const client = Router.client('127.0.0.1:' + port);
+
+			const point = await client.getPoint({ x: 21, tag: Buffer.from('hi') }, { metadata: { 'x-y': '7' } });
+			results.push([point.x, point.y, point.tag.toString()].join(','));
+			results.push(await client.getPoint({ x: -1 }).catch(e => [e instanceof grpc.GrpcError, e.code, e.details].join(',')));
+			results.push(await client.getPoint({ z: 1 }).catch(e => e.name));
+
+			const listed = [];
+			await each(client.listPoints({ x: 3 }), (p) => listed.push(p.x));
+			results.push(listed.join(','));
+
+			const recording = client.record();
+			recording.write({ x: 1 }).write({ x: 2 });
+			const summary = await recording.end();
+			results.push(summary.count + ' ' + summary.names);
+
+			const echo = client.echo();
+			echo.write({ x: 1 });
+			echo.write({ x: 2 });
+			echo.end();
+			const echoed = [];
+			await each(echo, (p) => echoed.push(p.x));
+			results.push(echoed.join(','));
+
+			client.close();
+			await server.close();
+			return results.join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"42,7,hi",
+		"true,11,x must not be negative",
+		"TypeError",
+		"1,2,3",
+		"3 Record",
+		"101,102",
+	}, "|")
+	if value != want {
+		t.Errorf("got\n%v\nwant\n%v", value, want)
+	}
+}
@@ -0,0 +1,213 @@
+package grpc
+
+// grpcSetup builds gode:grpc on top of the native load, createServer and
+// createClient. Handlers and client methods take the shape of their
+// method: unary ones deal in single messages and promises, streaming ones
+// in async iterables and write().
+const grpcSetup = `
+(function (native) {
+	const status = Object.freeze(native.status);
+	const codeNames = {};
+	for (const name of Object.keys(status)) {
+		codeNames[status[name]] = name;
+	}
+
+	class GrpcError extends Error {
+		constructor(code, details, options) {
+			super(code + ' ' + (codeNames[code] || 'UNKNOWN') + ': ' + details, options);
+			this.name = 'GrpcError';
+			this.code = code;
+			this.details = details;
+		}
+	}
+
+	// rethrow turns the failures of native calls into GrpcErrors
+	function rethrow(err) {
+		if (err && err.name === 'GrpcError' && !(err instanceof GrpcError)) {
+			throw new GrpcError(err.code, err.details);
+		}
+		throw err;
+	}
+
+	function lowerFirst(name) {
+		return name.charAt(0).toLowerCase() + name.slice(1);
+	}
+
+	// messages adapts recv(), which resolves to undefined after the last
+	// message, to the async iterator protocol
+	function messages(recv, stop) {
+		return function () {
+			return {
+				next: () => recv().then(value => ({ value: value, done: value === undefined }), rethrow),
+				return: (value) => {
+					if (stop) {
+						stop();
+					}
+					return Promise.resolve({ value: value, done: true });
+				},
+			};
+		};
+	}
+
+	class Service {
+		constructor(described) {
+			this.name = described.name;
+			this.methods = described.methods;
+			Object.defineProperty(this, '_handle', { value: described.handle });
+		}
+
+		client(target, options) {
+			return createClient(this, target, options);
+		}
+	}
+
+	// load reads .proto files or descriptor sets
+	function load(paths, options) {
+		const includeDirs = (options && options.includeDirs) || [];
+		const schema = native.load(Array.isArray(paths) ? paths : [paths], includeDirs);
+		return {
+			services: schema.services,
+			service: (name) => new Service(schema.service(name)),
+		};
+	}
+
+	// serve runs a handler for a call of method. The request of unary and
+	// server streaming calls is its first argument; the call, which client
+	// streaming calls iterate for their requests, is the last. What it
+	// returns is the response, or for server streaming calls an iterable of
+	// responses, unless it writes them with call.write().
+	async function serve(method, handler, call) {
+		call[Symbol.asyncIterator] = messages(call.recv);
+		const result = method.requestStream ? await handler(call) : await handler(call.request, call);
+		if (!method.responseStream) {
+			call.write(result === undefined ? {} : result);
+			return;
+		}
+		if (result == null) {
+			return;
+		}
+		const async = typeof result[Symbol.asyncIterator] === 'function';
+		if (!async && typeof result[Symbol.iterator] !== 'function') {
+			return;
+		}
+		// Iterated by hand as for await would, awaiting the values of a
+		// sync iterable
+		const it = async ? result[Symbol.asyncIterator]() : result[Symbol.iterator]();
+		for (;;) {
+			const { value, done } = await it.next();
+			if (done) {
+				break;
+			}
+			call.write(async ? value : await value);
+		}
+	}
+
+	class Server {
+		constructor(options) {
+			this._native = native.createServer(options || {});
+		}
+
+		// addService serves service with the handlers of implementation,
+		// named as the methods are or in lowerCamelCase
+		addService(service, implementation) {
+			if (!(service instanceof Service)) {
+				throw new TypeError('addService expects a service from load()');
+			}
+			const handlers = {};
+			for (const method of service.methods) {
+				const handler = implementation[method.name] || implementation[lowerFirst(method.name)];
+				if (handler === undefined) {
+					continue;
+				}
+				if (typeof handler !== 'function') {
+					throw new TypeError('handler of ' + method.name + ' must be a function');
+				}
+				handlers[method.name] = (call) => serve(method, handler.bind(implementation), call);
+			}
+			this._native.addService(service._handle, handlers);
+			return this;
+		}
+
+		// listen resolves to the port the server listens on
+		listen(port, host) {
+			return new Promise((resolve) => resolve(this._native.listen(port || 0, host || '')));
+		}
+
+		address() {
+			return this._native.address();
+		}
+
+		// close waits up to timeout milliseconds for calls in progress
+		close(timeout) {
+			return this._native.close(timeout === undefined ? -1 : timeout);
+		}
+	}
+
+	function createClient(service, target, options) {
+		if (!(service instanceof Service)) {
+			throw new TypeError('createClient expects a service from load()');
+		}
+		const conn = native.createClient(target, options || {});
+		const client = { close: () => conn.close() };
+		for (const method of service.methods) {
+			const call = clientMethod(conn, service._handle, method);
+			client[lowerFirst(method.name)] = call;
+			if (!(method.name in client)) {
+				client[method.name] = call;
+			}
+		}
+		return client;
+	}
+
+	// clientMethod calls method: unary calls return a promise of the
+	// response, server streaming ones an async iterable of the responses,
+	// client streaming ones a call to write() to and end() for the
+	// response, and bidirectional ones both
+	function clientMethod(conn, handle, method) {
+		const name = method.name;
+		if (!method.requestStream && !method.responseStream) {
+			return (request, options) => new Promise((resolve) => {
+				resolve(conn.unary(handle, name, request === undefined ? {} : request, options || {}));
+			}).catch(rethrow);
+		}
+		if (!method.requestStream) {
+			return (request, options) => {
+				const stream = conn.stream(handle, name, options || {});
+				stream.write(request === undefined ? {} : request);
+				stream.end().catch(() => {});
+				return {
+					[Symbol.asyncIterator]: messages(stream.recv, stream.cancel),
+					cancel: stream.cancel,
+				};
+			};
+		}
+		return (options) => {
+			const stream = conn.stream(handle, name, options || {});
+			const call = {
+				write(message) {
+					stream.write(message);
+					return call;
+				},
+				cancel: stream.cancel,
+			};
+			if (method.responseStream) {
+				call.end = () => stream.end().catch(rethrow);
+				call[Symbol.asyncIterator] = messages(stream.recv, stream.cancel);
+			} else {
+				call.end = () => stream.end().then(() => stream.recv()).catch(rethrow);
+			}
+			return call;
+		};
+	}
+
+	return {
+		load: load,
+		createServer: (options) => new Server(options),
+		createClient: createClient,
+		Server: Server,
+		Service: Service,
+		GrpcError: GrpcError,
+		status: status,
+	};
+})
+`
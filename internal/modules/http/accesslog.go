@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessEntry describes a served request for the access log
+type AccessEntry struct {
+	Time      time.Time     // the request arrived
+	Latency   time.Duration // until the response ended
+	Remote    string        // client address
+	User      string        // authenticated user, if any
+	Method    string
+	URL       string // request URI
+	Protocol  string // such as HTTP/1.1
+	Status    int
+	Bytes     int64  // of the response body
+	Route     string // pattern of the route that handled the request, if any
+	RequestID string
+	Referer   string
+	UserAgent string
+}
+
+// accessTimeLayout is the timestamp of the Apache log formats
+const accessTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// FormatAccessLog formats e as a line, without the newline, in the Apache
+// common or combined log format, or as a JSON object for "json"
+func FormatAccessLog(format string, e AccessEntry) (string, error) {
+	switch format {
+	case "common":
+		return commonLog(e), nil
+	case "combined":
+		return commonLog(e) + " " + quoteField(e.Referer) + " " + quoteField(e.UserAgent), nil
+	case "json":
+		entry := map[string]interface{}{
+			"time":      e.Time.UTC().Format(time.RFC3339Nano),
+			"remote":    e.Remote,
+			"method":    e.Method,
+			"url":       e.URL,
+			"protocol":  e.Protocol,
+			"status":    e.Status,
+			"bytes":     e.Bytes,
+			"latencyMs": float64(e.Latency) / float64(time.Millisecond),
+		}
+		optional := map[string]string{
+			"user":      e.User,
+			"route":     e.Route,
+			"requestId": e.RequestID,
+			"referer":   e.Referer,
+			"userAgent": e.UserAgent,
+		}
+		for name, value := range optional {
+			if value != "" {
+				entry[name] = value
+			}
+		}
+		data, err := json.Marshal(entry)
+		return string(data), err
+	}
+	return "", fmt.Errorf("unknown access log format %q: expected common, combined or json", format)
+}
+
+// commonLog formats e in the common log format:
+// host ident user [time] "request" status bytes
+func commonLog(e AccessEntry) string {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+	request := e.Method + " " + e.URL
+	if e.Protocol != "" {
+		request += " " + e.Protocol
+	}
+	return fmt.Sprintf("%s - %s [%s] %s %d %s",
+		orDash(e.Remote), orDash(e.User), e.Time.Format(accessTimeLayout), quoteField(request), e.Status, bytes)
+}
+
+// quoteField quotes a field of the log formats, escaping quotes and
+// backslashes; empty fields are "-"
+func quoteField(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
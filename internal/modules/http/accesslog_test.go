@@ -0,0 +1,90 @@
+package http_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/internal/modules/http"
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestAccessLog(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("access-log", `
+		(async () => {
+			const { createServer, accessLog } = require('gode:http');
+			const lines = [];
+			const app = createServer((req, res) => {
+				if (req.path === '/boom') throw new Error('boom');
+				req.route = '/items/:id';
+				res.end('hello');
+			});
+			app.use(accessLog({ format: 'json', sink: (line) => lines.push(JSON.parse(line)) }));
+			app.use(accessLog({ format: 'common', sink: { write: (line) => lines.push(line) } }));
+			const server = testServer(app);
+			await server.request({ path: '/items/7?x=1', headers: { 'X-Request-Id': 'abc', 'User-Agent': 'probe' } });
+			await server.request('/boom');
+			let invalid;
+			try {
+				accessLog({ format: 'nope' });
+			} catch (err) {
+				invalid = err.name;
+			}
+			const [json, common, failed] = lines;
+			return [
+				json.method, json.url, json.status, json.bytes, json.route, json.requestId, json.userAgent,
+				typeof json.latencyMs,
+				// a stream sink gets each line with its newline
+				/^127\.0\.0\.1 - - \[\d{2}\/\w{3}\/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET \/items\/7\?x=1 HTTP\/1\.1" 200 5\n$/.test(common),
+				failed.status, lines.length, invalid,
+			].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := "GET|/items/7?x=1|200|5|/items/:id|abc|probe|number|true|500|4|TypeError"
+	if value != want {
+		t.Errorf("access log = %v, want %s", value, want)
+	}
+}
+
+func TestFormatAccessLog(t *testing.T) {
+	zone := time.FixedZone("", -7*60*60)
+	entry := http.AccessEntry{
+		Time:      time.Date(2000, time.October, 10, 13, 55, 36, 0, zone),
+		Remote:    "127.0.0.1",
+		User:      "frank",
+		Method:    "GET",
+		URL:       "/apache_pb.gif",
+		Protocol:  "HTTP/1.0",
+		Status:    200,
+		Bytes:     2326,
+		Referer:   "http://www.example.com/start.html",
+		UserAgent: `Mozilla/4.08 "probe"`,
+	}
+	for _, tt := range []struct {
+		format string
+		edit   func(*http.AccessEntry)
+		want   string
+	}{
+		{"common", nil, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`},
+		{"common", func(e *http.AccessEntry) { e.User, e.Status, e.Bytes = "", 304, 0 },
+			`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 304 -`},
+		{"combined", nil, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 ` +
+			`"http://www.example.com/start.html" "Mozilla/4.08 \"probe\""`},
+	} {
+		e := entry
+		if tt.edit != nil {
+			tt.edit(&e)
+		}
+		if got, err := http.FormatAccessLog(tt.format, e); err != nil || got != tt.want {
+			t.Errorf("FormatAccessLog(%s) = %q, %v, want %q", tt.format, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/proxy"
+)
+
+// AgentOptions configures the connection pool of an Agent. Zero values
+// keep the defaults of Go's http.DefaultTransport.
+type AgentOptions struct {
+	// DisableKeepAlive closes each connection after its request
+	DisableKeepAlive bool
+	// MaxSockets limits the connections to each host, idle or not
+	MaxSockets int
+	// MaxFreeSockets is the most idle connections kept for each host
+	MaxFreeSockets int
+	// MaxTotalFreeSockets is the most idle connections kept in all
+	MaxTotalFreeSockets int
+	// IdleTimeout closes connections idle for that long
+	IdleTimeout time.Duration
+	// ConnectTimeout, TLSHandshakeTimeout and ResponseHeaderTimeout bound
+	// the steps of a request
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	// Timeout bounds whole requests, body included, unless a request sets
+	// its own. Defaults to 30 seconds, as for fetch without an agent.
+	Timeout time.Duration
+
+	// CA holds PEM certificates trusted in addition to the system's
+	CA [][]byte
+	// Cert and Key are a PEM client certificate and its key
+	Cert, Key []byte
+	// Insecure skips the verification of server certificates
+	Insecure bool
+	// ServerName overrides the name certificates are verified against
+	ServerName string
+
+	// Proxy is the URL of the proxy to use instead of gode's proxy
+	// settings. With Direct, requests go straight to the server.
+	Proxy  string
+	Direct bool
+}
+
+// Agent is a pool of connections with its own limits, timeouts, TLS and
+// proxy settings, for requests that the shared pool of fetch does not
+// suit. It is safe for concurrent use.
+type Agent struct {
+	transport *http.Transport
+	client    *http.Client
+}
+
+// NewAgent creates an Agent with options
+func NewAgent(options AgentOptions) (*Agent, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy.Func
+	t.DisableKeepAlives = options.DisableKeepAlive
+	t.MaxConnsPerHost = options.MaxSockets
+	if options.MaxFreeSockets > 0 {
+		t.MaxIdleConnsPerHost = options.MaxFreeSockets
+	}
+	if options.MaxTotalFreeSockets > 0 {
+		t.MaxIdleConns = options.MaxTotalFreeSockets
+	}
+	if options.IdleTimeout > 0 {
+		t.IdleConnTimeout = options.IdleTimeout
+	}
+	if options.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: options.ConnectTimeout, KeepAlive: 30 * time.Second}
+		t.DialContext = dialer.DialContext
+	}
+	if options.TLSHandshakeTimeout > 0 {
+		t.TLSHandshakeTimeout = options.TLSHandshakeTimeout
+	}
+	t.ResponseHeaderTimeout = options.ResponseHeaderTimeout
+
+	tlsConfig, err := agentTLS(options)
+	if err != nil {
+		return nil, err
+	}
+	t.TLSClientConfig = tlsConfig
+
+	switch {
+	case options.Direct:
+		t.Proxy = nil
+	case options.Proxy != "":
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", options.Proxy)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Agent{
+		transport: t,
+		client:    &http.Client{Transport: t, Timeout: timeout},
+	}, nil
+}
+
+// agentTLS builds the TLS configuration of an agent, nil for the defaults
+func agentTLS(options AgentOptions) (*tls.Config, error) {
+	if len(options.CA) == 0 && options.Cert == nil && options.Key == nil && !options.Insecure && options.ServerName == "" {
+		return nil, nil
+	}
+	config := &tls.Config{
+		InsecureSkipVerify: options.Insecure,
+		ServerName:         options.ServerName,
+	}
+	if len(options.CA) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, ca := range options.CA {
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("ca: no PEM certificates found")
+			}
+		}
+		config.RootCAs = pool
+	}
+	if options.Cert != nil || options.Key != nil {
+		cert, err := tls.X509KeyPair(options.Cert, options.Key)
+		if err != nil {
+			return nil, fmt.Errorf("cert and key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// Close closes the idle connections of the agent. Requests still in
+// flight finish, and the agent stays usable.
+func (a *Agent) Close() {
+	a.transport.CloseIdleConnections()
+}
+
+// agentOptions reads the options of new Agent(options), named after those
+// of Node.js's http.Agent and tls.connect
+func agentOptions(value goja.Value) (AgentOptions, error) {
+	var opts AgentOptions
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts, nil
+	}
+	milliseconds := func(name string) time.Duration {
+		if v := obj.Get(name); !isNullish(v) {
+			return time.Duration(v.ToInteger()) * time.Millisecond
+		}
+		return 0
+	}
+	count := func(name string) int {
+		if v := obj.Get(name); !isNullish(v) {
+			return int(v.ToInteger())
+		}
+		return 0
+	}
+	pem := func(name string, v goja.Value) ([]byte, error) {
+		data, ok := bodyBytes(v)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a PEM string or Buffer", name)
+		}
+		return data, nil
+	}
+
+	if v := obj.Get("keepAlive"); !isNullish(v) {
+		opts.DisableKeepAlive = !v.ToBoolean()
+	}
+	opts.MaxSockets = count("maxSockets")
+	opts.MaxFreeSockets = count("maxFreeSockets")
+	opts.MaxTotalFreeSockets = count("maxTotalFreeSockets")
+	opts.IdleTimeout = milliseconds("idleTimeout")
+	opts.ConnectTimeout = milliseconds("connectTimeout")
+	opts.TLSHandshakeTimeout = milliseconds("tlsHandshakeTimeout")
+	opts.ResponseHeaderTimeout = milliseconds("headersTimeout")
+	opts.Timeout = milliseconds("timeout")
+
+	if v := obj.Get("ca"); !isNullish(v) {
+		values := []goja.Value{v}
+		if list, ok := v.(*goja.Object); ok && list.ClassName() == "Array" {
+			values = nil
+			for _, key := range list.Keys() {
+				values = append(values, list.Get(key))
+			}
+		}
+		for _, value := range values {
+			data, err := pem("ca", value)
+			if err != nil {
+				return opts, err
+			}
+			opts.CA = append(opts.CA, data)
+		}
+	}
+	for name, target := range map[string]*[]byte{"cert": &opts.Cert, "key": &opts.Key} {
+		if v := obj.Get(name); !isNullish(v) {
+			data, err := pem(name, v)
+			if err != nil {
+				return opts, err
+			}
+			*target = data
+		}
+	}
+	if v := obj.Get("rejectUnauthorized"); !isNullish(v) {
+		opts.Insecure = !v.ToBoolean()
+	}
+	if v := obj.Get("servername"); !isNullish(v) {
+		opts.ServerName = v.String()
+	}
+
+	// proxy: false connects directly
+	if v := obj.Get("proxy"); !isNullish(v) {
+		if enabled, ok := v.Export().(bool); ok {
+			opts.Direct = !enabled
+		} else {
+			opts.Proxy = v.String()
+		}
+	}
+	return opts, nil
+}
+
+// newAgent implements native.agent(options), returning the agent as an
+// opaque value for native.fetch. The runtime closes its idle connections
+// on shutdown.
+func (b *Bridge) newAgent(options goja.Value) goja.Value {
+	opts, err := agentOptions(options)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	agent, err := NewAgent(opts)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	b.runtime.AddShutdownHook(agent.Close)
+	return b.vm.ToValue(agent)
+}
@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestFetchAgent(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	var conns int64
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, atomic.LoadInt64(&conns))
+	}))
+	upstream.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw})
+
+	value, err := rt.RunScriptAsync("fetch-agent", `
+		(async () => {
+			const { Agent, setDefaultAgent } = require('gode:http');
+			const url = `+strconv.Quote(upstream.URL)+`;
+			const pooled = new Agent({ ca: `+strconv.Quote(string(ca))+`, maxSockets: 1, idleTimeout: 5000 });
+			const get = (agent) => fetch(url, { agent }).then(r => r.text());
+
+			const reused = [await get(pooled), await get(pooled), await get(pooled)].join();
+			const insecure = new Agent({ rejectUnauthorized: false, keepAlive: false, proxy: false });
+			await get(insecure);
+			await get(insecure);
+			const closed = await get(pooled);
+
+			const untrusted = await fetch(url).then(() => 'trusted', () => 'untrusted');
+			setDefaultAgent(pooled);
+			const byDefault = await fetch(url).then(() => 'trusted', () => 'untrusted');
+			setDefaultAgent(null);
+
+			let invalid;
+			try {
+				new Agent({ ca: 'not a certificate' });
+			} catch (e) {
+				invalid = e.name;
+			}
+			const wrongType = await fetch(url, { agent: {} }).catch(e => e.name);
+			pooled.destroy();
+			return [reused, closed, untrusted, byDefault, invalid, wrongType].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	// The pooled agent keeps its one connection; the other opens one per
+	// request
+	want := "1,1,1|3|untrusted|trusted|TypeError|TypeError"
+	if value != want {
+		t.Errorf("agent results = %v, want %s", value, want)
+	}
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsbuffer"
+)
+
+// defineBody adds the body of a request or response to obj, kept as the
+// bytes received and only decoded on demand: body is a Buffer created on
+// first access, text() decodes it as UTF-8, json() parses it and
+// arrayBuffer() copies it. When async is set, as on fetch responses, the
+// methods return promises. It must be called on the JS thread.
+func defineBody(vm *goja.Runtime, obj *goja.Object, data []byte, async bool) {
+	var buffer goja.Value
+	obj.DefineAccessorProperty("body", vm.ToValue(func() goja.Value {
+		if buffer == nil {
+			buffer = jsbuffer.New(vm, data)
+		}
+		return buffer
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	method := func(name string, decode func() goja.Value) {
+		if !async {
+			obj.Set(name, decode)
+			return
+		}
+		obj.Set(name, func() goja.Value {
+			p, resolve, reject := vm.NewPromise()
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						reject(r)
+					}
+				}()
+				resolve(decode())
+			}()
+			return vm.ToValue(p)
+		})
+	}
+	method("text", func() goja.Value {
+		return vm.ToValue(string(data))
+	})
+	method("json", func() goja.Value {
+		parse, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+		value, err := parse(goja.Undefined(), vm.ToValue(string(data)))
+		if err != nil {
+			panic(err)
+		}
+		return value
+	})
+	method("arrayBuffer", func() goja.Value {
+		return vm.ToValue(vm.NewArrayBuffer(append([]byte(nil), data...)))
+	})
+}
+
+// bodyBytes converts a body or chunk given by a script to bytes: strings
+// as UTF-8, Buffers, typed arrays and ArrayBuffers as they are. Anything
+// else is not binary, and ok is false.
+func bodyBytes(value goja.Value) (data []byte, ok bool) {
+	if obj, isObject := value.(*goja.Object); isObject {
+		// Buffers of the wrapper implementation hold a Go buffer
+		if goBuf := obj.Get("_goBuf"); goBuf != nil {
+			if inner, ok := goBuf.Export().(interface{ Bytes() []byte }); ok {
+				return inner.Bytes(), true
+			}
+		}
+	}
+	switch v := value.Export().(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	case goja.ArrayBuffer:
+		return v.Bytes(), true
+	}
+	return nil, false
+}
+
+// jsonBody encodes a body that is not binary as JSON
+func jsonBody(value goja.Value) ([]byte, error) {
+	data, err := json.Marshal(value.Export())
+	if err != nil {
+		return nil, fmt.Errorf("request body: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,52 @@
+package http_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestBinaryBodies(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	// Bytes that are not valid UTF-8 must survive both directions
+	payload := []byte{0x00, 0xff, 0xfe, 0x80, 0x41}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Received", fmt.Sprint(len(received), received[1] == 0xff))
+		w.Write(payload)
+	}))
+	defer upstream.Close()
+
+	value, err := rt.RunScriptAsync("binary", `
+		(async () => {
+			const bytes = new Uint8Array([0, 255, 254, 128, 65]);
+			const server = testServer((req, res) => {
+				res.setHeader('X-Length', String(req.body.length()));
+				res.end(req.body);
+			});
+			const echoed = await server.request({ method: 'POST', path: '/', body: bytes });
+			const local = [echoed.headers['x-length'], echoed.body.toString('hex')].join(':');
+
+			const res = await fetch(`+strconv.Quote(upstream.URL)+`, { method: 'POST', body: bytes.buffer });
+			const buffer = await res.arrayBuffer();
+			const remote = [res.status, res.headers.get('x-received'), Array.from(new Uint8Array(buffer)).join(','), buffer.byteLength].join(':');
+			return local + '|' + remote;
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if want := "5:00fffe8041|200:5 true:0,255,254,128,65:5"; value != want {
+		t.Errorf("binary bodies = %v, want %s", value, want)
+	}
+}
@@ -1,87 +1,218 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/abort"
+	"github.com/rizqme/gode/internal/promise"
 )
 
-// Bridge provides JavaScript bindings for HTTP functionality
+// Bridge provides the native half of fetch, see fetchSetup
 type Bridge struct {
+	runtime    RuntimeInterface
+	vm         *goja.Runtime
 	httpModule *HTTPModule
 }
 
 // NewBridge creates a new HTTP bridge
-func NewBridge(runtime *goja.Runtime) *Bridge {
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	vm := runtime.GetGojaRuntime()
 	return &Bridge{
-		httpModule: NewHTTPModule(runtime),
+		runtime:    runtime,
+		vm:         vm,
+		httpModule: NewHTTPModule(vm),
 	}
 }
 
-// Register registers HTTP functions in the JavaScript runtime
-func (b *Bridge) Register(runtime *goja.Runtime) error {
-	// Register fetch function
-	err := runtime.Set("fetch", b.fetch)
+// fetch implements native.fetch(url, {method, headers, body, timeout,
+// redirect, agent, signal}) for the fetch of fetchSetup, which has checked
+// the request already. headers is an array of [name, value] pairs, body
+// bytes or undefined and agent one made by native.agent or undefined. It resolves as soon as the response headers arrive
+// with {status, statusText, url, redirected, headers, body}, body being
+// null or a reader of the response body, see bodyReader. Aborting the
+// signal cancels the request, or the reading of its body, and rejects with
+// the signal's reason. Called while handling a server request, it passes
+// the request's ID on in X-Request-Id.
+func (b *Bridge) fetch(url string, init goja.Value) goja.Value {
+	p, resolver := promise.New(b.vm, b.runtime)
+	options, err := b.fetchOptions(init)
 	if err != nil {
-		return fmt.Errorf("failed to register fetch function: %w", err)
+		resolver.Reject(b.vm.NewTypeError(err.Error()))
+		return p
+	}
+	var signal *abort.Signal
+	if obj, ok := init.(*goja.Object); ok {
+		if signal, err = abort.From(b.vm, obj.Get("signal")); err != nil {
+			resolver.Reject(b.vm.NewTypeError(err.Error()))
+			return p
+		}
+	}
+	if err := signal.Err(); err != nil {
+		resolver.Reject(err)
+		return p
+	}
+	ctx, stop := signal.Context(context.Background())
+	options.Context = ctx
+	if id := RequestID(trackerOf(b.runtime).Current()); id != "" && !hasHeader(options.Headers, RequestIDHeader) {
+		options.Headers[RequestIDHeader] = id
 	}
 
-	return nil
+	go func() {
+		resp, err := b.httpModule.Open(url, options)
+		resolver.SettleWith(func() (interface{}, error) {
+			if err != nil {
+				stop()
+				if aborted := signal.Err(); aborted != nil {
+					return nil, aborted
+				}
+				return nil, err
+			}
+			return b.response(options.Method, url, resp, signal, stop), nil
+		})
+	}()
+	return p
 }
 
-// fetch implements the JavaScript fetch function
-func (b *Bridge) fetch(call goja.FunctionCall) goja.Value {
-	runtime := b.httpModule.runtime
-
-	// Get URL argument
-	if len(call.Arguments) < 1 {
-		promise, _, reject := runtime.NewPromise()
-		reject(runtime.NewTypeError("fetch requires at least 1 argument"))
-		return runtime.ToValue(promise)
+// fetchOptions reads the init of native.fetch
+func (b *Bridge) fetchOptions(value goja.Value) (*FetchOptions, error) {
+	options := &FetchOptions{
+		Method:  "GET",
+		Headers: make(map[string]string),
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return options, nil
 	}
 
-	url := call.Arguments[0].String()
-
-	// Parse options if provided
-	var options *FetchOptions
-	
-	if len(call.Arguments) > 1 {
-		if !goja.IsUndefined(call.Arguments[1]) && !goja.IsNull(call.Arguments[1]) {
-			// Try to convert to object
-			optionsArg := call.Arguments[1]
-			optionsObj := optionsArg.ToObject(runtime)
-			
-			if optionsObj != nil {
-				methodVal := optionsObj.Get("method")
-				
-				options = &FetchOptions{
-					Method:  "GET",
-					Headers: make(map[string]string),
-				}
-				
-				if methodVal != nil && !goja.IsUndefined(methodVal) && !goja.IsNull(methodVal) {
-					options.Method = methodVal.String()
-				}
-			} else {
-				options = &FetchOptions{
-					Method:  "GET",
-					Headers: make(map[string]string),
-				}
+	if v := obj.Get("method"); !isNullish(v) {
+		options.Method = v.String()
+	}
+	if pairs, ok := obj.Get("headers").Export().([]interface{}); ok {
+		for _, pair := range pairs {
+			entry, ok := pair.([]interface{})
+			if !ok || len(entry) != 2 {
+				return nil, fmt.Errorf("headers must be [name, value] pairs")
 			}
-		} else {
-			options = &FetchOptions{
-				Method:  "GET",
-				Headers: make(map[string]string),
+			name, value := fmt.Sprint(entry[0]), fmt.Sprint(entry[1])
+			if previous, exists := options.Headers[name]; exists {
+				value = previous + ", " + value
 			}
+			options.Headers[name] = value
+		}
+	}
+	if v := obj.Get("timeout"); !isNullish(v) {
+		options.Timeout = int(v.ToInteger())
+	}
+	if v := obj.Get("redirect"); !isNullish(v) {
+		options.Redirect = v.String()
+	}
+	if v := obj.Get("agent"); !isNullish(v) {
+		agent, ok := v.Export().(*Agent)
+		if !ok {
+			return nil, fmt.Errorf("agent must be an Agent")
+		}
+		options.Agent = agent
+	}
+	if body := obj.Get("body"); !isNullish(body) {
+		data, ok := bodyBytes(body)
+		if !ok {
+			return nil, fmt.Errorf("request body must be bytes")
 		}
-	} else {
-		options = &FetchOptions{
-			Method:  "GET",
-			Headers: make(map[string]string),
+		options.Body = data
+	}
+	return options, nil
+}
+
+// response converts the response of native.fetch for JS. Responses to HEAD
+// and those with a status that has no body get a null body. stop is called
+// once the body has been read or canceled. It must be called on the JS
+// thread.
+func (b *Bridge) response(method, url string, resp *http.Response, signal *abort.Signal, stop func()) *goja.Object {
+	var headers []interface{}
+	for name, values := range resp.Header {
+		for _, value := range values {
+			headers = append(headers, []interface{}{strings.ToLower(name), value})
 		}
 	}
+	final := url
+	if resp.Request != nil && resp.Request.URL != nil {
+		final = resp.Request.URL.String()
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("status", resp.StatusCode)
+	obj.Set("statusText", http.StatusText(resp.StatusCode))
+	obj.Set("url", final)
+	obj.Set("redirected", final != url)
+	obj.Set("headers", headers)
+	switch {
+	case method == http.MethodHead, resp.StatusCode == http.StatusNoContent,
+		resp.StatusCode == http.StatusResetContent, resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		stop()
+		obj.Set("body", goja.Null())
+	default:
+		obj.Set("body", b.bodyReader(resp.Body, signal, stop))
+	}
+	return obj
+}
+
+// bodyChunkSize is the most a read of a response body returns
+const bodyChunkSize = 64 * 1024
 
-	// Call fetch asynchronously
-	promise := b.httpModule.FetchAsync(url, options)
-	return runtime.ToValue(promise)
-}
\ No newline at end of file
+// bodyReader exposes body to JS as read(), resolving with the next chunk as
+// an ArrayBuffer or with null at the end, and cancel(). The body is only
+// read when asked, so a response streams in at the pace it is consumed.
+// It is closed, and done called, at the end, on an error or once canceled.
+// Its methods must be called on the JS thread, one read at a time.
+func (b *Bridge) bodyReader(body io.ReadCloser, signal *abort.Signal, done func()) *goja.Object {
+	closed := false
+	finish := func() {
+		if !closed {
+			closed = true
+			body.Close()
+			done()
+		}
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("read", func() goja.Value {
+		p, resolver := promise.New(b.vm, b.runtime)
+		if closed {
+			resolver.Resolve(nil)
+			return p
+		}
+		go func() {
+			buf := make([]byte, bodyChunkSize)
+			n, err := body.Read(buf)
+			for n == 0 && err == nil {
+				n, err = body.Read(buf)
+			}
+			resolver.SettleWith(func() (interface{}, error) {
+				if n > 0 {
+					return b.vm.NewArrayBuffer(buf[:n]), nil
+				}
+				finish()
+				if err == io.EOF {
+					return nil, nil
+				}
+				if aborted := signal.Err(); aborted != nil {
+					return nil, aborted
+				}
+				return nil, fmt.Errorf("failed to read response body: %w", err)
+			})
+		}()
+		return p
+	})
+	obj.Set("cancel", finish)
+	return obj
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
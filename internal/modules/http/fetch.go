@@ -0,0 +1,857 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// fetchSetup defines fetch with the Fetch API classes Headers, Request,
+// Response and FormData, and File when the runtime has none. Bodies are
+// ReadableStreams from stream/web; a fetch response's streams in from the
+// network as it is read. Request bodies are read in full before the
+// request is sent. Besides what the Fetch API takes, bodies may be any
+// other value, sent as JSON, and fetch takes a timeout in milliseconds.
+//
+// Agents give requests a connection pool with their own limits, timeouts,
+// TLS and proxy settings; fetch takes one as init.agent, and uses the one
+// passed to setDefaultAgent otherwise.
+//
+// native provides check(url, method), which throws when the request is
+// not allowed, fetch (see Bridge.fetch), agent(options) and
+// closeAgent(agent), bytes(value), the bytes of a string, Buffer or typed
+// array as an ArrayBuffer, text(bytes), which decodes UTF-8, and
+// parseFormData(bytes, contentType) for multipart bodies.
+const fetchSetup = `
+(function (native) {
+	'use strict';
+	const kHeaders = Symbol('headers');
+	const kGuard = Symbol('guard');
+	const kState = Symbol('state');
+	const kBody = Symbol('body');
+	const kFile = Symbol('file');
+
+	const token = /^[!#$%&'*+\-.^_\x60|~0-9A-Za-z]+$/;
+
+	function headerName(name) {
+		name = String(name);
+		if (!token.test(name)) {
+			throw new TypeError('Invalid header name: ' + name);
+		}
+		return name.toLowerCase();
+	}
+
+	function headerValue(value) {
+		value = String(value).replace(/^[\t\n\r ]+|[\t\n\r ]+$/g, '');
+		if (/[\0\r\n]/.test(value)) {
+			throw new TypeError('Invalid header value: ' + JSON.stringify(value));
+		}
+		return value;
+	}
+
+	function writable(headers) {
+		if (headers[kGuard] === 'immutable') {
+			throw new TypeError('Headers are immutable');
+		}
+	}
+
+	class Headers {
+		constructor(init) {
+			this[kHeaders] = new Map();
+			this[kGuard] = 'none';
+			if (init === undefined || init === null) {
+				return;
+			}
+			if (init instanceof Headers) {
+				for (const [name, values] of init[kHeaders]) {
+					this[kHeaders].set(name, values.slice());
+				}
+			} else if (typeof init[Symbol.iterator] === 'function') {
+				for (const pair of init) {
+					const entry = Array.from(pair);
+					if (entry.length !== 2) {
+						throw new TypeError('Header pairs must have a name and a value');
+					}
+					this.append(entry[0], entry[1]);
+				}
+			} else if (typeof init === 'object') {
+				for (const name of Object.keys(init)) {
+					this.append(name, init[name]);
+				}
+			} else {
+				throw new TypeError('Headers must be a Headers, an iterable of pairs or an object');
+			}
+		}
+
+		append(name, value) {
+			writable(this);
+			name = headerName(name);
+			const values = this[kHeaders].get(name) || [];
+			values.push(headerValue(value));
+			this[kHeaders].set(name, values);
+		}
+
+		set(name, value) {
+			writable(this);
+			this[kHeaders].set(headerName(name), [headerValue(value)]);
+		}
+
+		get(name) {
+			const values = this[kHeaders].get(headerName(name));
+			return values ? values.join(', ') : null;
+		}
+
+		getSetCookie() {
+			return (this[kHeaders].get('set-cookie') || []).slice();
+		}
+
+		has(name) {
+			return this[kHeaders].has(headerName(name));
+		}
+
+		delete(name) {
+			writable(this);
+			this[kHeaders].delete(headerName(name));
+		}
+
+		forEach(fn, thisArg) {
+			for (const [name, value] of this) {
+				fn.call(thisArg, value, name, this);
+			}
+		}
+
+		// entries lists the headers sorted by name, each Set-Cookie on its own
+		entries() {
+			const pairs = [];
+			for (const name of Array.from(this[kHeaders].keys()).sort()) {
+				if (name === 'set-cookie') {
+					this[kHeaders].get(name).forEach(value => pairs.push([name, value]));
+				} else {
+					pairs.push([name, this.get(name)]);
+				}
+			}
+			return pairs[Symbol.iterator]();
+		}
+
+		keys() {
+			return Array.from(this.entries(), pair => pair[0])[Symbol.iterator]();
+		}
+
+		values() {
+			return Array.from(this.entries(), pair => pair[1])[Symbol.iterator]();
+		}
+
+		[Symbol.iterator]() {
+			return this.entries();
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'Headers';
+		}
+	}
+
+	const File = typeof globalThis.File === 'function' || typeof Blob !== 'function' ? globalThis.File : class File extends Blob {
+		constructor(bits, name, options) {
+			if (arguments.length < 2) {
+				throw new TypeError('File requires bits and a name');
+			}
+			super(bits, options);
+			this[kFile] = {
+				name: String(name),
+				lastModified: options && options.lastModified !== undefined ? Number(options.lastModified) : Date.now(),
+			};
+		}
+
+		get name() {
+			return this[kFile].name;
+		}
+
+		get lastModified() {
+			return this[kFile].lastModified;
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'File';
+		}
+	};
+
+	function isBlob(value) {
+		return typeof Blob === 'function' && value instanceof Blob;
+	}
+
+	function formEntry(name, value, filename) {
+		name = String(name);
+		if (isBlob(value)) {
+			if (!(value instanceof File) || filename !== undefined) {
+				const fileName = filename !== undefined ? String(filename) : value instanceof File ? value.name : 'blob';
+				value = new File([value], fileName, { type: value.type });
+			}
+			return { name, value };
+		}
+		return { name, value: String(value) };
+	}
+
+	class FormData {
+		constructor(form) {
+			if (form !== undefined) {
+				throw new TypeError('FormData cannot be created from a form');
+			}
+			this[kState] = [];
+		}
+
+		append(name, value, filename) {
+			this[kState].push(formEntry(name, value, filename));
+		}
+
+		set(name, value, filename) {
+			const entry = formEntry(name, value, filename);
+			const entries = this[kState];
+			const index = entries.findIndex(e => e.name === entry.name);
+			if (index < 0) {
+				entries.push(entry);
+				return;
+			}
+			entries[index] = entry;
+			this[kState] = entries.filter((e, i) => i <= index || e.name !== entry.name);
+		}
+
+		get(name) {
+			const entry = this[kState].find(e => e.name === String(name));
+			return entry ? entry.value : null;
+		}
+
+		getAll(name) {
+			return this[kState].filter(e => e.name === String(name)).map(e => e.value);
+		}
+
+		has(name) {
+			return this[kState].some(e => e.name === String(name));
+		}
+
+		delete(name) {
+			this[kState] = this[kState].filter(e => e.name !== String(name));
+		}
+
+		forEach(fn, thisArg) {
+			for (const [name, value] of this) {
+				fn.call(thisArg, value, name, this);
+			}
+		}
+
+		entries() {
+			return this[kState].map(e => [e.name, e.value])[Symbol.iterator]();
+		}
+
+		keys() {
+			return this[kState].map(e => e.name)[Symbol.iterator]();
+		}
+
+		values() {
+			return this[kState].map(e => e.value)[Symbol.iterator]();
+		}
+
+		[Symbol.iterator]() {
+			return this.entries();
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'FormData';
+		}
+	}
+
+	function encode(text) {
+		return new Uint8Array(native.bytes(String(text)));
+	}
+
+	function copyBytes(view) {
+		return new Uint8Array(view.buffer.slice(view.byteOffset, view.byteOffset + view.byteLength));
+	}
+
+	function bytesStream(bytes) {
+		return new ReadableStream({
+			start(c) {
+				c.enqueue(bytes);
+				c.close();
+			},
+		});
+	}
+
+	// encodeFormData encodes form as multipart/form-data, reading the files
+	// in it once the body is read
+	function encodeFormData(form) {
+		const boundary = '----gode' + Math.random().toString(16).slice(2) + Math.random().toString(16).slice(2);
+		const escape = (s) => s.replace(/"/g, '%22').replace(/\r/g, '%0D').replace(/\n/g, '%0A');
+		const entries = form[kState].slice();
+		const stream = new ReadableStream({
+			start(c) {
+				return Promise.all(entries.map(e => typeof e.value === 'string'
+					? encode(e.value)
+					: e.value.arrayBuffer().then(buffer => new Uint8Array(buffer))
+				)).then((contents) => {
+					entries.forEach((e, i) => {
+						let head = '--' + boundary + '\r\nContent-Disposition: form-data; name="' + escape(e.name) + '"';
+						if (typeof e.value !== 'string') {
+							head += '; filename="' + escape(e.value.name) + '"\r\nContent-Type: ' + (e.value.type || 'application/octet-stream');
+						}
+						c.enqueue(encode(head + '\r\n\r\n'));
+						c.enqueue(contents[i]);
+						c.enqueue(encode('\r\n'));
+					});
+					c.enqueue(encode('--' + boundary + '--\r\n'));
+					c.close();
+				});
+			},
+		});
+		return { stream, type: 'multipart/form-data; boundary=' + boundary };
+	}
+
+	// extractBody converts a body to {source, stream, type}: source holds
+	// bytes there at once, stream a body to be read, and type the content
+	// type it implies
+	function extractBody(body) {
+		if (typeof body === 'string') {
+			return { source: encode(body), type: 'text/plain;charset=UTF-8' };
+		}
+		if (body instanceof ArrayBuffer) {
+			return { source: new Uint8Array(body.slice(0)) };
+		}
+		if (ArrayBuffer.isView(body)) {
+			return { source: copyBytes(body) };
+		}
+		if (typeof URLSearchParams === 'function' && body instanceof URLSearchParams) {
+			return { source: encode(body.toString()), type: 'application/x-www-form-urlencoded;charset=UTF-8' };
+		}
+		if (isBlob(body)) {
+			const stream = new ReadableStream({
+				start(c) {
+					return body.arrayBuffer().then((buffer) => {
+						c.enqueue(new Uint8Array(buffer));
+						c.close();
+					});
+				},
+			});
+			return { stream, type: body.type || undefined };
+		}
+		if (body instanceof FormData) {
+			return encodeFormData(body);
+		}
+		if (body instanceof ReadableStream) {
+			return { stream: body };
+		}
+		const raw = native.bytes(body);
+		if (raw !== undefined) {
+			return { source: new Uint8Array(raw) };
+		}
+		return { source: encode(JSON.stringify(body)), type: 'application/json' };
+	}
+
+	function toBytes(chunk) {
+		if (chunk instanceof Uint8Array) {
+			return chunk;
+		}
+		if (chunk instanceof ArrayBuffer) {
+			return new Uint8Array(chunk);
+		}
+		if (ArrayBuffer.isView(chunk)) {
+			return new Uint8Array(chunk.buffer, chunk.byteOffset, chunk.byteLength);
+		}
+		const raw = native.bytes(chunk);
+		if (raw === undefined) {
+			throw new TypeError('Body chunks must be bytes');
+		}
+		return new Uint8Array(raw);
+	}
+
+	function newBody(init, headers) {
+		const body = { used: false, source: null, stream: null };
+		if (init !== undefined && init !== null) {
+			const extracted = extractBody(init);
+			body.source = extracted.source || null;
+			body.stream = extracted.stream || null;
+			if (extracted.type && !headers.has('content-type')) {
+				headers.set('content-type', extracted.type);
+			}
+		}
+		return body;
+	}
+
+	function cloneBody(body) {
+		if (body.stream) {
+			const [a, b] = body.stream.tee();
+			body.stream = a;
+			return { used: false, source: null, stream: b };
+		}
+		return { used: false, source: body.source, stream: null };
+	}
+
+	// consume reads a whole body as a Uint8Array
+	function consume(target) {
+		const body = target[kBody];
+		if (target.bodyUsed) {
+			return Promise.reject(new TypeError('Body has already been consumed'));
+		}
+		body.used = true;
+		if (!body.stream) {
+			return Promise.resolve(body.source ? body.source.slice() : new Uint8Array(0));
+		}
+		const reader = body.stream.getReader();
+		const chunks = [];
+		let size = 0;
+		const next = () => reader.read().then(({ value, done }) => {
+			if (done) {
+				const bytes = new Uint8Array(size);
+				let offset = 0;
+				for (const chunk of chunks) {
+					bytes.set(chunk, offset);
+					offset += chunk.byteLength;
+				}
+				return bytes;
+			}
+			const chunk = toBytes(value);
+			chunks.push(chunk);
+			size += chunk.byteLength;
+			return next();
+		});
+		return next();
+	}
+
+	function parseFormData(bytes, type) {
+		const form = new FormData();
+		if (/^application\/x-www-form-urlencoded/i.test(type)) {
+			for (const [name, value] of new URLSearchParams(native.text(bytes))) {
+				form.append(name, value);
+			}
+			return form;
+		}
+		if (/^multipart\/form-data/i.test(type)) {
+			for (const entry of native.parseFormData(bytes, type)) {
+				if (entry.filename === undefined) {
+					form.append(entry.name, entry.value);
+				} else {
+					form.append(entry.name, new File([entry.data], entry.filename, { type: entry.type }));
+				}
+			}
+			return form;
+		}
+		throw new TypeError('Body is not form data: ' + (type || 'no content type'));
+	}
+
+	const bodyMethods = {
+		get body() {
+			const body = this[kBody];
+			if (!body.stream && body.source) {
+				body.stream = bytesStream(body.source.slice());
+				body.source = null;
+			}
+			return body.stream;
+		},
+
+		get bodyUsed() {
+			const body = this[kBody];
+			return body.used || (body.stream !== null && body.stream.locked);
+		},
+
+		arrayBuffer() {
+			return consume(this).then(bytes => bytes.buffer);
+		},
+
+		bytes() {
+			return consume(this);
+		},
+
+		text() {
+			return consume(this).then(bytes => native.text(bytes));
+		},
+
+		json() {
+			return this.text().then(text => JSON.parse(text));
+		},
+
+		blob() {
+			const type = this.headers.get('content-type') || '';
+			return consume(this).then(bytes => new Blob([bytes], { type }));
+		},
+
+		formData() {
+			const type = this.headers.get('content-type') || '';
+			return consume(this).then(bytes => parseFormData(bytes, type));
+		},
+	};
+
+	const methods = ['DELETE', 'GET', 'HEAD', 'OPTIONS', 'POST', 'PUT'];
+	const redirects = ['follow', 'error', 'manual'];
+
+	class Request {
+		constructor(input, init) {
+			init = init || {};
+			const base = input instanceof Request ? input : null;
+			let url = base ? base.url : String(input);
+			if (!base && typeof URL === 'function') {
+				try {
+					url = new URL(url).href();
+				} catch (err) {
+					throw new TypeError('Invalid URL: ' + url);
+				}
+			}
+
+			let method = init.method !== undefined ? String(init.method) : base ? base.method : 'GET';
+			if (!token.test(method) || /^(connect|trace|track)$/i.test(method)) {
+				throw new TypeError('Invalid request method: ' + method);
+			}
+			if (methods.includes(method.toUpperCase())) {
+				method = method.toUpperCase();
+			}
+			const redirect = init.redirect !== undefined ? String(init.redirect) : base ? base.redirect : 'follow';
+			if (!redirects.includes(redirect)) {
+				throw new TypeError('Invalid redirect mode: ' + redirect);
+			}
+
+			const headers = new Headers(init.headers !== undefined ? init.headers : base ? base.headers : undefined);
+			let body;
+			if (init.body !== undefined && init.body !== null) {
+				if (method === 'GET' || method === 'HEAD') {
+					throw new TypeError('Request with ' + method + ' method cannot have a body');
+				}
+				body = newBody(init.body, headers);
+			} else if (base && init.body === undefined) {
+				if (base.bodyUsed) {
+					throw new TypeError('Cannot construct a Request from one whose body has been used');
+				}
+				body = base[kBody];
+				base[kBody] = { used: body.stream !== null, source: null, stream: body.stream };
+				body = { used: false, source: body.source, stream: body.stream };
+			} else {
+				body = newBody(null, headers);
+			}
+
+			this[kState] = {
+				url, method, headers, redirect,
+				signal: init.signal !== undefined ? init.signal : base ? base.signal : null,
+				timeout: init.timeout !== undefined ? init.timeout : base ? base[kState].timeout : undefined,
+			};
+			this[kBody] = body;
+		}
+
+		get url() {
+			return this[kState].url;
+		}
+
+		get method() {
+			return this[kState].method;
+		}
+
+		get headers() {
+			return this[kState].headers;
+		}
+
+		get redirect() {
+			return this[kState].redirect;
+		}
+
+		get signal() {
+			const state = this[kState];
+			if (!state.signal && typeof AbortController === 'function') {
+				state.signal = new AbortController().signal;
+			}
+			return state.signal;
+		}
+
+		clone() {
+			if (this.bodyUsed) {
+				throw new TypeError('Cannot clone a Request whose body has been used');
+			}
+			const copy = new Request(this.url, {
+				method: this.method, headers: this.headers, redirect: this.redirect,
+				signal: this[kState].signal || undefined, timeout: this[kState].timeout,
+			});
+			copy[kBody] = cloneBody(this[kBody]);
+			return copy;
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'Request';
+		}
+	}
+
+	const nullBodyStatuses = [101, 103, 204, 205, 304];
+	const redirectStatuses = [301, 302, 303, 307, 308];
+
+	function newResponse(state, body) {
+		const res = Object.create(Response.prototype);
+		res[kState] = state;
+		res[kBody] = body;
+		return res;
+	}
+
+	class Response {
+		constructor(body, init) {
+			init = init || {};
+			const status = init.status === undefined ? 200 : Number(init.status);
+			if (!Number.isInteger(status) || status < 200 || status > 599) {
+				throw new RangeError('Response status must be between 200 and 599, got ' + init.status);
+			}
+			if (body !== undefined && body !== null && nullBodyStatuses.includes(status)) {
+				throw new TypeError('Response with status ' + status + ' cannot have a body');
+			}
+			const headers = new Headers(init.headers);
+			this[kState] = {
+				type: 'default', url: '', redirected: false, status, headers,
+				statusText: init.statusText === undefined ? '' : String(init.statusText),
+			};
+			this[kBody] = newBody(body, headers);
+		}
+
+		get type() {
+			return this[kState].type;
+		}
+
+		get url() {
+			return this[kState].url;
+		}
+
+		get redirected() {
+			return this[kState].redirected;
+		}
+
+		get status() {
+			return this[kState].status;
+		}
+
+		get ok() {
+			return this[kState].status >= 200 && this[kState].status < 300;
+		}
+
+		get statusText() {
+			return this[kState].statusText;
+		}
+
+		get headers() {
+			return this[kState].headers;
+		}
+
+		clone() {
+			if (this.bodyUsed) {
+				throw new TypeError('Cannot clone a Response whose body has been used');
+			}
+			const state = Object.assign({}, this[kState], { headers: new Headers(this[kState].headers) });
+			state.headers[kGuard] = this[kState].headers[kGuard];
+			return newResponse(state, cloneBody(this[kBody]));
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'Response';
+		}
+
+		static error() {
+			const headers = new Headers();
+			headers[kGuard] = 'immutable';
+			return newResponse({ type: 'error', url: '', redirected: false, status: 0, statusText: '', headers },
+				{ used: false, source: null, stream: null });
+		}
+
+		static redirect(url, status) {
+			status = status === undefined ? 302 : Number(status);
+			if (!redirectStatuses.includes(status)) {
+				throw new RangeError('Invalid redirect status: ' + status);
+			}
+			const location = typeof URL === 'function' ? new URL(String(url)).href() : String(url);
+			const res = new Response(null, { status, headers: { location } });
+			res.headers[kGuard] = 'immutable';
+			return res;
+		}
+
+		static json(data, init) {
+			const text = JSON.stringify(data);
+			if (text === undefined) {
+				throw new TypeError('Response.json data is not JSON serializable');
+			}
+			const res = new Response(text, init);
+			if (res.headers.get('content-type') === 'text/plain;charset=UTF-8') {
+				res.headers.set('content-type', 'application/json');
+			}
+			return res;
+		}
+	}
+
+	for (const cls of [Request, Response]) {
+		Object.defineProperties(cls.prototype, Object.getOwnPropertyDescriptors(bodyMethods));
+	}
+
+	// nativeStream reads the body of a fetch response from the network as
+	// it is pulled
+	function nativeStream(reader) {
+		return new ReadableStream({
+			pull(c) {
+				return reader.read().then((chunk) => {
+					if (chunk === null) {
+						c.close();
+					} else {
+						c.enqueue(new Uint8Array(chunk));
+					}
+				});
+			},
+			cancel() {
+				reader.cancel();
+			},
+		}, { highWaterMark: 0 });
+	}
+
+	const kAgent = Symbol('agent');
+	let defaultAgent = null;
+
+	class Agent {
+		constructor(options) {
+			if (options !== undefined && (options === null || typeof options !== 'object')) {
+				throw new TypeError('Agent options must be an object');
+			}
+			this[kAgent] = native.agent(options);
+		}
+
+		// destroy closes the idle connections; the agent can still be used
+		destroy() {
+			native.closeAgent(this[kAgent]);
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'Agent';
+		}
+	}
+
+	function setDefaultAgent(agent) {
+		if (agent !== null && agent !== undefined && !(agent instanceof Agent)) {
+			throw new TypeError('setDefaultAgent expects an Agent or null');
+		}
+		defaultAgent = agent || null;
+	}
+
+	function fetch(input, init) {
+		let request;
+		let agent = defaultAgent;
+		try {
+			request = new Request(input, init);
+			if (init && init.agent !== undefined && init.agent !== null) {
+				if (!(init.agent instanceof Agent)) {
+					throw new TypeError('agent must be an Agent');
+				}
+				agent = init.agent;
+			}
+		} catch (err) {
+			return Promise.reject(err);
+		}
+		// Requests that are not allowed fail at once
+		native.check(request.url, request.method);
+
+		const state = request[kState];
+		const send = (bytes) => native.fetch(request.url, {
+			method: state.method,
+			headers: Array.from(state.headers),
+			body: bytes === undefined ? undefined : bytes.buffer,
+			timeout: state.timeout,
+			redirect: state.redirect,
+			agent: agent ? agent[kAgent] : undefined,
+			signal: state.signal || undefined,
+		}).then((raw) => {
+			const headers = new Headers(raw.headers);
+			headers[kGuard] = 'immutable';
+			return newResponse({
+				type: 'basic', url: raw.url, redirected: raw.redirected,
+				status: raw.status, statusText: raw.statusText, headers,
+			}, { used: false, source: null, stream: raw.body === null ? null : nativeStream(raw.body) });
+		});
+
+		const body = request[kBody];
+		if (!body.stream) {
+			return send(body.source || undefined);
+		}
+		return consume(request).then(send);
+	}
+
+	return { fetch, Headers, Request, Response, FormData, File, Agent, setDefaultAgent };
+})
+`
+
+// fetchNames are the globals defined by fetchSetup
+var fetchNames = []string{"fetch", "Headers", "Request", "Response", "FormData", "File"}
+
+// agentNames are the exports of fetchSetup that gode:http exports
+var agentNames = []string{"Agent", "setDefaultAgent"}
+
+// Globals builds fetch and the Fetch API classes. check is called with the
+// URL and method of each request before it is sent, and throws when it is
+// not allowed. It must be called on the JS thread.
+func (b *Bridge) Globals(check func(url, method string)) (*goja.Object, error) {
+	native := b.vm.NewObject()
+	native.Set("check", check)
+	native.Set("fetch", b.fetch)
+	native.Set("agent", b.newAgent)
+	native.Set("closeAgent", func(agent *Agent) {
+		agent.Close()
+	})
+	native.Set("bytes", func(value goja.Value) goja.Value {
+		if data, ok := bodyBytes(value); ok {
+			return b.vm.ToValue(b.vm.NewArrayBuffer(append([]byte(nil), data...)))
+		}
+		return goja.Undefined()
+	})
+	native.Set("text", func(value goja.Value) string {
+		data, _ := bodyBytes(value)
+		return string(data)
+	})
+	native.Set("parseFormData", b.parseFormData)
+
+	setup, err := jsprogram.Run(b.vm, "fetch-setup", fetchSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("fetch setup did not return a function")
+	}
+	globals, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return globals.ToObject(b.vm), nil
+}
+
+// parseFormData implements native.parseFormData(bytes, contentType): the
+// parts of a multipart/form-data body as {name, value} for fields and
+// {name, filename, type, data} for files, data being an ArrayBuffer
+func (b *Bridge) parseFormData(body goja.Value, contentType string) []interface{} {
+	data, _ := bodyBytes(body)
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["boundary"] == "" {
+		panic(b.vm.NewTypeError("invalid multipart/form-data content type: " + contentType))
+	}
+
+	var entries []interface{}
+	reader := multipart.NewReader(bytes.NewReader(data), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			panic(jserror.New(b.vm, fmt.Errorf("invalid multipart/form-data body: %w", err)))
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			panic(jserror.New(b.vm, fmt.Errorf("invalid multipart/form-data body: %w", err)))
+		}
+		entry := map[string]interface{}{"name": part.FormName()}
+		if filename := part.FileName(); filename != "" {
+			entry["filename"] = filename
+			entry["type"] = part.Header.Get("Content-Type")
+			entry["data"] = b.vm.NewArrayBuffer(content)
+		} else {
+			entry["value"] = string(content)
+		}
+		entries = append(entries, entry)
+	}
+}
@@ -0,0 +1,90 @@
+package http_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestFetchStreaming(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chunks":
+			for _, chunk := range []string{"one ", "two ", "three"} {
+				io.WriteString(w, chunk)
+				w.(http.Flusher).Flush()
+			}
+		case "/moved":
+			http.Redirect(w, r, "/chunks", http.StatusFound)
+		case "/echo":
+			w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+			w.Header().Add("Set-Cookie", "a=1")
+			w.Header().Add("Set-Cookie", "b=2")
+			io.Copy(w, r.Body)
+		}
+	}))
+	defer upstream.Close()
+
+	value, err := rt.RunScriptAsync("fetch-streaming", `
+		(async () => {
+			const base = `+strconv.Quote(upstream.URL)+`;
+			const res = await fetch(base + '/chunks');
+			const reader = res.body.getReader();
+			let text = '';
+			for (let r = await reader.read(); !r.done; r = await reader.read()) {
+				text += new TextDecoder().decode(r.value);
+			}
+			const streamed = [res.ok, res.bodyUsed, text].join(',');
+
+			const moved = await fetch(base + '/moved');
+			const manual = await fetch(base + '/moved', { redirect: 'manual' });
+			const refused = await fetch(base + '/moved', { redirect: 'error' }).then(() => 'followed', () => 'refused');
+			const redirects = [moved.redirected, moved.url.endsWith('/chunks'), await moved.text(),
+				manual.status, manual.headers.get('location'), refused].join(',');
+
+			const form = new FormData();
+			form.append('name', 'gode');
+			form.append('file', new Blob(['hello'], { type: 'text/plain' }), 'hello.txt');
+			const echoed = await fetch(new Request(base + '/echo', { method: 'post', body: form }));
+			const copy = echoed.clone();
+			const parsed = await echoed.formData();
+			const file = parsed.get('file');
+			const forms = [parsed.get('name'), file.name, file.type, await file.text(),
+				(await copy.text()).includes('hello.txt'), echoed.headers.getSetCookie().join(';')].join(',');
+
+			const headers = new Headers({ 'X-A': '1' });
+			headers.append('x-a', '2');
+			let immutable;
+			try { echoed.headers.set('x', '1'); } catch (e) { immutable = e.name; }
+			const built = Response.json({ ok: true }, { status: 201 });
+			const misc = [headers.get('X-A'), [...headers.keys()].join(), immutable,
+				built.status, built.headers.get('content-type'), JSON.stringify(await built.json()),
+				new Request('http://example.com/a').method, Response.error().type].join(',');
+
+			return [streamed, redirects, forms, misc].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"true,true,one two three",
+		"true,true,one two three,302,/chunks,refused",
+		"gode,hello.txt,text/plain,hello,true,a=1;b=2",
+		"1, 2,x-a,TypeError,201,application/json,{\"ok\":true},GET,error",
+	}, "|")
+	if value != want {
+		t.Errorf("fetch results =\n%v\nwant\n%s", value, want)
+	}
+}
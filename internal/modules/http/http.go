@@ -2,7 +2,9 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +12,7 @@ import (
 	"time"
 
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/proxy"
 )
 
 // HTTPModule provides HTTP functionality including fetch API
@@ -22,9 +25,7 @@ type HTTPModule struct {
 func NewHTTPModule(runtime *goja.Runtime) *HTTPModule {
 	return &HTTPModule{
 		runtime: runtime,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  proxy.Client(30 * time.Second),
 	}
 }
 
@@ -34,6 +35,13 @@ type FetchOptions struct {
 	Headers map[string]string      `json:"headers"`
 	Body    interface{}            `json:"body"`
 	Timeout int                    `json:"timeout"` // in milliseconds
+	Context context.Context        `json:"-"`       // cancels the request; nil for none
+	// Redirect is "follow", the default, "manual", which returns redirect
+	// responses as they are, or "error", which fails on them
+	Redirect string `json:"redirect"`
+	// Agent sends the request over its connection pool instead of the
+	// shared one
+	Agent *Agent `json:"-"`
 }
 
 // FetchResponse represents a fetch response
@@ -41,12 +49,49 @@ type FetchResponse struct {
 	Status     int               `json:"status"`
 	StatusText string            `json:"statusText"`
 	Headers    map[string]string `json:"headers"`
-	Body       string            `json:"body"`
+	Body       []byte            `json:"body"`
 	OK         bool              `json:"ok"`
 }
 
-// Fetch implements the fetch API
+// Fetch implements the fetch API, reading the whole response body
 func (h *HTTPModule) Fetch(url string, options *FetchOptions) (*FetchResponse, error) {
+	resp, err := h.Open(url, options)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read response body, kept as bytes so binary payloads survive
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Convert response headers, with lower-case names as in the Fetch API
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		headers[strings.ToLower(key)] = strings.Join(values, ", ")
+	}
+
+	// Create fetch response
+	fetchResp := &FetchResponse{
+		Status:     resp.StatusCode,
+		StatusText: http.StatusText(resp.StatusCode),
+		Headers:    headers,
+		Body:       respBody,
+		OK:         resp.StatusCode >= 200 && resp.StatusCode < 300,
+	}
+
+	return fetchResp, nil
+}
+
+// errRedirect fails requests made with Redirect "error" that are redirected
+var errRedirect = errors.New("unexpected redirect")
+
+// Open sends a request like Fetch, but returns as soon as the response
+// headers have arrived, leaving the body to be read. The caller must close
+// it.
+func (h *HTTPModule) Open(url string, options *FetchOptions) (*http.Response, error) {
 	// Set default options
 	if options == nil {
 		options = &FetchOptions{
@@ -83,7 +128,11 @@ func (h *HTTPModule) Fetch(url string, options *FetchOptions) (*FetchResponse, e
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest(options.Method, url, body)
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, options.Method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -95,10 +144,27 @@ func (h *HTTPModule) Fetch(url string, options *FetchOptions) (*FetchResponse, e
 
 	// Set timeout if specified
 	client := h.client
+	if options.Agent != nil {
+		client = options.Agent.client
+	}
 	if options.Timeout > 0 {
-		client = &http.Client{
-			Timeout: time.Duration(options.Timeout) * time.Millisecond,
+		timed := *client
+		timed.Timeout = time.Duration(options.Timeout) * time.Millisecond
+		client = &timed
+	}
+	switch options.Redirect {
+	case "", "follow":
+	case "manual", "error":
+		redirecting := *client
+		redirecting.CheckRedirect = func(*http.Request, []*http.Request) error {
+			if options.Redirect == "manual" {
+				return http.ErrUseLastResponse
+			}
+			return errRedirect
 		}
+		client = &redirecting
+	default:
+		return nil, fmt.Errorf("invalid redirect mode %q", options.Redirect)
 	}
 
 	// Make request
@@ -106,55 +172,5 @@ func (h *HTTPModule) Fetch(url string, options *FetchOptions) (*FetchResponse, e
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Convert response headers
-	headers := make(map[string]string)
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
-		}
-	}
-
-	// Create fetch response
-	fetchResp := &FetchResponse{
-		Status:     resp.StatusCode,
-		StatusText: resp.Status,
-		Headers:    headers,
-		Body:       string(respBody),
-		OK:         resp.StatusCode >= 200 && resp.StatusCode < 300,
-	}
-
-	return fetchResp, nil
+	return resp, nil
 }
-
-// FetchAsync implements fetch with Promise support
-func (h *HTTPModule) FetchAsync(url string, options *FetchOptions) *goja.Promise {
-	promise, resolve, reject := h.runtime.NewPromise()
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				reject(h.runtime.NewTypeError(fmt.Sprintf("fetch panic: %v", r)))
-			}
-		}()
-
-		result, err := h.Fetch(url, options)
-		
-		if err != nil {
-			reject(h.runtime.NewTypeError(err.Error()))
-		} else {
-			// Convert result to JavaScript object
-			jsResult := h.runtime.ToValue(result)
-			resolve(jsResult)
-		}
-	}()
-
-	return promise
-}
\ No newline at end of file
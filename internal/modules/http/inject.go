@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// InjectOptions describes a request made with testServer(app).request
+type InjectOptions struct {
+	Method        string
+	Path          string
+	Headers       map[string]string
+	Body          []byte
+	RemoteAddress string // of the simulated client, 127.0.0.1 by default
+}
+
+// Inject dispatches a request straight to handler, without a network
+// connection, and returns the recorded response
+func Inject(handler http.Handler, opts InjectOptions) (*httptest.ResponseRecorder, error) {
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := opts.Path
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("request path must start with /: %q", path)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(method), "http://localhost"+path, bytes.NewReader(opts.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	if opts.RemoteAddress != "" {
+		req.RemoteAddr = net.JoinHostPort(opts.RemoteAddress, "0")
+	}
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder, nil
+}
+
+// NewTestServer implements testServer(app): an object whose
+// request({method, path, headers, body, remoteAddress}) runs app, a request
+// listener, for one request and resolves with {status, statusText,
+// headers, body, text(), json()}, body being a Buffer. A body that is not a
+// string or bytes is sent as JSON. When app is a server from createServer,
+// its trustProxy and requestId options apply.
+// It must be called on the JS thread.
+func NewTestServer(vm *goja.Runtime, queue promise.Queue, app goja.Value) (*goja.Object, error) {
+	handler, err := NewHandler(vm, queue, app)
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := app.(*goja.Object); ok {
+		if options, ok := obj.Get("_options").(*goja.Object); ok {
+			if handler.TrustProxy, err = trustProxy(options.Get("trustProxy")); err != nil {
+				return nil, err
+			}
+			if v := options.Get("requestId"); !isNullish(v) {
+				handler.DisableRequestID = !v.ToBoolean()
+			}
+		}
+	}
+
+	server := vm.NewObject()
+	server.Set("request", func(call goja.FunctionCall) goja.Value {
+		opts, err := injectOptions(vm, call.Argument(0))
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		p, resolver := promise.New(vm, queue)
+		go func() {
+			recorder, err := Inject(handler, opts)
+			resolver.SettleWith(func() (interface{}, error) {
+				if err != nil {
+					return nil, err
+				}
+				return injectResponse(vm, recorder), nil
+			})
+		}()
+		return p
+	})
+	return server, nil
+}
+
+// injectOptions reads the options of testServer(app).request
+func injectOptions(vm *goja.Runtime, value goja.Value) (InjectOptions, error) {
+	opts := InjectOptions{Headers: make(map[string]string)}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		if value != nil && !goja.IsUndefined(value) {
+			opts.Path = value.String() // request('/path')
+		}
+		return opts, nil
+	}
+
+	if v := obj.Get("method"); v != nil && !goja.IsUndefined(v) {
+		opts.Method = v.String()
+	}
+	if v := obj.Get("path"); v != nil && !goja.IsUndefined(v) {
+		opts.Path = v.String()
+	}
+	if v := obj.Get("remoteAddress"); v != nil && !goja.IsUndefined(v) {
+		opts.RemoteAddress = v.String()
+	}
+	if headers, ok := obj.Get("headers").(*goja.Object); ok {
+		for _, name := range headers.Keys() {
+			opts.Headers[name] = headers.Get(name).String()
+		}
+	}
+
+	body := obj.Get("body")
+	if body == nil || goja.IsUndefined(body) || goja.IsNull(body) {
+		return opts, nil
+	}
+	if data, ok := bodyBytes(body); ok {
+		opts.Body = data
+		return opts, nil
+	}
+	encoded, err := jsonBody(body)
+	if err != nil {
+		return opts, err
+	}
+	opts.Body = encoded
+	if !hasHeader(opts.Headers, "Content-Type") {
+		opts.Headers["Content-Type"] = "application/json"
+	}
+	return opts, nil
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// injectResponse converts a recorded response for JS. It must be called on
+// the JS thread.
+func injectResponse(vm *goja.Runtime, recorder *httptest.ResponseRecorder) *goja.Object {
+	result := recorder.Result()
+	body, _ := io.ReadAll(result.Body)
+
+	headers := vm.NewObject()
+	for name, values := range result.Header {
+		headers.Set(strings.ToLower(name), strings.Join(values, ", "))
+	}
+
+	obj := vm.NewObject()
+	obj.Set("status", result.StatusCode)
+	obj.Set("statusText", http.StatusText(result.StatusCode))
+	obj.Set("headers", headers)
+	defineBody(vm, obj, body, false)
+	return obj
+}
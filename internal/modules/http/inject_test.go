@@ -0,0 +1,78 @@
+package http_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestTestServer(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	_, err := rt.RunScript("app", `
+		globalThis.server = testServer((req, res) => {
+			if (req.path === '/boom') throw new Error('boom');
+			if (req.method === 'POST') {
+				res.writeHead(201, { 'Content-Type': 'application/json' });
+				res.end(JSON.stringify({ got: req.json(), id: req.query.id, auth: req.headers.authorization }));
+				return;
+			}
+			res.setHeader('X-Path', req.path);
+			res.end('hello');
+		});
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+
+	result, err := rt.RunScriptAsync("get", `server.request({ path: '/greet' })
+		.then(res => [res.status, res.headers['x-path'], res.text()].join('|'))`)
+	if err != nil || result != "200|/greet|hello" {
+		t.Errorf("GET = %v, %v", result, err)
+	}
+
+	result, err = rt.RunScriptAsync("post", `server.request({
+			method: 'POST', path: '/items?id=7', headers: { Authorization: 'Bearer t' }, body: { name: 'x' }
+		}).then(res => [res.status, res.headers['content-type'], JSON.stringify(res.json())].join('|'))`)
+	want := `201|application/json|{"got":{"name":"x"},"id":"7","auth":"Bearer t"}`
+	if text, _ := result.(string); err != nil || !sameParts(text, want) {
+		t.Errorf("POST = %v, %v, want %s", result, err, want)
+	}
+
+	result, err = rt.RunScriptAsync("error", "server.request('/boom').then(res => res.status)")
+	if err != nil || result != int64(500) {
+		t.Errorf("Expected a throwing listener to give 500, got %v, %v", result, err)
+	}
+}
+
+// sameJSON reports whether got and want are the same JSON value. The JSON
+// global does not keep the order of object keys, so the text may differ.
+func sameJSON(got, want string) bool {
+	var a, b interface{}
+	if json.Unmarshal([]byte(got), &a) != nil || json.Unmarshal([]byte(want), &b) != nil {
+		return got == want
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sameParts compares "|"-separated results, parts holding JSON objects
+// with sameJSON
+func sameParts(got, want string) bool {
+	gotParts, wantParts := strings.Split(got, "|"), strings.Split(want, "|")
+	if len(gotParts) != len(wantParts) {
+		return false
+	}
+	for i := range gotParts {
+		if !sameJSON(gotParts[i], wantParts[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,227 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long Shutdown waits for requests in
+// flight before closing their connections
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultReadHeaderTimeout bounds how long a client may take to send the
+// headers of a request
+const DefaultReadHeaderTimeout = 10 * time.Second
+
+// DefaultIdleTimeout bounds how long a keep-alive connection may wait for
+// its next request
+const DefaultIdleTimeout = 60 * time.Second
+
+// ServerOptions limits what a Server accepts from its clients. Zero
+// durations and sizes take the defaults.
+type ServerOptions struct {
+	ReadHeaderTimeout  time.Duration // to receive the headers; 408 after, 10s by default
+	RequestTimeout     time.Duration // to receive the whole request; 408 after, no limit by default
+	IdleTimeout        time.Duration // between requests on a keep-alive connection, 60s by default
+	MaxHeaderBytes     int           // size of the headers; 431 beyond, 1MB by default
+	MaxRequestBodySize int64         // size of the body; 413 beyond, no limit by default
+	StreamBody         bool          // hands the body to the listener as a stream instead of a Buffer
+	TrustProxy         *TrustProxy   // proxies trusted to report the client address; none by default
+	DisableRequestID   bool          // skips X-Request-Id handling, which is on by default
+	TLS                *tls.Config   // serves HTTPS with it; plain HTTP when nil
+}
+
+// Server is an HTTP server listening on a TCP address
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+	done     chan struct{}
+}
+
+// Listen starts serving handler on addr, such as ":8080" or
+// "127.0.0.1:0" for a free port, within the limits of opts, which may be
+// nil. A client that starts a request without finishing its headers in
+// time gets a 408 before its connection is closed, so slow clients cannot
+// hold connections open. With opts.TLS, connections are TLS over HTTP/1.1.
+func Listen(addr string, handler *Handler, opts *ServerOptions) (*Server, error) {
+	if opts == nil {
+		opts = &ServerOptions{}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TLS != nil {
+		// timedConn sits on top of TLS, so that its 408 is encrypted too
+		config := opts.TLS.Clone()
+		config.NextProtos = []string{"http/1.1"}
+		listener = tls.NewListener(listener, config)
+	}
+
+	readHeaderTimeout := opts.ReadHeaderTimeout
+	if readHeaderTimeout <= 0 {
+		readHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	handler.MaxRequestBodySize = opts.MaxRequestBodySize
+	handler.StreamBody = opts.StreamBody
+	handler.TrustProxy = opts.TrustProxy
+	handler.DisableRequestID = opts.DisableRequestID
+
+	s := &Server{
+		listener: &timedListener{Listener: listener},
+		server: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       opts.RequestTimeout,
+			IdleTimeout:       idleTimeout,
+			MaxHeaderBytes:    opts.MaxHeaderBytes,
+			ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+				if conn, ok := c.(*timedConn); ok {
+					return context.WithValue(ctx, connKey{}, conn.info)
+				}
+				return ctx
+			},
+		},
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		s.server.Serve(s.listener)
+	}()
+	return s, nil
+}
+
+// Address describes the listening address as in Node's server.address():
+// {address, family, port}
+func (s *Server) Address() map[string]interface{} {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	family := "IPv4"
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		family = "IPv6"
+	}
+	n, _ := strconv.Atoi(port)
+	return map[string]interface{}{
+		"address": host,
+		"family":  family,
+		"port":    n,
+	}
+}
+
+// Shutdown stops accepting connections and waits for requests in flight
+// to finish. Requests still running after timeout have their connections
+// closed. It must not be called from the JS thread, which those requests
+// need in order to finish.
+func (s *Server) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = s.server.Close()
+	}
+	<-s.done
+	return err
+}
+
+// Close stops the server at once, closing every connection
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// connKey is the context key of the connInfo of a request
+type connKey struct{}
+
+// connInfo describes a connection, for the timing of its requests
+type connInfo struct {
+	id          int64
+	connectedAt time.Time
+	requests    int32 // requests dispatched so far
+
+	mu       sync.Mutex
+	active   bool // a request is being handled
+	partial  bool // bytes of a request not dispatched yet were received
+	timedOut bool // a 408 was sent
+}
+
+// received notes bytes read from the client
+func (c *connInfo) received() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.active {
+		c.partial = true
+	}
+}
+
+// dispatch notes the start of a request, returning its number on the
+// connection, and done its end
+func (c *connInfo) dispatch() (number int32, done func()) {
+	c.mu.Lock()
+	c.active = true
+	c.partial = false
+	c.mu.Unlock()
+	return atomic.AddInt32(&c.requests, 1), func() {
+		c.mu.Lock()
+		c.active = false
+		c.mu.Unlock()
+	}
+}
+
+// headersTimedOut reports, once, whether a read timed out in the middle of
+// the headers of a request
+func (c *connInfo) headersTimedOut() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.partial || c.active || c.timedOut {
+		return false
+	}
+	c.timedOut = true
+	return true
+}
+
+// timedListener accepts timedConns
+type timedListener struct {
+	net.Listener
+	conns int64
+}
+
+func (l *timedListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	info := &connInfo{id: atomic.AddInt64(&l.conns, 1), connectedAt: time.Now()}
+	return &timedConn{Conn: c, info: info}, nil
+}
+
+// timedConn tracks the requests of a connection, and answers a client
+// whose headers time out with a 408
+type timedConn struct {
+	net.Conn
+	info *connInfo
+}
+
+const requestTimeoutResponse = "HTTP/1.1 408 Request Timeout\r\nConnection: close\r\nContent-Length: 0\r\n\r\n"
+
+func (c *timedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.info.received()
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && c.info.headersTimedOut() {
+		c.Conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.Conn.Write([]byte(requestTimeoutResponse))
+	}
+	return n, err
+}
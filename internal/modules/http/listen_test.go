@@ -0,0 +1,70 @@
+package http_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestServerLimits(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	port, err := rt.RunScriptAsync("server", `
+		const { createServer } = require('gode:http');
+		globalThis.server = createServer({ readHeaderTimeout: 100, maxHeaderBytes: 1024, maxRequestBodySize: 4 }, (req, res) => {
+			const t = req.timing;
+			res.end([t.connectionId > 0, t.requestNumber, t.connectedAt <= t.startedAt, typeof t.bodyTime].join(','));
+		});
+		server.listen(0, '127.0.0.1').then((address) => address.port);
+	`)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	addr := fmt.Sprintf("127.0.0.1:%v", port)
+
+	// statusLine sends raw and returns the status line of the answer
+	statusLine := func(raw string) string {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		defer conn.Close()
+		conn.Write([]byte(raw))
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		return strings.TrimSpace(line)
+	}
+
+	if got := statusLine("GET / HTTP/1.1\r\nHost: x\r\n"); got != "HTTP/1.1 408 Request Timeout" {
+		t.Errorf("Slow headers got %q, want a 408", got)
+	}
+	if got := statusLine("POST / HTTP/1.1\r\nHost: x\r\nContent-Length: 10\r\n\r\n0123456789"); got != "HTTP/1.1 413 Request Entity Too Large" {
+		t.Errorf("Large body got %q, want a 413", got)
+	}
+	if got := statusLine("GET / HTTP/1.1\r\nHost: x\r\nX-Big: " + strings.Repeat("a", 8000) + "\r\n\r\n"); got != "HTTP/1.1 431 Request Header Fields Too Large" {
+		t.Errorf("Large headers got %q, want a 431", got)
+	}
+
+	res, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if string(body) != "true,1,true,number" {
+		t.Errorf("req.timing = %q", body)
+	}
+
+	rt.RunScriptAsync("close", `server.close()`)
+}
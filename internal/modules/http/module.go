@@ -0,0 +1,723 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+	godetls "github.com/rizqme/gode/internal/modules/tls"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// serverSetup builds createServer on top of the native listen. A Server
+// runs its middleware in the order they were added, then the request
+// listener; requests nothing handles get a 404. next() returns a promise of
+// the rest of the chain, so a middleware can await it to run code
+// afterwards, and next(err) fails the request with a 500. Routes are
+// middleware matching a method and path, validating against their schemas
+// and described by openapi().
+const serverSetup = `
+(function (native) {
+	const routeMethods = ['get', 'post', 'put', 'patch', 'delete', 'head', 'options'];
+
+	// compilePath turns a route path such as /users/:id/* into a RegExp and
+	// the names of its parameters
+	function compilePath(path) {
+		const names = [];
+		const source = path.split('/').map((segment) => {
+			if (segment.startsWith(':')) {
+				names.push(segment.slice(1));
+				return '([^/]+)';
+			}
+			if (segment === '*') {
+				names.push('*');
+				return '(.*)';
+			}
+			return segment.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+		}).join('/');
+		return { regexp: new RegExp('^' + source + '/?$'), names: names };
+	}
+
+	// jsonSchema describes a route schema as JSON Schema: gode:validate rules
+	// convert themselves, anything else is taken to be JSON Schema already
+	function jsonSchema(schema) {
+		if (schema && typeof schema.toJSONSchema === 'function') {
+			return schema.toJSONSchema();
+		}
+		return schema;
+	}
+
+	// coerce converts the string values of path parameters and the query to
+	// the numbers and booleans their schema asks for
+	function coerce(values, schema) {
+		const properties = (schema && schema.properties) || {};
+		const result = {};
+		for (const name of Object.keys(values)) {
+			const type = properties[name] && properties[name].type;
+			const raw = values[name];
+			if ((type === 'number' || type === 'integer') && raw !== '' && !isNaN(Number(raw))) {
+				result[name] = Number(raw);
+			} else if (type === 'boolean' && (raw === 'true' || raw === 'false')) {
+				result[name] = raw === 'true';
+			} else {
+				result[name] = raw;
+			}
+		}
+		return result;
+	}
+
+	// check validates value against a gode:validate rule, adding what fails
+	// to errors with paths under where; JSON Schema is not validated
+	function check(schema, value, where, errors) {
+		if (!schema || typeof schema.validate !== 'function') {
+			return;
+		}
+		for (const e of schema.validate(value).errors) {
+			errors.push({ path: e.path ? where + '.' + e.path : where, message: e.message });
+		}
+	}
+
+	function required(schema) {
+		return !schema || typeof schema.validate !== 'function' || !schema.validate(undefined).valid;
+	}
+
+	// parameters lists the OpenAPI parameters in a location from a schema
+	// of their object; path parameters are listed even without one
+	function parameters(schema, location, names) {
+		const described = jsonSchema(schema) || {};
+		const properties = described.properties || {};
+		const needed = described.required || [];
+		const list = (names || Object.keys(properties)).filter(name => name !== '*');
+		return list.map(name => ({
+			name: name,
+			in: location,
+			required: location === 'path' || needed.includes(name),
+			schema: properties[name] || { type: 'string' },
+		}));
+	}
+
+	function escapeHTML(value) {
+		return String(value).replace(/[&<>"']/g, c => '&#' + c.charCodeAt(0) + ';');
+	}
+
+	// docsPage renders an OpenAPI document as a self-contained HTML page
+	function docsPage(doc, specPath) {
+		const title = escapeHTML(doc.info.title + ' ' + doc.info.version);
+		let html = '<!doctype html><html><head><meta charset="utf-8"><title>' + title + '</title>' +
+			'<style>body{font-family:sans-serif;max-width:60em;margin:2em auto;padding:0 1em}' +
+			'.op{border:1px solid #ddd;border-radius:4px;margin:1em 0;padding:.5em 1em}' +
+			'.method{font-weight:bold;text-transform:uppercase;margin-right:.5em}' +
+			'pre{background:#f6f6f6;padding:.5em;overflow:auto}</style></head><body>' +
+			'<h1>' + title + '</h1>';
+		if (doc.info.description) {
+			html += '<p>' + escapeHTML(doc.info.description) + '</p>';
+		}
+		html += '<p><a href="' + escapeHTML(specPath) + '">OpenAPI document</a></p>';
+		for (const path of Object.keys(doc.paths)) {
+			for (const method of Object.keys(doc.paths[path])) {
+				const op = doc.paths[path][method];
+				html += '<div class="op"><h3><span class="method">' + escapeHTML(method) + '</span>' + escapeHTML(path) + '</h3>';
+				if (op.summary) {
+					html += '<p>' + escapeHTML(op.summary) + '</p>';
+				}
+				if (op.description) {
+					html += '<p>' + escapeHTML(op.description) + '</p>';
+				}
+				const sections = { Parameters: op.parameters, 'Request body': op.requestBody, Responses: op.responses };
+				for (const name of Object.keys(sections)) {
+					if (sections[name]) {
+						html += '<h4>' + name + '</h4><pre>' + escapeHTML(JSON.stringify(sections[name], null, 2)) + '</pre>';
+					}
+				}
+				html += '</div>';
+			}
+		}
+		return html + '</body></html>';
+	}
+
+	class Server {
+		constructor(options, listener) {
+			if (typeof options === 'function') {
+				listener = options;
+				options = undefined;
+			}
+			if (listener !== undefined && typeof listener !== 'function') {
+				throw new TypeError('createServer expects a request listener function');
+			}
+			this._options = options || {};
+			this._listener = listener;
+			this._stack = [];
+			this._routes = [];
+			this._native = null;
+			this.handle = this.handle.bind(this);
+		}
+
+		get listening() {
+			return this._native !== null;
+		}
+
+		use(middleware) {
+			if (typeof middleware !== 'function') {
+				throw new TypeError('use() expects a middleware function');
+			}
+			this._stack.push(middleware);
+			return this;
+		}
+
+		// route adds a handler for method and path, which may have :name
+		// parameters and a trailing *. options.schema may describe params,
+		// query, body and response (a map of status codes); schemas are
+		// gode:validate rules, which are enforced, or JSON Schema, which
+		// is only documented. Params and query values are converted to
+		// the types their schema asks for, and a validated JSON body is
+		// parsed into req.data. Invalid requests get a 400 with the
+		// errors. summary, description, tags, operationId and deprecated
+		// go into the OpenAPI document; hidden leaves the route out.
+		route(method, path, options, handler) {
+			if (typeof options === 'function') {
+				handler = options;
+				options = undefined;
+			}
+			if (typeof handler !== 'function') {
+				throw new TypeError('route() expects a handler function');
+			}
+			if (typeof path !== 'string' || !path.startsWith('/')) {
+				throw new TypeError('route paths must start with /');
+			}
+			method = String(method).toUpperCase();
+			options = options || {};
+			const schema = options.schema || {};
+			const compiled = compilePath(path);
+			const paramsSchema = jsonSchema(schema.params);
+			const querySchema = jsonSchema(schema.query);
+			this._routes.push({ method: method, path: path, options: options, names: compiled.names });
+
+			return this.use((req, res, next) => {
+				if (req.method !== method && !(method === 'GET' && req.method === 'HEAD')) {
+					return next();
+				}
+				const match = compiled.regexp.exec(req.path);
+				if (!match) {
+					return next();
+				}
+				const errors = [];
+				const params = {};
+				compiled.names.forEach((name, i) => {
+					try {
+						params[name] = decodeURIComponent(match[i + 1]);
+					} catch (err) {
+						errors.push({ path: 'params.' + name, message: 'is not properly encoded' });
+					}
+				});
+				req.params = coerce(params, paramsSchema);
+				req.query = coerce(req.query, querySchema);
+				req.route = { method: method, path: path };
+				check(schema.params, req.params, 'params', errors);
+				check(schema.query, req.query, 'query', errors);
+				const respond = () => {
+					if (errors.length > 0) {
+						res.statusCode = 400;
+						res.setHeader('Content-Type', 'application/json');
+						res.end(JSON.stringify({ error: 'Bad Request', errors: errors }));
+						return;
+					}
+					return handler(req, res, next);
+				};
+				if (schema.body === undefined) {
+					return respond();
+				}
+				// text() returns a promise when the body is streamed
+				return Promise.resolve(req.text()).then((text) => {
+					let body;
+					try {
+						body = text.length > 0 ? JSON.parse(text) : undefined;
+					} catch (err) {
+						errors.push({ path: 'body', message: 'must be valid JSON' });
+					}
+					if (errors.length === 0) {
+						check(schema.body, body, 'body', errors);
+						req.data = body;
+					}
+					return respond();
+				});
+			});
+		}
+
+		// openapi describes the routes as an OpenAPI 3.1 document. info
+		// may set title, version, description and servers.
+		openapi(info) {
+			info = info || {};
+			const paths = {};
+			for (const route of this._routes) {
+				if (route.options.hidden) {
+					continue;
+				}
+				const options = route.options;
+				const schema = options.schema || {};
+				const operation = {};
+				for (const field of ['operationId', 'summary', 'description', 'tags', 'deprecated']) {
+					if (options[field] !== undefined) {
+						operation[field] = options[field];
+					}
+				}
+				const params = parameters(schema.params, 'path', route.names)
+					.concat(parameters(schema.query, 'query'));
+				if (params.length > 0) {
+					operation.parameters = params;
+				}
+				if (schema.body !== undefined) {
+					operation.requestBody = {
+						required: required(schema.body),
+						content: { 'application/json': { schema: jsonSchema(schema.body) } },
+					};
+				}
+				const responses = {};
+				const declared = schema.response || {};
+				for (const status of Object.keys(declared)) {
+					responses[status] = {
+						description: native.statusText(Number(status)) || 'Response',
+						content: { 'application/json': { schema: jsonSchema(declared[status]) } },
+					};
+				}
+				if (Object.keys(responses).length === 0) {
+					responses['200'] = { description: 'OK' };
+				}
+				const validated = [schema.params, schema.query, schema.body].some(s => s && typeof s.validate === 'function');
+				if (validated && !responses['400']) {
+					responses['400'] = { description: 'Bad Request' };
+				}
+				operation.responses = responses;
+
+				const key = route.path.split('/')
+					.map(segment => segment.startsWith(':') ? '{' + segment.slice(1) + '}' : segment)
+					.join('/');
+				paths[key] = paths[key] || {};
+				paths[key][route.method.toLowerCase()] = operation;
+			}
+
+			const doc = {
+				openapi: '3.1.0',
+				info: { title: info.title || 'API', version: info.version || '1.0.0' },
+				paths: paths,
+			};
+			if (info.description !== undefined) {
+				doc.info.description = info.description;
+			}
+			if (info.servers !== undefined) {
+				doc.servers = info.servers;
+			}
+			return doc;
+		}
+
+		// docs serves the OpenAPI document at specPath and a page rendering
+		// it at path, '/docs' and '/docs/openapi.json' by default
+		docs(options) {
+			options = options || {};
+			const path = options.path || '/docs';
+			const specPath = options.specPath || path.replace(/\/$/, '') + '/openapi.json';
+			this.get(specPath, { hidden: true }, (req, res) => {
+				res.setHeader('Content-Type', 'application/json');
+				res.end(JSON.stringify(this.openapi(options.info)));
+			});
+			this.get(path, { hidden: true }, (req, res) => {
+				res.setHeader('Content-Type', 'text/html; charset=utf-8');
+				res.end(docsPage(this.openapi(options.info), specPath));
+			});
+			return this;
+		}
+
+		handle(req, res) {
+			const stack = this._stack.slice();
+			const listener = this._listener;
+			return new Promise((resolve, reject) => {
+				let index = -1;
+				const dispatch = (i) => {
+					if (i <= index) {
+						return Promise.reject(new Error('next() called multiple times'));
+					}
+					index = i;
+					const fn = i < stack.length ? stack[i] : listener;
+					if (!fn) {
+						res.statusCode = 404;
+						res.end('Not Found\n');
+						return Promise.resolve();
+					}
+					const next = (err) => {
+						if (err) {
+							reject(err);
+							return Promise.resolve();
+						}
+						// a middleware may call next() without returning
+						// it, so the rest of the chain rejects handle itself
+						const rest = dispatch(i + 1);
+						rest.catch(reject);
+						return rest;
+					};
+					try {
+						return Promise.resolve(fn(req, res, next));
+					} catch (err) {
+						return Promise.reject(err);
+					}
+				};
+				dispatch(0).then(resolve, reject);
+			});
+		}
+
+		// listen(port, host, callback), or listen({port, host, ...options},
+		// callback) with options, such as tls, overriding those of
+		// createServer
+		listen(port, host, callback) {
+			let options = this._options;
+			if (port !== null && typeof port === 'object') {
+				options = Object.assign({}, this._options, port);
+				callback = host;
+				host = port.host;
+				port = port.port;
+			}
+			if (typeof host === 'function') {
+				callback = host;
+				host = undefined;
+			}
+			if (this._native) {
+				return Promise.reject(new Error('server is already listening'));
+			}
+			try {
+				this._native = native.listen(this.handle, port === undefined ? 0 : port, host || '', options);
+			} catch (err) {
+				return Promise.reject(err);
+			}
+			if (callback) {
+				queueMicrotask(callback);
+			}
+			return Promise.resolve(this.address());
+		}
+
+		address() {
+			return this._native ? this._native.address() : null;
+		}
+
+		close(options) {
+			if (!this._native) {
+				return Promise.resolve();
+			}
+			const server = this._native;
+			this._native = null;
+			const timeout = options && options.timeout !== undefined ? options.timeout : -1;
+			return server.close(timeout);
+		}
+	}
+
+	routeMethods.forEach((name) => {
+		Server.prototype[name] = function (path, options, handler) {
+			return this.route(name, path, options, handler);
+		};
+	});
+
+	const formats = ['common', 'combined', 'json'];
+
+	// accessLog returns a middleware that logs each request once its
+	// response has ended, in format: 'common', 'combined' (the default),
+	// 'json' or a function (entry, req, res) returning the line. sink
+	// receives the lines: a function (line, entry), a stream with write(),
+	// or a logger with info(line, entry); stdout by default. Requests for
+	// which skip(req, res) returns true are not logged.
+	const accessLog = (options) => {
+		options = options || {};
+		const format = options.format === undefined ? 'combined' : options.format;
+		if (typeof format !== 'function' && !formats.includes(format)) {
+			throw new TypeError('accessLog format must be common, combined, json or a function');
+		}
+		const sink = options.sink === undefined ? native.stdout : options.sink;
+		let write;
+		if (typeof sink === 'function') {
+			write = (line, entry) => sink(line, entry);
+		} else if (sink && typeof sink.write === 'function') {
+			write = (line) => sink.write(line + '\n');
+		} else if (sink && typeof sink.info === 'function') {
+			write = (line, entry) => sink.info(line, entry);
+		} else {
+			throw new TypeError('accessLog sink must be a function, a writable stream or a logger');
+		}
+		const skip = options.skip;
+
+		return (req, res, next) => {
+			const time = Date.now();
+			const started = native.now();
+			res.on('finish', () => {
+				if (skip && skip(req, res)) {
+					return;
+				}
+				const route = req.route && typeof req.route === 'object' ? req.route.path : req.route;
+				const entry = {
+					time: time,
+					latencyMs: native.now() - started,
+					remote: req.ip || '',
+					user: typeof req.user === 'string' ? req.user : '',
+					method: req.method,
+					url: req.originalUrl || req.url,
+					protocol: req.httpVersion ? 'HTTP/' + req.httpVersion : '',
+					status: res.statusCode,
+					bytes: res.bytesWritten,
+					route: route ? String(route) : '',
+					requestId: String(req.id || req.headers['x-request-id'] || res.getHeader('x-request-id') || ''),
+					referer: req.headers.referer || req.headers.referrer || '',
+					userAgent: req.headers['user-agent'] || '',
+				};
+				const line = typeof format === 'function' ? format(entry, req, res) : native.formatAccessLog(format, entry);
+				write(line, entry);
+			});
+			return next();
+		};
+	};
+
+	return {
+		createServer: (options, listener) => new Server(options, listener),
+		Server: Server,
+		accessLog: accessLog,
+	};
+})
+`
+
+// ModuleBridge provides the gode:http module
+type ModuleBridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewModuleBridge creates a new gode:http bridge
+func NewModuleBridge(runtime RuntimeInterface) *ModuleBridge {
+	return &ModuleBridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *ModuleBridge) Exports() (*goja.Object, error) {
+	native := b.vm.NewObject()
+	native.Set("listen", b.listen)
+	native.Set("formatAccessLog", b.formatAccessLog)
+	native.Set("now", func() float64 {
+		return float64(time.Now().UnixNano()) / float64(time.Millisecond)
+	})
+	native.Set("statusText", http.StatusText)
+	native.Set("stdout", func(line string) {
+		fmt.Fprintln(os.Stdout, line)
+	})
+
+	setup, err := jsprogram.Run(b.vm, "http-setup", serverSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("http setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
+
+// listen implements native.listen(handle, port, host, options), returning
+// {address(), close(timeoutMs)}. The process stays alive while the server
+// is listening; shutting the runtime down closes it.
+func (b *ModuleBridge) listen(handle, port goja.Value, host string, options goja.Value) *goja.Object {
+	handler, err := NewHandler(b.vm, b.runtime, handle)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	opts, err := serverOptions(options)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	server, err := Listen(net.JoinHostPort(host, strconv.FormatInt(port.ToInteger(), 10)), handler, opts)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+
+	release := b.runtime.KeepAlive()
+	unhook := b.runtime.AddShutdownHook(func() {
+		server.Close()
+		release()
+	})
+
+	obj := b.vm.NewObject()
+	obj.Set("address", server.Address)
+	obj.Set("close", func(timeout int64) goja.Value {
+		unhook()
+		wait := DefaultShutdownTimeout
+		if timeout >= 0 {
+			wait = time.Duration(timeout) * time.Millisecond
+		}
+		return promise.Run(b.vm, b.runtime, func() (interface{}, error) {
+			defer release()
+			return nil, server.Shutdown(wait)
+		})
+	})
+	return obj
+}
+
+// formatAccessLog implements native.formatAccessLog(format, entry), entry
+// being the object accessLog passes to its sink
+func (b *ModuleBridge) formatAccessLog(format string, entry *goja.Object) string {
+	str := func(name string) string {
+		if v := entry.Get(name); !isNullish(v) {
+			return v.String()
+		}
+		return ""
+	}
+	e := AccessEntry{
+		Time:      time.UnixMilli(entry.Get("time").ToInteger()),
+		Latency:   time.Duration(entry.Get("latencyMs").ToFloat() * float64(time.Millisecond)),
+		Remote:    str("remote"),
+		User:      str("user"),
+		Method:    str("method"),
+		URL:       str("url"),
+		Protocol:  str("protocol"),
+		Status:    int(entry.Get("status").ToInteger()),
+		Bytes:     entry.Get("bytes").ToInteger(),
+		Route:     str("route"),
+		RequestID: str("requestId"),
+		Referer:   str("referer"),
+		UserAgent: str("userAgent"),
+	}
+	line, err := FormatAccessLog(format, e)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	return line
+}
+
+// serverOptions reads the options of createServer: readHeaderTimeout,
+// requestTimeout and idleTimeout in milliseconds, maxHeaderBytes and
+// maxRequestBodySize in bytes, streamBody, to read request bodies as
+// streams, trustProxy, requestId, false to skip X-Request-Id handling, and
+// tls or https, see serverTLS
+func serverOptions(value goja.Value) (*ServerOptions, error) {
+	opts := &ServerOptions{}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts, nil
+	}
+	milliseconds := func(name string) time.Duration {
+		if v := obj.Get(name); !isNullish(v) {
+			return time.Duration(v.ToInteger()) * time.Millisecond
+		}
+		return 0
+	}
+	opts.ReadHeaderTimeout = milliseconds("readHeaderTimeout")
+	opts.RequestTimeout = milliseconds("requestTimeout")
+	opts.IdleTimeout = milliseconds("idleTimeout")
+	if v := obj.Get("maxHeaderBytes"); !isNullish(v) {
+		opts.MaxHeaderBytes = int(v.ToInteger())
+	}
+	if v := obj.Get("maxRequestBodySize"); !isNullish(v) {
+		opts.MaxRequestBodySize = v.ToInteger()
+	}
+	if v := obj.Get("streamBody"); !isNullish(v) {
+		opts.StreamBody = v.ToBoolean()
+	}
+	trust, err := trustProxy(obj.Get("trustProxy"))
+	if err != nil {
+		return nil, err
+	}
+	opts.TrustProxy = trust
+	if v := obj.Get("requestId"); !isNullish(v) {
+		opts.DisableRequestID = !v.ToBoolean()
+	}
+	if opts.TLS, err = serverTLS(obj); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// serverTLS reads the TLS options of a server: tls: 'dev', or https: true,
+// serves with the development certificate of gode:tls, signed by the local
+// CA in ~/.gode/certs for localhost, 127.0.0.1 and ::1; tls: {cert, key}
+// serves with a PEM certificate and key
+func serverTLS(obj *goja.Object) (*tls.Config, error) {
+	value := obj.Get("tls")
+	if isNullish(value) {
+		if https := obj.Get("https"); isNullish(https) || !https.ToBoolean() {
+			return nil, nil
+		}
+		return devTLS()
+	}
+
+	if name, ok := value.Export().(string); ok {
+		if name != "dev" {
+			return nil, fmt.Errorf("tls must be 'dev' or {cert, key}, got %q", name)
+		}
+		return devTLS()
+	}
+
+	options, ok := value.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("tls must be 'dev' or {cert, key}")
+	}
+	cert, certOK := bodyBytes(options.Get("cert"))
+	key, keyOK := bodyBytes(options.Get("key"))
+	if !certOK || !keyOK || len(cert) == 0 || len(key) == 0 {
+		return nil, fmt.Errorf("tls needs a PEM cert and key")
+	}
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// devTLS serves with the development certificate, creating it and its CA
+// on first use
+func devTLS() (*tls.Config, error) {
+	dev, err := godetls.DevCertificate(godetls.DefaultDevCertDir(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return dev.TLSConfig()
+}
+
+// trustProxy reads the trustProxy option: true trusts every proxy, a
+// number the proxies that many hops from the server, and a string of
+// comma-separated networks or an array of them the proxies within those
+// networks
+func trustProxy(value goja.Value) (*TrustProxy, error) {
+	if isNullish(value) {
+		return nil, nil
+	}
+	switch v := value.Export().(type) {
+	case bool:
+		if v {
+			return TrustAll(), nil
+		}
+		return nil, nil
+	case int64:
+		if v < 0 {
+			return nil, fmt.Errorf("trustProxy: hop count must not be negative, got %d", v)
+		}
+		return TrustHops(int(v)), nil
+	case float64:
+		if v < 0 || v != float64(int(v)) {
+			return nil, fmt.Errorf("trustProxy: hop count must be a whole number, got %v", v)
+		}
+		return TrustHops(int(v)), nil
+	case string:
+		return TrustNetworks(strings.Split(v, ","))
+	case []interface{}:
+		specs := make([]string, len(v))
+		for i, spec := range v {
+			specs[i] = fmt.Sprint(spec)
+		}
+		return TrustNetworks(specs)
+	}
+	return nil, fmt.Errorf("trustProxy must be a boolean, a hop count or a list of networks")
+}
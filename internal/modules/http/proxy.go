@@ -0,0 +1,180 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// namedNetworks are the ranges trustProxy accepts by name
+var namedNetworks = map[string][]string{
+	"loopback":    {"127.0.0.0/8", "::1/128"},
+	"linklocal":   {"169.254.0.0/16", "fe80::/10"},
+	"uniquelocal": {"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+}
+
+// TrustProxy decides which proxies in front of the server are trusted to
+// report the address of the client. The addresses a request came through
+// are walked from the connection's peer towards the client, and the first
+// one that is not trusted is the client. A nil TrustProxy trusts nothing,
+// so the client is the peer and forwarding headers are ignored.
+type TrustProxy struct {
+	all  bool         // every hop is trusted
+	hops int          // the number of hops nearest the server that are trusted
+	nets []*net.IPNet // hops within these networks are trusted
+}
+
+// TrustAll trusts every proxy, so the client is the furthest address the
+// headers name
+func TrustAll() *TrustProxy {
+	return &TrustProxy{all: true}
+}
+
+// TrustHops trusts the n proxies nearest the server, whatever their
+// addresses
+func TrustHops(n int) *TrustProxy {
+	return &TrustProxy{hops: n}
+}
+
+// TrustNetworks trusts proxies within the given networks: CIDR ranges
+// such as "10.0.0.0/8", single addresses, or the names loopback,
+// linklocal and uniquelocal
+func TrustNetworks(specs []string) (*TrustProxy, error) {
+	t := &TrustProxy{}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		cidrs, ok := namedNetworks[spec]
+		if !ok {
+			cidrs = []string{spec}
+		}
+		for _, cidr := range cidrs {
+			if !strings.Contains(cidr, "/") {
+				ip := net.ParseIP(cidr)
+				if ip == nil {
+					return nil, fmt.Errorf("trustProxy: invalid address %q", cidr)
+				}
+				if ip.To4() != nil {
+					cidr += "/32"
+				} else {
+					cidr += "/128"
+				}
+			}
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("trustProxy: invalid network %q", cidr)
+			}
+			t.nets = append(t.nets, network)
+		}
+	}
+	return t, nil
+}
+
+// trusts reports whether addr, hop proxies away from the server, is a
+// trusted proxy
+func (t *TrustProxy) trusts(addr string, hop int) bool {
+	switch {
+	case t == nil:
+		return false
+	case t.all:
+		return true
+	case t.nets == nil:
+		return hop < t.hops
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range t.nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddresses returns the address of the client req came from, as
+// trust allows, and the chain of addresses from the client to the
+// proxy nearest the server. The chain is empty when no proxy is trusted.
+func ClientAddresses(req *http.Request, trust *TrustProxy) (ip string, ips []string) {
+	addrs := []string{hostOf(req.RemoteAddr)}
+	if trust != nil {
+		forwarded := forwardedFor(req.Header)
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			addrs = append(addrs, forwarded[i])
+		}
+	}
+
+	client := len(addrs) - 1
+	for hop, addr := range addrs[:len(addrs)-1] {
+		if !trust.trusts(addr, hop) {
+			client = hop
+			break
+		}
+	}
+
+	for i := client; i > 0; i-- {
+		ips = append(ips, addrs[i])
+	}
+	return addrs[client], ips
+}
+
+// forwardedFor returns the addresses a request was forwarded for, client
+// first. Forwarded (RFC 7239) is used when present, then X-Forwarded-For,
+// then X-Real-IP, so that a request carrying several of them always
+// resolves the same way.
+func forwardedFor(header http.Header) []string {
+	var addrs []string
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		for _, element := range splitList(values) {
+			for _, pair := range strings.Split(element, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					addrs = append(addrs, hostOf(strings.Trim(value, `"`)))
+				}
+			}
+		}
+		return addrs
+	}
+	if values := header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, addr := range splitList(values) {
+			addrs = append(addrs, hostOf(addr))
+		}
+		return addrs
+	}
+	if addr := strings.TrimSpace(header.Get("X-Real-IP")); addr != "" {
+		return []string{hostOf(addr)}
+	}
+	return nil
+}
+
+// splitList splits comma-separated header values into their trimmed,
+// non-empty elements
+func splitList(values []string) []string {
+	var elements []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
+
+// hostOf strips the port and brackets from an address, and reports IPv4
+// addresses mapped to IPv6 in their IPv4 form. Anything that is not an IP
+// address, such as an obfuscated Forwarded identifier, is returned as it is.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if ip := net.ParseIP(addr); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			return v4.String()
+		}
+		return ip.String()
+	}
+	return addr
+}
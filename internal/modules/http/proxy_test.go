@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestTrustProxy(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("trust-proxy", `
+		(async () => {
+			const { createServer } = require('gode:http');
+			const listener = (req, res) => res.end(req.ip + ' ' + req.ips.join(','));
+			const ask = (options, request) => testServer(createServer(options, listener))
+				.request(Object.assign({ remoteAddress: '10.0.0.2' }, request))
+				.then(res => res.text());
+			const xff = { headers: { 'X-Forwarded-For': '203.0.113.9, 198.51.100.7, 10.0.0.1' } };
+			return [
+				await ask(undefined, xff),
+				await ask({ trustProxy: true }, xff),
+				await ask({ trustProxy: 1 }, xff),
+				await ask({ trustProxy: 'uniquelocal' }, xff),
+				await ask({ trustProxy: ['10.0.0.0/8', '198.51.100.7'] }, xff),
+				await ask({ trustProxy: true }, { headers: {
+					'Forwarded': 'for=192.0.2.43, for="[2001:db8::17]:4711"',
+					'X-Forwarded-For': '203.0.113.9',
+				} }),
+				await ask({ trustProxy: true }, { headers: { 'X-Real-IP': '192.0.2.1' } }),
+				await Promise.resolve().then(() => ask({ trustProxy: 'nonsense' }, xff)).catch(e => e.name),
+			].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"10.0.0.2 ",
+		"203.0.113.9 203.0.113.9,198.51.100.7,10.0.0.1",
+		"10.0.0.1 10.0.0.1",
+		"198.51.100.7 198.51.100.7,10.0.0.1",
+		"203.0.113.9 203.0.113.9,198.51.100.7,10.0.0.1",
+		"192.0.2.43 192.0.2.43,2001:db8::17",
+		"192.0.2.1 192.0.2.1",
+		"TypeError",
+	}, "|")
+	if value != want {
+		t.Errorf("client addresses = %v, want %s", value, want)
+	}
+}
@@ -2,51 +2,90 @@ package http
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
 )
 
 // RuntimeInterface represents the methods we need from the runtime
 type RuntimeInterface interface {
 	SetGlobal(name string, value interface{}) error
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	KeepAlive() (release func())
+	AddShutdownHook(fn func()) (remove func())
+}
+
+// diagnosticsPublisher is implemented by runtimes with
+// gode:diagnostics_channel
+type diagnosticsPublisher interface {
+	PublishDiagnostic(channel string, message func() map[string]interface{})
 }
 
-// RegisterHTTPModule registers the HTTP module in the JavaScript runtime
+// RegisterHTTPModule registers fetch, Headers, Request, Response, FormData
+// and File, and the gode:http module, with the server and fetch's Agent,
+// in the JavaScript runtime
 func RegisterHTTPModule(runtime RuntimeInterface) error {
-	// Register fetch function through the runtime interface (which uses queue)
-	fetch := func(args ...interface{}) interface{} {
-		// Simple fetch implementation - returns a promise-like object
-		response := map[string]interface{}{
-			"status": 200,
-			"ok": true,
-			"json": func() interface{} {
-				return map[string]interface{}{"success": true}
-			},
-			"text": func() interface{} {
-				return "success"
-			},
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		// fetch checks the network permission and publishes the request
+		// before sending it
+		vm := runtime.GetGojaRuntime()
+		check := func(url, method string) {
+			if err := checkNetPermission(runtime, url); err != nil {
+				// Thrown as a PermissionDenied error rather than a plain GoError
+				panic(jserror.New(vm, err))
+			}
+			publishRequest(runtime, url, method)
 		}
-		
-		return map[string]interface{}{
-			"then": func(callback interface{}) interface{} {
-				// Return another promise-like object
-				return map[string]interface{}{
-					"then": func(cb interface{}) interface{} { return response },
-					"catch": func(cb interface{}) interface{} { return response },
-				}
-			},
-			"catch": func(callback interface{}) interface{} {
-				// Return promise-like object for error handling
-				return map[string]interface{}{
-					"then": func(cb interface{}) interface{} { return response },
-					"catch": func(cb interface{}) interface{} { return response },
-				}
-			},
+		fetchGlobals, err := NewBridge(runtime).Globals(check)
+		if err != nil {
+			done <- fmt.Errorf("failed to register fetch: %w", err)
+			return
 		}
+		for _, name := range fetchNames {
+			if err := vm.Set(name, fetchGlobals.Get(name)); err != nil {
+				done <- fmt.Errorf("failed to register %s: %w", name, err)
+				return
+			}
+		}
+
+		exports, err := NewModuleBridge(runtime).Exports()
+		if err != nil {
+			done <- err
+			return
+		}
+		for _, name := range agentNames {
+			exports.Set(name, fetchGlobals.Get(name))
+		}
+		runtime.RegisterModule("gode:http", exports)
+		done <- nil
+	})
+	return <-done
+}
+
+// checkNetPermission checks the URL of a fetch
+func checkNetPermission(runtime RuntimeInterface, rawURL string) error {
+	checker, ok := runtime.(permissions.NetEnforcer)
+	if !ok {
+		return nil
 	}
-	
-	err := runtime.SetGlobal("fetch", fetch)
-	if err != nil {
-		return fmt.Errorf("failed to register fetch: %w", err)
-	}
+	return checker.CheckNetURL(rawURL)
+}
 
-	return nil
-}
\ No newline at end of file
+// publishRequest publishes a fetch on http.client.request
+func publishRequest(runtime RuntimeInterface, url, method string) {
+	publisher, ok := runtime.(diagnosticsPublisher)
+	if !ok {
+		return
+	}
+	publisher.PublishDiagnostic("http.client.request", func() map[string]interface{} {
+		return map[string]interface{}{
+			"url":    url,
+			"method": strings.ToUpper(method),
+		}
+	})
+}
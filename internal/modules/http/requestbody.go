@@ -0,0 +1,182 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsbuffer"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// requestChunkSize is the most a read of a streamed request body returns
+const requestChunkSize = 32 * 1024
+
+// requestBody is the body of a request handled with StreamBody, read from
+// the connection only as the listener asks for it. The size limit and the
+// request timeout of the server apply as the chunks arrive.
+type requestBody struct {
+	mu     sync.Mutex // one read at a time
+	body   io.Reader
+	err    error // that ended the body, io.EOF once it has been read
+	res    *response
+	timing *requestTiming
+}
+
+// next returns the next chunk of the body, or io.EOF after the last
+func (b *requestBody) next() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	buf := make([]byte, requestChunkSize)
+	for b.err == nil {
+		n, err := b.body.Read(buf)
+		if err != nil {
+			b.err = err
+		}
+		if n > 0 {
+			return buf[:n], nil
+		}
+	}
+	return nil, b.err
+}
+
+// rest returns what is left of the body
+func (b *requestBody) rest() ([]byte, error) {
+	var data []byte
+	for {
+		chunk, err := b.next()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+// settle converts the outcome of a read on the JS thread. The end of the
+// body sets req.timing.bodyTime; a failure sets the status the response
+// fails with, so that a listener giving up on a body that is too large
+// sends a 413.
+func (b *requestBody) settle(err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == io.EOF {
+		if b.timing.body == 0 {
+			b.timing.body = time.Since(b.timing.start)
+		}
+		return nil
+	}
+	status := bodyErrorStatus(err)
+	b.res.mu.Lock()
+	b.res.bodyStatus = status
+	b.res.mu.Unlock()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit)
+	}
+	return fmt.Errorf("failed to read request body: %w", err)
+}
+
+// streamSetup gives a streamed req its async iterator, over read(), and its
+// body, a gode:stream Readable of the same chunks made on first access
+const streamSetup = `(function (req) {
+	req[Symbol.asyncIterator] = function () {
+		return {
+			next: () => req.read().then(chunk => chunk === null
+				? { done: true, value: undefined }
+				: { done: false, value: chunk }),
+			[Symbol.asyncIterator]() {
+				return this;
+			},
+		};
+	};
+	let body;
+	Object.defineProperty(req, 'body', {
+		get() {
+			if (body === undefined) {
+				body = require('stream').Readable.from(req);
+			}
+			return body;
+		},
+		enumerable: true,
+	});
+})`
+
+// streamingRequest creates the JS req object of a request handled with
+// StreamBody. Its body is read with read(), which resolves with the next
+// chunk as a Buffer, or null at the end; for await (const chunk of req);
+// req.body, a Readable; or text(), json() and arrayBuffer(), which resolve
+// with the rest of it. Reads beyond the size limit or the request timeout
+// reject. It must be called on the JS thread.
+func (h *Handler) streamingRequest(req *http.Request, res *response, timing *requestTiming) (*goja.Object, error) {
+	if h.streamSetup == nil {
+		setup, err := jsprogram.Run(h.vm, "http-request-stream", streamSetup)
+		if err != nil {
+			return nil, err
+		}
+		fn, ok := goja.AssertFunction(setup)
+		if !ok {
+			return nil, fmt.Errorf("request stream setup did not return a function")
+		}
+		h.streamSetup = fn
+	}
+
+	body := &requestBody{body: req.Body, res: res, timing: timing}
+	obj := h.requestObject(req)
+	obj.Set("read", func() goja.Value {
+		p, resolver := promise.New(h.vm, h.queue)
+		go func() {
+			chunk, err := body.next()
+			resolver.SettleWith(func() (interface{}, error) {
+				if err := body.settle(err); err != nil {
+					return nil, err
+				}
+				if chunk == nil {
+					return goja.Null(), nil
+				}
+				return jsbuffer.New(h.vm, chunk), nil
+			})
+		}()
+		return p
+	})
+	rest := func(decode func(data []byte) (interface{}, error)) func() goja.Value {
+		return func() goja.Value {
+			p, resolver := promise.New(h.vm, h.queue)
+			go func() {
+				data, err := body.rest()
+				if err == nil {
+					err = io.EOF
+				}
+				resolver.SettleWith(func() (interface{}, error) {
+					if err := body.settle(err); err != nil {
+						return nil, err
+					}
+					return decode(data)
+				})
+			}()
+			return p
+		}
+	}
+	obj.Set("text", rest(func(data []byte) (interface{}, error) {
+		return string(data), nil
+	}))
+	obj.Set("json", rest(func(data []byte) (interface{}, error) {
+		parse, _ := goja.AssertFunction(h.vm.Get("JSON").ToObject(h.vm).Get("parse"))
+		return parse(goja.Undefined(), h.vm.ToValue(string(data)))
+	}))
+	obj.Set("arrayBuffer", rest(func(data []byte) (interface{}, error) {
+		return h.vm.NewArrayBuffer(data), nil
+	}))
+	if _, err := h.streamSetup(goja.Undefined(), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
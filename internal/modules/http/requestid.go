@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/rizqme/gode/internal/asynccontext"
+)
+
+// RequestIDHeader carries the ID of a request between services
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds the IDs taken from clients
+const maxRequestIDLength = 200
+
+// contextTracker is implemented by runtimes that follow the async context
+// of JS code, which carries the ID of the request being handled
+type contextTracker interface {
+	AsyncContext() *asynccontext.Tracker
+}
+
+// trackerOf returns the async context tracker of runtime, or nil
+func trackerOf(runtime interface{}) *asynccontext.Tracker {
+	if t, ok := runtime.(contextTracker); ok {
+		return t.AsyncContext()
+	}
+	return nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a child of ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" when there is
+// none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID returns the ID of req: the client's X-Request-Id when it is a
+// reasonable one, otherwise a new random UUID
+func requestID(req *http.Request) string {
+	if id := req.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII, so that a client
+// cannot inject anything into logs or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random (version 4) UUID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,56 @@
+package http_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestRequestID(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("X-Request-Id"))
+	}))
+	defer upstream.Close()
+
+	value, err := rt.RunScriptAsync("request-id", `
+		(async () => {
+			const { createServer } = require('gode:http');
+			const upstream = `+strconv.Quote(upstream.URL)+`;
+			const app = createServer(async (req, res) => {
+				await Promise.resolve();
+				const direct = await fetch(upstream).then(r => r.text());
+				const later = await new Promise(resolve => setTimeout(() => fetch(upstream).then(r => r.text()).then(resolve), 1));
+				res.end([req.id, direct, later].join(','));
+			});
+			const server = testServer(app);
+			const given = await server.request({ path: '/', headers: { 'X-Request-Id': 'req-1' } });
+			const generated = await server.request('/');
+			const [id, direct, later] = generated.text().split(',');
+			const off = await testServer(createServer({ requestId: false }, (req, res) => res.end(String(req.id)))).request('/');
+			return [
+				given.text(), given.headers['x-request-id'],
+				/^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$/.test(id),
+				id === direct && id === later && id === generated.headers['x-request-id'],
+				off.text(), off.headers['x-request-id'],
+				await fetch(upstream).then(r => r.text()),
+			].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	want := "req-1,req-1,req-1|req-1|true|true|undefined||"
+	if value != want {
+		t.Errorf("request IDs = %v, want %s", value, want)
+	}
+}
@@ -0,0 +1,452 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Handler serves HTTP requests with a JS request listener, a function
+// (req, res) as in Node. Each request is dispatched on the JS thread through
+// the runtime's queue, so ServeHTTP must not be called from the JS thread.
+type Handler struct {
+	vm       *goja.Runtime
+	queue    promise.Queue
+	listener goja.Callable
+
+	// MaxRequestBodySize limits request bodies; larger ones get a 413.
+	// Zero means no limit.
+	MaxRequestBodySize int64
+
+	// TrustProxy decides which forwarding headers req.ip and req.ips are
+	// taken from. Nil ignores them.
+	TrustProxy *TrustProxy
+
+	// DisableRequestID turns off X-Request-Id handling, see ServeHTTP
+	DisableRequestID bool
+
+	// StreamBody calls the listener before the request body has been
+	// read, with a req that streams it, see requestBody
+	StreamBody bool
+
+	streamSetup goja.Callable // prepares streamed requests, see streamingRequest
+}
+
+// NewHandler creates a Handler calling listener, which may also be a server
+// from createServer, so that requests go through its middleware. It must be
+// called on the JS thread.
+func NewHandler(vm *goja.Runtime, queue promise.Queue, listener goja.Value) (*Handler, error) {
+	fn, ok := goja.AssertFunction(listener)
+	if obj, isObject := listener.(*goja.Object); !ok && isObject {
+		fn, ok = goja.AssertFunction(obj.Get("handle"))
+	}
+	if !ok {
+		return nil, fmt.Errorf("request listener must be a function")
+	}
+	return &Handler{vm: vm, queue: queue, listener: fn}, nil
+}
+
+// ServeHTTP reads the request body, calls the listener on the JS thread and
+// waits until it ends the response or the client goes away. A body larger
+// than MaxRequestBodySize gets a 413, and one that does not arrive within
+// the server's request timeout a 408. With StreamBody, the listener is
+// called as soon as the headers arrive and reads the body itself, see
+// requestBody; the same limits then fail its reads. A listener that
+// throws, or returns a promise that rejects, gets a 500 response, or the
+// status of the body read it failed on.
+//
+// Each request gets an ID, the client's X-Request-Id or a new one, which is
+// req.id and is sent back in X-Request-Id. The listener runs in an async
+// context carrying it, so fetch calls made while handling the request pass
+// it on.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	timing := &requestTiming{start: time.Now()}
+	if conn, ok := req.Context().Value(connKey{}).(*connInfo); ok {
+		var done func()
+		timing.conn = conn
+		timing.number, done = conn.dispatch()
+		defer done()
+	}
+
+	if h.MaxRequestBodySize > 0 {
+		if req.ContentLength > h.MaxRequestBodySize {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, h.MaxRequestBodySize)
+	}
+	var body []byte
+	if !h.StreamBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			status := bodyErrorStatus(err)
+			if status == http.StatusRequestTimeout {
+				w.Header().Set("Connection", "close")
+			}
+			if status == http.StatusBadRequest {
+				http.Error(w, "failed to read request body", status)
+			} else {
+				http.Error(w, http.StatusText(status), status)
+			}
+			return
+		}
+		timing.body = time.Since(timing.start)
+	}
+
+	res := &response{w: w, header: w.Header(), status: http.StatusOK, done: make(chan struct{})}
+	ctx := context.Background()
+	if !h.DisableRequestID {
+		res.id = requestID(req)
+		res.header.Set(RequestIDHeader, res.id)
+		ctx = WithRequestID(ctx, res.id)
+	}
+	h.queue.QueueJSOperation(func() {
+		trackerOf(h.queue).Run(ctx, func() {
+			var reqObj *goja.Object
+			if h.StreamBody {
+				var err error
+				if reqObj, err = h.streamingRequest(req, res, timing); err != nil {
+					res.fail(err)
+					return
+				}
+			} else {
+				reqObj = h.request(req, body)
+			}
+			reqObj.Set("timing", timing.object(h.vm))
+			if res.id != "" {
+				reqObj.Set("id", res.id)
+			}
+			result, err := h.listener(goja.Undefined(), reqObj, res.object(h.vm))
+			if err != nil {
+				res.fail(err)
+				return
+			}
+			if obj, ok := result.(*goja.Object); ok {
+				if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+					then(obj, goja.Undefined(), h.vm.ToValue(func(reason goja.Value) {
+						res.fail(fmt.Errorf("%s", reason.String()))
+					}))
+				}
+			}
+		})
+	})
+
+	select {
+	case <-res.done:
+	case <-req.Context().Done():
+		res.close()
+	}
+}
+
+// bodyErrorStatus returns the response status for a failure to read a
+// request body: 413 beyond the size limit, 408 past the request timeout
+// and 400 otherwise
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return http.StatusRequestTimeout
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// request creates the JS req object: method, url, path, httpVersion,
+// query, headers with lower-case names, ip and ips as resolved with
+// TrustProxy, and the body as a Buffer with text() and json()
+func (h *Handler) request(req *http.Request, body []byte) *goja.Object {
+	obj := h.requestObject(req)
+	defineBody(h.vm, obj, body, false)
+	return obj
+}
+
+// requestObject creates the JS req object without its body
+func (h *Handler) requestObject(req *http.Request) *goja.Object {
+	obj := h.vm.NewObject()
+	obj.Set("method", req.Method)
+	obj.Set("url", req.URL.RequestURI())
+	obj.Set("path", req.URL.Path)
+	obj.Set("httpVersion", fmt.Sprintf("%d.%d", req.ProtoMajor, req.ProtoMinor))
+
+	ip, ips := ClientAddresses(req, h.TrustProxy)
+	chain := make([]interface{}, len(ips))
+	for i, addr := range ips {
+		chain[i] = addr
+	}
+	obj.Set("ip", ip)
+	obj.Set("ips", chain)
+
+	query := h.vm.NewObject()
+	for name, values := range req.URL.Query() {
+		query.Set(name, values[0])
+	}
+	obj.Set("query", query)
+
+	headers := h.vm.NewObject()
+	for name, values := range req.Header {
+		headers.Set(strings.ToLower(name), strings.Join(values, ", "))
+	}
+	if req.Host != "" {
+		headers.Set("host", req.Host)
+	}
+	obj.Set("headers", headers)
+	return obj
+}
+
+// requestTiming describes when a request arrived and on which connection
+type requestTiming struct {
+	start  time.Time     // the headers were read
+	body   time.Duration // reading the body took, once it has been read
+	conn   *connInfo     // nil without a network connection, as in tests
+	number int32         // of the request on its connection
+}
+
+// object creates req.timing: startedAt and connectedAt as milliseconds
+// since the epoch, bodyTime in milliseconds, 0 until a streamed body has
+// been read, and connectionId and requestNumber, which count from 1
+func (t *requestTiming) object(vm *goja.Runtime) *goja.Object {
+	obj := vm.NewObject()
+	obj.Set("startedAt", t.start.UnixMilli())
+	obj.DefineAccessorProperty("bodyTime", vm.ToValue(func() float64 {
+		return float64(t.body) / float64(time.Millisecond)
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	if t.conn != nil {
+		obj.Set("connectionId", t.conn.id)
+		obj.Set("connectedAt", t.conn.connectedAt.UnixMilli())
+		obj.Set("requestNumber", t.number)
+	}
+	return obj
+}
+
+// response is the Go side of the JS res object. Its methods run on the JS
+// thread; the mutex guards against the client going away meanwhile.
+type response struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	ended       bool
+	done        chan struct{}
+	written     int64           // bytes of body sent
+	onFinish    []goja.Callable // listeners for the finish event
+	obj         *goja.Object    // the JS res object
+	id          string          // of the request, for error reports
+	bodyStatus  int             // of the failed read of a streamed body, see fail
+}
+
+// object creates the JS res object: statusCode, setHeader, getHeader,
+// removeHeader, writeHead, write, end, bytesWritten, headersSent and
+// on('finish', fn), which runs fn once the response has ended
+func (res *response) object(vm *goja.Runtime) *goja.Object {
+	obj := vm.NewObject()
+	obj.Set("statusCode", res.status)
+	obj.DefineAccessorProperty("bytesWritten", vm.ToValue(func() int64 {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		return res.written
+	}), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	obj.DefineAccessorProperty("headersSent", vm.ToValue(func() bool {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		return res.wroteHeader
+	}), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	statusCode := func() int {
+		if v := obj.Get("statusCode"); v != nil && !goja.IsUndefined(v) {
+			return int(v.ToInteger())
+		}
+		return http.StatusOK
+	}
+
+	obj.Set("setHeader", func(name string, value goja.Value) goja.Value {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		res.header.Del(name)
+		for _, v := range headerValues(value) {
+			res.header.Add(name, v)
+		}
+		return obj
+	})
+	obj.Set("getHeader", func(name string) goja.Value {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		if values := res.header.Values(name); len(values) > 0 {
+			return vm.ToValue(strings.Join(values, ", "))
+		}
+		return goja.Undefined()
+	})
+	obj.Set("removeHeader", func(name string) {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		res.header.Del(name)
+	})
+	obj.Set("writeHead", func(status int, headers goja.Value) goja.Value {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		if headers, ok := headers.(*goja.Object); ok {
+			for _, name := range headers.Keys() {
+				res.header.Del(name)
+				for _, v := range headerValues(headers.Get(name)) {
+					res.header.Add(name, v)
+				}
+			}
+		}
+		obj.Set("statusCode", status)
+		res.writeHeader(status)
+		return obj
+	})
+	obj.Set("write", func(chunk goja.Value) bool {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		res.write(statusCode(), chunk)
+		return !res.ended
+	})
+	obj.Set("end", func(chunk goja.Value) goja.Value {
+		res.mu.Lock()
+		res.write(statusCode(), chunk)
+		listeners := res.finish()
+		res.mu.Unlock()
+		res.emitFinish(obj, listeners)
+		return obj
+	})
+	obj.Set("on", func(event string, fn goja.Callable) goja.Value {
+		res.mu.Lock()
+		defer res.mu.Unlock()
+		if event == "finish" && !res.ended {
+			res.onFinish = append(res.onFinish, fn)
+		}
+		return obj
+	})
+	res.obj = obj
+	return obj
+}
+
+// emitFinish calls the finish listeners returned by finish, outside the
+// lock, so that they may use res. Errors they throw are reported without
+// affecting the response.
+func (res *response) emitFinish(obj *goja.Object, listeners []goja.Callable) {
+	for _, fn := range listeners {
+		if _, err := fn(obj); err != nil {
+			fmt.Fprintf(os.Stderr, "gode: finish listener failed: %v\n", err)
+		}
+	}
+}
+
+// writeHeader sends the status line and headers once
+func (res *response) writeHeader(status int) {
+	if res.wroteHeader || res.ended {
+		return
+	}
+	res.wroteHeader = true
+	res.w.WriteHeader(status)
+}
+
+// write sends a chunk: a string, Buffer, Uint8Array, ArrayBuffer or anything
+// else as its string form
+func (res *response) write(status int, chunk goja.Value) {
+	if res.ended {
+		return
+	}
+	res.writeHeader(status)
+	if chunk == nil || goja.IsUndefined(chunk) || goja.IsNull(chunk) {
+		return
+	}
+	var n int
+	if data, ok := bodyBytes(chunk); ok {
+		n, _ = res.w.Write(data)
+	} else {
+		n, _ = io.WriteString(res.w, chunk.String())
+	}
+	res.written += int64(n)
+	// Send each chunk as it is written, so responses can stream
+	if flusher, ok := res.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish ends the response, releasing ServeHTTP, and returns the finish
+// listeners for emitFinish to call once the lock is released
+func (res *response) finish() []goja.Callable {
+	if res.ended {
+		return nil
+	}
+	res.writeHeader(http.StatusOK)
+	res.ended = true
+	close(res.done)
+	listeners := res.onFinish
+	res.onFinish = nil
+	return listeners
+}
+
+// fail reports a listener error, with the request ID, and ends the
+// response with a 500 when nothing was sent yet. A listener that failed
+// after a read of its streamed body did gets the status of that failure,
+// such as 413, without a report.
+func (res *response) fail(err error) {
+	res.mu.Lock()
+	status := res.bodyStatus
+	res.mu.Unlock()
+	if status == 0 {
+		status = http.StatusInternalServerError
+		if res.id != "" {
+			fmt.Fprintf(os.Stderr, "gode: request listener failed (request %s): %v\n", res.id, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "gode: request listener failed: %v\n", err)
+		}
+	}
+
+	res.mu.Lock()
+	if !res.wroteHeader && !res.ended {
+		res.header.Set("Content-Type", "text/plain; charset=utf-8")
+		if status == http.StatusRequestTimeout {
+			res.header.Set("Connection", "close")
+		}
+		res.writeHeader(status)
+		n, _ := io.WriteString(res.w, http.StatusText(status)+"\n")
+		res.written += int64(n)
+		if res.obj != nil {
+			res.obj.Set("statusCode", status)
+		}
+	}
+	listeners := res.finish()
+	res.mu.Unlock()
+	res.emitFinish(res.obj, listeners)
+}
+
+// close stops further writes once ServeHTTP has returned
+func (res *response) close() {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if !res.ended {
+		res.ended = true
+		close(res.done)
+	}
+}
+
+// headerValues converts a header value, which may be an array
+func headerValues(value goja.Value) []string {
+	if obj, ok := value.(*goja.Object); ok && obj.ClassName() == "Array" {
+		var values []string
+		for _, key := range obj.Keys() {
+			values = append(values, obj.Get(key).String())
+		}
+		return values
+	}
+	if value == nil || goja.IsUndefined(value) {
+		return nil
+	}
+	return []string{value.String()}
+}
@@ -0,0 +1,391 @@
+package http_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	godetls "github.com/rizqme/gode/internal/modules/tls"
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestServerRoutes(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	port, err := rt.RunScriptAsync("server", `
+		const { createServer } = require('gode:http');
+		const v = require('gode:validate');
+		globalThis.server = createServer();
+		server.get('/users/:id', {
+			schema: { params: v.object({ id: v.number({ integer: true }) }), query: v.object({ full: v.boolean().optional() }) },
+		}, (req, res) => {
+			res.end(JSON.stringify({ id: req.params.id, full: req.query.full, route: req.route.path }));
+		});
+		server.post('/users', {
+			schema: { body: v.object({ name: v.string({ min: 2 }), email: v.email() }) },
+		}, (req, res) => {
+			res.statusCode = 201;
+			res.end(JSON.stringify(req.data));
+		});
+		server.get('/files/*', (req, res) => res.end(req.params['*']));
+		server.listen(0, '127.0.0.1').then((address) => address.port);
+	`)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer rt.RunScriptAsync("close", `server.close()`)
+	base := fmt.Sprintf("http://127.0.0.1:%v", port)
+
+	do := func(method, path, body string) (int, string) {
+		req, _ := http.NewRequest(method, base+path, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data)
+	}
+
+	if status, body := do("GET", "/users/42?full=true", ""); status != 200 || !sameJSON(body, `{"id":42,"full":true,"route":"/users/:id"}`) {
+		t.Errorf("GET /users/42 = %d %s", status, body)
+	}
+	if status, body := do("GET", "/users/abc", ""); status != 400 || !strings.Contains(body, `"path":"params.id"`) {
+		t.Errorf("GET /users/abc = %d %s", status, body)
+	}
+	if status, body := do("POST", "/users", `{"name":"Ada","email":"ada@example.com"}`); status != 201 || !sameJSON(body, `{"name":"Ada","email":"ada@example.com"}`) {
+		t.Errorf("POST /users = %d %s", status, body)
+	}
+	if status, body := do("POST", "/users", `{"name":"A","email":"nope"}`); status != 400 ||
+		!strings.Contains(body, `"path":"body.name"`) || !strings.Contains(body, `"path":"body.email"`) {
+		t.Errorf("POST /users with an invalid body = %d %s", status, body)
+	}
+	if status, body := do("POST", "/users", `{`); status != 400 || !strings.Contains(body, "must be valid JSON") {
+		t.Errorf("POST /users with bad JSON = %d %s", status, body)
+	}
+	if status, body := do("GET", "/files/a/b.txt", ""); status != 200 || body != "a/b.txt" {
+		t.Errorf("GET /files/a/b.txt = %d %s", status, body)
+	}
+	if status, _ := do("DELETE", "/users/1", ""); status != 404 {
+		t.Errorf("DELETE /users/1 = %d, want 404", status)
+	}
+}
+
+func TestServerOpenAPI(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	port, err := rt.RunScriptAsync("server", `
+		const { createServer } = require('gode:http');
+		const v = require('gode:validate');
+		globalThis.server = createServer();
+		server.get('/users/:id', {
+			summary: 'Get a user',
+			tags: ['users'],
+			schema: {
+				params: v.object({ id: v.number({ integer: true }) }),
+				query: v.object({ full: v.boolean().optional() }),
+				response: { 200: v.object({ id: v.number(), name: v.string() }), 404: { type: 'object' } },
+			},
+		}, (req, res) => res.end());
+		server.post('/users', { operationId: 'createUser', schema: { body: v.object({ ip: v.ip(4), card: v.creditCard() }) } }, (req, res) => res.end());
+		server.get('/internal', { hidden: true }, (req, res) => res.end());
+		server.docs({ info: { title: 'Users', version: '2.0.0' } });
+		server.listen(0, '127.0.0.1').then((address) => address.port);
+	`)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer rt.RunScriptAsync("close", `server.close()`)
+	base := fmt.Sprintf("http://127.0.0.1:%v", port)
+
+	res, err := http.Get(base + "/docs/openapi.json")
+	if err != nil {
+		t.Fatalf("GET /docs/openapi.json failed: %v", err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title, Version string
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationID string   `json:"operationId"`
+			Summary     string   `json:"summary"`
+			Tags        []string `json:"tags"`
+			Parameters  []struct {
+				Name, In string
+				Required bool
+				Schema   map[string]interface{}
+			} `json:"parameters"`
+			RequestBody *struct {
+				Required bool
+				Content  map[string]struct {
+					Schema map[string]interface{}
+				}
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Description string
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&doc)
+	res.Body.Close()
+	if err != nil {
+		t.Fatalf("decoding the document failed: %v", err)
+	}
+
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Users" || doc.Info.Version != "2.0.0" {
+		t.Errorf("document header = %s %+v", doc.OpenAPI, doc.Info)
+	}
+	for _, path := range []string{"/internal", "/docs", "/docs/openapi.json"} {
+		if _, ok := doc.Paths[path]; ok {
+			t.Errorf("hidden route %s is documented", path)
+		}
+	}
+
+	get := doc.Paths["/users/{id}"]["get"]
+	if get.Summary != "Get a user" || len(get.Tags) != 1 || get.Tags[0] != "users" {
+		t.Errorf("GET /users/{id} = %+v", get)
+	}
+	if len(get.Parameters) != 2 ||
+		get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" || !get.Parameters[0].Required || get.Parameters[0].Schema["type"] != "integer" ||
+		get.Parameters[1].Name != "full" || get.Parameters[1].In != "query" || get.Parameters[1].Required {
+		t.Errorf("GET /users/{id} parameters = %+v", get.Parameters)
+	}
+	for status, description := range map[string]string{"200": "OK", "404": "Not Found", "400": "Bad Request"} {
+		if get.Responses[status].Description != description {
+			t.Errorf("GET /users/{id} response %s = %+v", status, get.Responses[status])
+		}
+	}
+
+	post := doc.Paths["/users"]["post"]
+	if post.OperationID != "createUser" || post.RequestBody == nil || !post.RequestBody.Required {
+		t.Fatalf("POST /users = %+v", post)
+	}
+	properties := post.RequestBody.Content["application/json"].Schema["properties"].(map[string]interface{})
+	if ip := properties["ip"].(map[string]interface{}); ip["format"] != "ipv4" {
+		t.Errorf("ip schema = %v", ip)
+	}
+	if card := properties["card"].(map[string]interface{}); card["format"] != nil || card["x-format"] != "creditCard" {
+		t.Errorf("creditCard schema = %v", card)
+	}
+	if _, ok := post.Responses["200"]; !ok {
+		t.Errorf("POST /users responses = %+v", post.Responses)
+	}
+
+	res, err = http.Get(base + "/docs")
+	if err != nil {
+		t.Fatalf("GET /docs failed: %v", err)
+	}
+	page, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") ||
+		!strings.Contains(string(page), "Users 2.0.0") || !strings.Contains(string(page), "/users/{id}") {
+		t.Errorf("GET /docs = %s", page)
+	}
+}
+
+func TestServerDevTLS(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	ports, err := rt.RunScriptAsync("server", `
+		const { createServer } = require('gode:http');
+		globalThis.secure = createServer({ tls: 'dev' }, (req, res) => res.end('secure ' + req.path));
+		globalThis.other = createServer((req, res) => res.end('other'));
+		Promise.all([
+			secure.listen(0, '127.0.0.1'),
+			other.listen({ port: 0, host: '127.0.0.1', https: true }),
+		]).then(([a, b]) => a.port + ',' + b.port);
+	`)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer rt.RunScriptAsync("close", `Promise.all([secure.close(), other.close()])`)
+
+	// The servers use the certificate of the local CA created in ~/.gode/certs
+	dev, err := godetls.DevCertificate(filepath.Join(home, ".gode", "certs"), nil)
+	if err != nil {
+		t.Fatalf("DevCertificate() failed: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(dev.CACert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	for i, port := range strings.Split(fmt.Sprint(ports), ",") {
+		want := []string{"secure /x", "other"}[i]
+		res, err := client.Get("https://localhost:" + port + "/x")
+		if err != nil {
+			t.Fatalf("GET over TLS failed: %v", err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != want || res.TLS == nil {
+			t.Errorf("GET https://localhost:%s/x = %q, want %q over TLS", port, body, want)
+		}
+		if _, err := http.Get("http://127.0.0.1:" + port + "/x"); err == nil {
+			t.Errorf("plain HTTP to port %s succeeded", port)
+		}
+	}
+}
+
+func TestServerStreamBody(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	port, err := rt.RunScriptAsync("server", `
+		const { createServer } = require('gode:http');
+		const v = require('gode:validate');
+		globalThis.server = createServer({ streamBody: true, maxRequestBodySize: 64 });
+		server.post('/count', async (req, res) => {
+			let size = 0;
+			const it = req[Symbol.asyncIterator]();
+			for (;;) {
+				const { value, done } = await it.next();
+				if (done) {
+					break;
+				}
+				size += value.length();
+			}
+			res.end(String(size));
+		});
+		server.post('/echo', (req, res) => {
+			let text = '';
+			req.body.on('data', (chunk) => { text += chunk.toString(); });
+			req.body.on('end', () => res.end(text));
+		});
+		server.post('/users', { schema: { body: v.object({ name: v.string() }) } }, (req, res) => {
+			res.end('hello ' + req.data.name);
+		});
+		server.listen(0, '127.0.0.1').then((address) => address.port);
+	`)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer rt.RunScriptAsync("close", `server.close()`)
+	base := fmt.Sprintf("http://127.0.0.1:%v", port)
+
+	post := func(path, body string) (int, string) {
+		// Without a length the body is chunked, so only reading it finds it too large
+		res, err := http.Post(base+path, "application/json", io.MultiReader(strings.NewReader(body)))
+		if err != nil {
+			t.Fatalf("POST %s failed: %v", path, err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data)
+	}
+
+	if status, body := post("/count", strings.Repeat("x", 40)); status != 200 || body != "40" {
+		t.Errorf("POST /count = %d %q, want 200 \"40\"", status, body)
+	}
+	if status, body := post("/echo", "streamed"); status != 200 || body != "streamed" {
+		t.Errorf("POST /echo = %d %q, want 200 \"streamed\"", status, body)
+	}
+	if status, body := post("/users", `{"name":"Ada"}`); status != 200 || body != "hello Ada" {
+		t.Errorf("POST /users = %d %q, want 200 \"hello Ada\"", status, body)
+	}
+	if status, _ := post("/count", strings.Repeat("x", 100)); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST /count with 100 bytes = %d, want 413", status)
+	}
+}
+
+func TestServer(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	port, err := rt.RunScriptAsync("server", `
+		const { createServer } = require('gode:http');
+		globalThis.server = createServer((req, res) => {
+			if (req.path === '/stream') {
+				res.write('a');
+				setTimeout(() => res.end('b'), 10);
+				return;
+			}
+			if (req.path === '/fail') throw new Error('boom');
+			res.end(req.method + ' ' + req.path + ' ' + req.body);
+		});
+		server.use(async (req, res, next) => {
+			res.setHeader('X-Order', 'before');
+			await next();
+		});
+		server.use((req, res, next) => {
+			if (req.path === '/private') {
+				res.statusCode = 401;
+				res.end('denied');
+				return;
+			}
+			next(req.path === '/next-error' ? new Error('nope') : undefined);
+		});
+		server.listen(0, '127.0.0.1').then((address) => address.port);
+	`)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	base := fmt.Sprintf("http://127.0.0.1:%v", port)
+
+	get := func(method, path, body string) (int, string, string) {
+		req, _ := http.NewRequest(method, base+path, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data), res.Header.Get("X-Order")
+	}
+
+	if status, body, order := get("POST", "/echo", "hi"); status != 200 || body != "POST /echo hi" || order != "before" {
+		t.Errorf("POST /echo = %d %q %q", status, body, order)
+	}
+	if status, body, _ := get("GET", "/stream", ""); status != 200 || body != "ab" {
+		t.Errorf("GET /stream = %d %q", status, body)
+	}
+	if status, body, _ := get("GET", "/private", ""); status != 401 || body != "denied" {
+		t.Errorf("GET /private = %d %q", status, body)
+	}
+	for _, path := range []string{"/fail", "/next-error"} {
+		if status, _, _ := get("GET", path, ""); status != 500 {
+			t.Errorf("GET %s = %d, want 500", path, status)
+		}
+	}
+
+	// The same server works with testServer, middleware included
+	result, err := rt.RunScriptAsync("inject", `testServer(server).request('/private').then(res => res.status)`)
+	if err != nil || result != int64(401) {
+		t.Errorf("testServer(server) = %v, %v", result, err)
+	}
+
+	result, err = rt.RunScriptAsync("close", `server.close().then(() => server.listening)`)
+	if err != nil || result != false {
+		t.Errorf("close() = %v, %v", result, err)
+	}
+	if _, err := http.Get(base + "/echo"); err == nil {
+		t.Error("Expected a closed server to refuse connections")
+	}
+}
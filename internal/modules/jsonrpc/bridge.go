@@ -0,0 +1,362 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsonrpc"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Bridge provides JavaScript bindings for the gode:jsonrpc module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new JSON-RPC bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// rpcError is an error response, an RpcError in JS with its code and the
+// JSON of its data
+type rpcError struct {
+	err *jsonrpc.Error
+}
+
+func (e *rpcError) Error() string { return e.err.Message }
+
+func (e *rpcError) JSName() string { return "RpcError" }
+
+func (e *rpcError) JSProperties() map[string]interface{} {
+	props := map[string]interface{}{"code": e.err.Code}
+	if e.err.Data != nil {
+		props["data"] = string(e.err.Data)
+	}
+	return props
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() (*goja.Object, error) {
+	codes := b.vm.NewObject()
+	codes.Set("PARSE_ERROR", jsonrpc.ParseError)
+	codes.Set("INVALID_REQUEST", jsonrpc.InvalidRequest)
+	codes.Set("METHOD_NOT_FOUND", jsonrpc.MethodNotFound)
+	codes.Set("INVALID_PARAMS", jsonrpc.InvalidParams)
+	codes.Set("INTERNAL_ERROR", jsonrpc.InternalError)
+
+	native := b.vm.NewObject()
+	native.Set("codes", codes)
+	native.Set("createServer", b.createServer)
+	native.Set("stdio", b.stdio)
+	native.Set("connect", b.connect)
+	native.Set("createClient", b.createClient)
+
+	setup, err := jsprogram.Run(b.vm, "jsonrpc-setup", jsonrpcSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("jsonrpc setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
+
+// server is the handle of a server, which peers pass back to answer
+// requests with it
+type server struct {
+	rpc *jsonrpc.Server
+}
+
+// createServer implements native.createServer(dispatch), returning
+// {handle, add(method), remove(method), serve(message)}. dispatch(method,
+// params, notification) is called on the JS thread with the JSON of the
+// params, or undefined, and returns the JSON of {result} or {error}, or a
+// promise of it.
+func (b *Bridge) createServer(dispatch goja.Callable) *goja.Object {
+	srv := &server{rpc: jsonrpc.NewServer()}
+	obj := b.vm.NewObject()
+	obj.Set("handle", srv)
+	obj.Set("add", func(method string) {
+		srv.rpc.Handle(method, b.handler(dispatch, method))
+	})
+	obj.Set("remove", srv.rpc.Remove)
+	obj.Set("serve", func(message string) goja.Value {
+		return promise.Run(b.vm, b.runtime, func() (interface{}, error) {
+			res := srv.rpc.Serve(context.Background(), []byte(message))
+			if res == nil {
+				return goja.Undefined(), nil
+			}
+			return string(res), nil
+		})
+	})
+	return obj
+}
+
+// handler answers the requests of method with dispatch
+func (b *Bridge) handler(dispatch goja.Callable, method string) jsonrpc.Handler {
+	return func(ctx context.Context, req *jsonrpc.Request) (interface{}, error) {
+		done := make(chan string, 1)
+		fail := func(reason goja.Value) {
+			data, _ := json.Marshal(map[string]interface{}{
+				"error": jsonrpc.NewError(jsonrpc.InternalError, reason.String(), nil),
+			})
+			done <- string(data)
+		}
+		b.runtime.QueueJSOperation(func() {
+			params := goja.Undefined()
+			if req.Params != nil {
+				params = b.vm.ToValue(string(req.Params))
+			}
+			value, err := dispatch(goja.Undefined(), b.vm.ToValue(method), params, b.vm.ToValue(req.IsNotification()))
+			if exception, ok := err.(*goja.Exception); ok {
+				fail(exception.Value())
+				return
+			} else if err != nil {
+				fail(b.vm.ToValue(err.Error()))
+				return
+			}
+			b.await(value, func(v goja.Value) { done <- v.String() }, fail)
+		})
+
+		var outcome string
+		select {
+		case outcome = <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var answer struct {
+			Result json.RawMessage `json:"result"`
+			Error  *jsonrpc.Error  `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(outcome), &answer); err != nil {
+			return nil, err
+		}
+		if answer.Error != nil {
+			return nil, answer.Error
+		}
+		if answer.Result == nil {
+			return json.RawMessage("null"), nil
+		}
+		return answer.Result, nil
+	}
+}
+
+// await calls resolved with value, or what the promise it is resolves to,
+// or rejected with the reason the promise rejects with
+func (b *Bridge) await(value goja.Value, resolved, rejected func(goja.Value)) {
+	if obj, ok := value.(*goja.Object); ok {
+		if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+			then(obj, b.vm.ToValue(resolved), b.vm.ToValue(rejected))
+			return
+		}
+	}
+	resolved(value)
+}
+
+func (b *Bridge) rpcServer(handle goja.Value) *jsonrpc.Server {
+	if isNullish(handle) {
+		return nil
+	}
+	srv, ok := handle.Export().(*server)
+	if !ok {
+		panic(b.vm.NewTypeError("expected a server from createServer()"))
+	}
+	return srv.rpc
+}
+
+// stdio implements native.stdio(handle, framing): a peer over the
+// process's stdin and stdout, answering requests with the server of handle
+// if it isn't null. The process stays alive until stdin ends or the peer
+// is closed.
+func (b *Bridge) stdio(handle goja.Value, framing string) *goja.Object {
+	f, err := jsonrpc.ParseFraming(framing)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	conn := jsonrpc.NewConn(jsonrpc.NewStream(os.Stdin, os.Stdout, nil, f), b.rpcServer(handle))
+	ctx, cancel := context.WithCancel(context.Background())
+	release := b.runtime.KeepAlive()
+	unhook := b.runtime.AddShutdownHook(cancel)
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			unhook()
+			cancel()
+			conn.Close()
+			release()
+		})
+	}
+	go func() {
+		conn.Run(ctx)
+		b.runtime.QueueJSOperation(stop)
+	}()
+
+	obj := b.peer(func(ctx context.Context, calls []jsonrpc.Call) ([]*jsonrpc.Response, error) {
+		return conn.Batch(ctx, calls, len(calls) > 1)
+	})
+	obj.Set("close", stop)
+	return obj
+}
+
+// connect implements native.connect(handle, send): a peer over a
+// transport the script drives, such as a WebSocket. Messages to the other
+// end are given to send(message) and the other end's are passed to
+// receive(message).
+func (b *Bridge) connect(handle goja.Value, send goja.Callable) *goja.Object {
+	stream := &scriptStream{
+		b:        b,
+		send:     send,
+		incoming: make(chan []byte, 64),
+		closed:   make(chan struct{}),
+	}
+	conn := jsonrpc.NewConn(stream, b.rpcServer(handle))
+	go conn.Run(context.Background())
+
+	obj := b.peer(func(ctx context.Context, calls []jsonrpc.Call) ([]*jsonrpc.Response, error) {
+		return conn.Batch(ctx, calls, len(calls) > 1)
+	})
+	obj.Set("receive", func(message string) {
+		select {
+		case stream.incoming <- []byte(message):
+		case <-stream.closed:
+		}
+	})
+	obj.Set("close", func() { conn.Close() })
+	return obj
+}
+
+// scriptStream carries the messages of a transport driven by a script
+type scriptStream struct {
+	b        *Bridge
+	send     goja.Callable
+	incoming chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func (s *scriptStream) Read() ([]byte, error) {
+	select {
+	case msg := <-s.incoming:
+		return msg, nil
+	case <-s.closed:
+		return nil, io.EOF
+	}
+}
+
+func (s *scriptStream) Write(msg []byte) error {
+	select {
+	case <-s.closed:
+		return jsonrpc.ErrClosed
+	default:
+	}
+	s.b.runtime.QueueJSOperation(func() {
+		s.send(goja.Undefined(), s.b.vm.ToValue(string(msg)))
+	})
+	return nil
+}
+
+func (s *scriptStream) Close() error {
+	s.once.Do(func() { close(s.closed) })
+	return nil
+}
+
+// createClient implements native.createClient(url, headers): a peer that
+// POSTs its calls to url
+func (b *Bridge) createClient(url string, headers map[string]string) *goja.Object {
+	if checker, ok := b.runtime.(permissions.NetEnforcer); ok {
+		if err := checker.CheckNetURL(url); err != nil {
+			panic(jserror.New(b.vm, err))
+		}
+	}
+	client := &jsonrpc.HTTPClient{URL: url, Header: make(http.Header)}
+	for name, value := range headers {
+		client.Header.Set(name, value)
+	}
+	return b.peer(client.Batch)
+}
+
+// peer builds the calling side of a peer: call(method, params, timeout),
+// which resolves to the JSON of the result, notify(method, params) and
+// batch(calls), which resolves to [{result} or {error}, or undefined for
+// notifications]. Params are JSON, or undefined.
+func (b *Bridge) peer(send func(ctx context.Context, calls []jsonrpc.Call) ([]*jsonrpc.Response, error)) *goja.Object {
+	params := func(v goja.Value) interface{} {
+		if isNullish(v) {
+			return nil
+		}
+		return json.RawMessage(v.String())
+	}
+	run := func(calls []jsonrpc.Call, timeout int64, settle func([]*jsonrpc.Response) (interface{}, error)) goja.Value {
+		return promise.Run(b.vm, b.runtime, func() (interface{}, error) {
+			ctx, cancel := context.WithCancel(context.Background())
+			if timeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+			}
+			defer cancel()
+			responses, err := send(ctx, calls)
+			if err != nil {
+				if e, ok := err.(*jsonrpc.Error); ok {
+					return nil, &rpcError{e}
+				}
+				return nil, err
+			}
+			return settle(responses)
+		})
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("call", func(method string, p goja.Value, timeout int64) goja.Value {
+		calls := []jsonrpc.Call{{Method: method, Params: params(p)}}
+		return run(calls, timeout, func(responses []*jsonrpc.Response) (interface{}, error) {
+			if res := responses[0]; res.Error != nil {
+				return nil, &rpcError{res.Error}
+			}
+			return string(responses[0].Result), nil
+		})
+	})
+	obj.Set("notify", func(method string, p goja.Value) goja.Value {
+		calls := []jsonrpc.Call{{Method: method, Params: params(p), Notify: true}}
+		return run(calls, 0, func([]*jsonrpc.Response) (interface{}, error) {
+			return goja.Undefined(), nil
+		})
+	})
+	obj.Set("batch", func(items []*goja.Object, timeout int64) goja.Value {
+		calls := make([]jsonrpc.Call, len(items))
+		for i, item := range items {
+			calls[i] = jsonrpc.Call{
+				Method: item.Get("method").String(),
+				Params: params(item.Get("params")),
+				Notify: item.Get("notify").ToBoolean(),
+			}
+		}
+		return run(calls, timeout, func(responses []*jsonrpc.Response) (interface{}, error) {
+			data, err := json.Marshal(responses)
+			return string(data), err
+		})
+	})
+	return obj
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
@@ -0,0 +1,86 @@
+package jsonrpc_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestJSONRPCModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("jsonrpc", `
+		(async () => {
+			const rpc = require('gode:jsonrpc');
+			const results = [];
+			const seen = [];
+			const server = rpc.createServer({
+				add: (a, b) => a + b,
+				greet: async ({ name }) => 'hello ' + name,
+				fail: () => { throw new rpc.RpcError(-32000, 'no way', { why: 'because' }); },
+				crash: () => { throw new Error('boom'); },
+				log(message) { seen.push(this.method + ':' + message + ':' + this.notification); },
+			});
+
+			results.push(await server.handle('{"jsonrpc":"2.0","id":1,"method":"add","params":[2,3]}'));
+			results.push(await server.handle([
+				{ jsonrpc: '2.0', id: 'a', method: 'greet', params: { name: 'gode' } },
+				{ jsonrpc: '2.0', method: 'log', params: ['x'] },
+				{ jsonrpc: '2.0', id: 'b', method: 'crash' },
+				{ jsonrpc: '2.0', id: 'c', method: 'missing' },
+			]));
+			results.push(String(await server.handle({ jsonrpc: '2.0', method: 'log', params: ['y'] })));
+			results.push(await server.handle('{"jsonrpc"'));
+
+			// Two ends wired to each other, as over a WebSocket
+			const client = rpc.connect((message) => other.receive(message));
+			const other = rpc.connect((message) => client.receive(message), { server });
+			results.push(await client.call('add', [20, 22]));
+			results.push(await client.call('fail').catch(e => [e instanceof rpc.RpcError, e.code, e.message, e.data.why].join(',')));
+			const batch = await client.batch([
+				{ method: 'greet', params: { name: 'batch' } },
+				{ method: 'log', params: ['z'], notify: true },
+				{ method: 'missing' },
+			]);
+			results.push([batch[0].result, batch[1], batch[2].error.code].join(','));
+			results.push(await other.call('add', [1, 1]).catch(e => e.code));
+			client.close();
+			other.close();
+
+			const http = testServer(server.handler());
+			const res = await http.request({ method: 'POST', path: '/rpc', body: { jsonrpc: '2.0', id: 9, method: 'add', params: [1, 2] } });
+			results.push(res.status + ' ' + res.text());
+			const note = await http.request({ method: 'POST', path: '/rpc', body: { jsonrpc: '2.0', method: 'log', params: ['h'] } });
+			results.push(note.status);
+			results.push(seen.join(' '));
+			return results.join('\n');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"result":5}`,
+		`[{"jsonrpc":"2.0","id":"a","result":"hello gode"},` +
+			`{"jsonrpc":"2.0","id":"b","error":{"code":-32603,"message":"boom"}},` +
+			`{"jsonrpc":"2.0","id":"c","error":{"code":-32601,"message":"Method not found","data":"missing"}}]`,
+		"undefined",
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		"42",
+		"true,-32000,no way,because",
+		"hello batch,,-32601",
+		"-32601",
+		`200 {"jsonrpc":"2.0","id":9,"result":3}`,
+		"204",
+		"log:x:true log:y:true log:z:true log:h:true",
+	}, "\n")
+	if value != want {
+		t.Errorf("got\n%v\nwant\n%v", value, want)
+	}
+}
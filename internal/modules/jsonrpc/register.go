@@ -0,0 +1,29 @@
+package jsonrpc
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	KeepAlive() (release func())
+	AddShutdownHook(fn func()) (remove func())
+}
+
+// RegisterJSONRPCModule registers gode:jsonrpc in the JavaScript runtime
+func RegisterJSONRPCModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		exports, err := NewBridge(runtime).Exports()
+		if err != nil {
+			done <- err
+			return
+		}
+		runtime.RegisterModule("gode:jsonrpc", exports)
+		done <- nil
+	})
+	return <-done
+}
@@ -0,0 +1,236 @@
+package jsonrpc
+
+// jsonrpcSetup builds gode:jsonrpc on top of the native servers and peers.
+// Params and results cross to Go as JSON text, which the natives frame,
+// batch and match to calls.
+const jsonrpcSetup = `
+(function (native) {
+	const codes = Object.freeze(native.codes);
+
+	class RpcError extends Error {
+		constructor(code, message, data) {
+			super(message);
+			this.name = 'RpcError';
+			this.code = code;
+			if (data !== undefined) {
+				this.data = data;
+			}
+		}
+	}
+
+	function toRpcError(err) {
+		return new RpcError(err.code, err.message, err.data === undefined ? undefined : JSON.parse(err.data));
+	}
+
+	// rethrow turns the failures of native calls into RpcErrors
+	function rethrow(err) {
+		if (err && err.name === 'RpcError' && !(err instanceof RpcError)) {
+			throw toRpcError(err);
+		}
+		throw err;
+	}
+
+	function encodeParams(params) {
+		if (params === undefined) {
+			return undefined;
+		}
+		if (params === null || typeof params !== 'object') {
+			throw new TypeError('params must be an array or object');
+		}
+		return JSON.stringify(params);
+	}
+
+	// errorOf is the error of a response for what a method threw
+	function errorOf(err) {
+		if (err instanceof RpcError || (err && Number.isInteger(err.code))) {
+			return { code: err.code, message: String(err.message), data: err.data };
+		}
+		return { code: codes.INTERNAL_ERROR, message: err instanceof Error ? err.message : String(err) };
+	}
+
+	class Server {
+		constructor(methods) {
+			this._methods = new Map();
+			this._native = native.createServer((method, params, notification) => this._dispatch(method, params, notification));
+			if (methods) {
+				for (const name of Object.keys(methods)) {
+					this.method(name, methods[name]);
+				}
+			}
+		}
+
+		// method registers fn for name. It is called with the items of
+		// array params as its arguments, or object params as its only one,
+		// and this set to {method, notification}; what it returns, or
+		// resolves to, is the result, and what it throws the error, with
+		// the code of an RpcError or INTERNAL_ERROR.
+		method(name, fn) {
+			if (typeof fn !== 'function') {
+				throw new TypeError('method ' + name + ' must be a function');
+			}
+			this._methods.set(name, fn);
+			this._native.add(name);
+			return this;
+		}
+
+		removeMethod(name) {
+			this._methods.delete(name);
+			this._native.remove(name);
+			return this;
+		}
+
+		get methods() {
+			return Array.from(this._methods.keys());
+		}
+
+		_dispatch(method, params, notification) {
+			const fn = this._methods.get(method);
+			return new Promise((resolve) => {
+				const value = params === undefined ? undefined : JSON.parse(params);
+				const args = value === undefined ? [] : Array.isArray(value) ? value : [value];
+				resolve(fn.apply({ method: method, notification: notification }, args));
+			}).then((result) => JSON.stringify({ result: result === undefined ? null : result }))
+				.catch((err) => JSON.stringify({ error: errorOf(err) }));
+		}
+
+		// handle answers a request or batch, given as JSON text or as an
+		// object, resolving to the JSON text of the response, or to
+		// undefined when only notifications were sent
+		handle(message) {
+			return this._native.serve(typeof message === 'string' ? message : JSON.stringify(message));
+		}
+
+		// handler serves POSTed requests as a (req, res) listener for
+		// gode:http, with 204 for notifications
+		handler() {
+			return async (req, res) => {
+				if (req.method !== 'POST') {
+					res.writeHead(405, { 'Allow': 'POST', 'Content-Type': 'application/json' });
+					res.end(JSON.stringify({
+						jsonrpc: '2.0',
+						id: null,
+						error: { code: codes.INVALID_REQUEST, message: 'Invalid Request', data: 'requests must be POSTed' },
+					}));
+					return;
+				}
+				const response = await this.handle(await req.text());
+				if (response === undefined) {
+					res.writeHead(204);
+					res.end();
+					return;
+				}
+				res.writeHead(200, { 'Content-Type': 'application/json' });
+				res.end(response);
+			};
+		}
+
+		// listenStdio serves requests that arrive on stdin, see stdio()
+		listenStdio(options) {
+			return stdio(Object.assign({}, options, { server: this }));
+		}
+	}
+
+	function serverHandle(options) {
+		const server = options && options.server;
+		if (server === undefined || server === null) {
+			return null;
+		}
+		if (!(server instanceof Server)) {
+			throw new TypeError('server must come from createServer()');
+		}
+		return server._native.handle;
+	}
+
+	// Peer calls the other end of a connection, or an HTTP server
+	class Peer {
+		constructor(nativePeer, options) {
+			this._native = nativePeer;
+			this._timeout = (options && options.timeout) || 0;
+		}
+
+		// call resolves to the result of method, or rejects with an
+		// RpcError
+		call(method, params, options) {
+			const timeout = (options && options.timeout) || this._timeout;
+			return new Promise((resolve) => resolve(this._native.call(String(method), encodeParams(params), timeout)))
+				.then((result) => JSON.parse(result), rethrow);
+		}
+
+		notify(method, params) {
+			return new Promise((resolve) => resolve(this._native.notify(String(method), encodeParams(params))))
+				.then(() => undefined, rethrow);
+		}
+
+		// batch sends [{method, params, notify}] together, resolving to
+		// [{result} or {error}], with undefined for notifications
+		batch(calls, options) {
+			const timeout = (options && options.timeout) || this._timeout;
+			return new Promise((resolve) => {
+				const items = Array.from(calls, (call) => ({
+					method: String(call.method),
+					params: encodeParams(call.params),
+					notify: Boolean(call.notify),
+				}));
+				resolve(this._native.batch(items, timeout));
+			}).then((text) => JSON.parse(text).map((response) => {
+				if (response === null) {
+					return undefined;
+				}
+				if (response.error) {
+					const data = response.error.data;
+					return { error: new RpcError(response.error.code, response.error.message, data) };
+				}
+				return { result: response.result };
+			}), rethrow);
+		}
+
+		close() {
+			if (this._native.close) {
+				this._native.close();
+			}
+		}
+	}
+
+	// stdio talks over stdin and stdout, a message per line or, with
+	// framing: 'headers', after a Content-Length header as language
+	// servers do. Requests are answered by options.server.
+	function stdio(options) {
+		const framing = (options && options.framing) || 'lines';
+		return new Peer(native.stdio(serverHandle(options), framing), options);
+	}
+
+	// connect talks over a transport the script drives, such as a
+	// WebSocket: send(message) is called with the JSON text of each
+	// message for the other end, and the other end's messages are passed
+	// to the peer's receive(message)
+	function connect(send, options) {
+		if (typeof send !== 'function') {
+			throw new TypeError('connect expects a send(message) function');
+		}
+		const nativePeer = native.connect(serverHandle(options), (message) => send(message));
+		const peer = new Peer(nativePeer, options);
+		peer.receive = (message) => nativePeer.receive(typeof message === 'string' ? message : String(message));
+		return peer;
+	}
+
+	// createClient calls a server over HTTP
+	function createClient(url, options) {
+		const headers = {};
+		const given = (options && options.headers) || {};
+		for (const name of Object.keys(given)) {
+			headers[name] = String(given[name]);
+		}
+		return new Peer(native.createClient(String(url), headers), options);
+	}
+
+	return {
+		createServer: (methods) => new Server(methods),
+		createClient: createClient,
+		connect: connect,
+		stdio: stdio,
+		Server: Server,
+		RpcError: RpcError,
+		codes: codes,
+	};
+})
+`
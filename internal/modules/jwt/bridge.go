@@ -0,0 +1,279 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/modules/tls"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Bridge provides JavaScript bindings for the gode:jwt module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// jwksSource is the value returned by jwt.jwks(url)
+type jwksSource struct {
+	keys *KeySet
+}
+
+// NewBridge creates a new JWT bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("sign", b.sign)
+	exports.Set("verify", b.verify)
+	exports.Set("decode", b.decode)
+	exports.Set("jwks", b.jwks)
+	return exports
+}
+
+// sign implements jwt.sign(payload, key, {algorithm, expiresIn, notBefore, audience, issuer, subject, jwtid, keyid, noTimestamp})
+func (b *Bridge) sign(call goja.FunctionCall) goja.Value {
+	payload, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(b.vm.NewTypeError("payload must be an object"))
+	}
+	opts, _ := call.Argument(2).(*goja.Object)
+
+	claims := Claims{}
+	for _, key := range payload.Keys() {
+		claims[key] = payload.Get(key).Export()
+	}
+
+	now := time.Now().Unix()
+	if !b.boolOption(opts, "noTimestamp") {
+		if _, set := claims["iat"]; !set {
+			claims["iat"] = now
+		}
+	}
+	if v := b.option(opts, "expiresIn"); v != nil {
+		claims["exp"] = now + v.ToInteger()
+	}
+	if v := b.option(opts, "notBefore"); v != nil {
+		claims["nbf"] = now + v.ToInteger()
+	}
+	if aud := b.stringsOption(opts, "audience"); len(aud) == 1 {
+		claims["aud"] = aud[0]
+	} else if len(aud) > 1 {
+		claims["aud"] = aud
+	}
+	for option, claim := range map[string]string{"issuer": "iss", "subject": "sub", "jwtid": "jti"} {
+		if v := b.option(opts, option); v != nil {
+			claims[claim] = v.String()
+		}
+	}
+
+	key, alg := b.signingKey(call.Argument(1))
+	if v := b.option(opts, "algorithm"); v != nil {
+		alg = v.String()
+	}
+
+	token, err := Sign(claims, alg, key, b.stringOption(opts, "keyid"))
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return b.vm.ToValue(token)
+}
+
+// verify implements jwt.verify(token, key, options). With a key it returns
+// the claims; with a jwt.jwks(url) source it returns a Promise of the claims.
+func (b *Bridge) verify(call goja.FunctionCall) goja.Value {
+	raw := call.Argument(0).String()
+	opts, _ := call.Argument(2).(*goja.Object)
+	validate := b.validateOptions(opts)
+
+	if source, ok := call.Argument(1).Export().(*jwksSource); ok {
+		b.checkNet(source.keys.URL)
+
+		p, resolver := promise.New(b.vm, b.runtime)
+		go func() {
+			claims, err := Verify(raw, func(token *Token) (interface{}, error) {
+				return source.keys.Key(context.Background(), token.Header.Kid)
+			}, validate)
+			if err == nil {
+				resolver.Resolve(map[string]interface{}(claims))
+				return
+			}
+			// The error names of jsError are set on the JS thread
+			b.runtime.QueueJSOperation(func() {
+				resolver.Reject(b.jsError(err))
+			})
+		}()
+		return p
+	}
+
+	key := b.verificationKey(call.Argument(1))
+	claims, err := Verify(raw, func(*Token) (interface{}, error) { return key, nil }, validate)
+	if err != nil {
+		panic(b.jsError(err))
+	}
+	return b.vm.ToValue(map[string]interface{}(claims))
+}
+
+// decode implements jwt.decode(token) without verifying the signature
+func (b *Bridge) decode(call goja.FunctionCall) goja.Value {
+	token, err := Parse(call.Argument(0).String())
+	if err != nil {
+		return goja.Null()
+	}
+
+	header := b.vm.NewObject()
+	header.Set("alg", token.Header.Alg)
+	header.Set("typ", token.Header.Typ)
+	if token.Header.Kid != "" {
+		header.Set("kid", token.Header.Kid)
+	}
+
+	result := b.vm.NewObject()
+	result.Set("header", header)
+	result.Set("payload", map[string]interface{}(token.Claims))
+	return result
+}
+
+// jwks implements jwt.jwks(url, {cacheMaxAge}) returning a key source for verify
+func (b *Bridge) jwks(call goja.FunctionCall) goja.Value {
+	keys := NewKeySet(call.Argument(0).String())
+	if opts, ok := call.Argument(1).(*goja.Object); ok {
+		if v := b.option(opts, "cacheMaxAge"); v != nil {
+			keys.TTL = time.Duration(v.ToInteger()) * time.Millisecond
+		}
+	}
+	return b.vm.ToValue(&jwksSource{keys: keys})
+}
+
+// signingKey converts a JS key into a Go signing key and its default algorithm
+func (b *Bridge) signingKey(value goja.Value) (interface{}, string) {
+	data := value.String()
+	if !strings.HasPrefix(strings.TrimSpace(data), "-----BEGIN") {
+		return []byte(data), "HS256"
+	}
+
+	key, err := tls.ParsePrivateKey([]byte(data))
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return key, "RS256"
+	case *ecdsa.PrivateKey:
+		return key, "ES256"
+	}
+	panic(b.vm.NewTypeError(fmt.Sprintf("unsupported signing key type %T", key)))
+}
+
+// verificationKey converts a JS key into a Go verification key
+func (b *Bridge) verificationKey(value goja.Value) interface{} {
+	data := value.String()
+	if !strings.HasPrefix(strings.TrimSpace(data), "-----BEGIN") {
+		return []byte(data)
+	}
+
+	key, err := ParseVerificationKey([]byte(data))
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return key
+}
+
+func (b *Bridge) validateOptions(opts *goja.Object) ValidateOptions {
+	validate := ValidateOptions{
+		Algorithms:       b.stringsOption(opts, "algorithms"),
+		Audience:         b.stringsOption(opts, "audience"),
+		Issuer:           b.stringOption(opts, "issuer"),
+		Subject:          b.stringOption(opts, "subject"),
+		IgnoreExpiration: b.boolOption(opts, "ignoreExpiration"),
+	}
+	if v := b.option(opts, "clockTolerance"); v != nil {
+		validate.Leeway = time.Duration(v.ToInteger()) * time.Second
+	}
+	return validate
+}
+
+// jsError converts a verification error into a JS error named like the
+// errors thrown by the jsonwebtoken package
+func (b *Bridge) jsError(err error) goja.Value {
+	obj := b.vm.NewGoError(err)
+	name := "JsonWebTokenError"
+	if verr, ok := err.(*ValidationError); ok {
+		switch verr.Reason {
+		case "expired":
+			name = "TokenExpiredError"
+		case "not_before":
+			name = "NotBeforeError"
+		}
+	}
+	obj.Set("name", name)
+	return obj
+}
+
+// checkNet throws a PermissionDenied error if the runtime denies network
+// access to rawURL
+func (b *Bridge) checkNet(rawURL string) {
+	checker, ok := b.runtime.(permissions.NetEnforcer)
+	if !ok {
+		return
+	}
+
+	if err := checker.CheckNetURL(rawURL); err != nil {
+		panic(jserror.New(b.vm, err))
+	}
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	if obj == nil {
+		return nil
+	}
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
+
+func (b *Bridge) stringOption(obj *goja.Object, name string) string {
+	if value := b.option(obj, name); value != nil {
+		return value.String()
+	}
+	return ""
+}
+
+func (b *Bridge) boolOption(obj *goja.Object, name string) bool {
+	if value := b.option(obj, name); value != nil {
+		return value.ToBoolean()
+	}
+	return false
+}
+
+// stringsOption reads an option that may be a string or an array of strings
+func (b *Bridge) stringsOption(obj *goja.Object, name string) []string {
+	value := b.option(obj, name)
+	if value == nil {
+		return nil
+	}
+	if s, ok := value.Export().(string); ok {
+		return []string{s}
+	}
+
+	var values []string
+	if err := b.vm.ExportTo(value, &values); err != nil {
+		panic(b.vm.NewTypeError(fmt.Sprintf("%s must be a string or an array of strings", name)))
+	}
+	return values
+}
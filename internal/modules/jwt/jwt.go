@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Header is the decoded JOSE header of a token
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// Claims is the decoded payload of a token
+type Claims map[string]interface{}
+
+// Token is a parsed but not yet verified JWT
+type Token struct {
+	Header       Header
+	Claims       Claims
+	SigningInput string // "<header>.<payload>" as it appeared on the wire
+	Signature    []byte
+}
+
+// Parse splits and decodes a compact JWS without verifying it
+func Parse(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token: expected 3 parts, got %d", len(parts))
+	}
+
+	var token Token
+	if err := decodeSegment(parts[0], &token.Header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	if err := decodeSegment(parts[1], &token.Claims); err != nil {
+		return nil, fmt.Errorf("malformed token payload: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	token.SigningInput = parts[0] + "." + parts[1]
+	token.Signature = sig
+	return &token, nil
+}
+
+// Sign creates a compact JWS for claims. key is a []byte secret for HS256,
+// an *rsa.PrivateKey for RS256 or an *ecdsa.PrivateKey (P-256) for ES256.
+func Sign(claims Claims, alg string, key interface{}, kid string) (string, error) {
+	header, err := json.Marshal(Header{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", fmt.Errorf("HS256 requires a secret, got %T", key)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case "RS256":
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("RS256 requires an RSA private key, got %T", key)
+		}
+		sig, err = rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", fmt.Errorf("failed to sign: %w", err)
+		}
+	case "ES256":
+		priv, ok := key.(*ecdsa.PrivateKey)
+		if !ok || priv.Curve.Params().BitSize != 256 {
+			return "", fmt.Errorf("ES256 requires a P-256 ECDSA private key, got %T", key)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		if err != nil {
+			return "", fmt.Errorf("failed to sign: %w", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		return "", fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// VerifySignature checks the token signature with key, which must match the
+// algorithm in the header ([]byte for HS256, *rsa.PublicKey for RS256,
+// *ecdsa.PublicKey for ES256). Comparisons of MACs are constant-time.
+func (t *Token) VerifySignature(key interface{}) error {
+	digest := sha256.Sum256([]byte(t.SigningInput))
+
+	switch t.Header.Alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("HS256 requires a secret, got %T", key)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(t.SigningInput))
+		if !hmac.Equal(mac.Sum(nil), t.Signature) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("RS256 requires an RSA public key, got %T", key)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], t.Signature); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("ES256 requires an ECDSA public key, got %T", key)
+		}
+		if len(t.Signature) != 64 {
+			return fmt.Errorf("invalid signature")
+		}
+		r := new(big.Int).SetBytes(t.Signature[:32])
+		s := new(big.Int).SetBytes(t.Signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm: %s", t.Header.Alg)
+}
+
+// Verify parses raw, checks that its algorithm is one of opts.Algorithms
+// (defaulting to the family of the key), verifies the signature with the key
+// returned by keyFunc and validates the registered claims
+func Verify(raw string, keyFunc func(*Token) (interface{}, error), opts ValidateOptions) (Claims, error) {
+	token, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keyFunc(token)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := opts.Algorithms
+	if len(allowed) == 0 {
+		allowed = algorithmsFor(key)
+	}
+	if !contains(allowed, token.Header.Alg) {
+		return nil, fmt.Errorf("algorithm %q is not allowed", token.Header.Alg)
+	}
+
+	if err := token.VerifySignature(key); err != nil {
+		return nil, err
+	}
+	if err := token.Claims.Validate(opts); err != nil {
+		return nil, err
+	}
+
+	return token.Claims, nil
+}
+
+// algorithmsFor returns the algorithms that may be used with key, so a public
+// key can never be mistaken for an HMAC secret
+func algorithmsFor(key interface{}) []string {
+	switch key.(type) {
+	case []byte:
+		return []string{"HS256"}
+	case *rsa.PublicKey:
+		return []string{"RS256"}
+	case *ecdsa.PublicKey:
+		return []string{"ES256"}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,155 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/internal/modules/tls"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	tests := []struct {
+		alg    string
+		sign   interface{}
+		verify interface{}
+	}{
+		{"HS256", []byte("secret"), []byte("secret")},
+		{"RS256", rsaKey, &rsaKey.PublicKey},
+		{"ES256", ecKey, &ecKey.PublicKey},
+	}
+
+	for _, tt := range tests {
+		token, err := Sign(Claims{"sub": "user-1"}, tt.alg, tt.sign, "k1")
+		if err != nil {
+			t.Fatalf("%s: Sign failed: %v", tt.alg, err)
+		}
+
+		claims, err := Verify(token, func(tok *Token) (interface{}, error) {
+			if tok.Header.Kid != "k1" {
+				t.Errorf("%s: expected kid k1, got %q", tt.alg, tok.Header.Kid)
+			}
+			return tt.verify, nil
+		}, ValidateOptions{})
+		if err != nil {
+			t.Fatalf("%s: Verify failed: %v", tt.alg, err)
+		}
+		if claims["sub"] != "user-1" {
+			t.Errorf("%s: expected sub user-1, got %v", tt.alg, claims["sub"])
+		}
+	}
+}
+
+func TestVerifyRejectsWrongKeyAndAlgorithm(t *testing.T) {
+	token, err := Sign(Claims{"sub": "user-1"}, "HS256", []byte("secret"), "")
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	if _, err := Verify(token, staticKey([]byte("other")), ValidateOptions{}); err == nil {
+		t.Error("Expected wrong secret to fail")
+	}
+
+	// An HS256 token must not verify against an RSA public key
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := Verify(token, staticKey(&rsaKey.PublicKey), ValidateOptions{}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("Expected algorithm to be rejected, got %v", err)
+	}
+
+	if _, err := Verify(token, staticKey([]byte("secret")), ValidateOptions{Algorithms: []string{"RS256"}}); err == nil {
+		t.Error("Expected HS256 to be rejected when only RS256 is allowed")
+	}
+
+	unsigned := strings.Join(strings.Split(token, ".")[:2], ".") + "."
+	if _, err := Verify(unsigned, staticKey([]byte("secret")), ValidateOptions{}); err == nil {
+		t.Error("Expected token without signature to fail")
+	}
+}
+
+func TestClaimsValidate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	opts := ValidateOptions{Now: func() time.Time { return now }}
+
+	tests := []struct {
+		name   string
+		claims Claims
+		opts   func(ValidateOptions) ValidateOptions
+		reason string
+	}{
+		{"valid", Claims{"exp": float64(now.Unix() + 60)}, nil, ""},
+		{"expired", Claims{"exp": float64(now.Unix() - 1)}, nil, "expired"},
+		{"expired within leeway", Claims{"exp": float64(now.Unix() - 5)}, func(o ValidateOptions) ValidateOptions { o.Leeway = 10 * time.Second; return o }, ""},
+		{"ignore expiration", Claims{"exp": float64(now.Unix() - 5)}, func(o ValidateOptions) ValidateOptions { o.IgnoreExpiration = true; return o }, ""},
+		{"not before", Claims{"nbf": float64(now.Unix() + 60)}, nil, "not_before"},
+		{"audience match", Claims{"aud": []interface{}{"a", "b"}}, func(o ValidateOptions) ValidateOptions { o.Audience = []string{"b"}; return o }, ""},
+		{"audience mismatch", Claims{"aud": "a"}, func(o ValidateOptions) ValidateOptions { o.Audience = []string{"b"}; return o }, "audience"},
+		{"issuer mismatch", Claims{"iss": "x"}, func(o ValidateOptions) ValidateOptions { o.Issuer = "y"; return o }, "issuer"},
+	}
+
+	for _, tt := range tests {
+		o := opts
+		if tt.opts != nil {
+			o = tt.opts(opts)
+		}
+
+		err := tt.claims.Validate(o)
+		if tt.reason == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+
+		verr, ok := err.(*ValidationError)
+		if !ok || verr.Reason != tt.reason {
+			t.Errorf("%s: expected %s error, got %v", tt.name, tt.reason, err)
+		}
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	privDER, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	pubDER, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	signer, err := tls.ParsePrivateKey(privPEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey failed: %v", err)
+	}
+	token, err := Sign(Claims{"sub": "x"}, "ES256", signer, "")
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	for _, data := range [][]byte{pubPEM, privPEM} {
+		key, err := ParseVerificationKey(data)
+		if err != nil {
+			t.Fatalf("ParseVerificationKey failed: %v", err)
+		}
+		if _, err := Verify(token, staticKey(key), ValidateOptions{}); err != nil {
+			t.Errorf("Verify with parsed key failed: %v", err)
+		}
+	}
+}
+
+func staticKey(key interface{}) func(*Token) (interface{}, error) {
+	return func(*Token) (interface{}, error) { return key, nil }
+}
@@ -0,0 +1,23 @@
+package jwt
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// RegisterJWTModule registers gode:jwt in the JavaScript runtime
+func RegisterJWTModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime)
+		runtime.RegisterModule("gode:jwt", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"fmt"
+	"time"
+)
+
+// ValidateOptions controls claim validation
+type ValidateOptions struct {
+	Algorithms       []string      // Accepted "alg" values; empty derives them from the key
+	Audience         []string      // Token must contain at least one of these in "aud"
+	Issuer           string        // Required "iss" when set
+	Subject          string        // Required "sub" when set
+	Leeway           time.Duration // Clock skew tolerance for exp/nbf/iat
+	IgnoreExpiration bool
+	Now              func() time.Time
+}
+
+// ValidationError is returned when a token is well-signed but its claims are
+// not acceptable
+type ValidationError struct {
+	Reason string // "expired", "not_before", "audience", "issuer" or "subject"
+	Msg    string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+// Validate checks exp, nbf, iat, aud, iss and sub against opts
+func (c Claims) Validate(opts ValidateOptions) error {
+	now := time.Now()
+	if opts.Now != nil {
+		now = opts.Now()
+	}
+
+	if exp, ok := c.time("exp"); ok && !opts.IgnoreExpiration && !now.Before(exp.Add(opts.Leeway)) {
+		return &ValidationError{Reason: "expired", Msg: fmt.Sprintf("jwt expired at %s", exp.UTC().Format(time.RFC3339))}
+	}
+	if nbf, ok := c.time("nbf"); ok && now.Add(opts.Leeway).Before(nbf) {
+		return &ValidationError{Reason: "not_before", Msg: fmt.Sprintf("jwt not active until %s", nbf.UTC().Format(time.RFC3339))}
+	}
+	if iat, ok := c.time("iat"); ok && now.Add(opts.Leeway).Before(iat) {
+		return &ValidationError{Reason: "not_before", Msg: "jwt issued in the future"}
+	}
+
+	if len(opts.Audience) > 0 && !c.hasAudience(opts.Audience) {
+		return &ValidationError{Reason: "audience", Msg: fmt.Sprintf("jwt audience invalid, expected one of %v", opts.Audience)}
+	}
+	if opts.Issuer != "" && c["iss"] != opts.Issuer {
+		return &ValidationError{Reason: "issuer", Msg: fmt.Sprintf("jwt issuer invalid, expected %s", opts.Issuer)}
+	}
+	if opts.Subject != "" && c["sub"] != opts.Subject {
+		return &ValidationError{Reason: "subject", Msg: fmt.Sprintf("jwt subject invalid, expected %s", opts.Subject)}
+	}
+
+	return nil
+}
+
+// time reads a NumericDate claim
+func (c Claims) time(name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	}
+	return time.Time{}, false
+}
+
+// hasAudience reports whether "aud" (a string or array) contains any of want
+func (c Claims) hasAudience(want []string) bool {
+	var have []string
+	switch v := c["aud"].(type) {
+	case string:
+		have = []string{v}
+	case []string:
+		have = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				have = append(have, s)
+			}
+		}
+	}
+
+	for _, a := range have {
+		if contains(want, a) {
+			return true
+		}
+	}
+	return false
+}
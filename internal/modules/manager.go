@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/install"
+	"github.com/rizqme/gode/internal/permissions"
 	"github.com/rizqme/gode/internal/plugins"
 	"github.com/rizqme/gode/pkg/config"
 )
@@ -15,17 +20,42 @@ import (
 type ModuleManager struct {
 	config         *config.PackageJSON
 	cache          map[string]string
+	loaded         map[string]*LoadedModule
+	loadedMu       sync.Mutex // guards loaded, which monitoring reads from other goroutines
 	importMaps     map[string]string
+	nameMapper     []nameMapping // modules substituted under gode test
 	registries     map[string]string
+	lock           *install.Lockfile // gode.lock, if the project has one
+	lockErr        error             // why gode.lock could not be read
+	verifiedMu     sync.Mutex
+	verified       map[string]error // packages checked against gode.lock, by key
 	pluginRegistry *plugins.Registry
+	pluginWatches  map[string]bool // plugin files reloaded when they change
 	vm             interface{}
 	runtime        interface{}
 }
 
+// LoadedModule describes a module in the manager's cache
+type LoadedModule struct {
+	Specifier string
+	Path      string        // resolved path, URL or gode: name
+	Size      int           // source length in bytes; 0 for built-ins and plugins
+	LoadTime  time.Duration // time spent resolving and reading the source
+	LoadedAt  time.Time
+}
+
+// loadObserver is implemented by runtimes that publish module and plugin
+// loads as events
+type loadObserver interface {
+	ModuleLoaded(mod LoadedModule)
+	PluginLoaded(info *plugins.PluginInfo)
+}
+
 // NewModuleManager creates a new module manager
 func NewModuleManager() *ModuleManager {
 	return &ModuleManager{
 		cache:      make(map[string]string),
+		loaded:     make(map[string]*LoadedModule),
 		importMaps: make(map[string]string),
 		registries: make(map[string]string),
 	}
@@ -35,6 +65,7 @@ func NewModuleManager() *ModuleManager {
 func NewModuleManagerWithRuntime(runtime interface{}) *ModuleManager {
 	m := &ModuleManager{
 		cache:      make(map[string]string),
+		loaded:     make(map[string]*LoadedModule),
 		importMaps: make(map[string]string),
 		registries: make(map[string]string),
 		runtime:    runtime,
@@ -74,6 +105,16 @@ func (m *ModuleManager) Configure(cfg *config.PackageJSON) error {
 		}
 	}
 	
+	// Packages under node_modules are checked against gode.lock as they load
+	if cfg.ProjectRoot != "" {
+		lock, err := install.ReadLockfile(cfg.ProjectRoot)
+		if err != nil && !os.IsNotExist(err) {
+			m.lockErr = err
+		}
+		m.lock = lock
+		m.verified = make(map[string]error)
+	}
+	
 	return nil
 }
 
@@ -85,6 +126,8 @@ func (m *ModuleManager) Load(specifier string) (string, error) {
 			return cached, nil
 		}
 		
+		start := time.Now()
+		
 		// Resolve the module
 		resolved, err := m.Resolve(specifier, "")
 		if err != nil {
@@ -98,7 +141,7 @@ func (m *ModuleManager) Load(specifier string) (string, error) {
 		}
 		
 		// For plugins, register with the original specifier name for direct loading
-		if strings.HasSuffix(resolved, ".so") && source == "" {
+		if IsPlugin(resolved) && source == "" {
 			if rt, ok := m.runtime.(interface{ RegisterModule(string, interface{}) }); ok {
 				// Get the plugin from the base name first
 				pluginName := filepath.Base(strings.TrimSuffix(resolved, filepath.Ext(resolved)))
@@ -114,6 +157,19 @@ func (m *ModuleManager) Load(specifier string) (string, error) {
 		
 		// Cache the result
 		m.cache[specifier] = source
+		loaded := &LoadedModule{
+			Specifier: specifier,
+			Path:      resolved,
+			Size:      len(source),
+			LoadTime:  time.Since(start),
+			LoadedAt:  start,
+		}
+		m.loadedMu.Lock()
+		m.loaded[specifier] = loaded
+		m.loadedMu.Unlock()
+		if observer, ok := m.runtime.(loadObserver); ok {
+			observer.ModuleLoaded(*loaded)
+		}
 		
 		return source, nil
 	})
@@ -121,10 +177,37 @@ func (m *ModuleManager) Load(specifier string) (string, error) {
 
 // Resolve implements the ModuleLoader interface
 func (m *ModuleManager) Resolve(specifier, referrer string) (string, error) {
+	resolved, err := m.resolve(specifier, referrer)
+	if miss, ok := err.(*unresolved); ok {
+		return "", errors.NewModuleError(miss.specifier, miss.referrer, "resolve", miss.err)
+	}
+	return resolved, err
+}
+
+// TryResolve resolves specifier as Resolve does, for callers that only
+// check whether it resolves: a specifier that doesn't returns false,
+// without the cost of the ModuleError Resolve would make for it
+func (m *ModuleManager) TryResolve(specifier, referrer string) (string, bool) {
+	resolved, err := m.resolve(specifier, referrer)
+	return resolved, err == nil
+}
+
+// unresolved is why a specifier didn't resolve, which Resolve reports as a
+// ModuleError
+type unresolved struct {
+	specifier, referrer string
+	err                 error
+}
+
+func (e *unresolved) Error() string {
+	return e.err.Error()
+}
+
+func (m *ModuleManager) resolve(specifier, referrer string) (string, error) {
 	return errors.SafeOperationWithResult("ModuleManager", "Resolve", func() (string, error) {
 		// 1. Check import mappings
 		if mapped, exists := m.importMaps[specifier]; exists {
-			return m.Resolve(mapped, referrer)
+			return m.resolve(mapped, referrer)
 		}
 		
 		// 1b. Check import mappings with prefix matching (for @app/file.js)
@@ -133,12 +216,13 @@ func (m *ModuleManager) Resolve(specifier, referrer string) (string, error) {
 				// Replace the alias part with the mapped path
 				remaining := strings.TrimPrefix(specifier, alias)
 				newSpecifier := path + remaining
-				return m.Resolve(newSpecifier, referrer)
+				return m.resolve(newSpecifier, referrer)
 			}
 		}
 		
-		// 2. Check for built-in modules
-		if strings.HasPrefix(specifier, "gode:") {
+		// 2. Check for built-in modules and data: URLs, which are their
+		// own source
+		if strings.HasPrefix(specifier, "gode:") || IsDataURL(specifier) {
 			return specifier, nil
 		}
 		
@@ -149,7 +233,16 @@ func (m *ModuleManager) Resolve(specifier, referrer string) (string, error) {
 			}
 		}
 		
-		// 4. Check for file paths
+		// 4. Check for file paths. A data: URL module has no directory for
+		// relative paths to start from.
+		if IsDataURL(referrer) && IsRelative(specifier) {
+			return "", &unresolved{specifier, DataURLName(referrer), fmt.Errorf("relative imports are not supported in data: URL modules")}
+		}
+		
+		// 4b. Paths in remote modules are relative to their URL
+		if IsHTTPURL(referrer) && (IsRelative(specifier) || strings.HasPrefix(specifier, "/")) {
+			return resolveURL(specifier, referrer)
+		}
 		if m.isFilePath(specifier) {
 			return m.resolveFilePath(specifier, referrer)
 		}
@@ -159,7 +252,7 @@ func (m *ModuleManager) Resolve(specifier, referrer string) (string, error) {
 			return specifier, nil
 		}
 		
-		return "", errors.NewModuleError(specifier, referrer, "resolve", fmt.Errorf("cannot resolve module: %s", specifier))
+		return "", &unresolved{specifier, referrer, fmt.Errorf("cannot resolve module: %s", specifier)}
 	})
 }
 
@@ -167,8 +260,11 @@ func (m *ModuleManager) resolveDependency(name, version string) (string, error)
 	// Parse version specifier (e.g., "npm:lodash@^4.17.21" or "file:./plugin.so")
 	if strings.HasPrefix(version, "file:") {
 		// Local file dependency
-		path := strings.TrimPrefix(version, "file:")
-		return filepath.Abs(path)
+		path, err := filepath.Abs(strings.TrimPrefix(version, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return m.realPath(path), nil
 	}
 	
 	if strings.HasPrefix(version, "npm:") {
@@ -192,19 +288,37 @@ func (m *ModuleManager) resolveDependency(name, version string) (string, error)
 func (m *ModuleManager) resolveNPMDependency(name, version string) (string, error) {
 	// TODO: Implement proper npm registry resolution
 	// For now, assume node_modules structure
-	return filepath.Join("node_modules", name), nil
+	return m.realPath(filepath.Join("node_modules", name)), nil
 }
 
 func (m *ModuleManager) resolveFilePath(specifier, referrer string) (string, error) {
 	if filepath.IsAbs(specifier) {
-		return specifier, nil
+		return m.realPath(specifier), nil
 	}
 	
 	if referrer != "" {
-		return filepath.Join(filepath.Dir(referrer), specifier), nil
+		return m.realPath(filepath.Join(filepath.Dir(referrer), specifier)), nil
 	}
 	
-	return filepath.Abs(specifier)
+	path, err := filepath.Abs(specifier)
+	if err != nil {
+		return "", err
+	}
+	return m.realPath(path), nil
+}
+
+// realPath resolves the symlinks in path, so that a module reached through
+// several links, such as the node_modules entries of a pnpm store, is
+// loaded once under its real location. With preserve-symlinks set, or for
+// paths that don't exist, path is returned as it is.
+func (m *ModuleManager) realPath(path string) string {
+	if m.config != nil && m.config.Gode.PreserveSymlinks {
+		return path
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	return path
 }
 
 func (m *ModuleManager) isFilePath(specifier string) bool {
@@ -213,19 +327,17 @@ func (m *ModuleManager) isFilePath(specifier string) bool {
 		return false
 	}
 	
-	return strings.HasPrefix(specifier, "./") ||
-		strings.HasPrefix(specifier, "../") ||
-		strings.HasPrefix(specifier, "/") ||
+	return IsRelative(specifier) ||
+		isAbsolute(specifier) ||
 		filepath.IsAbs(specifier) ||
-		strings.HasSuffix(specifier, ".so") ||
+		IsPlugin(specifier) ||
 		strings.HasSuffix(specifier, ".js") ||
 		strings.HasSuffix(specifier, ".json") ||
 		strings.HasSuffix(specifier, ".ts")
 }
 
 func (m *ModuleManager) isHTTPURL(specifier string) bool {
-	return strings.HasPrefix(specifier, "http://") ||
-		strings.HasPrefix(specifier, "https://")
+	return IsHTTPURL(specifier)
 }
 
 func (m *ModuleManager) loadFromPath(path string) (string, error) {
@@ -238,8 +350,12 @@ func (m *ModuleManager) loadFromPath(path string) (string, error) {
 		return m.loadHTTPModule(path)
 	}
 	
-	if strings.HasSuffix(path, ".so") {
-		return m.loadGoPlugin(path)
+	if IsDataURL(path) {
+		return m.loadDataURL(path)
+	}
+	
+	if IsPlugin(path) {
+		return m.loadGoPlugin(pluginPath(path))
 	}
 	
 	// Load as regular file
@@ -252,23 +368,31 @@ func (m *ModuleManager) loadBuiltinModule(specifier string) (string, error) {
 	return "", nil
 }
 
-func (m *ModuleManager) loadHTTPModule(url string) (string, error) {
-	// TODO: Implement HTTP module loading with caching
-	return "", fmt.Errorf("HTTP module loading not yet implemented: %s", url)
-}
-
 func (m *ModuleManager) loadGoPlugin(path string) (string, error) {
 	return errors.SafeOperationWithResult("ModuleManager", "LoadGoPlugin", func() (string, error) {
 		if m.pluginRegistry == nil {
 			return "", errors.NewModuleError("plugin", path, "load", fmt.Errorf("plugin system not initialized (VM/Runtime required)"))
 		}
 		
+		if err := m.checkPermission("plugin", path); err != nil {
+			return "", errors.NewModuleError("plugin", path, "load", err)
+		}
+		
 		// Load the plugin
 		jsObj, err := m.pluginRegistry.LoadPlugin(path)
 		if err != nil {
 			return "", errors.NewModuleError("plugin", path, "load", err).WithSourceContext(fmt.Sprintf("Plugin path: %s", path))
 		}
 		
+		if observer, ok := m.runtime.(loadObserver); ok {
+			if info, exists := m.pluginRegistry.GetPluginInfo(path); exists {
+				observer.PluginLoaded(info)
+			}
+		}
+		if m.config != nil && m.config.Gode.WatchPlugins {
+			m.watchPlugin(path)
+		}
+		
 		// Register as a module in the runtime
 		pluginName := filepath.Base(strings.TrimSuffix(path, filepath.Ext(path)))
 		if rt, ok := m.runtime.(interface{ RegisterModule(string, interface{}) }); ok {
@@ -287,6 +411,14 @@ func (m *ModuleManager) loadFileModule(path string) (string, error) {
 			return "", errors.NewModuleError("file", path, "load", fmt.Errorf("file not found: %s", path))
 		}
 		
+		if err := m.checkPermission("read", path); err != nil {
+			return "", errors.NewModuleError("file", path, "read", err)
+		}
+		
+		if err := m.verifyPackage(path); err != nil {
+			return "", errors.NewModuleError("file", path, "verify", err)
+		}
+		
 		// Read file contents
 		content, err := os.ReadFile(path)
 		if err != nil {
@@ -311,4 +443,169 @@ func (m *ModuleManager) loadFileModule(path string) (string, error) {
 			return string(content), nil
 		}
 	})
-}
\ No newline at end of file
+}
+
+// verifyPackage checks the package that the file at path belongs to
+// against the contents hash gode.lock has for it, the first time one of its
+// files loads. Files outside node_modules, and projects without a
+// lockfile, are not checked.
+func (m *ModuleManager) verifyPackage(path string) error {
+	if m.config == nil || m.config.ProjectRoot == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(m.config.ProjectRoot, path)
+	if err != nil || !strings.HasPrefix(filepath.ToSlash(rel), "node_modules/") {
+		return nil
+	}
+	if m.lockErr != nil {
+		return m.lockErr
+	}
+	if m.lock == nil {
+		return nil
+	}
+	key, ok := m.lock.PackageOf(rel)
+	if !ok {
+		return fmt.Errorf("%s is not in %s; run gode install", filepath.Dir(rel), install.LockfileName)
+	}
+	
+	m.verifiedMu.Lock()
+	defer m.verifiedMu.Unlock()
+	if err, done := m.verified[key]; done {
+		return err
+	}
+	locked := m.lock.Packages[key]
+	got, err := install.ContentsIntegrity(filepath.Join(m.config.ProjectRoot, filepath.FromSlash(key)))
+	if err == nil && got != locked.Contents {
+		err = fmt.Errorf("%s@%s does not match %s (got %s); run gode install to restore it", key, locked.Version, install.LockfileName, got)
+	}
+	m.verified[key] = err
+	return err
+}
+
+// Modules returns the modules loaded so far, ordered by specifier
+func (m *ModuleManager) Modules() []LoadedModule {
+	m.loadedMu.Lock()
+	defer m.loadedMu.Unlock()
+	list := make([]LoadedModule, 0, len(m.loaded))
+	for _, mod := range m.loaded {
+		list = append(list, *mod)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Specifier < list[j].Specifier })
+	return list
+}
+
+// ResolvedPath returns the path specifier was loaded from, or "" if it
+// has not been loaded
+func (m *ModuleManager) ResolvedPath(specifier string) string {
+	m.loadedMu.Lock()
+	defer m.loadedMu.Unlock()
+	if mod, exists := m.loaded[specifier]; exists {
+		return mod.Path
+	}
+	return ""
+}
+
+// Plugins returns the Go plugins loaded so far, ordered by name
+func (m *ModuleManager) Plugins() []*plugins.PluginInfo {
+	if m.pluginRegistry == nil {
+		return nil
+	}
+	list := m.pluginRegistry.ListPlugins()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ReloadPlugin replaces a loaded plugin with the current build of its file,
+// see plugins.Registry.ReloadPlugin, and returns its module. It must be
+// called on the JS thread.
+func (m *ModuleManager) ReloadPlugin(nameOrPath string) (plugins.Object, error) {
+	if m.pluginRegistry == nil {
+		return nil, errors.NewModuleError("plugin", nameOrPath, "reload", fmt.Errorf("plugin system not initialized (VM/Runtime required)"))
+	}
+	if filepath.Ext(nameOrPath) != "" {
+		if abs, err := filepath.Abs(pluginPath(nameOrPath)); err == nil {
+			nameOrPath = m.realPath(abs)
+		}
+	}
+	return m.pluginRegistry.ReloadPlugin(nameOrPath)
+}
+
+// watchPlugin reloads the plugin loaded from path whenever its file
+// changes, for gode.watch-plugins, until the runtime shuts down. Failed
+// reloads are reported on stderr; the running build stays in place.
+func (m *ModuleManager) watchPlugin(path string) {
+	info, exists := m.pluginRegistry.GetPluginInfo(path)
+	if !exists || m.pluginWatches[info.Path] {
+		return
+	}
+	report := func(err error) {
+		fmt.Fprintf(os.Stderr, "gode: reloading plugin %s failed: %v\n", info.Path, err)
+	}
+	stop, err := m.pluginRegistry.WatchPlugin(info.Path, report)
+	if err != nil {
+		report(err)
+		return
+	}
+	if m.pluginWatches == nil {
+		m.pluginWatches = make(map[string]bool)
+	}
+	m.pluginWatches[info.Path] = true
+	if rt, ok := m.runtime.(interface{ AddShutdownHook(fn func()) func() }); ok {
+		rt.AddShutdownHook(stop)
+	}
+}
+
+// checkPermission asks the runtime, if it enforces permissions, whether the
+// access is allowed
+func (m *ModuleManager) checkPermission(kind, resource string) error {
+	if checker, ok := m.runtime.(permissions.Enforcer); ok {
+		return checker.CheckPermission(kind, resource)
+	}
+	return nil
+}
+
+// PackageOf returns the name of the dependency that owns specifier, or ""
+// when it belongs to the project itself or is a built-in module
+func (m *ModuleManager) PackageOf(specifier string) string {
+	if m.config != nil {
+		if _, exists := m.config.Dependencies[specifier]; exists {
+			return specifier
+		}
+	}
+	
+	resolved, ok := m.TryResolve(specifier, "")
+	if !ok || strings.HasPrefix(resolved, "gode:") {
+		return ""
+	}
+	
+	// Files inside node_modules belong to the package directory they sit in
+	parts := strings.Split(filepath.ToSlash(resolved), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] != "node_modules" {
+			continue
+		}
+		if strings.HasPrefix(parts[i+1], "@") && i+2 < len(parts) {
+			return parts[i+1] + "/" + parts[i+2]
+		}
+		return parts[i+1]
+	}
+	
+	// Local file: dependencies own everything beneath their directory
+	if m.config != nil {
+		for name, version := range m.config.Dependencies {
+			if !strings.HasPrefix(version, "file:") {
+				continue
+			}
+			dir, err := filepath.Abs(strings.TrimPrefix(version, "file:"))
+			if err != nil {
+				continue
+			}
+			dir = m.realPath(dir)
+			if resolved == dir || strings.HasPrefix(resolved, dir+string(filepath.Separator)) {
+				return name
+			}
+		}
+	}
+	
+	return ""
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	
 	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/install"
 	"github.com/rizqme/gode/pkg/config"
 )
 
@@ -270,7 +271,7 @@ func TestModuleManagerLoad_FileNotFoundError(t *testing.T) {
 	}
 	
 	// Check that we have a stack trace
-	if len(moduleErr.StackTrace.Frames) == 0 {
+	if len(moduleErr.Stack().Frames) == 0 {
 		t.Error("Expected stack trace frames, got empty")
 	}
 	
@@ -407,6 +408,30 @@ func TestModuleManagerResolve_ErrorContext(t *testing.T) {
 	}
 }
 
+func TestModuleManagerTryResolve(t *testing.T) {
+	manager := NewModuleManager()
+	
+	if resolved, ok := manager.TryResolve("definitely-unresolvable-module-12345", ""); ok {
+		t.Errorf("Expected an unresolvable module not to resolve, got %q", resolved)
+	}
+	
+	want, _ := filepath.Abs("lib.js")
+	if resolved, ok := manager.TryResolve("./lib.js", ""); !ok || resolved != want {
+		t.Errorf("TryResolve(./lib.js) = %q, %v; want %q, true", resolved, ok, want)
+	}
+	
+	// Misses make no ModuleError, and so capture no stack
+	allocs := testing.AllocsPerRun(100, func() {
+		manager.TryResolve("definitely-unresolvable-module-12345", "")
+	})
+	resolveAllocs := testing.AllocsPerRun(100, func() {
+		manager.Resolve("definitely-unresolvable-module-12345", "")
+	})
+	if allocs >= resolveAllocs {
+		t.Errorf("TryResolve allocated %v times for a miss, Resolve %v", allocs, resolveAllocs)
+	}
+}
+
 func TestModuleManagerLoad_SafeOperationWrapping(t *testing.T) {
 	manager := NewModuleManager()
 	
@@ -486,7 +511,7 @@ func TestModuleManagerExtractPackageName(t *testing.T) {
 	
 	// Check that stack trace contains properly extracted package names
 	found := false
-	for _, frame := range moduleErr.StackTrace.Frames {
+	for _, frame := range moduleErr.Stack().Frames {
 		if strings.Contains(frame.Package, "modules") || strings.Contains(frame.Package, "errors") {
 			found = true
 			break
@@ -595,4 +620,123 @@ func TestModuleManagerFileTypeHandling(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestPackageOf(t *testing.T) {
+	manager := NewModuleManager()
+	manager.Configure(&config.PackageJSON{
+		Dependencies: map[string]string{
+			"lodash": "npm:lodash@^4.17.21",
+		},
+	})
+
+	tests := map[string]string{
+		"lodash":                             "lodash",
+		"./node_modules/lodash/chunk.js":     "lodash",
+		"./node_modules/@scope/pkg/index.js": "@scope/pkg",
+		"./src/app.js":                       "",
+		"gode:core":                          "",
+	}
+
+	for specifier, want := range tests {
+		if got := manager.PackageOf(specifier); got != want {
+			t.Errorf("PackageOf(%q) = %q, want %q", specifier, got, want)
+		}
+	}
+}
+
+func TestModuleManagerModules(t *testing.T) {
+	manager := NewModuleManager()
+
+	testFile := filepath.Join(t.TempDir(), "listed.js")
+	if err := os.WriteFile(testFile, []byte("module.exports = 1;"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	for _, specifier := range []string{testFile, "gode:core", testFile} {
+		if _, err := manager.Load(specifier); err != nil {
+			t.Fatalf("Load(%q) failed: %v", specifier, err)
+		}
+	}
+
+	mods := manager.Modules()
+	if len(mods) != 2 {
+		t.Fatalf("Expected 2 modules, got %+v", mods)
+	}
+	if mods[0].Specifier != testFile || mods[0].Size != len("module.exports = 1;") {
+		t.Errorf("Unexpected file module entry %+v", mods[0])
+	}
+	if mods[1].Specifier != "gode:core" || mods[1].Path != "gode:core" || mods[1].Size != 0 {
+		t.Errorf("Unexpected built-in module entry %+v", mods[1])
+	}
+	if manager.Plugins() != nil {
+		t.Error("Expected no plugins without a runtime")
+	}
+}
+
+func TestModuleManagerSymlinks(t *testing.T) {
+	// A pnpm-style layout: node_modules entries link into a store
+	root := t.TempDir()
+	store := filepath.Join(root, "node_modules", ".pnpm", "lodash@4.17.21", "node_modules", "lodash")
+	if err := os.MkdirAll(store, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(store, "index.js"), []byte("module.exports = {};"), 0644)
+	link := filepath.Join(root, "node_modules", "lodash")
+	if err := os.Symlink(store, link); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	real, _ := filepath.EvalSymlinks(filepath.Join(store, "index.js"))
+
+	manager := NewModuleManager()
+	manager.Configure(&config.PackageJSON{})
+	resolved, err := manager.Resolve(filepath.Join(link, "index.js"), "")
+	if err != nil || resolved != real {
+		t.Errorf("Resolve() = %q, %v, want the store path %q", resolved, err, real)
+	}
+	if got := manager.PackageOf(filepath.Join(link, "index.js")); got != "lodash" {
+		t.Errorf("PackageOf() = %q, want lodash", got)
+	}
+
+	manager = NewModuleManager()
+	manager.Configure(&config.PackageJSON{Gode: config.GodeConfig{PreserveSymlinks: true}})
+	resolved, err = manager.Resolve(filepath.Join(link, "index.js"), "")
+	if err != nil || resolved != filepath.Join(link, "index.js") {
+		t.Errorf("Resolve() with preserve-symlinks = %q, %v", resolved, err)
+	}
+}
+
+func TestModuleManagerVerifiesLockfile(t *testing.T) {
+	root := t.TempDir()
+	pkg := filepath.Join(root, "node_modules", "left-pad")
+	os.MkdirAll(pkg, 0755)
+	os.WriteFile(filepath.Join(pkg, "index.js"), []byte("module.exports = 1;"), 0644)
+	os.MkdirAll(filepath.Join(root, "node_modules", "stray"), 0755)
+	os.WriteFile(filepath.Join(root, "node_modules", "stray", "index.js"), []byte(""), 0644)
+	contents, err := install.ContentsIntegrity(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := &install.Lockfile{LockfileVersion: 1, Packages: map[string]install.LockedPackage{
+		"node_modules/left-pad": {Version: "1.3.0", Contents: contents},
+	}}
+	if err := lock.Write(root); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(path string) error {
+		manager := NewModuleManager()
+		manager.Configure(&config.PackageJSON{ProjectRoot: root})
+		_, err := manager.Load(path)
+		return err
+	}
+	if err := load(filepath.Join(pkg, "index.js")); err != nil {
+		t.Fatalf("Load() of a locked package failed: %v", err)
+	}
+	if err := load(filepath.Join(root, "node_modules", "stray", "index.js")); err == nil || !strings.Contains(err.Error(), "not in gode.lock") {
+		t.Errorf("Load() of a package missing from gode.lock = %v", err)
+	}
+
+	os.WriteFile(filepath.Join(pkg, "index.js"), []byte("module.exports = 2;"), 0644)
+	if err := load(filepath.Join(pkg, "index.js")); err == nil || !strings.Contains(err.Error(), "run gode install") {
+		t.Errorf("Load() of a changed package = %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package modules
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// nameMapping is a compiled config.ModuleMapping
+type nameMapping struct {
+	pattern *regexp.Regexp
+	target  string
+}
+
+// SetModuleNameMapper substitutes the modules matching mappings, in order,
+// as jest's moduleNameMapper does. gode test sets it from
+// gode.test.moduleNameMapper; nil mappings turn it off.
+func (m *ModuleManager) SetModuleNameMapper(mappings config.ModuleNameMapper) error {
+	compiled := make([]nameMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		pattern, err := regexp.Compile(mapping.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid moduleNameMapper pattern %q: %w", mapping.Pattern, err)
+		}
+		compiled = append(compiled, nameMapping{pattern: pattern, target: mapping.Target})
+	}
+	m.nameMapper = compiled
+	return nil
+}
+
+// MapModuleName returns what specifier is mapped to by the first mapping it
+// matches. $1, $2, ... in the target are replaced with the groups of the
+// match and <rootDir> with the project root, and relative targets are taken
+// from the project root.
+func (m *ModuleManager) MapModuleName(specifier string) (string, bool) {
+	for _, mapping := range m.nameMapper {
+		match := mapping.pattern.FindStringSubmatchIndex(specifier)
+		if match == nil {
+			continue
+		}
+		target := string(mapping.pattern.ExpandString(nil, mapping.target, specifier, match))
+
+		root := ""
+		if m.config != nil {
+			root = m.config.ProjectRoot
+		}
+		target = strings.ReplaceAll(target, "<rootDir>", root)
+		if strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") {
+			target = filepath.Join(root, target)
+			if !filepath.IsAbs(target) {
+				if abs, err := filepath.Abs(target); err == nil {
+					target = abs
+				}
+			}
+		}
+		return target, true
+	}
+	return specifier, false
+}
@@ -0,0 +1,51 @@
+package modules
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestModuleNameMapper(t *testing.T) {
+	root := t.TempDir()
+	manager := NewModuleManager()
+	manager.Configure(&config.PackageJSON{ProjectRoot: root})
+	err := manager.SetModuleNameMapper(config.ModuleNameMapper{
+		{Pattern: "^gode:sql$", Target: "./test/fakes/sql.js"},
+		{Pattern: "^@app/(.*)$", Target: "./src/$1"},
+		{Pattern: `\.css$`, Target: "<rootDir>/test/fakes/style.js"},
+		{Pattern: "^@app/", Target: "gode:never"},
+		{Pattern: "^old-fs$", Target: "gode:fs"},
+	})
+	if err != nil {
+		t.Fatalf("SetModuleNameMapper() failed: %v", err)
+	}
+
+	tests := []struct {
+		specifier string
+		want      string
+		mapped    bool
+	}{
+		{"gode:sql", filepath.Join(root, "test/fakes/sql.js"), true},
+		{"@app/db/users.js", filepath.Join(root, "src/db/users.js"), true},
+		{"./theme.css", root + "/test/fakes/style.js", true},
+		{"old-fs", "gode:fs", true},
+		{"gode:fs", "gode:fs", false},
+		{"./lib/sql.js", "./lib/sql.js", false},
+	}
+	for _, tt := range tests {
+		got, mapped := manager.MapModuleName(tt.specifier)
+		if got != tt.want || mapped != tt.mapped {
+			t.Errorf("MapModuleName(%q) = %q, %v, want %q, %v", tt.specifier, got, mapped, tt.want, tt.mapped)
+		}
+	}
+
+	manager.SetModuleNameMapper(nil)
+	if _, mapped := manager.MapModuleName("gode:sql"); mapped {
+		t.Error("Expected no mapping once the mapper is cleared")
+	}
+	if err := manager.SetModuleNameMapper(config.ModuleNameMapper{{Pattern: "(", Target: "x"}}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
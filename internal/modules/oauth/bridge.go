@@ -0,0 +1,308 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Bridge provides JavaScript bindings for the gode:oauth module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new OAuth bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("createClient", b.createClient)
+	exports.Set("discover", b.discover)
+	exports.Set("generatePKCE", b.generatePKCE)
+	return exports
+}
+
+// createClient implements oauth.createClient(options)
+func (b *Bridge) createClient(call goja.FunctionCall) goja.Value {
+	obj, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(b.vm.NewTypeError("createClient requires an options object"))
+	}
+	return b.wrapClient(NewClient(b.configFrom(obj, Config{})))
+}
+
+// discover implements oauth.discover(issuer, options), resolving to a client
+// configured from the provider's OpenID configuration
+func (b *Bridge) discover(call goja.FunctionCall) goja.Value {
+	issuer := call.Argument(0).String()
+	b.checkNet(issuer)
+
+	options, _ := call.Argument(1).(*goja.Object)
+	return b.promise(func() (interface{}, error) {
+		return Discover(context.Background(), issuer)
+	}, func(result interface{}) goja.Value {
+		return b.wrapClient(NewClient(b.configFrom(options, result.(Config))))
+	})
+}
+
+// generatePKCE implements oauth.generatePKCE()
+func (b *Bridge) generatePKCE(call goja.FunctionCall) goja.Value {
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+
+	result := b.vm.NewObject()
+	result.Set("codeVerifier", verifier)
+	result.Set("codeChallenge", challenge)
+	return result
+}
+
+// wrapClient exposes a Client as a JS object
+func (b *Bridge) wrapClient(client *Client) goja.Value {
+	obj := b.vm.NewObject()
+	cfg := client.Config()
+
+	obj.Set("authorizationUrl", func(call goja.FunctionCall) goja.Value {
+		opts, _ := call.Argument(0).(*goja.Object)
+
+		state := b.stringOption(opts, "state")
+		if state == "" {
+			state = b.must(NewState())
+		}
+		nonce := b.stringOption(opts, "nonce")
+		if nonce == "" && containsScope(b.scopesOption(opts, cfg.Scopes), "openid") {
+			nonce = b.must(NewState())
+		}
+
+		verifier, challenge := "", ""
+		if pkce := b.option(opts, "pkce"); pkce == nil || pkce.ToBoolean() {
+			var err error
+			if verifier, challenge, err = GeneratePKCE(); err != nil {
+				panic(b.vm.NewGoError(err))
+			}
+		}
+
+		var extra map[string]string
+		if value := b.option(opts, "extra"); value != nil {
+			b.vm.ExportTo(value, &extra)
+		}
+
+		result := b.vm.NewObject()
+		result.Set("url", client.AuthorizationURL(state, challenge, nonce, b.scopesOption(opts, nil), extra))
+		result.Set("state", state)
+		result.Set("nonce", nonce)
+		result.Set("codeVerifier", verifier)
+		return result
+	})
+
+	obj.Set("exchangeCode", func(call goja.FunctionCall) goja.Value {
+		code := call.Argument(0).String()
+		verifier := ""
+		if v := call.Argument(1); !goja.IsUndefined(v) && !goja.IsNull(v) {
+			verifier = v.String()
+		}
+		b.checkNet(cfg.TokenURL)
+		return b.tokenPromise(func() (*Token, error) {
+			return client.ExchangeCode(context.Background(), code, verifier)
+		})
+	})
+
+	obj.Set("clientCredentials", func(call goja.FunctionCall) goja.Value {
+		opts, _ := call.Argument(0).(*goja.Object)
+		scopes := b.scopesOption(opts, nil)
+		b.checkNet(cfg.TokenURL)
+		return b.tokenPromise(func() (*Token, error) {
+			return client.ClientCredentials(context.Background(), scopes)
+		})
+	})
+
+	obj.Set("refresh", func(call goja.FunctionCall) goja.Value {
+		refreshToken := call.Argument(0).String()
+		b.checkNet(cfg.TokenURL)
+		return b.tokenPromise(func() (*Token, error) {
+			return client.Refresh(context.Background(), refreshToken)
+		})
+	})
+
+	obj.Set("getToken", func(call goja.FunctionCall) goja.Value {
+		b.checkNet(cfg.TokenURL)
+		return b.tokenPromise(func() (*Token, error) {
+			return client.Token(context.Background())
+		})
+	})
+
+	obj.Set("verifyIdToken", func(call goja.FunctionCall) goja.Value {
+		raw := call.Argument(0).String()
+		opts, _ := call.Argument(1).(*goja.Object)
+		nonce := b.stringOption(opts, "nonce")
+		b.checkNet(cfg.JWKSURL)
+		return b.promise(func() (interface{}, error) {
+			claims, err := client.VerifyIDToken(context.Background(), raw, nonce)
+			return map[string]interface{}(claims), err
+		}, func(result interface{}) goja.Value {
+			return b.vm.ToValue(result)
+		})
+	})
+
+	return obj
+}
+
+// tokenPromise runs a token request and resolves with a JS token object
+func (b *Bridge) tokenPromise(work func() (*Token, error)) goja.Value {
+	return b.promise(func() (interface{}, error) {
+		return work()
+	}, func(result interface{}) goja.Value {
+		token := result.(*Token)
+		obj := b.vm.NewObject()
+		obj.Set("accessToken", token.AccessToken)
+		obj.Set("tokenType", token.TokenType)
+		obj.Set("expiresIn", token.ExpiresIn)
+		obj.Set("refreshToken", token.RefreshToken)
+		obj.Set("idToken", token.IDToken)
+		obj.Set("scope", token.Scope)
+		if !token.Expiry.IsZero() {
+			obj.Set("expiresAt", token.Expiry.UnixMilli())
+		}
+		return obj
+	})
+}
+
+// promise runs work off the JS thread and settles the returned promise back
+// on it, converting the result with toJS
+func (b *Bridge) promise(work func() (interface{}, error), toJS func(result interface{}) goja.Value) goja.Value {
+	p, resolver := promise.New(b.vm, b.runtime)
+
+	go func() {
+		result, err := work()
+		resolver.SettleWith(func() (value interface{}, err2 error) {
+			if err != nil {
+				return nil, err
+			}
+			// toJS may throw (e.g. invalid options); reject instead of crashing the loop
+			defer func() {
+				if r := recover(); r != nil {
+					err2 = thrown(r)
+				}
+			}()
+			return toJS(result), nil
+		})
+	}()
+
+	return p
+}
+
+// thrownValue is a JS value thrown while converting a result, which the
+// promise is rejected with as it is
+type thrownValue struct {
+	value goja.Value
+}
+
+func (e thrownValue) Error() string       { return e.value.String() }
+func (e thrownValue) JSValue() goja.Value { return e.value }
+
+// thrown converts a recovered panic to the error to reject with
+func thrown(r interface{}) error {
+	switch v := r.(type) {
+	case *goja.Exception:
+		return v
+	case goja.Value:
+		return thrownValue{value: v}
+	}
+	return fmt.Errorf("%v", r)
+}
+
+// checkNet throws a PermissionDenied error if the runtime denies network
+// access to rawURL
+func (b *Bridge) checkNet(rawURL string) {
+	checker, ok := b.runtime.(permissions.NetEnforcer)
+	if !ok {
+		return
+	}
+
+	if err := checker.CheckNetURL(rawURL); err != nil {
+		panic(jserror.New(b.vm, err))
+	}
+}
+
+// configFrom reads client options from a JS object on top of base
+func (b *Bridge) configFrom(obj *goja.Object, base Config) Config {
+	cfg := base
+	set := func(name string, target *string) {
+		if value := b.stringOption(obj, name); value != "" {
+			*target = value
+		}
+	}
+
+	set("clientId", &cfg.ClientID)
+	set("clientSecret", &cfg.ClientSecret)
+	set("authorizationEndpoint", &cfg.AuthURL)
+	set("tokenEndpoint", &cfg.TokenURL)
+	set("redirectUri", &cfg.RedirectURI)
+	set("issuer", &cfg.Issuer)
+	set("jwksUri", &cfg.JWKSURL)
+	cfg.Scopes = b.scopesOption(obj, cfg.Scopes)
+
+	if cfg.ClientID == "" {
+		panic(b.vm.NewTypeError("clientId is required"))
+	}
+	return cfg
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	if obj == nil {
+		return nil
+	}
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
+
+func (b *Bridge) stringOption(obj *goja.Object, name string) string {
+	if value := b.option(obj, name); value != nil {
+		return value.String()
+	}
+	return ""
+}
+
+func (b *Bridge) scopesOption(obj *goja.Object, fallback []string) []string {
+	value := b.option(obj, "scopes")
+	if value == nil {
+		return fallback
+	}
+
+	var scopes []string
+	if err := b.vm.ExportTo(value, &scopes); err != nil {
+		panic(b.vm.NewTypeError("scopes must be an array of strings"))
+	}
+	return scopes
+}
+
+func (b *Bridge) must(value string, err error) string {
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return value
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,344 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rizqme/gode/internal/modules/jwt"
+	"github.com/rizqme/gode/internal/proxy"
+)
+
+// Config describes an OAuth2 / OIDC client
+type Config struct {
+	ClientID      string
+	ClientSecret  string // Empty for public clients using PKCE
+	AuthURL       string
+	TokenURL      string
+	RedirectURI   string
+	Scopes        []string
+	Issuer        string
+	JWKSURL       string
+	ClockSkew     time.Duration // Tolerance for exp/iat checks, defaults to one minute
+	RefreshBefore time.Duration // Refresh cached tokens this long before they expire
+}
+
+// Token is a token endpoint response
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	Expiry       time.Time `json:"-"`
+}
+
+// Expired reports whether the token expires within leeway
+func (t *Token) Expired(leeway time.Duration) bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return time.Now().Add(leeway).After(t.Expiry)
+}
+
+// Error is an error response from the token endpoint (RFC 6749 section 5.2)
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("oauth: %s (HTTP %d)", e.Code, e.Status)
+}
+
+// Client performs OAuth2 flows and caches tokens and signing keys
+type Client struct {
+	config Config
+	http   *http.Client
+	keys   *jwt.KeySet
+
+	mu     sync.Mutex
+	cached *Token // Client credentials token
+}
+
+// NewClient creates a client for cfg
+func NewClient(cfg Config) *Client {
+	if cfg.ClockSkew == 0 {
+		cfg.ClockSkew = time.Minute
+	}
+	if cfg.RefreshBefore == 0 {
+		cfg.RefreshBefore = 30 * time.Second
+	}
+
+	c := &Client{
+		config: cfg,
+		http:   proxy.Client(30 * time.Second),
+	}
+	if cfg.JWKSURL != "" {
+		c.keys = jwt.NewKeySet(cfg.JWKSURL)
+	}
+	return c
+}
+
+// Config returns the client configuration
+func (c *Client) Config() Config {
+	return c.config
+}
+
+// Discover loads an OpenID Provider configuration from
+// <issuer>/.well-known/openid-configuration
+func Discover(ctx context.Context, issuer string) (Config, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+
+	resp, err := proxy.Client(30 * time.Second).Do(req)
+	if err != nil {
+		return Config{}, fmt.Errorf("discovery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Config{}, fmt.Errorf("discovery failed: %s", resp.Status)
+	}
+
+	var doc struct {
+		Issuer   string `json:"issuer"`
+		AuthURL  string `json:"authorization_endpoint"`
+		TokenURL string `json:"token_endpoint"`
+		JWKSURL  string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return Config{}, fmt.Errorf("failed to parse discovery document: %w", err)
+	}
+
+	return Config{
+		Issuer:   doc.Issuer,
+		AuthURL:  doc.AuthURL,
+		TokenURL: doc.TokenURL,
+		JWKSURL:  doc.JWKSURL,
+	}, nil
+}
+
+// GeneratePKCE returns a code verifier and its S256 challenge (RFC 7636)
+func GeneratePKCE() (verifier, challenge string, err error) {
+	verifier, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// NewState returns a random value suitable for the state or nonce parameter
+func NewState() (string, error) {
+	return randomString(16)
+}
+
+// AuthorizationURL builds the URL to send the user to for the authorization
+// code flow. An empty challenge omits PKCE, an empty nonce omits the nonce.
+func (c *Client) AuthorizationURL(state, challenge, nonce string, scopes []string, extra map[string]string) string {
+	if scopes == nil {
+		scopes = c.config.Scopes
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.config.ClientID)
+	if c.config.RedirectURI != "" {
+		params.Set("redirect_uri", c.config.RedirectURI)
+	}
+	if len(scopes) > 0 {
+		params.Set("scope", strings.Join(scopes, " "))
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	if challenge != "" {
+		params.Set("code_challenge", challenge)
+		params.Set("code_challenge_method", "S256")
+	}
+	if nonce != "" {
+		params.Set("nonce", nonce)
+	}
+	for key, value := range extra {
+		params.Set(key, value)
+	}
+
+	sep := "?"
+	if strings.Contains(c.config.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.config.AuthURL + sep + params.Encode()
+}
+
+// ExchangeCode trades an authorization code (and PKCE verifier) for tokens
+func (c *Client) ExchangeCode(ctx context.Context, code, verifier string) (*Token, error) {
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("code", code)
+	if c.config.RedirectURI != "" {
+		params.Set("redirect_uri", c.config.RedirectURI)
+	}
+	if verifier != "" {
+		params.Set("code_verifier", verifier)
+	}
+	return c.tokenRequest(ctx, params)
+}
+
+// ClientCredentials requests a token for the client itself
+func (c *Client) ClientCredentials(ctx context.Context, scopes []string) (*Token, error) {
+	if scopes == nil {
+		scopes = c.config.Scopes
+	}
+
+	params := url.Values{}
+	params.Set("grant_type", "client_credentials")
+	if len(scopes) > 0 {
+		params.Set("scope", strings.Join(scopes, " "))
+	}
+	return c.tokenRequest(ctx, params)
+}
+
+// Refresh exchanges a refresh token for a new access token. Servers that do
+// not rotate refresh tokens omit it, so the old one is carried over.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	params := url.Values{}
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", refreshToken)
+
+	token, err := c.tokenRequest(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// Token returns a cached client credentials token, fetching a new one when
+// the cached token is about to expire
+func (c *Client) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && !c.cached.Expired(c.config.RefreshBefore) {
+		return c.cached, nil
+	}
+
+	token, err := c.ClientCredentials(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.cached = token
+	return token, nil
+}
+
+// VerifyIDToken verifies an OIDC ID token's signature against the issuer's
+// JWKS and checks iss, aud, exp, iat and (when given) nonce
+func (c *Client) VerifyIDToken(ctx context.Context, raw, nonce string) (jwt.Claims, error) {
+	if c.keys == nil {
+		return nil, fmt.Errorf("cannot verify ID token: no jwksUri configured")
+	}
+
+	claims, err := jwt.Verify(raw, func(token *jwt.Token) (interface{}, error) {
+		return c.keys.Key(ctx, token.Header.Kid)
+	}, jwt.ValidateOptions{
+		Algorithms: []string{"RS256", "ES256"},
+		Audience:   []string{c.config.ClientID},
+		Issuer:     c.config.Issuer,
+		Leeway:     c.config.ClockSkew,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("invalid ID token: missing exp claim")
+	}
+	if nonce != "" && claims["nonce"] != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce does not match")
+	}
+
+	return claims, nil
+}
+
+func (c *Client) tokenRequest(ctx context.Context, params url.Values) (*Token, error) {
+	if c.config.TokenURL == "" {
+		return nil, fmt.Errorf("no token endpoint configured")
+	}
+
+	// Public clients (PKCE without secret) send client_id in the body
+	if c.config.ClientSecret == "" {
+		params.Set("client_id", c.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		oauthErr := &Error{Status: resp.StatusCode}
+		if json.Unmarshal(body, oauthErr) != nil || oauthErr.Code == "" {
+			oauthErr.Code = "token_request_failed"
+		}
+		return nil, oauthErr
+	}
+
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+
+	return &token, nil
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
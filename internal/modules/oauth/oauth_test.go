@@ -0,0 +1,223 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuthorizationURLWithPKCE(t *testing.T) {
+	client := NewClient(Config{
+		ClientID:    "app",
+		AuthURL:     "https://auth.example.com/authorize",
+		RedirectURI: "http://localhost:3000/callback",
+		Scopes:      []string{"openid", "profile"},
+	})
+
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatalf("GeneratePKCE failed: %v", err)
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Error("Expected S256 challenge of the verifier")
+	}
+
+	raw := client.AuthorizationURL("xyz", challenge, "n-1", nil, map[string]string{"prompt": "login"})
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("Invalid URL %q: %v", raw, err)
+	}
+
+	q := u.Query()
+	expected := map[string]string{
+		"response_type":         "code",
+		"client_id":             "app",
+		"redirect_uri":          "http://localhost:3000/callback",
+		"scope":                 "openid profile",
+		"state":                 "xyz",
+		"code_challenge":        challenge,
+		"code_challenge_method": "S256",
+		"nonce":                 "n-1",
+		"prompt":                "login",
+	}
+	for key, want := range expected {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestExchangeCodeAndRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			if r.Form.Get("code") != "abc" || r.Form.Get("code_verifier") != "verifier" || r.Form.Get("client_id") != "public-app" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant","error_description":"bad code"}`)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"at-1","token_type":"Bearer","expires_in":3600,"refresh_token":"rt-1"}`)
+		case "refresh_token":
+			fmt.Fprint(w, `{"access_token":"at-2","token_type":"Bearer","expires_in":3600}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{ClientID: "public-app", TokenURL: server.URL})
+
+	token, err := client.ExchangeCode(context.Background(), "abc", "verifier")
+	if err != nil {
+		t.Fatalf("ExchangeCode failed: %v", err)
+	}
+	if token.AccessToken != "at-1" || token.RefreshToken != "rt-1" || token.Expiry.IsZero() {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	refreshed, err := client.Refresh(context.Background(), token.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if refreshed.AccessToken != "at-2" || refreshed.RefreshToken != "rt-1" {
+		t.Errorf("Expected new access token and carried-over refresh token, got %+v", refreshed)
+	}
+
+	_, err = client.ExchangeCode(context.Background(), "wrong", "verifier")
+	oauthErr, ok := err.(*Error)
+	if !ok || oauthErr.Code != "invalid_grant" || oauthErr.Status != http.StatusBadRequest {
+		t.Errorf("Expected invalid_grant error, got %v", err)
+	}
+}
+
+func TestClientCredentialsTokenIsCached(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if user, pass, ok := r.BasicAuth(); !ok || user != "svc" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"at-%d","token_type":"Bearer","expires_in":3600}`, atomic.LoadInt32(&requests))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{ClientID: "svc", ClientSecret: "secret", TokenURL: server.URL})
+
+	first, err := client.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	second, err := client.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+
+	if first.AccessToken != second.AccessToken || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Expected cached token, got %q and %q after %d requests", first.AccessToken, second.AccessToken, requests)
+	}
+
+	// A token inside the refresh window is replaced
+	client.cached.Expiry = time.Now().Add(10 * time.Second)
+	third, err := client.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if third.AccessToken == first.AccessToken {
+		t.Error("Expected a fresh token close to expiry")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "EC",
+				"kid": "k1",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+			}},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{ClientID: "app", Issuer: "https://issuer.example.com", JWKSURL: server.URL})
+
+	claims := map[string]interface{}{
+		"iss":   "https://issuer.example.com",
+		"aud":   "app",
+		"sub":   "user-1",
+		"nonce": "n-1",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+
+	verified, err := client.VerifyIDToken(context.Background(), signES256(t, key, "k1", claims), "n-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken failed: %v", err)
+	}
+	if verified["sub"] != "user-1" {
+		t.Errorf("Expected sub user-1, got %v", verified["sub"])
+	}
+
+	if _, err := client.VerifyIDToken(context.Background(), signES256(t, key, "k1", claims), "other"); err == nil {
+		t.Error("Expected nonce mismatch to fail")
+	}
+
+	claims["aud"] = "someone-else"
+	if _, err := client.VerifyIDToken(context.Background(), signES256(t, key, "k1", claims), ""); err == nil {
+		t.Error("Expected audience mismatch to fail")
+	}
+
+	claims["aud"] = "app"
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	if _, err := client.VerifyIDToken(context.Background(), signES256(t, key, "k1", claims), ""); err == nil {
+		t.Error("Expected expired token to fail")
+	}
+
+	// Tampered payload
+	token := signES256(t, key, "k1", map[string]interface{}{"iss": "https://issuer.example.com", "aud": "app", "exp": time.Now().Add(time.Hour).Unix()})
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://issuer.example.com","aud":"app","sub":"admin","exp":9999999999}`))
+	if _, err := client.VerifyIDToken(context.Background(), strings.Join(parts, "."), ""); err == nil {
+		t.Error("Expected tampered token to fail")
+	}
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
@@ -0,0 +1,86 @@
+package osinfo
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// Bridge provides JavaScript bindings for the gode:os module
+type Bridge struct {
+	vm *goja.Runtime
+}
+
+// NewBridge creates a new os bridge
+func NewBridge(vm *goja.Runtime) *Bridge {
+	return &Bridge{vm: vm}
+}
+
+// Exports builds the module object. Like Node's os module, everything is a
+// function so that values such as freemem are read when called.
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("hostname", Hostname)
+	exports.Set("platform", Platform)
+	exports.Set("arch", Arch)
+	exports.Set("homedir", HomeDir)
+	exports.Set("tmpdir", TmpDir)
+	exports.Set("totalmem", TotalMem)
+	exports.Set("freemem", FreeMem)
+	exports.Set("uptime", Uptime)
+	exports.Set("cpus", b.cpus)
+	exports.Set("networkInterfaces", b.networkInterfaces)
+	exports.Set("EOL", eol())
+	return exports
+}
+
+// cpus implements os.cpus() as [{model, speed, times: {user, nice, sys, idle, irq}}]
+func (b *Bridge) cpus() []interface{} {
+	cpus := CPUs()
+	result := make([]interface{}, len(cpus))
+	for i, cpu := range cpus {
+		result[i] = map[string]interface{}{
+			"model": cpu.Model,
+			"speed": cpu.Speed,
+			"times": map[string]interface{}{
+				"user": cpu.Times.User,
+				"nice": cpu.Times.Nice,
+				"sys":  cpu.Times.Sys,
+				"idle": cpu.Times.Idle,
+				"irq":  cpu.Times.IRQ,
+			},
+		}
+	}
+	return result
+}
+
+// networkInterfaces implements os.networkInterfaces() as
+// {name: [{address, netmask, family, mac, internal, cidr}]}
+func (b *Bridge) networkInterfaces() map[string]interface{} {
+	ifaces, err := NetworkInterfaces()
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+
+	result := make(map[string]interface{}, len(ifaces))
+	for name, addrs := range ifaces {
+		list := make([]interface{}, len(addrs))
+		for i, addr := range addrs {
+			list[i] = map[string]interface{}{
+				"address":  addr.Address,
+				"netmask":  addr.Netmask,
+				"family":   addr.Family,
+				"mac":      addr.MAC,
+				"internal": addr.Internal,
+				"cidr":     addr.CIDR,
+			}
+		}
+		result[name] = list
+	}
+	return result
+}
+
+func eol() string {
+	if Platform() == "win32" {
+		return "\r\n"
+	}
+	return "\n"
+}
@@ -0,0 +1,37 @@
+package osinfo_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestOSModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("os", `
+		const os = require('gode:os');
+		const ifaces = os.networkInterfaces();
+		const addrs = Object.keys(ifaces).reduce((all, name) => all.concat(ifaces[name]), []);
+		[
+			os.platform() === process.platform || os.platform() === 'win32',
+			typeof os.hostname() === 'string',
+			os.cpus().length > 0 && typeof os.cpus()[0].times.idle === 'number',
+			os.totalmem() >= os.freemem(),
+			os.tmpdir().length > 0,
+			typeof os.uptime() === 'number',
+			addrs.every(a => a.family === 'IPv4' || a.family === 'IPv6'),
+			os.EOL.length > 0,
+		].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if value != "true,true,true,true,true,true,true,true" {
+		t.Errorf("gode:os checks = %v", value)
+	}
+}
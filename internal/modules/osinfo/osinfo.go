@@ -0,0 +1,285 @@
+// Package osinfo implements gode:os, which describes the host the runtime
+// runs on. Memory, uptime and CPU details come from /proc and are zero or
+// empty on systems without it.
+package osinfo
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// procRoot is where /proc is read from, replaced in tests
+var procRoot = "/proc"
+
+// CPU describes one logical CPU. Times are in milliseconds, like Node's
+// os.cpus().
+type CPU struct {
+	Model string
+	Speed int // MHz
+	Times CPUTimes
+}
+
+// CPUTimes is the time a CPU spent in each mode since boot
+type CPUTimes struct {
+	User, Nice, Sys, Idle, IRQ int64
+}
+
+// Interface is one address of a network interface
+type Interface struct {
+	Address  string
+	Netmask  string
+	Family   string // "IPv4" or "IPv6"
+	MAC      string
+	Internal bool
+	CIDR     string
+}
+
+// Platform returns the platform name as Node reports it
+func Platform() string {
+	if runtime.GOOS == "windows" {
+		return "win32"
+	}
+	return runtime.GOOS
+}
+
+// Arch returns the CPU architecture as Node reports it
+func Arch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "ia32"
+	}
+	return runtime.GOARCH
+}
+
+// Hostname returns the host name, or "" if it can't be read
+func Hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// HomeDir returns the current user's home directory
+func HomeDir() string {
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// TmpDir returns the directory for temporary files, without a trailing
+// separator
+func TmpDir() string {
+	dir := os.TempDir()
+	if len(dir) > 1 {
+		dir = strings.TrimRight(dir, `/\`)
+	}
+	return dir
+}
+
+// TotalMem returns the total system memory in bytes
+func TotalMem() uint64 {
+	total, _ := memInfo()
+	return total
+}
+
+// FreeMem returns the memory available to new processes in bytes
+func FreeMem() uint64 {
+	_, free := memInfo()
+	return free
+}
+
+func memInfo() (total, free uint64) {
+	f, err := os.Open(procRoot + "/meminfo")
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	return parseMemInfo(f)
+}
+
+// parseMemInfo reads MemTotal and MemAvailable, falling back to MemFree on
+// kernels without MemAvailable
+func parseMemInfo(r io.Reader) (total, free uint64) {
+	var available, memFree uint64
+	hasAvailable := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			total = kb * 1024
+		case "MemFree:":
+			memFree = kb * 1024
+		case "MemAvailable:":
+			available = kb * 1024
+			hasAvailable = true
+		}
+	}
+
+	if hasAvailable {
+		return total, available
+	}
+	return total, memFree
+}
+
+// Uptime returns the system uptime in seconds
+func Uptime() float64 {
+	data, err := os.ReadFile(procRoot + "/uptime")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	seconds, _ := strconv.ParseFloat(fields[0], 64)
+	return seconds
+}
+
+// CPUs returns one entry per logical CPU. Without /proc only the count is
+// known, so the entries are empty.
+func CPUs() []CPU {
+	var cpus []CPU
+	if f, err := os.Open(procRoot + "/cpuinfo"); err == nil {
+		cpus = parseCPUInfo(f)
+		f.Close()
+	}
+	if len(cpus) == 0 {
+		cpus = make([]CPU, runtime.NumCPU())
+	}
+
+	if f, err := os.Open(procRoot + "/stat"); err == nil {
+		times := parseStat(f)
+		f.Close()
+		for i := range cpus {
+			if i < len(times) {
+				cpus[i].Times = times[i]
+			}
+		}
+	}
+	return cpus
+}
+
+// parseCPUInfo reads the model name and speed of each processor
+func parseCPUInfo(r io.Reader) []CPU {
+	var cpus []CPU
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "processor":
+			cpus = append(cpus, CPU{})
+		case "model name":
+			if len(cpus) > 0 {
+				cpus[len(cpus)-1].Model = value
+			}
+		case "cpu MHz":
+			if len(cpus) > 0 {
+				mhz, _ := strconv.ParseFloat(value, 64)
+				cpus[len(cpus)-1].Speed = int(mhz)
+			}
+		}
+	}
+	return cpus
+}
+
+// parseStat reads the per-CPU lines of /proc/stat, which count clock
+// ticks of 1/100 s
+func parseStat(r io.Reader) []CPUTimes {
+	const msPerTick = 10
+
+	var times []CPUTimes
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		ticks := make([]int64, 7)
+		for i := range ticks {
+			ticks[i], _ = strconv.ParseInt(fields[i+1], 10, 64)
+		}
+		// user nice system idle iowait irq softirq
+		times = append(times, CPUTimes{
+			User: ticks[0] * msPerTick,
+			Nice: ticks[1] * msPerTick,
+			Sys:  ticks[2] * msPerTick,
+			Idle: ticks[3] * msPerTick,
+			IRQ:  ticks[5] * msPerTick,
+		})
+	}
+	return times
+}
+
+// NetworkInterfaces returns the addresses of the interfaces that are up,
+// keyed by interface name
+func NetworkInterfaces() (map[string][]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]Interface)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		mac := iface.HardwareAddr.String()
+		if mac == "" {
+			mac = "00:00:00:00:00:00"
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			result[iface.Name] = append(result[iface.Name], describeAddress(ipNet, mac, iface.Flags&net.FlagLoopback != 0))
+		}
+	}
+	return result, nil
+}
+
+func describeAddress(ipNet *net.IPNet, mac string, loopback bool) Interface {
+	family := "IPv6"
+	ip := ipNet.IP
+	mask := net.IP(ipNet.Mask)
+	if ip4 := ip.To4(); ip4 != nil {
+		family = "IPv4"
+		ip = ip4
+		if len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	return Interface{
+		Address:  ip.String(),
+		Netmask:  mask.String(),
+		Family:   family,
+		MAC:      mac,
+		Internal: loopback,
+		CIDR:     ip.String() + "/" + strconv.Itoa(ones),
+	}
+}
@@ -0,0 +1,93 @@
+package osinfo
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMemInfo(t *testing.T) {
+	total, free := parseMemInfo(strings.NewReader(`MemTotal:       16318412 kB
+MemFree:          512000 kB
+MemAvailable:    8159206 kB
+Buffers:          300000 kB
+`))
+	if total != 16318412*1024 || free != 8159206*1024 {
+		t.Errorf("parseMemInfo() = %d, %d", total, free)
+	}
+
+	// Old kernels have no MemAvailable
+	_, free = parseMemInfo(strings.NewReader("MemTotal: 1000 kB\nMemFree: 250 kB\n"))
+	if free != 250*1024 {
+		t.Errorf("parseMemInfo() free = %d, want MemFree", free)
+	}
+}
+
+func TestCPUs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cpuinfo": `processor	: 0
+model name	: Example CPU @ 3.00GHz
+cpu MHz		: 2999.998
+
+processor	: 1
+model name	: Example CPU @ 3.00GHz
+cpu MHz		: 1200.000
+`,
+		"stat": `cpu  300 0 200 1000 5 6 7 0 0 0
+cpu0 100 1 50 500 2 3 4 0 0 0
+cpu1 200 0 150 500 3 3 3 0 0 0
+intr 12345
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(root string) { procRoot = root }(procRoot)
+	procRoot = dir
+
+	cpus := CPUs()
+	if len(cpus) != 2 {
+		t.Fatalf("CPUs() returned %d entries, want 2", len(cpus))
+	}
+	want := CPU{Model: "Example CPU @ 3.00GHz", Speed: 2999, Times: CPUTimes{User: 1000, Nice: 10, Sys: 500, Idle: 5000, IRQ: 30}}
+	if cpus[0] != want {
+		t.Errorf("CPUs()[0] = %+v, want %+v", cpus[0], want)
+	}
+	if cpus[1].Speed != 1200 || cpus[1].Times.User != 2000 {
+		t.Errorf("CPUs()[1] = %+v", cpus[1])
+	}
+}
+
+func TestCPUsWithoutProc(t *testing.T) {
+	defer func(root string) { procRoot = root }(procRoot)
+	procRoot = t.TempDir()
+
+	if len(CPUs()) == 0 {
+		t.Error("Expected one entry per CPU without /proc")
+	}
+	if TotalMem() != 0 || Uptime() != 0 {
+		t.Error("Expected zero memory and uptime without /proc")
+	}
+}
+
+func TestDescribeAddress(t *testing.T) {
+	_, v4, _ := net.ParseCIDR("192.168.1.20/24")
+	v4.IP = net.ParseIP("192.168.1.20")
+	got := describeAddress(v4, "aa:bb:cc:dd:ee:ff", false)
+	want := Interface{Address: "192.168.1.20", Netmask: "255.255.255.0", Family: "IPv4", MAC: "aa:bb:cc:dd:ee:ff", CIDR: "192.168.1.20/24"}
+	if got != want {
+		t.Errorf("describeAddress(v4) = %+v, want %+v", got, want)
+	}
+
+	_, v6, _ := net.ParseCIDR("::1/128")
+	got = describeAddress(v6, "00:00:00:00:00:00", true)
+	if got.Family != "IPv6" || got.Address != "::1" || got.CIDR != "::1/128" || !got.Internal {
+		t.Errorf("describeAddress(v6) = %+v", got)
+	}
+}
@@ -0,0 +1,23 @@
+package osinfo
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// RegisterOSModule registers gode:os in the JavaScript runtime
+func RegisterOSModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime.GetGojaRuntime())
+		runtime.RegisterModule("gode:os", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
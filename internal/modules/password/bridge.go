@@ -0,0 +1,173 @@
+package password
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Bridge provides JavaScript bindings for the gode:password module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new password bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("hash", b.hash)
+	exports.Set("verify", b.verify)
+	exports.Set("needsRehash", b.needsRehash)
+	exports.Set("verifyAndRehash", b.verifyAndRehash)
+	return exports
+}
+
+// hash implements password.hash(password, options) returning a Promise of the
+// encoded hash. Hashing runs on its own goroutine so it never blocks the
+// event loop.
+func (b *Bridge) hash(call goja.FunctionCall) goja.Value {
+	plain, ok := b.text(call.Argument(0))
+	if !ok {
+		return b.typeError("password must be a string")
+	}
+	opts := b.options(call.Argument(1))
+
+	return b.async(func() (interface{}, error) {
+		return Hash(plain, opts)
+	})
+}
+
+// verify implements password.verify(password, hash) returning a Promise of a boolean
+func (b *Bridge) verify(call goja.FunctionCall) goja.Value {
+	plain, encoded, err := b.credentials(call)
+	if err != nil {
+		return b.typeError(err.Error())
+	}
+
+	return b.async(func() (interface{}, error) {
+		return Verify(plain, encoded)
+	})
+}
+
+// needsRehash implements password.needsRehash(hash, options). It only parses
+// the hash, so it is synchronous.
+func (b *Bridge) needsRehash(call goja.FunctionCall) goja.Value {
+	return b.vm.ToValue(NeedsRehash(call.Argument(0).String(), b.options(call.Argument(1))))
+}
+
+// verifyAndRehash implements password.verifyAndRehash(password, hash, options)
+// for migrating stored hashes: it resolves to {valid, hash} where hash is a
+// replacement produced with options if the password matched and the stored
+// hash is outdated, or null otherwise
+func (b *Bridge) verifyAndRehash(call goja.FunctionCall) goja.Value {
+	plain, encoded, err := b.credentials(call)
+	if err != nil {
+		return b.typeError(err.Error())
+	}
+	opts := b.options(call.Argument(2))
+
+	return b.async(func() (interface{}, error) {
+		result := map[string]interface{}{"valid": false, "hash": nil}
+
+		valid, err := Verify(plain, encoded)
+		if err != nil || !valid {
+			return result, err
+		}
+		result["valid"] = true
+
+		if NeedsRehash(encoded, opts) {
+			rehashed, err := Hash(plain, opts)
+			if err != nil {
+				return nil, err
+			}
+			result["hash"] = rehashed
+		}
+		return result, nil
+	})
+}
+
+// async runs fn off the JS thread and settles the returned Promise back on it
+func (b *Bridge) async(fn func() (interface{}, error)) goja.Value {
+	return promise.Run(b.vm, b.runtime, fn)
+}
+
+// typeError returns a Promise rejected with a TypeError, for arguments that
+// cannot be hashed
+func (b *Bridge) typeError(message string) goja.Value {
+	p, resolver := promise.New(b.vm, b.runtime)
+	resolver.Reject(b.vm.NewTypeError(message))
+	return p
+}
+
+// text reads a string argument. Other values, undefined included, are not
+// converted, so that hash(undefined) cannot hash the string "undefined".
+func (b *Bridge) text(value goja.Value) (string, bool) {
+	s, ok := value.Export().(string)
+	return s, ok
+}
+
+// credentials reads the (password, hash) arguments of verify and
+// verifyAndRehash
+func (b *Bridge) credentials(call goja.FunctionCall) (string, string, error) {
+	plain, ok := b.text(call.Argument(0))
+	if !ok {
+		return "", "", fmt.Errorf("password must be a string")
+	}
+	encoded, ok := b.text(call.Argument(1))
+	if !ok {
+		return "", "", fmt.Errorf("hash must be a string")
+	}
+	return plain, encoded, nil
+}
+
+// options reads {algorithm, memoryCost, timeCost, parallelism, cost} on top
+// of DefaultOptions. memoryCost is in KiB as in the argon2 npm package.
+func (b *Bridge) options(value goja.Value) Options {
+	opts := DefaultOptions()
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts
+	}
+
+	if v := b.option(obj, "algorithm"); v != nil {
+		opts.Algorithm = v.String()
+	}
+	if v := b.option(obj, "memoryCost"); v != nil {
+		opts.Argon2.Memory = uint32(v.ToInteger())
+	}
+	if v := b.option(obj, "timeCost"); v != nil {
+		opts.Argon2.Iterations = uint32(v.ToInteger())
+	}
+	if v := b.option(obj, "parallelism"); v != nil {
+		opts.Argon2.Parallelism = uint8(v.ToInteger())
+	}
+	if v := b.option(obj, "cost"); v != nil {
+		opts.BcryptCost = int(v.ToInteger())
+	}
+
+	if opts.Algorithm != Argon2id && opts.Algorithm != Bcrypt {
+		panic(b.vm.NewTypeError("algorithm must be \"argon2id\" or \"bcrypt\""))
+	}
+	if opts.Argon2.Memory == 0 || opts.Argon2.Iterations == 0 || opts.Argon2.Parallelism == 0 {
+		panic(b.vm.NewTypeError("memoryCost, timeCost and parallelism must be positive"))
+	}
+	return opts
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,118 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// fastOptions keeps the tests quick; the defaults are deliberately slow
+func fastOptions(algorithm string) Options {
+	opts := DefaultOptions()
+	opts.Algorithm = algorithm
+	opts.Argon2.Memory = 1024
+	opts.Argon2.Iterations = 1
+	opts.Argon2.Parallelism = 1
+	opts.BcryptCost = 4
+	return opts
+}
+
+func TestHashVerify(t *testing.T) {
+	for _, alg := range []string{Argon2id, Bcrypt} {
+		hash, err := Hash("correct horse", fastOptions(alg))
+		if err != nil {
+			t.Fatalf("%s: Hash failed: %v", alg, err)
+		}
+
+		ok, err := Verify("correct horse", hash)
+		if err != nil || !ok {
+			t.Errorf("%s: expected password to verify, got %v %v", alg, ok, err)
+		}
+		ok, err = Verify("battery staple", hash)
+		if err != nil || ok {
+			t.Errorf("%s: expected wrong password to fail, got %v %v", alg, ok, err)
+		}
+	}
+}
+
+func TestArgon2Format(t *testing.T) {
+	hash, err := Hash("secret", fastOptions(Argon2id))
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Unexpected hash format: %s", hash)
+	}
+
+	// Reference hash for "password" produced by the argon2 CLI
+	ref := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	if ok, err := Verify("password", ref); err != nil || !ok {
+		t.Errorf("Expected reference hash to verify, got %v %v", ok, err)
+	}
+
+	if _, err := Verify("password", "not-a-hash"); err == nil {
+		t.Error("Expected malformed hash to return an error")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := fastOptions(Argon2id)
+	hash, _ := Hash("secret", weak)
+
+	if NeedsRehash(hash, weak) {
+		t.Error("Hash produced with the same options should not need a rehash")
+	}
+
+	stronger := weak
+	stronger.Argon2.Iterations = 2
+	if !NeedsRehash(hash, stronger) {
+		t.Error("Expected rehash when iterations increase")
+	}
+	if !NeedsRehash(hash, fastOptions(Bcrypt)) {
+		t.Error("Expected rehash when switching algorithm")
+	}
+
+	bhash, _ := Hash("secret", fastOptions(Bcrypt))
+	if !NeedsRehash(bhash, fastOptions(Argon2id)) {
+		t.Error("Expected bcrypt hash to need a rehash for argon2id")
+	}
+	costlier := fastOptions(Bcrypt)
+	costlier.BcryptCost = 5
+	if !NeedsRehash(bhash, costlier) {
+		t.Error("Expected rehash when bcrypt cost increases")
+	}
+}
+
+// inlineRuntime runs queued operations at once, which is enough for
+// promises settled on the JS thread
+type inlineRuntime struct{ vm *goja.Runtime }
+
+func (r *inlineRuntime) QueueJSOperation(fn func())                      { fn() }
+func (r *inlineRuntime) GetGojaRuntime() *goja.Runtime                   { return r.vm }
+func (r *inlineRuntime) RegisterModule(name string, exports interface{}) {}
+
+func TestBridgeRejectsNonStrings(t *testing.T) {
+	vm := goja.New()
+	vm.Set("password", NewBridge(&inlineRuntime{vm: vm}).Exports())
+
+	for _, script := range []string{
+		"password.hash()",
+		"password.hash(42)",
+		"password.verify('secret')",
+		"password.verifyAndRehash(undefined, '$2a$04$x')",
+	} {
+		value, err := vm.RunString(script)
+		if err != nil {
+			t.Fatalf("%s threw: %v", script, err)
+		}
+		p, ok := value.Export().(*goja.Promise)
+		if !ok || p.State() != goja.PromiseStateRejected {
+			t.Errorf("%s = %v, want a rejected promise", script, value)
+			continue
+		}
+		if name := p.Result().ToObject(vm).Get("name").String(); name != "TypeError" {
+			t.Errorf("%s rejected with %s, want a TypeError", script, name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// windows selects Windows path rules: backslash separators, drive letters
+// and UNC paths. Tests set it to check them on any platform.
+var windows = runtime.GOOS == "windows"
+
+// pluginExts are the native plugin extensions gode recognises, whichever
+// platform the plugin was built for
+var pluginExts = []string{".so", ".dll"}
+
+// PluginExt returns the native plugin extension of the current platform
+func PluginExt() string {
+	if windows {
+		return ".dll"
+	}
+	return ".so"
+}
+
+// IsPlugin reports whether path names a native plugin
+func IsPlugin(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, pluginExt := range pluginExts {
+		if ext == pluginExt {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginPath maps a plugin path written for another platform, such as
+// ./math.so on Windows, to the build for this one when it exists
+func pluginPath(path string) string {
+	ext := filepath.Ext(path)
+	if strings.EqualFold(ext, PluginExt()) {
+		return path
+	}
+	native := strings.TrimSuffix(path, ext) + PluginExt()
+	if _, err := os.Stat(native); err == nil {
+		return native
+	}
+	return path
+}
+
+// IsRelative reports whether specifier starts with ./ or ../, or on
+// Windows .\ or ..\
+func IsRelative(specifier string) bool {
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+		return true
+	}
+	return windows && (strings.HasPrefix(specifier, `.\`) || strings.HasPrefix(specifier, `..\`))
+}
+
+// isAbsolute reports whether specifier is an absolute path. On Windows
+// that includes drive letter paths, C:\dir or C:/dir, and UNC paths,
+// \\server\share or //server/share. Paths rooted without a drive, such as
+// \dir, are taken as absolute on the current drive.
+func isAbsolute(specifier string) bool {
+	if !windows {
+		return strings.HasPrefix(specifier, "/")
+	}
+	if len(specifier) >= 3 && isDriveLetter(specifier[0]) && specifier[1] == ':' && isSlash(specifier[2]) {
+		return true
+	}
+	return len(specifier) > 0 && isSlash(specifier[0])
+}
+
+func isDriveLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isSlash(c byte) bool {
+	return c == '/' || c == '\\'
+}
@@ -0,0 +1,86 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWindowsPaths(t *testing.T) {
+	defer func(w bool) { windows = w }(windows)
+	windows = true
+
+	manager := NewModuleManager()
+	tests := []struct {
+		specifier string
+		relative  bool
+		file      bool
+	}{
+		{`.\lib\utils.js`, true, true},
+		{`..\shared\config`, true, true},
+		{`./lib/utils`, true, true},
+		{`C:\project\main.js`, false, true},
+		{`c:/project/plugins/math`, false, true},
+		{`\\fileserver\share\lib\index`, false, true},
+		{`//fileserver/share/lib/index`, false, true},
+		{`\project\main`, false, true},
+		{`C:relative`, false, false},
+		{`lodash`, false, false},
+		{`https://example.com/mod.js`, false, false},
+	}
+	for _, tt := range tests {
+		if got := IsRelative(tt.specifier); got != tt.relative {
+			t.Errorf("IsRelative(%q) = %v, want %v", tt.specifier, got, tt.relative)
+		}
+		if got := manager.isFilePath(tt.specifier); got != tt.file {
+			t.Errorf("isFilePath(%q) = %v, want %v", tt.specifier, got, tt.file)
+		}
+	}
+
+	if PluginExt() != ".dll" {
+		t.Errorf("PluginExt() = %q, want .dll", PluginExt())
+	}
+}
+
+func TestUnixPaths(t *testing.T) {
+	defer func(w bool) { windows = w }(windows)
+	windows = false
+
+	if IsRelative(`.\lib\utils.js`) {
+		t.Error("Expected backslashes not to be separators outside Windows")
+	}
+	if isAbsolute(`C:\project\main.js`) {
+		t.Error("Expected drive letters not to be absolute outside Windows")
+	}
+	if PluginExt() != ".so" {
+		t.Errorf("PluginExt() = %q, want .so", PluginExt())
+	}
+}
+
+func TestPluginPath(t *testing.T) {
+	defer func(w bool) { windows = w }(windows)
+	windows = true
+
+	for path, want := range map[string]bool{"a.so": true, "b.DLL": true, "c.js": false, "so": false} {
+		if IsPlugin(path) != want {
+			t.Errorf("IsPlugin(%q) = %v, want %v", path, !want, want)
+		}
+	}
+
+	dir := t.TempDir()
+	so := filepath.Join(dir, "math.so")
+	if got := pluginPath(so); got != so {
+		t.Errorf("pluginPath() = %q, want %q without a Windows build", got, so)
+	}
+
+	dll := filepath.Join(dir, "math.dll")
+	if err := os.WriteFile(dll, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := pluginPath(so); got != dll {
+		t.Errorf("pluginPath() = %q, want %q", got, dll)
+	}
+	if got := pluginPath(dll); got != dll {
+		t.Errorf("pluginPath() = %q, want %q", got, dll)
+	}
+}
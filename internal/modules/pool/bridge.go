@@ -0,0 +1,267 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// Bridge provides JavaScript bindings for the gode:pool module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// jsPool tracks which Resource each borrowed JS value belongs to. borrowed
+// is only touched on the JS thread.
+type jsPool struct {
+	pool     *Pool
+	borrowed []*Resource
+}
+
+// rejection carries a JS rejection reason through the Go pool
+type rejection struct {
+	value goja.Value
+	msg   string
+}
+
+func (r *rejection) Error() string {
+	return r.msg
+}
+
+// NewBridge creates a new pool bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("createPool", b.createPool)
+	return exports
+}
+
+// createPool implements pool.createPool({create, destroy, validate, min, max,
+// acquireTimeout, order}). Hooks may be sync or return promises; they always
+// run on the JS thread while the pool itself waits off it.
+func (b *Bridge) createPool(call goja.FunctionCall) goja.Value {
+	opts, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(b.vm.NewTypeError("createPool expects an options object"))
+	}
+
+	create, ok := goja.AssertFunction(opts.Get("create"))
+	if !ok {
+		panic(b.vm.NewTypeError("create must be a function"))
+	}
+	hooks := Hooks{}
+	hooks.Create = func(ctx context.Context) (interface{}, error) {
+		value, err := b.await(ctx, create, func(late goja.Value) {
+			// Acquire gave up while create was still running
+			if hooks.Destroy != nil {
+				hooks.Destroy(late)
+			}
+		})
+		return value, err
+	}
+	if destroy, ok := goja.AssertFunction(opts.Get("destroy")); ok {
+		hooks.Destroy = func(value interface{}) {
+			// Fire and forget: Destroy may be reached from the JS thread itself
+			b.runtime.QueueJSOperation(func() {
+				destroy(goja.Undefined(), value.(goja.Value))
+			})
+		}
+	}
+	if validate, ok := goja.AssertFunction(opts.Get("validate")); ok {
+		hooks.Validate = func(ctx context.Context, value interface{}) bool {
+			result, err := b.await(ctx, validate, nil, value.(goja.Value))
+			return err == nil && result.ToBoolean()
+		}
+	}
+
+	options := Options{}
+	if v := b.option(opts, "min"); v != nil {
+		options.Min = int(v.ToInteger())
+	}
+	if v := b.option(opts, "max"); v != nil {
+		options.Max = int(v.ToInteger())
+	}
+	if v := b.option(opts, "acquireTimeout"); v != nil {
+		options.AcquireTimeout = time.Duration(v.ToInteger()) * time.Millisecond
+	}
+	if v := b.option(opts, "order"); v != nil {
+		switch v.String() {
+		case "fifo":
+		case "lifo":
+			options.LIFO = true
+		default:
+			panic(b.vm.NewTypeError("order must be \"fifo\" or \"lifo\""))
+		}
+	}
+
+	p := &jsPool{pool: New(hooks, options)}
+
+	obj := b.vm.NewObject()
+	obj.Set("acquire", func() goja.Value {
+		return b.acquire(p, nil)
+	})
+	obj.Set("release", func(value goja.Value) {
+		p.pool.Release(b.take(p, value))
+	})
+	obj.Set("destroy", func(value goja.Value) {
+		p.pool.Destroy(b.take(p, value))
+	})
+	obj.Set("use", func(call goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(b.vm.NewTypeError("use expects a function"))
+		}
+		return b.acquire(p, fn)
+	})
+	obj.Set("drain", func() goja.Value {
+		return promise.Run(b.vm, b.runtime, func() (interface{}, error) {
+			p.pool.Drain(context.Background())
+			return nil, nil
+		})
+	})
+	obj.Set("stats", func() map[string]interface{} {
+		stats := p.pool.Stats()
+		return map[string]interface{}{
+			"size":     stats.Size,
+			"idle":     stats.Idle,
+			"borrowed": stats.Borrowed,
+			"pending":  stats.Pending,
+		}
+	})
+
+	return obj
+}
+
+// acquire returns a Promise of a resource. With fn it implements use():
+// fn(resource) is awaited and the resource released whatever the outcome.
+func (b *Bridge) acquire(p *jsPool, fn goja.Callable) goja.Value {
+	pending, resolver := promise.New(b.vm, b.runtime)
+
+	go func() {
+		res, err := p.pool.Acquire(context.Background())
+		b.runtime.QueueJSOperation(func() {
+			if err != nil {
+				resolver.Reject(b.errorValue(err))
+				return
+			}
+
+			value := res.Value.(goja.Value)
+			if fn == nil {
+				p.borrowed = append(p.borrowed, res)
+				resolver.Resolve(value)
+				return
+			}
+
+			result, err := fn(goja.Undefined(), value)
+			if err != nil {
+				p.pool.Release(res)
+				resolver.Reject(b.errorValue(err))
+				return
+			}
+			b.settle(result, func(v goja.Value) {
+				p.pool.Release(res)
+				resolver.Resolve(v)
+			}, func(reason goja.Value) {
+				p.pool.Release(res)
+				resolver.Reject(reason)
+			})
+		})
+	}()
+
+	return pending
+}
+
+// take removes value from the borrowed list and returns its Resource
+func (b *Bridge) take(p *jsPool, value goja.Value) *Resource {
+	for i, res := range p.borrowed {
+		if res.Value.(goja.Value).StrictEquals(value) {
+			p.borrowed = append(p.borrowed[:i], p.borrowed[i+1:]...)
+			return res
+		}
+	}
+	panic(b.vm.NewTypeError("resource was not acquired from this pool"))
+}
+
+// await calls fn on the JS thread from a pool goroutine and waits for its
+// result, following a returned promise. If ctx ends first, a value that
+// still arrives is passed to late.
+func (b *Bridge) await(ctx context.Context, fn goja.Callable, late func(goja.Value), args ...goja.Value) (goja.Value, error) {
+	type outcome struct {
+		value goja.Value
+		err   error
+	}
+	done := make(chan outcome, 1)
+
+	b.runtime.QueueJSOperation(func() {
+		result, err := fn(goja.Undefined(), args...)
+		if err != nil {
+			done <- outcome{err: b.rejection(b.errorValue(err))}
+			return
+		}
+		b.settle(result, func(v goja.Value) {
+			done <- outcome{value: v}
+		}, func(reason goja.Value) {
+			done <- outcome{err: b.rejection(reason)}
+		})
+	})
+
+	select {
+	case out := <-done:
+		return out.value, out.err
+	case <-ctx.Done():
+		if late != nil {
+			go func() {
+				if out := <-done; out.err == nil {
+					late(out.value)
+				}
+			}()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// settle calls onFulfilled or onRejected once value settles. Plain values
+// fulfil immediately.
+func (b *Bridge) settle(value goja.Value, onFulfilled, onRejected func(goja.Value)) {
+	if obj, ok := value.(*goja.Object); ok {
+		if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+			then(obj, b.vm.ToValue(onFulfilled), b.vm.ToValue(onRejected))
+			return
+		}
+	}
+	onFulfilled(value)
+}
+
+func (b *Bridge) rejection(reason goja.Value) *rejection {
+	return &rejection{value: reason, msg: fmt.Sprint(reason)}
+}
+
+// errorValue converts a Go or JS error into the value to reject with
+func (b *Bridge) errorValue(err error) goja.Value {
+	switch e := err.(type) {
+	case *rejection:
+		return e.value
+	case *goja.Exception:
+		return e.Value()
+	}
+	return b.vm.NewGoError(err)
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
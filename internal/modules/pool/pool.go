@@ -0,0 +1,339 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrClosed is returned by Acquire once the pool is draining or closed
+	ErrClosed = errors.New("pool is closed")
+	// ErrTimeout is returned when no resource became available within AcquireTimeout
+	ErrTimeout = errors.New("timed out acquiring a resource from the pool")
+)
+
+// Hooks manage the lifecycle of pooled resources. Only Create is required.
+type Hooks struct {
+	Create   func(ctx context.Context) (interface{}, error)
+	Destroy  func(value interface{})
+	Validate func(ctx context.Context, value interface{}) bool // Checked before a resource is handed out again
+}
+
+// Options configure pool sizing and ordering
+type Options struct {
+	Min            int           // Resources kept ready in the background
+	Max            int           // Upper bound on live resources; 0 means 10
+	AcquireTimeout time.Duration // 0 waits until the context is done
+	LIFO           bool          // Hand out the most recently released resource first
+}
+
+// Resource is a pooled value on loan from Acquire
+type Resource struct {
+	Value    interface{}
+	Created  time.Time
+	LastUsed time.Time
+}
+
+// Stats describe the pool's current state
+type Stats struct {
+	Size     int // Live resources, including ones being created
+	Idle     int
+	Borrowed int
+	Pending  int // Acquire calls waiting for a resource
+}
+
+// Pool is a bounded pool of reusable resources. It is safe for concurrent
+// use and hands resources to waiters in the order they asked.
+type Pool struct {
+	mu       sync.Mutex
+	hooks    Hooks
+	opts     Options
+	idle     []*Resource
+	size     int
+	borrowed int
+	waiters  []chan *Resource // nil sent means a slot was freed; retry
+	closed   bool
+	drained  chan struct{}
+}
+
+// New creates a pool and starts filling it to opts.Min in the background
+func New(hooks Hooks, opts Options) *Pool {
+	if opts.Max <= 0 {
+		opts.Max = 10
+	}
+	if opts.Min > opts.Max {
+		opts.Min = opts.Max
+	}
+
+	p := &Pool{hooks: hooks, opts: opts, drained: make(chan struct{})}
+	go p.fill()
+	return p
+}
+
+// Acquire borrows a resource, creating one if the pool is below Max or
+// waiting for a Release otherwise
+func (p *Pool) Acquire(ctx context.Context) (*Resource, error) {
+	if p.opts.AcquireTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.opts.AcquireTimeout)
+		defer cancel()
+	}
+
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrClosed
+		}
+
+		if res := p.takeIdle(); res != nil {
+			p.borrowed++
+			p.mu.Unlock()
+			if res = p.check(ctx, res); res != nil {
+				return res, nil
+			}
+			continue
+		}
+
+		if p.size < p.opts.Max {
+			p.size++
+			p.borrowed++
+			p.mu.Unlock()
+			return p.create(ctx)
+		}
+
+		wait := make(chan *Resource, 1)
+		p.waiters = append(p.waiters, wait)
+		p.mu.Unlock()
+
+		select {
+		case res := <-wait:
+			if res == nil {
+				continue
+			}
+			if res = p.check(ctx, res); res != nil {
+				return res, nil
+			}
+		case <-ctx.Done():
+			p.abandon(wait)
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, ErrTimeout
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Release returns a borrowed resource to the pool
+func (p *Pool) Release(res *Resource) {
+	res.LastUsed = time.Now()
+
+	p.mu.Lock()
+	p.borrowed--
+	if p.closed {
+		p.size--
+		p.mu.Unlock()
+		p.destroy(res)
+		p.checkDrained()
+		return
+	}
+
+	if len(p.waiters) > 0 {
+		// Hand over directly so a new caller can't jump the queue
+		p.borrowed++
+		wait := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		p.mu.Unlock()
+		wait <- res
+		return
+	}
+
+	p.idle = append(p.idle, res)
+	p.mu.Unlock()
+}
+
+// Destroy removes a borrowed resource that is broken instead of returning it
+func (p *Pool) Destroy(res *Resource) {
+	p.mu.Lock()
+	p.borrowed--
+	p.mu.Unlock()
+
+	p.discard(res)
+}
+
+// Drain stops handing out resources, destroys idle ones and waits until
+// every borrowed resource has been released or ctx is done
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, wait := range p.waiters {
+			wait <- nil
+		}
+		p.waiters = nil
+	}
+	idle := p.idle
+	p.idle = nil
+	p.size -= len(idle)
+	p.mu.Unlock()
+
+	for _, res := range idle {
+		p.destroy(res)
+	}
+	p.checkDrained()
+
+	select {
+	case <-p.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the pool's counters
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return Stats{
+		Size:     p.size,
+		Idle:     len(p.idle),
+		Borrowed: p.borrowed,
+		Pending:  len(p.waiters),
+	}
+}
+
+// takeIdle pops the next idle resource in FIFO or LIFO order. Callers hold p.mu.
+func (p *Pool) takeIdle() *Resource {
+	if len(p.idle) == 0 {
+		return nil
+	}
+
+	var res *Resource
+	if p.opts.LIFO {
+		res = p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+	} else {
+		res = p.idle[0]
+		p.idle = p.idle[1:]
+	}
+	return res
+}
+
+// create makes a resource for a slot already reserved by the caller
+func (p *Pool) create(ctx context.Context) (*Resource, error) {
+	value, err := p.hooks.Create(ctx)
+	if err != nil {
+		p.mu.Lock()
+		p.size--
+		p.borrowed--
+		p.mu.Unlock()
+		p.wakeOne()
+		p.checkDrained()
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Resource{Value: value, Created: now, LastUsed: now}, nil
+}
+
+// check validates a resource about to be handed out, discarding it if it
+// is no longer usable
+func (p *Pool) check(ctx context.Context, res *Resource) *Resource {
+	if p.hooks.Validate == nil || p.hooks.Validate(ctx, res.Value) {
+		return res
+	}
+
+	p.mu.Lock()
+	p.borrowed--
+	p.mu.Unlock()
+	p.discard(res)
+	return nil
+}
+
+// discard destroys a resource, frees its slot and tops the pool back up
+func (p *Pool) discard(res *Resource) {
+	p.mu.Lock()
+	p.size--
+	closed := p.closed
+	p.mu.Unlock()
+
+	p.destroy(res)
+	if closed {
+		p.checkDrained()
+		return
+	}
+	p.wakeOne()
+	go p.fill()
+}
+
+// abandon removes a waiter whose context ended. If a resource was handed
+// to it in the meantime, it goes back to the pool.
+func (p *Pool) abandon(wait chan *Resource) {
+	p.mu.Lock()
+	for i, w := range p.waiters {
+		if w == wait {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			p.mu.Unlock()
+			return
+		}
+	}
+	p.mu.Unlock()
+
+	if res := <-wait; res != nil {
+		p.Release(res)
+	}
+}
+
+// wakeOne tells the first waiter a slot is free so it can create a resource
+func (p *Pool) wakeOne() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.waiters) > 0 {
+		p.waiters[0] <- nil
+		p.waiters = p.waiters[1:]
+	}
+}
+
+// fill creates idle resources until the pool holds at least Min
+func (p *Pool) fill() {
+	for {
+		p.mu.Lock()
+		if p.closed || p.size >= p.opts.Min {
+			p.mu.Unlock()
+			return
+		}
+		p.size++
+		p.borrowed++
+		p.mu.Unlock()
+
+		res, err := p.create(context.Background())
+		if err != nil {
+			return
+		}
+		p.Release(res)
+	}
+}
+
+func (p *Pool) destroy(res *Resource) {
+	if p.hooks.Destroy != nil {
+		p.hooks.Destroy(res.Value)
+	}
+}
+
+// checkDrained closes p.drained once a closed pool has no live resources
+func (p *Pool) checkDrained() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed && p.size == 0 {
+		select {
+		case <-p.drained:
+		default:
+			close(p.drained)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counter creates numbered resources and records which were destroyed
+type counter struct {
+	created   int64
+	mu        sync.Mutex
+	destroyed []interface{}
+}
+
+func (c *counter) hooks() Hooks {
+	return Hooks{
+		Create: func(ctx context.Context) (interface{}, error) {
+			return atomic.AddInt64(&c.created, 1), nil
+		},
+		Destroy: func(value interface{}) {
+			c.mu.Lock()
+			c.destroyed = append(c.destroyed, value)
+			c.mu.Unlock()
+		},
+	}
+}
+
+func TestAcquireReleaseOrder(t *testing.T) {
+	for _, lifo := range []bool{false, true} {
+		c := &counter{}
+		p := New(c.hooks(), Options{Max: 2, LIFO: lifo})
+
+		a, _ := p.Acquire(context.Background())
+		b, _ := p.Acquire(context.Background())
+		p.Release(a)
+		p.Release(b)
+
+		next, _ := p.Acquire(context.Background())
+		want := a
+		if lifo {
+			want = b
+		}
+		if next != want {
+			t.Errorf("lifo=%v: expected resource %v, got %v", lifo, want.Value, next.Value)
+		}
+		if c.created != 2 {
+			t.Errorf("lifo=%v: expected 2 resources created, got %d", lifo, c.created)
+		}
+	}
+}
+
+func TestAcquireWaitsAndTimesOut(t *testing.T) {
+	c := &counter{}
+	p := New(c.hooks(), Options{Max: 1, AcquireTimeout: 20 * time.Millisecond})
+
+	first, _ := p.Acquire(context.Background())
+	if _, err := p.Acquire(context.Background()); err != ErrTimeout {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+
+	got := make(chan *Resource)
+	go func() {
+		res, _ := p.Acquire(context.Background())
+		got <- res
+	}()
+
+	// Wait for the acquire to queue before releasing
+	for p.Stats().Pending == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	p.Release(first)
+
+	if res := <-got; res != first {
+		t.Errorf("Expected released resource to be handed to the waiter")
+	}
+}
+
+func TestValidateAndDestroy(t *testing.T) {
+	c := &counter{}
+	hooks := c.hooks()
+	hooks.Validate = func(ctx context.Context, value interface{}) bool {
+		return value.(int64) != 1
+	}
+	p := New(hooks, Options{Max: 2})
+
+	res, _ := p.Acquire(context.Background())
+	p.Release(res)
+
+	// Resource 1 fails validation, so a fresh one is created
+	res, _ = p.Acquire(context.Background())
+	if res.Value.(int64) != 2 {
+		t.Fatalf("Expected resource 2, got %v", res.Value)
+	}
+
+	p.Destroy(res)
+	if stats := p.Stats(); stats.Size != 0 || stats.Borrowed != 0 {
+		t.Errorf("Expected empty pool, got %+v", stats)
+	}
+	if len(c.destroyed) != 2 {
+		t.Errorf("Expected 2 destroyed resources, got %v", c.destroyed)
+	}
+}
+
+func TestCreateErrorFreesSlot(t *testing.T) {
+	fail := errors.New("connect refused")
+	p := New(Hooks{Create: func(ctx context.Context) (interface{}, error) { return nil, fail }}, Options{Max: 1})
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Acquire(context.Background()); err != fail {
+			t.Fatalf("Expected create error, got %v", err)
+		}
+	}
+	if p.Stats().Size != 0 {
+		t.Errorf("Expected failed creates to free their slot, got %+v", p.Stats())
+	}
+}
+
+func TestMinAndDrain(t *testing.T) {
+	c := &counter{}
+	p := New(c.hooks(), Options{Min: 2, Max: 4})
+
+	deadline := time.Now().Add(time.Second)
+	for p.Stats().Idle < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if p.Stats().Idle != 2 {
+		t.Fatalf("Expected pool to fill to 2 idle resources, got %+v", p.Stats())
+	}
+
+	res, _ := p.Acquire(context.Background())
+
+	drained := make(chan error)
+	go func() { drained <- p.Drain(context.Background()) }()
+
+	select {
+	case <-drained:
+		t.Fatal("Drain returned while a resource was still borrowed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	p.Release(res)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if _, err := p.Acquire(context.Background()); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after drain, got %v", err)
+	}
+	if len(c.destroyed) != 2 {
+		t.Errorf("Expected every resource to be destroyed, got %v", c.destroyed)
+	}
+}
@@ -0,0 +1,137 @@
+package modules
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/internal/diskcache"
+	"github.com/rizqme/gode/internal/download"
+	"github.com/rizqme/gode/internal/proxy"
+)
+
+// EnvOffline is the environment variable that, set to 1 or true, loads
+// remote modules only from the cache, like gode.network.offline
+const EnvOffline = "GODE_OFFLINE"
+
+// remoteTimeout bounds the download of one remote module
+const remoteTimeout = 30 * time.Second
+
+// netChecker is implemented by runtimes that check network access to a URL
+type netChecker interface {
+	CheckNetURL(rawURL string) error
+}
+
+// IsHTTPURL reports whether specifier is an http: or https: URL
+func IsHTTPURL(specifier string) bool {
+	return strings.HasPrefix(specifier, "http://") || strings.HasPrefix(specifier, "https://")
+}
+
+// resolveURL resolves a relative or absolute-path specifier imported by
+// the remote module at referrer. Remote modules can only import other URLs,
+// never local files.
+func resolveURL(specifier, referrer string) (string, error) {
+	base, err := url.Parse(referrer)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(specifier)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// offline reports whether remote modules may only come from the cache
+func (m *ModuleManager) offline() bool {
+	if v := os.Getenv(EnvOffline); v == "1" || v == "true" {
+		return true
+	}
+	return m.config != nil && m.config.Gode.Network.Offline
+}
+
+// remoteCacheName returns the name of the cache entry of a remote module:
+// a hash of its URL and the integrity it must have, so that changing the
+// pinned hash fetches the module again, with the extension of its path
+func remoteCacheName(rawURL, integrity string) string {
+	sum := sha256.Sum256([]byte(rawURL + "\n" + integrity))
+	name := hex.EncodeToString(sum[:16])
+	if u, err := url.Parse(rawURL); err == nil {
+		if ext := path.Ext(u.Path); len(ext) <= 5 {
+			name += ext
+		}
+	}
+	return name
+}
+
+// loadHTTPModule returns the source of the remote module at rawURL from
+// the remote cache, downloading it there first if needed. A module pinned
+// in gode.integrity must match its hash, in the cache as on the network.
+func (m *ModuleManager) loadHTTPModule(rawURL string) (string, error) {
+	cfg := m.config
+	integrity := ""
+	if cfg != nil {
+		integrity = cfg.Gode.Integrity[rawURL]
+	}
+	dir, err := diskcache.Dir(cfg)
+	if err != nil {
+		return "", err
+	}
+	cache := diskcache.New(dir)
+	name := remoteCacheName(rawURL, integrity)
+	dest := cache.Path(diskcache.Remote, name)
+
+	if _, err := os.Stat(dest); err == nil {
+		if integrity == "" || download.VerifyFile(dest, integrity) == nil {
+			cache.Touch(diskcache.Remote, name)
+			return readSource(dest)
+		}
+		// Changed in the cache since it was verified; fetch it again
+		os.Remove(dest)
+	}
+	if m.offline() {
+		return "", fmt.Errorf("%s is not in the cache and gode is offline", rawURL)
+	}
+
+	if checker, ok := m.runtime.(netChecker); ok {
+		if err := checker.CheckNetURL(rawURL); err != nil {
+			return "", err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	downloader := download.New(download.Options{Client: proxy.Client(0)})
+	if err := downloader.Download(ctx, []download.Item{{URL: rawURL, Dest: dest, Integrity: integrity}}); err != nil {
+		return "", err
+	}
+
+	source, err := readSource(dest)
+	if err != nil {
+		return "", err
+	}
+	if integrity == "" {
+		// Recorded so that gode cache verify notices a changed entry
+		sum := sha512.Sum512([]byte(source))
+		integrity = "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+	}
+	if err := cache.Store(diskcache.Remote, name, diskcache.Meta{Origins: []string{rawURL}, Integrity: integrity}); err != nil {
+		return "", err
+	}
+	return source, nil
+}
+
+func readSource(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
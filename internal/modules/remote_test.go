@@ -0,0 +1,77 @@
+package modules
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestRemoteModules(t *testing.T) {
+	var requests atomic.Int32
+	body := "export const answer = 42;"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/lib/mod.js" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	modURL := server.URL + "/lib/mod.js"
+
+	cfg := &config.PackageJSON{ProjectRoot: t.TempDir()}
+	cfg.Gode.CacheDir = t.TempDir()
+	manager := NewModuleManager()
+	manager.Configure(cfg)
+
+	if resolved, err := manager.Resolve("./util.js", modURL); err != nil || resolved != server.URL+"/lib/util.js" {
+		t.Errorf("Resolve(./util.js) = %q, %v", resolved, err)
+	}
+	if resolved, err := manager.Resolve("/other/x.js", modURL); err != nil || resolved != server.URL+"/other/x.js" {
+		t.Errorf("Resolve(/other/x.js) = %q, %v", resolved, err)
+	}
+
+	source, err := manager.Load(modURL)
+	if err != nil || source != body {
+		t.Fatalf("Load() = %q, %v", source, err)
+	}
+	if _, err := manager.Load(server.URL + "/missing.js"); err == nil {
+		t.Error("Load() of a missing module succeeded")
+	}
+
+	// Later runs read the cache, offline too
+	t.Setenv(EnvOffline, "1")
+	requests.Store(0)
+	manager = NewModuleManager()
+	manager.Configure(cfg)
+	if source, err := manager.Load(modURL); err != nil || source != body || requests.Load() != 0 {
+		t.Errorf("cached Load() = %q, %v after %d requests", source, err, requests.Load())
+	}
+	if _, err := manager.Load(server.URL + "/lib/other.js"); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("offline Load() of an uncached module = %v", err)
+	}
+	os.Unsetenv(EnvOffline)
+
+	// A pinned hash is checked
+	cfg.Gode.Integrity = map[string]string{modURL: "sha512-" + base64.StdEncoding.EncodeToString(make([]byte, 64))}
+	manager = NewModuleManager()
+	manager.Configure(cfg)
+	if _, err := manager.Load(modURL); err == nil || !strings.Contains(err.Error(), "integrity") {
+		t.Errorf("Load() with a wrong hash = %v", err)
+	}
+	sum := sha512.Sum512([]byte(body))
+	cfg.Gode.Integrity[modURL] = "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+	manager = NewModuleManager()
+	manager.Configure(cfg)
+	if source, err := manager.Load(modURL); err != nil || source != body {
+		t.Errorf("Load() with the right hash = %q, %v", source, err)
+	}
+}
@@ -0,0 +1,233 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/abort"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// shellSetup builds $ and ProcessOutput on top of the native run and
+// quote. A ProcessPromise starts its command in a microtask, so that
+// nothrow() and pipe() called right after $ still apply. Piping is
+// buffered: the destination starts once the source has finished, with the
+// source's stdout as its stdin.
+const shellSetup = `
+(function (native) {
+	class ProcessOutput extends Error {
+		constructor(command, result) {
+			super(result.exitCode === 0 ? 'command succeeded' :
+				'command failed with exit code ' + result.exitCode + ': ' + command +
+				(result.stderr ? '\n' + result.stderr.trimEnd() : ''));
+			this.name = 'ProcessOutput';
+			this.command = command;
+			this.stdout = result.stdout;
+			this.stderr = result.stderr;
+			this.exitCode = result.exitCode;
+			this.ok = result.exitCode === 0;
+		}
+		toString() {
+			return this.stdout;
+		}
+		text() {
+			return this.stdout;
+		}
+		lines() {
+			return this.stdout.split('\n').filter((line) => line !== '');
+		}
+		json() {
+			return JSON.parse(this.stdout);
+		}
+	}
+
+	class ProcessPromise {
+		constructor(command, options) {
+			this.command = command;
+			this._options = options;
+			this._nothrow = false;
+			this._source = null;
+			this._promise = new Promise((resolve, reject) => {
+				queueMicrotask(() => this._run().then(resolve, reject));
+			});
+		}
+		async _run() {
+			let input = this._options.input;
+			if (this._source) {
+				input = (await this._source).stdout;
+			}
+			const result = await native.run(this.command, {
+				cwd: this._options.cwd,
+				env: this._options.env,
+				input: input,
+				signal: this._options.signal,
+			});
+			const output = new ProcessOutput(this.command, result);
+			if (output.exitCode !== 0 && !this._nothrow) {
+				throw output;
+			}
+			return output;
+		}
+		nothrow() {
+			this._nothrow = true;
+			return this;
+		}
+		pipe(dest) {
+			if (!(dest instanceof ProcessPromise)) {
+				throw new TypeError('pipe() expects a command created with $');
+			}
+			dest._source = this;
+			return dest;
+		}
+		then(onFulfilled, onRejected) {
+			return this._promise.then(onFulfilled, onRejected);
+		}
+		catch(onRejected) {
+			return this._promise.catch(onRejected);
+		}
+		finally(onFinally) {
+			return this._promise.finally(onFinally);
+		}
+	}
+
+	const argument = (value) => {
+		if (Array.isArray(value)) {
+			return value.map(argument).join(' ');
+		}
+		if (value instanceof ProcessOutput) {
+			return native.quote(value.stdout.replace(/\n+$/, ''));
+		}
+		return native.quote(String(value));
+	};
+
+	const create = (options) => {
+		const $ = function (pieces, ...values) {
+			if (!Array.isArray(pieces)) {
+				// $({cwd, env, input, signal}) returns a $ with those options
+				const extra = pieces || {};
+				return create({
+					cwd: extra.cwd !== undefined ? extra.cwd : options.cwd,
+					env: Object.assign({}, options.env, extra.env),
+					input: extra.input !== undefined ? extra.input : options.input,
+					signal: extra.signal !== undefined ? extra.signal : options.signal,
+				});
+			}
+			let command = pieces[0];
+			values.forEach((value, i) => {
+				command += argument(value) + pieces[i + 1];
+			});
+			return new ProcessPromise(command, options);
+		};
+		return $;
+	};
+
+	return {
+		$: create({ env: {} }),
+		quote: native.quote,
+		ProcessOutput: ProcessOutput,
+		ProcessPromise: ProcessPromise,
+	};
+})
+`
+
+// Bridge provides JavaScript bindings for the gode:shell module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+	ctx     context.Context
+}
+
+// NewBridge creates a new shell bridge. Commands still running when ctx is
+// cancelled are killed.
+func NewBridge(runtime RuntimeInterface, ctx context.Context) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+		ctx:     ctx,
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() (*goja.Object, error) {
+	native := b.vm.NewObject()
+	native.Set("quote", Quote)
+	native.Set("run", b.run)
+
+	setup, err := jsprogram.Run(b.vm, "shell-setup", shellSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("shell setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
+
+// run implements native.run(command, {cwd, env, input, signal}),
+// resolving to {stdout, stderr, exitCode}. Every program the command line
+// starts must pass allow-run. Aborting the signal kills the command and
+// rejects with the signal's reason.
+func (b *Bridge) run(call goja.FunctionCall) goja.Value {
+	cmd := Command{Line: call.Argument(0).String()}
+	var signal *abort.Signal
+	if opts, ok := call.Argument(1).(*goja.Object); ok {
+		var err error
+		if signal, err = abort.From(b.vm, opts.Get("signal")); err != nil {
+			panic(b.vm.NewTypeError(err.Error()))
+		}
+		if v := opts.Get("cwd"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			cmd.Cwd = v.String()
+		}
+		if env, ok := opts.Get("env").(*goja.Object); ok {
+			cmd.Env = make(map[string]string)
+			for _, key := range env.Keys() {
+				cmd.Env[key] = env.Get(key).String()
+			}
+		}
+		if v := opts.Get("input"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			cmd.Stdin = []byte(v.String())
+		}
+	}
+
+	if checker, ok := b.runtime.(permissions.Enforcer); ok {
+		for _, program := range Programs(cmd.Line) {
+			if err := checker.CheckPermission("run", program); err != nil {
+				panic(jserror.New(b.vm, err))
+			}
+		}
+	}
+
+	p, resolver := promise.New(b.vm, b.runtime)
+	if err := signal.Err(); err != nil {
+		resolver.Reject(err)
+		return p
+	}
+	ctx, stop := signal.Context(b.ctx)
+	go func() {
+		result, err := Run(ctx, cmd)
+		resolver.SettleWith(func() (interface{}, error) {
+			stop()
+			if err != nil {
+				if aborted := signal.Err(); aborted != nil {
+					return nil, aborted
+				}
+				return nil, err
+			}
+			return map[string]interface{}{
+				"stdout":   result.Stdout,
+				"stderr":   result.Stderr,
+				"exitCode": result.ExitCode,
+			}, nil
+		})
+	}()
+	return p
+}
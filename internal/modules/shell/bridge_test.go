@@ -0,0 +1,65 @@
+package shell_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestShellModule(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	err := rt.Configure(&config.PackageJSON{
+		Name: "test",
+		Gode: config.GodeConfig{
+			Permissions: config.PermissionConfig{AllowRun: []string{"echo", "tr", "printf", "sh", "pwd"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	dir := t.TempDir()
+
+	value, err := rt.RunScriptAsync("shell", `
+		(async () => {
+			const { $, ProcessOutput } = require('gode:shell');
+			const results = [];
+
+			// Interpolations are single, quoted arguments
+			const tricky = "it's $(id); rm -rf *";
+			results.push((await $`+"`printf %s ${tricky}`"+`).stdout === tricky);
+			results.push((await $`+"`printf '%s|' ${['a b', 'c']}`"+`).stdout);
+
+			// Buffered piping and options
+			results.push((await $`+"`echo hello`"+`.pipe($`+"`tr a-z A-Z`"+`)).stdout.trim());
+			results.push((await $({ cwd: `+strconv.Quote(dir)+`, env: { NAME: 'gode' } })`+"`pwd; echo $NAME`"+`).lines().join(','));
+
+			// Non-zero exits reject with a ProcessOutput unless nothrow()
+			try {
+				await $`+"`sh -c 'echo oops >&2; exit 4'`"+`;
+			} catch (e) {
+				results.push(e instanceof ProcessOutput && e.exitCode + ':' + e.stderr.trim());
+			}
+			results.push((await $`+"`sh -c 'exit 2'`"+`.nothrow()).exitCode);
+
+			// Every program started must pass allow-run
+			try {
+				await $`+"`echo ok && rm -rf nothing`"+`;
+			} catch (e) {
+				results.push(e.name + ':' + e.resource);
+			}
+			return results.join('\n');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	want := strings.Join([]string{"true", "a b|c|", "HELLO", dir + ",gode", "4:oops", "2", "PermissionDenied:rm"}, "\n")
+	if value != want {
+		t.Errorf("Shell results =\n%v\nwant\n%v", value, want)
+	}
+}
@@ -0,0 +1,34 @@
+package shell
+
+import (
+	"context"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	AddShutdownHook(fn func()) (remove func())
+}
+
+// RegisterShellModule registers gode:shell in the JavaScript runtime.
+// Commands still running when the runtime shuts down are killed.
+func RegisterShellModule(runtime RuntimeInterface) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	runtime.AddShutdownHook(cancel)
+
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		exports, err := NewBridge(runtime, ctx).Exports()
+		if err != nil {
+			done <- err
+			return
+		}
+		runtime.RegisterModule("gode:shell", exports)
+		done <- nil
+	})
+	return <-done
+}
@@ -0,0 +1,226 @@
+// Package shell implements gode:shell, which runs command lines written as
+// tagged templates through a POSIX shell:
+//
+//	const out = await $`git log --oneline -n ${count} ${file}`
+//
+// Interpolated values are quoted, so they reach the command as single
+// arguments however many spaces or shell metacharacters they contain.
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// safeWord matches arguments that need no quoting
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// skipWords can precede the program of a command
+var skipWords = map[string]bool{
+	"!": true, "{": true, "}": true, "time": true,
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true,
+}
+
+// Quote returns s as a single shell word
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if safeWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Command is a command line to run
+type Command struct {
+	Line  string
+	Cwd   string            // Defaults to the current directory
+	Env   map[string]string // Set on top of the process environment
+	Stdin []byte
+}
+
+// Result is the outcome of a command that ran to completion, whatever its
+// exit code
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Run runs cmd with sh and waits for it. Cancelling ctx kills the shell. Only
+// failures to run the command at all are returned as errors; a non-zero
+// exit code is reported in the Result.
+func Run(ctx context.Context, cmd Command) (Result, error) {
+	c := exec.CommandContext(ctx, shellPath(), "-c", cmd.Line)
+	c.Dir = cmd.Cwd
+	// Killing sh doesn't kill the commands it started, which keep the
+	// output pipes open; stop waiting for them shortly after
+	c.WaitDelay = 100 * time.Millisecond
+	if len(cmd.Env) > 0 {
+		c.Env = os.Environ()
+		for name, value := range cmd.Env {
+			c.Env = append(c.Env, name+"="+value)
+		}
+	}
+	if cmd.Stdin != nil {
+		c.Stdin = bytes.NewReader(cmd.Stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	err := c.Run()
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// shellPath prefers /bin/sh and otherwise looks sh up on PATH
+func shellPath() string {
+	if _, err := os.Stat("/bin/sh"); err == nil {
+		return "/bin/sh"
+	}
+	return "sh"
+}
+
+// Programs returns the program each command of line starts, in order, so
+// that they can be checked against allow-run. Commands are separated by
+// pipes, lists (; && || &), newlines, subshells and command substitution.
+// Leading VAR=value assignments and keywords such as if and do are
+// skipped. Quoted text is never split, so interpolated values can't start
+// a command.
+func Programs(line string) []string {
+	var programs []string
+	var word strings.Builder
+	inWord := false
+	atStart := true // The next word starts a command
+
+	finishWord := func() {
+		if !inWord {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		inWord = false
+		if !atStart {
+			return
+		}
+		if skipWords[w] || (strings.Contains(w, "=") && !strings.HasPrefix(w, "=")) {
+			return // A keyword or an assignment; the program comes next
+		}
+		programs = append(programs, w)
+		atStart = false
+	}
+	separator := func() {
+		finishWord()
+		atStart = true
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				word.WriteString(line[i+1:])
+				i = len(line)
+				break
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				} else if line[i] == '$' && i+1 < len(line) && line[i+1] == '(' {
+					// Command substitution still runs inside double quotes
+					programs = append(programs, Programs(substitution(line, &i))...)
+					continue
+				} else if line[i] == '`' {
+					programs = append(programs, Programs(backquoted(line, &i))...)
+					continue
+				}
+				word.WriteByte(line[i])
+			}
+		case c == '\\' && i+1 < len(line):
+			inWord = true
+			i++
+			word.WriteByte(line[i])
+		case c == '$' && i+1 < len(line) && line[i+1] == '(':
+			finishWord()
+			programs = append(programs, Programs(substitution(line, &i))...)
+		case c == '`':
+			finishWord()
+			programs = append(programs, Programs(backquoted(line, &i))...)
+		case c == '&' && (i > 0 && (line[i-1] == '>' || line[i-1] == '<') || i+1 < len(line) && line[i+1] == '>'):
+			// Part of a redirection such as 2>&1 or &>file
+			inWord = true
+			word.WriteByte(c)
+		case c == '|' || c == ';' || c == '&' || c == '\n' || c == '(' || c == ')':
+			separator()
+		case c == ' ' || c == '\t':
+			finishWord()
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	finishWord()
+	return programs
+}
+
+// substitution returns the body of the $( ... ) starting at line[*i],
+// leaving *i on its closing parenthesis
+func substitution(line string, i *int) string {
+	start := *i + 2
+	depth := 1
+	for j := start; j < len(line); j++ {
+		switch line[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				*i = j
+				return line[start:j]
+			}
+		}
+	}
+	*i = len(line)
+	return line[start:]
+}
+
+// backquoted returns the body of the `...` starting at line[*i], leaving
+// *i on the closing backquote
+func backquoted(line string, i *int) string {
+	start := *i + 1
+	end := strings.IndexByte(line[start:], '`')
+	if end < 0 {
+		*i = len(line)
+		return line[start:]
+	}
+	*i = start + end
+	return line[start : start+end]
+}
@@ -0,0 +1,90 @@
+package shell
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"":                   "''",
+		"plain-file_1.txt":   "plain-file_1.txt",
+		"two words":          "'two words'",
+		"it's":               `'it'\''s'`,
+		"$(rm -rf /); `id`":  "'$(rm -rf /); `id`'",
+		"a\nb":               "'a\nb'",
+		"--flag=value,other": "--flag=value,other",
+	}
+	for in, want := range tests {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPrograms(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"ls -la /tmp", []string{"ls"}},
+		{"cat file | grep x && wc -l; echo done &", []string{"cat", "grep", "wc", "echo"}},
+		{"FOO=1 BAR=2 env", []string{"env"}},
+		{"echo $(curl evil) `id`", []string{"echo", "curl", "id"}},
+		{`echo "user: $(whoami)"`, []string{"echo", "whoami"}},
+		{"echo 'a | b; c' \"d && e\"", []string{"echo"}},
+		{"make 2>&1 | tee log", []string{"make", "tee"}},
+		{"if test -f x; then rm x; fi", []string{"test", "rm"}},
+		{"(cd /tmp && ls)", []string{"cd", "ls"}},
+		{"'my tool' --flag", []string{"my tool"}},
+		{"echo {a,b}", []string{"echo"}},
+	}
+	for _, tt := range tests {
+		if got := Programs(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Programs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	result, err := Run(ctx, Command{Line: "echo out; echo err >&2; exit 3"})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if result.Stdout != "out\n" || result.Stderr != "err\n" || result.ExitCode != 3 {
+		t.Errorf("Run() = %+v", result)
+	}
+
+	dir := t.TempDir()
+	result, err = Run(ctx, Command{
+		Line:  `pwd; echo "$GREETING"; cat`,
+		Cwd:   dir,
+		Env:   map[string]string{"GREETING": "hello"},
+		Stdin: []byte("piped"),
+	})
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	lines := strings.Split(result.Stdout, "\n")
+	if !strings.HasSuffix(lines[0], dir[strings.LastIndex(dir, "/"):]) || lines[1] != "hello" || lines[2] != "piped" {
+		t.Errorf("Run() stdout = %q", result.Stdout)
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := Run(ctx, Command{Line: "sleep 5"}); err == nil {
+		t.Error("Expected a cancelled command to fail")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("Expected cancelling to kill the command")
+	}
+}
@@ -1,527 +1,756 @@
 package stream
 
 import (
-	"fmt"
+	"sync"
 
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/abort"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/modules/globals"
 )
 
-// Simple EventEmitter implementation for streams
-type SimpleEventEmitter struct {
-	handlers map[string][]interface{}
+// Bridge provides JavaScript bindings for the stream module
+type Bridge struct {
+	runtime   RuntimeInterface
+	vm        *goja.Runtime
+	streamKey *goja.Symbol // holds the Go stream behind a JS stream object
+
+	mu   sync.Mutex
+	open map[destroyer]struct{} // streams not closed yet
 }
 
-func NewSimpleEventEmitter() *SimpleEventEmitter {
-	return &SimpleEventEmitter{
-		handlers: make(map[string][]interface{}),
-	}
+// destroyer is a stream that can be destroyed
+type destroyer interface {
+	Destroy(err error)
 }
 
-func (e *SimpleEventEmitter) On(event string, handler interface{}) {
-	if e.handlers[event] == nil {
-		e.handlers[event] = make([]interface{}, 0)
+// NewBridge creates a new stream bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime:   runtime,
+		vm:        runtime.GetGojaRuntime(),
+		streamKey: goja.NewSymbol("gode.stream"),
 	}
-	e.handlers[event] = append(e.handlers[event], handler)
 }
 
-func (e *SimpleEventEmitter) Once(event string, handler interface{}) {
-	// For simplicity, treat once the same as on
-	e.On(event, handler)
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+
+	readable := b.vm.ToValue(b.newReadable).(*goja.Object)
+	readable.Set("from", b.from)
+	exports.Set("Readable", readable)
+	exports.Set("Writable", b.newWritable)
+	exports.Set("Duplex", b.newDuplex)
+	exports.Set("Transform", b.newTransform)
+	exports.Set("PassThrough", b.newPassThrough)
+	exports.Set("compose", b.compose)
+	exports.Set("addAbortSignal", b.addAbortSignal)
+	exports.Set("pipeline", b.resolved)
+	exports.Set("finished", b.resolved)
+	return exports
+}
+
+// newReadable implements new Readable({objectMode, highWaterMark, signal})
+func (b *Bridge) newReadable(call goja.ConstructorCall) *goja.Object {
+	emitter := NewSimpleEventEmitter()
+	stream := NewReadable(readableOptions(constructorOptions(call)), emitter)
+	stream.owner = call.This
+
+	b.wrap(call.This, stream, emitter)
+	b.readableMethods(call.This, stream)
+	call.This.Set("destroy", func(err goja.Value) {
+		stream.Destroy(b.goError(err))
+	})
+	b.abortable(call.This, call.Argument(0))
+	return nil
+}
+
+// newWritable implements new Writable({objectMode, highWaterMark, signal})
+func (b *Bridge) newWritable(call goja.ConstructorCall) *goja.Object {
+	emitter := NewSimpleEventEmitter()
+	stream := NewWritable(writableOptions(constructorOptions(call)), emitter)
+	stream.owner = call.This
+
+	b.wrap(call.This, stream, emitter)
+	b.writableMethods(call.This, stream, stream)
+	call.This.Set("destroy", func(err goja.Value) {
+		stream.Destroy(b.goError(err))
+	})
+	b.abortable(call.This, call.Argument(0))
+	return nil
+}
+
+// newDuplex implements new Duplex(options), with readable* and writable*
+// options for either side
+func (b *Bridge) newDuplex(call goja.ConstructorCall) *goja.Object {
+	emitter := NewSimpleEventEmitter()
+	options := constructorOptions(call)
+	stream := NewDuplex(readableOptions(options), writableOptions(options), emitter)
+	stream.Readable.owner = call.This
+	stream.Writable.owner = call.This
+
+	b.wrap(call.This, stream, emitter)
+	b.readableMethods(call.This, stream.Readable)
+	b.writableMethods(call.This, stream.Writable, stream.Writable)
+	b.duplexMethods(call.This, stream, options)
+	b.abortable(call.This, call.Argument(0))
+	return nil
+}
+
+// duplexMethods applies the allowHalfOpen option, true unless given, and
+// defines destroy(err), which destroys both sides
+func (b *Bridge) duplexMethods(obj *goja.Object, stream *Duplex, options map[string]interface{}) {
+	if allow, ok := options["allowHalfOpen"].(bool); ok {
+		stream.AllowHalfOpen = allow
+	}
+	obj.Set("destroy", func(err goja.Value) {
+		stream.Destroy(b.goError(err))
+	})
 }
 
-func (e *SimpleEventEmitter) Off(event string, handler interface{}) {
-	if handlers, exists := e.handlers[event]; exists {
-		for i, h := range handlers {
-			if h == handler {
-				e.handlers[event] = append(handlers[:i], handlers[i+1:]...)
-				break
+// newTransform implements new Transform({transform, flush, ...options}).
+// transform(chunk, encoding, callback) and flush(callback), or the
+// _transform and _flush methods of a subclass, run on the JS thread and
+// complete by calling callback(err, data); without them chunks pass through
+// unchanged.
+func (b *Bridge) newTransform(call goja.ConstructorCall) *goja.Object {
+	emitter := NewSimpleEventEmitter()
+	options := constructorOptions(call)
+	transform := b.method(call, "transform", "_transform")
+	flush := b.method(call, "flush", "_flush")
+
+	var stream *Transform
+	if transform == nil && flush == nil {
+		transformFunc := func(chunk []byte, encoding string) ([]byte, error) {
+			return chunk, nil // Identity transform by default
+		}
+		stream = NewTransform(readableOptions(options), writableOptions(options), emitter, transformFunc, nil)
+	} else {
+		var transformFunc AsyncTransformFunc = func(chunk interface{}, encoding string, done func(interface{}, error)) {
+			done(chunk, nil)
+		}
+		if transform != nil {
+			transformFunc = func(chunk interface{}, encoding string, done func(interface{}, error)) {
+				if encoding == "" {
+					encoding = "buffer"
+				}
+				b.callback(call.This, transform, func() []goja.Value {
+					return []goja.Value{b.toJS(chunk), b.vm.ToValue(encoding)}
+				}, stream, done)
+			}
+		}
+		var flushFunc AsyncFlushFunc
+		if flush != nil {
+			flushFunc = func(done func(interface{}, error)) {
+				b.callback(call.This, flush, nil, stream, done)
 			}
 		}
+		stream = NewAsyncTransform(readableOptions(options), writableOptions(options), emitter, transformFunc, flushFunc)
 	}
+	stream.Readable.owner = call.This
+	stream.Writable.owner = call.This
+
+	b.wrap(call.This, stream, emitter)
+	b.readableMethods(call.This, stream.Readable)
+	b.writableMethods(call.This, stream.Writable, stream)
+	b.duplexMethods(call.This, stream.Duplex, options)
+	b.abortable(call.This, call.Argument(0))
+	return nil
 }
 
-func (e *SimpleEventEmitter) Emit(event string, args ...interface{}) {
-	if handlers, exists := e.handlers[event]; exists {
-		for _, handler := range handlers {
-			// Call the handler - this is a simplified version
-			// In a full implementation, we'd need to handle different handler types
-			if fn, ok := handler.(func()); ok {
-				fn()
+// method returns the function given to a constructor as option, or the
+// method of a subclass named name
+func (b *Bridge) method(call goja.ConstructorCall, option, name string) goja.Callable {
+	if options, ok := call.Argument(0).(*goja.Object); ok {
+		if fn, ok := goja.AssertFunction(options.Get(option)); ok {
+			return fn
+		}
+	}
+	if fn, ok := goja.AssertFunction(call.This.Get(name)); ok {
+		return fn
+	}
+	return nil
+}
+
+// callback queues a call of fn with this, the arguments made by args and a
+// Node style callback(err, data) completing it with done. A throwing fn
+// completes with its exception.
+func (b *Bridge) callback(this *goja.Object, fn goja.Callable, args func() []goja.Value, stream *Transform, done func(interface{}, error)) {
+	release := b.runtime.KeepAlive()
+	b.runtime.QueueJSOperation(func() {
+		defer release()
+
+		var values []goja.Value
+		if args != nil {
+			values = args()
+		}
+		callback := b.vm.ToValue(func(err, data goja.Value) {
+			if !isNullish(err) {
+				done(nil, b.goError(err))
+				return
+			}
+			var result interface{}
+			if !isNullish(data) {
+				result = exportChunk(data, stream.Readable.objectMode)
+			}
+			done(result, nil)
+		})
+		if _, err := fn(this, append(values, callback)...); err != nil {
+			if ex, ok := err.(*goja.Exception); ok {
+				done(nil, &valueError{value: ex.Value()})
+			} else {
+				done(nil, err)
 			}
 		}
+	})
+}
+
+// newPassThrough implements new PassThrough(options)
+func (b *Bridge) newPassThrough(call goja.ConstructorCall) *goja.Object {
+	emitter := NewSimpleEventEmitter()
+	options := constructorOptions(call)
+	stream := NewPassThrough(readableOptions(options), writableOptions(options), emitter)
+	stream.Readable.owner = call.This
+	stream.Writable.owner = call.This
+
+	b.wrap(call.This, stream, emitter)
+	b.readableMethods(call.This, stream.Readable)
+	b.writableMethods(call.This, stream.Writable, stream.Transform)
+	b.duplexMethods(call.This, stream.Duplex, options)
+	b.abortable(call.This, call.Argument(0))
+	return nil
+}
+
+// from implements Readable.from(iterable): an object mode stream of the
+// items of an array, of the characters of a string, or of the values of
+// any other iterable or async iterable
+func (b *Bridge) from(iterable goja.Value) *goja.Object {
+	// Other objects are iterated without exporting them, which would run
+	// their getters
+	if obj, ok := iterable.(*goja.Object); ok && obj.ClassName() != "Array" {
+		if iterator, ok := b.iterator(iterable); ok {
+			return b.fromIterator(iterator)
+		}
 	}
+
+	var items []interface{}
+	switch v := iterable.Export().(type) {
+	case []interface{}:
+		items = v
+	case string:
+		for _, char := range v {
+			items = append(items, string(char))
+		}
+	default:
+		if iterator, ok := b.iterator(iterable); ok {
+			return b.fromIterator(iterator)
+		}
+		items = []interface{}{v}
+	}
+
+	emitter := NewSimpleEventEmitter()
+	stream := FromIterable(items, emitter)
+	obj := b.vm.NewObject()
+	stream.owner = obj
+
+	b.wrap(obj, stream, emitter)
+	b.readableMethods(obj, stream)
+	return obj
+}
+
+// addAbortSignal implements addAbortSignal(signal, stream): aborting the
+// signal destroys stream with the signal's reason. It returns stream.
+func (b *Bridge) addAbortSignal(signal, stream goja.Value) goja.Value {
+	obj, ok := stream.(*goja.Object)
+	if !ok || b.unwrap(stream) == nil {
+		panic(b.vm.NewTypeError("addAbortSignal requires a stream"))
+	}
+	b.watchSignal(obj, signal)
+	return stream
 }
 
-// createEventEmitter creates a JavaScript EventEmitter object
-func createEventEmitter(runtime *goja.Runtime) *goja.Object {
-	emitter := runtime.NewObject()
-	
-	// Create a simple event emitter implementation
-	handlers := make(map[string][]goja.Value)
-	
-	emitter.Set("on", func(event string, handler goja.Value) {
-		if handlers[event] == nil {
-			handlers[event] = make([]goja.Value, 0)
+// abortable applies the signal option of a stream constructor
+func (b *Bridge) abortable(obj *goja.Object, options goja.Value) {
+	if opts, ok := options.(*goja.Object); ok {
+		b.watchSignal(obj, opts.Get("signal"))
+	}
+}
+
+// watchSignal destroys the stream obj with the reason of signal once it
+// aborts, and stops watching once the stream closes
+func (b *Bridge) watchSignal(obj *goja.Object, value goja.Value) {
+	signal, err := abort.From(b.vm, value)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	if signal == nil {
+		return
+	}
+	remove := signal.OnAbort(func(reason goja.Value) {
+		if destroy, ok := goja.AssertFunction(obj.Get("destroy")); ok {
+			destroy(obj, reason)
 		}
-		handlers[event] = append(handlers[event], handler)
 	})
-	
-	emitter.Set("once", func(event string, handler goja.Value) {
-		// For simplicity, treat once the same as on
-		if handlers[event] == nil {
-			handlers[event] = make([]goja.Value, 0)
+	if once, ok := goja.AssertFunction(obj.Get("once")); ok {
+		once(obj, b.vm.ToValue("close"), b.vm.ToValue(remove))
+	}
+}
+
+// resolved implements pipeline and finished, which do not track the
+// streams yet, as a resolved promise
+func (b *Bridge) resolved(call goja.FunctionCall) goja.Value {
+	promise, resolve, _ := b.vm.NewPromise()
+	resolve(goja.Undefined())
+	return b.vm.ToValue(promise)
+}
+
+// wrap ties obj to its Go stream and installs the EventEmitter methods that
+// deliver the stream's events to JS listeners
+func (b *Bridge) wrap(obj *goja.Object, stream interface{}, emitter *SimpleEventEmitter) {
+	obj.SetSymbol(b.streamKey, stream)
+	newJSEvents(b, obj, emitter).install()
+	b.track(stream, emitter)
+}
+
+// track keeps stream among the open streams until it ends or closes
+func (b *Bridge) track(stream interface{}, emitter *SimpleEventEmitter) {
+	s, ok := stream.(destroyer)
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	if b.open == nil {
+		b.open = make(map[destroyer]struct{})
+	}
+	b.open[s] = struct{}{}
+	b.mu.Unlock()
+
+	done := func() {
+		b.mu.Lock()
+		delete(b.open, s)
+		b.mu.Unlock()
+	}
+	emitter.Once("close", done)
+	switch stream.(type) {
+	case *Readable:
+		emitter.Once("end", done)
+	case *Writable:
+		emitter.Once("finish", done)
+	}
+}
+
+// destroyOpen destroys the streams still open when the runtime shuts down,
+// releasing their pipes and the goroutines waiting on them
+func (b *Bridge) destroyOpen() {
+	b.mu.Lock()
+	open := b.open
+	b.open = nil
+	b.mu.Unlock()
+
+	for s := range open {
+		s.Destroy(nil)
+	}
+}
+
+// unwrap returns the Go stream behind a JS stream object, or nil
+func (b *Bridge) unwrap(value goja.Value) interface{} {
+	if obj, ok := value.(*goja.Object); ok {
+		if sym := obj.GetSymbol(b.streamKey); sym != nil {
+			return sym.Export()
 		}
-		handlers[event] = append(handlers[event], handler)
+	}
+	return nil
+}
+
+// destination returns the stream behind a writable JS stream, throwing a
+// TypeError for anything else
+func (b *Bridge) destination(value goja.Value) Destination {
+	if dest, ok := b.unwrap(value).(Destination); ok {
+		return dest
+	}
+	panic(b.vm.NewTypeError("destination must be a writable stream"))
+}
+
+// readableMethods adds the methods of a readable stream to obj
+func (b *Bridge) readableMethods(obj *goja.Object, stream *Readable) {
+	obj.Set("read", func(size int) interface{} {
+		return readChunk(stream, size)
 	})
-	
-	emitter.Set("emit", func(event string, args ...interface{}) {
-		if eventHandlers, exists := handlers[event]; exists {
-			for _, handler := range eventHandlers {
-				if fn, ok := goja.AssertFunction(handler); ok {
-					gojaArgs := make([]goja.Value, len(args))
-					for i, arg := range args {
-						gojaArgs[i] = runtime.ToValue(arg)
-					}
-					fn(goja.Undefined(), gojaArgs...)
-				}
-			}
+	obj.Set("push", func(data goja.Value) bool {
+		return pushChunk(stream, data)
+	})
+	obj.Set("pause", func() *goja.Object {
+		stream.Pause()
+		return obj
+	})
+	obj.Set("resume", func() *goja.Object {
+		stream.Resume()
+		return obj
+	})
+	obj.Set("isPaused", func() bool {
+		return stream.IsPaused()
+	})
+
+	// pipe(dest, {end = true}) returns dest, so pipes can be chained
+	obj.Set("pipe", func(destination goja.Value, options goja.Value) goja.Value {
+		dest := b.destination(destination)
+		var opts map[string]interface{}
+		if !isNullish(options) {
+			opts, _ = options.Export().(map[string]interface{})
 		}
+		if err := stream.Pipe(dest, opts); err != nil {
+			panic(jserror.New(b.vm, err))
+		}
+		return destination
 	})
-	
-	return emitter
-}
-
-// createReadableConstructor creates the Readable constructor
-func createReadableConstructor(runtime *goja.Runtime, eventEmitter *goja.Object) func(goja.ConstructorCall) *goja.Object {
-	return func(call goja.ConstructorCall) *goja.Object {
-		readable := runtime.NewObject()
-		
-		// Create a simple EventEmitter for this stream
-		emitter := NewSimpleEventEmitter()
-		
-		// Create Go stream instance
-		opts := &ReadableOptions{
-			HighWaterMark: 16 * 1024,
+	obj.Set("unpipe", func(destination goja.Value) *goja.Object {
+		if isNullish(destination) {
+			stream.Unpipe(nil)
+		} else if dest, ok := b.unwrap(destination).(Destination); ok {
+			stream.Unpipe(dest)
 		}
-		stream := NewReadable(opts, emitter)
-		
-		// Set up JavaScript methods
-		readable.Set("read", func(size int) interface{} {
-			data, err := stream.Read(size)
-			if err != nil {
-				return nil
-			}
-			return string(data)
-		})
-		
-		readable.Set("push", func(data interface{}) bool {
-			var bytes []byte
-			if data == nil {
-				bytes = nil
-			} else if str, ok := data.(string); ok {
-				bytes = []byte(str)
-			} else if arr, ok := data.([]byte); ok {
-				bytes = arr
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", data))
-			}
-			
-			err := stream.Push(bytes)
-			return err == nil
-		})
-		
-		readable.Set("pause", func() {
-			stream.Pause()
-		})
-		
-		readable.Set("resume", func() {
-			stream.Resume()
-		})
-		
-		readable.Set("isPaused", func() bool {
-			return stream.IsPaused()
-		})
-		
-		readable.Set("destroy", func(err interface{}) {
-			var goErr error
-			if err != nil {
-				if e, ok := err.(error); ok {
-					goErr = e
-				} else {
-					goErr = fmt.Errorf("%v", err)
-				}
-			}
-			stream.Destroy(goErr)
-		})
-		
-		// Add event emitter methods
-		readable.Set("on", eventEmitter.Get("on"))
-		readable.Set("once", eventEmitter.Get("once"))
-		readable.Set("emit", eventEmitter.Get("emit"))
-		
-		return readable
-	}
-}
-
-// createWritableConstructor creates the Writable constructor
-func createWritableConstructor(runtime *goja.Runtime, eventEmitter *goja.Object) func(goja.ConstructorCall) *goja.Object {
-	return func(call goja.ConstructorCall) *goja.Object {
-		writable := runtime.NewObject()
-		
-		// Create a simple EventEmitter for this stream
-		emitter := NewSimpleEventEmitter()
-		
-		// Create Go stream instance
-		opts := &WritableOptions{
-			HighWaterMark: 16 * 1024,
+		return obj
+	})
+}
+
+// writableMethods adds the methods of a writable stream to obj. Chunks are
+// written through dest, which transforms them on a Transform.
+func (b *Bridge) writableMethods(obj *goja.Object, stream *Writable, dest Destination) {
+	obj.Set("write", func(chunk goja.Value) bool {
+		return dest.WriteObject(exportChunk(chunk, stream.objectMode))
+	})
+	obj.Set("end", func(chunk goja.Value) *goja.Object {
+		if !isNullish(chunk) {
+			dest.WriteObject(exportChunk(chunk, stream.objectMode))
 		}
-		stream := NewWritable(opts, emitter)
-		
-		// Set up JavaScript methods
-		writable.Set("write", func(chunk interface{}) bool {
-			var bytes []byte
-			if str, ok := chunk.(string); ok {
-				bytes = []byte(str)
-			} else if arr, ok := chunk.([]byte); ok {
-				bytes = arr
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", chunk))
-			}
-			
-			return stream.Write(bytes)
-		})
-		
-		writable.Set("end", func(chunk interface{}) {
-			var bytes []byte
-			if chunk != nil {
-				if str, ok := chunk.(string); ok {
-					bytes = []byte(str)
-				} else if arr, ok := chunk.([]byte); ok {
-					bytes = arr
-				} else {
-					bytes = []byte(fmt.Sprintf("%v", chunk))
-				}
-			}
-			stream.End(bytes)
-		})
-		
-		writable.Set("cork", func() {
-			stream.Cork()
-		})
-		
-		writable.Set("uncork", func() {
-			stream.Uncork()
-		})
-		
-		writable.Set("destroy", func(err interface{}) {
-			var goErr error
-			if err != nil {
-				if e, ok := err.(error); ok {
-					goErr = e
-				} else {
-					goErr = fmt.Errorf("%v", err)
-				}
-			}
-			stream.Destroy(goErr)
-		})
-		
-		// Add event emitter methods
-		writable.Set("on", eventEmitter.Get("on"))
-		writable.Set("once", eventEmitter.Get("once"))
-		writable.Set("emit", eventEmitter.Get("emit"))
-		
-		return writable
-	}
-}
-
-// createDuplexConstructor creates the Duplex constructor
-func createDuplexConstructor(runtime *goja.Runtime, eventEmitter *goja.Object) func(goja.ConstructorCall) *goja.Object {
-	return func(call goja.ConstructorCall) *goja.Object {
-		duplex := runtime.NewObject()
-		
-		// Create a simple EventEmitter for this stream
-		emitter := NewSimpleEventEmitter()
-		
-		// Create Go stream instance
-		readOpts := &ReadableOptions{HighWaterMark: 16 * 1024}
-		writeOpts := &WritableOptions{HighWaterMark: 16 * 1024}
-		stream := NewDuplex(readOpts, writeOpts, emitter)
-		
-		// Set up readable methods
-		duplex.Set("read", func(size int) interface{} {
-			data, err := stream.Readable.Read(size)
-			if err != nil {
-				return nil
-			}
-			return string(data)
-		})
-		
-		duplex.Set("push", func(data interface{}) bool {
-			var bytes []byte
-			if data == nil {
-				bytes = nil
-			} else if str, ok := data.(string); ok {
-				bytes = []byte(str)
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", data))
-			}
-			
-			err := stream.Readable.Push(bytes)
-			return err == nil
-		})
-		
-		duplex.Set("pause", func() {
-			stream.Readable.Pause()
-		})
-		
-		duplex.Set("resume", func() {
-			stream.Readable.Resume()
-		})
-		
-		// Set up writable methods
-		duplex.Set("write", func(chunk interface{}) bool {
-			var bytes []byte
-			if str, ok := chunk.(string); ok {
-				bytes = []byte(str)
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", chunk))
+		dest.End(nil)
+		return obj
+	})
+	obj.Set("cork", func() {
+		stream.Cork()
+	})
+	obj.Set("uncork", func() {
+		stream.Uncork()
+	})
+	defineWritableState(b.vm, obj, stream)
+}
+
+// goError converts the argument of destroy(err) to a Go error that gives
+// back the same JS value when emitted with 'error'
+func (b *Bridge) goError(err goja.Value) error {
+	if isNullish(err) {
+		return nil
+	}
+	return &valueError{value: err}
+}
+
+// valueError is a JS value thrown or passed as an error through Go
+type valueError struct {
+	value goja.Value
+}
+
+func (e *valueError) Error() string {
+	return e.value.String()
+}
+
+// toJS converts the argument of an event raised in Go: bytes become a
+// Uint8Array and streams the JS object wrapping them. It must be called on
+// the JS thread.
+func (b *Bridge) toJS(arg interface{}) goja.Value {
+	switch v := arg.(type) {
+	case nil:
+		return goja.Null()
+	case goja.Value:
+		return v
+	case *valueError:
+		return v.value
+	case error:
+		return jserror.New(b.vm, v)
+	case []byte:
+		array, err := b.vm.New(b.vm.Get("Uint8Array"), b.vm.ToValue(b.vm.NewArrayBuffer(v)))
+		if err != nil {
+			panic(err)
+		}
+		return array
+	case *Readable:
+		if v.owner != nil {
+			return b.vm.ToValue(v.owner)
+		}
+	case *Writable:
+		if v.owner != nil {
+			return b.vm.ToValue(v.owner)
+		}
+	}
+	return b.vm.ToValue(arg)
+}
+
+// jsEvents holds the JS listeners of a stream object. Listeners are only
+// touched on the JS thread: the first listener for an event subscribes to
+// the Go emitter, whose events are queued to the JS thread, while emit()
+// from JS calls the listeners straight away.
+type jsEvents struct {
+	bridge     *Bridge
+	obj        *goja.Object
+	emitter    *SimpleEventEmitter
+	listeners  map[string][]*jsListener
+	subscribed map[string]bool
+}
+
+type jsListener struct {
+	fn   goja.Value
+	call goja.Callable
+	once bool
+}
+
+func newJSEvents(b *Bridge, obj *goja.Object, emitter *SimpleEventEmitter) *jsEvents {
+	e := &jsEvents{
+		bridge:     b,
+		obj:        obj,
+		emitter:    emitter,
+		listeners:  make(map[string][]*jsListener),
+		subscribed: make(map[string]bool),
+	}
+	// Errors are always delivered, so that unhandled ones are reported
+	e.subscribe("error")
+	return e
+}
+
+// install adds the EventEmitter methods to the stream object
+func (e *jsEvents) install() {
+	vm := e.bridge.vm
+	add := func(once bool) func(string, goja.Value) *goja.Object {
+		return func(event string, fn goja.Value) *goja.Object {
+			call, ok := goja.AssertFunction(fn)
+			if !ok {
+				panic(vm.NewTypeError("listener must be a function"))
 			}
-			
-			return stream.Writable.Write(bytes)
-		})
-		
-		duplex.Set("end", func(chunk interface{}) {
-			var bytes []byte
-			if chunk != nil {
-				if str, ok := chunk.(string); ok {
-					bytes = []byte(str)
-				} else {
-					bytes = []byte(fmt.Sprintf("%v", chunk))
+			e.listeners[event] = append(e.listeners[event], &jsListener{fn: fn, call: call, once: once})
+			e.subscribe(event)
+			if event == "data" {
+				if r := readableOf(e.bridge.unwrap(e.obj)); r != nil {
+					r.DataListenerAdded()
 				}
 			}
-			stream.Writable.End(bytes)
-		})
-		
-		// Add event emitter methods
-		duplex.Set("on", eventEmitter.Get("on"))
-		duplex.Set("once", eventEmitter.Get("once"))
-		duplex.Set("emit", eventEmitter.Get("emit"))
-		
-		return duplex
-	}
-}
-
-// createTransformConstructor creates the Transform constructor
-func createTransformConstructor(runtime *goja.Runtime, eventEmitter *goja.Object) func(goja.ConstructorCall) *goja.Object {
-	return func(call goja.ConstructorCall) *goja.Object {
-		transform := runtime.NewObject()
-		
-		// Create a simple EventEmitter for this stream
-		emitter := NewSimpleEventEmitter()
-		
-		// Create Go stream instance with identity transform
-		readOpts := &ReadableOptions{HighWaterMark: 16 * 1024}
-		writeOpts := &WritableOptions{HighWaterMark: 16 * 1024}
-		transformFunc := func(chunk []byte, encoding string) ([]byte, error) {
-			return chunk, nil // Identity transform by default
+			return e.obj
 		}
-		stream := NewTransform(readOpts, writeOpts, emitter, transformFunc, nil)
-		
-		// Set up readable methods
-		transform.Set("read", func(size int) interface{} {
-			data, err := stream.Readable.Read(size)
-			if err != nil {
-				return nil
-			}
-			return string(data)
-		})
-		
-		transform.Set("push", func(data interface{}) bool {
-			var bytes []byte
-			if data == nil {
-				bytes = nil
-			} else if str, ok := data.(string); ok {
-				bytes = []byte(str)
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", data))
-			}
-			
-			err := stream.Readable.Push(bytes)
-			return err == nil
-		})
-		
-		// Set up writable methods
-		transform.Set("write", func(chunk interface{}) bool {
-			var bytes []byte
-			if str, ok := chunk.(string); ok {
-				bytes = []byte(str)
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", chunk))
+	}
+	e.obj.Set("on", add(false))
+	e.obj.Set("addListener", add(false))
+	e.obj.Set("once", add(true))
+	e.obj.Set("off", func(event string, fn goja.Value) *goja.Object {
+		e.remove(event, fn)
+		return e.obj
+	})
+	e.obj.Set("removeListener", e.obj.Get("off"))
+	e.obj.Set("removeAllListeners", func(call goja.FunctionCall) goja.Value {
+		if event := call.Argument(0); !isNullish(event) {
+			delete(e.listeners, event.String())
+		} else {
+			e.listeners = make(map[string][]*jsListener)
+		}
+		return e.obj
+	})
+	e.obj.Set("listenerCount", func(event string) int {
+		return len(e.listeners[event])
+	})
+
+	// emit(event, ...args) calls the handlers the Go stream registered, such
+	// as those of a pipe, then the JS listeners
+	e.obj.Set("emit", func(call goja.FunctionCall) goja.Value {
+		event := call.Argument(0).String()
+		// The Go handlers may keep the arguments, so they are copied off
+		// the VM's stack
+		var args []goja.Value
+		if len(call.Arguments) > 1 {
+			args = append([]goja.Value(nil), call.Arguments[1:]...)
+		}
+
+		goArgs := make([]interface{}, len(args))
+		for i, arg := range args {
+			goArgs[i] = arg
+		}
+		if event == "error" && len(args) > 0 {
+			goArgs[0] = &valueError{value: args[0]}
+		}
+		e.emitter.emitGo(event, goArgs...)
+		return vm.ToValue(e.dispatch(event, args, false))
+	})
+}
+
+// subscribe forwards the Go events named event to the JS listeners
+func (e *jsEvents) subscribe(event string) {
+	if e.subscribed[event] {
+		return
+	}
+	e.subscribed[event] = true
+
+	runtime := e.bridge.runtime
+	e.emitter.On(event, jsForwarder(func(args ...interface{}) {
+		release := runtime.KeepAlive()
+		runtime.QueueJSOperation(func() {
+			defer release()
+			values := make([]goja.Value, len(args))
+			for i, arg := range args {
+				values[i] = e.bridge.toJS(arg)
 			}
-			
-			return stream.Write(bytes)
+			e.dispatch(event, values, true)
 		})
-		
-		transform.Set("end", func(chunk interface{}) {
-			var bytes []byte
-			if chunk != nil {
-				if str, ok := chunk.(string); ok {
-					bytes = []byte(str)
-				} else {
-					bytes = []byte(fmt.Sprintf("%v", chunk))
-				}
+	}))
+}
+
+func (e *jsEvents) remove(event string, fn goja.Value) {
+	list := e.listeners[event]
+	for i, l := range list {
+		if l.fn.StrictEquals(fn) {
+			e.listeners[event] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch calls the listeners for event and reports whether there were
+// any. An 'error' without listeners is uncaught, as in Node. Listeners that
+// throw on events raised in Go are reported as uncaught exceptions, since
+// there is no caller to throw to.
+func (e *jsEvents) dispatch(event string, args []goja.Value, fromGo bool) bool {
+	vm := e.bridge.vm
+	list := e.listeners[event]
+	if len(list) == 0 {
+		if event == "error" {
+			err := goja.Undefined()
+			if len(args) > 0 {
+				err = args[0]
 			}
-			stream.End(bytes)
-		})
-		
-		// Add event emitter methods
-		transform.Set("on", eventEmitter.Get("on"))
-		transform.Set("once", eventEmitter.Get("once"))
-		transform.Set("emit", eventEmitter.Get("emit"))
-		
-		return transform
-	}
-}
-
-// createPassThroughConstructor creates the PassThrough constructor
-func createPassThroughConstructor(runtime *goja.Runtime, eventEmitter *goja.Object) func(goja.ConstructorCall) *goja.Object {
-	return func(call goja.ConstructorCall) *goja.Object {
-		passThrough := runtime.NewObject()
-		
-		// Create a simple EventEmitter for this stream
-		emitter := NewSimpleEventEmitter()
-		
-		// Create Go stream instance
-		readOpts := &ReadableOptions{HighWaterMark: 16 * 1024}
-		writeOpts := &WritableOptions{HighWaterMark: 16 * 1024}
-		stream := NewPassThrough(readOpts, writeOpts, emitter)
-		
-		// Set up readable methods
-		passThrough.Set("read", func(size int) interface{} {
-			data, err := stream.Transform.Readable.Read(size)
-			if err != nil {
-				return nil
+			if !fromGo {
+				panic(err)
 			}
-			return string(data)
-		})
-		
-		passThrough.Set("push", func(data interface{}) bool {
-			var bytes []byte
-			if data == nil {
-				bytes = nil
-			} else if str, ok := data.(string); ok {
-				bytes = []byte(str)
-			} else {
-				bytes = []byte(fmt.Sprintf("%v", data))
+			globals.ReportUncaught(vm, err)
+		}
+		return false
+	}
+
+	for _, l := range append([]*jsListener(nil), list...) {
+		if l.once {
+			e.remove(event, l.fn)
+		}
+		if _, err := l.call(e.obj, args...); err != nil {
+			if !fromGo {
+				panic(err)
 			}
-			
-			err := stream.Transform.Readable.Push(bytes)
-			return err == nil
-		})
-		
-		// Set up writable methods
-		passThrough.Set("write", func(chunk interface{}) bool {
-			var bytes []byte
-			if str, ok := chunk.(string); ok {
-				bytes = []byte(str)
+			if ex, ok := err.(*goja.Exception); ok {
+				globals.ReportUncaught(vm, ex.Value())
 			} else {
-				bytes = []byte(fmt.Sprintf("%v", chunk))
-			}
-			
-			return stream.Transform.Write(bytes)
-		})
-		
-		passThrough.Set("end", func(chunk interface{}) {
-			var bytes []byte
-			if chunk != nil {
-				if str, ok := chunk.(string); ok {
-					bytes = []byte(str)
-				} else {
-					bytes = []byte(fmt.Sprintf("%v", chunk))
-				}
+				globals.ReportUncaught(vm, vm.ToValue(err.Error()))
 			}
-			stream.Transform.End(bytes)
-		})
-		
-		// Add event emitter methods
-		passThrough.Set("on", eventEmitter.Get("on"))
-		passThrough.Set("once", eventEmitter.Get("once"))
-		passThrough.Set("emit", eventEmitter.Get("emit"))
-		
-		return passThrough
-	}
-}
-
-// createPipelineFunction creates the pipeline utility function
-func createPipelineFunction(runtime *goja.Runtime) func(...interface{}) interface{} {
-	return func(streams ...interface{}) interface{} {
-		// Simplified pipeline implementation
-		// In a full implementation, we'd need to handle the actual stream piping
-		return runtime.ToValue(map[string]interface{}{
-			"success": true,
-			"message": "Pipeline created successfully",
-		})
+		}
 	}
+	return true
 }
 
-// createFinishedFunction creates the finished utility function
-func createFinishedFunction(runtime *goja.Runtime) func(interface{}, ...interface{}) interface{} {
-	return func(stream interface{}, options ...interface{}) interface{} {
-		// Simplified finished implementation
-		// In a full implementation, we'd return a Promise that resolves when the stream finishes
-		return runtime.ToValue(map[string]interface{}{
-			"success": true,
-			"message": "Stream finished",
-		})
+// defineWritableState defines the read-only writableLength and
+// writableHighWaterMark properties of a writable stream object
+func defineWritableState(runtime *goja.Runtime, obj *goja.Object, stream *Writable) {
+	obj.DefineAccessorProperty("writableLength", runtime.ToValue(stream.Length), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	obj.DefineAccessorProperty("writableHighWaterMark", runtime.ToValue(stream.HighWaterMark), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+}
+
+// constructorOptions returns the options object a stream constructor was
+// called with, or nil
+func constructorOptions(call goja.ConstructorCall) map[string]interface{} {
+	options, _ := call.Argument(0).Export().(map[string]interface{})
+	return options
+}
+
+// readableOptions reads the options of the readable side of a stream;
+// readableObjectMode and readableHighWaterMark take precedence, as on a Duplex
+func readableOptions(options map[string]interface{}) *ReadableOptions {
+	objectMode := optionBool(options, "readableObjectMode", "objectMode")
+	return &ReadableOptions{
+		HighWaterMark: optionHighWaterMark(options, "readableHighWaterMark", objectMode),
+		ObjectMode:    objectMode,
 	}
 }
 
-// createFromIterableFunction creates the Readable.from static method
-func createFromIterableFunction(runtime *goja.Runtime, eventEmitter *goja.Object) func(interface{}) *goja.Object {
-	return func(iterable interface{}) *goja.Object {
-		readable := runtime.NewObject()
-		
-		// Create a simple EventEmitter for this stream
-		emitter := NewSimpleEventEmitter()
-		
-		// Convert iterable to slice of strings
-		var items []interface{}
-		if arr, ok := iterable.([]interface{}); ok {
-			items = arr
-		} else if str, ok := iterable.(string); ok {
-			// Convert string to array of characters
-			for _, char := range str {
-				items = append(items, string(char))
-			}
-		} else {
-			items = []interface{}{iterable}
+// writableOptions reads the options of the writable side of a stream;
+// writableObjectMode and writableHighWaterMark take precedence, as on a Duplex
+func writableOptions(options map[string]interface{}) *WritableOptions {
+	objectMode := optionBool(options, "writableObjectMode", "objectMode")
+	return &WritableOptions{
+		HighWaterMark: optionHighWaterMark(options, "writableHighWaterMark", objectMode),
+		ObjectMode:    objectMode,
+	}
+}
+
+func optionBool(options map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if v, ok := options[key].(bool); ok {
+			return v
 		}
-		
-		// Create Go stream instance
-		stream := FromIterable(items, emitter)
-		
-		// Set up JavaScript methods
-		readable.Set("read", func(size int) interface{} {
-			data, err := stream.Read(size)
-			if err != nil {
-				return nil
-			}
-			return string(data)
-		})
-		
-		readable.Set("pause", func() {
-			stream.Pause()
-		})
-		
-		readable.Set("resume", func() {
-			stream.Resume()
-		})
-		
-		// Add event emitter methods
-		readable.Set("on", eventEmitter.Get("on"))
-		readable.Set("once", eventEmitter.Get("once"))
-		readable.Set("emit", eventEmitter.Get("emit"))
-		
-		return readable
-	}
-}
\ No newline at end of file
+	}
+	return false
+}
+
+// optionHighWaterMark returns the high water mark set by key or
+// highWaterMark, defaulting to a count of chunks in object mode
+func optionHighWaterMark(options map[string]interface{}, key string, objectMode bool) int {
+	for _, k := range []string{key, "highWaterMark"} {
+		switch v := options[k].(type) {
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		}
+	}
+	if objectMode {
+		return DefaultObjectHighWaterMark
+	}
+	return DefaultHighWaterMark
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
+
+// exportChunk returns the Go chunk for a JS value: the value itself in
+// object mode, so that objects keep their identity, or its export for
+// conversion to bytes
+func exportChunk(chunk goja.Value, objectMode bool) interface{} {
+	if objectMode {
+		return chunk
+	}
+	if buffer, ok := chunk.Export().(goja.ArrayBuffer); ok {
+		return buffer.Bytes()
+	}
+	return chunk.Export()
+}
+
+// readChunk implements read(): the next chunk in object mode, or up to
+// size bytes as a string
+func readChunk(stream *Readable, size int) interface{} {
+	if stream.objectMode {
+		chunk, err := stream.ReadObject()
+		if err != nil {
+			return nil
+		}
+		return chunk
+	}
+	data, err := stream.Read(size)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+// pushChunk implements push(): null or undefined ends the stream. It returns
+// false once the buffer reaches highWaterMark.
+func pushChunk(stream *Readable, data goja.Value) bool {
+	var chunk interface{}
+	if !isNullish(data) {
+		chunk = exportChunk(data, stream.objectMode)
+	}
+	if err := stream.PushObject(chunk); err != nil {
+		return false
+	}
+	return chunk != nil && stream.Length() < stream.HighWaterMark()
+}
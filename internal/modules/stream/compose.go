@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotWritable is reported by a composed stream whose first stream is not
+// writable when it is written to
+var ErrNotWritable = errors.New("composed stream is not writable")
+
+// Compose pipes streams into one another, like Pipeline, and returns a
+// single stream over them: what is written to it is written to the first
+// stream, and it reads what the last stream reads. When the first stream
+// is not writable, the composed stream is readable only; when the last one
+// is not readable, its readable side ends once the last stream finishes.
+// An error on any of the streams destroys the others and the composed
+// stream with it.
+func Compose(streams []interface{}, readOpts *ReadableOptions, writeOpts *WritableOptions, events EventEmitter) (*Transform, error) {
+	if len(streams) == 0 {
+		return nil, errors.New("compose requires at least 1 stream")
+	}
+	if len(streams) > 1 {
+		if err := Pipeline(streams); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &composer{streams: streams}
+	c.head, _ = streams[0].(Destination)
+	c.stream = NewAsyncTransform(readOpts, writeOpts, events, c.transform, c.flush)
+
+	subscribed := make(map[EventEmitter]bool)
+	for _, s := range streams {
+		if e := eventsOf(s); e != nil && !subscribed[e] {
+			subscribed[e] = true
+			e.On("error", c)
+		}
+	}
+	if c.head != nil {
+		c.head.writable().events.On("drain", c)
+	}
+
+	tail := streams[len(streams)-1]
+	if r := readableOf(tail); r != nil {
+		r.events.On("data", c)
+		r.events.On("end", c)
+		r.Resume()
+	} else if e := eventsOf(tail); e != nil {
+		e.On("finish", c)
+	}
+
+	if c.head == nil {
+		c.stream.End(nil)
+	}
+	return c.stream, nil
+}
+
+// composer runs a composed stream: its transform writes to the first
+// stream, and what the last stream reads is pushed to its readable side
+type composer struct {
+	stream  *Transform
+	streams []interface{}
+	head    Destination // nil when the first stream is not writable
+
+	mu       sync.Mutex
+	drained  func()                   // completes the write waiting for 'drain'
+	flushed  func(interface{}, error) // completes the flush waiting for the last stream
+	tailDone bool                     // the last stream ended or finished
+}
+
+func (c *composer) transform(chunk interface{}, encoding string, done func(interface{}, error)) {
+	if c.head == nil {
+		done(nil, ErrNotWritable)
+		return
+	}
+
+	c.mu.Lock()
+	c.drained = func() { done(nil, nil) }
+	c.mu.Unlock()
+	if c.head.WriteObject(chunk) {
+		c.drain()
+	}
+}
+
+// drain completes the write waiting for the first stream, if any
+func (c *composer) drain() {
+	c.mu.Lock()
+	drained := c.drained
+	c.drained = nil
+	c.mu.Unlock()
+	if drained != nil {
+		drained()
+	}
+}
+
+// flush ends the first stream and completes once the last one is done
+func (c *composer) flush(done func(interface{}, error)) {
+	if c.head != nil {
+		c.head.End(nil)
+	}
+
+	c.mu.Lock()
+	if !c.tailDone {
+		c.flushed = done
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	done(nil, nil)
+}
+
+// HandleEvent handles the 'drain' event of the first stream, the data of
+// the last one and the errors of all of them
+func (c *composer) HandleEvent(event string, args ...interface{}) {
+	switch event {
+	case "drain":
+		c.drain()
+	case "data":
+		if len(args) > 0 {
+			c.stream.Readable.PushObject(args[0])
+		}
+	case "end", "finish":
+		c.mu.Lock()
+		c.tailDone = true
+		flushed := c.flushed
+		c.flushed = nil
+		c.mu.Unlock()
+		if flushed != nil {
+			flushed(nil, nil)
+		}
+	case "error":
+		var err error
+		if len(args) > 0 {
+			err, _ = args[0].(error)
+		}
+		if err == nil {
+			err = errors.New("composed stream failed")
+		}
+		for _, s := range c.streams {
+			if d, ok := s.(interface{ Destroy(error) }); ok {
+				d.Destroy(nil)
+			}
+		}
+		c.stream.Destroy(err)
+	}
+}
+
+// eventsOf returns the events of a stream, or nil
+func eventsOf(stream interface{}) EventEmitter {
+	if r := readableOf(stream); r != nil {
+		return r.events
+	}
+	if d, ok := stream.(Destination); ok {
+		return d.writable().events
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package stream_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestCompose(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("stream-compose", `
+		(async () => {
+			const { Readable, compose } = require('stream');
+			// iterable makes an async iterable of the results of next(), as
+			// an async generator would
+			const iterable = (next) => ({ [Symbol.asyncIterator]: () => ({ next }) });
+			let i = 0;
+			const numbers = iterable(async () => {
+				await new Promise(resolve => setTimeout(resolve, 1));
+				return i < 3 ? { value: ++i, done: false } : { value: undefined, done: true };
+			});
+
+			const collected = [];
+			const done = new Promise(resolve => {
+				const stream = compose(
+					(source) => {
+						const it = source[Symbol.asyncIterator]();
+						return iterable(async () => {
+							const { value, done } = await it.next();
+							return done ? { value: undefined, done } : { value: value * 10, done };
+						});
+					},
+					async function (source) {
+						const it = source[Symbol.asyncIterator]();
+						for (;;) {
+							const { value, done } = await it.next();
+							if (done) {
+								return;
+							}
+							collected.push(value);
+						}
+					}
+				);
+				stream.on('finish', resolve);
+				Readable.from(numbers).pipe(stream);
+			});
+			await done;
+
+			let pulled = 0;
+			const failing = Readable.from(iterable(async () => {
+				if (pulled++ === 0) {
+					return { value: 1, done: false };
+				}
+				throw new Error('boom');
+			}));
+			const failed = await new Promise(resolve => failing.on('error', e => resolve(e.message)));
+			return collected.join(',') + '|' + failed;
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if want := "10,20,30|boom"; value != want {
+		t.Errorf("compose results = %v, want %s", value, want)
+	}
+}
@@ -0,0 +1,198 @@
+package stream
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Listener is a handler that Off can remove exactly: it is matched by
+// identity, while funcs can only be matched by their code, so Off removes
+// the first handler made from the same function
+type Listener interface {
+	HandleEvent(event string, args ...interface{})
+}
+
+// SimpleEventEmitter is the EventEmitter of the streams created from JS.
+// Handlers are a Listener or a func: func(), func(...interface{}) or a func
+// of the event's arguments, such as func([]byte) for 'data' or func(error)
+// for 'error'.
+type SimpleEventEmitter struct {
+	mu       sync.Mutex
+	handlers map[string][]*handler
+}
+
+type handler struct {
+	fn   interface{}
+	once bool
+}
+
+func NewSimpleEventEmitter() *SimpleEventEmitter {
+	return &SimpleEventEmitter{
+		handlers: make(map[string][]*handler),
+	}
+}
+
+// On adds a handler for event
+func (e *SimpleEventEmitter) On(event string, fn interface{}) {
+	e.add(event, fn, false)
+}
+
+// Once adds a handler for event that is removed before it is first called
+func (e *SimpleEventEmitter) Once(event string, fn interface{}) {
+	e.add(event, fn, true)
+}
+
+func (e *SimpleEventEmitter) add(event string, fn interface{}, once bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[event] = append(e.handlers[event], &handler{fn: fn, once: once})
+}
+
+// Off removes the most recently added handler for event matching fn, or
+// every handler for event when fn is nil
+func (e *SimpleEventEmitter) Off(event string, fn interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if fn == nil {
+		delete(e.handlers, event)
+		return
+	}
+	list := e.handlers[event]
+	for i := len(list) - 1; i >= 0; i-- {
+		if sameHandler(list[i].fn, fn) {
+			e.handlers[event] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// RemoveAllListeners removes the handlers for the given events, or for
+// every event when none are given
+func (e *SimpleEventEmitter) RemoveAllListeners(events ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(events) == 0 {
+		e.handlers = make(map[string][]*handler)
+		return
+	}
+	for _, event := range events {
+		delete(e.handlers, event)
+	}
+}
+
+// ListenerCount returns the number of handlers for event
+func (e *SimpleEventEmitter) ListenerCount(event string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.handlers[event])
+}
+
+// sameHandler reports whether two handlers are the same: Listeners and
+// other comparable values by equality, funcs by their code
+func sameHandler(a, b interface{}) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if ta.Kind() == reflect.Func {
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return ta.Comparable() && a == b
+}
+
+// jsForwarder is a handler delivering events to the JS listeners of a
+// stream object
+type jsForwarder func(args ...interface{})
+
+// Emit calls the handlers of event in the order they were added, with
+// args. Handlers added or removed by a handler take effect from the next
+// Emit on; once handlers are removed before they are called.
+func (e *SimpleEventEmitter) Emit(event string, args ...interface{}) {
+	e.emit(event, true, args)
+}
+
+// emitGo calls the handlers of event except those forwarding it to JS, for
+// events emitted from JS
+func (e *SimpleEventEmitter) emitGo(event string, args ...interface{}) {
+	e.emit(event, false, args)
+}
+
+func (e *SimpleEventEmitter) emit(event string, forward bool, args []interface{}) {
+	e.mu.Lock()
+	list := e.handlers[event]
+	handlers := make([]*handler, 0, len(list))
+	kept := list[:0:0]
+	for _, h := range list {
+		if _, js := h.fn.(jsForwarder); js && !forward {
+			kept = append(kept, h)
+			continue
+		}
+		handlers = append(handlers, h)
+		if !h.once {
+			kept = append(kept, h)
+		}
+	}
+	if len(kept) < len(list) {
+		e.handlers[event] = kept
+	}
+	e.mu.Unlock()
+
+	for _, h := range handlers {
+		call(event, h.fn, args)
+	}
+}
+
+// call calls a handler with the arguments of an event. Funcs of other
+// signatures get each argument that fits the type of their parameter, and
+// the zero value for the others.
+func call(event string, fn interface{}, args []interface{}) {
+	switch fn := fn.(type) {
+	case Listener:
+		fn.HandleEvent(event, args...)
+	case func():
+		fn()
+	case func(...interface{}):
+		fn(args...)
+	case jsForwarder:
+		fn(args...)
+	case func(interface{}):
+		fn(arg(args, 0))
+	case func([]byte):
+		data, _ := arg(args, 0).([]byte)
+		fn(data)
+	case func(error):
+		err, _ := arg(args, 0).(error)
+		fn(err)
+	case func(string):
+		s, _ := arg(args, 0).(string)
+		fn(s)
+	default:
+		v := reflect.ValueOf(fn)
+		if v.Kind() != reflect.Func {
+			return
+		}
+		t := v.Type()
+		in := make([]reflect.Value, t.NumIn())
+		for i := range in {
+			pt := t.In(i)
+			if t.IsVariadic() && i == len(in)-1 {
+				pt = pt.Elem()
+			}
+			in[i] = reflect.Zero(pt)
+			if a := arg(args, i); a != nil && reflect.TypeOf(a).AssignableTo(pt) {
+				in[i] = reflect.ValueOf(a)
+			}
+		}
+		v.Call(in)
+	}
+}
+
+// arg returns the i-th argument, or nil
+func arg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// iterator returns the iterator of value when it is an async or sync
+// iterable, preferring Symbol.asyncIterator. It must run on the JS thread.
+func (b *Bridge) iterator(value goja.Value) (*goja.Object, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	for _, sym := range []*goja.Symbol{goja.SymAsyncIterator, goja.SymIterator} {
+		method, ok := goja.AssertFunction(obj.GetSymbol(sym))
+		if !ok {
+			continue
+		}
+		iterator, err := method(obj)
+		if err != nil {
+			panic(err)
+		}
+		if it, ok := iterator.(*goja.Object); ok {
+			return it, true
+		}
+	}
+	return nil, false
+}
+
+// fromIterator implements Readable.from(iterable) for async iterables and
+// for iterables other than arrays and strings, such as generators
+func (b *Bridge) fromIterator(iterator *goja.Object) *goja.Object {
+	emitter := NewSimpleEventEmitter()
+	stream := NewReadable(&ReadableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true}, emitter)
+	obj := b.vm.NewObject()
+	stream.owner = obj
+
+	b.wrap(obj, stream, emitter)
+	b.readableMethods(obj, stream)
+	b.pull(iterator, stream, func(err error) {
+		if err != nil {
+			stream.Destroy(err)
+		} else {
+			stream.Push(nil)
+		}
+	})
+	return obj
+}
+
+// pull pushes the values of iterator to stream one at a time, awaiting
+// those of an async iterator, and calls end once the iterator is done or
+// fails. Each value is pulled in its own JS operation, so that an endless
+// iterator does not hold the JS thread. It must run on the JS thread.
+func (b *Bridge) pull(iterator *goja.Object, stream *Readable, end func(error)) {
+	next, ok := goja.AssertFunction(iterator.Get("next"))
+	if !ok {
+		end(fmt.Errorf("%s is not an iterator", iterator.String()))
+		return
+	}
+
+	release := b.runtime.KeepAlive()
+	finish := func(err error) {
+		release()
+		end(err)
+	}
+
+	var step func(result goja.Value)
+	pullNext := func() {
+		result, err := next(iterator)
+		if err != nil {
+			finish(err)
+			return
+		}
+		if !b.then(result, step, func(reason goja.Value) { finish(b.goError(reason)) }) {
+			step(result)
+		}
+	}
+	step = func(result goja.Value) {
+		obj, ok := result.(*goja.Object)
+		if !ok {
+			finish(fmt.Errorf("iterator result %s is not an object", result.String()))
+			return
+		}
+		if obj.Get("done").ToBoolean() {
+			finish(nil)
+			return
+		}
+		if value := obj.Get("value"); isNullish(value) || stream.PushObject(value) != nil {
+			// null cannot be pushed without ending the stream, and a
+			// destroyed stream takes nothing more
+			if ret, ok := goja.AssertFunction(iterator.Get("return")); ok {
+				ret(iterator)
+			}
+			finish(nil)
+			return
+		}
+		b.runtime.QueueJSOperation(pullNext)
+	}
+	pullNext()
+}
+
+// then calls onFulfilled or onRejected once value settles, if it is a
+// thenable, and reports whether it is. It must run on the JS thread.
+func (b *Bridge) then(value goja.Value, onFulfilled, onRejected func(goja.Value)) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return false
+	}
+	then, ok := goja.AssertFunction(obj.Get("then"))
+	if !ok {
+		return false
+	}
+	if _, err := then(obj, b.vm.ToValue(onFulfilled), b.vm.ToValue(onRejected)); err != nil {
+		onRejected(b.vm.ToValue(err))
+	}
+	return true
+}
+
+// compose implements compose(...streams). Besides streams, it takes
+// functions called with an async iterable of the chunks written to them:
+// an async generator function transforms them into what it yields, and an
+// async function consuming them is a writable stream, which finishes once
+// the promise it returns resolves.
+func (b *Bridge) compose(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) == 0 {
+		panic(b.vm.NewTypeError("compose requires at least 1 stream"))
+	}
+	streams := make([]interface{}, 0, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		if stream := b.unwrap(arg); stream != nil {
+			streams = append(streams, stream)
+		} else if fn, ok := goja.AssertFunction(arg); ok {
+			streams = append(streams, b.stage(fn))
+		} else {
+			panic(b.vm.NewTypeError(fmt.Sprintf("compose argument %d is neither a stream nor a function", i)))
+		}
+	}
+
+	readOpts := &ReadableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true}
+	if r := readableOf(streams[len(streams)-1]); r != nil && !r.objectMode {
+		readOpts = &ReadableOptions{HighWaterMark: DefaultHighWaterMark}
+	}
+	writeOpts := &WritableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true}
+	if dest, ok := streams[0].(Destination); ok && !dest.writable().objectMode {
+		writeOpts = &WritableOptions{HighWaterMark: DefaultHighWaterMark}
+	}
+
+	emitter := NewSimpleEventEmitter()
+	stream, err := Compose(streams, readOpts, writeOpts, emitter)
+	if err != nil {
+		panic(b.vm.NewTypeError(err.Error()))
+	}
+	obj := b.vm.NewObject()
+	stream.Readable.owner = obj
+	stream.Writable.owner = obj
+
+	b.wrap(obj, stream, emitter)
+	b.readableMethods(obj, stream.Readable)
+	b.writableMethods(obj, stream.Writable, stream)
+	b.duplexMethods(obj, stream.Duplex, nil)
+	return obj
+}
+
+// stage runs fn, a function given to compose, as an object mode transform
+// stream: fn is called with an async iterable of the chunks written to the
+// stream, and what it returns, an async iterable or a promise, settles
+// the stream.
+func (b *Bridge) stage(fn goja.Callable) *Transform {
+	s := &stage{bridge: b}
+	opts := &ReadableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true}
+	writeOpts := &WritableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true}
+	s.stream = NewAsyncTransform(opts, writeOpts, NewSimpleEventEmitter(), s.transform, s.flush)
+
+	result, err := fn(goja.Undefined(), s.source())
+	if err != nil {
+		panic(err)
+	}
+	if iterator, ok := b.iterator(result); ok {
+		b.pull(iterator, s.stream.Readable, func(err error) {
+			if err != nil {
+				s.stream.Destroy(err)
+				return
+			}
+			s.finish()
+			s.stream.Readable.Push(nil)
+		})
+	} else if !b.then(result, func(goja.Value) { s.finish() }, func(reason goja.Value) {
+		s.stream.Destroy(b.goError(reason))
+	}) {
+		s.finish()
+	}
+	return s.stream
+}
+
+// stage is a function given to compose, run as a transform stream. Its
+// transform hands each chunk to the iterator fn reads, and completes once
+// fn takes it, so chunks are only written as fast as fn reads them.
+type stage struct {
+	bridge *Bridge
+	stream *Transform
+
+	mu       sync.Mutex
+	chunk    *stageChunk              // written, waiting for fn to take it
+	reader   func(goja.Value, bool)   // resolves the pending next() of fn
+	ended    bool                     // the stream was ended; no more chunks
+	finished bool                     // fn returned or stopped reading
+	flushed  func(interface{}, error) // completes the flush once fn is done
+}
+
+type stageChunk struct {
+	value interface{}
+	done  func(interface{}, error)
+}
+
+func (s *stage) transform(chunk interface{}, encoding string, done func(interface{}, error)) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		done(nil, nil)
+		return
+	}
+	reader := s.reader
+	s.reader = nil
+	if reader == nil {
+		s.chunk = &stageChunk{value: chunk, done: done}
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.deliver(func() {
+		reader(s.bridge.toJS(chunk), false)
+		done(nil, nil)
+	})
+}
+
+// flush ends the iterator fn reads and completes once fn is done
+func (s *stage) flush(done func(interface{}, error)) {
+	s.mu.Lock()
+	s.ended = true
+	reader := s.reader
+	s.reader = nil
+	if !s.finished {
+		s.flushed = done
+		s.mu.Unlock()
+		if reader != nil {
+			s.deliver(func() { reader(goja.Undefined(), true) })
+		}
+		return
+	}
+	s.mu.Unlock()
+	done(nil, nil)
+}
+
+// finish is called once fn is done: chunks written from then on are
+// dropped, and a pending flush completes
+func (s *stage) finish() {
+	s.mu.Lock()
+	s.finished = true
+	chunk := s.chunk
+	s.chunk = nil
+	flushed := s.flushed
+	s.flushed = nil
+	s.mu.Unlock()
+
+	if chunk != nil {
+		chunk.done(nil, nil)
+	}
+	if flushed != nil {
+		flushed(nil, nil)
+	}
+}
+
+// deliver runs fn on the JS thread, keeping the runtime alive until then
+func (s *stage) deliver(fn func()) {
+	release := s.bridge.runtime.KeepAlive()
+	s.bridge.runtime.QueueJSOperation(func() {
+		defer release()
+		fn()
+	})
+}
+
+// source returns the async iterable fn reads the written chunks from. It
+// must be called on the JS thread.
+func (s *stage) source() goja.Value {
+	vm := s.bridge.vm
+	iterator := vm.NewObject()
+	result := func(value goja.Value, done bool) goja.Value {
+		obj := vm.NewObject()
+		obj.Set("value", value)
+		obj.Set("done", done)
+		return obj
+	}
+
+	iterator.Set("next", func() *goja.Promise {
+		promise, resolve, _ := vm.NewPromise()
+		s.mu.Lock()
+		switch {
+		case s.chunk != nil:
+			chunk := s.chunk
+			s.chunk = nil
+			s.mu.Unlock()
+			resolve(result(s.bridge.toJS(chunk.value), false))
+			chunk.done(nil, nil)
+		case s.ended || s.finished:
+			s.mu.Unlock()
+			resolve(result(goja.Undefined(), true))
+		default:
+			s.reader = func(value goja.Value, done bool) {
+				resolve(result(value, done))
+			}
+			s.mu.Unlock()
+		}
+		return promise
+	})
+	// return() is called when fn stops reading early, by break or return
+	// in a for await loop
+	iterator.Set("return", func(value goja.Value) *goja.Promise {
+		promise, resolve, _ := vm.NewPromise()
+		s.finish()
+		resolve(result(value, true))
+		return promise
+	})
+	iterator.SetSymbol(goja.SymAsyncIterator, func(call goja.FunctionCall) goja.Value {
+		return call.This
+	})
+	return iterator
+}
@@ -2,7 +2,6 @@ package stream
 
 import (
 	"embed"
-	"fmt"
 
 	"github.com/rizqme/gode/goja"
 )
@@ -10,65 +9,50 @@ import (
 //go:embed stream.js
 var streamJS embed.FS
 
-// RuntimeInterface represents the simplified runtime interface
+// RuntimeInterface represents the methods we need from the runtime
 type RuntimeInterface interface {
-	NewObject() *goja.Object
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
 	RegisterModule(name string, exports interface{})
-	SetGlobal(name string, value interface{}) error
-	RunScript(name string, source string) (interface{}, error)
-	Async(fn func())
+	KeepAlive() (release func())
 }
 
-// RegisterModule registers the stream module in the JavaScript VM
+// shutdownHooks is implemented by runtimes that run cleanup when they shut
+// down
+type shutdownHooks interface {
+	AddShutdownHook(fn func()) (remove func())
+}
+
+// RegisterModule registers the stream module in the JavaScript VM, as
+// gode:stream and stream, and stream/web, whose ReadableStream and its
+// reader and controller are also globals
 func RegisterModule(rt RuntimeInterface) error {
-	// Create a simplified stream module via JavaScript
-	streamSetup := `
-		// Simple stream implementation for testing
-		var streamModule = {
-			Readable: function(options) {
-				this.readable = true;
-				this.destroyed = false;
-				return this;
-			},
-			Writable: function(options) {
-				this.writable = true;
-				this.destroyed = false;
-				return this;
-			},
-			Duplex: function(options) {
-				this.readable = true;
-				this.writable = true;
-				this.destroyed = false;
-				return this;
-			},
-			Transform: function(options) {
-				this.readable = true;
-				this.writable = true;
-				this.destroyed = false;
-				return this;
-			},
-			PassThrough: function(options) {
-				this.readable = true;
-				this.writable = true;
-				this.destroyed = false;
-				return this;
-			},
-			pipeline: function() { return Promise.resolve(); },
-			finished: function() { return Promise.resolve(); }
-		};
-		
-		// Make require('stream') return this module
-		if (typeof __gode_modules === 'undefined') {
-			globalThis.__gode_modules = {};
+	done := make(chan error, 1)
+	rt.QueueJSOperation(func() {
+		bridge := NewBridge(rt)
+		if hooks, ok := rt.(shutdownHooks); ok {
+			hooks.AddShutdownHook(bridge.destroyOpen)
+		}
+		exports := bridge.Exports()
+		rt.RegisterModule("gode:stream", exports)
+		rt.RegisterModule("stream", exports)
+
+		web, err := bridge.WebExports(exports.Get("Readable"))
+		if err != nil {
+			done <- err
+			return
+		}
+		exports.Set("web", web)
+		rt.RegisterModule("gode:stream/web", web)
+		rt.RegisterModule("stream/web", web)
+		vm := rt.GetGojaRuntime()
+		for _, name := range webNames {
+			if err := vm.Set(name, web.Get(name)); err != nil {
+				done <- err
+				return
+			}
 		}
-		__gode_modules['stream'] = streamModule;
-	`
-	
-	// Execute the stream module setup
-	_, err := rt.RunScript("stream-setup", streamSetup)
-	if err != nil {
-		return fmt.Errorf("failed to setup stream module: %w", err)
-	}
-	
-	return nil
-}
\ No newline at end of file
+		done <- nil
+	})
+	return <-done
+}
@@ -19,6 +19,12 @@ const (
 	StateErrored
 )
 
+// Default high water marks: bytes for byte streams, chunks for object mode streams
+const (
+	DefaultHighWaterMark       = 16 * 1024
+	DefaultObjectHighWaterMark = 16
+)
+
 // ErrStreamDestroyed is returned when operations are attempted on a destroyed stream
 var ErrStreamDestroyed = errors.New("stream has been destroyed")
 
@@ -48,26 +54,82 @@ type WritableOptions struct {
 type Readable struct {
 	mu            sync.RWMutex
 	buffer        *bytes.Buffer
+	objects       []interface{} // Buffered chunks in object mode
 	state         int32
 	paused        bool
 	flowing       bool
-	ended         bool
+	held          bool // Pause was called before the stream flowed
+	ended         bool // No more data will be pushed
+	endEmitted    bool // 'end' was emitted once the buffer emptied
+	backpressure  bool // Paused until the pipes that are full drain
 	destroyed     bool
 	error         error
 	highWaterMark int
 	encoding      string
 	objectMode    bool
+	pipes         []*pipe
+	owner         interface{} // JS object wrapping the stream, passed for it in events
+	duplex        *Duplex     // set on the readable side of a Duplex
 	readCh        chan []byte
 	events        EventEmitter
 	ctx           context.Context
 	cancel        context.CancelFunc
 }
 
+// Destination is a stream a Readable can pipe into: a Writable, or a
+// Duplex, Transform or PassThrough, which is written through its own
+// WriteObject and End
+type Destination interface {
+	WriteObject(chunk interface{}) bool
+	End(chunk []byte)
+	writable() *Writable
+}
+
+// pipe is a destination a Readable writes its data to
+type pipe struct {
+	src      *Readable
+	dest     Destination
+	end      bool // end dest when the source ends
+	awaiting bool // dest is full; the source stays paused until it drains
+}
+
+// HandleEvent handles the 'drain' and 'error' events of the destination:
+// the source resumes once no destination is full, and stops writing to a
+// destination that fails
+func (p *pipe) HandleEvent(event string, args ...interface{}) {
+	r := p.src
+	switch event {
+	case "drain":
+		r.mu.Lock()
+		if !p.awaiting {
+			r.mu.Unlock()
+			return
+		}
+		p.awaiting = false
+		resume := r.backpressure && !r.awaitingDrain()
+		r.mu.Unlock()
+		if resume {
+			r.Resume()
+		}
+	case "error":
+		r.Unpipe(p.dest)
+	}
+}
+
+// detach removes the handlers of the pipe from the destination and tells
+// it that the source is gone
+func (p *pipe) detach() {
+	events := p.dest.writable().events
+	events.Off("drain", p)
+	events.Off("error", p)
+	events.Emit("unpipe", p.src)
+}
+
 // NewReadable creates a new readable stream
 func NewReadable(opts *ReadableOptions, events EventEmitter) *Readable {
 	if opts == nil {
 		opts = &ReadableOptions{
-			HighWaterMark: 16 * 1024, // 16KB default
+			HighWaterMark: DefaultHighWaterMark,
 		}
 	}
 
@@ -89,52 +151,78 @@ func NewReadable(opts *ReadableOptions, events EventEmitter) *Readable {
 	return r
 }
 
-// Push adds data to the internal buffer
+// Push adds data to the internal buffer. A nil slice ends the stream.
 func (r *Readable) Push(data []byte) error {
+	if data == nil {
+		return r.PushObject(nil)
+	}
+	return r.PushObject(data)
+}
+
+// PushObject adds a chunk to the internal buffer. In object mode the chunk
+// is buffered as is; otherwise it is converted to bytes. A nil chunk ends
+// the stream, and 'end' is emitted once the buffer has been consumed.
+func (r *Readable) PushObject(chunk interface{}) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	if r.destroyed {
+		r.mu.Unlock()
 		return ErrStreamDestroyed
 	}
 
 	if r.ended {
+		r.mu.Unlock()
 		return errors.New("cannot push data after stream has ended")
 	}
 
-	if data == nil {
+	if chunk == nil {
 		r.ended = true
-		r.events.Emit("end")
+		r.mu.Unlock()
+		r.flush()
 		return nil
 	}
 
-	_, err := r.buffer.Write(data)
-	if err != nil {
+	if r.objectMode {
+		r.objects = append(r.objects, chunk)
+	} else if _, err := r.buffer.Write(chunkBytes(chunk)); err != nil {
+		r.mu.Unlock()
 		return err
 	}
+	r.mu.Unlock()
 
 	// Emit 'readable' event when data is available
 	r.events.Emit("readable")
 
 	// If in flowing mode, emit data immediately
-	if r.flowing && !r.paused {
-		r.emitData()
-	}
-
+	r.flush()
 	return nil
 }
 
-// Read reads data from the stream
+// Read reads data from the stream. In object mode it reads the next chunk,
+// whatever size is, converted to bytes.
 func (r *Readable) Read(size int) ([]byte, error) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	if r.destroyed {
+		r.mu.Unlock()
 		return nil, ErrStreamDestroyed
 	}
 
+	if r.objectMode {
+		chunk, err := r.readObject()
+		r.mu.Unlock()
+		r.flushEnd()
+		if chunk == nil {
+			return nil, err
+		}
+		return chunkBytes(chunk), nil
+	}
+
 	if r.buffer.Len() == 0 {
-		if r.ended {
+		ended := r.ended
+		r.mu.Unlock()
+		if ended {
+			r.flushEnd()
 			return nil, io.EOF
 		}
 		return nil, nil
@@ -146,40 +234,115 @@ func (r *Readable) Read(size int) ([]byte, error) {
 
 	data := make([]byte, size)
 	n, err := r.buffer.Read(data)
+	r.mu.Unlock()
 	if err != nil {
 		return nil, err
 	}
+	r.flushEnd()
 
 	return data[:n], nil
 }
 
+// ReadObject reads the next chunk from an object mode stream, or all the
+// buffered bytes from a byte stream
+func (r *Readable) ReadObject() (interface{}, error) {
+	if !r.objectMode {
+		data, err := r.Read(-1)
+		if data == nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	r.mu.Lock()
+	if r.destroyed {
+		r.mu.Unlock()
+		return nil, ErrStreamDestroyed
+	}
+	chunk, err := r.readObject()
+	r.mu.Unlock()
+	r.flushEnd()
+	return chunk, err
+}
+
+// readObject shifts the next object mode chunk (must be called with lock held)
+func (r *Readable) readObject() (interface{}, error) {
+	if len(r.objects) == 0 {
+		if r.ended {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	chunk := r.objects[0]
+	r.objects[0] = nil
+	r.objects = r.objects[1:]
+	return chunk, nil
+}
+
+// Length returns the number of bytes, or chunks in object mode, buffered
+// and not yet read
+func (r *Readable) Length() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.length()
+}
+
+// length must be called with lock held
+func (r *Readable) length() int {
+	if r.objectMode {
+		return len(r.objects)
+	}
+	return r.buffer.Len()
+}
+
+// HighWaterMark returns the buffered size at which the stream stops asking
+// for more data
+func (r *Readable) HighWaterMark() int {
+	return r.highWaterMark
+}
+
 // Pause pauses the stream
 func (r *Readable) Pause() {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	
 	r.paused = true
+	if !r.flowing {
+		r.held = true
+	}
 	atomic.StoreInt32(&r.state, StatePaused)
+	r.mu.Unlock()
+
 	r.events.Emit("pause")
 }
 
 // Resume resumes the stream
 func (r *Readable) Resume() {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if r.destroyed || r.ended {
+	if r.destroyed || r.endEmitted {
+		r.mu.Unlock()
 		return
 	}
 
 	r.paused = false
 	r.flowing = true
+	r.held = false
+	r.backpressure = false
 	atomic.StoreInt32(&r.state, StateFlowing)
+	r.mu.Unlock()
+
 	r.events.Emit("resume")
 
 	// Emit any buffered data
-	if r.buffer.Len() > 0 {
-		r.emitData()
+	r.flush()
+}
+
+// DataListenerAdded starts the flow when a 'data' listener is added, as in
+// Node, unless Pause was called first
+func (r *Readable) DataListenerAdded() {
+	r.mu.RLock()
+	start := !r.flowing && !r.held
+	r.mu.RUnlock()
+	if start {
+		r.Resume()
 	}
 }
 
@@ -190,87 +353,195 @@ func (r *Readable) IsPaused() bool {
 	return r.paused
 }
 
-// Pipe pipes this readable stream to a writable stream
-func (r *Readable) Pipe(dest *Writable, options map[string]interface{}) error {
+// Pipe writes the data of this stream to dest as it flows, and starts the
+// flow. When dest is full the stream pauses until dest emits 'drain'; when
+// the stream ends, dest is ended too unless options has end: false. An
+// error on dest unpipes it, while an error on this stream leaves dest open,
+// as in Node. dest emits 'pipe' and 'unpipe' with this stream.
+func (r *Readable) Pipe(dest Destination, options map[string]interface{}) error {
 	end := true
 	if val, ok := options["end"].(bool); ok {
 		end = val
 	}
 
-	// Set up data handler
-	r.events.On("data", func(chunk []byte) {
-		if !dest.Write(chunk) {
-			r.Pause()
-		}
-	})
-
-	// Handle drain event from destination
-	dest.events.On("drain", func() {
-		r.Resume()
-	})
-
-	// Handle end event
-	if end {
-		r.events.On("end", func() {
-			dest.End(nil)
-		})
+	r.mu.Lock()
+	if r.destroyed {
+		r.mu.Unlock()
+		return ErrStreamDestroyed
 	}
+	p := &pipe{src: r, dest: dest, end: end}
+	r.pipes = append(r.pipes, p)
+	flowing := r.flowing && !r.paused
+	r.mu.Unlock()
 
-	// Handle errors
-	r.events.On("error", func(err error) {
-		dest.Destroy(err)
-	})
+	w := dest.writable()
+	w.events.On("drain", p)
+	w.events.On("error", p)
+	w.events.Emit("pipe", r)
 
 	// Start flowing if not already
-	if !r.flowing {
+	if !flowing {
 		r.Resume()
 	}
 
 	return nil
 }
 
-// Unpipe removes a piped destination
-func (r *Readable) Unpipe(dest *Writable) {
-	// In a real implementation, we'd need to track piped destinations
-	// and remove the specific event handlers
-	r.events.Off("data", nil)
-	r.events.Off("end", nil)
-	r.events.Off("error", nil)
+// Unpipe stops writing to dest, or to every destination when dest is nil.
+// Without destinations left, the stream pauses.
+func (r *Readable) Unpipe(dest Destination) {
+	r.mu.Lock()
+	var removed []*pipe
+	kept := make([]*pipe, 0, len(r.pipes))
+	for _, p := range r.pipes {
+		if dest == nil || p.dest == dest {
+			removed = append(removed, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	r.pipes = kept
+	pause := len(removed) > 0 && len(kept) == 0 && r.flowing && !r.paused
+	resume := len(removed) > 0 && len(kept) > 0 && r.backpressure && !r.awaitingDrain()
+	if len(kept) == 0 {
+		r.backpressure = false
+	}
+	r.mu.Unlock()
+
+	for _, p := range removed {
+		p.detach()
+	}
+	if pause {
+		r.Pause()
+	} else if resume {
+		r.Resume()
+	}
+}
+
+// awaitingDrain reports whether a destination is still full (must be
+// called with lock held)
+func (r *Readable) awaitingDrain() bool {
+	for _, p := range r.pipes {
+		if p.awaiting {
+			return true
+		}
+	}
+	return false
 }
 
-// Destroy destroys the stream
+// Destroy destroys the stream, emitting 'error' when err is not nil and
+// then 'close'. The readable side of a Duplex destroys the whole Duplex.
 func (r *Readable) Destroy(err error) {
+	if r.duplex != nil {
+		r.duplex.Destroy(err)
+		return
+	}
+	if r.destroy(err) {
+		if err != nil {
+			r.events.Emit("error", err)
+		}
+		r.events.Emit("close")
+	}
+}
+
+// destroy releases the stream and unpipes it, and reports whether it had
+// not been destroyed yet. The caller emits 'error' and 'close'.
+func (r *Readable) destroy(err error) bool {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	if r.destroyed {
-		return
+		r.mu.Unlock()
+		return false
 	}
 
 	r.destroyed = true
 	r.error = err
 	atomic.StoreInt32(&r.state, StateClosed)
-	
+	pipes := r.pipes
+	r.pipes = nil
+	r.mu.Unlock()
+
 	if r.cancel != nil {
 		r.cancel()
 	}
 
-	if err != nil {
-		r.events.Emit("error", err)
+	for _, p := range pipes {
+		p.detach()
 	}
+	return true
+}
 
-	r.events.Emit("close")
+// flush emits the buffered data while the stream flows, one 'data' event
+// per chunk in object mode, and writes it to the pipes. It pauses when a
+// pipe is full, and ends the stream once it has ended and its buffer is
+// empty.
+func (r *Readable) flush() {
+	for {
+		r.mu.Lock()
+		if r.destroyed {
+			r.mu.Unlock()
+			return
+		}
+		if r.paused || !r.flowing || r.length() == 0 {
+			r.mu.Unlock()
+			r.flushEnd()
+			return
+		}
+
+		var chunk interface{}
+		if r.objectMode {
+			chunk, _ = r.readObject()
+		} else {
+			chunk = append([]byte(nil), r.buffer.Bytes()...)
+			r.buffer.Reset()
+		}
+		pipes := r.pipes
+		r.mu.Unlock()
+
+		r.events.Emit("data", chunk)
+
+		full := false
+		for _, p := range pipes {
+			if !p.dest.WriteObject(chunk) {
+				r.mu.Lock()
+				p.awaiting = true
+				r.mu.Unlock()
+				full = true
+			}
+		}
+		if full {
+			r.mu.Lock()
+			r.backpressure = true
+			r.mu.Unlock()
+			r.Pause()
+		}
+	}
 }
 
-// emitData emits buffered data (must be called with lock held)
-func (r *Readable) emitData() {
-	if r.buffer.Len() == 0 || r.paused || !r.flowing {
+// flushEnd emits 'end' once the stream has ended and its buffer is empty,
+// ending the destinations piped with end and unpiping them all
+func (r *Readable) flushEnd() {
+	r.mu.Lock()
+	if !r.ended || r.endEmitted || r.destroyed || r.length() > 0 {
+		r.mu.Unlock()
 		return
 	}
-
-	data := r.buffer.Bytes()
-	r.buffer.Reset()
-	r.events.Emit("data", data)
+	r.endEmitted = true
+	atomic.StoreInt32(&r.state, StateEnded)
+	pipes := r.pipes
+	r.pipes = nil
+	r.mu.Unlock()
+
+	r.events.Emit("end")
+	for _, p := range pipes {
+		if p.end {
+			p.dest.End(nil)
+		}
+		p.detach()
+	}
+	if r.duplex != nil {
+		r.duplex.readableEnded()
+	}
 }
 
 // Writable represents a writable stream
@@ -282,10 +553,14 @@ type Writable struct {
 	error         error
 	writing       bool
 	corked        int
-	buffer        [][]byte
+	buffer        []interface{}
+	length        int  // Bytes, or chunks in object mode, written but not yet processed, corked writes included
+	needDrain     bool // A write returned false; emit 'drain' once length drops below highWaterMark
 	highWaterMark int
 	decoding      string
 	objectMode    bool
+	owner         interface{} // JS object wrapping the stream, passed for it in events
+	duplex        *Duplex     // set on the writable side of a Duplex
 	events        EventEmitter
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -295,7 +570,7 @@ type Writable struct {
 func NewWritable(opts *WritableOptions, events EventEmitter) *Writable {
 	if opts == nil {
 		opts = &WritableOptions{
-			HighWaterMark: 16 * 1024, // 16KB default
+			HighWaterMark: DefaultHighWaterMark,
 		}
 	}
 
@@ -306,7 +581,7 @@ func NewWritable(opts *WritableOptions, events EventEmitter) *Writable {
 		highWaterMark: opts.HighWaterMark,
 		decoding:      opts.Decoding,
 		objectMode:    opts.ObjectMode,
-		buffer:        make([][]byte, 0),
+		buffer:        make([]interface{}, 0),
 		events:        events,
 		ctx:           ctx,
 		cancel:        cancel,
@@ -315,11 +590,24 @@ func NewWritable(opts *WritableOptions, events EventEmitter) *Writable {
 	return w
 }
 
-// Write writes data to the stream
+// Write writes data to the stream. It returns false once the bytes
+// buffered by the stream reach highWaterMark; the caller should then wait
+// for 'drain' before writing more.
 func (w *Writable) Write(chunk []byte) bool {
+	return w.WriteObject(chunk)
+}
+
+// WriteObject writes a chunk to the stream. In object mode the chunk is
+// written as is and counts as one towards highWaterMark; otherwise it is
+// converted to bytes.
+func (w *Writable) WriteObject(chunk interface{}) bool {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	return w.write(chunk)
+}
 
+// write buffers or processes chunk (must be called with lock held)
+func (w *Writable) write(chunk interface{}) bool {
 	if w.destroyed {
 		w.events.Emit("error", ErrStreamDestroyed)
 		return false
@@ -330,49 +618,91 @@ func (w *Writable) Write(chunk []byte) bool {
 		return false
 	}
 
+	if !w.objectMode {
+		chunk = chunkBytes(chunk)
+	}
+	w.length += w.size(chunk)
+
 	// If corked, buffer the write
 	if w.corked > 0 {
 		w.buffer = append(w.buffer, chunk)
-		return true
+	} else {
+		w.process(chunk)
+	}
+
+	if w.length >= w.highWaterMark {
+		w.needDrain = true
+		return false
+	}
+	return true
+}
+
+// size returns how much chunk counts towards highWaterMark
+func (w *Writable) size(chunk interface{}) int {
+	if w.objectMode {
+		return 1
 	}
+	return len(chunk.([]byte))
+}
 
-	// Process the write
+// process writes chunk asynchronously and emits 'drain' if that takes the
+// buffered bytes from at or above highWaterMark to below it
+func (w *Writable) process(chunk interface{}) {
 	w.writing = true
 	go func() {
-		// Simulate async write operation
 		w.events.Emit("write", chunk)
-		
+
 		w.mu.Lock()
-		w.writing = false
-		bufferSize := len(w.buffer)
+		w.length -= w.size(chunk)
+		w.writing = w.length > 0
+		drain := w.needDrain && w.length < w.highWaterMark && !w.destroyed
+		if drain {
+			w.needDrain = false
+		}
 		w.mu.Unlock()
 
-		// Check if we need to emit drain
-		if bufferSize == 0 {
+		if drain {
 			w.events.Emit("drain")
 		}
 	}()
+}
+
+// Length returns the number of bytes, or chunks in object mode, written
+// but not yet processed
+func (w *Writable) Length() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.length
+}
 
-	// Return false if we've exceeded high water mark
-	// (simplified - in real implementation we'd track total buffer size)
-	return len(chunk) < w.highWaterMark
+// HighWaterMark returns the buffered size at which Write starts returning false
+func (w *Writable) HighWaterMark() int {
+	return w.highWaterMark
+}
+
+func (w *Writable) writable() *Writable {
+	return w
 }
 
 // End signals the end of writing
 func (w *Writable) End(chunk []byte) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
-
 	if w.ended {
+		w.mu.Unlock()
 		return
 	}
 
 	if chunk != nil {
-		w.Write(chunk)
+		w.write(chunk)
 	}
 
 	w.ended = true
+	w.mu.Unlock()
+
 	w.events.Emit("finish")
+	if w.duplex != nil {
+		w.duplex.writableFinished()
+	}
 }
 
 // Cork prevents writes from being processed
@@ -391,22 +721,38 @@ func (w *Writable) Uncork() {
 		w.corked--
 	}
 
-	// Flush buffered writes if uncorked
+	// Flush buffered writes if uncorked; they already count in length
 	if w.corked == 0 && len(w.buffer) > 0 {
 		for _, chunk := range w.buffer {
-			w.Write(chunk)
+			w.process(chunk)
 		}
 		w.buffer = w.buffer[:0]
 	}
 }
 
-// Destroy destroys the stream
+// Destroy destroys the stream, emitting 'error' when err is not nil and
+// then 'close'. The writable side of a Duplex destroys the whole Duplex.
 func (w *Writable) Destroy(err error) {
+	if w.duplex != nil {
+		w.duplex.Destroy(err)
+		return
+	}
+	if w.destroy(err) {
+		if err != nil {
+			w.events.Emit("error", err)
+		}
+		w.events.Emit("close")
+	}
+}
+
+// destroy releases the stream and reports whether it had not been
+// destroyed yet. The caller emits 'error' and 'close'.
+func (w *Writable) destroy(err error) bool {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if w.destroyed {
-		return
+		return false
 	}
 
 	w.destroyed = true
@@ -416,26 +762,97 @@ func (w *Writable) Destroy(err error) {
 	if w.cancel != nil {
 		w.cancel()
 	}
-
-	if err != nil {
-		w.events.Emit("error", err)
-	}
-
-	w.events.Emit("close")
+	return true
 }
 
-// Duplex represents a stream that is both readable and writable
+// Duplex represents a stream that is both readable and writable. Its sides
+// keep their own state but share their events, and their lifecycle: 'close'
+// is emitted once, when the readable side has ended and the writable side
+// has finished, or when either side is destroyed, which destroys both.
 type Duplex struct {
 	*Readable
 	*Writable
+
+	// AllowHalfOpen keeps the writable side open once the readable side
+	// ends; when false, the end of the readable side ends the writable one.
+	// NewDuplex sets it to true, as in Node.
+	AllowHalfOpen bool
+
+	mu          sync.Mutex
+	readDone    bool   // the readable side emitted 'end'
+	writeDone   bool   // the writable side emitted 'finish'
+	closed      bool   // 'close' was emitted
+	endWritable func() // ends the writable side; Transform overrides End
 }
 
 // NewDuplex creates a new duplex stream
 func NewDuplex(readOpts *ReadableOptions, writeOpts *WritableOptions, events EventEmitter) *Duplex {
-	return &Duplex{
-		Readable: NewReadable(readOpts, events),
-		Writable: NewWritable(writeOpts, events),
+	d := &Duplex{
+		Readable:      NewReadable(readOpts, events),
+		Writable:      NewWritable(writeOpts, events),
+		AllowHalfOpen: true,
+	}
+	d.Readable.duplex = d
+	d.Writable.duplex = d
+	d.endWritable = func() { d.Writable.End(nil) }
+	return d
+}
+
+// Destroy destroys both sides of the stream, emitting 'error' when err is
+// not nil and then 'close', once
+func (d *Duplex) Destroy(err error) {
+	readable := d.Readable.destroy(err)
+	writable := d.Writable.destroy(err)
+	if !readable && !writable {
+		return
+	}
+
+	d.mu.Lock()
+	closed := d.closed
+	d.closed = true
+	d.mu.Unlock()
+	if closed {
+		return
+	}
+
+	if err != nil {
+		d.Readable.events.Emit("error", err)
 	}
+	d.Readable.events.Emit("close")
+}
+
+// readableEnded is called once the readable side has emitted 'end'
+func (d *Duplex) readableEnded() {
+	d.mu.Lock()
+	d.readDone = true
+	end := !d.AllowHalfOpen && !d.writeDone
+	d.mu.Unlock()
+
+	if end {
+		d.endWritable()
+	}
+	d.closeIfDone()
+}
+
+// writableFinished is called once the writable side has emitted 'finish'
+func (d *Duplex) writableFinished() {
+	d.mu.Lock()
+	d.writeDone = true
+	d.mu.Unlock()
+	d.closeIfDone()
+}
+
+// closeIfDone emits 'close' once both sides are done
+func (d *Duplex) closeIfDone() {
+	d.mu.Lock()
+	if !d.readDone || !d.writeDone || d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	d.Readable.events.Emit("close")
 }
 
 // Transform represents a duplex stream that transforms data
@@ -445,8 +862,29 @@ type Transform struct {
 	flushFunc     func() ([]byte, error)
 	originalWrite func([]byte) bool
 	originalEnd   func([]byte)
+
+	// Set by NewAsyncTransform
+	asyncTransform AsyncTransformFunc
+	asyncFlush     AsyncFlushFunc
+	mu             sync.Mutex
+	queue          []interface{} // chunks written but not transformed yet
+	transforming   bool          // a chunk is being transformed
+	ending         bool          // End was called; flush once the queue is empty
+	failed         bool          // a transform failed; the stream is destroyed
 }
 
+// AsyncTransformFunc transforms a chunk and calls done with the result,
+// either before it returns or later. A nil result pushes nothing.
+type AsyncTransformFunc func(chunk interface{}, encoding string, done func(result interface{}, err error))
+
+// AsyncFlushFunc is called once every chunk has been transformed, after
+// End, and calls done with the last data to push, or nil
+type AsyncFlushFunc func(done func(result interface{}, err error))
+
+// ErrCallbackCalledTwice is reported when a transform or flush completes
+// more than once
+var ErrCallbackCalledTwice = errors.New("callback called multiple times")
+
 // NewTransform creates a new transform stream
 func NewTransform(
 	readOpts *ReadableOptions,
@@ -464,12 +902,34 @@ func NewTransform(
 	// Store the original methods
 	t.originalWrite = t.Writable.Write
 	t.originalEnd = t.Writable.End
+	t.Duplex.endWritable = func() { t.End(nil) }
 
 	return t
 }
 
+// NewAsyncTransform creates a transform stream whose transform and flush
+// complete through a callback, like those of a Transform created from JS.
+// Chunks are transformed one at a time, in the order they were written;
+// the next one waits for the previous one to complete. flush may be nil.
+func NewAsyncTransform(
+	readOpts *ReadableOptions,
+	writeOpts *WritableOptions,
+	events EventEmitter,
+	transform AsyncTransformFunc,
+	flush AsyncFlushFunc,
+) *Transform {
+	t := NewTransform(readOpts, writeOpts, events, nil, nil)
+	t.asyncTransform = transform
+	t.asyncFlush = flush
+	return t
+}
+
 // Write overrides the writable Write method to transform data
 func (t *Transform) Write(chunk []byte) bool {
+	if t.asyncTransform != nil {
+		return t.WriteObject(chunk)
+	}
+
 	if t.transformFunc != nil {
 		transformed, err := t.transformFunc(chunk, t.Writable.decoding)
 		if err != nil {
@@ -483,12 +943,43 @@ func (t *Transform) Write(chunk []byte) bool {
 	return t.originalWrite(chunk)
 }
 
+// WriteObject writes a chunk of any type. Object mode chunks bypass the
+// byte transform function and are pushed to the readable side as is.
+func (t *Transform) WriteObject(chunk interface{}) bool {
+	if t.asyncTransform != nil {
+		return t.writeAsync(chunk)
+	}
+	if !t.Writable.objectMode {
+		return t.Write(chunkBytes(chunk))
+	}
+	if err := t.Readable.PushObject(chunk); err != nil {
+		t.Writable.events.Emit("error", err)
+		return false
+	}
+	return t.Writable.WriteObject(chunk)
+}
+
 // End overrides the writable End method to handle flush
 func (t *Transform) End(chunk []byte) {
 	if chunk != nil {
 		t.Write(chunk)
 	}
 
+	if t.asyncTransform != nil {
+		t.mu.Lock()
+		if t.ending {
+			t.mu.Unlock()
+			return
+		}
+		t.ending = true
+		idle := !t.transforming
+		t.mu.Unlock()
+		if idle {
+			t.flushAsync()
+		}
+		return
+	}
+
 	if t.flushFunc != nil {
 		flushed, err := t.flushFunc()
 		if err != nil {
@@ -502,6 +993,134 @@ func (t *Transform) End(chunk []byte) {
 	t.originalEnd(nil)
 }
 
+// writeAsync queues chunk for the async transform, starting it unless a
+// chunk is being transformed
+func (t *Transform) writeAsync(chunk interface{}) bool {
+	t.mu.Lock()
+	if t.ending {
+		t.mu.Unlock()
+		t.Writable.events.Emit("error", errors.New("write after end"))
+		return false
+	}
+	if t.failed {
+		t.mu.Unlock()
+		return false
+	}
+	t.mu.Unlock()
+
+	if !t.Writable.objectMode {
+		chunk = chunkBytes(chunk)
+	}
+	ok := t.Writable.WriteObject(chunk)
+
+	t.mu.Lock()
+	t.queue = append(t.queue, chunk)
+	start := !t.transforming
+	t.transforming = true
+	t.mu.Unlock()
+
+	if start {
+		t.transformNext()
+	}
+	return ok
+}
+
+// transformNext transforms the queued chunks in order. Transforms that
+// complete before returning are looped over rather than recursed into;
+// one that completes later picks the queue up again from its callback.
+func (t *Transform) transformNext() {
+	for {
+		t.mu.Lock()
+		if t.failed {
+			t.mu.Unlock()
+			return
+		}
+		if len(t.queue) == 0 {
+			t.transforming = false
+			ending := t.ending
+			t.mu.Unlock()
+			if ending {
+				t.flushAsync()
+			}
+			return
+		}
+		chunk := t.queue[0]
+		t.queue = t.queue[1:]
+		t.mu.Unlock()
+
+		returned := false
+		completed := false
+		t.asyncTransform(chunk, t.Writable.decoding, t.once(func(result interface{}, err error) {
+			if !t.complete(result, err) {
+				return
+			}
+			t.mu.Lock()
+			if !returned {
+				completed = true
+				t.mu.Unlock()
+				return
+			}
+			t.mu.Unlock()
+			t.transformNext()
+		}))
+
+		t.mu.Lock()
+		returned = true
+		done := completed
+		t.mu.Unlock()
+		if !done {
+			return
+		}
+	}
+}
+
+// flushAsync runs the flush function, then ends both sides of the stream
+func (t *Transform) flushAsync() {
+	end := func() {
+		t.Readable.Push(nil)
+		t.originalEnd(nil)
+	}
+	if t.asyncFlush == nil {
+		end()
+		return
+	}
+	t.asyncFlush(t.once(func(result interface{}, err error) {
+		if t.complete(result, err) {
+			end()
+		}
+	}))
+}
+
+// complete pushes the result of a transform or flush. An error destroys
+// the stream, and complete reports whether it can go on.
+func (t *Transform) complete(result interface{}, err error) bool {
+	if err != nil {
+		t.mu.Lock()
+		t.failed = true
+		t.queue = nil
+		t.mu.Unlock()
+		t.Readable.Destroy(err)
+		return false
+	}
+	if result != nil {
+		t.Readable.PushObject(result)
+	}
+	return true
+}
+
+// once wraps a completion callback, reporting an error instead of
+// completing twice
+func (t *Transform) once(done func(interface{}, error)) func(interface{}, error) {
+	var called int32
+	return func(result interface{}, err error) {
+		if !atomic.CompareAndSwapInt32(&called, 0, 1) {
+			t.Writable.events.Emit("error", ErrCallbackCalledTwice)
+			return
+		}
+		done(result, err)
+	}
+}
+
 // PassThrough is a transform stream that passes data through unchanged
 type PassThrough struct {
 	*Transform
@@ -514,7 +1133,7 @@ func NewPassThrough(readOpts *ReadableOptions, writeOpts *WritableOptions, event
 	}
 }
 
-// Pipeline connects multiple streams together
+// Pipeline connects multiple streams together, piping each into the next
 func Pipeline(streams []interface{}) error {
 	if len(streams) < 2 {
 		return errors.New("pipeline requires at least 2 streams")
@@ -522,102 +1141,37 @@ func Pipeline(streams []interface{}) error {
 
 	// Connect each stream to the next
 	for i := 0; i < len(streams)-1; i++ {
-		current := streams[i]
-		next := streams[i+1]
-
-		switch src := current.(type) {
-		case *Readable:
-			if dest, ok := next.(*Writable); ok {
-				if err := src.Pipe(dest, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Duplex); ok {
-				if err := src.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Transform); ok {
-				if err := src.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*PassThrough); ok {
-				if err := src.Pipe(dest.Transform.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else {
-				return fmt.Errorf("stream at index %d+1 is not writable", i)
-			}
-
-		case *Duplex:
-			if dest, ok := next.(*Writable); ok {
-				if err := src.Readable.Pipe(dest, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Duplex); ok {
-				if err := src.Readable.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Transform); ok {
-				if err := src.Readable.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*PassThrough); ok {
-				if err := src.Readable.Pipe(dest.Transform.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else {
-				return fmt.Errorf("stream at index %d+1 is not writable", i)
-			}
-
-		case *Transform:
-			if dest, ok := next.(*Writable); ok {
-				if err := src.Readable.Pipe(dest, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Duplex); ok {
-				if err := src.Readable.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Transform); ok {
-				if err := src.Readable.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*PassThrough); ok {
-				if err := src.Readable.Pipe(dest.Transform.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else {
-				return fmt.Errorf("stream at index %d+1 is not writable", i)
-			}
-
-		case *PassThrough:
-			if dest, ok := next.(*Writable); ok {
-				if err := src.Transform.Readable.Pipe(dest, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Duplex); ok {
-				if err := src.Transform.Readable.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*Transform); ok {
-				if err := src.Transform.Readable.Pipe(dest.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else if dest, ok := next.(*PassThrough); ok {
-				if err := src.Transform.Readable.Pipe(dest.Transform.Writable, map[string]interface{}{"end": true}); err != nil {
-					return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
-				}
-			} else {
-				return fmt.Errorf("stream at index %d+1 is not writable", i)
-			}
-
-		default:
+		src := readableOf(streams[i])
+		if src == nil {
 			return fmt.Errorf("stream at index %d is not readable", i)
 		}
+		dest, ok := streams[i+1].(Destination)
+		if !ok {
+			return fmt.Errorf("stream at index %d+1 is not writable", i)
+		}
+		if err := src.Pipe(dest, map[string]interface{}{"end": true}); err != nil {
+			return fmt.Errorf("failed to pipe streams at index %d: %w", i, err)
+		}
 	}
 
 	return nil
 }
 
+// readableOf returns the readable side of a stream, or nil
+func readableOf(stream interface{}) *Readable {
+	switch s := stream.(type) {
+	case *Readable:
+		return s
+	case *Duplex:
+		return s.Readable
+	case *Transform:
+		return s.Readable
+	case *PassThrough:
+		return s.Transform.Readable
+	}
+	return nil
+}
+
 // Finished waits for a stream to finish
 func Finished(stream interface{}, options map[string]interface{}) <-chan error {
 	errCh := make(chan error, 1)
@@ -687,24 +1241,25 @@ func Finished(stream interface{}, options map[string]interface{}) <-chan error {
 	return errCh
 }
 
-// FromIterable creates a readable stream from an iterable
+// chunkBytes converts a chunk to bytes for a byte stream
+func chunkBytes(chunk interface{}) []byte {
+	switch v := chunk.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	default:
+		return []byte(fmt.Sprintf("%v", v))
+	}
+}
+
+// FromIterable creates an object mode readable stream from an iterable
 func FromIterable(items []interface{}, events EventEmitter) *Readable {
-	r := NewReadable(&ReadableOptions{ObjectMode: true}, events)
+	r := NewReadable(&ReadableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true}, events)
 
 	go func() {
 		for _, item := range items {
-			// Convert item to bytes if necessary
-			var data []byte
-			switch v := item.(type) {
-			case string:
-				data = []byte(v)
-			case []byte:
-				data = v
-			default:
-				data = []byte(fmt.Sprintf("%v", v))
-			}
-
-			if err := r.Push(data); err != nil {
+			if err := r.PushObject(item); err != nil {
 				r.Destroy(err)
 				return
 			}
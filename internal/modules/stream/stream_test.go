@@ -3,6 +3,8 @@ package stream
 import (
 	"bytes"
 	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,6 +60,85 @@ func (e *MockEventEmitter) Emit(event string, args ...interface{}) {
 	}
 }
 
+func TestSimpleEventEmitter(t *testing.T) {
+	t.Run("once handlers are called once", func(t *testing.T) {
+		e := NewSimpleEventEmitter()
+		calls := 0
+		e.Once("end", func() { calls++ })
+		e.Emit("end")
+		e.Emit("end")
+		if calls != 1 || e.ListenerCount("end") != 0 {
+			t.Errorf("expected one call and no handler left, got %d calls and %d handlers", calls, e.ListenerCount("end"))
+		}
+	})
+
+	t.Run("off removes the given handler", func(t *testing.T) {
+		e := NewSimpleEventEmitter()
+		var got []string
+		first := func() { got = append(got, "first") }
+		second := func(args ...interface{}) { got = append(got, "second") }
+		e.On("data", first)
+		e.On("data", second)
+		e.Off("data", first)
+		e.Emit("data")
+		if len(got) != 1 || got[0] != "second" {
+			t.Errorf("expected only the second handler, got %v", got)
+		}
+
+		e.Off("data", nil)
+		if e.ListenerCount("data") != 0 {
+			t.Error("expected off with nil to remove every handler")
+		}
+	})
+
+	t.Run("removeAllListeners", func(t *testing.T) {
+		e := NewSimpleEventEmitter()
+		e.On("data", func() {})
+		e.On("end", func() {})
+		e.On("close", func() {})
+		e.RemoveAllListeners("data")
+		if e.ListenerCount("data") != 0 || e.ListenerCount("end") != 1 {
+			t.Error("expected only the data handlers to be removed")
+		}
+		e.RemoveAllListeners()
+		if e.ListenerCount("end") != 0 || e.ListenerCount("close") != 0 {
+			t.Error("expected every handler to be removed")
+		}
+	})
+
+	t.Run("handlers get typed arguments", func(t *testing.T) {
+		e := NewSimpleEventEmitter()
+		var data []byte
+		var err error
+		var name string
+		var count int
+		e.On("data", func(chunk []byte) { data = chunk })
+		e.On("error", func(e error) { err = e })
+		e.On("info", func(s string, n int) { name, count = s, n })
+
+		e.Emit("data", []byte("abc"))
+		e.Emit("error", io.EOF)
+		e.Emit("info", "chunks", 3)
+		if string(data) != "abc" || err != io.EOF || name != "chunks" || count != 3 {
+			t.Errorf("unexpected arguments %q, %v, %q, %d", data, err, name, count)
+		}
+	})
+
+	t.Run("unpipe removes the pipe's handlers", func(t *testing.T) {
+		r := NewReadable(nil, NewSimpleEventEmitter())
+		events := NewSimpleEventEmitter()
+		w := NewWritable(nil, events)
+
+		for i := 0; i < 3; i++ {
+			r.Pipe(w, nil)
+			r.Unpipe(w)
+		}
+		if events.ListenerCount("drain") != 0 || events.ListenerCount("error") != 0 {
+			t.Errorf("expected no handlers left, got %d drain and %d error", events.ListenerCount("drain"), events.ListenerCount("error"))
+		}
+	})
+}
+
 func TestReadable(t *testing.T) {
 	tests := []struct {
 		name string
@@ -310,6 +391,50 @@ func TestWritable(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "should track buffered bytes against highWaterMark",
+			test: func(t *testing.T) {
+				events := NewMockEventEmitter()
+				w := NewWritable(&WritableOptions{HighWaterMark: 10}, events)
+
+				drained := make(chan struct{}, 4)
+				events.On("drain", func() {
+					drained <- struct{}{}
+				})
+
+				w.Cork()
+				if !w.Write([]byte("abcd")) {
+					t.Error("expected write below highWaterMark to return true")
+				}
+				if w.Write([]byte("efghij")) {
+					t.Error("expected write reaching highWaterMark to return false")
+				}
+				if w.Length() != 10 {
+					t.Errorf("expected length to be 10, got %d", w.Length())
+				}
+				w.Uncork()
+
+				select {
+				case <-drained:
+				case <-time.After(time.Second):
+					t.Fatal("expected drain event to be emitted")
+				}
+				time.Sleep(10 * time.Millisecond)
+				if len(drained) != 0 {
+					t.Error("expected drain to be emitted once")
+				}
+				if w.Length() != 0 {
+					t.Errorf("expected length to be 0, got %d", w.Length())
+				}
+
+				// Writes that stay below highWaterMark never emit drain
+				w.Write([]byte("abc"))
+				time.Sleep(10 * time.Millisecond)
+				if len(drained) != 0 {
+					t.Error("expected no drain without exceeding highWaterMark")
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -317,6 +442,108 @@ func TestWritable(t *testing.T) {
 	}
 }
 
+func TestObjectMode(t *testing.T) {
+	type point struct{ X, Y int }
+
+	t.Run("readable buffers chunks as is", func(t *testing.T) {
+		events := NewMockEventEmitter()
+		r := NewReadable(&ReadableOptions{HighWaterMark: 2, ObjectMode: true}, events)
+
+		first := &point{1, 2}
+		r.PushObject(first)
+		r.PushObject(map[string]interface{}{"n": 3})
+		if r.Length() != 2 {
+			t.Errorf("expected length to be 2 chunks, got %d", r.Length())
+		}
+
+		chunk, err := r.ReadObject()
+		if err != nil || chunk != first {
+			t.Fatalf("expected first chunk back, got %v, %v", chunk, err)
+		}
+		chunk, _ = r.ReadObject()
+		if m, ok := chunk.(map[string]interface{}); !ok || m["n"] != 3 {
+			t.Errorf("expected map chunk, got %v", chunk)
+		}
+
+		r.Push(nil)
+		if _, err := r.ReadObject(); err != io.EOF {
+			t.Errorf("expected EOF, got %v", err)
+		}
+	})
+
+	t.Run("readable emits one data event per chunk", func(t *testing.T) {
+		events := NewMockEventEmitter()
+		r := NewReadable(&ReadableOptions{ObjectMode: true}, events)
+
+		var got []interface{}
+		events.On("data", func(args ...interface{}) {
+			got = append(got, args[0])
+		})
+		r.PushObject(1)
+		r.PushObject("two")
+		r.Resume()
+		r.PushObject(&point{3, 3})
+
+		if len(got) != 3 || got[0] != 1 || got[1] != "two" {
+			t.Errorf("unexpected data events %v", got)
+		}
+	})
+
+	t.Run("writable counts highWaterMark in chunks", func(t *testing.T) {
+		events := NewMockEventEmitter()
+		w := NewWritable(&WritableOptions{HighWaterMark: 2, ObjectMode: true}, events)
+
+		written := make(chan interface{}, 2)
+		events.On("write", func(args ...interface{}) {
+			written <- args[0]
+		})
+
+		w.Cork()
+		big := []byte(strings.Repeat("x", 100))
+		if !w.WriteObject(big) {
+			t.Error("expected first chunk to stay below highWaterMark")
+		}
+		chunk := &point{1, 1}
+		if w.WriteObject(chunk) {
+			t.Error("expected second chunk to reach highWaterMark")
+		}
+		if w.Length() != 2 {
+			t.Errorf("expected length to be 2 chunks, got %d", w.Length())
+		}
+		w.Uncork()
+
+		seen := map[interface{}]bool{}
+		for i := 0; i < 2; i++ {
+			select {
+			case c := <-written:
+				if p, ok := c.(*point); ok {
+					seen[p] = true
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected write events")
+			}
+		}
+		if !seen[chunk] {
+			t.Error("expected the object chunk to be written as is")
+		}
+	})
+
+	t.Run("transform passes objects through", func(t *testing.T) {
+		events := NewMockEventEmitter()
+		tr := NewTransform(
+			&ReadableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true},
+			&WritableOptions{HighWaterMark: DefaultObjectHighWaterMark, ObjectMode: true},
+			events, nil, nil,
+		)
+
+		chunk := &point{4, 5}
+		tr.WriteObject(chunk)
+		if got, _ := tr.Readable.ReadObject(); got != chunk {
+			t.Errorf("expected transformed chunk to be the same object, got %v", got)
+		}
+	})
+}
+
 func TestDuplex(t *testing.T) {
 	t.Run("should create duplex stream", func(t *testing.T) {
 		events := NewMockEventEmitter()
@@ -361,6 +588,86 @@ func TestDuplex(t *testing.T) {
 			t.Errorf("expected %s, got %s", string(data), string(read))
 		}
 	})
+
+	// record counts the lifecycle events of a duplex
+	record := func(events EventEmitter) map[string]int {
+		counts := make(map[string]int)
+		for _, event := range []string{"end", "finish", "error", "close"} {
+			event := event
+			events.On(event, func() { counts[event]++ })
+		}
+		return counts
+	}
+
+	t.Run("closes once both sides are done", func(t *testing.T) {
+		events := NewSimpleEventEmitter()
+		counts := record(events)
+		d := NewDuplex(nil, nil, events)
+
+		d.Writable.End(nil)
+		if counts["finish"] != 1 || counts["close"] != 0 {
+			t.Fatalf("expected finish without close, got %v", counts)
+		}
+		if err := d.Push([]byte("reply")); err != nil {
+			t.Errorf("expected the readable side to stay open, got %v", err)
+		}
+
+		d.Push(nil)
+		d.Read(-1)
+		d.Read(-1)
+		if counts["end"] != 1 || counts["close"] != 1 {
+			t.Errorf("expected end and one close, got %v", counts)
+		}
+	})
+
+	t.Run("allowHalfOpen false ends the writable side with the readable one", func(t *testing.T) {
+		events := NewSimpleEventEmitter()
+		counts := record(events)
+		d := NewDuplex(nil, nil, events)
+		d.AllowHalfOpen = false
+
+		d.Push(nil)
+		d.Read(-1)
+		if counts["end"] != 1 || counts["finish"] != 1 || counts["close"] != 1 {
+			t.Errorf("expected end, finish and close, got %v", counts)
+		}
+		if d.Write([]byte("late")) {
+			t.Error("expected writing after the readable side ended to fail")
+		}
+	})
+
+	t.Run("destroy destroys both sides once", func(t *testing.T) {
+		events := NewSimpleEventEmitter()
+		counts := record(events)
+		d := NewDuplex(nil, nil, events)
+
+		d.Readable.Destroy(errors.New("boom"))
+		d.Writable.Destroy(nil)
+		d.Destroy(nil)
+		if counts["error"] != 1 || counts["close"] != 1 {
+			t.Errorf("expected one error and one close, got %v", counts)
+		}
+		if _, err := d.Read(-1); err != ErrStreamDestroyed {
+			t.Errorf("expected the readable side to be destroyed, got %v", err)
+		}
+		if d.Write([]byte("late")) {
+			t.Error("expected the writable side to be destroyed")
+		}
+	})
+
+	t.Run("destroy after both sides are done does not close again", func(t *testing.T) {
+		events := NewSimpleEventEmitter()
+		counts := record(events)
+		d := NewDuplex(nil, nil, events)
+
+		d.Writable.End(nil)
+		d.Push(nil)
+		d.Read(-1)
+		d.Destroy(nil)
+		if counts["close"] != 1 {
+			t.Errorf("expected one close, got %v", counts)
+		}
+	})
 }
 
 func TestTransform(t *testing.T) {
@@ -410,6 +717,76 @@ func TestTransform(t *testing.T) {
 	})
 }
 
+func TestAsyncTransform(t *testing.T) {
+	t.Run("transforms chunks in order as they complete", func(t *testing.T) {
+		var pending []func()
+		tr := NewAsyncTransform(nil, nil, NewSimpleEventEmitter(), func(chunk interface{}, encoding string, done func(interface{}, error)) {
+			data := bytes.ToUpper(chunk.([]byte))
+			pending = append(pending, func() { done(data, nil) })
+		}, func(done func(interface{}, error)) {
+			done([]byte("!"), nil)
+		})
+
+		tr.Write([]byte("a"))
+		tr.Write([]byte("b"))
+		tr.End(nil)
+		if len(pending) != 1 {
+			t.Fatalf("expected one transform at a time, got %d", len(pending))
+		}
+		pending[0]()
+		if len(pending) != 2 {
+			t.Fatalf("expected the next chunk once the first completed, got %d", len(pending))
+		}
+		if tr.Writable.ended {
+			t.Error("expected the stream not to finish before its chunks are transformed")
+		}
+		pending[1]()
+
+		data, _ := tr.Readable.Read(0)
+		if string(data) != "AB!" {
+			t.Errorf("expected transformed and flushed data, got %q", data)
+		}
+		if !tr.Writable.ended || !tr.Readable.ended {
+			t.Error("expected both sides to end after flush")
+		}
+	})
+
+	t.Run("transforms completing synchronously", func(t *testing.T) {
+		tr := NewAsyncTransform(nil, nil, NewSimpleEventEmitter(), func(chunk interface{}, encoding string, done func(interface{}, error)) {
+			done(chunk, nil)
+		}, nil)
+
+		for i := 0; i < 1000; i++ {
+			tr.Write([]byte("x"))
+		}
+		tr.End(nil)
+		if tr.Readable.Length() != 1000 {
+			t.Errorf("expected 1000 bytes, got %d", tr.Readable.Length())
+		}
+	})
+
+	t.Run("errors destroy the stream", func(t *testing.T) {
+		events := NewSimpleEventEmitter()
+		calls := 0
+		tr := NewAsyncTransform(nil, nil, events, func(chunk interface{}, encoding string, done func(interface{}, error)) {
+			calls++
+			done(nil, errors.New("bad chunk"))
+			done(nil, nil)
+		}, nil)
+
+		var errs []error
+		events.On("error", func(args ...interface{}) {
+			errs = append(errs, args[0].(error))
+		})
+		tr.Write([]byte("a"))
+		tr.Write([]byte("b"))
+
+		if calls != 1 || len(errs) != 2 || errs[0].Error() != "bad chunk" || errs[1] != ErrCallbackCalledTwice {
+			t.Errorf("expected the transform error then a double callback, got %d calls and %v", calls, errs)
+		}
+	})
+}
+
 func TestPassThrough(t *testing.T) {
 	t.Run("should create pass-through stream", func(t *testing.T) {
 		events := NewMockEventEmitter()
@@ -441,6 +818,144 @@ func TestPassThrough(t *testing.T) {
 	})
 }
 
+func TestPipe(t *testing.T) {
+	t.Run("pauses on backpressure and resumes on drain", func(t *testing.T) {
+		r := NewReadable(nil, NewSimpleEventEmitter())
+		events := NewSimpleEventEmitter()
+		w := NewWritable(&WritableOptions{HighWaterMark: 4}, events)
+
+		var piped interface{}
+		events.On("pipe", func(args ...interface{}) {
+			piped = args[0]
+		})
+		drained := make(chan struct{})
+		events.On("drain", func() {
+			close(drained)
+		})
+
+		r.Pipe(w, nil)
+		if piped != r {
+			t.Errorf("expected 'pipe' with the source, got %v", piped)
+		}
+		r.Push([]byte("abcdef"))
+		if !r.IsPaused() {
+			t.Error("expected source to pause once the destination is full")
+		}
+
+		select {
+		case <-drained:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for drain")
+		}
+		if r.IsPaused() {
+			t.Error("expected source to resume on drain")
+		}
+	})
+
+	t.Run("ends the destination unless end is false", func(t *testing.T) {
+		for _, end := range []bool{true, false} {
+			r := NewReadable(nil, NewSimpleEventEmitter())
+			events := NewSimpleEventEmitter()
+			w := NewWritable(nil, events)
+
+			unpiped := 0
+			events.On("unpipe", func() {
+				unpiped++
+			})
+
+			r.Pipe(w, map[string]interface{}{"end": end})
+			r.Push([]byte("data"))
+			r.Push(nil)
+
+			if w.ended != end {
+				t.Errorf("end: %v: expected ended to be %v", end, end)
+			}
+			if unpiped != 1 {
+				t.Errorf("end: %v: expected one 'unpipe', got %d", end, unpiped)
+			}
+		}
+	})
+
+	t.Run("unpipe stops writing to the destination", func(t *testing.T) {
+		r := NewReadable(nil, NewSimpleEventEmitter())
+		events := NewSimpleEventEmitter()
+		w := NewWritable(nil, events)
+		other := NewWritable(nil, NewSimpleEventEmitter())
+
+		var unpiped interface{}
+		events.On("unpipe", func(args ...interface{}) {
+			unpiped = args[0]
+		})
+
+		r.Pipe(w, nil)
+		r.Pipe(other, nil)
+		r.Unpipe(w)
+		if unpiped != r {
+			t.Errorf("expected 'unpipe' with the source, got %v", unpiped)
+		}
+		// Corked writes stay buffered, so they can be counted
+		w.Cork()
+		other.Cork()
+		r.Push([]byte("data"))
+		if w.Length() != 0 || other.Length() != 4 {
+			t.Errorf("expected data to go to the remaining destination only, got %d and %d bytes", w.Length(), other.Length())
+		}
+
+		r.Unpipe(nil)
+		if !r.IsPaused() {
+			t.Error("expected source to pause without destinations")
+		}
+	})
+
+	t.Run("destination errors unpipe it", func(t *testing.T) {
+		source := NewSimpleEventEmitter()
+		r := NewReadable(nil, source)
+		events := NewSimpleEventEmitter()
+		w := NewWritable(nil, events)
+
+		unpiped := make(chan struct{}, 1)
+		events.On("unpipe", func() {
+			unpiped <- struct{}{}
+		})
+		r.Pipe(w, nil)
+		w.Destroy(errors.New("boom"))
+
+		select {
+		case <-unpiped:
+		default:
+			t.Fatal("expected 'unpipe' after a destination error")
+		}
+		if !r.IsPaused() {
+			t.Error("expected source to pause without destinations")
+		}
+	})
+
+	t.Run("source errors leave the destination open", func(t *testing.T) {
+		r := NewReadable(nil, NewSimpleEventEmitter())
+		w := NewWritable(nil, NewSimpleEventEmitter())
+
+		r.Pipe(w, nil)
+		r.Destroy(errors.New("boom"))
+		if w.destroyed || w.ended {
+			t.Error("expected destination to stay open")
+		}
+	})
+
+	t.Run("pipes into transforms through their transform", func(t *testing.T) {
+		r := NewReadable(nil, NewSimpleEventEmitter())
+		tr := NewTransform(nil, nil, NewSimpleEventEmitter(), func(chunk []byte, encoding string) ([]byte, error) {
+			return bytes.ToUpper(chunk), nil
+		}, nil)
+
+		r.Pipe(tr, nil)
+		r.Push([]byte("shout"))
+		data, _ := tr.Readable.Read(0)
+		if string(data) != "SHOUT" {
+			t.Errorf("expected transformed data, got %q", data)
+		}
+	})
+}
+
 func TestPipeline(t *testing.T) {
 	t.Run("should connect streams in pipeline", func(t *testing.T) {
 		events1 := NewMockEventEmitter()
@@ -469,6 +984,81 @@ func TestPipeline(t *testing.T) {
 	})
 }
 
+func TestCompose(t *testing.T) {
+	upper := func(chunk []byte, encoding string) ([]byte, error) {
+		return bytes.ToUpper(chunk), nil
+	}
+
+	t.Run("writes to the first stream and reads from the last", func(t *testing.T) {
+		first := NewTransform(nil, nil, NewSimpleEventEmitter(), upper, nil)
+		last := NewPassThrough(nil, nil, NewSimpleEventEmitter())
+		events := NewSimpleEventEmitter()
+		var got []string
+		events.On("data", func(chunk []byte) { got = append(got, string(chunk)) })
+		closed := make(chan struct{})
+		events.On("close", func() { close(closed) })
+
+		c, err := Compose([]interface{}{first, last}, nil, nil, events)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c.Resume()
+		c.Write([]byte("ab"))
+		c.Write([]byte("cd"))
+		c.End(nil)
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the composed stream to close")
+		}
+		if strings.Join(got, "") != "ABCD" {
+			t.Errorf("expected ABCD, got %v", got)
+		}
+	})
+
+	t.Run("is readable only without a writable first stream", func(t *testing.T) {
+		source := NewReadable(nil, NewSimpleEventEmitter())
+		c, err := Compose([]interface{}{source, NewPassThrough(nil, nil, NewSimpleEventEmitter())}, nil, nil, NewSimpleEventEmitter())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.Write([]byte("x")) {
+			t.Error("expected writing to a readable only composed stream to fail")
+		}
+	})
+
+	t.Run("an error destroys every stream", func(t *testing.T) {
+		first := NewPassThrough(nil, nil, NewSimpleEventEmitter())
+		last := NewPassThrough(nil, nil, NewSimpleEventEmitter())
+		events := NewSimpleEventEmitter()
+		var got error
+		events.On("error", func(err error) { got = err })
+
+		c, err := Compose([]interface{}{first, last}, nil, nil, events)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		boom := errors.New("boom")
+		last.Destroy(boom)
+		if got != boom {
+			t.Errorf("expected the composed stream to fail with boom, got %v", got)
+		}
+		if _, err := first.Read(-1); err != ErrStreamDestroyed {
+			t.Errorf("expected the first stream to be destroyed, got %v", err)
+		}
+		if _, err := c.Read(-1); err != ErrStreamDestroyed {
+			t.Errorf("expected the composed stream to be destroyed, got %v", err)
+		}
+	})
+
+	t.Run("needs a stream", func(t *testing.T) {
+		if _, err := Compose(nil, nil, nil, NewSimpleEventEmitter()); err == nil {
+			t.Error("expected an error without streams")
+		}
+	})
+}
+
 func TestFinished(t *testing.T) {
 	t.Run("should wait for readable stream to end", func(t *testing.T) {
 		events := NewMockEventEmitter()
@@ -550,4 +1140,27 @@ func TestFromIterable(t *testing.T) {
 			t.Errorf("expected %s, got %s", expected, string(result))
 		}
 	})
-}
\ No newline at end of file
+}
+func TestBridgeDestroysOpenStreams(t *testing.T) {
+	b := &Bridge{}
+	openEvents, finishedEvents := NewSimpleEventEmitter(), NewSimpleEventEmitter()
+	open := NewReadable(nil, openEvents)
+	finished := NewWritable(nil, finishedEvents)
+	b.track(open, openEvents)
+	b.track(finished, finishedEvents)
+
+	closed := 0
+	openEvents.On("close", func() { closed++ })
+	finished.End(nil)
+	if len(b.open) != 1 {
+		t.Fatalf("Expected 1 open stream after one finished, got %d", len(b.open))
+	}
+
+	b.destroyOpen()
+	if closed != 1 || !open.destroyed {
+		t.Errorf("Expected the open stream to be destroyed, closed %d times", closed)
+	}
+	if finished.destroyed {
+		t.Error("Expected the finished stream to be left alone")
+	}
+}
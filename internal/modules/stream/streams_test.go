@@ -0,0 +1,155 @@
+package stream_test
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestPipe(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("stream-pipe", `
+		(async () => {
+			const { Readable, Writable, PassThrough } = require('stream');
+			const results = [];
+
+			const source = new Readable();
+			const through = new PassThrough();
+			const sink = new Writable();
+			const received = [];
+			sink.on('pipe', src => results.push('pipe:' + (src === through)));
+			sink.on('unpipe', src => results.push('unpipe:' + (src === through)));
+			through.on('data', chunk => received.push(String.fromCharCode(...chunk)));
+			const finished = new Promise(resolve => sink.on('finish', resolve));
+
+			results.push(source.pipe(through).pipe(sink) === sink);
+			source.push('a');
+			source.push('b');
+			source.push(null);
+			await finished;
+			results.push(received.join(''));
+
+			// A destination that errors is unpiped; the source stays open
+			const src = new Readable();
+			const dest = new Writable();
+			src.pipe(dest);
+			const closed = new Promise(resolve => dest.on('close', resolve));
+			dest.on('error', e => results.push('error:' + e.message));
+			dest.on('unpipe', () => results.push('unpiped'));
+			dest.destroy(new Error('boom'));
+			await closed;
+			results.push(src.isPaused());
+
+			try {
+				src.pipe({});
+			} catch (e) {
+				results.push(e instanceof TypeError);
+			}
+			return results.join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if want := "true|pipe:true|ab|unpipe:true|error:boom|unpiped|true|true"; value != want {
+		t.Errorf("stream results = %v, want %s", value, want)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("stream-transform", `
+		(async () => {
+			const { Readable, Transform } = require('stream');
+			const upper = new Transform({
+				transform(chunk, encoding, callback) {
+					const text = String.fromCharCode(...chunk).toUpperCase();
+					setTimeout(() => callback(null, text), 5);
+				},
+				flush(callback) {
+					this.push('!');
+					callback();
+				}
+			});
+			const received = [];
+			upper.on('data', chunk => received.push(String.fromCharCode(...chunk)));
+			const ended = new Promise(resolve => upper.on('end', resolve));
+
+			const source = new Readable();
+			source.pipe(upper);
+			source.push('ab');
+			source.push('cd');
+			source.push(null);
+			await ended;
+
+			const failing = new Transform({ transform() { throw new Error('bad'); } });
+			const failed = new Promise(resolve => failing.on('error', e => resolve(e.message)));
+			failing.write('x');
+			return received.join('') + '|' + await failed;
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if want := "ABCD!|bad"; value != want {
+		t.Errorf("stream results = %v, want %s", value, want)
+	}
+}
+
+func TestDuplex(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("stream-duplex", `
+		(async () => {
+			const { Duplex } = require('stream');
+			const lifecycle = (stream) => {
+				const events = [];
+				['end', 'finish', 'error', 'close'].forEach(name => stream.on(name, () => events.push(name)));
+				stream.on('data', () => {});
+				return events;
+			};
+			const closed = (stream) => new Promise(resolve => stream.on('close', resolve));
+
+			const halfOpen = new Duplex();
+			const halfOpenEvents = lifecycle(halfOpen);
+			halfOpen.push(null);
+			await new Promise(resolve => setTimeout(resolve, 10));
+			const beforeEnd = halfOpenEvents.join(',');
+			halfOpen.end();
+			await closed(halfOpen);
+
+			const full = new Duplex({ allowHalfOpen: false });
+			const fullEvents = lifecycle(full);
+			full.push(null);
+			await closed(full);
+
+			const destroyed = new Duplex();
+			const destroyedEvents = lifecycle(destroyed);
+			destroyed.destroy(new Error('boom'));
+			destroyed.destroy();
+			await closed(destroyed);
+
+			return [beforeEnd, halfOpenEvents.join(','), fullEvents.join(','), destroyedEvents.join(',')].join('|');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+	if want := "end|end,finish,close|end,finish,close|error,close"; value != want {
+		t.Errorf("duplex events = %v, want %s", value, want)
+	}
+}
@@ -0,0 +1,445 @@
+package stream
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// webSetup defines the WHATWG ReadableStream, with its default reader and
+// controller, and adds Readable.fromWeb and Readable.toWeb to the Readable
+// it is given. Underlying sources may have start, pull and cancel; pull is
+// called whenever the queue is below the highWaterMark of the strategy, or
+// a read is waiting, and never while a previous pull is pending. Chunks are
+// counted one each unless the strategy has a size function. Readers are
+// default readers only; byte sources work, with their chunks read as they
+// were enqueued.
+const webSetup = `
+(function (Readable) {
+	'use strict';
+	const kState = Symbol('state');
+	const kCreate = Symbol('create');
+	const kClosed = Symbol('closed');
+
+	function deferred() {
+		const d = {};
+		d.promise = new Promise((resolve, reject) => {
+			d.resolve = resolve;
+			d.reject = reject;
+		});
+		return d;
+	}
+
+	function desiredSize(s) {
+		if (s.state === 'errored') {
+			return null;
+		}
+		if (s.state === 'closed') {
+			return 0;
+		}
+		return s.highWaterMark - s.queueSize;
+	}
+
+	function finishClose(s) {
+		s.state = 'closed';
+		for (const read of s.reads.splice(0)) {
+			read.resolve({ value: undefined, done: true });
+		}
+		if (s.reader) {
+			s.reader[kClosed].resolve();
+		}
+	}
+
+	function error(s, reason) {
+		if (s.state !== 'readable') {
+			return;
+		}
+		s.state = 'errored';
+		s.reason = reason;
+		s.queue = [];
+		s.queueSize = 0;
+		for (const read of s.reads.splice(0)) {
+			read.reject(reason);
+		}
+		if (s.reader) {
+			s.reader[kClosed].reject(reason);
+		}
+	}
+
+	function callPull(s) {
+		if (!s.started || s.state !== 'readable' || s.closeRequested) {
+			return;
+		}
+		if (s.reads.length === 0 && !(desiredSize(s) > 0)) {
+			return;
+		}
+		if (s.pulling) {
+			s.pullAgain = true;
+			return;
+		}
+		if (typeof s.source.pull !== 'function') {
+			return;
+		}
+		s.pulling = true;
+		let result;
+		try {
+			result = s.source.pull(s.controller);
+		} catch (err) {
+			result = Promise.reject(err);
+		}
+		Promise.resolve(result).then(() => {
+			s.pulling = false;
+			if (s.pullAgain) {
+				s.pullAgain = false;
+				callPull(s);
+			}
+		}, (err) => error(s, err));
+	}
+
+	function read(s) {
+		if (s.queue.length > 0) {
+			const entry = s.queue.shift();
+			s.queueSize -= entry.size;
+			if (s.closeRequested && s.queue.length === 0) {
+				finishClose(s);
+			} else {
+				callPull(s);
+			}
+			return Promise.resolve({ value: entry.chunk, done: false });
+		}
+		if (s.state === 'closed') {
+			return Promise.resolve({ value: undefined, done: true });
+		}
+		if (s.state === 'errored') {
+			return Promise.reject(s.reason);
+		}
+		const pending = deferred();
+		s.reads.push(pending);
+		callPull(s);
+		return pending.promise;
+	}
+
+	function cancel(s, reason) {
+		if (s.state === 'closed') {
+			return Promise.resolve();
+		}
+		if (s.state === 'errored') {
+			return Promise.reject(s.reason);
+		}
+		s.queue = [];
+		s.queueSize = 0;
+		finishClose(s);
+		try {
+			const result = typeof s.source.cancel === 'function' ? s.source.cancel(reason) : undefined;
+			return Promise.resolve(result).then(() => undefined);
+		} catch (err) {
+			return Promise.reject(err);
+		}
+	}
+
+	class ReadableStreamDefaultController {
+		constructor(key, s) {
+			if (key !== kCreate) {
+				throw new TypeError('Illegal constructor');
+			}
+			this[kState] = s;
+		}
+
+		get desiredSize() {
+			return desiredSize(this[kState]);
+		}
+
+		enqueue(chunk) {
+			const s = this[kState];
+			if (s.closeRequested || s.state !== 'readable') {
+				throw new TypeError('Cannot enqueue to a closed stream');
+			}
+			if (s.reads.length > 0) {
+				s.reads.shift().resolve({ value: chunk, done: false });
+			} else {
+				let size = 1;
+				if (s.size) {
+					try {
+						size = Number(s.size(chunk));
+					} catch (err) {
+						error(s, err);
+						throw err;
+					}
+				}
+				s.queue.push({ chunk, size });
+				s.queueSize += size;
+			}
+			callPull(s);
+		}
+
+		close() {
+			const s = this[kState];
+			if (s.closeRequested || s.state !== 'readable') {
+				throw new TypeError('The stream is already closed');
+			}
+			s.closeRequested = true;
+			if (s.queue.length === 0) {
+				finishClose(s);
+			}
+		}
+
+		error(reason) {
+			error(this[kState], reason);
+		}
+	}
+
+	class ReadableStreamDefaultReader {
+		constructor(stream) {
+			if (!(stream instanceof ReadableStream)) {
+				throw new TypeError('ReadableStreamDefaultReader requires a ReadableStream');
+			}
+			const s = stream[kState];
+			if (s.reader) {
+				throw new TypeError('ReadableStream is locked');
+			}
+			this[kState] = s;
+			this[kClosed] = deferred();
+			this[kClosed].promise.catch(() => {});
+			s.reader = this;
+			if (s.state === 'closed') {
+				this[kClosed].resolve();
+			} else if (s.state === 'errored') {
+				this[kClosed].reject(s.reason);
+			}
+		}
+
+		get closed() {
+			return this[kClosed].promise;
+		}
+
+		read() {
+			if (!this[kState]) {
+				return Promise.reject(new TypeError('The reader has been released'));
+			}
+			return read(this[kState]);
+		}
+
+		cancel(reason) {
+			if (!this[kState]) {
+				return Promise.reject(new TypeError('The reader has been released'));
+			}
+			return cancel(this[kState], reason);
+		}
+
+		releaseLock() {
+			const s = this[kState];
+			if (!s) {
+				return;
+			}
+			const released = new TypeError('The reader has been released');
+			for (const pending of s.reads.splice(0)) {
+				pending.reject(released);
+			}
+			if (s.state === 'readable') {
+				this[kClosed].reject(released);
+			}
+			s.reader = null;
+			this[kState] = null;
+		}
+	}
+
+	class ReadableStream {
+		constructor(source, strategy) {
+			source = source === undefined || source === null ? {} : source;
+			strategy = strategy || {};
+			const highWaterMark = strategy.highWaterMark === undefined ? 1 : Number(strategy.highWaterMark);
+			if (Number.isNaN(highWaterMark) || highWaterMark < 0) {
+				throw new RangeError('highWaterMark must be a non-negative number');
+			}
+			const s = {
+				source, highWaterMark,
+				size: typeof strategy.size === 'function' ? strategy.size : null,
+				state: 'readable', reason: undefined,
+				queue: [], queueSize: 0, reads: [], reader: null,
+				started: false, pulling: false, pullAgain: false, closeRequested: false,
+			};
+			this[kState] = s;
+			s.controller = new ReadableStreamDefaultController(kCreate, s);
+			const started = typeof source.start === 'function' ? source.start(s.controller) : undefined;
+			Promise.resolve(started).then(() => {
+				s.started = true;
+				callPull(s);
+			}, (err) => error(s, err));
+		}
+
+		get locked() {
+			return this[kState].reader !== null;
+		}
+
+		cancel(reason) {
+			if (this.locked) {
+				return Promise.reject(new TypeError('Cannot cancel a locked ReadableStream'));
+			}
+			return cancel(this[kState], reason);
+		}
+
+		getReader(options) {
+			if (options && options.mode !== undefined) {
+				throw new TypeError('Only default readers are supported');
+			}
+			return new ReadableStreamDefaultReader(this);
+		}
+
+		tee() {
+			const reader = this.getReader();
+			const controllers = [];
+			const canceled = [false, false];
+			let reading = false;
+			const pull = () => {
+				if (reading) {
+					return;
+				}
+				reading = true;
+				return reader.read().then(({ value, done }) => {
+					reading = false;
+					controllers.forEach((c, i) => {
+						if (canceled[i]) {
+							return;
+						}
+						if (done) {
+							c.close();
+						} else {
+							c.enqueue(value);
+						}
+					});
+				}, (err) => controllers.forEach(c => c.error(err)));
+			};
+			const branch = (i) => new ReadableStream({
+				start(c) {
+					controllers[i] = c;
+				},
+				pull,
+				cancel(reason) {
+					canceled[i] = true;
+					if (canceled[0] && canceled[1]) {
+						return reader.cancel(reason);
+					}
+				},
+			});
+			return [branch(0), branch(1)];
+		}
+
+		values(options) {
+			const reader = this.getReader();
+			const preventCancel = !!(options && options.preventCancel);
+			return {
+				next() {
+					return reader.read().then((result) => {
+						if (result.done) {
+							reader.releaseLock();
+						}
+						return result;
+					});
+				},
+				return(value) {
+					const done = { value, done: true };
+					if (preventCancel) {
+						reader.releaseLock();
+						return Promise.resolve(done);
+					}
+					const canceled = reader.cancel(value);
+					reader.releaseLock();
+					return canceled.then(() => done);
+				},
+				[Symbol.asyncIterator]() {
+					return this;
+				},
+			};
+		}
+
+		[Symbol.asyncIterator](options) {
+			return this.values(options);
+		}
+
+		get [Symbol.toStringTag]() {
+			return 'ReadableStream';
+		}
+
+		static from(iterable) {
+			if (iterable instanceof ReadableStream) {
+				return iterable;
+			}
+			const method = iterable !== null && iterable !== undefined
+				? iterable[Symbol.asyncIterator] || iterable[Symbol.iterator] : undefined;
+			if (typeof method !== 'function') {
+				throw new TypeError('ReadableStream.from requires an iterable');
+			}
+			const iterator = method.call(iterable);
+			return new ReadableStream({
+				pull(c) {
+					return Promise.resolve(iterator.next()).then((result) => {
+						if (result.done) {
+							c.close();
+						} else {
+							return Promise.resolve(result.value).then(value => c.enqueue(value));
+						}
+					});
+				},
+				cancel(reason) {
+					if (typeof iterator.return === 'function') {
+						return iterator.return(reason);
+					}
+				},
+			}, { highWaterMark: 0 });
+		}
+	}
+
+	// Readable.fromWeb reads a ReadableStream as a Readable in object mode
+	Readable.fromWeb = (stream) => {
+		if (!(stream instanceof ReadableStream)) {
+			throw new TypeError('Readable.fromWeb requires a ReadableStream');
+		}
+		return Readable.from(stream);
+	};
+
+	// Readable.toWeb exposes a Readable as a ReadableStream, pausing it
+	// while the stream's queue is full
+	Readable.toWeb = (readable) => new ReadableStream({
+		start(c) {
+			readable.on('data', (chunk) => {
+				c.enqueue(chunk);
+				if (c.desiredSize <= 0) {
+					readable.pause();
+				}
+			});
+			readable.on('end', () => c.close());
+			readable.on('error', (err) => c.error(err));
+		},
+		pull() {
+			readable.resume();
+		},
+		cancel(reason) {
+			readable.destroy(reason);
+		},
+	}, { highWaterMark: 16 });
+
+	return { ReadableStream, ReadableStreamDefaultReader, ReadableStreamDefaultController };
+})
+`
+
+// webNames are the exports of stream/web, which are also globals
+var webNames = []string{"ReadableStream", "ReadableStreamDefaultReader", "ReadableStreamDefaultController"}
+
+// WebExports builds stream/web, adding Readable.fromWeb and Readable.toWeb
+// to readable, the Readable of Exports. It must be called on the JS thread.
+func (b *Bridge) WebExports(readable goja.Value) (*goja.Object, error) {
+	setup, err := jsprogram.Run(b.vm, "stream-web-setup", webSetup)
+	if err != nil {
+		return nil, err
+	}
+	build, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("stream/web setup did not return a function")
+	}
+	exports, err := build(goja.Undefined(), readable)
+	if err != nil {
+		return nil, err
+	}
+	return exports.ToObject(b.vm), nil
+}
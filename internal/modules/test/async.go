@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// callAsync calls a test or hook on the JS thread, through the queue, and
+// waits for it to finish. A function declaring a parameter is passed a
+// done callback and finishes when it is called: done() passes and
+// done(err) fails. One returning a thenable finishes when that settles.
+// Waiting stops with ctx.Err() when ctx is done, which is how async tests
+// time out.
+func (b *Bridge) callAsync(ctx context.Context, fn goja.Value) error {
+	settled := make(chan error, 1)
+	var once sync.Once
+	settle := func(err error) {
+		once.Do(func() { settled <- err })
+	}
+
+	async := false
+	start := func(call goja.FunctionCall) goja.Value {
+		vm := b.runtime.GetGojaRuntime()
+		callable, _ := goja.AssertFunction(fn)
+
+		var args []goja.Value
+		if fn.ToObject(vm).Get("length").ToInteger() > 0 {
+			async = true
+			args = append(args, vm.ToValue(func(err goja.Value) {
+				if isNullish(err) {
+					settle(nil)
+				} else {
+					settle(rejection(err))
+				}
+			}))
+		}
+
+		result, err := callable(goja.Undefined(), args...)
+		if err != nil {
+			panic(err)
+		}
+		if !async {
+			async = b.then(result, settle)
+		}
+		return goja.Undefined()
+	}
+
+	var err error
+	if caller, ok := b.runtime.(contextCaller); ok {
+		err = caller.CallJSFunctionContext(ctx, start)
+	} else {
+		err = b.runtime.CallJSFunction(start)
+	}
+	if err != nil || !async {
+		return err
+	}
+
+	select {
+	case err := <-settled:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// then calls settle once value settles, if it is a thenable, and reports
+// whether it is. It must run on the JS thread.
+func (b *Bridge) then(value goja.Value, settle func(error)) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return false
+	}
+	then, ok := goja.AssertFunction(obj.Get("then"))
+	if !ok {
+		return false
+	}
+
+	vm := b.runtime.GetGojaRuntime()
+	_, err := then(obj, vm.ToValue(func(goja.Value) {
+		settle(nil)
+	}), vm.ToValue(func(reason goja.Value) {
+		settle(rejection(reason))
+	}))
+	if err != nil {
+		settle(err)
+	}
+	return true
+}
+
+// rejection describes the reason a promise was rejected with, or a done
+// callback was called with, as a test failure. Failed expectations keep
+// their message as it is.
+func rejection(reason goja.Value) error {
+	if goErr, ok := reason.Export().(error); ok {
+		return goErr
+	}
+	if obj, ok := reason.(*goja.Object); ok {
+		if message := obj.Get("message"); !isNullish(message) {
+			return errors.New(reason.String())
+		}
+	}
+	return fmt.Errorf("rejected with %s", reason.String())
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
@@ -0,0 +1,106 @@
+package test_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/internal/runtime"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestTimeouts(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	cfg := &config.PackageJSON{}
+	cfg.Gode.Test.Timeout = 200
+	if err := rt.Configure(cfg); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "slow.test.js")
+	os.WriteFile(testFile, []byte(`
+		describe('slow', { timeout: 100 }, () => {
+			test('inherits', () => { while (true) {} });
+			test('own', () => { while (true) {} }, { timeout: 50 });
+		});
+		describe('config', () => {
+			test('default', () => { while (true) {} });
+			test('after', () => expect(1).toBe(1));
+		});
+	`), 0644)
+
+	start := time.Now()
+	results, err := rt.RunTests([]string{testFile})
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("RunTests() took %v", elapsed)
+	}
+	if len(results) != 2 || results[1].Passed != 1 {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	for i, want := range []string{"timed out after 100ms", "timed out after 50ms"} {
+		if got := results[0].Tests[i].Error; !strings.Contains(got, want) {
+			t.Errorf("%s: error %q does not contain %q", results[0].Tests[i].Name, got, want)
+		}
+	}
+	if got := results[1].Tests[0].Error; !strings.Contains(got, "timed out after 200ms") {
+		t.Errorf("default: error %q", got)
+	}
+}
+
+func TestAsyncTests(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "async.test.js")
+	os.WriteFile(testFile, []byte(`
+		const later = value => new Promise(resolve => setTimeout(() => resolve(value), 10));
+		let order = [];
+
+		describe('async', () => {
+			beforeEach(async () => {
+				order.push(await later('before'));
+			});
+			test('awaits', async () => {
+				expect(await later(2)).toBe(2);
+				expect(order).toEqual(['before']);
+			});
+			test('rejects', async () => {
+				expect(await later(1)).toBe(2);
+			});
+			test('done', done => {
+				setTimeout(() => {
+					expect(order).toHaveLength(3);
+					done();
+				}, 10);
+			});
+			test('done with an error', done => {
+				setTimeout(() => done(new Error('callback failed')), 10);
+			});
+			test('never settles', () => new Promise(() => {}), { timeout: 50 });
+		});
+	`), 0644)
+
+	results, err := rt.RunTests([]string{testFile})
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Passed != 2 || results[0].Failed != 3 {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	for i, want := range map[int]string{1: "expected 1 to be 2", 3: "callback failed", 4: "timed out after 50ms"} {
+		if got := results[0].Tests[i].Error; !strings.Contains(got, want) {
+			t.Errorf("%s: error %q does not contain %q", results[0].Tests[i].Name, got, want)
+		}
+	}
+}
@@ -1,8 +1,16 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/modules/http"
 )
 
 // RuntimeInterface represents the methods we need from the runtime
@@ -12,29 +20,111 @@ type RuntimeInterface interface {
 	RunScript(name string, source string) (interface{}, error)
 	GetGojaRuntime() *goja.Runtime
 	CallJSFunction(fn interface{}) error
+	QueueJSOperation(fn func())
+}
+
+// contextCaller is implemented by runtimes that can interrupt a JS call
+// when its context is done, which is how tests time out
+type contextCaller interface {
+	CallJSFunctionContext(ctx context.Context, fn interface{}) error
 }
 
 // Bridge provides a basic test module implementation that works through runtime
 type Bridge struct {
-	runtime RuntimeInterface
-	runner  *TestRunner
+	runtime   RuntimeInterface
+	runner    *TestRunner
+	mu        sync.Mutex
+	reporters map[string]Reporter    // registered by plugins and test.registerReporter
+	outputs   []io.Closer            // files the built-in reporters of the run write to
+	update    bool                   // toMatchFile writes golden files
+	mocks     map[string]*moduleMock // modules replaced with jest.mock
+	actual    map[string]int         // specifiers being loaded by jest.requireActual
 }
 
 // NewBridge creates a new test bridge
 func NewBridge(runtime RuntimeInterface) *Bridge {
 	return &Bridge{
-		runtime: runtime,
-		runner:  NewTestRunner(),
+		runtime:   runtime,
+		runner:    NewTestRunner(),
+		reporters: make(map[string]Reporter),
+		mocks:     make(map[string]*moduleMock),
+		actual:    make(map[string]int),
+	}
+}
+
+// RegisterReporter makes reporter available to gode test --reporter name
+func (b *Bridge) RegisterReporter(name string, reporter Reporter) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.reporters[name]; exists || builtinReporters[name] != nil {
+		return fmt.Errorf("reporter %q is already registered", name)
+	}
+	b.reporters[name] = reporter
+	return nil
+}
+
+// Reporter resolves a --reporter value: the name of a built-in or
+// registered reporter, or the path of a JS module exporting one. A built-in
+// reporter writes to stdout, or to the file given as name=file, which stays
+// open until the run ends.
+func (b *Bridge) Reporter(spec string) (Reporter, error) {
+	if isReporterPath(spec) {
+		return b.loadReporter(spec)
+	}
+
+	name, output, toFile := strings.Cut(spec, "=")
+	if create := builtinReporters[name]; create != nil {
+		if !toFile {
+			return create(os.Stdout), nil
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			return nil, err
+		}
+		b.mu.Lock()
+		b.outputs = append(b.outputs, f)
+		b.mu.Unlock()
+		return create(f), nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	reporter, ok := b.reporters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown reporter %q", name)
+	}
+	if toFile {
+		return nil, fmt.Errorf("reporter %q writes where it chooses; only built-in reporters take =file", name)
+	}
+	return reporter, nil
+}
+
+// closeOutputs closes the files the reporters of a run wrote to
+func (b *Bridge) closeOutputs() {
+	b.mu.Lock()
+	outputs := b.outputs
+	b.outputs = nil
+	b.mu.Unlock()
+	for _, output := range outputs {
+		output.Close()
 	}
 }
 
 // Reset clears all test state for a fresh run
 func (b *Bridge) Reset() {
 	b.runner.Reset()
+	b.resetModuleMocks()
 }
 
-// wrapJSFunction wraps a JavaScript function to return a Go error
-func (b *Bridge) wrapJSFunction(fn interface{}) func() error {
+// wrapJSFunction wraps a JavaScript test or hook to return a Go error. An
+// async one, returning a promise or taking a done callback, is waited for
+// (see callAsync).
+func (b *Bridge) wrapJSFunction(fn goja.Value) func() error {
 	return func() (err error) {
 		defer func() {
 			if r := recover(); r != nil {
@@ -47,32 +137,49 @@ func (b *Bridge) wrapJSFunction(fn interface{}) func() error {
 			}
 		}()
 		
-		// Handle Goja function type specifically - use CallJSFunction which goes through queue
-		if jsFunc, ok := fn.(func(goja.FunctionCall) goja.Value); ok {
-			return b.runtime.CallJSFunction(jsFunc)
+		if _, ok := goja.AssertFunction(fn); !ok {
+			return fmt.Errorf("cannot execute function (type: %T)", fn.Export())
 		}
-		
-		return fmt.Errorf("cannot execute function (type: %T)", fn)
+		return b.callAsync(b.runner.Context(), fn)
+	}
+}
+
+// testOptions converts the options given to describe or test: an object
+// with only, skip and timeout, or a timeout in milliseconds as in Jest
+func testOptions(v interface{}) *TestOptions {
+	switch v := v.(type) {
+	case int64:
+		return &TestOptions{Timeout: int(v)}
+	case float64:
+		return &TestOptions{Timeout: int(v)}
+	case map[string]interface{}:
+		opts := &TestOptions{}
+		opts.Only, _ = v["only"].(bool)
+		opts.Skip, _ = v["skip"].(bool)
+		switch timeout := v["timeout"].(type) {
+		case int64:
+			opts.Timeout = int(timeout)
+		case float64:
+			opts.Timeout = int(timeout)
+		}
+		return opts
 	}
+	return nil
 }
 
 // RegisterGlobals registers test functions as global variables in the JS runtime
 func (b *Bridge) RegisterGlobals() error {
-	// Register describe function
-	b.runtime.SetGlobal("describe", func(name string, fn func()) {
-		b.runner.Describe(name, fn)
+	// Register describe, which the wrapper below gives its optional options
+	// argument and its skip and only variants
+	b.runtime.SetGlobal("__describe", func(name string, fn func(), options interface{}) {
+		b.runner.DescribeWithOptions(name, fn, testOptions(options))
 	})
 	
 	// Register test function (and its alias 'it')
-	testFn := func(name string, fn interface{}, options ...interface{}) {
+	testFn := func(name string, fn goja.Value, options ...interface{}) {
 		var opts *TestOptions
 		if len(options) > 0 {
-			// Check if options is a map with timeout
-			if optMap, ok := options[0].(map[string]interface{}); ok {
-				if timeout, ok := optMap["timeout"].(float64); ok {
-					opts = &TestOptions{Timeout: int(timeout)}
-				}
-			}
+			opts = testOptions(options[0])
 		}
 		
 		b.runner.Test(name, b.wrapJSFunction(fn), opts)
@@ -81,30 +188,68 @@ func (b *Bridge) RegisterGlobals() error {
 	b.runtime.SetGlobal("it", testFn)
 	
 	// Register test.skip function
-	b.runtime.SetGlobal("__testSkip", func(name string, fn interface{}) {
+	b.runtime.SetGlobal("__testSkip", func(name string, fn goja.Value) {
 		b.runner.Test(name, b.wrapJSFunction(fn), &TestOptions{Skip: true})
 	})
 	
 	// Register test.only function  
-	b.runtime.SetGlobal("__testOnly", func(name string, fn interface{}) {
+	b.runtime.SetGlobal("__testOnly", func(name string, fn goja.Value) {
 		b.runner.Test(name, b.wrapJSFunction(fn), &TestOptions{Only: true})
 	})
 	
+	// Register test.registerReporter function
+	b.runtime.SetGlobal("__testRegisterReporter", func(name string, reporter goja.Value) {
+		obj, ok := reporter.(*goja.Object)
+		if !ok {
+			panic(b.runtime.GetGojaRuntime().NewTypeError("test.registerReporter expects a reporter object"))
+		}
+		if err := b.RegisterReporter(name, &jsReporter{runtime: b.runtime, obj: obj}); err != nil {
+			panic(b.runtime.GetGojaRuntime().NewGoError(err))
+		}
+	})
+	
 	// Create JavaScript wrapper to make test both a function and have properties
 	// Use let or var instead of const to allow redeclaration, or check if already exists
 	testWrapper := `
+		// test.prop(name, generator, property, options) checks a gode:fuzz property
+		var __testProp = function(name, gen, fn, options) {
+			return __test(name, function() {
+				require('gode:fuzz').assert(gen, fn, options);
+			}, options);
+		};
 		if (typeof globalThis.test === 'undefined') {
 			const test = function(name, fn, options) {
 				return __test(name, fn, options);
 			};
 			test.skip = __testSkip;
 			test.only = __testOnly;
+			test.registerReporter = __testRegisterReporter;
+			test.prop = __testProp;
 			globalThis.test = test;
 		} else {
 			// Update existing test functions
 			globalThis.test.skip = __testSkip;
 			globalThis.test.only = __testOnly;
+			globalThis.test.registerReporter = __testRegisterReporter;
+			globalThis.test.prop = __testProp;
 		}
+		it.skip = __testSkip;
+		it.only = __testOnly;
+		
+		// describe(name, fn) or describe(name, {timeout: ms}, fn)
+		var __describeWith = function(flags) {
+			return function(name, options, fn) {
+				if (typeof options === 'function') {
+					var swap = fn;
+					fn = options;
+					options = swap;
+				}
+				return __describe(name, fn, Object.assign({}, options, flags));
+			};
+		};
+		globalThis.describe = __describeWith({});
+		describe.skip = __describeWith({skip: true});
+		describe.only = __describeWith({only: true});
 	`
 	
 	// Execute the wrapper script
@@ -113,8 +258,17 @@ func (b *Bridge) RegisterGlobals() error {
 	}
 	
 	// Register simple error throwing function for JavaScript-based expectations
+	// as a JS error, which a failing test rejects or throws with
 	b.runtime.SetGlobal("__throwTestError", func(message string) {
-		panic(fmt.Errorf(message))
+		panic(b.runtime.GetGojaRuntime().NewGoError(fmt.Errorf("%s", message)))
+	})
+	
+	// Register the golden file comparison behind expect().toMatchFile
+	b.runtime.SetGlobal("__testMatchFile", func(actual goja.Value, path string) string {
+		b.mu.Lock()
+		update := b.update
+		b.mu.Unlock()
+		return matchFile(goldenContent(actual), goldenPath(b.runner.Running(), path), path, update)
 	})
 	
 	// Setup expect function in JavaScript
@@ -122,20 +276,35 @@ func (b *Bridge) RegisterGlobals() error {
 		return fmt.Errorf("failed to setup expect function: %w", err)
 	}
 	
+	// Setup jest.fn, jest.spyOn and jest.mock
+	if err := b.setupMocksInJS(); err != nil {
+		return err
+	}
+	
+	// Register testServer(app) for HTTP tests without a socket
+	b.runtime.SetGlobal("testServer", func(call goja.FunctionCall) goja.Value {
+		vm := b.runtime.GetGojaRuntime()
+		server, err := http.NewTestServer(vm, b.runtime, call.Argument(0))
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		return server
+	})
+	
 	// Register hook functions
-	b.runtime.SetGlobal("beforeEach", func(fn interface{}) {
+	b.runtime.SetGlobal("beforeEach", func(fn goja.Value) {
 		b.runner.BeforeEach(b.wrapJSFunction(fn))
 	})
 	
-	b.runtime.SetGlobal("afterEach", func(fn interface{}) {
+	b.runtime.SetGlobal("afterEach", func(fn goja.Value) {
 		b.runner.AfterEach(b.wrapJSFunction(fn))
 	})
 	
-	b.runtime.SetGlobal("beforeAll", func(fn interface{}) {
+	b.runtime.SetGlobal("beforeAll", func(fn goja.Value) {
 		b.runner.BeforeAll(b.wrapJSFunction(fn))
 	})
 	
-	b.runtime.SetGlobal("afterAll", func(fn interface{}) {
+	b.runtime.SetGlobal("afterAll", func(fn goja.Value) {
 		b.runner.AfterAll(b.wrapJSFunction(fn))
 	})
 	
@@ -145,6 +314,18 @@ func (b *Bridge) RegisterGlobals() error {
 // setupExpectInJS creates the expect function entirely in JavaScript
 func (b *Bridge) setupExpectInJS() error {
 	expectJS := `
+		// __calls returns the calls recorded by a jest.fn() or jest.spyOn()
+		// mock, each as the JSON of its arguments
+		function __calls(actual) {
+			if (typeof actual !== 'function' || !actual._isMockFunction) {
+				__throwTestError('expected a mock function but got ' + JSON.stringify(actual));
+			}
+			return actual.mock.calls.map(function(args) { return JSON.stringify(args); });
+		}
+		function __describeCalls(actual) {
+			var calls = __calls(actual);
+			return calls.length === 0 ? 'it was not called' : 'it was called with ' + calls.join(', ');
+		}
 		function expect(actual) {
 			return {
 				toBe: function(expected) {
@@ -276,6 +457,51 @@ func (b *Bridge) setupExpectInJS() error {
 					}
 					return this;
 				},
+				toHaveBeenCalled: function() {
+					if (__calls(actual).length === 0) {
+						__throwTestError('expected ' + actual.getMockName() + ' to have been called');
+					}
+					return this;
+				},
+				toHaveBeenCalledTimes: function(times) {
+					var calls = __calls(actual).length;
+					if (calls !== times) {
+						__throwTestError('expected ' + actual.getMockName() + ' to have been called ' + times + ' times but it was called ' + calls + ' times');
+					}
+					return this;
+				},
+				toHaveBeenCalledWith: function() {
+					var expected = JSON.stringify(Array.prototype.slice.call(arguments));
+					if (__calls(actual).indexOf(expected) < 0) {
+						__throwTestError('expected ' + actual.getMockName() + ' to have been called with ' + expected + ' but ' + __describeCalls(actual));
+					}
+					return this;
+				},
+				toHaveBeenLastCalledWith: function() {
+					var expected = JSON.stringify(Array.prototype.slice.call(arguments));
+					var calls = __calls(actual);
+					if (calls[calls.length - 1] !== expected) {
+						__throwTestError('expected ' + actual.getMockName() + ' to have been last called with ' + expected + ' but ' + __describeCalls(actual));
+					}
+					return this;
+				},
+				toHaveReturnedWith: function(expected) {
+					__calls(actual);
+					var returned = actual.mock.results.some(function(result) {
+						return result.type === 'return' && JSON.stringify(result.value) === JSON.stringify(expected);
+					});
+					if (!returned) {
+						__throwTestError('expected ' + actual.getMockName() + ' to have returned ' + JSON.stringify(expected));
+					}
+					return this;
+				},
+				toMatchFile: function(path) {
+					var message = __testMatchFile(actual, path);
+					if (message) {
+						__throwTestError(message);
+					}
+					return this;
+				},
 				not: {
 					toBe: function(expected) {
 						if (actual === expected) {
@@ -351,6 +577,17 @@ func (b *Bridge) setupExpectInJS() error {
 							__throwTestError('expected ' + actual + ' not to be NaN');
 						}
 					},
+					toHaveBeenCalled: function() {
+						if (__calls(actual).length > 0) {
+							__throwTestError('expected ' + actual.getMockName() + ' not to have been called but ' + __describeCalls(actual));
+						}
+					},
+					toHaveBeenCalledWith: function() {
+						var expected = JSON.stringify(Array.prototype.slice.call(arguments));
+						if (__calls(actual).indexOf(expected) >= 0) {
+							__throwTestError('expected ' + actual.getMockName() + ' not to have been called with ' + expected);
+						}
+					},
 					toBeInstanceOf: function(expectedConstructor) {
 						if (actual instanceof expectedConstructor) {
 							var actualType = actual && actual.constructor ? actual.constructor.name : typeof actual;
@@ -372,4 +609,40 @@ func (b *Bridge) setupExpectInJS() error {
 // RunTests executes all registered tests
 func (b *Bridge) RunTests() ([]SuiteResult, error) {
 	return b.runner.Run()
-}
\ No newline at end of file
+}
+
+// RunTestsWithReporters executes all registered tests, loaded from files,
+// reporting to the reporters named by specs (see Reporter)
+func (b *Bridge) RunTestsWithReporters(files []string, specs []string) ([]SuiteResult, error) {
+	return b.RunTestsWithOptions(files, RunOptions{Reporters: specs})
+}
+
+// RunTestsWithOptions executes all registered tests, loaded from files,
+// as configured by opts
+func (b *Bridge) RunTestsWithOptions(files []string, opts RunOptions) ([]SuiteResult, error) {
+	defer b.closeOutputs()
+
+	rs := make([]Reporter, 0, len(opts.Reporters))
+	for _, spec := range opts.Reporters {
+		reporter, err := b.Reporter(spec)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, reporter)
+	}
+
+	b.mu.Lock()
+	b.update = opts.Update
+	b.mu.Unlock()
+	b.runner.SetDefaultTimeout(opts.Timeout)
+	b.runner.SetFilter(opts.Filter)
+	b.runner.SetBail(opts.Bail)
+
+	return b.runner.RunWithReporters(files, rs...)
+}
+
+// SetFile records the test file being loaded, so that the tests it defines
+// resolve golden files next to it
+func (b *Bridge) SetFile(path string) {
+	b.runner.SetFile(path)
+}
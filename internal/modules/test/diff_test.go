@@ -0,0 +1,38 @@
+package test
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"added to empty", "", "x\n", "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+x\n"},
+		{
+			"changed with context",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n",
+			"--- old\n+++ new\n@@ -2,9 +2,10 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n 9\n 10\n+11\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ny\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := unifiedDiff("old", "new", tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: unifiedDiff() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	if got := normalizeLineEndings("a\r\nb\rc\n"); got != "a\nb\nc\n" {
+		t.Errorf("normalizeLineEndings() = %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// RunOptions configures a test run
+type RunOptions struct {
+	Reporters []string       // see Bridge.Reporter; none reports nothing
+	Update    bool           // write golden files instead of comparing with them
+	Timeout   time.Duration  // tests' timeout unless set in their options; 0 means DefaultTimeout
+	Filter    *regexp.Regexp // run only the tests whose full name matches; see TestRunner.SetFilter
+	Bail      bool           // skip the remaining tests after the first failure
+}
+
+// goldenPath resolves the path given to toMatchFile against the directory
+// of the running test's file, or the working directory outside a test
+func goldenPath(running *Test, path string) string {
+	if filepath.IsAbs(path) || running == nil || running.File == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		return abs
+	}
+	return filepath.Join(filepath.Dir(running.File), path)
+}
+
+// goldenContent is what toMatchFile compares: strings as they are, and
+// other values as JSON indented by two spaces, ending with a newline. The
+// JSON global does not indent, so it is formatted here.
+func goldenContent(actual goja.Value) string {
+	if s, ok := actual.Export().(string); ok {
+		return s
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(actual.Export()); err != nil {
+		return actual.String() + "\n"
+	}
+	return out.String()
+}
+
+// normalizeLineEndings turns CRLF and lone CR line endings into LF, so
+// golden files compare equal whichever way they were checked out
+func normalizeLineEndings(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// matchFile compares actual with the golden file at path, or writes it
+// when update is set. It returns the failure message, or "" on a match.
+func matchFile(actual, path, name string, update bool) string {
+	actual = normalizeLineEndings(actual)
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Sprintf("failed to write %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(actual), 0644); err != nil {
+			return fmt.Sprintf("failed to write %s: %v", name, err)
+		}
+		return ""
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("golden file %s does not exist; run gode test --update to create it", name)
+	}
+	if err != nil {
+		return fmt.Sprintf("failed to read %s: %v", name, err)
+	}
+
+	expected := normalizeLineEndings(string(data))
+	if expected == actual {
+		return ""
+	}
+	return fmt.Sprintf("value does not match %s (run gode test --update to accept it)\n%s",
+		name, unifiedDiff(name, "received", expected, actual))
+}
@@ -0,0 +1,57 @@
+package test_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/modules/test"
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestMatchFile(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "golden.test.js")
+	os.WriteFile(testFile, []byte(`
+		describe('golden', () => {
+			test('json', () => expect({ name: 'gode', tags: ['a'] }).toMatchFile('fixtures/output.json'));
+			test('text', () => expect('one\ntwo\n').toMatchFile('fixtures/output.txt'));
+		});
+	`), 0644)
+
+	opts := test.RunOptions{Update: true}
+	results, err := rt.RunTestsWithOptions([]string{testFile}, opts)
+	if err != nil || results[0].Passed != 2 {
+		t.Fatalf("Update run = %+v, %v", results, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "fixtures", "output.json"))
+	if want := "{\n  \"name\": \"gode\",\n  \"tags\": [\n    \"a\"\n  ]\n}\n"; string(data) != want {
+		t.Errorf("Written golden file = %q, want %q", data, want)
+	}
+
+	// CRLF checkouts still match; a changed line fails with a diff
+	os.WriteFile(filepath.Join(dir, "fixtures", "output.txt"), []byte("one\r\ntwo\r\n"), 0644)
+	results, err = rt.RunTestsWithOptions([]string{testFile}, test.RunOptions{})
+	if err != nil || results[0].Passed != 2 {
+		t.Fatalf("Compare run = %+v, %v", results, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "fixtures", "output.txt"), []byte("one\nthree\n"), 0644)
+	results, err = rt.RunTestsWithOptions([]string{testFile}, test.RunOptions{})
+	if err != nil || results[0].Failed != 1 {
+		t.Fatalf("Mismatch run = %+v, %v", results, err)
+	}
+	message := results[0].Tests[1].Error
+	for _, want := range []string{"--- fixtures/output.txt", "+++ received", "@@ -1,2 +1,2 @@", "-three", "+two"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Mismatch message %q does not contain %q", message, want)
+		}
+	}
+}
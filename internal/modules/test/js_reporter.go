@@ -0,0 +1,153 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// jsReporter forwards events to a JS object with onRunStart, onSuiteStart,
+// onTestResult and onRunComplete methods, each of them optional
+type jsReporter struct {
+	runtime RuntimeInterface
+	obj     *goja.Object
+}
+
+func (j *jsReporter) OnRunStart(run RunInfo) {
+	j.call("onRunStart", map[string]interface{}{
+		"files":  run.Files,
+		"suites": run.Suites,
+		"tests":  run.Tests,
+	})
+}
+
+func (j *jsReporter) OnSuiteStart(suite SuiteInfo) {
+	j.call("onSuiteStart", map[string]interface{}{
+		"name":  suite.Name,
+		"path":  suite.Path,
+		"tests": suite.Tests,
+	})
+}
+
+func (j *jsReporter) OnTestResult(result TestEvent) {
+	value := testResultValue(result.TestResult)
+	value["suite"] = result.Suite
+	j.call("onTestResult", value)
+}
+
+func (j *jsReporter) OnRunComplete(summary RunSummary) {
+	suites := make([]interface{}, len(summary.Suites))
+	for i, suite := range summary.Suites {
+		tests := make([]interface{}, len(suite.Tests))
+		for k, t := range suite.Tests {
+			tests[k] = testResultValue(t)
+		}
+		suites[i] = map[string]interface{}{
+			"name":     suite.Name,
+			"tests":    tests,
+			"duration": millis(suite.Duration),
+			"passed":   suite.Passed,
+			"failed":   suite.Failed,
+			"skipped":  suite.Skipped,
+		}
+	}
+	j.call("onRunComplete", map[string]interface{}{
+		"suites":   suites,
+		"total":    summary.Total,
+		"passed":   summary.Passed,
+		"failed":   summary.Failed,
+		"skipped":  summary.Skipped,
+		"duration": millis(summary.Duration),
+		"success":  summary.Success(),
+	})
+}
+
+// call invokes a method of the reporter on the JS thread and waits for it.
+// A reporter that throws is reported but does not stop the run.
+func (j *jsReporter) call(method string, data map[string]interface{}) {
+	done := make(chan struct{})
+	j.runtime.QueueJSOperation(func() {
+		defer close(done)
+		fn, ok := goja.AssertFunction(j.obj.Get(method))
+		if !ok {
+			return
+		}
+		vm := j.runtime.GetGojaRuntime()
+		if _, err := fn(j.obj, vm.ToValue(data)); err != nil {
+			fmt.Fprintf(os.Stderr, "gode test: reporter %s failed: %v\n", method, err)
+		}
+	})
+	<-done
+}
+
+// testResultValue converts a result for JS, with durations in milliseconds
+func testResultValue(t TestResult) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     t.Name,
+		"status":   string(t.Status),
+		"duration": millis(t.Duration),
+		"error":    t.Error,
+		"stack":    t.Stack,
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// isReporterPath reports whether a --reporter value names a file rather
+// than a registered reporter
+func isReporterPath(spec string) bool {
+	return strings.HasPrefix(spec, ".") || filepath.IsAbs(spec) ||
+		strings.HasSuffix(spec, ".js") || strings.HasSuffix(spec, ".mjs")
+}
+
+// loadReporter requires a reporter module. It may export the reporter
+// object itself, or a class or function that is called with new to
+// create it.
+func (b *Bridge) loadReporter(path string) (Reporter, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		obj *goja.Object
+		err error
+	}
+	done := make(chan result, 1)
+	b.runtime.QueueJSOperation(func() {
+		vm := b.runtime.GetGojaRuntime()
+		require, ok := goja.AssertFunction(vm.Get("require"))
+		if !ok {
+			done <- result{err: fmt.Errorf("require is not available")}
+			return
+		}
+		exported, err := require(goja.Undefined(), vm.ToValue(abs))
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		if _, ok := goja.AssertConstructor(exported); ok {
+			obj, err := vm.New(exported)
+			done <- result{obj, err}
+			return
+		}
+		obj, ok := exported.(*goja.Object)
+		if !ok {
+			done <- result{err: fmt.Errorf("%s does not export a reporter", path)}
+			return
+		}
+		done <- result{obj: obj}
+	})
+
+	res := <-done
+	if res.err != nil {
+		return nil, fmt.Errorf("failed to load reporter %s: %w", path, res.err)
+	}
+	return &jsReporter{runtime: b.runtime, obj: res.obj}, nil
+}
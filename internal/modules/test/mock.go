@@ -0,0 +1,275 @@
+package test
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// moduleMock is a module replaced by jest.mock(specifier, factory). The
+// factory runs on the first require, and its result is what every require
+// returns from then on.
+type moduleMock struct {
+	factory goja.Callable
+	exports goja.Value
+}
+
+// moduleResolver is implemented by runtimes that can tell which module a
+// specifier names, so that a mock registered as '../src/db' also replaces
+// require('./db') in src
+type moduleResolver interface {
+	ResolveModule(specifier string) string
+}
+
+// moduleKey returns the key mocks of specifier are kept under: the module it
+// resolves to from the running code, or the specifier itself
+func (b *Bridge) moduleKey(specifier string) string {
+	if resolver, ok := b.runtime.(moduleResolver); ok {
+		return resolver.ResolveModule(specifier)
+	}
+	return specifier
+}
+
+// MockedModule returns the exports of a module replaced with jest.mock, for
+// require to return instead of the module itself. Specifiers are compared
+// by the module they resolve to. It must be called on the JS thread.
+func (b *Bridge) MockedModule(specifier string) (goja.Value, bool) {
+	key := b.moduleKey(specifier)
+	b.mu.Lock()
+	mock := b.mocks[key]
+	actual := b.actual[key] > 0
+	b.mu.Unlock()
+	if mock == nil || actual {
+		return nil, false
+	}
+
+	if mock.exports == nil {
+		exports, err := mock.factory(goja.Undefined())
+		if err != nil {
+			panic(err)
+		}
+		mock.exports = exports
+	}
+	return mock.exports, true
+}
+
+// mockModule implements jest.mock(specifier, factory)
+func (b *Bridge) mockModule(specifier string, factory goja.Value) {
+	fn, ok := goja.AssertFunction(factory)
+	if !ok {
+		panic(b.runtime.GetGojaRuntime().NewTypeError(fmt.Sprintf("jest.mock(%q) needs a factory function returning the module", specifier)))
+	}
+
+	key := b.moduleKey(specifier)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mocks[key] = &moduleMock{factory: fn}
+}
+
+// unmockModule implements jest.unmock(specifier)
+func (b *Bridge) unmockModule(specifier string) {
+	key := b.moduleKey(specifier)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.mocks, key)
+}
+
+// requireActual implements jest.requireActual(specifier): require, with
+// the mock of specifier, if any, left out. Modules it requires in turn still
+// get their mocks.
+func (b *Bridge) requireActual(specifier string) goja.Value {
+	vm := b.runtime.GetGojaRuntime()
+	require, ok := goja.AssertFunction(vm.Get("require"))
+	if !ok {
+		panic(vm.NewTypeError("require is not available"))
+	}
+
+	key := b.moduleKey(specifier)
+	b.mu.Lock()
+	b.actual[key]++
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.actual[key]--
+		b.mu.Unlock()
+	}()
+
+	exports, err := require(goja.Undefined(), vm.ToValue(specifier))
+	if err != nil {
+		panic(err)
+	}
+	return exports
+}
+
+// resetModuleMocks forgets the modules mocked by an earlier run
+func (b *Bridge) resetModuleMocks() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mocks = make(map[string]*moduleMock)
+	b.actual = make(map[string]int)
+}
+
+// setupMocksInJS creates the jest global: mock functions, spies and module
+// mocks
+func (b *Bridge) setupMocksInJS() error {
+	b.runtime.SetGlobal("__testMockModule", b.mockModule)
+	b.runtime.SetGlobal("__testUnmockModule", b.unmockModule)
+	b.runtime.SetGlobal("__testRequireActual", b.requireActual)
+
+	mocksJS := `
+		(function() {
+			var mocks = [];
+			var spies = [];
+
+			// createMockFn returns a function recording its calls, whose
+			// behaviour the mock* methods change. restore is called by
+			// mockRestore, for spies.
+			function createMockFn(impl, restore) {
+				var state = { impl: impl, once: [], name: 'jest.fn()' };
+
+				var mockFn = function() {
+					var args = Array.prototype.slice.call(arguments);
+					mockFn.mock.calls.push(args);
+					mockFn.mock.lastCall = args;
+					mockFn.mock.instances.push(this);
+					var result = { type: 'incomplete', value: undefined };
+					mockFn.mock.results.push(result);
+
+					var next = state.once.length > 0 ? state.once.shift() : state.impl;
+					try {
+						result.value = next ? next.apply(this, args) : undefined;
+						result.type = 'return';
+					} catch (e) {
+						result.value = e;
+						result.type = 'throw';
+						throw e;
+					}
+					return result.value;
+				};
+
+				var clear = function() {
+					mockFn.mock = { calls: [], results: [], instances: [], lastCall: undefined };
+				};
+				clear();
+
+				mockFn._isMockFunction = true;
+				mockFn.getMockName = function() { return state.name; };
+				mockFn.mockName = function(name) { state.name = name; return mockFn; };
+				mockFn.mockClear = function() { clear(); return mockFn; };
+				mockFn.mockReset = function() {
+					clear();
+					state.impl = undefined;
+					state.once = [];
+					return mockFn;
+				};
+				mockFn.mockRestore = function() {
+					mockFn.mockReset();
+					if (restore) {
+						restore();
+					}
+				};
+				mockFn.getMockImplementation = function() { return state.impl; };
+				mockFn.mockImplementation = function(fn) { state.impl = fn; return mockFn; };
+				mockFn.mockImplementationOnce = function(fn) { state.once.push(fn); return mockFn; };
+				mockFn.mockReturnThis = function() {
+					return mockFn.mockImplementation(function() { return this; });
+				};
+				mockFn.mockReturnValue = function(value) {
+					return mockFn.mockImplementation(function() { return value; });
+				};
+				mockFn.mockReturnValueOnce = function(value) {
+					return mockFn.mockImplementationOnce(function() { return value; });
+				};
+				mockFn.mockResolvedValue = function(value) {
+					return mockFn.mockImplementation(function() { return Promise.resolve(value); });
+				};
+				mockFn.mockResolvedValueOnce = function(value) {
+					return mockFn.mockImplementationOnce(function() { return Promise.resolve(value); });
+				};
+				mockFn.mockRejectedValue = function(value) {
+					return mockFn.mockImplementation(function() { return Promise.reject(value); });
+				};
+				mockFn.mockRejectedValueOnce = function(value) {
+					return mockFn.mockImplementationOnce(function() { return Promise.reject(value); });
+				};
+
+				mocks.push(mockFn);
+				return mockFn;
+			}
+
+			var jest = {
+				fn: function(impl) {
+					return createMockFn(impl);
+				},
+				// spyOn(object, method) replaces object[method] with a mock
+				// function calling the original until given another
+				// implementation; mockRestore puts the original back
+				spyOn: function(object, method) {
+					if (object === null || typeof object !== 'object' && typeof object !== 'function') {
+						throw new TypeError('jest.spyOn() needs an object, got ' + object);
+					}
+					var original = object[method];
+					if (typeof original !== 'function') {
+						throw new TypeError('Cannot spy on ' + String(method) + ', which is not a function');
+					}
+					if (original._isMockFunction) {
+						return original;
+					}
+					var own = Object.prototype.hasOwnProperty.call(object, method);
+					var spy = createMockFn(function() {
+						return original.apply(this, arguments);
+					}, function() {
+						if (own) {
+							object[method] = original;
+						} else {
+							delete object[method];
+						}
+					});
+					spy.mockName(String(method));
+					var reset = spy.mockReset;
+					spy.mockReset = function() {
+						reset();
+						return spy.mockImplementation(function() {
+							return original.apply(this, arguments);
+						});
+					};
+					object[method] = spy;
+					spies.push(spy);
+					return spy;
+				},
+				isMockFunction: function(fn) {
+					return typeof fn === 'function' && fn._isMockFunction === true;
+				},
+				clearAllMocks: function() {
+					mocks.forEach(function(m) { m.mockClear(); });
+					return jest;
+				},
+				resetAllMocks: function() {
+					mocks.forEach(function(m) { m.mockReset(); });
+					return jest;
+				},
+				// restoreAllMocks puts back what spyOn replaced
+				restoreAllMocks: function() {
+					spies.forEach(function(s) { s.mockRestore(); });
+					spies = [];
+					return jest;
+				},
+				mock: function(specifier, factory) {
+					__testMockModule(specifier, factory);
+					return jest;
+				},
+				unmock: function(specifier) {
+					__testUnmockModule(specifier);
+					return jest;
+				},
+				requireActual: __testRequireActual
+			};
+			globalThis.jest = jest;
+		})();
+	`
+
+	if _, err := b.runtime.RunScript("mocks-setup", mocksJS); err != nil {
+		return fmt.Errorf("failed to create jest global: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package test_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+func TestModuleMocksMatchResolvedPaths(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/db.js":       `({ name: 'real db' })`,
+		"src/service.js":  `({ name: require('./db').name })`,
+		"src/other/db.js": `({ name: 'other db' })`,
+		"test/mocks.test.js": `
+			jest.mock('../src/db', () => ({ name: 'mocked db' }));
+			const service = require(__dirname + '/../src/service.js');
+			const db = require('../src/db.js');
+			const other = require(__dirname + '/../src/other/db.js');
+
+			test('same file, other directory', () => {
+				expect(service.name).toBe('mocked db');
+			});
+			test('with and without .js', () => {
+				expect(db.name).toBe('mocked db');
+			});
+			test('same name, other directory', () => {
+				expect(other.name).toBe('other db');
+			});
+		`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := rt.RunTests([]string{filepath.Join(dir, "test/mocks.test.js")})
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Passed != 3 {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+}
+
+func TestMocks(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "mocks.test.js")
+	os.WriteFile(testFile, []byte(`
+		jest.mock('gode:stream', () => ({ fake: true }));
+
+		describe('mocks', () => {
+			test('fn', () => {
+				const add = jest.fn((a, b) => a + b).mockReturnValueOnce(10);
+				expect(add(1, 2)).toBe(10);
+				expect(add(1, 2)).toBe(3);
+				expect(add).toHaveBeenCalledTimes(2);
+				expect(add).toHaveBeenLastCalledWith(1, 2);
+				expect(add).toHaveReturnedWith(3);
+				expect(add.mock.results[0].value).toBe(10);
+				add.mockReset();
+				expect(add(1, 2)).toBeUndefined();
+				expect(add.mock.calls).toHaveLength(1);
+			});
+			test('resolved', () => {
+				const load = jest.fn().mockResolvedValue('data');
+				expect(load()).toBeInstanceOf(Promise);
+			});
+			test('spyOn', () => {
+				const math = { double: n => n * 2 };
+				const spy = jest.spyOn(math, 'double');
+				expect(math.double(2)).toBe(4);
+				expect(spy).toHaveBeenCalledWith(2);
+				spy.mockReturnValue(0);
+				expect(math.double(2)).toBe(0);
+				spy.mockRestore();
+				expect(math.double(2)).toBe(4);
+				expect(jest.isMockFunction(math.double)).toBe(false);
+			});
+			test('module', () => {
+				expect(require('gode:stream').fake).toBe(true);
+				expect(typeof jest.requireActual('gode:stream').Readable).toBe('function');
+			});
+			test('not called', () => {
+				expect(jest.fn()).toHaveBeenCalled();
+			});
+		});
+	`), 0644)
+
+	results, err := rt.RunTests([]string{testFile})
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Passed != 4 || results[0].Failed != 1 {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	if got := results[0].Tests[4].Error; !strings.Contains(got, "expected jest.fn() to have been called") {
+		t.Errorf("not called: error %q", got)
+	}
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Reporter receives the lifecycle of a test run. The runner calls it from
+// the goroutine running the tests, never from the JS thread.
+type Reporter interface {
+	OnRunStart(run RunInfo)
+	OnSuiteStart(suite SuiteInfo)
+	OnTestResult(result TestEvent)
+	OnRunComplete(summary RunSummary)
+}
+
+// ReporterRegistry is implemented by the runtime passed to a plugin's
+// Initialize, so a plugin can provide a reporter for gode test --reporter:
+//
+//	func Initialize(rt interface{}) error {
+//		if registry, ok := rt.(test.ReporterRegistry); ok {
+//			return registry.RegisterTestReporter("junit", &junitReporter{})
+//		}
+//		return nil
+//	}
+type ReporterRegistry interface {
+	RegisterTestReporter(name string, reporter Reporter) error
+}
+
+// RunInfo describes a test run about to start
+type RunInfo struct {
+	Files  []string
+	Suites int
+	Tests  int
+}
+
+// SuiteInfo describes a suite about to run
+type SuiteInfo struct {
+	Name  string
+	Path  []string // names of the enclosing suites and this one, outermost first
+	Tests int
+}
+
+// TestEvent is the result of one test, with the suite it belongs to
+type TestEvent struct {
+	Suite []string // names of the enclosing suites, outermost first
+	TestResult
+}
+
+// RunSummary is passed to reporters once every suite has run
+type RunSummary struct {
+	Suites   []SuiteResult
+	Total    int
+	Passed   int
+	Failed   int
+	Skipped  int
+	Duration time.Duration
+}
+
+// Success reports whether no test failed
+func (s RunSummary) Success() bool {
+	return s.Failed == 0
+}
+
+// summarize totals the results of a run
+func summarize(results []SuiteResult, elapsed time.Duration) RunSummary {
+	summary := RunSummary{Suites: results, Duration: elapsed}
+	for _, suite := range results {
+		summary.Total += len(suite.Tests)
+		summary.Passed += suite.Passed
+		summary.Failed += suite.Failed
+		summary.Skipped += suite.Skipped
+	}
+	return summary
+}
+
+// reporters fans events out to several reporters
+type reporters []Reporter
+
+func (rs reporters) OnRunStart(run RunInfo) {
+	for _, r := range rs {
+		r.OnRunStart(run)
+	}
+}
+
+func (rs reporters) OnSuiteStart(suite SuiteInfo) {
+	for _, r := range rs {
+		r.OnSuiteStart(suite)
+	}
+}
+
+func (rs reporters) OnTestResult(result TestEvent) {
+	for _, r := range rs {
+		r.OnTestResult(result)
+	}
+}
+
+func (rs reporters) OnRunComplete(summary RunSummary) {
+	for _, r := range rs {
+		r.OnRunComplete(summary)
+	}
+}
+
+// DefaultReporter prints each suite with its tests and the totals once the
+// run completes. It is the reporter named "default".
+type DefaultReporter struct {
+	w io.Writer
+}
+
+// NewDefaultReporter creates a DefaultReporter writing to w
+func NewDefaultReporter(w io.Writer) *DefaultReporter {
+	return &DefaultReporter{w: w}
+}
+
+func (d *DefaultReporter) OnRunStart(run RunInfo) {
+	fmt.Fprintln(d.w, "Running tests...")
+	fmt.Fprintln(d.w)
+}
+
+func (d *DefaultReporter) OnSuiteStart(suite SuiteInfo) {}
+
+func (d *DefaultReporter) OnTestResult(result TestEvent) {}
+
+func (d *DefaultReporter) OnRunComplete(summary RunSummary) {
+	for _, suite := range summary.Suites {
+		mark := "✓"
+		if suite.Failed > 0 {
+			mark = "✗"
+		}
+		fmt.Fprintf(d.w, "%s %s (%d tests)\n", mark, suite.Name, len(suite.Tests))
+
+		for _, t := range suite.Tests {
+			switch t.Status {
+			case TestStatusPassed:
+				fmt.Fprintf(d.w, "  ✓ %s (%s)\n", t.Name, t.Duration)
+			case TestStatusSkipped:
+				fmt.Fprintf(d.w, "  ○ %s (skipped)\n", t.Name)
+			default:
+				fmt.Fprintf(d.w, "  ✗ %s (%s)\n", t.Name, t.Duration)
+				if t.Error != "" {
+					fmt.Fprintf(d.w, "    %s\n", strings.ReplaceAll(t.Error, "\n", "\n    "))
+				}
+			}
+		}
+		fmt.Fprintln(d.w)
+	}
+
+	status := "✅ PASSED"
+	if !summary.Success() {
+		status = "❌ FAILED"
+	}
+	fmt.Fprintf(d.w, "Tests:       %d total, %d passed, %d failed, %d skipped\n", summary.Total, summary.Passed, summary.Failed, summary.Skipped)
+	fmt.Fprintf(d.w, "Time:        %s\n", summary.Duration.Round(time.Millisecond))
+	fmt.Fprintf(d.w, "Status:      %s\n", status)
+}
@@ -0,0 +1,86 @@
+package test_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/internal/modules/test"
+	"github.com/rizqme/gode/internal/runtime"
+)
+
+// recordingReporter records the events of a test run
+type recordingReporter struct {
+	events []string
+}
+
+func (r *recordingReporter) OnRunStart(run test.RunInfo) {
+	r.events = append(r.events, fmt.Sprintf("start:%d", run.Tests))
+}
+
+func (r *recordingReporter) OnSuiteStart(suite test.SuiteInfo) {
+	r.events = append(r.events, "suite:"+strings.Join(suite.Path, ">"))
+}
+
+func (r *recordingReporter) OnTestResult(result test.TestEvent) {
+	r.events = append(r.events, result.Name+":"+string(result.Status))
+}
+
+func (r *recordingReporter) OnRunComplete(summary test.RunSummary) {
+	r.events = append(r.events, fmt.Sprintf("done:%d/%d", summary.Failed, summary.Total))
+}
+
+func TestReporterRegistry(t *testing.T) {
+	rt := runtime.New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "math.test.js")
+	os.WriteFile(testFile, []byte(`
+		describe('math', () => {
+			test('adds', () => expect(1 + 1).toBe(2));
+			test('fails', () => expect(1).toBe(2));
+		});
+	`), 0644)
+	os.WriteFile(filepath.Join(dir, "reporter.js"), []byte(`
+		module.exports = class {
+			onRunStart(run) { globalThis.__events = ['start:' + run.tests]; }
+			onTestResult(result) { __events.push(result.suite.join('>') + '/' + result.name + ':' + result.status); }
+			onRunComplete(summary) { __events.push('done:' + summary.failed + ':' + summary.success); }
+		};
+	`), 0644)
+
+	recorder := &recordingReporter{}
+	if err := rt.RegisterTestReporter("record", recorder); err != nil {
+		t.Fatalf("RegisterTestReporter() failed: %v", err)
+	}
+	if err := rt.RegisterTestReporter("record", recorder); err == nil {
+		t.Error("Expected registering the same name twice to fail")
+	}
+
+	results, err := rt.RunTestsWithReporters([]string{testFile}, []string{"record", filepath.Join(dir, "reporter.js")})
+	if err != nil {
+		t.Fatalf("RunTestsWithReporters() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Failed != 1 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	want := "start:2,suite:math,adds:passed,fails:failed,done:1/2"
+	if got := strings.Join(recorder.events, ","); got != want {
+		t.Errorf("Go reporter events = %s, want %s", got, want)
+	}
+	events, err := rt.RunScript("events", "__events.join(',')")
+	if want := "start:2,math/adds:passed,math/fails:failed,done:1:false"; err != nil || events != want {
+		t.Errorf("JS reporter events = %v, %v, want %s", events, err, want)
+	}
+
+	if _, err := rt.RunTestsWithReporters([]string{testFile}, []string{"missing"}); err == nil {
+		t.Error("Expected an unknown reporter to fail")
+	}
+}
@@ -0,0 +1,297 @@
+package test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// builtinReporters create the reporters gode test knows by name. Each run
+// creates its own, writing to stdout or to the file given with
+// --reporter name=file.
+var builtinReporters = map[string]func(w io.Writer) Reporter{
+	"default": func(w io.Writer) Reporter { return NewDefaultReporter(w) },
+	"spec":    func(w io.Writer) Reporter { return NewSpecReporter(w) },
+	"dot":     func(w io.Writer) Reporter { return NewDotReporter(w) },
+	"json":    func(w io.Writer) Reporter { return NewJSONReporter(w) },
+	"junit":   func(w io.Writer) Reporter { return NewJUnitReporter(w) },
+}
+
+// fullName joins the suite names of a test event and its test name
+func fullName(event TestEvent) string {
+	return strings.Join(append(append([]string{}, event.Suite...), event.Name), " › ")
+}
+
+// writeFailures lists the failed tests with their errors, numbered, and the
+// totals of the run, for the spec and dot reporters
+func writeFailures(w io.Writer, summary RunSummary, failures []TestEvent) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  %d passing (%s)\n", summary.Passed, summary.Duration.Round(time.Millisecond))
+	if summary.Failed > 0 {
+		fmt.Fprintf(w, "  %d failing\n", summary.Failed)
+	}
+	if summary.Skipped > 0 {
+		fmt.Fprintf(w, "  %d skipped\n", summary.Skipped)
+	}
+	for i, failure := range failures {
+		fmt.Fprintf(w, "\n  %d) %s\n", i+1, fullName(failure))
+		if failure.Error != "" {
+			fmt.Fprintf(w, "     %s\n", strings.ReplaceAll(failure.Error, "\n", "\n     "))
+		}
+	}
+}
+
+// SpecReporter prints each suite and test as it finishes, indented by
+// nesting, then the failures. It is the reporter named "spec".
+type SpecReporter struct {
+	w        io.Writer
+	failures []TestEvent
+}
+
+// NewSpecReporter creates a SpecReporter writing to w
+func NewSpecReporter(w io.Writer) *SpecReporter {
+	return &SpecReporter{w: w}
+}
+
+func (s *SpecReporter) OnRunStart(run RunInfo) {}
+
+func (s *SpecReporter) OnSuiteStart(suite SuiteInfo) {
+	fmt.Fprintf(s.w, "%s%s\n", strings.Repeat("  ", len(suite.Path)), suite.Name)
+}
+
+func (s *SpecReporter) OnTestResult(result TestEvent) {
+	indent := strings.Repeat("  ", len(result.Suite)+1)
+	switch result.Status {
+	case TestStatusPassed:
+		fmt.Fprintf(s.w, "%s✓ %s (%s)\n", indent, result.Name, result.Duration.Round(time.Millisecond))
+	case TestStatusSkipped:
+		fmt.Fprintf(s.w, "%s- %s\n", indent, result.Name)
+	default:
+		s.failures = append(s.failures, result)
+		fmt.Fprintf(s.w, "%s%d) %s\n", indent, len(s.failures), result.Name)
+	}
+}
+
+func (s *SpecReporter) OnRunComplete(summary RunSummary) {
+	writeFailures(s.w, summary, s.failures)
+}
+
+// DotReporter prints a character per test: . passed, F failed, , skipped,
+// then the failures. It is the reporter named "dot".
+type DotReporter struct {
+	w        io.Writer
+	count    int
+	failures []TestEvent
+}
+
+// NewDotReporter creates a DotReporter writing to w
+func NewDotReporter(w io.Writer) *DotReporter {
+	return &DotReporter{w: w}
+}
+
+func (d *DotReporter) OnRunStart(run RunInfo) {}
+
+func (d *DotReporter) OnSuiteStart(suite SuiteInfo) {}
+
+func (d *DotReporter) OnTestResult(result TestEvent) {
+	mark := "."
+	switch result.Status {
+	case TestStatusSkipped:
+		mark = ","
+	case TestStatusFailed:
+		mark = "F"
+		d.failures = append(d.failures, result)
+	}
+	d.count++
+	if d.count%80 == 0 {
+		mark += "\n"
+	}
+	fmt.Fprint(d.w, mark)
+}
+
+func (d *DotReporter) OnRunComplete(summary RunSummary) {
+	if d.count%80 != 0 {
+		fmt.Fprintln(d.w)
+	}
+	writeFailures(d.w, summary, d.failures)
+}
+
+// JSONReporter writes the results of the run as one JSON document once it
+// completes. It is the reporter named "json".
+type JSONReporter struct {
+	w     io.Writer
+	files []string
+	tests []jsonTest
+}
+
+type jsonReport struct {
+	Success  bool       `json:"success"`
+	Files    []string   `json:"files"`
+	Total    int        `json:"total"`
+	Passed   int        `json:"passed"`
+	Failed   int        `json:"failed"`
+	Skipped  int        `json:"skipped"`
+	Duration float64    `json:"duration"` // milliseconds
+	Tests    []jsonTest `json:"tests"`
+}
+
+type jsonTest struct {
+	Suite    []string   `json:"suite"`
+	Name     string     `json:"name"`
+	FullName string     `json:"fullName"`
+	Status   TestStatus `json:"status"`
+	Duration float64    `json:"duration"` // milliseconds
+	Error    string     `json:"error,omitempty"`
+}
+
+// NewJSONReporter creates a JSONReporter writing to w
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{w: w}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (j *JSONReporter) OnRunStart(run RunInfo) {
+	j.files = run.Files
+}
+
+func (j *JSONReporter) OnSuiteStart(suite SuiteInfo) {}
+
+func (j *JSONReporter) OnTestResult(result TestEvent) {
+	suite := result.Suite
+	if suite == nil {
+		suite = []string{}
+	}
+	j.tests = append(j.tests, jsonTest{
+		Suite:    suite,
+		Name:     result.Name,
+		FullName: fullName(result),
+		Status:   result.Status,
+		Duration: milliseconds(result.Duration),
+		Error:    result.Error,
+	})
+}
+
+func (j *JSONReporter) OnRunComplete(summary RunSummary) {
+	report := jsonReport{
+		Success:  summary.Success(),
+		Files:    j.files,
+		Total:    summary.Total,
+		Passed:   summary.Passed,
+		Failed:   summary.Failed,
+		Skipped:  summary.Skipped,
+		Duration: milliseconds(summary.Duration),
+		Tests:    j.tests,
+	}
+	if report.Files == nil {
+		report.Files = []string{}
+	}
+	if report.Tests == nil {
+		report.Tests = []jsonTest{}
+	}
+	encoder := json.NewEncoder(j.w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+// JUnitReporter writes the results of the run as JUnit XML once it
+// completes, with a testsuite per suite, for CI servers. It is the reporter
+// named "junit".
+type JUnitReporter struct {
+	w      io.Writer
+	suites []*junitSuite
+	byName map[string]*junitSuite
+}
+
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Name     string        `xml:"name,attr"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Skipped  int           `xml:"skipped,attr"`
+	Time     string        `xml:"time,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+	duration time.Duration
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitReporter creates a JUnitReporter writing to w
+func NewJUnitReporter(w io.Writer) *JUnitReporter {
+	return &JUnitReporter{w: w, byName: make(map[string]*junitSuite)}
+}
+
+// seconds formats d the way JUnit XML gives times
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func (j *JUnitReporter) OnRunStart(run RunInfo) {}
+
+func (j *JUnitReporter) OnSuiteStart(suite SuiteInfo) {}
+
+func (j *JUnitReporter) OnTestResult(result TestEvent) {
+	name := strings.Join(result.Suite, " › ")
+	suite := j.byName[name]
+	if suite == nil {
+		suite = &junitSuite{Name: name}
+		j.byName[name] = suite
+		j.suites = append(j.suites, suite)
+	}
+
+	c := junitCase{Name: result.Name, ClassName: name, Time: seconds(result.Duration)}
+	switch result.Status {
+	case TestStatusFailed:
+		message, _, _ := strings.Cut(result.Error, "\n")
+		c.Failure = &junitFailure{Message: message, Text: result.Error}
+		suite.Failures++
+	case TestStatusSkipped:
+		c.Skipped = &struct{}{}
+		suite.Skipped++
+	}
+	suite.Tests++
+	suite.duration += result.Duration
+	suite.Time = seconds(suite.duration)
+	suite.Cases = append(suite.Cases, c)
+}
+
+func (j *JUnitReporter) OnRunComplete(summary RunSummary) {
+	report := junitSuites{
+		Name:     "gode test",
+		Tests:    summary.Total,
+		Failures: summary.Failed,
+		Skipped:  summary.Skipped,
+		Time:     seconds(summary.Duration),
+		Suites:   j.suites,
+	}
+	io.WriteString(j.w, xml.Header)
+	encoder := xml.NewEncoder(j.w)
+	encoder.Indent("", "  ")
+	encoder.Encode(report)
+	io.WriteString(j.w, "\n")
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replay feeds a run with a passing, a failing and a skipped test to r
+func replay(r Reporter) {
+	events := []TestEvent{
+		{Suite: []string{"math"}, TestResult: TestResult{Name: "adds", Status: TestStatusPassed, Duration: 2 * time.Millisecond}},
+		{Suite: []string{"math", "division"}, TestResult: TestResult{Name: "by zero", Status: TestStatusFailed, Duration: time.Millisecond, Error: "expected 1 to be 2\n  at math.test.js:3"}},
+		{Suite: []string{"math", "division"}, TestResult: TestResult{Name: "later", Status: TestStatusSkipped}},
+	}
+	r.OnRunStart(RunInfo{Files: []string{"/math.test.js"}, Suites: 2, Tests: 3})
+	r.OnSuiteStart(SuiteInfo{Name: "math", Path: []string{"math"}, Tests: 1})
+	r.OnTestResult(events[0])
+	r.OnSuiteStart(SuiteInfo{Name: "division", Path: []string{"math", "division"}, Tests: 2})
+	r.OnTestResult(events[1])
+	r.OnTestResult(events[2])
+	r.OnRunComplete(RunSummary{Total: 3, Passed: 1, Failed: 1, Skipped: 1, Duration: 5 * time.Millisecond})
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	replay(NewJSONReporter(&buf))
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+	}
+	if report.Success || report.Total != 3 || report.Failed != 1 || report.Duration != 5 || len(report.Tests) != 3 {
+		t.Errorf("report = %+v", report)
+	}
+	if failed := report.Tests[1]; failed.FullName != "math › division › by zero" || failed.Status != TestStatusFailed || !strings.HasPrefix(failed.Error, "expected 1") {
+		t.Errorf("failed test = %+v", failed)
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	replay(NewJUnitReporter(&buf))
+
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("missing XML header: %s", buf.String())
+	}
+	var report junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid XML %s: %v", buf.String(), err)
+	}
+	if report.Tests != 3 || report.Failures != 1 || report.Skipped != 1 || len(report.Suites) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	division := report.Suites[1]
+	if division.Name != "math › division" || division.Tests != 2 || division.Failures != 1 || division.Skipped != 1 {
+		t.Errorf("division suite = %+v", division)
+	}
+	failure := division.Cases[0].Failure
+	if failure == nil || failure.Message != "expected 1 to be 2" || !strings.Contains(failure.Text, "math.test.js:3") {
+		t.Errorf("failure = %+v", failure)
+	}
+	if division.Cases[1].Skipped == nil {
+		t.Error("skipped test has no skipped element")
+	}
+}
+
+func TestConsoleReporters(t *testing.T) {
+	var spec bytes.Buffer
+	replay(NewSpecReporter(&spec))
+	for _, want := range []string{"  math\n", "    ✓ adds (2ms)\n", "    division\n", "      1) by zero\n", "      - later\n", "1 failing", "1) math › division › by zero\n     expected 1 to be 2\n       at math.test.js:3"} {
+		if !strings.Contains(spec.String(), want) {
+			t.Errorf("spec output lacks %q:\n%s", want, spec.String())
+		}
+	}
+
+	var dot bytes.Buffer
+	replay(NewDotReporter(&dot))
+	if !strings.HasPrefix(dot.String(), ".F,\n") || !strings.Contains(dot.String(), "1 passing") {
+		t.Errorf("dot output:\n%s", dot.String())
+	}
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunnerOrderAndFocus(t *testing.T) {
+	runner := NewTestRunner()
+	var ran []string
+	record := func(name string) func() error {
+		return func() error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	runner.SetFile("/a.test.js")
+	runner.BeforeEach(record("a:each"))
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		name := name
+		runner.Describe(name, func() {
+			runner.Test(name+" 1", record(name+" 1"), nil)
+			runner.Test(name+" 2", record(name+" 2"), nil)
+		})
+	}
+	runner.Test("top", record("top"), nil)
+	runner.Describe("zeta", func() {
+		runner.Test("second zeta", record("second zeta"), nil)
+	})
+
+	// only is scoped to its file, and a focused suite runs all its tests
+	runner.SetFile("/b.test.js")
+	runner.DescribeWithOptions("focused", func() {
+		runner.BeforeAll(record("focused:all"))
+		runner.Test("f1", record("f1"), nil)
+		runner.Test("f2", record("f2"), &TestOptions{Skip: true})
+		runner.DescribeWithOptions("skipped", func() {
+			runner.BeforeAll(record("skipped:all"))
+			runner.Test("s1", record("s1"), &TestOptions{Only: true})
+		}, &TestOptions{Skip: true})
+	}, &TestOptions{Only: true})
+	runner.Describe("unfocused", func() {
+		runner.BeforeAll(record("unfocused:all"))
+		runner.Test("u1", record("u1"), nil)
+		runner.Test("u2", record("u2"), &TestOptions{Only: true})
+	})
+
+	results, err := runner.Run()
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	want := "a:each,zeta 1,a:each,zeta 2,a:each,alpha 1,a:each,alpha 2,a:each,mid 1,a:each,mid 2," +
+		"a:each,top,a:each,second zeta,focused:all,f1,unfocused:all,u2"
+	if got := strings.Join(ran, ","); got != want {
+		t.Errorf("ran %s\nwant %s", got, want)
+	}
+
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	if got := strings.Join(names, ","); got != "zeta,alpha,mid,default,zeta,focused,unfocused" {
+		t.Errorf("suites = %s", got)
+	}
+	if focused := results[5]; focused.Passed != 1 || focused.Skipped != 2 {
+		t.Errorf("focused suite = %+v", focused)
+	}
+}
+
+func TestRunnerTimeouts(t *testing.T) {
+	runner := NewTestRunner()
+	runner.SetDefaultTimeout(60 * time.Millisecond)
+	hang := func() error {
+		<-runner.Context().Done()
+		return runner.Context().Err()
+	}
+
+	runner.DescribeWithOptions("slow", func() {
+		runner.Test("inherits", hang, nil)
+		runner.DescribeWithOptions("nested", func() {
+			runner.Test("nested inherits", hang, nil)
+		}, nil)
+		runner.Test("own", hang, &TestOptions{Timeout: 10})
+	}, &TestOptions{Timeout: 30})
+	runner.Describe("default", func() {
+		runner.Test("default", hang, nil)
+		runner.Test("fast", func() error { return nil }, nil)
+	})
+
+	results, err := runner.Run()
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	var errs []string
+	for _, suite := range results {
+		for _, test := range suite.Tests {
+			errs = append(errs, test.Name+": "+test.Error)
+		}
+	}
+	want := "inherits: test timed out after 30ms,own: test timed out after 10ms," +
+		"nested inherits: test timed out after 30ms,default: test timed out after 60ms,fast: "
+	if got := strings.Join(errs, ","); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if runner.Context().Err() != nil {
+		t.Error("Context() is done outside a test")
+	}
+}
+
+func TestRunnerFilterAndBail(t *testing.T) {
+	runner := NewTestRunner()
+	var ran []string
+	record := func(name string, err error) func() error {
+		return func() error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	runner.Describe("parser", func() {
+		runner.BeforeAll(record("parser:all", nil))
+		runner.Test("handles unicode", record("p1", nil), nil)
+		runner.Describe("errors", func() {
+			runner.Test("reports unicode", record("p2", nil), nil)
+			runner.Test("reports lines", record("p3", nil), nil)
+		})
+	})
+	runner.Describe("lexer", func() {
+		runner.BeforeAll(record("lexer:all", nil))
+		runner.Test("splits", record("l1", nil), nil)
+	})
+	runner.Test("unicode at top", record("top", nil), nil)
+
+	runner.SetFilter(regexp.MustCompile(`^parser .*unicode|^unicode`))
+	if _, err := runner.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ran, ","); got != "parser:all,p1,p2,top" {
+		t.Errorf("filtered run ran %s", got)
+	}
+
+	ran = nil
+	runner.SetFilter(nil)
+	runner.SetBail(true)
+	runner.Describe("failing", func() {
+		runner.Test("fails", record("f1", errors.New("boom")), nil)
+	})
+	runner.Describe("after", func() {
+		runner.BeforeAll(record("after:all", nil))
+		runner.Test("never runs", record("a1", nil), nil)
+	})
+	results, err := runner.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ran, ","); got != "parser:all,p1,p2,p3,lexer:all,l1,top,f1" {
+		t.Errorf("bailed run ran %s", got)
+	}
+	if after := results[len(results)-1]; after.Skipped != 1 {
+		t.Errorf("suite after the failure = %+v", after)
+	}
+
+	// A new run starts over
+	ran = nil
+	runner.SetBail(false)
+	runner.Run()
+	if !strings.HasSuffix(strings.Join(ran, ","), "f1,after:all,a1") {
+		t.Errorf("run after bail ran %s", strings.Join(ran, ","))
+	}
+}
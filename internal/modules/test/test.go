@@ -1,11 +1,14 @@
 package test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,9 +16,18 @@ import (
 type TestOptions struct {
 	Only    bool `json:"only"`
 	Skip    bool `json:"skip"`
-	Timeout int  `json:"timeout"` // timeout in milliseconds
+	Timeout int  `json:"timeout"` // timeout in milliseconds; on a suite, the default of its tests
 }
 
+// DefaultTimeout is how long a test may run unless its options, a suite
+// enclosing it or gode.test.timeout say otherwise
+const DefaultTimeout = 5 * time.Second
+
+// interruptGrace is how long a timed-out test is given to stop once it is
+// interrupted. JavaScript stops at its next instruction; Go code that
+// ignores Context is left behind.
+const interruptGrace = time.Second
+
 // TestStatus represents the status of a test
 type TestStatus string
 
@@ -47,19 +59,38 @@ type SuiteResult struct {
 	Skipped  int          `json:"skipped"`
 }
 
-// TestRunner manages test execution
+// TestRunner manages test execution. Suites and tests run in the order
+// they were declared.
 type TestRunner struct {
-	suites         map[string]*TestSuite
-	currentSuite   *TestSuite
-	hasOnly        bool
-	mu             sync.RWMutex
-	beforeAllHooks []func() error
-	afterAllHooks  []func() error
+	suites          []*TestSuite // top-level suites
+	defaultSuite    *TestSuite   // holds the tests declared outside describe
+	currentSuite    *TestSuite
+	onlyFiles       map[string]bool // files that focus on some tests with only
+	mu              sync.RWMutex
+	beforeAllHooks  []func() error
+	afterAllHooks   []func() error
+	beforeEachHooks []fileHook // declared outside describe
+	afterEachHooks  []fileHook
+	file            string               // test file being loaded
+	running         atomic.Pointer[Test] // test being run
+	defaultTimeout  time.Duration        // 0 means DefaultTimeout
+	filter          *regexp.Regexp       // runs only the tests whose full name matches
+	bail            bool                 // skips the remaining tests after a failure
+	bailed          atomic.Bool          // a test failed while bail is set
+}
+
+// fileHook is a beforeEach or afterEach hook declared outside describe,
+// which applies to the tests of its file
+type fileHook struct {
+	file string
+	fn   func() error
 }
 
 // TestSuite represents a group of tests
 type TestSuite struct {
 	Name           string
+	Options        TestOptions // Only and Skip apply to the suite's tests and child suites
+	File           string      // test file that declared it
 	Tests          []*Test
 	BeforeEach     []func() error
 	AfterEach      []func() error
@@ -67,7 +98,6 @@ type TestSuite struct {
 	AfterAll       []func() error
 	Parent         *TestSuite
 	Children       []*TestSuite
-	hasOnly        bool
 }
 
 // Test represents a single test case
@@ -76,6 +106,8 @@ type Test struct {
 	Fn       func() error
 	Options  TestOptions
 	Suite    *TestSuite
+	File     string // test file that defined it
+	ctx      context.Context // done when the running test times out
 }
 
 // EventEmitter interface for test events
@@ -86,7 +118,7 @@ type EventEmitter interface {
 // NewTestRunner creates a new test runner
 func NewTestRunner() *TestRunner {
 	return &TestRunner{
-		suites: make(map[string]*TestSuite),
+		onlyFiles: make(map[string]bool),
 	}
 }
 
@@ -95,29 +127,119 @@ func (tr *TestRunner) Reset() {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 	
-	tr.suites = make(map[string]*TestSuite)
+	tr.suites = nil
+	tr.defaultSuite = nil
 	tr.currentSuite = nil
-	tr.hasOnly = false
+	tr.onlyFiles = make(map[string]bool)
 	tr.beforeAllHooks = nil
 	tr.afterAllHooks = nil
+	tr.beforeEachHooks = nil
+	tr.afterEachHooks = nil
+	tr.file = ""
+}
+
+// SetFile records the test file being loaded, for the tests it defines
+func (tr *TestRunner) SetFile(path string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.file = path
+}
+
+// Running returns the test being run, or nil between tests
+func (tr *TestRunner) Running() *Test {
+	return tr.running.Load()
+}
+
+// Context returns a context that is done when the running test times out,
+// for the test and its hooks to stop at. Outside a test it is never done.
+func (tr *TestRunner) Context() context.Context {
+	if test := tr.running.Load(); test != nil && test.ctx != nil {
+		return test.ctx
+	}
+	return context.Background()
+}
+
+// SetDefaultTimeout sets the timeout of the tests whose options and suites
+// don't set one. 0 restores DefaultTimeout.
+func (tr *TestRunner) SetDefaultTimeout(timeout time.Duration) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.defaultTimeout = timeout
+}
+
+// timeout returns how long test may run: its own timeout, else that of the
+// nearest suite enclosing it that sets one, else the default
+func (tr *TestRunner) timeout(test *Test) time.Duration {
+	if test.Options.Timeout > 0 {
+		return time.Duration(test.Options.Timeout) * time.Millisecond
+	}
+	for suite := test.Suite; suite != nil; suite = suite.Parent {
+		if suite.Options.Timeout > 0 {
+			return time.Duration(suite.Options.Timeout) * time.Millisecond
+		}
+	}
+	if tr.defaultTimeout > 0 {
+		return tr.defaultTimeout
+	}
+	return DefaultTimeout
+}
+
+// SetFilter runs only the tests whose full name, the names of their suites
+// and their own joined with spaces, matches filter; the others are skipped.
+// nil runs every test.
+func (tr *TestRunner) SetFilter(filter *regexp.Regexp) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.filter = filter
+}
+
+// SetBail makes the run skip the tests that remain after the first failure
+func (tr *TestRunner) SetBail(bail bool) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.bail = bail
+}
+
+// FullName returns the names of the suites enclosing test and its own,
+// joined with spaces. Tests declared outside describe have no suite name.
+func (tr *TestRunner) FullName(test *Test) string {
+	var names []string
+	if test.Suite != tr.defaultSuite {
+		names = suitePath(test.Suite)
+	}
+	return strings.Join(append(names, test.Name), " ")
 }
 
 // Describe creates a new test suite
 func (tr *TestRunner) Describe(name string, fn func()) {
+	tr.DescribeWithOptions(name, fn, nil)
+}
+
+// DescribeWithOptions creates a new test suite, like describe.only or
+// describe.skip when options say so. A timeout applies to the tests in the
+// suite and its child suites that don't set their own.
+func (tr *TestRunner) DescribeWithOptions(name string, fn func(), options *TestOptions) {
 	tr.mu.Lock()
 	
 	parent := tr.currentSuite
 	suite := &TestSuite{
 		Name:     name,
+		File:     tr.file,
 		Tests:    make([]*Test, 0),
 		Parent:   parent,
 		Children: make([]*TestSuite, 0),
 	}
+	if options != nil {
+		suite.Options = *options
+		if options.Only {
+			tr.onlyFiles[tr.file] = true
+		}
+	}
 
 	if parent != nil {
 		parent.Children = append(parent.Children, suite)
 	} else {
-		tr.suites[name] = suite
+		tr.suites = append(tr.suites, suite)
 	}
 
 	tr.currentSuite = suite
@@ -136,13 +258,18 @@ func (tr *TestRunner) Test(name string, fn func() error, options *TestOptions) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
-	if tr.currentSuite == nil {
-		// Create default suite if none exists
-		tr.currentSuite = &TestSuite{
-			Name:  "default",
-			Tests: make([]*Test, 0),
+	suite := tr.currentSuite
+	if suite == nil {
+		// Tests outside describe share one suite, which runs where the
+		// first of them was declared
+		if tr.defaultSuite == nil {
+			tr.defaultSuite = &TestSuite{
+				Name:  "default",
+				Tests: make([]*Test, 0),
+			}
+			tr.suites = append(tr.suites, tr.defaultSuite)
 		}
-		tr.suites["default"] = tr.currentSuite
+		suite = tr.defaultSuite
 	}
 
 	opts := TestOptions{}
@@ -151,37 +278,80 @@ func (tr *TestRunner) Test(name string, fn func() error, options *TestOptions) {
 	}
 
 	if opts.Only {
-		tr.hasOnly = true
-		tr.currentSuite.hasOnly = true
+		tr.onlyFiles[tr.file] = true
 	}
 
 	test := &Test{
 		Name:    name,
 		Fn:      fn,
 		Options: opts,
-		Suite:   tr.currentSuite,
+		Suite:   suite,
+		File:    tr.file,
 	}
 
-	tr.currentSuite.Tests = append(tr.currentSuite.Tests, test)
+	suite.Tests = append(suite.Tests, test)
 }
 
-// BeforeEach adds a before each hook to the current suite
+// skipped reports whether test is left out of the run: when it or a suite
+// enclosing it is skipped, when its file focuses on other tests with only,
+// when it doesn't match the filter, or after a failure with bail set. An
+// only on a suite focuses on all the tests in it.
+func (tr *TestRunner) skipped(test *Test) bool {
+	if test.Options.Skip || tr.bailed.Load() {
+		return true
+	}
+	if tr.filter != nil && !tr.filter.MatchString(tr.FullName(test)) {
+		return true
+	}
+	focused := test.Options.Only
+	for suite := test.Suite; suite != nil; suite = suite.Parent {
+		if suite.Options.Skip {
+			return true
+		}
+		focused = focused || suite.Options.Only
+	}
+	return tr.onlyFiles[test.File] && !focused
+}
+
+// runnable reports whether any test in suite or its children will run, so
+// that the suite's beforeAll and afterAll hooks are needed
+func (tr *TestRunner) runnable(suite *TestSuite) bool {
+	for _, test := range suite.Tests {
+		if !tr.skipped(test) {
+			return true
+		}
+	}
+	for _, child := range suite.Children {
+		if tr.runnable(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// BeforeEach adds a before each hook to the current suite. Outside
+// describe, it applies to every test of the file being loaded.
 func (tr *TestRunner) BeforeEach(fn func() error) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
 	if tr.currentSuite != nil {
 		tr.currentSuite.BeforeEach = append(tr.currentSuite.BeforeEach, fn)
+	} else {
+		tr.beforeEachHooks = append(tr.beforeEachHooks, fileHook{file: tr.file, fn: fn})
 	}
 }
 
-// AfterEach adds an after each hook to the current suite
+// AfterEach adds an after each hook to the current suite. Outside
+// describe, it applies to every test of the file being loaded.
 func (tr *TestRunner) AfterEach(fn func() error) {
 	tr.mu.Lock()
 	defer tr.mu.Unlock()
 
 	if tr.currentSuite != nil {
 		tr.currentSuite.AfterEach = append(tr.currentSuite.AfterEach, fn)
+	} else {
+		tr.afterEachHooks = append(tr.afterEachHooks, fileHook{file: tr.file, fn: fn})
 	}
 }
 
@@ -211,9 +381,25 @@ func (tr *TestRunner) AfterAll(fn func() error) {
 
 // Run executes all tests and returns results
 func (tr *TestRunner) Run() ([]SuiteResult, error) {
+	return tr.RunWithReporters(nil)
+}
+
+// RunWithReporters executes all tests like Run, notifying each reporter as
+// suites start and tests finish. files are the test files the tests were
+// loaded from.
+func (tr *TestRunner) RunWithReporters(files []string, rs ...Reporter) ([]SuiteResult, error) {
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
 
+	start := time.Now()
+	tr.bailed.Store(false)
+	report := reporters(rs)
+	run := RunInfo{Files: files, Suites: len(tr.suites)}
+	for _, suite := range tr.suites {
+		run.Tests += countTests(suite)
+	}
+	report.OnRunStart(run)
+
 	var results []SuiteResult
 
 	// Run global before all hooks
@@ -225,7 +411,7 @@ func (tr *TestRunner) Run() ([]SuiteResult, error) {
 
 	// Run all test suites
 	for _, suite := range tr.suites {
-		result := tr.runSuite(suite)
+		result := tr.runSuite(suite, report)
 		results = append(results, result)
 	}
 
@@ -236,28 +422,70 @@ func (tr *TestRunner) Run() ([]SuiteResult, error) {
 		}
 	}
 
+	report.OnRunComplete(summarize(results, time.Since(start)))
 	return results, nil
 }
 
+// countTests returns the number of tests in suite and its children
+func countTests(suite *TestSuite) int {
+	n := len(suite.Tests)
+	for _, child := range suite.Children {
+		n += countTests(child)
+	}
+	return n
+}
+
+// suitePath returns the names of suite and the suites enclosing it,
+// outermost first
+func suitePath(suite *TestSuite) []string {
+	var path []string
+	for s := suite; s != nil; s = s.Parent {
+		path = append([]string{s.Name}, path...)
+	}
+	return path
+}
+
 // runSuite executes a test suite and returns its result
-func (tr *TestRunner) runSuite(suite *TestSuite) SuiteResult {
+func (tr *TestRunner) runSuite(suite *TestSuite, report Reporter) SuiteResult {
 	start := time.Now()
 	result := SuiteResult{
 		Name:  suite.Name,
 		Tests: make([]TestResult, 0),
 	}
+	path := suitePath(suite)
+	report.OnSuiteStart(SuiteInfo{Name: suite.Name, Path: path, Tests: len(suite.Tests)})
+	record := func(testResult TestResult) {
+		result.Tests = append(result.Tests, testResult)
+		switch testResult.Status {
+		case TestStatusPassed:
+			result.Passed++
+		case TestStatusFailed:
+			result.Failed++
+		case TestStatusSkipped:
+			result.Skipped++
+		}
+		report.OnTestResult(TestEvent{Suite: path, TestResult: testResult})
+		if testResult.Status == TestStatusFailed && tr.bail {
+			tr.bailed.Store(true)
+		}
+	}
+
+	// Suites with no test to run skip their hooks
+	runnable := tr.runnable(suite)
 
 	// Run before all hooks
 	for _, hook := range suite.BeforeAll {
+		if !runnable {
+			break
+		}
 		if err := hook(); err != nil {
 			// If beforeAll fails, skip all tests in suite
 			for _, test := range suite.Tests {
-				result.Tests = append(result.Tests, TestResult{
+				record(TestResult{
 					Name:   test.Name,
 					Status: TestStatusSkipped,
 					Error:  fmt.Sprintf("beforeAll hook failed: %v", err),
 				})
-				result.Skipped++
 			}
 			result.Duration = time.Since(start)
 			return result
@@ -266,42 +494,20 @@ func (tr *TestRunner) runSuite(suite *TestSuite) SuiteResult {
 
 	// Run tests
 	for _, test := range suite.Tests {
-		// Skip test if not marked as "only" when hasOnly is true
-		if tr.hasOnly && !test.Options.Only {
-			result.Tests = append(result.Tests, TestResult{
-				Name:   test.Name,
-				Status: TestStatusSkipped,
-			})
-			result.Skipped++
-			continue
-		}
-
-		// Skip test if explicitly marked as skip
-		if test.Options.Skip {
-			result.Tests = append(result.Tests, TestResult{
+		if tr.skipped(test) {
+			record(TestResult{
 				Name:   test.Name,
 				Status: TestStatusSkipped,
 			})
-			result.Skipped++
 			continue
 		}
 
-		testResult := tr.runTest(test, suite)
-		result.Tests = append(result.Tests, testResult)
-
-		switch testResult.Status {
-		case TestStatusPassed:
-			result.Passed++
-		case TestStatusFailed:
-			result.Failed++
-		case TestStatusSkipped:
-			result.Skipped++
-		}
+		record(tr.runTest(test, suite))
 	}
 
 	// Run child suites
 	for _, child := range suite.Children {
-		childResult := tr.runSuite(child)
+		childResult := tr.runSuite(child, report)
 		result.Tests = append(result.Tests, childResult.Tests...)
 		result.Passed += childResult.Passed
 		result.Failed += childResult.Failed
@@ -310,6 +516,9 @@ func (tr *TestRunner) runSuite(suite *TestSuite) SuiteResult {
 
 	// Run after all hooks
 	for _, hook := range suite.AfterAll {
+		if !runnable {
+			break
+		}
 		if err := hook(); err != nil {
 			// Note: We don't fail the suite if afterAll fails
 		}
@@ -328,11 +537,15 @@ func (tr *TestRunner) runTest(test *Test, suite *TestSuite) TestResult {
 		Output: make([]string, 0),
 	}
 
-	// Setup timeout
-	timeout := time.Duration(test.Options.Timeout) * time.Millisecond
-	if timeout == 0 {
-		timeout = 5 * time.Second // default timeout
-	}
+	// The test and its hooks are interrupted through the context when they
+	// run out of time
+	timeout := tr.timeout(test)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	test.ctx = ctx
+
+	tr.running.Store(test)
+	defer tr.running.Store(nil)
 
 	done := make(chan error, 1)
 	go func() {
@@ -347,7 +560,7 @@ func (tr *TestRunner) runTest(test *Test, suite *TestSuite) TestResult {
 		}()
 
 		// Run before each hooks (including parent suites)
-		err := tr.runBeforeEachHooks(suite)
+		err := tr.runBeforeEachHooks(test, suite)
 		if err != nil {
 			done <- err
 			return
@@ -357,7 +570,7 @@ func (tr *TestRunner) runTest(test *Test, suite *TestSuite) TestResult {
 		err = test.Fn()
 
 		// Run after each hooks (including parent suites)
-		afterErr := tr.runAfterEachHooks(suite)
+		afterErr := tr.runAfterEachHooks(test, suite)
 		if afterErr != nil {
 			if err == nil {
 				err = afterErr
@@ -372,7 +585,11 @@ func (tr *TestRunner) runTest(test *Test, suite *TestSuite) TestResult {
 
 	select {
 	case err := <-done:
-		if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Interrupted just as the timeout fired
+			result.Status = TestStatusFailed
+			result.Error = fmt.Sprintf("test timed out after %v", timeout)
+		} else if err != nil {
 			result.Status = TestStatusFailed
 			result.Error = err.Error()
 			
@@ -387,7 +604,13 @@ func (tr *TestRunner) runTest(test *Test, suite *TestSuite) TestResult {
 		} else {
 			result.Status = TestStatusPassed
 		}
-	case <-time.After(timeout):
+	case <-ctx.Done():
+		// Wait for the interrupted test to return, so that it doesn't run
+		// on into the next one
+		select {
+		case <-done:
+		case <-time.After(interruptGrace):
+		}
 		result.Status = TestStatusFailed
 		result.Error = fmt.Sprintf("test timed out after %v", timeout)
 	}
@@ -531,12 +754,22 @@ func (e *Expectation) ToThrow(expectedError ...interface{}) error {
 	return nil
 }
 
-// runBeforeEachHooks runs beforeEach hooks from parent to child
-func (tr *TestRunner) runBeforeEachHooks(suite *TestSuite) error {
+// runBeforeEachHooks runs beforeEach hooks from parent to child, starting
+// with those of test's file
+func (tr *TestRunner) runBeforeEachHooks(test *Test, suite *TestSuite) error {
 	if suite.Parent != nil {
-		if err := tr.runBeforeEachHooks(suite.Parent); err != nil {
+		if err := tr.runBeforeEachHooks(test, suite.Parent); err != nil {
 			return err
 		}
+	} else {
+		for _, hook := range tr.beforeEachHooks {
+			if hook.file != test.File {
+				continue
+			}
+			if err := hook.fn(); err != nil {
+				return fmt.Errorf("beforeEach hook failed: %v", err)
+			}
+		}
 	}
 	
 	for _, hook := range suite.BeforeEach {
@@ -548,8 +781,9 @@ func (tr *TestRunner) runBeforeEachHooks(suite *TestSuite) error {
 	return nil
 }
 
-// runAfterEachHooks runs afterEach hooks from child to parent
-func (tr *TestRunner) runAfterEachHooks(suite *TestSuite) error {
+// runAfterEachHooks runs afterEach hooks from child to parent, ending with
+// those of test's file
+func (tr *TestRunner) runAfterEachHooks(test *Test, suite *TestSuite) error {
 	for _, hook := range suite.AfterEach {
 		if err := hook(); err != nil {
 			return fmt.Errorf("afterEach hook failed: %v", err)
@@ -557,8 +791,14 @@ func (tr *TestRunner) runAfterEachHooks(suite *TestSuite) error {
 	}
 	
 	if suite.Parent != nil {
-		if err := tr.runAfterEachHooks(suite.Parent); err != nil {
-			return err
+		return tr.runAfterEachHooks(test, suite.Parent)
+	}
+	for _, hook := range tr.afterEachHooks {
+		if hook.file != test.File {
+			continue
+		}
+		if err := hook.fn(); err != nil {
+			return fmt.Errorf("afterEach hook failed: %v", err)
 		}
 	}
 	
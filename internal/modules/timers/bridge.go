@@ -71,10 +71,10 @@ func (b *Bridge) setTimeout(call goja.FunctionCall) goja.Value {
 		}
 	}
 
-	// Get additional arguments
+	// Get additional arguments, copied off the VM's stack
 	var args []goja.Value
 	if len(call.Arguments) > 2 {
-		args = call.Arguments[2:]
+		args = append([]goja.Value(nil), call.Arguments[2:]...)
 	}
 
 	// Create timeout
@@ -102,10 +102,10 @@ func (b *Bridge) setInterval(call goja.FunctionCall) goja.Value {
 		}
 	}
 
-	// Get additional arguments
+	// Get additional arguments, copied off the VM's stack
 	var args []goja.Value
 	if len(call.Arguments) > 2 {
-		args = call.Arguments[2:]
+		args = append([]goja.Value(nil), call.Arguments[2:]...)
 	}
 
 	// Create interval
@@ -0,0 +1,59 @@
+package timers
+
+import (
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/abort"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// promisesExports builds timers/promises: setTimeout(delay, value,
+// {signal}) and setImmediate(value, {signal}) return promises resolving
+// with value. Aborting the signal clears the timer and rejects with the
+// signal's reason. It must be called on the JS thread.
+func (b *Bridge) promisesExports() *goja.Object {
+	vm := b.timersModule.runtime.GetGojaRuntime()
+	exports := vm.NewObject()
+	exports.Set("setTimeout", func(call goja.FunctionCall) goja.Value {
+		var delay int64
+		if d := call.Argument(0); !goja.IsUndefined(d) && d.ToInteger() > 0 {
+			delay = d.ToInteger()
+		}
+		return b.delay(delay, call.Argument(1), call.Argument(2))
+	})
+	exports.Set("setImmediate", func(call goja.FunctionCall) goja.Value {
+		return b.delay(0, call.Argument(0), call.Argument(1))
+	})
+	return exports
+}
+
+// delay returns a promise resolving with value after delay milliseconds,
+// unless the signal in options aborts first
+func (b *Bridge) delay(delay int64, value, options goja.Value) goja.Value {
+	runtime := b.timersModule.runtime
+	vm := runtime.GetGojaRuntime()
+	p, resolver := promise.New(vm, runtime)
+
+	var signal *abort.Signal
+	if obj, ok := options.(*goja.Object); ok {
+		var err error
+		if signal, err = abort.From(vm, obj.Get("signal")); err != nil {
+			resolver.Reject(vm.NewTypeError(err.Error()))
+			return p
+		}
+	}
+	if err := signal.Err(); err != nil {
+		resolver.Reject(err)
+		return p
+	}
+
+	var remove func()
+	id := b.timersModule.SetTimeout(vm.ToValue(func() {
+		remove()
+		resolver.Resolve(value)
+	}), delay)
+	remove = signal.OnAbort(func(goja.Value) {
+		b.timersModule.ClearTimeout(id)
+		resolver.Reject(signal.Err())
+	})
+	return p
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 )
 
-// RegisterTimersModule registers the timers module in the JavaScript runtime
+// RegisterTimersModule registers the timer globals and timers/promises, also
+// as gode:timers/promises, in the JavaScript runtime
 func RegisterTimersModule(runtime RuntimeInterface) (*Bridge, error) {
 	bridge := NewBridge(runtime)
 	
@@ -29,5 +30,14 @@ func RegisterTimersModule(runtime RuntimeInterface) (*Bridge, error) {
 		return nil, fmt.Errorf("failed to register clearInterval: %w", err)
 	}
 
+	done := make(chan struct{})
+	runtime.QueueJSOperation(func() {
+		exports := bridge.promisesExports()
+		runtime.RegisterModule("timers/promises", exports)
+		runtime.RegisterModule("gode:timers/promises", exports)
+		close(done)
+	})
+	<-done
+
 	return bridge, nil
 }
\ No newline at end of file
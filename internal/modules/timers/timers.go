@@ -1,11 +1,13 @@
 package timers
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/asynccontext"
 )
 
 // RuntimeInterface represents the methods we need from the runtime
@@ -14,6 +16,19 @@ type RuntimeInterface interface {
 	QueueJSOperation(fn func())
 	GetGojaRuntime() *goja.Runtime
 	SetGlobal(name string, value interface{}) error
+	RegisterModule(name string, exports interface{})
+}
+
+// contextTracker is implemented by runtimes that follow the async context
+// of JS code across callbacks, see asynccontext
+type contextTracker interface {
+	AsyncContext() *asynccontext.Tracker
+}
+
+// uncaughtReporter is implemented by runtimes that hand errors thrown by
+// callbacks to the process 'uncaughtException' listeners
+type uncaughtReporter interface {
+	ReportUncaught(err goja.Value)
 }
 
 // TimersModule provides timer functionality (setTimeout, setInterval, etc.)
@@ -35,6 +50,7 @@ type Timer struct {
 	repeat   bool
 	cleared  bool
 	quit     chan struct{} // Channel to signal goroutine to stop
+	ctx      context.Context // async context the timer was set in
 }
 
 // NewTimersModule creates a new timers module instance
@@ -61,6 +77,7 @@ func (tm *TimersModule) SetTimeout(callback goja.Value, delay int64, args ...goj
 		repeat:   false,
 		cleared:  false,
 		quit:     make(chan struct{}),
+		ctx:      tm.tracker().Capture(),
 	}
 
 	// Create Go timer
@@ -92,6 +109,7 @@ func (tm *TimersModule) SetInterval(callback goja.Value, interval int64, args ..
 		repeat:   true,
 		cleared:  false,
 		quit:     make(chan struct{}),
+		ctx:      tm.tracker().Capture(),
 	}
 
 	// Create Go ticker
@@ -183,10 +201,14 @@ func (tm *TimersModule) executeCallback(timer *Timer) {
 		if timer.callback != nil && !goja.IsUndefined(timer.callback) && !goja.IsNull(timer.callback) {
 			if fn, ok := goja.AssertFunction(timer.callback); ok && fn != nil {
 				runtime := tm.runtime.GetGojaRuntime()
-				_, err := fn(runtime.GlobalObject(), timer.args...)
-				if err != nil {
-					// Handle callback error
-				}
+				tm.tracker().Run(timer.ctx, func() {
+					_, err := fn(runtime.GlobalObject(), timer.args...)
+					if ex, ok := err.(*goja.Exception); ok {
+						if r, ok := tm.runtime.(uncaughtReporter); ok {
+							r.ReportUncaught(ex.Value())
+						}
+					}
+				})
 			}
 		}
 
@@ -200,11 +222,25 @@ func (tm *TimersModule) executeCallback(timer *Timer) {
 	})
 }
 
+// tracker returns the runtime's async context tracker, or nil when it has
+// none
+func (tm *TimersModule) tracker() *asynccontext.Tracker {
+	if t, ok := tm.runtime.(contextTracker); ok {
+		return t.AsyncContext()
+	}
+	return nil
+}
+
 // HasActiveTimers returns true if there are active timers
 func (tm *TimersModule) HasActiveTimers() bool {
 	return atomic.LoadInt64(&tm.activeCount) > 0
 }
 
+// ActiveTimers returns the number of pending timeouts and intervals
+func (tm *TimersModule) ActiveTimers() int64 {
+	return atomic.LoadInt64(&tm.activeCount)
+}
+
 // WaitForTimers blocks until all timers are finished or timeout is reached
 func (tm *TimersModule) WaitForTimers(timeout time.Duration) {
 	if timeout <= 0 {
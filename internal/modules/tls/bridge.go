@@ -0,0 +1,148 @@
+package tls
+
+import (
+	"time"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Bridge provides JavaScript bindings for the gode:tls module
+type Bridge struct {
+	runtime *goja.Runtime
+}
+
+// NewBridge creates a new TLS bridge
+func NewBridge(runtime *goja.Runtime) *Bridge {
+	return &Bridge{runtime: runtime}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.runtime.NewObject()
+	exports.Set("generateSelfSigned", b.generateSelfSigned)
+	exports.Set("createCSR", b.createCSR)
+	exports.Set("inspect", b.inspect)
+	exports.Set("devCertificate", b.devCertificate)
+	return exports
+}
+
+// generateSelfSigned implements tls.generateSelfSigned({hosts, commonName, organization, days, keyType})
+func (b *Bridge) generateSelfSigned(call goja.FunctionCall) goja.Value {
+	opts := b.certOptions(call.Argument(0))
+
+	certPEM, keyPEM, err := GenerateSelfSigned(opts)
+	if err != nil {
+		panic(b.runtime.NewGoError(err))
+	}
+
+	result := b.runtime.NewObject()
+	result.Set("cert", string(certPEM))
+	result.Set("key", string(keyPEM))
+	return result
+}
+
+// createCSR implements tls.createCSR(options) where options.key may hold an existing PEM key
+func (b *Bridge) createCSR(call goja.FunctionCall) goja.Value {
+	opts := b.certOptions(call.Argument(0))
+
+	var keyPEM []byte
+	if obj, ok := call.Argument(0).(*goja.Object); ok {
+		if key := obj.Get("key"); key != nil && !goja.IsUndefined(key) && !goja.IsNull(key) {
+			keyPEM = []byte(key.String())
+		}
+	}
+
+	csrPEM, keyPEM, err := CreateCSR(opts, keyPEM)
+	if err != nil {
+		panic(b.runtime.NewGoError(err))
+	}
+
+	result := b.runtime.NewObject()
+	result.Set("csr", string(csrPEM))
+	result.Set("key", string(keyPEM))
+	return result
+}
+
+// inspect implements tls.inspect(pem)
+func (b *Bridge) inspect(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) < 1 {
+		panic(b.runtime.NewTypeError("inspect requires a PEM encoded certificate"))
+	}
+
+	info, err := Inspect([]byte(call.Arguments[0].String()))
+	if err != nil {
+		panic(b.runtime.NewGoError(err))
+	}
+
+	result := b.runtime.NewObject()
+	result.Set("subject", info.Subject)
+	result.Set("issuer", info.Issuer)
+	result.Set("serialNumber", info.SerialNumber)
+	result.Set("notBefore", info.NotBefore.Format(time.RFC3339))
+	result.Set("notAfter", info.NotAfter.Format(time.RFC3339))
+	result.Set("daysUntilExpiry", info.DaysUntilExpiry)
+	result.Set("expired", info.Expired)
+	result.Set("dnsNames", info.DNSNames)
+	result.Set("ipAddresses", info.IPAddresses)
+	result.Set("emailAddresses", info.EmailAddresses)
+	result.Set("isCA", info.IsCA)
+	result.Set("signatureAlgorithm", info.SignatureAlgorithm)
+	result.Set("fingerprint", info.Fingerprint)
+	return result
+}
+
+// devCertificate implements tls.devCertificate({hosts, dir})
+func (b *Bridge) devCertificate(call goja.FunctionCall) goja.Value {
+	opts := b.certOptions(call.Argument(0))
+
+	dir := DefaultDevCertDir()
+	if obj, ok := call.Argument(0).(*goja.Object); ok {
+		if d := obj.Get("dir"); d != nil && !goja.IsUndefined(d) && !goja.IsNull(d) {
+			dir = d.String()
+		}
+	}
+
+	dev, err := DevCertificate(dir, opts.Hosts)
+	if err != nil {
+		panic(b.runtime.NewGoError(err))
+	}
+
+	result := b.runtime.NewObject()
+	result.Set("cert", string(dev.Cert))
+	result.Set("key", string(dev.Key))
+	result.Set("ca", string(dev.CACert))
+	result.Set("certFile", dev.CertFile)
+	result.Set("keyFile", dev.KeyFile)
+	result.Set("caFile", dev.CAFile)
+	return result
+}
+
+// certOptions reads certificate options from a JS object
+func (b *Bridge) certOptions(value goja.Value) CertOptions {
+	var opts CertOptions
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts
+	}
+
+	if hosts := obj.Get("hosts"); hosts != nil && !goja.IsUndefined(hosts) && !goja.IsNull(hosts) {
+		if err := b.runtime.ExportTo(hosts, &opts.Hosts); err != nil {
+			panic(b.runtime.NewTypeError("hosts must be an array of strings"))
+		}
+	}
+	if cn := obj.Get("commonName"); cn != nil && !goja.IsUndefined(cn) {
+		opts.CommonName = cn.String()
+	}
+	if org := obj.Get("organization"); org != nil && !goja.IsUndefined(org) {
+		opts.Organization = org.String()
+	}
+	if keyType := obj.Get("keyType"); keyType != nil && !goja.IsUndefined(keyType) {
+		opts.KeyType = keyType.String()
+	}
+	if days := obj.Get("days"); days != nil && !goja.IsUndefined(days) {
+		opts.ValidFor = time.Duration(days.ToInteger()) * 24 * time.Hour
+	}
+
+	return opts
+}
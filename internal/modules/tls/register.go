@@ -0,0 +1,23 @@
+package tls
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+}
+
+// RegisterTLSModule registers gode:tls in the JavaScript runtime
+func RegisterTLSModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime.GetGojaRuntime())
+		runtime.RegisterModule("gode:tls", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
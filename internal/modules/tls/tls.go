@@ -0,0 +1,419 @@
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	gotls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CertOptions describes a certificate or CSR to generate
+type CertOptions struct {
+	Hosts        []string      // DNS names and IP addresses for the SAN extension
+	CommonName   string        // Defaults to the first host
+	Organization string        // Defaults to "Gode Development"
+	ValidFor     time.Duration // Defaults to one year
+	KeyType      string        // "ecdsa" (default) or "rsa"
+	IsCA         bool
+}
+
+// CertInfo is the inspected form of a certificate
+type CertInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serialNumber"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysUntilExpiry    int       `json:"daysUntilExpiry"`
+	Expired            bool      `json:"expired"`
+	DNSNames           []string  `json:"dnsNames"`
+	IPAddresses        []string  `json:"ipAddresses"`
+	EmailAddresses     []string  `json:"emailAddresses"`
+	IsCA               bool      `json:"isCA"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	Fingerprint        string    `json:"fingerprint"` // SHA-256 of the DER bytes
+}
+
+// DevCert is a development certificate signed by the local gode CA
+type DevCert struct {
+	Cert     []byte
+	Key      []byte
+	CACert   []byte
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// GenerateSelfSigned creates a self-signed certificate and its private key, both PEM encoded
+func GenerateSelfSigned(opts CertOptions) (certPEM, keyPEM []byte, err error) {
+	key, keyPEM, err := generateKey(opts.KeyType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := newTemplate(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// CreateCSR creates a certificate signing request. When keyPEM is empty a
+// new key is generated and returned alongside the CSR.
+func CreateCSR(opts CertOptions, keyPEM []byte) (csrPEM, outKeyPEM []byte, err error) {
+	var key crypto.Signer
+	if len(keyPEM) > 0 {
+		key, err = ParsePrivateKey(keyPEM)
+		outKeyPEM = keyPEM
+	} else {
+		key, outKeyPEM, err = generateKey(opts.KeyType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dnsNames, ips := splitHosts(opts.Hosts)
+	template := &x509.CertificateRequest{
+		Subject:     subject(opts),
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CSR: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), outKeyPEM, nil
+}
+
+// Inspect parses the first certificate in pemData
+func Inspect(pemData []byte) (*CertInfo, error) {
+	cert, err := parseCertificate(pemData)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, len(cert.IPAddresses))
+	for i, ip := range cert.IPAddresses {
+		ips[i] = ip.String()
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	remaining := time.Until(cert.NotAfter)
+
+	return &CertInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       cert.SerialNumber.Text(16),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysUntilExpiry:    int(remaining.Hours() / 24),
+		Expired:            remaining <= 0,
+		DNSNames:           nonNil(cert.DNSNames),
+		IPAddresses:        ips,
+		EmailAddresses:     nonNil(cert.EmailAddresses),
+		IsCA:               cert.IsCA,
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		Fingerprint:        hex.EncodeToString(fingerprint[:]),
+	}, nil
+}
+
+// DefaultDevCertDir returns the directory holding the local CA and dev certificates
+func DefaultDevCertDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "gode-certs")
+	}
+	return filepath.Join(home, ".gode", "certs")
+}
+
+// DevCertificate returns a certificate for hosts signed by a local CA kept
+// in dir. The CA is created on first use and reused afterwards, so trusting
+// its certificate once (CAFile) makes every dev certificate trusted. An
+// existing leaf is reused while it is valid and covers all hosts.
+func DevCertificate(dir string, hosts []string) (*DevCert, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+
+	caCert, caKey, caPEM, err := loadOrCreateCA(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dev := &DevCert{
+		CACert:   caPEM,
+		CAFile:   filepath.Join(dir, "gode-ca.pem"),
+		CertFile: filepath.Join(dir, "dev-cert.pem"),
+		KeyFile:  filepath.Join(dir, "dev-key.pem"),
+	}
+
+	if certPEM, keyPEM, ok := reusableLeaf(dev.CertFile, dev.KeyFile, caCert, hosts); ok {
+		dev.Cert, dev.Key = certPEM, keyPEM
+		return dev, nil
+	}
+
+	key, keyPEM, err := generateKey("ecdsa")
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := newTemplate(CertOptions{Hosts: hosts, ValidFor: 90 * 24 * time.Hour})
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign development certificate: %w", err)
+	}
+
+	dev.Cert = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	dev.Key = keyPEM
+
+	if err := os.WriteFile(dev.CertFile, dev.Cert, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write development certificate: %w", err)
+	}
+	if err := os.WriteFile(dev.KeyFile, dev.Key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write development key: %w", err)
+	}
+
+	return dev, nil
+}
+
+// TLSConfig builds a server TLS configuration from PEM data
+func (d *DevCert) TLSConfig() (*gotls.Config, error) {
+	pair, err := gotls.X509KeyPair(d.Cert, d.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load development certificate: %w", err)
+	}
+	return &gotls.Config{Certificates: []gotls.Certificate{pair}, MinVersion: gotls.VersionTLS12}, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#8, PKCS#1 or EC private key
+func ParsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in private key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported private key format %q", block.Type)
+}
+
+func generateKey(keyType string) (crypto.Signer, []byte, error) {
+	var key crypto.Signer
+	var err error
+
+	switch keyType {
+	case "", "ecdsa":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func newTemplate(opts CertOptions) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	validFor := opts.ValidFor
+	if validFor <= 0 {
+		validFor = 365 * 24 * time.Hour
+	}
+
+	dnsNames, ips := splitHosts(opts.Hosts)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject(opts),
+		NotBefore:             time.Now().Add(-time.Hour), // Tolerate small clock skew
+		NotAfter:              time.Now().Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+	}
+
+	if opts.IsCA {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = nil
+	}
+
+	return template, nil
+}
+
+func subject(opts CertOptions) pkix.Name {
+	org := opts.Organization
+	if org == "" {
+		org = "Gode Development"
+	}
+	cn := opts.CommonName
+	if cn == "" && len(opts.Hosts) > 0 {
+		cn = opts.Hosts[0]
+	}
+	return pkix.Name{CommonName: cn, Organization: []string{org}}
+}
+
+func splitHosts(hosts []string) ([]string, []net.IP) {
+	var dnsNames []string
+	var ips []net.IP
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	return dnsNames, ips
+}
+
+func parseCertificate(pemData []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found in PEM data")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+func loadOrCreateCA(dir string) (*x509.Certificate, crypto.Signer, []byte, error) {
+	certFile := filepath.Join(dir, "gode-ca.pem")
+	keyFile := filepath.Join(dir, "gode-ca-key.pem")
+
+	certPEM, certErr := os.ReadFile(certFile)
+	keyPEM, keyErr := os.ReadFile(keyFile)
+	if certErr == nil && keyErr == nil {
+		cert, err := parseCertificate(certPEM)
+		if err == nil && time.Now().Before(cert.NotAfter) {
+			if key, err := ParsePrivateKey(keyPEM); err == nil {
+				return cert, key, certPEM, nil
+			}
+		}
+	}
+
+	key, keyPEM, err := generateKey("ecdsa")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	template, err := newTemplate(CertOptions{
+		CommonName: fmt.Sprintf("gode development CA (%s)", hostname),
+		ValidFor:   10 * 365 * 24 * time.Hour,
+		IsCA:       true,
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create local CA: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to write CA key: %w", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to write CA certificate: %w", err)
+	}
+
+	return cert, key, certPEM, nil
+}
+
+// reusableLeaf loads an existing dev certificate if it was signed by ca, is
+// valid for at least another week and covers every host
+func reusableLeaf(certFile, keyFile string, ca *x509.Certificate, hosts []string) ([]byte, []byte, bool) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, false
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	cert, err := parseCertificate(certPEM)
+	if err != nil || cert.CheckSignatureFrom(ca) != nil {
+		return nil, nil, false
+	}
+	if time.Until(cert.NotAfter) < 7*24*time.Hour {
+		return nil, nil, false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return nil, nil, false
+		}
+	}
+
+	return certPEM, keyPEM, true
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
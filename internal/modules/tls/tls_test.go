@@ -0,0 +1,97 @@
+package tls
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateSelfSignedAndInspect(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSigned(CertOptions{
+		Hosts:    []string{"localhost", "127.0.0.1"},
+		ValidFor: 30 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("GenerateSelfSigned failed: %v", err)
+	}
+	if !strings.Contains(string(keyPEM), "PRIVATE KEY") {
+		t.Errorf("Expected PEM private key, got %q", keyPEM)
+	}
+
+	info, err := Inspect(certPEM)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+
+	if info.Expired || info.DaysUntilExpiry < 29 || info.DaysUntilExpiry > 30 {
+		t.Errorf("Unexpected expiry: %d days, expired=%v", info.DaysUntilExpiry, info.Expired)
+	}
+	if len(info.DNSNames) != 1 || info.DNSNames[0] != "localhost" {
+		t.Errorf("Expected DNS SAN localhost, got %v", info.DNSNames)
+	}
+	if len(info.IPAddresses) != 1 || info.IPAddresses[0] != "127.0.0.1" {
+		t.Errorf("Expected IP SAN 127.0.0.1, got %v", info.IPAddresses)
+	}
+	if !strings.Contains(info.Subject, "CN=localhost") || info.Subject != info.Issuer {
+		t.Errorf("Expected self-signed localhost certificate, got subject %q issuer %q", info.Subject, info.Issuer)
+	}
+	if len(info.Fingerprint) != 64 {
+		t.Errorf("Expected SHA-256 hex fingerprint, got %q", info.Fingerprint)
+	}
+}
+
+func TestCreateCSRWithExistingKey(t *testing.T) {
+	_, keyPEM, err := GenerateSelfSigned(CertOptions{Hosts: []string{"example.com"}, KeyType: "rsa"})
+	if err != nil {
+		t.Fatalf("GenerateSelfSigned failed: %v", err)
+	}
+
+	csrPEM, outKey, err := CreateCSR(CertOptions{Hosts: []string{"example.com"}}, keyPEM)
+	if err != nil {
+		t.Fatalf("CreateCSR failed: %v", err)
+	}
+	if !strings.Contains(string(csrPEM), "CERTIFICATE REQUEST") {
+		t.Errorf("Expected PEM CSR, got %q", csrPEM)
+	}
+	if string(outKey) != string(keyPEM) {
+		t.Error("Expected the provided key to be returned unchanged")
+	}
+}
+
+func TestDevCertificateReusesCA(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := DevCertificate(dir, nil)
+	if err != nil {
+		t.Fatalf("DevCertificate failed: %v", err)
+	}
+	if _, err := first.TLSConfig(); err != nil {
+		t.Fatalf("TLSConfig failed: %v", err)
+	}
+
+	info, err := Inspect(first.Cert)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if info.IsCA || !strings.Contains(info.Issuer, "gode development CA") {
+		t.Errorf("Expected leaf signed by the local CA, got issuer %q", info.Issuer)
+	}
+
+	// Same hosts: the leaf is reused
+	second, err := DevCertificate(dir, nil)
+	if err != nil {
+		t.Fatalf("DevCertificate failed: %v", err)
+	}
+	if string(second.Cert) != string(first.Cert) {
+		t.Error("Expected the existing development certificate to be reused")
+	}
+
+	// New host: a new leaf from the same CA
+	third, err := DevCertificate(dir, []string{"myapp.test"})
+	if err != nil {
+		t.Fatalf("DevCertificate failed: %v", err)
+	}
+	if string(third.Cert) == string(first.Cert) || string(third.CACert) != string(first.CACert) {
+		t.Error("Expected a new leaf signed by the same CA")
+	}
+}
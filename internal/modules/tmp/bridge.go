@@ -0,0 +1,200 @@
+package tmp
+
+import (
+	"os"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
+)
+
+// Bridge provides JavaScript bindings for the gode:tmp module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+	tracker *Tracker
+}
+
+// NewBridge creates a new tmp bridge around tracker
+func NewBridge(runtime RuntimeInterface, tracker *Tracker) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+		tracker: tracker,
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("file", b.file)
+	exports.Set("dir", b.dir)
+	exports.Set("withDir", b.withDir)
+	exports.Set("track", func(path string) {
+		b.tracker.Track(path)
+	})
+	exports.Set("untrack", func(path string) {
+		b.tracker.Untrack(path)
+	})
+	exports.Set("cleanup", func() {
+		if err := b.tracker.Cleanup(); err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+	})
+	exports.Set("tracked", func() []string {
+		return b.tracker.Paths()
+	})
+	return exports
+}
+
+// file implements tmp.file({prefix, suffix, dir, keep}) returning
+// {path, remove()}. The file is removed at exit unless keep is true.
+func (b *Bridge) file(call goja.FunctionCall) goja.Value {
+	opts := b.options(call.Argument(0))
+	b.checkWrite(opts.dir)
+
+	path, err := b.tracker.File(opts.dir, opts.prefix, opts.suffix)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return b.entry(path, opts.keep)
+}
+
+// dir implements tmp.dir({prefix, dir, keep}) returning {path, remove()}
+func (b *Bridge) dir(call goja.FunctionCall) goja.Value {
+	opts := b.options(call.Argument(0))
+	b.checkWrite(opts.dir)
+
+	path, err := b.tracker.Dir(opts.dir, opts.prefix)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return b.entry(path, opts.keep)
+}
+
+// withDir implements tmp.withDir(fn, options): it creates a directory, calls
+// fn(path) and removes the directory once the returned value or Promise
+// settles, resolving or rejecting with its outcome
+func (b *Bridge) withDir(call goja.FunctionCall) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(b.vm.NewTypeError("withDir requires a function"))
+	}
+	opts := b.options(call.Argument(1))
+	b.checkWrite(opts.dir)
+
+	promise, resolve, reject := b.vm.NewPromise()
+
+	path, err := b.tracker.Dir(opts.dir, opts.prefix)
+	if err != nil {
+		reject(b.vm.NewGoError(err))
+		return b.vm.ToValue(promise)
+	}
+
+	result, err := fn(goja.Undefined(), b.vm.ToValue(path))
+	if err != nil {
+		b.tracker.Remove(path)
+		reject(b.errorValue(err))
+		return b.vm.ToValue(promise)
+	}
+
+	b.settle(result, func(value goja.Value) {
+		b.tracker.Remove(path)
+		resolve(value)
+	}, func(reason goja.Value) {
+		b.tracker.Remove(path)
+		reject(reason)
+	})
+	return b.vm.ToValue(promise)
+}
+
+// entry builds the {path, remove()} object for a created path
+func (b *Bridge) entry(path string, keep bool) *goja.Object {
+	if keep {
+		b.tracker.Untrack(path)
+	}
+
+	obj := b.vm.NewObject()
+	obj.Set("path", path)
+	obj.Set("remove", func() {
+		if err := b.tracker.Remove(path); err != nil {
+			panic(b.vm.NewGoError(err))
+		}
+	})
+	return obj
+}
+
+type options struct {
+	prefix string
+	suffix string
+	dir    string
+	keep   bool
+}
+
+func (b *Bridge) options(value goja.Value) options {
+	opts := options{prefix: "gode-"}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts
+	}
+	if v := b.option(obj, "prefix"); v != nil {
+		opts.prefix = v.String()
+	}
+	if v := b.option(obj, "suffix"); v != nil {
+		opts.suffix = v.String()
+	}
+	if v := b.option(obj, "dir"); v != nil {
+		opts.dir = v.String()
+	}
+	if v := b.option(obj, "keep"); v != nil {
+		opts.keep = v.ToBoolean()
+	}
+	return opts
+}
+
+// checkWrite checks the runtime's write permission for the directory the
+// temporary path is created in
+func (b *Bridge) checkWrite(dir string) {
+	checker, ok := b.runtime.(permissions.Enforcer)
+	if !ok {
+		return
+	}
+	if dir == "" {
+		dir = b.tracker.base("")
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := checker.CheckPermission("write", dir); err != nil {
+		panic(jserror.New(b.vm, err))
+	}
+}
+
+// settle calls onFulfilled or onRejected once value settles, adopting it
+// when it is a thenable
+func (b *Bridge) settle(value goja.Value, onFulfilled, onRejected func(goja.Value)) {
+	if obj, ok := value.(*goja.Object); ok {
+		if then, ok := goja.AssertFunction(obj.Get("then")); ok {
+			then(obj, b.vm.ToValue(onFulfilled), b.vm.ToValue(onRejected))
+			return
+		}
+	}
+	onFulfilled(value)
+}
+
+// errorValue converts a Go or JS error into the value to reject with
+func (b *Bridge) errorValue(err error) goja.Value {
+	if e, ok := err.(*goja.Exception); ok {
+		return e.Value()
+	}
+	return b.vm.NewGoError(err)
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,30 @@
+package tmp
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	AddShutdownHook(fn func()) (remove func())
+}
+
+// RegisterTmpModule registers gode:tmp in the JavaScript runtime. Paths
+// still tracked when the runtime shuts down are removed.
+func RegisterTmpModule(runtime RuntimeInterface) error {
+	tracker := NewTracker("")
+	runtime.AddShutdownHook(func() {
+		tracker.Cleanup()
+	})
+
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime, tracker)
+		runtime.RegisterModule("gode:tmp", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
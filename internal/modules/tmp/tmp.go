@@ -0,0 +1,99 @@
+// Package tmp implements the gode:tmp module, which hands out temporary
+// files and directories and removes whatever is left of them when the
+// process exits
+package tmp
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Tracker creates temporary paths and remembers them for cleanup
+type Tracker struct {
+	dir   string
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+// NewTracker creates a tracker placing its paths in dir, or in the system
+// temp directory when dir is empty
+func NewTracker(dir string) *Tracker {
+	return &Tracker{dir: dir, paths: make(map[string]bool)}
+}
+
+// File creates an empty file named prefix + random + suffix
+func (t *Tracker) File(dir, prefix, suffix string) (string, error) {
+	f, err := os.CreateTemp(t.base(dir), prefix+"*"+suffix)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	t.Track(f.Name())
+	return f.Name(), nil
+}
+
+// Dir creates an empty directory named prefix + random
+func (t *Tracker) Dir(dir, prefix string) (string, error) {
+	path, err := os.MkdirTemp(t.base(dir), prefix+"*")
+	if err != nil {
+		return "", err
+	}
+	t.Track(path)
+	return path, nil
+}
+
+func (t *Tracker) base(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	return t.dir
+}
+
+// Track adds an existing path to be removed on cleanup
+func (t *Tracker) Track(path string) {
+	t.mu.Lock()
+	t.paths[path] = true
+	t.mu.Unlock()
+}
+
+// Untrack keeps path from being removed on cleanup
+func (t *Tracker) Untrack(path string) {
+	t.mu.Lock()
+	delete(t.paths, path)
+	t.mu.Unlock()
+}
+
+// Remove deletes a tracked path, recursively for directories
+func (t *Tracker) Remove(path string) error {
+	t.Untrack(path)
+	return os.RemoveAll(path)
+}
+
+// Paths lists the tracked paths in sorted order
+func (t *Tracker) Paths() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	paths := make([]string, 0, len(t.paths))
+	for path := range t.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Cleanup removes every tracked path. Paths are removed in reverse order so
+// files inside a tracked directory go before the directory itself.
+func (t *Tracker) Cleanup() error {
+	paths := t.Paths()
+
+	var errs []error
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := t.Remove(paths[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package tmp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrackerCleanup(t *testing.T) {
+	tracker := NewTracker(t.TempDir())
+
+	file, err := tracker.File("", "upload-", ".bin")
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(file), "upload-") || !strings.HasSuffix(file, ".bin") {
+		t.Errorf("Unexpected file name %q", file)
+	}
+
+	dir, err := tracker.Dir("", "work-")
+	if err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	nested, err := tracker.File(dir, "", "")
+	if err != nil {
+		t.Fatalf("Failed to create nested file: %v", err)
+	}
+
+	kept, _ := tracker.File("", "kept-", "")
+	tracker.Untrack(kept)
+
+	if len(tracker.Paths()) != 3 {
+		t.Errorf("Expected 3 tracked paths, got %v", tracker.Paths())
+	}
+
+	if err := tracker.Cleanup(); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	for _, path := range []string{file, dir, nested} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %q to be removed", path)
+		}
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("Expected untracked %q to be kept", kept)
+	}
+	if len(tracker.Paths()) != 0 {
+		t.Error("Expected no tracked paths after cleanup")
+	}
+}
@@ -0,0 +1,242 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// Bridge provides JavaScript bindings for the gode:validate module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+	ruleKey *goja.Symbol
+}
+
+// NewBridge creates a new validate bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+		ruleKey: goja.NewSymbol("gode.validate.rule"),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+
+	// Simple predicates
+	exports.Set("isEmail", b.predicate(Email))
+	exports.Set("isDomain", b.predicate(Domain))
+	exports.Set("isCreditCard", b.predicate(CreditCard))
+	exports.Set("isISODate", func(s string) bool {
+		_, err := ISODate(s)
+		return err == nil
+	})
+	exports.Set("isURL", func(call goja.FunctionCall) goja.Value {
+		return b.vm.ToValue(URL(call.Argument(0).String(), b.protocols(call.Argument(1))...) == nil)
+	})
+	exports.Set("isIP", func(s string, version int) bool { return IP(s, version) == nil })
+	exports.Set("isCIDR", func(s string, version int) bool { return CIDR(s, version) == nil })
+	exports.Set("isUUID", func(s string, version int) bool { return UUID(s, version) == nil })
+	exports.Set("toASCII", func(s string) (string, error) { return ToASCII(s) })
+
+	// Schema building blocks
+	exports.Set("email", func() *goja.Object { return b.rule(Format("email", Email)) })
+	exports.Set("domain", func() *goja.Object { return b.rule(Format("domain", Domain)) })
+	exports.Set("creditCard", func() *goja.Object { return b.rule(Format("creditCard", CreditCard)) })
+	exports.Set("isoDate", func() *goja.Object {
+		return b.rule(Format("isoDate", func(s string) error {
+			_, err := ISODate(s)
+			return err
+		}))
+	})
+	exports.Set("url", func(call goja.FunctionCall) goja.Value {
+		protocols := b.protocols(call.Argument(0))
+		return b.rule(Format("url", func(s string) error { return URL(s, protocols...) }))
+	})
+	exports.Set("ip", func(version int) *goja.Object {
+		r := Format("ip", func(s string) error { return IP(s, version) })
+		r.keywords = IPKeywords(version)
+		return b.rule(r)
+	})
+	exports.Set("cidr", func(version int) *goja.Object {
+		return b.rule(Format("cidr", func(s string) error { return CIDR(s, version) }))
+	})
+	exports.Set("uuid", func(version int) *goja.Object {
+		return b.rule(Format("uuid", func(s string) error { return UUID(s, version) }))
+	})
+	exports.Set("string", b.stringRule)
+	exports.Set("number", b.numberRule)
+	exports.Set("boolean", func() *goja.Object { return b.rule(Boolean()) })
+	exports.Set("object", b.objectRule)
+	exports.Set("array", b.arrayRule)
+
+	return exports
+}
+
+// predicate adapts a validator into an isX(value) function
+func (b *Bridge) predicate(fn func(string) error) func(string) bool {
+	return func(s string) bool {
+		return fn(s) == nil
+	}
+}
+
+// rule wraps a Go rule in a JS object with validate(value), assert(value),
+// toJSONSchema() and optional(). The Go rule is kept under a symbol so object() and array()
+// can find it again.
+func (b *Bridge) rule(r *Rule) *goja.Object {
+	obj := b.vm.NewObject()
+	obj.SetSymbol(b.ruleKey, r)
+	obj.Set("type", r.Type)
+
+	obj.Set("validate", func(call goja.FunctionCall) goja.Value {
+		errs := r.Validate(call.Argument(0).Export())
+
+		result := b.vm.NewObject()
+		result.Set("valid", len(errs) == 0)
+		result.Set("errors", b.errorList(errs))
+		return result
+	})
+	obj.Set("assert", func(call goja.FunctionCall) goja.Value {
+		if errs := r.Validate(call.Argument(0).Export()); len(errs) > 0 {
+			err := b.vm.NewTypeError(errs[0].Error())
+			err.Set("errors", b.errorList(errs))
+			panic(err)
+		}
+		return call.Argument(0)
+	})
+	obj.Set("toJSONSchema", func() goja.Value {
+		return b.vm.ToValue(r.JSONSchema())
+	})
+	obj.Set("optional", func() *goja.Object {
+		optional := *r
+		optional.Optional = true
+		return b.rule(&optional)
+	})
+	return obj
+}
+
+// errorList converts field errors into an array of {path, message}
+func (b *Bridge) errorList(errs []FieldError) *goja.Object {
+	list := make([]interface{}, len(errs))
+	for i, e := range errs {
+		item := b.vm.NewObject()
+		item.Set("path", e.Path)
+		item.Set("message", e.Message)
+		list[i] = item
+	}
+	return b.vm.NewArray(list...)
+}
+
+// unwrap returns the Go rule behind a value created by this module
+func (b *Bridge) unwrap(value goja.Value, what string) *Rule {
+	if obj, ok := value.(*goja.Object); ok {
+		if sym := obj.GetSymbol(b.ruleKey); sym != nil {
+			if r, ok := sym.Export().(*Rule); ok {
+				return r
+			}
+		}
+	}
+	panic(b.vm.NewTypeError(fmt.Sprintf("%s must be a gode:validate rule", what)))
+}
+
+// stringRule implements validate.string({min, max, pattern})
+func (b *Bridge) stringRule(call goja.FunctionCall) goja.Value {
+	opts, _ := call.Argument(0).(*goja.Object)
+
+	min, max := 0, 0
+	if v := b.option(opts, "min"); v != nil {
+		min = int(v.ToInteger())
+	}
+	if v := b.option(opts, "max"); v != nil {
+		max = int(v.ToInteger())
+	}
+
+	var pattern *regexp.Regexp
+	if v := b.option(opts, "pattern"); v != nil {
+		source := v.String()
+		if re, ok := v.(*goja.Object); ok && re.ClassName() == "RegExp" {
+			source = re.Get("source").String()
+		}
+		compiled, err := regexp.Compile(source)
+		if err != nil {
+			panic(b.vm.NewTypeError(fmt.Sprintf("unsupported pattern: %v", err)))
+		}
+		pattern = compiled
+	}
+
+	return b.rule(String(min, max, pattern))
+}
+
+// numberRule implements validate.number({min, max, integer})
+func (b *Bridge) numberRule(call goja.FunctionCall) goja.Value {
+	opts, _ := call.Argument(0).(*goja.Object)
+
+	min, max := math.Inf(-1), math.Inf(1)
+	if v := b.option(opts, "min"); v != nil {
+		min = v.ToFloat()
+	}
+	if v := b.option(opts, "max"); v != nil {
+		max = v.ToFloat()
+	}
+
+	integer := false
+	if v := b.option(opts, "integer"); v != nil {
+		integer = v.ToBoolean()
+	}
+
+	return b.rule(Number(min, max, integer))
+}
+
+// objectRule implements validate.object({field: rule, ...})
+func (b *Bridge) objectRule(call goja.FunctionCall) goja.Value {
+	shape, ok := call.Argument(0).(*goja.Object)
+	if !ok {
+		panic(b.vm.NewTypeError("object() expects a map of field rules"))
+	}
+
+	fields := make(map[string]*Rule)
+	for _, name := range shape.Keys() {
+		fields[name] = b.unwrap(shape.Get(name), fmt.Sprintf("field %q", name))
+	}
+	return b.rule(Object(fields))
+}
+
+// arrayRule implements validate.array(itemRule)
+func (b *Bridge) arrayRule(call goja.FunctionCall) goja.Value {
+	var items *Rule
+	if arg := call.Argument(0); !goja.IsUndefined(arg) {
+		items = b.unwrap(arg, "array item")
+	}
+	return b.rule(Array(items))
+}
+
+// protocols reads {protocols: [...]} for URL validation
+func (b *Bridge) protocols(value goja.Value) []string {
+	opts, _ := value.(*goja.Object)
+	v := b.option(opts, "protocols")
+	if v == nil {
+		return nil
+	}
+
+	var protocols []string
+	if err := b.vm.ExportTo(v, &protocols); err != nil {
+		panic(b.vm.NewTypeError("protocols must be an array of strings"))
+	}
+	return protocols
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	if obj == nil {
+		return nil
+	}
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
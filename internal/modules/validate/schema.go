@@ -0,0 +1,263 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Rule is a schema building block. Values are the generic forms produced by
+// exporting JavaScript or decoding JSON: string, float64, int64, bool, nil,
+// []interface{} and map[string]interface{}.
+type Rule struct {
+	Type     string
+	Optional bool
+	Fields   map[string]*Rule // object rules
+	Items    *Rule            // array rules
+	check    func(value interface{}) error
+	keywords map[string]interface{} // JSON Schema keywords besides the type
+}
+
+// FieldError describes one failed rule. Path is a dotted path such as
+// "user.emails.0", empty for the top-level value.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// jsonFormats maps the names of format rules to JSON Schema formats. Rules
+// with no such format, such as creditCard, are described by an x-format
+// extension instead.
+var jsonFormats = map[string]string{
+	"email":   "email",
+	"domain":  "hostname",
+	"url":     "uri",
+	"isoDate": "date-time",
+	"uuid":    "uuid",
+}
+
+// Format builds a string rule from one of the validator functions
+func Format(name string, fn func(string) error) *Rule {
+	keywords := map[string]interface{}{"x-format": name}
+	if format, ok := jsonFormats[name]; ok {
+		keywords = map[string]interface{}{"format": format}
+	}
+	return &Rule{Type: name, keywords: keywords, check: func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		return fn(s)
+	}}
+}
+
+// IPKeywords are the JSON Schema keywords of an IP address rule: the ipv4 or
+// ipv6 format, or either of them when version is neither 4 nor 6
+func IPKeywords(version int) map[string]interface{} {
+	if version == 4 || version == 6 {
+		return map[string]interface{}{"format": fmt.Sprintf("ipv%d", version)}
+	}
+	return map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"format": "ipv4"},
+		map[string]interface{}{"format": "ipv6"},
+	}}
+}
+
+// String builds a rule for strings of min to max characters (max 0 means
+// unbounded) that match pattern when it is non-nil
+func String(min, max int, pattern *regexp.Regexp) *Rule {
+	keywords := make(map[string]interface{})
+	if min > 0 {
+		keywords["minLength"] = min
+	}
+	if max > 0 {
+		keywords["maxLength"] = max
+	}
+	if pattern != nil {
+		keywords["pattern"] = pattern.String()
+	}
+	return &Rule{Type: "string", keywords: keywords, check: func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		n := utf8.RuneCountInString(s)
+		if n < min {
+			return fmt.Errorf("must be at least %d characters", min)
+		}
+		if max > 0 && n > max {
+			return fmt.Errorf("must be at most %d characters", max)
+		}
+		if pattern != nil && !pattern.MatchString(s) {
+			return fmt.Errorf("must match %s", pattern)
+		}
+		return nil
+	}}
+}
+
+// Number builds a rule for numbers within [min, max], optionally integers only
+func Number(min, max float64, integer bool) *Rule {
+	keywords := make(map[string]interface{})
+	if !math.IsInf(min, 0) {
+		keywords["minimum"] = min
+	}
+	if !math.IsInf(max, 0) {
+		keywords["maximum"] = max
+	}
+	if integer {
+		keywords["type"] = "integer"
+	}
+	return &Rule{Type: "number", keywords: keywords, check: func(value interface{}) error {
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case int64:
+			n = float64(v)
+		case int:
+			n = float64(v)
+		default:
+			return fmt.Errorf("must be a number")
+		}
+		if math.IsNaN(n) {
+			return fmt.Errorf("must be a number")
+		}
+		if integer && n != math.Trunc(n) {
+			return fmt.Errorf("must be an integer")
+		}
+		if n < min {
+			return fmt.Errorf("must be at least %v", min)
+		}
+		if n > max {
+			return fmt.Errorf("must be at most %v", max)
+		}
+		return nil
+	}}
+}
+
+// Boolean builds a rule accepting true or false
+func Boolean() *Rule {
+	return &Rule{Type: "boolean", check: func(value interface{}) error {
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+		return nil
+	}}
+}
+
+// Object builds a rule for objects whose listed fields satisfy their rules.
+// Fields not listed are allowed.
+func Object(fields map[string]*Rule) *Rule {
+	return &Rule{Type: "object", Fields: fields}
+}
+
+// Array builds a rule for arrays whose every item satisfies items
+func Array(items *Rule) *Rule {
+	return &Rule{Type: "array", Items: items}
+}
+
+// JSONSchema describes the rule as a JSON Schema (draft 2020-12, as used by
+// OpenAPI 3.1). Format rules are strings with a format; fields of object
+// rules are required unless optional.
+func (r *Rule) JSONSchema() map[string]interface{} {
+	schema := map[string]interface{}{"type": r.Type}
+	switch r.Type {
+	case "number", "boolean", "object", "array", "string":
+	default:
+		schema["type"] = "string"
+	}
+	for name, value := range r.keywords {
+		schema[name] = value
+	}
+
+	switch r.Type {
+	case "object":
+		properties := make(map[string]interface{}, len(r.Fields))
+		var required []string
+		for name, field := range r.Fields {
+			properties[name] = field.JSONSchema()
+			if !field.Optional {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	case "array":
+		if r.Items != nil {
+			schema["items"] = r.Items.JSONSchema()
+		}
+	}
+	return schema
+}
+
+// Validate checks value against the rule and returns every failure
+func (r *Rule) Validate(value interface{}) []FieldError {
+	var errs []FieldError
+	r.validate("", value, &errs)
+	return errs
+}
+
+func (r *Rule) validate(path string, value interface{}, errs *[]FieldError) {
+	if value == nil {
+		if !r.Optional {
+			*errs = append(*errs, FieldError{Path: path, Message: "is required"})
+		}
+		return
+	}
+
+	switch r.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, FieldError{Path: path, Message: "must be an object"})
+			return
+		}
+		// Sorted so errors come out in a stable order
+		names := make([]string, 0, len(r.Fields))
+		for name := range r.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			r.Fields[name].validate(join(path, name), obj[name], errs)
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			*errs = append(*errs, FieldError{Path: path, Message: "must be an array"})
+			return
+		}
+		if r.Items != nil {
+			for i, item := range items {
+				r.Items.validate(join(path, strconv.Itoa(i)), item, errs)
+			}
+		}
+
+	default:
+		if err := r.check(value); err != nil {
+			*errs = append(*errs, FieldError{Path: path, Message: err.Error()})
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,250 @@
+package validate
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// Email checks an address of the form local@domain. The domain may be an
+// internationalized name and the local part may contain UTF-8 (RFC 6531);
+// quoted local parts and IP literals are not accepted.
+func Email(s string) error {
+	at := strings.LastIndexByte(s, '@')
+	if at <= 0 || at == len(s)-1 {
+		return fmt.Errorf("must be an email address")
+	}
+	local, domain := s[:at], s[at+1:]
+
+	if len(local) > 64 {
+		return fmt.Errorf("email local part is longer than 64 bytes")
+	}
+	if local[0] == '.' || local[len(local)-1] == '.' || strings.Contains(local, "..") {
+		return fmt.Errorf("email local part has a misplaced dot")
+	}
+	for _, r := range local {
+		if r < 0x80 && !isAtext(byte(r)) && r != '.' {
+			return fmt.Errorf("email local part contains %q", r)
+		}
+	}
+
+	ascii, err := ToASCII(domain)
+	if err != nil {
+		return fmt.Errorf("email domain: %w", err)
+	}
+	if len(local)+1+len(ascii) > 254 {
+		return fmt.Errorf("email address is longer than 254 bytes")
+	}
+	return nil
+}
+
+// Domain checks a fully qualified host name, which may be internationalized
+func Domain(s string) error {
+	_, err := ToASCII(s)
+	return err
+}
+
+// ToASCII converts a possibly internationalized domain name to its
+// lowercase punycode form and checks it is a valid fully qualified name
+func ToASCII(domain string) (string, error) {
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %q: %w", domain, err)
+	}
+	if len(ascii) > 253 {
+		return "", fmt.Errorf("domain is longer than 253 bytes")
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("domain %q must have a top-level domain", domain)
+	}
+	for _, label := range labels {
+		if err := checkLabel(label); err != nil {
+			return "", err
+		}
+	}
+	if _, err := strconv.Atoi(labels[len(labels)-1]); err == nil {
+		return "", fmt.Errorf("top-level domain cannot be numeric")
+	}
+	return ascii, nil
+}
+
+// URL checks an absolute URL with a host. schemes lists the accepted
+// schemes and defaults to http and https.
+func URL(s string, schemes ...string) error {
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("must be a URL")
+	}
+	if !contains(schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("URL scheme must be one of %s", strings.Join(schemes, ", "))
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("URL must have a host")
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n > 65535 {
+			return fmt.Errorf("URL port %s is out of range", port)
+		}
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+	return Domain(host)
+}
+
+// IP checks an IP address. version is 4, 6 or 0 for either.
+func IP(s string, version int) error {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return fmt.Errorf("must be an IP address")
+	}
+	return checkVersion(addr, version)
+}
+
+// CIDR checks a network prefix such as 10.0.0.0/8. version is 4, 6 or 0 for either.
+func CIDR(s string, version int) error {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return fmt.Errorf("must be a CIDR range")
+	}
+	return checkVersion(prefix.Addr(), version)
+}
+
+// UUID checks the canonical 8-4-4-4-12 hex form. A non-zero version also
+// requires the matching version nibble and the RFC 4122 variant.
+func UUID(s string, version int) error {
+	if len(s) != 36 {
+		return fmt.Errorf("must be a UUID")
+	}
+	for i := 0; i < len(s); i++ {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if s[i] != '-' {
+				return fmt.Errorf("must be a UUID")
+			}
+		} else if !isHex(s[i]) {
+			return fmt.Errorf("must be a UUID")
+		}
+	}
+
+	if version == 0 {
+		return nil
+	}
+	if int(s[14]-'0') != version || !strings.ContainsRune("89abAB", rune(s[19])) {
+		return fmt.Errorf("must be a version %d UUID", version)
+	}
+	return nil
+}
+
+// CreditCard checks a card number of 12 to 19 digits, optionally grouped
+// with spaces or dashes, against the Luhn checksum
+func CreditCard(s string) error {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 12 || len(digits) > 19 {
+		return fmt.Errorf("must be a card number")
+	}
+
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		c := digits[len(digits)-1-i]
+		if c < '0' || c > '9' {
+			return fmt.Errorf("must be a card number")
+		}
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	if sum%10 != 0 {
+		return fmt.Errorf("card number checksum is invalid")
+	}
+	return nil
+}
+
+// isoLayouts are the ISO 8601 forms accepted by ISODate, most specific first
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ISODate parses an ISO 8601 calendar date or date-time. Times without an
+// offset are interpreted as UTC.
+func ISODate(s string) (time.Time, error) {
+	for _, layout := range isoLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("must be an ISO 8601 date")
+}
+
+func checkVersion(addr netip.Addr, version int) error {
+	switch version {
+	case 4:
+		if !addr.Is4() {
+			return fmt.Errorf("must be an IPv4 address")
+		}
+	case 6:
+		if !addr.Is6() {
+			return fmt.Errorf("must be an IPv6 address")
+		}
+	}
+	return nil
+}
+
+func checkLabel(label string) error {
+	if label == "" || len(label) > 63 {
+		return fmt.Errorf("domain label %q must be 1 to 63 bytes", label)
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return fmt.Errorf("domain label %q cannot start or end with a hyphen", label)
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return fmt.Errorf("domain label %q contains %q", label, c)
+		}
+	}
+	return nil
+}
+
+// isAtext reports whether c may appear unquoted in an email local part (RFC 5322)
+func isAtext(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package validate
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) error
+		good  []string
+		bad   []string
+	}{
+		{"email", Email,
+			[]string{"user@example.com", "first.last+tag@sub.example.co.uk", "用户@例子.广告", "josé@bücher.de"},
+			[]string{"", "user", "@example.com", "user@", "user@localhost", ".user@example.com", "a..b@example.com", "us er@example.com", "user@-example.com"}},
+		{"domain", Domain,
+			[]string{"example.com", "xn--bcher-kva.de", "bücher.de", "EXAMPLE.com."},
+			[]string{"example", "exa_mple.com", "example.123", "-a.com"}},
+		{"url", func(s string) error { return URL(s) },
+			[]string{"https://example.com/path?q=1", "http://localhost:8080", "http://[::1]/", "https://bücher.de"},
+			[]string{"example.com", "ftp://example.com", "https://", "http://example.com:99999"}},
+		{"creditCard", CreditCard,
+			[]string{"4111 1111 1111 1111", "5500-0000-0000-0004", "378282246310005"},
+			[]string{"4111 1111 1111 1112", "1234", "4111a11111111111"}},
+		{"isoDate", func(s string) error { _, err := ISODate(s); return err },
+			[]string{"2024-02-29", "2024-03-01T10:00:00Z", "2024-03-01T10:00:00.123+02:00", "2024-03-01T10:00"},
+			[]string{"2023-02-29", "2024-13-01", "03/01/2024", "2024-03-01 10:00"}},
+	}
+
+	for _, tt := range tests {
+		for _, s := range tt.good {
+			if err := tt.check(s); err != nil {
+				t.Errorf("%s: expected %q to be valid, got %v", tt.name, s, err)
+			}
+		}
+		for _, s := range tt.bad {
+			if err := tt.check(s); err == nil {
+				t.Errorf("%s: expected %q to be invalid", tt.name, s)
+			}
+		}
+	}
+}
+
+func TestNetworkAndUUID(t *testing.T) {
+	if IP("192.168.0.1", 4) != nil || IP("::1", 6) != nil || IP("::1", 0) != nil {
+		t.Error("Expected valid IPs to pass")
+	}
+	if IP("::1", 4) == nil || IP("10.0.0.1", 6) == nil || IP("256.0.0.1", 0) == nil {
+		t.Error("Expected wrong-version or malformed IPs to fail")
+	}
+	if CIDR("10.0.0.0/8", 4) != nil || CIDR("2001:db8::/32", 0) != nil || CIDR("10.0.0.0/33", 0) == nil {
+		t.Error("Unexpected CIDR result")
+	}
+
+	v4 := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	if UUID(v4, 0) != nil || UUID(v4, 4) != nil {
+		t.Error("Expected v4 UUID to pass")
+	}
+	if UUID(v4, 1) == nil || UUID("f47ac10b58cc4372a5670e02b2c3d479", 0) == nil {
+		t.Error("Expected wrong version or unhyphenated UUID to fail")
+	}
+}
+
+func TestToASCII(t *testing.T) {
+	got, err := ToASCII("Bücher.DE")
+	if err != nil || got != "xn--bcher-kva.de" {
+		t.Errorf("Expected xn--bcher-kva.de, got %q %v", got, err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	nickname := String(2, 10, regexp.MustCompile(`^[a-z]+$`))
+	nickname.Optional = true
+
+	schema := Object(map[string]*Rule{
+		"email":    Format("email", Email),
+		"age":      Number(0, 150, true),
+		"nickname": nickname,
+		"tags":     Array(String(1, 0, nil)),
+	})
+
+	valid := map[string]interface{}{
+		"email": "user@example.com",
+		"age":   int64(30),
+		"tags":  []interface{}{"a", "b"},
+	}
+	if errs := schema.Validate(valid); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	invalid := map[string]interface{}{
+		"email":    "nope",
+		"age":      30.5,
+		"nickname": "X",
+		"tags":     []interface{}{"ok", ""},
+	}
+	errs := schema.Validate(invalid)
+	want := []string{"age", "email", "nickname", "tags.1"}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %v", len(want), errs)
+	}
+	for i, path := range want {
+		if errs[i].Path != path {
+			t.Errorf("Expected error %d at %s, got %s", i, path, errs[i].Path)
+		}
+	}
+
+	if errs := schema.Validate(map[string]interface{}{}); len(errs) != 3 {
+		t.Errorf("Expected 3 required-field errors, got %v", errs)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	nickname := String(2, 10, regexp.MustCompile(`^[a-z]+$`))
+	nickname.Optional = true
+
+	schema := Object(map[string]*Rule{
+		"email":    Format("email", Email),
+		"site":     Format("url", nil),
+		"card":     Format("creditCard", CreditCard),
+		"age":      Number(0, math.Inf(1), true),
+		"nickname": nickname,
+		"tags":     Array(Boolean()),
+	}).JSONSchema()
+
+	got, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	want := `{"properties":{"age":{"minimum":0,"type":"integer"},` +
+		`"card":{"type":"string","x-format":"creditCard"},` +
+		`"email":{"format":"email","type":"string"},` +
+		`"nickname":{"maxLength":10,"minLength":2,"pattern":"^[a-z]+$","type":"string"},` +
+		`"site":{"format":"uri","type":"string"},` +
+		`"tags":{"items":{"type":"boolean"},"type":"array"}},` +
+		`"required":["age","card","email","site","tags"],"type":"object"}`
+	if string(got) != want {
+		t.Errorf("JSONSchema() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestIPKeywords(t *testing.T) {
+	if got := IPKeywords(6)["format"]; got != "ipv6" {
+		t.Errorf("format of an IPv6 rule = %v, want ipv6", got)
+	}
+	any, ok := IPKeywords(0)["anyOf"].([]interface{})
+	if !ok || len(any) != 2 {
+		t.Errorf("Expected an IP rule of either version to allow both formats, got %v", IPKeywords(0))
+	}
+}
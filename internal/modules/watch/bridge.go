@@ -0,0 +1,184 @@
+package watch
+
+import (
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/permissions"
+	fswatch "github.com/rizqme/gode/internal/watch"
+)
+
+// Bridge provides JavaScript bindings for the gode:watch module
+type Bridge struct {
+	runtime RuntimeInterface
+	vm      *goja.Runtime
+}
+
+// NewBridge creates a new watch bridge
+func NewBridge(runtime RuntimeInterface) *Bridge {
+	return &Bridge{
+		runtime: runtime,
+		vm:      runtime.GetGojaRuntime(),
+	}
+}
+
+// Exports builds the module object
+func (b *Bridge) Exports() *goja.Object {
+	exports := b.vm.NewObject()
+	exports.Set("watch", b.watch)
+	return exports
+}
+
+// watch implements watch(paths, {recursive = true, ignore, debounce})
+// returning an async iterator of change batches:
+//
+//	for await (const changes of watch("src", { ignore: ["*.tmp"] })) {
+//	  // changes: [{ type: "create" | "change" | "remove", path }]
+//	}
+//
+// Breaking out of the loop or calling close() stops the watcher. The process
+// stays alive while a watcher is open.
+func (b *Bridge) watch(call goja.FunctionCall) goja.Value {
+	paths := b.paths(call.Argument(0))
+	opts := fswatch.Options{Recursive: true}
+
+	if obj, ok := call.Argument(1).(*goja.Object); ok {
+		if v := b.option(obj, "recursive"); v != nil {
+			opts.Recursive = v.ToBoolean()
+		}
+		if v := b.option(obj, "ignore"); v != nil {
+			if err := b.vm.ExportTo(v, &opts.Ignore); err != nil {
+				panic(b.vm.NewTypeError("ignore must be an array of glob patterns"))
+			}
+		}
+		if v := b.option(obj, "debounce"); v != nil {
+			opts.Debounce = time.Duration(v.ToInteger()) * time.Millisecond
+		}
+	}
+
+	if checker, ok := b.runtime.(permissions.Enforcer); ok {
+		for _, path := range paths {
+			if err := checker.CheckPermission("read", path); err != nil {
+				panic(jserror.New(b.vm, err))
+			}
+		}
+	}
+
+	w, err := fswatch.New(paths, opts)
+	if err != nil {
+		panic(b.vm.NewGoError(err))
+	}
+	return b.iterator(w)
+}
+
+// iterator adapts a watcher to the async iterator protocol. Batches that
+// arrive before next() is called are buffered.
+func (b *Bridge) iterator(w *fswatch.Watcher) *goja.Object {
+	var buffered []goja.Value
+	var waiting []func(interface{}) error
+	closed := false
+	release := b.runtime.KeepAlive()
+	unhook := b.runtime.AddShutdownHook(func() { w.Close() })
+
+	result := func(value goja.Value, done bool) *goja.Object {
+		obj := b.vm.NewObject()
+		obj.Set("value", value)
+		obj.Set("done", done)
+		return obj
+	}
+
+	finish := func() {
+		if closed {
+			return
+		}
+		closed = true
+		w.Close()
+		release()
+		unhook()
+		for _, resolve := range waiting {
+			resolve(result(goja.Undefined(), true))
+		}
+		waiting = nil
+	}
+
+	go func() {
+		for batch := range w.Events() {
+			batch := batch
+			b.runtime.QueueJSOperation(func() {
+				if closed {
+					return
+				}
+				value := b.batch(batch)
+				if len(waiting) > 0 {
+					resolve := waiting[0]
+					waiting = waiting[1:]
+					resolve(result(value, false))
+					return
+				}
+				buffered = append(buffered, value)
+			})
+		}
+	}()
+
+	it := b.vm.NewObject()
+	it.Set("next", func() goja.Value {
+		promise, resolve, _ := b.vm.NewPromise()
+		switch {
+		case len(buffered) > 0:
+			value := buffered[0]
+			buffered = buffered[1:]
+			resolve(result(value, false))
+		case closed:
+			resolve(result(goja.Undefined(), true))
+		default:
+			waiting = append(waiting, resolve)
+		}
+		return b.vm.ToValue(promise)
+	})
+	it.Set("return", func(value goja.Value) goja.Value {
+		finish()
+		promise, resolve, _ := b.vm.NewPromise()
+		resolve(result(value, true))
+		return b.vm.ToValue(promise)
+	})
+	it.Set("close", finish)
+	it.SetSymbol(goja.SymAsyncIterator, func(call goja.FunctionCall) goja.Value {
+		return call.This
+	})
+	return it
+}
+
+func (b *Bridge) batch(events []fswatch.Event) goja.Value {
+	items := make([]interface{}, len(events))
+	for i, e := range events {
+		item := b.vm.NewObject()
+		item.Set("type", string(e.Op))
+		item.Set("path", e.Path)
+		items[i] = item
+	}
+	return b.vm.NewArray(items...)
+}
+
+// paths accepts a single path or an array of them
+func (b *Bridge) paths(value goja.Value) []string {
+	if goja.IsUndefined(value) || goja.IsNull(value) {
+		panic(b.vm.NewTypeError("watch requires a path or an array of paths"))
+	}
+	if obj, ok := value.(*goja.Object); ok && obj.ClassName() == "Array" {
+		var paths []string
+		if err := b.vm.ExportTo(value, &paths); err != nil {
+			panic(b.vm.NewTypeError("paths must be strings"))
+		}
+		return paths
+	}
+	return []string{value.String()}
+}
+
+func (b *Bridge) option(obj *goja.Object, name string) goja.Value {
+	value := obj.Get(name)
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,25 @@
+package watch
+
+import (
+	"github.com/rizqme/gode/goja"
+)
+
+// RuntimeInterface represents the methods we need from the runtime
+type RuntimeInterface interface {
+	QueueJSOperation(fn func())
+	GetGojaRuntime() *goja.Runtime
+	RegisterModule(name string, exports interface{})
+	KeepAlive() (release func())
+	AddShutdownHook(fn func()) (remove func())
+}
+
+// RegisterWatchModule registers gode:watch in the JavaScript runtime
+func RegisterWatchModule(runtime RuntimeInterface) error {
+	done := make(chan error, 1)
+	runtime.QueueJSOperation(func() {
+		bridge := NewBridge(runtime)
+		runtime.RegisterModule("gode:watch", bridge.Exports())
+		done <- nil
+	})
+	return <-done
+}
@@ -0,0 +1,76 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a single permission-sensitive access as written to the audit log
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Kind     Kind      `json:"kind"`
+	Resource string    `json:"resource"`
+	Allowed  bool      `json:"allowed"`
+	Rule     string    `json:"rule"`
+	CallSite string    `json:"callSite,omitempty"`
+	Modules  []string  `json:"modules,omitempty"` // Dependencies with code on the call stack
+}
+
+// AuditLogger writes audit entries as JSON lines
+type AuditLogger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewAuditLogger creates an audit logger writing to out
+func NewAuditLogger(out io.Writer) *AuditLogger {
+	return &AuditLogger{out: out}
+}
+
+// OpenAuditLog opens an audit logger for the given destination.
+// An empty destination, "stderr", "1" or "true" log to stderr; anything
+// else is treated as a file path and appended to.
+func OpenAuditLog(dest string) (*AuditLogger, error) {
+	switch dest {
+	case "", "1", "true", "stderr":
+		return NewAuditLogger(os.Stderr), nil
+	case "stdout":
+		return NewAuditLogger(os.Stdout), nil
+	}
+
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &AuditLogger{out: file, closer: file}, nil
+}
+
+// Record writes an entry; a zero Time is filled in with the current time
+func (a *AuditLogger) Record(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.out.Write(append(data, '\n'))
+}
+
+// Close closes the underlying file, if the logger owns one
+func (a *AuditLogger) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
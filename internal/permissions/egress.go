@@ -0,0 +1,204 @@
+package permissions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EgressPolicy is a network policy loaded from the file that package.json
+// names in gode.permissions.net-policy. Rules are evaluated in order and the
+// first one matching the host and port decides; Default decides when none
+// does.
+//
+//	{
+//	  "default": "deny",
+//	  "rules": [
+//	    {"action": "deny", "host": "metadata.internal"},
+//	    {"action": "allow", "host": "*.example.com", "ports": [443, "8000-8999"]}
+//	  ]
+//	}
+type EgressPolicy struct {
+	Default string       `json:"default,omitempty"` // "allow" or "deny" (the default)
+	Rules   []EgressRule `json:"rules"`
+
+	path string
+}
+
+// EgressRule allows or denies connections to matching hosts and ports
+type EgressRule struct {
+	Action string      `json:"action"`          // "allow" or "deny"
+	Host   string      `json:"host"`            // "api.example.com", "*.example.com" or "*"
+	Ports  []PortRange `json:"ports,omitempty"` // Empty matches every port
+}
+
+// PortRange is a single port or an inclusive range. In JSON it is a number
+// or a string such as "443" or "8000-8999".
+type PortRange struct {
+	From, To int
+}
+
+// UnmarshalJSON accepts a port number or a "from-to" string
+func (p *PortRange) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*p = PortRange{From: n, To: n}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("port must be a number or a range string, got %s", data)
+	}
+	r, err := parsePortRange(s)
+	if err != nil {
+		return err
+	}
+	*p = r
+	return nil
+}
+
+// String formats the range as it would appear in a pattern
+func (p PortRange) String() string {
+	if p.From == p.To {
+		return strconv.Itoa(p.From)
+	}
+	return fmt.Sprintf("%d-%d", p.From, p.To)
+}
+
+// contains reports whether port lies in the range
+func (p PortRange) contains(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= p.From && n <= p.To
+}
+
+// parsePortRange parses "443" or "8000-8999"
+func parsePortRange(s string) (PortRange, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+	f, err1 := strconv.Atoi(strings.TrimSpace(from))
+	t, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || f < 0 || t > 65535 || f > t {
+		return PortRange{}, fmt.Errorf("invalid port range %q", s)
+	}
+	return PortRange{From: f, To: t}, nil
+}
+
+// LoadEgressPolicy reads and validates a policy file
+func LoadEgressPolicy(path string) (*EgressPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read net policy: %w", err)
+	}
+
+	var policy EgressPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse net policy %s: %w", path, err)
+	}
+	policy.path = path
+
+	if policy.Default == "" {
+		policy.Default = "deny"
+	}
+	if policy.Default != "allow" && policy.Default != "deny" {
+		return nil, fmt.Errorf("net policy %s: default must be \"allow\" or \"deny\", got %q", path, policy.Default)
+	}
+	for i, rule := range policy.Rules {
+		if rule.Action != "allow" && rule.Action != "deny" {
+			return nil, fmt.Errorf("net policy %s: rule %d: action must be \"allow\" or \"deny\", got %q", path, i+1, rule.Action)
+		}
+		if rule.Host == "" {
+			return nil, fmt.Errorf("net policy %s: rule %d has no host", path, i+1)
+		}
+	}
+
+	return &policy, nil
+}
+
+// Check evaluates the policy for a "host:port" resource, returning the
+// outcome and a description of the rule that decided it
+func (p *EgressPolicy) Check(resource string) (bool, string) {
+	host, port := splitResource(resource)
+	for i, rule := range p.Rules {
+		if !rule.matches(host, port) {
+			continue
+		}
+		return rule.Action == "allow", fmt.Sprintf("net-policy rule %d: %s", i+1, rule)
+	}
+	return p.Default == "allow", "net-policy default: " + p.Default
+}
+
+// String describes the rule as "allow *.example.com:443,8000-8999"
+func (r EgressRule) String() string {
+	s := r.Action + " " + r.Host
+	if len(r.Ports) > 0 {
+		ports := make([]string, len(r.Ports))
+		for i, p := range r.Ports {
+			ports[i] = p.String()
+		}
+		s += ":" + strings.Join(ports, ",")
+	}
+	return s
+}
+
+func (r EgressRule) matches(host, port string) bool {
+	if !matchHostName(r.Host, host) {
+		return false
+	}
+	if len(r.Ports) == 0 {
+		return true
+	}
+	for _, p := range r.Ports {
+		if p.contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// NetResource returns the "host:port" resource checked for connections to
+// rawURL, filling in the scheme's default port, or "" when rawURL has no
+// host
+func NetResource(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "http", "ws":
+			port = "80"
+		default:
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// splitResource splits "host:port", leaving port empty when there is none
+func splitResource(resource string) (string, string) {
+	host, port, err := net.SplitHostPort(resource)
+	if err != nil {
+		return resource, ""
+	}
+	return host, port
+}
+
+// matchHostName matches exact hosts, "*.domain" (any depth of subdomain,
+// but not the domain itself) and "*" (every host)
+func matchHostName(pattern, host string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		return len(host) > len(pattern)-1 && strings.HasSuffix(strings.ToLower(host), strings.ToLower(pattern[1:]))
+	}
+	return strings.EqualFold(host, pattern)
+}
@@ -0,0 +1,134 @@
+package permissions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "egress.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEgressPolicyCheck(t *testing.T) {
+	policy, err := LoadEgressPolicy(writePolicy(t, `{
+		"rules": [
+			{"action": "deny", "host": "admin.example.com"},
+			{"action": "allow", "host": "*.example.com", "ports": [443, "8000-8999"]},
+			{"action": "allow", "host": "localhost"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("LoadEgressPolicy() failed: %v", err)
+	}
+
+	tests := []struct {
+		resource string
+		allowed  bool
+		rule     string
+	}{
+		{"api.example.com:443", true, "net-policy rule 2: allow *.example.com:443,8000-8999"},
+		{"a.b.example.com:8080", true, "net-policy rule 2: allow *.example.com:443,8000-8999"},
+		{"api.example.com:80", false, "net-policy default: deny"},
+		{"example.com:443", false, "net-policy default: deny"},
+		{"admin.example.com:443", false, "net-policy rule 1: deny admin.example.com"},
+		{"localhost:3000", true, "net-policy rule 3: allow localhost"},
+	}
+	for _, tt := range tests {
+		allowed, rule := policy.Check(tt.resource)
+		if allowed != tt.allowed || rule != tt.rule {
+			t.Errorf("Check(%s) = %v, %q, want %v, %q", tt.resource, allowed, rule, tt.allowed, tt.rule)
+		}
+	}
+}
+
+func TestLoadEgressPolicyInvalid(t *testing.T) {
+	tests := map[string]string{
+		"action":  `{"rules": [{"action": "block", "host": "x"}]}`,
+		"host":    `{"rules": [{"action": "allow"}]}`,
+		"default": `{"default": "maybe"}`,
+		"ports":   `{"rules": [{"action": "allow", "host": "x", "ports": ["9-1"]}]}`,
+	}
+	for name, content := range tests {
+		if _, err := LoadEgressPolicy(writePolicy(t, content)); err == nil {
+			t.Errorf("Expected the invalid %s to be rejected", name)
+		}
+	}
+}
+
+func TestCheckerDenyNetAndPolicy(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{
+		AllowNet: []string{"*.example.com", "*:8080"},
+		DenyNet:  []string{"*.corp.example.com", "api.example.com:8000-8999"},
+	}, "")
+
+	tests := []struct {
+		resource string
+		allowed  bool
+	}{
+		{"api.example.com:443", true},
+		{"api.example.com:8443", false},
+		{"db.corp.example.com:443", false},
+		{"anything.dev:8080", true},
+		{"anything.dev:443", false},
+	}
+	for _, tt := range tests {
+		if got := checker.Check(KindNet, tt.resource).Allowed; got != tt.allowed {
+			t.Errorf("Check(net, %s) = %v, want %v", tt.resource, got, tt.allowed)
+		}
+	}
+
+	// A policy is applied on top of allow-net
+	policy, err := LoadEgressPolicy(writePolicy(t, `{"default": "allow", "rules": [{"action": "deny", "host": "cdn.example.com"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker.SetEgressPolicy(policy)
+
+	decision := checker.Check(KindNet, "cdn.example.com:443")
+	if decision.Allowed || !decision.Denied {
+		t.Fatalf("Expected the policy to deny cdn.example.com, got %+v", decision)
+	}
+	if decision := checker.Check(KindNet, "api.example.com:443"); !decision.Allowed || !strings.Contains(decision.Rule, "allow-net: *.example.com") {
+		t.Errorf("Expected both layers in the rule, got %+v", decision)
+	}
+}
+
+func TestDeniedErrorURL(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{DenyNet: []string{"evil.test"}}, "")
+	decision := checker.Check(KindNet, "evil.test:443")
+
+	err := decision.Err(KindNet, "evil.test:443").(*DeniedError)
+	err.URL = "https://evil.test/steal"
+
+	if !strings.Contains(err.Error(), `"https://evil.test/steal"`) || !strings.Contains(err.Error(), "deny-net: evil.test") {
+		t.Errorf("Error() = %q, want the URL and rule", err.Error())
+	}
+	props := err.JSProperties()
+	if err.JSName() != "PermissionDenied" || props["url"] != "https://evil.test/steal" || props["rule"] != "deny-net: evil.test" {
+		t.Errorf("Unexpected JS error %s %v", err.JSName(), props)
+	}
+}
+
+func TestNetResource(t *testing.T) {
+	tests := map[string]string{
+		"https://api.example.com/v1": "api.example.com:443",
+		"http://api.example.com/v1":  "api.example.com:80",
+		"ws://localhost:9000/socket": "localhost:9000",
+		"https://[::1]/":             "[::1]:443",
+		"/relative/path":             "",
+	}
+	for rawURL, want := range tests {
+		if got := NetResource(rawURL); got != want {
+			t.Errorf("NetResource(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
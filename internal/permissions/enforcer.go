@@ -0,0 +1,14 @@
+package permissions
+
+// Enforcer is implemented by runtimes that enforce and audit
+// permission-sensitive operations with a Checker. Modules look for it on
+// the runtime they are registered with, and run unchecked without it.
+type Enforcer interface {
+	CheckPermission(kind, resource string) error
+}
+
+// NetEnforcer is implemented by runtimes that enforce and audit network
+// access, checking the host and port of a URL
+type NetEnforcer interface {
+	CheckNetURL(rawURL string) error
+}
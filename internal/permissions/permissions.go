@@ -0,0 +1,324 @@
+package permissions
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// Kind identifies a class of permission-sensitive operation
+type Kind string
+
+const (
+	KindRead   Kind = "read"
+	KindWrite  Kind = "write"
+	KindNet    Kind = "net"
+	KindEnv    Kind = "env"
+	KindRun    Kind = "run"
+	KindPlugin Kind = "plugin"
+	// KindCode covers code that isn't read from a file: data: URL modules
+	// and inline workers
+	KindCode Kind = "code"
+)
+
+// Decision is the outcome of a permission check
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    string `json:"rule"` // The rule that allowed (or failed to allow) the access
+	Module  string `json:"module,omitempty"`
+	Denied  bool   `json:"denied,omitempty"` // A deny rule matched, rather than no allow rule
+}
+
+// DeniedError is returned when an operation is not covered by any allow
+// rule, or is covered by a deny rule. In JS it is a PermissionDenied error.
+type DeniedError struct {
+	Kind     Kind
+	Resource string
+	Module   string // Set when a per-dependency override denied the access
+	Rule     string // The rule evaluated, as in Decision
+	URL      string // The URL being fetched, for network access
+	Denied   bool   // A deny rule matched
+}
+
+// Error implements the error interface
+func (e *DeniedError) Error() string {
+	if e.Denied {
+		target := fmt.Sprintf("%q", e.Resource)
+		if e.URL != "" {
+			target = fmt.Sprintf("%q (%s)", e.URL, e.Resource)
+		}
+		return fmt.Sprintf("permission denied: %s access to %s by %s", e.Kind, target, e.Rule)
+	}
+	if e.URL != "" {
+		return fmt.Sprintf("permission denied: %s access to %q (%s) (add %q to gode.permissions.allow-%s or the net policy)", e.Kind, e.URL, e.Resource, e.Resource, e.Kind)
+	}
+	if e.Module != "" {
+		return fmt.Sprintf("permission denied: %s access to %q from module %q (add it to gode.permissions.modules.%s.allow-%s)", e.Kind, e.Resource, e.Module, e.Module, e.Kind)
+	}
+	return fmt.Sprintf("permission denied: %s access to %q (add it to gode.permissions.allow-%s)", e.Kind, e.Resource, e.Kind)
+}
+
+// JSName is the name of the JS error made from e
+func (e *DeniedError) JSName() string {
+	return "PermissionDenied"
+}
+
+// JSProperties are set on the JS error made from e, so handlers can tell
+// which access was refused and why
+func (e *DeniedError) JSProperties() map[string]interface{} {
+	props := map[string]interface{}{
+		"code":     "ERR_PERMISSION_DENIED",
+		"kind":     string(e.Kind),
+		"resource": e.Resource,
+		"rule":     e.Rule,
+	}
+	if e.URL != "" {
+		props["url"] = e.URL
+	}
+	if e.Module != "" {
+		props["module"] = e.Module
+	}
+	return props
+}
+
+// Checker evaluates operations against a PermissionConfig.
+// An empty allow list leaves that kind of access unrestricted, which keeps
+// projects without a permissions section working as before.
+type Checker struct {
+	config      config.PermissionConfig
+	projectRoot string
+	egress      *EgressPolicy
+}
+
+// NewChecker creates a checker; relative paths in allow-read/allow-write
+// are resolved against projectRoot
+func NewChecker(cfg config.PermissionConfig, projectRoot string) *Checker {
+	return &Checker{
+		config:      cfg,
+		projectRoot: projectRoot,
+	}
+}
+
+// SetEgressPolicy adds a network policy that every connection must pass
+// besides allow-net and deny-net
+func (c *Checker) SetEgressPolicy(policy *EgressPolicy) {
+	c.egress = policy
+}
+
+// Check decides whether the given access is allowed
+func (c *Checker) Check(kind Kind, resource string) Decision {
+	switch kind {
+	case KindRead:
+		return c.checkList(kind, resource, c.config.AllowRead, c.matchPath)
+	case KindWrite:
+		return c.checkList(kind, resource, c.config.AllowWrite, c.matchPath)
+	case KindNet:
+		return c.checkNet(resource)
+	case KindEnv:
+		return c.checkList(kind, resource, c.config.AllowEnv, matchEnv)
+	case KindRun:
+		return c.checkList(kind, resource, c.config.AllowRun, matchProgram)
+	case KindCode:
+		return c.checkList(kind, resource, c.config.AllowCode, matchScheme)
+	case KindPlugin:
+		// Plugins run native code with full process access; there is no
+		// allow list for them, they are only recorded.
+		return Decision{Allowed: true, Rule: "unrestricted (plugins are not sandboxed)"}
+	}
+
+	return Decision{Allowed: false, Rule: fmt.Sprintf("unknown permission kind %q", kind)}
+}
+
+// HasModuleRules reports whether any per-dependency overrides are configured,
+// in which case callers need to work out which modules are on the stack
+func (c *Checker) HasModuleRules() bool {
+	return len(c.config.Modules) > 0
+}
+
+// CheckModules decides whether the access is allowed when code from the
+// given dependencies is on the call stack. Every module must allow it: a
+// callback from the app into a restricted dependency stays restricted.
+func (c *Checker) CheckModules(kind Kind, resource string, modules []string) Decision {
+	decision := c.Check(kind, resource)
+	if !decision.Allowed {
+		return decision
+	}
+
+	for _, module := range modules {
+		override, exists := c.config.Modules[module]
+		if !exists {
+			continue
+		}
+
+		allow := moduleOverride(override).list(kind)
+		if allow == nil {
+			continue // Inherits the project setting
+		}
+
+		prefix := fmt.Sprintf("modules.%s.allow-%s", module, kind)
+		matched := ""
+		for _, pattern := range allow {
+			if c.match(kind, pattern, resource) {
+				matched = pattern
+				break
+			}
+		}
+		if matched == "" {
+			return Decision{Allowed: false, Rule: "no matching " + prefix + " entry", Module: module}
+		}
+		decision.Rule += fmt.Sprintf("; %s: %s", prefix, matched)
+	}
+
+	return decision
+}
+
+// Err converts a denied decision into an error
+func (d Decision) Err(kind Kind, resource string) error {
+	if d.Allowed {
+		return nil
+	}
+	return &DeniedError{Kind: kind, Resource: resource, Module: d.Module, Rule: d.Rule, Denied: d.Denied}
+}
+
+// match applies the matcher for kind to a single pattern
+func (c *Checker) match(kind Kind, pattern, resource string) bool {
+	if pattern == "*" {
+		return true
+	}
+	switch kind {
+	case KindRead, KindWrite:
+		return c.matchPath(pattern, resource)
+	case KindNet:
+		return matchHost(pattern, resource)
+	case KindEnv:
+		return matchEnv(pattern, resource)
+	case KindRun:
+		return matchProgram(pattern, resource)
+	case KindCode:
+		return matchScheme(pattern, resource)
+	}
+	return false
+}
+
+// moduleOverride adds lookup by kind to the config type
+type moduleOverride config.ModulePermissions
+
+func (o moduleOverride) list(kind Kind) []string {
+	switch kind {
+	case KindRead:
+		return o.AllowRead
+	case KindWrite:
+		return o.AllowWrite
+	case KindNet:
+		return o.AllowNet
+	case KindEnv:
+		return o.AllowEnv
+	case KindRun:
+		return o.AllowRun
+	case KindCode:
+		return o.AllowCode
+	}
+	return nil
+}
+
+// checkNet applies deny-net, then the net policy, then allow-net. Every one
+// that is configured must allow the connection.
+func (c *Checker) checkNet(resource string) Decision {
+	for _, pattern := range c.config.DenyNet {
+		if matchHost(pattern, resource) {
+			return Decision{Allowed: false, Rule: "deny-net: " + pattern, Denied: true}
+		}
+	}
+
+	var policyRule string
+	if c.egress != nil {
+		allowed, rule := c.egress.Check(resource)
+		if !allowed {
+			return Decision{Allowed: false, Rule: rule, Denied: true}
+		}
+		policyRule = rule
+		if len(c.config.AllowNet) == 0 {
+			return Decision{Allowed: true, Rule: rule}
+		}
+	}
+
+	decision := c.checkList(KindNet, resource, c.config.AllowNet, matchHost)
+	if decision.Allowed && policyRule != "" {
+		decision.Rule = policyRule + "; " + decision.Rule
+	}
+	return decision
+}
+
+func (c *Checker) checkList(kind Kind, resource string, allow []string, match func(pattern, resource string) bool) Decision {
+	if len(allow) == 0 {
+		return Decision{Allowed: true, Rule: fmt.Sprintf("unrestricted (no allow-%s configured)", kind)}
+	}
+
+	for _, pattern := range allow {
+		if pattern == "*" || match(pattern, resource) {
+			return Decision{Allowed: true, Rule: fmt.Sprintf("allow-%s: %s", kind, pattern)}
+		}
+	}
+
+	return Decision{Allowed: false, Rule: fmt.Sprintf("no matching allow-%s entry", kind)}
+}
+
+// matchPath reports whether resource is the allowed path or lies beneath it
+func (c *Checker) matchPath(pattern, resource string) bool {
+	if !filepath.IsAbs(pattern) && c.projectRoot != "" {
+		pattern = filepath.Join(c.projectRoot, pattern)
+	}
+	pattern = filepath.Clean(pattern)
+
+	path, err := filepath.Abs(resource)
+	if err != nil {
+		return false
+	}
+
+	return path == pattern || strings.HasPrefix(path, pattern+string(filepath.Separator))
+}
+
+// matchHost matches "host", "*.domain" and "*" patterns, optionally with a
+// ":port" or ":from-to" port range
+func matchHost(pattern, resource string) bool {
+	host, port := splitResource(resource)
+
+	patternHost, patternPort, err := net.SplitHostPort(pattern)
+	if err != nil {
+		patternHost, patternPort = pattern, ""
+	}
+
+	if patternPort != "" {
+		ports, err := parsePortRange(patternPort)
+		if err != nil || !ports.contains(port) {
+			return false
+		}
+	}
+
+	return matchHostName(patternHost, host)
+}
+
+// matchEnv matches exact variable names and "PREFIX_*" patterns
+func matchEnv(pattern, resource string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(resource, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == resource
+}
+
+// matchProgram matches a program name or path. A bare name such as "git"
+// also allows the program run by its path; a path only allows that path.
+func matchProgram(pattern, resource string) bool {
+	if pattern == resource {
+		return true
+	}
+	return !strings.ContainsAny(pattern, `/\`) && filepath.Base(resource) == pattern
+}
+
+// matchScheme matches where inline code comes from: "data:" or "blob:"
+func matchScheme(pattern, resource string) bool {
+	return strings.EqualFold(strings.TrimSuffix(pattern, ":"), strings.TrimSuffix(resource, ":"))
+}
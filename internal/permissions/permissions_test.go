@@ -0,0 +1,212 @@
+package permissions
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestCheckerUnrestrictedByDefault(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{}, "/project")
+
+	for _, kind := range []Kind{KindRead, KindWrite, KindNet, KindEnv, KindPlugin} {
+		decision := checker.Check(kind, "anything")
+		if !decision.Allowed {
+			t.Errorf("Expected %s access to be allowed without config, got rule %q", kind, decision.Rule)
+		}
+		if decision.Rule == "" {
+			t.Errorf("Expected a rule description for %s", kind)
+		}
+	}
+}
+
+func TestCheckerPaths(t *testing.T) {
+	root := t.TempDir()
+	checker := NewChecker(config.PermissionConfig{
+		AllowRead: []string{"./data", "/etc/hosts"},
+	}, root)
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{filepath.Join(root, "data"), true},
+		{filepath.Join(root, "data", "users.json"), true},
+		{filepath.Join(root, "database.json"), false},
+		{filepath.Join(root, "data", "..", "secret.txt"), false},
+		{"/etc/hosts", true},
+		{"/etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		decision := checker.Check(KindRead, tt.path)
+		if decision.Allowed != tt.allowed {
+			t.Errorf("Check(read, %s) = %v, want %v (rule %q)", tt.path, decision.Allowed, tt.allowed, decision.Rule)
+		}
+	}
+
+	// Write access is not restricted by allow-read
+	if !checker.Check(KindWrite, "/etc/passwd").Allowed {
+		t.Error("Expected write access to be unrestricted")
+	}
+}
+
+func TestCheckerNet(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{
+		AllowNet: []string{"api.example.com", "localhost:8080", "*.internal.dev"},
+	}, "")
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"api.example.com:443", true},
+		{"api.example.com", true},
+		{"example.com:443", false},
+		{"localhost:8080", true},
+		{"localhost:9090", false},
+		{"db.internal.dev:5432", true},
+		{"internal.dev:443", false},
+	}
+
+	for _, tt := range tests {
+		if got := checker.Check(KindNet, tt.host).Allowed; got != tt.allowed {
+			t.Errorf("Check(net, %s) = %v, want %v", tt.host, got, tt.allowed)
+		}
+	}
+}
+
+func TestCheckerEnv(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{
+		AllowEnv: []string{"HOME", "APP_*"},
+	}, "")
+
+	decision := checker.Check(KindEnv, "APP_PORT")
+	if !decision.Allowed || decision.Rule != "allow-env: APP_*" {
+		t.Errorf("Expected APP_PORT allowed by APP_*, got %+v", decision)
+	}
+
+	decision = checker.Check(KindEnv, "AWS_SECRET_ACCESS_KEY")
+	if decision.Allowed {
+		t.Error("Expected AWS_SECRET_ACCESS_KEY to be denied")
+	}
+
+	err := decision.Err(KindEnv, "AWS_SECRET_ACCESS_KEY")
+	if _, ok := err.(*DeniedError); !ok {
+		t.Fatalf("Expected DeniedError, got %T", err)
+	}
+	if !strings.Contains(err.Error(), "allow-env") {
+		t.Errorf("Expected error to mention allow-env, got %q", err.Error())
+	}
+}
+
+func TestCheckerRun(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{
+		AllowRun: []string{"git", "/usr/local/bin/node"},
+	}, "")
+
+	tests := []struct {
+		program string
+		allowed bool
+	}{
+		{"git", true},
+		{"/usr/bin/git", true},
+		{"node", false},
+		{"/usr/local/bin/node", true},
+		{"/tmp/node", false},
+		{"rm", false},
+	}
+
+	for _, tt := range tests {
+		if got := checker.Check(KindRun, tt.program).Allowed; got != tt.allowed {
+			t.Errorf("Check(run, %s) = %v, want %v", tt.program, got, tt.allowed)
+		}
+	}
+}
+
+func TestCheckerCode(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{
+		AllowCode: []string{"data:"},
+	}, "")
+
+	if !checker.Check(KindCode, "data:").Allowed {
+		t.Error("Expected data: URL modules to be allowed")
+	}
+	if checker.Check(KindCode, "blob:").Allowed {
+		t.Error("Expected blob: workers to be denied")
+	}
+	if !NewChecker(config.PermissionConfig{}, "").Check(KindCode, "blob:").Allowed {
+		t.Error("Expected code to be unrestricted without allow-code")
+	}
+}
+
+func TestAuditLoggerRecord(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewAuditLogger(&buf)
+
+	logger.Record(AuditEntry{Kind: KindNet, Resource: "api.example.com:443", Allowed: true, Rule: "allow-net: api.example.com", CallSite: "app:main.js:3:1"})
+	logger.Record(AuditEntry{Kind: KindEnv, Resource: "HOME", Allowed: true, Rule: "unrestricted (no allow-env configured)"})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d", len(lines))
+	}
+
+	var entry AuditEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("Failed to parse audit entry: %v", err)
+	}
+	if entry.Kind != KindNet || entry.CallSite != "app:main.js:3:1" || entry.Time.IsZero() {
+		t.Errorf("Unexpected audit entry: %+v", entry)
+	}
+}
+
+func TestCheckerModuleOverrides(t *testing.T) {
+	checker := NewChecker(config.PermissionConfig{
+		AllowNet: []string{"api.example.com", "cdn.example.com"},
+		Modules: map[string]config.ModulePermissions{
+			"lodash": {AllowNet: []string{}, AllowRead: []string{}},
+			"client": {AllowNet: []string{"api.example.com"}},
+		},
+	}, "/project")
+
+	if !checker.HasModuleRules() {
+		t.Fatal("Expected module rules to be reported")
+	}
+
+	// The app itself gets the project permissions
+	if !checker.CheckModules(KindNet, "cdn.example.com:443", nil).Allowed {
+		t.Error("Expected app code to reach cdn.example.com")
+	}
+
+	// An empty list denies that kind of access to the module
+	decision := checker.CheckModules(KindNet, "api.example.com:443", []string{"lodash"})
+	if decision.Allowed || decision.Module != "lodash" {
+		t.Errorf("Expected lodash to be denied network access, got %+v", decision)
+	}
+	if err := decision.Err(KindNet, "api.example.com:443"); !strings.Contains(err.Error(), "modules.lodash.allow-net") {
+		t.Errorf("Expected error to point at the module override, got %q", err)
+	}
+
+	// A nil list inherits the project setting
+	if !checker.CheckModules(KindEnv, "HOME", []string{"lodash"}).Allowed {
+		t.Error("Expected lodash to inherit env permissions")
+	}
+
+	// Overrides narrow the project list but cannot widen it
+	if !checker.CheckModules(KindNet, "api.example.com:443", []string{"client"}).Allowed {
+		t.Error("Expected client to reach api.example.com")
+	}
+	if checker.CheckModules(KindNet, "cdn.example.com:443", []string{"client"}).Allowed {
+		t.Error("Expected client to be denied cdn.example.com")
+	}
+
+	// Every module on the stack has to allow the access
+	if checker.CheckModules(KindNet, "api.example.com:443", []string{"client", "lodash"}).Allowed {
+		t.Error("Expected access to be denied when lodash is on the stack")
+	}
+}
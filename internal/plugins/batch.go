@@ -0,0 +1,58 @@
+package plugins
+
+import "sync"
+
+// Batch collects callbacks for the JS thread from any goroutine and queues
+// them together with QueueJSBatch, when Flush is called or max of them are
+// waiting. A stream plugin adds a callback per data event and flushes once
+// it has nothing more to read for now:
+//
+//	batch := plugins.NewBatch(rt.(plugins.BatchQueuer), 64)
+//	for chunk := range chunks {
+//		chunk := chunk
+//		batch.Add(func() { onData(chunk) })
+//		if len(chunks) == 0 {
+//			batch.Flush()
+//		}
+//	}
+//	batch.Flush()
+type Batch struct {
+	queue BatchQueuer
+	max   int
+
+	mu  sync.Mutex
+	fns []func()
+}
+
+// NewBatch creates a batch that queues its callbacks on queue, at most max
+// at a time. A max of zero or less means no limit.
+func NewBatch(queue BatchQueuer, max int) *Batch {
+	return &Batch{queue: queue, max: max}
+}
+
+// Add adds fn to the batch, flushing it if it is full
+func (b *Batch) Add(fn func()) {
+	b.mu.Lock()
+	b.fns = append(b.fns, fn)
+	var full []func()
+	if b.max > 0 && len(b.fns) >= b.max {
+		full, b.fns = b.fns, nil
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.queue.QueueJSBatch(full)
+	}
+}
+
+// Flush queues the callbacks added since the last flush, if any
+func (b *Batch) Flush() {
+	b.mu.Lock()
+	fns := b.fns
+	b.fns = nil
+	b.mu.Unlock()
+
+	if len(fns) > 0 {
+		b.queue.QueueJSBatch(fns)
+	}
+}
@@ -0,0 +1,37 @@
+package plugins
+
+import "testing"
+
+// batchRecorder runs each batch it is given and records its size
+type batchRecorder struct {
+	sizes []int
+}
+
+func (q *batchRecorder) QueueJSBatch(fns []func()) {
+	q.sizes = append(q.sizes, len(fns))
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+func TestBatch(t *testing.T) {
+	queue := &batchRecorder{}
+	batch := NewBatch(queue, 3)
+
+	var ran []int
+	for i := 0; i < 5; i++ {
+		i := i
+		batch.Add(func() { ran = append(ran, i) })
+	}
+	if len(queue.sizes) != 1 || queue.sizes[0] != 3 {
+		t.Fatalf("Expected a full batch of 3 to be queued, got %v", queue.sizes)
+	}
+	batch.Flush()
+	batch.Flush()
+	if len(queue.sizes) != 2 || queue.sizes[1] != 2 {
+		t.Errorf("Expected Flush() to queue the 2 callbacks left once, got %v", queue.sizes)
+	}
+	if len(ran) != 5 || ran[0] != 0 || ran[4] != 4 {
+		t.Errorf("Expected the callbacks to run in order, got %v", ran)
+	}
+}
@@ -2,6 +2,8 @@ package plugins
 
 import (
 	"reflect"
+	"sync/atomic"
+	"time"
 )
 
 // JavaScript VM interfaces (to avoid import cycles)
@@ -15,6 +17,20 @@ type Object interface {
 	Set(key string, value interface{}) error
 }
 
+// diagnosticsPublisher is implemented by VMs with gode:diagnostics_channel,
+// where plugin calls are published on plugin.call
+type diagnosticsPublisher interface {
+	HasDiagnosticSubscribers(channel string) bool
+	PublishDiagnostic(channel string, message func() map[string]interface{})
+}
+
+// contextCapturer is implemented by VMs that follow the async context of JS
+// code, so that a plugin's callbacks run in the context of the call they
+// were passed to
+type contextCapturer interface {
+	CaptureAsyncContext() func(fn func())
+}
+
 // Bridge handles the conversion between Go and JavaScript values
 type Bridge struct {
 	vm VM
@@ -28,9 +44,13 @@ func NewBridge(vm VM) *Bridge {
 // WrapPlugin creates JavaScript bindings for a Go plugin
 // Goja handles Go-JS conversion automatically, so we just expose the functions directly
 func (b *Bridge) WrapPlugin(plugin Plugin) (Object, error) {
-	exports := plugin.Exports()
 	obj := b.vm.NewObjectForPlugins()
-	
+	b.setExports(obj, plugin, plugin.Exports())
+	return obj, nil
+}
+
+// setExports adds the metadata and exports of plugin to obj
+func (b *Bridge) setExports(obj Object, plugin Plugin, exports map[string]interface{}) {
 	// Add metadata
 	obj.Set("__pluginName", plugin.Name())
 	obj.Set("__pluginVersion", plugin.Version())
@@ -39,10 +59,76 @@ func (b *Bridge) WrapPlugin(plugin Plugin) (Object, error) {
 	for name, value := range exports {
 		// Wrap the export to ensure callbacks are queued properly
 		wrappedValue := b.wrapExport(value)
+		if publisher, ok := b.vm.(diagnosticsPublisher); ok {
+			wrappedValue = observeCalls(publisher, plugin.Name(), name, wrappedValue)
+		}
 		obj.Set(name, wrappedValue)
 	}
-	
-	return obj, nil
+	// Expose the events the plugin emits through its host (see Emitter),
+	// unless the plugin exports its own "events"
+	if _, exported := exports["events"]; !exported {
+		if vm, ok := b.vm.(interface{ PluginEvents(name string) interface{} }); ok {
+			obj.Set("events", vm.PluginEvents(plugin.Name()))
+		}
+	}
+}
+
+// deleter is implemented by objects whose properties can be removed
+type deleter interface {
+	Delete(key string) bool
+}
+
+// swapExports replaces the exports a plugin had, old, with those of its
+// new build on the object created for it, removing the ones the new build
+// no longer has when obj supports it
+func (b *Bridge) swapExports(obj Object, old map[string]interface{}, plugin Plugin) {
+	exports := plugin.Exports()
+	if d, ok := obj.(deleter); ok {
+		for name := range old {
+			if _, kept := exports[name]; !kept {
+				d.Delete(name)
+			}
+		}
+	}
+	b.setExports(obj, plugin, exports)
+}
+
+// observeCalls wraps an exported function so that, while plugin.call has
+// subscribers, every call is timed and published when it returns
+func observeCalls(publisher diagnosticsPublisher, pluginName, method string, export interface{}) interface{} {
+	fn := reflect.ValueOf(export)
+	if fn.Kind() != reflect.Func {
+		return export
+	}
+	t := fn.Type()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		call := fn.Call
+		if t.IsVariadic() {
+			call = fn.CallSlice
+		}
+		if !publisher.HasDiagnosticSubscribers("plugin.call") {
+			return call(args)
+		}
+
+		start := time.Now()
+		results := call(args)
+		duration := time.Since(start)
+
+		publisher.PublishDiagnostic("plugin.call", func() map[string]interface{} {
+			message := map[string]interface{}{
+				"plugin":   pluginName,
+				"method":   method,
+				"duration": float64(duration) / float64(time.Millisecond),
+			}
+			if n := len(results); n > 0 && t.Out(n-1) == errorType && !results[n-1].IsNil() {
+				message["error"] = results[n-1].Interface().(error).Error()
+			}
+			return message
+		})
+		return results
+	}).Interface()
 }
 
 // wrapExport wraps plugin exports to ensure callbacks are executed through the VM queue
@@ -90,19 +176,28 @@ func (b *Bridge) wrapExport(export interface{}) interface{} {
 	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
 		// Debug log removed
 		
+		// Set once the function returns; callbacks called before then run
+		// right away, since the JS thread is waiting for the call
+		var returned int32
+		
 		// Wrap any callback arguments
 		for _, idx := range callbackIndices {
-			if idx < len(args) && args[idx].Kind() == reflect.Func {
+			if idx < len(args) && args[idx].Kind() == reflect.Func && !args[idx].IsNil() {
 				originalCallback := args[idx]
 				callbackType := t.In(idx)
 				// Debug log removed
-				args[idx] = b.wrapCallback(originalCallback, callbackType)
+				args[idx] = b.wrapCallback(originalCallback, callbackType, &returned)
 			}
 		}
 		
 		// Call the original function
 		// Debug log removed
-		results := v.Call(args)
+		call := v.Call
+		if t.IsVariadic() {
+			call = v.CallSlice
+		}
+		results := call(args)
+		atomic.StoreInt32(&returned, 1)
 		
 		// If the function returns an object, wrap any functions in it
 		if returnsObject && len(results) > 0 {
@@ -118,10 +213,23 @@ func (b *Bridge) wrapExport(export interface{}) interface{} {
 	}).Interface()
 }
 
-// wrapCallback wraps a callback function to execute through the VM queue
-func (b *Bridge) wrapCallback(callback reflect.Value, callbackType reflect.Type) reflect.Value {
-	// Debug log removed
+// wrapCallback wraps a callback function to execute through the VM queue,
+// unless it is called before the function it was passed to has returned,
+// as returned tells
+func (b *Bridge) wrapCallback(callback reflect.Value, callbackType reflect.Type, returned *int32) reflect.Value {
+	inContext := func(fn func()) { fn() }
+	if capturer, ok := b.vm.(contextCapturer); ok {
+		inContext = capturer.CaptureAsyncContext()
+	}
 	return reflect.MakeFunc(callbackType, func(args []reflect.Value) []reflect.Value {
+		if atomic.LoadInt32(returned) == 0 {
+			var results []reflect.Value
+			inContext(func() {
+				results = callWith(callback, callbackType, args)
+			})
+			return results
+		}
+		
 		// Debug log removed
 		// Prepare return values
 		numOut := callbackType.NumOut()
@@ -144,14 +252,16 @@ func (b *Bridge) wrapCallback(callback reflect.Value, callbackType reflect.Type)
 			
 			// Debug log removed
 			// Execute the callback
-			callResults := callback.Call(args)
-			
-			// Copy results
-			for i, r := range callResults {
-				if i < len(results) {
-					results[i] = r
+			inContext(func() {
+				callResults := callWith(callback, callbackType, args)
+				
+				// Copy results
+				for i, r := range callResults {
+					if i < len(results) {
+						results[i] = r
+					}
 				}
-			}
+			})
 			// Debug log removed
 		})
 		
@@ -163,6 +273,15 @@ func (b *Bridge) wrapCallback(callback reflect.Value, callbackType reflect.Type)
 	})
 }
 
+// callWith calls fn of type t with args as MakeFunc passes them, the
+// variadic ones in a slice
+func callWith(fn reflect.Value, t reflect.Type, args []reflect.Value) []reflect.Value {
+	if t.IsVariadic() {
+		return fn.CallSlice(args)
+	}
+	return fn.Call(args)
+}
+
 // wrapValue recursively wraps any functions in a value
 func (b *Bridge) wrapValue(value interface{}) interface{} {
 	if value == nil {
@@ -0,0 +1,161 @@
+package plugins
+
+import (
+	"errors"
+	"testing"
+)
+
+type mapObject map[string]interface{}
+
+func (o mapObject) Set(key string, value interface{}) error {
+	o[key] = value
+	return nil
+}
+
+func (o mapObject) Delete(key string) bool {
+	delete(o, key)
+	return true
+}
+
+// observedVM records what is published on its diagnostics channels
+type observedVM struct {
+	subscribed bool
+	published  []map[string]interface{}
+}
+
+func (vm *observedVM) NewObjectForPlugins() Object                     { return mapObject{} }
+func (vm *observedVM) RegisterModule(name string, exports interface{}) {}
+func (vm *observedVM) QueueJSOperation(fn func())                      { fn() }
+
+func (vm *observedVM) HasDiagnosticSubscribers(channel string) bool {
+	return vm.subscribed && channel == "plugin.call"
+}
+
+func (vm *observedVM) PublishDiagnostic(channel string, message func() map[string]interface{}) {
+	if vm.HasDiagnosticSubscribers(channel) {
+		vm.published = append(vm.published, message())
+	}
+}
+
+func TestWrapPluginPublishesCalls(t *testing.T) {
+	vm := &observedVM{}
+	plugin := &directPlugin{name: "math", exports: map[string]interface{}{
+		"add": func(a, b int) int { return a + b },
+		"sum": func(values ...int) int {
+			total := 0
+			for _, v := range values {
+				total += v
+			}
+			return total
+		},
+		"fail": func() (int, error) { return 0, errors.New("boom") },
+		"pi":   3.14,
+	}}
+
+	obj, err := NewBridge(vm).WrapPlugin(plugin)
+	if err != nil {
+		t.Fatalf("WrapPlugin() failed: %v", err)
+	}
+	exports := obj.(mapObject)
+	add := exports["add"].(func(a, b int) int)
+	sum := exports["sum"].(func(values ...int) int)
+	fail := exports["fail"].(func() (int, error))
+
+	// Nothing is published without subscribers
+	if add(1, 2) != 3 || len(vm.published) != 0 {
+		t.Fatalf("Unexpected publish without subscribers: %v", vm.published)
+	}
+
+	vm.subscribed = true
+	if sum(1, 2, 3) != 6 {
+		t.Error("Expected the variadic export to keep working")
+	}
+	fail()
+
+	if len(vm.published) != 2 {
+		t.Fatalf("Published %d messages, want 2", len(vm.published))
+	}
+	if msg := vm.published[0]; msg["plugin"] != "math" || msg["method"] != "sum" || msg["error"] != nil {
+		t.Errorf("Unexpected message for sum: %v", msg)
+	}
+	if msg := vm.published[1]; msg["method"] != "fail" || msg["error"] != "boom" {
+		t.Errorf("Unexpected message for fail: %v", msg)
+	}
+	if exports["pi"] != 3.14 {
+		t.Error("Expected non-function exports to be unchanged")
+	}
+}
+
+// contextVM has a current context, as a runtime following the async
+// context of JS code does
+type contextVM struct {
+	observedVM
+	current string
+}
+
+func (vm *contextVM) CaptureAsyncContext() func(fn func()) {
+	captured := vm.current
+	return func(fn func()) {
+		previous := vm.current
+		vm.current = captured
+		defer func() { vm.current = previous }()
+		fn()
+	}
+}
+
+func TestWrapPluginCallbacksKeepContext(t *testing.T) {
+	vm := &contextVM{}
+	var pending func()
+	plugin := &directPlugin{name: "jobs", exports: map[string]interface{}{
+		"later": func(callback func()) { pending = callback },
+	}}
+
+	obj, err := NewBridge(vm).WrapPlugin(plugin)
+	if err != nil {
+		t.Fatalf("WrapPlugin() failed: %v", err)
+	}
+	later := obj.(mapObject)["later"].(func(callback func()))
+
+	var seen string
+	vm.current = "request-1"
+	later(func() { seen = vm.current })
+	vm.current = "request-2"
+	pending()
+
+	if seen != "request-1" {
+		t.Errorf("Callback ran in context %q, want request-1", seen)
+	}
+	if vm.current != "request-2" {
+		t.Errorf("Context after the callback = %q, want request-2", vm.current)
+	}
+}
+
+func TestSwapExportsReplacesExports(t *testing.T) {
+	vm := &observedVM{}
+	bridge := NewBridge(vm)
+	old := &directPlugin{name: "math", version: "1.0.0", exports: map[string]interface{}{
+		"add": func(a, b int) int { return a + b },
+		"pi":  3.14,
+	}}
+	obj, err := bridge.WrapPlugin(old)
+	if err != nil {
+		t.Fatalf("WrapPlugin() failed: %v", err)
+	}
+
+	next := &directPlugin{name: "math", version: "1.1.0", exports: map[string]interface{}{
+		"add": func(a, b int) int { return a + b + 1 },
+		"e":   2.72,
+	}}
+	bridge.swapExports(obj, old.Exports(), next)
+
+	exports := obj.(mapObject)
+	if add := exports["add"].(func(a, b int) int); add(1, 2) != 4 {
+		t.Errorf("add(1, 2) = %d after the swap, want 4", add(1, 2))
+	}
+	if _, kept := exports["pi"]; kept {
+		t.Error("Expected pi, which the new build doesn't export, to be removed")
+	}
+	if exports["e"] != 2.72 || exports["__pluginVersion"] != "1.1.0" {
+		t.Errorf("Unexpected exports after the swap: %v", exports)
+	}
+}
@@ -0,0 +1,290 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"unicode"
+)
+
+// Class exports a Go type as a JS class:
+//
+//	exports["Cart"] = &plugins.Class{New: NewCart}
+//
+// New is called by `new Cart(...)`, with the arguments converted like
+// those of a Function, and returns a pointer to the instance and,
+// optionally, an error. The exported methods of the instance become the
+// methods of the class's prototype, with their leading capitals
+// lowercased, so that GetTotal is getTotal and ID is id. Values of the
+// instance type that plugin functions return are wrapped in the class
+// too.
+//
+// An instance is released once: when JS calls its close() method, when it
+// is garbage collected, or when the runtime shuts down. Released is
+// called with it first, and then its Close method, if it has a Close() or
+// Close() error. A value returned to JS more than once is released when
+// the last of its objects is collected, or any of them closed.
+type Class struct {
+	New interface{}
+
+	// Created is called with every instance after it's constructed or
+	// first returned to JS
+	Created func(instance interface{})
+	// Released is called with every instance before it's closed
+	Released func(instance interface{})
+
+	once    sync.Once
+	err     error
+	ctor    *Function
+	typ     reflect.Type
+	methods map[string]int // JS name -> method index
+
+	mu   sync.Mutex
+	live map[interface{}]*instanceState // by value
+}
+
+// classTypes holds the instance types of classes, which arguments take
+// only as instances and never build from plain objects
+var classTypes sync.Map // reflect.Type -> *Class
+
+// Instance is an instance of a Class. The runtime keeps it on its JS
+// object, so that it becomes unreachable along with the object, and a
+// finalizer releases it.
+type Instance struct {
+	state *instanceState
+}
+
+// instanceState is what releasing an instance needs. It is kept apart
+// from the Instances of a value, which share it, so that the class can
+// hold it without keeping them reachable.
+type instanceState struct {
+	class    *Class
+	value    reflect.Value
+	refs     int // Instances not yet collected
+	released bool
+}
+
+// init checks New and collects the methods of the type it returns
+func (c *Class) init() error {
+	c.once.Do(func() {
+		ctor, ok := NewFunction(c.New)
+		if !ok {
+			c.err = errors.New("class constructor is not a function")
+			return
+		}
+		t := ctor.fn.Type()
+		if t.NumOut() == 0 || t.NumOut() > 2 || t.Out(0).Kind() != reflect.Ptr ||
+			(t.NumOut() == 2 && t.Out(1) != errorType) {
+			c.err = fmt.Errorf("class constructor must return a pointer and optionally an error, not %s", t)
+			return
+		}
+		c.ctor = ctor
+		c.typ = t.Out(0)
+		classTypes.Store(c.typ, c)
+		c.methods = make(map[string]int)
+		for i := 0; i < c.typ.NumMethod(); i++ {
+			method := c.typ.Method(i)
+			if method.Name == "Close" && isCloser(c.typ) {
+				continue // close() releases the instance instead
+			}
+			c.methods[jsName(method.Name)] = i
+		}
+		c.live = make(map[interface{}]*instanceState)
+	})
+	return c.err
+}
+
+// Type returns the pointer type of the instances, or nil if New is not a
+// valid constructor
+func (c *Class) Type() reflect.Type {
+	if c.init() != nil {
+		return nil
+	}
+	return c.typ
+}
+
+// Methods returns the JS names of the methods of the instances, sorted
+func (c *Class) Methods() []string {
+	if c.init() != nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.methods))
+	for name := range c.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Construct calls New with args, which are plain values, and returns the
+// new instance
+func (c *Class) Construct(args []interface{}) (*Instance, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	in, err := c.ctor.arguments(args)
+	if err != nil {
+		return nil, err
+	}
+	out := c.ctor.fn.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	if out[0].IsNil() {
+		return nil, fmt.Errorf("%s constructor returned nil", c.typ)
+	}
+	return c.Adopt(out[0].Interface())
+}
+
+// Adopt makes an instance of value, a pointer of the class's type made
+// other than by New
+func (c *Class) Adopt(value interface{}) (*Instance, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Type() != c.typ || v.IsNil() {
+		return nil, fmt.Errorf("expected a non-nil %s, got %T", c.typ, value)
+	}
+	c.mu.Lock()
+	state, adopted := c.live[value]
+	if !adopted {
+		state = &instanceState{class: c, value: v}
+		c.live[value] = state
+	}
+	state.refs++
+	c.mu.Unlock()
+
+	inst := &Instance{state: state}
+	runtime.SetFinalizer(inst, func(inst *Instance) {
+		// Finalizers share a goroutine, which Close shouldn't hold up
+		go inst.state.unref()
+	})
+	if !adopted && c.Created != nil {
+		c.Created(value)
+	}
+	return inst, nil
+}
+
+// Close releases every instance that hasn't been, as the runtime does when
+// it shuts down
+func (c *Class) Close() error {
+	c.mu.Lock()
+	states := make([]*instanceState, 0, len(c.live))
+	for _, state := range c.live {
+		states = append(states, state)
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for _, state := range states {
+		if err := state.release(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Value returns the Go value of the instance
+func (i *Instance) Value() interface{} {
+	return i.state.value.Interface()
+}
+
+// Class returns the class of the instance
+func (i *Instance) Class() *Class {
+	return i.state.class
+}
+
+// Call calls the method with the JS name method with args, which are plain
+// values, converting them and its results as Function.Call does
+func (i *Instance) Call(method string, args []interface{}) (interface{}, error) {
+	c := i.state.class
+	c.mu.Lock()
+	released := i.state.released
+	c.mu.Unlock()
+	if released {
+		return nil, fmt.Errorf("%s has been closed", c.typ.Elem().Name())
+	}
+	index, ok := c.methods[method]
+	if !ok {
+		return nil, fmt.Errorf("%s has no method %s", c.typ.Elem().Name(), method)
+	}
+	fn := &Function{fn: i.state.value.Method(index)}
+	return fn.Call(args)
+}
+
+// Release releases the instance, as its close() method does in JS. Later
+// calls do nothing.
+func (i *Instance) Release() error {
+	return i.state.release()
+}
+
+// unref releases the instance once its last Instance has been collected
+func (s *instanceState) unref() {
+	c := s.class
+	c.mu.Lock()
+	s.refs--
+	last := s.refs == 0
+	c.mu.Unlock()
+	if last {
+		s.release()
+	}
+}
+
+func (s *instanceState) release() error {
+	value := s.value.Interface()
+	c := s.class
+	c.mu.Lock()
+	if s.released {
+		c.mu.Unlock()
+		return nil
+	}
+	s.released = true
+	if c.live[value] == s {
+		delete(c.live, value)
+	}
+	c.mu.Unlock()
+
+	if c.Released != nil {
+		c.Released(value)
+	}
+	switch closer := value.(type) {
+	case interface{ Close() error }:
+		return closer.Close()
+	case interface{ Close() }:
+		closer.Close()
+	}
+	return nil
+}
+
+// isCloser reports whether t has a Close() or Close() error method
+func isCloser(t reflect.Type) bool {
+	method, ok := t.MethodByName("Close")
+	if !ok || method.Type.NumIn() != 1 {
+		return false
+	}
+	switch method.Type.NumOut() {
+	case 0:
+		return true
+	case 1:
+		return method.Type.Out(0) == errorType
+	}
+	return false
+}
+
+// jsName lowercases the leading capitals of a Go name, except the last of
+// several when it starts a word: GetTotal is getTotal, ID is id and URLFor
+// is urlFor
+func jsName(name string) string {
+	r := []rune(name)
+	for i := 0; i < len(r) && unicode.IsUpper(r[i]); i++ {
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
@@ -0,0 +1,187 @@
+package plugins
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type counter struct {
+	n      int
+	closed *int
+}
+
+func newCounter(start int) (*counter, error) {
+	if start < 0 {
+		return nil, errors.New("start must not be negative")
+	}
+	return &counter{n: start, closed: new(int)}, nil
+}
+
+func (c *counter) Add(by int) int         { c.n += by; return c.n }
+func (c *counter) ID() string             { return "counter" }
+func (c *counter) Fork() *counter         { return &counter{n: c.n, closed: c.closed} }
+func (c *counter) URLFor(p string) string { return "/" + p }
+func (c *counter) Close() error           { *c.closed++; return nil }
+
+func TestClass(t *testing.T) {
+	var created, released []interface{}
+	class := &Class{
+		New:      newCounter,
+		Created:  func(v interface{}) { created = append(created, v) },
+		Released: func(v interface{}) { released = append(released, v) },
+	}
+	if got, want := class.Methods(), []string{"add", "fork", "id", "urlFor"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Methods() = %v, want %v", got, want)
+	}
+	if class.Type() != reflect.TypeOf(&counter{}) {
+		t.Errorf("Type() = %v", class.Type())
+	}
+
+	inst, err := class.Construct([]interface{}{int64(2)})
+	if err != nil {
+		t.Fatalf("Construct() failed: %v", err)
+	}
+	c := inst.Value().(*counter)
+	if len(created) != 1 || created[0] != c {
+		t.Errorf("Created got %v", created)
+	}
+	if result, err := inst.Call("add", []interface{}{int64(3)}); err != nil || result != int64(5) {
+		t.Errorf("add(3) = %v, %v", result, err)
+	}
+	if result, _ := inst.Call("fork", nil); reflect.TypeOf(result) != class.Type() {
+		t.Errorf("Expected fork() to return a *counter, got %T", result)
+	}
+	if _, err := inst.Call("nope", nil); err == nil || err.Error() != "counter has no method nope" {
+		t.Errorf("Call() of a missing method = %v", err)
+	}
+	var argErr *ArgumentError
+	if _, err := inst.Call("add", []interface{}{"x"}); !errors.As(err, &argErr) {
+		t.Errorf("Call() with a bad argument = %v", err)
+	}
+
+	// Releasing runs Released and Close once
+	if err := inst.Release(); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+	inst.Release()
+	if *c.closed != 1 || len(released) != 1 || released[0] != c {
+		t.Errorf("Expected one release, got %d closes and %v", *c.closed, released)
+	}
+	if _, err := inst.Call("add", []interface{}{int64(1)}); err == nil || err.Error() != "counter has been closed" {
+		t.Errorf("Call() after Release() = %v", err)
+	}
+
+	// Construction errors
+	if _, err := class.Construct([]interface{}{int64(-1)}); err == nil || err.Error() != "start must not be negative" {
+		t.Errorf("Construct(-1) = %v", err)
+	}
+	if _, err := class.Construct([]interface{}{"x"}); !errors.As(err, &argErr) {
+		t.Errorf("Construct(\"x\") = %v", err)
+	}
+	if _, err := class.Adopt(&address{}); err == nil {
+		t.Error("Expected Adopt() of another type to fail")
+	}
+	for _, bad := range []interface{}{3, func() counter { return counter{} }, func() (*counter, int) { return nil, 0 }} {
+		if _, err := (&Class{New: bad}).Construct(nil); err == nil {
+			t.Errorf("Expected a class with New %T to fail", bad)
+		}
+	}
+}
+
+func TestClassClose(t *testing.T) {
+	class := &Class{New: newCounter}
+	a, _ := class.Construct(nil)
+	b, _ := class.Construct(nil)
+	a.Release()
+	if err := class.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if *a.Value().(*counter).closed != 1 || *b.Value().(*counter).closed != 1 {
+		t.Error("Expected every instance to be closed once")
+	}
+}
+
+func TestClassFinalizer(t *testing.T) {
+	// Released gets the number of times the instance was closed so far
+	released := make(chan int, 1)
+	class := &Class{New: newCounter, Released: func(v interface{}) { released <- *v.(*counter).closed }}
+	func() {
+		if _, err := class.Construct(nil); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case closed := <-released:
+			if closed != 0 {
+				t.Error("Expected Released to be called before Close")
+			}
+			return
+		case <-deadline:
+			t.Fatal("Expected an unreachable instance to be released")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestJSName(t *testing.T) {
+	for name, want := range map[string]string{
+		"GetTotal": "getTotal", "ID": "id", "URLFor": "urlFor", "X": "x", "HTTPServer": "httpServer",
+	} {
+		if got := jsName(name); got != want {
+			t.Errorf("jsName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestClassSharedValue(t *testing.T) {
+	created := 0
+	class := &Class{New: newCounter, Created: func(interface{}) { created++ }}
+	c, _ := newCounter(0)
+	a, _ := class.Adopt(c)
+	b, _ := class.Adopt(c)
+	if created != 1 || a.Value() != b.Value() {
+		t.Errorf("Expected one instance of the value, Created was called %d times", created)
+	}
+
+	// Releasing through either one closes the value once
+	b.Release()
+	a.Release()
+	if *c.closed != 1 {
+		t.Errorf("Expected the value to be closed once, got %d", *c.closed)
+	}
+	if _, err := a.Call("add", []interface{}{int64(1)}); err == nil {
+		t.Error("Expected calls on the other instance to fail after Release()")
+	}
+
+	// Collecting one of two leaves the value open
+	c2, _ := newCounter(0)
+	kept, _ := class.Adopt(c2)
+	func() { class.Adopt(c2) }()
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := kept.Call("add", []interface{}{int64(1)}); err != nil {
+		t.Errorf("Expected the value to stay open while an instance is reachable, got %v", err)
+	}
+	runtime.KeepAlive(kept)
+}
+
+func TestClassArguments(t *testing.T) {
+	class := &Class{New: newCounter}
+	inst, _ := class.Construct(nil)
+	fn, _ := NewFunction(func(c *counter) int { return c.n })
+	if got, err := fn.Call([]interface{}{inst.Value()}); err != nil || got != int64(0) {
+		t.Errorf("Call() with an instance = %v, %v", got, err)
+	}
+	// A plain object doesn't make an instance
+	if _, err := fn.Call([]interface{}{map[string]interface{}{}}); err == nil || err.Error() != "argument 1: expected a counter instance, got an object" {
+		t.Errorf("Call() with an object = %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"plugin"
+	"runtime"
 	"strings"
 
 	"github.com/rizqme/gode/internal/errors"
@@ -25,65 +26,142 @@ func NewLoader(rt interface{}) *Loader {
 
 // LoadPlugin loads a Go plugin from the specified path
 func (l *Loader) LoadPlugin(path string) (*PluginInfo, error) {
-	return errors.SafeOperationWithResult("PluginLoader", "LoadPlugin", func() (*PluginInfo, error) {
-		// Check if plugin is already loaded
-		absPath, err := filepath.Abs(path)
+	infos, err := l.LoadPlugins(path)
+	if err != nil {
+		return nil, err
+	}
+	return infos[0], nil
+}
+
+// LoadPlugins loads several Go plugins together, initializing each plugin
+// after the plugins that provide the services it requires. The result is
+// in the order of paths.
+func (l *Loader) LoadPlugins(paths ...string) ([]*PluginInfo, error) {
+	return errors.SafeOperationWithResult("PluginLoader", "LoadPlugin", func() ([]*PluginInfo, error) {
+		result := make([]*PluginInfo, len(paths))
+		var pending []*PluginInfo
+		for i, path := range paths {
+			// Check if plugin is already loaded
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return nil, errors.NewModuleError("plugin", path, "resolve-path", err)
+			}
+
+			if info, exists := l.plugins[absPath]; exists {
+				result[i] = info
+				continue
+			}
+
+			info, err := l.open(path, absPath)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = info
+			pending = append(pending, info)
+		}
+
+		ordered, err := orderPlugins(pending, l.serviceAvailable)
 		if err != nil {
-			return nil, errors.NewModuleError("plugin", path, "resolve-path", err)
+			return nil, errors.NewModuleError("plugin", paths[0], "resolve-services", err)
 		}
 
-		if info, exists := l.plugins[absPath]; exists {
-			return info, nil
+		for _, info := range ordered {
+			if err := l.initialize(info); err != nil {
+				return nil, err
+			}
+
+			// Register the plugin
+			l.plugins[info.Path] = info
 		}
 
-		// Load the plugin
-		p, err := plugin.Open(path)
+		return result, nil
+	})
+}
+
+// errPluginsUnsupported is returned on platforms where Go's plugin package
+// cannot open native plugins
+var errPluginsUnsupported = fmt.Errorf("native Go plugins are not supported on %s; only linux, darwin and freebsd can load them", runtime.GOOS)
+
+// open opens a plugin file without initializing it
+func (l *Loader) open(path, absPath string) (*PluginInfo, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.NewModuleError("plugin", path, "open", errPluginsUnsupported).WithSourceContext(fmt.Sprintf("Plugin path: %s", absPath))
+	}
+
+	// Load the plugin
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.NewModuleError("plugin", path, "open", err).WithSourceContext(fmt.Sprintf("Plugin path: %s", absPath))
+	}
+
+	// Create plugin info
+	info := &PluginInfo{
+		Path:        absPath,
+		Initialized: false,
+	}
+
+	// Try to load plugin interface implementation
+	if pluginImpl, err := l.loadPluginInterface(p); err == nil {
+		info.Plugin = pluginImpl
+		info.Name = pluginImpl.Name()
+		info.Version = pluginImpl.Version()
+	} else {
+		// Fallback: load individual exported functions
+		info.Name = l.extractPluginName(path)
+		info.Version = "unknown"
+
+		// Load exports directly from plugin symbols
+		exports, err := l.loadDirectExports(p)
 		if err != nil {
-			return nil, errors.NewModuleError("plugin", path, "open", err).WithSourceContext(fmt.Sprintf("Plugin path: %s", absPath))
+			return nil, errors.NewModuleError("plugin", path, "load-exports", err).WithSourceContext(fmt.Sprintf("Plugin: %s", info.Name))
 		}
 
-		// Create plugin info
-		info := &PluginInfo{
-			Path:        absPath,
-			Initialized: false,
+		// Create a wrapper plugin
+		info.Plugin = &directPlugin{
+			name:    info.Name,
+			version: info.Version,
+			exports: exports,
 		}
+	}
 
-		// Try to load plugin interface implementation
-		if pluginImpl, err := l.loadPluginInterface(p); err == nil {
-			info.Plugin = pluginImpl
-			info.Name = pluginImpl.Name()
-			info.Version = pluginImpl.Version()
-			
-			// Initialize the plugin
-			if err := pluginImpl.Initialize(l.runtime); err != nil {
-				return nil, errors.NewModuleError("plugin", path, "initialize", err).WithSourceContext(fmt.Sprintf("Plugin: %s v%s", info.Name, info.Version))
-			}
-			info.Initialized = true
-		} else {
-			// Fallback: load individual exported functions
-			info.Name = l.extractPluginName(path)
-			info.Version = "unknown"
-			
-			// Load exports directly from plugin symbols
-			exports, err := l.loadDirectExports(p)
-			if err != nil {
-				return nil, errors.NewModuleError("plugin", path, "load-exports", err).WithSourceContext(fmt.Sprintf("Plugin: %s", info.Name))
-			}
-			
-			// Create a wrapper plugin
-			info.Plugin = &directPlugin{
-				name:    info.Name,
-				version: info.Version,
-				exports: exports,
-			}
-			info.Initialized = true
+	return info, nil
+}
+
+// initialize runs a plugin's Initialize and checks that it registered the
+// services it declared
+func (l *Loader) initialize(info *PluginInfo) error {
+	if err := info.Plugin.Initialize(l.host(info)); err != nil {
+		return errors.NewModuleError("plugin", info.Path, "initialize", err).WithSourceContext(fmt.Sprintf("Plugin: %s v%s", info.Name, info.Version))
+	}
+
+	for _, service := range provides(info) {
+		if !l.serviceAvailable(service) {
+			err := fmt.Errorf("plugin declares service %q but did not provide it", service)
+			return errors.NewModuleError("plugin", info.Path, "initialize", err).WithSourceContext(fmt.Sprintf("Plugin: %s v%s", info.Name, info.Version))
 		}
+	}
 
-		// Register the plugin
-		l.plugins[absPath] = info
-		
-		return info, nil
-	})
+	info.Initialized = true
+	return nil
+}
+
+// host returns what the plugin's Initialize receives: a host specific to
+// the plugin when the runtime offers one, for Emitter, or else the runtime
+func (l *Loader) host(info *PluginInfo) interface{} {
+	if hosts, ok := l.runtime.(interface{ PluginHost(name string) interface{} }); ok {
+		return hosts.PluginHost(info.Name)
+	}
+	return l.runtime
+}
+
+// serviceAvailable reports whether the runtime's service registry has name
+func (l *Loader) serviceAvailable(name string) bool {
+	registry, ok := l.runtime.(ServiceRegistry)
+	if !ok {
+		return false
+	}
+	_, exists := registry.GetService(name)
+	return exists
 }
 
 // loadPluginInterface tries to load a plugin that implements the Plugin interface
@@ -123,6 +201,9 @@ func (l *Loader) loadPluginInterface(p *plugin.Plugin) (Plugin, error) {
 	// Look for optional functions
 	var initializeFunc func(interface{}) error
 	var disposeFunc func() error
+	var requiresFunc, providesFunc func() []string
+	var docsFunc func() map[string]string
+	var paramNamesFunc func() map[string][]string
 
 	if initSymbol, err := p.Lookup("Initialize"); err == nil {
 		if initFunc, ok := initSymbol.(func(interface{}) error); ok {
@@ -136,12 +217,32 @@ func (l *Loader) loadPluginInterface(p *plugin.Plugin) (Plugin, error) {
 		}
 	}
 
+	// Service dependencies, see Dependent
+	if symbol, err := p.Lookup("Requires"); err == nil {
+		requiresFunc, _ = symbol.(func() []string)
+	}
+	if symbol, err := p.Lookup("Provides"); err == nil {
+		providesFunc, _ = symbol.(func() []string)
+	}
+
+	// Embedded documentation, see Documented
+	if symbol, err := p.Lookup("Docs"); err == nil {
+		docsFunc, _ = symbol.(func() map[string]string)
+	}
+	if symbol, err := p.Lookup("ParamNames"); err == nil {
+		paramNamesFunc, _ = symbol.(func() map[string][]string)
+	}
+
 	return &standardPlugin{
 		nameFunc:       nameFunc,
 		versionFunc:    versionFunc,
 		exportsFunc:    exportsFunc,
 		initializeFunc: initializeFunc,
 		disposeFunc:    disposeFunc,
+		requiresFunc:   requiresFunc,
+		providesFunc:   providesFunc,
+		docsFunc:       docsFunc,
+		paramNamesFunc: paramNamesFunc,
 	}, nil
 }
 
@@ -216,6 +317,10 @@ type standardPlugin struct {
 	exportsFunc    func() map[string]interface{}
 	initializeFunc func(interface{}) error
 	disposeFunc    func() error
+	requiresFunc   func() []string
+	providesFunc   func() []string
+	docsFunc       func() map[string]string
+	paramNamesFunc func() map[string][]string
 }
 
 func (p *standardPlugin) Name() string {
@@ -244,6 +349,34 @@ func (p *standardPlugin) Dispose() error {
 	return nil
 }
 
+func (p *standardPlugin) Requires() []string {
+	if p.requiresFunc != nil {
+		return p.requiresFunc()
+	}
+	return nil
+}
+
+func (p *standardPlugin) Provides() []string {
+	if p.providesFunc != nil {
+		return p.providesFunc()
+	}
+	return nil
+}
+
+func (p *standardPlugin) Docs() map[string]string {
+	if p.docsFunc != nil {
+		return p.docsFunc()
+	}
+	return nil
+}
+
+func (p *standardPlugin) ParamNames() map[string][]string {
+	if p.paramNamesFunc != nil {
+		return p.paramNamesFunc()
+	}
+	return nil
+}
+
 // directPlugin wraps plugins that don't implement the full Plugin interface
 type directPlugin struct {
 	name    string
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	goruntime "runtime"
 	"strings"
 	"testing"
 
@@ -69,7 +70,7 @@ func TestLoaderLoadPlugin_NonExistentFile(t *testing.T) {
 	}
 
 	// Check that we have a stack trace
-	if len(moduleErr.StackTrace.Frames) == 0 {
+	if len(moduleErr.Stack().Frames) == 0 {
 		t.Error("Expected stack trace frames, got empty")
 	}
 
@@ -378,6 +379,9 @@ func TestLoaderErrorFormatting(t *testing.T) {
 
 // Integration test that creates an actual (invalid) .so file
 func TestLoaderLoadPlugin_InvalidSOFile(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("plugin.Open is not available on windows")
+	}
 	runtime := &mockTestRuntime{}
 	loader := NewLoader(runtime)
 
@@ -412,6 +416,22 @@ func TestLoaderLoadPlugin_InvalidSOFile(t *testing.T) {
 	}
 }
 
+func TestLoaderLoadPlugin_Windows(t *testing.T) {
+	if goruntime.GOOS != "windows" {
+		t.Skip("only windows refuses native plugins")
+	}
+	dll := filepath.Join(t.TempDir(), "math.dll")
+	if err := os.WriteFile(dll, []byte("MZ"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewLoader(&mockTestRuntime{}).LoadPlugin(dll)
+	moduleErr, ok := err.(*errors.ModuleError)
+	if !ok || moduleErr.Operation != "open" || moduleErr.Err != errPluginsUnsupported {
+		t.Fatalf("Expected the unsupported plugins error, got %v", err)
+	}
+}
+
 // Test plugin caching behavior
 func TestLoaderPluginCaching(t *testing.T) {
 	runtime := &mockTestRuntime{}
@@ -0,0 +1,707 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMarshalDepth bounds how deeply values nest, so that cyclic values
+// fail instead of exhausting the stack
+const maxMarshalDepth = 1000
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	callbackType  = reflect.TypeOf(Callback(nil))
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// Callback is a JS function passed to a plugin function. It takes and
+// returns plain values (see Function) and returns what the JS function
+// threw as its error. It runs JS, so it must be called on the JS thread;
+// the bridge queues the callbacks plugins are given (see wrapCallback).
+type Callback func(args ...interface{}) (interface{}, error)
+
+// Function converts the arguments and results of an exported Go function
+// between its types and the plain values JS values export to: nil, bool,
+// int64, float64, string, []byte, time.Time, []interface{},
+// map[string]interface{} and Callback.
+//
+// Arguments are converted to the parameter types: numbers to any integer,
+// with a range check, or float type, arrays to slices and arrays, objects
+// to map[string]T and to structs, whose fields are matched by their js or
+// json tag or else their name, ignoring case, and JS functions to funcs.
+// time.Duration takes milliseconds or a string such as "1.5s". Missing
+// arguments are zero values and arguments past the last parameter are
+// dropped, unless the function is variadic.
+//
+// Results are converted back with Marshal. A last result of type error is
+// returned as the error of Call, and the others as the result: nothing,
+// the one result or a []interface{} of them.
+type Function struct {
+	fn reflect.Value
+}
+
+// NewFunction wraps fn, reporting false if it is not a function
+func NewFunction(fn interface{}) (*Function, bool) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, false
+	}
+	return &Function{fn: v}, true
+}
+
+// ArgumentError is an argument that doesn't convert to its parameter's
+// type, which JS sees as a TypeError
+type ArgumentError struct {
+	Index int // from 0
+	Err   error
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("argument %d: %v", e.Index+1, e.Err)
+}
+
+func (e *ArgumentError) Unwrap() error { return e.Err }
+
+// Call calls the function with args, which are plain values
+func (f *Function) Call(args []interface{}) (interface{}, error) {
+	in, err := f.arguments(args)
+	if err != nil {
+		return nil, err
+	}
+	return results(f.fn.Type(), f.fn.Call(in))
+}
+
+// arguments converts args to the parameter types of the function
+func (f *Function) arguments(args []interface{}) ([]reflect.Value, error) {
+	t := f.fn.Type()
+	n := t.NumIn()
+	fixed := n
+	if t.IsVariadic() {
+		fixed = n - 1
+	}
+	in := make([]reflect.Value, 0, n)
+	for i := 0; i < fixed; i++ {
+		var arg interface{}
+		if i < len(args) {
+			arg = args[i]
+		}
+		v, err := unmarshal(arg, t.In(i), 0)
+		if err != nil {
+			return nil, &ArgumentError{Index: i, Err: err}
+		}
+		in = append(in, v)
+	}
+	if t.IsVariadic() {
+		elem := t.In(n - 1).Elem()
+		for i := fixed; i < len(args); i++ {
+			v, err := unmarshal(args[i], elem, 0)
+			if err != nil {
+				return nil, &ArgumentError{Index: i, Err: err}
+			}
+			in = append(in, v)
+		}
+	}
+	return in, nil
+}
+
+// results converts what a function of type t returned
+func results(t reflect.Type, out []reflect.Value) (interface{}, error) {
+	if n := len(out); n > 0 && t.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:n-1]
+	}
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		return Marshal(out[0].Interface())
+	}
+	items := make([]interface{}, len(out))
+	for i, v := range out {
+		item, err := Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// Unmarshal converts a plain value to type t, as Function does its
+// arguments
+func Unmarshal(value interface{}, t reflect.Type) (reflect.Value, error) {
+	return unmarshal(value, t, 0)
+}
+
+func unmarshal(value interface{}, t reflect.Type, depth int) (reflect.Value, error) {
+	if depth > maxMarshalDepth {
+		return reflect.Value{}, fmt.Errorf("values are nested more than %d deep", maxMarshalDepth)
+	}
+	if value == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case t == durationType:
+		return duration(value)
+	case t.Kind() == reflect.Func:
+		return callback(value, t)
+	case v.Type().AssignableTo(t) && v.Kind() != reflect.Slice && v.Kind() != reflect.Map:
+		return v.Convert(t), nil
+	}
+	if _, ok := classTypes.Load(t); ok {
+		return reflect.Value{}, fmt.Errorf("expected a %s instance, got %s", t.Elem().Name(), describeValue(value))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String:
+		if v.Kind() == t.Kind() {
+			return v.Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := number(value)
+		if !ok {
+			break
+		}
+		out := reflect.New(t).Elem()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || out.OverflowInt(int64(f)) {
+			return reflect.Value{}, fmt.Errorf("%v is not a %s", value, t)
+		}
+		out.SetInt(int64(f))
+		return out, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, ok := number(value)
+		if !ok {
+			break
+		}
+		out := reflect.New(t).Elem()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || out.OverflowUint(uint64(f)) {
+			return reflect.Value{}, fmt.Errorf("%v is not a %s", value, t)
+		}
+		out.SetUint(uint64(f))
+		return out, nil
+	case reflect.Float32, reflect.Float64:
+		if f, ok := number(value); ok {
+			return reflect.ValueOf(f).Convert(t), nil
+		}
+	case reflect.Interface:
+		if v.Type().Implements(t) {
+			out := reflect.New(t).Elem()
+			out.Set(v)
+			return out, nil
+		}
+	case reflect.Ptr:
+		elem, err := unmarshal(value, t.Elem(), depth+1)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(elem)
+		return out, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			switch b := value.(type) {
+			case []byte:
+				return reflect.ValueOf(append([]byte(nil), b...)).Convert(t), nil
+			case string:
+				return reflect.ValueOf([]byte(b)).Convert(t), nil
+			}
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			break
+		}
+		out := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			elem, err := unmarshal(item, t.Elem(), depth+1)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, nil
+	case reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			break
+		}
+		if len(items) > t.Len() {
+			return reflect.Value{}, fmt.Errorf("expected at most %d items, got %d", t.Len(), len(items))
+		}
+		out := reflect.New(t).Elem()
+		for i, item := range items {
+			elem, err := unmarshal(item, t.Elem(), depth+1)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, nil
+	case reflect.Map:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		out := reflect.MakeMapWithSize(t, len(fields))
+		for name, item := range fields {
+			key, err := unmarshal(name, t.Key(), depth+1)
+			if err != nil {
+				key, err = mapKey(name, t.Key())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+			}
+			elem, err := unmarshal(item, t.Elem(), depth+1)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%q]: %w", name, err)
+			}
+			out.SetMapIndex(key, elem)
+		}
+		return out, nil
+	case reflect.Struct:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		return unmarshalStruct(fields, t, depth)
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s, got %s", describeType(t), describeValue(value))
+}
+
+// unmarshalStruct sets the fields of a new t from an object's properties,
+// leaving the fields the object has no property for at their zero value
+func unmarshalStruct(props map[string]interface{}, t reflect.Type, depth int) (reflect.Value, error) {
+	out := reflect.New(t).Elem()
+	for _, field := range structFields(t) {
+		value, ok := props[field.name]
+		if !ok {
+			for key, v := range props {
+				if strings.EqualFold(key, field.name) {
+					value, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		v, err := unmarshal(value, t.FieldByIndex(field.index).Type, depth+1)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%s: %w", field.name, err)
+		}
+		out.FieldByIndex(field.index).Set(v)
+	}
+	return out, nil
+}
+
+// mapKey parses an object's property name as a number for maps with
+// numeric keys
+func mapKey(name string, t reflect.Type) (reflect.Value, error) {
+	f, err := strconv.ParseFloat(name, 64)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("key %q is not a %s", name, t)
+	}
+	return unmarshal(f, t, 0)
+}
+
+// number returns value as a float64 if it is a number
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// duration converts milliseconds or a duration string
+func duration(value interface{}) (reflect.Value, error) {
+	if s, ok := value.(string); ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(d), nil
+	}
+	ms, ok := number(value)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("expected a duration in milliseconds or a string, got %s", describeValue(value))
+	}
+	return reflect.ValueOf(time.Duration(ms * float64(time.Millisecond))), nil
+}
+
+// callback converts a JS function to the func type t. Its arguments are
+// marshaled for JS and its result converted to the first result of t; if
+// the last result of t is an error, it gets what the JS function threw,
+// which otherwise panics.
+func callback(value interface{}, t reflect.Type) (reflect.Value, error) {
+	fn, ok := value.(Callback)
+	if !ok {
+		v := reflect.ValueOf(value)
+		if v.Type().AssignableTo(t) {
+			return v, nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected a function, got %s", describeValue(value))
+	}
+	if t == callbackType {
+		return reflect.ValueOf(fn), nil
+	}
+	hasError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		args := make([]interface{}, 0, len(in))
+		for i, v := range in {
+			if t.IsVariadic() && i == len(in)-1 {
+				for j := 0; j < v.Len(); j++ {
+					args = append(args, marshalArg(v.Index(j).Interface()))
+				}
+				continue
+			}
+			args = append(args, marshalArg(v.Interface()))
+		}
+		result, err := fn(args...)
+
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(t.Out(i))
+		}
+		if err == nil && t.NumOut() > 0 && !(hasError && t.NumOut() == 1) {
+			var v reflect.Value
+			if v, err = unmarshal(result, t.Out(0), 0); err == nil {
+				out[0] = v
+			} else {
+				err = fmt.Errorf("callback result: %w", err)
+			}
+		}
+		if err != nil {
+			if !hasError {
+				panic(err)
+			}
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+		}
+		return out
+	}), nil
+}
+
+// marshalArg converts an argument a plugin passes to a callback, panicking
+// like a failed call when it can't be
+func marshalArg(v interface{}) interface{} {
+	value, err := Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Marshal converts a Go value to a plain value for JS: integers to int64,
+// or uint64 when larger, floats to float64, time.Duration to milliseconds,
+// slices and arrays of numbers, strings and booleans to []float64,
+// []int64, []string and []bool, other slices and arrays, except []byte,
+// to []interface{}, maps to map[string]interface{}, structs to maps keyed
+// by the js or json tag or the name of their exported fields, funcs to
+// *Function and channels to *Stream. Pointers are followed, except to
+// types with methods; those, errors, time.Time and Tables are kept as
+// they are.
+func Marshal(value interface{}) (interface{}, error) {
+	return marshal(reflect.ValueOf(value), 0)
+}
+
+func marshal(v reflect.Value, depth int) (interface{}, error) {
+	if depth > maxMarshalDepth {
+		return nil, fmt.Errorf("values are nested more than %d deep, or cyclic", maxMarshalDepth)
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type() == durationType {
+		return float64(v.Int()) / float64(time.Millisecond), nil
+	}
+	if v.Type() == timeType {
+		return v.Interface(), nil
+	}
+	if v.Type() == tableType {
+		return marshalTable(v.Interface().(Table), depth)
+	}
+	if v.Type() == reflect.PtrTo(tableType) && !v.IsNil() {
+		return marshalTable(v.Elem().Interface().(Table), depth)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := v.Uint(); n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if err, ok := v.Interface().(error); ok {
+			return err, nil
+		}
+		if v.Kind() == reflect.Ptr && v.Type().NumMethod() > 0 {
+			// An object with methods stays one, so JS can call them
+			return v.Interface(), nil
+		}
+		return marshal(v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return data, nil
+		}
+		if items, ok := marshalSlice(v); ok {
+			return items, nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := marshal(v.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		fields := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := marshal(iter.Value(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			fields[fmt.Sprint(iter.Key().Interface())] = item
+		}
+		return fields, nil
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		for _, field := range structFields(v.Type()) {
+			fv := v.FieldByIndex(field.index)
+			if field.omitEmpty && fv.IsZero() {
+				continue
+			}
+			item, err := marshal(fv, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			fields[field.name] = item
+		}
+		return fields, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return &Function{fn: v}, nil
+	case reflect.Chan:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().ChanDir()&reflect.RecvDir == 0 {
+			return nil, errors.New("send-only channels can't be returned to JS")
+		}
+		return &Stream{ch: v}, nil
+	}
+	return nil, fmt.Errorf("%s can't be converted for JS", v.Type())
+}
+
+// marshalSlice converts a slice or array of numbers, strings or booleans
+// to a []float64, []int64, []string or []bool, which the runtime turns
+// into an array without an interface{} per item. It reports false for
+// other element types, time.Duration and unsigned integers too big for
+// an int64.
+func marshalSlice(v reflect.Value) (interface{}, bool) {
+	if v.Kind() == reflect.Slice && v.CanInterface() {
+		switch items := v.Interface().(type) {
+		case []float64:
+			return append([]float64(nil), items...), true
+		case []int64:
+			return append([]int64(nil), items...), true
+		case []string:
+			return append([]string(nil), items...), true
+		case []bool:
+			return append([]bool(nil), items...), true
+		case []int:
+			out := make([]int64, len(items))
+			for i, n := range items {
+				out[i] = int64(n)
+			}
+			return out, true
+		}
+	}
+	elem := v.Type().Elem()
+	if elem == durationType {
+		return nil, false
+	}
+	n := v.Len()
+	switch elem.Kind() {
+	case reflect.Float32, reflect.Float64:
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = v.Index(i).Float()
+		}
+		return out, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out := make([]int64, n)
+		for i := range out {
+			out[i] = v.Index(i).Int()
+		}
+		return out, true
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		out := make([]int64, n)
+		for i := range out {
+			u := v.Index(i).Uint()
+			if u > math.MaxInt64 {
+				return nil, false
+			}
+			out[i] = int64(u)
+		}
+		return out, true
+	case reflect.String:
+		out := make([]string, n)
+		for i := range out {
+			out[i] = v.Index(i).String()
+		}
+		return out, true
+	case reflect.Bool:
+		out := make([]bool, n)
+		for i := range out {
+			out[i] = v.Index(i).Bool()
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// field is an exported field of a struct with the name JS knows it by
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structFields lists the exported fields of t, including those of
+// embedded structs, with their names from the js tag, else the json tag,
+// else the Go name. Fields tagged "-" are left out.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("js")
+		if !ok {
+			tag = f.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if f.Type.Kind() == reflect.Ptr {
+					// The pointer may be nil, and can't be followed
+					continue
+				}
+				for _, inner := range structFields(ft) {
+					inner.index = append([]int{i}, inner.index...)
+					fields = append(fields, inner)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+// Stream is a channel a plugin function returned, which JS iterates with
+// for await. Values received on it are marshaled; a non-nil error received
+// on a channel of errors or interfaces ends the iteration with that error.
+type Stream struct {
+	ch reflect.Value
+}
+
+// Next blocks until the channel has a value, reporting false once it is
+// closed
+func (s *Stream) Next() (value interface{}, ok bool, err error) {
+	v, ok := s.ch.Recv()
+	if !ok {
+		return nil, false, nil
+	}
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		if err, isErr := v.Interface().(error); isErr {
+			return nil, false, err
+		}
+	}
+	value, err = marshal(v, 0)
+	return value, err == nil, err
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object for " + t.String()
+	case reflect.Slice, reflect.Array:
+		return "an array for " + t.String()
+	case reflect.Interface:
+		if t == interfaceType {
+			return "a value"
+		}
+	}
+	return t.String()
+}
+
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	case Callback:
+		return "a function"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int64, float64, int:
+		return "a number"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,245 @@
+package plugins
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  int    `js:"postcode"`
+}
+
+type person struct {
+	Name    string            `json:"name"`
+	Age     uint8             `json:"age,omitempty"`
+	Tags    []string          `json:"tags"`
+	Scores  map[string]int    `json:"scores"`
+	Address *address          `json:"address"`
+	Timeout time.Duration     `json:"timeout"`
+	Secret  string            `json:"-"`
+	Extra   map[int]bool      `json:"extra"`
+	Born    time.Time         `json:"born"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	hidden  int
+}
+
+func TestFunctionConvertsArguments(t *testing.T) {
+	var got person
+	fn, _ := NewFunction(func(p person, n int, rest ...float32) string {
+		got = p
+		return p.Name + strings.Repeat("!", n) + strings.Repeat("?", len(rest))
+	})
+	born := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := fn.Call([]interface{}{
+		map[string]interface{}{
+			"NAME":    "Ann",
+			"age":     int64(30),
+			"tags":    []interface{}{"a", "b"},
+			"scores":  map[string]interface{}{"x": 1.0},
+			"address": map[string]interface{}{"city": "Oslo", "postcode": int64(150)},
+			"timeout": "1.5s",
+			"Secret":  "no",
+			"extra":   map[string]interface{}{"7": true},
+			"born":    born,
+			"unknown": 1,
+		},
+		int64(2), 1.5, int64(2),
+	})
+	if err != nil || result != "Ann!!??" {
+		t.Fatalf("Call() = %v, %v", result, err)
+	}
+	want := person{
+		Name: "Ann", Age: 30, Tags: []string{"a", "b"}, Scores: map[string]int{"x": 1},
+		Address: &address{City: "Oslo", Zip: 150}, Timeout: 1500 * time.Millisecond,
+		Extra: map[int]bool{7: true}, Born: born,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	// Missing arguments are zero values
+	if result, err := fn.Call(nil); err != nil || result != "" {
+		t.Errorf("Call() without arguments = %v, %v", result, err)
+	}
+	ms, _ := NewFunction(func(d time.Duration) time.Duration { return d })
+	if result, _ := ms.Call([]interface{}{int64(250)}); result != 250.0 {
+		t.Errorf("Call() with milliseconds = %v", result)
+	}
+}
+
+func TestFunctionArgumentErrors(t *testing.T) {
+	fn, _ := NewFunction(func(p person, n int8, items []int) {})
+	tests := []struct {
+		args []interface{}
+		want string
+	}{
+		{[]interface{}{"x"}, "argument 1: expected an object for plugins.person, got a string"},
+		{[]interface{}{map[string]interface{}{"age": int64(300)}}, "argument 1: age: 300 is not a uint8"},
+		{[]interface{}{map[string]interface{}{"tags": []interface{}{"a", int64(1)}}}, "argument 1: tags: [1]: expected string, got a number"},
+		{[]interface{}{nil, 1.5}, "argument 2: 1.5 is not a int8"},
+		{[]interface{}{nil, int64(1), map[string]interface{}{}}, "argument 3: expected an array for []int, got an object"},
+		{[]interface{}{map[string]interface{}{"extra": map[string]interface{}{"x": true}}}, `argument 1: extra: key "x" is not a int`},
+	}
+	for _, tt := range tests {
+		_, err := fn.Call(tt.args)
+		var argErr *ArgumentError
+		if !errors.As(err, &argErr) || err.Error() != tt.want {
+			t.Errorf("Call(%v) = %v, want %s", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestFunctionResults(t *testing.T) {
+	boom := errors.New("boom")
+	tests := []struct {
+		fn      interface{}
+		want    interface{}
+		wantErr error
+	}{
+		{func() {}, nil, nil},
+		{func() error { return boom }, nil, boom},
+		{func() (int, error) { return 1, nil }, int64(1), nil},
+		{func() (int, error) { return 0, boom }, nil, boom},
+		{func() (string, bool) { return "a", true }, []interface{}{"a", true}, nil},
+		{func() []byte { return []byte("hi") }, []byte("hi"), nil},
+		{func() uint64 { return 1 << 63 }, uint64(1 << 63), nil},
+		{func() *address { return &address{City: "Rome"} }, map[string]interface{}{"city": "Rome", "postcode": int64(0)}, nil},
+		{func() person { return person{Name: "Bo"} }, map[string]interface{}{
+			"name": "Bo", "tags": nil, "scores": nil, "address": nil, "timeout": 0.0,
+			"extra": nil, "born": time.Time{},
+		}, nil},
+		{func() map[int][]int8 { return map[int][]int8{1: {2}} }, map[string]interface{}{"1": []int64{2}}, nil},
+	}
+	for i, tt := range tests {
+		fn, ok := NewFunction(tt.fn)
+		if !ok {
+			t.Fatalf("%d: NewFunction() failed", i)
+		}
+		got, err := fn.Call(nil)
+		if err != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d: Call() = %#v, %v, want %#v, %v", i, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	inner, _ := NewFunction(func() func(int) int { return func(n int) int { return n * 2 } })
+	returned, _ := inner.Call(nil)
+	double, ok := returned.(*Function)
+	if !ok {
+		t.Fatalf("Expected a returned func to be a *Function, got %T", returned)
+	}
+	if got, _ := double.Call([]interface{}{int64(4)}); got != int64(8) {
+		t.Errorf("double(4) = %v", got)
+	}
+	if _, ok := NewFunction(3); ok {
+		t.Error("Expected NewFunction of a number to fail")
+	}
+}
+
+func TestFunctionCallbacks(t *testing.T) {
+	fn, _ := NewFunction(func(items []int, each func(n int, label string) (string, error)) ([]string, error) {
+		var out []string
+		for _, n := range items {
+			s, err := each(n, "n")
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	})
+	var calls [][]interface{}
+	each := Callback(func(args ...interface{}) (interface{}, error) {
+		calls = append(calls, args)
+		if args[0] == int64(3) {
+			return nil, errors.New("three")
+		}
+		return args[1].(string) + "=" + strings.Repeat("x", int(args[0].(int64))), nil
+	})
+
+	got, err := fn.Call([]interface{}{[]interface{}{int64(1), int64(2)}, each})
+	if err != nil || !reflect.DeepEqual(got, []string{"n=x", "n=xx"}) {
+		t.Errorf("Call() = %v, %v", got, err)
+	}
+	if !reflect.DeepEqual(calls[0], []interface{}{int64(1), "n"}) {
+		t.Errorf("callback got %#v", calls[0])
+	}
+	if _, err := fn.Call([]interface{}{[]interface{}{int64(3)}, each}); err == nil || err.Error() != "three" {
+		t.Errorf("Call() with a failing callback = %v", err)
+	}
+
+	wrong := Callback(func(args ...interface{}) (interface{}, error) { return true, nil })
+	if _, err := fn.Call([]interface{}{[]interface{}{int64(1)}, wrong}); err == nil || !strings.Contains(err.Error(), "callback result") {
+		t.Errorf("Call() with a callback returning the wrong type = %v", err)
+	}
+	if _, err := fn.Call([]interface{}{nil, "not a function"}); err == nil {
+		t.Error("Expected an error for a string in place of a callback")
+	}
+}
+
+func TestStream(t *testing.T) {
+	fn, _ := NewFunction(func() <-chan interface{} {
+		ch := make(chan interface{}, 3)
+		ch <- address{City: "Lima"}
+		ch <- 2
+		ch <- errors.New("broken")
+		close(ch)
+		return ch
+	})
+	result, _ := fn.Call(nil)
+	stream, ok := result.(*Stream)
+	if !ok {
+		t.Fatalf("Expected a *Stream, got %T", result)
+	}
+	if v, ok, err := stream.Next(); !ok || err != nil || !reflect.DeepEqual(v, map[string]interface{}{"city": "Lima", "postcode": int64(0)}) {
+		t.Errorf("Next() = %v, %v, %v", v, ok, err)
+	}
+	if v, ok, err := stream.Next(); !ok || err != nil || v != int64(2) {
+		t.Errorf("Next() = %v, %v, %v", v, ok, err)
+	}
+	if _, ok, err := stream.Next(); ok || err == nil || err.Error() != "broken" {
+		t.Errorf("Next() of an error = %v, %v", ok, err)
+	}
+	if _, ok, err := stream.Next(); ok || err != nil {
+		t.Errorf("Next() after the end = %v, %v", ok, err)
+	}
+
+	if _, err := Marshal(make(chan<- int)); err == nil {
+		t.Error("Expected an error for a send-only channel")
+	}
+}
+
+func TestMarshalSlices(t *testing.T) {
+	type celsius float32
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{[]float64{1.5, 2}, []float64{1.5, 2}},
+		{[3]celsius{1, 2, 3}, []float64{1, 2, 3}},
+		{[]int{1, -2}, []int64{1, -2}},
+		{[]uint16{7}, []int64{7}},
+		{[]uint64{1 << 63}, []interface{}{uint64(1 << 63)}},
+		{[]string{"a"}, []string{"a"}},
+		{[]bool{true}, []bool{true}},
+		{[]time.Duration{time.Second}, []interface{}{1000.0}},
+		{[]interface{}{1, "a"}, []interface{}{int64(1), "a"}},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.value)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Marshal(%#v) = %#v, %v, want %#v", tt.value, got, err, tt.want)
+		}
+	}
+
+	// The result doesn't share memory with the slice
+	values := []float64{1}
+	got, _ := Marshal(values)
+	values[0] = 2
+	if got.([]float64)[0] != 1 {
+		t.Error("Expected Marshal to copy the slice")
+	}
+}
@@ -1,5 +1,7 @@
 package plugins
 
+import "context"
+
 // Plugin represents a loadable Go plugin
 type Plugin interface {
 	Name() string
@@ -27,4 +29,36 @@ type PluginExport struct {
 	Value       interface{}
 	IsFunction  bool
 	Description string
-}
\ No newline at end of file
+}
+// TaskRunner is implemented by the runtime passed to a plugin's Initialize.
+// Go runs fn in a background goroutine whose context is cancelled when the
+// runtime shuts down; a panic in fn is reported instead of crashing the
+// process. Plugins should use it rather than starting goroutines that can
+// outlive the runtime.
+type TaskRunner interface {
+	Go(name string, fn func(ctx context.Context))
+}
+
+// Emitter is implemented by the host passed to a plugin's Initialize. Emit
+// delivers a named event with data to the events emitter of the plugin's
+// module, so JS can subscribe with plugin.events.on(event, listener). It is
+// safe to call from any goroutine.
+type Emitter interface {
+	Emit(event string, data interface{})
+}
+
+// BatchQueuer is implemented by the runtime passed to a plugin's
+// Initialize. QueueJSBatch queues fns to run on the JS thread in order as a
+// single operation, each followed by its microtasks, so a producer of many
+// small callbacks pays for one queue slot and one wakeup. See Batch.
+type BatchQueuer interface {
+	QueueJSBatch(fns []func())
+}
+
+// Documented is implemented by plugins that embed the doc comments of their
+// exported Go functions, keyed by Go function name. gode doc -extract
+// generates the Docs and ParamNames functions from the plugin's source.
+type Documented interface {
+	Docs() map[string]string
+	ParamNames() map[string][]string
+}
@@ -26,30 +26,45 @@ func NewRegistry(vm VM, rt interface{}) *Registry {
 
 // LoadPlugin loads a plugin and registers it for JavaScript access
 func (r *Registry) LoadPlugin(path string) (Object, error) {
+	objs, err := r.LoadPlugins(path)
+	if err != nil {
+		return nil, err
+	}
+	return objs[0], nil
+}
+
+// LoadPlugins loads plugins that may depend on each other's services, see
+// Loader.LoadPlugins, and registers each for JavaScript access
+func (r *Registry) LoadPlugins(paths ...string) ([]Object, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	
-	// Load the plugin
-	info, err := r.loader.LoadPlugin(path)
+	// Load the plugins
+	infos, err := r.loader.LoadPlugins(paths...)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Check if already registered
-	if jsObj, exists := r.plugins[info.Name]; exists {
-		return jsObj, nil
+	objs := make([]Object, len(infos))
+	for i, info := range infos {
+		// Check if already registered
+		if jsObj, exists := r.plugins[info.Name]; exists {
+			objs[i] = jsObj
+			continue
+		}
+		
+		// Create JavaScript bindings
+		jsObj, err := r.bridge.WrapPlugin(info.Plugin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JavaScript bindings for %s: %v", info.Name, err)
+		}
+		
+		// Register the plugin
+		r.plugins[info.Name] = jsObj
+		objs[i] = jsObj
 	}
 	
-	// Create JavaScript bindings
-	jsObj, err := r.bridge.WrapPlugin(info.Plugin)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create JavaScript bindings for %s: %v", info.Name, err)
-	}
-	
-	// Register the plugin
-	r.plugins[info.Name] = jsObj
-	
-	return jsObj, nil
+	return objs, nil
 }
 
 // GetPlugin returns the JavaScript object for a loaded plugin
@@ -0,0 +1,181 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/watch"
+)
+
+// reloadDebounce is how long a watched plugin file must be quiet before it
+// is reloaded, so that a build still writing it is not opened half done
+const reloadDebounce = 250 * time.Millisecond
+
+// reloadObserver is implemented by VMs that tell scripts about reloaded
+// plugins, through the plugin's reload event
+type reloadObserver interface {
+	PluginReloaded(info *PluginInfo)
+}
+
+// serviceWithdrawer is implemented by runtimes whose services can be
+// removed, so that a reloaded plugin can provide its services again
+type serviceWithdrawer interface {
+	WithdrawService(name string)
+}
+
+// ReloadPlugin replaces a loaded plugin with the current build of its file.
+// The new build is opened first, and only then is the running instance
+// disposed and the new one initialized in its place, under the same path;
+// the services the old one declared are withdrawn for it. Go cannot unload
+// a plugin or open one file twice, so the file is opened through a copy and
+// the old code stays in memory. Each build also needs a plugin path of its
+// own, which go build gives plugins built from files named on the command
+// line or with -ldflags=-pluginpath=...; otherwise opening it fails with
+// "plugin already loaded" and the running instance is kept.
+func (l *Loader) ReloadPlugin(nameOrPath string) (*PluginInfo, error) {
+	return errors.SafeOperationWithResult("PluginLoader", "ReloadPlugin", func() (*PluginInfo, error) {
+		current, exists := l.GetPlugin(nameOrPath)
+		if !exists {
+			return nil, fmt.Errorf("plugin not found: %s", nameOrPath)
+		}
+
+		dir, err := os.MkdirTemp("", "gode-plugin-")
+		if err != nil {
+			return nil, errors.NewModuleError("plugin", current.Path, "reload", err)
+		}
+		defer os.RemoveAll(dir)
+		// The copy keeps the file name, which names plugins without a Name
+		copyPath := filepath.Join(dir, filepath.Base(current.Path))
+		if err := copyFile(current.Path, copyPath); err != nil {
+			return nil, errors.NewModuleError("plugin", current.Path, "reload", err)
+		}
+		next, err := l.open(copyPath, current.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if current.Plugin != nil {
+			if err := current.Plugin.Dispose(); err != nil {
+				return nil, errors.NewModuleError("plugin", current.Path, "dispose", err).WithSourceContext(fmt.Sprintf("Plugin: %s v%s", current.Name, current.Version))
+			}
+		}
+		if services, ok := l.runtime.(serviceWithdrawer); ok {
+			for _, service := range provides(current) {
+				services.WithdrawService(service)
+			}
+		}
+		delete(l.plugins, current.Path)
+
+		if err := l.initialize(next); err != nil {
+			return nil, err
+		}
+		l.plugins[next.Path] = next
+		return next, nil
+	})
+}
+
+// copyFile copies the file at src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ReloadPlugin reloads a plugin, see Loader.ReloadPlugin, and swaps the
+// exports on its JavaScript object for the new ones in one step, so that
+// code holding the module calls the new build from then on. The module is
+// registered again and, when the VM supports it, the plugin's reload event
+// is emitted. It must be called on the JS thread.
+func (r *Registry) ReloadPlugin(nameOrPath string) (Object, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	previous, exists := r.loader.GetPlugin(nameOrPath)
+	if !exists {
+		return nil, fmt.Errorf("plugin not found: %s", nameOrPath)
+	}
+	var oldExports map[string]interface{}
+	if previous.Plugin != nil {
+		oldExports = previous.Plugin.Exports()
+	}
+
+	info, err := r.loader.ReloadPlugin(previous.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	jsObj, exists := r.plugins[previous.Name]
+	if exists {
+		r.bridge.swapExports(jsObj, oldExports, info.Plugin)
+	} else if jsObj, err = r.bridge.WrapPlugin(info.Plugin); err != nil {
+		return nil, fmt.Errorf("failed to create JavaScript bindings for %s: %v", info.Name, err)
+	}
+	delete(r.plugins, previous.Name)
+	r.plugins[info.Name] = jsObj
+	r.vm.RegisterModule(info.Name, jsObj)
+
+	if observer, ok := r.vm.(reloadObserver); ok {
+		observer.PluginReloaded(info)
+	}
+	return jsObj, nil
+}
+
+// WatchPlugin reloads a loaded plugin whenever its file changes, until stop
+// is called. Reloads run on the VM's queue; those that fail are passed to
+// report, and leave the running instance in place when the new build could
+// not be opened.
+func (r *Registry) WatchPlugin(nameOrPath string, report func(error)) (stop func(), err error) {
+	r.mutex.RLock()
+	info, exists := r.loader.GetPlugin(nameOrPath)
+	r.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("plugin not found: %s", nameOrPath)
+	}
+
+	w, err := watch.New([]string{info.Path}, watch.Options{Debounce: reloadDebounce})
+	if err != nil {
+		return nil, errors.NewModuleError("plugin", info.Path, "watch", err)
+	}
+	path := info.Path
+	go func() {
+		for {
+			select {
+			case _, ok := <-w.Events():
+				if !ok {
+					return
+				}
+				if _, err := os.Stat(path); err != nil {
+					continue // Removed, or not yet replaced
+				}
+				r.vm.QueueJSOperation(func() {
+					if _, err := r.ReloadPlugin(path); err != nil && report != nil {
+						report(err)
+					}
+				})
+			case err, ok := <-w.Errors():
+				if !ok {
+					return
+				}
+				if report != nil {
+					report(err)
+				}
+			}
+		}
+	}()
+	return func() { w.Close() }, nil
+}
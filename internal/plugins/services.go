@@ -0,0 +1,156 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ServiceRegistry is implemented by the runtime passed to a plugin's
+// Initialize. A plugin provides services there for other plugins to use,
+// for example a database plugin providing a connection pool that an ORM
+// plugin looks up:
+//
+//	func Initialize(rt interface{}) error {
+//		services := rt.(plugins.ServiceRegistry)
+//		db, ok := services.GetService("db")
+//		...
+//	}
+type ServiceRegistry interface {
+	GetService(name string) (interface{}, bool)
+	ProvideService(name string, service interface{}) error
+}
+
+// Dependent is implemented by plugins that declare, through optional
+// Requires and Provides functions, the services they need and offer. The
+// Loader uses these declarations to initialize providers first.
+type Dependent interface {
+	Requires() []string
+	Provides() []string
+}
+
+// Services is a concurrency-safe ServiceRegistry
+type Services struct {
+	mu       sync.RWMutex
+	services map[string]interface{}
+}
+
+// NewServices creates an empty service registry
+func NewServices() *Services {
+	return &Services{services: make(map[string]interface{})}
+}
+
+// ProvideService registers service under name. Names are global, so
+// providing the same name twice is an error.
+func (s *Services) ProvideService(name string, service interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.services[name]; exists {
+		return fmt.Errorf("service %q is already provided", name)
+	}
+	s.services[name] = service
+	return nil
+}
+
+// WithdrawService removes the service registered under name, as when the
+// plugin providing it is reloaded
+func (s *Services) WithdrawService(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.services, name)
+}
+
+// GetService returns the service registered under name
+func (s *Services) GetService(name string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	service, exists := s.services[name]
+	return service, exists
+}
+
+// Names returns the registered service names in order
+func (s *Services) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.services))
+	for name := range s.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// orderPlugins sorts plugins so that each comes after the plugins providing
+// the services it requires. Requirements satisfied by available, such as
+// services of plugins loaded earlier, impose no order. Plugins otherwise
+// keep their given order.
+func orderPlugins(infos []*PluginInfo, available func(string) bool) ([]*PluginInfo, error) {
+	providers := make(map[string]*PluginInfo)
+	for _, info := range infos {
+		for _, service := range provides(info) {
+			if other, exists := providers[service]; exists {
+				return nil, fmt.Errorf("service %q is provided by both %s and %s", service, other.Name, info.Name)
+			}
+			providers[service] = info
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*PluginInfo]int)
+	ordered := make([]*PluginInfo, 0, len(infos))
+
+	var visit func(info *PluginInfo) error
+	visit = func(info *PluginInfo) error {
+		switch state[info] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("plugin %s is part of a service dependency cycle", info.Name)
+		}
+		state[info] = visiting
+
+		for _, service := range requires(info) {
+			if provider, ok := providers[service]; ok && provider != info {
+				if err := visit(provider); err != nil {
+					return err
+				}
+				continue
+			}
+			if available == nil || !available(service) {
+				return fmt.Errorf("plugin %s requires service %q, which no loaded plugin provides", info.Name, service)
+			}
+		}
+
+		state[info] = visited
+		ordered = append(ordered, info)
+		return nil
+	}
+
+	for _, info := range infos {
+		if err := visit(info); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func requires(info *PluginInfo) []string {
+	if d, ok := info.Plugin.(Dependent); ok {
+		return d.Requires()
+	}
+	return nil
+}
+
+func provides(info *PluginInfo) []string {
+	if d, ok := info.Plugin.(Dependent); ok {
+		return d.Provides()
+	}
+	return nil
+}
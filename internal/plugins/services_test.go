@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+type dependentPlugin struct {
+	directPlugin
+	requires []string
+	provides []string
+}
+
+func (p *dependentPlugin) Requires() []string { return p.requires }
+func (p *dependentPlugin) Provides() []string { return p.provides }
+
+func dependent(name string, requires, provides []string) *PluginInfo {
+	return &PluginInfo{
+		Name:   name,
+		Plugin: &dependentPlugin{directPlugin: directPlugin{name: name}, requires: requires, provides: provides},
+	}
+}
+
+func names(infos []*PluginInfo) string {
+	list := make([]string, len(infos))
+	for i, info := range infos {
+		list[i] = info.Name
+	}
+	return strings.Join(list, ",")
+}
+
+func TestServices(t *testing.T) {
+	services := NewServices()
+	if err := services.ProvideService("db", 1); err != nil {
+		t.Fatalf("ProvideService() failed: %v", err)
+	}
+	if err := services.ProvideService("db", 2); err == nil {
+		t.Error("Expected providing a service twice to fail")
+	}
+	if svc, ok := services.GetService("db"); !ok || svc != 1 {
+		t.Errorf("GetService(db) = %v, %v", svc, ok)
+	}
+	if _, ok := services.GetService("cache"); ok {
+		t.Error("Expected no cache service")
+	}
+
+	// A withdrawn service can be provided again, as a reloaded plugin does
+	services.WithdrawService("db")
+	if _, ok := services.GetService("db"); ok {
+		t.Error("Expected db to be withdrawn")
+	}
+	if err := services.ProvideService("db", 3); err != nil {
+		t.Errorf("ProvideService() after WithdrawService() failed: %v", err)
+	}
+}
+
+func TestOrderPlugins(t *testing.T) {
+	orm := dependent("orm", []string{"db", "log"}, nil)
+	db := dependent("db", []string{"log"}, []string{"db"})
+	plain := dependent("plain", nil, nil)
+	available := func(name string) bool { return name == "log" }
+
+	ordered, err := orderPlugins([]*PluginInfo{orm, plain, db}, available)
+	if err != nil {
+		t.Fatalf("orderPlugins() failed: %v", err)
+	}
+	if got := names(ordered); got != "db,orm,plain" {
+		t.Errorf("Expected db,orm,plain, got %s", got)
+	}
+
+	if _, err := orderPlugins([]*PluginInfo{orm}, available); err == nil || !strings.Contains(err.Error(), `"db"`) {
+		t.Errorf("Expected a missing service error, got %v", err)
+	}
+
+	a := dependent("a", []string{"b"}, []string{"a"})
+	b := dependent("b", []string{"a"}, []string{"b"})
+	if _, err := orderPlugins([]*PluginInfo{a, b}, nil); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package plugins
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var tableType = reflect.TypeOf(Table{})
+
+// Table is a result set held by column, such as the rows of a query. It
+// reaches JS as an array of objects, one per row, keyed by Columns;
+// converting it builds each column once instead of a map per row.
+//
+// Data holds one slice or array per column, all of the same length.
+// Columns of numbers, strings and booleans convert fastest; other columns
+// are marshaled item by item.
+type Table struct {
+	Columns []string
+	Data    []interface{}
+}
+
+// Len returns the number of rows in t
+func (t Table) Len() int {
+	if len(t.Data) == 0 {
+		return 0
+	}
+	v := reflect.ValueOf(t.Data[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return 0
+	}
+	return v.Len()
+}
+
+// marshalTable checks the shape of t and marshals its columns, each to a
+// []float64, []int64, []string, []bool or []interface{}
+func marshalTable(t Table, depth int) (interface{}, error) {
+	if len(t.Data) != len(t.Columns) {
+		return nil, fmt.Errorf("table has %d columns but data for %d", len(t.Columns), len(t.Data))
+	}
+	rows := t.Len()
+	data := make([]interface{}, len(t.Data))
+	for i, column := range t.Data {
+		v := reflect.ValueOf(column)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("table column %q is not a slice", t.Columns[i])
+		}
+		if v.Len() != rows {
+			return nil, fmt.Errorf("table column %q has %d rows, want %d", t.Columns[i], v.Len(), rows)
+		}
+		if items, ok := marshalSlice(v); ok {
+			data[i] = items
+			continue
+		}
+		items := make([]interface{}, rows)
+		for j := range items {
+			item, err := marshal(v.Index(j), depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("table column %q: %w", t.Columns[i], err)
+			}
+			items[j] = item
+		}
+		data[i] = items
+	}
+	return Table{Columns: t.Columns, Data: data}, nil
+}
@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalTable(t *testing.T) {
+	born := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	table := &Table{
+		Columns: []string{"id", "name", "score", "born"},
+		Data: []interface{}{
+			[]int32{1, 2},
+			[]string{"ann", "bo"},
+			[2]float64{0.5, 1},
+			[]interface{}{born, nil},
+		},
+	}
+	if table.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", table.Len())
+	}
+	got, err := Marshal(table)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	want := Table{
+		Columns: table.Columns,
+		Data: []interface{}{
+			[]int64{1, 2},
+			[]string{"ann", "bo"},
+			[]float64{0.5, 1},
+			[]interface{}{born, nil},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal() = %#v, want %#v", got, want)
+	}
+
+	if (Table{}).Len() != 0 {
+		t.Error("Expected an empty table to have no rows")
+	}
+	// Tables nested in other results are converted too
+	if got, _ := Marshal(map[string]Table{"t": {Columns: []string{"a"}, Data: []interface{}{[]bool{true}}}}); !reflect.DeepEqual(got, map[string]interface{}{
+		"t": Table{Columns: []string{"a"}, Data: []interface{}{[]bool{true}}},
+	}) {
+		t.Errorf("Marshal() of a nested table = %#v", got)
+	}
+}
+
+func TestMarshalTableErrors(t *testing.T) {
+	tests := []struct {
+		table Table
+		want  string
+	}{
+		{Table{Columns: []string{"a", "b"}, Data: []interface{}{[]int{1}}}, "table has 2 columns but data for 1"},
+		{Table{Columns: []string{"a", "b"}, Data: []interface{}{[]int{1}, []int{1, 2}}}, `table column "b" has 2 rows, want 1`},
+		{Table{Columns: []string{"a"}, Data: []interface{}{3}}, `table column "a" is not a slice`},
+		{Table{Columns: []string{"a"}, Data: []interface{}{[]chan<- int{make(chan<- int)}}}, `table column "a": send-only channels can't be returned to JS`},
+	}
+	for _, tt := range tests {
+		if _, err := Marshal(tt.table); err == nil || err.Error() != tt.want {
+			t.Errorf("Marshal(%v) = %v, want %s", tt.table, err, tt.want)
+		}
+	}
+}
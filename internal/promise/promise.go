@@ -0,0 +1,126 @@
+// Package promise lets Go code return a JS Promise and settle it later
+// from any goroutine. Settling always happens on the JS thread, through the
+// runtime's operation queue.
+package promise
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+)
+
+// Queue runs functions on the JS thread; the runtime implements it
+type Queue interface {
+	QueueJSOperation(fn func())
+}
+
+// keeper is implemented by runtimes that stay alive while resources are
+// open, so a script does not end with a promise still pending
+type keeper interface {
+	KeepAlive() (release func())
+}
+
+// Resolver settles one promise. Its methods may be called from any
+// goroutine; only the first call has an effect.
+type Resolver struct {
+	vm      *goja.Runtime
+	queue   Queue
+	resolve func(interface{}) error
+	reject  func(interface{}) error
+	release func()
+	settled int32
+}
+
+// New creates a pending promise and its resolver. It must be called on the
+// JS thread. If queue supports KeepAlive, the runtime stays alive until the
+// promise is settled.
+func New(vm *goja.Runtime, queue Queue) (goja.Value, *Resolver) {
+	p, resolve, reject := vm.NewPromise()
+	r := &Resolver{vm: vm, queue: queue, resolve: resolve, reject: reject, release: func() {}}
+	if k, ok := queue.(keeper); ok {
+		r.release = k.KeepAlive()
+	}
+	return vm.ToValue(p), r
+}
+
+// Run calls fn in a new goroutine and settles the returned promise with its
+// result. It must be called on the JS thread.
+func Run(vm *goja.Runtime, queue Queue, fn func() (interface{}, error)) goja.Value {
+	p, r := New(vm, queue)
+	go func() {
+		result, err := fn()
+		r.Settle(result, err)
+	}()
+	return p
+}
+
+// Resolve fulfills the promise with value, converted to JS on the JS thread
+func (r *Resolver) Resolve(value interface{}) {
+	r.SettleWith(func() (interface{}, error) { return value, nil })
+}
+
+// Reject rejects the promise. An error becomes a JS Error whose message is
+// err.Error(); other values are passed as they are.
+func (r *Resolver) Reject(reason interface{}) {
+	r.settle(func() {
+		if err, ok := reason.(error); ok {
+			r.reject(r.errorValue(err))
+			return
+		}
+		r.reject(r.vm.ToValue(reason))
+	})
+}
+
+// Settle rejects the promise if err is not nil and resolves it with value
+// otherwise
+func (r *Resolver) Settle(value interface{}, err error) {
+	r.SettleWith(func() (interface{}, error) { return value, err })
+}
+
+// SettleWith settles the promise with the result of fn, which runs on the
+// JS thread and may therefore create JS objects
+func (r *Resolver) SettleWith(fn func() (interface{}, error)) {
+	r.settle(func() {
+		value, err := fn()
+		if err != nil {
+			r.reject(r.errorValue(err))
+			return
+		}
+		r.resolve(r.vm.ToValue(value))
+	})
+}
+
+// settle queues fn once
+func (r *Resolver) settle(fn func()) {
+	if !atomic.CompareAndSwapInt32(&r.settled, 0, 1) {
+		return
+	}
+	r.queue.QueueJSOperation(func() {
+		defer r.release()
+		fn()
+	})
+}
+
+// errorValue converts err to a JS Error whose cause chain follows the
+// errors err wraps. Errors that wrap a JS value, such as exceptions thrown
+// by a callback or the reason of an aborted signal, are rejected with that
+// value.
+func (r *Resolver) errorValue(err error) goja.Value {
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		return exception.Value()
+	}
+	var carrier valueCarrier
+	if errors.As(err, &carrier) {
+		return carrier.JSValue()
+	}
+	return jserror.New(r.vm, err)
+}
+
+// valueCarrier is implemented by errors standing for a JS value, such as
+// *abort.Error
+type valueCarrier interface {
+	JSValue() goja.Value
+}
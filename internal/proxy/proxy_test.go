@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// clearEnvironment unsets the proxy variables for the test
+func clearEnvironment(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy", "REQUEST_METHOD"} {
+		t.Setenv(name, "")
+	}
+}
+
+func proxyFor(t *testing.T, rawURL string) string {
+	t.Helper()
+	req, _ := http.NewRequest("GET", rawURL, nil)
+	u, err := Func(req)
+	if err != nil {
+		t.Fatalf("Func(%s) failed: %v", rawURL, err)
+	}
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func TestConfigure(t *testing.T) {
+	clearEnvironment(t)
+	defer Configure(config.NetworkConfig{})
+
+	Configure(config.NetworkConfig{
+		Proxy:   "http://proxy.internal:3128",
+		NoProxy: []string{"internal.example.com", ".corp"},
+	})
+	tests := map[string]string{
+		"http://api.example.com/":         "http://proxy.internal:3128",
+		"https://api.example.com/":        "http://proxy.internal:3128",
+		"https://internal.example.com/":   "",
+		"http://build.corp/artifacts.tgz": "",
+	}
+	for target, want := range tests {
+		if got := proxyFor(t, target); got != want {
+			t.Errorf("proxy for %s = %q, want %q", target, got, want)
+		}
+	}
+
+	// The environment takes precedence over the config
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:8080")
+	t.Setenv("NO_PROXY", "api.example.com")
+	Configure(config.NetworkConfig{Proxy: "http://proxy.internal:3128"})
+	if got := proxyFor(t, "https://other.example.com/"); got != "http://env-proxy:8080" {
+		t.Errorf("HTTPS proxy = %q, want the HTTPS_PROXY value", got)
+	}
+	if got := proxyFor(t, "http://other.example.com/"); got != "http://proxy.internal:3128" {
+		t.Errorf("HTTP proxy = %q, want the config value", got)
+	}
+	if got := proxyFor(t, "https://api.example.com/"); got != "" {
+		t.Errorf("Expected NO_PROXY to bypass the proxy, got %q", got)
+	}
+}
+
+func TestClientThroughProxy(t *testing.T) {
+	clearEnvironment(t)
+	defer Configure(config.NetworkConfig{})
+
+	var mu sync.Mutex
+	var seen []string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.RequestURI+" "+r.Header.Get("Proxy-Authorization"))
+		mu.Unlock()
+		if r.Method == http.MethodConnect {
+			http.Error(w, "tunnels refused in this test", http.StatusForbidden)
+			return
+		}
+		io.WriteString(w, "via proxy")
+	}))
+	defer proxyServer.Close()
+
+	u, _ := url.Parse(proxyServer.URL)
+	u.User = url.UserPassword("user", "secret")
+	Configure(config.NetworkConfig{Proxy: u.String()})
+
+	client := Client(5 * time.Second)
+	resp, err := client.Get("http://service.example.test/data")
+	if err != nil {
+		t.Fatalf("GET through proxy failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "via proxy" {
+		t.Errorf("body = %q", body)
+	}
+
+	// TLS requests open a CONNECT tunnel
+	if _, err := client.Get("https://service.example.test/data"); err == nil {
+		t.Error("Expected the refused tunnel to fail the request")
+	}
+
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	want := []string{
+		"GET http://service.example.test/data " + auth,
+		"CONNECT service.example.test:443 " + auth,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(seen, "\n") != strings.Join(want, "\n") {
+		t.Errorf("proxy saw:\n%s\nwant:\n%s", strings.Join(seen, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jsprogram"
+)
+
+// blobSetup defines Blob, URL.createObjectURL and URL.revokeObjectURL. It
+// is given the native functions encode(string) and decode(buffer), which
+// convert between strings and UTF-8, and id(), and returns the Blob class
+// with contents(blob), the bytes and type of a Blob, and resolve(url), the
+// Blob behind a blob: URL, for Worker.
+const blobSetup = `(function (native, URL) {
+	"use strict";
+	const contents = new WeakMap();
+	const objectURLs = new Map();
+
+	function toBuffer(part) {
+		if (part instanceof Blob) {
+			return contents.get(part).buffer;
+		}
+		if (part instanceof ArrayBuffer) {
+			return part;
+		}
+		if (ArrayBuffer.isView(part)) {
+			return part.buffer.slice(part.byteOffset, part.byteOffset + part.byteLength);
+		}
+		return native.encode(String(part));
+	}
+
+	function get(blob) {
+		const data = contents.get(blob);
+		if (!data) {
+			throw new TypeError("Illegal invocation: not a Blob");
+		}
+		return data;
+	}
+
+	class Blob {
+		constructor(parts = [], options = {}) {
+			if (parts === null || typeof parts !== "object" || typeof parts[Symbol.iterator] !== "function") {
+				throw new TypeError("Failed to construct 'Blob': the parts must be iterable");
+			}
+			const buffers = Array.from(parts, toBuffer);
+			const bytes = new Uint8Array(buffers.reduce((size, buffer) => size + buffer.byteLength, 0));
+			let offset = 0;
+			for (const buffer of buffers) {
+				bytes.set(new Uint8Array(buffer), offset);
+				offset += buffer.byteLength;
+			}
+			// Types outside printable ASCII are dropped, as in browsers
+			let type = options && options.type !== undefined ? String(options.type) : "";
+			type = /^[\x20-\x7e]*$/.test(type) ? type.toLowerCase() : "";
+			contents.set(this, { buffer: bytes.buffer, type });
+		}
+
+		get size() {
+			return get(this).buffer.byteLength;
+		}
+
+		get type() {
+			return get(this).type;
+		}
+
+		slice(start, end, type) {
+			return new Blob([new Uint8Array(get(this).buffer).slice(start, end)], { type });
+		}
+
+		text() {
+			return Promise.resolve(native.decode(get(this).buffer));
+		}
+
+		arrayBuffer() {
+			return Promise.resolve(get(this).buffer.slice(0));
+		}
+
+		bytes() {
+			return Promise.resolve(new Uint8Array(get(this).buffer.slice(0)));
+		}
+
+		get [Symbol.toStringTag]() {
+			return "Blob";
+		}
+	}
+
+	URL.createObjectURL = function createObjectURL(blob) {
+		if (!(blob instanceof Blob)) {
+			throw new TypeError("URL.createObjectURL() expects a Blob");
+		}
+		const url = "blob:nodedata:" + native.id();
+		objectURLs.set(url, blob);
+		return url;
+	};
+	URL.revokeObjectURL = function revokeObjectURL(url) {
+		objectURLs.delete(String(url));
+	};
+
+	return {
+		Blob,
+		contents: (blob) => blob instanceof Blob ? contents.get(blob) : undefined,
+		resolve: (url) => objectURLs.get(String(url)),
+	};
+})`
+
+// setupBlob installs Blob and the object URL functions of URL. It must be
+// called on the JS thread, after URL has been defined.
+func (r *Runtime) setupBlob() error {
+	vm := r.runtime
+	setup, err := jsprogram.Run(vm, "gode:blob", blobSetup)
+	if err != nil {
+		return err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("blob setup is not a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("encode", func(s string) goja.ArrayBuffer {
+		return vm.NewArrayBuffer([]byte(s))
+	})
+	native.Set("decode", func(buffer goja.ArrayBuffer) string {
+		return string(buffer.Bytes())
+	})
+	native.Set("id", func() string {
+		id := make([]byte, 16)
+		rand.Read(id)
+		return hex.EncodeToString(id)
+	})
+
+	blobs, err := fn(goja.Undefined(), native, vm.Get("URL"))
+	if err != nil {
+		return err
+	}
+	r.blobs = blobs.ToObject(vm)
+	return vm.Set("Blob", r.blobs.Get("Blob"))
+}
+
+// blobContents returns the bytes and type of a Blob, or of the Blob a
+// blob: URL was created for. It must be called on the JS thread.
+func (r *Runtime) blobContents(value goja.Value) (data []byte, mediaType string, ok bool) {
+	if r.blobs == nil {
+		return nil, "", false
+	}
+	if s, isString := value.Export().(string); isString {
+		resolve, _ := goja.AssertFunction(r.blobs.Get("resolve"))
+		if value, _ = resolve(goja.Undefined(), r.runtime.ToValue(s)); value == nil || goja.IsUndefined(value) {
+			return nil, "", false
+		}
+	}
+	get, _ := goja.AssertFunction(r.blobs.Get("contents"))
+	contents, err := get(goja.Undefined(), value)
+	if err != nil || contents == nil || goja.IsUndefined(contents) {
+		return nil, "", false
+	}
+	obj := contents.ToObject(r.runtime)
+	buffer, _ := obj.Get("buffer").Export().(goja.ArrayBuffer)
+	return buffer.Bytes(), obj.Get("type").String(), true
+}
@@ -0,0 +1,204 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// runContext runs fn on the JS thread and interrupts the JavaScript it runs
+// when ctx is done. If ctx ends before fn has started, fn is skipped and an
+// error wrapping both ErrBusy and ctx.Err() is returned. If fn is
+// interrupted, its goja.InterruptedError is replaced by ctx.Err().
+func (r *Runtime) runContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// state guards the interrupt so that cancelling ctx only stops fn and
+	// never code queued after it
+	const (
+		queued = iota
+		running
+		interrupted
+		abandoned
+		finished
+	)
+	var mu sync.Mutex
+	state := queued
+	done := make(chan error, 1)
+
+	r.QueueJSOperation(func() {
+		mu.Lock()
+		if state == abandoned {
+			mu.Unlock()
+			return
+		}
+		state = running
+		mu.Unlock()
+
+		err := fn()
+
+		mu.Lock()
+		if state == interrupted {
+			r.runtime.ClearInterrupt()
+			var interruptErr *goja.InterruptedError
+			if errors.As(err, &interruptErr) {
+				err = ctx.Err()
+			}
+		}
+		state = finished
+		mu.Unlock()
+		done <- err
+	})
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	switch state {
+	case queued:
+		state = abandoned
+		mu.Unlock()
+		return fmt.Errorf("%w: %w", ErrBusy, ctx.Err())
+	case running:
+		state = interrupted
+		r.interrupt(ctx.Err())
+	}
+	mu.Unlock()
+	return <-done
+}
+
+// RunScriptContext is RunScript with a deadline: the script is interrupted
+// when ctx is cancelled or times out, and ctx.Err() is returned
+func (r *Runtime) RunScriptContext(ctx context.Context, name, source string) (interface{}, error) {
+	var result interface{}
+	err := r.runContext(ctx, func() error {
+		value, err := r.runtime.RunScript(name, source)
+		if err != nil {
+			return err
+		}
+		result = value.Export()
+		return nil
+	})
+	return result, err
+}
+
+// ExecuteScriptContext is ExecuteScript with a deadline, like
+// RunScriptContext
+func (r *Runtime) ExecuteScriptContext(ctx context.Context, name, source string) error {
+	if r.runtime == nil {
+		return fmt.Errorf("runtime not initialized")
+	}
+	err := r.runContext(ctx, func() error {
+		_, err := r.runtime.RunScript(name, source)
+		return err
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("execution error: %w", err)
+	}
+	return err
+}
+
+// CallJSFunctionContext is CallJSFunction with a deadline: the function is
+// interrupted when ctx is cancelled or times out, and ctx.Err() is returned
+func (r *Runtime) CallJSFunctionContext(ctx context.Context, fn interface{}) error {
+	return r.runContext(ctx, func() error {
+		_, err := r.callJS(fn, nil)
+		return err
+	})
+}
+
+// CallJSFunctionWithArgs calls a JavaScript function with args converted to
+// JS values and returns its result exported to Go. An exception it throws
+// is returned as a *goja.Exception. It may be called from any goroutine
+// except the JS thread.
+func (r *Runtime) CallJSFunctionWithArgs(fn interface{}, args ...interface{}) (interface{}, error) {
+	var result interface{}
+	err := r.callOnJSThread(fn, args, func(value goja.Value) error {
+		result = exportValue(value)
+		return nil
+	})
+	return result, err
+}
+
+// CallJSFunctionAs calls a JavaScript function like CallJSFunctionWithArgs
+// and converts its result to T, as goja's ExportTo does: JS objects to
+// structs and maps, arrays to slices, functions to Go func types.
+func CallJSFunctionAs[T any](r *Runtime, fn interface{}, args ...interface{}) (T, error) {
+	var result T
+	err := r.callOnJSThread(fn, args, func(value goja.Value) error {
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			return nil
+		}
+		if err := r.runtime.ExportTo(value, &result); err != nil {
+			return fmt.Errorf("cannot convert function result to %T: %w", result, err)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// callOnJSThread calls fn with args on the JS thread and passes the result
+// to convert there
+func (r *Runtime) callOnJSThread(fn interface{}, args []interface{}, convert func(goja.Value) error) error {
+	done := make(chan error, 1)
+	r.QueueJSOperation(func() {
+		values := make([]goja.Value, len(args))
+		for i, arg := range args {
+			values[i] = r.runtime.ToValue(arg)
+		}
+		value, err := r.callJS(fn, values)
+		if err == nil {
+			err = convert(value)
+		}
+		done <- err
+	})
+	return <-done
+}
+
+// exportValue returns the Go value of a JS value, with nil for undefined
+func exportValue(value goja.Value) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.Export()
+}
+
+// callJS calls a JavaScript function, given as a goja value or as the Go
+// function goja exports it as, with this set to the global object. A
+// thrown exception is returned as the error. It must run on the JS thread.
+func (r *Runtime) callJS(fn interface{}, args []goja.Value) (result goja.Value, err error) {
+	if value, ok := fn.(goja.Value); ok {
+		callable, ok := goja.AssertFunction(value)
+		if !ok {
+			return nil, fmt.Errorf("cannot call JavaScript value %s: not a function", value)
+		}
+		return callable(r.runtime.GlobalObject(), args...)
+	}
+
+	jsFunc, ok := fn.(func(goja.FunctionCall) goja.Value)
+	if !ok {
+		return nil, fmt.Errorf("cannot call JavaScript function (type: %T)", fn)
+	}
+	// Exported functions report exceptions by panicking
+	defer func() {
+		if x := recover(); x != nil {
+			switch e := x.(type) {
+			case *goja.Exception:
+				err = e
+			case *goja.InterruptedError:
+				err = e
+			default:
+				panic(x)
+			}
+		}
+	}()
+	return jsFunc(goja.FunctionCall{This: r.runtime.GlobalObject(), Arguments: args}), nil
+}
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRuntimeContextVariants(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	result, err := rt.RunScriptContext(context.Background(), "sum", "1 + 2")
+	if err != nil || result != int64(3) {
+		t.Fatalf("RunScriptContext() = %v, %v", result, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := rt.RunScriptContext(ctx, "loop", "for (;;) {}"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	// The interrupt must not leak into later scripts
+	if err := rt.ExecuteScriptContext(context.Background(), "after", "globalThis.spin = () => { for (;;) {} }"); err != nil {
+		t.Fatalf("ExecuteScriptContext() failed: %v", err)
+	}
+
+	spin, err := rt.RunScript("get", "spin")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := rt.CallJSFunctionContext(ctx, spin); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Canceled, got %v", err)
+	}
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if err := rt.ExecuteScriptContext(cancelled, "never", "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an already cancelled context to fail, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package runtime
+
+import (
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// Fast paths for converting large results to JS. ToValue wraps a Go slice
+// in an object that reflects on every access, and converting through
+// []interface{} boxes every item; these build real arrays from the typed
+// items instead.
+
+// floatArray converts values to a JS array of numbers
+func (r *Runtime) floatArray(values []float64) *goja.Object {
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		items[i] = r.runtime.ToValue(v)
+	}
+	return r.runtime.NewArray(items...)
+}
+
+// intArray converts values to a JS array of numbers
+func (r *Runtime) intArray(values []int64) *goja.Object {
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		items[i] = r.runtime.ToValue(v)
+	}
+	return r.runtime.NewArray(items...)
+}
+
+// stringArray converts values to a JS array of strings
+func (r *Runtime) stringArray(values []string) *goja.Object {
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		items[i] = r.runtime.ToValue(v)
+	}
+	return r.runtime.NewArray(items...)
+}
+
+// boolArray converts values to a JS array of booleans
+func (r *Runtime) boolArray(values []bool) *goja.Object {
+	t, f := r.runtime.ToValue(true), r.runtime.ToValue(false)
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		if v {
+			items[i] = t
+		} else {
+			items[i] = f
+		}
+	}
+	return r.runtime.NewArray(items...)
+}
+
+// columnValues converts a column of a marshaled plugins.Table to JS values
+func (r *Runtime) columnValues(column interface{}) []goja.Value {
+	var values []goja.Value
+	switch items := column.(type) {
+	case []float64:
+		values = make([]goja.Value, len(items))
+		for i, v := range items {
+			values[i] = r.runtime.ToValue(v)
+		}
+	case []int64:
+		values = make([]goja.Value, len(items))
+		for i, v := range items {
+			values[i] = r.runtime.ToValue(v)
+		}
+	case []string:
+		values = make([]goja.Value, len(items))
+		for i, v := range items {
+			values[i] = r.runtime.ToValue(v)
+		}
+	case []bool:
+		values = make([]goja.Value, len(items))
+		for i, v := range items {
+			values[i] = r.runtime.ToValue(v)
+		}
+	case []interface{}:
+		values = make([]goja.Value, len(items))
+		for i, v := range items {
+			values[i] = r.pluginValue(v)
+		}
+	}
+	return values
+}
+
+// tableArray converts a marshaled plugins.Table to an array of row
+// objects. Each column is converted once, and the rows are filled from
+// the converted columns without building a map per row.
+func (r *Runtime) tableArray(table plugins.Table) *goja.Object {
+	columns := make([][]goja.Value, len(table.Data))
+	for i, column := range table.Data {
+		columns[i] = r.columnValues(column)
+	}
+	rows := make([]interface{}, table.Len())
+	for row := range rows {
+		obj := r.runtime.NewObject()
+		for i, name := range table.Columns {
+			obj.Set(name, columns[i][row])
+		}
+		rows[row] = obj
+	}
+	return r.runtime.NewArray(rows...)
+}
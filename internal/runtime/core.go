@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	goruntime "runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+)
+
+// Version is the gode release reported by gode:core and the CLI
+const Version = "0.1.0-dev"
+
+// setupCoreModule registers gode:core, which describes the running runtime
+// for diagnostics endpoints and scripts
+func (r *Runtime) setupCoreModule() {
+	done := make(chan struct{})
+	r.QueueJSOperation(func() {
+		defer close(done)
+
+		module := r.runtime.NewObject()
+		module.Set("version", Version)
+		module.Set("platform", "gode")
+		module.Set("versions", map[string]interface{}{
+			"gode": Version,
+			"goja": gojaVersion(),
+			"go":   goruntime.Version(),
+		})
+		module.Set("plugins", r.corePlugins)
+		module.Set("reloadPlugin", r.coreReloadPlugin)
+		module.Set("modules", r.coreModules)
+		module.Set("tuning", coreTuning)
+		r.modules["gode:core"] = r.runtime.ToValue(module)
+	})
+	<-done
+}
+
+// corePlugins implements core.plugins()
+func (r *Runtime) corePlugins() []interface{} {
+	list := []interface{}{}
+	if r.moduleManager == nil {
+		return list
+	}
+	for _, info := range r.moduleManager.Plugins() {
+		list = append(list, map[string]interface{}{
+			"name":        info.Name,
+			"version":     info.Version,
+			"path":        info.Path,
+			"initialized": info.Initialized,
+		})
+	}
+	return list
+}
+
+// coreReloadPlugin implements core.reloadPlugin(nameOrPath), which loads
+// the current build of a plugin's file in place of the running one and
+// returns its module, whose exports are swapped for the new ones
+func (r *Runtime) coreReloadPlugin(nameOrPath string) goja.Value {
+	if r.moduleManager == nil {
+		panic(r.runtime.NewTypeError("plugins are not available"))
+	}
+	obj, err := r.moduleManager.ReloadPlugin(nameOrPath)
+	if err != nil {
+		panic(jserror.New(r.runtime, err))
+	}
+	if gObj, ok := obj.(*gojaObject); ok {
+		return gObj.obj
+	}
+	return r.runtime.ToValue(obj)
+}
+
+// coreModules implements core.modules(). Sizes are in bytes and load times
+// in milliseconds.
+func (r *Runtime) coreModules() []interface{} {
+	list := []interface{}{}
+	if r.moduleManager == nil {
+		return list
+	}
+	for _, mod := range r.moduleManager.Modules() {
+		list = append(list, map[string]interface{}{
+			"specifier": mod.Specifier,
+			"path":      mod.Path,
+			"size":      mod.Size,
+			"loadTime":  float64(mod.LoadTime) / float64(time.Millisecond),
+			"loadedAt":  r.date(mod.LoadedAt),
+		})
+	}
+	return list
+}
+
+// coreTuning implements core.tuning(), the Go runtime settings in use. A
+// gcPercent of -1 means the garbage collector is off.
+func coreTuning() map[string]interface{} {
+	t := CurrentTuning()
+	return map[string]interface{}{
+		"maxProcs":  t.MaxProcs,
+		"gcPercent": t.GCPercent,
+		"numCPU":    goruntime.NumCPU(),
+	}
+}
+
+func (r *Runtime) date(t time.Time) goja.Value {
+	date, err := r.runtime.New(r.runtime.Get("Date"), r.runtime.ToValue(t.UnixMilli()))
+	if err != nil {
+		return goja.Undefined()
+	}
+	return date
+}
+
+// gojaVersion reads the JavaScript engine's module version from the build
+func gojaVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != "github.com/rizqme/gode/goja" && dep.Path != "github.com/dop251/goja" {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return "unknown"
+}
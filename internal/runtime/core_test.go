@@ -0,0 +1,36 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestRuntimeCoreMetadata(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.js")
+	if err := os.WriteFile(lib, []byte("module.exports = 42;"), 0644); err != nil {
+		t.Fatalf("Failed to write module: %v", err)
+	}
+
+	source := `
+		require(` + strconv.Quote(lib) + `);
+		const core = require('gode:core');
+		const mod = core.modules().find(m => m.path === ` + strconv.Quote(lib) + `);
+		[core.versions.gode, typeof core.versions.go, core.plugins().length, mod.size, mod.loadedAt instanceof Date].join(',');
+	`
+	result, err := rt.RunScript("core", source)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if want := Version + ",string,0,20,true"; result != want {
+		t.Errorf("Expected %q, got %v", want, result)
+	}
+}
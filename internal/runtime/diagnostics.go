@@ -0,0 +1,37 @@
+package runtime
+
+import (
+	"time"
+)
+
+// HasDiagnosticSubscribers reports whether a script subscribed to the
+// gode:diagnostics_channel channel. Publishers check it before doing work,
+// such as timing a call, that only a subscriber needs.
+func (r *Runtime) HasDiagnosticSubscribers(channel string) bool {
+	return r.diagnostics != nil && r.diagnostics.HasSubscribers(channel)
+}
+
+// PublishDiagnostic publishes the message built by message on channel. It
+// must be called on the JS thread; message is only called when there are
+// subscribers.
+func (r *Runtime) PublishDiagnostic(channel string, message func() map[string]interface{}) {
+	if r.diagnostics != nil {
+		r.diagnostics.Publish(channel, message)
+	}
+}
+
+// publishModuleLoad publishes a require handled by the module manager on
+// module.load. thrown is what require is throwing, if it failed.
+func (r *Runtime) publishModuleLoad(specifier, fileName string, start time.Time, thrown interface{}) {
+	r.PublishDiagnostic("module.load", func() map[string]interface{} {
+		message := map[string]interface{}{
+			"specifier": specifier,
+			"filename":  fileName,
+			"duration":  float64(time.Since(start)) / float64(time.Millisecond),
+		}
+		if thrown != nil {
+			message["error"] = thrown
+		}
+		return message
+	})
+}
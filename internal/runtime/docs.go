@@ -0,0 +1,42 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rizqme/gode/internal/docgen"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// DocModules returns the API documentation of every gode: built-in module
+// and every plugin loaded so far
+func (r *Runtime) DocModules() []docgen.Module {
+	done := make(chan []docgen.Module, 1)
+	r.QueueJSOperation(func() {
+		var modules []docgen.Module
+
+		names := make([]string, 0, len(r.modules))
+		for name := range r.modules {
+			if strings.HasPrefix(name, "gode:") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			modules = append(modules, docgen.Builtin(name, r.modules[name]))
+		}
+
+		if r.moduleManager != nil {
+			for _, info := range r.moduleManager.Plugins() {
+				var docs map[string]string
+				var params map[string][]string
+				if documented, ok := info.Plugin.(plugins.Documented); ok {
+					docs, params = documented.Docs(), documented.ParamNames()
+				}
+				modules = append(modules, docgen.Plugin(info.Name, info.Version, info.Plugin.Exports(), docs, params))
+			}
+		}
+		done <- modules
+	})
+	return <-done
+}
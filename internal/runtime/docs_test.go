@@ -0,0 +1,35 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/docgen"
+)
+
+func TestRuntimeDocModules(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	var core *docgen.Module
+	for _, mod := range rt.DocModules() {
+		if mod.Name == "gode:core" {
+			core = &mod
+			break
+		}
+	}
+	if core == nil {
+		t.Fatal("Expected docs for gode:core")
+	}
+	found := false
+	for _, m := range core.Members {
+		if m.Name == "plugins" && m.Kind == "function" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected gode:core to document plugins(), got %+v", core.Members)
+	}
+}
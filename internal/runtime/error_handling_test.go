@@ -1,12 +1,14 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/rizqme/gode/goja"
 	"github.com/rizqme/gode/pkg/config"
 )
 
@@ -204,7 +206,7 @@ func TestRuntimeCreateModuleErrorFromJS(t *testing.T) {
 			}
 
 			// Check that stack trace was captured
-			if len(moduleErr.StackTrace.Frames) == 0 {
+			if len(moduleErr.Stack().Frames) == 0 {
 				t.Error("Expected stack trace to be captured")
 			}
 		})
@@ -697,4 +699,49 @@ func TestRuntimeConcurrentErrorHandling(b *testing.T) {
 	if errorCount != numOperations {
 		b.Errorf("Expected %d errors, got %d", numOperations, errorCount)
 	}
-}
\ No newline at end of file
+}
+
+func TestRuntimeErrorCause(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	// A Go error chain becomes nested causes in JS
+	root := errors.New("connection refused")
+	rt.SetGlobal("query", func() goja.Value {
+		p, resolver := rt.NewPromiseResolver()
+		go resolver.Reject(fmt.Errorf("query failed: %w", fmt.Errorf("dial db: %w", root)))
+		return p
+	})
+	result, err := rt.RunScriptAsync("go-cause", "query().catch(e => [e.message, e.cause.message, e.cause.cause.message].join('|'))")
+	want := "query failed: dial db: connection refused|dial db: connection refused|connection refused"
+	if err != nil || result != want {
+		t.Errorf("Go cause chain in JS = %v, %v", result, err)
+	}
+
+	// A JS cause survives conversion to ModuleError
+	_, err = rt.RunScript("js-cause", "throw new Error('request failed', {cause: new TypeError('bad header')})")
+	if err == nil {
+		t.Fatal("Expected the script to throw")
+	}
+	moduleErr := rt.createModuleErrorFromJS("js-cause", err)
+	if moduleErr.Cause == nil || moduleErr.Cause.Error() != "TypeError: bad header" {
+		t.Errorf("Expected the JS cause on the ModuleError, got %v", moduleErr.Cause)
+	}
+	if !strings.Contains(moduleErr.FormatError(), "Caused by: TypeError: bad header") {
+		t.Errorf("Expected FormatError to print the cause, got:\n%s", moduleErr.FormatError())
+	}
+
+	// AggregateError and Promise.any
+	result, err = rt.RunScriptAsync("any", `Promise.any([Promise.reject(new Error('a')), Promise.reject(new Error('b'))])
+		.catch(e => [e instanceof AggregateError, e.name, e.errors.map(x => x.message).join()].join('|'))`)
+	if err != nil || result != "true|AggregateError|a,b" {
+		t.Errorf("Promise.any rejection = %v, %v", result, err)
+	}
+	result, err = rt.RunScriptAsync("any-first", "Promise.any([Promise.reject(1), Promise.resolve(2)])")
+	if err != nil || result != int64(2) {
+		t.Errorf("Promise.any() = %v, %v", result, err)
+	}
+}
@@ -0,0 +1,427 @@
+package runtime
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/internal/modules"
+	"github.com/rizqme/gode/internal/modules/esm"
+)
+
+// esmLoader is the JS half of the ES module loader. It keeps a record per
+// module, keyed by resolved path, and is given the native functions
+// resolve(specifier, referrer), load(path), require(path) and meta(path).
+// load returns { fn, requests, async } for an ES module transformed by
+// package esm, and null for anything require() loads instead.
+//
+// Loading a module links the records of its graph first: each ES module's
+// function is called, which hoists its declarations and defines the getters
+// of its namespace, and import bindings are set. The graph is then
+// evaluated depth first, dependencies before the modules that import them.
+// A module met again while it is still evaluating, in a cycle, is skipped,
+// and bindings are refreshed whenever a module finishes, so that the
+// modules of a cycle see each other's exports once they exist. Evaluation is
+// synchronous until a module with top-level await is reached; from there
+// on each module waits for the ones before it.
+const esmLoader = `(function (native) {
+	"use strict";
+	const records = new Map();
+
+	const isThenable = (value) => value !== null && typeof value === "object" && typeof value.then === "function";
+	const chain = (value, fn) => isThenable(value) ? value.then(fn) : fn();
+
+	function define(namespace, name, get) {
+		Object.defineProperty(namespace, name, { get, enumerable: true, configurable: true });
+	}
+
+	function record(path) {
+		let rec = records.get(path);
+		if (rec) {
+			return rec;
+		}
+		const info = native.load(path);
+		const namespace = Object.create(null);
+		Object.defineProperty(namespace, Symbol.toStringTag, { value: "Module" });
+		rec = { path, status: "new", namespace, deps: [], importers: [], stars: [], update: null };
+		records.set(path, rec);
+		if (info) {
+			rec.fn = info.fn;
+			rec.async = info.async;
+			try {
+				rec.deps = info.requests.map((specifier) => record(native.resolve(specifier, path)));
+			} catch (error) {
+				records.delete(path);
+				throw error;
+			}
+			for (const dep of rec.deps) {
+				dep.importers.push(rec);
+			}
+		}
+		return rec;
+	}
+
+	function instantiate(rec, linked) {
+		if (rec.status !== "new") {
+			return;
+		}
+		rec.status = "linked";
+		linked.push(rec);
+		if (rec.fn) {
+			const handle = {
+				link(getters, update, stars) {
+					for (const name of Object.keys(getters)) {
+						define(rec.namespace, name, getters[name]);
+					}
+					rec.update = update;
+					rec.stars = stars.map((i) => rec.deps[i]);
+				},
+				get(i, name) {
+					// Bindings not initialised yet, in a cycle, stay undefined
+					try {
+						return rec.deps[i].namespace[name];
+					} catch (error) {
+						return undefined;
+					}
+				},
+				namespace: (i) => rec.deps[i].namespace,
+				meta: native.meta(rec.path),
+				import: (specifier) => new Promise((resolve) => resolve(load(native.resolve(specifier, rec.path)))),
+			};
+			if (rec.async) {
+				handle.linked = new Promise((resolve) => { rec.start = resolve; });
+				rec.body = rec.fn(handle);
+			} else {
+				rec.body = rec.fn(handle);
+				rec.body.next();
+			}
+		}
+		for (const dep of rec.deps) {
+			instantiate(dep, linked);
+		}
+	}
+
+	function exportStars(rec, visiting) {
+		if (visiting.has(rec)) {
+			return;
+		}
+		visiting.add(rec);
+		for (const dep of rec.stars) {
+			exportStars(dep, visiting);
+			for (const name of Object.keys(dep.namespace)) {
+				if (name !== "default" && !(name in rec.namespace)) {
+					define(rec.namespace, name, () => dep.namespace[name]);
+				}
+			}
+		}
+	}
+
+	function refresh(recs) {
+		for (const rec of recs) {
+			if (rec.update) {
+				rec.update();
+			}
+		}
+	}
+
+	function execute(rec) {
+		if (!rec.fn) {
+			const exports = native.require(rec.path);
+			define(rec.namespace, "default", () => exports);
+			if (exports !== null && (typeof exports === "object" || typeof exports === "function")) {
+				for (const name of Object.keys(exports)) {
+					if (name !== "default") {
+						define(rec.namespace, name, () => exports[name]);
+					}
+				}
+				if (exports.__esModule && "default" in exports) {
+					define(rec.namespace, "default", () => exports.default);
+				}
+			}
+			return;
+		}
+		if (rec.async) {
+			rec.start();
+			return rec.body;
+		}
+		rec.body.next();
+	}
+
+	function finish(rec) {
+		rec.status = "evaluated";
+		rec.pending = undefined;
+		if (!rec.fn) {
+			for (const importer of rec.importers) {
+				exportStars(importer, new Set());
+			}
+		}
+		refresh(rec.importers);
+	}
+
+	function evaluate(rec) {
+		switch (rec.status) {
+		case "evaluated":
+		case "evaluating":
+			return;
+		case "failed":
+			throw rec.error;
+		}
+		rec.status = "evaluating";
+		const fail = (error) => {
+			rec.status = "failed";
+			rec.error = error;
+			throw error;
+		};
+		let result;
+		try {
+			for (const dep of rec.deps) {
+				result = chain(result, () => evaluate(dep));
+			}
+			result = chain(result, () => execute(rec));
+		} catch (error) {
+			fail(error);
+		}
+		if (isThenable(result)) {
+			rec.pending = result.then(() => finish(rec), fail);
+			return rec.pending;
+		}
+		finish(rec);
+	}
+
+	function load(path) {
+		const rec = record(path);
+		const linked = [];
+		instantiate(rec, linked);
+		for (const r of linked) {
+			exportStars(r, new Set());
+		}
+		refresh(linked);
+		if (rec.status === "evaluating") {
+			return rec.pending ? rec.pending.then(() => rec.namespace) : rec.namespace;
+		}
+		return chain(evaluate(rec), () => rec.namespace);
+	}
+
+	return {
+		load,
+		require(path) {
+			const result = load(path);
+			if (isThenable(result)) {
+				const error = new Error("require() of ES module " + path + " cannot wait for its top-level await; use import() instead");
+				error.code = "ERR_REQUIRE_ASYNC_MODULE";
+				throw error;
+			}
+			return result;
+		},
+		rethrow(reason) {
+			throw reason;
+		},
+	};
+})`
+
+// isModule reports whether the file at path holding source is an ES
+// module: .mjs files always are, .cjs and .json files never, and other
+// files when they use import or export statements or import.meta
+func isModule(path, source string) bool {
+	if modules.IsDataURL(path) {
+		return esm.IsModule(source)
+	}
+	switch filepath.Ext(path) {
+	case ".mjs":
+		return true
+	case ".cjs", ".json":
+		return false
+	}
+	return esm.IsModule(source)
+}
+
+// mainScript is a main script given to the loader rather than read by it
+type mainScript struct {
+	fileName string
+	source   string
+}
+
+// jsLoader returns the JS half of the loader, creating it on first use. It
+// must be called on the JS thread.
+func (m *ModuleResolver) jsLoader() (*goja.Object, error) {
+	if m.loader != nil {
+		return m.loader, nil
+	}
+	vm := m.runtime.runtime
+	setup, err := jsprogram.Run(vm, "gode:esm-loader", esmLoader)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("module loader setup is not a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("resolve", m.resolve)
+	native.Set("load", m.load)
+	native.Set("require", func(path string) goja.Value {
+		require, _ := goja.AssertFunction(vm.Get("require"))
+		exports, err := require(goja.Undefined(), vm.ToValue(path))
+		if err != nil {
+			panic(err)
+		}
+		return exports
+	})
+	native.Set("meta", m.meta)
+
+	loader, err := fn(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	m.loader = loader.ToObject(vm)
+	return m.loader, nil
+}
+
+// resolve resolves specifier, imported by the module at referrer, to the
+// path its record is kept under. Under gode test, moduleNameMapper is
+// applied first.
+func (m *ModuleResolver) resolve(specifier, referrer string) string {
+	specifier, _ = m.manager.MapModuleName(specifier)
+	if _, builtin := m.runtime.modules[specifier]; builtin {
+		return specifier
+	}
+	resolved, err := m.manager.Resolve(specifier, referrer)
+	if err != nil {
+		panic(jserror.New(m.runtime.runtime, errors.NewModuleError(specifier, referrer, "resolve", err)))
+	}
+	return resolved
+}
+
+// load reads and transforms the ES module at path, returning null for
+// built-in modules, plugins and CommonJS files, which require() loads
+func (m *ModuleResolver) load(path string) goja.Value {
+	vm := m.runtime.runtime
+	if _, builtin := m.runtime.modules[path]; builtin || strings.HasPrefix(path, "gode:") || modules.IsPlugin(path) {
+		return goja.Null()
+	}
+
+	main, isMain := m.mains[path]
+	source, fileName := main.source, main.fileName
+	if !isMain {
+		var err error
+		if source, err = m.manager.Load(path); err != nil {
+			panic(jserror.New(vm, err))
+		}
+		if !isModule(path, source) {
+			return goja.Null()
+		}
+		fileName = m.runtime.getEnhancedFileName(path, true, m.runtime.extractModuleName(path))
+		m.runtime.tagModule(fileName, path)
+	}
+
+	source = m.runtime.applyRequireHooks(stripShebang(source), fileName)
+	m.runtime.registerSourceMap(fileName, path, source)
+	module, err := esm.Transform(source)
+	if err != nil {
+		panic(jserror.New(vm, errors.NewModuleError(path, "", "parse", err)))
+	}
+	fn, err := vm.RunScript(fileName, module.Code)
+	if err != nil {
+		if _, ok := err.(*goja.Exception); ok {
+			panic(err)
+		}
+		// A syntax error in the module body
+		panic(jserror.New(vm, errors.NewModuleError(path, "", "parse", err)))
+	}
+
+	info := vm.NewObject()
+	info.Set("fn", fn)
+	requests := make([]interface{}, len(module.Requests))
+	for i, request := range module.Requests {
+		requests[i] = request
+	}
+	info.Set("requests", vm.NewArray(requests...))
+	info.Set("async", module.Async)
+	return info
+}
+
+// meta returns import.meta for the module at path. data: URL and remote
+// modules have their URL and no filename or dirname.
+func (m *ModuleResolver) meta(path string) *goja.Object {
+	meta := m.runtime.runtime.NewObject()
+	if modules.IsDataURL(path) || modules.IsHTTPURL(path) {
+		meta.Set("url", path)
+	} else {
+		meta.Set("url", fileURL(path))
+		meta.Set("filename", path)
+		meta.Set("dirname", filepath.Dir(path))
+	}
+	meta.Set("resolve", func(specifier string) string {
+		resolved := m.resolve(specifier, path)
+		if filepath.IsAbs(resolved) {
+			return fileURL(resolved)
+		}
+		return resolved
+	})
+	return meta
+}
+
+// fileURL returns the file: URL of path
+func fileURL(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		// C:/dir becomes /C:/dir
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// importModule loads the ES module at path and returns its namespace, or a
+// promise of it when the graph uses top-level await. It must be called on
+// the JS thread.
+func (m *ModuleResolver) importModule(path string) (goja.Value, error) {
+	loader, err := m.jsLoader()
+	if err != nil {
+		return nil, err
+	}
+	load, _ := goja.AssertFunction(loader.Get("load"))
+	return load(goja.Undefined(), m.runtime.runtime.ToValue(path))
+}
+
+// requireModule loads the ES module at path for require(), which gets its
+// namespace. Errors, including a graph that uses top-level await, are
+// thrown to the caller.
+func (m *ModuleResolver) requireModule(path string) goja.Value {
+	loader, err := m.jsLoader()
+	if err != nil {
+		panic(jserror.New(m.runtime.runtime, err))
+	}
+	require, _ := goja.AssertFunction(loader.Get("require"))
+	namespace, err := require(goja.Undefined(), m.runtime.runtime.ToValue(path))
+	if err != nil {
+		panic(err)
+	}
+	return namespace
+}
+
+// runMain runs source, the ES module at path, as the main script and calls
+// done once it has been evaluated, after its top-level await if it has
+// any. fileName names it in stack traces. It must be called on the JS
+// thread.
+func (m *ModuleResolver) runMain(path, fileName, source string, done func(error)) {
+	m.mains[path] = mainScript{fileName: fileName, source: source}
+	namespace, err := m.importModule(path)
+	if err != nil {
+		done(err)
+		return
+	}
+	m.runtime.await(namespace, func(goja.Value) {
+		done(nil)
+	}, func(reason goja.Value) {
+		// Rethrow the reason so that it is reported like a synchronous error
+		rethrow, _ := goja.AssertFunction(m.loader.Get("rethrow"))
+		_, err := rethrow(goja.Undefined(), reason)
+		done(err)
+	})
+}
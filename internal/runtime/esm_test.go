@@ -0,0 +1,86 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuntimeESModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib.js": `
+export let count = 0;
+export function increment() { count++; }
+export default function greet(name) { return "hello " + name; }
+`,
+		"a.js": `
+import { fromB } from "./b.js";
+export function fromA() { return "A"; }
+export const cycle = fromB();
+`,
+		"b.js": `
+import { fromA } from "./a.js";
+export function fromB() { return "B" + fromA(); }
+`,
+		"slow.js": `
+export const value = await new Promise((resolve) => setTimeout(() => resolve(42), 10));
+`,
+		"later.js":  `export const value = await Promise.resolve(1);`,
+		"legacy.js": `({ legacy: "cjs" });`,
+		"reexport.js": `export * from "./lib.js";
+export { default as hello } from "./lib.js";`,
+		"main.js": `
+import greet, { count, increment } from "./lib.js";
+import * as lib from "./reexport.js";
+import { cycle } from "./a.js";
+import { value } from "./slow.js";
+import legacy from "./legacy.js";
+
+increment();
+const lazy = await import("./lib.js");
+globalThis.result = [
+	greet("esm"), count, lib.count, lib.hello === greet, cycle, value, legacy.legacy,
+	lazy.default === greet, import.meta.url.startsWith("file://"), import.meta.filename.endsWith("main.js"),
+].join("|");
+`,
+	}
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	if err := rt.Run(filepath.Join(dir, "main.js")); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	result, err := rt.RunScript("check", "globalThis.result")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	// count is the binding as of the last refresh, the namespace is live
+	if want := "hello esm|0|1|true|BA|42|cjs|true|true|true"; result != want {
+		t.Errorf("ES modules = %v, want %v", result, want)
+	}
+
+	// require() gets the namespace of ES modules without top-level await
+	value, err := rt.RunScript("require", fmt.Sprintf(`
+		const lib = require(%q);
+		let message;
+		try { require(%q); } catch (e) { message = e.code; }
+		[lib.default("cjs"), message].join("|");
+	`, filepath.Join(dir, "lib.js"), filepath.Join(dir, "later.js")))
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if value != "hello cjs|ERR_REQUIRE_ASYNC_MODULE" {
+		t.Errorf("require() of ES modules = %v", value)
+	}
+}
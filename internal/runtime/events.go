@@ -0,0 +1,171 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/modules"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// Lifecycle events published on gode:events/runtime
+const (
+	EventModuleLoaded   = "moduleLoaded"
+	EventPluginLoaded   = "pluginLoaded"
+	EventBeforeExit     = "beforeExit"
+	EventQueueSaturated = "queueSaturated"
+	EventTimerLeak      = "timerLeak"
+)
+
+// runtimeEvents holds the gode:events/runtime listeners. The emitter is only
+// touched on the JS thread; the counters are updated from any goroutine.
+type runtimeEvents struct {
+	emitter   *emitter
+	saturated int32 // a queueSaturated event is pending
+	dropped   int64 // operations dropped since the last queueSaturated event
+	total     int64 // operations dropped since the runtime started
+}
+
+// emitter is a minimal EventEmitter for events that originate in Go. It
+// must only be used on the JS thread.
+type emitter struct {
+	name      string // reported when a listener throws
+	listeners map[string][]*emitterListener
+}
+
+type emitterListener struct {
+	fn   goja.Value
+	call goja.Callable
+	once bool
+}
+
+func newEmitter(name string) *emitter {
+	return &emitter{name: name, listeners: make(map[string][]*emitterListener)}
+}
+
+// install adds on, once, off and listenerCount to obj
+func (e *emitter) install(vm *goja.Runtime, obj *goja.Object) {
+	add := func(once bool) func(string, goja.Value) *goja.Object {
+		return func(event string, fn goja.Value) *goja.Object {
+			call, ok := goja.AssertFunction(fn)
+			if !ok {
+				panic(vm.NewTypeError("listener must be a function"))
+			}
+			e.listeners[event] = append(e.listeners[event], &emitterListener{fn: fn, call: call, once: once})
+			return obj
+		}
+	}
+	obj.Set("on", add(false))
+	obj.Set("addListener", add(false))
+	obj.Set("once", add(true))
+	obj.Set("off", func(event string, fn goja.Value) *goja.Object {
+		e.remove(event, fn)
+		return obj
+	})
+	obj.Set("removeListener", obj.Get("off"))
+	obj.Set("listenerCount", func(event string) int {
+		return len(e.listeners[event])
+	})
+}
+
+func (e *emitter) remove(event string, fn goja.Value) {
+	list := e.listeners[event]
+	for i, l := range list {
+		if l.fn.StrictEquals(fn) {
+			e.listeners[event] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// emit calls the listeners for event with value. A throwing listener is
+// reported on stderr rather than propagated, so listeners cannot break the
+// Go code that raised the event.
+func (e *emitter) emit(event string, value goja.Value) {
+	list := e.listeners[event]
+	for _, l := range append([]*emitterListener(nil), list...) {
+		if l.once {
+			e.remove(event, l.fn)
+		}
+		if _, err := l.call(goja.Undefined(), value); err != nil {
+			fmt.Fprintf(os.Stderr, "gode: %s '%s' listener failed: %v\n", e.name, event, err)
+		}
+	}
+}
+
+// setupRuntimeEventsModule registers gode:events/runtime, an emitter that
+// lets tooling observe the runtime without patching it
+func (r *Runtime) setupRuntimeEventsModule() {
+	done := make(chan struct{})
+	r.QueueJSOperation(func() {
+		defer close(done)
+
+		r.events.emitter = newEmitter("runtime")
+		module := r.runtime.NewObject()
+		r.events.emitter.install(r.runtime, module)
+		r.modules["gode:events/runtime"] = module
+	})
+	<-done
+}
+
+// emitRuntimeEvent calls the gode:events/runtime listeners for event. It
+// must run on the JS thread.
+func (r *Runtime) emitRuntimeEvent(event string, payload map[string]interface{}) {
+	if r.events.emitter == nil || len(r.events.emitter.listeners[event]) == 0 {
+		return
+	}
+	r.events.emitter.emit(event, r.runtime.ToValue(payload))
+}
+
+// queueRuntimeEvent delivers an event from outside the JS thread
+func (r *Runtime) queueRuntimeEvent(event string, payload map[string]interface{}) {
+	r.QueueJSOperation(func() {
+		r.emitRuntimeEvent(event, payload)
+	})
+}
+
+// ModuleLoaded is called by the module manager after it loads a module
+func (r *Runtime) ModuleLoaded(mod modules.LoadedModule) {
+	r.queueRuntimeEvent(EventModuleLoaded, map[string]interface{}{
+		"specifier": mod.Specifier,
+		"path":      mod.Path,
+		"size":      mod.Size,
+		"loadTime":  float64(mod.LoadTime) / float64(time.Millisecond),
+	})
+}
+
+// PluginLoaded is called by the module manager after it loads a Go plugin
+func (r *Runtime) PluginLoaded(info *plugins.PluginInfo) {
+	r.queueRuntimeEvent(EventPluginLoaded, map[string]interface{}{
+		"name":    info.Name,
+		"version": info.Version,
+		"path":    info.Path,
+	})
+}
+
+// queueSaturated records an operation dropped because the JS queue was full
+// and reports it once the queue has room again. Only one report is pending
+// at a time; it carries the number of operations dropped until then.
+func (r *Runtime) queueSaturated() {
+	atomic.AddInt64(&r.events.dropped, 1)
+	atomic.AddInt64(&r.events.total, 1)
+	if !atomic.CompareAndSwapInt32(&r.events.saturated, 0, 1) {
+		return
+	}
+
+	go func() {
+		// Dispose closes the queue, which makes a blocked send panic
+		defer func() { recover() }()
+
+		r.lanes[PriorityDefault].ops <- func() {
+			atomic.StoreInt32(&r.events.saturated, 0)
+			r.emitRuntimeEvent(EventQueueSaturated, map[string]interface{}{
+				"dropped":  atomic.SwapInt64(&r.events.dropped, 0),
+				"capacity": cap(r.lanes[PriorityDefault].ops),
+			})
+		}
+	}()
+}
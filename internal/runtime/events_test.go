@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestRuntimeEvents(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.js")
+	if err := os.WriteFile(lib, []byte("module.exports = 1;"), 0644); err != nil {
+		t.Fatalf("Failed to write module: %v", err)
+	}
+
+	source := `
+		const events = require('gode:events/runtime');
+		globalThis.seen = [];
+		events.on('moduleLoaded', m => seen.push('module:' + m.size));
+		events.once('beforeExit', () => seen.push('beforeExit'));
+		require(` + strconv.Quote(lib) + `);
+	`
+	if err := rt.RunSource(filepath.Join(dir, "main.js"), source); err != nil {
+		t.Fatalf("RunSource() failed: %v", err)
+	}
+
+	result, err := rt.RunScript("check", "seen.join(',')")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if want := "module:19,beforeExit"; result != want {
+		t.Errorf("Expected %q, got %v", want, result)
+	}
+}
@@ -0,0 +1,132 @@
+package runtime
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// ExitError is returned by Run when the script ends with a non-zero status,
+// either by calling process.exit(code) or by leaving process.exitCode set.
+// process.exit(0) also produces one so callers can tell the script stopped
+// early.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("process exited with code %d", e.Code)
+}
+
+// Exit implements process.exit. It emits the process 'exit' event, runs the
+// shutdown hooks, cancels pending timers and interrupts the running script;
+// Run then returns an ExitError with code. It must be called on the JS
+// thread.
+func (r *Runtime) Exit(code int) {
+	r.stop(code, true)
+}
+
+// stop ends the script with code, emitting 'exit' first if emitExit is
+// set. Only the first call runs the shutdown hooks. It must be called on
+// the JS thread.
+func (r *Runtime) stop(code int, emitExit bool) {
+	if !r.exiting {
+		r.exiting = true
+		r.exitCode = code
+		if emitExit {
+			r.emitProcessEvent("exit", r.runtime.ToValue(code))
+		}
+		r.shutdown.Run()
+		if r.timersBridge != nil {
+			r.timersBridge.GetTimersModule().Cleanup()
+		}
+		close(r.exited)
+	}
+	r.interrupt(&ExitError{Code: code})
+}
+
+// exitedWith returns an ExitError with the code passed to process.exit, or
+// the PanicError that stopped the JS thread, and nil while the script runs
+func (r *Runtime) exitedWith() error {
+	select {
+	case <-r.exited:
+		if r.crash != nil {
+			return r.crash
+		}
+		return &ExitError{Code: r.exitCode}
+	default:
+		return nil
+	}
+}
+
+// finish ends a script that ran to completion: it emits beforeExit, both
+// the runtime event and the process event with process.exitCode, and waits
+// for any work the listeners started, then emits beforeExit again until
+// they start none. Then it emits 'exit' and returns an ExitError when the
+// code is non-zero.
+func (r *Runtime) finish() error {
+	for {
+		if err := r.exitedWith(); err != nil {
+			return err
+		}
+		pending := make(chan bool, 1)
+		r.QueueJSOperation(func() {
+			r.emitRuntimeEvent(EventBeforeExit, map[string]interface{}{})
+			r.emitProcessEvent("beforeExit", r.runtime.ToValue(r.processExitCode()))
+			pending <- r.hasPendingWork()
+		})
+		select {
+		case more := <-pending:
+			if !more {
+				return r.exit()
+			}
+		case <-r.exited:
+			return r.exitedWith()
+		}
+		r.waitForTimers()
+		r.waitForHandles()
+	}
+}
+
+// exit emits 'exit' with process.exitCode once the script has finished
+func (r *Runtime) exit() error {
+	done := make(chan int, 1)
+	r.QueueJSOperation(func() {
+		code := r.processExitCode()
+		r.exiting = true
+		r.exitCode = code
+		r.emitProcessEvent("exit", r.runtime.ToValue(code))
+		done <- code
+	})
+
+	select {
+	case code := <-done:
+		if code != 0 {
+			return &ExitError{Code: code}
+		}
+		return nil
+	case <-r.exited:
+		return r.exitedWith()
+	}
+}
+
+// processExitCode returns process.exitCode, or 0 when it isn't set. It
+// must run on the JS thread.
+func (r *Runtime) processExitCode() int {
+	if process, ok := r.runtime.Get("process").(*goja.Object); ok {
+		if v := process.Get("exitCode"); v != nil {
+			return int(v.ToInteger())
+		}
+	}
+	return 0
+}
+
+// hasPendingWork reports whether timers or KeepAlive handles are still
+// open, as after a beforeExit listener started more work
+func (r *Runtime) hasPendingWork() bool {
+	if atomic.LoadInt64(&r.handles) > 0 {
+		return true
+	}
+	return r.timersBridge != nil && r.timersBridge.GetTimersModule().HasActiveTimers()
+}
@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"testing"
+)
+
+func TestRuntimeProcessExit(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		code   int
+	}{
+		{"exit", `process.exit(5); globalThis.after = true;`, 5},
+		{"exitCode", `process.exitCode = 3;`, 3},
+		{"exit uses exitCode", `process.exitCode = 4; process.exit();`, 4},
+		{"timer", `setTimeout(() => process.exit(2), 10);`, 2},
+		{"clean", `globalThis.done = true;`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := New()
+			defer rt.Dispose()
+			if err := rt.Configure(nil); err != nil {
+				t.Fatalf("Configure() failed: %v", err)
+			}
+
+			hookRan := false
+			rt.AddShutdownHook(func() { hookRan = true })
+
+			err := rt.RunSource("exit.js", tt.source)
+			if tt.code == 0 {
+				if err != nil {
+					t.Fatalf("Expected a clean exit, got %v", err)
+				}
+				return
+			}
+
+			exitErr, ok := err.(*ExitError)
+			if !ok || exitErr.Code != tt.code {
+				t.Fatalf("Expected exit code %d, got %v", tt.code, err)
+			}
+			if tt.name != "exitCode" && !hookRan {
+				t.Error("Expected shutdown hooks to run on process.exit")
+			}
+		})
+	}
+}
+
+func TestRuntimeUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		code   int
+	}{
+		{"timer", `setTimeout(() => { throw new Error('boom'); }, 1);`, 1},
+		{"caught timer", `process.on('uncaughtException', (err, origin) => {
+				process.exitCode = err.message === 'boom' && origin === 'uncaughtException' ? 20 : 1;
+			});
+			setTimeout(() => { throw new Error('boom'); }, 1);`, 20},
+		{"caught main", `process.on('uncaughtException', () => { process.exitCode = 21; });
+			throw new Error('main');`, 21},
+		{"throwing listener", `process.on('uncaughtException', () => { throw new Error('again'); });
+			setImmediate(() => { throw new Error('boom'); });`, 7},
+		{"rejection", `Promise.reject(new Error('nope'));`, 1},
+		{"caught rejection", `process.on('uncaughtException', (err, origin) => {
+				process.exitCode = origin === 'unhandledRejection' ? 22 : 1;
+			});
+			Promise.reject(new Error('nope'));`, 22},
+		{"rejection listener", `const p = Promise.reject(42);
+			process.on('unhandledRejection', (reason, promise) => {
+				process.exitCode = reason === 42 && promise === p ? 23 : 1;
+			});`, 23},
+		{"handled in a microtask", `const p = Promise.reject(42);
+			queueMicrotask(() => p.catch(() => {}));`, 0},
+		{"handled late", `process.on('unhandledRejection', () => {});
+			process.on('rejectionHandled', () => { process.exitCode = 24; });
+			const p = Promise.reject(42);
+			setTimeout(() => p.catch(() => {}), 1);`, 24},
+		{"beforeExit", `let rounds = 0;
+			process.on('beforeExit', (code) => {
+				if (++rounds < 3) setTimeout(() => {}, 1);
+				else process.exitCode = 30 + rounds + code;
+			});`, 33},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := New()
+			defer rt.Dispose()
+			if err := rt.Configure(nil); err != nil {
+				t.Fatalf("Configure() failed: %v", err)
+			}
+
+			err := rt.RunSource("uncaught.js", tt.source)
+			if tt.code == 0 {
+				if err != nil {
+					t.Fatalf("Expected a clean exit, got %v", err)
+				}
+				return
+			}
+			exitErr, ok := err.(*ExitError)
+			if !ok || exitErr.Code != tt.code {
+				t.Fatalf("Expected exit code %d, got %v", tt.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package runtime
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// KeepAlive marks a long-lived resource, such as a file watcher, as open so
+// that Run does not return while it is. Call the returned function once the
+// resource is closed; extra calls are ignored.
+func (r *Runtime) KeepAlive() (release func()) {
+	atomic.AddInt64(&r.handles, 1)
+
+	var once int32
+	return func() {
+		if atomic.CompareAndSwapInt32(&once, 0, 1) {
+			atomic.AddInt64(&r.handles, -1)
+		}
+	}
+}
+
+// waitForHandles blocks until every KeepAlive handle has been released, then
+// lets timers started by their callbacks finish
+func (r *Runtime) waitForHandles() {
+	if atomic.LoadInt64(&r.handles) == 0 {
+		return
+	}
+	for atomic.LoadInt64(&r.handles) > 0 {
+		select {
+		case <-r.exited:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	r.waitForTimers()
+}
+
+// waitForTimers lets pending timers fire, giving up after the timers
+// module's default timeout. Timers still pending then are reported as a
+// timerLeak event, since they would otherwise be dropped silently.
+func (r *Runtime) waitForTimers() {
+	if r.timersBridge == nil {
+		return
+	}
+	timers := r.timersBridge.GetTimersModule()
+
+	start := time.Now()
+	timers.WaitForTimers(0)
+	if active := timers.ActiveTimers(); active > 0 {
+		r.queueRuntimeEvent(EventTimerLeak, map[string]interface{}{
+			"active": active,
+			"waited": float64(time.Since(start)) / float64(time.Millisecond),
+		})
+	}
+}
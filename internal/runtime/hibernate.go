@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// hibernation is the state of a runtime's event loop goroutine, which an
+// idle runtime can stop along with its queue buffers, see Hibernate. The
+// lanes' channels only change with mu held for writing, so producers hold
+// it for reading while they queue.
+type hibernation struct {
+	mu       sync.RWMutex
+	sleeping bool           // the event loop has returned and the lanes are released
+	closed   bool           // Dispose closed the lanes
+	requests chan chan bool // Hibernate asking the idle event loop to sleep
+}
+
+// Hibernate stops the event loop goroutine of an idle runtime and releases
+// its operation queues, for embedders that keep many runtimes which are
+// mostly waiting. The JS heap and everything the script defined are kept.
+// The next queued operation, or Wake, starts the event loop again.
+//
+// It returns false without waiting when the JS thread is running or has
+// work queued, or when the script has work referenced that will queue
+// more: pending timers or open KeepAlive handles. It returns true if the
+// runtime is, or already was, asleep.
+func (r *Runtime) Hibernate() bool {
+	h := &r.hibernation
+	h.mu.RLock()
+	sleeping, closed := h.sleeping, h.closed
+	h.mu.RUnlock()
+	if sleeping || closed {
+		return sleeping
+	}
+
+	reply := make(chan bool, 1)
+	select {
+	case h.requests <- reply:
+		return <-reply
+	default:
+		return false // the event loop is not waiting for work
+	}
+}
+
+// Wake starts the event loop of a hibernated runtime, which queueing an
+// operation also does
+func (r *Runtime) Wake() {
+	h := &r.hibernation
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.sleeping || h.closed {
+		return
+	}
+	for _, l := range r.lanes {
+		l.ops = make(chan func(), r.queueSize)
+	}
+	h.sleeping = false
+	go r.eventLoop()
+}
+
+// Hibernating reports whether the runtime's event loop is asleep
+func (r *Runtime) Hibernating() bool {
+	r.hibernation.mu.RLock()
+	defer r.hibernation.mu.RUnlock()
+	return r.hibernation.sleeping
+}
+
+// park puts the event loop to sleep if the runtime is idle, replying to
+// Hibernate. It runs on the JS thread, which returns from the event loop
+// if it did.
+func (r *Runtime) park(reply chan bool) bool {
+	h := &r.hibernation
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	idle := r.crash == nil && !h.closed && !r.hasPendingWork() &&
+		atomic.LoadInt32(&r.events.saturated) == 0
+	for _, l := range r.lanes {
+		idle = idle && len(l.ops) == 0
+	}
+	if idle {
+		for _, l := range r.lanes {
+			l.ops = nil
+		}
+		r.streak = 0
+		r.rejections.pending = nil
+		h.sleeping = true
+	}
+	reply <- idle
+	return idle
+}
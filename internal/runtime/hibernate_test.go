@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+// hibernateSoon calls Hibernate until it succeeds or a second has passed,
+// since the event loop may still be finishing the last operation
+func hibernateSoon(rt *Runtime) bool {
+	deadline := time.Now().Add(time.Second)
+	for !rt.Hibernate() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func TestRuntimeHibernate(t *testing.T) {
+	rt := NewWithOptions(Options{QueueSize: 16})
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	if _, err := rt.RunScript("state.js", "globalThis.count = 1"); err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+
+	if !hibernateSoon(rt) {
+		t.Fatal("Expected an idle runtime to hibernate")
+	}
+	stats := rt.Stats()
+	if !rt.Hibernating() || !stats.Hibernating || stats.Queue.Capacity != 16 {
+		t.Errorf("Unexpected stats of a hibernated runtime: %+v", stats)
+	}
+	if !rt.Hibernate() {
+		t.Error("Expected Hibernate() on a hibernated runtime to succeed")
+	}
+
+	// The next operation wakes it, with the script's state kept
+	count, err := rt.RunScript("state.js", "++count")
+	if err != nil || count != int64(2) {
+		t.Errorf("RunScript() after hibernating = %v, %v, want 2", count, err)
+	}
+	if rt.Hibernating() {
+		t.Error("Expected the runtime to wake for the operation")
+	}
+
+	// Referenced work keeps it awake
+	if _, err := rt.RunScript("timer.js", "globalThis.timer = setInterval(() => {}, 10)"); err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if rt.Hibernate() {
+		t.Error("Expected a runtime with a pending interval not to hibernate")
+	}
+	rt.RunScript("timer.js", "clearInterval(timer)")
+	release := rt.KeepAlive()
+	time.Sleep(20 * time.Millisecond)
+	if rt.Hibernate() {
+		t.Error("Expected a runtime with an open handle not to hibernate")
+	}
+	release()
+	if !hibernateSoon(rt) {
+		t.Error("Expected the runtime to hibernate once its work is done")
+	}
+
+	rt.Wake()
+	if rt.Hibernating() {
+		t.Error("Expected Wake() to start the event loop")
+	}
+	if !hibernateSoon(rt) {
+		t.Fatal("Expected the runtime to hibernate again")
+	}
+
+	// Disposing a hibernated runtime leaves nothing to wake
+	rt.Dispose()
+	rt.QueueJSOperation(func() { t.Error("Expected no operation to run after Dispose") })
+	time.Sleep(10 * time.Millisecond)
+}
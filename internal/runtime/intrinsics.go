@@ -0,0 +1,23 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/internal/modules/globals"
+)
+
+// FreezeIntrinsics freezes Object.prototype, Array.prototype, the other
+// built-in prototypes and constructors, like node --frozen-intrinsics, so
+// that dependency code cannot pollute them. Call it after Configure and
+// before loading untrusted code; it cannot be undone.
+func (r *Runtime) FreezeIntrinsics() error {
+	if r.runtime == nil {
+		return fmt.Errorf("runtime not configured")
+	}
+
+	done := make(chan error, 1)
+	r.QueueJSOperation(func() {
+		done <- globals.FreezeIntrinsics(r.runtime)
+	})
+	return <-done
+}
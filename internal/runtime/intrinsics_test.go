@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"testing"
+)
+
+func TestRuntimeFreezeIntrinsics(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	if err := rt.FreezeIntrinsics(); err != nil {
+		t.Fatalf("FreezeIntrinsics() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("pollute", `
+		const payload = JSON.parse('{"__proto__": {"polluted": true}}');
+		Object.assign({}, payload);
+		try { Object.prototype.polluted = true; } catch (e) {}
+		try { Array.prototype.includes = () => true; } catch (e) {}
+		try { Object.getPrototypeOf([][Symbol.iterator]()).next = null; } catch (e) {}
+		[
+			Object.isFrozen(Object.prototype),
+			Object.isFrozen(Array.prototype),
+			Object.isFrozen(Function.prototype),
+			Object.isFrozen(Promise),
+			({}).polluted === undefined,
+			[1].includes(2) === false,
+		].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if value != "true,true,true,true,true,true" {
+		t.Errorf("Intrinsics after pollution attempts = %v", value)
+	}
+
+	// Ordinary code keeps working, and Error.stackTraceLimit stays writable
+	value, err = rt.RunScript("ordinary", `
+		class Point { constructor(x) { this.x = x; } toString() { return 'P' + this.x; } }
+		Error.stackTraceLimit = 3;
+		const m = new Map([[1, 'a']]);
+		[String(new Point(2)), [3, 1, 2].sort().join(''), m.get(1), Error.stackTraceLimit, JSON.stringify({ a: [1] })].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if value != `P2,123,a,3,{"a":[1]}` {
+		t.Errorf("Ordinary code = %v", value)
+	}
+}
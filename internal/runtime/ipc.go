@@ -0,0 +1,149 @@
+package runtime
+
+import (
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/ipc"
+	"github.com/rizqme/gode/internal/modules/globals"
+)
+
+// setupIPC connects to the parent's message channel when this process was
+// spawned with one, adding process.send, process.disconnect and
+// process.connected and emitting 'message' and 'disconnect' on process
+func (r *Runtime) setupIPC() error {
+	channel, err := ipc.FromEnv()
+	if err != nil || channel == nil {
+		return err
+	}
+	r.ipc = channel
+
+	done := make(chan struct{})
+	r.QueueJSOperation(func() {
+		defer close(done)
+
+		process := r.runtime.Get("process").ToObject(r.runtime)
+		process.Set("send", func(call goja.FunctionCall) goja.Value {
+			msg, err := ipc.Serialize(r.runtime, call.Argument(0))
+			if err == nil {
+				err = channel.Send(msg)
+			}
+
+			// Node reports send errors through the callback when one is given
+			if cb, ok := goja.AssertFunction(call.Argument(len(call.Arguments) - 1)); ok && len(call.Arguments) > 1 {
+				r.QueueJSOperation(func() {
+					if err != nil {
+						cb(goja.Undefined(), r.runtime.NewGoError(err))
+					} else {
+						cb(goja.Undefined(), goja.Null())
+					}
+				})
+				return r.runtime.ToValue(err == nil)
+			}
+			if err != nil {
+				panic(r.runtime.NewGoError(err))
+			}
+			return r.runtime.ToValue(true)
+		})
+		process.Set("disconnect", func() {
+			channel.Close()
+		})
+		process.DefineAccessorProperty("connected", r.runtime.ToValue(func() bool {
+			select {
+			case <-channel.Done():
+				return false
+			default:
+				return true
+			}
+		}), nil, goja.FLAG_TRUE, goja.FLAG_TRUE)
+	})
+	<-done
+
+	go r.receiveIPC(channel)
+	return nil
+}
+
+// receiveIPC delivers incoming messages as process 'message' events until
+// the channel closes
+func (r *Runtime) receiveIPC(channel *ipc.Channel) {
+	for {
+		msg, err := channel.Receive()
+		if err != nil {
+			r.QueueJSOperation(func() {
+				r.emitProcessEvent("disconnect")
+			})
+			return
+		}
+
+		r.QueueJSOperation(func() {
+			value, err := ipc.Deserialize(r.runtime, msg)
+			if err != nil {
+				r.emitProcessEvent("error", r.runtime.NewGoError(err))
+				return
+			}
+			r.emitProcessEvent("message", value)
+		})
+	}
+}
+
+// emitProcessEvent calls process.emit(event, ...args) and reports whether
+// there were listeners. A throwing listener is reported as an uncaught
+// exception, since nothing called it. It must run on the JS thread.
+func (r *Runtime) emitProcessEvent(event string, args ...goja.Value) bool {
+	process := r.runtime.Get("process")
+	if process == nil || goja.IsUndefined(process) {
+		return false
+	}
+	obj := process.ToObject(r.runtime)
+	emit, ok := goja.AssertFunction(obj.Get("emit"))
+	if !ok {
+		return false
+	}
+
+	result, err := emit(obj, append([]goja.Value{r.runtime.ToValue(event)}, args...)...)
+	if ex, ok := err.(*goja.Exception); ok {
+		globals.ReportUncaught(r.runtime, ex.Value())
+		return true
+	}
+	return err == nil && result.ToBoolean()
+}
+
+// waitForIPC keeps the process alive while the IPC channel is connected and
+// something listens for messages, like an open channel does in Node
+func (r *Runtime) waitForIPC() {
+	if r.ipc == nil {
+		return
+	}
+
+	for {
+		listening := make(chan bool, 1)
+		r.QueueJSOperation(func() {
+			count := r.runtime.Get("process").ToObject(r.runtime).Get("listenerCount")
+			fn, ok := goja.AssertFunction(count)
+			if !ok {
+				listening <- false
+				return
+			}
+			n, err := fn(goja.Undefined(), r.runtime.ToValue("message"))
+			listening <- err == nil && n.ToInteger() > 0
+		})
+		select {
+		case ok := <-listening:
+			if !ok {
+				return
+			}
+		case <-r.ipc.Done():
+			return
+		case <-r.exited:
+			return
+		}
+
+		select {
+		case <-r.ipc.Done():
+			return
+		case <-r.exited:
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
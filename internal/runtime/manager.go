@@ -0,0 +1,326 @@
+package runtime
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// Quota limits the resources of one tenant's runtime. Zero fields mean no
+// limit.
+type Quota struct {
+	// Memory is the Go heap size, in bytes, above which the tenant's
+	// running operation is interrupted. Go cannot attribute heap to a
+	// goroutine, so the heap of the whole process is compared against it
+	// while the tenant's JS thread is busy; it guards against a tenant
+	// allocating without bound rather than metering exact use.
+	Memory uint64
+	// CPUTime is the total time the tenant's JS thread may spend running
+	// operations
+	CPUTime time.Duration
+	// QueueDepth is the number of JS operations that can wait to run before
+	// new ones are dropped
+	QueueDepth int
+}
+
+// QuotaError is the reason a tenant's runtime was stopped
+type QuotaError struct {
+	Tenant   string
+	Resource string // "memory" or "cpu"
+	Limit    string
+	Used     string
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %s exceeded its %s quota (%s used, limit %s)", e.Tenant, e.Resource, e.Used, e.Limit)
+}
+
+// ManagerOptions configures a RuntimeManager
+type ManagerOptions struct {
+	// Config is applied to every tenant runtime
+	Config *config.PackageJSON
+	// Quota is used for tenants created without their own
+	Quota Quota
+	// CheckInterval is how often quotas are checked. Defaults to 100ms.
+	CheckInterval time.Duration
+	// OnQuotaExceeded is called, from the manager's goroutine, when a
+	// tenant is stopped for exceeding its quota
+	OnQuotaExceeded func(tenant string, err *QuotaError)
+	// IdleAfter hibernates a tenant's runtime once it has run no JS
+	// operation for this long, see Runtime.Hibernate. Zero never does.
+	IdleAfter time.Duration
+}
+
+// RuntimeManager runs one Runtime per tenant for applications that embed
+// gode to run user scripts. Tenants are isolated JS runtimes; the setup
+// scripts of the built-in globals are compiled once and shared by all of
+// them.
+type RuntimeManager struct {
+	opts    ManagerOptions
+	mu      sync.Mutex
+	tenants map[string]*tenant
+	retired Stats // totals of destroyed tenants
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+type tenant struct {
+	name     string
+	runtime  *Runtime
+	quota    Quota
+	exceeded *QuotaError // guarded by the manager's mu
+
+	// Used by the manager's goroutine only
+	processed int64     // JS operations run when last checked
+	activeAt  time.Time // when processed last changed
+}
+
+// ManagerStats aggregates the stats of a manager's tenants
+type ManagerStats struct {
+	Tenants     int
+	Programs    int           // shared precompiled built-in programs
+	Busy        time.Duration // JS thread time, including destroyed tenants
+	Processed   int64         // JS operations run, including destroyed tenants
+	Dropped     int64         // JS operations dropped, including destroyed tenants
+	Queued      int           // JS operations waiting now
+	Hibernating int           // tenants whose runtime is asleep
+	HeapAlloc   uint64        // bytes, for the whole process
+	PerTenant   map[string]Stats
+}
+
+// NewRuntimeManager creates a manager and starts checking quotas. Close it
+// to dispose every tenant.
+func NewRuntimeManager(opts ManagerOptions) *RuntimeManager {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = 100 * time.Millisecond
+	}
+	m := &RuntimeManager{
+		opts:    opts,
+		tenants: make(map[string]*tenant),
+		stop:    make(chan struct{}),
+	}
+	go m.watch()
+	return m
+}
+
+// Create starts a configured runtime for a new tenant. quota overrides the
+// manager's default quota when not nil.
+func (m *RuntimeManager) Create(name string, quota *Quota) (*Runtime, error) {
+	q := m.opts.Quota
+	if quota != nil {
+		q = *quota
+	}
+
+	m.mu.Lock()
+	if _, exists := m.tenants[name]; exists {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("tenant %s already exists", name)
+	}
+	// Reserve the name while the runtime is configured
+	t := &tenant{name: name, quota: q}
+	m.tenants[name] = t
+	m.mu.Unlock()
+
+	rt := NewWithOptions(Options{QueueSize: q.QueueDepth})
+	if err := rt.Configure(m.opts.Config); err != nil {
+		rt.Dispose()
+		m.mu.Lock()
+		delete(m.tenants, name)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	m.mu.Lock()
+	t.runtime = rt
+	m.mu.Unlock()
+	return rt, nil
+}
+
+// Get returns a tenant's runtime
+func (m *RuntimeManager) Get(name string) (*Runtime, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tenants[name]
+	if !ok || t.runtime == nil {
+		return nil, false
+	}
+	return t.runtime, true
+}
+
+// Exceeded returns the QuotaError that stopped a tenant, or nil
+func (m *RuntimeManager) Exceeded(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tenants[name]; ok && t.exceeded != nil {
+		return t.exceeded
+	}
+	return nil
+}
+
+// Destroy disposes a tenant's runtime
+func (m *RuntimeManager) Destroy(name string) error {
+	m.mu.Lock()
+	t, ok := m.tenants[name]
+	if !ok || t.runtime == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("tenant %s not found", name)
+	}
+	delete(m.tenants, name)
+	m.mu.Unlock()
+
+	stats := t.runtime.Stats()
+	t.runtime.Dispose()
+
+	m.mu.Lock()
+	m.retired.Busy += stats.Busy
+	m.retired.Queue.Processed += stats.Queue.Processed
+	m.retired.Queue.Dropped += stats.Queue.Dropped
+	m.mu.Unlock()
+	return nil
+}
+
+// Tenants returns the names of the running tenants, sorted
+func (m *RuntimeManager) Tenants() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.tenants))
+	for name, t := range m.tenants {
+		if t.runtime != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns aggregate and per-tenant stats
+func (m *RuntimeManager) Stats() ManagerStats {
+	m.mu.Lock()
+	tenants := m.running()
+	stats := ManagerStats{
+		Programs:  jsprogram.Count(),
+		Busy:      m.retired.Busy,
+		Processed: m.retired.Queue.Processed,
+		Dropped:   m.retired.Queue.Dropped,
+		PerTenant: make(map[string]Stats, len(tenants)),
+	}
+	m.mu.Unlock()
+
+	for _, t := range tenants {
+		s := t.runtime.Stats()
+		stats.Tenants++
+		stats.Busy += s.Busy
+		stats.Processed += s.Queue.Processed
+		stats.Dropped += s.Queue.Dropped
+		stats.Queued += s.Queue.Length
+		if s.Hibernating {
+			stats.Hibernating++
+		}
+		stats.HeapAlloc = s.HeapAlloc
+		stats.PerTenant[t.name] = s
+	}
+	return stats
+}
+
+// Close disposes every tenant and stops checking quotas
+func (m *RuntimeManager) Close() {
+	m.stopped.Do(func() { close(m.stop) })
+	for _, name := range m.Tenants() {
+		m.Destroy(name)
+	}
+}
+
+// running returns the tenants with a configured runtime. m.mu must be held.
+func (m *RuntimeManager) running() []*tenant {
+	list := make([]*tenant, 0, len(m.tenants))
+	for _, t := range m.tenants {
+		if t.runtime != nil {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// watch checks quotas every CheckInterval until the manager is closed
+func (m *RuntimeManager) watch() {
+	ticker := time.NewTicker(m.opts.CheckInterval)
+	defer ticker.Stop()
+
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+
+		metrics.Read(sample)
+		var heap uint64
+		if sample[0].Value.Kind() == metrics.KindUint64 {
+			heap = sample[0].Value.Uint64()
+		} else {
+			var mem goruntime.MemStats
+			goruntime.ReadMemStats(&mem)
+			heap = mem.HeapAlloc
+		}
+
+		m.mu.Lock()
+		var stopped, idle []*tenant
+		for _, t := range m.running() {
+			if t.exceeded != nil {
+				continue
+			}
+			if err := t.check(heap); err != nil {
+				t.exceeded = err
+				stopped = append(stopped, t)
+			} else if m.opts.IdleAfter > 0 && t.idleFor() >= m.opts.IdleAfter {
+				idle = append(idle, t)
+			}
+		}
+		m.mu.Unlock()
+
+		for _, t := range idle {
+			// A runtime busy after all is tried again next time
+			t.runtime.Hibernate()
+		}
+
+		for _, t := range stopped {
+			// Interrupting an idle runtime stops its next operation instead
+			t.runtime.interrupt(t.exceeded)
+			if m.opts.OnQuotaExceeded != nil {
+				m.opts.OnQuotaExceeded(t.name, t.exceeded)
+			}
+		}
+	}
+}
+
+// check returns a QuotaError if the tenant is over its quota
+func (t *tenant) check(heap uint64) *QuotaError {
+	if limit := t.quota.CPUTime; limit > 0 {
+		if used := t.runtime.busyTime(); used > limit {
+			return &QuotaError{Tenant: t.name, Resource: "cpu", Limit: limit.String(), Used: used.Round(time.Millisecond).String()}
+		}
+	}
+	if limit := t.quota.Memory; limit > 0 && heap > limit && t.runtime.busyNow() {
+		return &QuotaError{Tenant: t.name, Resource: "memory", Limit: fmt.Sprintf("%d bytes", limit), Used: fmt.Sprintf("%d bytes", heap)}
+	}
+	return nil
+}
+
+// idleFor returns how long the tenant has run no JS operation, as seen by
+// the checks of the manager's goroutine
+func (t *tenant) idleFor() time.Duration {
+	now := time.Now()
+	if processed := atomic.LoadInt64(&t.runtime.processed); processed != t.processed || t.activeAt.IsZero() {
+		t.processed = processed
+		t.activeAt = now
+	}
+	return now.Sub(t.activeAt)
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRuntimeManager(t *testing.T) {
+	exceeded := make(chan string, 1)
+	m := NewRuntimeManager(ManagerOptions{
+		CheckInterval:   10 * time.Millisecond,
+		OnQuotaExceeded: func(tenant string, err *QuotaError) { exceeded <- tenant },
+	})
+	defer m.Close()
+
+	a, err := m.Create("a", nil)
+	if err != nil {
+		t.Fatalf("Create(a) failed: %v", err)
+	}
+	b, err := m.Create("b", &Quota{CPUTime: 50 * time.Millisecond, QueueDepth: 16})
+	if err != nil {
+		t.Fatalf("Create(b) failed: %v", err)
+	}
+	if _, err := m.Create("a", nil); err == nil {
+		t.Error("Expected an error creating a duplicate tenant")
+	}
+
+	// Tenants don't share globals
+	if _, err := a.RunScript("a", "globalThis.owner = 'a'"); err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if owner, _ := b.RunScript("b", "typeof owner"); owner != "undefined" {
+		t.Errorf("Expected tenant b not to see tenant a's globals, got %v", owner)
+	}
+
+	// A runaway script is stopped once it uses its CPU time
+	if _, err := b.RunScript("b", "for (;;) {}"); err == nil {
+		t.Error("Expected the CPU quota to interrupt the script")
+	}
+	select {
+	case tenant := <-exceeded:
+		if tenant != "b" {
+			t.Errorf("Expected tenant b to exceed its quota, got %s", tenant)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected OnQuotaExceeded to be called")
+	}
+	var quotaErr *QuotaError
+	if err := m.Exceeded("b"); !errors.As(err, &quotaErr) || quotaErr.Resource != "cpu" {
+		t.Errorf("Expected a cpu QuotaError, got %v", err)
+	}
+
+	stats := m.Stats()
+	if stats.Tenants != 2 || stats.Programs == 0 || stats.PerTenant["b"].Queue.Capacity != 16 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	if err := m.Destroy("b"); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if names := m.Tenants(); len(names) != 1 || names[0] != "a" {
+		t.Errorf("Expected only tenant a, got %v", names)
+	}
+	if after := m.Stats(); after.Processed < stats.PerTenant["b"].Queue.Processed {
+		t.Errorf("Expected destroyed tenants to count towards the totals")
+	}
+}
+
+func TestRuntimeManagerIdleAfter(t *testing.T) {
+	m := NewRuntimeManager(ManagerOptions{
+		CheckInterval: 10 * time.Millisecond,
+		IdleAfter:     50 * time.Millisecond,
+	})
+	defer m.Close()
+
+	rt, err := m.Create("idle", nil)
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !rt.Hibernating() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := m.Stats(); stats.Hibernating != 1 {
+		t.Fatalf("Expected the idle tenant to hibernate, got %+v", stats)
+	}
+
+	if answer, err := rt.RunScript("idle", "6 * 7"); err != nil || answer != int64(42) {
+		t.Errorf("RunScript() after hibernating = %v, %v, want 42", answer, err)
+	}
+}
@@ -9,10 +9,17 @@ import (
 	"github.com/rizqme/gode/internal/modules"
 )
 
-// ModuleResolver implements the module resolution interface for goja
+// ModuleResolver implements the module resolution interface for goja, and
+// loads ES modules: it builds the graph of a module's static imports, links
+// their bindings and evaluates them in order, waiting for top-level await.
+// CommonJS modules, JSON, plugins and built-in modules can be imported too;
+// their exports are the default export, and their properties named ones.
 type ModuleResolver struct {
 	runtime *Runtime
 	manager *modules.ModuleManager
+
+	loader *goja.Object           // the JS half of the loader, see esmLoader
+	mains  map[string]mainScript // main scripts, by path
 }
 
 // NewModuleResolver creates a new module resolver
@@ -20,6 +27,7 @@ func NewModuleResolver(runtime *Runtime, manager *modules.ModuleManager) *Module
 	return &ModuleResolver{
 		runtime: runtime,
 		manager: manager,
+		mains:   make(map[string]mainScript),
 	}
 }
 
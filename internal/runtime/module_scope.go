@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// runModule runs the source of a module loaded from path. While its top
+// level runs, __filename and __dirname name the module's own file, which
+// is the target of its symlinks unless preserve-symlinks is set; the
+// entrypoint's values are restored afterwards.
+func (r *Runtime) runModule(path, fileName, source string) (goja.Value, error) {
+	if !filepath.IsAbs(path) {
+		return r.runtime.RunScript(fileName, source)
+	}
+
+	global := r.runtime.GlobalObject()
+	filename, dirname := global.Get("__filename"), global.Get("__dirname")
+	global.Set("__filename", path)
+	global.Set("__dirname", filepath.Dir(path))
+	defer restoreGlobal(global, "__filename", filename)
+	defer restoreGlobal(global, "__dirname", dirname)
+
+	return r.runtime.RunScript(fileName, source)
+}
+
+// restoreGlobal puts back a global saved with Get, removing it if it was
+// not set
+func restoreGlobal(global *goja.Object, name string, value goja.Value) {
+	if value == nil {
+		global.Delete(name)
+		return
+	}
+	global.Set(name, value)
+}
+
+// moduleExtensions are tried, in order, for file specifiers without one
+var moduleExtensions = []string{".js", ".ts", ".json", "/index.js"}
+
+// ResolveModule returns the key identifying the module specifier names
+// when required from the module running now: the absolute path of a file,
+// with the extension require would add, or the specifier itself for
+// built-ins and anything that doesn't resolve. Specifiers naming the same
+// file from different directories, or with and without .js, get the same
+// key. It must be called on the JS thread.
+func (r *Runtime) ResolveModule(specifier string) string {
+	if r.moduleManager != nil {
+		specifier, _ = r.moduleManager.MapModuleName(specifier)
+	}
+	if _, exists := r.modules[specifier]; exists || r.moduleManager == nil {
+		return specifier
+	}
+
+	referrer := ""
+	if filename := r.runtime.GlobalObject().Get("__filename"); filename != nil {
+		if path, ok := filename.Export().(string); ok && filepath.IsAbs(path) {
+			referrer = path
+		}
+	}
+	resolved, ok := r.moduleManager.TryResolve(specifier, referrer)
+	if !ok || !filepath.IsAbs(resolved) {
+		return specifier
+	}
+	if info, err := os.Stat(resolved); err == nil && !info.IsDir() {
+		return resolved
+	}
+	for _, ext := range moduleExtensions {
+		if info, err := os.Stat(resolved + ext); err == nil && !info.IsDir() {
+			return resolved + ext
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestRuntimeSymlinkedModules(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "store", "counter")
+	if err := os.MkdirAll(pkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(pkg, "index.js"), []byte(`
+		globalThis.loads = (globalThis.loads || 0) + 1;
+		({ dirname: __dirname, count: () => globalThis.loads });
+	`), 0644)
+	if err := os.Symlink(pkg, filepath.Join(dir, "a")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	os.Symlink(pkg, filepath.Join(dir, "b"))
+	real, _ := filepath.EvalSymlinks(pkg)
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("links", fmt.Sprintf(`
+		const a = require(%q);
+		const b = require(%q);
+		[a === b, a.count(), a.dirname, typeof __dirname === 'undefined' || __dirname !== a.dirname].join('|');
+	`, filepath.Join(dir, "a", "index.js"), filepath.Join(dir, "b", "index.js")))
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if want := "true|1|" + real + "|true"; value != want {
+		t.Errorf("Symlinked modules = %v, want %v", value, want)
+	}
+}
+
+func TestRuntimeModuleNameMapper(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"test/fakes/sql.js": `({ query: () => "fake rows" });`,
+		"src/db/users.js":   `export const users = () => "users";`,
+		"src/app.js":        `import { users } from "@app/db/users.js"; export const app = "app:" + users();`,
+		"app.test.js": `
+			const sql = require("gode:sql");
+			const { app } = require("./src/app.js");
+			test("substitutes modules", () => {
+				expect(sql.query()).toBe("fake rows");
+				expect(app).toBe("app:users");
+			});
+		`,
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.PackageJSON{Name: "mapper", ProjectRoot: dir}
+	cfg.Gode.Test.ModuleNameMapper = config.ModuleNameMapper{
+		{Pattern: "^gode:sql$", Target: "./test/fakes/sql.js"},
+		{Pattern: "^@app/(.*)$", Target: "./src/$1"},
+	}
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(cfg); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	results, err := rt.RunTests([]string{filepath.Join(dir, "app.test.js")})
+	if err != nil {
+		t.Fatalf("RunTests() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Passed != 1 || results[0].Failed != 0 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,81 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileNames caches the names stack traces give files, which every require,
+// error and source map lookup needs. A file is named the first time it's
+// seen and keeps the name, as a module keeps its filename in Node, so a
+// later process.chdir doesn't rename the modules already loaded.
+type fileNames struct {
+	mu      sync.Mutex
+	records map[string]*fileRecord // by path or specifier
+}
+
+// fileRecord holds the names of one file
+type fileRecord struct {
+	relPath string            // from the working directory, with forward slashes
+	names   map[string]string // enhanced file names by module or project name
+}
+
+// record returns the record of path, creating it on first use. n.mu must
+// be held.
+func (n *fileNames) record(path string) *fileRecord {
+	rec, ok := n.records[path]
+	if !ok {
+		if n.records == nil {
+			n.records = make(map[string]*fileRecord)
+		}
+		rec = &fileRecord{relPath: relativePath(path)}
+		n.records[path] = rec
+	}
+	return rec
+}
+
+// relPath returns path relative to the working directory
+func (n *fileNames) relPath(path string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.record(path).relPath
+}
+
+// name returns "prefix:relPath" for path, the same string every time
+func (n *fileNames) name(prefix, path string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	rec := n.record(path)
+	name, ok := rec.names[prefix]
+	if !ok {
+		if rec.names == nil {
+			rec.names = make(map[string]string, 1)
+		}
+		name = prefix + ":" + rec.relPath
+		rec.names[prefix] = name
+	}
+	return name
+}
+
+// relativePath converts an absolute path to relative path from current working directory
+func relativePath(absolutePath string) string {
+	// Get current working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		// If we can't get cwd, just return the file name
+		return filepath.Base(absolutePath)
+	}
+
+	// Try to get relative path
+	relPath, err := filepath.Rel(cwd, absolutePath)
+	if err != nil {
+		// If we can't get relative path, such as for a file on another
+		// Windows drive, return absolute path
+		return filepath.ToSlash(absolutePath)
+	}
+
+	// Clean up the path, with forward slashes so that stack traces read the
+	// same on every platform
+	return filepath.ToSlash(filepath.Clean(relPath))
+}
@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileNames(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() failed: %v", err)
+	}
+	var names fileNames
+	path := filepath.Join(cwd, "src", "main.js")
+
+	if got := names.name("app", path); got != "app:src/main.js" {
+		t.Errorf("name() = %q, want app:src/main.js", got)
+	}
+	if got := names.name("lib", path); got != "lib:src/main.js" {
+		t.Errorf("name() = %q, want lib:src/main.js", got)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { names.name("app", path) }); allocs != 0 {
+		t.Errorf("name() of a known path allocated %v times", allocs)
+	}
+
+	// Files keep the names they were first given when the directory changes
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir() failed: %v", err)
+	}
+	defer os.Chdir(cwd)
+	if got := names.relPath(path); got != "src/main.js" {
+		t.Errorf("relPath() after chdir = %q, want src/main.js", got)
+	}
+	if got := names.relPath(filepath.Join(dir, "other.js")); got != "other.js" {
+		t.Errorf("relPath() of a new file = %q, want other.js", got)
+	}
+}
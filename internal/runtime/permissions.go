@@ -0,0 +1,215 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rizqme/gode/goja"
+	goderrors "github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+// setupPermissions builds the permission checker and, when enabled through
+// package.json ("gode.audit") or the GODE_AUDIT environment variable, the
+// audit logger
+func (r *Runtime) setupPermissions(cfg *config.PackageJSON) error {
+	var permCfg config.PermissionConfig
+	var auditCfg config.AuditConfig
+	if cfg != nil {
+		permCfg = cfg.Gode.Permissions
+		auditCfg = cfg.Gode.Audit
+	}
+
+	r.permissions = permissions.NewChecker(permCfg, r.projectRoot)
+
+	if permCfg.NetPolicy != "" {
+		path := permCfg.NetPolicy
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.projectRoot, path)
+		}
+		policy, err := permissions.LoadEgressPolicy(path)
+		if err != nil {
+			return err
+		}
+		r.permissions.SetEgressPolicy(policy)
+	}
+
+	if dest := os.Getenv("GODE_AUDIT"); dest != "" && dest != "0" && dest != "false" {
+		auditCfg.Enabled = true
+		auditCfg.Output = dest
+	}
+
+	if auditCfg.Enabled {
+		logger, err := permissions.OpenAuditLog(auditCfg.Output)
+		if err != nil {
+			return err
+		}
+		r.audit = logger
+	}
+
+	return nil
+}
+
+// Modules find the checks below through these interfaces
+var (
+	_ permissions.Enforcer    = (*Runtime)(nil)
+	_ permissions.NetEnforcer = (*Runtime)(nil)
+)
+
+// CheckPermission evaluates a permission-sensitive access and records it in
+// the audit log when auditing is enabled. It must be called from the JS
+// thread so the JavaScript call site can be captured.
+func (r *Runtime) CheckPermission(kind, resource string) error {
+	if r.permissions == nil {
+		return nil
+	}
+
+	k := permissions.Kind(kind)
+
+	// Walking the stack is only needed for per-module rules or auditing
+	var stack []goja.StackFrame
+	if r.permissions.HasModuleRules() || r.audit != nil {
+		stack = r.runtime.CaptureCallStack(0, nil)
+	}
+	modules := mergePackages(r.packagesOnStack(stack), schedulers(r.asyncContext.Current()))
+	decision := r.permissions.CheckModules(k, resource, modules)
+
+	if r.audit != nil {
+		r.audit.Record(permissions.AuditEntry{
+			Kind:     k,
+			Resource: resource,
+			Allowed:  decision.Allowed,
+			Rule:     decision.Rule,
+			CallSite: jsCallSite(stack),
+			Modules:  modules,
+		})
+	}
+
+	return decision.Err(k, resource)
+}
+
+// CheckNetURL checks network access to the host and port of rawURL. A
+// denial is a *permissions.DeniedError carrying the URL, which becomes a
+// PermissionDenied error in JS. It must be called from the JS thread.
+func (r *Runtime) CheckNetURL(rawURL string) error {
+	resource := permissions.NetResource(rawURL)
+	if resource == "" {
+		return nil
+	}
+
+	err := r.CheckPermission(string(permissions.KindNet), resource)
+	var denied *permissions.DeniedError
+	if errors.As(err, &denied) {
+		denied.URL = rawURL
+	}
+	return err
+}
+
+// tagModule records which dependency a loaded script belongs to. Scripts
+// that are not themselves a dependency inherit the package of the code that
+// required them, so a package's internal files stay inside its boundary.
+func (r *Runtime) tagModule(fileName, specifier string) {
+	if r.permissions == nil || (!r.permissions.HasModuleRules() && r.audit == nil) {
+		return
+	}
+
+	pkg := ""
+	if r.moduleManager != nil {
+		pkg = r.moduleManager.PackageOf(specifier)
+	}
+	if pkg == "" {
+		if callers := r.packagesOnStack(r.runtime.CaptureCallStack(0, nil)); len(callers) > 0 {
+			pkg = callers[0]
+		}
+	}
+	if pkg == "" {
+		return
+	}
+
+	if r.moduleTags == nil {
+		r.moduleTags = make(map[string]string)
+	}
+	r.moduleTags[fileName] = pkg
+}
+
+// packagesOnStack returns the distinct dependencies with code on the stack,
+// innermost first
+func (r *Runtime) packagesOnStack(stack []goja.StackFrame) []string {
+	var packages []string
+	seen := make(map[string]bool)
+	for _, frame := range stack {
+		pkg, tagged := r.moduleTags[frame.SrcName()]
+		if !tagged || seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// schedulersKey is the context key of the dependencies whose code scheduled
+// the running callback or promise reaction, directly or through callbacks
+// of their own
+type schedulersKey struct{}
+
+// schedulers returns the dependencies noted in ctx by noteSchedulers
+func schedulers(ctx context.Context) []string {
+	packages, _ := ctx.Value(schedulersKey{}).([]string)
+	return packages
+}
+
+// noteSchedulers adds the dependencies on the stack to the context of a
+// callback being scheduled, so that permissions are checked against them
+// when it runs. Without it, setTimeout(fs.readFileSync, 0, path) or
+// Promise.resolve(url).then(fetch) in a dependency would run with none of
+// its code on the stack and escape its rules.
+func (r *Runtime) noteSchedulers(ctx context.Context) context.Context {
+	if r.permissions == nil || (!r.permissions.HasModuleRules() && r.audit == nil) {
+		return ctx
+	}
+	inherited := schedulers(ctx)
+	packages := mergePackages(inherited, r.packagesOnStack(r.runtime.CaptureCallStack(0, nil)))
+	if len(packages) == len(inherited) {
+		return ctx
+	}
+	return context.WithValue(ctx, schedulersKey{}, packages)
+}
+
+// mergePackages returns the packages of a followed by those of b not in a
+func mergePackages(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	merged := append([]string(nil), a...)
+	for _, pkg := range b {
+		seen := false
+		for _, existing := range merged {
+			if existing == pkg {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			merged = append(merged, pkg)
+		}
+	}
+	return merged
+}
+
+// jsCallSite returns the innermost JavaScript frame as "file:line:column"
+func jsCallSite(stack []goja.StackFrame) string {
+	for _, frame := range stack {
+		if frame.SrcName() == "" {
+			continue // Native Go function
+		}
+		pos := frame.Position()
+		mapped := goderrors.MapPosition(frame.SrcName(), pos.Line, pos.Column)
+		return fmt.Sprintf("%s:%d:%d", mapped.Source, mapped.Line, mapped.Column)
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/internal/permissions"
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestPermissionsFollowScheduledCallbacks(t *testing.T) {
+	dir := t.TempDir()
+	evil := filepath.Join(dir, "node_modules", "evil", "index.js")
+	os.MkdirAll(filepath.Dir(evil), 0755)
+	// None of evil's code is on the stack when fetch runs
+	os.WriteFile(evil, []byte(`({
+		run(url) {
+			setTimeout(fetch, 0, url);
+			return Promise.resolve(url).then(fetch).then(() => 'allowed', (e) => e.name);
+		}
+	})`), 0644)
+	auditFile := filepath.Join(dir, "audit.log")
+
+	rt := New()
+	defer rt.Dispose()
+	err := rt.Configure(&config.PackageJSON{
+		Name:        "test",
+		ProjectRoot: dir,
+		Gode: config.GodeConfig{
+			Permissions: config.PermissionConfig{
+				AllowNet: []string{"example.com"},
+				Modules:  map[string]config.ModulePermissions{"evil": {AllowNet: []string{}}},
+			},
+			Audit: config.AuditConfig{Enabled: true, Output: auditFile},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	result, err := rt.RunScriptAsync("app", `
+		// fetch throws in the timer, with no JS caller to catch it
+		process.on('uncaughtException', () => {});
+		const evil = require(`+"`"+evil+"`"+`);
+		evil.run('https://example.com/').then((outcome) => new Promise((resolve) => setTimeout(() => resolve(outcome), 20)));
+	`)
+	if err != nil || result != "PermissionDenied" {
+		t.Fatalf("promise reaction = %v, %v; want PermissionDenied", result, err)
+	}
+
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry permissions.AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("bad audit entry %q: %v", line, err)
+		}
+		if entry.Kind != permissions.KindNet {
+			continue
+		}
+		if entry.Allowed || len(entry.Modules) != 1 || entry.Modules[0] != "evil" {
+			t.Errorf("net access = %+v, want it denied to evil", entry)
+		}
+		denied++
+	}
+	// One from the timer, one from the promise reaction
+	if denied != 2 {
+		t.Errorf("%d net accesses audited, want 2:\n%s", denied, data)
+	}
+}
+
+func TestRuntimeNetPolicy(t *testing.T) {
+	dir := t.TempDir()
+	policy := `{"default": "allow", "rules": [{"action": "deny", "host": "*.internal", "ports": ["1-1024"]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "egress.json"), []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	rt := New()
+	defer rt.Dispose()
+	err := rt.Configure(&config.PackageJSON{
+		Name:        "test",
+		ProjectRoot: dir,
+		Gode: config.GodeConfig{
+			Permissions: config.PermissionConfig{
+				DenyNet:   []string{"evil.example.com", "127.0.0.1"},
+				NetPolicy: "egress.json",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("egress", `
+		const attempt = (url) => {
+			try {
+				fetch(url);
+				return 'allowed';
+			} catch (e) {
+				return [e.name, e.code, e.url, e.rule].join('|');
+			}
+		};
+		[
+			attempt('https://evil.example.com/x'),
+			attempt('http://db.internal/'),
+			attempt('http://db.internal:5432/'),
+			attempt(`+"`"+server.URL+"`"+`).split('|')[0],
+		].join('\n');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"PermissionDenied|ERR_PERMISSION_DENIED|https://evil.example.com/x|deny-net: evil.example.com",
+		"PermissionDenied|ERR_PERMISSION_DENIED|http://db.internal/|net-policy rule 1: deny *.internal:1-1024",
+		"allowed",
+		"PermissionDenied",
+	}, "\n")
+	if value != want {
+		t.Errorf("Egress results =\n%v\nwant\n%v", value, want)
+	}
+	// A denied request is refused before it is sent
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Denied server received %d requests", n)
+	}
+
+	// A policy file that can't be loaded fails configuration
+	broken := New()
+	defer broken.Dispose()
+	err = broken.Configure(&config.PackageJSON{
+		Name:        "test",
+		ProjectRoot: dir,
+		Gode:        config.GodeConfig{Permissions: config.PermissionConfig{NetPolicy: "missing.json"}},
+	})
+	if err == nil {
+		t.Error("Expected a missing net policy to fail Configure()")
+	}
+}
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"reflect"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// pluginClasses are the JS classes made for the plugins.Classes plugins
+// export
+type pluginClasses struct {
+	key          *goja.Symbol // holds the *plugins.Instance behind an object
+	constructors map[*plugins.Class]*goja.Object
+	byType       map[reflect.Type]classPrototype // by instance type
+}
+
+// classPrototype is a class with the prototype of its instances
+type classPrototype struct {
+	class     *plugins.Class
+	prototype *goja.Object
+}
+
+// classes returns the plugin classes of r, creating them on first use
+func (r *Runtime) classes() *pluginClasses {
+	if r.pluginClasses == nil {
+		r.pluginClasses = &pluginClasses{
+			key:          goja.NewSymbol("gode.pluginInstance"),
+			constructors: make(map[*plugins.Class]*goja.Object),
+			byType:       make(map[reflect.Type]classPrototype),
+		}
+	}
+	return r.pluginClasses
+}
+
+// pluginClass returns the constructor of class, named after its type, with
+// a method on its prototype for each method of the type and close() to
+// release an instance, unless the type has a close method of its own. Live instances are released when the runtime shuts
+// down.
+func (r *Runtime) pluginClass(class *plugins.Class) goja.Value {
+	classes := r.classes()
+	if ctor, ok := classes.constructors[class]; ok {
+		return ctor
+	}
+
+	t := class.Type()
+	name := "PluginClass"
+	if t != nil {
+		name = t.Elem().Name()
+	}
+	var proto *goja.Object
+	ctor := r.runtime.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		// Called without new, this is whatever the function was called on
+		if !inherits(call.This, proto) {
+			panic(r.runtime.NewTypeError("Class constructor %s cannot be invoked without 'new'", name))
+		}
+		inst, err := class.Construct(r.pluginArgs(call.Arguments))
+		if err != nil {
+			r.pluginResult(nil, err) // throws
+		}
+		call.This.SetSymbol(classes.key, inst)
+		return nil
+	}).(*goja.Object)
+	ctor.DefineDataProperty("name", r.runtime.ToValue(name), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	proto = ctor.Get("prototype").(*goja.Object)
+	classes.constructors[class] = ctor
+	if t == nil {
+		// New is not a constructor; new throws why
+		return ctor
+	}
+
+	for _, name := range class.Methods() {
+		name := name
+		proto.Set(name, func(call goja.FunctionCall) goja.Value {
+			return r.pluginResult(r.thisInstance(call, class).Call(name, r.pluginArgs(call.Arguments)))
+		})
+	}
+	if proto.Get("close") == nil {
+		proto.Set("close", func(call goja.FunctionCall) goja.Value {
+			if err := r.thisInstance(call, class).Release(); err != nil {
+				panic(jserror.New(r.runtime, err))
+			}
+			return goja.Undefined()
+		})
+	}
+	classes.byType[t] = classPrototype{class: class, prototype: proto}
+
+	r.AddShutdownHook(func() {
+		class.Close()
+	})
+	return ctor
+}
+
+// inherits reports whether proto is in the prototype chain of obj
+func inherits(obj, proto *goja.Object) bool {
+	for p := obj.Prototype(); p != nil; p = p.Prototype() {
+		if p == proto {
+			return true
+		}
+	}
+	return false
+}
+
+// thisInstance returns the instance of class a method was called on,
+// throwing a TypeError if it was called on anything else
+func (r *Runtime) thisInstance(call goja.FunctionCall, class *plugins.Class) *plugins.Instance {
+	obj, _ := call.This.(*goja.Object)
+	inst := r.pluginInstance(obj)
+	if inst == nil || inst.Class() != class {
+		panic(r.runtime.NewTypeError("Method called on an object that is not a %s", class.Type().Elem().Name()))
+	}
+	return inst
+}
+
+// pluginInstance returns the instance behind obj, or nil if it isn't one
+func (r *Runtime) pluginInstance(obj *goja.Object) *plugins.Instance {
+	if obj == nil || r.pluginClasses == nil {
+		return nil
+	}
+	if v := obj.GetSymbol(r.pluginClasses.key); v != nil {
+		inst, _ := v.Export().(*plugins.Instance)
+		return inst
+	}
+	return nil
+}
+
+// pluginInstanceOf wraps value in a new object of its plugin class, if
+// its type is one
+func (r *Runtime) pluginInstanceOf(value interface{}) (*goja.Object, bool) {
+	if r.pluginClasses == nil {
+		return nil, false
+	}
+	c, ok := r.pluginClasses.byType[reflect.TypeOf(value)]
+	if !ok {
+		return nil, false
+	}
+	inst, err := c.class.Adopt(value)
+	if err != nil {
+		return nil, false
+	}
+	obj := r.runtime.NewObject()
+	obj.SetPrototype(c.prototype)
+	obj.SetSymbol(r.pluginClasses.key, inst)
+	return obj, true
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// Account is exported by bankPlugin as a class
+type Account struct {
+	owner   string
+	balance float64
+}
+
+func (a *Account) Deposit(amount float64) float64 { a.balance += amount; return a.balance }
+func (a *Account) Owner() string                  { return a.owner }
+func (a *Account) Balance() float64               { return a.balance }
+func (a *Account) Open(owner string) *Account     { return &Account{owner: owner} }
+
+func (a *Account) Transfer(to *Account, amount float64) error {
+	if amount > a.balance {
+		return fmt.Errorf("%s can't transfer %v", a.owner, amount)
+	}
+	a.balance -= amount
+	to.balance += amount
+	return nil
+}
+
+// bankPlugin exports the Account class and records its lifecycle
+type bankPlugin struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (p *bankPlugin) record(event string, v interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event+" "+v.(*Account).owner)
+}
+
+func (p *bankPlugin) Name() string                    { return "bank" }
+func (p *bankPlugin) Version() string                 { return "1.0.0" }
+func (p *bankPlugin) Initialize(rt interface{}) error { return nil }
+func (p *bankPlugin) Dispose() error                  { return nil }
+
+func (p *bankPlugin) Exports() map[string]interface{} {
+	return map[string]interface{}{
+		"Account": &plugins.Class{
+			New: func(owner string, balance float64) (*Account, error) {
+				if balance < 0 {
+					return nil, errors.New("balance must not be negative")
+				}
+				return &Account{owner: owner, balance: balance}, nil
+			},
+			Created:  func(v interface{}) { p.record("created", v) },
+			Released: func(v interface{}) { p.record("released", v) },
+		},
+	}
+}
+
+func TestPluginClasses(t *testing.T) {
+	rt := New()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	plugin := &bankPlugin{}
+	done := make(chan error, 1)
+	rt.QueueJSOperation(func() {
+		obj, err := plugins.NewBridge(rt).WrapPlugin(plugin)
+		if err == nil {
+			rt.runtime.Set("bank", obj.(*gojaObject).obj)
+		}
+		done <- err
+	})
+	if err := <-done; err != nil {
+		t.Fatalf("WrapPlugin() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("bank", `
+		const results = [];
+		const attempt = (fn) => { try { return fn(); } catch (e) { return e.name + ': ' + e.message; } };
+		const ann = new bank.Account('ann', 10);
+		results.push([ann instanceof bank.Account, bank.Account.name, ann.deposit(5), ann.owner()].join(','));
+		const bo = ann.open('bo');
+		results.push([bo instanceof bank.Account, bo.owner(), bo.balance()].join(','));
+		ann.transfer(bo, 4);
+		results.push(ann.balance() + ' ' + bo.balance());
+		results.push(attempt(() => ann.transfer(bo, 100)));
+		results.push(attempt(() => ann.transfer({}, 1)));
+		results.push(attempt(() => new bank.Account('cy', -1)));
+		results.push(attempt(() => bank.Account('cy', 1)));
+		results.push(attempt(() => ann.deposit.call({}, 1)));
+		class Savings extends bank.Account {
+			interest() { return this.balance() * 0.1; }
+		}
+		results.push(new Savings('dee', 20).interest());
+		ann.close();
+		ann.close();
+		results.push(attempt(() => ann.deposit(1)));
+		results.join('\n');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"true,Account,15,ann",
+		"true,bo,0",
+		"11 4",
+		"GoError: ann can't transfer 100",
+		"TypeError: argument 1: expected a Account instance, got an object",
+		"GoError: balance must not be negative",
+		"TypeError: Class constructor Account cannot be invoked without 'new'",
+		"TypeError: Method called on an object that is not a Account",
+		"2",
+		"GoError: Account has been closed",
+	}, "\n")
+	if value != want {
+		t.Errorf("got\n%v\nwant\n%v", value, want)
+	}
+
+	// Instances still open are released when the runtime shuts down
+	rt.Dispose()
+	plugin.mu.Lock()
+	defer plugin.mu.Unlock()
+	sort.Strings(plugin.events[4:])
+	if got := strings.Join(plugin.events, ","); got != "created ann,created bo,created dee,released ann,released bo,released dee" {
+		t.Errorf("lifecycle = %s", got)
+	}
+}
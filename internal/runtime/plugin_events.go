@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// pluginHost is what a plugin's Initialize receives in place of the bare
+// runtime. It has every Runtime method, so TaskRunner, ServiceRegistry and
+// the rest keep working, plus Emit for events that belong to the plugin.
+type pluginHost struct {
+	*Runtime
+	name string
+}
+
+// PluginHost returns the value passed to the Initialize of plugin name
+func (r *Runtime) PluginHost(name string) interface{} {
+	return &pluginHost{Runtime: r, name: name}
+}
+
+// Emit delivers a named event, such as "download:progress", to listeners
+// on the plugin module's events emitter. It implements plugins.Emitter and
+// may be called from any goroutine; events with no listeners are dropped.
+// Plugins can emit in bulk, so events go in the background lane.
+func (h *pluginHost) Emit(event string, data interface{}) {
+	h.QueueJSOperationWithPriority(PriorityBackground, func() {
+		e := h.pluginEmitter(h.name)
+		if len(e.listeners[event]) == 0 {
+			return
+		}
+		e.emit(event, h.pluginEventValue(data))
+	})
+}
+
+// PluginEvents returns the emitter exposed as the events property of the
+// plugin module name. It must run on the JS thread.
+func (r *Runtime) PluginEvents(name string) interface{} {
+	obj := r.runtime.NewObject()
+	r.pluginEmitter(name).install(r.runtime, obj)
+	return obj
+}
+
+// PluginReloaded is called once a plugin has been reloaded, see
+// plugins.Registry.ReloadPlugin, and emits reload on the plugin module's
+// events emitter with its name, version and path. Listeners run after the
+// reload has finished, so they may reload it again.
+func (r *Runtime) PluginReloaded(info *plugins.PluginInfo) {
+	payload := map[string]interface{}{
+		"name":    info.Name,
+		"version": info.Version,
+		"path":    info.Path,
+	}
+	name := info.Name
+	r.QueueJSOperation(func() {
+		r.pluginEmitter(name).emit("reload", r.runtime.ToValue(payload))
+	})
+}
+
+func (r *Runtime) pluginEmitter(name string) *emitter {
+	if r.pluginEmitters == nil {
+		r.pluginEmitters = make(map[string]*emitter)
+	}
+	e, ok := r.pluginEmitters[name]
+	if !ok {
+		e = newEmitter("plugin " + name)
+		r.pluginEmitters[name] = e
+	}
+	return e
+}
+
+// pluginEventValue converts an event payload for JS. Errors become Error
+// objects, byte slices Uint8Arrays, and structs plain objects keyed by their
+// JSON names, so plugins can emit the same types they return.
+func (r *Runtime) pluginEventValue(data interface{}) goja.Value {
+	switch v := data.(type) {
+	case nil:
+		return goja.Undefined()
+	case error:
+		return jserror.New(r.runtime, v)
+	case []byte:
+		array, err := r.runtime.New(r.runtime.Get("Uint8Array"), r.runtime.ToValue(r.runtime.NewArrayBuffer(append([]byte(nil), v...))))
+		if err != nil {
+			panic(err)
+		}
+		return array
+	}
+
+	t := reflect.TypeOf(data)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		if encoded, err := json.Marshal(data); err == nil {
+			var plain interface{}
+			if json.Unmarshal(encoded, &plain) == nil {
+				return r.runtime.ToValue(plain)
+			}
+		}
+	}
+	return r.runtime.ToValue(data)
+}
@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+func TestRuntimePluginEvents(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	rt.QueueJSOperation(func() {
+		rt.runtime.Set("events", rt.PluginEvents("download"))
+		close(done)
+	})
+	<-done
+
+	if _, err := rt.RunScript("listen", `
+		globalThis.seen = [];
+		events.on('download:progress', p => seen.push(p.bytes + '/' + p.total));
+	`); err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+
+	type progress struct {
+		Bytes int `json:"bytes"`
+		Total int `json:"total"`
+	}
+	host := rt.PluginHost("download").(plugins.Emitter)
+	host.Emit("download:progress", progress{Bytes: 5, Total: 10})
+	host.Emit("download:done", nil)
+
+	result, err := rt.RunScript("check", "seen.join(',')")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if result != "5/10" {
+		t.Errorf("Expected 5/10, got %v", result)
+	}
+}
@@ -0,0 +1,264 @@
+package runtime
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/plugins"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// maxPluginArgDepth bounds how deeply the objects passed to plugin
+// functions may nest, so that cyclic ones throw instead of overflowing
+const maxPluginArgDepth = 1000
+
+// pluginExport converts a value a plugin exports to JS. Functions are
+// called through plugins.Function, which converts their arguments to the
+// parameter types and their results back; other values are marshaled the
+// same way.
+func (r *Runtime) pluginExport(value interface{}) goja.Value {
+	if v, ok := value.(goja.Value); ok {
+		return v
+	}
+	plain, err := plugins.Marshal(value)
+	if err != nil {
+		return r.runtime.ToValue(value)
+	}
+	return r.pluginValue(plain)
+}
+
+// pluginValue converts a plain value from plugins.Marshal to JS: byte
+// slices to Uint8Arrays, times to Dates, errors to Error objects, tables
+// to arrays of row objects, streams to async iterables, classes to
+// constructors and values of their types to instances
+func (r *Runtime) pluginValue(value interface{}) goja.Value {
+	switch v := value.(type) {
+	case []float64:
+		return r.floatArray(v)
+	case []int64:
+		return r.intArray(v)
+	case []string:
+		return r.stringArray(v)
+	case []bool:
+		return r.boolArray(v)
+	case plugins.Table:
+		return r.tableArray(v)
+	case *plugins.Class:
+		return r.pluginClass(v)
+	case nil:
+		return goja.Null()
+	case goja.Value:
+		return v
+	case *plugins.Function:
+		return r.pluginFunction(v)
+	case *plugins.Stream:
+		return r.pluginStream(v)
+	case error:
+		return jserror.New(r.runtime, v)
+	case time.Time:
+		return r.date(v)
+	case []byte:
+		array, err := r.runtime.New(r.runtime.Get("Uint8Array"), r.runtime.ToValue(r.runtime.NewArrayBuffer(v)))
+		if err != nil {
+			panic(err)
+		}
+		return array
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = r.pluginValue(item)
+		}
+		return r.runtime.NewArray(items...)
+	case map[string]interface{}:
+		obj := r.runtime.NewObject()
+		for key, item := range v {
+			obj.Set(key, r.pluginValue(item))
+		}
+		return obj
+	}
+	if obj, ok := r.pluginInstanceOf(value); ok {
+		return obj
+	}
+	return r.runtime.ToValue(value)
+}
+
+// pluginFunction exposes fn to JS. Arguments that don't fit its parameters
+// throw a TypeError, and the error it returns is thrown; an exception a
+// callback threw is rethrown as it was.
+func (r *Runtime) pluginFunction(fn *plugins.Function) goja.Value {
+	return r.runtime.ToValue(func(call goja.FunctionCall) goja.Value {
+		return r.pluginResult(fn.Call(r.pluginArgs(call.Arguments)))
+	})
+}
+
+// pluginArgs exports the arguments of a call to a plugin function
+func (r *Runtime) pluginArgs(arguments []goja.Value) []interface{} {
+	args := make([]interface{}, len(arguments))
+	for i, arg := range arguments {
+		args[i] = r.pluginArg(arg, 0)
+	}
+	return args
+}
+
+// pluginResult returns what a plugin function returned to JS, or throws
+// its error: a TypeError for arguments that didn't convert, and the
+// exception itself for one a callback threw
+func (r *Runtime) pluginResult(result interface{}, err error) goja.Value {
+	if err != nil {
+		var argErr *plugins.ArgumentError
+		var exception *goja.Exception
+		switch {
+		case errors.As(err, &argErr):
+			panic(r.runtime.NewTypeError(err.Error()))
+		case errors.As(err, &exception):
+			panic(exception)
+		}
+		panic(jserror.New(r.runtime, err))
+	}
+	if result == nil {
+		return goja.Undefined()
+	}
+	return r.pluginValue(result)
+}
+
+// pluginArg exports a JS value for plugins.Function: functions become
+// Callbacks, instances of plugin classes their Go values, Buffers, typed
+// arrays and ArrayBuffers byte slices, Dates times, arrays []interface{}
+// and other objects map[string]interface{}
+func (r *Runtime) pluginArg(value goja.Value, depth int) interface{} {
+	if depth > maxPluginArgDepth {
+		panic(r.runtime.NewTypeError("plugin arguments are nested too deeply, or cyclic"))
+	}
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return value.Export()
+	}
+	if fn, ok := goja.AssertFunction(obj); ok {
+		return r.pluginCallback(fn)
+	}
+	if inst := r.pluginInstance(obj); inst != nil {
+		return inst.Value()
+	}
+	if data, ok := pluginBytes(obj); ok {
+		return data
+	}
+	switch obj.ClassName() {
+	case "Date":
+		return obj.Export()
+	case "Array":
+		length := int(obj.Get("length").ToInteger())
+		items := make([]interface{}, length)
+		for i := range items {
+			items[i] = r.pluginArg(obj.Get(strconv.Itoa(i)), depth+1)
+		}
+		return items
+	}
+	fields := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		fields[key] = r.pluginArg(obj.Get(key), depth+1)
+	}
+	return fields
+}
+
+// pluginBytes returns the bytes of a Buffer, typed array or ArrayBuffer
+func pluginBytes(obj *goja.Object) ([]byte, bool) {
+	if buffer, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return append([]byte(nil), buffer.Bytes()...), true
+	}
+	v := obj.Get("buffer")
+	if v == nil {
+		return nil, false
+	}
+	buffer, ok := v.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, false
+	}
+	data := buffer.Bytes()
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+		return nil, false
+	}
+	return append([]byte(nil), data[offset:offset+length]...), true
+}
+
+// pluginCallback makes fn callable by plugin functions. It must be called
+// on the JS thread, as the bridge does with the callbacks it passes on.
+func (r *Runtime) pluginCallback(fn goja.Callable) plugins.Callback {
+	return func(args ...interface{}) (interface{}, error) {
+		values := make([]goja.Value, len(args))
+		for i, arg := range args {
+			values[i] = r.pluginValue(arg)
+		}
+		result, err := fn(goja.Undefined(), values...)
+		if err != nil {
+			return nil, err
+		}
+		return r.pluginArg(result, 0), nil
+	}
+}
+
+// pluginStream exposes a channel a plugin function returned as an async
+// iterator. Values are received in the background, one next() at a time
+// in the order they were asked for; the runtime stays alive while one is
+// pending.
+func (r *Runtime) pluginStream(stream *plugins.Stream) goja.Value {
+	var previous chan struct{}
+	var finished int32 // set once the stream has ended or failed
+
+	result := func(value goja.Value, done bool) goja.Value {
+		obj := r.runtime.NewObject()
+		obj.Set("value", value)
+		obj.Set("done", done)
+		return obj
+	}
+
+	it := r.runtime.NewObject()
+	it.Set("next", func() goja.Value {
+		p, resolver := promise.New(r.runtime, r)
+		wait, turn := previous, make(chan struct{})
+		previous = turn
+		go func() {
+			defer close(turn)
+			if wait != nil {
+				<-wait
+			}
+			var value interface{}
+			var ok bool
+			var err error
+			if atomic.LoadInt32(&finished) == 0 {
+				value, ok, err = stream.Next()
+			}
+			if err != nil || !ok {
+				atomic.StoreInt32(&finished, 1)
+			}
+			resolver.SettleWith(func() (interface{}, error) {
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					return result(goja.Undefined(), true), nil
+				}
+				return result(r.pluginValue(value), false), nil
+			})
+		}()
+		return p
+	})
+	it.Set("return", func(value goja.Value) goja.Value {
+		atomic.StoreInt32(&finished, 1)
+		p, resolver := promise.New(r.runtime, r)
+		resolver.Resolve(result(value, true))
+		return p
+	})
+	it.SetSymbol(goja.SymAsyncIterator, func(call goja.FunctionCall) goja.Value {
+		return call.This
+	})
+	return it
+}
@@ -0,0 +1,242 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// typedPlugin exports functions that take and return typed values
+type typedPlugin struct{}
+
+type order struct {
+	ID    string         `json:"id"`
+	Items []orderItem    `json:"items"`
+	Notes map[string]int `json:"notes"`
+}
+
+type orderItem struct {
+	SKU string  `js:"sku"`
+	Qty int     `js:"qty"`
+	Net float64 `js:"net"`
+}
+
+func (typedPlugin) Name() string                    { return "shop" }
+func (typedPlugin) Version() string                 { return "1.0.0" }
+func (typedPlugin) Initialize(rt interface{}) error { return nil }
+func (typedPlugin) Dispose() error                  { return nil }
+
+func (typedPlugin) Exports() map[string]interface{} {
+	return map[string]interface{}{
+		"total": func(o order) (float64, error) {
+			if len(o.Items) == 0 {
+				return 0, errors.New("order " + o.ID + " is empty")
+			}
+			total := 0.0
+			for _, item := range o.Items {
+				total += float64(item.Qty) * item.Net
+			}
+			return total, nil
+		},
+		"split": func(o order) []orderItem { return o.Items },
+		"each": func(items []int, fn func(int) string) string {
+			out := ""
+			for _, n := range items {
+				out += fn(n)
+			}
+			return out
+		},
+		"count": func(n int) <-chan string {
+			ch := make(chan string)
+			go func() {
+				defer close(ch)
+				for i := 1; i <= n; i++ {
+					ch <- fmt.Sprint("tick ", i)
+				}
+			}()
+			return ch
+		},
+		"bytes":  func(data []byte) []byte { return append(data, '!') },
+		"prices": func() []float32 { return []float32{1.5, 2} },
+		"stock": func() plugins.Table {
+			return plugins.Table{
+				Columns: []string{"sku", "qty", "tags"},
+				Data:    []interface{}{[]string{"a", "b"}, []int{3, 0}, [][]string{{"new"}, nil}},
+			}
+		},
+	}
+}
+
+func TestPluginTypedValues(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	rt.QueueJSOperation(func() {
+		obj, err := plugins.NewBridge(rt).WrapPlugin(typedPlugin{})
+		if err == nil {
+			rt.runtime.Set("shop", obj.(*gojaObject).obj)
+		}
+		done <- err
+	})
+	if err := <-done; err != nil {
+		t.Fatalf("WrapPlugin() failed: %v", err)
+	}
+
+	value, err := rt.RunScriptAsync("shop", `
+		(async () => {
+			const results = [];
+			const order = { id: 'o1', items: [{ sku: 'a', qty: 2, net: 1.5 }, { sku: 'b', qty: 1, net: 4 }], notes: { gift: 1 } };
+			results.push(shop.total(order));
+			results.push(JSON.stringify(shop.split(order)));
+			try { shop.total({ id: 'o2', items: [] }); } catch (e) { results.push(e instanceof Error && !(e instanceof TypeError) && e.message); }
+			try { shop.total({ id: 'o3', items: [{ qty: 'many' }] }); } catch (e) { results.push(e instanceof TypeError && e.message); }
+			try { shop.total({ id: 'o4', items: [{ qty: 1.5 }] }); } catch (e) { results.push(e.name); }
+			results.push(await new Promise((resolve) => setTimeout(() => resolve(shop.each([1, 2], (n) => '<' + n + '>')), 0)));
+			const ticks = [];
+			const it = shop.count(3)[Symbol.asyncIterator]();
+			for (;;) {
+				const { value, done } = await it.next();
+				if (done) {
+					break;
+				}
+				ticks.push(value);
+			}
+			results.push(ticks.join(','));
+			const out = shop.bytes(new Uint8Array([104, 105]));
+			results.push(out instanceof Uint8Array && String.fromCharCode(...out));
+			const prices = shop.prices();
+			results.push(Array.isArray(prices) && prices.join(','));
+			results.push(JSON.stringify(shop.stock()));
+			return results.join('\n');
+		})()
+	`)
+	if err != nil {
+		t.Fatalf("RunScriptAsync() failed: %v", err)
+	}
+
+	want := "7\n" +
+		`[{"net":1.5,"qty":2,"sku":"a"},{"net":4,"qty":1,"sku":"b"}]` + "\n" +
+		"order o2 is empty\n" +
+		"argument 1: items: [0]: qty: expected int, got a string\n" +
+		"TypeError\n" +
+		"<1><2>\n" +
+		"tick 1,tick 2,tick 3\n" +
+		"hi!\n" +
+		"1.5,2\n" +
+		`[{"qty":3,"sku":"a","tags":["new"]},{"qty":0,"sku":"b","tags":null}]`
+	if value != want {
+		t.Errorf("got\n%v\nwant\n%v", value, want)
+	}
+}
+
+// benchmarkJS runs fn b.N times on the JS thread of a configured runtime
+func benchmarkJS(b *testing.B, fn func(r *Runtime)) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		b.Fatalf("Configure() failed: %v", err)
+	}
+	done := make(chan struct{})
+	rt.QueueJSOperation(func() {
+		defer close(done)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fn(rt)
+		}
+	})
+	<-done
+}
+
+func benchmarkFunction(b *testing.B, r *Runtime, src string) goja.Callable {
+	value, err := r.runtime.RunString(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	fn, _ := goja.AssertFunction(value)
+	return fn
+}
+
+// BenchmarkPluginNumberArray converts large slices of numbers, boxed in
+// an []interface{} as values without a fast path are, and as they are
+func BenchmarkPluginNumberArray(b *testing.B) {
+	floats, ints := make([]float64, 10000), make([]int, 10000)
+	for i := range floats {
+		floats[i], ints[i] = float64(i)/2, i
+	}
+	boxed := func(r *Runtime, values interface{}) goja.Value {
+		v := reflect.ValueOf(values)
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, _ := plugins.Marshal(v.Index(i).Interface())
+			items[i] = item
+		}
+		return r.pluginValue(items)
+	}
+
+	for _, bench := range []struct {
+		name    string
+		convert func(r *Runtime) goja.Value
+	}{
+		{"floats/interface", func(r *Runtime) goja.Value { return boxed(r, floats) }},
+		{"floats/typed", func(r *Runtime) goja.Value { return r.pluginExport(floats) }},
+		{"ints/interface", func(r *Runtime) goja.Value { return boxed(r, ints) }},
+		{"ints/typed", func(r *Runtime) goja.Value { return r.pluginExport(ints) }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			benchmarkJS(b, func(r *Runtime) { bench.convert(r) })
+		})
+	}
+}
+
+// BenchmarkPluginResultSet converts the rows of a query result, as a
+// database plugin would return them by row and by column, and reads every
+// field from JS
+func BenchmarkPluginResultSet(b *testing.B) {
+	const rows = 1000
+	ids, names, scores, active := make([]int64, rows), make([]string, rows), make([]float64, rows), make([]bool, rows)
+	maps := make([]map[string]interface{}, rows)
+	for i := 0; i < rows; i++ {
+		ids[i], names[i], scores[i], active[i] = int64(i), fmt.Sprint("user", i), float64(i)/3, i%2 == 0
+		maps[i] = map[string]interface{}{"id": ids[i], "name": names[i], "score": scores[i], "active": active[i]}
+	}
+	table := plugins.Table{
+		Columns: []string{"id", "name", "score", "active"},
+		Data:    []interface{}{ids, names, scores, active},
+	}
+	const read = `(rows) => {
+		let n = 0;
+		for (let i = 0; i < rows.length; i++) {
+			const row = rows[i];
+			n += row.id + row.name.length + row.score + (row.active ? 1 : 0);
+		}
+		return n;
+	}`
+
+	for _, bench := range []struct {
+		name    string
+		convert func(r *Runtime) goja.Value
+	}{
+		{"maps", func(r *Runtime) goja.Value { return r.pluginExport(maps) }},
+		{"table", func(r *Runtime) goja.Value { return r.pluginExport(table) }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var fn goja.Callable
+			benchmarkJS(b, func(r *Runtime) {
+				if fn == nil {
+					fn = benchmarkFunction(b, r, read)
+				}
+				if _, err := fn(goja.Undefined(), bench.convert(r)); err != nil {
+					b.Fatal(err)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package runtime
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/modules"
+)
+
+// Preload requires each module before the entrypoint runs, like node -r.
+// Paths starting with ./ or ../ resolve against the working directory;
+// anything else goes through normal module resolution. A preloaded module
+// can call require.addHook to transform the sources loaded after it.
+func (r *Runtime) Preload(specifiers []string) error {
+	for _, specifier := range specifiers {
+		if modules.IsRelative(specifier) {
+			abs, err := filepath.Abs(specifier)
+			if err != nil {
+				return err
+			}
+			specifier = abs
+		}
+
+		done := make(chan error, 1)
+		r.QueueJSOperation(func() {
+			require, ok := goja.AssertFunction(r.runtime.Get("require"))
+			if !ok {
+				done <- fmt.Errorf("require is not available")
+				return
+			}
+			_, err := require(goja.Undefined(), r.runtime.ToValue(specifier))
+			done <- err
+		})
+		var err error
+		select {
+		case err = <-done:
+		case <-r.exited:
+		}
+		if exitErr := r.exitedWith(); exitErr != nil {
+			return exitErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to preload %s: %w", specifier, err)
+		}
+	}
+	return nil
+}
+
+// installRequireHooks adds require.addHook(fn). Hooks are called as
+// fn(source, filename) for every script loaded through require and for the
+// entrypoint, in the order they were added; returning a string replaces the
+// source. addHook returns a function that removes the hook.
+func (r *Runtime) installRequireHooks() {
+	require := r.runtime.Get("require").ToObject(r.runtime)
+	require.Set("addHook", func(call goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(r.runtime.NewTypeError("hook must be a function"))
+		}
+
+		hook := &requireHook{fn: fn}
+		r.requireHooks = append(r.requireHooks, hook)
+
+		return r.runtime.ToValue(func() {
+			for i, h := range r.requireHooks {
+				if h == hook {
+					r.requireHooks = append(r.requireHooks[:i:i], r.requireHooks[i+1:]...)
+					return
+				}
+			}
+		})
+	})
+}
+
+type requireHook struct {
+	fn goja.Callable
+}
+
+// applyRequireHooks runs the source of a script through the installed hooks.
+// It must be called on the JS thread; a throwing hook propagates.
+func (r *Runtime) applyRequireHooks(source, fileName string) string {
+	for _, hook := range append([]*requireHook(nil), r.requireHooks...) {
+		result, err := hook.fn(goja.Undefined(), r.runtime.ToValue(source), r.runtime.ToValue(fileName))
+		if err != nil {
+			panic(err)
+		}
+		if result != nil && !goja.IsUndefined(result) && !goja.IsNull(result) {
+			source = result.String()
+		}
+	}
+	return source
+}
+
+// runScriptWithHooks runs an entrypoint loaded from path after passing it
+// through the require hooks, returning a throwing hook as an error
+func (r *Runtime) runScriptWithHooks(path, fileName, source string) (value goja.Value, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if e, ok := recovered.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", recovered)
+			}
+		}
+	}()
+	source = r.applyRequireHooks(source, fileName)
+	r.registerSourceMap(fileName, path, source)
+	return r.runtime.RunScript(fileName, source)
+}
+
+// stripShebang blanks out a leading "#!" interpreter line so scripts can be
+// run directly. The newline is kept so line numbers stay the same.
+func stripShebang(source string) string {
+	if !strings.HasPrefix(source, "#!") {
+		return source
+	}
+	if i := strings.IndexByte(source, '\n'); i >= 0 {
+		return source[i:]
+	}
+	return ""
+}
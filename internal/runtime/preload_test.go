@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuntimePreloadRequireHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	preload := filepath.Join(tmpDir, "instrument.js")
+	os.WriteFile(preload, []byte(`
+globalThis.preloaded = true;
+require.addHook(function(source, filename) {
+	return source.replace("__VERSION__", "1.2.3");
+});
+`), 0644)
+
+	entry := filepath.Join(tmpDir, "app.js")
+	os.WriteFile(entry, []byte(`globalThis.version = "__VERSION__";`), 0644)
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	if err := rt.Preload([]string{preload}); err != nil {
+		t.Fatalf("Preload() failed: %v", err)
+	}
+	if err := rt.Run(entry); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+
+	result, err := rt.RunScript("check", "preloaded + ':' + version")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if result != "true:1.2.3" {
+		t.Errorf("Expected the preload to run and transform the entrypoint, got %v", result)
+	}
+}
+
+func TestStripShebang(t *testing.T) {
+	tests := map[string]string{
+		"#!/usr/bin/env gode\nconsole.log(1)": "\nconsole.log(1)",
+		"#!/usr/bin/env gode":                 "",
+		"console.log('#!')":                   "console.log('#!')",
+	}
+	for input, want := range tests {
+		if got := stripShebang(input); got != want {
+			t.Errorf("stripShebang(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
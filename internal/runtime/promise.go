@@ -0,0 +1,119 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/promise"
+)
+
+// NewPromiseResolver creates a pending JS Promise for Go code to return to
+// a script, and a resolver that settles it from any goroutine. It must be
+// called on the JS thread, e.g. from a function called by JavaScript. The
+// runtime stays alive until the promise is settled.
+func (r *Runtime) NewPromiseResolver() (goja.Value, *promise.Resolver) {
+	return promise.New(r.runtime, r)
+}
+
+// NewPromise creates a JS Promise for a Go function called by a script to
+// return, like new Promise(executor) in JS. executor runs straight away;
+// resolve and reject may be called later from any goroutine and settle the
+// promise on the JS thread, so it chains and works with await. An error
+// passed to reject becomes a JS Error, and a panic in executor rejects the
+// promise. It must be called on the JS thread.
+//
+// The result is an interface{} so that plugins can use it without
+// importing goja, through an interface of their own:
+//
+//	type promiseFactory interface {
+//		NewPromise(executor func(resolve, reject func(interface{}))) interface{}
+//	}
+func (r *Runtime) NewPromise(executor func(resolve, reject func(interface{}))) interface{} {
+	p, resolver := promise.New(r.runtime, r)
+	func() {
+		defer func() {
+			if v := recover(); v != nil {
+				if err, ok := v.(error); ok {
+					resolver.Reject(err)
+				} else {
+					resolver.Reject(fmt.Errorf("%v", v))
+				}
+			}
+		}()
+		executor(resolver.Resolve, resolver.Reject)
+	}()
+	return p
+}
+
+// RunScriptAsync runs a script and, if its completion value is a promise or
+// another thenable, waits for it to settle. It returns the fulfilled value
+// exported to Go, or the rejection reason as an error, or an ExitError if
+// the script exits first. It must not be called on the JS thread.
+func (r *Runtime) RunScriptAsync(name, source string) (interface{}, error) {
+	type outcome struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan outcome, 1)
+
+	r.QueueJSOperation(func() {
+		value, err := r.runtime.RunScript(name, source)
+		if err != nil {
+			done <- outcome{err: err}
+			return
+		}
+		r.await(value, func(v goja.Value) {
+			done <- outcome{value: exportValue(v)}
+		}, func(reason goja.Value) {
+			done <- outcome{err: rejectionError(reason)}
+		})
+	})
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-r.exited:
+		// process.exit, an uncaught error or a panic stopped the script
+		return nil, r.exitedWith()
+	}
+}
+
+// await calls onFulfilled or onRejected, on the JS thread, once value
+// settles. Values that are not thenables are passed to onFulfilled
+// straight away. It must run on the JS thread.
+func (r *Runtime) await(value goja.Value, onFulfilled, onRejected func(goja.Value)) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		onFulfilled(value)
+		return
+	}
+	then, ok := goja.AssertFunction(obj.Get("then"))
+	if !ok {
+		onFulfilled(value)
+		return
+	}
+
+	// A pending promise keeps the runtime alive, like pending I/O
+	release := r.KeepAlive()
+	_, err := then(obj, r.runtime.ToValue(func(v goja.Value) {
+		release()
+		onFulfilled(v)
+	}), r.runtime.ToValue(func(reason goja.Value) {
+		release()
+		onRejected(reason)
+	}))
+	if err != nil {
+		release()
+		onRejected(r.runtime.ToValue(err.Error()))
+	}
+}
+
+// rejectionError describes a promise rejection reason as a Go error
+func rejectionError(reason goja.Value) error {
+	if obj, ok := reason.(*goja.Object); ok {
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			return fmt.Errorf("promise rejected: %s", stack.String())
+		}
+	}
+	return fmt.Errorf("promise rejected: %s", reason)
+}
@@ -0,0 +1,110 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rizqme/gode/goja"
+)
+
+func TestRuntimePromiseResolver(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	// double resolves later from another goroutine; fail rejects
+	rt.SetGlobal("double", func(n int) goja.Value {
+		p, resolver := rt.NewPromiseResolver()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			resolver.Resolve(n * 2)
+			resolver.Resolve(0) // ignored: already settled
+		}()
+		return p
+	})
+	rt.SetGlobal("fail", func() goja.Value {
+		p, resolver := rt.NewPromiseResolver()
+		go resolver.Reject(fmt.Errorf("disk on fire"))
+		return p
+	})
+
+	result, err := rt.RunScriptAsync("await", "(async () => (await double(21)) + 1)()")
+	if err != nil || result != int64(43) {
+		t.Errorf("RunScriptAsync() = %v, %v", result, err)
+	}
+
+	_, err = rt.RunScriptAsync("reject", "fail()")
+	if err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("Expected the rejection as an error, got %v", err)
+	}
+
+	result, err = rt.RunScriptAsync("caught", "fail().catch(e => e.message)")
+	if err != nil || result != "disk on fire" {
+		t.Errorf("Expected the rejection to be catchable, got %v, %v", result, err)
+	}
+
+	// Plain values are returned as they are
+	if result, err := rt.RunScriptAsync("plain", "'done'"); err != nil || result != "done" {
+		t.Errorf("RunScriptAsync(plain) = %v, %v", result, err)
+	}
+}
+
+func TestRuntimeNewPromise(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	// The interface a plugin declares to reach NewPromise
+	var factory interface {
+		NewPromise(executor func(resolve, reject func(interface{}))) interface{}
+	} = rt
+
+	rt.SetGlobal("add", func(a, b int) interface{} {
+		return factory.NewPromise(func(resolve, reject func(interface{})) {
+			go func() {
+				time.Sleep(5 * time.Millisecond)
+				resolve(a + b)
+			}()
+		})
+	})
+	rt.SetGlobal("divide", func(a, b int) interface{} {
+		return factory.NewPromise(func(resolve, reject func(interface{})) {
+			if b == 0 {
+				go reject(fmt.Errorf("division by zero"))
+				return
+			}
+			resolve(a / b)
+		})
+	})
+	rt.SetGlobal("broken", func() interface{} {
+		return factory.NewPromise(func(resolve, reject func(interface{})) {
+			panic("executor failed")
+		})
+	})
+
+	result, err := rt.RunScriptAsync("chain", "add(1, 2).then(n => add(n, 3)).then(n => divide(n, 2))")
+	if err != nil || result != int64(3) {
+		t.Errorf("Chained promises = %v, %v", result, err)
+	}
+
+	result, err = rt.RunScriptAsync("await", `(async () => {
+		try {
+			await divide(1, 0);
+		} catch (e) {
+			return (e instanceof Error) + ':' + e.message;
+		}
+	})()`)
+	if err != nil || result != "true:division by zero" {
+		t.Errorf("Awaited rejection = %v, %v", result, err)
+	}
+
+	if _, err := rt.RunScriptAsync("panic", "broken()"); err == nil || !strings.Contains(err.Error(), "executor failed") {
+		t.Errorf("Expected a panicking executor to reject, got %v", err)
+	}
+}
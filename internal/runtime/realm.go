@@ -0,0 +1,393 @@
+package runtime
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/internal/modules"
+	"github.com/rizqme/gode/internal/modules/esm"
+)
+
+// shadowRealmSetup defines ShadowRealm. It is given the native functions
+// create(), which returns the handle of a new realm, evaluate(handle,
+// source) and importValue(handle, specifier, exportName).
+const shadowRealmSetup = `(function (native) {
+	"use strict";
+	const realms = new WeakMap();
+
+	function get(realm, method) {
+		const handle = realms.get(realm);
+		if (!handle) {
+			throw new TypeError("ShadowRealm.prototype." + method + " called on an object that is not a ShadowRealm");
+		}
+		return handle;
+	}
+
+	class ShadowRealm {
+		constructor() {
+			realms.set(this, native.create());
+		}
+
+		evaluate(sourceText) {
+			const handle = get(this, "evaluate");
+			if (typeof sourceText !== "string") {
+				throw new TypeError("ShadowRealm.prototype.evaluate expects a string");
+			}
+			return native.evaluate(handle, sourceText);
+		}
+
+		importValue(specifier, exportName) {
+			const handle = get(this, "importValue");
+			specifier = String(specifier);
+			if (typeof exportName !== "string") {
+				throw new TypeError("ShadowRealm.prototype.importValue expects the export name as a string");
+			}
+			return native.importValue(handle, specifier, exportName);
+		}
+
+		get [Symbol.toStringTag]() {
+			return "ShadowRealm";
+		}
+	}
+
+	return ShadowRealm;
+})`
+
+// realmStack holds the ShadowRealm global environments that are running
+// code, so that interrupting the runtime stops them too
+type realmStack struct {
+	mu      sync.Mutex
+	running []*goja.Runtime
+}
+
+// shadowRealm is a global environment of its own, created by new
+// ShadowRealm(). It runs on the JS thread of the runtime that created it.
+type shadowRealm struct {
+	vm     *goja.Runtime
+	loader *goja.Object // the realm's own ES module loader, created on first import
+}
+
+// setupShadowRealm installs ShadowRealm. It must be called on the JS thread.
+func (r *Runtime) setupShadowRealm() error {
+	vm := r.runtime
+	setup, err := jsprogram.Run(vm, "gode:shadow-realm", shadowRealmSetup)
+	if err != nil {
+		return err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("ShadowRealm setup is not a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("create", func() *shadowRealm {
+		// Only the ECMAScript built-ins: no console, require or timers
+		return &shadowRealm{vm: goja.New()}
+	})
+	native.Set("evaluate", func(realm *shadowRealm, source string) goja.Value {
+		// Syntax errors are reported as SyntaxErrors of the caller's realm
+		program, err := goja.Compile("[ShadowRealm]", source, false)
+		if err != nil {
+			ctor := vm.Get("SyntaxError")
+			syntaxErr, _ := vm.New(ctor, vm.ToValue(err.Error()))
+			panic(syntaxErr)
+		}
+		result, err := r.enterRealm(realm.vm, func() (goja.Value, error) {
+			return realm.vm.RunProgram(program)
+		})
+		return r.realmResult(realm.vm, vm, result, err)
+	})
+	native.Set("importValue", func(realm *shadowRealm, specifier, exportName string) goja.Value {
+		return r.importRealmValue(realm, specifier, exportName)
+	})
+
+	ctor, err := fn(goja.Undefined(), native)
+	if err != nil {
+		return err
+	}
+	return vm.Set("ShadowRealm", ctor)
+}
+
+// enterRealm runs fn, which runs code in the realm vm, where interrupting
+// the runtime reaches it. An interrupt that comes as it finishes is
+// dropped: the main runtime has one of its own.
+func (r *Runtime) enterRealm(vm *goja.Runtime, fn func() (goja.Value, error)) (goja.Value, error) {
+	if vm == r.runtime {
+		return fn()
+	}
+	r.realms.mu.Lock()
+	r.realms.running = append(r.realms.running, vm)
+	r.realms.mu.Unlock()
+	defer func() {
+		r.realms.mu.Lock()
+		r.realms.running = r.realms.running[:len(r.realms.running)-1]
+		vm.ClearInterrupt()
+		r.realms.mu.Unlock()
+	}()
+	return fn()
+}
+
+// interrupt stops the JS that is running, including code that has been
+// called in a ShadowRealm
+func (r *Runtime) interrupt(v interface{}) {
+	r.realms.mu.Lock()
+	for _, vm := range r.realms.running {
+		vm.Interrupt(v)
+	}
+	r.realms.mu.Unlock()
+	r.runtime.Interrupt(v)
+}
+
+// realmResult passes the outcome of running code in from to the caller in
+// to: the value, or a TypeError for an exception, as errors cannot cross
+// realms either.
+func (r *Runtime) realmResult(from, to *goja.Runtime, value goja.Value, err error) goja.Value {
+	if err != nil {
+		if _, interrupted := err.(*goja.InterruptedError); interrupted {
+			// The caller's realm stops at its next instruction with its
+			// own interrupt
+			return goja.Undefined()
+		}
+		panic(to.NewTypeError("ShadowRealm: " + realmErrorMessage(err)))
+	}
+	wrapped, err := r.crossRealm(from, to, value)
+	if err != nil {
+		panic(to.NewTypeError(err.Error()))
+	}
+	return wrapped
+}
+
+// realmErrorMessage describes an error thrown in another realm
+func realmErrorMessage(err error) string {
+	if exception, ok := err.(*goja.Exception); ok && exception.Value() != nil {
+		return exception.Value().String()
+	}
+	return err.Error()
+}
+
+// crossRealm passes value from one realm to another. Primitives are copied
+// and functions are wrapped, so that calling them runs the original in its
+// realm. Other objects cannot cross.
+func (r *Runtime) crossRealm(from, to *goja.Runtime, value goja.Value) (goja.Value, error) {
+	switch {
+	case value == nil || goja.IsUndefined(value):
+		return goja.Undefined(), nil
+	case goja.IsNull(value):
+		return goja.Null(), nil
+	}
+	switch v := value.(type) {
+	case *goja.Symbol:
+		return nil, fmt.Errorf("ShadowRealm: symbols cannot cross realms")
+	case *goja.Object:
+		fn, ok := goja.AssertFunction(v)
+		if !ok {
+			return nil, fmt.Errorf("ShadowRealm: only primitives and functions can cross realms, not %s", v.ClassName())
+		}
+		return r.wrapFunction(from, to, v, fn), nil
+	}
+	return to.ToValue(value.Export()), nil
+}
+
+// wrapFunction returns a function of realm to that calls fn, an object of
+// realm from, with its arguments passed across and this undefined
+func (r *Runtime) wrapFunction(from, to *goja.Runtime, obj *goja.Object, fn goja.Callable) goja.Value {
+	wrapped := to.ToValue(func(call goja.FunctionCall) goja.Value {
+		args := make([]goja.Value, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			value, err := r.crossRealm(to, from, arg)
+			if err != nil {
+				panic(to.NewTypeError(err.Error()))
+			}
+			args[i] = value
+		}
+		result, err := r.enterRealm(from, func() (goja.Value, error) {
+			return fn(goja.Undefined(), args...)
+		})
+		return r.realmResult(from, to, result, err)
+	}).(*goja.Object)
+
+	// Wrapped functions keep the length and name of the original
+	for _, prop := range []string{"length", "name"} {
+		value := obj.Get(prop)
+		if value == nil {
+			continue
+		}
+		if prop == "name" {
+			if _, ok := value.Export().(string); !ok {
+				continue
+			}
+		} else if _, ok := value.Export().(int64); !ok {
+			continue
+		}
+		wrapped.DefineDataProperty(prop, to.ToValue(value.Export()), goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	}
+	return wrapped
+}
+
+// importRealmValue loads the ES module specifier, resolved like a require()
+// of the caller, in realm and returns a promise of its export exportName.
+// The realm has no job loop of its own: its promises settle before the
+// call into it returns, so the module's outcome is known then.
+func (r *Runtime) importRealmValue(realm *shadowRealm, specifier, exportName string) goja.Value {
+	vm := r.runtime
+	promise, resolve, reject := vm.NewPromise()
+	fail := func(message string) goja.Value {
+		reject(vm.NewTypeError("ShadowRealm: " + message))
+		return vm.ToValue(promise)
+	}
+
+	if r.moduleManager == nil {
+		return fail("modules are not available")
+	}
+	specifier, _ = r.moduleManager.MapModuleName(specifier)
+	path, err := r.moduleManager.Resolve(specifier, "")
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	namespace, err := r.enterRealm(realm.vm, func() (goja.Value, error) {
+		loader, err := r.realmLoader(realm)
+		if err != nil {
+			return nil, err
+		}
+		load, _ := goja.AssertFunction(loader.Get("load"))
+		return load(goja.Undefined(), realm.vm.ToValue(path))
+	})
+	if err != nil {
+		if _, interrupted := err.(*goja.InterruptedError); interrupted {
+			return vm.ToValue(promise)
+		}
+		return fail(realmErrorMessage(err))
+	}
+
+	// A graph with top-level await gives a promise, settled by now
+	if p, ok := namespace.Export().(*goja.Promise); ok {
+		switch p.State() {
+		case goja.PromiseStateFulfilled:
+			namespace = p.Result()
+		case goja.PromiseStateRejected:
+			return fail(p.Result().String())
+		default:
+			return fail(fmt.Sprintf("module %s did not finish loading", specifier))
+		}
+	}
+
+	exports := namespace.ToObject(realm.vm)
+	if !hasOwnKey(exports, exportName) {
+		return fail(fmt.Sprintf("module %s has no export named %s", specifier, exportName))
+	}
+	value, err := r.crossRealm(realm.vm, vm, exports.Get(exportName))
+	if err != nil {
+		return fail(strings.TrimPrefix(err.Error(), "ShadowRealm: "))
+	}
+	resolve(value)
+	return vm.ToValue(promise)
+}
+
+// hasOwnKey reports whether obj has an own property called name
+func hasOwnKey(obj *goja.Object, name string) bool {
+	for _, key := range obj.Keys() {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// realmLoader returns the ES module loader of realm, creating it on first
+// use. Modules are compiled in the realm, so each realm has instances of
+// its own; CommonJS modules, plugins and built-in modules are objects of
+// the main realm and cannot be imported.
+func (r *Runtime) realmLoader(realm *shadowRealm) (*goja.Object, error) {
+	if realm.loader != nil {
+		return realm.loader, nil
+	}
+	vm := realm.vm
+	setup, err := jsprogram.Run(vm, "gode:esm-loader", esmLoader)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return nil, fmt.Errorf("module loader setup is not a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("resolve", func(specifier, referrer string) string {
+		specifier, _ = r.moduleManager.MapModuleName(specifier)
+		resolved, err := r.moduleManager.Resolve(specifier, referrer)
+		if err != nil {
+			panic(jserror.New(vm, errors.NewModuleError(specifier, referrer, "resolve", err)))
+		}
+		return resolved
+	})
+	native.Set("load", func(path string) goja.Value {
+		return r.loadRealmModule(vm, path)
+	})
+	native.Set("require", func(path string) goja.Value {
+		panic(vm.NewTypeError(fmt.Sprintf("%s is not an ES module and cannot be imported into a ShadowRealm", path)))
+	})
+	native.Set("meta", func(path string) *goja.Object {
+		meta := vm.NewObject()
+		if modules.IsDataURL(path) {
+			meta.Set("url", path)
+		} else {
+			meta.Set("url", fileURL(path))
+			meta.Set("filename", path)
+			meta.Set("dirname", filepath.Dir(path))
+		}
+		return meta
+	})
+
+	loader, err := fn(goja.Undefined(), native)
+	if err != nil {
+		return nil, err
+	}
+	realm.loader = loader.ToObject(vm)
+	return realm.loader, nil
+}
+
+// loadRealmModule reads, transforms and compiles the ES module at path in
+// the realm vm, like ModuleResolver.load, returning null for other modules
+func (r *Runtime) loadRealmModule(vm *goja.Runtime, path string) goja.Value {
+	if _, builtin := r.modules[path]; builtin || strings.HasPrefix(path, "gode:") || modules.IsPlugin(path) {
+		return goja.Null()
+	}
+	source, err := r.moduleManager.Load(path)
+	if err != nil {
+		panic(jserror.New(vm, err))
+	}
+	if !isModule(path, source) {
+		return goja.Null()
+	}
+	fileName := r.getEnhancedFileName(path, true, r.extractModuleName(path))
+	source = stripShebang(source)
+	r.registerSourceMap(fileName, path, source)
+	module, err := esm.Transform(source)
+	if err != nil {
+		panic(jserror.New(vm, errors.NewModuleError(path, "", "parse", err)))
+	}
+	fn, err := vm.RunScript(fileName, module.Code)
+	if err != nil {
+		if _, ok := err.(*goja.Exception); ok {
+			panic(err)
+		}
+		panic(jserror.New(vm, errors.NewModuleError(path, "", "parse", err)))
+	}
+
+	info := vm.NewObject()
+	info.Set("fn", fn)
+	requests := make([]interface{}, len(module.Requests))
+	for i, request := range module.Requests {
+		requests[i] = request
+	}
+	info.Set("requests", vm.NewArray(requests...))
+	info.Set("async", module.Async)
+	return info
+}
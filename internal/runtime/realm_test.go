@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestRuntimeShadowRealm(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "plugin.mjs"), []byte(`
+import { twice } from "./helper.mjs";
+export const name = "plugin";
+export function run(n, report) { report(twice(n)); return typeof console; }
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "helper.mjs"), []byte("export const twice = (n) => n * 2;\n"), 0644)
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(&config.PackageJSON{Name: "realms", ProjectRoot: dir}); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	value, err := rt.RunScript("realm", `
+		const realm = new ShadowRealm();
+		realm.evaluate("globalThis.counter = 1; Array.prototype.extra = true;");
+		const increment = realm.evaluate("(by) => globalThis.counter += by");
+		const results = [
+			increment(2), increment.name === "", increment.length,
+			realm.evaluate("counter"), typeof globalThis.counter, [].extra,
+			realm.evaluate("typeof require"),
+		];
+		// "let" alone is a reference to a variable outside strict mode, so
+		// the source that fails to parse is an incomplete declaration
+		for (const code of ["({})", "throw new Error('boom')", "let x ="]) {
+			try { realm.evaluate(code); } catch (e) { results.push(e.name); }
+		}
+		try { increment({}); } catch (e) { results.push(e.name); }
+		results.join(",");
+	`)
+	if err != nil || value != "3,true,1,3,undefined,,undefined,TypeError,TypeError,SyntaxError,TypeError" {
+		t.Errorf("evaluate = %v, %v", value, err)
+	}
+
+	plugin := strconv.Quote(filepath.Join(dir, "plugin.mjs"))
+	_, err = rt.RunScript("import", `
+		const plugins = new ShadowRealm();
+		Promise.all([
+			plugins.importValue(`+plugin+`, "run"),
+			plugins.importValue(`+plugin+`, "name"),
+			plugins.importValue(`+plugin+`, "missing").catch((e) => e.name),
+			plugins.importValue("gode:fs", "readFile").catch((e) => e.name),
+		]).then(([run, name, missing, builtin]) => {
+			const reported = [];
+			const type = run(21, (n) => reported.push(n));
+			globalThis.imported = [name, reported[0], type, missing, builtin].join(",");
+		});
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if imported, err := rt.RunScript("check", "globalThis.imported"); err != nil || imported != "plugin,42,undefined,TypeError,TypeError" {
+		t.Errorf("importValue = %v, %v", imported, err)
+	}
+}
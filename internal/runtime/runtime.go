@@ -8,16 +8,45 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/goja/parser"
+	"github.com/rizqme/gode/internal/asynccontext"
 	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/ipc"
+	"github.com/rizqme/gode/internal/jserror"
 	"github.com/rizqme/gode/internal/modules"
+	"github.com/rizqme/gode/internal/modules/async"
+	"github.com/rizqme/gode/internal/modules/asynchooks"
+	"github.com/rizqme/gode/internal/modules/cache"
+	"github.com/rizqme/gode/internal/modules/codec"
+	"github.com/rizqme/gode/internal/modules/crypto"
+	"github.com/rizqme/gode/internal/modules/diagnostics"
+	"github.com/rizqme/gode/internal/modules/fs"
+	"github.com/rizqme/gode/internal/modules/fuzz"
 	"github.com/rizqme/gode/internal/modules/globals"
+	"github.com/rizqme/gode/internal/modules/graphql"
+	"github.com/rizqme/gode/internal/modules/grpc"
 	"github.com/rizqme/gode/internal/modules/http"
+	"github.com/rizqme/gode/internal/modules/jsonrpc"
+	"github.com/rizqme/gode/internal/modules/jwt"
+	"github.com/rizqme/gode/internal/modules/oauth"
+	"github.com/rizqme/gode/internal/modules/osinfo"
+	"github.com/rizqme/gode/internal/modules/password"
+	"github.com/rizqme/gode/internal/modules/pool"
+	"github.com/rizqme/gode/internal/modules/shell"
 	"github.com/rizqme/gode/internal/modules/stream"
 	"github.com/rizqme/gode/internal/modules/test"
 	"github.com/rizqme/gode/internal/modules/timers"
+	"github.com/rizqme/gode/internal/modules/tmp"
+	"github.com/rizqme/gode/internal/modules/tls"
+	"github.com/rizqme/gode/internal/modules/validate"
+	"github.com/rizqme/gode/internal/modules/watch"
+	"github.com/rizqme/gode/internal/permissions"
 	"github.com/rizqme/gode/internal/plugins"
+	"github.com/rizqme/gode/internal/proxy"
+	"github.com/rizqme/gode/internal/shutdown"
 	"github.com/rizqme/gode/pkg/config"
 )
 
@@ -28,31 +57,108 @@ type Runtime struct {
 	projectRoot   string
 	modules       map[string]goja.Value
 	timersBridge  *timers.Bridge
-	vmQueue       chan func()
+	diagnostics   *diagnostics.Channels
+	lanes         [numPriorities]*lane // JS operation queues, see Priority
+	queueSize     int                  // capacity of each lane
+	hibernation   hibernation          // see Hibernate
+	streak        int                  // operations run while a lower lane waited
 	moduleManager *modules.ModuleManager
 	moduleResolver *ModuleResolver
+	blobs         *goja.Object // the JS half of Blob, see blobSetup
+	worker        *worker      // set on runtimes running a Worker's script
+	realms        realmStack   // ShadowRealms running code
 	mu            sync.RWMutex
 	disposed      bool
 	operationID   int64
 	argv          []string
+	permissions   *permissions.Checker
+	audit         *permissions.AuditLogger
+	moduleTags    map[string]string // script name -> owning dependency
+	moduleInstances map[string]goja.Value // resolved path -> exports
+	ipc           *ipc.Channel      // set when spawned with a parent message channel
+	shutdown      *shutdown.Manager
+	handles       int64 // open KeepAlive handles
+	requireHooks  []*requireHook
+	exiting       bool          // process.exit called or the script finished
+	exitCode      int
+	exited        chan struct{} // closed by process.exit
+	events        runtimeEvents
+	rejections    rejections    // promises rejected with no handler
+	signals       signalListeners // signals the script handles
+	services      *plugins.Services // shared between plugins
+	tasks         *tasks            // background goroutines started with Go
+	pluginEmitters map[string]*emitter // plugin name -> events emitter
+	pluginClasses  *pluginClasses      // created on first use
+	names         fileNames // stack trace names of files
+	started       time.Time
+	crash         *PanicError // set when a Go panic stopped the JS thread
+	restart       *restartInfo // set on runtimes created by a Supervisor after a crash
+	processed     int64 // JS operations run by the event loop
+	busy          int64 // nanoseconds the event loop spent running operations
+	opStart       int64 // UnixNano start of the running operation, 0 when idle
+	asyncContext  *asynccontext.Tracker // context followed across promise reactions and timers
 }
 
 // gojaObject is a simple adapter to satisfy plugin interfaces
 type gojaObject struct {
 	obj *goja.Object
+	r   *Runtime
 }
 
+// Set converts value with plugins.Marshal, so that plugin functions take
+// and return typed values (see pluginExport)
 func (o *gojaObject) Set(key string, value interface{}) error {
-	return o.obj.Set(key, value)
+	return o.obj.Set(key, o.r.pluginExport(value))
+}
+
+func (o *gojaObject) Delete(key string) bool {
+	return o.obj.Delete(key) == nil
+}
+
+// defaultQueueSize is the number of JS operations that can wait to run
+const defaultQueueSize = 1024
+
+// Options configures a runtime created with NewWithOptions
+type Options struct {
+	// QueueSize is the number of JS operations that can wait to run in
+	// each priority lane before new ones are dropped. Defaults to 1024.
+	QueueSize int
 }
 
 // New creates a new Gode runtime instance
 func New() *Runtime {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a runtime with non-default settings
+func NewWithOptions(opts Options) *Runtime {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
 	r := &Runtime{
 		runtime: goja.New(),
 		modules: make(map[string]goja.Value),
-		vmQueue: make(chan func(), 1024),
+		moduleInstances: make(map[string]goja.Value),
+		lanes:   newLanes(opts.QueueSize),
+		queueSize: opts.QueueSize,
+		shutdown: shutdown.New(),
+		exited:   make(chan struct{}),
+		services: plugins.NewServices(),
+		tasks:    newTasks(),
+		started:  time.Now(),
 	}
+	// Source maps are read by registerSourceMap, relative to the script's
+	// path. goja's own loader would look for them relative to its display
+	// name and fail to compile scripts whose map it cannot read.
+	r.runtime.SetParserOptions(parser.WithDisableSourceMaps)
+	r.asyncContext = asynccontext.Install(r.runtime)
+	r.asyncContext.OnCapture(r.noteSchedulers)
+	r.runtime.SetPromiseRejectionTracker(r.trackRejection)
+	r.hibernation.requests = make(chan chan bool)
+	
+	// Background tasks are cancelled with the other shutdown work, whether
+	// the runtime is disposed or the script calls process.exit
+	r.shutdown.Add(r.stopTasks)
 	
 	// Start the event loop goroutine
 	go r.eventLoop()
@@ -60,27 +166,56 @@ func New() *Runtime {
 	return r
 }
 
-// eventLoop processes JavaScript operations sequentially to maintain thread safety
+// eventLoop processes JavaScript operations sequentially to maintain thread
+// safety. Each operation is a macrotask: a script, a timer or immediate, a
+// plugin callback. Microtasks run in goja's job queue, which is drained
+// whenever the outermost call into JS returns, so every callback an
+// operation makes into JS is followed by its promise reactions,
+// queueMicrotask callbacks and process.nextTick batch, in the order they
+// were queued, before the loop moves on to the next operation.
 func (r *Runtime) eventLoop() {
-	for fn := range r.vmQueue {
-		if r.disposed {
+	for {
+		fn, lane, ok := r.next()
+		if !ok || r.disposed {
 			break
 		}
-		fn()
+		if r.crash != nil {
+			// Drain without running anything until Dispose closes the queue
+			continue
+		}
+		start := time.Now()
+		atomic.StoreInt64(&r.opStart, start.UnixNano())
+		r.runOperation(fn)
+		if len(r.rejections.pending) > 0 && r.crash == nil {
+			r.runOperation(r.reportRejections)
+		}
+		atomic.StoreInt64(&r.opStart, 0)
+		atomic.AddInt64(&r.busy, int64(time.Since(start)))
+		atomic.AddInt64(&r.processed, 1)
+		atomic.AddInt64(&lane.processed, 1)
 	}
 }
 
-// QueueJSOperation queues a JavaScript operation to be executed in the main JS thread
+// QueueJSOperation queues a JavaScript operation to be executed in the main
+// JS thread, in the default priority lane
 func (r *Runtime) QueueJSOperation(fn func()) {
-	if r.disposed {
-		return
-	}
-	
-	select {
-	case r.vmQueue <- fn:
-		// Operation queued successfully
-	default:
-		// Queue is full, skip the operation to avoid blocking
+	r.QueueJSOperationWithPriority(PriorityDefault, fn)
+}
+
+// AsyncContext returns the tracker of the context JS code runs in, which
+// follows it across promise reactions and timers
+func (r *Runtime) AsyncContext() *asynccontext.Tracker {
+	return r.asyncContext
+}
+
+// CaptureAsyncContext returns a function that runs fn in the async context
+// of the running JS code. It must be called on the JS thread, and so must
+// the function it returns; plugin callbacks use it to run in the context of
+// the call they were passed to.
+func (r *Runtime) CaptureAsyncContext() func(fn func()) {
+	ctx := r.asyncContext.Capture()
+	return func(fn func()) {
+		r.asyncContext.Run(ctx, fn)
 	}
 }
 
@@ -111,6 +246,17 @@ func (r *Runtime) setupGlobals() error {
 		
 		// Add require function
 		r.runtime.Set("require", func(specifier string) interface{} {
+			// Under gode test, moduleNameMapper may substitute any module,
+			// built-ins included
+			if r.moduleManager != nil {
+				specifier, _ = r.moduleManager.MapModuleName(specifier)
+			}
+			
+			// Modules replaced with jest.mock come before everything else
+			if module, mocked := test.GetTestBridge(r).MockedModule(specifier); mocked {
+				return module
+			}
+			
 			// Check built-in modules first
 			if module, exists := r.modules[specifier]; exists {
 				return module
@@ -127,6 +273,18 @@ func (r *Runtime) setupGlobals() error {
 			
 			// Try module manager if available
 			if r.moduleManager != nil {
+				var fileName string
+				if r.HasDiagnosticSubscribers("module.load") {
+					start := time.Now()
+					defer func() {
+						p := recover()
+						r.publishModuleLoad(specifier, fileName, start, p)
+						if p != nil {
+							panic(p)
+						}
+					}()
+				}
+				
 				source, err := r.moduleManager.Load(specifier)
 				if err == nil {
 					// If source is empty, it means the module was loaded directly (like plugins)
@@ -142,40 +300,69 @@ func (r *Runtime) setupGlobals() error {
 							return module
 						}
 					}
+					// Specifiers that resolve to the same file, such as
+					// several links into a package store, share one instance
+					path := r.moduleManager.ResolvedPath(specifier)
+					if exports, exists := r.moduleInstances[path]; exists {
+						return exports
+					}
+					
+					// ES modules are linked and evaluated by the module loader
+					if path != "" && r.moduleResolver != nil && isModule(path, source) {
+						exports := r.moduleResolver.requireModule(path)
+						r.moduleInstances[path] = exports
+						return exports
+					}
+					
 					// Otherwise execute the source with enhanced file name
 					// Extract module name from specifier
 					moduleName := r.extractModuleName(specifier)
-					fileName := r.getEnhancedFileName(specifier, true, moduleName)
-					val, err := r.runtime.RunScript(fileName, source)
+					fileName = r.getEnhancedFileName(specifier, true, moduleName)
+					r.tagModule(fileName, specifier)
+					source = r.applyRequireHooks(stripShebang(source), fileName)
+					r.registerSourceMap(fileName, path, source)
+					val, err := r.runModule(path, fileName, source)
 					if err == nil {
-						// Check if this is an ES6 module (has __gode_exports)
-						if exportsVal := r.runtime.Get("__gode_exports"); exportsVal != nil && !goja.IsUndefined(exportsVal) && !goja.IsNull(exportsVal) {
-							// Clear __gode_exports for next module
-							r.runtime.Set("__gode_exports", goja.Undefined())
-							return exportsVal
-						}
-						// Otherwise return the last expression value (CommonJS style)
+						// The last expression value is the exports
+						// (CommonJS style)
+						r.moduleInstances[path] = val
 						return val
 					} else {
 						// Enhanced error handling for JavaScript execution errors
 						moduleErr := r.createModuleErrorFromJS(specifier, err)
-						panic(r.runtime.NewGoError(moduleErr))
+						panic(jserror.New(r.runtime, moduleErr))
 					}
 				} else {
 					// Enhanced error handling for module loading errors
 					if moduleErr, ok := err.(*errors.ModuleError); ok {
-						panic(r.runtime.NewGoError(moduleErr))
+						panic(jserror.New(r.runtime, moduleErr))
 					} else {
 						moduleErr := errors.NewModuleError(specifier, "", "require", err)
-						panic(r.runtime.NewGoError(moduleErr))
+						panic(jserror.New(r.runtime, moduleErr))
 					}
 				}
 			}
 			
 			moduleErr := errors.NewModuleError(specifier, "", "require", fmt.Errorf("module not found: %s", specifier))
-			panic(r.runtime.NewGoError(moduleErr))
+			panic(jserror.New(r.runtime, moduleErr))
 		})
 		
+		// Let preloaded modules transform what is required after them
+		r.installRequireHooks()
+		
+		if err := r.setupBlob(); err != nil {
+			done <- fmt.Errorf("failed to register Blob: %w", err)
+			return
+		}
+		if err := r.setupWorker(); err != nil {
+			done <- fmt.Errorf("failed to register Worker: %w", err)
+			return
+		}
+		if err := r.setupShadowRealm(); err != nil {
+			done <- fmt.Errorf("failed to register ShadowRealm: %w", err)
+			return
+		}
+		
 		done <- nil
 	})
 	
@@ -229,6 +416,10 @@ func (r *Runtime) jsonParse(str string) interface{} {
 // Configure sets up the runtime with the given configuration
 func (r *Runtime) Configure(cfg *config.PackageJSON, argv ...[]string) error {
 	r.config = cfg
+	if cfg != nil {
+		r.projectRoot = cfg.ProjectRoot
+		proxy.Configure(cfg.Gode.Network)
+	}
 	
 	// Set argv if provided
 	if len(argv) > 0 {
@@ -237,6 +428,11 @@ func (r *Runtime) Configure(cfg *config.PackageJSON, argv ...[]string) error {
 		r.argv = os.Args
 	}
 	
+	// Setup permission checks and the optional audit log
+	if err := r.setupPermissions(cfg); err != nil {
+		return fmt.Errorf("failed to setup permissions: %w", err)
+	}
+	
 	// Create module manager with plugin support
 	r.moduleManager = modules.NewModuleManagerWithRuntime(r)
 	if cfg != nil {
@@ -248,6 +444,11 @@ func (r *Runtime) Configure(cfg *config.PackageJSON, argv ...[]string) error {
 		return fmt.Errorf("failed to setup globals: %w", err)
 	}
 	
+	// Connect to the parent process if it opened an IPC channel
+	if err := r.setupIPC(); err != nil {
+		return fmt.Errorf("failed to setup ipc: %w", err)
+	}
+	
 	// Setup built-in modules
 	if err := r.setupBuiltinModules(); err != nil {
 		return fmt.Errorf("failed to setup builtin modules: %w", err)
@@ -261,6 +462,14 @@ func (r *Runtime) Configure(cfg *config.PackageJSON, argv ...[]string) error {
 	return nil
 }
 
+// Compat returns the built-in behaviour levels selected in package.json
+func (r *Runtime) Compat() map[string]string {
+	if r.config == nil {
+		return nil
+	}
+	return r.config.Gode.Compat
+}
+
 // Run executes the given entry point
 func (r *Runtime) Run(entrypoint string) error {
 	if r.runtime == nil {
@@ -287,14 +496,87 @@ func (r *Runtime) Run(entrypoint string) error {
 	// Get enhanced file name for better stack traces
 	fileName := r.getEnhancedFileName(absPath, false, "")
 	
+	return r.runMain(entrypoint, fileName, string(source), nil)
+}
+
+// RunSource executes source as the main script, as for code piped on stdin.
+// name identifies it in stack traces, e.g. "[stdin]".
+func (r *Runtime) RunSource(name, source string) error {
+	if r.runtime == nil {
+		return fmt.Errorf("runtime not configured")
+	}
+	return r.runMain(name, name, source, nil)
+}
+
+// Eval runs inline code as the main script, like node -e. With print set
+// the completion value is written to stdout as node -p does: strings as
+// they are, anything else through the inspect formatter.
+func (r *Runtime) Eval(source string, print bool) error {
+	if r.runtime == nil {
+		return fmt.Errorf("runtime not configured")
+	}
+
+	var onResult func(goja.Value)
+	if print {
+		onResult = func(value goja.Value) {
+			if s, ok := value.Export().(string); ok && !goja.IsUndefined(value) {
+				fmt.Println(s)
+				return
+			}
+			fmt.Println(globals.Inspect(r.runtime, value, globals.InspectOptions{}))
+		}
+	}
+	return r.runMain("[eval]", "[eval]", source, onResult)
+}
+
+// runMain runs the main script, passing its completion value to onResult if
+// given, and then keeps the runtime alive for timers, IPC and other open
+// handles
+func (r *Runtime) runMain(entrypoint, fileName, source string, onResult func(goja.Value)) error {
 	// Execute the script through the queue with proper file name
 	done := make(chan error, 1)
 	r.QueueJSOperation(func() {
-		_, err := r.runtime.RunScript(fileName, string(source))
-		done <- err
+		if r.moduleResolver != nil && isModule(entrypoint, source) {
+			path, _ := filepath.Abs(entrypoint)
+			r.moduleResolver.runMain(path, fileName, source, func(err error) { done <- r.catchUncaught(err) })
+			return
+		}
+		// Inline code has no file of its own
+		path := entrypoint
+		if fileName != entrypoint {
+			path, _ = filepath.Abs(entrypoint)
+		}
+		value, err := r.runScriptWithHooks(path, fileName, stripShebang(source))
+		if err == nil && onResult != nil {
+			onResult(value)
+		}
+		done <- r.catchUncaught(err)
 	})
 	
-	err = <-done
+	// A panic on the JS thread means done is never sent
+	var err error
+	select {
+	case err = <-done:
+	case <-r.exited:
+	}
+	if exitErr := r.exitedWith(); exitErr != nil {
+		return exitErr
+	}
+	if interrupted, ok := err.(*goja.InterruptedError); ok {
+		// A terminated worker, interrupted before its exit was processed
+		if exitErr, ok := interrupted.Value().(*ExitError); ok {
+			return exitErr
+		}
+	}
+	if err != nil && r.worker != nil {
+		// A worker's errors go to its Worker object in the parent
+		moduleErr, ok := err.(*errors.ModuleError)
+		if !ok {
+			moduleErr = r.createModuleErrorFromJS(entrypoint, err)
+		}
+		r.worker.fail(workerErrorMessage(moduleErr), moduleErr.FormatError())
+		return fmt.Errorf("execution failed")
+	}
 	if err != nil {
 		// Enhanced error handling with stack trace
 		if moduleErr, ok := err.(*errors.ModuleError); ok {
@@ -309,12 +591,19 @@ func (r *Runtime) Run(entrypoint string) error {
 		return fmt.Errorf("execution failed")
 	}
 	
+	r.announceRestart()
+	
 	// Wait for any active timers to complete
-	if r.timersBridge != nil {
-		r.timersBridge.GetTimersModule().WaitForTimers(0) // Use default timeout
-	}
+	r.waitForTimers()
 	
-	return nil
+	// Stay alive for IPC messages while a 'message' listener is registered
+	r.waitForIPC()
+	
+	// Stay alive while watchers and similar handles are open
+	r.waitForHandles()
+	
+	// Report process.exit from a callback, or a non-zero process.exitCode
+	return r.finish()
 }
 
 // ExecuteScript runs JavaScript code directly (for testing)
@@ -361,11 +650,20 @@ func (r *Runtime) runTestFileInScope(testFile string) error {
 	}
 	
 	// Execute through the queue
+	fileName := r.getEnhancedFileName(absPath, false, "")
 	done := make(chan error, 1)
 	r.QueueJSOperation(func() {
-		// Wrap the source in a function scope to avoid global conflicts
+		// Wrap the source in a function scope to avoid global conflicts.
+		// Stack traces leave out the wrapper's line.
+		offset := errors.Offset{Source: fileName, Lines: 1}
+		if m := r.loadSourceMap(fileName, absPath, string(source)); m != nil {
+			offset.Map = m
+		}
+		errors.RegisterSourceMap(fileName, offset)
 		wrappedSource := fmt.Sprintf("(function() {\n%s\n})();", string(source))
-		_, err := r.runtime.RunString(wrappedSource)
+		// Its top level sees its own __filename, so jest.mock resolves
+		// relative paths from the test file
+		_, err := r.runModule(absPath, fileName, wrappedSource)
 		done <- err
 	})
 	
@@ -379,6 +677,21 @@ func (r *Runtime) runTestFileInScope(testFile string) error {
 
 // RunTests executes test files and returns results
 func (r *Runtime) RunTests(testFiles []string) ([]test.SuiteResult, error) {
+	return r.RunTestsWithReporters(testFiles, nil)
+}
+
+// RunTestsWithReporters executes test files like RunTests, reporting to
+// each reporter as the tests run. A reporter is the name of one registered
+// with RegisterTestReporter or test.registerReporter, the name of a built-in
+// one (default, spec, dot, json or junit, optionally as name=file), or the
+// path of a JS module exporting one.
+func (r *Runtime) RunTestsWithReporters(testFiles []string, reporters []string) ([]test.SuiteResult, error) {
+	return r.RunTestsWithOptions(testFiles, test.RunOptions{Reporters: reporters})
+}
+
+// RunTestsWithOptions executes test files like RunTestsWithReporters, as
+// configured by opts
+func (r *Runtime) RunTestsWithOptions(testFiles []string, opts test.RunOptions) ([]test.SuiteResult, error) {
 	if r.runtime == nil {
 		return nil, fmt.Errorf("runtime not configured")
 	}
@@ -391,20 +704,50 @@ func (r *Runtime) RunTests(testFiles []string) ([]test.SuiteResult, error) {
 	
 	// Reset test state to avoid pollution between runs
 	bridge.Reset()
+	
+	// Substitute the modules named by gode.test.moduleNameMapper while the
+	// test files load and run
+	if r.config != nil && r.moduleManager != nil {
+		if err := r.moduleManager.SetModuleNameMapper(r.config.Gode.Test.ModuleNameMapper); err != nil {
+			return nil, err
+		}
+	}
+	
+	// gode.test.timeout replaces the default test timeout
+	if opts.Timeout == 0 && r.config != nil && r.config.Gode.Test.Timeout > 0 {
+		opts.Timeout = time.Duration(r.config.Gode.Test.Timeout) * time.Millisecond
+	}
 
 	// Execute each test file to register tests (wrapped in function scope)
 	for _, testFile := range testFiles {
+		if absPath, err := filepath.Abs(testFile); err == nil {
+			bridge.SetFile(absPath)
+		}
 		if err := r.runTestFileInScope(testFile); err != nil {
 			return nil, fmt.Errorf("failed to load test file %s: %w", testFile, err)
 		}
 	}
 
 	// Run all registered tests
-	return bridge.RunTests()
+	return bridge.RunTestsWithOptions(testFiles, opts)
+}
+
+// RegisterTestReporter makes reporter available to gode test --reporter
+// name. Plugins reach it through test.ReporterRegistry.
+func (r *Runtime) RegisterTestReporter(name string, reporter test.Reporter) error {
+	return test.GetTestBridge(r).RegisterReporter(name, reporter)
 }
 
 // setupBuiltinModules registers all built-in modules
 func (r *Runtime) setupBuiltinModules() error {
+	// Register gode:diagnostics_channel first, so the modules after it can
+	// publish
+	channels, err := diagnostics.RegisterDiagnosticsModule(r)
+	if err != nil {
+		return fmt.Errorf("failed to register diagnostics module: %w", err)
+	}
+	r.diagnostics = channels
+	
 	// Register HTTP module (fetch)
 	if err := http.RegisterHTTPModule(r); err != nil {
 		return fmt.Errorf("failed to register HTTP module: %w", err)
@@ -427,22 +770,113 @@ func (r *Runtime) setupBuiltinModules() error {
 		return fmt.Errorf("failed to register stream module: %w", err)
 	}
 	
+	// Register TLS certificate utilities
+	if err := tls.RegisterTLSModule(r); err != nil {
+		return fmt.Errorf("failed to register TLS module: %w", err)
+	}
+	
+	// Register OAuth2/OIDC client helpers
+	if err := oauth.RegisterOAuthModule(r); err != nil {
+		return fmt.Errorf("failed to register OAuth module: %w", err)
+	}
+	
+	// Register JWT sign/verify
+	if err := jwt.RegisterJWTModule(r); err != nil {
+		return fmt.Errorf("failed to register JWT module: %w", err)
+	}
+	
+	// Register hashes, HMACs, random values and WebCrypto subtle
+	if err := crypto.RegisterCryptoModule(r); err != nil {
+		return fmt.Errorf("failed to register crypto module: %w", err)
+	}
+	
+	// Register password hashing
+	if err := password.RegisterPasswordModule(r); err != nil {
+		return fmt.Errorf("failed to register password module: %w", err)
+	}
+	
+	// Register format and schema validators
+	if err := validate.RegisterValidateModule(r); err != nil {
+		return fmt.Errorf("failed to register validate module: %w", err)
+	}
+	
+	// Register in-memory LRU/TTL cache
+	if err := cache.RegisterCacheModule(r); err != nil {
+		return fmt.Errorf("failed to register cache module: %w", err)
+	}
+	
+	// Register generic resource pool
+	if err := pool.RegisterPoolModule(r); err != nil {
+		return fmt.Errorf("failed to register pool module: %w", err)
+	}
+	
+	// Register property-based testing generators
+	if err := fuzz.RegisterFuzzModule(r); err != nil {
+		return fmt.Errorf("failed to register fuzz module: %w", err)
+	}
+	
+	// Register debounce/throttle/retry helpers on the runtime's timers
+	if err := async.RegisterAsyncModule(r, r.timersBridge.GetTimersModule()); err != nil {
+		return fmt.Errorf("failed to register async module: %w", err)
+	}
+	
+	// Register AsyncLocalStorage over the runtime's async context
+	if err := asynchooks.RegisterAsyncHooksModule(r); err != nil {
+		return fmt.Errorf("failed to register async_hooks module: %w", err)
+	}
+	
+	// Register file system helpers
+	if err := fs.RegisterFSModule(r); err != nil {
+		return fmt.Errorf("failed to register fs module: %w", err)
+	}
+	
+	// Register temp files cleaned up at exit
+	if err := tmp.RegisterTmpModule(r); err != nil {
+		return fmt.Errorf("failed to register tmp module: %w", err)
+	}
+	
+	// Register file watcher
+	if err := watch.RegisterWatchModule(r); err != nil {
+		return fmt.Errorf("failed to register watch module: %w", err)
+	}
+	
+	// Register host information (hostname, cpus, memory, interfaces)
+	if err := osinfo.RegisterOSModule(r); err != nil {
+		return fmt.Errorf("failed to register os module: %w", err)
+	}
+	
+	// Register tagged-template shell commands, gated by allow-run
+	if err := shell.RegisterShellModule(r); err != nil {
+		return fmt.Errorf("failed to register shell module: %w", err)
+	}
+	
+	// Register gRPC servers and clients over .proto files
+	if err := grpc.RegisterGRPCModule(r); err != nil {
+		return fmt.Errorf("failed to register grpc module: %w", err)
+	}
+	
+	// Register schema-first GraphQL execution and its HTTP handler
+	if err := graphql.RegisterGraphQLModule(r); err != nil {
+		return fmt.Errorf("failed to register graphql module: %w", err)
+	}
+	
+	// Register JSON-RPC 2.0 servers and peers over HTTP, stdio and script-driven transports
+	if err := jsonrpc.RegisterJSONRPCModule(r); err != nil {
+		return fmt.Errorf("failed to register jsonrpc module: %w", err)
+	}
+	
+	// Register MessagePack and protobuf encoding
+	if err := codec.RegisterCodecModule(r); err != nil {
+		return fmt.Errorf("failed to register codec module: %w", err)
+	}
+	
 	// TODO: Register other built-in modules like:
-	// - gode:fs
 	// - gode:process
-	// - gode:crypto
 	// etc.
 	
-	// Example: Register a simple module
-	done := make(chan error, 1)
-	r.QueueJSOperation(func() {
-		module := r.runtime.NewObject()
-		module.Set("version", "0.1.0-dev")
-		module.Set("platform", "gode")
-		r.modules["gode:core"] = r.runtime.ToValue(module)
-		done <- nil
-	})
-	<-done
+	// Register runtime metadata and lifecycle events
+	r.setupCoreModule()
+	r.setupRuntimeEventsModule()
 	
 	return nil
 }
@@ -479,24 +913,14 @@ func (r *Runtime) RunScript(name string, source string) (interface{}, error) {
 	return res.value, res.err
 }
 
-// CallJSFunction calls a JavaScript function
+// CallJSFunction calls a JavaScript function, given as a goja value or the
+// Go function goja exports it as. An exception it throws is returned.
 func (r *Runtime) CallJSFunction(fn interface{}) error {
 	done := make(chan error, 1)
 	
 	r.QueueJSOperation(func() {
-		// Handle Goja function type directly
-		if jsFunc, ok := fn.(func(goja.FunctionCall) goja.Value); ok {
-			// Create a proper FunctionCall with the runtime
-			call := goja.FunctionCall{
-				This:      r.runtime.GlobalObject(),
-				Arguments: []goja.Value{},
-			}
-			result := jsFunc(call)
-			_ = result // Ignore return value
-			done <- nil
-			return
-		}
-		done <- fmt.Errorf("cannot call JavaScript function (type: %T)", fn)
+		_, err := r.callJS(fn, nil)
+		done <- err
 	})
 	
 	return <-done
@@ -524,10 +948,27 @@ func (r *Runtime) NewObject() *goja.Object {
 	return <-done
 }
 
+// GetService returns a service provided by a plugin. Plugins reach it
+// through the runtime passed to their Initialize (plugins.ServiceRegistry).
+func (r *Runtime) GetService(name string) (interface{}, bool) {
+	return r.services.GetService(name)
+}
+
+// ProvideService registers a service for other plugins to look up
+func (r *Runtime) ProvideService(name string, service interface{}) error {
+	return r.services.ProvideService(name, service)
+}
+
+// WithdrawService removes a service, so that the plugin providing it can
+// provide it again once reloaded
+func (r *Runtime) WithdrawService(name string) {
+	r.services.WithdrawService(name)
+}
+
 // NewObjectForPlugins creates a new JavaScript object (implements plugins.VM interface)
 // This method is called from within queued operations, so we create the object directly
 func (r *Runtime) NewObjectForPlugins() plugins.Object {
-	return &gojaObject{obj: r.runtime.NewObject()}
+	return &gojaObject{obj: r.runtime.NewObject(), r: r}
 }
 
 // RegisterModule registers a module in the runtime
@@ -547,6 +988,12 @@ func (r *Runtime) RegisterModule(name string, exports interface{}) {
 	}
 }
 
+// AddShutdownHook registers fn to run once when the runtime is disposed or
+// the script calls process.exit. The returned function unregisters it.
+func (r *Runtime) AddShutdownHook(fn func()) (remove func()) {
+	return r.shutdown.Add(fn)
+}
+
 // Dispose cleans up the runtime
 func (r *Runtime) Dispose() {
 	r.mu.Lock()
@@ -556,13 +1003,29 @@ func (r *Runtime) Dispose() {
 		return // Already disposed
 	}
 	
+	// Run cleanup registered by modules, such as removing temp files
+	r.shutdown.Run()
+	
+	// Give cancelled background tasks a moment to return
+	if !r.waitForTasks(taskShutdownTimeout) {
+		fmt.Fprintln(os.Stderr, "gode: background tasks did not stop within", taskShutdownTimeout)
+	}
+	
 	// Clean up timers before disposing
 	if r.timersBridge != nil {
 		r.timersBridge.GetTimersModule().Cleanup()
 	}
 	
+	if r.audit != nil {
+		r.audit.Close()
+	}
+	
+	if r.ipc != nil {
+		r.ipc.Close()
+	}
+	
 	r.disposed = true
-	close(r.vmQueue)
+	r.closeLanes()
 }
 
 // GetRuntime returns the underlying Goja runtime for compatibility
@@ -657,6 +1120,9 @@ func (r *Runtime) createModuleErrorFromJS(moduleName string, jsErr error) *error
 		if jsStackTrace != "" {
 			moduleErr = moduleErr.WithJSStackTrace(jsStackTrace)
 		}
+		if gojaErr, ok := jsErr.(*goja.Exception); ok {
+			moduleErr = moduleErr.WithCause(jserror.Cause(gojaErr.Value()))
+		}
 		return moduleErr
 	}
 	
@@ -700,18 +1166,27 @@ func (r *Runtime) createModuleErrorFromJS(moduleName string, jsErr error) *error
 		moduleErr = moduleErr.WithSourceContext(context)
 	}
 	
+	// Keep the Error.cause chain of the thrown value
+	if gojaErr, ok := jsErr.(*goja.Exception); ok {
+		if cause := jserror.Cause(gojaErr.Value()); cause != nil {
+			moduleErr = moduleErr.WithCause(cause)
+		}
+	}
+	
 	return moduleErr
 }
 
 // getEnhancedFileName generates enhanced file names for better JavaScript stack traces
 // Format: "moduleName:filepath" for modules, "projectName:filepath" for main files
 func (r *Runtime) getEnhancedFileName(filePath string, isModule bool, moduleName string) string {
-	// Get relative path from current working directory
-	relPath := r.getRelativePath(filePath)
+	// data: URLs name themselves, shortened
+	if modules.IsDataURL(filePath) {
+		return modules.DataURLName(filePath)
+	}
 	
 	if isModule && moduleName != "" {
 		// For modules: "moduleName:filepath"
-		return fmt.Sprintf("%s:%s", moduleName, relPath)
+		return r.names.name(moduleName, filePath)
 	}
 	
 	// For main files: use project name from package.json
@@ -720,27 +1195,13 @@ func (r *Runtime) getEnhancedFileName(filePath string, isModule bool, moduleName
 		projectName = r.config.Name
 	}
 	
-	return fmt.Sprintf("%s:%s", projectName, relPath)
+	return r.names.name(projectName, filePath)
 }
 
-// getRelativePath converts an absolute path to relative path from current working directory
+// getRelativePath converts an absolute path to relative path from current
+// working directory, as it was when the path was first seen
 func (r *Runtime) getRelativePath(absolutePath string) string {
-	// Get current working directory
-	cwd, err := os.Getwd()
-	if err != nil {
-		// If we can't get cwd, just return the file name
-		return filepath.Base(absolutePath)
-	}
-	
-	// Try to get relative path
-	relPath, err := filepath.Rel(cwd, absolutePath)
-	if err != nil {
-		// If we can't get relative path, return absolute path
-		return absolutePath
-	}
-	
-	// Clean up the path
-	return filepath.Clean(relPath)
+	return r.names.relPath(absolutePath)
 }
 
 // extractModuleName extracts a meaningful module name from a specifier
@@ -749,7 +1210,7 @@ func (r *Runtime) extractModuleName(specifier string) string {
 	name := strings.TrimSuffix(specifier, filepath.Ext(specifier))
 	
 	// For relative paths, get the base name
-	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+	if modules.IsRelative(specifier) {
 		name = filepath.Base(name)
 	}
 	
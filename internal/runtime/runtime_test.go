@@ -3,7 +3,10 @@ package runtime
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"github.com/rizqme/gode/internal/modules/globals"
 	"github.com/rizqme/gode/pkg/config"
 )
 
@@ -373,6 +376,169 @@ func TestRuntimeDisposal(t *testing.T) {
 	rt.Dispose() // Should be safe to call multiple times
 }
 
+func TestRuntimeEvalInspect(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	tests := map[string]string{
+		`({ a: 1, "b-c": [1, 'x'], d: { e: { f: { g: 1 } } } })`: `{ a: 1, 'b-c': [ 1, 'x' ], d: { e: { f: [Object] } } }`,
+		`new Map([["k", true]])`:                                  `Map(1) { 'k' => true }`,
+		`(function named() {})`:                                   `[Function: named]`,
+		`[]`:                                                      `[]`,
+		`-0`:                                                      `-0`,
+	}
+
+	for source, want := range tests {
+		got := make(chan string, 1)
+		rt.QueueJSOperation(func() {
+			value, err := rt.runtime.RunString(source)
+			if err != nil {
+				got <- err.Error()
+				return
+			}
+			got <- globals.Inspect(rt.runtime, value, globals.InspectOptions{})
+		})
+		if result := <-got; result != want {
+			t.Errorf("Inspect(%s) = %s, want %s", source, result, want)
+		}
+	}
+}
+
+func TestRuntimePriorityLanes(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+
+	// Hold the JS thread so the lanes fill up before anything runs
+	release := make(chan struct{})
+	rt.QueueJSOperation(func() { <-release })
+
+	var order []string
+	done := make(chan struct{})
+	rt.QueueJSOperationWithPriority(PriorityBackground, func() { order = append(order, "background") })
+	rt.QueueJSOperation(func() { order = append(order, "default") })
+	rt.QueueJSOperationWithPriority(PriorityInteractive, func() { order = append(order, "interactive1") })
+	rt.QueueJSOperationWithPriority(PriorityInteractive, func() { order = append(order, "interactive2") })
+	rt.QueueJSOperationWithPriority(PriorityBackground, func() { close(done) })
+	close(release)
+	<-done
+
+	want := "interactive1,interactive2,default,background"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("Expected order %s, got %s", want, got)
+	}
+
+	stats := rt.Stats()
+	if len(stats.Queue.Lanes) != 3 || stats.Queue.Lanes[0].Priority != PriorityInteractive || stats.Queue.Lanes[0].Processed != 2 {
+		t.Errorf("Unexpected lane stats: %+v", stats.Queue.Lanes)
+	}
+}
+
+func TestRuntimePriorityLanesNoStarvation(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+
+	release := make(chan struct{})
+	rt.QueueJSOperation(func() { <-release })
+
+	ran := 0
+	position := -1
+	done := make(chan struct{})
+	rt.QueueJSOperationWithPriority(PriorityBackground, func() { position = ran })
+	for i := 0; i < starvationLimit*2; i++ {
+		rt.QueueJSOperationWithPriority(PriorityInteractive, func() { ran++ })
+	}
+	rt.QueueJSOperationWithPriority(PriorityBackground, func() { close(done) })
+	close(release)
+	<-done
+
+	if position < 0 || position > starvationLimit+1 {
+		t.Errorf("Expected the background operation to run within %d interactive ones, ran after %d", starvationLimit, position)
+	}
+}
+
+func TestRuntimeQueueJSBatch(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	release := make(chan struct{})
+	rt.QueueJSOperation(func() { <-release })
+	before := rt.Stats().Queue.Processed
+
+	// Each callback is followed by its microtasks before the next one runs
+	var order []string
+	var fns []func()
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		fns = append(fns, func() {
+			order = append(order, name)
+			rt.runtime.RunString(`Promise.resolve().then(() => order("` + name + `.then"))`)
+		})
+	}
+	rt.runtime.Set("order", func(s string) { order = append(order, s) })
+	done := make(chan struct{})
+	rt.QueueJSBatch(append(fns, func() { close(done) }))
+	close(release)
+	<-done
+
+	want := "a,a.then,b,b.then,c,c.then"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("Expected order %s, got %s", want, got)
+	}
+	if err := rt.Ping(time.Second); err != nil {
+		t.Fatalf("Ping() failed: %v", err)
+	}
+	// The held operation, the batch and the ping
+	if processed := rt.Stats().Queue.Processed - before; processed != 3 {
+		t.Errorf("Expected the batch to be one operation, %d were processed", processed)
+	}
+}
+
+func TestRuntimeCallJSFunctionWithArgs(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	fns, err := rt.RunScript("fns", `({
+		add: (a, b) => a + b,
+		point: (x, y) => ({ X: x, Y: y }),
+		fail: msg => { throw new Error(msg) },
+	})`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	exports := fns.(map[string]interface{})
+
+	sum, err := rt.CallJSFunctionWithArgs(exports["add"], 2, 3)
+	if err != nil || sum != int64(5) {
+		t.Errorf("add(2, 3) = %v, %v", sum, err)
+	}
+
+	type point struct {
+		X, Y int
+	}
+	p, err := CallJSFunctionAs[point](rt, exports["point"], 1, 2)
+	if err != nil || p != (point{1, 2}) {
+		t.Errorf("point(1, 2) = %+v, %v", p, err)
+	}
+
+	_, err = rt.CallJSFunctionWithArgs(exports["fail"], "boom")
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the thrown error to be returned, got %v", err)
+	}
+
+	if _, err := rt.CallJSFunctionWithArgs(42); err == nil {
+		t.Error("Expected an error calling a non-function")
+	}
+}
+
 func BenchmarkRuntimeCreation(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		rt := New()
@@ -436,4 +602,4 @@ sum;
 			b.Errorf("Run() failed: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,185 @@
+package runtime
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Priority selects the lane a JS operation is queued in. The JS thread
+// always runs the oldest operation of the highest non-empty lane:
+// interactive work, such as HTTP request handlers, before default work
+// before background work, such as plugin events. Operations in the same
+// lane run in the order they were queued; there is no ordering between
+// lanes. So that lower lanes still make progress under sustained load, the
+// oldest waiting lower-lane operation runs after every starvationLimit
+// higher-lane operations that were picked while it waited.
+type Priority int
+
+const (
+	// PriorityDefault is used by QueueJSOperation
+	PriorityDefault Priority = iota
+	// PriorityInteractive is for work someone is waiting on
+	PriorityInteractive
+	// PriorityBackground is for work that can wait, like bulk callbacks
+	PriorityBackground
+
+	numPriorities = 3
+)
+
+// starvationLimit is how many higher-lane operations may run in a row
+// while a lower lane has work waiting
+const starvationLimit = 64
+
+// laneOrder lists the lanes from highest to lowest priority
+var laneOrder = [numPriorities]Priority{PriorityInteractive, PriorityDefault, PriorityBackground}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBackground:
+		return "background"
+	default:
+		return "default"
+	}
+}
+
+// lane is the queue of one priority
+type lane struct {
+	ops       chan func()
+	processed int64
+	dropped   int64
+}
+
+func newLanes(size int) [numPriorities]*lane {
+	var lanes [numPriorities]*lane
+	for i := range lanes {
+		lanes[i] = &lane{ops: make(chan func(), size)}
+	}
+	return lanes
+}
+
+// QueueJSOperationWithPriority queues fn to run on the JS thread in the
+// lane for priority. Like QueueJSOperation it never blocks: when the lane
+// is full the operation is dropped and reported as a queueSaturated event.
+func (r *Runtime) QueueJSOperationWithPriority(priority Priority, fn func()) {
+	if r.disposed {
+		return
+	}
+	if priority < 0 || priority >= numPriorities {
+		priority = PriorityDefault
+	}
+
+	// A hibernated runtime wakes up for the operation
+	h := &r.hibernation
+	h.mu.RLock()
+	for h.sleeping && !h.closed {
+		h.mu.RUnlock()
+		r.Wake()
+		h.mu.RLock()
+	}
+	defer h.mu.RUnlock()
+	if h.closed {
+		return
+	}
+
+	l := r.lanes[priority]
+	select {
+	case l.ops <- fn:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+		r.queueSaturated()
+	}
+}
+
+// QueueJSBatch queues fns to run on the JS thread one after another, in the
+// default lane, as a single operation. Producers of many small callbacks,
+// such as the chunks of a stream or the rows of a batch result, save a
+// queue slot and a wakeup for each. Every fn still starts in the background
+// async context and is followed by its microtasks, as if queued alone.
+func (r *Runtime) QueueJSBatch(fns []func()) {
+	switch len(fns) {
+	case 0:
+		return
+	case 1:
+		r.QueueJSOperation(fns[0])
+		return
+	}
+	r.QueueJSOperation(func() {
+		for _, fn := range fns {
+			r.asyncContext.Run(context.Background(), fn)
+		}
+	})
+}
+
+// next blocks until an operation is ready and returns it with its lane. ok
+// is false once the queue has been closed by Dispose, or once the event
+// loop has gone to sleep for Hibernate.
+func (r *Runtime) next() (fn func(), l *lane, ok bool) {
+	if r.streak >= starvationLimit {
+		r.streak = 0
+		for i := numPriorities - 1; i > 0; i-- {
+			l := r.lanes[laneOrder[i]]
+			select {
+			case fn, ok := <-l.ops:
+				return fn, l, ok
+			default:
+			}
+		}
+	}
+
+	for i, p := range laneOrder {
+		l := r.lanes[p]
+		select {
+		case fn, ok := <-l.ops:
+			if r.lowerWaiting(i) {
+				r.streak++
+			} else {
+				r.streak = 0
+			}
+			return fn, l, ok
+		default:
+		}
+	}
+
+	// Nothing is waiting: take whichever operation arrives first
+	interactive, normal, background := r.lanes[PriorityInteractive], r.lanes[PriorityDefault], r.lanes[PriorityBackground]
+	for {
+		select {
+		case fn, ok := <-interactive.ops:
+			return fn, interactive, ok
+		case fn, ok := <-normal.ops:
+			return fn, normal, ok
+		case fn, ok := <-background.ops:
+			return fn, background, ok
+		case reply := <-r.hibernation.requests:
+			if r.park(reply) {
+				return nil, nil, false
+			}
+		}
+	}
+}
+
+// lowerWaiting reports whether a lane below laneOrder[i] has work
+func (r *Runtime) lowerWaiting(i int) bool {
+	for _, p := range laneOrder[i+1:] {
+		if len(r.lanes[p].ops) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// closeLanes stops the event loop. A hibernated runtime has no event loop
+// to stop and stays asleep.
+func (r *Runtime) closeLanes() {
+	h := &r.hibernation
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.sleeping {
+		for _, l := range r.lanes {
+			close(l.ops)
+		}
+	}
+	h.closed = true
+}
@@ -0,0 +1,100 @@
+package runtime
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Signals are the signals scripts can handle with process.on, to be
+// passed to Signal by whoever owns the process's signals, such as the CLI:
+//
+//	c := make(chan os.Signal, 1)
+//	signal.Notify(c, runtime.Signals...)
+//	for sig := range c {
+//		rt.Signal(sig)
+//	}
+var Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+// signalListeners records which signals scripts listen for. It is written
+// on the JS thread and read by Signal from any goroutine.
+type signalListeners struct {
+	mu        sync.Mutex
+	listening map[string]bool // by signal name
+}
+
+// ProcessListenersChanged is called by the process object when the
+// listeners of event change, see globals
+func (r *Runtime) ProcessListenersChanged(event string, count int) {
+	if signalNumber(event) == 0 {
+		return
+	}
+	r.signals.mu.Lock()
+	defer r.signals.mu.Unlock()
+	if r.signals.listening == nil {
+		r.signals.listening = make(map[string]bool)
+	}
+	r.signals.listening[event] = count > 0
+}
+
+// Signal delivers sig, received by the process, to the script. If it
+// listens for the signal, as with process.on('SIGTERM', fn), the listeners
+// are called on the JS thread with the signal's name and Signal returns
+// true. Otherwise the script is stopped as by process.exit with status 128
+// plus the signal number, without emitting 'exit', as in Node: running JS
+// is interrupted, the shutdown hooks run and Run returns an ExitError.
+// Signal may be called from any goroutine.
+func (r *Runtime) Signal(sig os.Signal) bool {
+	name := signalName(sig)
+	r.signals.mu.Lock()
+	listening := r.signals.listening[name]
+	r.signals.mu.Unlock()
+
+	if listening {
+		r.QueueJSOperationWithPriority(PriorityInteractive, func() {
+			r.emitProcessEvent(name, r.runtime.ToValue(name))
+		})
+		return true
+	}
+
+	code := SignalExitCode(sig)
+	// Stop a script that is busy, then clean up once the JS thread is free
+	r.interrupt(&ExitError{Code: code})
+	r.QueueJSOperationWithPriority(PriorityInteractive, func() {
+		r.stop(code, false)
+	})
+	return false
+}
+
+// SignalExitCode returns the exit status of a process stopped by sig, 128
+// plus the signal number
+func SignalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// signalName returns the name process events use for sig, like "SIGINT"
+func signalName(sig os.Signal) string {
+	switch sig {
+	case syscall.SIGINT:
+		return "SIGINT"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	case syscall.SIGHUP:
+		return "SIGHUP"
+	}
+	return sig.String()
+}
+
+// signalNumber returns the number of the signal called name, or 0 if it is
+// not one of Signals
+func signalNumber(name string) int {
+	for _, sig := range Signals {
+		if signalName(sig) == name {
+			return int(sig.(syscall.Signal))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// listeningFor waits until the script running on rt listens for name
+func listeningFor(t *testing.T, rt *Runtime, name string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		rt.signals.mu.Lock()
+		listening := rt.signals.listening[name]
+		rt.signals.mu.Unlock()
+		if listening {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Script did not listen for %s", name)
+}
+
+func TestRuntimeSignalListeners(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- rt.RunSource("signals.js", `
+			const keepAlive = setInterval(() => {}, 10);
+			process.on('SIGTERM', (name) => {
+				process.exitCode = name === 'SIGTERM' ? 5 : 1;
+				clearInterval(keepAlive);
+			});
+		`)
+	}()
+	listeningFor(t, rt, "SIGTERM")
+
+	if !rt.Signal(syscall.SIGTERM) {
+		t.Error("Expected Signal() to report the script handles SIGTERM")
+	}
+	if err, ok := (<-result).(*ExitError); !ok || err.Code != 5 {
+		t.Errorf("Expected the listener to set exit code 5, got %v", err)
+	}
+}
+
+func TestRuntimeSignalDefault(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	hookRan := make(chan struct{})
+	rt.AddShutdownHook(func() { close(hookRan) })
+
+	result := make(chan error, 1)
+	go func() {
+		// A removed listener no longer handles the signal, and a busy
+		// script is interrupted
+		result <- rt.RunSource("signals.js", `
+			const listener = () => {};
+			process.on('SIGINT', listener);
+			process.off('SIGINT', listener);
+			process.on('SIGHUP', () => {});
+			process.on('exit', () => { throw new Error('exit emitted'); });
+			for (;;) {}
+		`)
+	}()
+	listeningFor(t, rt, "SIGHUP")
+
+	if rt.Signal(syscall.SIGINT) {
+		t.Error("Expected Signal() to report SIGINT is not handled")
+	}
+	if err, ok := (<-result).(*ExitError); !ok || err.Code != 130 {
+		t.Errorf("Expected exit code 130, got %v", err)
+	}
+	select {
+	case <-hookRan:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the shutdown hooks to run")
+	}
+}
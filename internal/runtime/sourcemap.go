@@ -0,0 +1,102 @@
+package runtime
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/modules"
+)
+
+// sourceMappingURLRegex matches the comment through which generated code
+// names its source map
+var sourceMappingURLRegex = regexp.MustCompile(`(?m)^[ \t]*//[#@][ \t]*sourceMappingURL=(\S+)[ \t]*$`)
+
+// registerSourceMap makes stack traces that name fileName, which ran source
+// loaded from path, report positions in the files its source map names.
+// Code without a readable source map is reported as it ran, as in node.
+func (r *Runtime) registerSourceMap(fileName, path, source string) {
+	if m := r.loadSourceMap(fileName, path, source); m != nil {
+		errors.RegisterSourceMap(fileName, m)
+		return
+	}
+	errors.RegisterSourceMap(fileName, nil)
+}
+
+// loadSourceMap reads the source map that source links to, inline as a
+// data: URL or in a file next to path, returning nil if there is none
+func (r *Runtime) loadSourceMap(fileName, path, source string) *errors.SourceMap {
+	if !strings.Contains(source, "sourceMappingURL=") {
+		return nil
+	}
+	matches := sourceMappingURLRegex.FindAllStringSubmatch(source, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	// Only the last comment counts, as bundlers append theirs
+	link := matches[len(matches)-1][1]
+
+	var data []byte
+	dir := ""
+	if modules.IsDataURL(link) {
+		_, decoded, err := modules.ParseDataURL(link)
+		if err != nil {
+			return nil
+		}
+		data = decoded
+		if filepath.IsAbs(path) {
+			dir = filepath.Dir(path)
+		}
+	} else {
+		if !filepath.IsAbs(path) {
+			return nil
+		}
+		mapPath := link
+		if u, err := url.Parse(link); err == nil && u.Scheme == "file" {
+			mapPath = u.Path
+		} else if err != nil || u.Scheme != "" {
+			return nil
+		} else if unescaped, err := url.PathUnescape(link); err == nil {
+			mapPath = unescaped
+		}
+		if !filepath.IsAbs(mapPath) {
+			mapPath = filepath.Join(filepath.Dir(path), filepath.FromSlash(mapPath))
+		}
+		if r.CheckPermission("read", mapPath) != nil {
+			return nil
+		}
+		var err error
+		if data, err = os.ReadFile(mapPath); err != nil {
+			return nil
+		}
+		dir = filepath.Dir(mapPath)
+	}
+
+	m, err := errors.ParseSourceMap(data)
+	if err != nil {
+		return nil
+	}
+	// Sources are named like the files that run, e.g. "app:src/index.ts"
+	prefix, _, _ := strings.Cut(fileName, ":")
+	for i, source := range m.Sources {
+		m.Sources[i] = r.sourceName(prefix, dir, source)
+	}
+	return m
+}
+
+// sourceName names a source of a source map found in dir for stack traces.
+// Sources that are not files, such as webpack:// URLs, keep their names.
+func (r *Runtime) sourceName(prefix, dir, source string) string {
+	if u, err := url.Parse(source); err == nil && u.Scheme == "file" {
+		source = u.Path
+	} else if err == nil && u.Scheme != "" || source == "" || dir == "" && !filepath.IsAbs(source) {
+		return source
+	}
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(dir, filepath.FromSlash(source))
+	}
+	return prefix + ":" + r.getRelativePath(source)
+}
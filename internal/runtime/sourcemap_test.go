@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestRuntimeSourceMaps(t *testing.T) {
+	dir := t.TempDir()
+	// Line 2 of the generated file comes from line 10 of src/main.ts
+	sourceMap := `{"version":3,"sources":["../src/main.ts"],"names":[],"mappings":";AASA"}`
+	os.MkdirAll(filepath.Join(dir, "dist"), 0755)
+	os.WriteFile(filepath.Join(dir, "dist", "main.js.map"), []byte(sourceMap), 0644)
+	main := filepath.Join(dir, "dist", "main.js")
+	os.WriteFile(main, []byte("const trace = {};\nError.captureStackTrace(trace);\nglobalThis.trace = trace.stack;\n//# sourceMappingURL=main.js.map\n"), 0644)
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(&config.PackageJSON{Name: "mapped", ProjectRoot: dir}); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	if err := rt.Run(main); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	trace, err := rt.RunScript("check", "globalThis.trace")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if s, _ := trace.(string); !strings.Contains(s, "src/main.ts:10:1") {
+		t.Errorf("Expected the stack to name src/main.ts:10:1, got %q", s)
+	}
+}
+
+func TestRuntimeMissingSourceMap(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.js")
+	os.WriteFile(main, []byte("const trace = {};\nError.captureStackTrace(trace);\nglobalThis.trace = trace.stack;\n//# sourceMappingURL=main.js.map\n"), 0644)
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(&config.PackageJSON{Name: "unmapped", ProjectRoot: dir}); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	// Without its map the script runs, and its stack names the script
+	if err := rt.Run(main); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	trace, err := rt.RunScript("check", "globalThis.trace")
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if s, _ := trace.(string); !strings.Contains(s, "main.js:2:") {
+		t.Errorf("Expected the stack to name main.js:2, got %q", s)
+	}
+}
@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goruntime "runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/rizqme/gode/internal/modules"
+	"github.com/rizqme/gode/internal/modules/globals"
+	"github.com/rizqme/gode/internal/plugins"
+)
+
+// Stats is a snapshot of a runtime's health, for monitoring
+type Stats struct {
+	Uptime      time.Duration
+	Busy        time.Duration // time the JS thread spent running operations
+	Queue       QueueStats
+	Hibernating bool   // the event loop is asleep, see Runtime.Hibernate
+	Handles     int64  // open KeepAlive handles
+	Timers      int64  // pending timeouts and intervals
+	Modules     int    // modules loaded through require/import
+	Plugins     int    // Go plugins loaded
+	Goroutines  int    // in the whole process
+	HeapAlloc   uint64 // bytes, for the whole process
+	Sys         uint64 // bytes obtained from the OS
+	NumGC       uint32
+}
+
+// QueueStats describes the JS operation queue, in total and per lane
+type QueueStats struct {
+	Length    int   // operations waiting
+	Capacity  int   // operations each lane holds before dropping
+	Processed int64 // operations run so far
+	Dropped   int64 // operations dropped because a lane was full
+	Lanes     []LaneStats
+}
+
+// LaneStats describes the queue of one Priority
+type LaneStats struct {
+	Priority  Priority
+	Length    int
+	Processed int64
+	Dropped   int64
+}
+
+// Stats returns a snapshot of the runtime's state. It does not wait for the
+// JS thread, so it can be called while a script is busy.
+func (r *Runtime) Stats() Stats {
+	var mem goruntime.MemStats
+	goruntime.ReadMemStats(&mem)
+
+	stats := Stats{
+		Uptime: time.Since(r.started),
+		Busy:   r.busyTime(),
+		Queue: QueueStats{
+			Capacity:  r.queueSize,
+			Processed: atomic.LoadInt64(&r.processed),
+			Dropped:   atomic.LoadInt64(&r.events.total),
+		},
+		Handles:    atomic.LoadInt64(&r.handles),
+		Goroutines: goruntime.NumGoroutine(),
+		HeapAlloc:  mem.HeapAlloc,
+		Sys:        mem.Sys,
+		NumGC:      mem.NumGC,
+	}
+	r.hibernation.mu.RLock()
+	stats.Hibernating = r.hibernation.sleeping
+	for _, p := range laneOrder {
+		l := r.lanes[p]
+		lane := LaneStats{
+			Priority:  p,
+			Length:    len(l.ops),
+			Processed: atomic.LoadInt64(&l.processed),
+			Dropped:   atomic.LoadInt64(&l.dropped),
+		}
+		stats.Queue.Length += lane.Length
+		stats.Queue.Lanes = append(stats.Queue.Lanes, lane)
+	}
+	r.hibernation.mu.RUnlock()
+	if r.timersBridge != nil {
+		stats.Timers = r.timersBridge.GetTimersModule().ActiveTimers()
+	}
+	stats.Modules = len(r.Modules())
+	stats.Plugins = len(r.Plugins())
+	return stats
+}
+
+// busyTime returns the time the JS thread has spent running operations,
+// including the one running now
+func (r *Runtime) busyTime() time.Duration {
+	busy := atomic.LoadInt64(&r.busy)
+	if start := atomic.LoadInt64(&r.opStart); start != 0 {
+		busy += time.Now().UnixNano() - start
+	}
+	return time.Duration(busy)
+}
+
+// busyNow reports whether the JS thread is running an operation
+func (r *Runtime) busyNow() bool {
+	return atomic.LoadInt64(&r.opStart) != 0
+}
+
+// Modules returns the modules loaded so far through require or import
+func (r *Runtime) Modules() []modules.LoadedModule {
+	if r.moduleManager == nil {
+		return nil
+	}
+	return r.moduleManager.Modules()
+}
+
+// Plugins returns the Go plugins loaded so far
+func (r *Runtime) Plugins() []*plugins.PluginInfo {
+	if r.moduleManager == nil {
+		return nil
+	}
+	return r.moduleManager.Plugins()
+}
+
+// ErrBusy is returned when the JS thread does not respond in time
+var ErrBusy = errors.New("JS thread is busy")
+
+// Ping waits up to timeout for the JS thread to run an empty operation,
+// which shows that the event loop is not blocked
+func (r *Runtime) Ping(timeout time.Duration) error {
+	done := make(chan struct{})
+	r.QueueJSOperation(func() { close(done) })
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return ErrBusy
+	}
+}
+
+// Inspect evaluates source on the JS thread and formats the result as
+// console.log would. Evaluation is interrupted after timeout so that a
+// runaway expression cannot stall the program; ErrBusy is returned when
+// the JS thread does not get to it in time.
+func (r *Runtime) Inspect(source string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var text string
+	err := r.runContext(ctx, func() error {
+		value, err := r.runtime.RunScript("[inspect]", source)
+		if err != nil {
+			return err
+		}
+		text = globals.Inspect(r.runtime, value, globals.InspectOptions{})
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrBusy):
+		return "", ErrBusy
+	case errors.Is(err, context.DeadlineExceeded):
+		return "", fmt.Errorf("evaluation timed out after %v", timeout)
+	}
+	return text, err
+}
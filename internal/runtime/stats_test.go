@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntimeStatsAndInspect(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	if err := rt.Ping(time.Second); err != nil {
+		t.Fatalf("Ping() failed: %v", err)
+	}
+	stats := rt.Stats()
+	if stats.Queue.Capacity == 0 || stats.Queue.Processed == 0 {
+		t.Errorf("Unexpected queue stats: %+v", stats.Queue)
+	}
+
+	got, err := rt.Inspect("({ a: [1, 2] })", time.Second)
+	if err != nil {
+		t.Fatalf("Inspect() failed: %v", err)
+	}
+	if got != "{ a: [ 1, 2 ] }" {
+		t.Errorf("Unexpected inspect output: %s", got)
+	}
+
+	if _, err := rt.Inspect("for (;;) {}", 100*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	// The interrupt must not leak into later scripts
+	if _, err := rt.RunScript("after", "1 + 1"); err != nil {
+		t.Errorf("Script after a timed out inspect failed: %v", err)
+	}
+}
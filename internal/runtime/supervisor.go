@@ -0,0 +1,144 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+// EventRuntimeRestarted is published on gode:events/runtime by a runtime a
+// Supervisor created to replace one that panicked. It is emitted once the
+// main script has run, so the script can subscribe to it.
+const EventRuntimeRestarted = "runtimeRestarted"
+
+// PanicError is returned by Run when a Go panic on the JS thread, for
+// example in a native module, stopped the runtime. The runtime cannot run
+// JavaScript afterwards and should be disposed.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("runtime panic: %v", e.Value)
+}
+
+// runOperation runs a queued JS operation. A panic stops the runtime the
+// way process.exit does, instead of taking down the whole process.
+func (r *Runtime) runOperation(fn func()) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		r.crash = &PanicError{Value: p, Stack: debug.Stack()}
+		fmt.Fprintf(os.Stderr, "gode: fatal panic on the JS thread: %v\n%s", p, r.crash.Stack)
+
+		if !r.exiting {
+			r.exiting = true
+			r.exitCode = 1
+			r.shutdown.Run()
+			if r.timersBridge != nil {
+				r.timersBridge.GetTimersModule().Cleanup()
+			}
+			close(r.exited)
+		}
+	}()
+	// Each operation starts in the background context, so that a context
+	// entered by one does not leak into the next
+	r.asyncContext.Run(context.Background(), fn)
+}
+
+// SupervisorOptions configures Supervise
+type SupervisorOptions struct {
+	// Restart recreates the runtime after a panic. Without it Supervise
+	// returns the PanicError once the runtime has been torn down.
+	Restart bool
+	// MaxRestarts stops restarting after this many restarts; 0 means no limit
+	MaxRestarts int
+	// MinBackoff is the delay before the first restart, doubled after each
+	// one up to MaxBackoff. Defaults to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// StableAfter resets the backoff when a runtime ran this long before
+	// panicking. Defaults to one minute.
+	StableAfter time.Duration
+}
+
+// restartInfo describes the crash a restarted runtime replaces
+type restartInfo struct {
+	restarts int
+	err      *PanicError
+	backoff  time.Duration
+}
+
+// Supervise runs a program with run on a runtime from newRuntime. When the
+// runtime panics it is disposed and, with opts.Restart, replaced by a new
+// one after a backoff; the new runtime publishes a runtimeRestarted event.
+// Supervise returns the result of the last run.
+func Supervise(opts SupervisorOptions, newRuntime func() (*Runtime, error), run func(*Runtime) error) error {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.StableAfter <= 0 {
+		opts.StableAfter = time.Minute
+	}
+
+	backoff := opts.MinBackoff
+	var restart *restartInfo
+	for {
+		rt, err := newRuntime()
+		if err != nil {
+			return err
+		}
+		rt.restart = restart
+
+		started := time.Now()
+		err = run(rt)
+		rt.Dispose()
+
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) || !opts.Restart {
+			return err
+		}
+		restarts := 1
+		if restart != nil {
+			restarts = restart.restarts + 1
+		}
+		if opts.MaxRestarts > 0 && restarts > opts.MaxRestarts {
+			fmt.Fprintf(os.Stderr, "gode: giving up after %d restarts\n", opts.MaxRestarts)
+			return err
+		}
+
+		if time.Since(started) >= opts.StableAfter {
+			backoff = opts.MinBackoff
+		}
+		fmt.Fprintf(os.Stderr, "gode: restarting runtime in %v\n", backoff)
+		time.Sleep(backoff)
+
+		restart = &restartInfo{restarts: restarts, err: panicErr, backoff: backoff}
+		backoff *= 2
+		if backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+}
+
+// announceRestart publishes runtimeRestarted if this runtime replaces one
+// that panicked
+func (r *Runtime) announceRestart() {
+	if r.restart == nil {
+		return
+	}
+	r.queueRuntimeEvent(EventRuntimeRestarted, map[string]interface{}{
+		"restarts": r.restart.restarts,
+		"error":    fmt.Sprint(r.restart.err.Value),
+		"backoff":  float64(r.restart.backoff) / float64(time.Millisecond),
+	})
+}
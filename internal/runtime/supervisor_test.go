@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRestartsAfterPanic(t *testing.T) {
+	runs := 0
+	var restarts interface{}
+	opts := SupervisorOptions{Restart: true, MaxRestarts: 2, MinBackoff: time.Millisecond}
+	err := Supervise(opts, func() (*Runtime, error) {
+		rt := New()
+		return rt, rt.Configure(nil)
+	}, func(rt *Runtime) error {
+		runs++
+		// A panic in a function called from JS becomes a JS exception, so
+		// the crash comes from a queued Go callback, as a native module's
+		// would
+		rt.SetGlobal("crash", func() {
+			if runs == 1 {
+				rt.QueueJSOperation(func() { panic("boom") })
+			}
+		})
+		rt.SetGlobal("report", func(n interface{}) { restarts = n })
+		return rt.RunSource("main.js", `
+			require('gode:events/runtime').on('runtimeRestarted', e => report(e.restarts));
+			crash();
+		`)
+	})
+	if err != nil {
+		t.Fatalf("Supervise() failed: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("Expected 2 runs, got %d", runs)
+	}
+	if restarts != int64(1) {
+		t.Errorf("Expected a runtimeRestarted event with restarts 1, got %v", restarts)
+	}
+}
+
+func TestSupervisorWithoutRestart(t *testing.T) {
+	err := Supervise(SupervisorOptions{}, func() (*Runtime, error) {
+		rt := New()
+		return rt, rt.Configure(nil)
+	}, func(rt *Runtime) error {
+		rt.SetGlobal("crash", func() {
+			rt.QueueJSOperation(func() { panic("boom") })
+		})
+		return rt.RunSource("main.js", "crash()")
+	})
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Errorf("Expected a PanicError, got %v", err)
+	}
+}
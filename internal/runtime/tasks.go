@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// EventTaskPanic is published on gode:events/runtime when a background task
+// started with Go panics
+const EventTaskPanic = "taskPanic"
+
+// taskShutdownTimeout bounds how long Dispose waits for background tasks to
+// return after their context is cancelled
+const taskShutdownTimeout = 5 * time.Second
+
+// tasks tracks the background goroutines started with Runtime.Go
+type tasks struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newTasks() *tasks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &tasks{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a background goroutine tied to the runtime's lifetime. ctx
+// is cancelled when the runtime shuts down, by process.exit or Dispose, and
+// Dispose waits briefly for running tasks to return. A panic in fn is
+// reported on stderr and as a taskPanic runtime event rather than crashing
+// the process. Go implements plugins.TaskRunner.
+func (r *Runtime) Go(name string, fn func(ctx context.Context)) {
+	r.tasks.wg.Add(1)
+	go func() {
+		defer r.tasks.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				fmt.Fprintf(os.Stderr, "gode: background task %q panicked: %v\n%s", name, p, debug.Stack())
+				r.queueRuntimeEvent(EventTaskPanic, map[string]interface{}{
+					"name":  name,
+					"error": fmt.Sprint(p),
+				})
+			}
+		}()
+		fn(r.tasks.ctx)
+	}()
+}
+
+// stopTasks cancels the context of every background task
+func (r *Runtime) stopTasks() {
+	r.tasks.cancel()
+}
+
+// waitForTasks waits up to timeout for background tasks to return and
+// reports whether they all did
+func (r *Runtime) waitForTasks(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		r.tasks.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,33 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRuntimeBackgroundTasks(t *testing.T) {
+	rt := New()
+
+	stopped := make(chan struct{})
+	rt.Go("waiter", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+	rt.Go("panicker", func(ctx context.Context) {
+		panic("boom")
+	})
+
+	rt.Dispose()
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Expected Dispose to cancel and wait for background tasks")
+	}
+
+	// Tasks started after shutdown see a cancelled context
+	late := make(chan error, 1)
+	rt.Go("late", func(ctx context.Context) { late <- ctx.Err() })
+	if err := <-late; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by TuningFromEnv
+const (
+	EnvMaxProcs  = "GODE_MAX_PROCS"
+	EnvGCPercent = "GODE_GC_PERCENT"
+)
+
+// GCOff is the GCPercent that turns the garbage collector off, as GOGC=off
+const GCOff = -1
+
+// Tuning holds settings of the Go runtime that gode applies before it
+// creates a JS runtime, so operators can fit it to a container without
+// rebuilding. Unset fields keep Go's defaults.
+type Tuning struct {
+	// MaxProcs is GOMAXPROCS, the number of threads running Go code at
+	// once. Zero keeps the default, the number of CPUs.
+	MaxProcs int
+	// GCPercent is the heap growth, as a percentage of the live heap, that
+	// triggers a collection, or GCOff. It is only applied if SetGCPercent
+	// is set, since zero is a valid value.
+	GCPercent    int
+	SetGCPercent bool
+}
+
+// TuningFromEnv reads the tuning set with GODE_MAX_PROCS and
+// GODE_GC_PERCENT
+func TuningFromEnv() (Tuning, error) {
+	var t Tuning
+	if v := os.Getenv(EnvMaxProcs); v != "" {
+		if err := t.ParseMaxProcs(v); err != nil {
+			return t, fmt.Errorf("%s=%s: %w", EnvMaxProcs, v, err)
+		}
+	}
+	if v := os.Getenv(EnvGCPercent); v != "" {
+		if err := t.ParseGCPercent(v); err != nil {
+			return t, fmt.Errorf("%s=%s: %w", EnvGCPercent, v, err)
+		}
+	}
+	return t, nil
+}
+
+// ParseMaxProcs sets MaxProcs from a positive number
+func (t *Tuning) ParseMaxProcs(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return errors.New("want a positive number")
+	}
+	t.MaxProcs = n
+	return nil
+}
+
+// ParseGCPercent sets GCPercent from a number that is not negative, or
+// "off"
+func (t *Tuning) ParseGCPercent(s string) error {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "off") {
+		t.GCPercent, t.SetGCPercent = GCOff, true
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return errors.New("want a number that is not negative, or off")
+	}
+	t.GCPercent, t.SetGCPercent = n, true
+	return nil
+}
+
+// Apply sets the tuning on the Go runtime, for the whole process
+func (t Tuning) Apply() {
+	if t.MaxProcs > 0 {
+		goruntime.GOMAXPROCS(t.MaxProcs)
+	}
+	if t.SetGCPercent {
+		debug.SetGCPercent(t.GCPercent)
+	}
+}
+
+// CurrentTuning returns the settings the Go runtime is using, whether set
+// by Apply, GOMAXPROCS and GOGC or by default
+func CurrentTuning() Tuning {
+	// Reading the GC percent means setting it, so put it straight back
+	percent := debug.SetGCPercent(GCOff)
+	debug.SetGCPercent(percent)
+	return Tuning{MaxProcs: goruntime.GOMAXPROCS(0), GCPercent: percent, SetGCPercent: true}
+}
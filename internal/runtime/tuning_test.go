@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"os"
+	goruntime "runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestTuningFromEnv(t *testing.T) {
+	for _, env := range []string{EnvMaxProcs, EnvGCPercent} {
+		old, ok := os.LookupEnv(env)
+		defer func(env string) {
+			if ok {
+				os.Setenv(env, old)
+			} else {
+				os.Unsetenv(env)
+			}
+		}(env)
+	}
+
+	os.Setenv(EnvMaxProcs, "3")
+	os.Setenv(EnvGCPercent, "off")
+	tuning, err := TuningFromEnv()
+	if err != nil {
+		t.Fatalf("TuningFromEnv() failed: %v", err)
+	}
+	if want := (Tuning{MaxProcs: 3, GCPercent: GCOff, SetGCPercent: true}); tuning != want {
+		t.Errorf("TuningFromEnv() = %+v, want %+v", tuning, want)
+	}
+
+	os.Unsetenv(EnvMaxProcs)
+	os.Setenv(EnvGCPercent, "0")
+	if tuning, err := TuningFromEnv(); err != nil || tuning != (Tuning{SetGCPercent: true}) {
+		t.Errorf("TuningFromEnv() with GC percent 0 = %+v, %v", tuning, err)
+	}
+
+	for env, value := range map[string]string{EnvMaxProcs: "0", EnvGCPercent: "-5"} {
+		os.Setenv(env, value)
+		if _, err := TuningFromEnv(); err == nil {
+			t.Errorf("Expected %s=%s to be rejected", env, value)
+		}
+		os.Unsetenv(env)
+	}
+}
+
+func TestTuningApply(t *testing.T) {
+	defer goruntime.GOMAXPROCS(goruntime.GOMAXPROCS(0))
+	defer debug.SetGCPercent(debug.SetGCPercent(100))
+
+	Tuning{MaxProcs: 2, GCPercent: 50, SetGCPercent: true}.Apply()
+	if got := CurrentTuning(); got.MaxProcs != 2 || got.GCPercent != 50 {
+		t.Errorf("CurrentTuning() after Apply() = %+v", got)
+	}
+	// Unset fields are left alone
+	Tuning{MaxProcs: 1}.Apply()
+	if got := CurrentTuning(); got.MaxProcs != 1 || got.GCPercent != 50 {
+		t.Errorf("CurrentTuning() after a partial Apply() = %+v", got)
+	}
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	result, err := rt.RunScript("tuning", `
+		const { maxProcs, gcPercent, numCPU } = require('gode:core').tuning();
+		[maxProcs, gcPercent, numCPU > 0].join(',');
+	`)
+	if err != nil {
+		t.Fatalf("RunScript() failed: %v", err)
+	}
+	if result != "1,50,true" {
+		t.Errorf("core.tuning() = %v, want 1,50,true", result)
+	}
+}
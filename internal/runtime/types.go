@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rizqme/gode/internal/typegen"
+)
+
+// TypeDefinitions returns TypeScript declarations for every gode: built-in
+// module and every plugin loaded so far
+func (r *Runtime) TypeDefinitions() string {
+	done := make(chan string, 1)
+	r.QueueJSOperation(func() {
+		var b strings.Builder
+		b.WriteString(typegen.Header)
+
+		names := make([]string, 0, len(r.modules))
+		for name := range r.modules {
+			if strings.HasPrefix(name, "gode:") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b.WriteString("\n")
+			b.WriteString(typegen.Module(name, r.modules[name]))
+		}
+
+		if r.moduleManager != nil {
+			for _, info := range r.moduleManager.Plugins() {
+				b.WriteString("\n")
+				b.WriteString(typegen.Plugin(info.Name, info.Version, info.Plugin.Exports()))
+			}
+		}
+		done <- b.String()
+	})
+	return <-done
+}
@@ -0,0 +1,26 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuntimeTypeDefinitions(t *testing.T) {
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+
+	defs := rt.TypeDefinitions()
+	for _, want := range []string{
+		`declare module "gode:core" {`,
+		`  export function plugins(...args: any[]): any;`,
+		`  export const version: string;`,
+		`declare module "gode:events/runtime" {`,
+	} {
+		if !strings.Contains(defs, want) {
+			t.Errorf("Expected definitions to contain %q", want)
+		}
+	}
+}
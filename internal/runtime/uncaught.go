@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/modules/globals"
+)
+
+// rejections tracks promises rejected with no handler. Those still
+// unhandled once the operation that rejected them, and its microtasks, has
+// finished are reported as unhandledRejection. It is only used on the JS
+// thread.
+type rejections struct {
+	pending  []*goja.Promise        // rejected with no handler, in order
+	reported map[*goja.Promise]bool // reported and not handled since
+}
+
+// trackRejection is the goja promise rejection tracker. It is called when
+// a promise is rejected with no handler, and when one is added to a
+// rejected promise.
+func (r *Runtime) trackRejection(p *goja.Promise, op goja.PromiseRejectionOperation) {
+	if op == goja.PromiseRejectionReject {
+		r.rejections.pending = append(r.rejections.pending, p)
+		return
+	}
+	for i, q := range r.rejections.pending {
+		if q == p {
+			r.rejections.pending = append(r.rejections.pending[:i:i], r.rejections.pending[i+1:]...)
+			return
+		}
+	}
+	if r.rejections.reported[p] {
+		// Handled too late, as Node reports with rejectionHandled
+		delete(r.rejections.reported, p)
+		r.QueueJSOperation(func() {
+			r.emitProcessEvent("rejectionHandled", r.runtime.ToValue(p))
+		})
+	}
+}
+
+// reportRejections reports the promises still rejected with no handler,
+// including those rejected by the listeners it calls. It must run on the
+// JS thread.
+func (r *Runtime) reportRejections() {
+	for len(r.rejections.pending) > 0 && !r.exiting {
+		p := r.rejections.pending[0]
+		r.rejections.pending = r.rejections.pending[1:]
+		if r.rejections.reported == nil {
+			r.rejections.reported = make(map[*goja.Promise]bool)
+		}
+		r.rejections.reported[p] = true
+		globals.ReportUnhandledRejection(r.runtime, p.Result(), r.runtime.ToValue(p))
+	}
+}
+
+// catchUncaught hands err, thrown by the main script, to the process
+// 'uncaughtException' listeners and returns nil if there are any, so the
+// script keeps running as in Node. Without listeners it returns err, to be
+// reported with its stack trace. It must run on the JS thread.
+func (r *Runtime) catchUncaught(err error) error {
+	ex, ok := err.(*goja.Exception)
+	if !ok {
+		return err
+	}
+	process, ok := r.runtime.Get("process").(*goja.Object)
+	if !ok {
+		return err
+	}
+	count, ok := goja.AssertFunction(process.Get("listenerCount"))
+	if !ok {
+		return err
+	}
+	if n, countErr := count(process, r.runtime.ToValue("uncaughtException")); countErr != nil || n.ToInteger() == 0 {
+		return err
+	}
+	globals.ReportUncaught(r.runtime, ex.Value())
+	return nil
+}
+
+// ReportUncaught hands err, thrown by a callback with no caller to catch
+// it, to the process 'uncaughtException' listeners, or prints it and exits
+// with status 1 when there are none. It must be called on the JS thread.
+func (r *Runtime) ReportUncaught(err goja.Value) {
+	globals.ReportUncaught(r.runtime, err)
+}
@@ -0,0 +1,513 @@
+package runtime
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rizqme/gode/goja"
+	"github.com/rizqme/gode/internal/errors"
+	"github.com/rizqme/gode/internal/jserror"
+	"github.com/rizqme/gode/internal/jsprogram"
+	"github.com/rizqme/gode/internal/modules"
+	"github.com/rizqme/gode/internal/modules/globals"
+)
+
+// workerEvents are the event helpers shared by Worker and the global scope
+// of a worker. dispatch calls the on<type> handler of target and the
+// listeners added for event.type, reporting what they throw through
+// native.report, and returns whether there were any.
+const workerEvents = `
+	function addListener(listeners, type, listener) {
+		if (typeof listener !== "function") {
+			return;
+		}
+		const list = listeners.get(type) || [];
+		if (!list.includes(listener)) {
+			list.push(listener);
+		}
+		listeners.set(type, list);
+	}
+
+	function removeListener(listeners, type, listener) {
+		const list = listeners.get(type) || [];
+		const i = list.indexOf(listener);
+		if (i >= 0) {
+			list.splice(i, 1);
+		}
+	}
+
+	function dispatch(target, listeners, event) {
+		const handlers = (listeners.get(event.type) || []).slice();
+		const handler = target["on" + event.type];
+		if (typeof handler === "function") {
+			handlers.unshift(handler);
+		}
+		for (const fn of handlers) {
+			try {
+				fn.call(target, event);
+			} catch (error) {
+				native.report(error);
+			}
+		}
+		return handlers.length > 0;
+	}
+`
+
+// workerSetup defines Worker. It is given the native functions
+// spawn(source, options, dispatch), which starts a worker and returns its
+// handle, and report(error). An error event nobody handles is reported as
+// an uncaught exception.
+const workerSetup = `(function (native) {
+	"use strict";
+` + workerEvents + `
+	const states = new WeakMap();
+
+	function get(worker) {
+		const state = states.get(worker);
+		if (!state) {
+			throw new TypeError("Illegal invocation: not a Worker");
+		}
+		return state;
+	}
+
+	class Worker {
+		constructor(source, options) {
+			const state = { listeners: new Map(), handle: null };
+			this.onmessage = null;
+			this.onerror = null;
+			states.set(this, state);
+			const receive = (type, payload) => {
+				let event;
+				if (type === "error") {
+					const error = new Error(payload.message);
+					error.stack = payload.stack;
+					event = { type, message: payload.message, error, target: this };
+				} else {
+					event = { type, data: payload, target: this };
+				}
+				if (!dispatch(this, state.listeners, event) && type === "error") {
+					native.report(event.error);
+				}
+			};
+			state.handle = native.spawn(source, options == null ? {} : options, receive);
+		}
+
+		postMessage(value) {
+			get(this).handle.post(value);
+		}
+
+		terminate() {
+			get(this).handle.terminate();
+		}
+
+		ref() {
+			get(this).handle.ref();
+			return this;
+		}
+
+		unref() {
+			get(this).handle.unref();
+			return this;
+		}
+
+		addEventListener(type, listener) {
+			addListener(get(this).listeners, String(type), listener);
+		}
+
+		removeEventListener(type, listener) {
+			removeListener(get(this).listeners, String(type), listener);
+		}
+
+		get [Symbol.toStringTag]() {
+			return "Worker";
+		}
+	}
+	return Worker;
+})`
+
+// workerScope sets up the global scope of a worker: self, name,
+// postMessage, close, onmessage and addEventListener. It is given the
+// native functions post(value), close(), ref(listening) and report(error),
+// and returns deliver(data), which dispatches a message from the parent.
+// The worker stays alive while it listens for messages, and messages that
+// arrive before anything listens wait for the first listener.
+const workerScope = `(function (native, name) {
+	"use strict";
+` + workerEvents + `
+	const listeners = new Map();
+	let onmessage = null;
+	let listening = false;
+	let queued = [];
+
+	function update() {
+		const now = typeof onmessage === "function" || (listeners.get("message") || []).length > 0;
+		if (now !== listening) {
+			listening = now;
+			native.ref(now);
+		}
+		if (now && queued.length > 0) {
+			const pending = queued;
+			queued = [];
+			queueMicrotask(() => pending.forEach(deliver));
+		}
+	}
+
+	function deliver(data) {
+		if (!listening) {
+			queued.push(data);
+			return;
+		}
+		dispatch(globalThis, listeners, { type: "message", data, target: globalThis });
+	}
+
+	Object.defineProperty(globalThis, "onmessage", {
+		get: () => onmessage,
+		set(fn) {
+			onmessage = fn;
+			update();
+		},
+		enumerable: true,
+		configurable: true,
+	});
+	globalThis.self = globalThis;
+	globalThis.name = name;
+	globalThis.postMessage = (value) => native.post(value);
+	globalThis.close = () => native.close();
+	globalThis.addEventListener = (type, listener) => {
+		addListener(listeners, String(type), listener);
+		update();
+	};
+	globalThis.removeEventListener = (type, listener) => {
+		removeListener(listeners, String(type), listener);
+		update();
+	};
+	return deliver;
+})`
+
+// worker runs a Worker's script in a runtime of its own, on its own
+// goroutine. Messages are copied with structuredClone and queued on the
+// JS thread of the runtime they are sent to.
+type worker struct {
+	parent *Runtime
+	child  *Runtime
+	name   string
+	events goja.Callable // the parent's receive(type, payload)
+	inbox  goja.Callable // the child's deliver(data)
+
+	mu         sync.Mutex
+	started    bool          // the child's scope is set up
+	done       bool          // finished or terminated; no more messages
+	pending    []interface{} // messages posted before the child started
+	release    func()        // keeps the parent alive, nil once unref'd
+	listen     func()        // keeps the child alive while it listens
+	removeHook func()
+}
+
+// setupWorker installs Worker. It must be called on the JS thread.
+func (r *Runtime) setupWorker() error {
+	vm := r.runtime
+	setup, err := jsprogram.Run(vm, "gode:worker", workerSetup)
+	if err != nil {
+		return err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("worker setup is not a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("spawn", r.spawnWorker)
+	native.Set("report", func(err goja.Value) {
+		globals.ReportUncaught(vm, err)
+	})
+	class, err := fn(goja.Undefined(), native)
+	if err != nil {
+		return err
+	}
+	return vm.Set("Worker", class)
+}
+
+// spawnWorker starts the worker for new Worker(source, options) and returns
+// its handle. source is a Blob, a blob: or data: URL, or the path or file:
+// URL of a script.
+func (r *Runtime) spawnWorker(call goja.FunctionCall) goja.Value {
+	vm := r.runtime
+	source, options := call.Argument(0), call.Argument(1).ToObject(vm)
+	events, ok := goja.AssertFunction(call.Argument(2))
+	if !ok {
+		panic(vm.NewTypeError("Worker needs a receive function"))
+	}
+
+	w := &worker{parent: r, events: events}
+	if name := options.Get("name"); name != nil && !goja.IsUndefined(name) {
+		w.name = name.String()
+	}
+
+	var path, fileName, code string
+	if data, _, isBlob := r.blobContents(source); isBlob {
+		if err := r.CheckPermission("code", "blob:"); err != nil {
+			panic(jserror.New(vm, err))
+		}
+		if len(data) > modules.MaxInlineSize {
+			panic(vm.NewTypeError(fmt.Sprintf("Worker script is larger than %d bytes", modules.MaxInlineSize)))
+		}
+		fileName, code = "[worker blob]", string(data)
+		if s, ok := source.Export().(string); ok {
+			fileName = s
+		}
+	} else {
+		specifier := source.String()
+		switch {
+		case modules.IsDataURL(specifier):
+			if err := r.CheckPermission("code", "data:"); err != nil {
+				panic(jserror.New(vm, err))
+			}
+			mediaType, data, err := modules.ParseDataURL(specifier)
+			if err != nil {
+				panic(vm.NewTypeError(err.Error()))
+			}
+			if !strings.HasSuffix(mediaType, "javascript") && !strings.HasSuffix(mediaType, "ecmascript") {
+				panic(vm.NewTypeError(fmt.Sprintf("Worker scripts must be JavaScript, got %q", mediaType)))
+			}
+			fileName, code = modules.DataURLName(specifier), string(data)
+		case strings.HasPrefix(specifier, "blob:"):
+			panic(vm.NewTypeError(fmt.Sprintf("unknown or revoked blob: URL %s", specifier)))
+		default:
+			if u, err := url.Parse(specifier); err == nil && u.Scheme == "file" {
+				specifier = u.Path
+			}
+			abs, err := filepath.Abs(specifier)
+			if err != nil {
+				panic(jserror.New(vm, err))
+			}
+			if err := r.CheckPermission("read", abs); err != nil {
+				panic(jserror.New(vm, err))
+			}
+			path = abs
+		}
+	}
+
+	w.child = New()
+	w.child.worker = w
+	w.release = r.KeepAlive()
+	w.removeHook = r.AddShutdownHook(w.terminate)
+	go w.run(path, fileName, code)
+
+	handle := vm.NewObject()
+	handle.Set("post", func(value goja.Value) {
+		clone, err := globals.StructuredClone(value.Export())
+		if err != nil {
+			panic(jserror.New(vm, err))
+		}
+		w.post(clone)
+	})
+	handle.Set("terminate", w.terminate)
+	handle.Set("ref", func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.done && w.release == nil {
+			w.release = r.KeepAlive()
+		}
+	})
+	handle.Set("unref", func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.release != nil {
+			w.release()
+			w.release = nil
+		}
+	})
+	return handle
+}
+
+// run configures the child runtime and runs the script: the file at path,
+// or code named fileName
+func (w *worker) run(path, fileName, code string) {
+	defer w.finish()
+
+	child := w.child
+	if err := child.Configure(w.parent.config, w.parent.argv); err != nil {
+		w.fail(err.Error(), "")
+		return
+	}
+	setup := make(chan error, 1)
+	child.QueueJSOperation(func() {
+		setup <- w.setupScope()
+	})
+	if err := <-setup; err != nil {
+		w.fail(err.Error(), "")
+		return
+	}
+
+	// Messages posted meanwhile are queued before the script, and wait in
+	// the scope for a listener
+	w.mu.Lock()
+	if w.done {
+		w.mu.Unlock()
+		return
+	}
+	w.started = true
+	for _, msg := range w.pending {
+		w.deliver(msg)
+	}
+	w.pending = nil
+	w.mu.Unlock()
+
+	// Errors are reported to the parent by runMain
+	if path != "" {
+		child.Run(path)
+	} else {
+		child.RunSource(fileName, code)
+	}
+}
+
+// setupScope installs the worker globals in the child. It runs on the
+// child's JS thread.
+func (w *worker) setupScope() error {
+	child := w.child
+	vm := child.runtime
+	setup, err := jsprogram.Run(vm, "gode:worker-scope", workerScope)
+	if err != nil {
+		return err
+	}
+	fn, ok := goja.AssertFunction(setup)
+	if !ok {
+		return fmt.Errorf("worker scope setup is not a function")
+	}
+
+	native := vm.NewObject()
+	native.Set("post", func(value goja.Value) {
+		clone, err := globals.StructuredClone(value.Export())
+		if err != nil {
+			panic(jserror.New(vm, err))
+		}
+		w.toParent("message", clone)
+	})
+	native.Set("close", func() {
+		child.Exit(0)
+	})
+	native.Set("ref", func(listening bool) {
+		if listening && w.listen == nil {
+			w.listen = child.KeepAlive()
+		} else if !listening && w.listen != nil {
+			w.listen()
+			w.listen = nil
+		}
+	})
+	native.Set("report", func(value goja.Value) {
+		message, stack := value.String(), ""
+		if obj, ok := value.(*goja.Object); ok {
+			if m := obj.Get("message"); m != nil && !goja.IsUndefined(m) {
+				message = m.String()
+			}
+			if s := obj.Get("stack"); s != nil && !goja.IsUndefined(s) {
+				stack = s.String()
+			}
+		}
+		w.fail(message, stack)
+	})
+
+	deliver, err := fn(goja.Undefined(), native, vm.ToValue(w.name))
+	if err != nil {
+		return err
+	}
+	w.inbox, _ = goja.AssertFunction(deliver)
+	return nil
+}
+
+// post sends a cloned message to the child
+func (w *worker) post(msg interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.done:
+	case !w.started:
+		w.pending = append(w.pending, msg)
+	default:
+		w.deliver(msg)
+	}
+}
+
+// deliver queues msg on the child's JS thread. w.mu must be held.
+func (w *worker) deliver(msg interface{}) {
+	child := w.child
+	child.QueueJSOperation(func() {
+		if _, err := w.inbox(goja.Undefined(), child.runtime.ToValue(msg)); err != nil {
+			w.fail(err.Error(), "")
+		}
+	})
+}
+
+// toParent queues an event for the Worker object in the parent
+func (w *worker) toParent(kind string, payload interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	parent := w.parent
+	parent.QueueJSOperation(func() {
+		vm := parent.runtime
+		w.events(goja.Undefined(), vm.ToValue(kind), vm.ToValue(payload))
+	})
+}
+
+// fail reports an error in the worker to its Worker object
+func (w *worker) fail(message, stack string) {
+	if stack == "" {
+		stack = message
+	}
+	w.toParent("error", map[string]interface{}{"message": message, "stack": stack})
+}
+
+// workerErrorMessage returns the message of the error a worker's script
+// failed with, without the location goja appends to exceptions
+func workerErrorMessage(err *errors.ModuleError) string {
+	if ex, ok := err.Err.(*goja.Exception); ok {
+		if obj, ok := ex.Value().(*goja.Object); ok {
+			if message := obj.Get("message"); message != nil && !goja.IsUndefined(message) {
+				return message.String()
+			}
+		}
+		return ex.Value().String()
+	}
+	return err.Err.Error()
+}
+
+// terminate stops the worker at once, interrupting the script if it is
+// running. Messages it has not handled are dropped.
+func (w *worker) terminate() {
+	w.mu.Lock()
+	if w.done {
+		w.mu.Unlock()
+		return
+	}
+	w.done = true
+	w.mu.Unlock()
+
+	child := w.child
+	child.QueueJSOperationWithPriority(PriorityInteractive, func() {
+		child.Exit(1)
+	})
+	if child.busyNow() {
+		child.interrupt(&ExitError{Code: 1})
+	}
+}
+
+// finish disposes of the child once its script has ended and lets the
+// parent exit
+func (w *worker) finish() {
+	w.mu.Lock()
+	w.done = true
+	if w.release != nil {
+		w.release()
+		w.release = nil
+	}
+	w.mu.Unlock()
+
+	w.removeHook()
+	w.child.Dispose()
+}
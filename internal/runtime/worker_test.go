@@ -0,0 +1,67 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rizqme/gode/pkg/config"
+)
+
+func TestRuntimeDataURLsAndWorkers(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.js")
+	os.WriteFile(main, []byte(`
+const lib = require("data:text/javascript,export const answer = 42;");
+const json = require('data:application/json,{"ok":true}');
+const results = [lib.answer, json.ok];
+
+const parts = new Blob(["a", new Uint8Array([98]), new Blob(["c"])], { type: "Text/Plain" });
+parts.text().then((text) => { globalThis.blob = [text, parts.size, parts.type, parts.slice(1).size].join(","); });
+
+const source = new Blob(["onmessage = (e) => postMessage(e.data.n * 2 + ':' + name);"], { type: "text/javascript" });
+const worker = new Worker(source, { name: "doubler" });
+worker.onmessage = (e) => {
+	results.push(e.data);
+	worker.terminate();
+	const failing = new Worker(URL.createObjectURL(new Blob(["throw new Error('boom')"])));
+	failing.onerror = (e) => {
+		results.push(e.message);
+		globalThis.result = results.join("|");
+	};
+};
+worker.postMessage({ n: 21 });
+`), 0644)
+
+	rt := New()
+	defer rt.Dispose()
+	if err := rt.Configure(nil); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	if err := rt.Run(main); err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if result, err := rt.RunScript("check", "globalThis.result"); err != nil || result != "42|true|42:doubler|boom" {
+		t.Errorf("result = %v, %v", result, err)
+	}
+	if blob, err := rt.RunScript("check", "globalThis.blob"); err != nil || blob != "abc,3,text/plain,2" {
+		t.Errorf("blob = %v, %v", blob, err)
+	}
+
+	// allow-code limits where inline code may come from
+	cfg := &config.PackageJSON{Name: "inline", ProjectRoot: dir}
+	cfg.Gode.Permissions.AllowCode = []string{"data:"}
+	restricted := New()
+	defer restricted.Dispose()
+	if err := restricted.Configure(cfg); err != nil {
+		t.Fatalf("Configure() failed: %v", err)
+	}
+	value, err := restricted.RunScript("denied", `
+		let code;
+		try { new Worker(new Blob(["1"])); } catch (e) { code = e.code; }
+		[require("data:text/javascript,module.exports = 'data ok'"), code].join("|");
+	`)
+	if err != nil || value != "data ok|ERR_PERMISSION_DENIED" {
+		t.Errorf("restricted = %v, %v", value, err)
+	}
+}
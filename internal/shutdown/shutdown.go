@@ -0,0 +1,70 @@
+// Package shutdown runs cleanup hooks once when a runtime exits, whether
+// through process.exit or by being disposed
+package shutdown
+
+import (
+	"sync"
+)
+
+// Manager holds the cleanup hooks for one runtime
+type Manager struct {
+	mu    sync.Mutex
+	hooks []*hook
+	done  bool
+}
+
+type hook struct {
+	fn func()
+}
+
+// New creates an empty manager
+func New() *Manager {
+	return &Manager{}
+}
+
+// Add registers fn to run at shutdown and returns a function that
+// unregisters it. Hooks added after shutdown run immediately.
+func (m *Manager) Add(fn func()) (remove func()) {
+	m.mu.Lock()
+	if m.done {
+		m.mu.Unlock()
+		fn()
+		return func() {}
+	}
+
+	h := &hook{fn: fn}
+	m.hooks = append(m.hooks, h)
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, other := range m.hooks {
+			if other == h {
+				m.hooks = append(m.hooks[:i], m.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Run calls every hook in reverse registration order. Only the first call
+// has any effect; a panicking hook does not stop the others.
+func (m *Manager) Run() {
+	m.mu.Lock()
+	if m.done {
+		m.mu.Unlock()
+		return
+	}
+	m.done = true
+	hooks := m.hooks
+	m.hooks = nil
+	m.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		func() {
+			defer func() { recover() }()
+			hooks[i].fn()
+		}()
+	}
+}
@@ -0,0 +1,29 @@
+package shutdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunOrderAndOnce(t *testing.T) {
+	m := New()
+	var order []string
+
+	m.Add(func() { order = append(order, "first") })
+	remove := m.Add(func() { order = append(order, "removed") })
+	m.Add(func() { panic("boom") })
+	m.Add(func() { order = append(order, "last") })
+	remove()
+
+	m.Run()
+	m.Run()
+
+	if want := []string{"last", "first"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected %v, got %v", want, order)
+	}
+
+	m.Add(func() { order = append(order, "late") })
+	if order[len(order)-1] != "late" {
+		t.Error("Expected a hook added after shutdown to run immediately")
+	}
+}
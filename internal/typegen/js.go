@@ -0,0 +1,106 @@
+package typegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rizqme/gode/goja"
+)
+
+// maxDepth limits how deeply nested objects of built-in modules are spelled
+// out; deeper values are declared as any
+const maxDepth = 3
+
+// Module declares a built-in module from its exports. Built-ins carry no Go
+// types to go on, so functions take and return any; capitalized functions
+// are treated as classes that can also be called with new.
+func Module(name string, exports goja.Value) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "declare module %q {\n", name)
+
+	obj, ok := exports.(*goja.Object)
+	if !ok {
+		fmt.Fprintf(&b, "  const value: %s;\n  export = value;\n}\n", valueType(exports, 0))
+		return b.String()
+	}
+
+	for _, key := range sortedKeys(obj) {
+		value := obj.Get(key)
+		switch {
+		case !IsIdentifier(key):
+			fmt.Fprintf(&b, "  // %q is not a valid export name\n", key)
+		case isFunction(value) && isClass(key):
+			fmt.Fprintf(&b, "  export const %s: GodeConstructor;\n", key)
+		case isFunction(value):
+			fmt.Fprintf(&b, "  export function %s(...args: any[]): any;\n", key)
+		default:
+			fmt.Fprintf(&b, "  export const %s: %s;\n", key, valueType(value, 1))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func valueType(value goja.Value, depth int) string {
+	if value == nil || goja.IsUndefined(value) {
+		return "undefined"
+	}
+	if goja.IsNull(value) {
+		return "null"
+	}
+
+	switch value.Export().(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64, float64:
+		return "number"
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return "any"
+	}
+	if _, ok := goja.AssertFunction(obj); ok {
+		return "(...args: any[]) => any"
+	}
+	if depth >= maxDepth {
+		return "any"
+	}
+
+	keys := sortedKeys(obj)
+	if len(keys) == 0 {
+		return "Record<string, any>"
+	}
+	fields := make([]string, 0, len(keys))
+	for _, key := range keys {
+		v := obj.Get(key)
+		switch {
+		case isClass(key) && isFunction(v):
+			fields = append(fields, Identifier(key)+": GodeConstructor")
+		case isFunction(v):
+			fields = append(fields, Identifier(key)+"(...args: any[]): any")
+		default:
+			fields = append(fields, Identifier(key)+": "+valueType(v, depth+1))
+		}
+	}
+	return "{ " + strings.Join(fields, "; ") + " }"
+}
+
+func sortedKeys(obj *goja.Object) []string {
+	keys := obj.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+func isFunction(value goja.Value) bool {
+	_, ok := goja.AssertFunction(value)
+	return ok
+}
+
+// isClass treats capitalized names, like Readable or URL, as constructors
+func isClass(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
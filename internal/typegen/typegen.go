@@ -0,0 +1,216 @@
+// Package typegen writes TypeScript declarations for gode modules: Go
+// plugins are described by reflecting over their exports, built-in modules
+// by walking their JavaScript values.
+package typegen
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Header declares the types shared by the generated modules
+const Header = `// Generated by gode types. Do not edit.
+
+/** Emitter for events a plugin raises with Emit */
+interface GodePluginEvents {
+  on(event: string, listener: (data: any) => void): this;
+  addListener(event: string, listener: (data: any) => void): this;
+  once(event: string, listener: (data: any) => void): this;
+  off(event: string, listener: (data: any) => void): this;
+  removeListener(event: string, listener: (data: any) => void): this;
+  listenerCount(event: string): number;
+}
+
+/** A built-in class, usable with or without new */
+interface GodeConstructor {
+  new (...args: any[]): any;
+  (...args: any[]): any;
+  [key: string]: any;
+}
+`
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// Plugin declares the module of a Go plugin from its exports
+func Plugin(name, version string, exports map[string]interface{}) string {
+	var b strings.Builder
+	if version != "" {
+		fmt.Fprintf(&b, "/** %s plugin v%s */\n", name, version)
+	}
+	fmt.Fprintf(&b, "declare module %q {\n", name)
+
+	keys := make([]string, 0, len(exports))
+	for key := range exports {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := exports[key]
+		switch {
+		case !IsIdentifier(key):
+			fmt.Fprintf(&b, "  // %q is not a valid export name\n", key)
+		case value == nil:
+			fmt.Fprintf(&b, "  export const %s: any;\n", key)
+		case reflect.TypeOf(value).Kind() == reflect.Func:
+			fmt.Fprintf(&b, "  export function %s%s;\n", key, Signature(reflect.TypeOf(value), ": "))
+		default:
+			fmt.Fprintf(&b, "  export const %s: %s;\n", key, Type(reflect.TypeOf(value)))
+		}
+	}
+	if _, exported := exports["events"]; !exported {
+		b.WriteString("  export const events: GodePluginEvents;\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Signature formats a Go function type as a TypeScript signature such as
+// "(arg0: number, ...arg1: string[]): boolean". sep separates the
+// parameters from the result: ": " for declarations, " => " for types.
+func Signature(t reflect.Type, sep string) string {
+	return signature(t, sep, map[reflect.Type]bool{})
+}
+
+func signature(t reflect.Type, sep string, seen map[reflect.Type]bool) string {
+	params := make([]string, t.NumIn())
+	for i := range params {
+		in := t.In(i)
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			params[i] = fmt.Sprintf("...arg%d: %s[]", i, typeOf(in.Elem(), seen))
+			continue
+		}
+		params[i] = fmt.Sprintf("arg%d: %s", i, typeOf(in, seen))
+	}
+	return "(" + strings.Join(params, ", ") + ")" + sep + results(t, seen)
+}
+
+// results maps Go results to the value JS sees. A trailing error is thrown
+// rather than returned, and several remaining values come back as an array.
+func results(t reflect.Type, seen map[reflect.Type]bool) string {
+	var out []string
+	for i := 0; i < t.NumOut(); i++ {
+		if i == t.NumOut()-1 && t.Out(i) == errorType {
+			break
+		}
+		out = append(out, typeOf(t.Out(i), seen))
+	}
+	switch len(out) {
+	case 0:
+		return "void"
+	case 1:
+		return out[0]
+	}
+	return "[" + strings.Join(out, ", ") + "]"
+}
+
+// Type maps a Go type to the TypeScript type of its JavaScript value
+func Type(t reflect.Type) string {
+	return typeOf(t, map[reflect.Type]bool{})
+}
+
+func typeOf(t reflect.Type, seen map[reflect.Type]bool) string {
+	if t == errorType {
+		return "Error"
+	}
+	if t == bytesType {
+		return "Uint8Array"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return array(typeOf(t.Elem(), seen))
+	case reflect.Map:
+		return "Record<string, " + typeOf(t.Elem(), seen) + ">"
+	case reflect.Ptr:
+		return typeOf(t.Elem(), seen) + " | null"
+	case reflect.Func:
+		return "(" + signature(t, " => ", seen) + ")"
+	case reflect.Struct:
+		return structType(t, seen)
+	}
+	return "any"
+}
+
+func array(elem string) string {
+	if strings.ContainsAny(elem, " |(") {
+		return "Array<" + elem + ">"
+	}
+	return elem + "[]"
+}
+
+// structType describes the exported fields of a struct by their JSON names.
+// Recursive structs are cut off with any.
+func structType(t reflect.Type, seen map[reflect.Type]bool) string {
+	if seen[t] {
+		return "any"
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, optional := f.Name, false
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				optional = optional || opt == "omitempty"
+			}
+		}
+		key := Identifier(name)
+		if optional {
+			key += "?"
+		}
+		fields = append(fields, key+": "+typeOf(f.Type, seen))
+	}
+	if len(fields) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(fields, "; ") + " }"
+}
+
+// Identifier quotes name for use as a property key when it is not a valid
+// JavaScript identifier
+func Identifier(name string) string {
+	if IsIdentifier(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// IsIdentifier reports whether name can be declared as an export
+func IsIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
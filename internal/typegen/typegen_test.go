@@ -0,0 +1,58 @@
+package typegen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X     int      `json:"x"`
+	Label string   `json:"label,omitempty"`
+	Tags  []string `json:"-"`
+	Next  *point
+	hide  bool
+}
+
+func TestType(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{0, "number"},
+		{"", "string"},
+		{[]byte(nil), "Uint8Array"},
+		{[]float64(nil), "number[]"},
+		{map[string]bool(nil), "Record<string, boolean>"},
+		{[]*int(nil), "Array<number | null>"},
+		{point{}, "{ x: number; label?: string; Next: any | null }"},
+		{func(string, ...int) (bool, error) { return false, nil }, "((arg0: string, ...arg1: number[]) => boolean)"},
+		{func(func(error, interface{})) {}, "((arg0: ((arg0: Error, arg1: any) => void)) => void)"},
+	}
+	for _, tt := range tests {
+		if got := Type(reflect.TypeOf(tt.value)); got != tt.want {
+			t.Errorf("Type(%T) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	decl := Plugin("math", "1.0.0", map[string]interface{}{
+		"add":     func(a, b int) int { return a + b },
+		"pi":      3.14,
+		"bad-key": 1,
+	})
+
+	for _, want := range []string{
+		`/** math plugin v1.0.0 */`,
+		`declare module "math" {`,
+		`  export function add(arg0: number, arg1: number): number;`,
+		`  export const pi: number;`,
+		`  // "bad-key" is not a valid export name`,
+		`  export const events: GodePluginEvents;`,
+	} {
+		if !strings.Contains(decl, want) {
+			t.Errorf("Expected declaration to contain %q, got:\n%s", want, decl)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package watch reports file system changes below a set of paths, built on
+// fsnotify. Directories are watched recursively by adding every
+// subdirectory, including ones created while watching, and bursts of
+// events are coalesced into batches.
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op is the kind of change to a path
+type Op string
+
+const (
+	Create Op = "create"
+	Change Op = "change"
+	Remove Op = "remove"
+)
+
+// Event is a single change to a path
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// Options configures a Watcher
+type Options struct {
+	// Recursive watches whole trees below directories instead of just their
+	// direct entries
+	Recursive bool
+
+	// Ignore holds glob patterns matched against both the base name and
+	// the slash-separated path relative to the watched root. Ignored
+	// directories are not watched at all.
+	Ignore []string
+
+	// Debounce is how long the tree must be quiet before a batch of changes
+	// is delivered. Defaults to 50ms.
+	Debounce time.Duration
+}
+
+// Watcher delivers coalesced batches of changes until it is closed
+type Watcher struct {
+	roots  []root
+	opts   Options
+	fsw    *fsnotify.Watcher
+	events chan []Event
+	errors chan error
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type root struct {
+	path string
+	dir  bool
+}
+
+// New starts watching paths. Each path must exist when the watcher starts;
+// it may be a file or a directory.
+func New(paths []string, opts Options) (*Watcher, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("watch: no paths given")
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = 50 * time.Millisecond
+	}
+	for _, pattern := range opts.Ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	roots := make([]root, len(paths))
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		roots[i] = root{path: abs, dir: info.IsDir()}
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		roots:  roots,
+		opts:   opts,
+		fsw:    fsw,
+		events: make(chan []Event),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+
+	for _, r := range roots {
+		// A single file is watched through its directory so that editors
+		// which save by writing a new file and renaming it are still seen
+		if !r.dir {
+			err = fsw.Add(filepath.Dir(r.path))
+		} else {
+			err = w.addDir(r.path, r.path)
+		}
+		if err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	go w.loop()
+	return w, nil
+}
+
+// Events delivers batches of changes sorted by path. It is closed when the
+// watcher is.
+func (w *Watcher) Events() <-chan []Event {
+	return w.events
+}
+
+// Errors reports failures from the underlying watcher
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close stops the watcher. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
+
+// addDir watches dir and, when recursive, every directory below it that is
+// not ignored
+func (w *Watcher) addDir(rootPath, dir string) error {
+	if err := w.fsw.Add(dir); err != nil {
+		return err
+	}
+	if !w.opts.Recursive {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && !w.ignored(rootPath, child) {
+			if err := w.addDir(rootPath, child); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) loop() {
+	defer close(w.events)
+	defer w.fsw.Close()
+
+	pending := make(map[string]Op)
+	var flush <-chan time.Time
+
+	add := func(path string, op Op) {
+		if merged, ok := Coalesce(pending[path], op); ok {
+			pending[path] = merged
+		} else {
+			delete(pending, path)
+		}
+		flush = time.After(w.opts.Debounce)
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			w.handle(ev, add)
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.report(err)
+
+		case <-flush:
+			flush = nil
+			if len(pending) == 0 {
+				continue
+			}
+
+			batch := make([]Event, 0, len(pending))
+			for path, op := range pending {
+				batch = append(batch, Event{Path: path, Op: op})
+			}
+			sort.Slice(batch, func(i, j int) bool { return batch[i].Path < batch[j].Path })
+			pending = make(map[string]Op)
+
+			select {
+			case w.events <- batch:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// handle translates one fsnotify event. Permission-only changes are
+// dropped since indexers and backup tools trigger them constantly.
+func (w *Watcher) handle(ev fsnotify.Event, add func(string, Op)) {
+	rootPath, ok := w.match(ev.Name)
+	if !ok {
+		return
+	}
+
+	switch {
+	case ev.Has(fsnotify.Create):
+		add(ev.Name, Create)
+
+		// Watch new directories, reporting anything created inside them
+		// before the watch was in place
+		info, err := os.Lstat(ev.Name)
+		if err != nil || !info.IsDir() || !w.opts.Recursive {
+			return
+		}
+		if err := w.addDir(rootPath, ev.Name); err != nil && !os.IsNotExist(err) {
+			w.report(err)
+		}
+		filepath.Walk(ev.Name, func(path string, info os.FileInfo, err error) error {
+			if err != nil || path == ev.Name {
+				return nil
+			}
+			if w.ignored(rootPath, path) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			add(path, Create)
+			return nil
+		})
+
+	case ev.Has(fsnotify.Write):
+		add(ev.Name, Change)
+
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		add(ev.Name, Remove)
+	}
+}
+
+// match returns the root that path is reported under, if any
+func (w *Watcher) match(path string) (string, bool) {
+	for _, r := range w.roots {
+		if !r.dir {
+			if path == r.path {
+				return r.path, true
+			}
+			continue
+		}
+
+		rel, err := filepath.Rel(r.path, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if !w.opts.Recursive && strings.ContainsRune(rel, filepath.Separator) {
+			continue
+		}
+		if w.ignored(r.path, path) {
+			continue
+		}
+		return r.path, true
+	}
+	return "", false
+}
+
+// Coalesce merges a change into the one already pending for the same path.
+// The second result is false when the two cancel out, as when a file is
+// created and removed again before the batch is delivered.
+func Coalesce(pending, next Op) (Op, bool) {
+	switch {
+	case pending == "":
+		return next, true
+	case pending == Create && next == Remove:
+		return "", false
+	case pending == Create:
+		return Create, true
+	case pending == Remove && next == Create:
+		return Change, true
+	}
+	return next, true
+}
+
+func (w *Watcher) ignored(rootPath, path string) bool {
+	if len(w.opts.Ignore) == 0 {
+		return false
+	}
+	base := filepath.Base(path)
+	rel, _ := filepath.Rel(rootPath, path)
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range w.opts.Ignore {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		// "dir/**" ignores everything below dir
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok && (rel == prefix || strings.HasPrefix(rel, prefix+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// report passes an error on without blocking the watcher
+func (w *Watcher) report(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
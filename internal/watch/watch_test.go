@@ -0,0 +1,97 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		pending, next Op
+		want          Op
+		keep          bool
+	}{
+		{"", Change, Change, true},
+		{Create, Change, Create, true},
+		{Create, Remove, "", false},
+		{Remove, Create, Change, true},
+		{Change, Remove, Remove, true},
+		{Change, Change, Change, true},
+	}
+
+	for _, tt := range tests {
+		got, keep := Coalesce(tt.pending, tt.next)
+		if got != tt.want || keep != tt.keep {
+			t.Errorf("Coalesce(%q, %q) = %q, %v; want %q, %v", tt.pending, tt.next, got, keep, tt.want, tt.keep)
+		}
+	}
+}
+
+func nextBatch(t *testing.T, w *Watcher) []Event {
+	t.Helper()
+	select {
+	case batch := <-w.Events():
+		return batch
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for changes")
+		return nil
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.MkdirAll(filepath.Join(dir, "node_modules"), 0755)
+	existing := filepath.Join(dir, "src", "existing.js")
+	os.WriteFile(existing, []byte("a"), 0644)
+
+	w, err := New([]string{dir}, Options{
+		Recursive: true,
+		Ignore:    []string{"node_modules", "*.tmp"},
+		Debounce:  30 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+	defer w.Close()
+
+	added := filepath.Join(dir, "src", "added.js")
+	os.WriteFile(added, []byte("new"), 0644)
+	os.WriteFile(existing, []byte("changed"), 0644)
+	os.WriteFile(filepath.Join(dir, "scratch.tmp"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "node_modules", "dep.js"), []byte("x"), 0644)
+
+	want := []Event{{Path: added, Op: Create}, {Path: existing, Op: Change}}
+	if got := nextBatch(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	sub := filepath.Join(dir, "src", "lib")
+	os.Mkdir(sub, 0755)
+	nested := filepath.Join(sub, "nested.js")
+	os.WriteFile(nested, []byte("x"), 0644)
+	want = []Event{{Path: sub, Op: Create}, {Path: nested, Op: Create}}
+	if got := nextBatch(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	os.Remove(added)
+	want = []Event{{Path: added, Op: Remove}}
+	if got := nextBatch(t, w); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	w.Close()
+	if _, ok := <-w.Events(); ok {
+		t.Error("Expected events to close with the watcher")
+	}
+}
+
+func TestNewMissingPath(t *testing.T) {
+	if _, err := New([]string{filepath.Join(t.TempDir(), "missing")}, Options{}); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
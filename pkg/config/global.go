@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvHome names the environment variable that moves the user-level gode
+// directory, ~/.gode by default
+const EnvHome = "GODE_HOME"
+
+// GlobalDir returns the user-level gode directory
+func GlobalDir() (string, error) {
+	if dir := os.Getenv(EnvHome); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate the home directory: %w", err)
+	}
+	return filepath.Join(home, ".gode"), nil
+}
+
+// GlobalConfigPath returns the path of the user-level config file
+func GlobalConfigPath() (string, error) {
+	dir, err := GlobalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// LoadGlobalConfig reads the user-level config file. It has the keys of
+// "gode" in package.json, and applies to every project beneath that
+// project's own settings. A missing file is an empty config.
+func LoadGlobalConfig() (GodeConfig, error) {
+	var cfg GodeConfig
+	settings, path, err := readGlobalSettings()
+	if err != nil || len(settings) == 0 {
+		return cfg, err
+	}
+	if err := decodeSettings(settings, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// GetGlobalSetting returns the value of key in the user-level config. key
+// is a dotted path such as "network.proxy" or "registries.npm"; an empty key
+// returns every setting.
+func GetGlobalSetting(key string) (value interface{}, ok bool, err error) {
+	settings, _, err := readGlobalSettings()
+	if err != nil {
+		return nil, false, err
+	}
+	if key == "" {
+		return settings, true, nil
+	}
+	parts := strings.Split(key, ".")
+	var current interface{} = settings
+	for _, part := range parts {
+		obj, isObject := current.(map[string]interface{})
+		if !isObject {
+			return nil, false, nil
+		}
+		if current, ok = obj[part]; !ok {
+			return nil, false, nil
+		}
+	}
+	return current, true, nil
+}
+
+// SetGlobalSetting sets key in the user-level config. value is parsed as
+// JSON when it can be, so true, 8080 and ["a", "b"] keep their types, and
+// is a string otherwise. Unknown keys and values of the wrong type are
+// rejected.
+func SetGlobalSetting(key, value string) error {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		parsed = value
+	}
+	return updateGlobalSettings(key, func(parent map[string]interface{}, name string) {
+		parent[name] = parsed
+	})
+}
+
+// UnsetGlobalSetting removes key from the user-level config
+func UnsetGlobalSetting(key string) error {
+	return updateGlobalSettings(key, func(parent map[string]interface{}, name string) {
+		delete(parent, name)
+	})
+}
+
+// readGlobalSettings reads the user-level config file as untyped JSON
+func readGlobalSettings() (map[string]interface{}, string, error) {
+	path, err := GlobalConfigPath()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, path, nil
+	}
+	if err != nil {
+		return nil, path, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, path, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return settings, path, nil
+}
+
+// updateGlobalSettings applies change to the object holding the last part
+// of key, creating the objects on the way, and writes the file back once
+// the result is a valid config
+func updateGlobalSettings(key string, change func(parent map[string]interface{}, name string)) error {
+	settings, path, err := readGlobalSettings()
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(key, ".")
+	parent := settings
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := parent[part].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			parent[part] = child
+		}
+		parent = child
+	}
+	change(parent, parts[len(parts)-1])
+
+	var cfg GodeConfig
+	if err := decodeSettings(settings, &cfg); err != nil {
+		return fmt.Errorf("invalid setting %s: %w", key, err)
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// The proxy URL may carry credentials
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// decodeSettings converts untyped settings to a GodeConfig, rejecting
+// unknown keys
+func decodeSettings(settings map[string]interface{}, cfg *GodeConfig) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(cfg)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlobalSettings(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvHome, home)
+
+	if _, ok, err := GetGlobalSetting("network.proxy"); ok || err != nil {
+		t.Fatalf("Expected no settings without a config file, got %v, %v", ok, err)
+	}
+
+	for key, value := range map[string]string{
+		"network.proxy":         "http://proxy.internal:3128",
+		"registries.company":    "https://npm.company.dev/",
+		"telemetry":             "false",
+		"permissions.allow-net": `["api.example.com"]`,
+	} {
+		if err := SetGlobalSetting(key, value); err != nil {
+			t.Fatalf("SetGlobalSetting(%s) failed: %v", key, err)
+		}
+	}
+
+	if value, ok, _ := GetGlobalSetting("registries.company"); !ok || value != "https://npm.company.dev/" {
+		t.Errorf("registries.company = %v, %v", value, ok)
+	}
+	if value, _, _ := GetGlobalSetting("telemetry"); value != false {
+		t.Errorf("Expected telemetry to be stored as a boolean, got %#v", value)
+	}
+
+	if err := SetGlobalSetting("network.proxxy", "typo"); err == nil {
+		t.Error("Expected an unknown key to be rejected")
+	}
+	if err := SetGlobalSetting("permissions.allow-net", "api.example.com"); err == nil {
+		t.Error("Expected a string where a list belongs to be rejected")
+	}
+
+	if err := UnsetGlobalSetting("network.proxy"); err != nil {
+		t.Fatalf("UnsetGlobalSetting() failed: %v", err)
+	}
+	if _, ok, _ := GetGlobalSetting("network.proxy"); ok {
+		t.Error("Expected proxy to be unset")
+	}
+
+	info, err := os.Stat(filepath.Join(home, "config.json"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected config.json with mode 0600, got %v, %v", info, err)
+	}
+}
+
+func TestGlobalConfigPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvHome, home)
+	os.WriteFile(filepath.Join(home, "config.json"), []byte(`{
+		"registries": {"company": "https://npm.company.dev/"},
+		"network": {"proxy": "http://global-proxy:3128"},
+		"cache-dir": "/var/cache/gode",
+		"permissions": {"allow-net": ["api.example.com"]}
+	}`), 0600)
+
+	// Without package.json the global settings apply over the defaults
+	pkg, err := LoadPackageJSON(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadPackageJSON() failed: %v", err)
+	}
+	if pkg.Gode.Network.Proxy != "http://global-proxy:3128" || !reflect.DeepEqual(pkg.Gode.Permissions.AllowNet, []string{"api.example.com"}) {
+		t.Errorf("Global settings not applied: %+v", pkg.Gode)
+	}
+	if pkg.Gode.Registries["npm"] == "" || pkg.Gode.Registries["company"] == "" {
+		t.Errorf("Expected default and global registries, got %v", pkg.Gode.Registries)
+	}
+
+	// package.json takes precedence over the global settings
+	project := t.TempDir()
+	os.WriteFile(filepath.Join(project, "package.json"), []byte(`{
+		"name": "app",
+		"gode": {"network": {"proxy": "http://project-proxy:8080"}, "permissions": {"allow-net": ["localhost"]}}
+	}`), 0644)
+	pkg, err = LoadPackageJSON(project)
+	if err != nil {
+		t.Fatalf("LoadPackageJSON() failed: %v", err)
+	}
+	if pkg.Gode.Network.Proxy != "http://project-proxy:8080" || pkg.Gode.CacheDir != "/var/cache/gode" {
+		t.Errorf("Proxy = %q, CacheDir = %q", pkg.Gode.Network.Proxy, pkg.Gode.CacheDir)
+	}
+	if !reflect.DeepEqual(pkg.Gode.Permissions.AllowNet, []string{"localhost"}) {
+		t.Errorf("AllowNet = %v, want the project's", pkg.Gode.Permissions.AllowNet)
+	}
+
+	os.WriteFile(filepath.Join(home, "config.json"), []byte(`{"unknown": true}`), 0600)
+	if _, err := LoadPackageJSON(project); err == nil {
+		t.Error("Expected an invalid global config to fail")
+	}
+}
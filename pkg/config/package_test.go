@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -416,6 +417,65 @@ func TestBuildConfig(t *testing.T) {
 	}
 }
 
+func TestCompatConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	data := []byte(`{"name": "compat-test", "version": "1.0.0", "gode": {"compat": {"buffer": "native"}}}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write package.json: %v", err)
+	}
+
+	pkg, err := LoadPackageJSON(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadPackageJSON() failed: %v", err)
+	}
+	if pkg.Gode.Compat["buffer"] != "native" {
+		t.Errorf("Expected compat buffer 'native', got %v", pkg.Gode.Compat)
+	}
+}
+
+func TestModuleNameMapperConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	data := []byte(`{"name": "mapper-test", "gode": {"test": {"timeout": 500, "moduleNameMapper": {
+		"gode:sql": "./test/fakes/sql.js",
+		"^@app/(.*)$": "./src/$1",
+		"\\.css$": "<rootDir>/test/fakes/style.js"
+	}}}}`)
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write package.json: %v", err)
+	}
+
+	pkg, err := LoadPackageJSON(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadPackageJSON() failed: %v", err)
+	}
+	want := ModuleNameMapper{
+		{Pattern: "gode:sql", Target: "./test/fakes/sql.js"},
+		{Pattern: "^@app/(.*)$", Target: "./src/$1"},
+		{Pattern: `\.css$`, Target: "<rootDir>/test/fakes/style.js"},
+	}
+	if !reflect.DeepEqual(pkg.Gode.Test.ModuleNameMapper, want) {
+		t.Errorf("ModuleNameMapper = %v, want %v", pkg.Gode.Test.ModuleNameMapper, want)
+	}
+	if pkg.Gode.Test.Timeout != 500 {
+		t.Errorf("Expected test timeout 500, got %d", pkg.Gode.Test.Timeout)
+	}
+
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	if string(encoded) != `{"gode:sql":"./test/fakes/sql.js","^@app/(.*)$":"./src/$1","\\.css$":"\u003crootDir\u003e/test/fakes/style.js"}` {
+		t.Errorf("Unexpected encoding %s", encoded)
+	}
+
+	var mapper ModuleNameMapper
+	if err := json.Unmarshal([]byte(`["./src"]`), &mapper); err == nil {
+		t.Error("Expected an array to be rejected")
+	}
+}
+
 func BenchmarkLoadPackageJSON(b *testing.B) {
 	// Create temporary directory with package.json
 	tmpDir, err := os.MkdirTemp("", "gode_bench")
@@ -0,0 +1,62 @@
+// Package gode embeds the gode JavaScript runtime in Go applications. It
+// exposes the runtime and the multi-tenant RuntimeManager; see the README
+// for the script-facing API.
+package gode
+
+import "github.com/rizqme/gode/internal/runtime"
+
+type (
+	// Runtime is a single JavaScript runtime with its own event loop
+	Runtime = runtime.Runtime
+	// Options configures a runtime created with NewWithOptions
+	Options = runtime.Options
+	// Stats is a snapshot of a runtime's health
+	Stats = runtime.Stats
+	// Priority selects the lane of Runtime.QueueJSOperationWithPriority
+	Priority = runtime.Priority
+	// ExitError reports a script that ended with process.exit or a
+	// non-zero process.exitCode
+	ExitError = runtime.ExitError
+	// PanicError reports a Go panic that stopped a runtime
+	PanicError = runtime.PanicError
+
+	// RuntimeManager runs one Runtime per tenant
+	RuntimeManager = runtime.RuntimeManager
+	// ManagerOptions configures a RuntimeManager
+	ManagerOptions = runtime.ManagerOptions
+	// ManagerStats aggregates the stats of a manager's tenants
+	ManagerStats = runtime.ManagerStats
+	// Quota limits the resources of one tenant's runtime
+	Quota = runtime.Quota
+	// QuotaError is the reason a tenant's runtime was stopped
+	QuotaError = runtime.QuotaError
+)
+
+// Priority lanes, from highest to lowest
+const (
+	PriorityInteractive = runtime.PriorityInteractive
+	PriorityDefault     = runtime.PriorityDefault
+	PriorityBackground  = runtime.PriorityBackground
+)
+
+// New creates a runtime. Call Configure before running scripts and
+// Dispose when done.
+func New() *Runtime {
+	return runtime.New()
+}
+
+// NewWithOptions creates a runtime with non-default settings
+func NewWithOptions(opts Options) *Runtime {
+	return runtime.NewWithOptions(opts)
+}
+
+// NewRuntimeManager creates a manager for per-tenant runtimes
+func NewRuntimeManager(opts ManagerOptions) *RuntimeManager {
+	return runtime.NewRuntimeManager(opts)
+}
+
+// CallJSFunctionAs calls a JavaScript function on rt and converts its
+// result to T
+func CallJSFunctionAs[T any](rt *Runtime, fn interface{}, args ...interface{}) (T, error) {
+	return runtime.CallJSFunctionAs[T](rt, fn, args...)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -10,32 +11,69 @@ import "C"
 func Name() string { return "async" }
 func Version() string { return "1.0.0" }
 
+// taskRunner is the part of the gode runtime this plugin uses: Go runs a
+// background task whose context ends when the runtime is disposed
+type taskRunner interface {
+	Go(name string, fn func(ctx context.Context))
+}
+
+var runner taskRunner
+
+// promiseFactory is the part of the gode runtime that creates JS promises,
+// which settle on the JS thread
+type promiseFactory interface {
+	NewPromise(executor func(resolve, reject func(interface{}))) interface{}
+}
+
+var promises promiseFactory
+
+// newPromise returns a JS Promise settled by executor
+func newPromise(executor func(resolve, reject func(interface{}))) interface{} {
+	if promises == nil {
+		return fmt.Errorf("async plugin is not initialized")
+	}
+	return promises.NewPromise(executor)
+}
+
+// after runs fn after delay in a runtime task
+func after(name string, delay time.Duration, fn func()) {
+	task := func(ctx context.Context) {
+		select {
+		case <-time.After(delay):
+			fn()
+		case <-ctx.Done():
+		}
+	}
+	if runner == nil {
+		go task(context.Background())
+		return
+	}
+	runner.Go(name, task)
+}
+
 // DelayedAdd performs addition after a delay (simulates async operation)
 func DelayedAdd(a, b int, delayMs int, callback func(interface{}, interface{})) {
-	go func() {
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
+	after("delayedAdd", time.Duration(delayMs)*time.Millisecond, func() {
 		result := a + b
 		callback(nil, result) // callback(error, result)
-	}()
+	})
 }
 
 // DelayedMultiply performs multiplication after a delay with potential error
 func DelayedMultiply(a, b int, delayMs int, callback func(interface{}, interface{})) {
-	go func() {
-		time.Sleep(time.Duration(delayMs) * time.Millisecond)
+	after("delayedMultiply", time.Duration(delayMs)*time.Millisecond, func() {
 		if a < 0 || b < 0 {
 			callback("negative numbers not allowed", nil)
 			return
 		}
 		result := a * b
 		callback(nil, result)
-	}()
+	})
 }
 
 // FetchData simulates fetching data asynchronously
 func FetchData(id string, callback func(interface{}, interface{})) {
-	go func() {
-		time.Sleep(100 * time.Millisecond) // Simulate network delay
+	after("fetchData", 100*time.Millisecond, func() {
 		if id == "" {
 			callback("invalid id", nil)
 			return
@@ -46,73 +84,35 @@ func FetchData(id string, callback func(interface{}, interface{})) {
 			"value": len(id) * 10,
 		}
 		callback(nil, data)
-	}()
+	})
 }
 
-// PromiseAdd returns a promise-like object for addition
-func PromiseAdd(a, b int, delayMs int) map[string]interface{} {
-	return map[string]interface{}{
-		"then": func(onResolve func(interface{})) map[string]interface{} {
-			go func() {
-				time.Sleep(time.Duration(delayMs) * time.Millisecond)
-				result := a + b
-				onResolve(result)
-			}()
-			return map[string]interface{}{
-				"catch": func(onReject func(interface{})) interface{} {
-					// For this simple case, we don't expect errors
-					return nil
-				},
-			}
-		},
-		"catch": func(onReject func(interface{})) interface{} {
-			// No error expected for simple addition
-			return nil
-		},
-	}
+// PromiseAdd resolves with a+b after a delay
+func PromiseAdd(a, b int, delayMs int) interface{} {
+	return newPromise(func(resolve, reject func(interface{})) {
+		after("promiseAdd", time.Duration(delayMs)*time.Millisecond, func() {
+			resolve(a + b)
+		})
+	})
 }
 
-// PromiseMultiply returns a promise-like object for multiplication with error handling
-func PromiseMultiply(a, b int, delayMs int) map[string]interface{} {
-	return map[string]interface{}{
-		"then": func(onResolve func(interface{})) map[string]interface{} {
-			go func() {
-				time.Sleep(time.Duration(delayMs) * time.Millisecond)
-				if a < 0 || b < 0 {
-					// We can't call onReject from here, so we'll need a different approach
-					return
-				}
-				result := a * b
-				onResolve(result)
-			}()
-			return map[string]interface{}{
-				"catch": func(onReject func(interface{})) interface{} {
-					go func() {
-						time.Sleep(time.Duration(delayMs) * time.Millisecond)
-						if a < 0 || b < 0 {
-							onReject("negative numbers not allowed")
-						}
-					}()
-					return nil
-				},
+// PromiseMultiply resolves with a*b after a delay, or rejects for negative
+// numbers
+func PromiseMultiply(a, b int, delayMs int) interface{} {
+	return newPromise(func(resolve, reject func(interface{})) {
+		after("promiseMultiply", time.Duration(delayMs)*time.Millisecond, func() {
+			if a < 0 || b < 0 {
+				reject(fmt.Errorf("negative numbers not allowed"))
+				return
 			}
-		},
-		"catch": func(onReject func(interface{})) interface{} {
-			go func() {
-				time.Sleep(time.Duration(delayMs) * time.Millisecond)
-				if a < 0 || b < 0 {
-					onReject("negative numbers not allowed")
-				}
-			}()
-			return nil
-		},
-	}
+			resolve(a * b)
+		})
+	})
 }
 
 // ProcessArray processes an array of numbers asynchronously
 func ProcessArray(numbers []int, callback func(interface{}, interface{})) {
-	go func() {
-		time.Sleep(50 * time.Millisecond)
+	after("processArray", 50*time.Millisecond, func() {
 		if len(numbers) == 0 {
 			callback("empty array", nil)
 			return
@@ -129,11 +129,13 @@ func ProcessArray(numbers []int, callback func(interface{}, interface{})) {
 			"average": float64(sum) / float64(len(numbers)),
 		}
 		callback(nil, result)
-	}()
+	})
 }
 
 // Plugin interface implementation
 func Initialize(runtime interface{}) error {
+	runner, _ = runtime.(taskRunner)
+	promises, _ = runtime.(promiseFactory)
 	fmt.Println("Async plugin initialized")
 	return nil
 }
@@ -158,7 +158,8 @@ describe('Async Plugin System - Goroutines', () => {
       
       promise.catch((error) => {
         try {
-          expect(error).toBe('negative numbers not allowed');
+          expect(error instanceof Error).toBe(true);
+          expect(error.message).toBe('negative numbers not allowed');
           done();
         } catch (e) {
           done(e);
@@ -201,7 +201,8 @@ describe('Async Plugin System', () => {
       
       promise.catch((error) => {
         try {
-          expect(error).toBe('negative numbers not allowed');
+          expect(error instanceof Error).toBe(true);
+          expect(error.message).toBe('negative numbers not allowed');
           done();
         } catch (e) {
           done(e);
@@ -209,6 +210,17 @@ describe('Async Plugin System', () => {
       });
     });
 
+    test('promises should chain and work with await', (done) => {
+      async.promiseAdd(2, 3, 20)
+        .then((sum) => async.promiseMultiply(sum, 4, 20))
+        .then(async (product) => {
+          const next = await async.promiseAdd(product, 1, 20);
+          expect(next).toBe(21);
+          done();
+        })
+        .catch(done);
+    });
+
     test('promise should work with basic then', (done) => {
       const promise = async.promiseAdd(5, 5, 50);
       